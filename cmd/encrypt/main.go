@@ -23,6 +23,8 @@ func main() {
 			secretsencrypt.Rotate,
 			secretsencrypt.Reencrypt,
 			secretsencrypt.RotateKeys,
+			secretsencrypt.Export,
+			secretsencrypt.Import,
 		),
 	}
 
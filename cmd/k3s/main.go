@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"io"
 	"io/fs"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/k3s-io/k3s/pkg/cli/cmds"
 	"github.com/k3s-io/k3s/pkg/configfilearg"
@@ -28,7 +30,15 @@ import (
 )
 
 var criDefaultConfigPath = "/etc/crictl.yaml"
-var externalCLIActions = []string{"crictl", "ctr", "kubectl"}
+var externalCLIActions = []string{"crictl", "ctr", "kubectl", "kubectl-" + version.Program}
+
+// auxCLIActions are the internal commands that may make use of the bundled aux binaries
+// (iptables, ipset, conntrack, and so on). The aux binaries are only extracted when one
+// of these commands is run.
+var auxCLIActions = []string{version.Program + "-server" + programPostfix, version.Program + "-agent" + programPostfix}
+
+// auxAssetSuffix is the suffix of the optional bindata asset containing the aux binaries.
+const auxAssetSuffix = "-aux.tar.zst"
 
 // main entrypoint for the k3s multicall binary
 func main() {
@@ -47,6 +57,20 @@ func main() {
 	etcdsnapshotCommand := internalCLIAction(version.Program+"-"+cmds.EtcdSnapshotCommand, dataDir, os.Args)
 	secretsencryptCommand := internalCLIAction(version.Program+"-"+cmds.SecretsEncryptCommand, dataDir, os.Args)
 	certCommand := internalCLIAction(version.Program+"-"+cmds.CertCommand, dataDir, os.Args)
+	staticPodCommand := internalCLIAction(version.Program+"-"+cmds.StaticPodCommand, dataDir, os.Args)
+	debugCommand := internalCLIAction(version.Program+"-"+cmds.DebugCommand, dataDir, os.Args)
+	generateCommand := internalCLIAction(version.Program+"-"+cmds.GenerateCommand, dataDir, os.Args)
+	clusterCommand := internalCLIAction(version.Program+"-"+cmds.ClusterCommand, dataDir, os.Args)
+	bootstrapCommand := internalCLIAction(version.Program+"-"+cmds.BootstrapCommand, dataDir, os.Args)
+	maintenanceCommand := internalCLIAction(version.Program+"-"+cmds.MaintenanceCommand, dataDir, os.Args)
+	backupCommand := internalCLIAction(version.Program+"-"+cmds.BackupCommand, dataDir, os.Args)
+	hibernateCommand := internalCLIAction(version.Program+"-"+cmds.HibernateCommand, dataDir, os.Args)
+	resumeCommand := internalCLIAction(version.Program+"-"+cmds.ResumeCommand, dataDir, os.Args)
+	servicesCommand := internalCLIAction(version.Program+"-"+cmds.ServicesCommand, dataDir, os.Args)
+	nodeShellCommand := internalCLIAction(version.Program+"-"+cmds.NodeShellCommand, dataDir, os.Args)
+	networkTestCommand := internalCLIAction(version.Program+"-"+cmds.NetworkTestCommand, dataDir, os.Args)
+	simulateCommand := internalCLIAction(version.Program+"-"+cmds.SimulateCommand, dataDir, os.Args)
+	resetIdentityCommand := internalCLIAction(version.Program+"-"+cmds.ResetIdentityCommand, dataDir, os.Args)
 
 	// Handle subcommand invocation (k3s server, k3s crictl, etc)
 	app := cmds.NewApp()
@@ -57,19 +81,22 @@ func main() {
 		cmds.NewKubectlCommand(externalCLIAction("kubectl", dataDir)),
 		cmds.NewCRICTL(externalCLIAction("crictl", dataDir)),
 		cmds.NewCtrCommand(externalCLIAction("ctr", dataDir)),
-		cmds.NewCheckConfigCommand(externalCLIAction("check-config", dataDir)),
+		cmds.NewCheckConfigCommand(internalCLIAction(version.Program+"-"+cmds.CheckConfigCommand, dataDir, os.Args)),
+		cmds.NewImagesCommand(internalCLIAction(version.Program+"-"+cmds.ImagesCommand, dataDir, os.Args)),
 		cmds.NewTokenCommands(
 			tokenCommand,
 			tokenCommand,
 			tokenCommand,
 			tokenCommand,
 			tokenCommand,
+			tokenCommand,
 		),
 		cmds.NewEtcdSnapshotCommands(
 			etcdsnapshotCommand,
 			etcdsnapshotCommand,
 			etcdsnapshotCommand,
 			etcdsnapshotCommand,
+			etcdsnapshotCommand,
 		),
 		cmds.NewSecretsEncryptCommands(
 			secretsencryptCommand,
@@ -79,13 +106,56 @@ func main() {
 			secretsencryptCommand,
 			secretsencryptCommand,
 			secretsencryptCommand,
+			secretsencryptCommand,
+			secretsencryptCommand,
 		),
 		cmds.NewCertCommands(
 			certCommand,
 			certCommand,
 			certCommand,
 		),
+		cmds.NewStaticPodCommands(
+			staticPodCommand,
+			staticPodCommand,
+		),
+		cmds.NewDebugCommands(
+			debugCommand,
+			debugCommand,
+			debugCommand,
+			debugCommand,
+		),
+		cmds.NewGenerateCommands(
+			generateCommand,
+		),
+		cmds.NewClusterCommands(
+			clusterCommand,
+			clusterCommand,
+			clusterCommand,
+		),
+		cmds.NewBootstrapCommands(
+			bootstrapCommand,
+		),
+		cmds.NewMaintenanceCommands(
+			maintenanceCommand,
+			maintenanceCommand,
+		),
+		cmds.NewBackupCommands(
+			backupCommand,
+			backupCommand,
+		),
+		cmds.NewHibernateCommand(hibernateCommand),
+		cmds.NewResumeCommand(resumeCommand),
+		cmds.NewServicesCommands(
+			servicesCommand,
+		),
+		cmds.NewNodeShellCommand(nodeShellCommand),
+		cmds.NewNetworkTestCommand(networkTestCommand),
+		cmds.NewSimulateCommands(
+			simulateCommand,
+		),
+		cmds.NewResetIdentityCommand(resetIdentityCommand),
 		cmds.NewCompletionCommand(internalCLIAction(version.Program+"-completion", dataDir, os.Args)),
+		cmds.NewPruneDataCommand(pruneDataAction(dataDir)),
 	}
 
 	if err := app.Run(os.Args); err != nil && !errors.Is(err, context.Canceled) {
@@ -205,17 +275,29 @@ func stageAndRunCLI(cli *cli.Context, cmd string, dataDir string, args []string)
 
 // stageAndRun does the actual work of setting up and calling an external binary.
 func stageAndRun(dataDir, cmd string, args []string, calledAsInternal bool) error {
+	startedAt := time.Now()
 	dir, err := extract(dataDir)
 	if err != nil {
 		return errors.Wrap(err, "extracting data")
 	}
 	logrus.Debugf("Asset dir %s", dir)
 
+	preferBundledBin := findPreferBundledBin(args)
+	if calledAsInternal && slices.Contains(auxCLIActions, cmd) && auxRequired(dir, preferBundledBin) {
+		if err := extractAux(dataDir, dir); err != nil {
+			return errors.Wrap(err, "extracting aux data")
+		}
+	}
+	if calledAsInternal {
+		os.Setenv(cmds.StartedAtEnv, startedAt.Format(time.RFC3339Nano))
+		os.Setenv(cmds.DataExtractedAtEnv, time.Now().Format(time.RFC3339Nano))
+	}
+
 	pathList := []string{
 		filepath.Clean(filepath.Join(dir, "..", "cni")),
 		filepath.Join(dir, "bin"),
 	}
-	if findPreferBundledBin(args) {
+	if preferBundledBin {
 		pathList = append(
 			pathList,
 			filepath.Join(dir, "bin", "aux"),
@@ -245,11 +327,96 @@ func stageAndRun(dataDir, cmd string, args []string, calledAsInternal bool) erro
 // getAssetAndDir returns the name of the bindata asset, along with a directory path
 // derived from the data-dir and bindata asset name.
 func getAssetAndDir(dataDir string) (string, string) {
-	asset := data.AssetNames()[0]
+	var asset string
+	for _, name := range data.AssetNames() {
+		if !strings.HasSuffix(name, auxAssetSuffix) {
+			asset = name
+			break
+		}
+	}
 	dir := filepath.Join(dataDir, "data", strings.SplitN(filepath.Base(asset), ".", 2)[0])
 	return asset, dir
 }
 
+// getAuxAsset returns the name of the bindata asset containing the aux binaries,
+// or an empty string if the aux binaries are not packaged separately.
+func getAuxAsset() string {
+	for _, name := range data.AssetNames() {
+		if strings.HasSuffix(name, auxAssetSuffix) {
+			return name
+		}
+	}
+	return ""
+}
+
+// auxRequired returns true if the aux binaries have not yet been extracted, and
+// are either preferred over host binaries, or one or more of them cannot be found
+// on the host. The list of aux binaries is read from the .aux file written alongside
+// the core binaries.
+func auxRequired(dir string, preferBundledBin bool) bool {
+	if _, err := os.Stat(filepath.Join(dir, "bin", "aux")); err == nil {
+		return false
+	}
+	tools, err := os.ReadFile(filepath.Join(dir, "bin", ".aux"))
+	if err != nil {
+		return false
+	}
+	if preferBundledBin {
+		return true
+	}
+	for _, tool := range strings.Fields(string(tools)) {
+		if _, err := exec.LookPath(filepath.Base(tool)); err != nil {
+			logrus.Debugf("Host binary %s not found, aux binaries are required", tool)
+			return true
+		}
+	}
+	return false
+}
+
+// extractAux unpacks the aux bindata archive into the bin/aux directory of the
+// extracted data dir.
+func extractAux(dataDir, dir string) error {
+	asset := getAuxAsset()
+	if asset == "" {
+		return nil
+	}
+
+	lockFile := filepath.Join(dataDir, "data", ".lock")
+	logrus.Infof("Acquiring lock file %s", lockFile)
+	lock, err := flock.Acquire(lockFile)
+	if err != nil {
+		return err
+	}
+	defer flock.Release(lock)
+
+	// check again if target directory exists
+	auxDir := filepath.Join(dir, "bin", "aux")
+	if _, err := os.Stat(auxDir); err == nil {
+		return nil
+	}
+
+	logrus.Infof("Preparing aux dir %s", auxDir)
+
+	content, err := data.Asset(asset)
+	if err != nil {
+		return err
+	}
+
+	tempDest := filepath.Join(dir, "aux-tmp")
+	defer os.RemoveAll(tempDest)
+	os.RemoveAll(tempDest)
+
+	sums, err := untar.UntarWithSums(bytes.NewReader(content), tempDest)
+	if err != nil {
+		return err
+	}
+	if err := dataverify.VerifyWithSums(filepath.Join(tempDest, "bin", "aux"), sums); err != nil {
+		return err
+	}
+
+	return os.Rename(filepath.Join(tempDest, "bin", "aux"), auxDir)
+}
+
 // extract checks for and if necessary unpacks the bindata archive, returning the unique path
 // to the extracted bindata asset.
 func extract(dataDir string) (string, error) {
@@ -286,20 +453,23 @@ func extract(dataDir string) (string, error) {
 
 	logrus.Infof("Preparing data dir %s", dir)
 
+	// The asset content is not copied out of the binary; it is streamed through the
+	// decompressor and written to disk file-by-file, with checksums calculated as
+	// each file is written so that the extracted files do not need to be read back.
 	content, err := data.Asset(asset)
 	if err != nil {
 		return "", err
 	}
-	buf := bytes.NewBuffer(content)
 
 	tempDest := dir + "-tmp"
 	defer os.RemoveAll(tempDest)
 	os.RemoveAll(tempDest)
 
-	if err := untar.Untar(buf, tempDest); err != nil {
+	sums, err := untar.UntarWithSums(bytes.NewReader(content), tempDest)
+	if err != nil {
 		return "", err
 	}
-	if err := dataverify.Verify(filepath.Join(tempDest, "bin")); err != nil {
+	if err := dataverify.VerifyWithSums(filepath.Join(tempDest, "bin"), sums); err != nil {
 		return "", err
 	}
 
@@ -367,9 +537,79 @@ func extract(dataDir string) (string, error) {
 		return "", err
 	}
 
+	// Clean up any data dirs left behind by older releases, now that the new
+	// directory is in place and the symlinks have been rotated.
+	if err := pruneDataDirs(dataDir, dir); err != nil {
+		logrus.Warnf("Failed to prune old data dirs: %v", err)
+	}
+
 	return dir, nil
 }
 
+// pruneDataAction returns a function that will remove old extracted data dirs, to be used as the Action of a cli.Command.
+func pruneDataAction(dataDir string) func(ctx *cli.Context) error {
+	return func(ctx *cli.Context) error {
+		os.MkdirAll(filepath.Join(dataDir, "data"), 0755)
+		lockFile := filepath.Join(dataDir, "data", ".lock")
+		logrus.Infof("Acquiring lock file %s", lockFile)
+		lock, err := flock.Acquire(lockFile)
+		if err != nil {
+			return err
+		}
+		defer flock.Release(lock)
+
+		_, dir := getAssetAndDir(dataDir)
+		return pruneDataDirs(dataDir, dir)
+	}
+}
+
+// pruneDataDirs removes extracted bindata directories from the data-dir, retaining the
+// directories targeted by the current and previous symlinks, as well as any additional
+// directories passed in by the caller. Leftover temporary directories from interrupted
+// extractions are also removed. The caller must hold the data dir lock.
+func pruneDataDirs(dataDir string, keep ...string) error {
+	base := filepath.Join(dataDir, "data")
+	retain := map[string]bool{}
+	for _, dir := range keep {
+		retain[filepath.Base(dir)] = true
+	}
+	for _, link := range []string{"current", "previous"} {
+		if target, err := os.Readlink(filepath.Join(base, link)); err == nil {
+			retain[filepath.Base(target)] = true
+		}
+	}
+
+	ents, err := os.ReadDir(base)
+	if err != nil {
+		return err
+	}
+	for _, ent := range ents {
+		name := strings.TrimSuffix(ent.Name(), "-tmp")
+		if !ent.IsDir() || !isAssetHash(name) {
+			continue
+		}
+		if retain[name] && name == ent.Name() {
+			continue
+		}
+		path := filepath.Join(base, ent.Name())
+		logrus.Infof("Removing old data dir %s", path)
+		if err := os.RemoveAll(path); err != nil {
+			return errors.Wrapf(err, "failed to remove %s", path)
+		}
+	}
+	return nil
+}
+
+// isAssetHash returns true if the name is a hex-encoded sha256 sum, as used to name
+// the bindata asset and the directory it is extracted into.
+func isAssetHash(name string) bool {
+	if len(name) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
 // findCriConfig returns the path to crictl.yaml
 // crictl won't search multiple locations for a config file. It will fall back to looking in
 // the same directory as the crictl binary, but that's it. We need to check the various possible
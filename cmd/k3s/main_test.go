@@ -1,6 +1,12 @@
 package main
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
 
 func Test_UnitFindPreferBundledBin(t *testing.T) {
 	tests := []struct {
@@ -57,3 +63,42 @@ func Test_UnitFindPreferBundledBin(t *testing.T) {
 		})
 	}
 }
+
+func Test_UnitPruneDataDirs(t *testing.T) {
+	hashes := []string{
+		strings.Repeat("a", 64),
+		strings.Repeat("b", 64),
+		strings.Repeat("c", 64),
+		strings.Repeat("d", 64),
+	}
+	dataDir := t.TempDir()
+	base := filepath.Join(dataDir, "data")
+	for _, dir := range append(hashes, "cni", hashes[0]+"-tmp") {
+		if err := os.MkdirAll(filepath.Join(base, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(base, hashes[2]), filepath.Join(base, "current")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(base, hashes[1]), filepath.Join(base, "previous")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pruneDataDirs(dataDir, filepath.Join(base, hashes[3])); err != nil {
+		t.Fatalf("pruneDataDirs() error = %v", err)
+	}
+
+	want := []string{hashes[1], hashes[2], "cni", "current", hashes[3], "previous"}
+	ents, err := os.ReadDir(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, ent := range ents {
+		got = append(got, ent.Name())
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("pruneDataDirs() left %v\nWant = %v", got, want)
+	}
+}
@@ -8,30 +8,51 @@ import (
 
 	"github.com/docker/docker/pkg/reexec"
 	"github.com/k3s-io/k3s/pkg/cli/agent"
+	"github.com/k3s-io/k3s/pkg/cli/backup"
+	"github.com/k3s-io/k3s/pkg/cli/bootstrap"
 	"github.com/k3s-io/k3s/pkg/cli/cert"
+	"github.com/k3s-io/k3s/pkg/cli/checkconfig"
+	"github.com/k3s-io/k3s/pkg/cli/cluster"
 	"github.com/k3s-io/k3s/pkg/cli/cmds"
 	"github.com/k3s-io/k3s/pkg/cli/completion"
 	"github.com/k3s-io/k3s/pkg/cli/crictl"
 	"github.com/k3s-io/k3s/pkg/cli/ctr"
+	"github.com/k3s-io/k3s/pkg/cli/debug"
 	"github.com/k3s-io/k3s/pkg/cli/etcdsnapshot"
+	"github.com/k3s-io/k3s/pkg/cli/generate"
+	"github.com/k3s-io/k3s/pkg/cli/hibernate"
+	"github.com/k3s-io/k3s/pkg/cli/images"
 	"github.com/k3s-io/k3s/pkg/cli/kubectl"
+	"github.com/k3s-io/k3s/pkg/cli/maintenance"
+	"github.com/k3s-io/k3s/pkg/cli/networktest"
+	"github.com/k3s-io/k3s/pkg/cli/nodeshell"
+	"github.com/k3s-io/k3s/pkg/cli/resetidentity"
 	"github.com/k3s-io/k3s/pkg/cli/secretsencrypt"
 	"github.com/k3s-io/k3s/pkg/cli/server"
+	"github.com/k3s-io/k3s/pkg/cli/services"
+	"github.com/k3s-io/k3s/pkg/cli/simulate"
+	"github.com/k3s-io/k3s/pkg/cli/staticpod"
 	"github.com/k3s-io/k3s/pkg/cli/token"
 	"github.com/k3s-io/k3s/pkg/configfilearg"
 	"github.com/k3s-io/k3s/pkg/containerd"
 	ctr2 "github.com/k3s-io/k3s/pkg/ctr"
+	"github.com/k3s-io/k3s/pkg/daemons/executor"
 	kubectl2 "github.com/k3s-io/k3s/pkg/kubectl"
+	"github.com/k3s-io/k3s/pkg/kubectlplugin"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
-	crictl2 "sigs.k8s.io/cri-tools/cmd/crictl"
 )
 
 func init() {
 	reexec.Register("containerd", containerd.Main)
 	reexec.Register("kubectl", kubectl2.Main)
-	reexec.Register("crictl", crictl2.Main)
+	reexec.Register(kubectlplugin.Name, kubectlplugin.Main)
+	reexec.Register("crictl", crictl.Main)
 	reexec.Register("ctr", ctr2.Main)
+	reexec.Register("kubelet", executor.KubeletMain)
+	reexec.Register("kube-apiserver", executor.APIServerMain)
+	reexec.Register("kube-scheduler", executor.SchedulerMain)
+	reexec.Register("kube-controller-manager", executor.ControllerManagerMain)
 }
 
 func main() {
@@ -49,18 +70,22 @@ func main() {
 		cmds.NewKubectlCommand(kubectl.Run),
 		cmds.NewCRICTL(crictl.Run),
 		cmds.NewCtrCommand(ctr.Run),
+		cmds.NewCheckConfigCommand(checkconfig.Run),
+		cmds.NewImagesCommand(images.Run),
 		cmds.NewTokenCommands(
 			token.Create,
 			token.Delete,
 			token.Generate,
 			token.List,
 			token.Rotate,
+			token.Kubeconfig,
 		),
 		cmds.NewEtcdSnapshotCommands(
 			etcdsnapshot.Delete,
 			etcdsnapshot.List,
 			etcdsnapshot.Prune,
 			etcdsnapshot.Save,
+			etcdsnapshot.Verify,
 		),
 		cmds.NewSecretsEncryptCommands(
 			secretsencrypt.Status,
@@ -70,12 +95,54 @@ func main() {
 			secretsencrypt.Rotate,
 			secretsencrypt.Reencrypt,
 			secretsencrypt.RotateKeys,
+			secretsencrypt.Export,
+			secretsencrypt.Import,
 		),
 		cmds.NewCertCommands(
 			cert.Check,
 			cert.Rotate,
 			cert.RotateCA,
 		),
+		cmds.NewStaticPodCommands(
+			staticpod.List,
+			staticpod.Validate,
+		),
+		cmds.NewDebugCommands(
+			debug.Profile,
+			debug.Tunnels,
+			debug.Egress,
+			debug.MemStats,
+		),
+		cmds.NewGenerateCommands(
+			generate.BootstrapData,
+		),
+		cmds.NewClusterCommands(
+			cluster.Export,
+			cluster.MigrateDomain,
+			cluster.ExpandCIDR,
+		),
+		cmds.NewBootstrapCommands(
+			bootstrap.Diff,
+		),
+		cmds.NewMaintenanceCommands(
+			maintenance.Enable,
+			maintenance.Disable,
+		),
+		cmds.NewBackupCommands(
+			backup.Create,
+			backup.Restore,
+		),
+		cmds.NewHibernateCommand(hibernate.Hibernate),
+		cmds.NewResumeCommand(hibernate.Resume),
+		cmds.NewServicesCommands(
+			services.Reallocate,
+		),
+		cmds.NewNodeShellCommand(nodeshell.Run),
+		cmds.NewNetworkTestCommand(networktest.Run),
+		cmds.NewSimulateCommands(
+			simulate.Upgrade,
+		),
+		cmds.NewResetIdentityCommand(resetidentity.Run),
 		cmds.NewCompletionCommand(completion.Run),
 	}
 
@@ -21,6 +21,7 @@ func main() {
 			token.Generate,
 			token.List,
 			token.Rotate,
+			token.Kubeconfig,
 		),
 	}
 
@@ -754,7 +754,7 @@ create_symlinks() {
     [ "${INSTALL_K3S_BIN_DIR_READ_ONLY}" = true ] && return
     [ "${INSTALL_K3S_SYMLINK}" = skip ] && return
 
-    for cmd in kubectl crictl ctr; do
+    for cmd in kubectl kubectl-k3s crictl ctr; do
         if [ ! -e ${BIN_DIR}/${cmd} ] || [ "${INSTALL_K3S_SYMLINK}" = force ]; then
             which_cmd=$(command -v ${cmd} 2>/dev/null || true)
             if [ -z "${which_cmd}" ] || [ "${INSTALL_K3S_SYMLINK}" = force ]; then
@@ -905,7 +905,7 @@ if (ls ${SYSTEMD_DIR}/k3s*.service || ls /etc/init.d/k3s*) >/dev/null 2>&1; then
     exit
 fi
 
-for cmd in kubectl crictl ctr; do
+for cmd in kubectl kubectl-k3s crictl ctr; do
     if [ -L ${BIN_DIR}/\$cmd ]; then
         rm -f ${BIN_DIR}/\$cmd
     fi
@@ -930,6 +930,29 @@ clean_mounted_directory() {
      done
 }
 
+if command -v firewall-cmd >/dev/null 2>&1; then
+    for service in k3s-server k3s-agent; do
+        if firewall-cmd --permanent --info-service=\$service >/dev/null 2>&1; then
+            firewall-cmd --permanent --remove-service=\$service
+            firewall-cmd --permanent --delete-service=\$service
+        fi
+    done
+    if firewall-cmd --permanent --info-zone=k3s >/dev/null 2>&1; then
+        firewall-cmd --permanent --delete-zone=k3s
+    fi
+    firewall-cmd --state >/dev/null 2>&1 && firewall-cmd --reload
+fi
+if command -v ufw >/dev/null 2>&1; then
+    ufw status numbered | sed -n 's/^\[ *\([0-9]*\)\].*# k3s\$/\1/p' | sort -rn | while read -r rule; do
+        ufw --force delete \$rule
+    done
+    rm -f /etc/ufw/applications.d/k3s-server /etc/ufw/applications.d/k3s-agent
+fi
+
+if [ -f \${K3S_DATA_DIR}/agent/etc/crio/managed-files ]; then
+    xargs rm -f < \${K3S_DATA_DIR}/agent/etc/crio/managed-files
+fi
+
 rm -rf /etc/rancher/k3s
 rm -rf /run/k3s
 rm -rf /run/flannel
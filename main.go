@@ -37,6 +37,7 @@ func main() {
 			etcdsnapshot.List,
 			etcdsnapshot.Prune,
 			etcdsnapshot.Save,
+			etcdsnapshot.Verify,
 		),
 		cmds.NewSecretsEncryptCommands(
 			secretsencrypt.Status,
@@ -46,6 +47,8 @@ func main() {
 			secretsencrypt.Rotate,
 			secretsencrypt.Reencrypt,
 			secretsencrypt.RotateKeys,
+			secretsencrypt.Export,
+			secretsencrypt.Import,
 		),
 		cmds.NewCertCommands(
 			cert.Check,
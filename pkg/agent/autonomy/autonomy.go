@@ -0,0 +1,246 @@
+// Package autonomy implements disconnected autonomy for agents. While the agent is connected, pods
+// assigned to the node that opt in via annotation are checkpointed to disk as static pod manifests,
+// along with the agent's configuration. If no server can be reached when the agent starts, the
+// kubelet is started from the checkpointed configuration, and the checkpointed pods are run as
+// static pods until the apiserver is reachable again.
+package autonomy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ManifestPrefix is prepended to the name of checkpointed pod manifests when they are restored
+	// into the kubelet's static pod path, so that they can be removed once the apiserver is reachable.
+	ManifestPrefix = "autonomy-"
+	// StartupTimeout is the time that the agent waits for a server at startup, before starting
+	// from checkpointed state.
+	StartupTimeout = time.Minute
+	// checkpointInterval is the interval at which pods assigned to the node are checkpointed.
+	checkpointInterval = 30 * time.Second
+	// nodeConfigFile is the name of the file that the agent configuration is checkpointed to.
+	nodeConfigFile = "node-config.json"
+	// kubeAPIAccessPrefix is the name prefix of the projected service account token volume
+	// that is added to pods by the ServiceAccount admission plugin.
+	kubeAPIAccessPrefix = "kube-api-access-"
+)
+
+// Annotation opts a pod in to being checkpointed and run while the node is disconnected.
+var Annotation = version.Program + ".io/autonomous"
+
+// Dir returns the directory that checkpoints are stored in.
+func Dir(dataDir string) string {
+	return filepath.Join(dataDir, "agent", "autonomy")
+}
+
+// SaveNodeConfig checkpoints the agent configuration.
+func SaveNodeConfig(dir string, nodeConfig *config.Node) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(nodeConfig)
+	if err != nil {
+		return err
+	}
+	return util.AtomicWrite(filepath.Join(dir, nodeConfigFile), b, 0600)
+}
+
+// LoadNodeConfig returns the checkpointed agent configuration.
+func LoadNodeConfig(dir string) (*config.Node, error) {
+	b, err := os.ReadFile(filepath.Join(dir, nodeConfigFile))
+	if err != nil {
+		return nil, err
+	}
+	nodeConfig := &config.Node{}
+	if err := json.Unmarshal(b, nodeConfig); err != nil {
+		return nil, err
+	}
+	return nodeConfig, nil
+}
+
+// HasNodeConfig returns true if the agent configuration has been checkpointed.
+func HasNodeConfig(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, nodeConfigFile))
+	return err == nil
+}
+
+// Checkpoint starts a goroutine that periodically checkpoints opted-in pods assigned to the node.
+func Checkpoint(ctx context.Context, dir, nodeName string, pods typedcorev1.PodInterface) {
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := checkpoint(ctx, dir, nodeName, pods); err != nil {
+			logrus.Warnf("Failed to checkpoint pods for disconnected autonomy: %v", err)
+		}
+	}, checkpointInterval)
+}
+
+// checkpoint writes a static pod manifest for each opted-in pod assigned to the node, and removes
+// checkpoints for pods that are no longer assigned to the node or have opted out.
+func checkpoint(ctx context.Context, dir, nodeName string, pods typedcorev1.PodInterface) error {
+	podList, err := pods.List(ctx, metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String()})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	current := map[string]bool{}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Annotations[Annotation] != "true" || pod.DeletionTimestamp != nil || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		name := pod.Namespace + "_" + pod.Name + ".yaml"
+		staticPod, err := toStaticPod(pod)
+		if err != nil {
+			logrus.Warnf("Not checkpointing pod %s/%s for disconnected autonomy: %v", pod.Namespace, pod.Name, err)
+			continue
+		}
+		b, err := yaml.Marshal(staticPod)
+		if err != nil {
+			return err
+		}
+		current[name] = true
+		path := filepath.Join(dir, name)
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, b) {
+			continue
+		}
+		if err := util.AtomicWrite(path, b, 0600); err != nil {
+			return err
+		}
+		logrus.Infof("Checkpointed pod %s/%s for disconnected autonomy", pod.Namespace, pod.Name)
+	}
+
+	manifests, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	for _, path := range manifests {
+		if current[filepath.Base(path)] {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		logrus.Infof("Removed disconnected autonomy checkpoint %s", path)
+	}
+	return nil
+}
+
+// toStaticPod returns a copy of the pod that can be run as a static pod. Static pods cannot
+// reference other API objects, and are run before the CNI is available when the node is
+// disconnected, so pods must use host networking and must not use API-backed volumes or
+// environment variables. The service account token volume added by admission is removed.
+func toStaticPod(pod *v1.Pod) (*v1.Pod, error) {
+	spec := pod.Spec.DeepCopy()
+	if !spec.HostNetwork {
+		return nil, errors.New("pod must use host networking")
+	}
+
+	volumes := []v1.Volume{}
+	for _, volume := range spec.Volumes {
+		switch {
+		case volume.Projected != nil && strings.HasPrefix(volume.Name, kubeAPIAccessPrefix):
+			continue
+		case volume.Secret != nil, volume.ConfigMap != nil, volume.Projected != nil, volume.PersistentVolumeClaim != nil:
+			return nil, fmt.Errorf("volume %s references an API object", volume.Name)
+		}
+		volumes = append(volumes, volume)
+	}
+	spec.Volumes = volumes
+
+	for _, containers := range [][]v1.Container{spec.InitContainers, spec.Containers} {
+		for i := range containers {
+			container := &containers[i]
+			if len(container.EnvFrom) > 0 {
+				return nil, fmt.Errorf("container %s references an API object in envFrom", container.Name)
+			}
+			for _, env := range container.Env {
+				if env.ValueFrom != nil && (env.ValueFrom.ConfigMapKeyRef != nil || env.ValueFrom.SecretKeyRef != nil) {
+					return nil, fmt.Errorf("container %s references an API object in env %s", container.Name, env.Name)
+				}
+			}
+			mounts := []v1.VolumeMount{}
+			for _, mount := range container.VolumeMounts {
+				if !strings.HasPrefix(mount.Name, kubeAPIAccessPrefix) {
+					mounts = append(mounts, mount)
+				}
+			}
+			container.VolumeMounts = mounts
+		}
+	}
+
+	spec.NodeName = ""
+	spec.ServiceAccountName = ""
+	spec.DeprecatedServiceAccount = ""
+	spec.AutomountServiceAccountToken = nil
+	spec.ImagePullSecrets = nil
+
+	return &v1.Pod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pod.Name,
+			Namespace:   pod.Namespace,
+			Labels:      pod.Labels,
+			Annotations: map[string]string{Annotation: "true"},
+		},
+		Spec: *spec,
+	}, nil
+}
+
+// Restore copies checkpointed pods into the kubelet's static pod path.
+func Restore(dir, podManifests string) error {
+	manifests, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(podManifests, 0750); err != nil {
+		return err
+	}
+	for _, path := range manifests {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := util.AtomicWrite(filepath.Join(podManifests, ManifestPrefix+filepath.Base(path)), b, 0600); err != nil {
+			return err
+		}
+		logrus.Infof("Restored checkpointed pod %s for disconnected autonomy", strings.TrimSuffix(filepath.Base(path), ".yaml"))
+	}
+	return nil
+}
+
+// Reconcile removes restored pods from the kubelet's static pod path, so that the pods assigned
+// to the node by the apiserver take over.
+func Reconcile(podManifests string) error {
+	manifests, err := filepath.Glob(filepath.Join(podManifests, ManifestPrefix+"*"))
+	if err != nil {
+		return err
+	}
+	for _, path := range manifests {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		logrus.Infof("Removed restored disconnected autonomy pod manifest %s", path)
+	}
+	return nil
+}
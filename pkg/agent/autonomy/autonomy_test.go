@@ -0,0 +1,148 @@
+package autonomy
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_UnitNodeConfig(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "autonomy")
+	if HasNodeConfig(dir) {
+		t.Fatal("HasNodeConfig() = true before node config was saved")
+	}
+
+	_, serviceCIDR, _ := net.ParseCIDR("10.43.0.0/16")
+	nodeConfig := &config.Node{
+		Token:           "K10abc::node:secret",
+		ServerHTTPSPort: 6443,
+		AgentConfig: config.Agent{
+			NodeName:     "node1",
+			NodeIPs:      []net.IP{net.ParseIP("10.0.0.1")},
+			ServiceCIDR:  serviceCIDR,
+			ServiceCIDRs: []*net.IPNet{serviceCIDR},
+			PodManifests: "/var/lib/rancher/k3s/agent/pod-manifests",
+		},
+	}
+	if err := SaveNodeConfig(dir, nodeConfig); err != nil {
+		t.Fatal(err)
+	}
+	if !HasNodeConfig(dir) {
+		t.Fatal("HasNodeConfig() = false after node config was saved")
+	}
+	got, err := LoadNodeConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Token != nodeConfig.Token || got.AgentConfig.NodeName != "node1" || got.AgentConfig.ServiceCIDR.String() != "10.43.0.0/16" || !got.AgentConfig.NodeIPs[0].Equal(nodeConfig.AgentConfig.NodeIPs[0]) {
+		t.Errorf("LoadNodeConfig() = %+v, want %+v", got, nodeConfig)
+	}
+}
+
+func Test_UnitToStaticPod(t *testing.T) {
+	newPod := func(mutate func(*v1.Pod)) *v1.Pod {
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "appliance",
+				Namespace:       "default",
+				UID:             "1234",
+				ResourceVersion: "5678",
+				Labels:          map[string]string{"app": "appliance"},
+				Annotations:     map[string]string{Annotation: "true", "other": "value"},
+			},
+			Spec: v1.PodSpec{
+				NodeName:           "node1",
+				HostNetwork:        true,
+				ServiceAccountName: "default",
+				Volumes: []v1.Volume{
+					{Name: "data", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/data"}}},
+					{Name: "kube-api-access-abcde", VolumeSource: v1.VolumeSource{Projected: &v1.ProjectedVolumeSource{}}},
+				},
+				Containers: []v1.Container{{
+					Name:  "app",
+					Image: "docker.io/library/nginx:latest",
+					VolumeMounts: []v1.VolumeMount{
+						{Name: "data", MountPath: "/data"},
+						{Name: "kube-api-access-abcde", MountPath: "/var/run/secrets/kubernetes.io/serviceaccount"},
+					},
+				}},
+			},
+		}
+		if mutate != nil {
+			mutate(pod)
+		}
+		return pod
+	}
+
+	tests := []struct {
+		name    string
+		pod     *v1.Pod
+		wantErr bool
+	}{
+		{name: "host network pod", pod: newPod(nil)},
+		{name: "pod network", pod: newPod(func(pod *v1.Pod) { pod.Spec.HostNetwork = false }), wantErr: true},
+		{name: "secret volume", pod: newPod(func(pod *v1.Pod) {
+			pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{Name: "creds", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "creds"}}})
+		}), wantErr: true},
+		{name: "configmap env", pod: newPod(func(pod *v1.Pod) {
+			pod.Spec.Containers[0].Env = []v1.EnvVar{{Name: "CONFIG", ValueFrom: &v1.EnvVarSource{ConfigMapKeyRef: &v1.ConfigMapKeySelector{Key: "config"}}}}
+		}), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toStaticPod(tt.pod)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("toStaticPod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.UID != "" || got.ResourceVersion != "" || got.Spec.NodeName != "" || got.Spec.ServiceAccountName != "" {
+				t.Errorf("toStaticPod() did not clear API-managed fields: %+v", got)
+			}
+			if !reflect.DeepEqual(got.Annotations, map[string]string{Annotation: "true"}) {
+				t.Errorf("toStaticPod() annotations = %v", got.Annotations)
+			}
+			if len(got.Spec.Volumes) != 1 || len(got.Spec.Containers[0].VolumeMounts) != 1 {
+				t.Errorf("toStaticPod() did not remove service account token volume: %+v", got.Spec)
+			}
+			if len(tt.pod.Spec.Volumes) != 2 {
+				t.Error("toStaticPod() modified the original pod")
+			}
+		})
+	}
+}
+
+func Test_UnitRestoreAndReconcile(t *testing.T) {
+	dir := t.TempDir()
+	podManifests := filepath.Join(t.TempDir(), "pod-manifests")
+	if err := os.WriteFile(filepath.Join(dir, "default_appliance.yaml"), []byte("kind: Pod\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(dir, podManifests); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(podManifests, "user-other.yaml"), []byte("kind: Pod\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(podManifests, ManifestPrefix+"default_appliance.yaml")); err != nil {
+		t.Fatalf("Restore() did not restore checkpointed pod: %v", err)
+	}
+
+	if err := Reconcile(podManifests); err != nil {
+		t.Fatal(err)
+	}
+	ents, err := os.ReadDir(podManifests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ents) != 1 || ents[0].Name() != "user-other.yaml" {
+		t.Errorf("Reconcile() left %v, want only user-other.yaml", ents)
+	}
+}
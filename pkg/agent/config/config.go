@@ -3,6 +3,7 @@ package config
 import (
 	"bufio"
 	"bytes"
+	"cmp"
 	"context"
 	cryptorand "crypto/rand"
 	"crypto/tls"
@@ -18,17 +19,26 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	goruntime "runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/k3s-io/k3s/pkg/agent/crio"
 	"github.com/k3s-io/k3s/pkg/agent/proxy"
+	"github.com/k3s-io/k3s/pkg/agent/stun"
 	agentutil "github.com/k3s-io/k3s/pkg/agent/util"
+	"github.com/k3s-io/k3s/pkg/cgroups"
 	"github.com/k3s-io/k3s/pkg/cli/cmds"
 	"github.com/k3s-io/k3s/pkg/clientaccess"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/daemons/control/deps"
+	"github.com/k3s-io/k3s/pkg/images"
+	"github.com/k3s-io/k3s/pkg/instance"
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
 	"github.com/k3s-io/k3s/pkg/spegel"
+	"github.com/k3s-io/k3s/pkg/tracing"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/k3s-io/k3s/pkg/vpn"
@@ -37,6 +47,8 @@ import (
 	"github.com/rancher/wharfie/pkg/registries"
 	"github.com/rancher/wrangler/v3/pkg/slice"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/apimachinery/pkg/util/wait"
 	utilsnet "k8s.io/utils/net"
@@ -46,6 +58,9 @@ const (
 	DefaultPodManifestPath = "pod-manifests"
 )
 
+// errNodePasswordRejected is returned when the server rejects the node password.
+var errNodePasswordRejected = errors.New("Node password rejected")
+
 // Get returns a pointer to a completed Node configuration struct,
 // containing a merging of the local CLI configuration with settings from the server.
 // Node configuration includes client certificates, which requires node password verification,
@@ -135,12 +150,31 @@ func Request(path string, info *clientaccess.Info, requester HTTPRequester) ([]b
 	return requester(u.String(), clientaccess.GetHTTPClient(info.CACerts, info.CertFile, info.KeyFile), info.Username, info.Password, info.Token())
 }
 
-func getNodeNamedCrt(nodeName string, nodeIPs []net.IP, nodePasswordFile string, csr []byte) HTTPRequester {
+// statusMessage returns the message of the Status in an error response body, or an empty string if the
+// body is not a Status.
+func statusMessage(body io.Reader) string {
+	status := &metav1.Status{}
+	if b, err := io.ReadAll(body); err != nil || json.Unmarshal(b, status) != nil {
+		return ""
+	}
+	return status.Message
+}
+
+// verifyNodePassword asks the server to validate the node password, without signing a new certificate.
+// This is used when existing kubelet certificates are reused on a warm restart, so that the node password
+// is still checked on every start.
+func verifyNodePassword(ctx context.Context, nodeName string, nodeIPs []net.IP, nodePasswordFile string, info *clientaccess.Info) error {
+	_, err := Request("/v1-"+version.Program+"/node-password", info, getNodeNamedCrt(ctx, nodeName, nodeIPs, nodePasswordFile, nil))
+	return err
+}
+
+func getNodeNamedCrt(ctx context.Context, nodeName string, nodeIPs []net.IP, nodePasswordFile string, csr []byte) HTTPRequester {
 	return func(u string, client *http.Client, username, password, token string) ([]byte, error) {
-		req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(csr))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(csr))
 		if err != nil {
 			return nil, err
 		}
+		tracing.Inject(ctx, req.Header)
 
 		if token != "" {
 			req.Header.Add("Authorization", "Bearer "+token)
@@ -177,7 +211,11 @@ func getNodeNamedCrt(nodeName string, nodeIPs []net.IP, nodePasswordFile string,
 		}
 
 		if resp.StatusCode == http.StatusForbidden {
-			return nil, fmt.Errorf("Node password rejected, duplicate hostname or contents of '%s' may not match server node-passwd entry, try enabling a unique node name with the --with-node-id flag", nodePasswordFile)
+			// servers that detect node name conflicts describe how to resolve them in the response status
+			if message := statusMessage(resp.Body); message != "" {
+				return nil, fmt.Errorf("%w: %s", errNodePasswordRejected, message)
+			}
+			return nil, fmt.Errorf("%w, duplicate hostname or contents of '%s' may not match server node-passwd entry, try enabling a unique node name with the --with-node-id flag", errNodePasswordRejected, nodePasswordFile)
 		}
 
 		if resp.StatusCode != http.StatusOK {
@@ -244,14 +282,16 @@ func upgradeOldNodePasswordPath(oldNodePasswordFile, newNodePasswordFile string)
 // from the server.  We attempt to POST a CSR to the server, in hopes that it will
 // sign the cert using our locally generated key. If the server does not support CSR
 // signing, the key generated by the server is used instead.
-func getKubeletServingCert(nodeName string, nodeIPs []net.IP, certFile, keyFile, nodePasswordFile string, info *clientaccess.Info) error {
+func getKubeletServingCert(ctx context.Context, nodeName string, nodeIPs []net.IP, certFile, keyFile, nodePasswordFile string, info *clientaccess.Info) error {
 	csr, err := getCSRBytes(keyFile)
 	if err != nil {
 		return errors.Wrapf(err, "failed to create certificate request %s", certFile)
 	}
 
 	basename := filepath.Base(certFile)
-	body, err := Request("/v1-"+version.Program+"/"+basename, info, getNodeNamedCrt(nodeName, nodeIPs, nodePasswordFile, csr))
+	ctx, span := tracing.Start(ctx, "agent.request-certificate", attribute.String("certificate.file", basename))
+	defer span.End()
+	body, err := Request("/v1-"+version.Program+"/"+basename, info, getNodeNamedCrt(ctx, nodeName, nodeIPs, nodePasswordFile, csr))
 	if err != nil {
 		return err
 	}
@@ -288,14 +328,16 @@ func getHostFile(filename string, info *clientaccess.Info) error {
 // We attempt to POST a CSR to the server, in hopes that it will sign the cert using
 // our locally generated key. If the server does not support CSR signing, the key
 // generated by the server is used instead.
-func getClientCert(certFile, keyFile string, info *clientaccess.Info) error {
+func getClientCert(ctx context.Context, certFile, keyFile string, info *clientaccess.Info) error {
 	csr, err := getCSRBytes(keyFile)
 	if err != nil {
 		return errors.Wrapf(err, "failed to create certificate request %s", certFile)
 	}
 
 	basename := filepath.Base(certFile)
-	fileBytes, err := info.Post("/v1-"+version.Program+"/"+basename, csr)
+	ctx, span := tracing.Start(ctx, "agent.request-certificate", attribute.String("certificate.file", basename))
+	defer span.End()
+	fileBytes, err := info.Post("/v1-"+version.Program+"/"+basename, csr, clientaccess.WithTraceContext(ctx))
 	if err != nil {
 		return err
 	}
@@ -315,6 +357,95 @@ func getClientCert(certFile, keyFile string, info *clientaccess.Info) error {
 	return nil
 }
 
+// ReissueClientCerts requests new client certificates for the kubelet, kube-proxy, and agent controller
+// if they were not issued by the signing certificate of the client CA bundle, as is the case once the
+// servers switch to a new CA during a phased CA rotation. Components load their client certificates
+// from these files, so the new certificates are used without restarting the agent. The hash of the
+// signing certificate is returned once all client certificates have been issued by it.
+func ReissueClientCerts(ctx context.Context, node *config.Node, info *clientaccess.Info) (string, error) {
+	certs, err := certutil.CertsFromFile(node.AgentConfig.ClientCA)
+	if err != nil {
+		return "", err
+	}
+	signer := certs[0]
+	nodePasswordFile := filepath.Join(node.AgentConfig.NodeConfigPath, "password")
+
+	if !issuedBy(node.AgentConfig.ClientKubeletCert, signer) {
+		logrus.Infof("Requesting new client certificate %s issued by %s", node.AgentConfig.ClientKubeletCert, signer.Subject)
+		if err := getKubeletClientCert(ctx, node.AgentConfig.ClientKubeletCert, node.AgentConfig.ClientKubeletKey, node.AgentConfig.NodeName, node.AgentConfig.NodeIPs, nodePasswordFile, info); err != nil {
+			return "", errors.Wrap(err, node.AgentConfig.ClientKubeletCert)
+		}
+	}
+	for _, pair := range [][2]string{
+		{node.AgentConfig.ClientKubeProxyCert, node.AgentConfig.ClientKubeProxyKey},
+		{node.AgentConfig.ClientK3sControllerCert, node.AgentConfig.ClientK3sControllerKey},
+	} {
+		if issuedBy(pair[0], signer) {
+			continue
+		}
+		logrus.Infof("Requesting new client certificate %s issued by %s", pair[0], signer.Subject)
+		if err := getClientCert(ctx, pair[0], pair[1], info); err != nil {
+			return "", errors.Wrap(err, pair[0])
+		}
+	}
+
+	for _, certFile := range []string{node.AgentConfig.ClientKubeletCert, node.AgentConfig.ClientKubeProxyCert, node.AgentConfig.ClientK3sControllerCert} {
+		if !issuedBy(certFile, signer) {
+			return "", fmt.Errorf("client certificate %s was not issued by %s", certFile, signer.Subject)
+		}
+	}
+	return nodeconfig.IssuerHash(signer), nil
+}
+
+// ReissueServingCert requests a new kubelet serving certificate if it was not issued by the signing
+// certificate of the server CA bundle, as is the case once the servers switch to a new CA during a phased
+// CA rotation. The kubelet reloads its serving certificate when the file changes. The hash of the signing
+// certificate is returned once the serving certificate has been issued by it. When the kubelet requests
+// its own serving certificate through the CertificateSigningRequest API, the certificate is only replaced
+// when the kubelet rotates it, or is restarted.
+func ReissueServingCert(ctx context.Context, node *config.Node, info *clientaccess.Info) (string, error) {
+	certs, err := certutil.CertsFromFile(node.AgentConfig.ServerCA)
+	if err != nil {
+		return "", err
+	}
+	signer := certs[0]
+
+	if node.AgentConfig.KubeletServingCSR {
+		rootDir := node.AgentConfig.RootDir
+		if rootDir == "" {
+			rootDir = "/var/lib/kubelet"
+		}
+		certFile := filepath.Join(rootDir, "pki", "kubelet-server-current.pem")
+		if !issuedBy(certFile, signer) {
+			return "", fmt.Errorf("kubelet serving certificate %s was not issued by %s; restart the agent so that the kubelet requests a new certificate", certFile, signer.Subject)
+		}
+		return nodeconfig.IssuerHash(signer), nil
+	}
+
+	certFile, keyFile := node.AgentConfig.ServingKubeletCert, node.AgentConfig.ServingKubeletKey
+	if !issuedBy(certFile, signer) {
+		logrus.Infof("Requesting new serving certificate %s issued by %s", certFile, signer.Subject)
+		nodePasswordFile := filepath.Join(node.AgentConfig.NodeConfigPath, "password")
+		nodeIPs := append(slices.Clone(node.AgentConfig.NodeIPs), node.AgentConfig.NodeExternalIPs...)
+		if err := getKubeletServingCert(ctx, node.AgentConfig.NodeName, nodeIPs, certFile, keyFile, nodePasswordFile, info); err != nil {
+			return "", errors.Wrap(err, certFile)
+		}
+		if !issuedBy(certFile, signer) {
+			return "", fmt.Errorf("serving certificate %s was not issued by %s", certFile, signer.Subject)
+		}
+	}
+	return nodeconfig.IssuerHash(signer), nil
+}
+
+// issuedBy returns true if the first certificate in the file was signed by the issuer.
+func issuedBy(certFile string, issuer *x509.Certificate) bool {
+	certs, err := certutil.CertsFromFile(certFile)
+	if err != nil {
+		return false
+	}
+	return certs[0].CheckSignatureFrom(issuer) == nil
+}
+
 func getCSRBytes(keyFile string) ([]byte, error) {
 	keyBytes, _, err := certutil.LoadOrGenerateKeyFile(keyFile, false)
 	if err != nil {
@@ -349,14 +480,16 @@ func splitCertKeyPEM(bytes []byte) (certPem []byte, keyPem []byte) {
 // from the server.  We attempt to POST a CSR to the server, in hopes that it will
 // sign the cert using our locally generated key. If the server does not support CSR
 // signing, the key generated by the server is used instead.
-func getKubeletClientCert(certFile, keyFile, nodeName string, nodeIPs []net.IP, nodePasswordFile string, info *clientaccess.Info) error {
+func getKubeletClientCert(ctx context.Context, certFile, keyFile, nodeName string, nodeIPs []net.IP, nodePasswordFile string, info *clientaccess.Info) error {
 	csr, err := getCSRBytes(keyFile)
 	if err != nil {
 		return errors.Wrapf(err, "failed to create certificate request %s", certFile)
 	}
 
 	basename := filepath.Base(certFile)
-	body, err := Request("/v1-"+version.Program+"/"+basename, info, getNodeNamedCrt(nodeName, nodeIPs, nodePasswordFile, csr))
+	ctx, span := tracing.Start(ctx, "agent.request-certificate", attribute.String("certificate.file", basename))
+	defer span.End()
+	body, err := Request("/v1-"+version.Program+"/"+basename, info, getNodeNamedCrt(ctx, nodeName, nodeIPs, nodePasswordFile, csr))
 	if err != nil {
 		return err
 	}
@@ -541,6 +674,21 @@ func get(ctx context.Context, envInfo *cmds.Agent, proxy proxy.Proxy) (*config.N
 		return nil, fmt.Errorf("invalid node-external-ip: %w", err)
 	}
 
+	// Discover external IPs for nodes behind NAT, if not set explicitly
+	if len(nodeExternalIPs) == 0 && len(envInfo.STUNServer.Value()) > 0 {
+		var stunServers []string
+		for _, server := range envInfo.STUNServer.Value() {
+			stunServers = append(stunServers, strings.Split(server, ",")...)
+		}
+		discoveredIPs, err := stun.ExternalIPs(ctx, stunServers, nodeIPs)
+		if err != nil {
+			logrus.Warnf("Failed to discover node external IPs: %v", err)
+		} else if len(discoveredIPs) > 0 {
+			logrus.Infof("Discovered node external IPs %v", discoveredIPs)
+			nodeExternalIPs = discoveredIPs
+		}
+	}
+
 	if envInfo.WithNodeID {
 		nodeID, err := ensureNodeID(filepath.Join(nodeConfigPath, "id"))
 		if err != nil {
@@ -556,14 +704,32 @@ func get(ctx context.Context, envInfo *cmds.Agent, proxy proxy.Proxy) (*config.N
 	// that the cert will not be valid for, as they are not present in the list collected here.
 	nodeExternalAndInternalIPs := append(nodeIPs, nodeExternalIPs...)
 
+	// If warm restart is enabled, existing kubelet certs are reused as long as they are still valid
+	// for the current CA, node name, and addresses, and the server still accepts the node password.
+	// If the password cannot be verified, new certs are requested, which also verifies the password.
+	reuseKubeletCerts := envInfo.WarmRestart
+	if reuseKubeletCerts {
+		if err := verifyNodePassword(ctx, nodeName, nodeIPs, newNodePasswordFile, info); err != nil {
+			if errors.Is(err, errNodePasswordRejected) {
+				return nil, err
+			}
+			logrus.Warnf("Failed to verify node password, requesting new kubelet certificates: %v", err)
+			reuseKubeletCerts = false
+		}
+	}
+
 	// Ask the server to sign our kubelet server cert.
-	if err := getKubeletServingCert(nodeName, nodeExternalAndInternalIPs, servingKubeletCert, servingKubeletKey, newNodePasswordFile, info); err != nil {
-		return nil, errors.Wrap(err, servingKubeletCert)
+	if !reuseKubeletCerts || !reusableCert(servingKubeletCert, servingKubeletKey, serverCAFile, "", []string{nodeName}, nodeExternalAndInternalIPs) {
+		if err := getKubeletServingCert(ctx, nodeName, nodeExternalAndInternalIPs, servingKubeletCert, servingKubeletKey, newNodePasswordFile, info); err != nil {
+			return nil, errors.Wrap(err, servingKubeletCert)
+		}
 	}
 
 	// Ask the server to sign our kubelet client cert.
-	if err := getKubeletClientCert(clientKubeletCert, clientKubeletKey, nodeName, nodeIPs, newNodePasswordFile, info); err != nil {
-		return nil, errors.Wrap(err, clientKubeletCert)
+	if !reuseKubeletCerts || !reusableCert(clientKubeletCert, clientKubeletKey, clientCAFile, "system:node:"+nodeName, nil, nil) {
+		if err := getKubeletClientCert(ctx, clientKubeletCert, clientKubeletKey, nodeName, nodeIPs, newNodePasswordFile, info); err != nil {
+			return nil, errors.Wrap(err, clientKubeletCert)
+		}
 	}
 
 	// Generate a kubeconfig for the kubelet.
@@ -576,8 +742,10 @@ func get(ctx context.Context, envInfo *cmds.Agent, proxy proxy.Proxy) (*config.N
 	clientKubeProxyKey := filepath.Join(envInfo.DataDir, "agent", "client-kube-proxy.key")
 
 	// Ask the server to sign our kube-proxy client cert.
-	if err := getClientCert(clientKubeProxyCert, clientKubeProxyKey, info); err != nil {
-		return nil, errors.Wrap(err, clientKubeProxyCert)
+	if !envInfo.WarmRestart || !reusableCert(clientKubeProxyCert, clientKubeProxyKey, clientCAFile, "system:kube-proxy", nil, nil) {
+		if err := getClientCert(ctx, clientKubeProxyCert, clientKubeProxyKey, info); err != nil {
+			return nil, errors.Wrap(err, clientKubeProxyCert)
+		}
 	}
 
 	// Generate a kubeconfig for kube-proxy.
@@ -590,8 +758,10 @@ func get(ctx context.Context, envInfo *cmds.Agent, proxy proxy.Proxy) (*config.N
 	clientK3sControllerKey := filepath.Join(envInfo.DataDir, "agent", "client-"+version.Program+"-controller.key")
 
 	// Ask the server to sign our agent controller client cert.
-	if err := getClientCert(clientK3sControllerCert, clientK3sControllerKey, info); err != nil {
-		return nil, errors.Wrap(err, clientK3sControllerCert)
+	if !envInfo.WarmRestart || !reusableCert(clientK3sControllerCert, clientK3sControllerKey, clientCAFile, "system:"+version.Program+"-controller", nil, nil) {
+		if err := getClientCert(ctx, clientK3sControllerCert, clientK3sControllerKey, info); err != nil {
+			return nil, errors.Wrap(err, clientK3sControllerCert)
+		}
 	}
 
 	// Generate a kubeconfig for the agent controller.
@@ -613,6 +783,7 @@ func get(ctx context.Context, envInfo *cmds.Agent, proxy proxy.Proxy) (*config.N
 		ImageServiceEndpoint:     envInfo.ImageServiceEndpoint,
 		EnablePProf:              envInfo.EnablePProf,
 		EmbeddedRegistry:         controlConfig.EmbeddedRegistry,
+		EmbeddedRegistryPin:      controlConfig.EmbeddedRegistryPin,
 		FlannelBackend:           controlConfig.FlannelBackend,
 		FlannelIPv6Masq:          controlConfig.FlannelIPv6Masq,
 		FlannelExternalIP:        controlConfig.FlannelExternalIP,
@@ -620,20 +791,36 @@ func get(ctx context.Context, envInfo *cmds.Agent, proxy proxy.Proxy) (*config.N
 		ServerHTTPSPort:          controlConfig.HTTPSPort,
 		SupervisorPort:           controlConfig.SupervisorPort,
 		SupervisorMetrics:        controlConfig.SupervisorMetrics,
+		InstanceName:             envInfo.InstanceName,
 		Token:                    info.String(),
 	}
 	nodeConfig.FlannelIface = flannelIface
 	nodeConfig.Images = filepath.Join(envInfo.DataDir, "agent", "images")
+	if envInfo.LogShippingEndpoint != "" && !envInfo.DisableLogShipping {
+		nodeConfig.LogShipping = config.LogShipping{
+			Type:          envInfo.LogShippingType,
+			Endpoint:      envInfo.LogShippingEndpoint,
+			PositionsFile: filepath.Join(envInfo.DataDir, "agent", "log-shipping-positions.json"),
+		}
+	}
+	if envInfo.DNSFallbackCache {
+		nodeConfig.AgentConfig.DNSFallbackCacheFile = filepath.Join(envInfo.DataDir, "agent", "dns-fallback-cache.json")
+	}
 	nodeConfig.AgentConfig.NodeName = nodeName
 	nodeConfig.AgentConfig.NodeConfigPath = nodeConfigPath
 	nodeConfig.AgentConfig.ClientKubeletCert = clientKubeletCert
 	nodeConfig.AgentConfig.ClientKubeletKey = clientKubeletKey
+	nodeConfig.AgentConfig.ClientKubeProxyCert = clientKubeProxyCert
+	nodeConfig.AgentConfig.ClientKubeProxyKey = clientKubeProxyKey
+	nodeConfig.AgentConfig.ClientK3sControllerCert = clientK3sControllerCert
+	nodeConfig.AgentConfig.ClientK3sControllerKey = clientK3sControllerKey
 	nodeConfig.AgentConfig.ServingKubeletCert = servingKubeletCert
 	nodeConfig.AgentConfig.ServingKubeletKey = servingKubeletKey
 	nodeConfig.AgentConfig.ClusterDNS = controlConfig.ClusterDNS
 	nodeConfig.AgentConfig.ClusterDomain = controlConfig.ClusterDomain
 	nodeConfig.AgentConfig.ResolvConf = locateOrGenerateResolvConf(envInfo)
 	nodeConfig.AgentConfig.ClientCA = clientCAFile
+	nodeConfig.AgentConfig.ServerCA = serverCAFile
 	nodeConfig.AgentConfig.KubeletConfigDir = kubeletConfigDir
 	nodeConfig.AgentConfig.KubeConfigKubelet = kubeconfigKubelet
 	nodeConfig.AgentConfig.KubeConfigKubeProxy = kubeconfigKubeproxy
@@ -643,16 +830,61 @@ func get(ctx context.Context, envInfo *cmds.Agent, proxy proxy.Proxy) (*config.N
 	nodeConfig.Containerd.Config = filepath.Join(envInfo.DataDir, "agent", "etc", "containerd", "config.toml")
 	nodeConfig.Containerd.Root = filepath.Join(envInfo.DataDir, "agent", "containerd")
 	nodeConfig.CRIDockerd.Root = filepath.Join(envInfo.DataDir, "agent", "cri-dockerd")
+	nodeConfig.CRIO.Enabled = crio.IsEndpoint(nodeConfig.ContainerRuntimeEndpoint)
+	nodeConfig.CRIO.AuthFile = filepath.Join(envInfo.DataDir, "agent", "etc", "crio", "auth.json")
+	nodeConfig.CRIO.ManagedFiles = filepath.Join(envInfo.DataDir, "agent", "etc", "crio", "managed-files")
 	nodeConfig.Containerd.Opt = filepath.Join(envInfo.DataDir, "agent", "containerd")
 	nodeConfig.Containerd.Log = filepath.Join(envInfo.DataDir, "agent", "containerd", "containerd.log")
 	nodeConfig.Containerd.Registry = filepath.Join(envInfo.DataDir, "agent", "etc", "containerd", "certs.d")
 	nodeConfig.Containerd.NoDefault = envInfo.ContainerdNoDefault
 	nodeConfig.Containerd.NonrootDevices = envInfo.ContainerdNonrootDevices
 	nodeConfig.Containerd.Debug = envInfo.Debug
+
+	if envInfo.ImageStoreDir != "" {
+		if nodeConfig.Docker || nodeConfig.ContainerRuntimeEndpoint != "" {
+			return nil, errors.New("image-store-dir can only be used with the embedded containerd")
+		}
+		if !filepath.IsAbs(envInfo.ImageStoreDir) {
+			return nil, fmt.Errorf("image-store-dir %q must be an absolute path", envInfo.ImageStoreDir)
+		}
+		if err := os.MkdirAll(envInfo.ImageStoreDir, 0700); err != nil {
+			return nil, errors.Wrap(err, "failed to create image store dir")
+		}
+		nodeConfig.Containerd.Root = envInfo.ImageStoreDir
+		nodeConfig.AgentConfig.ImageStoreDir = envInfo.ImageStoreDir
+	}
+
+	if envInfo.RegistryProxy != "" {
+		proxyURL, err := ProxyURL(envInfo.RegistryProxy, envInfo.ProxyCredentialsFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid registry proxy")
+		}
+		nodeConfig.Containerd.Proxy = proxyURL.String()
+	}
+
+	limits, err := cgroups.ParseLimits(envInfo.ComponentLimits, cgroups.ComponentSupervisor, cgroups.ComponentContainerd, cgroups.ComponentKubelet, cgroups.ComponentAPIServer, cgroups.ComponentScheduler, cgroups.ComponentControllerManager)
+	if err != nil {
+		return nil, err
+	}
+	nodeConfig.Containerd.Limits = limits[cgroups.ComponentContainerd]
+	if nodeConfig.Containerd.Limits == nil && envInfo.EtcdIOWeight > 0 {
+		// The etcd io weight is set on the supervisor's cgroup, and is only relative to containerd if
+		// containerd is moved into a sibling cgroup, which is done when it has limits.
+		nodeConfig.Containerd.Limits = &cgroups.Limits{}
+	}
+	nodeConfig.AgentConfig.SupervisorLimits = limits[cgroups.ComponentSupervisor]
+	nodeConfig.AgentConfig.KubeletLimits = limits[cgroups.ComponentKubelet]
+	nodeConfig.AgentConfig.SupervisorOOMProtection = envInfo.SupervisorOOMProtection
 	nodeConfig.Containerd.Template = filepath.Join(envInfo.DataDir, "agent", "etc", "containerd", "config.toml.tmpl")
 
 	if envInfo.Rootless {
 		nodeConfig.AgentConfig.RootDir = filepath.Join(envInfo.DataDir, "agent", "kubelet")
+	} else if envInfo.InstanceName != "" {
+		nodeConfig.AgentConfig.RootDir = instance.KubeletRootDir(envInfo.InstanceName)
+	}
+
+	if envInfo.WarmRestart {
+		nodeConfig.AgentConfig.WarmRestartFile = filepath.Join(envInfo.DataDir, "agent", "etc", "warm-restart.json")
 	}
 
 	if envInfo.BindAddress != "" {
@@ -687,8 +919,31 @@ func get(ctx context.Context, envInfo *cmds.Agent, proxy proxy.Proxy) (*config.N
 	}
 	nodeConfig.AgentConfig.NodeInternalDNSs = nodeInternalDNSs
 
+	if nodeConfig.InstanceName != "" {
+		if goruntime.GOOS == "windows" {
+			return nil, errors.New("instance-name is not supported on Windows")
+		}
+		if err := instance.Validate(nodeConfig.InstanceName); err != nil {
+			return nil, err
+		}
+		if nodeConfig.FlannelBackend == config.FlannelBackendWireguardNative {
+			return nil, fmt.Errorf("flannel backend %s cannot be used with instance-name, as its interface names cannot be namespaced", nodeConfig.FlannelBackend)
+		}
+		// kube-proxy and the network policy controller flush and rewrite iptables chains with fixed
+		// names, so instances on the same host would remove each other's rules.
+		if !controlConfig.DisableKubeProxy {
+			return nil, errors.New("instance-name requires kube-proxy to be disabled with --disable-kube-proxy, as its iptables chains cannot be namespaced")
+		}
+		if !controlConfig.DisableNPC {
+			return nil, errors.New("instance-name requires the network policy controller to be disabled with --disable-network-policy, as its iptables chains cannot be namespaced")
+		}
+	}
+
 	nodeConfig.NoFlannel = nodeConfig.FlannelBackend == config.FlannelBackendNone
 	if !nodeConfig.NoFlannel {
+		if err := instance.ClaimVNI(nodeConfig.InstanceName); err != nil {
+			return nil, err
+		}
 		hostLocal, err := exec.LookPath("host-local")
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to find host-local")
@@ -702,12 +957,37 @@ func get(ctx context.Context, envInfo *cmds.Agent, proxy proxy.Proxy) (*config.N
 		}
 		nodeConfig.AgentConfig.CNIBinDir = filepath.Dir(hostLocal)
 		nodeConfig.AgentConfig.CNIConfDir = filepath.Join(envInfo.DataDir, "agent", "etc", "cni", "net.d")
+		if nodeConfig.CRIO.Enabled {
+			// CRI-O loads CNI config from the system path, not the agent data dir
+			nodeConfig.AgentConfig.CNIConfDir = crio.CNIConfDir
+		}
 		nodeConfig.AgentConfig.FlannelCniConfFile = envInfo.FlannelCniConfFile
 
 		// It does not make sense to use VPN without its flannel backend
 		if envInfo.VPNAuth != "" {
 			nodeConfig.FlannelBackend = vpnInfo.ProviderName
 		}
+		if err := setFlannelPorts(nodeConfig, envInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	if envInfo.CNIConfDir != "" {
+		nodeConfig.AgentConfig.CNIConfDir = envInfo.CNIConfDir
+	}
+	if envInfo.CNIBinDir != "" {
+		if !nodeConfig.NoFlannel {
+			if err := linkCNIPlugins(nodeConfig.AgentConfig.CNIBinDir, envInfo.CNIBinDir); err != nil {
+				return nil, err
+			}
+		}
+		nodeConfig.AgentConfig.CNIBinDir = envInfo.CNIBinDir
+	}
+	nodeConfig.AgentConfig.CNINetworkName = instance.NetworkName(nodeConfig.InstanceName)
+	nodeConfig.AgentConfig.CNIConfName = instance.CNIConfName(nodeConfig.InstanceName)
+	if envInfo.CNIConfCompat {
+		nodeConfig.AgentConfig.CNINetworkName = instance.NetworkName("")
+		nodeConfig.AgentConfig.CNIConfName = instance.CNIConfName("")
 	}
 
 	if nodeConfig.ImageServiceEndpoint != "" {
@@ -764,9 +1044,15 @@ func get(ctx context.Context, envInfo *cmds.Agent, proxy proxy.Proxy) (*config.N
 	nodeConfig.AgentConfig.AirgapExtraRegistry = envInfo.AirgapExtraRegistry
 	nodeConfig.AgentConfig.SystemDefaultRegistry = controlConfig.SystemDefaultRegistry
 
+	// Apply SystemImages to PauseImage, if the pause image has not been set on this node
+	_, pauseOverride := controlConfig.SystemImages[images.Pause]
+	if pauseOverride && nodeConfig.AgentConfig.PauseImage == cmds.DefaultPauseImage {
+		nodeConfig.AgentConfig.PauseImage = images.Reference(images.Pause, controlConfig.SystemDefaultRegistry, controlConfig.SystemImages)
+	}
+
 	// Apply SystemDefaultRegistry to PauseImage and AirgapExtraRegistry
 	if controlConfig.SystemDefaultRegistry != "" {
-		if nodeConfig.AgentConfig.PauseImage != "" && !strings.HasPrefix(nodeConfig.AgentConfig.PauseImage, controlConfig.SystemDefaultRegistry) {
+		if nodeConfig.AgentConfig.PauseImage != "" && !pauseOverride && !strings.HasPrefix(nodeConfig.AgentConfig.PauseImage, controlConfig.SystemDefaultRegistry) {
 			nodeConfig.AgentConfig.PauseImage = controlConfig.SystemDefaultRegistry + "/" + nodeConfig.AgentConfig.PauseImage
 		}
 		if !slice.ContainsString(nodeConfig.AgentConfig.AirgapExtraRegistry, controlConfig.SystemDefaultRegistry) {
@@ -775,13 +1061,42 @@ func get(ctx context.Context, envInfo *cmds.Agent, proxy proxy.Proxy) (*config.N
 	}
 
 	nodeConfig.AgentConfig.ExtraKubeletArgs = envInfo.ExtraKubeletArgs
+	nodeConfig.AgentConfig.KubeletConfig = envInfo.KubeletConfig
+	nodeConfig.AgentConfig.KubeProxyConfig = envInfo.KubeProxyConfig
+	nodeConfig.AgentConfig.KubeProxyConfigPath = filepath.Join(envInfo.DataDir, "agent", "etc", "kube-proxy.yaml")
 	nodeConfig.AgentConfig.ExtraKubeProxyArgs = envInfo.ExtraKubeProxyArgs
 	nodeConfig.AgentConfig.NodeTaints = envInfo.Taints
 	nodeConfig.AgentConfig.NodeLabels = envInfo.Labels
+	if err := nodeconfig.ValidatePolicies(envInfo.TaintPolicies); err != nil {
+		return nil, errors.Wrap(err, "invalid node-taint-policy")
+	}
+	nodeConfig.AgentConfig.NodeTaintPolicies = envInfo.TaintPolicies
+	if err := nodeconfig.ValidatePolicies(envInfo.LabelPolicies); err != nil {
+		return nil, errors.Wrap(err, "invalid node-label-policy")
+	}
+	nodeConfig.AgentConfig.NodeLabelPolicies = envInfo.LabelPolicies
 	nodeConfig.AgentConfig.ImageCredProvBinDir = envInfo.ImageCredProvBinDir
 	nodeConfig.AgentConfig.ImageCredProvConfig = envInfo.ImageCredProvConfig
+	nodeConfig.AgentConfig.ImageCredProviders = envInfo.ImageCredProviders
+	if len(envInfo.ImageCredProviders) > 0 {
+		nodeConfig.AgentConfig.ImageCredProvConfig = filepath.Join(envInfo.DataDir, "agent", "etc", "credential-provider-config.yaml")
+	}
+	nodeConfig.AgentConfig.StaticPodDir = envInfo.StaticPodDir
+	nodeConfig.AgentConfig.SystemReservedProfile = envInfo.SystemReservedProfile
+	nodeConfig.AgentConfig.SwapBehavior = envInfo.SwapBehavior
+	nodeConfig.AgentConfig.KubeletServingCSR = envInfo.KubeletServingCSR
+	if err := util.ValidateTunnelKeepAliveTimeout(envInfo.TunnelKeepAliveTimeout); err != nil {
+		return nil, err
+	}
+	nodeConfig.AgentConfig.TunnelKeepAliveTimeout = envInfo.TunnelKeepAliveTimeout
+	nodeConfig.AgentConfig.TunnelCompression = envInfo.TunnelCompression
+	nodeConfig.AgentConfig.TunnelReconnectDelay = envInfo.TunnelReconnectDelay
+	nodeConfig.AgentConfig.TunnelReconnectJitter = envInfo.TunnelReconnectJitter
+	nodeConfig.AgentConfig.SupervisorProxy = envInfo.SupervisorProxy
 	nodeConfig.AgentConfig.DisableCCM = controlConfig.DisableCCM
 	nodeConfig.AgentConfig.DisableNPC = controlConfig.DisableNPC
+	nodeConfig.AgentConfig.DRA = controlConfig.DRA
+	nodeConfig.AgentConfig.PodResourcesDir = filepath.Join(envInfo.DataDir, "agent", "pod-resources")
 	nodeConfig.AgentConfig.MinTLSVersion = controlConfig.MinTLSVersion
 	nodeConfig.AgentConfig.CipherSuites = controlConfig.CipherSuites
 	nodeConfig.AgentConfig.Rootless = envInfo.Rootless
@@ -799,6 +1114,15 @@ func get(ctx context.Context, envInfo *cmds.Agent, proxy proxy.Proxy) (*config.N
 	}
 	nodeConfig.AgentConfig.Registry = privRegistries.Registry
 
+	// Docker cannot use the embedded registry mirror: it only supports mirrors for docker.io, does not
+	// pass the upstream registry on mirror requests so tags cannot be resolved by peers, and does not
+	// share its image store with containerd so this node has no content to serve to peers.
+	if nodeConfig.EmbeddedRegistry && nodeConfig.Docker {
+		logrus.Warn("Embedded registry mirror is not supported with docker; images will be pulled from their upstream registries on this node")
+		nodeConfig.EmbeddedRegistry = false
+		nodeConfig.EmbeddedRegistryPin = false
+	}
+
 	if nodeConfig.EmbeddedRegistry {
 		psk, err := hex.DecodeString(controlConfig.IPSECPSK)
 		if err != nil {
@@ -883,6 +1207,66 @@ func getReadyz(info *clientaccess.Info) error {
 	return err
 }
 
+// bundledCNIPlugins are the CNI plugins packaged with k3s.
+var bundledCNIPlugins = []string{"bandwidth", "bridge", "firewall", "flannel", "host-local", "loopback", "portmap"}
+
+// linkCNIPlugins links the bundled CNI plugins in srcDir into dstDir, so that they can be found by
+// container runtimes that load plugins from dstDir. Plugins in dstDir that are not links are left in
+// place, so that plugins installed by other runtimes are not replaced.
+func linkCNIPlugins(srcDir, dstDir string) error {
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return err
+	}
+	for _, plugin := range bundledCNIPlugins {
+		src := filepath.Join(srcDir, plugin)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		dst := filepath.Join(dstDir, plugin)
+		if fi, err := os.Lstat(dst); err == nil {
+			if fi.Mode()&os.ModeSymlink == 0 {
+				logrus.Infof("Using existing CNI plugin %s", dst)
+				continue
+			}
+			if target, err := os.Readlink(dst); err == nil && target == src {
+				continue
+			}
+			if err := os.Remove(dst); err != nil {
+				return err
+			}
+		}
+		if err := os.Symlink(src, dst); err != nil {
+			return errors.Wrapf(err, "failed to link CNI plugin %s", plugin)
+		}
+	}
+	return nil
+}
+
+// setFlannelPorts sets the UDP ports used by the flannel backend, using the backend's default
+// for any port that is not set, and checks that they are valid.
+func setFlannelPorts(nodeConfig *config.Node, envInfo *cmds.Agent) error {
+	nodeConfig.FlannelVXLANPort = envInfo.FlannelVXLANPort
+	if nodeConfig.FlannelVXLANPort == 0 {
+		nodeConfig.FlannelVXLANPort = config.DefaultFlannelVXLANPort
+		if goruntime.GOOS == "windows" {
+			nodeConfig.FlannelVXLANPort = config.DefaultFlannelVXLANPortWindows
+		}
+	}
+	nodeConfig.FlannelWireguardPort = cmp.Or(envInfo.FlannelWireguardPort, config.DefaultFlannelWireguardPort)
+	nodeConfig.FlannelWireguardPortIPv6 = cmp.Or(envInfo.FlannelWireguardPortIPv6, config.DefaultFlannelWireguardPortV6)
+
+	ports := nodeConfig.FlannelPorts()
+	for _, port := range ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid flannel %s port %d", nodeConfig.FlannelBackend, port)
+		}
+	}
+	if len(ports) == 2 && ports[0] == ports[1] {
+		return fmt.Errorf("flannel %s IPv4 and IPv6 ports must be different", nodeConfig.FlannelBackend)
+	}
+	return nil
+}
+
 // validateNetworkConfig ensures that the network configuration values provided by the server make sense.
 func validateNetworkConfig(nodeConfig *config.Node) error {
 	// Old versions of the server do not send enough information to correctly start the NPC. Users
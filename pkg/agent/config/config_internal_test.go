@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -38,3 +39,39 @@ func Test_isValidResolvConf(t *testing.T) {
 		})
 	}
 }
+
+func Test_UnitLinkCNIPlugins(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := filepath.Join(t.TempDir(), "bin")
+	for _, plugin := range []string{"bridge", "host-local", "portmap", "containerd"} {
+		if err := os.WriteFile(filepath.Join(srcDir, plugin), nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// a plugin installed by another runtime is not replaced
+	if err := os.WriteFile(filepath.Join(dstDir, "portmap"), []byte("other"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// a stale link from a previous release is updated
+	if err := os.Symlink(filepath.Join(t.TempDir(), "bridge"), filepath.Join(dstDir, "bridge")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := linkCNIPlugins(srcDir, dstDir); err != nil {
+		t.Fatal(err)
+	}
+	for _, plugin := range []string{"bridge", "host-local"} {
+		if target, err := os.Readlink(filepath.Join(dstDir, plugin)); err != nil || target != filepath.Join(srcDir, plugin) {
+			t.Errorf("linkCNIPlugins() %s links to %q, %v; want %s", plugin, target, err, filepath.Join(srcDir, plugin))
+		}
+	}
+	if b, err := os.ReadFile(filepath.Join(dstDir, "portmap")); err != nil || string(b) != "other" {
+		t.Errorf("linkCNIPlugins() replaced existing portmap plugin")
+	}
+	if _, err := os.Lstat(filepath.Join(dstDir, "containerd")); err == nil {
+		t.Errorf("linkCNIPlugins() linked a binary that is not a CNI plugin")
+	}
+}
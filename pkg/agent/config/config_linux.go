@@ -10,13 +10,14 @@ import (
 	"github.com/k3s-io/k3s/pkg/agent/containerd"
 	"github.com/k3s-io/k3s/pkg/cli/cmds"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/instance"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
 // applyContainerdOSSpecificConfig sets linux-specific containerd config
 func applyContainerdOSSpecificConfig(nodeConfig *config.Node) error {
-	nodeConfig.Containerd.State = "/run/k3s/containerd"
+	nodeConfig.Containerd.State = filepath.Join(instance.RunDir(nodeConfig.InstanceName), "containerd")
 	nodeConfig.Containerd.Address = filepath.Join(nodeConfig.Containerd.State, "containerd.sock")
 
 	// validate that the selected snapshotter supports the filesystem at the root path.
@@ -72,7 +73,7 @@ func applyContainerdQoSClassConfigFileIfPresent(envInfo *cmds.Agent, containerdC
 
 // applyCRIDockerdOSSpecificConfig sets linux-specific cri-dockerd config
 func applyCRIDockerdOSSpecificConfig(nodeConfig *config.Node) error {
-	nodeConfig.CRIDockerd.Address = "unix:///run/k3s/cri-dockerd/cri-dockerd.sock"
+	nodeConfig.CRIDockerd.Address = "unix://" + filepath.Join(instance.RunDir(nodeConfig.InstanceName), "cri-dockerd", "cri-dockerd.sock")
 	return nil
 }
 
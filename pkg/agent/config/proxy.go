@@ -0,0 +1,36 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ProxyURL parses the given proxy address, and adds the credentials from the given file to it if
+// the address does not already include credentials. The credentials file must contain a single
+// line in the form username:password.
+func ProxyURL(address, credentialsFile string) (*url.URL, error) {
+	proxyURL, err := url.Parse(address)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse proxy address")
+	}
+	if proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return nil, errors.Errorf("proxy address %s must be a URL with a scheme and host", proxyURL.Redacted())
+	}
+	if proxyURL.User != nil || credentialsFile == "" {
+		return proxyURL, nil
+	}
+
+	b, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read proxy credentials file")
+	}
+	username, password, ok := strings.Cut(strings.TrimSpace(string(b)), ":")
+	if !ok || username == "" {
+		return nil, errors.Errorf("proxy credentials file %s must contain username:password", credentialsFile)
+	}
+	proxyURL.User = url.UserPassword(username, password)
+	return proxyURL, nil
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"os"
+	"slices"
+	"time"
+
+	agentutil "github.com/k3s-io/k3s/pkg/agent/util"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	certutil "github.com/rancher/dynamiclistener/cert"
+	"github.com/sirupsen/logrus"
+)
+
+// WarmRestartState stores agent state that is persisted across restarts when warm
+// restart is enabled, so that the agent does not need to block on the server to
+// retrieve it again before starting the kubelet.
+type WarmRestartState struct {
+	APIServerAddresses []string `json:"apiServerAddresses,omitempty"`
+}
+
+// ReadWarmRestartState reads persisted warm restart state from disk. An empty state is returned
+// if the file does not exist or cannot be parsed.
+func ReadWarmRestartState(file string) *WarmRestartState {
+	state := &WarmRestartState{}
+	if b, err := os.ReadFile(file); err == nil {
+		if err := json.Unmarshal(b, state); err != nil {
+			logrus.Warnf("Failed to parse warm restart state from %s: %v", file, err)
+		}
+	}
+	return state
+}
+
+// WriteWarmRestartState persists warm restart state to disk.
+func WriteWarmRestartState(file string, state *WarmRestartState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return agentutil.WriteFile(file, string(b))
+}
+
+// reusableCert returns true if the certificate and key can be reused on a warm restart,
+// instead of requesting a new certificate from the server. The certificate must be signed
+// by the current CA bundle, must not be due for renewal, and must have the expected common
+// name and subject alternative names.
+func reusableCert(certFile, keyFile, caFile, commonName string, dnsNames []string, ips []net.IP) bool {
+	keyPair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return false
+	}
+
+	roots, err := certutil.NewPool(caFile)
+	if err != nil {
+		return false
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+		logrus.Debugf("Not reusing certificate %s: %v", certFile, err)
+		return false
+	}
+
+	if time.Until(cert.NotAfter) < config.CertificateRenewDays*24*time.Hour {
+		logrus.Debugf("Not reusing certificate %s: expires at %s", certFile, cert.NotAfter)
+		return false
+	}
+	if commonName != "" && cert.Subject.CommonName != commonName {
+		logrus.Debugf("Not reusing certificate %s: common name %s does not match %s", certFile, cert.Subject.CommonName, commonName)
+		return false
+	}
+	for _, name := range dnsNames {
+		if !slices.Contains(cert.DNSNames, name) {
+			logrus.Debugf("Not reusing certificate %s: missing DNS name %s", certFile, name)
+			return false
+		}
+	}
+	for _, ip := range ips {
+		if !slices.ContainsFunc(cert.IPAddresses, ip.Equal) {
+			logrus.Debugf("Not reusing certificate %s: missing IP address %s", certFile, ip)
+			return false
+		}
+	}
+
+	logrus.Infof("Reusing existing certificate %s for warm restart", certFile)
+	return true
+}
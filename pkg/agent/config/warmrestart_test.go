@@ -0,0 +1,96 @@
+package config
+
+import (
+	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	certutil "github.com/rancher/dynamiclistener/cert"
+)
+
+func Test_UnitReusableCert(t *testing.T) {
+	dir := t.TempDir()
+	caKey, err := certutil.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: "test-ca"}, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(caFile, certutil.EncodeCertPEM(caCert), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	otherCAKey, err := certutil.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCACert, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: "other-ca"}, otherCAKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCAFile := filepath.Join(dir, "other-ca.crt")
+	if err := os.WriteFile(otherCAFile, certutil.EncodeCertPEM(otherCACert), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	writeCert := func(name string, expiresAt time.Duration) (string, string) {
+		key, err := certutil.NewPrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := certutil.NewSignedCert(certutil.Config{
+			CommonName: "system:node:node1",
+			AltNames:   certutil.AltNames{DNSNames: []string{"node1"}, IPs: []net.IP{net.ParseIP("10.0.0.1")}},
+			Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+			ExpiresAt:  expiresAt,
+		}, key, caCert, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certFile := filepath.Join(dir, name+".crt")
+		keyFile := filepath.Join(dir, name+".key")
+		if err := os.WriteFile(certFile, certutil.EncodeCertPEM(cert), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(keyFile, certutil.EncodePrivateKeyPEM(key), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return certFile, keyFile
+	}
+
+	validCert, validKey := writeCert("valid", 365*24*time.Hour)
+	expiringCert, expiringKey := writeCert("expiring", 24*time.Hour)
+
+	tests := []struct {
+		name       string
+		certFile   string
+		keyFile    string
+		caFile     string
+		commonName string
+		dnsNames   []string
+		ips        []net.IP
+		want       bool
+	}{
+		{name: "Valid cert", certFile: validCert, keyFile: validKey, caFile: caFile, commonName: "system:node:node1", dnsNames: []string{"node1"}, ips: []net.IP{net.ParseIP("10.0.0.1")}, want: true},
+		{name: "Missing cert", certFile: filepath.Join(dir, "missing.crt"), keyFile: validKey, caFile: caFile, want: false},
+		{name: "Mismatched key", certFile: validCert, keyFile: expiringKey, caFile: caFile, want: false},
+		{name: "Different CA", certFile: validCert, keyFile: validKey, caFile: otherCAFile, want: false},
+		{name: "Expiring cert", certFile: expiringCert, keyFile: expiringKey, caFile: caFile, want: false},
+		{name: "Wrong common name", certFile: validCert, keyFile: validKey, caFile: caFile, commonName: "system:node:node2", want: false},
+		{name: "Missing DNS name", certFile: validCert, keyFile: validKey, caFile: caFile, dnsNames: []string{"node2"}, want: false},
+		{name: "Missing IP", certFile: validCert, keyFile: validKey, caFile: caFile, ips: []net.IP{net.ParseIP("10.0.0.2")}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reusableCert(tt.certFile, tt.keyFile, tt.caFile, tt.commonName, tt.dnsNames, tt.ips); got != tt.want {
+				t.Errorf("reusableCert() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -70,6 +70,15 @@ func writeContainerdHosts(cfg *config.Node, containerdConfig templates.Container
 	return nil
 }
 
+// WriteRegistryHosts renders and saves hosts.toml for the given registry configuration. Containerd reads
+// the hosts files each time it pulls an image, so changes take effect without restarting containerd.
+func WriteRegistryHosts(cfg *config.Node, registry *registries.Registry) error {
+	return writeContainerdHosts(cfg, templates.ContainerdConfig{
+		PrivateRegistryConfig: registry,
+		NoDefaultEndpoint:     cfg.Containerd.NoDefault,
+	})
+}
+
 // cleanContainerdHosts removes any registry host config dirs containing a hosts.toml file
 // with a header that indicates it was created by k3s, or directories where a hosts.toml
 // is about to be written.  Unmanaged directories not containing this file, or containing
@@ -5,6 +5,7 @@ package containerd
 
 import (
 	"os"
+	"os/exec"
 
 	"github.com/containerd/containerd"
 	overlayutils "github.com/containerd/containerd/snapshots/overlay/overlayutils"
@@ -80,6 +81,13 @@ func SetupContainerdConfig(cfg *config.Node) error {
 		NoDefaultEndpoint:     cfg.Containerd.NoDefault,
 	}
 
+	// Containers can be checkpointed with crictl checkpoint when criu is installed. The kubelet checkpoint API
+	// requires a container runtime that implements CRI checkpointing, which the embedded containerd does not.
+	if criuPath, err := exec.LookPath("criu"); err == nil {
+		logrus.Infof("Found criu at %s; containers can be checkpointed with %s crictl checkpoint", criuPath, version.Program)
+		containerdConfig.CriuPath = criuPath
+	}
+
 	selEnabled, selConfigured, err := selinuxStatus()
 	if err != nil {
 		return errors.Wrap(err, "failed to detect selinux")
@@ -21,6 +21,7 @@ import (
 	reference "github.com/google/go-containerregistry/pkg/name"
 	"github.com/k3s-io/k3s/pkg/agent/cri"
 	util2 "github.com/k3s-io/k3s/pkg/agent/util"
+	"github.com/k3s-io/k3s/pkg/cgroups"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/natefinch/lumberjack"
@@ -39,9 +40,27 @@ var (
 	k3sPinnedImageLabelValue = "pinned"
 )
 
+// proxyEnv returns the environment variables that configure containerd to use the given proxy for
+// image pulls, overriding any proxy set in the environment. Loopback addresses are excluded from the
+// proxy, unless NO_PROXY has already been set.
+func proxyEnv(proxy string, env []string) []string {
+	if proxy == "" {
+		return nil
+	}
+	proxyEnv := []string{"HTTP_PROXY=" + proxy, "HTTPS_PROXY=" + proxy, "http_proxy=" + proxy, "https_proxy=" + proxy}
+	for _, e := range env {
+		if k, _, _ := strings.Cut(e, "="); strings.EqualFold(k, "NO_PROXY") {
+			return proxyEnv
+		}
+	}
+	return append(proxyEnv, "NO_PROXY=127.0.0.0/8,::1,localhost")
+}
+
 // Run configures and starts containerd as a child process. Once it is up, images are preloaded
 // or pulled from files found in the agent images directory.
 func Run(ctx context.Context, cfg *config.Node) error {
+	cleanupStaleState(cfg)
+
 	args := getContainerdArgs(cfg)
 	stdOut := io.Writer(os.Stdout)
 	stdErr := io.Writer(os.Stderr)
@@ -94,9 +113,19 @@ func Run(ctx context.Context, cfg *config.Node) error {
 		cmd.Stdout = stdOut
 		cmd.Stderr = stdErr
 		cmd.Env = append(env, cenv...)
+		cmd.Env = append(cmd.Env, proxyEnv(cfg.Containerd.Proxy, cmd.Env)...)
 
 		addDeathSig(cmd)
-		err := cmd.Run()
+		err := cmd.Start()
+		if err == nil && cfg.Containerd.Limits != nil {
+			if err := cgroups.AddComponentProcess(cgroups.ComponentContainerd, cmd.Process.Pid, cfg.Containerd.Limits); err != nil {
+				cmd.Process.Kill()
+				logrus.Fatalf("Failed to apply resource limits to containerd: %v", err)
+			}
+		}
+		if err == nil {
+			err = cmd.Wait()
+		}
 		if err != nil && !errors.Is(err, context.Canceled) {
 			logrus.Errorf("containerd exited: %s", err)
 			os.Exit(1)
@@ -0,0 +1,253 @@
+//go:build linux
+// +build linux
+
+package containerd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/moby/sys/mountinfo"
+	"github.com/opencontainers/runc/libcontainer/userns"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	taskDir  = "io.containerd.runtime.v2.task"
+	criDir   = "io.containerd.grpc.v1.cri"
+	netnsDir = "/var/run/netns"
+	// defaultKubeletRootDir is the kubelet's root dir, when not overridden by the agent config.
+	defaultKubeletRootDir = "/var/lib/kubelet"
+)
+
+// podCgroupRegexp matches the pod UID in the cgroup path of a pod's processes, for both the cgroupfs
+// (pod<uid>) and systemd (pod<uid with underscores>.slice) cgroup drivers.
+var podCgroupRegexp = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})`)
+
+// cleanupStaleState unmounts container rootfs and sandbox shm mounts left behind in the containerd
+// state and root dirs by containers that are no longer running, unmounts volumes of pods that no
+// longer have any running processes, and removes the network namespaces of sandboxes that are no
+// longer running. These are left behind when k3s or containerd exits uncleanly without a reboot, and
+// prevent pods from starting or the kubelet from cleaning up orphaned pods. Mounts belonging to
+// containers whose init process is still running are not affected, as shims and their containers
+// survive a restart of containerd.
+func cleanupStaleState(cfg *config.Node) {
+	if userns.RunningInUserNS() {
+		return
+	}
+	running := containerRunning(cfg.Containerd.State)
+	for _, dir := range []string{cfg.Containerd.State, cfg.Containerd.Root} {
+		if dir == "" {
+			continue
+		}
+		unmountAll(staleMounts(dir, listMounts(dir), running))
+	}
+
+	podsDir := filepath.Join(cfg.AgentConfig.RootDir, "pods")
+	if cfg.AgentConfig.RootDir == "" {
+		podsDir = filepath.Join(defaultKubeletRootDir, "pods")
+	}
+	unmountAll(stalePodMounts(podsDir, listMounts(podsDir), runningPods()))
+
+	cleanupNetNS(staleNetNS(cfg.Containerd.State, running))
+}
+
+// listMounts returns the mountpoints under a directory.
+func listMounts(dir string) []string {
+	mounts, err := mountinfo.GetMounts(mountinfo.PrefixFilter(dir))
+	if err != nil {
+		logrus.Warnf("Failed to list mounts under %s: %v", dir, err)
+		return nil
+	}
+	mountpoints := make([]string, 0, len(mounts))
+	for _, m := range mounts {
+		mountpoints = append(mountpoints, m.Mountpoint)
+	}
+	return mountpoints
+}
+
+// unmountAll lazily unmounts the mountpoints, in order.
+func unmountAll(mountpoints []string) {
+	for _, mountpoint := range mountpoints {
+		if err := unix.Unmount(mountpoint, unix.MNT_DETACH); err != nil {
+			logrus.Warnf("Failed to unmount stale mount %s: %v", mountpoint, err)
+			continue
+		}
+		logrus.Infof("Unmounted stale mount %s", mountpoint)
+	}
+}
+
+// staleMounts returns the mountpoints under the containerd state dir that belong to containers or
+// sandboxes that are not running, ordered so that nested mounts are unmounted first. Mountpoints
+// that cannot be attributed to a container are ignored.
+func staleMounts(stateDir string, mountpoints []string, running func(namespace, id string) bool) []string {
+	stale := []string{}
+	for _, mountpoint := range mountpoints {
+		rel, err := filepath.Rel(stateDir, mountpoint)
+		if err != nil {
+			continue
+		}
+		var namespace, id string
+		parts := strings.Split(rel, string(filepath.Separator))
+		switch {
+		case len(parts) > 3 && parts[0] == taskDir:
+			namespace, id = parts[1], parts[2]
+		case len(parts) > 3 && parts[0] == criDir && parts[1] == "sandboxes":
+			// The sandbox id is also the id of the sandbox's pause container.
+			namespace, id = "k8s.io", parts[2]
+		default:
+			continue
+		}
+		if running(namespace, id) {
+			continue
+		}
+		stale = append(stale, mountpoint)
+	}
+	sort.Slice(stale, func(i, j int) bool { return len(stale[i]) > len(stale[j]) })
+	return stale
+}
+
+// containerRunning returns true if the init process of the container is running. Containers without
+// a readable pid file are assumed to be running.
+func containerRunning(stateDir string) func(namespace, id string) bool {
+	return func(namespace, id string) bool {
+		b, err := os.ReadFile(filepath.Join(stateDir, taskDir, namespace, id, "init.pid"))
+		if os.IsNotExist(err) {
+			return false
+		} else if err != nil {
+			return true
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+		if err != nil || pid <= 0 {
+			return true
+		}
+		return unix.Kill(pid, 0) != unix.ESRCH
+	}
+}
+
+// stalePodMounts returns the volume mountpoints under the kubelet pods dir that belong to pods that
+// are not running, ordered so that nested mounts are unmounted first. The kubelet does not remove the
+// directories of orphaned pods while their volumes are still mounted.
+func stalePodMounts(podsDir string, mountpoints []string, running map[string]bool) []string {
+	stale := []string{}
+	for _, mountpoint := range mountpoints {
+		rel, err := filepath.Rel(podsDir, mountpoint)
+		if err != nil {
+			continue
+		}
+		parts := strings.Split(rel, string(filepath.Separator))
+		if len(parts) < 3 || (parts[1] != "volumes" && parts[1] != "volume-subpaths") || running[parts[0]] {
+			continue
+		}
+		stale = append(stale, mountpoint)
+	}
+	sort.Slice(stale, func(i, j int) bool { return len(stale[i]) > len(stale[j]) })
+	return stale
+}
+
+// runningPods returns the UIDs of pods that have processes in their cgroup. The pause container of a
+// pod keeps running for as long as its sandbox does, even if the pod's other containers have exited.
+func runningPods() map[string]bool {
+	running := map[string]bool{}
+	files, _ := filepath.Glob("/proc/[0-9]*/cgroup")
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, uid := range podUIDs(string(b)) {
+			running[uid] = true
+		}
+	}
+	return running
+}
+
+// podUIDs returns the pod UIDs found in the contents of a /proc/<pid>/cgroup file.
+func podUIDs(cgroups string) []string {
+	uids := []string{}
+	for _, match := range podCgroupRegexp.FindAllStringSubmatch(cgroups, -1) {
+		if uid := strings.ReplaceAll(match[1], "_", "-"); !slices.Contains(uids, uid) {
+			uids = append(uids, uid)
+		}
+	}
+	return uids
+}
+
+// bundleSpec holds the fields of a container's OCI runtime spec that are needed to find its network namespace.
+type bundleSpec struct {
+	Linux *struct {
+		Namespaces []struct {
+			Type string `json:"type"`
+			Path string `json:"path"`
+		} `json:"namespaces"`
+	} `json:"linux"`
+}
+
+// staleNetNS returns the network namespaces of sandboxes in the containerd state dir that are not
+// running. Only namespaces created for this containerd are returned, so that namespaces being set up
+// by other container runtimes on the host are not affected.
+func staleNetNS(stateDir string, running func(namespace, id string) bool) []string {
+	stale := []string{}
+	bundles, _ := filepath.Glob(filepath.Join(stateDir, taskDir, "*", "*", "config.json"))
+	for _, bundle := range bundles {
+		id := filepath.Base(filepath.Dir(bundle))
+		namespace := filepath.Base(filepath.Dir(filepath.Dir(bundle)))
+		if running(namespace, id) {
+			continue
+		}
+		b, err := os.ReadFile(bundle)
+		if err != nil {
+			continue
+		}
+		spec := &bundleSpec{}
+		if err := json.Unmarshal(b, spec); err != nil || spec.Linux == nil {
+			continue
+		}
+		for _, ns := range spec.Linux.Namespaces {
+			if ns.Type == "network" && filepath.Dir(ns.Path) == netnsDir && !slices.Contains(stale, ns.Path) {
+				stale = append(stale, ns.Path)
+			}
+		}
+	}
+	return stale
+}
+
+// cleanupNetNS unmounts and removes network namespaces that are not in use by any process.
+// This must only be done before containerd is started, as namespaces are created by the CRI before
+// the sandbox process that uses them.
+func cleanupNetNS(paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	inUse := map[uint64]bool{}
+	links, _ := filepath.Glob("/proc/[0-9]*/ns/net")
+	for _, link := range links {
+		if fi, err := os.Stat(link); err == nil {
+			inUse[fi.Sys().(*syscall.Stat_t).Ino] = true
+		}
+	}
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil || inUse[fi.Sys().(*syscall.Stat_t).Ino] {
+			continue
+		}
+		if err := unix.Unmount(path, unix.MNT_DETACH); err != nil && err != unix.EINVAL {
+			logrus.Warnf("Failed to unmount orphaned network namespace %s: %v", path, err)
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			logrus.Warnf("Failed to remove orphaned network namespace %s: %v", path, err)
+			continue
+		}
+		logrus.Infof("Removed orphaned network namespace %s", path)
+	}
+}
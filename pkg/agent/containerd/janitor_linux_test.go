@@ -0,0 +1,92 @@
+//go:build linux
+// +build linux
+
+package containerd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_UnitStaleMounts(t *testing.T) {
+	stateDir := "/run/k3s/containerd"
+	mountpoints := []string{
+		stateDir + "/io.containerd.runtime.v2.task/k8s.io/running/rootfs",
+		stateDir + "/io.containerd.runtime.v2.task/k8s.io/stopped/rootfs",
+		stateDir + "/io.containerd.runtime.v2.task/k8s.io/stopped/rootfs/proc",
+		stateDir + "/io.containerd.grpc.v1.cri/sandboxes/running/shm",
+		stateDir + "/io.containerd.grpc.v1.cri/sandboxes/stopped/shm",
+		stateDir + "/io.containerd.grpc.v1.cri/sandboxes",
+		stateDir + "/other/mount/point",
+		"/var/lib/kubelet/pods/uid/volumes",
+	}
+	running := func(namespace, id string) bool {
+		if namespace != "k8s.io" {
+			t.Errorf("unexpected namespace %s", namespace)
+		}
+		return id == "running"
+	}
+	want := []string{
+		stateDir + "/io.containerd.runtime.v2.task/k8s.io/stopped/rootfs/proc",
+		stateDir + "/io.containerd.runtime.v2.task/k8s.io/stopped/rootfs",
+		stateDir + "/io.containerd.grpc.v1.cri/sandboxes/stopped/shm",
+	}
+	if got := staleMounts(stateDir, mountpoints, running); !reflect.DeepEqual(got, want) {
+		t.Errorf("staleMounts() = %v, want %v", got, want)
+	}
+}
+
+func Test_UnitStalePodMounts(t *testing.T) {
+	podsDir := "/var/lib/kubelet/pods"
+	running := "0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b"
+	stopped := "11111111-2222-3333-4444-555555555555"
+	mountpoints := []string{
+		podsDir + "/" + running + "/volumes/kubernetes.io~projected/kube-api-access",
+		podsDir + "/" + stopped + "/volumes/kubernetes.io~projected/kube-api-access",
+		podsDir + "/" + stopped + "/volume-subpaths/config/app/0",
+		podsDir + "/" + stopped + "/plugins/example",
+		podsDir,
+	}
+	want := []string{
+		podsDir + "/" + stopped + "/volumes/kubernetes.io~projected/kube-api-access",
+		podsDir + "/" + stopped + "/volume-subpaths/config/app/0",
+	}
+	if got := stalePodMounts(podsDir, mountpoints, map[string]bool{running: true}); !reflect.DeepEqual(got, want) {
+		t.Errorf("stalePodMounts() = %v, want %v", got, want)
+	}
+}
+
+func Test_UnitPodUIDs(t *testing.T) {
+	cgroups := "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod0f1e2d3c_4b5a_6978_8a9b_0c1d2e3f4a5b.slice/cri-containerd-abc.scope\n" +
+		"1:cpu:/kubepods/besteffort/pod11111111-2222-3333-4444-555555555555/abc\n"
+	want := []string{"0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b", "11111111-2222-3333-4444-555555555555"}
+	if got := podUIDs(cgroups); !reflect.DeepEqual(got, want) {
+		t.Errorf("podUIDs() = %v, want %v", got, want)
+	}
+}
+
+func Test_UnitStaleNetNS(t *testing.T) {
+	stateDir := t.TempDir()
+	bundles := map[string]string{
+		"running": `{"linux":{"namespaces":[{"type":"network","path":"/var/run/netns/cni-running"}]}}`,
+		"stopped": `{"linux":{"namespaces":[{"type":"pid"},{"type":"network","path":"/var/run/netns/cni-stopped"}]}}`,
+		"host":    `{"linux":{"namespaces":[{"type":"pid"}]}}`,
+		"other":   `{"linux":{"namespaces":[{"type":"network","path":"/proc/1/ns/net"}]}}`,
+	}
+	for id, spec := range bundles {
+		dir := filepath.Join(stateDir, taskDir, "k8s.io", id)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(spec), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	running := func(namespace, id string) bool { return id == "running" }
+	want := []string{"/var/run/netns/cni-stopped"}
+	if got := staleNetNS(stateDir, running); !reflect.DeepEqual(got, want) {
+		t.Errorf("staleNetNS() = %v, want %v", got, want)
+	}
+}
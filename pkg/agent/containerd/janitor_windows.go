@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package containerd
+
+import "github.com/k3s-io/k3s/pkg/daemons/config"
+
+// cleanupStaleState is not implemented on Windows.
+func cleanupStaleState(cfg *config.Node) {}
@@ -0,0 +1,214 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/pkg/cri/constants"
+	"github.com/containerd/containerd/reference/docker"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var pullProgressControllerName = version.Program + "-image-pull-monitor"
+
+// pullProgressInterval is the interval at which in-progress pulls are checked, and progress events emitted.
+const pullProgressInterval = 30 * time.Second
+
+// pullProgress summarizes the content ingests for image pulls that have exceeded the progress threshold.
+type pullProgress struct {
+	Layers  int
+	Elapsed time.Duration
+	Offset  int64
+	Total   int64
+	// Rate is the transfer rate in bytes per second since the previous check, or -1 if unknown.
+	Rate float64
+	// Images lists the images that pods on the node are waiting for, and the pods waiting for them.
+	Images []string
+}
+
+// pullTracker tracks the offset of in-progress content ingests across checks, so that a transfer
+// rate can be calculated.
+type pullTracker struct {
+	offsets map[string]int64
+	checked time.Time
+}
+
+// MonitorPullProgress periodically lists the content ingests in the CRI namespace, and emits an event on the Node
+// summarizing the progress of image pulls that have been running for longer than threshold, so that slow pulls
+// can be distinguished from stuck ones. Content ingests do not record the image they belong to, so the event
+// names the images that are not yet present, that pods on the node are waiting to start with.
+func MonitorPullProgress(ctx context.Context, cfg *config.Node, threshold time.Duration) error {
+	if threshold <= 0 {
+		return nil
+	}
+	logrus.Debugf("Starting %s with progress threshold %s", pullProgressControllerName, threshold)
+
+	client, err := util.GetClientSet(cfg.AgentConfig.KubeConfigKubelet)
+	if err != nil {
+		return err
+	}
+
+	recorder := util.BuildControllerEventRecorder(client, pullProgressControllerName, metav1.NamespaceDefault)
+	nodeRef := &corev1.ObjectReference{
+		Kind:      "Node",
+		Name:      cfg.AgentConfig.NodeName,
+		UID:       types.UID(cfg.AgentConfig.NodeName),
+		Namespace: "",
+	}
+
+	tracker := &pullTracker{offsets: map[string]int64{}}
+	ctx = namespaces.WithNamespace(ctx, constants.K8sContainerdNamespace)
+
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		containerdClient, err := Client(cfg.Containerd.Address)
+		if err != nil {
+			logrus.Debugf("Failed to create containerd client: %v", err)
+			return
+		}
+		defer containerdClient.Close()
+		statuses, err := containerdClient.ContentStore().ListStatuses(ctx)
+		if err != nil {
+			logrus.Debugf("Failed to list containerd content ingests: %v", err)
+			return
+		}
+		progress, ok := tracker.update(statuses, time.Now(), threshold)
+		if !ok {
+			return
+		}
+		pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + cfg.AgentConfig.NodeName})
+		if err != nil {
+			logrus.Debugf("Failed to list pods waiting for image pulls: %v", err)
+		} else {
+			imageService := containerdClient.ImageService()
+			progress.Images = waitingImages(pods.Items, func(ref string) bool {
+				_, err := imageService.Get(ctx, ref)
+				return err == nil
+			})
+		}
+		message := progress.String()
+		logrus.Info(message)
+		recorder.Event(nodeRef, corev1.EventTypeNormal, "ImagePullProgress", message)
+	}, pullProgressInterval)
+
+	return nil
+}
+
+// waitingImages returns the images that pods are waiting to start containers with, that are not
+// present in the image store, and the pods that are waiting for them, for example:
+// "docker.io/library/nginx:1.27 for pod default/web"
+func waitingImages(pods []corev1.Pod, present func(ref string) bool) []string {
+	waiting := map[string][]string{}
+	for _, pod := range pods {
+		for _, status := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+			if status.State.Waiting == nil || (status.State.Waiting.Reason != "ContainerCreating" && status.State.Waiting.Reason != "PodInitializing") {
+				continue
+			}
+			ref, err := docker.ParseDockerRef(status.Image)
+			if err != nil || present(ref.String()) {
+				continue
+			}
+			podName := pod.Namespace + "/" + pod.Name
+			if !slices.Contains(waiting[ref.String()], podName) {
+				waiting[ref.String()] = append(waiting[ref.String()], podName)
+			}
+		}
+	}
+	images := make([]string, 0, len(waiting))
+	for ref, podNames := range waiting {
+		noun := "pod"
+		if len(podNames) > 1 {
+			noun = "pods"
+		}
+		images = append(images, fmt.Sprintf("%s for %s %s", ref, noun, strings.Join(podNames, ", ")))
+	}
+	sort.Strings(images)
+	return images
+}
+
+// update records the current ingest offsets, and returns a summary of the ingests that started
+// more than threshold ago. False is returned if there are no such ingests.
+func (t *pullTracker) update(statuses []content.Status, now time.Time, threshold time.Duration) (pullProgress, bool) {
+	progress := pullProgress{Rate: -1}
+	offsets := make(map[string]int64, len(statuses))
+	var transferred int64
+	for _, status := range statuses {
+		offsets[status.Ref] = status.Offset
+		elapsed := now.Sub(status.StartedAt)
+		if elapsed < threshold {
+			continue
+		}
+		progress.Layers++
+		progress.Offset += status.Offset
+		if progress.Total >= 0 && status.Total > 0 {
+			progress.Total += status.Total
+		} else {
+			// the total size of the pull is unknown if the size of any layer is unknown
+			progress.Total = -1
+		}
+		if elapsed > progress.Elapsed {
+			progress.Elapsed = elapsed
+		}
+		if previous, ok := t.offsets[status.Ref]; ok && status.Offset >= previous {
+			transferred += status.Offset - previous
+		}
+	}
+	if !t.checked.IsZero() && now.After(t.checked) {
+		progress.Rate = float64(transferred) / now.Sub(t.checked).Seconds()
+	}
+	t.offsets = offsets
+	t.checked = now
+	return progress, progress.Layers > 0
+}
+
+// String returns a human-readable description of the pull progress, for example:
+// "Pulling 3 image layers for 2m30s (docker.io/library/nginx:1.27 for pod default/web): 512.0MiB of 1.2GiB (42%) at 3.1MiB/s, ETA 3m"
+func (p pullProgress) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "Pulling %d image layers for %s", p.Layers, duration.HumanDuration(p.Elapsed))
+	if len(p.Images) > 0 {
+		fmt.Fprintf(b, " (%s)", strings.Join(p.Images, "; "))
+	}
+	fmt.Fprintf(b, ": %s", formatBytes(p.Offset))
+	if p.Total > 0 {
+		fmt.Fprintf(b, " of %s (%d%%)", formatBytes(p.Total), p.Offset*100/p.Total)
+	}
+	switch {
+	case p.Rate > 0:
+		fmt.Fprintf(b, " at %s/s", formatBytes(int64(p.Rate)))
+		if p.Total > p.Offset {
+			eta := time.Duration(float64(p.Total-p.Offset)/p.Rate) * time.Second
+			fmt.Fprintf(b, ", ETA %s", duration.HumanDuration(eta))
+		}
+	case p.Rate == 0:
+		b.WriteString(", no progress since last check")
+	}
+	return b.String()
+}
+
+// formatBytes returns the size in binary units, for example 1.5MiB
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTP"[exp])
+}
@@ -0,0 +1,114 @@
+package containerd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_UnitPullTrackerUpdate(t *testing.T) {
+	start := time.Now()
+	tracker := &pullTracker{offsets: map[string]int64{}}
+	statuses := []content.Status{
+		{Ref: "layer-sha256:a", Offset: 10 << 20, Total: 100 << 20, StartedAt: start},
+		{Ref: "layer-sha256:b", Offset: 5 << 20, Total: 50 << 20, StartedAt: start},
+		{Ref: "layer-sha256:c", Offset: 1 << 20, Total: 2 << 20, StartedAt: start.Add(50 * time.Second)},
+	}
+
+	// first check; the rate is not yet known
+	progress, ok := tracker.update(statuses, start.Add(time.Minute), time.Minute)
+	if !ok {
+		t.Fatal("update() returned no progress for pulls exceeding the threshold")
+	}
+	if progress.Layers != 2 || progress.Offset != 15<<20 || progress.Total != 150<<20 || progress.Rate != -1 {
+		t.Errorf("update() = %+v, want 2 layers, 15MiB of 150MiB with unknown rate", progress)
+	}
+	if got, want := progress.String(), "Pulling 2 image layers for 60s: 15.0MiB of 150.0MiB (10%)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	// second check; 30MiB transferred across both layers in 30 seconds
+	statuses[0].Offset += 20 << 20
+	statuses[1].Offset += 10 << 20
+	progress, _ = tracker.update(statuses, start.Add(90*time.Second), time.Minute)
+	if got, want := progress.String(), "Pulling 2 image layers for 90s: 45.0MiB of 150.0MiB (30%) at 1.0MiB/s, ETA 105s"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	// third check; no progress, and one layer with an unknown size
+	statuses[2].Total = 0
+	progress, _ = tracker.update(statuses, start.Add(2*time.Minute), time.Minute)
+	if got, want := progress.String(), "Pulling 3 image layers for 2m: 46.0MiB, no progress since last check"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	// images that pods are waiting for are included in the message
+	progress.Images = []string{"docker.io/library/nginx:1.27 for pod default/web"}
+	if got, want := progress.String(), "Pulling 3 image layers for 2m (docker.io/library/nginx:1.27 for pod default/web): 46.0MiB, no progress since last check"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	// no pulls exceeding the threshold
+	if _, ok := tracker.update(statuses[2:], start.Add(2*time.Minute), 5*time.Minute); ok {
+		t.Error("update() returned progress for pulls below the threshold")
+	}
+}
+
+func Test_UnitWaitingImages(t *testing.T) {
+	waiting := func(reason string) corev1.ContainerState {
+		return corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}}
+	}
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{{Image: "busybox", State: waiting("PodInitializing")}},
+				ContainerStatuses:     []corev1.ContainerStatus{{Image: "nginx:1.27", State: waiting("PodInitializing")}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: "default"},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{Image: "docker.io/library/nginx:1.27", State: waiting("ContainerCreating")}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "crashing", Namespace: "default"},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{Image: "redis", State: waiting("CrashLoopBackOff")}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "kube-system"},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{Image: "rancher/mirrored-pause:3.6", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}},
+			},
+		},
+	}
+	present := func(ref string) bool {
+		return ref == "docker.io/library/busybox:latest"
+	}
+	want := []string{"docker.io/library/nginx:1.27 for pods default/web, default/web-2"}
+	if got := waitingImages(pods, present); !reflect.DeepEqual(got, want) {
+		t.Errorf("waitingImages() = %v, want %v", got, want)
+	}
+}
+
+func Test_UnitFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		512:             "512B",
+		1536:            "1.5KiB",
+		10 << 20:        "10.0MiB",
+		3 << 30:         "3.0GiB",
+		(1 << 40) * 1.5: "1.5TiB",
+	}
+	for size, want := range tests {
+		if got := formatBytes(size); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
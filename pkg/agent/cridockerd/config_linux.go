@@ -4,15 +4,31 @@
 package cridockerd
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
+	"github.com/k3s-io/k3s/pkg/agent/registryconf"
+	agentutil "github.com/k3s-io/k3s/pkg/agent/util"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
-const socketPrefix = "unix://"
+const (
+	socketPrefix          = "unix://"
+	daemonConfigFile      = "/etc/docker/daemon.json"
+	certsDir              = "/etc/docker/certs.d"
+	defaultKubeletRoot    = "/var/lib/kubelet"
+	kubeletAuthConfigFile = "config.json"
+)
 
 func setupDockerCRIConfig(ctx context.Context, cfg *config.Node) error {
 	clientOpts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
@@ -35,5 +51,110 @@ func setupDockerCRIConfig(ctx context.Context, cfg *config.Node) error {
 	// kubelet's cgroup-driver flag. This may merit moving to somewhere else in order
 	// to avoid mutating the configuration while setting up the docker CRI.
 	cfg.AgentConfig.Systemd = i.CgroupDriver == "systemd"
+
+	for _, warning := range i.Warnings {
+		logrus.Warnf("Docker: %s", warning)
+	}
+	return setupDockerRegistries(cfg, i.RegistryConfig)
+}
+
+// setupDockerRegistries translates registries.yaml into the docker daemon config, docker certs.d
+// directory, and a kubelet config.json that provides pull credentials to cri-dockerd. Settings that
+// docker cannot apply are rejected. Docker reloads registry settings on SIGHUP; if the running daemon
+// has not loaded them, a warning is logged.
+func setupDockerRegistries(cfg *config.Node, serviceConfig *registry.ServiceConfig) error {
+	reg := cfg.AgentConfig.Registry
+	if cfg.Containerd.NoDefault {
+		return errors.New("disabling the default registry endpoint is not supported by docker")
+	}
+
+	mirrors, insecure, err := registryDaemonConfig(reg)
+	if err != nil {
+		return errors.Wrap(err, "invalid registries.yaml for docker")
+	}
+	if len(mirrors) > 0 || len(insecure) > 0 {
+		if cfg.AgentConfig.Rootless {
+			logrus.Warnf("Registry mirrors and insecure registries must be added to the rootless docker daemon config manually")
+		} else if err := writeDaemonConfig(mirrors, insecure); err != nil {
+			return errors.Wrap(err, "failed to update docker daemon config")
+		}
+		if unapplied := unappliedRegistryConfig(serviceConfig, mirrors, insecure); len(unapplied) > 0 {
+			logrus.Warnf("Docker has not loaded registry settings for %s; reload the docker daemon to apply them", strings.Join(unapplied, ", "))
+		}
+	}
+
+	if _, err := registryconf.WriteCerts(reg, certsDir); err != nil {
+		return err
+	}
+	return writeKubeletAuthConfig(cfg)
+}
+
+// writeDaemonConfig merges the mirrors and insecure registries into the docker daemon config file.
+func writeDaemonConfig(mirrors, insecure []string) error {
+	daemonConfig := map[string]any{}
+	if b, err := os.ReadFile(daemonConfigFile); err == nil {
+		if err := json.Unmarshal(b, &daemonConfig); err != nil {
+			return errors.Wrapf(err, "failed to parse %s", daemonConfigFile)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if !mergeDaemonConfig(daemonConfig, mirrors, insecure) {
+		return nil
+	}
+	b, err := json.MarshalIndent(daemonConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := agentutil.WriteFileIfChanged(daemonConfigFile, b, 0644); err != nil {
+		return err
+	}
+	logrus.Infof("Added registries.yaml mirrors and insecure registries to %s", daemonConfigFile)
 	return nil
 }
+
+// writeKubeletAuthConfig writes the registries.yaml credentials to config.json in the kubelet root
+// dir, where the kubelet credential keyring reads them and passes them to cri-dockerd with each
+// pull. A copy is kept in the cri-dockerd root dir so that an existing config.json that was not
+// written by k3s is never overwritten or removed.
+func writeKubeletAuthConfig(cfg *config.Node) error {
+	rootDir := cfg.AgentConfig.RootDir
+	if rootDir == "" {
+		rootDir = defaultKubeletRoot
+	}
+	path := filepath.Join(rootDir, kubeletAuthConfigFile)
+	record := filepath.Join(cfg.CRIDockerd.Root, kubeletAuthConfigFile)
+
+	b, err := registryconf.AuthConfig(cfg.AgentConfig.Registry)
+	if err != nil {
+		return err
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if existing != nil {
+		if recorded, _ := os.ReadFile(record); !bytes.Equal(existing, recorded) {
+			if b != nil {
+				logrus.Warnf("Not writing registry credentials to %s, as it was not created by %s", path, version.Program)
+			}
+			return nil
+		}
+	}
+
+	if b == nil {
+		if existing != nil {
+			os.Remove(record)
+			return os.Remove(path)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(cfg.CRIDockerd.Root, 0700); err != nil {
+		return err
+	}
+	if err := util.AtomicWrite(record, b, 0600); err != nil {
+		return err
+	}
+	_, err = agentutil.WriteFileIfChanged(path, b, 0600)
+	return err
+}
@@ -0,0 +1,107 @@
+//go:build !no_cri_dockerd
+// +build !no_cri_dockerd
+
+package cridockerd
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/k3s-io/k3s/pkg/agent/registryconf"
+	"github.com/pkg/errors"
+	"github.com/rancher/wharfie/pkg/registries"
+)
+
+// registryDaemonConfig translates registries.yaml into the registry-mirrors and insecure-registries
+// docker daemon settings. Docker only supports mirrors for docker.io, so endpoints for other
+// registries, as well as rewrites and invalid endpoints, are rejected. Mirror entries without
+// endpoints only enable the embedded registry mirror, and are skipped.
+func registryDaemonConfig(reg *registries.Registry) (mirrors, insecure []string, err error) {
+	if reg == nil {
+		return nil, nil, nil
+	}
+	insecureHosts := map[string]bool{}
+	for host, config := range reg.Configs {
+		if host != "*" && config.TLS != nil && config.TLS.InsecureSkipVerify {
+			insecureHosts[host] = true
+		}
+	}
+	hosts := make([]string, 0, len(reg.Mirrors))
+	for host := range reg.Mirrors {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		mirror := reg.Mirrors[host]
+		if len(mirror.Rewrites) > 0 {
+			return nil, nil, errors.Errorf("registry rewrites for %s are not supported by docker", host)
+		}
+		if host != "docker.io" {
+			if len(mirror.Endpoints) > 0 {
+				return nil, nil, errors.Errorf("registry mirror endpoints for %s are not supported by docker; only docker.io may be mirrored", host)
+			}
+			continue
+		}
+		for _, endpoint := range mirror.Endpoints {
+			mirrorHost, plainHTTP, err := registryconf.MirrorHost(endpoint)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "invalid registry mirror endpoint %q for %s", endpoint, host)
+			}
+			if mirrorHost == host {
+				continue
+			}
+			if plainHTTP {
+				mirrors = append(mirrors, "http://"+mirrorHost)
+				insecureHosts[mirrorHost] = true
+			} else {
+				mirrors = append(mirrors, "https://"+mirrorHost)
+			}
+		}
+	}
+	for host := range insecureHosts {
+		insecure = append(insecure, host)
+	}
+	sort.Strings(insecure)
+	return mirrors, insecure, nil
+}
+
+// mergeDaemonConfig adds the mirrors and insecure registries to the docker daemon config, preserving
+// any existing entries, and returns true if the config was modified.
+func mergeDaemonConfig(daemonConfig map[string]any, mirrors, insecure []string) bool {
+	changed := false
+	for key, values := range map[string][]string{"registry-mirrors": mirrors, "insecure-registries": insecure} {
+		existing, _ := daemonConfig[key].([]any)
+		for _, value := range values {
+			if !slices.ContainsFunc(existing, func(e any) bool { s, _ := e.(string); return strings.TrimSuffix(s, "/") == value }) {
+				existing = append(existing, value)
+				changed = true
+			}
+		}
+		if len(existing) > 0 {
+			daemonConfig[key] = existing
+		}
+	}
+	return changed
+}
+
+// unappliedRegistryConfig returns the mirrors and insecure registries that have not been loaded by
+// the docker daemon.
+func unappliedRegistryConfig(serviceConfig *registry.ServiceConfig, mirrors, insecure []string) []string {
+	unapplied := []string{}
+	if serviceConfig == nil {
+		return append(append(unapplied, mirrors...), insecure...)
+	}
+	for _, mirror := range mirrors {
+		if !slices.ContainsFunc(serviceConfig.Mirrors, func(m string) bool { return strings.TrimSuffix(m, "/") == mirror }) {
+			unapplied = append(unapplied, mirror)
+		}
+	}
+	for _, host := range insecure {
+		if index, ok := serviceConfig.IndexConfigs[host]; !ok || index.Secure {
+			unapplied = append(unapplied, host)
+		}
+	}
+	return unapplied
+}
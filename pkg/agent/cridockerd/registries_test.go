@@ -0,0 +1,81 @@
+//go:build !no_cri_dockerd
+// +build !no_cri_dockerd
+
+package cridockerd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/rancher/wharfie/pkg/registries"
+)
+
+func Test_UnitRegistryDaemonConfig(t *testing.T) {
+	mirrors, insecure, err := registryDaemonConfig(&registries.Registry{
+		Mirrors: map[string]registries.Mirror{
+			"docker.io":            {Endpoints: []string{"https://mirror.example.com/v2", "http://10.0.0.1:5000", "https://docker.io"}},
+			"registry.example.com": {},
+			"*":                    {},
+		},
+		Configs: map[string]registries.RegistryConfig{
+			"registry.example.com": {TLS: &registries.TLSConfig{InsecureSkipVerify: true}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("registryDaemonConfig() error = %v", err)
+	}
+	if want := []string{"https://mirror.example.com", "http://10.0.0.1:5000"}; !reflect.DeepEqual(mirrors, want) {
+		t.Errorf("mirrors = %v, want %v", mirrors, want)
+	}
+	if want := []string{"10.0.0.1:5000", "registry.example.com"}; !reflect.DeepEqual(insecure, want) {
+		t.Errorf("insecure = %v, want %v", insecure, want)
+	}
+
+	for name, mirrors := range map[string]map[string]registries.Mirror{
+		"other registry endpoint": {"registry.example.com": {Endpoints: []string{"https://mirror.example.com"}}},
+		"wildcard endpoint":       {"*": {Endpoints: []string{"https://mirror.example.com"}}},
+		"rewrite":                 {"docker.io": {Rewrites: map[string]string{"^rancher/(.*)": "mirrored-rancher/$1"}}},
+		"invalid endpoint":        {"docker.io": {Endpoints: []string{"ftp://mirror.example.com"}}},
+		"endpoint path":           {"docker.io": {Endpoints: []string{"https://mirror.example.com/path"}}},
+	} {
+		if _, _, err := registryDaemonConfig(&registries.Registry{Mirrors: mirrors}); err == nil {
+			t.Errorf("registryDaemonConfig(%s) error = nil, want error", name)
+		}
+	}
+}
+
+func Test_UnitMergeDaemonConfig(t *testing.T) {
+	daemonConfig := map[string]any{
+		"log-driver":       "journald",
+		"registry-mirrors": []any{"https://mirror.example.com/"},
+	}
+	if !mergeDaemonConfig(daemonConfig, []string{"https://mirror.example.com", "https://other.example.com"}, []string{"registry.example.com"}) {
+		t.Fatal("expected config to be changed")
+	}
+	want := map[string]any{
+		"log-driver":          "journald",
+		"registry-mirrors":    []any{"https://mirror.example.com/", "https://other.example.com"},
+		"insecure-registries": []any{"registry.example.com"},
+	}
+	if !reflect.DeepEqual(daemonConfig, want) {
+		t.Errorf("daemon config = %v, want %v", daemonConfig, want)
+	}
+	if mergeDaemonConfig(daemonConfig, []string{"https://other.example.com"}, []string{"registry.example.com"}) {
+		t.Error("expected config to be unchanged")
+	}
+}
+
+func Test_UnitUnappliedRegistryConfig(t *testing.T) {
+	serviceConfig := &registry.ServiceConfig{
+		Mirrors: []string{"https://mirror.example.com/"},
+		IndexConfigs: map[string]*registry.IndexInfo{
+			"docker.io":            {Name: "docker.io", Secure: true},
+			"registry.example.com": {Name: "registry.example.com", Secure: false},
+		},
+	}
+	got := unappliedRegistryConfig(serviceConfig, []string{"https://mirror.example.com", "http://10.0.0.1:5000"}, []string{"registry.example.com", "10.0.0.1:5000"})
+	if want := []string{"http://10.0.0.1:5000", "10.0.0.1:5000"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unapplied = %v, want %v", got, want)
+	}
+}
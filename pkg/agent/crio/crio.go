@@ -0,0 +1,159 @@
+package crio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/agent/registryconf"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/rancher/wharfie/pkg/registries"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// CNIConfDir is the directory that CRI-O loads CNI network configuration from by default. When
+	// using CRI-O, the flannel CNI config is written here instead of the agent data dir, unless a
+	// different CNI config dir is configured.
+	CNIConfDir = "/etc/cni/net.d"
+
+	socketName          = "crio.sock"
+	serviceName         = "crio.service"
+	runtimeName         = "cri-o"
+	dropInName          = "50-k3s.conf"
+	crioConfDir         = "/etc/crio/crio.conf.d"
+	registriesConfDir   = "/etc/containers/registries.conf.d"
+	registriesCertsDir  = "/etc/containers/certs.d"
+	defaultCNIPluginDir = "/opt/cni/bin"
+	managedHeader       = "# This file is managed by k3s; any changes will be overwritten.\n"
+)
+
+// IsEndpoint returns true if the container runtime endpoint is a CRI-O socket. CRI-O is identified by
+// the name of its socket, so that the CNI config location can be determined before the runtime is
+// available; the runtime name is verified by Setup.
+func IsEndpoint(endpoint string) bool {
+	return endpoint != "" && filepath.Base(strings.TrimPrefix(endpoint, "unix://")) == socketName
+}
+
+// crioConfig returns a CRI-O config drop-in that points CRI-O at the flannel CNI config and plugins,
+// and at the registry credentials from registries.yaml. Empty values are omitted.
+func crioConfig(authFile, networkName, cniConfDir, cniBinDir string) string {
+	b := &strings.Builder{}
+	b.WriteString(managedHeader)
+	if authFile != "" {
+		b.WriteString("\n[crio.image]\n")
+		fmt.Fprintf(b, "global_auth_file = %s\n", strconv.Quote(authFile))
+	}
+	if networkName != "" {
+		b.WriteString("\n[crio.network]\n")
+		fmt.Fprintf(b, "cni_default_network = %s\n", strconv.Quote(networkName))
+		fmt.Fprintf(b, "network_dir = %s\n", strconv.Quote(cniConfDir))
+		fmt.Fprintf(b, "plugin_dirs = [%s, %s]\n", strconv.Quote(defaultCNIPluginDir), strconv.Quote(cniBinDir))
+	}
+	return b.String()
+}
+
+// registriesConfig translates the mirrors and TLS settings from registries.yaml into a
+// containers-registries.conf(5) drop-in. Settings that cannot be expressed in registries.conf, such
+// as wildcard mirrors, rewrites, and endpoint paths, are skipped with a warning.
+func registriesConfig(registry *registries.Registry) string {
+	b := &strings.Builder{}
+	b.WriteString(managedHeader)
+	if registry == nil {
+		return b.String()
+	}
+
+	hosts := map[string]bool{}
+	for host := range registry.Mirrors {
+		hosts[host] = true
+	}
+	for host, config := range registry.Configs {
+		if config.TLS != nil && config.TLS.InsecureSkipVerify {
+			hosts[host] = true
+		}
+	}
+	delete(hosts, "*")
+	if _, ok := registry.Mirrors["*"]; ok {
+		logrus.Warn("Wildcard registry mirrors are not supported by CRI-O and will be ignored")
+	}
+
+	for _, host := range sortedKeys(hosts) {
+		mirror := registry.Mirrors[host]
+		if len(mirror.Rewrites) > 0 {
+			logrus.Warnf("Registry rewrites for %s are not supported by CRI-O and will be ignored", host)
+		}
+		fmt.Fprintf(b, "\n[[registry]]\nprefix = %s\nlocation = %s\n", strconv.Quote(host), strconv.Quote(host))
+		if registryconf.Insecure(registry, host) {
+			b.WriteString("insecure = true\n")
+		}
+		for _, endpoint := range mirror.Endpoints {
+			location, plainHTTP, err := registryconf.MirrorHost(endpoint)
+			if err != nil {
+				logrus.Warnf("Registry mirror endpoint %q for %s will be ignored: %v", endpoint, host, err)
+				continue
+			}
+			if location == host {
+				continue
+			}
+			fmt.Fprintf(b, "\n[[registry.mirror]]\nlocation = %s\n", strconv.Quote(location))
+			if plainHTTP || registryconf.Insecure(registry, location) {
+				b.WriteString("insecure = true\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// readManagedFiles returns the paths of the files written by the last call to Setup.
+func readManagedFiles(managedFiles string) []string {
+	b, err := os.ReadFile(managedFiles)
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(b))
+}
+
+// writeManagedFiles records the paths of the files written by Setup, so that they can be removed
+// once they are no longer needed.
+func writeManagedFiles(managedFiles string, files []string) error {
+	if err := os.MkdirAll(filepath.Dir(managedFiles), 0700); err != nil {
+		return err
+	}
+	return util.AtomicWrite(managedFiles, []byte(strings.Join(files, "\n")+"\n"), 0600)
+}
+
+// removeFiles removes the given files, other than those to keep, along with any directories left
+// empty within the certs.d directory. The paths of the removed files are returned.
+func removeFiles(files, keep []string, certsDir string) []string {
+	removed := []string{}
+	for _, file := range files {
+		if slices.Contains(keep, file) {
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			if !os.IsNotExist(err) {
+				logrus.Warnf("Failed to remove CRI-O config %s: %v", file, err)
+			}
+			continue
+		}
+		logrus.Infof("Removed CRI-O config %s", file)
+		removed = append(removed, file)
+		if dir := filepath.Dir(file); filepath.Dir(dir) == certsDir {
+			os.Remove(dir)
+		}
+	}
+	return removed
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
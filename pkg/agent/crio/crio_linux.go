@@ -0,0 +1,174 @@
+//go:build linux
+// +build linux
+
+package crio
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/agent/cri"
+	"github.com/k3s-io/k3s/pkg/agent/registryconf"
+	agentutil "github.com/k3s-io/k3s/pkg/agent/util"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// Setup waits for CRI-O to become available, verifies that the endpoint is CRI-O, sets the kubelet
+// cgroup driver to match the runtime, and writes CRI-O config drop-ins for the registries.yaml
+// mirrors and credentials, and for the flannel CNI config. If the registries drop-in changes CRI-O
+// is reloaded, and if the network config drop-in changes CRI-O is restarted, as CRI-O only loads its
+// network config at startup. Files written for a previous configuration that are no longer needed
+// are removed.
+func Setup(ctx context.Context, cfg *config.Node) error {
+	address := strings.TrimPrefix(cfg.ContainerRuntimeEndpoint, "unix://")
+	if err := cri.WaitForService(ctx, address, "CRI-O"); err != nil {
+		return err
+	}
+	conn, err := cri.Connection(ctx, address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client := runtimeapi.NewRuntimeServiceClient(conn)
+	v, err := client.Version(ctx, &runtimeapi.VersionRequest{})
+	if err != nil {
+		return errors.Wrap(err, "failed to get CRI-O version")
+	}
+	if v.RuntimeName != runtimeName {
+		return fmt.Errorf("container runtime at %s is %s, not %s", cfg.ContainerRuntimeEndpoint, v.RuntimeName, runtimeName)
+	}
+	logrus.Infof("Using CRI-O %s at %s", v.RuntimeVersion, cfg.ContainerRuntimeEndpoint)
+
+	// note: this mutatation of the passed agent.Config is later used to set the
+	// kubelet's cgroup-driver flag, as is done for the docker CRI.
+	if rc, err := client.RuntimeConfig(ctx, &runtimeapi.RuntimeConfigRequest{}); err != nil || rc.Linux == nil {
+		logrus.Warnf("Failed to get CRI-O cgroup driver, assuming systemd: %v", err)
+		cfg.AgentConfig.Systemd = true
+	} else {
+		cfg.AgentConfig.Systemd = rc.Linux.CgroupDriver == runtimeapi.CgroupDriver_SYSTEMD
+	}
+
+	previous := readManagedFiles(cfg.CRIO.ManagedFiles)
+	files, changed, err := writeConfig(cfg)
+	if err != nil {
+		return err
+	}
+	removed := removeFiles(previous, files, registriesCertsDir)
+	if err := writeManagedFiles(cfg.CRIO.ManagedFiles, files); err != nil {
+		return err
+	}
+	changed = append(changed, removed...)
+
+	switch {
+	case slices.Contains(changed, filepath.Join(crioConfDir, dropInName)):
+		if err := signalService("restart"); err != nil {
+			return err
+		}
+		return cri.WaitForService(ctx, address, "CRI-O")
+	case slices.Contains(changed, filepath.Join(registriesConfDir, dropInName)):
+		return signalService("reload")
+	}
+	return nil
+}
+
+// Cleanup removes the files written by Setup, if CRI-O is no longer used as the container runtime.
+// If CRI-O is still running, it is restarted so that it no longer uses the removed config.
+func Cleanup(ctx context.Context, cfg *config.Node) error {
+	previous := readManagedFiles(cfg.CRIO.ManagedFiles)
+	if len(previous) == 0 {
+		return nil
+	}
+	removed := removeFiles(previous, nil, registriesCertsDir)
+	if err := os.Remove(cfg.CRIO.ManagedFiles); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(removed) > 0 && serviceActive() {
+		return signalService("restart")
+	}
+	return nil
+}
+
+// writeConfig writes the registries.conf, auth.json, certs.d and crio.conf drop-ins. The auth file
+// is written to the agent data dir rather than a system location, as it contains credentials. The
+// paths of all the files are returned, along with the paths of those that were changed.
+func writeConfig(cfg *config.Node) ([]string, []string, error) {
+	files := []string{}
+	changed := []string{}
+	write := func(path string, b []byte, perm os.FileMode) error {
+		c, err := agentutil.WriteFileIfChanged(path, b, perm)
+		if err != nil {
+			return err
+		}
+		files = append(files, path)
+		if c {
+			logrus.Infof("Wrote CRI-O config %s", path)
+			changed = append(changed, path)
+		}
+		return nil
+	}
+
+	registry := cfg.AgentConfig.Registry
+	if cfg.Containerd.NoDefault {
+		logrus.Warn("Disabling the default registry endpoint is not supported by CRI-O and will be ignored")
+	}
+	if err := write(filepath.Join(registriesConfDir, dropInName), []byte(registriesConfig(registry)), 0644); err != nil {
+		return nil, nil, err
+	}
+	certs, err := registryconf.WriteCerts(registry, registriesCertsDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	files = append(files, certs...)
+
+	authFile := ""
+	if b, err := registryconf.AuthConfig(registry); err != nil {
+		return nil, nil, err
+	} else if b != nil {
+		authFile = cfg.CRIO.AuthFile
+		if err := os.MkdirAll(filepath.Dir(authFile), 0700); err != nil {
+			return nil, nil, err
+		}
+		if err := write(authFile, b, 0600); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	networkName := ""
+	if !cfg.NoFlannel {
+		networkName = cfg.AgentConfig.CNINetworkName
+	}
+	if err := write(filepath.Join(crioConfDir, dropInName), []byte(crioConfig(authFile, networkName, cfg.AgentConfig.CNIConfDir, cfg.AgentConfig.CNIBinDir)), 0644); err != nil {
+		return nil, nil, err
+	}
+	return files, changed, nil
+}
+
+// serviceActive returns true if CRI-O is running as a systemd service.
+func serviceActive() bool {
+	return exec.Command("systemctl", "is-active", "--quiet", serviceName).Run() == nil
+}
+
+// signalService reloads or restarts the CRI-O systemd service, so that it picks up config changes.
+// If CRI-O is not running as a systemd service, a warning is logged instead, as there is no other
+// supported way to reload it.
+func signalService(action string) error {
+	if !serviceActive() {
+		logrus.Warnf("CRI-O config has changed, but CRI-O is not running as the %s systemd service; %s CRI-O for the changes to take effect", serviceName, action)
+		return nil
+	}
+	logrus.Infof("CRI-O config has changed; running systemctl %s %s", action, serviceName)
+	if err := util.RunServiceUnit(serviceName, action); err != nil {
+		return errors.Wrapf(err, "failed to %s CRI-O", action)
+	}
+	return nil
+}
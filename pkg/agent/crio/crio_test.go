@@ -0,0 +1,113 @@
+package crio
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rancher/wharfie/pkg/registries"
+)
+
+func Test_UnitIsEndpoint(t *testing.T) {
+	for endpoint, want := range map[string]bool{
+		"":                                       false,
+		"unix:///var/run/crio/crio.sock":         true,
+		"/run/crio/crio.sock":                    true,
+		"unix:///run/containerd/containerd.sock": false,
+		"/var/run/cri-dockerd.sock":              false,
+	} {
+		if got := IsEndpoint(endpoint); got != want {
+			t.Errorf("IsEndpoint(%q) = %v, want %v", endpoint, got, want)
+		}
+	}
+}
+
+func Test_UnitRegistriesConfig(t *testing.T) {
+	registry := &registries.Registry{
+		Mirrors: map[string]registries.Mirror{
+			"docker.io": {Endpoints: []string{"https://mirror.example.com/v2", "http://10.0.0.1:5000", "https://docker.io", "https://other.example.com/path"}},
+			"*":         {Endpoints: []string{"https://mirror.example.com"}},
+		},
+		Configs: map[string]registries.RegistryConfig{
+			"registry.example.com:5000": {TLS: &registries.TLSConfig{InsecureSkipVerify: true}},
+		},
+	}
+	got := registriesConfig(registry)
+	want := managedHeader + `
+[[registry]]
+prefix = "docker.io"
+location = "docker.io"
+
+[[registry.mirror]]
+location = "mirror.example.com"
+
+[[registry.mirror]]
+location = "10.0.0.1:5000"
+insecure = true
+
+[[registry]]
+prefix = "registry.example.com:5000"
+location = "registry.example.com:5000"
+insecure = true
+`
+	if got != want {
+		t.Errorf("unexpected registries config:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func Test_UnitCRIOConfig(t *testing.T) {
+	if got := crioConfig("", "", "/etc/cni/net.d", "/bin"); got != managedHeader {
+		t.Errorf("expected empty config, got:\n%s", got)
+	}
+	got := crioConfig("/var/lib/rancher/k3s/agent/etc/crio/auth.json", "cbr0", CNIConfDir, "/var/lib/rancher/k3s/data/current/bin")
+	for _, line := range []string{
+		`global_auth_file = "/var/lib/rancher/k3s/agent/etc/crio/auth.json"`,
+		`cni_default_network = "cbr0"`,
+		`network_dir = "/etc/cni/net.d"`,
+		`plugin_dirs = ["/opt/cni/bin", "/var/lib/rancher/k3s/data/current/bin"]`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("expected config to contain %s, got:\n%s", line, got)
+		}
+	}
+}
+
+func Test_UnitManagedFiles(t *testing.T) {
+	dir := t.TempDir()
+	certsDir := filepath.Join(dir, "certs.d")
+	managedFiles := filepath.Join(dir, "crio", "managed-files")
+	dropIn := filepath.Join(dir, dropInName)
+	kept := filepath.Join(certsDir, "registry.example.com", "ca.crt")
+	stale := filepath.Join(certsDir, "old.example.com", "ca.crt")
+	for _, file := range []string{dropIn, kept, stale} {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(managedHeader), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if files := readManagedFiles(managedFiles); len(files) != 0 {
+		t.Errorf("expected no managed files, got %v", files)
+	}
+	if err := writeManagedFiles(managedFiles, []string{dropIn, kept, stale}); err != nil {
+		t.Fatal(err)
+	}
+	files := readManagedFiles(managedFiles)
+	if want := []string{dropIn, kept, stale}; !reflect.DeepEqual(files, want) {
+		t.Errorf("readManagedFiles() = %v, want %v", files, want)
+	}
+
+	if removed := removeFiles(files, []string{dropIn, kept}, certsDir); !reflect.DeepEqual(removed, []string{stale}) {
+		t.Errorf("removeFiles() = %v, want %v", removed, []string{stale})
+	}
+	if _, err := os.Stat(filepath.Dir(stale)); !os.IsNotExist(err) {
+		t.Errorf("expected empty certs.d directory to be removed, got %v", err)
+	}
+	if removed := removeFiles(files, nil, certsDir); !reflect.DeepEqual(removed, []string{dropIn, kept}) {
+		t.Errorf("removeFiles() = %v, want %v", removed, []string{dropIn, kept})
+	}
+}
@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+package crio
+
+import (
+	"context"
+	"errors"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+)
+
+// Setup is not supported on Windows, as CRI-O is not available for Windows.
+func Setup(ctx context.Context, cfg *config.Node) error {
+	return errors.New("CRI-O is not supported on Windows")
+}
+
+// Cleanup does nothing on Windows, as Setup never writes any files.
+func Cleanup(ctx context.Context, cfg *config.Node) error {
+	return nil
+}
@@ -0,0 +1,388 @@
+// Package dnscache implements a node-local fallback DNS server for cluster services. The last-known
+// Services and EndpointSlices are cached on disk, so that pods on the node can continue to resolve
+// cluster services while the apiserver is unavailable, including across reboots. The server listens
+// on a link-local address that is only reachable from the node and its pods, and is not added to the
+// resolv.conf of pods that use cluster DNS. Instead, it replaces the node's nameservers in the
+// resolv.conf passed to the kubelet, so that it is used as the upstream nameserver of coredns, and of
+// other pods that use the node's DNS settings, such as nodelocaldns. Queries for names that are not in
+// the cache are forwarded to the node's nameservers.
+package dnscache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// saveInterval is the interval at which changes to the cache are written to disk.
+	saveInterval = 30 * time.Second
+	// ttl is the TTL of answers from the cache; this matches the default TTL used by coredns.
+	ttl = 5
+	// forwardTimeout is the timeout for queries forwarded to upstream nameservers.
+	forwardTimeout = 2 * time.Second
+	// defaultResolvConf is used to find upstream nameservers if the kubelet resolv.conf is not set.
+	defaultResolvConf = "/etc/resolv.conf"
+	// listenAddress is the link-local address that the server listens on. It is assigned to a dummy
+	// interface on the node, in the same way as the address of nodelocaldns.
+	listenAddress = "169.254.20.11"
+)
+
+// records maps fully qualified service names to their addresses.
+type records map[string][]string
+
+// resolver answers DNS queries for cluster services from the cached records.
+type resolver struct {
+	mu        sync.RWMutex
+	domain    string
+	records   records
+	dirty     bool
+	upstreams []string
+}
+
+// Run starts the fallback DNS server, and keeps its cache up to date with the cluster's Services
+// and EndpointSlices. Nothing is done if the fallback DNS cache is not enabled. This must be called
+// before the kubelet is started, as the kubelet's resolv.conf is only replaced once the server is
+// listening; if it cannot listen, a warning is logged and the cache is not used.
+func Run(ctx context.Context, nodeConfig *config.Node) error {
+	file := nodeConfig.AgentConfig.DNSFallbackCacheFile
+	if file == "" {
+		return nil
+	}
+
+	r := &resolver{
+		domain:  dns.Fqdn(nodeConfig.AgentConfig.ClusterDomain),
+		records: records{},
+	}
+	if err := r.load(file); err != nil && !os.IsNotExist(err) {
+		logrus.Warnf("Failed to load DNS fallback cache from %s: %v", file, err)
+	}
+
+	resolvConf := nodeConfig.AgentConfig.ResolvConf
+	if resolvConf == "" {
+		resolvConf = defaultResolvConf
+	}
+	upstreams, err := upstreamServers(resolvConf, listenAddress)
+	if err != nil {
+		logrus.Warnf("Failed to read upstream nameservers for DNS fallback cache from %s: %v", resolvConf, err)
+	}
+	r.upstreams = upstreams
+
+	addr := net.JoinHostPort(listenAddress, "53")
+	if err := ensureAddress(listenAddress); err != nil {
+		logrus.Warnf("DNS fallback cache is disabled, failed to add %s to the node: %v", listenAddress, err)
+		return nil
+	}
+	servers, err := listen(addr, r)
+	if err != nil {
+		logrus.Warnf("DNS fallback cache is disabled, failed to listen on %s: %v", addr, err)
+		return nil
+	}
+	kubeletResolvConf := filepath.Join(filepath.Dir(file), "etc", "dns-fallback-resolv.conf")
+	if err := writeResolvConf(resolvConf, kubeletResolvConf, listenAddress); err != nil {
+		for _, server := range servers {
+			server.Shutdown()
+		}
+		logrus.Warnf("DNS fallback cache is disabled, failed to write %s: %v", kubeletResolvConf, err)
+		return nil
+	}
+	for _, server := range servers {
+		go func() {
+			<-ctx.Done()
+			server.Shutdown()
+		}()
+		go func() {
+			if err := server.ActivateAndServe(); err != nil && ctx.Err() == nil {
+				logrus.Errorf("DNS fallback cache server on %s exited: %v", addr, err)
+			}
+		}()
+	}
+	nodeConfig.AgentConfig.ResolvConf = kubeletResolvConf
+
+	// kube-proxy's credentials are used, as it is already allowed to watch Services and EndpointSlices
+	client, err := util.GetClientSet(nodeConfig.AgentConfig.KubeConfigKubeProxy)
+	if err != nil {
+		return err
+	}
+	if err := r.watch(ctx, client); err != nil {
+		return err
+	}
+
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.save(file); err != nil {
+			logrus.Warnf("Failed to save DNS fallback cache to %s: %v", file, err)
+		}
+	}, saveInterval)
+
+	logrus.Infof("Serving DNS fallback cache for %s on %s", r.domain, addr)
+	return nil
+}
+
+// listen binds UDP and TCP listeners on the address, and returns DNS servers for them. If either
+// listener cannot be bound, both are closed and an error is returned.
+func listen(addr string, handler dns.Handler) ([]*dns.Server, error) {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		pc.Close()
+		return nil, err
+	}
+	return []*dns.Server{
+		{PacketConn: pc, Handler: handler},
+		{Listener: l, Handler: handler},
+	}, nil
+}
+
+// upstreamServers returns the addresses of the nameservers in a resolv.conf file, excluding the
+// address that the fallback server listens on.
+func upstreamServers(resolvConf, listenAddress string) ([]string, error) {
+	cfg, err := dns.ClientConfigFromFile(resolvConf)
+	if err != nil {
+		return nil, err
+	}
+	upstreams := []string{}
+	for _, server := range cfg.Servers {
+		if server != listenAddress {
+			upstreams = append(upstreams, net.JoinHostPort(server, cfg.Port))
+		}
+	}
+	return upstreams, nil
+}
+
+// writeResolvConf writes a copy of a resolv.conf file to dst, with its nameservers replaced by the
+// given nameserver. Search domains and options are retained.
+func writeResolvConf(src, dst, nameserver string) error {
+	b, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	out := &bytes.Buffer{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 && fields[0] == "nameserver" {
+			continue
+		}
+		out.WriteString(scanner.Text() + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	out.WriteString("nameserver " + nameserver + "\n")
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	return util.AtomicWrite(dst, out.Bytes(), 0644)
+}
+
+// watch updates the records whenever Services or EndpointSlices change. The records loaded from
+// disk are retained until the informer caches have synced, so that they are still served if the
+// apiserver is unavailable when the agent starts.
+func (r *resolver) watch(ctx context.Context, client kubernetes.Interface) error {
+	factory := informers.NewSharedInformerFactory(client, 0)
+	services := factory.Core().V1().Services()
+	slices := factory.Discovery().V1().EndpointSlices()
+
+	update := func() {
+		if !services.Informer().HasSynced() || !slices.Informer().HasSynced() {
+			return
+		}
+		recs, err := buildRecords(r.domain, services.Lister(), slices.Lister())
+		if err != nil {
+			logrus.Warnf("Failed to update DNS fallback cache: %v", err)
+			return
+		}
+		r.set(recs)
+	}
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { update() },
+		UpdateFunc: func(any, any) { update() },
+		DeleteFunc: func(any) { update() },
+	}
+	if _, err := services.Informer().AddEventHandler(handler); err != nil {
+		return err
+	}
+	if _, err := slices.Informer().AddEventHandler(handler); err != nil {
+		return err
+	}
+
+	factory.Start(ctx.Done())
+	go func() {
+		if cache.WaitForCacheSync(ctx.Done(), services.Informer().HasSynced, slices.Informer().HasSynced) {
+			update()
+		}
+	}()
+	return nil
+}
+
+// buildRecords returns records for all services. Services with a cluster IP resolve to their
+// cluster IPs; headless services resolve to the addresses of their ready endpoints.
+func buildRecords(domain string, services corelisters.ServiceLister, slices discoverylisters.EndpointSliceLister) (records, error) {
+	svcs, err := services.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	recs := records{}
+	for _, svc := range svcs {
+		name := svc.Name + "." + svc.Namespace + ".svc." + domain
+		switch {
+		case svc.Spec.Type == corev1.ServiceTypeExternalName:
+			continue
+		case svc.Spec.ClusterIP != corev1.ClusterIPNone:
+			for _, ip := range svc.Spec.ClusterIPs {
+				if ip != "" && ip != corev1.ClusterIPNone {
+					recs[name] = append(recs[name], ip)
+				}
+			}
+		default:
+			eps, err := slices.EndpointSlices(svc.Namespace).List(labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: svc.Name}))
+			if err != nil {
+				return nil, err
+			}
+			for _, eps := range eps {
+				for _, ep := range eps.Endpoints {
+					if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+						continue
+					}
+					recs[name] = append(recs[name], ep.Addresses...)
+					if ep.Hostname != nil {
+						recs[*ep.Hostname+"."+name] = append(recs[*ep.Hostname+"."+name], ep.Addresses...)
+					}
+				}
+			}
+		}
+	}
+	for name := range recs {
+		sort.Strings(recs[name])
+	}
+	return recs, nil
+}
+
+// ServeDNS answers A and AAAA queries for cached names in the cluster domain. Other queries are
+// forwarded to the upstream nameservers.
+func (r *resolver) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	resp := r.answer(req)
+	if resp == nil {
+		resp = r.forward(req, w.LocalAddr().Network())
+	}
+	if err := w.WriteMsg(resp); err != nil {
+		logrus.Debugf("Failed to write DNS fallback cache response: %v", err)
+	}
+}
+
+// answer returns a response for a query from the cached records, or nil if the queried name is not
+// cached.
+func (r *resolver) answer(req *dns.Msg) *dns.Msg {
+	resp := &dns.Msg{}
+	resp.SetReply(req)
+	if len(req.Question) != 1 {
+		return resp.SetRcode(req, dns.RcodeFormatError)
+	}
+	q := req.Question[0]
+	name := strings.ToLower(q.Name)
+	if !dns.IsSubDomain(r.domain, name) {
+		return nil
+	}
+
+	r.mu.RLock()
+	addrs, ok := r.records[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	resp.Authoritative = true
+	hdr := dns.RR_Header{Name: q.Name, Class: dns.ClassINET, Ttl: ttl}
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		switch {
+		case ip == nil:
+		case q.Qtype == dns.TypeA && ip.To4() != nil:
+			hdr.Rrtype = dns.TypeA
+			resp.Answer = append(resp.Answer, &dns.A{Hdr: hdr, A: ip.To4()})
+		case q.Qtype == dns.TypeAAAA && ip.To4() == nil:
+			hdr.Rrtype = dns.TypeAAAA
+			resp.Answer = append(resp.Answer, &dns.AAAA{Hdr: hdr, AAAA: ip})
+		}
+	}
+	return resp
+}
+
+// forward sends a query to each upstream nameserver in turn, and returns the first response. If
+// no upstream nameserver responds, a server failure response is returned.
+func (r *resolver) forward(req *dns.Msg, network string) *dns.Msg {
+	client := &dns.Client{Net: network, Timeout: forwardTimeout}
+	for _, upstream := range r.upstreams {
+		resp, _, err := client.Exchange(req, upstream)
+		if err == nil {
+			return resp
+		}
+		logrus.Debugf("Failed to forward DNS query for %s to %s: %v", req.Question[0].Name, upstream, err)
+	}
+	resp := &dns.Msg{}
+	return resp.SetRcode(req, dns.RcodeServerFailure)
+}
+
+func (r *resolver) set(recs records) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = recs
+	r.dirty = true
+}
+
+func (r *resolver) load(file string) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	recs := records{}
+	if err := json.Unmarshal(b, &recs); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = recs
+	return nil
+}
+
+// save writes the records to disk, if they have changed since they were last saved.
+func (r *resolver) save(file string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dirty {
+		return nil
+	}
+	b, err := json.Marshal(r.records)
+	if err != nil {
+		return err
+	}
+	if err := util.AtomicWrite(file, b, 0600); err != nil {
+		return err
+	}
+	r.dirty = false
+	return nil
+}
@@ -0,0 +1,31 @@
+package dnscache
+
+import (
+	"net"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
+)
+
+// interfaceName is the name of the dummy interface that the listen address is assigned to.
+var interfaceName = version.Program + "-dnscache"
+
+// ensureAddress assigns the address to a dummy interface, creating the interface if necessary, so that
+// the server can listen on it.
+func ensureAddress(address string) error {
+	link, err := netlink.LinkByName(interfaceName)
+	if _, ok := err.(netlink.LinkNotFoundError); ok {
+		link = &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: interfaceName}}
+		if err := netlink.LinkAdd(link); err != nil {
+			return errors.Wrapf(err, "failed to create interface %s", interfaceName)
+		}
+	} else if err != nil {
+		return err
+	}
+	addr := &netlink.Addr{IPNet: &net.IPNet{IP: net.ParseIP(address), Mask: net.CIDRMask(32, 32)}}
+	if err := netlink.AddrReplace(link, addr); err != nil {
+		return errors.Wrapf(err, "failed to add address to interface %s", interfaceName)
+	}
+	return netlink.LinkSetUp(link)
+}
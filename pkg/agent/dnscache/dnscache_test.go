@@ -0,0 +1,164 @@
+package dnscache
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/miekg/dns"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+)
+
+func Test_UnitBuildRecords(t *testing.T) {
+	services := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	slices := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	services.Add(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.43.0.10", ClusterIPs: []string{"10.43.0.10", "fd00:43::10"}},
+	})
+	services.Add(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone, ClusterIPs: []string{corev1.ClusterIPNone}},
+	})
+	services.Add(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "external", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName, ExternalName: "example.com"},
+	})
+	slices.Add(&discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-abc", Namespace: "default", Labels: map[string]string{discoveryv1.LabelServiceName: "db"}},
+		Endpoints: []discoveryv1.Endpoint{
+			{Addresses: []string{"10.42.0.5"}, Hostname: ptr.To("db-0"), Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(true)}},
+			{Addresses: []string{"10.42.1.5"}, Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(false)}},
+		},
+	})
+
+	recs, err := buildRecords("cluster.local.", corelisters.NewServiceLister(services), discoverylisters.NewEndpointSliceLister(slices))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := records{
+		"web.default.svc.cluster.local.":     {"10.43.0.10", "fd00:43::10"},
+		"db.default.svc.cluster.local.":      {"10.42.0.5"},
+		"db-0.db.default.svc.cluster.local.": {"10.42.0.5"},
+	}
+	if !reflect.DeepEqual(recs, want) {
+		t.Errorf("buildRecords() = %v, want %v", recs, want)
+	}
+}
+
+func Test_UnitAnswer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dns-fallback-cache.json")
+	r := &resolver{domain: "cluster.local."}
+	r.set(records{"web.default.svc.cluster.local.": {"10.43.0.10", "fd00:43::10"}})
+	if err := r.save(file); err != nil {
+		t.Fatal(err)
+	}
+
+	// answers are served from records loaded from disk
+	r = &resolver{domain: "cluster.local."}
+	if err := r.load(file); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		qtype     uint16
+		wantNil   bool
+		wantRcode int
+		wantCount int
+	}{
+		{name: "Web.default.svc.cluster.local.", qtype: dns.TypeA, wantRcode: dns.RcodeSuccess, wantCount: 1},
+		{name: "web.default.svc.cluster.local.", qtype: dns.TypeAAAA, wantRcode: dns.RcodeSuccess, wantCount: 1},
+		{name: "web.default.svc.cluster.local.", qtype: dns.TypeTXT, wantRcode: dns.RcodeSuccess, wantCount: 0},
+		{name: "missing.default.svc.cluster.local.", qtype: dns.TypeA, wantNil: true},
+		{name: "example.com.", qtype: dns.TypeA, wantNil: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &dns.Msg{}
+			req.SetQuestion(tt.name, tt.qtype)
+			resp := r.answer(req)
+			if tt.wantNil {
+				if resp != nil {
+					t.Errorf("answer() = %v, want nil so that the query is forwarded", resp)
+				}
+				return
+			}
+			if resp.Rcode != tt.wantRcode || len(resp.Answer) != tt.wantCount {
+				t.Errorf("answer() = rcode %d with %d answers, want rcode %d with %d answers", resp.Rcode, len(resp.Answer), tt.wantRcode, tt.wantCount)
+			}
+		})
+	}
+}
+
+func Test_UnitForward(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := &dns.Msg{}
+		resp.SetReply(req)
+		resp.Answer = append(resp.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("192.0.2.1").To4(),
+		})
+		w.WriteMsg(resp)
+	})}
+	go upstream.ActivateAndServe()
+	defer upstream.Shutdown()
+
+	req := &dns.Msg{}
+	req.SetQuestion("example.com.", dns.TypeA)
+
+	// the first upstream does not respond, so the query is sent to the next one
+	r := &resolver{domain: "cluster.local.", upstreams: []string{"127.0.0.1:1", pc.LocalAddr().String()}}
+	if resp := r.forward(req, "udp"); resp.Rcode != dns.RcodeSuccess || len(resp.Answer) != 1 {
+		t.Errorf("forward() = rcode %d with %d answers, want rcode %d with 1 answer", resp.Rcode, len(resp.Answer), dns.RcodeSuccess)
+	}
+
+	r = &resolver{domain: "cluster.local."}
+	if resp := r.forward(req, "udp"); resp.Rcode != dns.RcodeServerFailure {
+		t.Errorf("forward() with no upstreams = rcode %d, want %d", resp.Rcode, dns.RcodeServerFailure)
+	}
+}
+
+func Test_UnitUpstreamServers(t *testing.T) {
+	resolvConf := filepath.Join(t.TempDir(), "resolv.conf")
+	if err := os.WriteFile(resolvConf, []byte("nameserver 192.168.1.10\nnameserver 8.8.8.8\nnameserver 2001:db8::53\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	upstreams, err := upstreamServers(resolvConf, "192.168.1.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"8.8.8.8:53", "[2001:db8::53]:53"}; !reflect.DeepEqual(upstreams, want) {
+		t.Errorf("upstreamServers() = %v, want %v", upstreams, want)
+	}
+}
+
+func Test_UnitWriteResolvConf(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "resolv.conf")
+	dst := filepath.Join(dir, "etc", "dns-fallback-resolv.conf")
+	if err := os.WriteFile(src, []byte("# generated\nsearch example.com\nnameserver 8.8.8.8\nnameserver 2001:db8::53\noptions ndots:2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeResolvConf(src, dst, listenAddress); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# generated\nsearch example.com\noptions ndots:2\nnameserver 169.254.20.11\n"; string(b) != want {
+		t.Errorf("writeResolvConf() wrote %q, want %q", b, want)
+	}
+}
@@ -0,0 +1,7 @@
+package dnscache
+
+import "errors"
+
+func ensureAddress(address string) error {
+	return errors.New("not supported on windows")
+}
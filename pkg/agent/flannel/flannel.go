@@ -40,17 +40,13 @@ import (
 	_ "github.com/flannel-io/flannel/pkg/backend/wireguard"
 )
 
-const (
-	subnetFile = "/run/flannel/subnet.env"
-)
-
 var (
 	FlannelBaseAnnotation         = "flannel.alpha.coreos.com"
 	FlannelExternalIPv4Annotation = FlannelBaseAnnotation + "/public-ip-overwrite"
 	FlannelExternalIPv6Annotation = FlannelBaseAnnotation + "/public-ipv6-overwrite"
 )
 
-func flannel(ctx context.Context, flannelIface *net.Interface, flannelConf, kubeConfigFile string, flannelIPv6Masq bool, netMode int) error {
+func flannel(ctx context.Context, flannelIface *net.Interface, flannelConf, subnetFile, kubeConfigFile string, flannelIPv6Masq bool, netMode int) error {
 	extIface, err := LookupExtInterface(flannelIface, netMode)
 	if err != nil {
 		return errors.Wrap(err, "failed to find the interface")
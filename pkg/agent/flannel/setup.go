@@ -1,16 +1,20 @@
 package flannel
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
 	goruntime "runtime"
+	"strconv"
 	"strings"
 
 	agentutil "github.com/k3s-io/k3s/pkg/agent/util"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/instance"
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -49,7 +53,9 @@ const (
 	wireguardNativeBackend = `{
 	"Type": "wireguard",
 	"PersistentKeepaliveInterval": %PersistentKeepaliveInterval%,
-	"Mode": "%Mode%"
+	"Mode": "%Mode%",
+	"ListenPort": %ListenPort%,
+	"ListenPortV6": %ListenPortV6%
 }`
 
 	emptyIPv6Network = "::/0"
@@ -92,12 +98,14 @@ func Run(ctx context.Context, nodeConfig *config.Node) error {
 		return errors.Wrap(err, "flannel failed to wait for PodCIDR assignment")
 	}
 
+	checkPeerPorts(ctx, nodeConfig, coreClient.CoreV1().Nodes())
+
 	netMode, err := findNetMode(nodeConfig.AgentConfig.ClusterCIDRs)
 	if err != nil {
 		return errors.Wrap(err, "failed to check netMode for flannel")
 	}
 	go func() {
-		err := flannel(ctx, nodeConfig.FlannelIface, nodeConfig.FlannelConfFile, kubeConfig, nodeConfig.FlannelIPv6Masq, netMode)
+		err := flannel(ctx, nodeConfig.FlannelIface, nodeConfig.FlannelConfFile, instance.SubnetFile(nodeConfig.InstanceName), kubeConfig, nodeConfig.FlannelIPv6Masq, netMode)
 		if err != nil && !errors.Is(err, context.Canceled) {
 			logrus.Errorf("flannel exited: %v", err)
 			os.Exit(1)
@@ -136,12 +144,46 @@ func waitForPodCIDR(ctx context.Context, nodeName string, nodes typedcorev1.Node
 	return nil
 }
 
+// checkPeerPorts warns about nodes whose flannel backend uses different UDP ports to this node, as
+// overlay traffic between them will not be received. Nodes that do not advertise their ports are not checked.
+func checkPeerPorts(ctx context.Context, nodeConfig *config.Node, nodes typedcorev1.NodeInterface) {
+	ports := nodeConfig.FlannelPorts()
+	if len(ports) == 0 {
+		return
+	}
+	nodeList, err := nodes.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		logrus.Warnf("Failed to list nodes to check flannel ports: %v", err)
+		return
+	}
+	value := nodeconfig.FlannelPortsValue(ports)
+	for _, peer := range mismatchedPeers(nodeConfig.AgentConfig.NodeName, value, nodeList.Items) {
+		logrus.Warnf("Node %s uses flannel %s ports %s, but this node uses %s; all nodes must use the same ports",
+			peer.Name, nodeConfig.FlannelBackend, peer.Annotations[nodeconfig.FlannelPortsAnnotation], value)
+	}
+}
+
+// mismatchedPeers returns the nodes other than the named node whose flannel ports annotation is set to
+// a different value.
+func mismatchedPeers(nodeName, value string, nodes []v1.Node) []v1.Node {
+	var peers []v1.Node
+	for _, node := range nodes {
+		if node.Name == nodeName {
+			continue
+		}
+		if peerValue, ok := node.Annotations[nodeconfig.FlannelPortsAnnotation]; ok && peerValue != value {
+			peers = append(peers, node)
+		}
+	}
+	return peers
+}
+
 func createCNIConf(dir string, nodeConfig *config.Node) error {
 	logrus.Debugf("Creating the CNI conf in directory %s", dir)
 	if dir == "" {
 		return nil
 	}
-	p := filepath.Join(dir, "10-flannel.conflist")
+	p := filepath.Join(dir, cmp.Or(nodeConfig.AgentConfig.CNIConfName, instance.CNIConfName(nodeConfig.InstanceName)))
 
 	if nodeConfig.AgentConfig.FlannelCniConfFile != "" {
 		logrus.Debugf("Using %s as the flannel CNI conf", nodeConfig.AgentConfig.FlannelCniConfFile)
@@ -158,6 +200,11 @@ func createCNIConf(dir string, nodeConfig *config.Node) error {
 		cniConfJSON = strings.ReplaceAll(cniConfJSON, "%IPV4_ADDRESS%", extIface.IfaceAddr.String())
 		cniConfJSON = strings.ReplaceAll(cniConfJSON, "%CLUSTER_CIDR%", nodeConfig.AgentConfig.ClusterCIDR.String())
 		cniConfJSON = strings.ReplaceAll(cniConfJSON, "%SERVICE_CIDR%", nodeConfig.AgentConfig.ServiceCIDR.String())
+	} else {
+		cniConfJSON = strings.ReplaceAll(cniConfJSON, "%NETWORK_NAME%", cmp.Or(nodeConfig.AgentConfig.CNINetworkName, instance.NetworkName(nodeConfig.InstanceName)))
+		cniConfJSON = strings.ReplaceAll(cniConfJSON, "%SUBNET_FILE%", instance.SubnetFile(nodeConfig.InstanceName))
+		cniConfJSON = strings.ReplaceAll(cniConfJSON, "%DATA_DIR%", instance.CNIDataDir(nodeConfig.InstanceName))
+		cniConfJSON = strings.ReplaceAll(cniConfJSON, "%BRIDGE%", instance.BridgeName(nodeConfig.InstanceName))
 	}
 
 	return agentutil.WriteFile(p, cniConfJSON)
@@ -224,7 +271,8 @@ func createFlannelConf(nodeConfig *config.Node) error {
 
 	switch nodeConfig.FlannelBackend {
 	case config.FlannelBackendVXLAN:
-		backendConf = vxlanBackend
+		backendConf = strings.ReplaceAll(vxlanBackend, "%VNI%", strconv.Itoa(instance.VNI(nodeConfig.InstanceName)))
+		backendConf = strings.ReplaceAll(backendConf, "%Port%", strconv.Itoa(nodeConfig.FlannelVXLANPort))
 	case config.FlannelBackendHostGW:
 		backendConf = hostGWBackend
 	case config.FlannelBackendTailscale:
@@ -251,6 +299,8 @@ func createFlannelConf(nodeConfig *config.Node) error {
 		}
 		backendConf = strings.ReplaceAll(wireguardNativeBackend, "%Mode%", mode)
 		backendConf = strings.ReplaceAll(backendConf, "%PersistentKeepaliveInterval%", keepalive)
+		backendConf = strings.ReplaceAll(backendConf, "%ListenPort%", strconv.Itoa(nodeConfig.FlannelWireguardPort))
+		backendConf = strings.ReplaceAll(backendConf, "%ListenPortV6%", strconv.Itoa(nodeConfig.FlannelWireguardPortIPv6))
 	default:
 		return fmt.Errorf("Cannot configure unknown flannel backend '%s'", nodeConfig.FlannelBackend)
 	}
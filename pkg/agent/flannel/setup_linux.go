@@ -5,12 +5,15 @@ package flannel
 
 const (
 	cniConf = `{
-  "name":"cbr0",
+  "name":"%NETWORK_NAME%",
   "cniVersion":"1.0.0",
   "plugins":[
     {
       "type":"flannel",
+      "subnetFile":"%SUBNET_FILE%",
+      "dataDir":"%DATA_DIR%",
       "delegate":{
+        "bridge":"%BRIDGE%",
         "hairpinMode":true,
         "forceAddress":true,
         "isDefaultGateway":true
@@ -33,6 +36,8 @@ const (
 `
 
 	vxlanBackend = `{
-	"Type": "vxlan"
+	"Type": "vxlan",
+	"VNI": %VNI%,
+	"Port": %Port%
 }`
 )
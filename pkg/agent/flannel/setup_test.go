@@ -8,6 +8,9 @@ import (
 	"testing"
 
 	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func stringToCIDR(s string) []*net.IPNet {
@@ -55,14 +58,14 @@ func Test_createFlannelConf(t *testing.T) {
 		wantErr    bool
 	}{
 		{"dual-stack", "10.42.0.0/16,2001:cafe:22::/56", []string{"\"Network\": \"10.42.0.0/16\"", "\"IPv6Network\": \"2001:cafe:22::/56\"", "\"EnableIPv6\": true"}, false},
-		{"ipv4 only", "10.42.0.0/16", []string{"\"Network\": \"10.42.0.0/16\"", "\"IPv6Network\": \"::/0\"", "\"EnableIPv6\": false"}, false},
+		{"ipv4 only", "10.42.0.0/16", []string{"\"Network\": \"10.42.0.0/16\"", "\"IPv6Network\": \"::/0\"", "\"EnableIPv6\": false", "\"Port\": 4789"}, false},
 	}
 	var containerd = config.Containerd{}
 	for _, tt := range tests {
 		var agent = config.Agent{}
 		agent.ClusterCIDR = stringToCIDR(tt.args)[0]
 		agent.ClusterCIDRs = stringToCIDR(tt.args)
-		var nodeConfig = &config.Node{Docker: false, ContainerRuntimeEndpoint: "", SELinux: false, FlannelBackend: "vxlan", FlannelConfFile: "test_file", FlannelConfOverride: false, FlannelIface: nil, Containerd: containerd, Images: "", AgentConfig: agent, Token: "", ServerHTTPSPort: 0}
+		var nodeConfig = &config.Node{Docker: false, ContainerRuntimeEndpoint: "", SELinux: false, FlannelBackend: "vxlan", FlannelConfFile: "test_file", FlannelConfOverride: false, FlannelVXLANPort: 4789, FlannelIface: nil, Containerd: containerd, Images: "", AgentConfig: agent, Token: "", ServerHTTPSPort: 0}
 
 		t.Run(tt.name, func(t *testing.T) {
 			if err := createFlannelConf(nodeConfig); (err != nil) != tt.wantErr {
@@ -81,3 +84,16 @@ func Test_createFlannelConf(t *testing.T) {
 		})
 	}
 }
+
+func Test_UnitMismatchedPeers(t *testing.T) {
+	nodes := []v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Annotations: map[string]string{nodeconfig.FlannelPortsAnnotation: "4789"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Annotations: map[string]string{nodeconfig.FlannelPortsAnnotation: "4789"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-3", Annotations: map[string]string{nodeconfig.FlannelPortsAnnotation: "8472"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-4"}},
+	}
+	peers := mismatchedPeers("node-1", "4789", nodes)
+	if len(peers) != 1 || peers[0].Name != "node-3" {
+		t.Errorf("mismatchedPeers() = %v, want only node-3", peers)
+	}
+}
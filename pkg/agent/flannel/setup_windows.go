@@ -54,6 +54,6 @@ const (
 	vxlanBackend = `{
 	"Type": "vxlan",
 	"VNI": 4096,
-	"Port": 4789
+	"Port": %Port%
 }`
 )
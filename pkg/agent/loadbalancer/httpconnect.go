@@ -0,0 +1,322 @@
+package loadbalancer
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-ntlmssp"
+	krbclient "github.com/jcmturner/gokrb5/v8/client"
+	krbconfig "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
+)
+
+// maxAuthRounds is the maximum number of authentication round trips made on a proxy connection.
+// NTLM requires two: one to send the negotiate message, and one to respond to the challenge.
+const maxAuthRounds = 2
+
+// proxyAuthenticator generates the tokens sent in the Proxy-Authorization header for an authentication
+// scheme. A new authenticator is used for each connection, as connection-based schemes such as NTLM
+// carry state between round trips.
+type proxyAuthenticator interface {
+	// Scheme returns the name of the scheme, as used in the Proxy-Authenticate and Proxy-Authorization headers.
+	Scheme() string
+	// Next returns the token to send in response to the given challenge, which is empty if the proxy did
+	// not include one.
+	Next(challenge []byte) ([]byte, error)
+}
+
+// explicit interface check
+var _ proxy.ContextDialer = &httpConnectDialer{}
+
+// httpConnectDialer tunnels connections through an HTTP proxy using the CONNECT method. Credentials are only
+// sent once the proxy has responded with 407 Proxy Authentication Required, and only for the configured scheme.
+type httpConnectDialer struct {
+	proxyURL *url.URL
+	forward  *net.Dialer
+	newAuth  func() (proxyAuthenticator, error)
+}
+
+// newHTTPConnectDialer returns a dialer that tunnels connections through the given HTTP or HTTPS proxy,
+// authenticating with the credentials in the proxy URL, if any, using the given scheme.
+func newHTTPConnectDialer(proxyURL *url.URL, forward *net.Dialer, auth string) (*httpConnectDialer, error) {
+	d := &httpConnectDialer{proxyURL: proxyURL, forward: forward}
+	username := proxyURL.User.Username()
+	password, _ := proxyURL.User.Password()
+	switch auth {
+	case ProxyAuthBasic, "":
+		if proxyURL.User != nil {
+			d.newAuth = func() (proxyAuthenticator, error) { return &basicAuth{username: username, password: password}, nil }
+		}
+	case ProxyAuthNTLM:
+		if proxyURL.User == nil {
+			return nil, fmt.Errorf("proxy authentication scheme %s requires credentials", auth)
+		}
+		d.newAuth = func() (proxyAuthenticator, error) { return &ntlmAuth{username: username, password: password}, nil }
+	case ProxyAuthKerberos:
+		cl, err := kerberosClient(username, password)
+		if err != nil {
+			return nil, err
+		}
+		spn := "HTTP/" + proxyURL.Hostname()
+		d.newAuth = func() (proxyAuthenticator, error) { return &negotiateAuth{client: cl, spn: spn}, nil }
+	default:
+		return nil, fmt.Errorf("unsupported proxy authentication scheme: %s", auth)
+	}
+	return d, nil
+}
+
+func (d *httpConnectDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the proxy and requests a tunnel to the given address, authenticating if the proxy
+// requires it.
+func (d *httpConnectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if network != "tcp" {
+		return nil, fmt.Errorf("network type %s unsupported by HTTP proxy", network)
+	}
+	conn, err := d.dialProxy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+	br := bufio.NewReader(conn)
+	resp, err := roundTrip(conn, br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	var auth proxyAuthenticator
+	for round := 0; resp.StatusCode == http.StatusProxyAuthRequired && d.newAuth != nil && round < maxAuthRounds; round++ {
+		if auth == nil {
+			if auth, err = d.newAuth(); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+		challenge, ok := findChallenge(resp.Header.Values("Proxy-Authenticate"), auth.Scheme())
+		if !ok {
+			conn.Close()
+			return nil, fmt.Errorf("proxy does not accept %s authentication: %s", auth.Scheme(), strings.Join(resp.Header.Values("Proxy-Authenticate"), ", "))
+		}
+		token, err := auth.Next(challenge)
+		if err != nil {
+			conn.Close()
+			return nil, errors.Wrapf(err, "failed to generate %s proxy authentication", auth.Scheme())
+		}
+		// The response must be read in full before the connection can be reused; if the proxy closes the
+		// connection, a new one is opened. Connection-based schemes such as NTLM will then start over.
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.Close {
+			conn.Close()
+			if conn, err = d.dialProxy(ctx); err != nil {
+				return nil, err
+			}
+			br = bufio.NewReader(conn)
+		}
+		req.Header.Set("Proxy-Authorization", auth.Scheme()+" "+base64.StdEncoding.EncodeToString(token))
+		if resp, err = roundTrip(conn, br, req); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused connection to %s: %s", address, resp.Status)
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: br}, nil
+	}
+	return conn, nil
+}
+
+// dialProxy opens a connection to the proxy, using TLS for HTTPS proxies.
+func (d *httpConnectDialer) dialProxy(ctx context.Context) (net.Conn, error) {
+	address := d.proxyURL.Host
+	if d.proxyURL.Port() == "" {
+		port := "8080"
+		if d.proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		address = net.JoinHostPort(d.proxyURL.Hostname(), port)
+	}
+	conn, err := d.forward.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to proxy %s", address)
+	}
+	if d.proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, errors.Wrapf(err, "failed to connect to proxy %s", address)
+		}
+		return tlsConn, nil
+	}
+	return conn, nil
+}
+
+// roundTrip writes the request to the connection and reads the response.
+func roundTrip(conn net.Conn, br *bufio.Reader, req *http.Request) (*http.Response, error) {
+	if err := req.Write(conn); err != nil {
+		return nil, errors.Wrap(err, "failed to write proxy request")
+	}
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read proxy response")
+	}
+	return resp, nil
+}
+
+// findChallenge returns the challenge token for the given scheme from the Proxy-Authenticate headers. True
+// is returned if the scheme was offered, even if no token was included.
+func findChallenge(headers []string, scheme string) ([]byte, bool) {
+	for _, header := range headers {
+		name, token, _ := strings.Cut(strings.TrimSpace(header), " ")
+		if !strings.EqualFold(name, scheme) {
+			continue
+		}
+		// Basic challenges carry a realm parameter rather than a token
+		if b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(token)); err == nil {
+			return b, true
+		}
+		return nil, true
+	}
+	return nil, false
+}
+
+// bufferedConn is a connection with data that has already been read into a buffer.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// basicAuth authenticates with a username and password sent in the clear.
+type basicAuth struct {
+	username string
+	password string
+}
+
+func (b *basicAuth) Scheme() string {
+	return "Basic"
+}
+
+func (b *basicAuth) Next(challenge []byte) ([]byte, error) {
+	return []byte(b.username + ":" + b.password), nil
+}
+
+// ntlmAuth authenticates using NTLMv2. Usernames may be given in the form DOMAIN\user, or user@domain.
+type ntlmAuth struct {
+	username   string
+	password   string
+	negotiated bool
+}
+
+func (n *ntlmAuth) Scheme() string {
+	return "NTLM"
+}
+
+// Next returns the negotiate message if the proxy has not yet sent a challenge, and the authenticate
+// message in response to the challenge.
+func (n *ntlmAuth) Next(challenge []byte) ([]byte, error) {
+	user, domain, domainNeeded := ntlmssp.GetDomain(n.username)
+	if len(challenge) == 0 {
+		if n.negotiated {
+			return nil, errors.New("proxy did not send an NTLM challenge")
+		}
+		n.negotiated = true
+		return ntlmssp.NewNegotiateMessage(domain, "")
+	}
+	return ntlmssp.ProcessChallenge(challenge, user, n.password, domainNeeded)
+}
+
+// negotiateAuth authenticates using Kerberos, through SPNEGO.
+type negotiateAuth struct {
+	client *krbclient.Client
+	spn    string
+}
+
+func (n *negotiateAuth) Scheme() string {
+	return "Negotiate"
+}
+
+func (n *negotiateAuth) Next(challenge []byte) ([]byte, error) {
+	if len(challenge) != 0 {
+		return nil, errors.New("proxy rejected Kerberos authentication")
+	}
+	token, err := spnego.SPNEGOClient(n.client, n.spn).InitSecContext()
+	if err != nil {
+		return nil, err
+	}
+	return token.Marshal()
+}
+
+// kerberosClient returns a Kerberos client configured from the system krb5.conf, or the file set in
+// KRB5_CONFIG. If a password is given, the client logs in as the given user, which may include the realm
+// as user@REALM; otherwise the credentials cache set in KRB5CCNAME, or the default cache for the current
+// user, is used.
+func kerberosClient(username, password string) (*krbclient.Client, error) {
+	configPath := os.Getenv("KRB5_CONFIG")
+	if configPath == "" {
+		configPath = "/etc/krb5.conf"
+	}
+	cfg, err := krbconfig.Load(configPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load Kerberos configuration from %s", configPath)
+	}
+
+	if password != "" {
+		user, realm, ok := strings.Cut(username, "@")
+		if !ok {
+			realm = cfg.LibDefaults.DefaultRealm
+		}
+		cl := krbclient.NewWithPassword(user, realm, password, cfg, krbclient.DisablePAFXFAST(true))
+		if err := cl.Login(); err != nil {
+			return nil, errors.Wrapf(err, "failed to log in to Kerberos realm %s as %s", realm, user)
+		}
+		return cl, nil
+	}
+
+	ccachePath := strings.TrimPrefix(os.Getenv("KRB5CCNAME"), "FILE:")
+	if ccachePath == "" {
+		ccachePath = fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid())
+	}
+	ccache, err := credentials.LoadCCache(ccachePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load Kerberos credentials cache from %s", ccachePath)
+	}
+	cl, err := krbclient.NewFromCCache(ccache, cfg, krbclient.DisablePAFXFAST(true))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load Kerberos credentials from %s", ccachePath)
+	}
+	return cl, nil
+}
@@ -0,0 +1,173 @@
+package loadbalancer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProxy accepts CONNECT requests, and responds using the given handler, which is passed the
+// Proxy-Authorization header of each request on a connection, along with the number of requests
+// already received on that connection. Authorization headers are recorded in the order received.
+type fakeProxy struct {
+	listener net.Listener
+	handler  func(auth string, round int) (status int, authenticate string)
+
+	mu       sync.Mutex
+	received []string
+}
+
+func newFakeProxy(t *testing.T, handler func(auth string, round int) (int, string)) *fakeProxy {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProxy{listener: listener, handler: handler}
+	go p.serve()
+	t.Cleanup(func() { listener.Close() })
+	return p
+}
+
+func (p *fakeProxy) serve() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			br := bufio.NewReader(conn)
+			for round := 0; ; round++ {
+				req, err := http.ReadRequest(br)
+				if err != nil {
+					return
+				}
+				auth := req.Header.Get("Proxy-Authorization")
+				p.mu.Lock()
+				p.received = append(p.received, auth)
+				p.mu.Unlock()
+				status, authenticate := p.handler(auth, round)
+				resp := fmt.Sprintf("HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+				if authenticate != "" {
+					resp += "Proxy-Authenticate: " + authenticate + "\r\n"
+				}
+				if status != http.StatusOK {
+					resp += "Content-Length: 0\r\n"
+				}
+				conn.Write([]byte(resp + "\r\n"))
+				if status == http.StatusOK {
+					conn.Write([]byte("hello"))
+					time.Sleep(100 * time.Millisecond)
+					return
+				}
+			}
+		}()
+	}
+}
+
+// authorizations returns the Proxy-Authorization headers received, and resets the list.
+func (p *fakeProxy) authorizations() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	received := p.received
+	p.received = nil
+	return received
+}
+
+func (p *fakeProxy) dial(t *testing.T, user *url.Userinfo, auth string) error {
+	d, err := newHTTPConnectDialer(&url.URL{Scheme: "http", Host: p.listener.Addr().String(), User: user}, &net.Dialer{}, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := d.Dial("tcp", "server:6443")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	b := make([]byte, 5)
+	if _, err := conn.Read(b); err != nil || string(b) != "hello" {
+		t.Errorf("expected tunneled data, got %q, %v", b, err)
+	}
+	return nil
+}
+
+func Test_UnitHTTPConnectBasic(t *testing.T) {
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	p := newFakeProxy(t, func(auth string, round int) (int, string) {
+		if auth == want {
+			return http.StatusOK, ""
+		}
+		return http.StatusProxyAuthRequired, `Basic realm="proxy"`
+	})
+
+	if err := p.dial(t, url.UserPassword("user", "pass"), ProxyAuthBasic); err != nil {
+		t.Fatalf("expected connection to succeed: %v", err)
+	}
+	if received := p.authorizations(); len(received) != 2 || received[0] != "" || received[1] != want {
+		t.Errorf("expected credentials to be sent only after a 407, got %q", received)
+	}
+}
+
+func Test_UnitHTTPConnectNoAuthRequired(t *testing.T) {
+	p := newFakeProxy(t, func(auth string, round int) (int, string) {
+		return http.StatusOK, ""
+	})
+
+	if err := p.dial(t, url.UserPassword("user", "pass"), ProxyAuthNTLM); err != nil {
+		t.Fatalf("expected connection to succeed: %v", err)
+	}
+	if received := p.authorizations(); len(received) != 1 || received[0] != "" {
+		t.Errorf("expected no credentials to be sent, got %q", received)
+	}
+}
+
+func Test_UnitHTTPConnectNTLM(t *testing.T) {
+	// minimal NTLM challenge message with no target info
+	challenge := make([]byte, 48)
+	copy(challenge, "NTLMSSP\x00")
+	binary.LittleEndian.PutUint32(challenge[8:], 2)
+	binary.LittleEndian.PutUint32(challenge[20:], 0x00088201)
+	copy(challenge[24:], "\x01\x23\x45\x67\x89\xab\xcd\xef")
+	binary.LittleEndian.PutUint32(challenge[44:], 48)
+
+	p := newFakeProxy(t, func(auth string, round int) (int, string) {
+		token, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "NTLM "))
+		switch {
+		case round == 0:
+			return http.StatusProxyAuthRequired, "NTLM"
+		case round == 1 && len(token) > 12 && token[8] == 1:
+			return http.StatusProxyAuthRequired, "NTLM " + base64.StdEncoding.EncodeToString(challenge)
+		case round == 2 && len(token) > 12 && token[8] == 3:
+			return http.StatusOK, ""
+		}
+		return http.StatusForbidden, ""
+	})
+
+	if err := p.dial(t, url.UserPassword(`DOMAIN\user`, "pass"), ProxyAuthNTLM); err != nil {
+		t.Fatalf("expected NTLM handshake to succeed: %v", err)
+	}
+	if received := p.authorizations(); len(received) != 3 || received[0] != "" {
+		t.Errorf("expected credentials to be sent only after a 407, got %q", received)
+	}
+}
+
+func Test_UnitHTTPConnectRejected(t *testing.T) {
+	p := newFakeProxy(t, func(auth string, round int) (int, string) {
+		return http.StatusProxyAuthRequired, `Basic realm="proxy"`
+	})
+
+	if err := p.dial(t, nil, ProxyAuthBasic); err == nil {
+		t.Errorf("expected connection without credentials to fail")
+	}
+	if err := p.dial(t, url.UserPassword("user", "pass"), ProxyAuthNTLM); err == nil {
+		t.Errorf("expected NTLM to fail against a proxy that only offers Basic")
+	}
+}
@@ -1,6 +1,7 @@
 package loadbalancer
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/url"
@@ -16,6 +17,13 @@ import (
 	"golang.org/x/net/proxy"
 )
 
+// Supported authentication schemes for HTTP proxies
+const (
+	ProxyAuthBasic    = "basic"
+	ProxyAuthNTLM     = "ntlm"
+	ProxyAuthKerberos = "kerberos"
+)
+
 var defaultDialer proxy.Dialer = &net.Dialer{
 	Timeout:   10 * time.Second,
 	KeepAlive: 30 * time.Second,
@@ -25,6 +33,11 @@ var defaultDialer proxy.Dialer = &net.Dialer{
 // if the agent has been configured to allow use of a HTTP proxy, and the environment has been configured
 // to indicate use of a HTTP proxy for the server URL.
 func SetHTTPProxy(address string) error {
+	// An explicitly configured supervisor proxy takes precedence over the environment
+	if _, ok := defaultDialer.(*net.Dialer); !ok {
+		return nil
+	}
+
 	// Check if env variable for proxy is set
 	if useProxy, _ := strconv.ParseBool(os.Getenv(version.ProgramUpper + "_AGENT_HTTP_PROXY_ALLOWED")); !useProxy || address == "" {
 		return nil
@@ -46,21 +59,64 @@ func SetHTTPProxy(address string) error {
 		return nil
 	}
 
-	dialer, err := proxyDialer(proxyURL, defaultDialer)
+	dialer, err := proxyDialer(proxyURL, defaultDialer, ProxyAuthBasic)
 	if err != nil {
 		return errors.Wrapf(err, "failed to create proxy dialer for %s", proxyURL)
 	}
 
 	defaultDialer = dialer
-	logrus.Debugf("Using proxy %s for agent connection to %s", proxyURL, serverURL)
+	logrus.Debugf("Using proxy %s for agent connection to %s", proxyURL.Redacted(), serverURL)
+	return nil
+}
+
+// SetSupervisorProxy configures a dialer that routes all loadbalancer connections through the given proxy,
+// regardless of the proxy environment variables. Credentials for HTTP proxies are taken from the proxy URL,
+// and used with the requested authentication scheme once the proxy requests authentication.
+func SetSupervisorProxy(address, auth string) error {
+	proxyURL, err := url.Parse(address)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse supervisor proxy address")
+	}
+
+	var dialer proxy.Dialer
+	if proxyURL.Scheme == "http" || proxyURL.Scheme == "https" {
+		dialer, err = newHTTPConnectDialer(proxyURL, defaultDialer.(*net.Dialer), auth)
+	} else {
+		dialer, err = proxyDialer(proxyURL, defaultDialer, auth)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to create proxy dialer for %s", proxyURL.Redacted())
+	}
+
+	defaultDialer = dialer
+	logrus.Infof("Using proxy %s for agent connections to servers", proxyURL.Redacted())
 	return nil
 }
 
+// DialContext dials the given address using the dialer used for loadbalancer connections,
+// so that the connection is routed through the supervisor proxy, if one is configured.
+func DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if dialer, ok := defaultDialer.(proxy.ContextDialer); ok {
+		return dialer.DialContext(ctx, network, address)
+	}
+	return defaultDialer.Dial(network, address)
+}
+
 // proxyDialer creates a new proxy.Dialer that routes connections through the specified proxy.
-func proxyDialer(proxyURL *url.URL, forward proxy.Dialer) (proxy.Dialer, error) {
+func proxyDialer(proxyURL *url.URL, forward proxy.Dialer, auth string) (proxy.Dialer, error) {
 	if proxyURL.Scheme == "http" || proxyURL.Scheme == "https" {
 		// Create a new HTTP proxy dialer
-		httpProxyDialer := http_dialer.New(proxyURL, http_dialer.WithConnectionTimeout(10*time.Second), http_dialer.WithDialer(forward.(*net.Dialer)))
+		var proxyAuth http_dialer.ProxyAuthorization
+		if proxyURL.User != nil {
+			password, _ := proxyURL.User.Password()
+			switch auth {
+			case ProxyAuthBasic, "":
+				proxyAuth = http_dialer.AuthBasic(proxyURL.User.Username(), password)
+			default:
+				return nil, fmt.Errorf("unsupported proxy authentication scheme: %s", auth)
+			}
+		}
+		httpProxyDialer := http_dialer.New(proxyURL, http_dialer.WithConnectionTimeout(10*time.Second), http_dialer.WithDialer(forward.(*net.Dialer)), http_dialer.WithProxyAuth(proxyAuth))
 		return httpProxyDialer, nil
 	} else if proxyURL.Scheme == "socks5" {
 		// For SOCKS5 proxies, use the proxy package's FromURL
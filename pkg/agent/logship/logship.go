@@ -0,0 +1,285 @@
+// Package logship forwards container logs written by the kubelet to a remote syslog, Loki, or
+// OTLP endpoint, so that nodes without a logging agent can still have their logs collected.
+package logship
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// podLogsDir is the directory that the kubelet writes container logs to, in the form
+	// <namespace>_<pod>_<uid>/<container>/<restart count>.log
+	podLogsDir = "/var/log/pods"
+	// pollInterval is the interval at which container logs are checked for new lines.
+	pollInterval = 5 * time.Second
+	// maxReadBytes is the maximum number of bytes read from a single log file in each poll, so
+	// that a busy container does not delay shipping of logs from other containers.
+	maxReadBytes = 256 * 1024
+)
+
+// Entry is a single container log line.
+type Entry struct {
+	Timestamp time.Time
+	Namespace string
+	Pod       string
+	Container string
+	Stream    string
+	Line      string
+}
+
+// shipper tails container log files, and sends new lines to a sink. The offset of each file is
+// only advanced once its lines have been sent, so that lines are retried if the endpoint is unavailable.
+type shipper struct {
+	dir           string
+	positionsFile string
+	sink          sink
+	// positions is the offset up to which each log file has been shipped
+	positions map[string]int64
+	// files holds the file info for each log file as of the last poll, to detect rotation
+	files map[string]os.FileInfo
+	// partial holds the content of partial lines, that are completed by a later log entry
+	partial map[string]string
+	// initialized is false until positions have been loaded or created; when there are no saved
+	// positions, existing logs are skipped so that the endpoint is not flooded with old lines.
+	initialized bool
+}
+
+// Run starts forwarding container logs to the configured endpoint. Nothing is done if log shipping
+// is not configured.
+func Run(ctx context.Context, cfg *config.Node) error {
+	if cfg.LogShipping.Endpoint == "" {
+		return nil
+	}
+	s, err := newSink(cfg.LogShipping.Type, cfg.LogShipping.Endpoint, cfg.AgentConfig.NodeName)
+	if err != nil {
+		return err
+	}
+
+	shipper := &shipper{
+		dir:           podLogsDir,
+		positionsFile: cfg.LogShipping.PositionsFile,
+		sink:          s,
+		positions:     map[string]int64{},
+		files:         map[string]os.FileInfo{},
+		partial:       map[string]string{},
+	}
+	if err := shipper.loadPositions(); err != nil {
+		logrus.Warnf("Failed to load log shipping positions from %s: %v", shipper.positionsFile, err)
+	}
+
+	logrus.Infof("Forwarding container logs to %s", s)
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		// keep polling while files have more data than can be read in a single poll
+		for {
+			more, err := shipper.poll(ctx)
+			if err != nil {
+				logrus.Warnf("Failed to forward container logs: %v", err)
+				return
+			}
+			if !more || ctx.Err() != nil {
+				return
+			}
+		}
+	}, pollInterval)
+
+	return nil
+}
+
+// poll reads new lines from all container log files, and sends them to the sink. True is
+// returned if any file has more data available than was read.
+func (s *shipper) poll(ctx context.Context) (bool, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*", "*", "*.log"))
+	if err != nil {
+		return false, err
+	}
+	sort.Strings(paths)
+
+	entries := []Entry{}
+	positions := make(map[string]int64, len(paths))
+	files := make(map[string]os.FileInfo, len(paths))
+	partial := make(map[string]string, len(s.partial))
+	more := false
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		files[path] = info
+
+		offset, ok := s.positions[path]
+		switch {
+		case !s.initialized:
+			offset = info.Size()
+		case !ok:
+			offset = 0
+		case info.Size() < offset:
+			// the file has been truncated
+			offset = 0
+		case s.files[path] != nil && !os.SameFile(s.files[path], info):
+			// the file has been rotated and replaced
+			offset = 0
+		}
+
+		read, fileEntries, pending, hasMore, err := readLog(path, offset, s.partial[path])
+		if err != nil {
+			logrus.Debugf("Failed to read container log %s: %v", path, err)
+		}
+		positions[path] = offset + read
+		if pending != "" {
+			partial[path] = pending
+		}
+		entries = append(entries, fileEntries...)
+		more = more || hasMore
+	}
+
+	if len(entries) > 0 {
+		if err := s.sink.send(ctx, entries); err != nil {
+			return false, err
+		}
+	}
+
+	s.positions = positions
+	s.files = files
+	s.partial = partial
+	s.initialized = true
+	if err := s.savePositions(); err != nil {
+		logrus.Debugf("Failed to save log shipping positions to %s: %v", s.positionsFile, err)
+	}
+	return more, nil
+}
+
+// readLog reads complete lines from the log file starting at offset, and returns the number of
+// bytes consumed, the parsed entries, any partial line that has not yet been completed, and
+// whether there is more data available than was read.
+func readLog(path string, offset int64, partial string) (int64, []Entry, string, bool, error) {
+	namespace, pod, container, ok := parseLogPath(path)
+	if !ok {
+		return 0, nil, partial, false, errors.New("unrecognized log path")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, nil, partial, false, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, nil, partial, false, err
+	}
+	buf := make([]byte, maxReadBytes+1)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return 0, nil, partial, false, err
+	}
+	hasMore := n > maxReadBytes
+	buf = buf[:min(n, maxReadBytes)]
+
+	// only consume complete lines; the remainder is read again on the next poll
+	end := bytes.LastIndexByte(buf, '\n')
+	if end < 0 {
+		if hasMore {
+			// a single line longer than the read limit; skip it
+			return int64(len(buf)), nil, "", hasMore, nil
+		}
+		return 0, nil, partial, false, nil
+	}
+	buf = buf[:end+1]
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxReadBytes)
+	for scanner.Scan() {
+		timestamp, stream, isPartial, line, err := parseCRILine(scanner.Text())
+		if err != nil {
+			continue
+		}
+		if isPartial {
+			partial += line
+			continue
+		}
+		entries = append(entries, Entry{
+			Timestamp: timestamp,
+			Namespace: namespace,
+			Pod:       pod,
+			Container: container,
+			Stream:    stream,
+			Line:      partial + line,
+		})
+		partial = ""
+	}
+	return int64(len(buf)), entries, partial, hasMore, scanner.Err()
+}
+
+// parseLogPath returns the namespace, pod, and container for a log file path of the form
+// <dir>/<namespace>_<pod>_<uid>/<container>/<restart count>.log
+func parseLogPath(path string) (string, string, string, bool) {
+	containerDir := filepath.Dir(path)
+	podDir := filepath.Base(filepath.Dir(containerDir))
+	parts := strings.Split(podDir, "_")
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], filepath.Base(containerDir), true
+}
+
+// parseCRILine parses a log line in the CRI logging format, for example:
+// 2016-10-06T00:17:09.669794202Z stdout F log content
+func parseCRILine(line string) (time.Time, string, bool, string, error) {
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) < 3 {
+		return time.Time{}, "", false, "", errors.New("invalid CRI log line")
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return time.Time{}, "", false, "", errors.Wrap(err, "invalid CRI log timestamp")
+	}
+	content := ""
+	if len(fields) == 4 {
+		content = fields[3]
+	}
+	return timestamp, fields[1], fields[2] == "P", content, nil
+}
+
+// loadPositions reads the saved log file offsets. If there are no saved positions, logs that
+// already exist at startup are skipped.
+func (s *shipper) loadPositions() error {
+	b, err := os.ReadFile(s.positionsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := json.Unmarshal(b, &s.positions); err != nil {
+		return err
+	}
+	s.initialized = true
+	return nil
+}
+
+func (s *shipper) savePositions() error {
+	if s.positionsFile == "" {
+		return nil
+	}
+	b, err := json.Marshal(s.positions)
+	if err != nil {
+		return err
+	}
+	return util.AtomicWrite(s.positionsFile, b, 0600)
+}
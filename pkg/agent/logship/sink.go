@@ -0,0 +1,286 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+)
+
+// Supported log shipping types
+const (
+	TypeSyslog = "syslog"
+	TypeLoki   = "loki"
+	TypeOTLP   = "otlp"
+)
+
+const sendTimeout = 30 * time.Second
+
+// sink sends container log entries to a remote endpoint.
+type sink interface {
+	send(ctx context.Context, entries []Entry) error
+	String() string
+}
+
+// newSink returns a sink for the given type and endpoint. Entries are labeled with the node name.
+func newSink(shipType, endpoint, nodeName string) (sink, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, errors.Errorf("log shipping endpoint %s must be a URL with a scheme and host", endpoint)
+	}
+	switch shipType {
+	case TypeSyslog:
+		if u.Scheme != "udp" && u.Scheme != "tcp" {
+			return nil, errors.Errorf("syslog log shipping endpoint must use the udp or tcp scheme, not %s", u.Scheme)
+		}
+		return &syslogSink{network: u.Scheme, address: u.Host, nodeName: nodeName}, nil
+	case TypeLoki:
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/loki/api/v1/push"
+		}
+		return newHTTPSink(shipType, u, nodeName, lokiPayload)
+	case TypeOTLP:
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/v1/logs"
+		}
+		return newHTTPSink(shipType, u, nodeName, otlpPayload)
+	}
+	return nil, errors.Errorf("unsupported log shipping type %q; must be one of %s, %s, %s", shipType, TypeSyslog, TypeLoki, TypeOTLP)
+}
+
+// syslogSink sends entries as RFC 5424 syslog messages. The namespace and pod are used as the
+// app name, and the container as the message ID. Messages sent over TCP use octet-counting framing.
+type syslogSink struct {
+	network  string
+	address  string
+	nodeName string
+}
+
+func (s *syslogSink) String() string {
+	return TypeSyslog + " endpoint " + s.network + "://" + s.address
+}
+
+func (s *syslogSink) send(ctx context.Context, entries []Entry) error {
+	dialer := &net.Dialer{Timeout: sendTimeout}
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(sendTimeout))
+
+	for _, entry := range entries {
+		message := syslogMessage(s.nodeName, entry)
+		if s.network == "tcp" {
+			message = strconv.Itoa(len(message)) + " " + message
+		}
+		if _, err := io.WriteString(conn, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syslogMessage formats the entry as an RFC 5424 syslog message, with the user-level facility.
+// Lines written to stderr are sent with error severity, all others with informational severity.
+func syslogMessage(nodeName string, entry Entry) string {
+	priority := 1*8 + 6
+	if entry.Stream == "stderr" {
+		priority = 1*8 + 3
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s - %s - %s",
+		priority,
+		entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		syslogField(nodeName, 255),
+		syslogField(entry.Namespace+"/"+entry.Pod, 48),
+		syslogField(entry.Container, 32),
+		entry.Line)
+}
+
+// syslogField truncates the value to the maximum length of its header field, and replaces empty
+// values with the nil value.
+func syslogField(value string, maxLen int) string {
+	if value == "" {
+		return "-"
+	}
+	if len(value) > maxLen {
+		return value[:maxLen]
+	}
+	return value
+}
+
+// httpSink sends entries as a JSON payload to an HTTP endpoint. Credentials included in the
+// endpoint URL are sent using basic auth.
+type httpSink struct {
+	shipType string
+	url      *url.URL
+	nodeName string
+	client   *http.Client
+	payload  func(nodeName string, entries []Entry) any
+}
+
+func newHTTPSink(shipType string, u *url.URL, nodeName string, payload func(string, []Entry) any) (*httpSink, error) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("%s log shipping endpoint must use the http or https scheme, not %s", shipType, u.Scheme)
+	}
+	return &httpSink{
+		shipType: shipType,
+		url:      u,
+		nodeName: nodeName,
+		client:   &http.Client{Timeout: sendTimeout},
+		payload:  payload,
+	}, nil
+}
+
+func (s *httpSink) String() string {
+	return s.shipType + " endpoint " + s.url.Redacted()
+}
+
+func (s *httpSink) send(ctx context.Context, entries []Entry) error {
+	b, err := json.Marshal(s.payload(s.nodeName, entries))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url.String(), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("%s endpoint returned %s: %s", s.shipType, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// streamKey identifies the container and stream that an entry was written by.
+type streamKey struct {
+	namespace string
+	pod       string
+	container string
+	stream    string
+}
+
+// groupEntries groups entries by container and stream, preserving the order of entries within
+// each group. Groups are returned in a consistent order.
+func groupEntries(entries []Entry) ([]streamKey, map[streamKey][]Entry) {
+	groups := map[streamKey][]Entry{}
+	keys := []streamKey{}
+	for _, entry := range entries {
+		key := streamKey{namespace: entry.Namespace, pod: entry.Pod, container: entry.Container, stream: entry.Stream}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], entry)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	return keys, groups
+}
+
+// lokiPayload returns the entries in the format used by the Loki push API, with a stream for
+// each container and output stream.
+func lokiPayload(nodeName string, entries []Entry) any {
+	type lokiStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	keys, groups := groupEntries(entries)
+	streams := make([]lokiStream, 0, len(keys))
+	for _, key := range keys {
+		stream := lokiStream{
+			Stream: map[string]string{
+				"job":       version.Program,
+				"node":      nodeName,
+				"namespace": key.namespace,
+				"pod":       key.pod,
+				"container": key.container,
+				"stream":    key.stream,
+			},
+		}
+		for _, entry := range groups[key] {
+			stream.Values = append(stream.Values, [2]string{strconv.FormatInt(entry.Timestamp.UnixNano(), 10), entry.Line})
+		}
+		streams = append(streams, stream)
+	}
+	return map[string]any{"streams": streams}
+}
+
+// otlpPayload returns the entries in the OTLP/HTTP JSON logs format, with a resource for each
+// container, labeled using the OpenTelemetry Kubernetes semantic conventions.
+func otlpPayload(nodeName string, entries []Entry) any {
+	type otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	type otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	type otlpLogRecord struct {
+		TimeUnixNano   string          `json:"timeUnixNano"`
+		SeverityNumber int             `json:"severityNumber"`
+		SeverityText   string          `json:"severityText"`
+		Body           otlpValue       `json:"body"`
+		Attributes     []otlpAttribute `json:"attributes"`
+	}
+	type otlpScopeLogs struct {
+		Scope      map[string]string `json:"scope"`
+		LogRecords []otlpLogRecord   `json:"logRecords"`
+	}
+	type otlpResourceLogs struct {
+		Resource  map[string][]otlpAttribute `json:"resource"`
+		ScopeLogs []otlpScopeLogs            `json:"scopeLogs"`
+	}
+	attr := func(key, value string) otlpAttribute {
+		return otlpAttribute{Key: key, Value: otlpValue{StringValue: value}}
+	}
+
+	keys, groups := groupEntries(entries)
+	resourceLogs := make([]otlpResourceLogs, 0, len(keys))
+	for _, key := range keys {
+		records := make([]otlpLogRecord, 0, len(groups[key]))
+		for _, entry := range groups[key] {
+			// severity numbers are INFO (9) and ERROR (17) from the OpenTelemetry log data model
+			record := otlpLogRecord{
+				TimeUnixNano:   strconv.FormatInt(entry.Timestamp.UnixNano(), 10),
+				SeverityNumber: 9,
+				SeverityText:   "INFO",
+				Body:           otlpValue{StringValue: entry.Line},
+				Attributes:     []otlpAttribute{attr("log.iostream", entry.Stream)},
+			}
+			if entry.Stream == "stderr" {
+				record.SeverityNumber = 17
+				record.SeverityText = "ERROR"
+			}
+			records = append(records, record)
+		}
+		resourceLogs = append(resourceLogs, otlpResourceLogs{
+			Resource: map[string][]otlpAttribute{
+				"attributes": {
+					attr("k8s.node.name", nodeName),
+					attr("k8s.namespace.name", key.namespace),
+					attr("k8s.pod.name", key.pod),
+					attr("k8s.container.name", key.container),
+				},
+			},
+			ScopeLogs: []otlpScopeLogs{{Scope: map[string]string{"name": version.Program}, LogRecords: records}},
+		})
+	}
+	return map[string]any{"resourceLogs": resourceLogs}
+}
@@ -0,0 +1,63 @@
+package oomguard
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SupervisorScoreAdj is the oom_score_adj applied to the supervisor process. This is the same value as
+	// the kubelet's default --oom-score-adj, which the kubelet applies to its own process once it starts; as
+	// the kubelet normally runs within the supervisor process, setting it here only takes effect earlier,
+	// while containerd is started, and when the kubelet is run as a separate process. It is lower than the
+	// score the kubelet assigns to the containers of any pod, so that workloads are killed before the supervisor.
+	SupervisorScoreAdj = -999
+	// checkInterval is the interval at which the supervisor's memory use is checked.
+	checkInterval = 10 * time.Second
+	// softLimit is the fraction of the supervisor memory limit that the Go runtime's soft memory limit is set
+	// to, so that the garbage collector runs more often, and returns memory to the OS, before the limit is hit.
+	softLimit = 0.75
+	// highWatermark is the fraction of the supervisor memory limit above which memory is released.
+	highWatermark = 0.85
+)
+
+// watchdog releases memory held by the supervisor when its resident set size nears the memory limit.
+type watchdog struct {
+	limit        uint64
+	readRSS      func() uint64
+	freeOSMemory func()
+
+	pressure bool
+}
+
+// check releases memory if the supervisor's resident set size is above the high watermark, by forcing a
+// garbage collection and returning unused Go heap to the OS. Memory that is still in use cannot be
+// released; if the supervisor remains above the high watermark, a warning is logged, as it may be OOM
+// killed if its memory use continues to grow.
+func (w *watchdog) check() {
+	rss := w.readRSS()
+	if rss == 0 || float64(rss) < float64(w.limit)*highWatermark {
+		if w.pressure {
+			logrus.Infof("Supervisor memory use %s is below %d%% of limit %s", mebibytes(rss), int(highWatermark*100), mebibytes(w.limit))
+			w.pressure = false
+		}
+		return
+	}
+
+	w.freeOSMemory()
+	if rss = w.readRSS(); float64(rss) < float64(w.limit)*highWatermark {
+		logrus.Infof("Released unused memory; supervisor memory use is %s of limit %s", mebibytes(rss), mebibytes(w.limit))
+		return
+	}
+	if !w.pressure {
+		logrus.Warnf("Supervisor memory use %s remains above %d%% of limit %s after releasing unused memory; the supervisor may be OOM killed", mebibytes(rss), int(highWatermark*100), mebibytes(w.limit))
+		w.pressure = true
+	}
+}
+
+// mebibytes formats a byte count in whole mebibytes, for logging.
+func mebibytes(b uint64) string {
+	return strconv.FormatUint(b>>20, 10) + "Mi"
+}
@@ -0,0 +1,93 @@
+//go:build linux
+// +build linux
+
+package oomguard
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Run protects the supervisor from the OOM killer, if supervisor OOM protection is enabled. The supervisor's
+// oom_score_adj is lowered so that workloads are killed first; this must be done before containerd is started
+// so that it is inherited by containerd. If the supervisor has a memory limit, either from the supervisor
+// component limit or from the cgroup that it was started in, the Go runtime's soft memory limit is set below
+// it, unless GOMEMLIMIT is set, and a watchdog is started to release memory when the supervisor nears the limit.
+func Run(ctx context.Context, nodeConfig *config.Node) error {
+	if !nodeConfig.AgentConfig.SupervisorOOMProtection {
+		return nil
+	}
+
+	if err := setScoreAdj(os.Getpid(), SupervisorScoreAdj); err != nil {
+		if !nodeConfig.AgentConfig.Rootless {
+			return errors.Wrap(err, "failed to set supervisor oom_score_adj")
+		}
+		logrus.Warnf("Failed to set supervisor oom_score_adj in rootless mode: %v", err)
+	}
+
+	var limit uint64
+	if limits := nodeConfig.AgentConfig.SupervisorLimits; limits != nil && limits.Memory != nil {
+		limit = uint64(limits.Memory.Value())
+	} else if max, err := cgroupMemoryMax(); err != nil {
+		logrus.Warnf("Failed to read supervisor cgroup memory limit: %v", err)
+	} else {
+		limit = max
+	}
+	if limit == 0 {
+		logrus.Info("Supervisor memory watchdog not started; the supervisor has no memory limit")
+		return nil
+	}
+
+	if os.Getenv("GOMEMLIMIT") == "" {
+		debug.SetMemoryLimit(int64(float64(limit) * softLimit))
+	}
+	w := &watchdog{
+		limit:        limit,
+		readRSS:      func() uint64 { return util.ReadRSS(util.SelfStatusFile) },
+		freeOSMemory: debug.FreeOSMemory,
+	}
+	go wait.UntilWithContext(ctx, func(ctx context.Context) { w.check() }, checkInterval)
+	return nil
+}
+
+// setScoreAdj sets the oom_score_adj of a process.
+func setScoreAdj(pid, score int) error {
+	return os.WriteFile("/proc/"+strconv.Itoa(pid)+"/oom_score_adj", []byte(strconv.Itoa(score)), 0644)
+}
+
+// cgroupMemoryMax returns the memory limit of the cgroups v2 cgroup containing the current process, or 0 if
+// it has no limit or cgroups v2 is not in use.
+func cgroupMemoryMax() (uint64, error) {
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		path, ok := strings.CutPrefix(line, "0::")
+		if !ok {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join("/sys/fs/cgroup", path, "memory.max"))
+		if os.IsNotExist(err) {
+			return 0, nil
+		} else if err != nil {
+			return 0, err
+		}
+		if b = bytes.TrimSpace(b); string(b) == "max" {
+			return 0, nil
+		}
+		return strconv.ParseUint(string(b), 10, 64)
+	}
+	return 0, nil
+}
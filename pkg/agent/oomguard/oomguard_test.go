@@ -0,0 +1,57 @@
+package oomguard
+
+import "testing"
+
+func Test_UnitWatchdogCheck(t *testing.T) {
+	const mi = 1 << 20
+	tests := []struct {
+		name         string
+		rss          []uint64
+		wantFree     bool
+		wantPressure bool
+	}{
+		{
+			name: "below watermark",
+			rss:  []uint64{500 * mi},
+		},
+		{
+			name: "rss unknown",
+			rss:  []uint64{0},
+		},
+		{
+			name:     "released by freeing heap",
+			rss:      []uint64{900 * mi, 700 * mi},
+			wantFree: true,
+		},
+		{
+			name:         "still above watermark",
+			rss:          []uint64{950 * mi, 900 * mi},
+			wantFree:     true,
+			wantPressure: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var freed bool
+			rss := tt.rss
+			w := &watchdog{
+				limit: 1024 * mi,
+				readRSS: func() uint64 {
+					r := rss[0]
+					if len(rss) > 1 {
+						rss = rss[1:]
+					}
+					return r
+				},
+				freeOSMemory: func() { freed = true },
+			}
+			w.check()
+			if freed != tt.wantFree {
+				t.Errorf("check() freed = %v, want %v", freed, tt.wantFree)
+			}
+			if w.pressure != tt.wantPressure {
+				t.Errorf("check() pressure = %v, want %v", w.pressure, tt.wantPressure)
+			}
+		})
+	}
+}
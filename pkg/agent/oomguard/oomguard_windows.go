@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package oomguard
+
+import (
+	"context"
+	"errors"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+)
+
+func Run(ctx context.Context, nodeConfig *config.Node) error {
+	if nodeConfig.AgentConfig.SupervisorOOMProtection {
+		return errors.New("supervisor OOM protection is not supported on windows")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Package registryconf translates registries.yaml into the registry configuration formats used by
+// container runtimes other than the embedded containerd.
+package registryconf
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	agentutil "github.com/k3s-io/k3s/pkg/agent/util"
+	"github.com/pkg/errors"
+	"github.com/rancher/wharfie/pkg/registries"
+)
+
+// MirrorHost converts a containerd-style mirror endpoint URL into a registry host, returning true if
+// the endpoint uses plain HTTP. Endpoints with a path other than the default /v2 are rejected, as
+// other runtimes either do not support paths, or interpret them as a repository namespace.
+func MirrorHost(endpoint string) (string, bool, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", false, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", false, errors.New("no host")
+	}
+	if path := strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/v2"); path != "" {
+		return "", false, errors.New("endpoint paths are not supported")
+	}
+	return u.Host, u.Scheme == "http", nil
+}
+
+// Insecure returns true if TLS verification is disabled for the registry host.
+func Insecure(registry *registries.Registry, host string) bool {
+	config, ok := registry.Configs[host]
+	return ok && config.TLS != nil && config.TLS.InsecureSkipVerify
+}
+
+// AuthConfig translates the registry credentials from registries.yaml into a docker config.json,
+// which is also the format of containers-auth.json(5). If no credentials are configured, nil is
+// returned.
+func AuthConfig(registry *registries.Registry) ([]byte, error) {
+	if registry == nil {
+		return nil, nil
+	}
+	type auth struct {
+		Auth          string `json:"auth,omitempty"`
+		IdentityToken string `json:"identitytoken,omitempty"`
+	}
+	auths := map[string]auth{}
+	for host, config := range registry.Configs {
+		if config.Auth == nil || host == "*" {
+			continue
+		}
+		a := auth{Auth: config.Auth.Auth, IdentityToken: config.Auth.IdentityToken}
+		if config.Auth.Username != "" || config.Auth.Password != "" {
+			a.Auth = base64.StdEncoding.EncodeToString([]byte(config.Auth.Username + ":" + config.Auth.Password))
+		}
+		auths[host] = a
+	}
+	if len(auths) == 0 {
+		return nil, nil
+	}
+	return json.MarshalIndent(map[string]any{"auths": auths}, "", "  ")
+}
+
+// WriteCerts copies the TLS CA and client certificates from registries.yaml into a certs.d directory,
+// using the file names expected by both docker and containers-certs.d(5). The paths of the
+// certificate files are returned, whether or not they were changed.
+func WriteCerts(registry *registries.Registry, certsDir string) ([]string, error) {
+	files := []string{}
+	if registry == nil {
+		return files, nil
+	}
+	for host, config := range registry.Configs {
+		if config.TLS == nil || host == "*" {
+			continue
+		}
+		for src, name := range map[string]string{config.TLS.CAFile: "ca.crt", config.TLS.CertFile: "client.cert", config.TLS.KeyFile: "client.key"} {
+			if src == "" {
+				continue
+			}
+			b, err := os.ReadFile(src)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read TLS file for registry %s", host)
+			}
+			file := filepath.Join(certsDir, host, name)
+			if _, err := agentutil.WriteFileIfChanged(file, b, 0600); err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
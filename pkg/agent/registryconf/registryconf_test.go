@@ -0,0 +1,61 @@
+package registryconf
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rancher/wharfie/pkg/registries"
+)
+
+func Test_UnitMirrorHost(t *testing.T) {
+	tests := []struct {
+		endpoint  string
+		host      string
+		plainHTTP bool
+		wantErr   bool
+	}{
+		{endpoint: "https://mirror.example.com", host: "mirror.example.com"},
+		{endpoint: "https://mirror.example.com/v2/", host: "mirror.example.com"},
+		{endpoint: "mirror.example.com:5000", host: "mirror.example.com:5000"},
+		{endpoint: "http://10.0.0.1:5000", host: "10.0.0.1:5000", plainHTTP: true},
+		{endpoint: "https://mirror.example.com/path", wantErr: true},
+		{endpoint: "ftp://mirror.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		host, plainHTTP, err := MirrorHost(tt.endpoint)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("MirrorHost(%q) error = %v, wantErr %v", tt.endpoint, err, tt.wantErr)
+			continue
+		}
+		if host != tt.host || plainHTTP != tt.plainHTTP {
+			t.Errorf("MirrorHost(%q) = %q, %v, want %q, %v", tt.endpoint, host, plainHTTP, tt.host, tt.plainHTTP)
+		}
+	}
+}
+
+func Test_UnitAuthConfig(t *testing.T) {
+	if b, err := AuthConfig(&registries.Registry{}); err != nil || b != nil {
+		t.Fatalf("expected no auth config, got %q: %v", b, err)
+	}
+	b, err := AuthConfig(&registries.Registry{
+		Configs: map[string]registries.RegistryConfig{
+			"registry.example.com": {Auth: &registries.AuthConfig{Username: "user", Password: "pass"}},
+			"token.example.com":    {Auth: &registries.AuthConfig{IdentityToken: "token"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auths := struct {
+		Auths map[string]map[string]string `json:"auths"`
+	}{}
+	if err := json.Unmarshal(b, &auths); err != nil {
+		t.Fatal(err)
+	}
+	if got := auths.Auths["registry.example.com"]["auth"]; got != "dXNlcjpwYXNz" {
+		t.Errorf("unexpected auth %q", got)
+	}
+	if got := auths.Auths["token.example.com"]["identitytoken"]; got != "token" {
+		t.Errorf("unexpected identity token %q", got)
+	}
+}
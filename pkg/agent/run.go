@@ -12,11 +12,18 @@ import (
 	"time"
 
 	systemd "github.com/coreos/go-systemd/v22/daemon"
+	"github.com/k3s-io/k3s/pkg/agent/autonomy"
 	"github.com/k3s-io/k3s/pkg/agent/config"
 	"github.com/k3s-io/k3s/pkg/agent/containerd"
+	"github.com/k3s-io/k3s/pkg/agent/crio"
+	"github.com/k3s-io/k3s/pkg/agent/dnscache"
 	"github.com/k3s-io/k3s/pkg/agent/flannel"
+	"github.com/k3s-io/k3s/pkg/agent/loadbalancer"
+	"github.com/k3s-io/k3s/pkg/agent/logship"
 	"github.com/k3s-io/k3s/pkg/agent/netpol"
+	"github.com/k3s-io/k3s/pkg/agent/oomguard"
 	"github.com/k3s-io/k3s/pkg/agent/proxy"
+	"github.com/k3s-io/k3s/pkg/agent/staticpod"
 	"github.com/k3s-io/k3s/pkg/agent/syssetup"
 	"github.com/k3s-io/k3s/pkg/agent/tunnel"
 	"github.com/k3s-io/k3s/pkg/certmonitor"
@@ -27,35 +34,53 @@ import (
 	"github.com/k3s-io/k3s/pkg/daemons/agent"
 	daemonconfig "github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/daemons/executor"
+	"github.com/k3s-io/k3s/pkg/firewall"
 	"github.com/k3s-io/k3s/pkg/metrics"
 	"github.com/k3s-io/k3s/pkg/nodeconfig"
+	"github.com/k3s-io/k3s/pkg/preflight"
 	"github.com/k3s-io/k3s/pkg/profile"
 	"github.com/k3s-io/k3s/pkg/rootless"
 	"github.com/k3s-io/k3s/pkg/spegel"
+	"github.com/k3s-io/k3s/pkg/startup"
+	"github.com/k3s-io/k3s/pkg/tracing"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
 	toolswatch "k8s.io/client-go/tools/watch"
 	"k8s.io/component-base/cli/globalflag"
 	"k8s.io/component-base/logs"
+	nodeutil "k8s.io/component-helpers/node/util"
+	kubeproxyconfigv1alpha1 "k8s.io/kube-proxy/config/v1alpha1"
 	app2 "k8s.io/kubernetes/cmd/kube-proxy/app"
+	"k8s.io/kubernetes/pkg/cluster/ports"
 	kubeproxyconfig "k8s.io/kubernetes/pkg/proxy/apis/config"
+	kubeproxyconfigscheme "k8s.io/kubernetes/pkg/proxy/apis/config/scheme"
 	utilsnet "k8s.io/utils/net"
 	utilsptr "k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
 )
 
+// selinuxPolicyCondition is the node condition that reports the status of the SELinux policy.
+const selinuxPolicyCondition = v1.NodeConditionType("SELinuxPolicyReady")
+
 func run(ctx context.Context, cfg cmds.Agent, proxy proxy.Proxy) error {
-	nodeConfig, err := config.Get(ctx, cfg, proxy)
+	joinCtx, span := tracing.Start(ctx, "agent.join", attribute.String("server.url", cfg.ServerURL))
+	nodeConfig, disconnected, err := getNodeConfig(joinCtx, cfg, proxy)
+	tracing.End(span, err)
 	if err != nil {
 		return errors.Wrap(err, "failed to retrieve agent configuration")
 	}
@@ -97,16 +122,51 @@ func run(ctx context.Context, cfg cmds.Agent, proxy proxy.Proxy) error {
 		return fmt.Errorf("dual-stack or IPv6 are not supported on Windows node")
 	}
 
-	conntrackConfig, err := getConntrackConfig(nodeConfig)
+	cidrs := []string{}
+	for _, cidr := range append(nodeConfig.AgentConfig.ClusterCIDRs, nodeConfig.AgentConfig.ServiceCIDRs...) {
+		cidrs = append(cidrs, cidr.String())
+	}
+
+	// Register with the host firewall before checking for conflicts, so that the cluster CIDRs are
+	// not reported as blocked by a firewall that k3s manages.
+	if err := firewall.Register(cfg.HostFirewall, firewall.RoleAgent, firewallPorts(nodeConfig), cidrs); err != nil {
+		return errors.Wrap(err, "failed to register with host firewall")
+	}
+
+	if !cfg.Rootless {
+		if err := preflight.CheckConflicts(preflight.ConflictOptions{
+			Ports:              []int{ports.KubeletPort},
+			Services:           true,
+			CNIConfDir:         nodeConfig.AgentConfig.CNIConfDir,
+			EmbeddedContainerd: !nodeConfig.Docker && nodeConfig.ContainerRuntimeEndpoint == "",
+			ClusterCIDRs:       cidrs,
+			InstanceName:       nodeConfig.InstanceName,
+		}); err != nil {
+			return err
+		}
+	}
+
+	proxyConfig, err := getKubeProxyConfig(nodeConfig)
 	if err != nil {
-		return errors.Wrap(err, "failed to validate kube-proxy conntrack configuration")
+		return errors.Wrap(err, "failed to validate kube-proxy configuration")
 	}
-	syssetup.Configure(enableIPv6, conntrackConfig)
+	if proxyConfig.Mode == kubeproxyconfig.ProxyModeIPVS && !cfg.Rootless {
+		if err := syssetup.LoadIPVSModules(proxyConfig.IPVS.Scheduler); err != nil {
+			return err
+		}
+	}
+	sysctlProfile := map[string]string{}
+	if cfg.SysctlProfile != "" {
+		if sysctlProfile, err = syssetup.ReadProfile(cfg.SysctlProfile); err != nil {
+			return err
+		}
+	}
+	sysctls := syssetup.Configure(enableIPv6, &proxyConfig.Linux.Conntrack, sysctlProfile)
 	nodeConfig.AgentConfig.EnableIPv4 = enableIPv4
 	nodeConfig.AgentConfig.EnableIPv6 = enableIPv6
 
 	if nodeConfig.EmbeddedRegistry {
-		if nodeConfig.Docker || nodeConfig.ContainerRuntimeEndpoint != "" {
+		if nodeConfig.ContainerRuntimeEndpoint != "" {
 			return errors.New("embedded registry mirror requires embedded containerd")
 		}
 
@@ -131,6 +191,16 @@ func run(ctx context.Context, cfg cmds.Agent, proxy proxy.Proxy) error {
 		return err
 	}
 
+	if nodeConfig.AgentConfig.SupervisorLimits != nil {
+		if err := cgroups.SetSupervisorLimits(nodeConfig.AgentConfig.SupervisorLimits); err != nil {
+			return errors.Wrap(err, "failed to set supervisor resource limits")
+		}
+	}
+
+	if err := oomguard.Run(ctx, nodeConfig); err != nil {
+		return err
+	}
+
 	if err := executor.Bootstrap(ctx, nodeConfig, cfg); err != nil {
 		return err
 	}
@@ -150,6 +220,10 @@ func run(ctx context.Context, cfg cmds.Agent, proxy proxy.Proxy) error {
 		if err := executor.Docker(ctx, nodeConfig); err != nil {
 			return err
 		}
+	} else if nodeConfig.CRIO.Enabled {
+		if err := crio.Setup(ctx, nodeConfig); err != nil {
+			return errors.Wrap(err, "failed to configure CRI-O")
+		}
 	} else if nodeConfig.ContainerRuntimeEndpoint == "" {
 		if err := containerd.SetupContainerdConfig(nodeConfig); err != nil {
 			return err
@@ -158,6 +232,12 @@ func run(ctx context.Context, cfg cmds.Agent, proxy proxy.Proxy) error {
 			return err
 		}
 	}
+	if !nodeConfig.CRIO.Enabled {
+		// remove config written while CRI-O was previously used as the container runtime
+		if err := crio.Cleanup(ctx, nodeConfig); err != nil {
+			logrus.Warnf("Failed to remove CRI-O config: %v", err)
+		}
+	}
 	// the container runtime is ready to host workloads when containerd is up and the airgap
 	// images have finished loading, as that portion of startup may block for an arbitrary
 	// amount of time depending on how long it takes to import whatever the user has placed
@@ -165,13 +245,29 @@ func run(ctx context.Context, cfg cmds.Agent, proxy proxy.Proxy) error {
 	if cfg.ContainerRuntimeReady != nil {
 		close(cfg.ContainerRuntimeReady)
 	}
+	startup.Done(startup.PhaseContainerRuntime)
+
+	if err := staticpod.Start(ctx, nodeConfig.AgentConfig.StaticPodDir, nodeConfig.AgentConfig.PodManifests); err != nil {
+		return errors.Wrap(err, "failed to sync user static pod manifests")
+	}
+
+	if disconnected {
+		if err := autonomy.Restore(autonomy.Dir(cfg.DataDir), nodeConfig.AgentConfig.PodManifests); err != nil {
+			return errors.Wrap(err, "failed to restore checkpointed pods")
+		}
+	}
+
+	if err := dnscache.Run(ctx, nodeConfig); err != nil {
+		return errors.Wrap(err, "failed to start DNS fallback cache")
+	}
 
 	notifySocket := os.Getenv("NOTIFY_SOCKET")
 	os.Unsetenv("NOTIFY_SOCKET")
 
-	if err := setupTunnelAndRunAgent(ctx, nodeConfig, cfg, proxy); err != nil {
+	if err := setupTunnelAndRunAgent(ctx, nodeConfig, cfg, proxy, disconnected); err != nil {
 		return err
 	}
+	startup.Done(startup.PhaseKubelet)
 
 	if err := util.WaitForAPIServerReady(ctx, nodeConfig.AgentConfig.KubeConfigKubelet, util.DefaultAPIServerReadyTimeout); err != nil {
 		return errors.Wrap(err, "failed to wait for apiserver ready")
@@ -183,9 +279,38 @@ func run(ctx context.Context, cfg cmds.Agent, proxy proxy.Proxy) error {
 		return err
 	}
 
+	if cfg.DisconnectedAutonomy {
+		if err := autonomy.Reconcile(nodeConfig.AgentConfig.PodManifests); err != nil {
+			return errors.Wrap(err, "failed to remove restored pods")
+		}
+		autonomy.Checkpoint(ctx, autonomy.Dir(cfg.DataDir), nodeConfig.AgentConfig.NodeName, kubeletClient.CoreV1().Pods(metav1.NamespaceAll))
+	}
+
 	if err := configureNode(ctx, nodeConfig, kubeletClient.CoreV1().Nodes()); err != nil {
 		return err
 	}
+	startup.Done(startup.PhaseNodeRegistered)
+	go recordNodeReady(ctx, nodeConfig.AgentConfig.NodeName, kubeletClient.CoreV1().Nodes())
+
+	if err := setSELinuxCondition(nodeConfig, kubeletClient); err != nil {
+		logrus.Warnf("Failed to set SELinux policy condition on node %s: %v", nodeConfig.AgentConfig.NodeName, err)
+	}
+
+	if !cfg.Rootless {
+		if err := syssetup.Monitor(ctx, nodeConfig, sysctls, cfg.SysctlCheckInterval, cfg.SysctlResetDrift); err != nil {
+			return errors.Wrap(err, "failed to start sysctl monitor")
+		}
+	}
+
+	if !nodeConfig.Docker && nodeConfig.ContainerRuntimeEndpoint == "" {
+		if err := containerd.MonitorPullProgress(ctx, nodeConfig, cfg.ImagePullProgress); err != nil {
+			return errors.Wrap(err, "failed to start image pull progress monitor")
+		}
+	}
+
+	if err := logship.Run(ctx, nodeConfig); err != nil {
+		return errors.Wrap(err, "failed to start container log shipping")
+	}
 
 	if !nodeConfig.NoFlannel {
 		if err := flannel.Run(ctx, nodeConfig); err != nil {
@@ -199,6 +324,10 @@ func run(ctx context.Context, cfg cmds.Agent, proxy proxy.Proxy) error {
 		}
 	}
 
+	if cfg.AgentReady != nil {
+		close(cfg.AgentReady)
+	}
+
 	// By default, the server is responsible for notifying systemd
 	// On agent-only nodes, the agent will notify systemd
 	if notifySocket != "" {
@@ -215,44 +344,96 @@ func run(ctx context.Context, cfg cmds.Agent, proxy proxy.Proxy) error {
 // extract the conntrack settings so that K3s can set them itself. This allows us to soft-fail when
 // running K3s in Docker, where kube-proxy is no longer allowed to set conntrack sysctls on newer kernels.
 // When running rootless, we do not attempt to set conntrack sysctls - this behavior is copied from kubeadm.
-func getConntrackConfig(nodeConfig *daemonconfig.Node) (*kubeproxyconfig.KubeProxyConntrackConfiguration, error) {
-	ctConfig := &kubeproxyconfig.KubeProxyConntrackConfiguration{
-		MaxPerCore:            utilsptr.To(int32(0)),
-		Min:                   utilsptr.To(int32(0)),
-		TCPEstablishedTimeout: &metav1.Duration{},
-		TCPCloseWaitTimeout:   &metav1.Duration{},
+// getKubeProxyConfig returns the kube-proxy settings that the agent applies on behalf of
+// kube-proxy: the proxy mode and IPVS scheduler, which determine the kernel modules to load, and
+// the conntrack settings, which kube-proxy cannot apply from a non-init network namespace. In ipvs
+// mode, the conntrack table is sized for the memory of the node, unless its size has been set.
+func getKubeProxyConfig(nodeConfig *daemonconfig.Node) (*kubeproxyconfig.KubeProxyConfiguration, error) {
+	proxyConfig := &kubeproxyconfig.KubeProxyConfiguration{
+		Mode: kubeproxyconfig.ProxyModeIPTables,
+		Linux: kubeproxyconfig.KubeProxyLinuxConfiguration{
+			Conntrack: kubeproxyconfig.KubeProxyConntrackConfiguration{
+				MaxPerCore:            utilsptr.To(int32(0)),
+				Min:                   utilsptr.To(int32(0)),
+				TCPEstablishedTimeout: &metav1.Duration{},
+				TCPCloseWaitTimeout:   &metav1.Duration{},
+			},
+		},
 	}
+	ctConfig := &proxyConfig.Linux.Conntrack
 
 	if nodeConfig.AgentConfig.Rootless {
-		return ctConfig, nil
+		return proxyConfig, nil
 	}
 
-	cmd := app2.NewProxyCommand()
-	globalflag.AddGlobalFlags(cmd.Flags(), cmd.Name(), logs.SkipLoggingConfigurationFlags())
-	if err := cmd.ParseFlags(daemonconfig.GetArgs(map[string]string{}, nodeConfig.AgentConfig.ExtraKubeProxyArgs)); err != nil {
-		return nil, err
-	}
-	maxPerCore, err := cmd.Flags().GetInt32("conntrack-max-per-core")
-	if err != nil {
-		return nil, err
-	}
-	ctConfig.MaxPerCore = &maxPerCore
-	min, err := cmd.Flags().GetInt32("conntrack-min")
-	if err != nil {
-		return nil, err
-	}
-	ctConfig.Min = &min
-	establishedTimeout, err := cmd.Flags().GetDuration("conntrack-tcp-timeout-established")
-	if err != nil {
-		return nil, err
+	var conntrackSizeSet bool
+	if file := nodeConfig.AgentConfig.KubeProxyConfig; file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		typedConfig := &kubeproxyconfigv1alpha1.KubeProxyConfiguration{}
+		if err := yaml.Unmarshal(b, typedConfig); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", file)
+		}
+		conntrackSizeSet = typedConfig.Conntrack.MaxPerCore != nil || typedConfig.Conntrack.Min != nil
+		fileConfig := &kubeproxyconfig.KubeProxyConfiguration{}
+		kubeproxyconfigscheme.Scheme.Default(typedConfig)
+		if err := kubeproxyconfigscheme.Scheme.Convert(typedConfig, fileConfig, nil); err != nil {
+			return nil, err
+		}
+		if fileConfig.Mode != "" {
+			proxyConfig.Mode = fileConfig.Mode
+		}
+		proxyConfig.IPVS.Scheduler = fileConfig.IPVS.Scheduler
+		ctConfig.MaxPerCore = fileConfig.Linux.Conntrack.MaxPerCore
+		ctConfig.Min = fileConfig.Linux.Conntrack.Min
+		ctConfig.TCPEstablishedTimeout = fileConfig.Linux.Conntrack.TCPEstablishedTimeout
+		ctConfig.TCPCloseWaitTimeout = fileConfig.Linux.Conntrack.TCPCloseWaitTimeout
+	} else {
+		cmd := app2.NewProxyCommand()
+		globalflag.AddGlobalFlags(cmd.Flags(), cmd.Name(), logs.SkipLoggingConfigurationFlags())
+		if err := cmd.ParseFlags(daemonconfig.GetArgs(map[string]string{}, nodeConfig.AgentConfig.ExtraKubeProxyArgs)); err != nil {
+			return nil, err
+		}
+		conntrackSizeSet = cmd.Flags().Changed("conntrack-max-per-core") || cmd.Flags().Changed("conntrack-min")
+		if mode := cmd.Flags().Lookup("proxy-mode").Value.String(); mode != "" {
+			proxyConfig.Mode = kubeproxyconfig.ProxyMode(mode)
+		}
+		scheduler, err := cmd.Flags().GetString("ipvs-scheduler")
+		if err != nil {
+			return nil, err
+		}
+		proxyConfig.IPVS.Scheduler = scheduler
+		maxPerCore, err := cmd.Flags().GetInt32("conntrack-max-per-core")
+		if err != nil {
+			return nil, err
+		}
+		ctConfig.MaxPerCore = &maxPerCore
+		min, err := cmd.Flags().GetInt32("conntrack-min")
+		if err != nil {
+			return nil, err
+		}
+		ctConfig.Min = &min
+		establishedTimeout, err := cmd.Flags().GetDuration("conntrack-tcp-timeout-established")
+		if err != nil {
+			return nil, err
+		}
+		ctConfig.TCPEstablishedTimeout.Duration = establishedTimeout
+		closeWaitTimeout, err := cmd.Flags().GetDuration("conntrack-tcp-timeout-close-wait")
+		if err != nil {
+			return nil, err
+		}
+		ctConfig.TCPCloseWaitTimeout.Duration = closeWaitTimeout
 	}
-	ctConfig.TCPEstablishedTimeout.Duration = establishedTimeout
-	closeWaitTimeout, err := cmd.Flags().GetDuration("conntrack-tcp-timeout-close-wait")
-	if err != nil {
-		return nil, err
+
+	if proxyConfig.Mode == kubeproxyconfig.ProxyModeIPVS && !conntrackSizeSet {
+		if min := syssetup.ConntrackMinForMemory(); ctConfig.Min != nil && min > *ctConfig.Min {
+			logrus.Infof("Sizing conntrack table for %d entries based on node memory for kube-proxy ipvs mode", min)
+			ctConfig.Min = &min
+		}
 	}
-	ctConfig.TCPCloseWaitTimeout.Duration = closeWaitTimeout
-	return ctConfig, nil
+	return proxyConfig, nil
 }
 
 // RunStandalone bootstraps the executor, but does not run the kubelet or containerd.
@@ -278,6 +459,14 @@ func RunStandalone(ctx context.Context, cfg cmds.Agent) error {
 		close(cfg.ContainerRuntimeReady)
 	}
 
+	if !cfg.ClusterReset && cfg.ETCDAgent {
+		// Servers without a local apiserver still need to find one for the load-balancer,
+		// even if there is no kubelet to connect to it.
+		if err := waitForAPIServerAddresses(ctx, nodeConfig, cfg, proxy); err != nil {
+			return err
+		}
+	}
+
 	if err := tunnelSetup(ctx, nodeConfig, cfg, proxy); err != nil {
 		return err
 	}
@@ -301,6 +490,19 @@ func RunStandalone(ctx context.Context, cfg cmds.Agent) error {
 	return ctx.Err()
 }
 
+// firewallPorts returns the ports that must be opened in the host firewall for the agent.
+func firewallPorts(nodeConfig *daemonconfig.Node) []firewall.Port {
+	firewallPorts := []firewall.Port{firewall.TCP(ports.KubeletPort)}
+	for _, port := range nodeConfig.FlannelPorts() {
+		firewallPorts = append(firewallPorts, firewall.UDP(port))
+	}
+	if nodeConfig.EmbeddedRegistry {
+		// default p2p port for the embedded registry mirror
+		firewallPorts = append(firewallPorts, firewall.TCP(5001))
+	}
+	return firewallPorts
+}
+
 // Run sets up cgroups, configures the LB proxy, and triggers startup
 // of containerd and kubelet. It will only return in case of error or context
 // cancellation.
@@ -309,6 +511,10 @@ func Run(ctx context.Context, cfg cmds.Agent) error {
 		return err
 	}
 
+	if err := cgroups.ValidateSwap(cfg.SwapBehavior); err != nil {
+		return err
+	}
+
 	if cfg.Rootless && !cfg.RootlessAlreadyUnshared {
 		dualNode, err := utilsnet.IsDualStackIPStrings(cfg.NodeIP)
 		if err != nil {
@@ -337,6 +543,20 @@ func createProxyAndValidateToken(ctx context.Context, cfg *cmds.Agent) (proxy.Pr
 	}
 	isIPv6 := utilsnet.IsIPv6(net.ParseIP(util.GetFirstValidIPString(cfg.NodeIP)))
 
+	if cfg.SupervisorProxy != "" {
+		if cfg.DisableLoadBalancer {
+			logrus.Warnf("Agent load-balancer is disabled, ignoring --supervisor-proxy")
+		} else {
+			proxyURL, err := config.ProxyURL(cfg.SupervisorProxy, cfg.ProxyCredentialsFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid supervisor proxy")
+			}
+			if err := loadbalancer.SetSupervisorProxy(proxyURL.String(), cfg.SupervisorProxyAuth); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	proxy, err := proxy.NewSupervisorProxy(ctx, !cfg.DisableLoadBalancer, agentDir, cfg.ServerURL, cfg.LBServerPort, isIPv6)
 	if err != nil {
 		return nil, err
@@ -347,10 +567,15 @@ func createProxyAndValidateToken(ctx context.Context, cfg *cmds.Agent) (proxy.Pr
 		clientaccess.WithClientCertificate(clientKubeletCert, clientKubeletKey),
 	}
 
+	deadline := time.Now().Add(autonomy.StartupTimeout)
 	for {
 		newToken, err := clientaccess.ParseAndValidateToken(proxy.SupervisorURL(), cfg.Token, options...)
 		if err != nil {
 			logrus.Error(err)
+			if cfg.DisconnectedAutonomy && time.Now().After(deadline) && autonomy.HasNodeConfig(autonomy.Dir(cfg.DataDir)) {
+				logrus.Warn("Unable to validate token with server; continuing with checkpointed configuration for disconnected autonomy")
+				return proxy, nil
+			}
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
@@ -410,6 +635,30 @@ func configureNode(ctx context.Context, nodeConfig *daemonconfig.Node, nodes typ
 			updateNode = true
 		}
 
+		if changed, err := nodeconfig.SetNodeMetadataAnnotation(nodeConfig, node); err != nil {
+			return false, err
+		} else if changed {
+			updateNode = true
+		}
+
+		if changed, err := nodeconfig.SetContainerdConfigAnnotation(nodeConfig, node); err != nil {
+			return false, err
+		} else if changed {
+			updateNode = true
+		}
+
+		if nodeconfig.SetNodeVersionAnnotations(node) {
+			updateNode = true
+		}
+
+		if nodeconfig.SetFlannelPortsAnnotation(nodeConfig, node) {
+			updateNode = true
+		}
+
+		if nodeconfig.SetClusterCIDRAnnotation(nodeConfig, node) {
+			updateNode = true
+		}
+
 		if changed, err := nodeconfig.SetNodeConfigLabels(nodeConfig, node); err != nil {
 			return false, err
 		} else if changed {
@@ -434,6 +683,42 @@ func configureNode(ctx context.Context, nodeConfig *daemonconfig.Node, nodes typ
 	return nil
 }
 
+// recordNodeReady waits for the node to become Ready, then records the completion of the node ready
+// startup phase, and annotates the node with the time taken by each startup phase.
+func recordNodeReady(ctx context.Context, nodeName string, nodes typedcorev1.NodeInterface) {
+	if err := wait.PollUntilContextCancel(ctx, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		node, err := nodes.Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == v1.NodeReady {
+				return condition.Status == v1.ConditionTrue, nil
+			}
+		}
+		return false, nil
+	}); err != nil {
+		return
+	}
+	startup.Done(startup.PhaseNodeReady)
+
+	durations := startup.GetDurations()
+	fields := logrus.Fields{}
+	for phase, d := range durations {
+		fields[startup.PhaseKey(phase)] = d.Round(100 * time.Millisecond).String()
+	}
+	logrus.WithFields(fields).Infof("Node %s is ready", nodeName)
+
+	patch, err := nodeconfig.StartupDurationsPatch(durations)
+	if err != nil {
+		logrus.Warnf("Failed to generate startup durations annotation: %v", err)
+		return
+	}
+	if _, err := nodes.Patch(ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		logrus.Warnf("Failed to set startup durations annotation on node %s: %v", nodeName, err)
+	}
+}
+
 func updateMutableLabels(agentConfig *daemonconfig.Agent, nodeLabels map[string]string) (map[string]string, bool) {
 	result := map[string]string{}
 
@@ -511,8 +796,16 @@ func updateAddressAnnotations(nodeConfig *daemonconfig.Node, nodeAnnotations map
 // there are special case for etcd agents, it will wait until it can find the apiaddress from
 // the address channel and update the proxy with the servers addresses, if in rke2 we need to
 // start the agent before the tunnel is setup to allow kubelet to start first and start the pods
-func setupTunnelAndRunAgent(ctx context.Context, nodeConfig *daemonconfig.Node, cfg cmds.Agent, proxy proxy.Proxy) error {
+func setupTunnelAndRunAgent(ctx context.Context, nodeConfig *daemonconfig.Node, cfg cmds.Agent, proxy proxy.Proxy, disconnected bool) error {
 	var agentRan bool
+	if disconnected {
+		// When started from checkpointed configuration, the kubelet needs to be started before
+		// the tunnel, so that restored pods can run until a server can be reached.
+		if err := agent.Agent(ctx, nodeConfig, proxy); err != nil {
+			return err
+		}
+		agentRan = true
+	}
 	// IsAPIServerLBEnabled is used as a shortcut for detecting RKE2, where the kubelet needs to
 	// be run earlier in order to manage static pods. This should probably instead query a
 	// flag on the executor or something.
@@ -553,6 +846,47 @@ func setupTunnelAndRunAgent(ctx context.Context, nodeConfig *daemonconfig.Node,
 	return nil
 }
 
+// getNodeConfig retrieves the agent configuration from the server. If disconnected autonomy is
+// enabled, the configuration is checkpointed, and the checkpointed configuration is used if the
+// server cannot be reached before the startup timeout. True is returned if the checkpointed
+// configuration is used.
+func getNodeConfig(ctx context.Context, cfg cmds.Agent, proxy proxy.Proxy) (*daemonconfig.Node, bool, error) {
+	if !cfg.DisconnectedAutonomy {
+		nodeConfig, err := config.Get(ctx, cfg, proxy)
+		return nodeConfig, false, err
+	}
+
+	dir := autonomy.Dir(cfg.DataDir)
+	getCtx, cancel := context.WithTimeout(ctx, autonomy.StartupTimeout)
+	defer cancel()
+	nodeConfig, err := config.Get(getCtx, cfg, proxy)
+	if nodeConfig == nil && ctx.Err() == nil {
+		if checkpoint, lerr := autonomy.LoadNodeConfig(dir); lerr == nil {
+			logrus.Warnf("Unable to retrieve agent configuration from server; starting with checkpointed configuration for disconnected autonomy: %v", err)
+			if checkpoint.SupervisorPort != checkpoint.ServerHTTPSPort {
+				isIPv6 := utilsnet.IsIPv6(net.ParseIP(checkpoint.AgentConfig.NodeIP))
+				if err := proxy.SetAPIServerPort(checkpoint.ServerHTTPSPort, isIPv6); err != nil {
+					return nil, false, errors.Wrapf(err, "failed to set apiserver port to %d", checkpoint.ServerHTTPSPort)
+				}
+			}
+			return checkpoint, true, nil
+		} else if !os.IsNotExist(lerr) {
+			logrus.Warnf("Failed to load checkpointed agent configuration: %v", lerr)
+		}
+		// Nothing has been checkpointed yet, so the agent cannot start until the server is reachable.
+		nodeConfig, err = config.Get(ctx, cfg, proxy)
+	}
+	if err != nil {
+		return nil, false, err
+	} else if nodeConfig == nil {
+		return nil, false, ctx.Err()
+	}
+	if err := autonomy.SaveNodeConfig(dir, nodeConfig); err != nil {
+		logrus.Warnf("Failed to checkpoint agent configuration: %v", err)
+	}
+	return nodeConfig, false, nil
+}
+
 func waitForAPIServerAddresses(ctx context.Context, nodeConfig *daemonconfig.Node, cfg cmds.Agent, proxy proxy.Proxy) error {
 	var localSupervisorDefault bool
 	if addresses := proxy.SupervisorAddresses(); len(addresses) > 0 {
@@ -604,6 +938,29 @@ func certMonitorSetup(ctx context.Context, nodeConfig *daemonconfig.Node, cfg cm
 	return certmonitor.Setup(ctx, nodeConfig, cfg.DataDir)
 }
 
+// setSELinuxCondition sets a condition on the node reporting whether the SELinux policy is installed,
+// up to date, and in use. No condition is set if SELinux is not enabled on the host.
+func setSELinuxCondition(nodeConfig *daemonconfig.Node, client kubernetes.Interface) error {
+	ready, reason, message := preflight.DetectSELinux().Condition(nodeConfig.SELinux)
+	if reason == "" {
+		return nil
+	}
+	condition := v1.NodeCondition{
+		Type:               selinuxPolicyCondition,
+		Status:             v1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		LastHeartbeatTime:  metav1.Now(),
+		LastTransitionTime: metav1.Now(),
+	}
+	if ready {
+		condition.Status = v1.ConditionTrue
+	} else {
+		logrus.Warn(message)
+	}
+	return nodeutil.SetNodeCondition(client, types.NodeName(nodeConfig.AgentConfig.NodeName), condition)
+}
+
 // getHostname returns the actual system hostname.
 // If the hostname cannot be determined, or is invalid, the node name is used.
 func getHostname(agentConfig *daemonconfig.Agent) string {
@@ -11,11 +11,6 @@ import (
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 )
 
-const (
-	criDockerdSock = "unix:///run/k3s/cri-dockerd/cri-dockerd.sock"
-	containerdSock = "unix:///run/k3s/containerd/containerd.sock"
-)
-
 // setupCriCtlConfig creates the crictl config file and populates it
 // with the given data from config.
 func setupCriCtlConfig(cfg cmds.Agent, nodeConfig *config.Node) error {
@@ -23,9 +18,9 @@ func setupCriCtlConfig(cfg cmds.Agent, nodeConfig *config.Node) error {
 	if cre == "" {
 		switch {
 		case cfg.Docker:
-			cre = criDockerdSock
+			cre = nodeConfig.CRIDockerd.Address
 		default:
-			cre = containerdSock
+			cre = "unix://" + nodeConfig.Containerd.Address
 		}
 	}
 
@@ -44,7 +39,7 @@ func setupCriCtlConfig(cfg cmds.Agent, nodeConfig *config.Node) error {
 	crp := "runtime-endpoint: " + cre + "\n"
 	ise := nodeConfig.ImageServiceEndpoint
 	if ise != "" && ise != cre {
-		crp += "image-endpoint: " + cre + "\n"
+		crp += "image-endpoint: " + ise + "\n"
 	}
 	return os.WriteFile(agentConfDir+"/crictl.yaml", []byte(crp), 0600)
 }
@@ -1,10 +1,13 @@
 package agent
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/k3s-io/k3s/pkg/agent/syssetup"
 	daemonconfig "github.com/k3s-io/k3s/pkg/daemons/config"
 	v1alpha1 "k8s.io/kube-proxy/config/v1alpha1"
 	kubeproxyconfig "k8s.io/kubernetes/pkg/proxy/apis/config"
@@ -86,13 +89,64 @@ func Test_UnitGetConntrackConfig(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := getConntrackConfig(tt.args.nodeConfig)
+			got, err := getKubeProxyConfig(tt.args.nodeConfig)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("getConntrackConfig() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("getKubeProxyConfig() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("getConntrackConfig() = %+v\nWant = %+v", got, tt.want)
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(&got.Linux.Conntrack, tt.want) {
+				t.Errorf("getKubeProxyConfig() conntrack = %+v\nWant = %+v", &got.Linux.Conntrack, tt.want)
+			}
+		})
+	}
+}
+
+func Test_UnitGetKubeProxyConfigIPVS(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "kube-proxy.yaml")
+	config := "apiVersion: kubeproxy.config.k8s.io/v1alpha1\nkind: KubeProxyConfiguration\nmode: ipvs\nipvs:\n  scheduler: sh\n"
+	if err := os.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	memoryMin := syssetup.ConntrackMinForMemory()
+
+	tests := []struct {
+		name          string
+		agentConfig   daemonconfig.Agent
+		wantScheduler string
+		wantMin       int32
+	}{
+		{
+			name:          "Args",
+			agentConfig:   daemonconfig.Agent{ExtraKubeProxyArgs: []string{"proxy-mode=ipvs", "ipvs-scheduler=wrr"}},
+			wantScheduler: "wrr",
+			wantMin:       max(memoryMin, 131072),
+		},
+		{
+			name:        "Args with conntrack size",
+			agentConfig: daemonconfig.Agent{ExtraKubeProxyArgs: []string{"proxy-mode=ipvs", "conntrack-min=100"}},
+			wantMin:     100,
+		},
+		{
+			name:          "Config file",
+			agentConfig:   daemonconfig.Agent{KubeProxyConfig: configFile},
+			wantScheduler: "sh",
+			wantMin:       max(memoryMin, 131072),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getKubeProxyConfig(&daemonconfig.Node{AgentConfig: tt.agentConfig})
+			if err != nil {
+				t.Fatalf("getKubeProxyConfig() error = %v", err)
+			}
+			if got.Mode != kubeproxyconfig.ProxyModeIPVS || got.IPVS.Scheduler != tt.wantScheduler {
+				t.Errorf("getKubeProxyConfig() mode = %q, scheduler = %q; want ipvs, %q", got.Mode, got.IPVS.Scheduler, tt.wantScheduler)
+			}
+			if got.Linux.Conntrack.Min == nil || *got.Linux.Conntrack.Min != tt.wantMin {
+				t.Errorf("getKubeProxyConfig() conntrack min = %v, want %d", got.Linux.Conntrack.Min, tt.wantMin)
 			}
 		})
 	}
@@ -0,0 +1,211 @@
+package staticpod
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// ManifestPrefix is prepended to the name of user-provided static pod manifests when they are
+// copied into the kubelet's static pod path, so that they can be distinguished from manifests
+// managed by other components.
+const ManifestPrefix = "user-"
+
+// Manifest describes a user-provided static pod manifest, and the result of validating it.
+type Manifest struct {
+	Path    string
+	PodName string
+	Hash    string
+	Err     error
+	content []byte
+}
+
+// Start validates and copies any user-provided static pod manifests from the source directory into
+// the kubelet's static pod path, and then starts a goroutine to periodically sync changes.
+func Start(ctx context.Context, srcDir, podManifests string) error {
+	if srcDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(podManifests, 0750); err != nil {
+		return errors.Wrapf(err, "failed to create static pod manifest dir %s", podManifests)
+	}
+
+	s := &syncer{
+		srcDir:       srcDir,
+		podManifests: podManifests,
+		hashes:       map[string]string{},
+	}
+	if err := s.sync(); err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(15 * time.Second):
+			}
+			if err := s.sync(); err != nil {
+				logrus.Errorf("Failed to sync user static pod manifests: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// List returns all static pod manifests in the source directory, along with the result of validating them.
+func List(srcDir string) ([]*Manifest, error) {
+	ents, err := os.ReadDir(srcDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	manifests := []*Manifest{}
+	for _, ent := range ents {
+		if ent.IsDir() || strings.HasPrefix(ent.Name(), ".") || !isManifest(ent.Name()) {
+			continue
+		}
+		manifests = append(manifests, Validate(filepath.Join(srcDir, ent.Name())))
+	}
+	return manifests, nil
+}
+
+// Validate reads a static pod manifest from disk and checks that it contains a single valid Pod.
+func Validate(path string) *Manifest {
+	m := &Manifest{Path: path}
+	m.content, m.Err = os.ReadFile(path)
+	if m.Err != nil {
+		return m
+	}
+	sum := sha256.Sum256(m.content)
+	m.Hash = hex.EncodeToString(sum[:])
+	m.PodName, m.Err = validatePod(m.content)
+	return m
+}
+
+// SyncedPath returns the path that a user-provided manifest is copied to within the kubelet's static pod path.
+func SyncedPath(podManifests, path string) string {
+	return filepath.Join(podManifests, ManifestPrefix+filepath.Base(path))
+}
+
+// validatePod decodes the manifest content, and performs basic validation of the pod spec.
+// The kubelet will perform complete validation of the pod when it is loaded; this just catches
+// common problems so that they can be reported before the manifest is handed to the kubelet.
+func validatePod(content []byte) (string, error) {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return "", errors.New("manifest is empty")
+	}
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(content, nil, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode manifest")
+	}
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return "", fmt.Errorf("manifest contains %s, not Pod", obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	if pod.Name == "" {
+		return "", errors.New("pod name must be set")
+	}
+	if errs := validation.IsDNS1123Subdomain(pod.Name); len(errs) > 0 {
+		return "", fmt.Errorf("invalid pod name %q: %s", pod.Name, strings.Join(errs, ", "))
+	}
+	if len(pod.Spec.Containers) == 0 {
+		return "", fmt.Errorf("pod %s must have at least one container", pod.Name)
+	}
+	for _, container := range pod.Spec.Containers {
+		if container.Name == "" {
+			return "", fmt.Errorf("pod %s has a container with no name", pod.Name)
+		}
+		if container.Image == "" {
+			return "", fmt.Errorf("container %s in pod %s must specify an image", container.Name, pod.Name)
+		}
+	}
+	return pod.Name, nil
+}
+
+// isManifest returns true if the file has an extension that the kubelet will load as a static pod.
+func isManifest(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+type syncer struct {
+	srcDir       string
+	podManifests string
+	hashes       map[string]string
+}
+
+// sync copies valid manifests from the source directory into the kubelet's static pod path,
+// and removes previously copied manifests whose source has been deleted. If an existing
+// manifest is modified and fails validation, the previously synced copy is left in place.
+func (s *syncer) sync() error {
+	manifests, err := List(s.srcDir)
+	if err != nil {
+		return err
+	}
+
+	current := map[string]bool{}
+	for _, m := range manifests {
+		dest := SyncedPath(s.podManifests, m.Path)
+		current[dest] = true
+		if m.Err != nil {
+			if s.hashes[dest] != m.Hash {
+				logrus.Errorf("Invalid user static pod manifest %s: %v", m.Path, m.Err)
+				s.hashes[dest] = m.Hash
+			}
+			continue
+		}
+		if s.hashes[dest] == m.Hash {
+			continue
+		}
+		if existing, err := os.ReadFile(dest); err == nil && bytes.Equal(existing, m.content) {
+			s.hashes[dest] = m.Hash
+			continue
+		}
+		_, statErr := os.Stat(dest)
+		if err := os.WriteFile(dest, m.content, 0600); err != nil {
+			return errors.Wrapf(err, "failed to write static pod manifest %s", dest)
+		}
+		if statErr == nil {
+			logrus.Infof("Updated user static pod %s from %s", m.PodName, m.Path)
+		} else {
+			logrus.Infof("Added user static pod %s from %s", m.PodName, m.Path)
+		}
+		s.hashes[dest] = m.Hash
+	}
+
+	ents, err := os.ReadDir(s.podManifests)
+	if err != nil {
+		return err
+	}
+	for _, ent := range ents {
+		dest := filepath.Join(s.podManifests, ent.Name())
+		if ent.IsDir() || !strings.HasPrefix(ent.Name(), ManifestPrefix) || current[dest] {
+			continue
+		}
+		if err := os.Remove(dest); err != nil {
+			return errors.Wrapf(err, "failed to remove static pod manifest %s", dest)
+		}
+		logrus.Infof("Removed user static pod manifest %s", dest)
+		delete(s.hashes, dest)
+	}
+	return nil
+}
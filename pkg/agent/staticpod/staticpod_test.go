@@ -0,0 +1,86 @@
+package staticpod
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const validPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: appliance
+spec:
+  containers:
+  - name: app
+    image: docker.io/library/nginx:latest
+`
+
+func Test_UnitValidatePod(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "Valid pod", content: validPod},
+		{name: "Empty manifest", content: "\n", wantErr: true},
+		{name: "Not a pod", content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n", wantErr: true},
+		{name: "Missing name", content: "apiVersion: v1\nkind: Pod\nspec:\n  containers:\n  - name: app\n    image: nginx\n", wantErr: true},
+		{name: "Invalid name", content: "apiVersion: v1\nkind: Pod\nmetadata:\n  name: Not_Valid\nspec:\n  containers:\n  - name: app\n    image: nginx\n", wantErr: true},
+		{name: "No containers", content: "apiVersion: v1\nkind: Pod\nmetadata:\n  name: test\nspec: {}\n", wantErr: true},
+		{name: "Missing image", content: "apiVersion: v1\nkind: Pod\nmetadata:\n  name: test\nspec:\n  containers:\n  - name: app\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := validatePod([]byte(tt.content)); (err != nil) != tt.wantErr {
+				t.Errorf("validatePod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_UnitSync(t *testing.T) {
+	srcDir := t.TempDir()
+	podManifests := t.TempDir()
+	s := &syncer{srcDir: srcDir, podManifests: podManifests, hashes: map[string]string{}}
+
+	srcFile := filepath.Join(srcDir, "appliance.yaml")
+	destFile := SyncedPath(podManifests, srcFile)
+	if err := os.WriteFile(srcFile, []byte(validPod), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "broken.yaml"), []byte("kind: Pod\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.sync(); err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+	if b, err := os.ReadFile(destFile); err != nil || string(b) != validPod {
+		t.Errorf("sync() did not copy valid manifest: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(podManifests, ManifestPrefix+"broken.yaml")); err == nil {
+		t.Errorf("sync() copied invalid manifest")
+	}
+
+	// an invalid update should leave the previous copy in place
+	if err := os.WriteFile(srcFile, []byte("kind: Pod\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.sync(); err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+	if b, err := os.ReadFile(destFile); err != nil || string(b) != validPod {
+		t.Errorf("sync() replaced manifest with invalid content: %v", err)
+	}
+
+	// removing the source should remove the copy
+	if err := os.Remove(srcFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.sync(); err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+	if _, err := os.Stat(destFile); !os.IsNotExist(err) {
+		t.Errorf("sync() did not remove manifest for deleted source: %v", err)
+	}
+}
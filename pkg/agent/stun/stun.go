@@ -0,0 +1,129 @@
+// Package stun discovers the public addresses of a node that is behind NAT, by sending binding
+// requests to STUN servers.
+package stun
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pion/stun"
+	"github.com/sirupsen/logrus"
+	utilsnet "k8s.io/utils/net"
+)
+
+const (
+	// defaultPort is the port used for STUN servers that are specified without a port.
+	defaultPort = "3478"
+	// attempts is the number of binding requests sent to each server, as requests and responses
+	// are sent over UDP and may be lost.
+	attempts = 3
+	// attemptTimeout is the time allowed for a response to each binding request.
+	attemptTimeout = 2 * time.Second
+)
+
+// ExternalIPs returns the public addresses of the node as seen by the STUN servers, with at most
+// one address for each IP family. Addresses that are also node IPs are not returned, as the node
+// is not behind NAT for that family. An error is returned only if no server could be reached.
+func ExternalIPs(ctx context.Context, servers []string, nodeIPs []net.IP) ([]net.IP, error) {
+	var ipv4, ipv6 net.IP
+	var lastErr error
+	reached := false
+	for _, server := range servers {
+		ip, err := discover(ctx, server)
+		if err != nil {
+			logrus.Debugf("Failed to discover external address using STUN server %s: %v", server, err)
+			lastErr = err
+			continue
+		}
+		reached = true
+		logrus.Debugf("STUN server %s reports external address %s", server, ip)
+		if isNodeIP(ip, nodeIPs) {
+			continue
+		}
+		if utilsnet.IsIPv4(ip) && ipv4 == nil {
+			ipv4 = ip
+		} else if utilsnet.IsIPv6(ip) && ipv6 == nil {
+			ipv6 = ip
+		}
+	}
+	if !reached && lastErr != nil {
+		return nil, lastErr
+	}
+	ips := []net.IP{}
+	for _, ip := range []net.IP{ipv4, ipv6} {
+		if ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// discover returns the address that the STUN server sees binding requests from.
+func discover(ctx context.Context, server string) (net.IP, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, defaultPort)
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	request := stun.MustBuild(stun.TransactionID, stun.BindingRequest, stun.Fingerprint)
+	buf := make([]byte, 1500)
+	for i := 0; i < attempts; i++ {
+		if _, err = conn.Write(request.Raw); err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(attemptTimeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetReadDeadline(deadline)
+		var ip net.IP
+		if ip, err = readResponse(conn, buf, request.TransactionID); err == nil {
+			return ip, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, err
+}
+
+// readResponse reads a binding response matching the transaction ID, and returns the mapped address.
+func readResponse(conn net.Conn, buf []byte, transactionID [stun.TransactionIDSize]byte) (net.IP, error) {
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		response := &stun.Message{Raw: append([]byte{}, buf[:n]...)}
+		if err := response.Decode(); err != nil || response.TransactionID != transactionID {
+			continue
+		}
+		if response.Type != stun.BindingSuccess {
+			return nil, fmt.Errorf("unexpected STUN response %s", response.Type)
+		}
+		var xorAddr stun.XORMappedAddress
+		if err := xorAddr.GetFrom(response); err == nil {
+			return xorAddr.IP, nil
+		}
+		var addr stun.MappedAddress
+		if err := addr.GetFrom(response); err != nil {
+			return nil, err
+		}
+		return addr.IP, nil
+	}
+}
+
+func isNodeIP(ip net.IP, nodeIPs []net.IP) bool {
+	for _, nodeIP := range nodeIPs {
+		if nodeIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
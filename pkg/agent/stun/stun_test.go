@@ -0,0 +1,58 @@
+package stun
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/pion/stun"
+)
+
+// serve answers binding requests on a local UDP socket, reporting mapped as the client address.
+func serve(t *testing.T, mapped net.IP) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			request := &stun.Message{Raw: append([]byte{}, buf[:n]...)}
+			if err := request.Decode(); err != nil {
+				continue
+			}
+			response := stun.MustBuild(stun.NewTransactionIDSetter(request.TransactionID), stun.BindingSuccess,
+				&stun.XORMappedAddress{IP: mapped, Port: addr.(*net.UDPAddr).Port}, stun.Fingerprint)
+			conn.WriteTo(response.Raw, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func Test_UnitExternalIPs(t *testing.T) {
+	ctx := context.Background()
+	public := net.ParseIP("203.0.113.10")
+	nodeIP := net.ParseIP("10.0.0.4")
+
+	ips, err := ExternalIPs(ctx, []string{serve(t, public)}, []net.IP{nodeIP})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || !ips[0].Equal(public) {
+		t.Errorf("expected external IP %s, got %v", public, ips)
+	}
+
+	// a node that is not behind NAT has no external IPs
+	ips, err = ExternalIPs(ctx, []string{serve(t, nodeIP)}, []net.IP{nodeIP})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 0 {
+		t.Errorf("expected no external IPs, got %v", ips)
+	}
+}
@@ -0,0 +1,104 @@
+//go:build !windows
+
+package syssetup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	daemonconfig "github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var controllerName = version.Program + "-sysctl-monitor"
+
+// Monitor periodically checks the managed sysctls for drift from their managed value, for example due to
+// live tuning of the host. Drift is reported as an event on the Node, once for each drifted value. Drifted
+// sysctls are only reset to their managed value if reset is true, so that values tuned by an administrator
+// are left alone by default.
+func Monitor(ctx context.Context, nodeConfig *daemonconfig.Node, sysctls map[string]string, interval time.Duration, reset bool) error {
+	if interval <= 0 || len(sysctls) == 0 {
+		return nil
+	}
+	logrus.Debugf("Starting %s with monitoring period %s", controllerName, interval)
+
+	client, err := util.GetClientSet(nodeConfig.AgentConfig.KubeConfigKubelet)
+	if err != nil {
+		return err
+	}
+
+	recorder := util.BuildControllerEventRecorder(client, controllerName, metav1.NamespaceDefault)
+	nodeRef := &corev1.ObjectReference{
+		Kind:      "Node",
+		Name:      nodeConfig.AgentConfig.NodeName,
+		UID:       types.UID(nodeConfig.AgentConfig.NodeName),
+		Namespace: "",
+	}
+
+	entries := make([]string, 0, len(sysctls))
+	for entry := range sysctls {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+
+	// reported holds the drifted value most recently reported for each sysctl
+	reported := map[string]string{}
+	go wait.Until(func() {
+		for _, entry := range entries {
+			value := sysctls[entry]
+			current, err := getSysctl(entry)
+			if err != nil || current == value {
+				delete(reported, entry)
+				continue
+			}
+			if !reset {
+				if reported[entry] != current {
+					reported[entry] = current
+					message := fmt.Sprintf("Sysctl %s has drifted from %q to %q", entry, value, current)
+					logrus.Warn(message)
+					recorder.Event(nodeRef, corev1.EventTypeWarning, "SysctlDrift", message)
+				}
+				continue
+			}
+			if err := setSysctl(entry, value); err != nil {
+				if reported[entry] != current {
+					reported[entry] = current
+					message := fmt.Sprintf("Sysctl %s has drifted from %q to %q, and could not be reset: %v", entry, value, current, err)
+					logrus.Warn(message)
+					recorder.Event(nodeRef, corev1.EventTypeWarning, "SysctlDriftResetFailed", message)
+				}
+				continue
+			}
+			delete(reported, entry)
+			message := fmt.Sprintf("Sysctl %s had drifted from %q to %q, and was reset", entry, value, current)
+			logrus.Info(message)
+			recorder.Event(nodeRef, corev1.EventTypeWarning, "SysctlDrift", message)
+		}
+	}, interval, ctx.Done())
+
+	return nil
+}
+
+// getSysctl returns the current value of the sysctl, with fields separated by single spaces.
+func getSysctl(entry string) (string, error) {
+	b, err := os.ReadFile(filepath.Join("/proc/sys", entry))
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(string(b)), " "), nil
+}
+
+func setSysctl(entry, value string) error {
+	return os.WriteFile(filepath.Join("/proc/sys", entry), []byte(value), 0640)
+}
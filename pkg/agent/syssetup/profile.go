@@ -0,0 +1,42 @@
+package syssetup
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ReadProfile reads sysctls from a file in sysctl.conf format. Keys may use either dots or slashes
+// as separators, and are returned in the slash-separated form used under /proc/sys. Values are
+// normalized to single spaces between fields, matching the format read back from /proc/sys.
+func ReadProfile(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open sysctl profile")
+	}
+	defer f.Close()
+
+	profile := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, ";") {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		key = strings.TrimPrefix(strings.TrimSpace(key), "-")
+		if !ok || key == "" {
+			return nil, errors.Errorf("invalid sysctl profile entry on line %d of %s: %q", line, file, text)
+		}
+		if !strings.Contains(key, "/") {
+			key = strings.ReplaceAll(key, ".", "/")
+		}
+		profile[strings.Trim(key, "/")] = strings.Join(strings.Fields(value), " ")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read sysctl profile")
+	}
+	return profile, nil
+}
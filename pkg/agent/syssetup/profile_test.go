@@ -0,0 +1,48 @@
+package syssetup
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_UnitReadProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "sysctl.conf format",
+			content: "# comment\n; comment\n\nvm.max_map_count = 262144\nnet.ipv4.ip_local_port_range=1024\t65000\n-fs.inotify.max_user_watches = 524288\nnet/ipv4/conf/eth0.100/rp_filter = 2\n",
+			want: map[string]string{
+				"vm/max_map_count":                 "262144",
+				"net/ipv4/ip_local_port_range":     "1024 65000",
+				"fs/inotify/max_user_watches":      "524288",
+				"net/ipv4/conf/eth0.100/rp_filter": "2",
+			},
+		},
+		{
+			name:    "Missing value",
+			content: "vm.max_map_count\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "sysctl.conf")
+			if err := os.WriteFile(file, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadProfile(file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadProfile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
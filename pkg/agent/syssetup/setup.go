@@ -3,18 +3,32 @@
 package syssetup
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/cadvisor/machine"
 	"github.com/google/cadvisor/utils/sysfs"
 	"github.com/sirupsen/logrus"
-	"k8s.io/component-helpers/node/util/sysctl"
+	"golang.org/x/sys/unix"
 	kubeproxyconfig "k8s.io/kubernetes/pkg/proxy/apis/config"
 )
 
+const (
+	// conntrackMemoryPerEntry is the amount of node memory to allow for each conntrack entry when
+	// sizing the conntrack table for memory. At roughly 300 bytes per entry, this allows the table
+	// to use up to 2% of memory.
+	conntrackMemoryPerEntry = 16 * 1024
+	// maxConntrackMinForMemory caps the conntrack table size computed from node memory.
+	maxConntrackMinForMemory = 1 << 21
+)
+
 func loadKernelModule(moduleName string) {
 	if _, err := os.Stat("/sys/module/" + moduleName); err == nil {
 		logrus.Info("Module " + moduleName + " was already loaded")
@@ -26,9 +40,72 @@ func loadKernelModule(moduleName string) {
 	}
 }
 
+// moduleAvailable returns true if the kernel module is loaded, or built in to the kernel.
+func moduleAvailable(moduleName string) bool {
+	if _, err := os.Stat("/sys/module/" + moduleName); err == nil {
+		return true
+	}
+	var uname unix.Utsname
+	if err := unix.Uname(&uname); err != nil {
+		return false
+	}
+	release := unix.ByteSliceToString(uname.Release[:])
+	builtin, err := os.ReadFile(filepath.Join("/lib/modules", release, "modules.builtin"))
+	if err != nil {
+		return false
+	}
+	for _, line := range bytes.Split(builtin, []byte("\n")) {
+		if strings.TrimSuffix(filepath.Base(string(line)), ".ko") == moduleName {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadIPVSModules loads the kernel modules required by kube-proxy in ipvs mode with the given
+// scheduler, and returns an error listing any that are not available. Without this check,
+// kube-proxy fails to sync rules with errors that do not identify the missing module.
+func LoadIPVSModules(scheduler string) error {
+	if scheduler == "" {
+		scheduler = "rr"
+	}
+	var missing []string
+	for _, moduleName := range []string{"ip_vs", "ip_vs_" + scheduler, "nf_conntrack"} {
+		loadKernelModule(moduleName)
+		if !moduleAvailable(moduleName) {
+			missing = append(missing, moduleName)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("kube-proxy ipvs mode requires kernel modules that are not available: %s; install the modules for the running kernel, or use iptables or nftables mode", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// ConntrackMinForMemory returns a minimum conntrack table size scaled to the memory of the node,
+// for proxy modes that track more connections than the default table size allows for. Zero is
+// returned if the memory of the node cannot be determined.
+func ConntrackMinForMemory() int32 {
+	capacity, err := machine.GetMachineMemoryCapacity()
+	if err != nil {
+		logrus.Warnf("Failed to get memory capacity for conntrack table sizing: %v", err)
+		return 0
+	}
+	return conntrackMinForMemory(capacity)
+}
+
+func conntrackMinForMemory(capacity uint64) int32 {
+	entries := capacity / conntrackMemoryPerEntry
+	if entries > maxConntrackMinForMemory {
+		entries = maxConntrackMinForMemory
+	}
+	return int32(entries)
+}
+
 // Configure loads required kernel modules and sets sysctls required for other components to
-// function properly.
-func Configure(enableIPv6 bool, config *kubeproxyconfig.KubeProxyConntrackConfiguration) {
+// function properly, followed by any sysctls from the user-provided profile. The successfully
+// applied sysctls are returned, so that they can be monitored for drift.
+func Configure(enableIPv6 bool, config *kubeproxyconfig.KubeProxyConntrackConfiguration, profile map[string]string) map[string]string {
 	loadKernelModule("overlay")
 	loadKernelModule("nf_conntrack")
 	loadKernelModule("br_netfilter")
@@ -68,15 +145,25 @@ func Configure(enableIPv6 bool, config *kubeproxyconfig.KubeProxyConntrackConfig
 		sysctls["net/netfilter/nf_conntrack_tcp_timeout_close_wait"] = int(config.TCPCloseWaitTimeout.Duration / time.Second)
 	}
 
-	sys := sysctl.New()
+	managed := map[string]string{}
 	for entry, value := range sysctls {
-		if val, _ := sys.GetSysctl(entry); val != value {
+		managed[entry] = strconv.Itoa(value)
+	}
+	for entry, value := range profile {
+		managed[entry] = value
+	}
+
+	for entry, value := range managed {
+		if val, _ := getSysctl(entry); val != value {
 			logrus.Infof("Set sysctl '%v' to %v", entry, value)
-			if err := sys.SetSysctl(entry, value); err != nil {
+			if err := setSysctl(entry, value); err != nil {
 				logrus.Errorf("Failed to set sysctl: %v", err)
+				// sysctls that cannot be set are not monitored, as drift could never be corrected
+				delete(managed, entry)
 			}
 		}
 	}
+	return managed
 }
 
 // getConntrackMax is cribbed from kube-proxy, as recent kernels no longer allow non-init namespaces
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package syssetup
+
+import "testing"
+
+func Test_UnitConntrackMinForMemory(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity uint64
+		want     int32
+	}{
+		{name: "1GiB", capacity: 1 << 30, want: 65536},
+		{name: "8GiB", capacity: 8 << 30, want: 524288},
+		{name: "capped", capacity: 1 << 40, want: maxConntrackMinForMemory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := conntrackMinForMemory(tt.capacity); got != tt.want {
+				t.Errorf("conntrackMinForMemory() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,25 @@
 package syssetup
 
-import kubeproxyconfig "k8s.io/kubernetes/pkg/proxy/apis/config"
+import (
+	"context"
+	"time"
 
-func Configure(enableIPv6 bool, config *kubeproxyconfig.KubeProxyConntrackConfiguration) {
+	daemonconfig "github.com/k3s-io/k3s/pkg/daemons/config"
+	kubeproxyconfig "k8s.io/kubernetes/pkg/proxy/apis/config"
+)
 
+func Configure(enableIPv6 bool, config *kubeproxyconfig.KubeProxyConntrackConfiguration, profile map[string]string) map[string]string {
+	return nil
+}
+
+func LoadIPVSModules(scheduler string) error {
+	return nil
+}
+
+func ConntrackMinForMemory() int32 {
+	return 0
+}
+
+func Monitor(ctx context.Context, nodeConfig *daemonconfig.Node, sysctls map[string]string, interval time.Duration, reset bool) error {
+	return nil
 }
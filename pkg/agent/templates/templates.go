@@ -27,6 +27,8 @@ type ContainerdConfig struct {
 	PrivateRegistryConfig *registries.Registry
 	ExtraRuntimes         map[string]ContainerdRuntimeConfig
 	Program               string
+	// CriuPath is the path of the criu binary used by runc to checkpoint containers, if it is installed
+	CriuPath string
 }
 
 type RegistryEndpoint struct {
@@ -102,10 +104,14 @@ enable_keychain = true
 {{end}}
 {{end}}
 
-{{- if not .NodeConfig.NoFlannel }}
+{{- if or .NodeConfig.AgentConfig.CNIBinDir .NodeConfig.AgentConfig.CNIConfDir }}
 [plugins."io.containerd.grpc.v1.cri".cni]
+{{- if .NodeConfig.AgentConfig.CNIBinDir }}
   bin_dir = "{{ .NodeConfig.AgentConfig.CNIBinDir }}"
+{{- end}}
+{{- if .NodeConfig.AgentConfig.CNIConfDir }}
   conf_dir = "{{ .NodeConfig.AgentConfig.CNIConfDir }}"
+{{- end}}
 {{end}}
 
 {{- if or .NodeConfig.Containerd.BlockIOConfig .NodeConfig.Containerd.RDTConfig }}
@@ -119,6 +125,9 @@ enable_keychain = true
 
 [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc.options]
   SystemdCgroup = {{ .SystemdCgroup }}
+{{- if .CriuPath }}
+  CriuPath = "{{ .CriuPath }}"
+{{- end}}
 
 [plugins."io.containerd.grpc.v1.cri".registry]
   config_path = "{{ .NodeConfig.Containerd.Registry }}"
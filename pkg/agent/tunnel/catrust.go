@@ -0,0 +1,135 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	agentconfig "github.com/k3s-io/k3s/pkg/agent/config"
+	"github.com/k3s-io/k3s/pkg/agent/proxy"
+	"github.com/k3s-io/k3s/pkg/clientaccess"
+	daemonconfig "github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// caTrustSyncInterval is the interval at which the agent checks the supervisor for updated CA bundles.
+var caTrustSyncInterval = time.Minute
+
+// watchCATrust periodically retrieves the CA bundles from the supervisor, and replaces the agent's copies
+// if they have changed, so that new CA certificates distributed during a phased CA rotation are trusted
+// without re-joining the node. The kubelet reloads the client CA bundle when it changes; the server CA
+// bundle is used by agent components the next time they connect. Once the servers sign with the new CA,
+// the agent's client certificates and kubelet serving certificate are re-issued by it. The hash of the
+// server CA bundle and the issuers of the certificates are recorded on the node, so that servers can track
+// the progress of each node.
+func watchCATrust(ctx context.Context, apiServerReady <-chan struct{}, config *daemonconfig.Node, proxy proxy.Proxy) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-apiServerReady:
+	}
+
+	client, err := util.GetClientSet(config.AgentConfig.KubeConfigKubelet)
+	if err != nil {
+		logrus.Warnf("Failed to create client for CA trust sync: %v", err)
+		return
+	}
+	nodes := client.CoreV1().Nodes()
+
+	var lastHash, lastIssuerHash, lastServingIssuerHash string
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		// The supervisor's certificate is validated with the server CA bundle currently trusted by the agent,
+		// instead of the CA hash in the token, which no longer matches once the CA has been rotated.
+		cacerts, err := os.ReadFile(config.AgentConfig.ServerCA)
+		if err != nil {
+			logrus.Warnf("Failed to read server CA bundle for CA trust sync: %v", err)
+			return
+		}
+		withCert := clientaccess.WithClientCertificate(config.AgentConfig.ClientKubeletCert, config.AgentConfig.ClientKubeletKey)
+		info, err := clientaccess.ParseTokenWithCACerts(proxy.SupervisorURL(), config.Token, cacerts, withCert)
+		if err != nil {
+			logrus.Warnf("Failed to parse server token for CA trust sync: %v", err)
+			return
+		}
+
+		if _, err := syncCAFile(info, config.AgentConfig.ClientCA); err != nil {
+			logrus.Warnf("Failed to sync client CA bundle: %v", err)
+			return
+		}
+		serverCA, err := syncCAFile(info, config.AgentConfig.ServerCA)
+		if err != nil {
+			logrus.Warnf("Failed to sync server CA bundle: %v", err)
+			return
+		}
+
+		if hash := nodeconfig.ServerCAHash(serverCA); hash != lastHash {
+			if err := patchNode(ctx, nodes, config.AgentConfig.NodeName, nodeconfig.ServerCAHashPatch, hash); err != nil {
+				logrus.Warnf("Failed to set server CA hash annotation on node %s: %v", config.AgentConfig.NodeName, err)
+				return
+			}
+			lastHash = hash
+		}
+
+		issuerHash, err := agentconfig.ReissueClientCerts(ctx, config, info)
+		if err != nil {
+			logrus.Warnf("Failed to re-issue client certificates: %v", err)
+			return
+		}
+		if issuerHash != lastIssuerHash {
+			if err := patchNode(ctx, nodes, config.AgentConfig.NodeName, nodeconfig.ClientCertIssuerPatch, issuerHash); err != nil {
+				logrus.Warnf("Failed to set client cert issuer annotation on node %s: %v", config.AgentConfig.NodeName, err)
+				return
+			}
+			lastIssuerHash = issuerHash
+		}
+
+		servingIssuerHash, err := agentconfig.ReissueServingCert(ctx, config, info)
+		if err != nil {
+			logrus.Warnf("Failed to re-issue kubelet serving certificate: %v", err)
+			return
+		}
+		if servingIssuerHash != lastServingIssuerHash {
+			if err := patchNode(ctx, nodes, config.AgentConfig.NodeName, nodeconfig.ServingCertIssuerPatch, servingIssuerHash); err != nil {
+				logrus.Warnf("Failed to set serving cert issuer annotation on node %s: %v", config.AgentConfig.NodeName, err)
+				return
+			}
+			lastServingIssuerHash = servingIssuerHash
+		}
+	}, caTrustSyncInterval)
+}
+
+// patchNode applies the patch generated from hash to the node.
+func patchNode(ctx context.Context, nodes typedcorev1.NodeInterface, nodeName string, genPatch func(string) ([]byte, error), hash string) error {
+	patch, err := genPatch(hash)
+	if err != nil {
+		return err
+	}
+	_, err = nodes.Patch(ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// syncCAFile retrieves a CA bundle from the supervisor, and replaces the local file if the contents
+// have changed. The current contents of the bundle are returned.
+func syncCAFile(info *clientaccess.Info, file string) ([]byte, error) {
+	b, err := info.Get("/v1-" + version.Program + "/" + filepath.Base(file))
+	if err != nil {
+		return nil, err
+	}
+	if current, err := os.ReadFile(file); err == nil && bytes.Equal(current, b) {
+		return b, nil
+	}
+	if err := util.AtomicWrite(file, b, 0600); err != nil {
+		return nil, err
+	}
+	logrus.Infof("Updated CA bundle %s from server", file)
+	return b, nil
+}
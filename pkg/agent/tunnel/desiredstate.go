@@ -0,0 +1,153 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/agent/containerd"
+	"github.com/k3s-io/k3s/pkg/agent/proxy"
+	"github.com/k3s-io/k3s/pkg/clientaccess"
+	daemonconfig "github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/desiredstate"
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// desiredStateSyncInterval is the interval at which the agent checks the supervisor for changes to its desired state.
+var desiredStateSyncInterval = time.Minute
+
+// watchDesiredState periodically retrieves this node's desired state, merged from NodeConfig resources, from
+// the supervisor, and applies the parts of it that are local to the node. Kubelet settings are written to a
+// drop-in that the kubelet reads the next time it is started; registry mirrors are written to the containerd
+// registry hosts files, which containerd reads each time it pulls an image. Labels and taints are applied to
+// the node object by the servers. The hash of the applied state is recorded on the node, so that servers can
+// track which nodes have applied their configuration.
+func watchDesiredState(ctx context.Context, apiServerReady <-chan struct{}, config *daemonconfig.Node, proxy proxy.Proxy) {
+	dropIn := filepath.Join(config.AgentConfig.KubeletConfigDir, desiredstate.KubeletDropIn)
+	// the drop-in has not yet been written by this process, so its current contents are what the kubelet
+	// is started with.
+	loaded, _ := os.ReadFile(dropIn)
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-apiServerReady:
+	}
+
+	client, err := util.GetClientSet(config.AgentConfig.KubeConfigKubelet)
+	if err != nil {
+		logrus.Warnf("Failed to create client for desired state sync: %v", err)
+		return
+	}
+	nodes := client.CoreV1().Nodes()
+	embeddedContainerd := !config.Docker && config.ContainerRuntimeEndpoint == ""
+	lastRegistries, _ := json.Marshal(desiredstate.State{}.Registries)
+
+	var info *clientaccess.Info
+	var lastPatch []byte
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		// wait for the node to be registered, so that the kubelet has loaded its configuration before
+		// the drop-in is changed.
+		if _, err := nodes.Get(ctx, config.AgentConfig.NodeName, metav1.GetOptions{}); err != nil {
+			if !apierrors.IsNotFound(err) {
+				logrus.Warnf("Failed to get node %s for desired state sync: %v", config.AgentConfig.NodeName, err)
+			}
+			return
+		}
+		if info == nil {
+			withCert := clientaccess.WithClientCertificate(config.AgentConfig.ClientKubeletCert, config.AgentConfig.ClientKubeletKey)
+			info, err = clientaccess.ParseAndValidateToken(proxy.SupervisorURL(), config.Token, withCert)
+			if err != nil {
+				logrus.Warnf("Failed to validate server token for desired state sync: %v", err)
+				return
+			}
+		}
+
+		b, err := info.Get("/v1-" + version.Program + "/desired-state")
+		if err != nil {
+			logrus.Warnf("Failed to retrieve desired state: %v", err)
+			return
+		}
+		state := &desiredstate.State{}
+		if err := json.Unmarshal(b, state); err != nil {
+			logrus.Warnf("Failed to decode desired state: %v", err)
+			return
+		}
+
+		kubeletConfig, err := desiredstate.KubeletConfig(state.Kubelet)
+		if err != nil {
+			logrus.Warnf("Failed to generate kubelet configuration from desired state: %v", err)
+			return
+		}
+		if err := syncDropIn(dropIn, kubeletConfig); err != nil {
+			logrus.Warnf("Failed to write kubelet configuration from desired state: %v", err)
+			return
+		}
+
+		registries, _ := json.Marshal(state.Registries)
+		if !bytes.Equal(registries, lastRegistries) {
+			if embeddedContainerd {
+				if err := containerd.WriteRegistryHosts(config, desiredstate.MergeRegistry(config.AgentConfig.Registry, state.Registries)); err != nil {
+					logrus.Warnf("Failed to write registry configuration from desired state: %v", err)
+					return
+				}
+				logrus.Infof("Updated registry mirrors from NodeConfigs %v", state.Configs)
+			} else if len(state.Registries.Mirrors) > 0 {
+				logrus.Warnf("Registry mirrors from NodeConfigs %v are only applied to nodes using the embedded containerd", state.Configs)
+			}
+			lastRegistries = registries
+		}
+
+		restartRequired := !bytes.Equal(kubeletConfig, loaded)
+		patch, err := nodeconfig.DesiredStatePatch(state.Hash(), restartRequired)
+		if err != nil {
+			logrus.Warnf("Failed to generate desired state annotations: %v", err)
+			return
+		}
+		if bytes.Equal(patch, lastPatch) {
+			return
+		}
+		if restartRequired {
+			logrus.Warnf("Kubelet settings from NodeConfigs %v have changed, and will take effect when %s is restarted", state.Configs, version.Program)
+		}
+		if _, err := nodes.Patch(ctx, config.AgentConfig.NodeName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			logrus.Warnf("Failed to set desired state annotations on node %s: %v", config.AgentConfig.NodeName, err)
+			return
+		}
+		lastPatch = patch
+	}, desiredStateSyncInterval)
+}
+
+// syncDropIn replaces the kubelet configuration drop-in if its contents have changed, or removes it if
+// there is no configuration.
+func syncDropIn(file string, b []byte) error {
+	current, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if bytes.Equal(current, b) {
+		return nil
+	}
+	if len(b) == 0 {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		logrus.Infof("Removed kubelet configuration drop-in %s", file)
+		return nil
+	}
+	if err := util.AtomicWrite(file, b, 0600); err != nil {
+		return err
+	}
+	logrus.Infof("Updated kubelet configuration drop-in %s", file)
+	return nil
+}
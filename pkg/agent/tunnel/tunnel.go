@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"strconv"
 	"sync"
@@ -50,6 +51,12 @@ type agentTunnel struct {
 	kubeletAddr string
 	kubeletPort string
 	startTime   time.Time
+
+	keepAliveTimeout time.Duration
+	compression      bool
+	reconnectDelay   time.Duration
+	reconnectJitter  float64
+	proxied          bool
 }
 
 // explicit interface check
@@ -88,6 +95,12 @@ func Setup(ctx context.Context, config *daemonconfig.Node, proxy proxy.Proxy) er
 		kubeletAddr: config.AgentConfig.ListenAddress,
 		kubeletPort: fmt.Sprint(ports.KubeletPort),
 		startTime:   time.Now().Truncate(time.Second),
+
+		keepAliveTimeout: config.AgentConfig.TunnelKeepAliveTimeout,
+		compression:      config.AgentConfig.TunnelCompression,
+		reconnectDelay:   config.AgentConfig.TunnelReconnectDelay,
+		reconnectJitter:  config.AgentConfig.TunnelReconnectJitter,
+		proxied:          config.AgentConfig.SupervisorProxy != "",
 	}
 
 	apiServerReady := make(chan struct{})
@@ -112,6 +125,12 @@ func Setup(ctx context.Context, config *daemonconfig.Node, proxy proxy.Proxy) er
 		// Allow the kubelet port, as published via our node object.
 		go tunnel.setKubeletPort(ctx, apiServerReady)
 
+		// Pick up CA bundle changes from the servers, and report the trusted server CA on our node object.
+		go watchCATrust(ctx, apiServerReady, config, proxy)
+
+		// Apply kubelet settings and registry mirrors from NodeConfigs, and report the applied state on our node object.
+		go watchDesiredState(ctx, apiServerReady, config, proxy)
+
 		switch tunnel.mode {
 		case daemonconfig.EgressSelectorModeCluster:
 			// In Cluster mode, we allow the cluster CIDRs, and any connections to the node's IPs for pods using host network.
@@ -134,28 +153,22 @@ func Setup(ctx context.Context, config *daemonconfig.Node, proxy proxy.Proxy) er
 	}
 
 	if proxy.IsSupervisorLBEnabled() && proxy.SupervisorURL() != "" {
-		logrus.Info("Getting list of apiserver endpoints from server")
-		// If not running an apiserver locally, try to get a list of apiservers from the server we're
-		// connecting to. If that fails, fall back to querying the endpoints list from Kubernetes. This
-		// fallback requires that the server we're joining be running an apiserver, but is the only safe
-		// thing to do if its supervisor is down-level and can't provide us with an endpoint list.
-		addresses := agentconfig.WaitForAPIServers(ctx, config, proxy)
+		// If warm restart is enabled and we have a list of apiserver addresses from the
+		// previous run, seed the loadbalancer with them and refresh the list in the background
+		// instead of blocking startup until the server provides a new list.
+		var addresses []string
+		if warmRestartFile := config.AgentConfig.WarmRestartFile; warmRestartFile != "" {
+			addresses = agentconfig.ReadWarmRestartState(warmRestartFile).APIServerAddresses
+		}
 		if len(addresses) > 0 {
-			logrus.Infof("Got apiserver addresses from supervisor: %v", addresses)
+			logrus.Infof("Using cached apiserver addresses for warm restart: %v", addresses)
 			if localSupervisorDefault {
 				proxy.SetSupervisorDefault(addresses[0])
 			}
 			proxy.Update(addresses)
+			go getAPIServerAddresses(ctx, config, proxy, client, false)
 		} else {
-			if endpoint, err := client.CoreV1().Endpoints(metav1.NamespaceDefault).Get(ctx, "kubernetes", metav1.GetOptions{}); err != nil {
-				logrus.Errorf("Failed to get apiserver addresses from kubernetes endpoints: %v", err)
-			} else {
-				addresses := util.GetAddresses(endpoint)
-				logrus.Infof("Got apiserver addresses from kubernetes endpoints: %v", addresses)
-				if len(addresses) > 0 {
-					proxy.Update(addresses)
-				}
-			}
+			getAPIServerAddresses(ctx, config, proxy, client, localSupervisorDefault)
 		}
 	}
 
@@ -179,6 +192,41 @@ func Setup(ctx context.Context, config *daemonconfig.Node, proxy proxy.Proxy) er
 	return nil
 }
 
+// getAPIServerAddresses retrieves the list of apiserver addresses, and updates the proxy with them.
+// If warm restart is enabled, the list of addresses is persisted for use on the next startup.
+func getAPIServerAddresses(ctx context.Context, config *daemonconfig.Node, proxy proxy.Proxy, client kubernetes.Interface, setSupervisorDefault bool) {
+	logrus.Info("Getting list of apiserver endpoints from server")
+	// If not running an apiserver locally, try to get a list of apiservers from the server we're
+	// connecting to. If that fails, fall back to querying the endpoints list from Kubernetes. This
+	// fallback requires that the server we're joining be running an apiserver, but is the only safe
+	// thing to do if its supervisor is down-level and can't provide us with an endpoint list.
+	addresses := agentconfig.WaitForAPIServers(ctx, config, proxy)
+	if len(addresses) > 0 {
+		logrus.Infof("Got apiserver addresses from supervisor: %v", addresses)
+		if setSupervisorDefault {
+			proxy.SetSupervisorDefault(addresses[0])
+		}
+		proxy.Update(addresses)
+	} else {
+		if endpoint, err := client.CoreV1().Endpoints(metav1.NamespaceDefault).Get(ctx, "kubernetes", metav1.GetOptions{}); err != nil {
+			logrus.Errorf("Failed to get apiserver addresses from kubernetes endpoints: %v", err)
+		} else {
+			addresses = util.GetAddresses(endpoint)
+			logrus.Infof("Got apiserver addresses from kubernetes endpoints: %v", addresses)
+			if len(addresses) > 0 {
+				proxy.Update(addresses)
+			}
+		}
+	}
+
+	if warmRestartFile := config.AgentConfig.WarmRestartFile; warmRestartFile != "" && len(addresses) > 0 {
+		state := &agentconfig.WarmRestartState{APIServerAddresses: addresses}
+		if err := agentconfig.WriteWarmRestartState(warmRestartFile, state); err != nil {
+			logrus.Warnf("Failed to write warm restart state: %v", err)
+		}
+	}
+}
+
 // setKubeletPort retrieves the configured kubelet port from our node object
 func (a *agentTunnel) setKubeletPort(ctx context.Context, apiServerReady <-chan struct{}) {
 	<-apiServerReady
@@ -407,7 +455,13 @@ func (a *agentTunnel) connect(rootCtx context.Context, waitGroup *sync.WaitGroup
 
 	wsURL := fmt.Sprintf("wss://%s/v1-"+version.Program+"/connect", address)
 	ws := &websocket.Dialer{
-		TLSClientConfig: tlsConfig,
+		NetDialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return a.dialTLS(ctx, network, addr, tlsConfig)
+		},
+	}
+	var headers http.Header
+	if a.compression {
+		headers = http.Header{util.TunnelCompressionHeader: []string{util.TunnelCompressionDeflate}}
 	}
 
 	once := sync.Once{}
@@ -433,12 +487,15 @@ func (a *agentTunnel) connect(rootCtx context.Context, waitGroup *sync.WaitGroup
 	go func() {
 		for {
 			// ConnectToProxy blocks until error or context cancellation
-			err := remotedialer.ConnectToProxyWithDialer(ctx, wsURL, nil, auth, ws, a.dialContext, onConnect)
+			err := remotedialer.ConnectToProxyWithDialer(ctx, wsURL, headers, auth, ws, a.dialContext, onConnect)
 			status = loadbalancer.HealthCheckResultFailed
 			if err != nil && !errors.Is(err, context.Canceled) {
 				logrus.WithField("url", wsURL).WithError(err).Error("Remotedialer proxy error; reconnecting...")
 				// wait between reconnection attempts to avoid hammering the server
-				time.Sleep(endpointDebounceDelay)
+				select {
+				case <-time.After(a.getReconnectDelay()):
+				case <-ctx.Done():
+				}
 			}
 			// If the context has been cancelled, exit the goroutine instead of retrying
 			if ctx.Err() != nil {
@@ -458,6 +515,48 @@ func (a *agentTunnel) connect(rootCtx context.Context, waitGroup *sync.WaitGroup
 	}
 }
 
+// dialTLS dials a server's websocket tunnel endpoint, through the same proxy as the loadbalancer
+// if one is configured, and wraps the connection so that the tunnel is compressed if requested,
+// and closed if websocket pings are not acknowledged within the keepalive timeout.
+func (a *agentTunnel) dialTLS(ctx context.Context, network, addr string, tlsConfig *tls.Config) (net.Conn, error) {
+	dialContext := defaultDialer.DialContext
+	if a.proxied {
+		dialContext = loadbalancer.DialContext
+	}
+	conn, err := dialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig = tlsConfig.Clone()
+	if tlsConfig.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		tlsConfig.ServerName = host
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return util.NewTunnelClientConn(tlsConn, a.compression, a.keepAliveTimeout), nil
+}
+
+// getReconnectDelay returns the time to wait before reconnecting to a server, with jitter
+// applied if configured so that agents do not all reconnect at once after a server outage.
+func (a *agentTunnel) getReconnectDelay() time.Duration {
+	delay := a.reconnectDelay
+	if delay <= 0 {
+		delay = endpointDebounceDelay
+	}
+	if a.reconnectJitter > 0 {
+		delay = wait.Jitter(delay, a.reconnectJitter)
+	}
+	return delay
+}
+
 // isKubeletOrStreamPort returns true if the connection is to a reserved TCP port on a loopback address.
 func (a *agentTunnel) isKubeletOrStreamPort(proto, host, port string) bool {
 	return proto == "tcp" && (host == "127.0.0.1" || host == "::1") && (port == a.kubeletPort || port == daemonconfig.StreamServerPort)
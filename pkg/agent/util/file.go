@@ -1,9 +1,11 @@
 package util
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 
+	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/pkg/errors"
 )
 
@@ -30,3 +32,18 @@ func CopyFile(sourceFile string, destinationFile string, ignoreNotExist bool) er
 	}
 	return nil
 }
+
+// WriteFileIfChanged atomically writes the file if its content differs from the existing file, and
+// returns true if the file was written.
+func WriteFileIfChanged(name string, content []byte, perm os.FileMode) (bool, error) {
+	if existing, err := os.ReadFile(name); err == nil && bytes.Equal(existing, content) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return false, err
+	}
+	if err := util.AtomicWrite(name, content, perm); err != nil {
+		return false, errors.Wrapf(err, "writing %s", name)
+	}
+	return true, nil
+}
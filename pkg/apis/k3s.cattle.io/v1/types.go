@@ -90,9 +90,34 @@ type ETCDSnapshotStatus struct {
 	CreationTime *metav1.Time `json:"creationTime,omitempty" column:""`
 	// ReadyToUse indicates that the snapshot is available to be restored.
 	ReadyToUse *bool `json:"readyToUse,omitempty"`
+	// Checksum is the hex-encoded sha256 checksum of the snapshot file, as written to storage.
+	// If not specified, the snapshot was taken before checksums were recorded.
+	Checksum string `json:"checksum,omitempty"`
+	// Revision is the etcd revision at which the snapshot was taken.
+	Revision int64 `json:"revision,omitempty"`
 	// Error is the last observed error during snapshot creation, if any.
 	// If the snapshot is retried, this field will be cleared on success.
 	Error *ETCDSnapshotError `json:"error,omitempty"`
+	// DownloadURL is a signed URL from which the snapshot file can be downloaded from the supervisor
+	// of a server with access to the snapshot, using a client that trusts the cluster's server CA.
+	// No other credentials are required. If not specified, the snapshot cannot be downloaded.
+	DownloadURL string `json:"downloadURL,omitempty"`
+	// DownloadURLExpiration is the time after which the download URL is no longer valid. The URL is
+	// refreshed by the server before it expires.
+	DownloadURLExpiration *metav1.Time `json:"downloadURLExpiration,omitempty"`
+	// Retention describes the automatic retention policy that applies to the snapshot.
+	Retention *ETCDSnapshotRetention `json:"retention,omitempty"`
+}
+
+// ETCDSnapshotRetention describes the automatic retention policy that applies to a snapshot.
+type ETCDSnapshotRetention struct {
+	// Policy is Count if the snapshot is pruned once there are more than Count newer snapshots
+	// with the same Prefix in the same storage location, or None if the snapshot is not automatically pruned.
+	Policy string `json:"policy"`
+	// Count is the number of snapshots retained by the Count policy.
+	Count int `json:"count,omitempty"`
+	// Prefix is the snapshot name prefix that the Count policy applies to.
+	Prefix string `json:"prefix,omitempty"`
 }
 
 // ETCDSnapshotError describes an error encountered during snapshot creation.
@@ -103,3 +128,242 @@ type ETCDSnapshotError struct {
 	// NOTE: message may be logged, and it should not contain sensitive information.
 	Message *string `json:"message,omitempty"`
 }
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterSecretsEncryption tracks the progress of secrets encryption key rotation and reencryption
+// across all servers in the cluster. It is maintained by the servers, and should not be modified.
+type ClusterSecretsEncryption struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Status represents the current secrets encryption state of the cluster.
+	Status ClusterSecretsEncryptionStatus `json:"status,omitempty"`
+}
+
+// ClusterSecretsEncryptionStatus is the status of the ClusterSecretsEncryption object.
+type ClusterSecretsEncryptionStatus struct {
+	// Phase is the secrets encryption stage that servers are converging on, for example
+	// prepare, rotate, or reencrypt_finished.
+	Phase string `json:"phase,omitempty" column:""`
+	// KeyHash is the hash of the encryption configuration that servers are converging on.
+	KeyHash string `json:"keyHash,omitempty"`
+	// NodesDone is the number of servers that have reached the current phase and key hash.
+	NodesDone int `json:"nodesDone" column:"name=Done"`
+	// NodesTotal is the number of servers in the cluster.
+	NodesTotal int `json:"nodesTotal" column:"name=Total"`
+	// Converged is true once all servers have reached the current phase and key hash.
+	Converged bool `json:"converged" column:""`
+	// Nodes contains the secrets encryption state of each server.
+	Nodes []SecretsEncryptionNodeStatus `json:"nodes,omitempty"`
+}
+
+// SecretsEncryptionNodeStatus describes the secrets encryption state of a single server.
+type SecretsEncryptionNodeStatus struct {
+	// Name is the name of the server's node.
+	Name string `json:"name"`
+	// Phase is the secrets encryption stage that the server has reached.
+	Phase string `json:"phase,omitempty"`
+	// KeyHash is the hash of the encryption configuration in use by the server.
+	KeyHash string `json:"keyHash,omitempty"`
+	// LastTransitionTime is the time that the server's phase or key hash last changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageDigestPin records the digest that an image tag resolved to when it was first pulled by a node
+// in the cluster. When digest pinning is enabled for the embedded registry mirror, nodes resolve the
+// tag to the pinned digest, so that every node runs the same image even if the upstream tag is moved.
+// Deleting an ImageDigestPin allows the tag to be re-pinned on the next pull.
+type ImageDigestPin struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec describes the pinned image.
+	Spec ImageDigestPinSpec `json:"spec"`
+}
+
+// ImageDigestPinSpec describes an image tag and the manifest that it is pinned to.
+type ImageDigestPinSpec struct {
+	// Image is the tagged image reference, including the registry, for example docker.io/library/busybox:1.36.
+	Image string `json:"image" column:""`
+	// Digest is the digest of the manifest or index that the tag is pinned to.
+	Digest string `json:"digest" column:""`
+	// MediaType is the media type of the manifest or index that the tag is pinned to.
+	MediaType string `json:"mediaType"`
+	// Size is the size in bytes of the manifest or index that the tag is pinned to.
+	Size int64 `json:"size"`
+	// NodeName is the name of the node that pinned the tag.
+	NodeName string `json:"nodeName,omitempty" column:"name=Node"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterCARotation tracks the progress of a phased certificate authority rotation across all nodes
+// in the cluster. It is maintained by the servers, and should not be modified.
+type ClusterCARotation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Status represents the current CA rotation state of the cluster.
+	Status ClusterCARotationStatus `json:"status,omitempty"`
+}
+
+// ClusterCARotationStatus is the status of the ClusterCARotation object.
+type ClusterCARotationStatus struct {
+	// Phase is the most recently applied CA rotation phase: trust, reissue, or retire.
+	Phase string `json:"phase,omitempty" column:""`
+	// BundleHash is the hash of the server CA bundle that nodes are expected to trust in the current phase.
+	BundleHash string `json:"bundleHash,omitempty"`
+	// IssuerHash is the hash of the CA certificate that nodes are expected to have re-issued their client
+	// certificates with in the current phase. It is empty if client certificates do not need to be re-issued.
+	IssuerHash string `json:"issuerHash,omitempty"`
+	// ServingIssuerHash is the hash of the CA certificate that nodes are expected to have re-issued their
+	// kubelet serving certificates with in the current phase. It is empty if serving certificates do not need
+	// to be re-issued.
+	ServingIssuerHash string `json:"servingIssuerHash,omitempty"`
+	// NodesDone is the number of nodes that trust the current server CA bundle, and have re-issued their
+	// client and serving certificates if required.
+	NodesDone int `json:"nodesDone" column:"name=Done"`
+	// NodesTotal is the number of nodes in the cluster.
+	NodesTotal int `json:"nodesTotal" column:"name=Total"`
+	// Converged is true once all nodes are done.
+	Converged bool `json:"converged" column:""`
+	// Nodes contains the CA trust state of each node.
+	Nodes []CARotationNodeStatus `json:"nodes,omitempty"`
+}
+
+// CARotationNodeStatus describes the CA trust state of a single node.
+type CARotationNodeStatus struct {
+	// Name is the name of the node.
+	Name string `json:"name"`
+	// BundleHash is the hash of the server CA bundle in use by the node's agent.
+	BundleHash string `json:"bundleHash,omitempty"`
+	// Trusted is true if the node trusts the current server CA bundle.
+	Trusted bool `json:"trusted"`
+	// IssuerHash is the hash of the CA certificate that issued the client certificates in use by the node's agent.
+	IssuerHash string `json:"issuerHash,omitempty"`
+	// ServingIssuerHash is the hash of the CA certificate that issued the node's kubelet serving certificate.
+	ServingIssuerHash string `json:"servingIssuerHash,omitempty"`
+	// Reissued is true if the node's client and serving certificates were issued by the expected CA certificates.
+	Reissued bool `json:"reissued"`
+	// LastTransitionTime is the time that the node's bundle hash last changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NodeConfig declares configuration for the nodes that it selects, which is applied without editing
+// the configuration file or restarting k3s on each node. Labels and taints are set on the node objects
+// by the servers; kubelet settings and registry mirrors are retrieved from the servers and applied by
+// each node's agent. When more than one NodeConfig selects a node, they are merged in order of name,
+// with values from later NodeConfigs taking precedence.
+type NodeConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec describes the nodes to configure, and their configuration.
+	Spec NodeConfigSpec `json:"spec"`
+	// Status represents the progress of the selected nodes towards the configuration.
+	Status NodeConfigStatus `json:"status,omitempty"`
+}
+
+// NodeConfigSpec describes the nodes to configure, and their configuration.
+type NodeConfigSpec struct {
+	// NodeNames selects nodes by name.
+	NodeNames []string `json:"nodeNames,omitempty"`
+	// NodeSelector selects nodes that have all of the given labels. Labels set by NodeConfigs are not
+	// considered. Nodes are selected if they are named in NodeNames or match NodeSelector; if neither
+	// are set, all nodes are selected.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Labels are set on the selected nodes.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Taints are set on the selected nodes.
+	Taints []NodeConfigTaint `json:"taints,omitempty"`
+	// Kubelet contains kubelet settings for the selected nodes. The kubelet only reads its configuration
+	// when it starts, and runs within the k3s process, so these settings only take effect when k3s is next
+	// restarted on each node. k3s is not restarted automatically; nodes that must be restarted are listed
+	// in the status with RestartRequired set.
+	Kubelet NodeConfigKubelet `json:"kubelet,omitempty"`
+	// Registries contains registry mirrors for the selected nodes, which are merged with the mirrors
+	// from each node's registries.yaml. Mirrors are only applied to nodes using the embedded containerd.
+	Registries NodeConfigRegistries `json:"registries,omitempty"`
+}
+
+// NodeConfigTaint is a taint set on the selected nodes.
+type NodeConfigTaint struct {
+	// Key is the taint key.
+	Key string `json:"key"`
+	// Value is the taint value.
+	Value string `json:"value,omitempty"`
+	// Effect is the taint effect: NoSchedule, PreferNoSchedule, or NoExecute.
+	Effect string `json:"effect"`
+}
+
+// NodeConfigKubelet contains the kubelet settings that can be set by a NodeConfig. Unset values are
+// left at the value from the node's configuration.
+type NodeConfigKubelet struct {
+	// MaxPods is the maximum number of pods that can run on the node.
+	MaxPods int32 `json:"maxPods,omitempty"`
+	// ImageGCHighThresholdPercent is the percent of disk usage after which image garbage collection is always run.
+	ImageGCHighThresholdPercent int32 `json:"imageGCHighThresholdPercent,omitempty"`
+	// ImageGCLowThresholdPercent is the percent of disk usage before which image garbage collection is never run.
+	ImageGCLowThresholdPercent int32 `json:"imageGCLowThresholdPercent,omitempty"`
+	// EvictionHard is a map of signal names to quantities that define hard eviction thresholds.
+	EvictionHard map[string]string `json:"evictionHard,omitempty"`
+	// SystemReserved is a map of resource names to quantities reserved for system components.
+	SystemReserved map[string]string `json:"systemReserved,omitempty"`
+	// KubeReserved is a map of resource names to quantities reserved for Kubernetes components.
+	KubeReserved map[string]string `json:"kubeReserved,omitempty"`
+}
+
+// NodeConfigRegistries contains registry mirrors for the selected nodes.
+type NodeConfigRegistries struct {
+	// Mirrors maps registry hosts to their mirrors, as in the mirrors section of registries.yaml. A mirror
+	// for a host replaces the mirror for the same host from registries.yaml.
+	Mirrors map[string]NodeConfigMirror `json:"mirrors,omitempty"`
+}
+
+// NodeConfigMirror is a registry mirror.
+type NodeConfigMirror struct {
+	// Endpoints are the mirror endpoints, tried in order before the default endpoint for the registry.
+	Endpoints []string `json:"endpoint,omitempty"`
+}
+
+// NodeConfigStatus is the status of the NodeConfig object.
+type NodeConfigStatus struct {
+	// NodesSelected is the number of nodes selected by the NodeConfig.
+	NodesSelected int `json:"nodesSelected" column:"name=Selected"`
+	// NodesApplied is the number of selected nodes that have applied their current configuration.
+	NodesApplied int `json:"nodesApplied" column:"name=Applied"`
+	// NodesRestartRequired is the number of selected nodes that have applied kubelet settings that do not
+	// take effect until k3s is restarted on the node.
+	NodesRestartRequired int `json:"nodesRestartRequired,omitempty" column:"name=RestartRequired"`
+	// Nodes contains the configuration state of each selected node.
+	Nodes []NodeConfigNodeStatus `json:"nodes,omitempty"`
+}
+
+// NodeConfigNodeStatus describes the configuration state of a single node.
+type NodeConfigNodeStatus struct {
+	// Name is the name of the node.
+	Name string `json:"name"`
+	// Applied is true if the node's agent has applied the node's current configuration, merged from all
+	// NodeConfigs that select it. Kubelet settings are not in effect until k3s is restarted on the node if
+	// RestartRequired is also set.
+	Applied bool `json:"applied"`
+	// RestartRequired is true if the node's agent has written kubelet settings that do not take effect
+	// until k3s is restarted on the node.
+	RestartRequired bool `json:"restartRequired,omitempty"`
+	// LastTransitionTime is the time that the node's applied state last changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
@@ -57,3 +57,71 @@ func NewETCDSnapshotFile(namespace, name string, obj ETCDSnapshotFile) *ETCDSnap
 	obj.Namespace = namespace
 	return &obj
 }
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterSecretsEncryptionList is a list of ClusterSecretsEncryption resources
+type ClusterSecretsEncryptionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ClusterSecretsEncryption `json:"items"`
+}
+
+func NewClusterSecretsEncryption(namespace, name string, obj ClusterSecretsEncryption) *ClusterSecretsEncryption {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("ClusterSecretsEncryption").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageDigestPinList is a list of ImageDigestPin resources
+type ImageDigestPinList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ImageDigestPin `json:"items"`
+}
+
+func NewImageDigestPin(namespace, name string, obj ImageDigestPin) *ImageDigestPin {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("ImageDigestPin").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterCARotationList is a list of ClusterCARotation resources
+type ClusterCARotationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ClusterCARotation `json:"items"`
+}
+
+func NewClusterCARotation(namespace, name string, obj ClusterCARotation) *ClusterCARotation {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("ClusterCARotation").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NodeConfigList is a list of NodeConfig resources
+type NodeConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []NodeConfig `json:"items"`
+}
+
+func NewNodeConfig(namespace, name string, obj NodeConfig) *NodeConfig {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("NodeConfig").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
@@ -28,8 +28,12 @@ import (
 )
 
 var (
-	AddonResourceName            = "addons"
-	ETCDSnapshotFileResourceName = "etcdsnapshotfiles"
+	AddonResourceName                    = "addons"
+	ClusterCARotationResourceName        = "clustercarotations"
+	ClusterSecretsEncryptionResourceName = "clustersecretsencryptions"
+	ETCDSnapshotFileResourceName         = "etcdsnapshotfiles"
+	ImageDigestPinResourceName           = "imagedigestpins"
+	NodeConfigResourceName               = "nodeconfigs"
 )
 
 // SchemeGroupVersion is group version used to register these objects
@@ -55,8 +59,16 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Addon{},
 		&AddonList{},
+		&ClusterCARotation{},
+		&ClusterCARotationList{},
+		&ClusterSecretsEncryption{},
+		&ClusterSecretsEncryptionList{},
 		&ETCDSnapshotFile{},
 		&ETCDSnapshotFileList{},
+		&ImageDigestPin{},
+		&ImageDigestPinList{},
+		&NodeConfig{},
+		&NodeConfigList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -0,0 +1,89 @@
+// Package v1alpha1 contains the types served by the supervisor's aggregated API. These are not
+// custom resources; requests for them are proxied to the supervisor by the apiserver's aggregation
+// layer, and they are not stored in the datastore.
+//
+// +groupName=k3s.cattle.io
+package v1alpha1
+
+import (
+	v1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// SchemeGroupVersion is the group version served by the supervisor's aggregated API.
+var SchemeGroupVersion = schema.GroupVersion{Group: "k3s.cattle.io", Version: "v1alpha1"}
+
+// ETCDSnapshot is an etcd snapshot. Creating an ETCDSnapshot takes an on-demand snapshot, using the
+// object name as the base name of the snapshot file. Deleting an ETCDSnapshot deletes the snapshot
+// file, along with any other copies of the snapshot that have the same snapshot name.
+type ETCDSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec describes the snapshot file.
+	Spec v1.ETCDSnapshotSpec `json:"spec,omitempty"`
+	// Status represents current information about the snapshot.
+	Status v1.ETCDSnapshotStatus `json:"status,omitempty"`
+}
+
+// ETCDSnapshotList is a list of etcd snapshots.
+type ETCDSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ETCDSnapshot `json:"items"`
+}
+
+// TokenRotation rotates the server token. TokenRotations can only be created; the server token is
+// replaced when the request is handled, and the object is not persisted.
+type TokenRotation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec describes the token rotation.
+	Spec TokenRotationSpec `json:"spec,omitempty"`
+}
+
+// TokenRotationSpec describes a server token rotation.
+type TokenRotationSpec struct {
+	// NewToken is the token that replaces the existing server token. If not specified, a random
+	// token is generated, and can be read from the token file on the servers.
+	NewToken string `json:"newToken,omitempty"`
+}
+
+// CertificateCheck is the status of a certificate used by a component on a server, as reported by
+// the certificate check command. Certificates are read from disk on the server that handles the request.
+type CertificateCheck struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Status represents current information about the certificate.
+	Status CertificateCheckStatus `json:"status,omitempty"`
+}
+
+// CertificateCheckStatus is the status of a certificate.
+type CertificateCheckStatus struct {
+	// NodeName contains the name of the server that the certificate was read from.
+	NodeName string `json:"nodeName"`
+	// Service is the name of the component that uses the certificate.
+	Service string `json:"service"`
+	// File is the path on disk to the certificate.
+	File string `json:"file"`
+	// Subject is the certificate subject.
+	Subject string `json:"subject"`
+	// NotBefore is the time at which the certificate becomes valid.
+	NotBefore metav1.Time `json:"notBefore"`
+	// NotAfter is the time at which the certificate expires.
+	NotAfter metav1.Time `json:"notAfter"`
+	// Status is one of OK, WARNING, EXPIRED, or NOT YET VALID.
+	Status string `json:"status"`
+}
+
+// CertificateCheckList is a list of certificate checks.
+type CertificateCheckList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []CertificateCheck `json:"items"`
+}
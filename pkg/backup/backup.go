@@ -0,0 +1,176 @@
+// Package backup reads and writes server backup archives. A backup archive is a gzip-compressed
+// tarball containing a metadata file, and files from the server's data-dir stored at their path
+// relative to the data-dir, so that restoring a backup is a matter of extracting it into an empty
+// data-dir.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MetadataFile is the name of the metadata file within a backup archive.
+const MetadataFile = "backup.json"
+
+const (
+	DatastoreEtcd     = "etcd"
+	DatastoreSQLite   = "sqlite"
+	DatastoreExternal = "external"
+)
+
+// Metadata describes the content of a backup archive.
+type Metadata struct {
+	Version   string    `json:"version"`
+	Created   time.Time `json:"created"`
+	NodeName  string    `json:"nodeName"`
+	Datastore string    `json:"datastore"`
+	// Snapshot is the path of the etcd snapshot within the archive, if the datastore is etcd.
+	Snapshot string `json:"snapshot,omitempty"`
+	// Files lists the paths of all files within the archive, other than the metadata file.
+	Files []string `json:"files"`
+}
+
+// Write writes a backup archive. Files are read from the data-dir; each entry in paths is relative
+// to the data-dir, and may be a file or a directory. Directories are added recursively, and paths
+// that do not exist are skipped.
+func Write(w io.Writer, dataDir string, paths []string, meta *Metadata) error {
+	files := []string{}
+	for _, p := range paths {
+		err := filepath.WalkDir(filepath.Join(dataDir, p), func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.Type().IsRegular() {
+				rel, err := filepath.Rel(dataDir, file)
+				if err != nil {
+					return err
+				}
+				files = append(files, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	sort.Strings(files)
+	meta.Files = files
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	b, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: MetadataFile, Mode: 0600, Size: int64(len(b)), ModTime: meta.Created}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(b); err != nil {
+		return err
+	}
+
+	for _, name := range files {
+		if err := addFile(tw, filepath.Join(dataDir, filepath.FromSlash(name)), name); err != nil {
+			return errors.Wrapf(err, "failed to add %s to backup", name)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addFile(tw *tar.Writer, file, name string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Extract extracts a backup archive into the data-dir, and returns its metadata.
+func Extract(r io.Reader, dataDir string) (*Metadata, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	var meta *Metadata
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Name == MetadataFile {
+			meta = &Metadata{}
+			if err := json.NewDecoder(tr).Decode(meta); err != nil {
+				return nil, errors.Wrap(err, "failed to read backup metadata")
+			}
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("invalid path %s in backup", hdr.Name)
+		}
+		if err := extractFile(tr, filepath.Join(dataDir, filepath.FromSlash(name)), hdr.FileInfo().Mode().Perm()); err != nil {
+			return nil, errors.Wrapf(err, "failed to extract %s from backup", name)
+		}
+	}
+	if meta == nil {
+		return nil, fmt.Errorf("backup does not contain %s", MetadataFile)
+	}
+	return meta, nil
+}
+
+func extractFile(r io.Reader, file string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
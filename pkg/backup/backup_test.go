@@ -0,0 +1,89 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_UnitWriteAndExtract(t *testing.T) {
+	src := t.TempDir()
+	files := map[string]string{
+		"server/tls/server-ca.crt":     "ca",
+		"server/tls/etcd/peer-ca.key":  "key",
+		"server/token":                 "token",
+		"server/manifests/app.yaml":    "app",
+		"server/manifests/other.yaml":  "other",
+		"server/db/backup/snapshot-1":  "snapshot",
+		"server/db/etcd/member/wal/db": "not included",
+	}
+	for name, content := range files {
+		path := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	meta := &Metadata{
+		Version:   "v1.32.1+k3s1",
+		Created:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		NodeName:  "server1",
+		Datastore: DatastoreEtcd,
+		Snapshot:  "server/db/backup/snapshot-1",
+	}
+	buf := &bytes.Buffer{}
+	paths := []string{"server/tls", "server/token", "server/agent-token", "server/db/backup", "server/manifests/app.yaml"}
+	if err := Write(buf, src, paths, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := t.TempDir()
+	got, err := Extract(bytes.NewReader(buf.Bytes()), dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFiles := []string{"server/db/backup/snapshot-1", "server/manifests/app.yaml", "server/tls/etcd/peer-ca.key", "server/tls/server-ca.crt", "server/token"}
+	if !reflect.DeepEqual(got.Files, wantFiles) || got.Snapshot != meta.Snapshot || !got.Created.Equal(meta.Created) {
+		t.Errorf("Extract() metadata = %+v, want files %v", got, wantFiles)
+	}
+	for _, name := range wantFiles {
+		b, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil || string(b) != files[name] {
+			t.Errorf("Extract() %s = %q, %v; want %q", name, b, err, files[name])
+		}
+	}
+	for _, name := range []string{"server/manifests/other.yaml", "server/db/etcd/member/wal/db"} {
+		if _, err := os.Stat(filepath.Join(dest, name)); err == nil {
+			t.Errorf("Extract() extracted %s, which was not included in the backup", name)
+		}
+	}
+}
+
+func Test_UnitExtractInvalidPath(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	content := []byte("escape")
+	if err := tw.WriteHeader(&tar.Header{Name: "../escape", Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(content)
+	tw.Close()
+	gz.Close()
+
+	dest := filepath.Join(t.TempDir(), "data")
+	if _, err := Extract(buf, dest); err == nil {
+		t.Error("Extract() succeeded for archive with a path outside the data-dir")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dest), "escape")); err == nil {
+		t.Error("Extract() wrote a file outside the data-dir")
+	}
+}
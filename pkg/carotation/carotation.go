@@ -0,0 +1,163 @@
+package carotation
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+
+	apisv1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	controllersv1 "github.com/k3s-io/k3s/pkg/generated/controllers/k3s.cattle.io/v1"
+	"github.com/k3s-io/k3s/pkg/version"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// Phases of a phased CA rotation. Each phase is applied by saving updated CA certificates and keys to
+// the datastore, and restarting the servers.
+const (
+	// PhaseTrust adds the new CA certificates to the trust bundles, while continuing to sign with the current CA.
+	PhaseTrust = "trust"
+	// PhaseReissue signs with the new CA, so that leaf certificates are reissued when servers are restarted.
+	// Agents re-issue their client certificates without restarting. The old CA certificates remain in the
+	// trust bundles.
+	PhaseReissue = "reissue"
+	// PhaseRetire removes the old CA certificates from the trust bundles.
+	PhaseRetire = "retire"
+)
+
+// Phases lists the CA rotation phases in the order they must be applied.
+var Phases = []string{PhaseTrust, PhaseReissue, PhaseRetire}
+
+// StatusName is the name of the ClusterCARotation object that tracks CA rotation progress.
+var StatusName = version.Program + "-ca-rotation"
+
+// IsValidPhase returns true if the phase is a known CA rotation phase.
+func IsValidPhase(phase string) bool {
+	for _, p := range Phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// previousPhase returns the phase that must have been applied, and converged, before the given phase.
+func previousPhase(phase string) string {
+	for i, p := range Phases {
+		if p == phase && i > 0 {
+			return Phases[i-1]
+		}
+	}
+	return ""
+}
+
+// ValidatePhase checks that the current CA bundle is in the expected state for the requested phase:
+// the new CA must not yet be signing when adding it to the trust bundle, must already be trusted when
+// switching to it for signing, and must already be signing when retiring the old CA.
+func ValidatePhase(phase string, current, new []*x509.Certificate) error {
+	if len(current) == 0 || len(new) == 0 {
+		return fmt.Errorf("CA bundle is empty")
+	}
+	switch phase {
+	case PhaseTrust:
+		if current[0].Equal(new[0]) {
+			return fmt.Errorf("new CA certificate %s is already the signing CA", new[0].Subject)
+		}
+	case PhaseReissue:
+		if !contains(current, new[0]) {
+			return fmt.Errorf("new CA certificate %s is not yet trusted; the %s phase must be applied first", new[0].Subject, PhaseTrust)
+		}
+	case PhaseRetire:
+		if !current[0].Equal(new[0]) {
+			return fmt.Errorf("new CA certificate %s is not yet the signing CA; the %s phase must be applied first", new[0].Subject, PhaseReissue)
+		}
+	default:
+		return fmt.Errorf("unknown CA rotation phase %s", phase)
+	}
+	return nil
+}
+
+// ComposeBundle returns the CA bundle to use for the requested phase. The first certificate in the
+// bundle is the CA that is used for signing; the remaining certificates are trusted. During the trust
+// phase the current CA continues to sign, and during the reissue phase the new CA signs, with both
+// trusted. Once the old CA is retired, only the new certificates remain.
+func ComposeBundle(phase string, current, new []*x509.Certificate) []*x509.Certificate {
+	switch phase {
+	case PhaseTrust:
+		return appendMissing(current, new)
+	case PhaseReissue:
+		return appendMissing(new, current)
+	default:
+		return new
+	}
+}
+
+// appendMissing returns a copy of a with any certificates from b that are not already present appended.
+func appendMissing(a, b []*x509.Certificate) []*x509.Certificate {
+	certs := append([]*x509.Certificate{}, a...)
+	for _, cert := range b {
+		if !contains(certs, cert) {
+			certs = append(certs, cert)
+		}
+	}
+	return certs
+}
+
+func contains(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if bytes.Equal(c.Raw, cert.Raw) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckConverged returns an error if the phase that must precede the requested phase has not been
+// applied, or not all nodes trust the CA bundle from that phase yet.
+func CheckConverged(rotations controllersv1.ClusterCARotationClient, phase string) error {
+	prev := previousPhase(phase)
+	if prev == "" {
+		return nil
+	}
+	rotation, err := rotations.Get(StatusName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("the %s phase has not been applied", prev)
+	} else if err != nil {
+		return err
+	}
+	if rotation.Status.Phase != prev {
+		return fmt.Errorf("the %s phase must be applied before %s, current phase is %s", prev, phase, rotation.Status.Phase)
+	}
+	if !rotation.Status.Converged {
+		if rotation.Status.IssuerHash != "" || rotation.Status.ServingIssuerHash != "" {
+			return fmt.Errorf("%d of %d nodes trust the CA bundle and have re-issued their client and serving certificates from the %s phase", rotation.Status.NodesDone, rotation.Status.NodesTotal, prev)
+		}
+		return fmt.Errorf("%d of %d nodes trust the CA bundle from the %s phase", rotation.Status.NodesDone, rotation.Status.NodesTotal, prev)
+	}
+	return nil
+}
+
+// SetPhase records the CA rotation phase that has been applied, the hash of the server CA bundle that
+// nodes are expected to trust, and the hashes of the client and server CA certificates that nodes are
+// expected to re-issue their client and kubelet serving certificates with, if any. Node progress is
+// updated by the status controller.
+func SetPhase(rotations controllersv1.ClusterCARotationClient, phase, bundleHash, issuerHash, servingIssuerHash string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := rotations.Get(StatusName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			existing, err = rotations.Create(&apisv1.ClusterCARotation{ObjectMeta: metav1.ObjectMeta{Name: StatusName}})
+		}
+		if err != nil {
+			return err
+		}
+		existing = existing.DeepCopy()
+		existing.Status.Phase = phase
+		existing.Status.BundleHash = bundleHash
+		existing.Status.IssuerHash = issuerHash
+		existing.Status.ServingIssuerHash = servingIssuerHash
+		existing.Status.Converged = false
+		_, err = rotations.UpdateStatus(existing)
+		return err
+	})
+}
@@ -0,0 +1,151 @@
+package carotation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	apisv1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func newCA(t *testing.T, name string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: name}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func Test_UnitPhasedRotation(t *testing.T) {
+	oldCA := newCA(t, "old-ca")
+	newCA := newCA(t, "new-ca")
+	current := []*x509.Certificate{oldCA}
+	rotated := []*x509.Certificate{newCA}
+
+	// The new CA cannot be used for signing or retiring until it is trusted
+	if err := ValidatePhase(PhaseReissue, current, rotated); err == nil {
+		t.Errorf("ValidatePhase(%s) before %s succeeded, want error", PhaseReissue, PhaseTrust)
+	}
+	if err := ValidatePhase(PhaseRetire, current, rotated); err == nil {
+		t.Errorf("ValidatePhase(%s) before %s succeeded, want error", PhaseRetire, PhaseReissue)
+	}
+
+	for _, tt := range []struct {
+		phase string
+		want  []*x509.Certificate
+	}{
+		{phase: PhaseTrust, want: []*x509.Certificate{oldCA, newCA}},
+		{phase: PhaseReissue, want: []*x509.Certificate{newCA, oldCA}},
+		{phase: PhaseRetire, want: []*x509.Certificate{newCA}},
+	} {
+		if err := ValidatePhase(tt.phase, current, rotated); err != nil {
+			t.Fatalf("ValidatePhase(%s) error = %v", tt.phase, err)
+		}
+		current = ComposeBundle(tt.phase, current, rotated)
+		if len(current) != len(tt.want) {
+			t.Fatalf("ComposeBundle(%s) returned %d certs, want %d", tt.phase, len(current), len(tt.want))
+		}
+		for i := range current {
+			if !current[i].Equal(tt.want[i]) {
+				t.Errorf("ComposeBundle(%s) cert %d = %s, want %s", tt.phase, i, current[i].Subject, tt.want[i].Subject)
+			}
+		}
+	}
+
+	// Applying the trust phase again after the new CA is signing is an error
+	if err := ValidatePhase(PhaseTrust, current, rotated); err == nil {
+		t.Errorf("ValidatePhase(%s) after %s succeeded, want error", PhaseTrust, PhaseRetire)
+	}
+}
+
+func Test_UnitBuildStatus(t *testing.T) {
+	then := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	newNode := func(name, hash string) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{}}}
+		if hash != "" {
+			node.Annotations[nodeconfig.ServerCAHashAnnotation] = hash
+		}
+		return node
+	}
+	prev := apisv1.ClusterCARotationStatus{
+		Phase:      PhaseTrust,
+		BundleHash: "new",
+		Nodes: []apisv1.CARotationNodeStatus{
+			{Name: "server-1", BundleHash: "new", Trusted: true, LastTransitionTime: then},
+			{Name: "agent-1", BundleHash: "old", LastTransitionTime: then},
+		},
+	}
+
+	status := buildStatus(prev, []*corev1.Node{newNode("server-1", "new"), newNode("agent-1", "old"), newNode("agent-2", "")}, now)
+	if status.NodesDone != 1 || status.NodesTotal != 3 || status.Converged {
+		t.Errorf("buildStatus() done=%d total=%d converged=%t, want done=1 total=3 converged=false", status.NodesDone, status.NodesTotal, status.Converged)
+	}
+	if status.Nodes[0].Name != "agent-1" || !status.Nodes[0].LastTransitionTime.Equal(&then) {
+		t.Errorf("buildStatus() did not preserve transition time for unchanged node: %+v", status.Nodes[0])
+	}
+
+	status = buildStatus(status, []*corev1.Node{newNode("server-1", "new"), newNode("agent-1", "new"), newNode("agent-2", "new")}, now)
+	if status.NodesDone != 3 || !status.Converged {
+		t.Errorf("buildStatus() done=%d converged=%t, want done=3 converged=true", status.NodesDone, status.Converged)
+	}
+	if status.Phase != PhaseTrust || status.BundleHash != "new" {
+		t.Errorf("buildStatus() phase=%s hash=%s, want phase=%s hash=new", status.Phase, status.BundleHash, PhaseTrust)
+	}
+	if !status.Nodes[0].LastTransitionTime.Equal(&now) {
+		t.Errorf("buildStatus() did not update transition time for changed node: %+v", status.Nodes[0])
+	}
+}
+
+func Test_UnitBuildStatusReissue(t *testing.T) {
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	newNode := func(name, issuerHash string) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{nodeconfig.ServerCAHashAnnotation: "new"}}}
+		if issuerHash != "" {
+			node.Annotations[nodeconfig.ClientCertIssuerAnnotation] = issuerHash
+			node.Annotations[nodeconfig.ServingCertIssuerAnnotation] = "new-serving-issuer"
+		}
+		return node
+	}
+	prev := apisv1.ClusterCARotationStatus{
+		Phase:             PhaseReissue,
+		BundleHash:        "new",
+		IssuerHash:        "new-issuer",
+		ServingIssuerHash: "new-serving-issuer",
+	}
+
+	// Nodes that trust the new bundle are not done until their client certs are issued by the new CA
+	status := buildStatus(prev, []*corev1.Node{newNode("server-1", "new-issuer"), newNode("agent-1", "old-issuer"), newNode("agent-2", "")}, now)
+	if status.NodesDone != 1 || status.NodesTotal != 3 || status.Converged {
+		t.Errorf("buildStatus() done=%d total=%d converged=%t, want done=1 total=3 converged=false", status.NodesDone, status.NodesTotal, status.Converged)
+	}
+	for _, n := range status.Nodes {
+		if !n.Trusted || n.Reissued != (n.Name == "server-1") {
+			t.Errorf("buildStatus() node %s trusted=%t reissued=%t", n.Name, n.Trusted, n.Reissued)
+		}
+	}
+
+	// Nodes are not done until their kubelet serving certs are also issued by the new CA
+	stale := newNode("agent-2", "new-issuer")
+	stale.Annotations[nodeconfig.ServingCertIssuerAnnotation] = "old-serving-issuer"
+	status = buildStatus(status, []*corev1.Node{newNode("server-1", "new-issuer"), newNode("agent-1", "new-issuer"), stale}, now)
+	if status.NodesDone != 2 || status.Converged {
+		t.Errorf("buildStatus() done=%d converged=%t, want done=2 converged=false", status.NodesDone, status.Converged)
+	}
+
+	status = buildStatus(status, []*corev1.Node{newNode("server-1", "new-issuer"), newNode("agent-1", "new-issuer"), newNode("agent-2", "new-issuer")}, now)
+	if status.NodesDone != 3 || !status.Converged || status.IssuerHash != "new-issuer" {
+		t.Errorf("buildStatus() done=%d converged=%t issuer=%s, want done=3 converged=true issuer=new-issuer", status.NodesDone, status.Converged, status.IssuerHash)
+	}
+}
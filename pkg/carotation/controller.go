@@ -0,0 +1,123 @@
+package carotation
+
+import (
+	"context"
+	"sort"
+
+	apisv1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	controllersv1 "github.com/k3s-io/k3s/pkg/generated/controllers/k3s.cattle.io/v1"
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
+	coreclient "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+)
+
+type statusHandler struct {
+	nodes     coreclient.NodeCache
+	rotations controllersv1.ClusterCARotationController
+}
+
+// RegisterStatusController registers a controller that maintains the ClusterCARotation status object,
+// using the server CA hash annotations set on each node by its agent. The status object is only
+// created when a CA rotation phase is applied; the controller does nothing until then.
+func RegisterStatusController(ctx context.Context, nodes coreclient.NodeController, rotations controllersv1.ClusterCARotationController) {
+	h := &statusHandler{
+		nodes:     nodes.Cache(),
+		rotations: rotations,
+	}
+	logrus.Infof("Starting CA rotation status controller")
+	nodes.OnChange(ctx, "ca-rotation-status", h.onChangeNode)
+	rotations.OnChange(ctx, "ca-rotation-status", h.onChangeRotation)
+}
+
+func (h *statusHandler) onChangeNode(key string, node *corev1.Node) (*corev1.Node, error) {
+	return node, h.sync()
+}
+
+func (h *statusHandler) onChangeRotation(key string, rotation *apisv1.ClusterCARotation) (*apisv1.ClusterCARotation, error) {
+	if rotation == nil || rotation.Name != StatusName {
+		return rotation, nil
+	}
+	return rotation, h.sync()
+}
+
+// sync updates the status object to reflect the CA trust state of all nodes.
+func (h *statusHandler) sync() error {
+	nodes, err := h.nodes.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := h.rotations.Get(StatusName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		status := buildStatus(existing.Status, nodes, metav1.Now())
+		if equality.Semantic.DeepEqual(existing.Status, status) {
+			return nil
+		}
+		if status.Converged && !existing.Status.Converged {
+			logrus.Infof("All %d nodes are done with the %s phase", status.NodesTotal, status.Phase)
+		}
+		existing = existing.DeepCopy()
+		existing.Status = status
+		_, err = h.rotations.UpdateStatus(existing)
+		return err
+	})
+}
+
+// buildStatus returns the status for the given nodes. A node trusts the current CA bundle once its
+// agent has retrieved the bundle from the servers, and recorded its hash on the node. Once the new CA
+// is signing, a node is not done until its agent has also re-issued its client certificates and kubelet
+// serving certificate with the new CAs, and recorded the issuers' hashes on the node, so that the old CA
+// is not retired while it is still in use.
+func buildStatus(prev apisv1.ClusterCARotationStatus, nodes []*corev1.Node, now metav1.Time) apisv1.ClusterCARotationStatus {
+	prevNodes := map[string]apisv1.CARotationNodeStatus{}
+	for _, n := range prev.Nodes {
+		prevNodes[n.Name] = n
+	}
+
+	status := apisv1.ClusterCARotationStatus{
+		Phase:             prev.Phase,
+		BundleHash:        prev.BundleHash,
+		IssuerHash:        prev.IssuerHash,
+		ServingIssuerHash: prev.ServingIssuerHash,
+	}
+	for _, node := range nodes {
+		hash := node.Annotations[nodeconfig.ServerCAHashAnnotation]
+		issuerHash := node.Annotations[nodeconfig.ClientCertIssuerAnnotation]
+		servingIssuerHash := node.Annotations[nodeconfig.ServingCertIssuerAnnotation]
+		nodeStatus := apisv1.CARotationNodeStatus{
+			Name:              node.Name,
+			BundleHash:        hash,
+			Trusted:           hash != "" && hash == status.BundleHash,
+			IssuerHash:        issuerHash,
+			ServingIssuerHash: servingIssuerHash,
+			Reissued: (status.IssuerHash == "" || issuerHash == status.IssuerHash) &&
+				(status.ServingIssuerHash == "" || servingIssuerHash == status.ServingIssuerHash),
+			LastTransitionTime: now,
+		}
+		if p, ok := prevNodes[node.Name]; ok && p.BundleHash == hash && p.IssuerHash == issuerHash && p.ServingIssuerHash == servingIssuerHash {
+			nodeStatus.LastTransitionTime = p.LastTransitionTime
+		}
+		if nodeStatus.Trusted && nodeStatus.Reissued {
+			status.NodesDone++
+		}
+		status.Nodes = append(status.Nodes, nodeStatus)
+	}
+	sort.Slice(status.Nodes, func(i, j int) bool {
+		return status.Nodes[i].Name < status.Nodes[j].Name
+	})
+	status.NodesTotal = len(status.Nodes)
+	status.Converged = status.NodesTotal > 0 && status.NodesDone == status.NodesTotal
+	return status
+}
@@ -74,6 +74,52 @@ func validateCgroupsV2() error {
 	return nil
 }
 
+// ValidateSwap checks that the requested kubelet swap behavior is supported by the host.
+// LimitedSwap requires cgroups v2; a warning is logged if swap is requested but no swap
+// devices (including zram) are active.
+func ValidateSwap(behavior string) error {
+	switch behavior {
+	case "", "NoSwap":
+		return nil
+	case "LimitedSwap":
+	default:
+		return fmt.Errorf("invalid kubelet swap behavior %q: must be one of NoSwap or LimitedSwap", behavior)
+	}
+
+	if cgroups.Mode() != cgroups.Unified {
+		return errors.New("kubelet swap behavior LimitedSwap requires cgroups v2")
+	}
+
+	if devices, err := swapDevices(); err != nil {
+		logrus.Warnf("Failed to check for active swap devices: %v", err)
+	} else if len(devices) == 0 {
+		logrus.Warn("Kubelet swap behavior LimitedSwap is enabled, but no swap devices are active")
+	} else {
+		logrus.Infof("Kubelet swap behavior LimitedSwap is enabled with swap devices: %s", strings.Join(devices, ", "))
+	}
+	return nil
+}
+
+// swapDevices returns the names of active swap devices, as listed in /proc/swaps.
+func swapDevices() ([]string, error) {
+	f, err := os.Open("/proc/swaps")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	devices := []string{}
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) == 0 || fields[0] == "Filename" {
+			continue
+		}
+		devices = append(devices, fields[0])
+	}
+	return devices, scan.Err()
+}
+
 func CheckCgroups() (kubeletRoot, runtimeRoot string, controllers map[string]bool) {
 	cgroupsModeV2 := cgroups.Mode() == cgroups.Unified
 	controllers = make(map[string]bool)
@@ -3,10 +3,31 @@
 
 package cgroups
 
+import "fmt"
+
 func Validate() error {
 	return nil
 }
 
+func ValidateSwap(behavior string) error {
+	if behavior != "" && behavior != "NoSwap" {
+		return fmt.Errorf("kubelet swap behavior %s is not supported on windows", behavior)
+	}
+	return nil
+}
+
 func CheckCgroups() (kubeletRoot, runtimeRoot string, controllers map[string]bool) {
 	return
 }
+
+func AddComponentProcess(component string, pid int, limits *Limits) error {
+	return fmt.Errorf("component cgroups are not supported on windows")
+}
+
+func SetSupervisorLimits(limits *Limits) error {
+	return fmt.Errorf("component cgroups are not supported on windows")
+}
+
+func SetSupervisorIOWeight(weight uint16) error {
+	return fmt.Errorf("component cgroups are not supported on windows")
+}
@@ -0,0 +1,145 @@
+//go:build linux
+// +build linux
+
+package cgroups
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	cgroups "github.com/containerd/cgroups/v3"
+	cgroupsv2 "github.com/containerd/cgroups/v3/cgroup2"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	unifiedMountpoint = "/sys/fs/cgroup"
+	cpuPeriod         = uint64(100000)
+)
+
+var (
+	componentParent     string
+	componentParentErr  error
+	componentParentOnce sync.Once
+)
+
+// AddComponentProcess places the process into a cgroup for the named component, nested within the
+// supervisor's cgroup, and applies the provided limits. This requires cgroups v2.
+func AddComponentProcess(component string, pid int, limits *Limits) error {
+	parent, err := componentCgroupParent()
+	if err != nil {
+		return err
+	}
+	manager, err := cgroupsv2.NewManager(unifiedMountpoint, filepath.Join(parent, component), limits.resources())
+	if err != nil {
+		return err
+	}
+	return manager.AddProc(uint64(pid))
+}
+
+// SetSupervisorLimits applies limits to the cgroup containing the supervisor process, and any
+// components that run embedded within it. This requires cgroups v2.
+func SetSupervisorLimits(limits *Limits) error {
+	_, err := supervisorManager(limits.resources())
+	return err
+}
+
+// SetSupervisorIOWeight sets the io.weight of the cgroup containing the supervisor process, and any
+// components that run embedded within it. The weight is relative to sibling cgroups, such as containerd,
+// and must be between 1 and 10000. This requires cgroups v2, and an IO scheduler or io.cost model that
+// supports proportional weights.
+func SetSupervisorIOWeight(weight uint16) error {
+	if weight < 1 || weight > 10000 {
+		return fmt.Errorf("invalid io weight %d: must be between 1 and 10000", weight)
+	}
+	manager, err := supervisorManager(&cgroupsv2.Resources{})
+	if err != nil {
+		return err
+	}
+	if err := manager.ToggleControllers([]string{"io"}, cgroupsv2.Enable); err != nil {
+		return err
+	}
+	parent, _ := componentCgroupParent()
+	weightFile := filepath.Join(unifiedMountpoint, parent, ComponentSupervisor, "io.weight")
+	return os.WriteFile(weightFile, []byte("default "+strconv.Itoa(int(weight))), 0)
+}
+
+// supervisorManager creates or updates the supervisor's cgroup with the provided resources.
+func supervisorManager(resources *cgroupsv2.Resources) (*cgroupsv2.Manager, error) {
+	parent, err := componentCgroupParent()
+	if err != nil {
+		return nil, err
+	}
+	manager, err := cgroupsv2.NewManager(unifiedMountpoint, filepath.Join(parent, ComponentSupervisor), resources)
+	if err != nil {
+		return nil, err
+	}
+	if parent == "/" {
+		return manager, manager.AddProc(uint64(os.Getpid()))
+	}
+	return manager, nil
+}
+
+// resources converts limits to cgroup v2 resources.
+func (l *Limits) resources() *cgroupsv2.Resources {
+	resources := &cgroupsv2.Resources{}
+	if l == nil {
+		return resources
+	}
+	if l.CPU != nil {
+		period := cpuPeriod
+		quota := l.CPU.MilliValue() * int64(period) / 1000
+		resources.CPU = &cgroupsv2.CPU{Max: cgroupsv2.NewCPUMax(&quota, &period)}
+	}
+	if l.Memory != nil {
+		max := l.Memory.Value()
+		resources.Memory = &cgroupsv2.Memory{Max: &max}
+	}
+	return resources
+}
+
+// componentCgroupParent returns the cgroup that component cgroups are created within. Under cgroups v2,
+// controllers can only be enabled for child cgroups if the parent cgroup does not itself contain any
+// processes, so any processes in the supervisor's cgroup are moved into a leaf cgroup the first time
+// this is called.
+func componentCgroupParent() (string, error) {
+	componentParentOnce.Do(func() {
+		if cgroups.Mode() != cgroups.Unified {
+			componentParentErr = errors.New("component cgroups require cgroups v2")
+			return
+		}
+		group, err := cgroupsv2.NestedGroupPath("")
+		if err != nil {
+			componentParentErr = err
+			return
+		}
+		if filepath.Base(group) == ComponentSupervisor {
+			componentParent = filepath.Dir(group)
+			return
+		}
+		componentParent = group
+		if group == "/" {
+			return
+		}
+		current, err := cgroupsv2.Load(group)
+		if err != nil {
+			componentParentErr = err
+			return
+		}
+		supervisor, err := cgroupsv2.NewManager(unifiedMountpoint, filepath.Join(group, ComponentSupervisor), &cgroupsv2.Resources{})
+		if err != nil {
+			componentParentErr = err
+			return
+		}
+		if err := current.MoveTo(supervisor); err != nil {
+			componentParentErr = err
+			return
+		}
+		logrus.Infof("Moved supervisor process %d into cgroup %s", os.Getpid(), filepath.Join(group, ComponentSupervisor))
+	})
+	return componentParent, componentParentErr
+}
@@ -0,0 +1,65 @@
+package cgroups
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Components that can be placed into their own cgroup with resource limits. The supervisor
+// component covers the main process, including all components that run embedded within it.
+const (
+	ComponentSupervisor        = "supervisor"
+	ComponentContainerd        = "containerd"
+	ComponentKubelet           = "kubelet"
+	ComponentAPIServer         = "kube-apiserver"
+	ComponentScheduler         = "kube-scheduler"
+	ComponentControllerManager = "kube-controller-manager"
+)
+
+// Limits describes the CPU and memory limits applied to the cgroup of an embedded component.
+type Limits struct {
+	CPU    *resource.Quantity
+	Memory *resource.Quantity
+}
+
+// ParseLimits parses a list of COMPONENT.RESOURCE=QUANTITY entries into a map of limits
+// keyed by component name. Supported resources are cpu and memory.
+func ParseLimits(entries []string, components ...string) (map[string]*Limits, error) {
+	limits := map[string]*Limits{}
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid resource limit %q: must be in the form COMPONENT.RESOURCE=QUANTITY", entry)
+		}
+		i := strings.LastIndex(key, ".")
+		if i < 1 {
+			return nil, fmt.Errorf("invalid resource limit %q: must be in the form COMPONENT.RESOURCE=QUANTITY", entry)
+		}
+		component, name := key[:i], key[i+1:]
+		if !slices.Contains(components, component) {
+			return nil, fmt.Errorf("invalid resource limit %q: unsupported component %s; must be one of %s", entry, component, strings.Join(components, ", "))
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resource limit %q: %v", entry, err)
+		}
+		if quantity.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid resource limit %q: quantity must be greater than zero", entry)
+		}
+		if limits[component] == nil {
+			limits[component] = &Limits{}
+		}
+		switch name {
+		case "cpu":
+			limits[component].CPU = &quantity
+		case "memory":
+			limits[component].Memory = &quantity
+		default:
+			return nil, fmt.Errorf("invalid resource limit %q: unsupported resource %s; must be one of cpu, memory", entry, name)
+		}
+	}
+	return limits, nil
+}
@@ -0,0 +1,71 @@
+package cgroups
+
+import (
+	"testing"
+)
+
+func Test_UnitParseLimits(t *testing.T) {
+	components := []string{"kube-scheduler", "kube-controller-manager"}
+	tests := []struct {
+		name       string
+		entries    []string
+		wantCPU    map[string]int64
+		wantMemory map[string]int64
+		wantErr    bool
+	}{
+		{
+			name:    "cpu and memory for multiple components",
+			entries: []string{"kube-scheduler.cpu=500m", "kube-scheduler.memory=512Mi", "kube-controller-manager.cpu=2"},
+			wantCPU: map[string]int64{
+				"kube-scheduler":          500,
+				"kube-controller-manager": 2000,
+			},
+			wantMemory: map[string]int64{
+				"kube-scheduler": 512 * 1024 * 1024,
+			},
+		},
+		{
+			name:    "missing quantity",
+			entries: []string{"kube-scheduler.cpu"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported component",
+			entries: []string{"kube-apiserver.memory=1Gi"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported resource",
+			entries: []string{"kube-scheduler.pids=100"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid quantity",
+			entries: []string{"kube-scheduler.memory=lots"},
+			wantErr: true,
+		},
+		{
+			name:    "zero quantity",
+			entries: []string{"kube-scheduler.cpu=0"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimits(tt.entries, components...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for component, want := range tt.wantCPU {
+				if got[component] == nil || got[component].CPU == nil || got[component].CPU.MilliValue() != want {
+					t.Errorf("ParseLimits() %s cpu = %v, want %dm", component, got[component], want)
+				}
+			}
+			for component, want := range tt.wantMemory {
+				if got[component] == nil || got[component].Memory == nil || got[component].Memory.Value() != want {
+					t.Errorf("ParseLimits() %s memory = %v, want %d", component, got[component], want)
+				}
+			}
+		})
+	}
+}
@@ -14,10 +14,13 @@ import (
 	"github.com/k3s-io/k3s/pkg/cli/cmds"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/datadir"
+	"github.com/k3s-io/k3s/pkg/identity"
 	k3smetrics "github.com/k3s-io/k3s/pkg/metrics"
 	"github.com/k3s-io/k3s/pkg/proctitle"
 	"github.com/k3s-io/k3s/pkg/profile"
 	"github.com/k3s-io/k3s/pkg/spegel"
+	"github.com/k3s-io/k3s/pkg/startup"
+	"github.com/k3s-io/k3s/pkg/tracing"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/k3s-io/k3s/pkg/vpn"
@@ -27,6 +30,17 @@ import (
 )
 
 func Run(ctx *cli.Context) error {
+	return run(ctx, signals.SetupSignalContext)
+}
+
+// RunWithContext is like Run, but stops the agent when the provided context is cancelled,
+// instead of on receipt of a signal.
+func RunWithContext(ctx context.Context, app *cli.Context) error {
+	return run(app, func() context.Context { return ctx })
+}
+
+func run(ctx *cli.Context, newContext func() context.Context) error {
+	startup.SetStartTimes(cmds.StartupTimesFromEnv())
 	// Validate build env
 	cmds.MustValidateGolang()
 
@@ -45,10 +59,23 @@ func Run(ctx *cli.Context) error {
 		return err
 	}
 
+	startup.Expect(version.Program+" agent is up and running", startup.PhaseContainerRuntime, startup.PhaseNodeRegistered)
+	if cmds.LogConfig.StartupSummary {
+		startup.EnableSummary(os.Stderr)
+	}
+
 	if runtime.GOOS != "windows" && os.Getuid() != 0 && !cmds.AgentConfig.Rootless {
 		return fmt.Errorf("agent must be run as root, or with --rootless")
 	}
 
+	if err := startup.WaitForGates(context.Background(), cmds.AgentConfig.StartupGates.Value(), cmds.AgentConfig.StartupGateTimeout); err != nil {
+		return err
+	}
+
+	if err := startup.WaitForPlausibleClock(context.Background(), startup.ClockFloor(), cmds.AgentConfig.InsecureSkewTolerance); err != nil {
+		return err
+	}
+
 	if cmds.AgentConfig.TokenFile != "" {
 		token, err := util.ReadFile(cmds.AgentConfig.TokenFile)
 		if err != nil {
@@ -69,6 +96,10 @@ func Run(ctx *cli.Context) error {
 		return fmt.Errorf("--server is required")
 	}
 
+	if cmds.AgentConfig.NodeName, err = identity.ResolveNodeName(cmds.AgentConfig.NodeName, cmds.AgentConfig.NodeNameTemplate); err != nil {
+		return err
+	}
+
 	if cmds.AgentConfig.FlannelIface != "" && len(cmds.AgentConfig.NodeIP) == 0 {
 		ip, err := util.GetIPFromInterface(cmds.AgentConfig.FlannelIface)
 		if err != nil {
@@ -88,9 +119,16 @@ func Run(ctx *cli.Context) error {
 	cfg.Debug = ctx.GlobalBool("debug")
 	cfg.DataDir = dataDir
 
-	contextCtx := signals.SetupSignalContext()
+	if err := identity.ResetIfCloned(dataDir, identity.NodePasswordFile(dataDir, cfg.Rootless), "", cfg.ResetIdentityOnClone); err != nil {
+		return err
+	}
+
+	contextCtx := newContext()
 
 	go cmds.WriteCoverage(contextCtx)
+	if err := tracing.Setup(contextCtx, cfg.TracingEndpoint, cfg.TracingSamplingRate, "agent"); err != nil {
+		return err
+	}
 	if cfg.VPNAuthFile != "" {
 		cfg.VPNAuth, err = util.ReadFile(cfg.VPNAuthFile)
 		if err != nil {
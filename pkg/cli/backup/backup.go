@@ -0,0 +1,309 @@
+package backup
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/backup"
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/clientaccess"
+	"github.com/k3s-io/k3s/pkg/cluster/managed"
+	"github.com/k3s-io/k3s/pkg/datadir"
+	"github.com/k3s-io/k3s/pkg/deploy"
+	"github.com/k3s-io/k3s/pkg/etcd"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	_ "github.com/mattn/go-sqlite3" // sqlite3 driver for taking a consistent copy of the kine database
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// snapshotTimeout is the time allowed for the etcd snapshot taken for the backup.
+	snapshotTimeout = 5 * time.Minute
+	// dbBackupDir is the directory, relative to the data-dir, that the datastore snapshot is
+	// written to while creating a backup, and extracted to when restoring one.
+	dbBackupDir = "server/db/backup"
+)
+
+// backupPaths are the paths, relative to the data-dir, that are included in a backup in addition
+// to the datastore snapshot and user-provided manifests.
+var backupPaths = []string{
+	"server/tls",
+	"server/cred",
+	"server/token",
+	"server/agent-token",
+}
+
+func Create(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return create(app, &cmds.ServerConfig, &cmds.BackupConfig)
+}
+
+func create(app *cli.Context, cfg *cmds.Server, backupCfg *cmds.Backup) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	dataDir, err := datadir.Resolve(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "server", "tls")); err != nil {
+		return errors.Wrap(err, "backups can only be created on a server")
+	}
+	nodeName, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	nodeName = strings.ToLower(nodeName)
+
+	meta := &backup.Metadata{
+		Version:  version.Version,
+		Created:  time.Now().UTC().Round(time.Second),
+		NodeName: nodeName,
+	}
+	if err := os.MkdirAll(filepath.Join(dataDir, dbBackupDir), 0700); err != nil {
+		return err
+	}
+	defer os.RemoveAll(filepath.Join(dataDir, dbBackupDir))
+
+	etcdDir, err := etcdDataDir(cfg, dataDir)
+	if err != nil {
+		return err
+	}
+	sqliteDB := filepath.Join(dataDir, "server", "db", "state.db")
+	switch {
+	case cfg.DatastoreEndpoint != "" && !strings.HasPrefix(cfg.DatastoreEndpoint, "sqlite://"):
+		meta.Datastore = backup.DatastoreExternal
+		logrus.Warn("Server is using an external datastore; the datastore must be backed up separately")
+	case exists(etcdDir):
+		meta.Datastore = backup.DatastoreEtcd
+		meta.Snapshot, err = saveEtcdSnapshot(dataDir, backupCfg)
+	case exists(sqliteDB):
+		meta.Datastore = backup.DatastoreSQLite
+		meta.Snapshot, err = saveSQLiteSnapshot(dataDir)
+	default:
+		return fmt.Errorf("no etcd data found in %s and no sqlite database found at %s; set --etcd-data-dir if etcd data is held elsewhere, or --datastore-endpoint if the server uses an external datastore", etcdDir, sqliteDB)
+	}
+	if err != nil {
+		return err
+	}
+
+	paths := append(slices.Clone(backupPaths), dbBackupDir)
+	manifests, err := userManifests(dataDir)
+	if err != nil {
+		return err
+	}
+	paths = append(paths, manifests...)
+
+	file := backupCfg.File
+	if file == "" {
+		file = filepath.Join(dataDir, "server", "backups", fmt.Sprintf("%s-backup-%s-%d.tar.gz", version.Program, nodeName, meta.Created.Unix()))
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := backup.Write(f, dataDir, paths, meta); err != nil {
+		os.Remove(file)
+		return err
+	}
+
+	logrus.Infof("Backup of %s datastore, certificates, tokens, and %d manifests saved to %s", meta.Datastore, len(manifests), file)
+	return nil
+}
+
+// saveEtcdSnapshot asks the server to save an etcd snapshot to the backup directory, and returns
+// the path of the snapshot relative to the data-dir.
+func saveEtcdSnapshot(dataDir string, backupCfg *cmds.Backup) (string, error) {
+	if backupCfg.Token == "" {
+		tokenByte, err := os.ReadFile(filepath.Join(dataDir, "server", "token"))
+		if err != nil {
+			return "", err
+		}
+		backupCfg.Token = string(bytes.TrimRight(tokenByte, "\n"))
+	}
+	info, err := clientaccess.ParseAndValidateToken(backupCfg.ServerURL, backupCfg.Token, clientaccess.WithUser("server"))
+	if err != nil {
+		return "", err
+	}
+
+	// retention is set to 0 to disable automatic pruning, same as an on-demand snapshot save
+	sr := &etcd.SnapshotRequest{
+		Operation: etcd.SnapshotOperationSave,
+		Name:      []string{"backup"},
+		Dir:       ptr.To(filepath.Join(dataDir, dbBackupDir)),
+		Compress:  ptr.To(false),
+		Retention: ptr.To(0),
+	}
+	b, err := json.Marshal(sr)
+	if err != nil {
+		return "", err
+	}
+	r, err := info.Post("/db/snapshot", b, clientaccess.WithTimeout(snapshotTimeout))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to take etcd snapshot; see server log for details")
+	}
+	resp := &managed.SnapshotResult{}
+	if err := json.Unmarshal(r, resp); err != nil {
+		return "", err
+	}
+	if len(resp.Created) != 1 {
+		return "", fmt.Errorf("expected 1 etcd snapshot to be created, got %d", len(resp.Created))
+	}
+	logrus.Infof("Snapshot %s saved.", resp.Created[0])
+	return filepath.ToSlash(filepath.Join(dbBackupDir, resp.Created[0])), nil
+}
+
+// saveSQLiteSnapshot takes a consistent copy of the sqlite database, and returns the path of the
+// copy relative to the data-dir. The copy can be taken while the server is running.
+func saveSQLiteSnapshot(dataDir string) (string, error) {
+	db, err := sql.Open("sqlite3", filepath.Join(dataDir, "server", "db", "state.db"))
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	snapshot := filepath.ToSlash(filepath.Join(dbBackupDir, "state.db"))
+	if _, err := db.Exec("VACUUM INTO ?", filepath.Join(dataDir, snapshot)); err != nil {
+		return "", errors.Wrap(err, "failed to copy sqlite database")
+	}
+	return snapshot, nil
+}
+
+// userManifests returns the paths, relative to the data-dir, of manifests that are not packaged
+// with this release. Packaged manifests are staged again when the server starts, and are not
+// included in the backup.
+func userManifests(dataDir string) ([]string, error) {
+	manifestsDir := filepath.Join(dataDir, "server", "manifests")
+	packaged := deploy.AssetNames()
+	manifests := []string{}
+	err := filepath.WalkDir(manifestsDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(manifestsDir, path)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(packaged, filepath.ToSlash(rel)) {
+			return nil
+		}
+		manifests = append(manifests, filepath.ToSlash(filepath.Join("server", "manifests", rel)))
+		return nil
+	})
+	return manifests, err
+}
+
+func Restore(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return restore(app, &cmds.ServerConfig, &cmds.BackupConfig)
+}
+
+func restore(app *cli.Context, cfg *cmds.Server, backupCfg *cmds.Backup) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	if backupCfg.File == "" {
+		return errors.New("--file must be set to the path of the backup archive to restore")
+	}
+	dataDir, err := datadir.Resolve(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+	etcdDir, err := etcdDataDir(cfg, dataDir)
+	if err != nil {
+		return err
+	}
+	for _, path := range []string{filepath.Join(dataDir, "server", "tls"), filepath.Join(dataDir, "server", "db"), etcdDir} {
+		if exists(path) {
+			return fmt.Errorf("%s already exists; backups can only be restored into an empty data-dir", path)
+		}
+	}
+
+	f, err := os.Open(backupCfg.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	meta, err := backup.Extract(f, dataDir)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Extracted backup of server %s created at %s by version %s", meta.NodeName, meta.Created.Format(time.RFC3339), meta.Version)
+
+	switch meta.Datastore {
+	case backup.DatastoreSQLite:
+		if err := os.Rename(filepath.Join(dataDir, meta.Snapshot), filepath.Join(dataDir, "server", "db", "state.db")); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(filepath.Join(dataDir, dbBackupDir)); err != nil {
+			return err
+		}
+		logrus.Info("Restored sqlite datastore; start the server to complete the restore")
+	case backup.DatastoreEtcd:
+		token, err := os.ReadFile(filepath.Join(dataDir, "server", "token"))
+		if err != nil {
+			return errors.Wrap(err, "failed to read token from backup")
+		}
+		args := []string{
+			"server",
+			"--cluster-reset",
+			"--cluster-reset-restore-path=" + filepath.Join(dataDir, meta.Snapshot),
+			"--data-dir=" + dataDir,
+			"--token=" + string(bytes.TrimRight(token, "\n")),
+		}
+		if cfg.EtcdDataDir != "" {
+			args = append(args, "--etcd-data-dir="+etcdDir)
+		}
+		logrus.Infof("Resetting etcd cluster membership and restoring snapshot %s", meta.Snapshot)
+		cmd := exec.Command(version.Program+"-server", args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return errors.Wrap(err, "failed to restore etcd snapshot")
+		}
+		if err := os.RemoveAll(filepath.Join(dataDir, dbBackupDir)); err != nil {
+			return err
+		}
+	default:
+		logrus.Warn("Backup was taken from a server using an external datastore; the datastore must be restored separately")
+	}
+	return nil
+}
+
+// etcdDataDir returns the absolute path of the directory that the server holds etcd data in.
+func etcdDataDir(cfg *cmds.Server, dataDir string) (string, error) {
+	if cfg.EtcdDataDir == "" {
+		return filepath.Join(dataDir, "server", "db", "etcd"), nil
+	}
+	return filepath.Abs(cfg.EtcdDataDir)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,203 @@
+package bootstrap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/clientaccess"
+	"github.com/k3s-io/k3s/pkg/cluster"
+	"github.com/k3s-io/k3s/pkg/datadir"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const defaultServerURL = "https://127.0.0.1:6443"
+
+func Diff(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return diff(app, &cmds.ServerConfig, &cmds.BootstrapConfig)
+}
+
+func diff(app *cli.Context, cfg *cmds.Server, bootstrapCfg *cmds.Bootstrap) error {
+	servers := bootstrapCfg.ServerURLs.Value()
+	if len(servers) == 0 {
+		servers = []string{defaultServerURL}
+	}
+	if bootstrapCfg.Reconcile && (len(servers) != 1 || !isLocal(servers[0])) {
+		return errors.New("--reconcile can only be used with a single local server")
+	}
+
+	if bootstrapCfg.Token == "" {
+		dataDir, err := datadir.Resolve(cfg.DataDir)
+		if err != nil {
+			return err
+		}
+		tokenByte, err := os.ReadFile(filepath.Join(dataDir, "server", "token"))
+		if err != nil {
+			return err
+		}
+		bootstrapCfg.Token = string(bytes.TrimRight(tokenByte, "\n"))
+	}
+
+	statuses := []*cluster.BootstrapStatus{}
+	for _, server := range servers {
+		info, err := clientaccess.ParseAndValidateToken(server, bootstrapCfg.Token, clientaccess.WithUser("server"))
+		if err != nil {
+			return errors.Wrapf(err, "failed to validate token for %s", server)
+		}
+		data, err := info.Get("/v1-" + version.Program + "/bootstrap/status")
+		if err != nil {
+			return errors.Wrapf(err, "failed to get bootstrap status from %s; see server log for details", server)
+		}
+		status := &cluster.BootstrapStatus{}
+		if err := json.Unmarshal(data, status); err != nil {
+			return err
+		}
+		if status.Server == "" {
+			status.Server = server
+		}
+		statuses = append(statuses, status)
+	}
+
+	if strings.ToLower(bootstrapCfg.Output) == "json" {
+		b, err := json.MarshalIndent(statuses, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	} else if err := printBootstrapStatus(os.Stdout, statuses); err != nil {
+		return err
+	}
+
+	if bootstrapCfg.Reconcile {
+		return reconcile(statuses[0], time.Now())
+	}
+
+	for _, status := range statuses[1:] {
+		if status.Revision != statuses[0].Revision {
+			return errors.Errorf("server %s reports bootstrap revision %d, but server %s reports revision %d; servers may not share a datastore",
+				status.Server, status.Revision, statuses[0].Server, statuses[0].Revision)
+		}
+	}
+	mismatched := 0
+	for _, status := range statuses {
+		mismatched += len(status.Mismatched())
+	}
+	if mismatched > 0 {
+		return errors.Errorf("found %d bootstrap files that do not match the datastore", mismatched)
+	}
+	return nil
+}
+
+// reconcile moves aside local bootstrap files that differ from the datastore. The server will
+// write the datastore copy of any missing files to disk when it is restarted.
+func reconcile(status *cluster.BootstrapStatus, now time.Time) error {
+	moved := 0
+	for _, file := range status.Mismatched() {
+		if file.Status != cluster.BootstrapFileDiffers && file.Status != cluster.BootstrapFileNewerOnDisk {
+			continue
+		}
+		backup := fmt.Sprintf("%s.bak-%d", file.Path, now.Unix())
+		if err := os.Rename(file.Path, backup); err != nil {
+			return errors.Wrapf(err, "failed to move aside %s", file.Path)
+		}
+		logrus.Infof("Moved %s to %s", file.Path, backup)
+		moved++
+	}
+	if moved == 0 {
+		logrus.Info("No local bootstrap files need to be reconciled")
+		return nil
+	}
+	logrus.Infof("Restart %s to restore %d bootstrap files from the datastore", version.Program, moved)
+	return nil
+}
+
+// printBootstrapStatus writes a table with a row for each bootstrap file, and a column for the
+// datastore and each server. Hashes are only shown for copies that do not match the datastore.
+func printBootstrapStatus(out io.Writer, statuses []*cluster.BootstrapStatus) error {
+	type row struct {
+		datastore string
+		servers   []string
+	}
+	rows := map[string]*row{}
+	for i, status := range statuses {
+		for _, file := range status.Files {
+			r, ok := rows[file.Name]
+			if !ok {
+				r = &row{servers: make([]string, len(statuses))}
+				rows[file.Name] = r
+			}
+			if r.datastore == "" && file.DatastoreHash != "" {
+				r.datastore = shortHash(file.DatastoreHash)
+			}
+			cell := file.Status
+			if file.Status != cluster.BootstrapFileMatch && file.DiskHash != "" {
+				cell += " (" + shortHash(file.DiskHash) + ")"
+			}
+			r.servers[i] = cell
+		}
+	}
+	names := make([]string, 0, len(rows))
+	for name := range rows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "FILE\tDATASTORE")
+	for _, status := range statuses {
+		fmt.Fprintf(w, "\t%s", status.Server)
+	}
+	fmt.Fprint(w, "\n")
+	for _, name := range names {
+		r := rows[name]
+		fmt.Fprintf(w, "%s\t%s", name, valueOrDash(r.datastore))
+		for _, cell := range r.servers {
+			fmt.Fprintf(w, "\t%s", valueOrDash(cell))
+		}
+		fmt.Fprint(w, "\n")
+	}
+	return w.Flush()
+}
+
+// isLocal returns true if the server URL refers to this host.
+func isLocal(server string) bool {
+	u, err := url.Parse(server)
+	if err != nil {
+		return false
+	}
+	if u.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -2,9 +2,11 @@ package cert
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -18,15 +20,21 @@ import (
 	"github.com/k3s-io/k3s/pkg/datadir"
 	"github.com/k3s-io/k3s/pkg/proctitle"
 	"github.com/k3s-io/k3s/pkg/server"
+	util2 "github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/util/services"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/otiai10/copy"
 	"github.com/pkg/errors"
 	certutil "github.com/rancher/dynamiclistener/cert"
+	"github.com/rancher/wrangler/v3/pkg/signals"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
+// regenerateTimeout is how long rotate waits for certificates to be regenerated after restarting the service.
+const regenerateTimeout = 5 * time.Minute
+
 func commandSetup(app *cli.Context, cfg *cmds.Server, sc *server.Config) (string, error) {
 	proctitle.SetProcTitle(os.Args[0])
 
@@ -70,6 +78,7 @@ func check(app *cli.Context, cfg *cmds.Server) error {
 	if err := validateCertConfig(); err != nil {
 		return err
 	}
+	cmds.ServicesList = services.Expand(cmds.ServicesList)
 
 	if len(cmds.ServicesList) == 0 {
 		// detecting if the command is being run on an agent or server based on presence of the server data-dir
@@ -91,6 +100,11 @@ func check(app *cli.Context, cfg *cmds.Server) error {
 		return err
 	}
 
+	if backupDir := app.String("compare"); backupDir != "" {
+		fmt.Print(formatCertDiff(fileMap, serverConfig.ControlConfig.DataDir, backupDir))
+		return nil
+	}
+
 	now := time.Now()
 	warn := now.Add(time.Hour * 24 * config.CertificateRenewDays)
 	outFmt := app.String("output")
@@ -170,6 +184,7 @@ func rotate(app *cli.Context, cfg *cmds.Server) error {
 	if err := validateCertConfig(); err != nil {
 		return err
 	}
+	cmds.ServicesList = services.Expand(cmds.ServicesList)
 
 	if len(cmds.ServicesList) == 0 {
 		// detecting if the command is being run on an agent or server based on presence of the server data-dir
@@ -191,6 +206,8 @@ func rotate(app *cli.Context, cfg *cmds.Server) error {
 		return err
 	}
 
+	fmt.Print("Certificates to be rotated:\n" + formatCertInfo(fileMap))
+
 	// back up all the files
 	agentDataDir := filepath.Join(dataDir, "agent")
 	tlsBackupDir, err := backupCertificates(serverConfig.ControlConfig.DataDir, agentDataDir, fileMap)
@@ -198,31 +215,125 @@ func rotate(app *cli.Context, cfg *cmds.Server) error {
 		return err
 	}
 
+	// Only services with certificates on disk need to be restarted to regenerate them.
+	rotated := []string{}
+	removedFiles := []string{}
+	regenFiles := []string{}
+
 	// The dynamiclistener cache file can't be simply deleted, we need to create a trigger
 	// file to indicate that the cert needs to be regenerated on startup.
 	for _, service := range cmds.ServicesList {
 		if service == version.Program+services.ProgramServer {
+			rotated = append(rotated, service)
 			dynamicListenerRegenFilePath := filepath.Join(serverConfig.ControlConfig.DataDir, "tls", "dynamic-cert-regenerate")
 			if err := os.WriteFile(dynamicListenerRegenFilePath, []byte{}, 0600); err != nil {
 				return err
 			}
+			regenFiles = append(regenFiles, dynamicListenerRegenFilePath)
 			logrus.Infof("Rotating dynamic listener certificate")
 		}
 	}
 
 	// remove all files
-	for service, files := range fileMap {
+	rotatedFileMap := map[string][]string{}
+	for _, service := range sortedServices(fileMap) {
 		logrus.Info("Rotating certificates for " + service)
-		for _, file := range files {
+		for _, file := range fileMap[service] {
 			if err := os.Remove(file); err == nil {
 				logrus.Debugf("file %s is deleted", file)
+				rotatedFileMap[service] = append(rotatedFileMap[service], file)
+				removedFiles = append(removedFiles, file)
 			}
 		}
+		if len(rotatedFileMap[service]) > 0 && !slices.Contains(rotated, service) {
+			rotated = append(rotated, service)
+		}
+	}
+	if len(rotated) == 0 {
+		logrus.Infof("No certificates found on disk for the selected services, no restart is needed")
+		return nil
+	}
+
+	_, err = os.Stat(serverConfig.ControlConfig.DataDir)
+	isServer := err == nil
+	if !app.Bool("restart") {
+		logrus.Infof("Successfully backed up certificates to %s, please restart %s %s to regenerate certificates for: %s", tlsBackupDir, version.Program, nodeRole(isServer), strings.Join(rotated, ", "))
+		logrus.Infof("After restarting, run '%s certificate check --compare %s' to compare certificate expiry and SANs", version.Program, tlsBackupDir)
+		return nil
+	}
+
+	units := ownerUnits(dataDir, isServer)
+	if len(units) == 0 {
+		return fmt.Errorf("unable to find the running %s %s service that uses %s; certificates were backed up to %s, restart %s manually to regenerate certificates for: %s", version.Program, nodeRole(isServer), dataDir, tlsBackupDir, version.Program, strings.Join(rotated, ", "))
+	}
+	for _, unit := range units {
+		logrus.Infof("Restarting service %s to regenerate certificates for: %s", unit, strings.Join(rotated, ", "))
+		if err := util2.RunServiceUnit(unit, "restart"); err != nil {
+			return errors.Wrapf(err, "failed to restart service %s", unit)
+		}
+	}
+	if err := waitForCertificates(signals.SetupSignalContext(), removedFiles, regenFiles); err != nil {
+		return errors.Wrapf(err, "certificates were backed up to %s", tlsBackupDir)
+	}
+	fmt.Print("Certificate changes:\n" + formatCertDiff(rotatedFileMap, serverConfig.ControlConfig.DataDir, tlsBackupDir))
+	return nil
+}
+
+// nodeRole returns the name of the command that runs on this node.
+func nodeRole(isServer bool) string {
+	if isServer {
+		return "server"
+	}
+	return "agent"
+}
+
+// ownerUnits returns the service unit that owns the certificates in the data dir. This is the unit
+// whose processes have files open in the data dir; if that cannot be determined, the server or agent
+// unit is used, as long as only one is running.
+func ownerUnits(dataDir string, isServer bool) []string {
+	active := util2.ActiveServiceUnits()
+	if units := util2.ServiceUnitsUsing(dataDir, active); len(units) > 0 {
+		return units
+	}
+	if units := serviceUnitsFor(active, isServer); len(units) == 1 {
+		return units
 	}
-	logrus.Infof("Successfully backed up certificates to %s, please restart %s server or agent to rotate certificates", tlsBackupDir, version.Program)
 	return nil
 }
 
+// serviceUnitsFor returns the service units that may own the certificates on this node. Server
+// nodes run the agent in the same process, so only the server service needs to be restarted;
+// agent-only nodes restart only the agent service.
+func serviceUnitsFor(units []string, isServer bool) []string {
+	owned := []string{}
+	for _, unit := range units {
+		if strings.HasPrefix(filepath.Base(unit), version.Program+"-agent") != isServer {
+			owned = append(owned, unit)
+		}
+	}
+	return owned
+}
+
+// waitForCertificates waits until the removed certificate files have been regenerated, and the
+// regeneration trigger files have been consumed.
+func waitForCertificates(ctx context.Context, removedFiles, regenFiles []string) error {
+	return wait.PollUntilContextTimeout(ctx, 5*time.Second, regenerateTimeout, true, func(ctx context.Context) (bool, error) {
+		for _, file := range removedFiles {
+			if _, err := os.Stat(file); err != nil {
+				logrus.Infof("Waiting for certificate %s to be regenerated", file)
+				return false, nil
+			}
+		}
+		for _, file := range regenFiles {
+			if _, err := os.Stat(file); err == nil {
+				logrus.Infof("Waiting for dynamic listener certificate to be regenerated")
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
 func backupCertificates(serverDataDir, agentDataDir string, fileMap map[string][]string) (string, error) {
 	backupDirName := fmt.Sprintf("tls-%d", time.Now().Unix())
 	serverTLSDir := filepath.Join(serverDataDir, "tls")
@@ -284,10 +395,34 @@ func rotateCA(app *cli.Context, cfg *cmds.Server, sync *cmds.CertRotateCA) error
 		return err
 	}
 
+	b, err := ReadCACertData(sync.CACertPath)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("/v1-%s/cert/cacerts?force=%t", version.Program, sync.Force)
+	if sync.Phase != "" {
+		url += "&phase=" + sync.Phase
+	}
+	if err = info.Put(url, b); err != nil {
+		return errors.Wrap(err, "see server log for details")
+	}
+
+	if sync.Phase != "" {
+		fmt.Printf("certificates for the %s phase saved to datastore; restart servers to apply, and check progress with 'kubectl get clustercarotation'\n", sync.Phase)
+		return nil
+	}
+	fmt.Println("certificates saved to datastore")
+	return nil
+}
+
+// ReadCACertData reads new CA certificates and keys from the given path, in the
+// format expected by the server's CA certificate replacement endpoint.
+func ReadCACertData(caCertPath string) ([]byte, error) {
 	// Set up dummy server config for reading new bootstrap data from disk.
 	tmpServer := &config.Control{
 		Runtime: config.NewRuntime(nil),
-		DataDir: sync.CACertPath,
+		DataDir: caCertPath,
 	}
 	deps.CreateRuntimeCertFiles(tmpServer)
 
@@ -297,14 +432,7 @@ func rotateCA(app *cli.Context, cfg *cmds.Server, sync *cmds.CertRotateCA) error
 
 	buf := &bytes.Buffer{}
 	if err := bootstrap.ReadFromDisk(buf, &tmpServer.Runtime.ControlRuntimeBootstrap); err != nil {
-		return err
+		return nil, err
 	}
-
-	url := fmt.Sprintf("/v1-%s/cert/cacerts?force=%t", version.Program, sync.Force)
-	if err = info.Put(url, buf.Bytes()); err != nil {
-		return errors.Wrap(err, "see server log for details")
-	}
-
-	fmt.Println("certificates saved to datastore")
-	return nil
+	return buf.Bytes(), nil
 }
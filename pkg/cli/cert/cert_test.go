@@ -0,0 +1,16 @@
+package cert
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_UnitServiceUnitsFor(t *testing.T) {
+	units := []string{"k3s.service", "k3s-agent.service", "/etc/init.d/k3s-agent"}
+	if got, want := serviceUnitsFor(units, true), []string{"k3s.service"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("serviceUnitsFor(server) = %v, want %v", got, want)
+	}
+	if got, want := serviceUnitsFor(units, false), []string{"k3s-agent.service", "/etc/init.d/k3s-agent"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("serviceUnitsFor(agent) = %v, want %v", got, want)
+	}
+}
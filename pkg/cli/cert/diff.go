@@ -0,0 +1,120 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	certutil "github.com/rancher/dynamiclistener/cert"
+)
+
+// certSANs returns the DNS names and IP addresses from a certificate's subject alternative names, sorted.
+func certSANs(cert *x509.Certificate) []string {
+	sans := []string{}
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sort.Strings(sans)
+	return sans
+}
+
+// diffSANs returns the subject alternative names that are present in after but not before, and
+// present in before but not after.
+func diffSANs(before, after []string) (added, removed []string) {
+	for _, san := range after {
+		if !slices.Contains(before, san) {
+			added = append(added, san)
+		}
+	}
+	for _, san := range before {
+		if !slices.Contains(after, san) {
+			removed = append(removed, san)
+		}
+	}
+	return added, removed
+}
+
+// backupPath returns the path that backupCertificates copied a certificate file to. Files from the
+// server TLS dir retain their relative path; agent files are copied into the root of the backup dir.
+func backupPath(file, serverDataDir, backupDir string) string {
+	if rel, err := filepath.Rel(filepath.Join(serverDataDir, "tls"), file); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join(backupDir, rel)
+	}
+	return filepath.Join(backupDir, filepath.Base(file))
+}
+
+// sortedServices returns the keys of a service file map, sorted.
+func sortedServices(fileMap map[string][]string) []string {
+	services := make([]string, 0, len(fileMap))
+	for service := range fileMap {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// formatCertInfo returns a table of the expiry and subject alternative names of the certificates
+// in the file map. Files that do not exist or do not contain certificates are skipped.
+func formatCertInfo(fileMap map[string][]string) string {
+	var tabBuffer bytes.Buffer
+	w := tabwriter.NewWriter(&tabBuffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "SERVICE\tCERTIFICATE\tEXPIRES\tSANS\n")
+	fmt.Fprintf(w, "-------\t-----------\t-------\t----\n")
+	for _, service := range sortedServices(fileMap) {
+		for _, file := range fileMap[service] {
+			certs, _ := certutil.CertsFromFile(file)
+			if len(certs) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", service, filepath.Base(file), certs[0].NotAfter.Format(time.RFC3339), strings.Join(certSANs(certs[0]), ","))
+		}
+	}
+	w.Flush()
+	return tabBuffer.String()
+}
+
+// formatCertDiff returns a table comparing the expiry and subject alternative names of the
+// certificates in the file map against the copies in a backup dir created by rotate.
+func formatCertDiff(fileMap map[string][]string, serverDataDir, backupDir string) string {
+	var tabBuffer bytes.Buffer
+	w := tabwriter.NewWriter(&tabBuffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "SERVICE\tCERTIFICATE\tEXPIRES BEFORE\tEXPIRES AFTER\tSANS ADDED\tSANS REMOVED\n")
+	fmt.Fprintf(w, "-------\t-----------\t--------------\t-------------\t----------\t------------\n")
+	for _, service := range sortedServices(fileMap) {
+		for _, file := range fileMap[service] {
+			after, _ := certutil.CertsFromFile(file)
+			before, _ := certutil.CertsFromFile(backupPath(file, serverDataDir, backupDir))
+			if len(after) == 0 && len(before) == 0 {
+				continue
+			}
+			expiresBefore, expiresAfter := "-", "-"
+			var sansBefore, sansAfter []string
+			if len(before) > 0 {
+				expiresBefore = before[0].NotAfter.Format(time.RFC3339)
+				sansBefore = certSANs(before[0])
+			}
+			if len(after) > 0 {
+				expiresAfter = after[0].NotAfter.Format(time.RFC3339)
+				sansAfter = certSANs(after[0])
+			}
+			added, removed := diffSANs(sansBefore, sansAfter)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", service, filepath.Base(file), expiresBefore, expiresAfter, joinOrDash(added), joinOrDash(removed))
+		}
+	}
+	w.Flush()
+	return tabBuffer.String()
+}
+
+func joinOrDash(s []string) string {
+	if len(s) == 0 {
+		return "-"
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,45 @@
+package cert
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_UnitDiffSANs(t *testing.T) {
+	before := []string{"10.43.0.1", "kubernetes", "server-1"}
+	after := []string{"10.43.0.1", "kubernetes", "server.example.com"}
+	added, removed := diffSANs(before, after)
+	if want := []string{"server.example.com"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("diffSANs() added = %v, want %v", added, want)
+	}
+	if want := []string{"server-1"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("diffSANs() removed = %v, want %v", removed, want)
+	}
+}
+
+func Test_UnitBackupPath(t *testing.T) {
+	serverDataDir := "/var/lib/rancher/k3s/server"
+	backupDir := "/var/lib/rancher/k3s/server/tls-1700000000"
+	tests := []struct {
+		file string
+		want string
+	}{
+		{
+			file: "/var/lib/rancher/k3s/server/tls/client-admin.crt",
+			want: "/var/lib/rancher/k3s/server/tls-1700000000/client-admin.crt",
+		},
+		{
+			file: "/var/lib/rancher/k3s/server/tls/etcd/client.crt",
+			want: "/var/lib/rancher/k3s/server/tls-1700000000/etcd/client.crt",
+		},
+		{
+			file: "/var/lib/rancher/k3s/agent/client-kubelet.crt",
+			want: "/var/lib/rancher/k3s/server/tls-1700000000/client-kubelet.crt",
+		},
+	}
+	for _, tt := range tests {
+		if got := backupPath(tt.file, serverDataDir, backupDir); got != tt.want {
+			t.Errorf("backupPath(%s) = %s, want %s", tt.file, got, tt.want)
+		}
+	}
+}
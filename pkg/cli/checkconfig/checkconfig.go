@@ -0,0 +1,47 @@
+package checkconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/preflight"
+	"github.com/urfave/cli"
+)
+
+func Run(app *cli.Context) error {
+	return run(app, &cmds.CheckConfigConfig)
+}
+
+func run(app *cli.Context, checkCfg *cmds.CheckConfig) error {
+	if checkCfg.Output != "text" && checkCfg.Output != "json" {
+		return fmt.Errorf("invalid output format %q; must be one of text, json", checkCfg.Output)
+	}
+
+	opts := preflight.Options{KernelConfig: checkCfg.KernelConfig, SELinux: checkCfg.SELinux}
+	if app.NArg() > 0 {
+		opts.KernelConfig = app.Args().First()
+	}
+	// verify the binaries alongside this one, which are extracted from the same bundle
+	if exe, err := os.Executable(); err == nil {
+		opts.BinDir = filepath.Dir(exe)
+	}
+
+	report, err := preflight.Run(opts)
+	if err != nil {
+		return err
+	}
+	if checkCfg.Output == "json" {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout, os.Getenv("NO_COLOR") == "")
+	}
+	if err != nil {
+		return err
+	}
+	if !report.Passed() {
+		return cli.NewExitError("", report.Failures)
+	}
+	return nil
+}
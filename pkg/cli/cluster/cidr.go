@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/clustercidr"
+	"github.com/k3s-io/k3s/pkg/server"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func ExpandCIDR(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return expandCIDR(app, &cmds.ServerConfig, &cmds.ClusterConfig)
+}
+
+func expandCIDR(app *cli.Context, cfg *cmds.Server, clusterCfg *cmds.Cluster) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	dataDir, err := server.ResolveDataDir(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+
+	state, err := clustercidr.Load(dataDir)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return errors.New("no cluster-cidr has been recorded; the server has not been started with this data-dir")
+	}
+	current := strings.Join(state.ClusterCIDRs, ",")
+
+	if clusterCfg.AddCIDR != "" {
+		_, additional, err := net.ParseCIDR(clusterCfg.AddCIDR)
+		if err != nil {
+			return errors.Wrapf(err, "invalid CIDR %s", clusterCfg.AddCIDR)
+		}
+		clusterCIDRs, err := parseCIDRs(state.ClusterCIDRs)
+		if err != nil {
+			return errors.Wrap(err, "invalid recorded cluster-cidr")
+		}
+		serviceCIDRs, err := parseCIDRs(state.ServiceCIDRs)
+		if err != nil {
+			return errors.Wrap(err, "invalid recorded service-cidr")
+		}
+		expanded, err := clustercidr.Expand(clusterCIDRs, serviceCIDRs, additional)
+		if err != nil {
+			return err
+		}
+		if util.JoinIPNets(expanded) == current {
+			logrus.Infof("%s is already within the cluster-cidr %s", additional, current)
+			return nil
+		}
+		logrus.Infof("Existing node pod CIDRs cannot be moved, so %s is added by expanding the cluster-cidr from %s to %s", additional, current, util.JoinIPNets(expanded))
+		logrus.Infof("To expand the cluster-cidr: set --cluster-cidr=%s on each server and restart %s on the servers one at a time; "+
+			"new nodes are allocated pod CIDRs from the expanded range once the controller-manager restarts. "+
+			"Then restart %s on the agents, and run '%s cluster expand-cidr' to find any nodes that have not been restarted", util.JoinIPNets(expanded), version.Program, version.Program, version.Program)
+		return nil
+	}
+
+	logrus.Infof("Cluster CIDR is %s", current)
+	client, err := util.GetClientSet(util.GetKubeConfigPath(clusterCfg.Kubeconfig))
+	if err != nil {
+		return err
+	}
+	nodes, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	pending := clustercidr.PendingNodes(nodes.Items, state)
+	if len(pending) == 0 {
+		logrus.Infof("All nodes are using the current cluster-cidr")
+		return nil
+	}
+	logrus.Infof("Restart %s on the following nodes so that flannel and kube-proxy use the current cluster-cidr; "+
+		"until then, traffic from their pods to pods in the expanded range is masqueraded: %s", version.Program, strings.Join(pending, ", "))
+	return nil
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	var parsed []*net.IPNet
+	for _, cidr := range cidrs {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, ipnet)
+	}
+	return parsed, nil
+}
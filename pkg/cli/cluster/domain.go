@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/clusterdomain"
+	"github.com/k3s-io/k3s/pkg/server"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+func MigrateDomain(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return migrateDomain(app, &cmds.ServerConfig, &cmds.ClusterConfig)
+}
+
+func migrateDomain(app *cli.Context, cfg *cmds.Server, clusterCfg *cmds.Cluster) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	dataDir, err := server.ResolveDataDir(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+
+	state, err := clusterdomain.Load(dataDir)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return errors.New("no cluster domain has been recorded; the server has not been started with this data-dir")
+	}
+	if len(state.Previous) == 0 {
+		logrus.Infof("Cluster domain is %s; no migration is in progress", state.Domain)
+		return nil
+	}
+
+	if !clusterCfg.Finalize {
+		logrus.Infof("Cluster domain is being migrated from %s to %s", strings.Join(state.Previous, ","), state.Domain)
+		logrus.Infof("Previous domains are served by CoreDNS, accepted as service account token issuers, and included in the apiserver certificate")
+		logrus.Infof("To complete the migration: restart %s on all servers and agents so that the kubelet uses the new domain, "+
+			"recreate pods so that they use the new DNS search domains and service account tokens, "+
+			"then run '%s cluster migrate-domain --finalize' and restart %s on each server", version.Program, version.Program, version.Program)
+		return nil
+	}
+
+	previous := state.Previous
+	if state, err = clusterdomain.Finalize(dataDir); err != nil {
+		return err
+	}
+	logrus.Infof("Finalized migration of cluster domain from %s to %s; restart %s to stop serving the previous domains", strings.Join(previous, ","), state.Domain, version.Program)
+	return nil
+}
@@ -0,0 +1,50 @@
+package cluster
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/cluster"
+	"github.com/k3s-io/k3s/pkg/server"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+func Export(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return export(&cmds.ServerConfig, &cmds.ClusterConfig)
+}
+
+func export(cfg *cmds.Server, clusterCfg *cmds.Cluster) error {
+	dataDir, err := server.ResolveDataDir(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+
+	output := clusterCfg.Output
+	if output == "" {
+		output = fmt.Sprintf("%s-cluster-%d.tar.gz", version.Program, time.Now().Unix())
+	}
+
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := cluster.Export(f, dataDir, clusterCfg.Rekey); err != nil {
+		os.Remove(output)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	logrus.Infof("Cluster archive written to %s", output)
+	return nil
+}
@@ -3,6 +3,7 @@ package cmds
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/urfave/cli"
@@ -25,7 +26,9 @@ type Agent struct {
 	NodeExternalIP           cli.StringSlice
 	NodeInternalDNS          cli.StringSlice
 	NodeExternalDNS          cli.StringSlice
+	STUNServer               cli.StringSlice
 	NodeName                 string
+	NodeNameTemplate         string
 	PauseImage               string
 	Snapshotter              string
 	Docker                   bool
@@ -37,6 +40,12 @@ type Agent struct {
 	FlannelIface             string
 	FlannelConf              string
 	FlannelCniConfFile       string
+	FlannelVXLANPort         int
+	FlannelWireguardPort     int
+	FlannelWireguardPortIPv6 int
+	CNIConfDir               string
+	CNIBinDir                string
+	CNIConfCompat            bool
 	VPNAuth                  string
 	VPNAuthFile              string
 	Debug                    bool
@@ -51,12 +60,52 @@ type Agent struct {
 	SystemDefaultRegistry    string
 	AirgapExtraRegistry      cli.StringSlice
 	ExtraKubeletArgs         cli.StringSlice
+	KubeletConfig            string
+	KubeProxyConfig          string
 	ExtraKubeProxyArgs       cli.StringSlice
 	Labels                   cli.StringSlice
 	Taints                   cli.StringSlice
+	LabelPolicies            cli.StringSlice
+	TaintPolicies            cli.StringSlice
 	ImageCredProvBinDir      string
 	ImageCredProvConfig      string
+	ImageCredProviders       cli.StringSlice
+	StaticPodDir             string
+	SystemReservedProfile    string
+	SwapBehavior             string
+	KubeletServingCSR        bool
+	ComponentLimits          cli.StringSlice
+	SupervisorOOMProtection  bool
+	StartupGates             cli.StringSlice
+	StartupGateTimeout       time.Duration
+	InsecureSkewTolerance    time.Duration
+	InstanceName             string
+	ImageStoreDir            string
 	ContainerRuntimeReady    chan<- struct{}
+	AgentReady               chan<- struct{}
+	EtcdIOWeight             int
+	WarmRestart              bool
+	DisconnectedAutonomy     bool
+	TunnelKeepAliveTimeout   time.Duration
+	TunnelCompression        bool
+	TunnelReconnectDelay     time.Duration
+	TunnelReconnectJitter    float64
+	SupervisorProxy          string
+	SupervisorProxyAuth      string
+	RegistryProxy            string
+	ProxyCredentialsFile     string
+	HostFirewall             string
+	SysctlProfile            string
+	SysctlCheckInterval      time.Duration
+	SysctlResetDrift         bool
+	ImagePullProgress        time.Duration
+	LogShippingType          string
+	LogShippingEndpoint      string
+	DisableLogShipping       bool
+	DNSFallbackCache         bool
+	TracingEndpoint          string
+	TracingSamplingRate      float64
+	ResetIdentityOnClone     bool
 	AgentShared
 }
 
@@ -93,12 +142,22 @@ var (
 		Usage: "(agent/networking) external DNS addresses to advertise for node",
 		Value: &AgentConfig.NodeExternalDNS,
 	}
+	STUNServerFlag = &cli.StringSliceFlag{
+		Name:  "stun-server",
+		Usage: "(agent/networking) STUN servers used to discover external IP addresses to advertise for node, if it is behind NAT and node-external-ip is not set. Advertised external IPs are preferred by the apiserver when connecting to the kubelet through the agent tunnel",
+		Value: &AgentConfig.STUNServer,
+	}
 	NodeNameFlag = &cli.StringFlag{
 		Name:        "node-name",
 		Usage:       "(agent/node) Node name",
 		EnvVar:      version.ProgramUpper + "_NODE_NAME",
 		Destination: &AgentConfig.NodeName,
 	}
+	NodeNameTemplateFlag = &cli.StringFlag{
+		Name:        "node-name-template",
+		Usage:       "(agent/node) Template to render the node name from, if node-name is not set, for example '{{.Hostname}}-{{.MachineID | shorthash}}'. Fields: .Hostname, .MachineID, .ProductUUID. Functions: shorthash, lower, trunc",
+		Destination: &AgentConfig.NodeNameTemplate,
+	}
 	WithNodeIDFlag = &cli.BoolFlag{
 		Name:        "with-node-id",
 		Usage:       "(agent/node) Append id to node name",
@@ -129,7 +188,7 @@ var (
 	}
 	CRIEndpointFlag = &cli.StringFlag{
 		Name:        "container-runtime-endpoint",
-		Usage:       "(agent/runtime) Disable embedded containerd and use the CRI socket at the given path; when used with --docker this sets the docker socket path",
+		Usage:       "(agent/runtime) Disable embedded containerd and use the CRI socket at the given path; when used with --docker this sets the docker socket path. When the socket is crio.sock, CNI and registry config is written for CRI-O",
 		Destination: &AgentConfig.ContainerRuntimeEndpoint,
 	}
 	DefaultRuntimeFlag = &cli.StringFlag{
@@ -166,6 +225,11 @@ var (
 		Destination: &AgentConfig.Snapshotter,
 		Value:       DefaultSnapshotter,
 	}
+	ImageStoreDirFlag = &cli.StringFlag{
+		Name:        "image-store-dir",
+		Usage:       "(agent/runtime) Directory for the containerd image store and container writable layers, for placing them on a different filesystem than the data-dir. Kubelet image filesystem eviction thresholds are set if it is on a different filesystem than the kubelet root dir. Existing images are not moved (default: ${data-dir}/agent/containerd)",
+		Destination: &AgentConfig.ImageStoreDir,
+	}
 	FlannelIfaceFlag = &cli.StringFlag{
 		Name:        "flannel-iface",
 		Usage:       "(agent/networking) Override default flannel interface",
@@ -181,6 +245,38 @@ var (
 		Usage:       "(agent/networking) Override default flannel cni config file",
 		Destination: &AgentConfig.FlannelCniConfFile,
 	}
+	FlannelVXLANPortFlag = &cli.IntFlag{
+		Name:        "flannel-vxlan-port",
+		Usage:       "(agent/networking) UDP port for flannel vxlan backend traffic between nodes; must be the same on all nodes (default: 8472, or 4789 on Windows)",
+		Destination: &AgentConfig.FlannelVXLANPort,
+	}
+	FlannelWireguardPortFlag = &cli.IntFlag{
+		Name:        "flannel-wireguard-port",
+		Usage:       "(agent/networking) UDP port for flannel wireguard-native backend IPv4 traffic between nodes; must be the same on all nodes",
+		Value:       51820,
+		Destination: &AgentConfig.FlannelWireguardPort,
+	}
+	FlannelWireguardPortIPv6Flag = &cli.IntFlag{
+		Name:        "flannel-wireguard-port-ipv6",
+		Usage:       "(agent/networking) UDP port for flannel wireguard-native backend IPv6 traffic between nodes; must be the same on all nodes",
+		Value:       51821,
+		Destination: &AgentConfig.FlannelWireguardPortIPv6,
+	}
+	CNIConfDirFlag = &cli.StringFlag{
+		Name:        "cni-conf-dir",
+		Usage:       "(agent/networking) Directory that the flannel CNI config is written to, and that the container runtime loads CNI config from (default: ${data-dir}/agent/etc/cni/net.d, or /etc/cni/net.d with CRI-O)",
+		Destination: &AgentConfig.CNIConfDir,
+	}
+	CNIBinDirFlag = &cli.StringFlag{
+		Name:        "cni-bin-dir",
+		Usage:       "(agent/networking) Directory that the container runtime loads CNI plugins from. The bundled CNI plugins are linked into it if they are not already present (default: the bundled CNI plugin directory)",
+		Destination: &AgentConfig.CNIBinDir,
+	}
+	CNIConfCompatFlag = &cli.BoolFlag{
+		Name:        "cni-conf-compat",
+		Usage:       "(agent/networking) Write the flannel CNI config with the standard flannel file name (10-flannel.conflist) and network name (cbr0) even when instance-name is set, for runtimes and tools that expect them",
+		Destination: &AgentConfig.CNIConfCompat,
+	}
 	VPNAuth = &cli.StringFlag{
 		Name:        "vpn-auth",
 		Usage:       "(agent/networking) (experimental) Credentials for the VPN provider. It must include the provider name and join key in the format name=<vpn-provider>,joinKey=<key>[,controlServerURL=<url>][,extraArgs=<args>]",
@@ -204,11 +300,21 @@ var (
 		Usage: "(agent/flags) Customized flag for kubelet process",
 		Value: &AgentConfig.ExtraKubeletArgs,
 	}
+	KubeletConfigFlag = &cli.StringFlag{
+		Name:        "kubelet-config",
+		Usage:       "(agent/flags) Path to a KubeletConfiguration file, or a directory of KubeletConfiguration files that are merged in lexical order, to apply over the default kubelet configuration",
+		Destination: &AgentConfig.KubeletConfig,
+	}
 	ExtraKubeProxyArgs = &cli.StringSliceFlag{
 		Name:  "kube-proxy-arg",
 		Usage: "(agent/flags) Customized flag for kube-proxy process",
 		Value: &AgentConfig.ExtraKubeProxyArgs,
 	}
+	KubeProxyConfigFlag = &cli.StringFlag{
+		Name:        "kube-proxy-config",
+		Usage:       "(agent/flags) Path to a KubeProxyConfiguration file to apply over the default kube-proxy configuration. The effective configuration is validated and written to the agent data directory",
+		Destination: &AgentConfig.KubeProxyConfig,
+	}
 	NodeTaints = &cli.StringSliceFlag{
 		Name:  "node-taint",
 		Usage: "(agent/node) Registering kubelet with set of taints",
@@ -219,6 +325,16 @@ var (
 		Usage: "(agent/node) Registering and starting kubelet with set of labels",
 		Value: &AgentConfig.Labels,
 	}
+	NodeLabelPolicy = &cli.StringSliceFlag{
+		Name:  "node-label-policy",
+		Usage: "(agent/node) Reconciliation policy for labels set with --node-label, in key=policy format, or a policy for all labels. 'initial' applies labels when the agent starts, after which they may be changed through the Kubernetes API; 'enforced' reverts changes made through the API, and removes labels from the node when they are removed from the configuration (default: initial)",
+		Value: &AgentConfig.LabelPolicies,
+	}
+	NodeTaintPolicy = &cli.StringSliceFlag{
+		Name:  "node-taint-policy",
+		Usage: "(agent/node) Reconciliation policy for taints set with --node-taint, in key=policy format, or a policy for all taints. 'initial' applies taints when the node registers, after which they may be changed through the Kubernetes API; 'enforced' reverts changes made through the API, and removes taints from the node when they are removed from the configuration (default: initial)",
+		Value: &AgentConfig.TaintPolicies,
+	}
 	ImageCredProvBinDirFlag = &cli.StringFlag{
 		Name:        "image-credential-provider-bin-dir",
 		Usage:       "(agent/node) The path to the directory where credential provider plugin binaries are located",
@@ -231,6 +347,32 @@ var (
 		Destination: &AgentConfig.ImageCredProvConfig,
 		Value:       "/var/lib/rancher/credentialprovider/config.yaml",
 	}
+	ImageCredProvFlag = &cli.StringSliceFlag{
+		Name:  "image-credential-provider",
+		Usage: "(agent/node) Credential provider plugin to enable for image pulls, in the form NAME[=MATCH-IMAGE]; known providers (ecr-credential-provider, acr-credential-provider, auth-provider-gcp) use default match images if none are given. When set, a generated config is used instead of the image-credential-provider-config file",
+		Value: &AgentConfig.ImageCredProviders,
+	}
+	SystemReservedProfileFlag = &cli.StringFlag{
+		Name:        "system-reserved-profile",
+		Usage:       "(agent/node) Reserve CPU and memory for system daemons and set eviction thresholds, scaled to the node's capacity. Options: small, medium, large",
+		Destination: &AgentConfig.SystemReservedProfile,
+	}
+	SwapBehaviorFlag = &cli.StringFlag{
+		Name:        "kubelet-swap-behavior",
+		Usage:       "(agent/node) Swap behavior for kubelet workloads. Options: NoSwap, LimitedSwap (requires cgroups v2)",
+		Destination: &AgentConfig.SwapBehavior,
+	}
+	KubeletServingCSRFlag = &cli.BoolFlag{
+		Name:        "kubelet-serving-csr",
+		Usage:       "(agent/node) Request the kubelet serving certificate through the cluster's CertificateSigningRequest API instead of from the supervisor, so that it is rotated by the kubelet",
+		Destination: &AgentConfig.KubeletServingCSR,
+	}
+	StaticPodDirFlag = &cli.StringFlag{
+		Name:        "static-pod-dir",
+		Usage:       "(agent/node) The path to the directory containing user-provided static pod manifests",
+		Destination: &AgentConfig.StaticPodDir,
+		Value:       "/etc/rancher/" + version.Program + "/static-pods",
+	}
 	DisableAgentLBFlag = &cli.BoolFlag{
 		Name:        "disable-apiserver-lb",
 		Usage:       "(agent/networking) (experimental) Disable the agent's client-side load-balancer and connect directly to the configured server address",
@@ -248,9 +390,157 @@ var (
 	}
 	EnablePProfFlag = &cli.BoolFlag{
 		Name:        "enable-pprof",
-		Usage:       "(experimental) Enable pprof endpoint on supervisor port",
+		Usage:       "(experimental) Enable pprof endpoint on supervisor port, and on control-plane components run as processes",
 		Destination: &AgentConfig.EnablePProf,
 	}
+	WarmRestartFlag = &cli.BoolFlag{
+		Name:        "warm-restart",
+		Usage:       "(experimental) Reuse still-valid certificates and cached apiserver addresses from the previous run to reduce restart time",
+		Destination: &AgentConfig.WarmRestart,
+	}
+	DisconnectedAutonomyFlag = &cli.BoolFlag{
+		Name:        "disconnected-autonomy",
+		Usage:       "(experimental) Checkpoint host-network pods annotated with " + version.Program + ".io/autonomous=true, and run them as static pods if no server can be reached when the agent starts",
+		Destination: &AgentConfig.DisconnectedAutonomy,
+	}
+	ComponentLimitFlag = &cli.StringSliceFlag{
+		Name:  "component-resource-limit",
+		Usage: "(experimental) Resource limit for a component, in the form COMPONENT.RESOURCE=QUANTITY (e.g. containerd.memory=1Gi); components are supervisor (the main process), containerd, kubelet (run as a process when limited), and kube-apiserver, kube-scheduler or kube-controller-manager when run as processes. Requires cgroups v2",
+		Value: &AgentConfig.ComponentLimits,
+	}
+	SupervisorOOMProtectionFlag = &cli.BoolFlag{
+		Name:        "supervisor-oom-protection",
+		Usage:       "(experimental) Lower the oom_score_adj of the supervisor and containerd below that of all workloads. If the supervisor has a memory limit, from the supervisor.memory component resource limit or the cgroup it is started in, the Go runtime soft memory limit is set below it unless GOMEMLIMIT is set, and unused memory is released when the supervisor nears the limit",
+		Destination: &AgentConfig.SupervisorOOMProtection,
+	}
+	StartupGateFlag = &cli.StringSliceFlag{
+		Name:  "startup-gate",
+		Usage: "(experimental) Condition to wait for before starting, for hosts where the network or clock may not be ready when " + version.Program + " is started. Options: interface-address[=IFACE], default-route, clock-sync",
+		Value: &AgentConfig.StartupGates,
+	}
+	StartupGateTimeoutFlag = &cli.DurationFlag{
+		Name:        "startup-gate-timeout",
+		Usage:       "(experimental) Maximum time to wait for startup gates to be satisfied before starting anyway; 0 waits indefinitely",
+		Value:       5 * time.Minute,
+		Destination: &AgentConfig.StartupGateTimeout,
+	}
+	InsecureSkewToleranceFlag = &cli.DurationFlag{
+		Name:        "insecure-skew-tolerance",
+		Usage:       "(agent/node) Start even if the system clock is behind the earliest plausible time by up to this duration, instead of waiting at startup for the clock to be set. Certificates generated or validated while the clock is wrong may not be valid once it is set",
+		Destination: &AgentConfig.InsecureSkewTolerance,
+	}
+	InstanceNameFlag = &cli.StringFlag{
+		Name:        "instance-name",
+		Usage:       "(experimental) Name used to namespace the runtime directory, containerd socket, kubelet root directory, CNI network and bridge, and flannel VXLAN interfaces, so that more than one instance can run on the same host. Each instance must also use its own data-dir, ports, and cluster and service CIDRs. kube-proxy and the network policy controller must be disabled, as their iptables chains cannot be namespaced; flannel adds rules for each instance's cluster CIDR to shared iptables chains, and pod host ports are mapped in shared iptables chains, so host ports must not be reused across instances",
+		EnvVar:      version.ProgramUpper + "_INSTANCE_NAME",
+		Destination: &AgentConfig.InstanceName,
+	}
+	TunnelKeepAliveTimeoutFlag = &cli.DurationFlag{
+		Name:        "tunnel-keepalive-timeout",
+		Usage:       "(agent/networking) Time to wait for a websocket pong from the server before the agent tunnel is closed and reconnected; agents send a websocket ping every 5s. On servers this is also the time to wait for a ping from each agent before its tunnel is closed. Must be longer than 5s (default: 60s)",
+		Destination: &AgentConfig.TunnelKeepAliveTimeout,
+	}
+	TunnelCompressionFlag = &cli.BoolFlag{
+		Name:        "tunnel-compression",
+		Usage:       "(agent/networking) Compress the agent websocket tunnel with deflate, if supported by the server, to reduce bandwidth on slow links",
+		Destination: &AgentConfig.TunnelCompression,
+	}
+	TunnelReconnectDelayFlag = &cli.DurationFlag{
+		Name:        "tunnel-reconnect-delay",
+		Usage:       "(agent/networking) Delay between attempts to reconnect the agent websocket tunnel to a server",
+		Value:       time.Second,
+		Destination: &AgentConfig.TunnelReconnectDelay,
+	}
+	TunnelReconnectJitterFlag = &cli.Float64Flag{
+		Name:        "tunnel-reconnect-jitter",
+		Usage:       "(agent/networking) Maximum random factor to add to the tunnel reconnect delay, so that agents do not reconnect in lockstep after a server outage (e.g. 1.0 waits up to twice the delay)",
+		Destination: &AgentConfig.TunnelReconnectJitter,
+	}
+	SupervisorProxyFlag = &cli.StringFlag{
+		Name:        "supervisor-proxy",
+		Usage:       "(agent/networking) Proxy URL to use for connections to servers, instead of the proxy environment variables. Supported schemes: http, https, socks5",
+		Destination: &AgentConfig.SupervisorProxy,
+	}
+	SupervisorProxyAuthFlag = &cli.StringFlag{
+		Name:        "supervisor-proxy-auth",
+		Usage:       "(agent/networking) Authentication scheme to use when an HTTP supervisor proxy requests authentication. Options: basic, ntlm, kerberos. Kerberos uses the system krb5.conf, and the credentials in the proxy URL or the user's credentials cache",
+		Value:       "basic",
+		Destination: &AgentConfig.SupervisorProxyAuth,
+	}
+	RegistryProxyFlag = &cli.StringFlag{
+		Name:        "registry-proxy",
+		Usage:       "(agent/networking) Proxy URL to use for image pulls by containerd, instead of the proxy environment variables. Loopback addresses are not proxied unless NO_PROXY is set",
+		Destination: &AgentConfig.RegistryProxy,
+	}
+	ProxyCredentialsFileFlag = &cli.StringFlag{
+		Name:        "proxy-credentials-file",
+		Usage:       "(agent/networking) File containing username:password to authenticate to the supervisor and registry proxies with, if not set in the proxy URL",
+		Destination: &AgentConfig.ProxyCredentialsFile,
+	}
+	HostFirewallFlag = &cli.StringFlag{
+		Name:        "host-firewall",
+		Usage:       "(agent/networking) Register required ports and cluster CIDRs with the host firewall instead of relying on it being disabled. Options: firewalld, ufw",
+		Destination: &AgentConfig.HostFirewall,
+	}
+	SysctlProfileFlag = &cli.StringFlag{
+		Name:        "sysctl-profile",
+		Usage:       "(agent/node) File in sysctl.conf format containing additional sysctls to apply and enforce on this node",
+		Destination: &AgentConfig.SysctlProfile,
+	}
+	SysctlCheckIntervalFlag = &cli.DurationFlag{
+		Name:        "sysctl-check-interval",
+		Usage:       "(agent/node) Interval at which managed sysctls are checked for drift from their managed value. Drift is reported as an event on the node, and only reset if sysctl-reset-drift is set. 0 disables drift checks",
+		Value:       5 * time.Minute,
+		Destination: &AgentConfig.SysctlCheckInterval,
+	}
+	SysctlResetDriftFlag = &cli.BoolFlag{
+		Name:        "sysctl-reset-drift",
+		Usage:       "(agent/node) Reset managed sysctls that have drifted from their managed value, instead of only reporting the drift",
+		Destination: &AgentConfig.SysctlResetDrift,
+	}
+	ImagePullProgressFlag = &cli.DurationFlag{
+		Name:        "image-pull-progress-threshold",
+		Usage:       "(agent/runtime) Duration after which in-progress image pulls are reported by periodic events on the node. 0 disables pull progress reporting",
+		Value:       time.Minute,
+		Destination: &AgentConfig.ImagePullProgress,
+	}
+	LogShippingTypeFlag = &cli.StringFlag{
+		Name:        "log-shipping-type",
+		Usage:       "(agent/logging) Protocol used to forward container logs to the log shipping endpoint. Options: syslog, loki, otlp",
+		Value:       "syslog",
+		Destination: &AgentConfig.LogShippingType,
+	}
+	LogShippingEndpointFlag = &cli.StringFlag{
+		Name:        "log-shipping-endpoint",
+		Usage:       "(agent/logging) Endpoint to forward container logs to, for example udp://syslog.example.com:514, http://loki.example.com:3100, or http://otel-collector.example.com:4318",
+		Destination: &AgentConfig.LogShippingEndpoint,
+	}
+	DisableLogShippingFlag = &cli.BoolFlag{
+		Name:        "disable-log-shipping",
+		Usage:       "(agent/logging) Disable forwarding of container logs from this node, even if a log shipping endpoint is configured",
+		Destination: &AgentConfig.DisableLogShipping,
+	}
+	DNSFallbackCacheFlag = &cli.BoolFlag{
+		Name:        "dns-fallback-cache",
+		Usage:       "(agent/networking) Cache the last-known cluster Services and Endpoints on the node, and serve it on the link-local address 169.254.20.11 as the upstream nameserver of coredns and other pods that use the node's DNS settings, so that cluster services can be resolved while the apiserver is unavailable. Queries for other names are forwarded to the node's nameservers",
+		Destination: &AgentConfig.DNSFallbackCache,
+	}
+	TracingEndpointFlag = &cli.StringFlag{
+		Name:        "tracing-endpoint",
+		Usage:       "(experimental) OTLP gRPC endpoint to export traces of " + version.Program + " startup, agent join, certificate issuance, manifest apply, and snapshot operations to, for example otel-collector.example.com:4317",
+		Destination: &AgentConfig.TracingEndpoint,
+	}
+	TracingSamplingRateFlag = &cli.Float64Flag{
+		Name:        "tracing-sampling-rate",
+		Usage:       "(experimental) Fraction of traces to export, between 0 and 1",
+		Value:       1,
+		Destination: &AgentConfig.TracingSamplingRate,
+	}
+	ResetIdentityOnCloneFlag = &cli.BoolFlag{
+		Name:        "reset-identity-on-clone",
+		Usage:       "(experimental) Reset certificates, keys, and tokens at startup if the data-dir was created on a different host, as when a disk image is cloned. Startup fails instead if the data-dir contains a datastore, which must be reset with the reset-identity command",
+		Destination: &AgentConfig.ResetIdentityOnClone,
+	}
 	BindAddressFlag = &cli.StringFlag{
 		Name:        "bind-address",
 		Usage:       "(listener) " + version.Program + " bind address (default: 0.0.0.0)",
@@ -271,6 +561,8 @@ func NewAgentCommand(action func(ctx *cli.Context) error) cli.Command {
 			VModule,
 			LogFile,
 			AlsoLogToStderr,
+			StartupSummary,
+			Quiet,
 			AgentTokenFlag,
 			&cli.StringFlag{
 				Name:        "token-file",
@@ -294,11 +586,19 @@ func NewAgentCommand(action func(ctx *cli.Context) error) cli.Command {
 				EnvVar:      version.ProgramUpper + "_DATA_DIR",
 			},
 			NodeNameFlag,
+			NodeNameTemplateFlag,
 			WithNodeIDFlag,
 			NodeLabels,
 			NodeTaints,
+			NodeLabelPolicy,
+			NodeTaintPolicy,
 			ImageCredProvBinDirFlag,
 			ImageCredProvConfigFlag,
+			ImageCredProvFlag,
+			StaticPodDirFlag,
+			SystemReservedProfileFlag,
+			SwapBehaviorFlag,
+			KubeletServingCSRFlag,
 			SELinuxFlag,
 			LBServerPortFlag,
 			ProtectKernelDefaultsFlag,
@@ -307,6 +607,7 @@ func NewAgentCommand(action func(ctx *cli.Context) error) cli.Command {
 			ImageServiceEndpointFlag,
 			PauseImageFlag,
 			SnapshotterFlag,
+			ImageStoreDirFlag,
 			PrivateRegistryFlag,
 			DisableDefaultRegistryEndpointFlag,
 			NonrootDevicesFlag,
@@ -316,14 +617,51 @@ func NewAgentCommand(action func(ctx *cli.Context) error) cli.Command {
 			NodeExternalIPFlag,
 			NodeInternalDNSFlag,
 			NodeExternalDNSFlag,
+			STUNServerFlag,
 			ResolvConfFlag,
 			FlannelIfaceFlag,
 			FlannelConfFlag,
 			FlannelCniConfFileFlag,
+			FlannelVXLANPortFlag,
+			FlannelWireguardPortFlag,
+			FlannelWireguardPortIPv6Flag,
+			CNIConfDirFlag,
+			CNIBinDirFlag,
+			CNIConfCompatFlag,
+			TunnelKeepAliveTimeoutFlag,
+			TunnelCompressionFlag,
+			TunnelReconnectDelayFlag,
+			TunnelReconnectJitterFlag,
+			SupervisorProxyFlag,
+			SupervisorProxyAuthFlag,
+			RegistryProxyFlag,
+			ProxyCredentialsFileFlag,
+			HostFirewallFlag,
+			SysctlProfileFlag,
+			SysctlCheckIntervalFlag,
+			SysctlResetDriftFlag,
+			ImagePullProgressFlag,
+			LogShippingTypeFlag,
+			LogShippingEndpointFlag,
+			DisableLogShippingFlag,
+			DNSFallbackCacheFlag,
+			TracingEndpointFlag,
+			TracingSamplingRateFlag,
 			ExtraKubeletArgs,
+			KubeletConfigFlag,
 			ExtraKubeProxyArgs,
+			KubeProxyConfigFlag,
 			// Experimental flags
 			EnablePProfFlag,
+			WarmRestartFlag,
+			DisconnectedAutonomyFlag,
+			ComponentLimitFlag,
+			SupervisorOOMProtectionFlag,
+			StartupGateFlag,
+			StartupGateTimeoutFlag,
+			InsecureSkewToleranceFlag,
+			InstanceNameFlag,
+			ResetIdentityOnCloneFlag,
 			&cli.BoolFlag{
 				Name:        "rootless",
 				Usage:       "(experimental) Run rootless",
@@ -0,0 +1,94 @@
+package cmds
+
+import (
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/urfave/cli"
+)
+
+const BackupCommand = "backup"
+
+// Backup holds CLI values for the backup subcommands
+type Backup struct {
+	ServerURL string
+	Token     string
+	File      string
+}
+
+var (
+	BackupConfig          = Backup{}
+	BackupEtcdDataDirFlag = &cli.StringFlag{
+		Name:        "etcd-data-dir",
+		Usage:       "(db) Directory that the server holds etcd data in, if it was set with --etcd-data-dir (default: ${data-dir}/server/db/etcd)",
+		Destination: &ServerConfig.EtcdDataDir,
+	}
+	BackupCreateFlags = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		BackupEtcdDataDirFlag,
+		&cli.StringFlag{
+			Name:        "datastore-endpoint",
+			Usage:       "(db) Datastore endpoint of the server, if it uses an external datastore that must be backed up separately",
+			Destination: &ServerConfig.DatastoreEndpoint,
+		},
+		&cli.StringFlag{
+			Name:        "server, s",
+			Usage:       "(cluster) Server to connect to when taking an etcd snapshot",
+			Value:       "https://127.0.0.1:6443",
+			Destination: &BackupConfig.ServerURL,
+		},
+		&cli.StringFlag{
+			Name:        "token, t",
+			Usage:       "(cluster) Shared secret used to authenticate to the server; read from the data-dir if not set",
+			EnvVar:      version.ProgramUpper + "_TOKEN",
+			Destination: &BackupConfig.Token,
+		},
+		&cli.StringFlag{
+			Name:        "file, f",
+			Usage:       "(backup) Path to write the backup archive to (default: ${data-dir}/server/backups/" + version.Program + "-backup-${hostname}-${timestamp}.tar.gz)",
+			Destination: &BackupConfig.File,
+		},
+	}
+	BackupRestoreFlags = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		BackupEtcdDataDirFlag,
+		&cli.StringFlag{
+			Name:        "file, f",
+			Usage:       "(backup) Path of the backup archive to restore",
+			Destination: &BackupConfig.File,
+		},
+	}
+)
+
+func NewBackupCommands(create, restore func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            BackupCommand,
+		Usage:           "Back up and restore server state",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Subcommands: []cli.Command{
+			{
+				Name:            "create",
+				Usage:           "Create an archive containing a snapshot of the datastore, the server's certificates and keys, tokens, and user-provided manifests",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          create,
+				Flags:           BackupCreateFlags,
+			},
+			{
+				Name:            "restore",
+				Usage:           "Restore a server from a backup archive into an empty data-dir. Servers using etcd are reset to a new single-member cluster from the snapshot in the archive",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          restore,
+				Flags:           BackupRestoreFlags,
+			},
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package cmds
+
+import (
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/urfave/cli"
+)
+
+const BootstrapCommand = "bootstrap"
+
+// Bootstrap holds CLI values for the bootstrap subcommands
+type Bootstrap struct {
+	ServerURLs cli.StringSlice
+	Token      string
+	Output     string
+	Reconcile  bool
+}
+
+var (
+	BootstrapConfig    = Bootstrap{}
+	BootstrapDiffFlags = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		&cli.StringSliceFlag{
+			Name:  "server, s",
+			Usage: "(bootstrap) Server to compare bootstrap data on; may be repeated to compare multiple servers (default: https://127.0.0.1:6443)",
+			Value: &BootstrapConfig.ServerURLs,
+		},
+		&cli.StringFlag{
+			Name:        "token, t",
+			Usage:       "(bootstrap) Shared secret used to authenticate to the servers; read from the data-dir if not set",
+			EnvVar:      version.ProgramUpper + "_TOKEN",
+			Destination: &BootstrapConfig.Token,
+		},
+		&cli.StringFlag{
+			Name:        "output, o",
+			Usage:       "(bootstrap) Output format. Default: text. Optional: json",
+			Destination: &BootstrapConfig.Output,
+		},
+		&cli.BoolFlag{
+			Name:        "reconcile",
+			Usage:       "(bootstrap) Move aside files in the local data-dir that differ from the datastore, so that they are restored from the datastore when the server is restarted",
+			Destination: &BootstrapConfig.Reconcile,
+		},
+	}
+)
+
+func NewBootstrapCommands(diff func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            BootstrapCommand,
+		Usage:           "Inspect cluster bootstrap data",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Subcommands: []cli.Command{
+			{
+				Name:            "diff",
+				Usage:           "Compare the bootstrap certificates and keys stored in the datastore to the files on disk on each server, and report any that do not match",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          diff,
+				Flags:           BootstrapDiffFlags,
+			},
+		},
+	}
+}
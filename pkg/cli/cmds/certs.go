@@ -10,6 +10,7 @@ const CertCommand = "certificate"
 type CertRotateCA struct {
 	CACertPath string
 	Force      bool
+	Phase      string
 }
 
 var (
@@ -23,7 +24,7 @@ var (
 		DataDirFlag,
 		&cli.StringSliceFlag{
 			Name:  "service,s",
-			Usage: "List of services to manage certificates for. Options include (admin, api-server, controller-manager, scheduler, supervisor, " + version.Program + "-controller, " + version.Program + "-server, cloud-controller, etcd, auth-proxy, kubelet, kube-proxy)",
+			Usage: "List of services to manage certificates for. Options include (admin, api-server, controller-manager, scheduler, supervisor, " + version.Program + "-controller, " + version.Program + "-server, cloud-controller, etcd, auth-proxy, kubelet, kube-proxy), or the groups (agent, server, all)",
 			Value: &ServicesList,
 		},
 	}
@@ -47,6 +48,11 @@ var (
 			Usage:       "Force certificate replacement, even if consistency checks fail",
 			Destination: &CertRotateCAConfig.Force,
 		},
+		cli.StringFlag{
+			Name:        "phase",
+			Usage:       "Apply a single phase of a phased CA rotation, in order: trust (add the new CA to trust bundles), reissue (sign with the new CA), retire (remove the old CA from trust bundles). Progress is tracked in the ClusterCARotation resource",
+			Destination: &CertRotateCAConfig.Phase,
+		},
 	}
 )
 
@@ -63,11 +69,16 @@ func NewCertCommands(check, rotate, rotateCA func(ctx *cli.Context) error) cli.C
 				SkipFlagParsing: false,
 				SkipArgReorder:  true,
 				Action:          check,
-				Flags: append(CertRotateCommandFlags, &cli.StringFlag{
-					Name:  "output,o",
-					Usage: "Format output. Options: text, table",
-					Value: "text",
-				}),
+				Flags: append(CertRotateCommandFlags,
+					&cli.StringFlag{
+						Name:  "output,o",
+						Usage: "Format output. Options: text, table",
+						Value: "text",
+					},
+					&cli.StringFlag{
+						Name:  "compare",
+						Usage: "Compare certificate expiry and SANs against a backup directory created by rotate",
+					}),
 			},
 			{
 				Name:            "rotate",
@@ -75,7 +86,11 @@ func NewCertCommands(check, rotate, rotateCA func(ctx *cli.Context) error) cli.C
 				SkipFlagParsing: false,
 				SkipArgReorder:  true,
 				Action:          rotate,
-				Flags:           CertRotateCommandFlags,
+				Flags: append(CertRotateCommandFlags,
+					&cli.BoolFlag{
+						Name:  "restart",
+						Usage: "Restart the " + version.Program + " service that owns the rotated certificates, and print the changes to their expiry and SANs once they have been regenerated",
+					}),
 			},
 			{
 				Name:            "rotate-ca",
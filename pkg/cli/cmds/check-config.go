@@ -1,15 +1,50 @@
 package cmds
 
 import (
+	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/urfave/cli"
 )
 
+const CheckConfigCommand = "check-config"
+
+// CheckConfig holds CLI values for the check-config command
+type CheckConfig struct {
+	KernelConfig string
+	Output       string
+	SELinux      bool
+}
+
+var (
+	CheckConfigConfig = CheckConfig{}
+	CheckConfigFlags  = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "kernel-config",
+			Usage:       "(check) Path to the kernel config; may also be provided as an argument (default: search well-known locations)",
+			EnvVar:      "CONFIG",
+			Destination: &CheckConfigConfig.KernelConfig,
+		},
+		&cli.StringFlag{
+			Name:        "output, o",
+			Usage:       "(check) Output format. Options: text, json",
+			Value:       "text",
+			Destination: &CheckConfigConfig.Output,
+		},
+		&cli.BoolFlag{
+			Name:        "selinux",
+			Usage:       "(check) Include SELinux and " + version.Program + "-selinux policy details",
+			Destination: &CheckConfigConfig.SELinux,
+		},
+	}
+)
+
 func NewCheckConfigCommand(action func(*cli.Context) error) cli.Command {
 	return cli.Command{
-		Name:            "check-config",
+		Name:            CheckConfigCommand,
 		Usage:           "Run config check",
-		SkipFlagParsing: true,
+		ArgsUsage:       "[kernel-config]",
+		SkipFlagParsing: false,
 		SkipArgReorder:  true,
 		Action:          action,
+		Flags:           CheckConfigFlags,
 	}
 }
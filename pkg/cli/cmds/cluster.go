@@ -0,0 +1,103 @@
+package cmds
+
+import (
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/urfave/cli"
+)
+
+const ClusterCommand = "cluster"
+
+// Cluster holds CLI values for the cluster subcommands
+type Cluster struct {
+	Output     string
+	Rekey      bool
+	Finalize   bool
+	Kubeconfig string
+	AddCIDR    string
+}
+
+var (
+	ClusterConfig      = Cluster{}
+	ClusterExportFlags = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		&cli.StringFlag{
+			Name:        "output, o",
+			Usage:       "(cluster) Path to write the cluster archive to (default: ./" + version.Program + "-cluster-<timestamp>.tar.gz)",
+			Destination: &ClusterConfig.Output,
+		},
+		&cli.BoolFlag{
+			Name:        "rekey",
+			Usage:       "(cluster) Generate new server and agent tokens for the archive, instead of exporting the tokens of this cluster",
+			Destination: &ClusterConfig.Rekey,
+		},
+	}
+	ClusterMigrateDomainFlags = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		&cli.BoolFlag{
+			Name:        "finalize",
+			Usage:       "(cluster) Stop serving previous cluster domains the next time the server is restarted",
+			Destination: &ClusterConfig.Finalize,
+		},
+	}
+	ClusterExpandCIDRFlags = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		&cli.StringFlag{
+			Name:        "kubeconfig",
+			Usage:       "(cluster) Server to connect to",
+			EnvVar:      "KUBECONFIG",
+			Destination: &ClusterConfig.Kubeconfig,
+		},
+		&cli.StringFlag{
+			Name:        "add",
+			Usage:       "(cluster) CIDR to add to the cluster-cidr; shows the expanded cluster-cidr to set on each server",
+			Destination: &ClusterConfig.AddCIDR,
+		},
+	}
+)
+
+func NewClusterCommands(export, migrateDomain, expandCIDR func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            ClusterCommand,
+		Usage:           "Manage cluster configuration",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Subcommands: []cli.Command{
+			{
+				Name:            "export",
+				Usage:           "Export bootstrap data, CA certificates, tokens, and manifest overrides to an archive that can be used to create a new cluster with --cluster-import",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          export,
+				Flags:           ClusterExportFlags,
+			},
+			{
+				Name:            "migrate-domain",
+				Usage:           "Show the status of a cluster-domain change on this server, or finalize it once all nodes have been restarted and pods recreated with the new domain",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          migrateDomain,
+				Flags:           ClusterMigrateDomainFlags,
+			},
+			{
+				Name:            "expand-cidr",
+				Usage:           "Show the expanded cluster-cidr that adds a CIDR to the pod address space, or the nodes that must be restarted to use the expanded cluster-cidr",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          expandCIDR,
+				Flags:           ClusterExpandCIDRFlags,
+			},
+		},
+	}
+}
@@ -0,0 +1,166 @@
+package cmds
+
+import (
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/urfave/cli"
+)
+
+const DebugCommand = "debug"
+
+// DebugProfile holds CLI values for the debug profile subcommand
+type DebugProfile struct {
+	ServerURL string
+	Duration  time.Duration
+	Output    string
+	Trace     bool
+}
+
+// DebugTunnels holds CLI values for the debug tunnels and egress subcommands
+type DebugTunnels struct {
+	ServerURL string
+	Token     string
+	Output    string
+}
+
+// DebugMemStats holds CLI values for the debug memstats subcommand
+type DebugMemStats struct {
+	DebugTunnels
+	Budget string
+}
+
+var (
+	DebugProfileConfig = DebugProfile{}
+	DebugProfileFlags  = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		&cli.StringFlag{
+			Name:        "server, s",
+			Usage:       "(debug) Supervisor to collect profiles from; must be a local address as profiles are collected using certificates from the data-dir",
+			Value:       "https://127.0.0.1:6443",
+			Destination: &DebugProfileConfig.ServerURL,
+		},
+		&cli.DurationFlag{
+			Name:        "duration",
+			Usage:       "(debug) Duration to collect CPU profiles and execution traces for",
+			Value:       30 * time.Second,
+			Destination: &DebugProfileConfig.Duration,
+		},
+		&cli.StringFlag{
+			Name:        "output, o",
+			Usage:       "(debug) Path to write the profile bundle to (default: ./" + version.Program + "-profile-<timestamp>.tar.gz)",
+			Destination: &DebugProfileConfig.Output,
+		},
+		&cli.BoolFlag{
+			Name:        "trace",
+			Usage:       "(debug) Also collect a runtime execution trace",
+			Destination: &DebugProfileConfig.Trace,
+		},
+	}
+
+	DebugTunnelsConfig = DebugTunnels{}
+	DebugTunnelsFlags  = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		&cli.StringFlag{
+			Name:        "server, s",
+			Usage:       "(debug) Server to query agent tunnels or egress status from",
+			EnvVar:      version.ProgramUpper + "_URL",
+			Value:       "https://127.0.0.1:6443",
+			Destination: &DebugTunnelsConfig.ServerURL,
+		},
+		&cli.StringFlag{
+			Name:        "token, t",
+			Usage:       "(debug) Shared secret used to authenticate to the server; read from the data-dir if not set",
+			EnvVar:      version.ProgramUpper + "_TOKEN",
+			Destination: &DebugTunnelsConfig.Token,
+		},
+		&cli.StringFlag{
+			Name:        "output, o",
+			Usage:       "(debug) Output format. Default: text. Optional: json",
+			Destination: &DebugTunnelsConfig.Output,
+		},
+	}
+
+	DebugMemStatsConfig = DebugMemStats{}
+	DebugMemStatsFlags  = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		&cli.StringFlag{
+			Name:        "server, s",
+			Usage:       "(debug) Server to query memory use from",
+			EnvVar:      version.ProgramUpper + "_URL",
+			Value:       "https://127.0.0.1:6443",
+			Destination: &DebugMemStatsConfig.ServerURL,
+		},
+		&cli.StringFlag{
+			Name:        "token, t",
+			Usage:       "(debug) Shared secret used to authenticate to the server; read from the data-dir if not set",
+			EnvVar:      version.ProgramUpper + "_TOKEN",
+			Destination: &DebugMemStatsConfig.Token,
+		},
+		&cli.StringFlag{
+			Name:        "output, o",
+			Usage:       "(debug) Output format. Default: text. Optional: json",
+			Destination: &DebugMemStatsConfig.Output,
+		},
+		&cli.StringFlag{
+			Name:        "budget",
+			Usage:       "(debug) Exit with an error if the supervisor's resident set size exceeds this quantity (example: 256Mi)",
+			Destination: &DebugMemStatsConfig.Budget,
+		},
+	}
+)
+
+func NewDebugCommands(profile, tunnels, egress, memstats func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            DebugCommand,
+		Usage:           "Collect debugging information",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Subcommands: []cli.Command{
+			{
+				Name:            "profile",
+				Usage:           "Collect CPU, heap, and goroutine profiles from the supervisor and control-plane component processes into a bundle. Requires --enable-pprof",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          profile,
+				Flags:           DebugProfileFlags,
+			},
+			{
+				Name:            "tunnels",
+				Usage:           "List agent websocket tunnel sessions currently connected to the server",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          tunnels,
+				Flags:           DebugTunnelsFlags,
+			},
+			{
+				Name:            "egress",
+				Usage:           "Show the apiserver egress selector mode for each egress type, and egress proxy connection counts",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          egress,
+				Flags:           DebugTunnelsFlags,
+			},
+			{
+				Name:            "memstats",
+				Usage:           "Show supervisor memory use and disabled embedded components, optionally checking resident memory against a budget",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          memstats,
+				Flags:           DebugMemStatsFlags,
+			},
+		},
+	}
+}
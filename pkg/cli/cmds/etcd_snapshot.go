@@ -132,7 +132,7 @@ var EtcdSnapshotFlags = []cli.Flag{
 	},
 }
 
-func NewEtcdSnapshotCommands(delete, list, prune, save func(ctx *cli.Context) error) cli.Command {
+func NewEtcdSnapshotCommands(delete, list, prune, save, verify func(ctx *cli.Context) error) cli.Command {
 	return cli.Command{
 		Name:            EtcdSnapshotCommand,
 		SkipFlagParsing: false,
@@ -175,6 +175,14 @@ func NewEtcdSnapshotCommands(delete, list, prune, save func(ctx *cli.Context) er
 				Action:          prune,
 				Flags:           EtcdSnapshotFlags,
 			},
+			{
+				Name:            "verify",
+				Usage:           "Verify the checksum and database structure of given snapshot(s)",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          verify,
+				Flags:           EtcdSnapshotFlags,
+			},
 		},
 		Flags: EtcdSnapshotFlags,
 	}
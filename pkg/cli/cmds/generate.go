@@ -0,0 +1,96 @@
+package cmds
+
+import (
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/urfave/cli"
+)
+
+const GenerateCommand = "generate"
+
+// Generate holds CLI values for the generate subcommands
+type Generate struct {
+	Role            string
+	ServerURL       string
+	Token           string
+	NodeConfig      string
+	PrivateRegistry string
+	Airgap          bool
+	InstallScript   string
+	InstallVersion  string
+	Output          string
+}
+
+var (
+	GenerateConfig             = Generate{}
+	GenerateBootstrapDataFlags = []cli.Flag{
+		DebugFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		&cli.StringFlag{
+			Name:        "role",
+			Usage:       "(bootstrap) Role of the new node. Options: server, agent",
+			Value:       "agent",
+			Destination: &GenerateConfig.Role,
+		},
+		&cli.StringFlag{
+			Name:        "server, s",
+			Usage:       "(bootstrap) Server for the new node to join",
+			Destination: &GenerateConfig.ServerURL,
+		},
+		&cli.StringFlag{
+			Name:        "token, t",
+			Usage:       "(bootstrap) Token for the new node to join with (default: the server or agent token from the data-dir)",
+			Destination: &GenerateConfig.Token,
+		},
+		&cli.StringFlag{
+			Name:        "node-config",
+			Usage:       "(bootstrap) Config file with additional settings for the new node, merged into the generated config.yaml",
+			Destination: &GenerateConfig.NodeConfig,
+		},
+		&cli.StringFlag{
+			Name:        "private-registry",
+			Usage:       "(bootstrap) Private registry configuration file to install on the new node",
+			Destination: &GenerateConfig.PrivateRegistry,
+		},
+		&cli.BoolFlag{
+			Name:        "airgap",
+			Usage:       "(bootstrap) Install from a binary, install script, and images already present on the new node, instead of downloading them",
+			Destination: &GenerateConfig.Airgap,
+		},
+		&cli.StringFlag{
+			Name:        "install-script",
+			Usage:       "(bootstrap) URL of the install script, or local path to the install script on the new node when --airgap is set (default: https://get." + version.Program + ".io, or /usr/local/bin/" + version.Program + "-install.sh with --airgap)",
+			Destination: &GenerateConfig.InstallScript,
+		},
+		&cli.StringFlag{
+			Name:        "install-version",
+			Usage:       "(bootstrap) Version to install on the new node (default: the version of this binary)",
+			Destination: &GenerateConfig.InstallVersion,
+		},
+		&cli.StringFlag{
+			Name:        "output, o",
+			Usage:       "(bootstrap) File to write the bootstrap data to (default: stdout)",
+			Destination: &GenerateConfig.Output,
+		},
+	}
+)
+
+func NewGenerateCommands(bootstrapData func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            GenerateCommand,
+		Usage:           "Generate configuration for new nodes",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Subcommands: []cli.Command{
+			{
+				Name:            "bootstrap-data",
+				Usage:           "Generate cloud-init user-data that installs and joins a new server or agent to this cluster",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          bootstrapData,
+				Flags:           GenerateBootstrapDataFlags,
+			},
+		},
+	}
+}
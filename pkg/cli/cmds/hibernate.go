@@ -0,0 +1,85 @@
+package cmds
+
+import (
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/urfave/cli"
+)
+
+const (
+	HibernateCommand = "hibernate"
+	ResumeCommand    = "resume"
+)
+
+// Hibernate holds CLI values for the hibernate and resume commands
+type Hibernate struct {
+	ServerURL    string
+	Token        string
+	SkipSnapshot bool
+	SkipStop     bool
+	StopTimeout  time.Duration
+}
+
+var (
+	HibernateConfig = Hibernate{}
+	ResumeFlags     = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		&cli.StringFlag{
+			Name:        "server, s",
+			Usage:       "(cluster) Server to connect to",
+			Value:       "https://127.0.0.1:6443",
+			Destination: &HibernateConfig.ServerURL,
+		},
+		&cli.StringFlag{
+			Name:        "token, t",
+			Usage:       "(cluster) Shared secret used to authenticate to the server; read from the data-dir if not set",
+			EnvVar:      version.ProgramUpper + "_TOKEN",
+			Destination: &HibernateConfig.Token,
+		},
+	}
+	HibernateFlags = append(ResumeFlags,
+		&cli.BoolFlag{
+			Name:        "skip-snapshot",
+			Usage:       "(db) Do not take an etcd snapshot before stopping a server with the etcd role",
+			Destination: &HibernateConfig.SkipSnapshot,
+		},
+		&cli.BoolFlag{
+			Name:        "skip-stop",
+			Usage:       "(hibernate) Do not stop the " + version.Program + " service and its containers after scaling down packaged components",
+			Destination: &HibernateConfig.SkipStop,
+		},
+		&cli.DurationFlag{
+			Name:        "stop-timeout",
+			Usage:       "(hibernate) Time to wait for containers to exit after being sent SIGTERM, before they are killed",
+			Value:       30 * time.Second,
+			Destination: &HibernateConfig.StopTimeout,
+		},
+	)
+)
+
+func NewHibernateCommand(action func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            HibernateCommand,
+		Usage:           "Prepare the server for the host to be suspended, by scaling down packaged components, checkpointing the datastore, and cleanly stopping the " + version.Program + " service and its containers",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Action:          action,
+		Flags:           HibernateFlags,
+	}
+}
+
+func NewResumeCommand(action func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            ResumeCommand,
+		Usage:           "Start the " + version.Program + " service stopped by hibernate, and restore packaged components to their previous scale",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Action:          action,
+		Flags:           ResumeFlags,
+	}
+}
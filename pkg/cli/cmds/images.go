@@ -0,0 +1,47 @@
+package cmds
+
+import (
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/urfave/cli"
+)
+
+const ImagesCommand = "images"
+
+// Images holds CLI values for the images command
+type Images struct {
+	Output string
+}
+
+var (
+	ImagesConfig = Images{}
+	ImagesFlags  = []cli.Flag{
+		DebugFlag,
+		LogFile,
+		AlsoLogToStderr,
+		&cli.StringFlag{
+			Name:        "system-default-registry",
+			Usage:       "(agent/runtime) Private registry to be used for all system images",
+			EnvVar:      version.ProgramUpper + "_SYSTEM_DEFAULT_REGISTRY",
+			Destination: &ServerConfig.SystemDefaultRegistry,
+		},
+		SystemImages,
+		SystemImagesFile,
+		&cli.StringFlag{
+			Name:        "output, o",
+			Usage:       "(images) Output format. Options: text, json",
+			Value:       "text",
+			Destination: &ImagesConfig.Output,
+		},
+	}
+)
+
+func NewImagesCommand(action func(*cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            ImagesCommand,
+		Usage:           "List the images used by packaged components, with their digests for each platform, after applying the system default registry and image overrides",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Action:          action,
+		Flags:           ImagesFlags,
+	}
+}
@@ -1,10 +1,12 @@
 package cmds
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 )
@@ -14,6 +16,8 @@ type Log struct {
 	VModule         string
 	LogFile         string
 	AlsoLogToStderr bool
+	StartupSummary  bool
+	Quiet           bool
 }
 
 var (
@@ -39,6 +43,16 @@ var (
 		Usage:       "(logging) Log to standard error as well as file (if set)",
 		Destination: &LogConfig.AlsoLogToStderr,
 	}
+	StartupSummary = &cli.BoolFlag{
+		Name:        "startup-summary",
+		Usage:       "(logging) Print a summary of startup phases with timing, instead of " + version.Program + " informational log messages, until startup completes. Embedded component logs are not affected",
+		Destination: &LogConfig.StartupSummary,
+	}
+	Quiet = &cli.BoolFlag{
+		Name:        "quiet",
+		Usage:       "(logging) Only log " + version.Program + " warnings and errors. Embedded component logs are not affected",
+		Destination: &LogConfig.Quiet,
+	}
 
 	logSetupOnce sync.Once
 )
@@ -46,6 +60,11 @@ var (
 func InitLogging() error {
 	var rErr error
 	logSetupOnce.Do(func() {
+		if Debug && LogConfig.Quiet {
+			rErr = errors.New("--debug and --quiet cannot be used together")
+			return
+		}
+
 		if err := forkIfLoggingOrReaping(); err != nil {
 			rErr = err
 			return
@@ -74,4 +93,7 @@ func setupLogging() {
 	if Debug {
 		logrus.SetLevel(logrus.DebugLevel)
 	}
+	if LogConfig.Quiet {
+		logrus.SetLevel(logrus.WarnLevel)
+	}
 }
@@ -0,0 +1,78 @@
+package cmds
+
+import (
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/urfave/cli"
+)
+
+const MaintenanceCommand = "maintenance"
+
+// Maintenance holds CLI values for the maintenance subcommands
+type Maintenance struct {
+	ServerURL    string
+	Token        string
+	SkipSnapshot bool
+}
+
+var (
+	MaintenanceConfig = Maintenance{}
+	MaintenanceFlags  = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		&cli.StringFlag{
+			Name:        "node-name",
+			Usage:       "(agent/node) Name of the node to put into or take out of maintenance mode (default: local node)",
+			EnvVar:      version.ProgramUpper + "_NODE_NAME",
+			Destination: &AgentConfig.NodeName,
+		},
+		&cli.StringFlag{
+			Name:        "server, s",
+			Usage:       "(cluster) Server to connect to",
+			Value:       "https://127.0.0.1:6443",
+			Destination: &MaintenanceConfig.ServerURL,
+		},
+		&cli.StringFlag{
+			Name:        "token, t",
+			Usage:       "(cluster) Shared secret used to authenticate to the server; read from the data-dir if not set",
+			EnvVar:      version.ProgramUpper + "_TOKEN",
+			Destination: &MaintenanceConfig.Token,
+		},
+	}
+	MaintenanceEnableFlags = append(MaintenanceFlags,
+		&cli.BoolFlag{
+			Name:        "skip-snapshot",
+			Usage:       "(db) Do not take an etcd snapshot before entering maintenance mode on a node with the etcd role",
+			Destination: &MaintenanceConfig.SkipSnapshot,
+		},
+	)
+)
+
+func NewMaintenanceCommands(enable, disable func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            MaintenanceCommand,
+		Usage:           "Manage node maintenance mode",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Subcommands: []cli.Command{
+			{
+				Name:            "enable",
+				Usage:           "Cordon the node, take an etcd snapshot if the node has the etcd role, and pause applying manifests on the node until maintenance mode is disabled",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          enable,
+				Flags:           MaintenanceEnableFlags,
+			},
+			{
+				Name:            "disable",
+				Usage:           "Take the node out of maintenance mode, uncordoning it if it was cordoned when maintenance mode was enabled",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          disable,
+				Flags:           MaintenanceFlags,
+			},
+		},
+	}
+}
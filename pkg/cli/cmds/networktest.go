@@ -0,0 +1,68 @@
+package cmds
+
+import (
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/networktest"
+	"github.com/urfave/cli"
+)
+
+const NetworkTestCommand = "network-test"
+
+// NetworkTest holds CLI values for the network-test command
+type NetworkTest struct {
+	Kubeconfig string
+	Image      string
+	HostPort   int
+	Timeout    time.Duration
+	Keep       bool
+}
+
+var (
+	NetworkTestConfig = NetworkTest{}
+	NetworkTestFlags  = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		&cli.StringFlag{
+			Name:        "kubeconfig",
+			Usage:       "(cluster) Server to connect to",
+			EnvVar:      "KUBECONFIG",
+			Destination: &NetworkTestConfig.Kubeconfig,
+		},
+		&cli.StringFlag{
+			Name:        "image",
+			Usage:       "(network-test) Image to run the test pods from; must contain busybox sh, httpd, wget and nslookup (default: the packaged busybox image)",
+			Destination: &NetworkTestConfig.Image,
+		},
+		&cli.IntFlag{
+			Name:        "host-port",
+			Usage:       "(network-test) Port to listen on in the host network of each node, for node-to-node checks",
+			Value:       networktest.DefaultHostPort,
+			Destination: &NetworkTestConfig.HostPort,
+		},
+		&cli.DurationFlag{
+			Name:        "timeout",
+			Usage:       "(network-test) Time to wait for the test pods to be ready and for the checks to complete",
+			Value:       3 * time.Minute,
+			Destination: &NetworkTestConfig.Timeout,
+		},
+		&cli.BoolFlag{
+			Name:        "keep",
+			Usage:       "(network-test) Do not delete the test namespace and pods when the test completes",
+			Destination: &NetworkTestConfig.Keep,
+		},
+	}
+)
+
+func NewNetworkTestCommand(action func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            NetworkTestCommand,
+		Usage:           "Test pod-to-pod, pod-to-service, node-to-node and DNS connectivity between all nodes, using temporary test pods, and report likely causes of failures",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Action:          action,
+		Flags:           NetworkTestFlags,
+	}
+}
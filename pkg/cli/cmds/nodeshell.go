@@ -0,0 +1,48 @@
+package cmds
+
+import (
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/urfave/cli"
+)
+
+const NodeShellCommand = "node-shell"
+
+// NodeShell holds CLI values for the node-shell command
+type NodeShell struct {
+	Image string
+	Keep  bool
+}
+
+var (
+	NodeShellConfig = NodeShell{}
+	NodeShellFlags  = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		InstanceNameFlag,
+		CRIEndpointFlag,
+		&cli.StringFlag{
+			Name:        "image",
+			Usage:       "(node-shell) Image to run the debug container from; must contain the command to run (default: the packaged busybox image)",
+			Destination: &NodeShellConfig.Image,
+		},
+		&cli.BoolFlag{
+			Name:        "keep",
+			Usage:       "(node-shell) Do not remove the debug pod when the command exits",
+			Destination: &NodeShellConfig.Keep,
+		},
+	}
+)
+
+func NewNodeShellCommand(action func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            NodeShellCommand,
+		Usage:           "Run a command in a privileged debug container that shares the host network, PID and IPC namespaces, with the host filesystem mounted at /host. The container is created directly through the container runtime, so " + version.Program + " and the apiserver do not need to be running. Refuses to run while the kubelet is running, as it would remove the container; use 'kubectl debug node/' instead",
+		ArgsUsage:       "[COMMAND [ARG...]]",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Action:          action,
+		Flags:           NodeShellFlags,
+	}
+}
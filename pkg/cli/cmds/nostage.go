@@ -7,4 +7,6 @@ const (
 	// The coredns and servicelb controllers can still be disabled, even if their manifests
 	// are missing. Same with CloudController/ccm.
 	DisableItems = "coredns, servicelb"
+	// All optional components are packaged manifests, so there is nothing to enable.
+	EnableItems = ""
 )
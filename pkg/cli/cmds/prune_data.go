@@ -0,0 +1,20 @@
+package cmds
+
+import (
+	"github.com/urfave/cli"
+)
+
+const PruneDataCommand = "prune-data"
+
+func NewPruneDataCommand(action func(*cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            PruneDataCommand,
+		Usage:           "Remove extracted data directories left behind by previous upgrades, retaining the current and previous versions",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Action:          action,
+		Flags: []cli.Flag{
+			DataDirFlag,
+		},
+	}
+}
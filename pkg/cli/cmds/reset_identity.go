@@ -0,0 +1,52 @@
+package cmds
+
+import (
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/urfave/cli"
+)
+
+const ResetIdentityCommand = "reset-identity"
+
+// ResetIdentity holds CLI values for the reset-identity command
+type ResetIdentity struct {
+	AcceptHost  bool
+	Rootless    bool
+	EtcdDataDir string
+}
+
+var (
+	ResetIdentityConfig = ResetIdentity{}
+	ResetIdentityFlags  = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		&cli.BoolFlag{
+			Name:        "accept-host",
+			Usage:       "(identity) Record this host as the owner of the data-dir without resetting anything, if the host's machine-id or hardware changed for a reason other than cloning",
+			Destination: &ResetIdentityConfig.AcceptHost,
+		},
+		&cli.StringFlag{
+			Name:        "etcd-data-dir",
+			Usage:       "(identity) Directory that the server holds etcd data in, if it was set with --etcd-data-dir (default: ${data-dir}/server/db/etcd)",
+			Destination: &ResetIdentityConfig.EtcdDataDir,
+		},
+		&cli.BoolFlag{
+			Name:        "rootless",
+			Usage:       "(experimental) Reset the node password of a rootless agent",
+			Destination: &ResetIdentityConfig.Rootless,
+		},
+	}
+)
+
+func NewResetIdentityCommand(action func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            ResetIdentityCommand,
+		Usage:           "Move the certificates, keys, tokens, node password, and local datastore out of the data-dir, so that new ones are generated when " + version.Program + " is next started. Use on the first boot of a host cloned from a disk image that " + version.Program + " was started in. " + version.Program + " must be stopped",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Action:          action,
+		Flags:           ResetIdentityFlags,
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"sort"
+	"time"
 
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/urfave/cli"
@@ -21,8 +23,24 @@ var (
 		Name:  "prefer-bundled-bin",
 		Usage: "(experimental) Prefer bundled userspace binaries over host binaries",
 	}
+
+	// StartedAtEnv and DataExtractedAtEnv pass the times at which the multicall binary was started and
+	// finished extracting the data-dir, to the server or agent that it executes.
+	StartedAtEnv       = version.ProgramUpper + "_STARTUP_STARTED_AT"
+	DataExtractedAtEnv = version.ProgramUpper + "_STARTUP_DATA_EXTRACTED_AT"
 )
 
+// StartupTimesFromEnv returns the times passed in StartedAtEnv and DataExtractedAtEnv by the multicall
+// binary, or zero times if they are not set, and removes them from the environment so that they are not
+// inherited by child processes.
+func StartupTimesFromEnv() (startedAt, extractedAt time.Time) {
+	startedAt, _ = time.Parse(time.RFC3339Nano, os.Getenv(StartedAtEnv))
+	extractedAt, _ = time.Parse(time.RFC3339Nano, os.Getenv(DataExtractedAtEnv))
+	os.Unsetenv(StartedAtEnv)
+	os.Unsetenv(DataExtractedAtEnv)
+	return startedAt, extractedAt
+}
+
 func init() {
 	// hack - force "file,dns" lookup order if go dns is used
 	if os.Getenv("RES_OPTIONS") == "" {
@@ -38,10 +56,25 @@ func NewApp() *cli.App {
 	cli.VersionPrinter = func(c *cli.Context) {
 		fmt.Printf("%s version %s\n", app.Name, app.Version)
 		fmt.Printf("go version %s\n", runtime.Version())
+		if c.Bool("verbose") {
+			components := version.Components()
+			names := make([]string, 0, len(components))
+			for name := range components {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Printf("%s version %s\n", name, components[name])
+			}
+		}
 	}
 	app.Flags = []cli.Flag{
 		DebugFlag,
 		DataDirFlag,
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "Include the versions of embedded components in --version output",
+		},
 	}
 
 	return app
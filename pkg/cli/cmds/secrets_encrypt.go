@@ -13,6 +13,22 @@ var (
 		Usage:       "Force this stage.",
 		Destination: &ServerConfig.EncryptForce,
 	}
+	dryRunFlag = &cli.BoolFlag{
+		Name:        "dry-run",
+		Usage:       "Report the secrets that would be reencrypted and the stage of each server, without making any changes",
+		Destination: &ServerConfig.EncryptDryRun,
+	}
+	outputFlag = &cli.StringFlag{
+		Name:        "output,o",
+		Usage:       "Output format. Default: text. Optional: json",
+		Destination: &ServerConfig.EncryptOutput,
+	}
+	passphraseFileFlag = &cli.StringFlag{
+		Name:        "passphrase-file",
+		Usage:       "File containing a passphrase to protect the exported encryption config with",
+		EnvVar:      version.ProgramUpper + "_ENCRYPT_PASSPHRASE_FILE",
+		Destination: &ServerConfig.EncryptPassphraseFile,
+	}
 	EncryptFlags = []cli.Flag{
 		DataDirFlag,
 		ServerToken,
@@ -26,7 +42,7 @@ var (
 	}
 )
 
-func NewSecretsEncryptCommands(status, enable, disable, prepare, rotate, reencrypt, rotateKeys func(ctx *cli.Context) error) cli.Command {
+func NewSecretsEncryptCommands(status, enable, disable, prepare, rotate, reencrypt, rotateKeys, export, importConfig func(ctx *cli.Context) error) cli.Command {
 	return cli.Command{
 		Name:           SecretsEncryptCommand,
 		Usage:          "Control secrets encryption and keys rotation",
@@ -37,11 +53,7 @@ func NewSecretsEncryptCommands(status, enable, disable, prepare, rotate, reencry
 				Usage:          "Print current status of secrets encryption",
 				SkipArgReorder: true,
 				Action:         status,
-				Flags: append(EncryptFlags, &cli.StringFlag{
-					Name:        "output,o",
-					Usage:       "Status format. Default: text. Optional: json",
-					Destination: &ServerConfig.EncryptOutput,
-				}),
+				Flags:          append(EncryptFlags, outputFlag),
 			},
 			{
 				Name:           "enable",
@@ -62,14 +74,14 @@ func NewSecretsEncryptCommands(status, enable, disable, prepare, rotate, reencry
 				Usage:          "Prepare for encryption keys rotation",
 				SkipArgReorder: true,
 				Action:         prepare,
-				Flags:          append(EncryptFlags, forceFlag),
+				Flags:          append(EncryptFlags, forceFlag, dryRunFlag, outputFlag),
 			},
 			{
 				Name:           "rotate",
 				Usage:          "Rotate secrets encryption keys",
 				SkipArgReorder: true,
 				Action:         rotate,
-				Flags:          append(EncryptFlags, forceFlag),
+				Flags:          append(EncryptFlags, forceFlag, dryRunFlag, outputFlag),
 			},
 			{
 				Name:           "reencrypt",
@@ -78,6 +90,8 @@ func NewSecretsEncryptCommands(status, enable, disable, prepare, rotate, reencry
 				Action:         reencrypt,
 				Flags: append(EncryptFlags,
 					forceFlag,
+					dryRunFlag,
+					outputFlag,
 					&cli.BoolFlag{
 						Name:        "skip",
 						Usage:       "Skip removing old key",
@@ -91,6 +105,37 @@ func NewSecretsEncryptCommands(status, enable, disable, prepare, rotate, reencry
 				Action:         rotateKeys,
 				Flags:          EncryptFlags,
 			},
+			{
+				Name:           "export",
+				Usage:          "Export the secrets encryption config for disaster recovery",
+				SkipArgReorder: true,
+				Action:         export,
+				Flags: []cli.Flag{
+					DataDirFlag,
+					passphraseFileFlag,
+					&cli.StringFlag{
+						Name:        "file",
+						Usage:       "File to write the export to (default: stdout)",
+						Destination: &ServerConfig.EncryptFile,
+					},
+				},
+			},
+			{
+				Name:           "import",
+				Usage:          "Import an exported secrets encryption config, for use when restoring a server",
+				SkipArgReorder: true,
+				Action:         importConfig,
+				Flags: []cli.Flag{
+					DataDirFlag,
+					passphraseFileFlag,
+					forceFlag,
+					&cli.StringFlag{
+						Name:        "file",
+						Usage:       "File to read the export from",
+						Destination: &ServerConfig.EncryptFile,
+					},
+				},
+			},
 		},
 	}
 }
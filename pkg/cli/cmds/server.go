@@ -2,9 +2,11 @@ package cmds
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/k3s-io/k3s/pkg/notify"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/urfave/cli"
 )
@@ -24,20 +26,28 @@ type StartupHookArgs struct {
 type StartupHook func(context.Context, *sync.WaitGroup, StartupHookArgs) error
 
 type Server struct {
-	ClusterCIDR          cli.StringSlice
-	AgentToken           string
-	AgentTokenFile       string
-	Token                string
-	TokenFile            string
-	ClusterSecret        string
-	ServiceCIDR          cli.StringSlice
-	ServiceNodePortRange string
-	ClusterDNS           cli.StringSlice
-	ClusterDomain        string
+	ClusterCIDR             cli.StringSlice
+	AgentToken              string
+	AgentTokenFile          string
+	Token                   string
+	TokenFile               string
+	ClusterSecret           string
+	ServiceCIDR             cli.StringSlice
+	ServiceNodePortRange    string
+	AllowServiceRangeChange bool
+	ClusterDNS              cli.StringSlice
+	ClusterDNSServiceIP     cli.StringSlice
+	NodeCIDRMaskSizeIPv4    int
+	NodeCIDRMaskSizeIPv6    int
+	ClusterDomain           string
 	// The port which kubectl clients can access k8s
 	HTTPSPort int
 	// The port which custom k3s API runs on
-	SupervisorPort int
+	SupervisorPort       int
+	SupervisorLegacyPort int
+	// Supervisor authentication lockout
+	SupervisorAuthMaxFailures int
+	SupervisorAuthLockout     time.Duration
 	// The port which kube-apiserver runs on
 	APIServerPort            int
 	APIServerBindAddress     string
@@ -49,6 +59,9 @@ type Server struct {
 	HelmJobImage             string
 	TLSSan                   cli.StringSlice
 	TLSSanSecurity           bool
+	CACertDuration           time.Duration
+	ServerCertDuration       time.Duration
+	ClientCertDuration       time.Duration
 	ExtraAPIArgs             cli.StringSlice
 	ExtraEtcdArgs            cli.StringSlice
 	ExtraSchedulerArgs       cli.StringSlice
@@ -68,30 +81,58 @@ type Server struct {
 	FlannelIPv6Masq          bool
 	FlannelExternalIP        bool
 	EgressSelectorMode       string
+	EgressSelectorTypeModes  cli.StringSlice
+	TunnelBandwidthLimits    cli.StringSlice
 	DefaultLocalStoragePath  string
+	SkipDeploy               cli.StringSlice
+	PinDeploy                cli.StringSlice
 	DisableCCM               bool
 	DisableNPC               bool
 	DisableHelmController    bool
+	TokenViewer              bool
 	DisableKubeProxy         bool
 	DisableAPIServer         bool
 	DisableControllerManager bool
 	DisableETCD              bool
+	EtcdArbiter              bool
 	EmbeddedRegistry         bool
+	EmbeddedRegistryPin      bool
 	ClusterInit              bool
 	ClusterReset             bool
 	ClusterResetRestorePath  string
+	ClusterImport            string
 	EncryptSecrets           bool
 	EncryptForce             bool
 	EncryptOutput            string
 	EncryptSkip              bool
+	EncryptDryRun            bool
+	EncryptFile              string
+	EncryptPassphraseFile    string
+	DataDirEncryption        string
+	DataDirEncryptionKeyFile string
+	DataDirEncryptionTPM     bool
+	KeyStorage               string
 	SystemDefaultRegistry    string
+	SystemImages             cli.StringSlice
+	SystemImagesFile         string
 	StartupHooks             []StartupHook
+	ServerReady              chan<- struct{}
 	SupervisorMetrics        bool
+	DRA                      bool
+	ControlPlaneExecMode     string
+	ResourceProfile          string
+	AgentProfile             string
+	NotifyWebhookURL         string
+	NotifyWebhookEvents      cli.StringSlice
 	EtcdSnapshotName         string
 	EtcdDisableSnapshots     bool
 	EtcdExposeMetrics        bool
+	EtcdDataDir              string
+	EtcdIOWeight             int
+	EtcdFsyncWarning         time.Duration
 	EtcdSnapshotDir          string
 	EtcdSnapshotCron         string
+	EtcdIncrementalCron      string
 	EtcdSnapshotRetention    int
 	EtcdSnapshotCompress     bool
 	EtcdListFormat           string
@@ -128,7 +169,7 @@ var (
 	}
 	ClusterCIDR = &cli.StringSliceFlag{
 		Name:  "cluster-cidr",
-		Usage: "(networking) IPv4/IPv6 network CIDRs to use for pod IPs (default: 10.42.0.0/16)",
+		Usage: "(networking) IPv4/IPv6 network CIDRs to use for pod IPs; at most one CIDR of each IP family (default: 10.42.0.0/16)",
 		Value: &ServerConfig.ClusterCIDR,
 	}
 	ServiceCIDR = &cli.StringSliceFlag{
@@ -144,9 +185,14 @@ var (
 	}
 	ClusterDNS = &cli.StringSliceFlag{
 		Name:  "cluster-dns",
-		Usage: "(networking) IPv4/IPv6 Cluster IP for coredns service. Should be in your service-cidr range (default: 10.43.0.10)",
+		Usage: "(networking) IPv4/IPv6 addresses of the DNS servers used by pods. Addresses within service-cidr that are not used by the coredns service are reserved for node-local DNS caches. Addresses outside service-cidr, such as the link-local address of a node-local DNS cache, are only passed to the kubelet (default: 10.43.0.10)",
 		Value: &ServerConfig.ClusterDNS,
 	}
+	ClusterDNSServiceIP = &cli.StringSliceFlag{
+		Name:  "cluster-dns-service-ip",
+		Usage: "(networking) IPv4/IPv6 Cluster IPs of the coredns service, at most one per IP family. Must be in your service-cidr range (default: the first address of each IP family in cluster-dns that is in service-cidr, or the tenth address of each service-cidr if there are none)",
+		Value: &ServerConfig.ClusterDNSServiceIP,
+	}
 	ClusterDomain = &cli.StringFlag{
 		Name:        "cluster-domain",
 		Usage:       "(networking) Cluster Domain",
@@ -173,6 +219,16 @@ var (
 		Usage: "(flags) Customized flag for kube-controller-manager process",
 		Value: &ServerConfig.ExtraControllerArgs,
 	}
+	SystemImages = &cli.StringSliceFlag{
+		Name:  "system-images",
+		Usage: "(agent/runtime) Image to use for a packaged component instead of the default, in component=image format. Images may be a tag prefixed with ':' or a version, which replaces the tag of the default image, or a reference by tag or digest; run '" + version.Program + " images' to list components and their images",
+		Value: &ServerConfig.SystemImages,
+	}
+	SystemImagesFile = &cli.StringFlag{
+		Name:        "system-images-file",
+		Usage:       "(agent/runtime) Path to a YAML file mapping packaged components to images to use instead of the default, in the same format as --system-images. Tags must be versions supported by this release",
+		Destination: &ServerConfig.SystemImagesFile,
+	}
 )
 
 var ServerFlags = []cli.Flag{
@@ -182,6 +238,8 @@ var ServerFlags = []cli.Flag{
 	VModule,
 	LogFile,
 	AlsoLogToStderr,
+	StartupSummary,
+	Quiet,
 	BindAddressFlag,
 	&cli.IntFlag{
 		Name:        "https-listen-port",
@@ -196,6 +254,25 @@ var ServerFlags = []cli.Flag{
 		Hidden:      true,
 		Destination: &ServerConfig.SupervisorPort,
 	},
+	&cli.IntFlag{
+		Name:        "supervisor-legacy-port",
+		EnvVar:      version.ProgramUpper + "_SUPERVISOR_LEGACY_PORT",
+		Usage:       "(experimental) Additional supervisor listen port, used while migrating agents to the supervisor port. Remove once no agents are using it",
+		Hidden:      true,
+		Destination: &ServerConfig.SupervisorLegacyPort,
+	},
+	&cli.IntFlag{
+		Name:        "supervisor-auth-max-failures",
+		Usage:       "(listener) Number of failed supervisor authentication attempts from a client address, within the supervisor-auth-lockout period, after which all requests from that address presenting a token or password are rejected with 429 Too Many Requests until the period has passed. Such requests are also rate limited per address. Only applies to supervisor routes, not the apiserver, and not to loopback addresses or client certificates; 0 to disable",
+		Value:       10,
+		Destination: &ServerConfig.SupervisorAuthMaxFailures,
+	},
+	&cli.DurationFlag{
+		Name:        "supervisor-auth-lockout",
+		Usage:       "(listener) Period over which failed supervisor authentication attempts are counted, and for which a client address is locked out once supervisor-auth-max-failures is reached. Only applies to supervisor routes",
+		Value:       5 * time.Minute,
+		Destination: &ServerConfig.SupervisorAuthLockout,
+	},
 	&cli.IntFlag{
 		Name:        "apiserver-port",
 		EnvVar:      version.ProgramUpper + "_APISERVER_PORT",
@@ -230,11 +307,42 @@ var ServerFlags = []cli.Flag{
 		Usage:       "(listener) Protect the server TLS cert by refusing to add Subject Alternative Names not associated with the kubernetes apiserver service, server nodes, or values of the tls-san option (default: true)",
 		Destination: &ServerConfig.TLSSanSecurity,
 	},
+	&cli.DurationFlag{
+		Name:        "cluster-signing-ca-duration",
+		Usage:       "(listener) Validity period of generated CA certificates (default: 87600h)",
+		Destination: &ServerConfig.CACertDuration,
+	},
+	&cli.DurationFlag{
+		Name:        "cluster-signing-server-duration",
+		Usage:       "(listener) Validity period of server certificates signed by the cluster CAs, including kubelet serving certificates (default: 8760h)",
+		Destination: &ServerConfig.ServerCertDuration,
+	},
+	&cli.DurationFlag{
+		Name:        "cluster-signing-client-duration",
+		Usage:       "(listener) Validity period of client certificates signed by the cluster CAs, including kubelet and control-plane client certificates (default: 8760h)",
+		Destination: &ServerConfig.ClientCertDuration,
+	},
 	DataDirFlag,
 	ClusterCIDR,
+	&cli.IntFlag{
+		Name:        "node-cidr-mask-size-ipv4",
+		Usage:       "(networking) Size of the IPv4 pod CIDR allocated to each node from the IPv4 cluster-cidr (default: 24)",
+		Destination: &ServerConfig.NodeCIDRMaskSizeIPv4,
+	},
+	&cli.IntFlag{
+		Name:        "node-cidr-mask-size-ipv6",
+		Usage:       "(networking) Size of the IPv6 pod CIDR allocated to each node from the IPv6 cluster-cidr (default: 64)",
+		Destination: &ServerConfig.NodeCIDRMaskSizeIPv6,
+	},
 	ServiceCIDR,
 	ServiceNodePortRange,
+	&cli.BoolFlag{
+		Name:        "allow-service-range-change",
+		Usage:       "(networking) Allow the server to start after service-cidr or service-node-port-range was changed such that existing services may be outside of the new ranges",
+		Destination: &ServerConfig.AllowServiceRangeChange,
+	},
 	ClusterDNS,
+	ClusterDNSServiceIP,
 	ClusterDomain,
 	&cli.StringFlag{
 		Name:        "flannel-backend",
@@ -258,6 +366,16 @@ var ServerFlags = []cli.Flag{
 		Destination: &ServerConfig.EgressSelectorMode,
 		Value:       "agent",
 	},
+	&cli.StringSliceFlag{
+		Name:  "egress-selector-type-mode",
+		Usage: "(networking) Egress selector mode for an apiserver egress type, in the format 'type=mode'. Type is one of 'cluster', 'controlplane', 'etcd', or 'kubelet' or 'webhook' to route apiserver connections to kubelets, or to webhooks and aggregated apiservers, separately from the rest of cluster egress; mode is either 'disabled' or the egress-selector-mode",
+		Value: &ServerConfig.EgressSelectorTypeModes,
+	},
+	&cli.StringSliceFlag{
+		Name:  "tunnel-bandwidth-limit",
+		Usage: "(networking) Bandwidth limit in bytes per second for connections proxied through agent tunnels, in the format 'scope=quantity'. Scope is 'node' or 'namespace' to set the default limit, or 'node/NAME' or 'namespace/NAME' to set the limit for a single node or namespace. Namespace limits apply to connections to pod IPs, such as webhooks and services proxied by the apiserver; kubelet connections for logs, exec and port-forward are end-to-end encrypted, and are limited by the node limit",
+		Value: &ServerConfig.TunnelBandwidthLimits,
+	},
 	&cli.StringFlag{
 		Name:        "servicelb-namespace",
 		Usage:       "(networking) Namespace of the pods for the servicelb component",
@@ -329,6 +447,11 @@ var ServerFlags = []cli.Flag{
 		Usage:       "(db) Path to snapshot file to be restored",
 		Destination: &ServerConfig.ClusterResetRestorePath,
 	},
+	&cli.StringFlag{
+		Name:        "cluster-import",
+		Usage:       "(cluster) Path to a cluster archive created by '" + version.Program + " cluster export', used to initialize a new cluster with the same certificate authorities, tokens, and manifest overrides",
+		Destination: &ServerConfig.ClusterImport,
+	},
 	ExtraAPIArgs,
 	ExtraEtcdArgs,
 	ExtraControllerArgs,
@@ -373,6 +496,27 @@ var ServerFlags = []cli.Flag{
 		Usage:       "(db) Expose etcd metrics to client interface. (default: false)",
 		Destination: &ServerConfig.EtcdExposeMetrics,
 	},
+	&cli.StringFlag{
+		Name:        "etcd-data-dir",
+		Usage:       "(db) Directory to hold etcd data, for example on a dedicated disk (default: ${data-dir}/server/db/etcd)",
+		Destination: &ServerConfig.EtcdDataDir,
+	},
+	&cli.IntFlag{
+		Name:        "etcd-io-weight",
+		Usage:       "(db) cgroup io.weight (1-10000) for etcd, relative to containerd. etcd runs within the supervisor process, so this also applies to other embedded components. Requires cgroups v2 (default: unset)",
+		Destination: &ServerConfig.EtcdIOWeight,
+	},
+	&cli.DurationFlag{
+		Name:        "etcd-fsync-latency-warning",
+		Usage:       "(db) Warn at startup if the 99th percentile fsync latency of the etcd data dir exceeds this duration; 0 disables the check",
+		Destination: &ServerConfig.EtcdFsyncWarning,
+		Value:       10 * time.Millisecond,
+	},
+	&cli.BoolFlag{
+		Name:        "etcd-arbiter",
+		Usage:       "(experimental/db) Run only an etcd voting member to provide quorum for the cluster, without apiserver, controller-manager, scheduler, or agent; requires --server",
+		Destination: &ServerConfig.EtcdArbiter,
+	},
 	&cli.BoolFlag{
 		Name:        "etcd-disable-snapshots",
 		Usage:       "(db) Disable automatic etcd snapshots",
@@ -390,6 +534,11 @@ var ServerFlags = []cli.Flag{
 		Destination: &ServerConfig.EtcdSnapshotCron,
 		Value:       "0 */12 * * *",
 	},
+	&cli.StringFlag{
+		Name:        "etcd-snapshot-incremental-schedule-cron",
+		Usage:       "(db) Incremental snapshot interval time in cron spec. Incremental snapshots contain only the changes since the previous snapshot, and are restored on top of the most recent full snapshot. Disabled if not set",
+		Destination: &ServerConfig.EtcdIncrementalCron,
+	},
 	&cli.IntFlag{
 		Name:        "etcd-snapshot-retention",
 		Usage:       "(db) Number of snapshots to retain",
@@ -491,6 +640,20 @@ var ServerFlags = []cli.Flag{
 		Name:  "disable",
 		Usage: "(components) Do not deploy packaged components and delete any deployed components (valid items: " + DisableItems + ")",
 	},
+	&cli.StringSliceFlag{
+		Name:  "enable",
+		Usage: "(components) Deploy optional packaged components that are not deployed by default (valid items: " + EnableItems + ")",
+	},
+	&cli.StringSliceFlag{
+		Name:  "skip-deploy",
+		Usage: "(components) Do not stage or deploy the named manifests, without deleting any deployed components. Takes precedence over .skip files",
+		Value: &ServerConfig.SkipDeploy,
+	},
+	&cli.StringSliceFlag{
+		Name:  "pin-deploy",
+		Usage: "(components) Do not update the named manifests when upgrading, and deploy them even if a .skip file exists",
+		Value: &ServerConfig.PinDeploy,
+	},
 	&cli.BoolFlag{
 		Name:        "disable-scheduler",
 		Usage:       "(components) Disable Kubernetes default scheduler",
@@ -511,6 +674,17 @@ var ServerFlags = []cli.Flag{
 		Usage:       "(components) Disable " + version.Program + " default network policy controller",
 		Destination: &ServerConfig.DisableNPC,
 	},
+	&cli.StringFlag{
+		Name:        "agent-profile",
+		Usage:       "(components) Set of node components to run on all nodes. The pico profile disables kube-proxy, network policy, and ServiceLB for use with a CNI that replaces them, and requires --flannel-backend=none. Options: default, pico",
+		Value:       "default",
+		Destination: &ServerConfig.AgentProfile,
+	},
+	&cli.BoolFlag{
+		Name:        "token-viewer",
+		Usage:       "(components) Deploy a service account with view-only access to the cluster, and allow the server token to be exchanged for short-lived tokens for it with the token kubeconfig command",
+		Destination: &ServerConfig.TokenViewer,
+	},
 	&cli.BoolFlag{
 		Name:        "disable-helm-controller",
 		Usage:       "(components) Disable Helm controller",
@@ -536,20 +710,60 @@ var ServerFlags = []cli.Flag{
 	},
 	&cli.BoolFlag{
 		Name:        "embedded-registry",
-		Usage:       "(components) Enable embedded distributed container registry; requires use of embedded containerd; when enabled agents will also listen on the supervisor port",
+		Usage:       "(components) Enable embedded distributed container registry; requires use of embedded containerd, and is disabled on nodes using docker; when enabled agents will also listen on the supervisor port",
 		Destination: &ServerConfig.EmbeddedRegistry,
 	},
+	&cli.BoolFlag{
+		Name:        "embedded-registry-pin-digests",
+		Usage:       "(components) Pin image tags pulled by servers to the digest they first resolved to, so that all nodes pulling through the embedded registry run the same image even if the tag is moved; pins for tags not used by any pod are deleted after a day; requires --embedded-registry",
+		Destination: &ServerConfig.EmbeddedRegistryPin,
+	},
 	&cli.BoolFlag{
 		Name:        "supervisor-metrics",
-		Usage:       "(experimental/components) Enable serving " + version.Program + " internal metrics on the supervisor port; when enabled agents will also listen on the supervisor port",
+		Usage:       "(experimental/components) Enable serving " + version.Program + " internal and control-plane component metrics on the supervisor port; when enabled agents will also listen on the supervisor port",
 		Destination: &ServerConfig.SupervisorMetrics,
 	},
+	&cli.BoolFlag{
+		Name:        "dynamic-resource-allocation",
+		Usage:       "(experimental/components) Enable Dynamic Resource Allocation feature gates and APIs on the control-plane and kubelets, including the resource claim controller",
+		Destination: &ServerConfig.DRA,
+	},
+	&cli.StringFlag{
+		Name:        "control-plane-exec-mode",
+		Usage:       "(experimental/components) How to run kube-apiserver, kube-scheduler and kube-controller-manager. Options: embedded, processes",
+		Value:       "embedded",
+		Destination: &ServerConfig.ControlPlaneExecMode,
+	},
+	&cli.StringFlag{
+		Name:        "control-plane-resource-profile",
+		Usage:       "(experimental/components) Tune apiserver watch cache sizes, datastore compaction interval, controller-manager concurrency, and etcd snapshot frequency together for the memory available on the server. Component args set with --kube-*-arg and --etcd-arg take precedence. Options: tiny, small, default",
+		Value:       "default",
+		Destination: &ServerConfig.ResourceProfile,
+	},
+	&cli.StringFlag{
+		Name:        "notify-webhook-url",
+		Usage:       "(experimental/components) URL to POST cluster lifecycle events to, as JSON",
+		Destination: &ServerConfig.NotifyWebhookURL,
+	},
+	&cli.StringSliceFlag{
+		Name:  "notify-webhook-events",
+		Usage: "(experimental/components) Cluster lifecycle events to send to the notification webhook (default: all). Options: " + strings.Join(notify.EventTypes, ", "),
+		Value: &ServerConfig.NotifyWebhookEvents,
+	},
 	NodeNameFlag,
+	NodeNameTemplateFlag,
 	WithNodeIDFlag,
 	NodeLabels,
 	NodeTaints,
+	NodeLabelPolicy,
+	NodeTaintPolicy,
 	ImageCredProvBinDirFlag,
 	ImageCredProvConfigFlag,
+	ImageCredProvFlag,
+	StaticPodDirFlag,
+	SystemReservedProfileFlag,
+	SwapBehaviorFlag,
+	KubeletServingCSRFlag,
 	DockerFlag,
 	CRIEndpointFlag,
 	DefaultRuntimeFlag,
@@ -558,6 +772,7 @@ var ServerFlags = []cli.Flag{
 	NonrootDevicesFlag,
 	PauseImageFlag,
 	SnapshotterFlag,
+	ImageStoreDirFlag,
 	PrivateRegistryFlag,
 	&cli.StringFlag{
 		Name:        "system-default-registry",
@@ -565,19 +780,47 @@ var ServerFlags = []cli.Flag{
 		EnvVar:      version.ProgramUpper + "_SYSTEM_DEFAULT_REGISTRY",
 		Destination: &ServerConfig.SystemDefaultRegistry,
 	},
+	SystemImages,
+	SystemImagesFile,
 	AirgapExtraRegistryFlag,
 	NodeIPFlag,
 	NodeExternalIPFlag,
 	NodeInternalDNSFlag,
 	NodeExternalDNSFlag,
+	STUNServerFlag,
 	ResolvConfFlag,
 	FlannelIfaceFlag,
 	FlannelConfFlag,
 	FlannelCniConfFileFlag,
+	FlannelVXLANPortFlag,
+	FlannelWireguardPortFlag,
+	FlannelWireguardPortIPv6Flag,
+	CNIConfDirFlag,
+	CNIBinDirFlag,
+	CNIConfCompatFlag,
+	TunnelKeepAliveTimeoutFlag,
+	TunnelCompressionFlag,
+	TunnelReconnectDelayFlag,
+	TunnelReconnectJitterFlag,
+	RegistryProxyFlag,
+	ProxyCredentialsFileFlag,
+	HostFirewallFlag,
+	SysctlProfileFlag,
+	SysctlCheckIntervalFlag,
+	SysctlResetDriftFlag,
+	ImagePullProgressFlag,
+	LogShippingTypeFlag,
+	LogShippingEndpointFlag,
+	DisableLogShippingFlag,
+	DNSFallbackCacheFlag,
+	TracingEndpointFlag,
+	TracingSamplingRateFlag,
 	VPNAuth,
 	VPNAuthFile,
 	ExtraKubeletArgs,
+	KubeletConfigFlag,
 	ExtraKubeProxyArgs,
+	KubeProxyConfigFlag,
 	ProtectKernelDefaultsFlag,
 	&cli.BoolFlag{
 		Name:        "secrets-encryption",
@@ -586,6 +829,35 @@ var ServerFlags = []cli.Flag{
 	},
 	// Experimental flags
 	EnablePProfFlag,
+	WarmRestartFlag,
+	ComponentLimitFlag,
+	SupervisorOOMProtectionFlag,
+	StartupGateFlag,
+	StartupGateTimeoutFlag,
+	InsecureSkewToleranceFlag,
+	InstanceNameFlag,
+	ResetIdentityOnCloneFlag,
+	&cli.StringFlag{
+		Name:        "data-dir-encryption",
+		Usage:       "(experimental/data) Protect the certificates, tokens, datastore, and node credentials: the server and agent directories in the data-dir, the node password file directory, and etcd-data-dir. 'fscrypt' encrypts these directories with a key from data-dir-encryption-key-file; they must be empty when first encrypted. The containerd image store in the agent directory is also encrypted, so the snapshotter must support encrypted directories. 'dm-crypt' requires these directories to be on dm-crypt volumes",
+		Destination: &ServerConfig.DataDirEncryption,
+	},
+	&cli.StringFlag{
+		Name:        "data-dir-encryption-key-file",
+		Usage:       "(experimental/data) File containing the 64-byte fscrypt key for the server directory; a key is generated if the file does not exist. Must not be stored unencrypted on the same disk as the data-dir",
+		Destination: &ServerConfig.DataDirEncryptionKeyFile,
+	},
+	&cli.BoolFlag{
+		Name:        "data-dir-encryption-tpm",
+		Usage:       "(experimental/data) Seal the data-dir-encryption-key-file to this host's TPM using systemd-creds, so that it can only be unlocked on this host",
+		Destination: &ServerConfig.DataDirEncryptionTPM,
+	},
+	&cli.StringFlag{
+		Name:        "key-storage",
+		Usage:       "(experimental/data) Storage for the private keys, tokens, and encryption config in the server data-dir, and the admin kubeconfig: 'file' stores them unencrypted, and 'tpm' seals them to this host's TPM using systemd-creds, keeping only unsealed copies in memory; requires root, and is not supported with --rootless",
+		Value:       "file",
+		Destination: &ServerConfig.KeyStorage,
+	},
 	&cli.BoolFlag{
 		Name:        "rootless",
 		Usage:       "(experimental) Run rootless",
@@ -595,14 +867,14 @@ var ServerFlags = []cli.Flag{
 	SELinuxFlag,
 	LBServerPortFlag,
 
-	// Hidden/Deprecated flags below
-
 	&cli.BoolFlag{
 		Name:        "disable-agent",
-		Usage:       "Do not run a local agent and register a local kubelet",
-		Hidden:      true,
+		Usage:       "(experimental) Do not run a local agent; the server runs only the control-plane and datastore, and does not register a Node",
 		Destination: &ServerConfig.DisableAgent,
 	},
+
+	// Hidden/Deprecated flags below
+
 	&cli.StringSliceFlag{
 		Hidden: true,
 		Name:   "kube-controller-arg",
@@ -0,0 +1,62 @@
+package cmds
+
+import (
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/urfave/cli"
+)
+
+const ServicesCommand = "services"
+
+// Services holds CLI values for the services subcommands
+type Services struct {
+	ServerURL string
+	Token     string
+	DryRun    bool
+}
+
+var (
+	ServicesConfig = Services{}
+	ServicesFlags  = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		&cli.StringFlag{
+			Name:        "server, s",
+			Usage:       "(cluster) Server to connect to",
+			Value:       "https://127.0.0.1:6443",
+			Destination: &ServicesConfig.ServerURL,
+		},
+		&cli.StringFlag{
+			Name:        "token, t",
+			Usage:       "(cluster) Shared secret used to authenticate to the server; read from the data-dir if not set",
+			EnvVar:      version.ProgramUpper + "_TOKEN",
+			Destination: &ServicesConfig.Token,
+		},
+		&cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "(services) List the services that would be changed, without changing them",
+			Destination: &ServicesConfig.DryRun,
+		},
+	}
+)
+
+func NewServicesCommands(reallocate func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            ServicesCommand,
+		Usage:           "Manage services after changes to the service CIDRs or NodePort range",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Subcommands: []cli.Command{
+			{
+				Name:            "reallocate",
+				Usage:           "Move services with cluster IPs or node ports outside of the current service-cidr and service-node-port-range into the ranges, by reallocating node ports, and recreating services whose cluster IPs must change",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          reallocate,
+				Flags:           ServicesFlags,
+			},
+		},
+	}
+}
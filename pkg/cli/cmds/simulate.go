@@ -0,0 +1,86 @@
+package cmds
+
+import (
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+const SimulateCommand = "simulate"
+
+// Simulate holds CLI values for the simulate subcommands
+type Simulate struct {
+	Snapshot  string
+	Binary    string
+	Token     string
+	HTTPSPort int
+	Timeout   time.Duration
+	Keep      bool
+}
+
+var (
+	SimulateConfig       = Simulate{}
+	SimulateUpgradeFlags = []cli.Flag{
+		DebugFlag,
+		ConfigFlag,
+		LogFile,
+		AlsoLogToStderr,
+		DataDirFlag,
+		&cli.StringFlag{
+			Name:        "etcd-snapshot-dir",
+			Usage:       "(db) Directory to find the latest etcd snapshot in (default: ${data-dir}/server/db/snapshots)",
+			Destination: &ServerConfig.EtcdSnapshotDir,
+		},
+		&cli.StringFlag{
+			Name:        "snapshot",
+			Usage:       "(simulate) Path to the etcd snapshot to restore (default: the latest snapshot in etcd-snapshot-dir)",
+			Destination: &SimulateConfig.Snapshot,
+		},
+		&cli.StringFlag{
+			Name:        "binary",
+			Usage:       "(simulate) Path to the binary of the target version to run the simulated control plane with (default: this binary)",
+			Destination: &SimulateConfig.Binary,
+		},
+		&cli.StringFlag{
+			Name:        "token,t",
+			Usage:       "(cluster) Token that the snapshot's bootstrap data is encrypted with (default: the token in ${data-dir}/server/token)",
+			Destination: &SimulateConfig.Token,
+		},
+		&cli.IntFlag{
+			Name:        "https-listen-port",
+			Usage:       "(simulate) Port for the simulated control plane to listen on; the apiserver listens on the following port",
+			Value:       16443,
+			Destination: &SimulateConfig.HTTPSPort,
+		},
+		&cli.DurationFlag{
+			Name:        "timeout",
+			Usage:       "(simulate) Time to wait for the simulated control plane to be ready and for the checks to complete",
+			Value:       10 * time.Minute,
+			Destination: &SimulateConfig.Timeout,
+		},
+		&cli.BoolFlag{
+			Name:        "keep",
+			Usage:       "(simulate) Do not delete the restored datastore and simulated control plane data dir when the simulation completes",
+			Destination: &SimulateConfig.Keep,
+		},
+	}
+)
+
+func NewSimulateCommands(upgrade func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            SimulateCommand,
+		Usage:           "Rehearse cluster operations against a copy of the cluster datastore",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Subcommands: []cli.Command{
+			{
+				Name:            "upgrade",
+				Usage:           "Restore an etcd snapshot into a throwaway datastore, start a control plane of the target version against it on alternate ports, and report API compatibility issues",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          upgrade,
+				Flags:           SimulateUpgradeFlags,
+			},
+		},
+	}
+}
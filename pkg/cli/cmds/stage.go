@@ -7,4 +7,6 @@ const (
 	// The k3s CloudController also has a bundled manifest and can be disabled via the
 	// --disable-cloud-controller flag or --disable=ccm, but the latter method is not documented.
 	DisableItems = "coredns, servicelb, traefik, local-storage, metrics-server, runtimes"
+	// Optional packaged components are not deployed unless enabled via the --enable flag.
+	EnableItems = "monitoring-defaults"
 )
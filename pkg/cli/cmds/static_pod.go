@@ -0,0 +1,44 @@
+package cmds
+
+import (
+	"github.com/urfave/cli"
+)
+
+const StaticPodCommand = "static-pod"
+
+var StaticPodFlags = []cli.Flag{
+	DebugFlag,
+	ConfigFlag,
+	LogFile,
+	AlsoLogToStderr,
+	DataDirFlag,
+	StaticPodDirFlag,
+}
+
+func NewStaticPodCommands(list, validate func(ctx *cli.Context) error) cli.Command {
+	return cli.Command{
+		Name:            StaticPodCommand,
+		Usage:           "Manage user-provided static pod manifests",
+		SkipFlagParsing: false,
+		SkipArgReorder:  true,
+		Subcommands: []cli.Command{
+			{
+				Name:            "list",
+				Usage:           "List user-provided static pod manifests and their sync status",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          list,
+				Flags:           StaticPodFlags,
+			},
+			{
+				Name:            "validate",
+				Usage:           "Validate user-provided static pod manifests. If no files are specified, all manifests in the static pod directory are validated",
+				ArgsUsage:       "[FILE...]",
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          validate,
+				Flags:           StaticPodFlags,
+			},
+		},
+	}
+}
@@ -35,7 +35,7 @@ var (
 	}
 )
 
-func NewTokenCommands(create, delete, generate, list, rotate func(ctx *cli.Context) error) cli.Command {
+func NewTokenCommands(create, delete, generate, list, rotate, kubeconfig func(ctx *cli.Context) error) cli.Command {
 	return cli.Command{
 		Name:            TokenCommand,
 		Usage:           "Manage tokens",
@@ -121,6 +121,38 @@ func NewTokenCommands(create, delete, generate, list, rotate func(ctx *cli.Conte
 				SkipArgReorder:  true,
 				Action:          rotate,
 			},
+			{
+				Name:  "kubeconfig",
+				Usage: "Exchange the server token for a short-lived kubeconfig with view-only access to the cluster. Requires servers to be started with --token-viewer",
+				Flags: append(TokenFlags,
+					&cli.StringFlag{
+						Name:        "token,t",
+						Usage:       "Existing token used to join a server to the cluster",
+						Destination: &TokenConfig.Token,
+						EnvVar:      version.ProgramUpper + "_TOKEN",
+					},
+					&cli.StringFlag{
+						Name:        "server, s",
+						Usage:       "(cluster) Server to connect to",
+						Destination: &TokenConfig.ServerURL,
+						EnvVar:      version.ProgramUpper + "_URL",
+						Value:       "https://127.0.0.1:6443",
+					},
+					&cli.DurationFlag{
+						Name:        "ttl",
+						Usage:       "The duration that the kubeconfig is valid for, between 10m and 24h",
+						Value:       time.Hour,
+						Destination: &TokenConfig.TTL,
+					},
+					&cli.StringFlag{
+						Name:        "output,o",
+						Usage:       "Path to write the kubeconfig to (default: stdout)",
+						Destination: &TokenConfig.Output,
+					}),
+				SkipFlagParsing: false,
+				SkipArgReorder:  true,
+				Action:          kubeconfig,
+			},
 		},
 	}
 }
@@ -0,0 +1,153 @@
+package crictl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/images/archive"
+	"github.com/containerd/containerd/pkg/cri/constants"
+	"github.com/k3s-io/k3s/pkg/version"
+	"sigs.k8s.io/yaml"
+)
+
+// errPassthrough indicates that the command should be handled by crictl.
+var errPassthrough = errors.New("passthrough to crictl")
+
+// checkpointArgs are the arguments to the crictl checkpoint command.
+type checkpointArgs struct {
+	export string
+	ids    []string
+}
+
+// parseCheckpointArgs parses the arguments to the crictl checkpoint command. Arguments that are not
+// supported return errPassthrough, so that crictl can handle them.
+func parseCheckpointArgs(args []string) (*checkpointArgs, error) {
+	c := &checkpointArgs{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--export" || arg == "-E":
+			if i+1 >= len(args) {
+				return nil, errPassthrough
+			}
+			i++
+			c.export = args[i]
+		case strings.HasPrefix(arg, "--export="):
+			c.export = strings.TrimPrefix(arg, "--export=")
+		case strings.HasPrefix(arg, "-E="):
+			c.export = strings.TrimPrefix(arg, "-E=")
+		case strings.HasPrefix(arg, "-"):
+			return nil, errPassthrough
+		default:
+			c.ids = append(c.ids, arg)
+		}
+	}
+	if c.export == "" || len(c.ids) == 0 {
+		return nil, errPassthrough
+	}
+	return c, nil
+}
+
+// runtimeEndpoint returns the runtime endpoint used by crictl, from the environment or the crictl
+// config file.
+func runtimeEndpoint() string {
+	if endpoint := os.Getenv("CONTAINER_RUNTIME_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	b, err := os.ReadFile(os.Getenv("CRI_CONFIG_FILE"))
+	if err != nil {
+		return ""
+	}
+	config := struct {
+		RuntimeEndpoint string `json:"runtime-endpoint"`
+	}{}
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return ""
+	}
+	return config.RuntimeEndpoint
+}
+
+// checkpoint checkpoints running containers using the containerd API, and exports the checkpoint to
+// a tar archive that can be restored with ctr. The container is left running. This is used in place
+// of the CRI CheckpointContainer call, which is not implemented by containerd prior to 2.0. If the
+// runtime is not containerd, or implements the CRI call, errPassthrough is returned.
+func checkpoint(ctx context.Context, args []string) error {
+	c, err := parseCheckpointArgs(args)
+	if err != nil {
+		return err
+	}
+	address, ok := strings.CutPrefix(runtimeEndpoint(), "unix://")
+	if !ok {
+		return errPassthrough
+	}
+	client, err := containerd.New(address, containerd.WithDefaultNamespace(constants.K8sContainerdNamespace))
+	if err != nil {
+		return errPassthrough
+	}
+	defer client.Close()
+
+	v, err := client.Version(ctx)
+	if err != nil {
+		return errPassthrough
+	}
+	if major, _, _ := strings.Cut(strings.TrimPrefix(v.Version, "v"), "."); major != "1" {
+		return errPassthrough
+	}
+	if _, err := exec.LookPath("criu"); err != nil {
+		return errors.New("criu must be installed to checkpoint containers")
+	}
+
+	if len(c.ids) > 1 {
+		return errors.New("only one container can be checkpointed to an export file")
+	}
+	container, err := findContainer(ctx, client, c.ids[0])
+	if err != nil {
+		return err
+	}
+
+	ref := version.Program + ".io/checkpoint/" + container.ID() + ":" + strconv.FormatInt(time.Now().Unix(), 10)
+	if _, err := container.Checkpoint(ctx, ref, containerd.WithCheckpointImage, containerd.WithCheckpointRW, containerd.WithCheckpointRuntime, containerd.WithCheckpointTask); err != nil {
+		return fmt.Errorf("checkpointing the container %q failed: %w", container.ID(), err)
+	}
+	defer client.ImageService().Delete(context.Background(), ref)
+
+	f, err := os.OpenFile(c.export, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := client.Export(ctx, f, archive.WithImage(client.ImageService(), ref)); err != nil {
+		return fmt.Errorf("exporting the checkpoint of container %q failed: %w", container.ID(), err)
+	}
+	fmt.Println(container.ID())
+	return nil
+}
+
+// findContainer returns the container with the given ID or unique ID prefix.
+func findContainer(ctx context.Context, client *containerd.Client, id string) (containerd.Container, error) {
+	containers, err := client.Containers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var found containerd.Container
+	for _, container := range containers {
+		if !strings.HasPrefix(container.ID(), id) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("container ID %q is ambiguous", id)
+		}
+		found = container
+	}
+	if found == nil {
+		return nil, fmt.Errorf("container %q not found", id)
+	}
+	return found, nil
+}
@@ -0,0 +1,53 @@
+package crictl
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_UnitParseCheckpointArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    *checkpointArgs
+		wantErr error
+	}{
+		{
+			name: "export flag",
+			args: []string{"--export", "/tmp/c.tar", "abc"},
+			want: &checkpointArgs{export: "/tmp/c.tar", ids: []string{"abc"}},
+		},
+		{
+			name: "short export flag with value",
+			args: []string{"abc", "-E=/tmp/c.tar"},
+			want: &checkpointArgs{export: "/tmp/c.tar", ids: []string{"abc"}},
+		},
+		{
+			name:    "missing export",
+			args:    []string{"abc"},
+			wantErr: errPassthrough,
+		},
+		{
+			name:    "missing container",
+			args:    []string{"--export=/tmp/c.tar"},
+			wantErr: errPassthrough,
+		},
+		{
+			name:    "unsupported flag",
+			args:    []string{"--help"},
+			wantErr: errPassthrough,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCheckpointArgs(tt.args)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseCheckpointArgs() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCheckpointArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
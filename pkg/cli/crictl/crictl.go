@@ -1,6 +1,9 @@
 package crictl
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"runtime"
 
@@ -12,6 +15,21 @@ func Run(ctx *cli.Context) error {
 	if runtime.GOOS == "windows" {
 		os.Args = os.Args[1:]
 	}
-	crictl.Main()
+	Main()
 	return nil
 }
+
+// Main runs crictl, handling the checkpoint command with the containerd API if the runtime does not
+// implement checkpointing through the CRI.
+func Main() {
+	if runtime.GOOS == "linux" && len(os.Args) > 1 && os.Args[1] == "checkpoint" {
+		if err := checkpoint(context.Background(), os.Args[2:]); !errors.Is(err, errPassthrough) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "crictl: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	crictl.Main()
+}
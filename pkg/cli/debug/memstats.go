@@ -0,0 +1,98 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func MemStats(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return memStats(app, &cmds.ServerConfig, &cmds.DebugMemStatsConfig)
+}
+
+func memStats(app *cli.Context, cfg *cmds.Server, debugCfg *cmds.DebugMemStats) error {
+	var budget *resource.Quantity
+	if debugCfg.Budget != "" {
+		quantity, err := resource.ParseQuantity(debugCfg.Budget)
+		if err != nil {
+			return errors.Wrap(err, "invalid budget")
+		}
+		budget = &quantity
+	}
+
+	info, err := getServerInfo(cfg, &debugCfg.DebugTunnels)
+	if err != nil {
+		return err
+	}
+
+	data, err := info.Get("/v1-" + version.Program + "/debug/memstats")
+	if err != nil {
+		return errors.Wrap(err, "see server log for details")
+	}
+	stats := config.MemStats{}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return err
+	}
+
+	if strings.ToLower(debugCfg.Output) == "json" {
+		b, err := json.MarshalIndent(stats, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	} else if err := printMemStats(os.Stdout, stats); err != nil {
+		return err
+	}
+	return checkBudget(stats, budget)
+}
+
+// checkBudget returns an error if the resident set size exceeds the budget. No budget is enforced
+// if the budget is nil, or the server could not determine its resident set size.
+func checkBudget(stats config.MemStats, budget *resource.Quantity) error {
+	if budget == nil || stats.RSS == 0 {
+		return nil
+	}
+	if limit := budget.Value(); limit > 0 && stats.RSS > uint64(limit) {
+		return fmt.Errorf("resident set size %s exceeds budget %s", formatBytes(stats.RSS), budget.String())
+	}
+	return nil
+}
+
+func printMemStats(out io.Writer, stats config.MemStats) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	rss := "unknown"
+	if stats.RSS != 0 {
+		rss = formatBytes(stats.RSS)
+	}
+	fmt.Fprintf(w, "RSS:\t%s\n", rss)
+	fmt.Fprintf(w, "Heap allocated:\t%s\n", formatBytes(stats.HeapAlloc))
+	fmt.Fprintf(w, "Heap in use:\t%s\n", formatBytes(stats.HeapInuse))
+	fmt.Fprintf(w, "Go runtime total:\t%s\n", formatBytes(stats.Sys))
+	fmt.Fprintf(w, "Goroutines:\t%d\n", stats.Goroutines)
+	fmt.Fprintf(w, "GC cycles:\t%d\n", stats.NumGC)
+	disabled := "none"
+	if len(stats.Disabled) > 0 {
+		disabled = strings.Join(stats.Disabled, ", ")
+	}
+	fmt.Fprintf(w, "Disabled:\t%s\n", disabled)
+	return w.Flush()
+}
+
+// formatBytes formats a byte count as a binary SI quantity, rounded up to the nearest Mi.
+func formatBytes(b uint64) string {
+	const mi = 1 << 20
+	return resource.NewQuantity(int64((b+mi-1)/mi)*mi, resource.BinarySI).String()
+}
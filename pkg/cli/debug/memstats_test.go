@@ -0,0 +1,53 @@
+package debug
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func Test_UnitCheckBudget(t *testing.T) {
+	budget := resource.MustParse("256Mi")
+	tests := []struct {
+		name    string
+		rss     uint64
+		budget  *resource.Quantity
+		wantErr bool
+	}{
+		{name: "no budget", rss: 512 << 20},
+		{name: "under budget", rss: 200 << 20, budget: &budget},
+		{name: "at budget", rss: 256 << 20, budget: &budget},
+		{name: "over budget", rss: 300 << 20, budget: &budget, wantErr: true},
+		{name: "rss unknown", budget: &budget},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBudget(config.MemStats{RSS: tt.rss}, tt.budget)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkBudget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_UnitPrintMemStats(t *testing.T) {
+	out := &bytes.Buffer{}
+	stats := config.MemStats{
+		RSS:        300<<20 + 1,
+		HeapAlloc:  90 << 20,
+		Goroutines: 812,
+		Disabled:   []string{"servicelb", "traefik"},
+	}
+	if err := printMemStats(out, stats); err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Join(strings.Fields(out.String()), " ")
+	for _, want := range []string{"RSS: 301Mi", "Heap allocated: 90Mi", "Goroutines: 812", "Disabled: servicelb, traefik"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("printMemStats() output missing %q:\n%s", want, out.String())
+		}
+	}
+}
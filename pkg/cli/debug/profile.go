@@ -0,0 +1,272 @@
+package debug
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/datadir"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/rancher/wrangler/v3/pkg/signals"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"golang.org/x/sync/errgroup"
+)
+
+// profileTarget is a process that serves the pprof API.
+type profileTarget struct {
+	name   string
+	url    string
+	client *http.Client
+	// optional targets are skipped if their pprof API is not available.
+	optional bool
+}
+
+// snapshotProfiles are collected once the CPU profile and trace are complete.
+var snapshotProfiles = map[string]string{
+	"heap.pprof":      "/debug/pprof/heap",
+	"goroutine.pprof": "/debug/pprof/goroutine",
+	"goroutine.txt":   "/debug/pprof/goroutine?debug=2",
+}
+
+func Profile(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return profile(app, &cmds.ServerConfig, &cmds.DebugProfileConfig)
+}
+
+func profile(app *cli.Context, cfg *cmds.Server, debugCfg *cmds.DebugProfile) error {
+	if debugCfg.Duration <= 0 {
+		return errors.New("duration must be greater than zero")
+	}
+	dataDir, err := datadir.Resolve(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+	targets, err := profileTargets(dataDir, debugCfg)
+	if err != nil {
+		return err
+	}
+
+	output := debugCfg.Output
+	if output == "" {
+		output = fmt.Sprintf("%s-profile-%d.tar.gz", version.Program, time.Now().Unix())
+	}
+
+	logrus.Infof("Collecting profiles for %s", debugCfg.Duration)
+	files := map[string][]byte{}
+	mu := sync.Mutex{}
+	eg, ctx := errgroup.WithContext(signals.SetupSignalContext())
+	for _, target := range targets {
+		eg.Go(func() error {
+			profiles, err := collect(ctx, target, debugCfg.Duration, debugCfg.Trace)
+			if err != nil {
+				if target.optional {
+					logrus.Warnf("Skipping %s profiles: %v", target.name, err)
+					return nil
+				}
+				return errors.Wrapf(err, "failed to collect %s profiles; ensure the server or agent was started with --enable-pprof", target.name)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for name, data := range profiles {
+				files[target.name+"/"+name] = data
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	if err := writeBundle(output, files); err != nil {
+		return errors.Wrap(err, "failed to write profile bundle")
+	}
+	fmt.Printf("Profiles written to %s\n", output)
+	return nil
+}
+
+// profileTargets returns the processes to collect profiles from. The supervisor is always profiled; control-plane
+// components are only available when running as separate processes with profiling enabled.
+func profileTargets(dataDir string, debugCfg *cmds.DebugProfile) ([]profileTarget, error) {
+	serverCA := filepath.Join(dataDir, "agent", "server-ca.crt")
+	caBytes, err := os.ReadFile(serverCA)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caBytes)
+
+	// Admin credentials are only present on servers; agents use the kubelet client certificate.
+	isServer := true
+	cert, err := tls.LoadX509KeyPair(filepath.Join(dataDir, "server", "tls", "client-admin.crt"), filepath.Join(dataDir, "server", "tls", "client-admin.key"))
+	if os.IsNotExist(err) {
+		isServer = false
+		cert, err = tls.LoadX509KeyPair(filepath.Join(dataDir, "agent", "client-kubelet.crt"), filepath.Join(dataDir, "agent", "client-kubelet.key"))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load client certificate")
+	}
+
+	targets := []profileTarget{{
+		name: version.Program,
+		url:  strings.TrimSuffix(debugCfg.ServerURL, "/"),
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					Certificates: []tls.Certificate{cert},
+					RootCAs:      pool,
+				},
+			},
+		},
+	}}
+
+	if isServer {
+		// The scheduler and controller-manager generate a self-signed serving certificate, which cannot be verified.
+		// The endpoints are only ever accessed over the loopback address.
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					Certificates:       []tls.Certificate{cert},
+					InsecureSkipVerify: true,
+				},
+			},
+		}
+		// The apiserver listens on the port following the supervisor's, unless configured otherwise.
+		apiServerURL := "https://localhost:6444"
+		if u, err := url.Parse(debugCfg.ServerURL); err == nil {
+			if port, err := strconv.Atoi(u.Port()); err == nil {
+				apiServerURL = "https://localhost:" + strconv.Itoa(port+1)
+			}
+		}
+		targets = append(targets,
+			profileTarget{name: "kube-apiserver", url: apiServerURL, client: client, optional: true},
+			profileTarget{name: "kube-scheduler", url: "https://localhost:10259", client: client, optional: true},
+			profileTarget{name: "kube-controller-manager", url: "https://localhost:10257", client: client, optional: true},
+		)
+	}
+
+	return targets, nil
+}
+
+// collect gathers profiles from a target. The CPU profile and execution trace are collected concurrently over
+// the requested duration, after which heap and goroutine profiles are collected.
+func collect(ctx context.Context, target profileTarget, duration time.Duration, trace bool) (map[string][]byte, error) {
+	// Check that the pprof API is available before starting any long-running profiles
+	if _, err := get(ctx, target, "/debug/pprof/cmdline"); err != nil {
+		return nil, err
+	}
+
+	seconds := int(duration.Round(time.Second).Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	timed := map[string]string{
+		"cpu.pprof": fmt.Sprintf("/debug/pprof/profile?seconds=%d", seconds),
+	}
+	if trace {
+		timed["trace.out"] = fmt.Sprintf("/debug/pprof/trace?seconds=%d", seconds)
+	}
+
+	files := map[string][]byte{}
+	mu := sync.Mutex{}
+	eg, egCtx := errgroup.WithContext(ctx)
+	for name, path := range timed {
+		eg.Go(func() error {
+			data, err := get(egCtx, target, path)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			files[name] = data
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	for name, path := range snapshotProfiles {
+		data, err := get(ctx, target, path)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = data
+	}
+	return files, nil
+}
+
+func get(ctx context.Context, target profileTarget, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := target.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", req.URL.Path, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// writeBundle writes the collected files to a gzipped tarball.
+func writeBundle(path string, files map[string][]byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	for _, name := range names {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(files[name])),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,98 @@
+package debug
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_UnitCollect(t *testing.T) {
+	mu := sync.Mutex{}
+	requests := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests[r.URL.RequestURI()] = true
+		w.Write([]byte(r.URL.RequestURI()))
+	}))
+	defer server.Close()
+
+	target := profileTarget{name: "test", url: server.URL, client: server.Client()}
+	files, err := collect(context.Background(), target, 2*time.Second, true)
+	if err != nil {
+		t.Fatalf("collect() error = %v", err)
+	}
+
+	want := map[string][]byte{
+		"cpu.pprof":       []byte("/debug/pprof/profile?seconds=2"),
+		"trace.out":       []byte("/debug/pprof/trace?seconds=2"),
+		"heap.pprof":      []byte("/debug/pprof/heap"),
+		"goroutine.pprof": []byte("/debug/pprof/goroutine"),
+		"goroutine.txt":   []byte("/debug/pprof/goroutine?debug=2"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("collect() = %q, want %q", files, want)
+	}
+	if !requests["/debug/pprof/cmdline"] {
+		t.Errorf("collect() did not check pprof availability")
+	}
+}
+
+func Test_UnitCollectNotEnabled(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	target := profileTarget{name: "test", url: server.URL, client: server.Client()}
+	if _, err := collect(context.Background(), target, time.Second, false); err == nil {
+		t.Errorf("collect() expected error when pprof is not enabled")
+	}
+}
+
+func Test_UnitWriteBundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	files := map[string][]byte{
+		"k3s/heap.pprof":            []byte("heap"),
+		"kube-scheduler/heap.pprof": []byte("scheduler"),
+	}
+	if err := writeBundle(path, files); err != nil {
+		t.Fatalf("writeBundle() error = %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	got := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[hdr.Name] = data
+	}
+	if !reflect.DeepEqual(got, files) {
+		t.Errorf("writeBundle() wrote %q, want %q", got, files)
+	}
+}
@@ -0,0 +1,127 @@
+package debug
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/clientaccess"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/datadir"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+func Tunnels(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return tunnels(app, &cmds.ServerConfig, &cmds.DebugTunnelsConfig)
+}
+
+func Egress(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return egress(app, &cmds.ServerConfig, &cmds.DebugTunnelsConfig)
+}
+
+// getServerInfo returns server access info for the tunnel debug commands, using the server token
+// from the data-dir if a token was not provided.
+func getServerInfo(cfg *cmds.Server, debugCfg *cmds.DebugTunnels) (*clientaccess.Info, error) {
+	if debugCfg.Token == "" {
+		dataDir, err := datadir.Resolve(cfg.DataDir)
+		if err != nil {
+			return nil, err
+		}
+		tokenByte, err := os.ReadFile(filepath.Join(dataDir, "server", "token"))
+		if err != nil {
+			return nil, err
+		}
+		debugCfg.Token = string(bytes.TrimRight(tokenByte, "\n"))
+	}
+	return clientaccess.ParseAndValidateToken(debugCfg.ServerURL, debugCfg.Token, clientaccess.WithUser("server"))
+}
+
+func tunnels(app *cli.Context, cfg *cmds.Server, debugCfg *cmds.DebugTunnels) error {
+	info, err := getServerInfo(cfg, debugCfg)
+	if err != nil {
+		return err
+	}
+
+	data, err := info.Get("/v1-" + version.Program + "/tunnel/sessions")
+	if err != nil {
+		return errors.Wrap(err, "see server log for details")
+	}
+	sessions := []config.TunnelSession{}
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return err
+	}
+
+	if strings.ToLower(debugCfg.Output) == "json" {
+		b, err := json.MarshalIndent(sessions, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	return printTunnelSessions(os.Stdout, sessions, time.Now())
+}
+
+func printTunnelSessions(out io.Writer, sessions []config.TunnelSession, now time.Time) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "NODE\tADDRESS\tCONNECTED\tRECEIVED\tSENT\n")
+	for _, s := range sessions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\n", s.NodeName, s.Address, duration.HumanDuration(now.Sub(s.ConnectedSince)), s.BytesReceived, s.BytesSent)
+	}
+	return w.Flush()
+}
+
+func egress(app *cli.Context, cfg *cmds.Server, debugCfg *cmds.DebugTunnels) error {
+	info, err := getServerInfo(cfg, debugCfg)
+	if err != nil {
+		return err
+	}
+
+	data, err := info.Get("/v1-" + version.Program + "/egress/status")
+	if err != nil {
+		return errors.Wrap(err, "see server log for details")
+	}
+	status := config.EgressStatus{}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return err
+	}
+
+	if strings.ToLower(debugCfg.Output) == "json" {
+		b, err := json.MarshalIndent(status, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	return printEgressStatus(os.Stdout, status)
+}
+
+func printEgressStatus(out io.Writer, status config.EgressStatus) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "TYPE\tMODE\tPROXIED\n")
+	for _, t := range status.Types {
+		fmt.Fprintf(w, "%s\t%s\t%t\n", t.Type, t.Mode, t.Proxied)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nSessions: %d\nConnections: %d tunneled, %d direct, %d failed\n", status.Sessions, status.TunneledConnections, status.DirectConnections, status.FailedConnections)
+	return nil
+}
@@ -186,6 +186,55 @@ func delete(app *cli.Context, cfg *cmds.Server) error {
 	return nil
 }
 
+func Verify(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return verify(app, &cmds.ServerConfig)
+}
+
+func verify(app *cli.Context, cfg *cmds.Server) error {
+	snapshots := app.Args()
+	if len(snapshots) == 0 {
+		return errors.New("no snapshots given for verification")
+	}
+
+	sr, info, err := commandSetup(app, cfg)
+	if err != nil {
+		return err
+	}
+
+	sr.Operation = etcd.SnapshotOperationVerify
+	sr.Name = snapshots
+
+	b, err := json.Marshal(sr)
+	if err != nil {
+		return err
+	}
+	r, err := info.Post("/db/snapshot", b, clientaccess.WithTimeout(timeout))
+	if err != nil {
+		return wrapServerError(err)
+	}
+	resp := &managed.SnapshotResult{}
+	if err := json.Unmarshal(r, resp); err != nil {
+		return err
+	}
+
+	for _, name := range resp.Verified {
+		logrus.Infof("Snapshot %s verified.", name)
+	}
+	for _, name := range snapshots {
+		if msg, ok := resp.Failed[name]; ok {
+			logrus.Errorf("Snapshot %s failed verification: %s", name, msg)
+		}
+	}
+	if len(resp.Failed) > 0 {
+		return fmt.Errorf("%d snapshot(s) failed verification", len(resp.Failed))
+	}
+
+	return nil
+}
+
 func List(app *cli.Context) error {
 	if err := cmds.InitLogging(); err != nil {
 		return err
@@ -0,0 +1,176 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/clientaccess"
+	"github.com/k3s-io/k3s/pkg/proctitle"
+	"github.com/k3s-io/k3s/pkg/server"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+)
+
+// cloudConfig is the subset of the cloud-init cloud-config format used for bootstrap data.
+type cloudConfig struct {
+	WriteFiles []cloudConfigFile `yaml:"write_files"`
+	RunCmd     []string          `yaml:"runcmd"`
+}
+
+type cloudConfigFile struct {
+	Path        string `yaml:"path"`
+	Owner       string `yaml:"owner"`
+	Permissions string `yaml:"permissions"`
+	Content     string `yaml:"content"`
+}
+
+func BootstrapData(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	// hide process arguments from ps output, since they may contain tokens.
+	proctitle.SetProcTitle(os.Args[0] + " generate")
+
+	b, err := bootstrapData(&cmds.ServerConfig, &cmds.GenerateConfig)
+	if err != nil {
+		return err
+	}
+	if cmds.GenerateConfig.Output == "" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(cmds.GenerateConfig.Output, b, 0600)
+}
+
+func bootstrapData(cfg *cmds.Server, genCfg *cmds.Generate) ([]byte, error) {
+	if genCfg.Role != "server" && genCfg.Role != "agent" {
+		return nil, fmt.Errorf("invalid role %q; must be one of server, agent", genCfg.Role)
+	}
+	if genCfg.ServerURL == "" {
+		return nil, errors.New("server URL is required")
+	}
+
+	dataDir, err := server.ResolveDataDir(cfg.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	token, err := joinToken(dataDir, genCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeConfig := yaml.MapSlice{
+		{Key: "server", Value: genCfg.ServerURL},
+		{Key: "token", Value: token},
+	}
+	if genCfg.NodeConfig != "" {
+		b, err := os.ReadFile(genCfg.NodeConfig)
+		if err != nil {
+			return nil, err
+		}
+		extra := yaml.MapSlice{}
+		if err := yaml.Unmarshal(b, &extra); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse node config %s", genCfg.NodeConfig)
+		}
+		for _, item := range extra {
+			if key, _ := item.Key.(string); key == "server" || key == "token" {
+				return nil, fmt.Errorf("node config %s must not set %s", genCfg.NodeConfig, key)
+			}
+			nodeConfig = append(nodeConfig, item)
+		}
+	}
+	configBytes, err := yaml.Marshal(nodeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	cc := cloudConfig{
+		WriteFiles: []cloudConfigFile{{
+			Path:        cmds.ConfigFlag.Value,
+			Owner:       "root:root",
+			Permissions: "0600",
+			Content:     string(configBytes),
+		}},
+	}
+
+	if genCfg.PrivateRegistry != "" {
+		b, err := os.ReadFile(genCfg.PrivateRegistry)
+		if err != nil {
+			return nil, err
+		}
+		cc.WriteFiles = append(cc.WriteFiles, cloudConfigFile{
+			Path:        cmds.PrivateRegistryFlag.Value,
+			Owner:       "root:root",
+			Permissions: "0600",
+			Content:     string(b),
+		})
+	}
+
+	cc.RunCmd = []string{installCommand(genCfg)}
+
+	b, err := yaml.Marshal(cc)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("#cloud-config\n"), b...), nil
+}
+
+// joinToken returns the token that the new node should use to join the cluster. Tokens read from
+// the data-dir are already in the full format that includes the CA hash; tokens provided on the
+// command line are converted to this format if they are not already.
+func joinToken(dataDir string, genCfg *cmds.Generate) (string, error) {
+	if genCfg.Token != "" {
+		if strings.HasPrefix(genCfg.Token, "K10") {
+			return genCfg.Token, nil
+		}
+		return clientaccess.FormatToken(genCfg.Token, filepath.Join(dataDir, "tls", "server-ca.crt"))
+	}
+
+	tokenFiles := []string{filepath.Join(dataDir, "token")}
+	if genCfg.Role == "agent" {
+		tokenFiles = append([]string{filepath.Join(dataDir, "agent-token")}, tokenFiles...)
+	}
+	for _, tokenFile := range tokenFiles {
+		b, err := os.ReadFile(tokenFile)
+		if err == nil {
+			return string(bytes.TrimRight(b, "\n")), nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no %s token found in %s; use --token to specify the token", genCfg.Role, dataDir)
+}
+
+// installCommand returns the command used to install and start the new node. Settings are read
+// from the config file, so only the role and version are passed to the install script.
+func installCommand(genCfg *cmds.Generate) string {
+	installVersion := genCfg.InstallVersion
+	if installVersion == "" && strings.HasPrefix(version.Version, "v") {
+		installVersion = version.Version
+	}
+
+	env := []string{"INSTALL_" + version.ProgramUpper + "_EXEC=" + genCfg.Role}
+	if genCfg.Airgap {
+		env = append(env, "INSTALL_"+version.ProgramUpper+"_SKIP_DOWNLOAD=true")
+	} else if installVersion != "" {
+		env = append(env, "INSTALL_"+version.ProgramUpper+"_VERSION="+installVersion)
+	}
+
+	script := genCfg.InstallScript
+	if genCfg.Airgap {
+		if script == "" {
+			script = "/usr/local/bin/" + version.Program + "-install.sh"
+		}
+		return strings.Join(env, " ") + " sh " + script
+	}
+	if script == "" {
+		script = "https://get." + version.Program + ".io"
+	}
+	return "curl -sfL " + script + " | " + strings.Join(env, " ") + " sh -s -"
+}
@@ -0,0 +1,123 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	testServerToken = "K10abc::server:secret"
+	testAgentToken  = "K10abc::node:secret"
+)
+
+func Test_UnitBootstrapData(t *testing.T) {
+	dataDir := t.TempDir()
+	serverDir := filepath.Join(dataDir, "server")
+	if err := os.MkdirAll(serverDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(serverDir, "token"), []byte(testServerToken+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(serverDir, "agent-token"), []byte(testAgentToken+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	nodeConfig := filepath.Join(dataDir, "node-config.yaml")
+	if err := os.WriteFile(nodeConfig, []byte("node-label:\n- role=edge\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	invalidNodeConfig := filepath.Join(dataDir, "invalid-node-config.yaml")
+	if err := os.WriteFile(invalidNodeConfig, []byte("token: other\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		genCfg      cmds.Generate
+		wantToken   string
+		wantCommand string
+		wantConfig  map[string]interface{}
+		wantErr     bool
+	}{
+		{
+			name:        "agent",
+			genCfg:      cmds.Generate{Role: "agent", ServerURL: "https://server:6443", InstallVersion: "v1.32.1+k3s1"},
+			wantToken:   testAgentToken,
+			wantCommand: "curl -sfL https://get.k3s.io | INSTALL_K3S_EXEC=agent INSTALL_K3S_VERSION=v1.32.1+k3s1 sh -s -",
+		},
+		{
+			name:        "airgap server",
+			genCfg:      cmds.Generate{Role: "server", ServerURL: "https://server:6443", Airgap: true, NodeConfig: nodeConfig},
+			wantToken:   testServerToken,
+			wantCommand: "INSTALL_K3S_EXEC=server INSTALL_K3S_SKIP_DOWNLOAD=true sh /usr/local/bin/k3s-install.sh",
+			wantConfig:  map[string]interface{}{"node-label": []interface{}{"role=edge"}},
+		},
+		{
+			name:        "explicit token",
+			genCfg:      cmds.Generate{Role: "agent", ServerURL: "https://server:6443", Token: "K10def::node:other", InstallVersion: "v1.32.1+k3s1"},
+			wantToken:   "K10def::node:other",
+			wantCommand: "curl -sfL https://get.k3s.io | INSTALL_K3S_EXEC=agent INSTALL_K3S_VERSION=v1.32.1+k3s1 sh -s -",
+		},
+		{
+			name:    "invalid role",
+			genCfg:  cmds.Generate{Role: "worker", ServerURL: "https://server:6443"},
+			wantErr: true,
+		},
+		{
+			name:    "missing server",
+			genCfg:  cmds.Generate{Role: "agent"},
+			wantErr: true,
+		},
+		{
+			name:    "node config sets token",
+			genCfg:  cmds.Generate{Role: "agent", ServerURL: "https://server:6443", NodeConfig: invalidNodeConfig},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := bootstrapData(&cmds.Server{DataDir: dataDir}, &tt.genCfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bootstrapData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !strings.HasPrefix(string(b), "#cloud-config\n") {
+				t.Errorf("bootstrapData() output is missing cloud-config header")
+			}
+
+			cc := cloudConfig{}
+			if err := yaml.Unmarshal(b, &cc); err != nil {
+				t.Fatalf("failed to parse cloud-config: %v", err)
+			}
+			if len(cc.RunCmd) != 1 || cc.RunCmd[0] != tt.wantCommand {
+				t.Errorf("bootstrapData() runcmd = %v, want %q", cc.RunCmd, tt.wantCommand)
+			}
+			if len(cc.WriteFiles) != 1 || cc.WriteFiles[0].Path != "/etc/rancher/k3s/config.yaml" {
+				t.Fatalf("bootstrapData() write_files = %v, want config.yaml", cc.WriteFiles)
+			}
+
+			config := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(cc.WriteFiles[0].Content), &config); err != nil {
+				t.Fatalf("failed to parse config.yaml: %v", err)
+			}
+			if config["server"] != tt.genCfg.ServerURL {
+				t.Errorf("config.yaml server = %v, want %s", config["server"], tt.genCfg.ServerURL)
+			}
+			if config["token"] != tt.wantToken {
+				t.Errorf("config.yaml token = %v, want %s", config["token"], tt.wantToken)
+			}
+			for key, value := range tt.wantConfig {
+				if got, ok := config[key].([]interface{}); !ok || len(got) != 1 || got[0] != value.([]interface{})[0] {
+					t.Errorf("config.yaml %s = %v, want %v", key, config[key], value)
+				}
+			}
+		})
+	}
+}
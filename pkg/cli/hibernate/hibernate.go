@@ -0,0 +1,229 @@
+package hibernate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/clientaccess"
+	"github.com/k3s-io/k3s/pkg/cluster/managed"
+	"github.com/k3s-io/k3s/pkg/datadir"
+	"github.com/k3s-io/k3s/pkg/etcd"
+	"github.com/k3s-io/k3s/pkg/hibernate"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	_ "github.com/mattn/go-sqlite3" // sqlite3 driver for checkpointing the kine database
+	"github.com/pkg/errors"
+	"github.com/rancher/wrangler/v3/pkg/signals"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// snapshotTimeout is the time allowed for the etcd snapshot taken when hibernating.
+	snapshotTimeout = 5 * time.Minute
+	// resumeTimeout is the time allowed for the server to become ready after its service is started.
+	resumeTimeout = 5 * time.Minute
+	// servicesFile is the file, relative to the data-dir, that lists the services stopped by
+	// hibernate, so that the same services can be started by resume.
+	servicesFile = "server/hibernate-services"
+)
+
+func Hibernate(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return hibernateServer(app, &cmds.ServerConfig, &cmds.HibernateConfig)
+}
+
+func hibernateServer(app *cli.Context, cfg *cmds.Server, hibernateCfg *cmds.Hibernate) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	dataDir, err := datadir.Resolve(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+	info, err := commandSetup(dataDir, hibernateCfg)
+	if err != nil {
+		return err
+	}
+
+	status, err := setHibernate(info, true)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Node %s is in maintenance mode; scaled down deployments %v", status.Node, status.Deployments)
+
+	if status.Etcd && !hibernateCfg.SkipSnapshot {
+		if err := saveEtcdSnapshot(info, status.Node); err != nil {
+			return err
+		}
+	}
+	if err := checkpointSQLite(dataDir); err != nil {
+		return err
+	}
+
+	if hibernateCfg.SkipStop {
+		logrus.Infof("Server is hibernating; stop %s before suspending the host, and run '%s %s' after it is resumed", version.Program, version.Program, cmds.ResumeCommand)
+		return nil
+	}
+
+	services := util.ActiveServiceUnits()
+	if len(services) == 0 {
+		logrus.Warnf("No running %s service found; stop %s before suspending the host, and run '%s %s' after it is resumed", version.Program, version.Program, version.Program, cmds.ResumeCommand)
+		return nil
+	}
+	if err := util.AtomicWrite(filepath.Join(dataDir, servicesFile), []byte(strings.Join(services, "\n")+"\n"), 0600); err != nil {
+		return err
+	}
+	for _, service := range services {
+		logrus.Infof("Stopping service %s", service)
+		if err := util.RunServiceUnit(service, "stop"); err != nil {
+			return errors.Wrapf(err, "failed to stop service %s", service)
+		}
+	}
+	if err := stopContainers(dataDir, hibernateCfg.StopTimeout); err != nil {
+		return errors.Wrap(err, "failed to stop containers")
+	}
+
+	logrus.Infof("Server is hibernating and the host can be suspended; run '%s %s' after it is resumed", version.Program, cmds.ResumeCommand)
+	return nil
+}
+
+func Resume(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return resumeServer(app, &cmds.ServerConfig, &cmds.HibernateConfig)
+}
+
+func resumeServer(app *cli.Context, cfg *cmds.Server, hibernateCfg *cmds.Hibernate) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	dataDir, err := datadir.Resolve(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+
+	b, err := os.ReadFile(filepath.Join(dataDir, servicesFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, service := range strings.Fields(string(b)) {
+		logrus.Infof("Starting service %s", service)
+		if err := util.RunServiceUnit(service, "start"); err != nil {
+			return errors.Wrapf(err, "failed to start service %s", service)
+		}
+	}
+
+	// the server may still be starting, so retry until the request succeeds or the timeout expires
+	var status *hibernate.Status
+	err = wait.PollUntilContextTimeout(signals.SetupSignalContext(), 5*time.Second, resumeTimeout, true, func(ctx context.Context) (bool, error) {
+		info, err := commandSetup(dataDir, hibernateCfg)
+		if err != nil {
+			logrus.Infof("Waiting for server to become ready: %v", err)
+			return false, nil
+		}
+		if status, err = setHibernate(info, false); err != nil {
+			logrus.Infof("Waiting for server to become ready: %v", err)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "timed out waiting for server to resume; see server log for details")
+	}
+	if err := os.Remove(filepath.Join(dataDir, servicesFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	logrus.Infof("Node %s is no longer in maintenance mode; restored deployments %v", status.Node, status.Deployments)
+	return nil
+}
+
+// commandSetup returns a client for the server. The token is read from the data-dir if not set.
+func commandSetup(dataDir string, hibernateCfg *cmds.Hibernate) (*clientaccess.Info, error) {
+	if hibernateCfg.Token == "" {
+		tokenByte, err := os.ReadFile(filepath.Join(dataDir, "server", "token"))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read server token; hibernate and resume must be run on a server")
+		}
+		hibernateCfg.Token = string(bytes.TrimRight(tokenByte, "\n"))
+	}
+	return clientaccess.ParseAndValidateToken(hibernateCfg.ServerURL, hibernateCfg.Token, clientaccess.WithUser("server"))
+}
+
+func setHibernate(info *clientaccess.Info, enable bool) (*hibernate.Status, error) {
+	b, err := json.Marshal(hibernate.Request{Hibernate: enable})
+	if err != nil {
+		return nil, err
+	}
+	r, err := info.Post("/v1-"+version.Program+"/hibernate", b)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set server hibernation; see server log for details")
+	}
+	status := &hibernate.Status{}
+	if err := json.Unmarshal(r, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func saveEtcdSnapshot(info *clientaccess.Info, nodeName string) error {
+	// retention is set to 0 to disable automatic pruning, same as an on-demand snapshot save
+	sr := &etcd.SnapshotRequest{
+		Operation: etcd.SnapshotOperationSave,
+		Name:      []string{"hibernate-" + nodeName},
+		Retention: ptr.To(0),
+	}
+	b, err := json.Marshal(sr)
+	if err != nil {
+		return err
+	}
+	r, err := info.Post("/db/snapshot", b, clientaccess.WithTimeout(snapshotTimeout))
+	if err != nil {
+		return errors.Wrap(err, "server is hibernating, but failed to take etcd snapshot; see server log for details")
+	}
+	resp := &managed.SnapshotResult{}
+	if err := json.Unmarshal(r, resp); err != nil {
+		return err
+	}
+	for _, name := range resp.Created {
+		logrus.Infof("Snapshot %s saved.", name)
+	}
+	return nil
+}
+
+// checkpointSQLite moves the content of the sqlite write-ahead log into the database, if the server
+// is using sqlite, so that the database file is consistent on its own while the host is suspended.
+func checkpointSQLite(dataDir string) error {
+	dbFile := filepath.Join(dataDir, "server", "db", "state.db")
+	if _, err := os.Stat(dbFile); err != nil {
+		return nil
+	}
+	db, err := sql.Open("sqlite3", dbFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var busy, walPages, checkpointed int
+	if err := db.QueryRow("PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &walPages, &checkpointed); err != nil {
+		return errors.Wrap(err, "failed to checkpoint sqlite database")
+	}
+	if busy != 0 {
+		logrus.Infof("Sqlite database is busy; %d of %d pages checkpointed", checkpointed, walPages)
+	} else {
+		logrus.Info("Sqlite database checkpointed")
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package hibernate
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func stopContainers(dataDir string, timeout time.Duration) error {
+	return errors.New("stopping containers is only supported on Linux")
+}
@@ -0,0 +1,112 @@
+//go:build linux
+
+package hibernate
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// stopContainers stops the containers left running after the server has been stopped. Containerd
+// exits with the server, but the shims that run containers do not; the init process of each
+// container is sent SIGTERM, and any that have not exited within the timeout are sent SIGKILL.
+// The shims are then killed, so that the containers are recreated when the server is started.
+func stopContainers(dataDir string, timeout time.Duration) error {
+	shims, children, err := listShims(dataDir)
+	if err != nil {
+		return err
+	}
+	if len(shims) == 0 {
+		return nil
+	}
+	logrus.Infof("Stopping %d container processes", len(children))
+	signalAll(children, syscall.SIGTERM)
+
+	deadline := time.Now().Add(timeout)
+	for len(children) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Second)
+		children = running(children)
+	}
+	if len(children) > 0 {
+		logrus.Warnf("Killing %d container processes that did not exit within %s", len(children), timeout)
+		signalAll(children, syscall.SIGKILL)
+	}
+	signalAll(shims, syscall.SIGKILL)
+	return nil
+}
+
+// listShims returns the pids of the containerd shims run from the data-dir, and the pids of their
+// child processes, which are the init processes of the containers run by the shims.
+func listShims(dataDir string) ([]int, []int, error) {
+	ents, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, nil, err
+	}
+	binDir := filepath.Join(dataDir, "data") + string(filepath.Separator)
+	parents := map[int]int{}
+	shims := []int{}
+	for _, ent := range ents {
+		pid, err := strconv.Atoi(ent.Name())
+		if err != nil {
+			continue
+		}
+		if ppid, err := parentPid(pid); err == nil {
+			parents[pid] = ppid
+		}
+		exe, err := os.Readlink(filepath.Join("/proc", ent.Name(), "exe"))
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(exe, binDir) && strings.HasPrefix(filepath.Base(exe), "containerd-shim") {
+			shims = append(shims, pid)
+		}
+	}
+	children := []int{}
+	for pid, ppid := range parents {
+		for _, shim := range shims {
+			if ppid == shim {
+				children = append(children, pid)
+			}
+		}
+	}
+	return shims, children, nil
+}
+
+// parentPid returns the parent pid from /proc/<pid>/stat. The process name field may contain
+// spaces, so fields are counted from the closing parenthesis that ends it.
+func parentPid(pid int) (int, error) {
+	b, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, err
+	}
+	stat := string(b)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	if len(fields) < 2 {
+		return 0, os.ErrInvalid
+	}
+	return strconv.Atoi(fields[1])
+}
+
+func signalAll(pids []int, sig syscall.Signal) {
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, sig); err != nil && err != syscall.ESRCH {
+			logrus.Warnf("Failed to send %s to process %d: %v", sig, pid, err)
+		}
+	}
+}
+
+func running(pids []int) []int {
+	alive := []int{}
+	for _, pid := range pids {
+		if syscall.Kill(pid, 0) == nil {
+			alive = append(alive, pid)
+		}
+	}
+	return alive
+}
@@ -0,0 +1,18 @@
+//go:build linux
+
+package hibernate
+
+import (
+	"os"
+	"testing"
+)
+
+func Test_UnitParentPid(t *testing.T) {
+	got, err := parentPid(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != os.Getppid() {
+		t.Errorf("parentPid() = %d, want %d", got, os.Getppid())
+	}
+}
@@ -0,0 +1,65 @@
+package images
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/images"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/urfave/cli"
+)
+
+func Run(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return run(app, &cmds.ServerConfig, &cmds.ImagesConfig)
+}
+
+func run(app *cli.Context, cfg *cmds.Server, imagesCfg *cmds.Images) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	overrides, err := images.LoadOverrides(cfg.SystemImagesFile, cfg.SystemImages.Value())
+	if err != nil {
+		return err
+	}
+	list, err := images.List(cfg.SystemDefaultRegistry, overrides)
+	if err != nil {
+		return err
+	}
+
+	switch imagesCfg.Output {
+	case "json":
+		return json.NewEncoder(os.Stdout).Encode(list)
+	case "text":
+		format := "%s\t%s\t%s\t%s\n"
+		w := tabwriter.NewWriter(os.Stdout, 10, 4, 3, ' ', 0)
+		defer w.Flush()
+
+		fmt.Fprintf(w, format, "COMPONENT", "IMAGE", "DIGEST", "PLATFORMS")
+		for _, image := range list {
+			platforms := make([]string, 0, len(image.Platforms))
+			for platform := range image.Platforms {
+				platforms = append(platforms, platform)
+			}
+			sort.Strings(platforms)
+			fmt.Fprintf(w, format, image.Component, image.Image, orNone(image.Digest), orNone(strings.Join(platforms, ",")))
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q: must be text or json", imagesCfg.Output)
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -0,0 +1,147 @@
+package maintenance
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/clientaccess"
+	"github.com/k3s-io/k3s/pkg/cluster/managed"
+	"github.com/k3s-io/k3s/pkg/datadir"
+	"github.com/k3s-io/k3s/pkg/etcd"
+	"github.com/k3s-io/k3s/pkg/maintenance"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"k8s.io/utils/ptr"
+)
+
+// snapshotTimeout is the time allowed for the etcd snapshot taken when entering maintenance mode.
+const snapshotTimeout = 5 * time.Minute
+
+func Enable(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return enable(app, &cmds.ServerConfig, &cmds.AgentConfig, &cmds.MaintenanceConfig)
+}
+
+func enable(app *cli.Context, cfg *cmds.Server, agentCfg *cmds.Agent, maintenanceCfg *cmds.Maintenance) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	info, nodeName, err := commandSetup(cfg, agentCfg, maintenanceCfg)
+	if err != nil {
+		return err
+	}
+
+	status, err := setMaintenance(info, nodeName, true)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Node %s is in maintenance mode since %s", status.Node, status.Since)
+
+	if status.Etcd && !maintenanceCfg.SkipSnapshot {
+		// retention is set to 0 to disable automatic pruning, same as an on-demand snapshot save
+		sr := &etcd.SnapshotRequest{
+			Operation: etcd.SnapshotOperationSave,
+			Name:      []string{"maintenance-" + status.Node},
+			Retention: ptr.To(0),
+		}
+		b, err := json.Marshal(sr)
+		if err != nil {
+			return err
+		}
+		r, err := info.Post("/db/snapshot", b, clientaccess.WithTimeout(snapshotTimeout))
+		if err != nil {
+			return errors.Wrap(err, "node is in maintenance mode, but failed to take etcd snapshot; see server log for details")
+		}
+		resp := &managed.SnapshotResult{}
+		if err := json.Unmarshal(r, resp); err != nil {
+			return err
+		}
+		for _, name := range resp.Created {
+			logrus.Infof("Snapshot %s saved.", name)
+		}
+	}
+
+	return nil
+}
+
+func Disable(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return disable(app, &cmds.ServerConfig, &cmds.AgentConfig, &cmds.MaintenanceConfig)
+}
+
+func disable(app *cli.Context, cfg *cmds.Server, agentCfg *cmds.Agent, maintenanceCfg *cmds.Maintenance) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	info, nodeName, err := commandSetup(cfg, agentCfg, maintenanceCfg)
+	if err != nil {
+		return err
+	}
+
+	status, err := setMaintenance(info, nodeName, false)
+	if err != nil {
+		return err
+	}
+	if status.Unschedulable {
+		logrus.Infof("Node %s is no longer in maintenance mode, and was left cordoned as it was cordoned before maintenance mode was enabled", status.Node)
+	} else {
+		logrus.Infof("Node %s is no longer in maintenance mode", status.Node)
+	}
+	return nil
+}
+
+// commandSetup returns a client for the server, and the name of the node to manage. The token
+// is read from the data-dir if not set, and the node name defaults to the local hostname.
+func commandSetup(cfg *cmds.Server, agentCfg *cmds.Agent, maintenanceCfg *cmds.Maintenance) (*clientaccess.Info, string, error) {
+	if maintenanceCfg.Token == "" {
+		dataDir, err := datadir.Resolve(cfg.DataDir)
+		if err != nil {
+			return nil, "", err
+		}
+		tokenByte, err := os.ReadFile(filepath.Join(dataDir, "server", "token"))
+		if err != nil {
+			return nil, "", errors.Wrap(err, "failed to read server token; set --token when not running on a server")
+		}
+		maintenanceCfg.Token = string(bytes.TrimRight(tokenByte, "\n"))
+	}
+
+	nodeName := agentCfg.NodeName
+	if nodeName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, "", err
+		}
+		nodeName = hostname
+	}
+
+	info, err := clientaccess.ParseAndValidateToken(maintenanceCfg.ServerURL, maintenanceCfg.Token, clientaccess.WithUser("server"))
+	return info, strings.ToLower(nodeName), err
+}
+
+func setMaintenance(info *clientaccess.Info, nodeName string, enable bool) (*maintenance.Status, error) {
+	b, err := json.Marshal(maintenance.Request{Node: nodeName, Enable: enable})
+	if err != nil {
+		return nil, err
+	}
+	r, err := info.Post("/v1-"+version.Program+"/maintenance", b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to set maintenance mode on node %s; see server log for details", nodeName)
+	}
+	status := &maintenance.Status{}
+	if err := json.Unmarshal(r, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
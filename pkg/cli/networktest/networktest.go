@@ -0,0 +1,214 @@
+package networktest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/images"
+	"github.com/k3s-io/k3s/pkg/networktest"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/rancher/wrangler/v3/pkg/signals"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	nodeutil "k8s.io/component-helpers/node/util"
+)
+
+// pollInterval is the interval at which test pods are checked while waiting for them.
+const pollInterval = 2 * time.Second
+
+func Run(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return run(app, &cmds.NetworkTestConfig)
+}
+
+func run(app *cli.Context, cfg *cmds.NetworkTest) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	image := cfg.Image
+	if image == "" {
+		image = images.Reference(images.LocalPathHelper, "", nil)
+	}
+	client, err := util.GetClientSet(util.GetKubeConfigPath(cfg.Kubeconfig))
+	if err != nil {
+		return err
+	}
+	ctx := signals.SetupSignalContext()
+
+	nodeList, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	var nodes []networktest.Node
+	var nodeNames []string
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		if _, condition := nodeutil.GetNodeCondition(&node.Status, corev1.NodeReady); condition == nil || condition.Status != corev1.ConditionTrue {
+			logrus.Warnf("Skipping node %s as it is not ready", node.Name)
+			continue
+		}
+		nodes = append(nodes, networktest.NodeFromObject(node))
+		nodeNames = append(nodeNames, node.Name)
+	}
+	if len(nodes) == 0 {
+		return errors.New("no nodes are ready")
+	}
+	sort.Strings(nodeNames)
+
+	namespaces := client.CoreV1().Namespaces()
+	if _, err := namespaces.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: networktest.Namespace}}, metav1.CreateOptions{}); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("namespace %s already exists; delete it with 'kubectl delete namespace %s' before running the test again", networktest.Namespace, networktest.Namespace)
+		}
+		return err
+	}
+	if cfg.Keep {
+		logrus.Infof("Test pods will be left in namespace %s", networktest.Namespace)
+	} else {
+		defer func() {
+			if err := namespaces.Delete(context.Background(), networktest.Namespace, metav1.DeleteOptions{}); err != nil {
+				logrus.Warnf("Failed to delete namespace %s: %v", networktest.Namespace, err)
+			}
+		}()
+	}
+
+	targets, serviceIP, err := startServers(ctx, client, image, cfg.HostPort, cfg.Timeout, nodeNames)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Running checks from %d nodes", len(nodeNames))
+	var clients []*corev1.Pod
+	for _, node := range nodeNames {
+		for _, pod := range networktest.ClientPods(image, node, serviceIP, cfg.HostPort, targets) {
+			if _, err := client.CoreV1().Pods(networktest.Namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+				return err
+			}
+			clients = append(clients, pod)
+		}
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	var results []networktest.Result
+	for _, pod := range clients {
+		// Checks that could not be run are shown as missing from the matrix.
+		podResults, err := clientResults(waitCtx, client, pod.Name, pod.Spec.NodeName)
+		if err != nil {
+			logrus.Warnf("Failed to get results from node %s: %v", pod.Spec.NodeName, err)
+		}
+		results = append(results, podResults...)
+	}
+
+	if err := networktest.WriteMatrix(os.Stdout, nodeNames, results); err != nil {
+		return err
+	}
+	causes := networktest.Diagnose(nodes, results)
+	if len(causes) == 0 {
+		fmt.Println("\nAll checks passed")
+		return nil
+	}
+	fmt.Println("\nLikely causes:")
+	for _, cause := range causes {
+		fmt.Println("  - " + cause)
+	}
+	return errors.New("network test failed")
+}
+
+// startServers creates the server pods and service, and waits for the server pods to be ready on
+// every node, or for the timeout to expire. It returns the servers to check from each node, and the
+// service ClusterIP.
+func startServers(ctx context.Context, client clientset.Interface, image string, hostPort int, timeout time.Duration, nodeNames []string) ([]networktest.Target, string, error) {
+	service, err := client.CoreV1().Services(networktest.Namespace).Create(ctx, networktest.ServerService(), metav1.CreateOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	for _, hostNetwork := range []bool{false, true} {
+		if _, err := client.AppsV1().DaemonSets(networktest.Namespace).Create(ctx, networktest.ServerDaemonSet(image, hostNetwork, hostPort), metav1.CreateOptions{}); err != nil {
+			return nil, "", err
+		}
+	}
+
+	logrus.Infof("Waiting for test servers to be ready on %d nodes", len(nodeNames))
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	targets := map[string]*networktest.Target{}
+	err = wait.PollUntilContextCancel(waitCtx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		pods, err := client.CoreV1().Pods(networktest.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return false, nil
+		}
+		for _, node := range nodeNames {
+			targets[node] = &networktest.Target{Node: node}
+		}
+		for _, pod := range pods.Items {
+			target := targets[pod.Spec.NodeName]
+			if target == nil || !podReady(&pod) {
+				continue
+			}
+			if pod.Spec.HostNetwork {
+				target.HostIP = pod.Status.HostIP
+			} else {
+				target.PodIP = pod.Status.PodIP
+			}
+		}
+		for _, target := range targets {
+			if target.PodIP == "" || target.HostIP == "" {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		// Check the servers that are ready; checks to other servers are shown as missing from the matrix.
+		logrus.Warnf("Test servers are not ready on all nodes: %v", err)
+	}
+
+	var result []networktest.Target
+	for _, node := range nodeNames {
+		if target := targets[node]; target != nil {
+			result = append(result, *target)
+		}
+	}
+	return result, service.Spec.ClusterIP, nil
+}
+
+// clientResults waits for a client pod to complete, and returns the results from its log.
+func clientResults(ctx context.Context, client clientset.Interface, name, node string) ([]networktest.Result, error) {
+	pods := client.CoreV1().Pods(networktest.Namespace)
+	if err := wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		pod, err := pods.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed, nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "checks from node %s did not complete", node)
+	}
+	logs, err := pods.GetLogs(name, &corev1.PodLogOptions{}).Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer logs.Close()
+	return networktest.ParseResults(node, logs)
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+package nodeshell
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/agent/cri"
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/images"
+	"github.com/k3s-io/k3s/pkg/instance"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	"k8s.io/kubectl/pkg/util/term"
+	"k8s.io/kubernetes/pkg/cluster/ports"
+)
+
+// hostMountPath is the path within the debug container that the host filesystem is mounted at.
+const hostMountPath = "/host"
+
+// nodeShellLabel is set on debug pods, so that any left behind can be found with crictl.
+var nodeShellLabel = version.Program + ".io/node-shell"
+
+// kubeletHealthzURLs are the kubelet's healthz endpoints, bound to the loopback address of the node's
+// primary address family.
+var kubeletHealthzURLs = []string{
+	"http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(ports.KubeletHealthzPort)) + "/healthz",
+	"http://" + net.JoinHostPort("::1", strconv.Itoa(ports.KubeletHealthzPort)) + "/healthz",
+}
+
+func Run(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return run(app, &cmds.AgentConfig, &cmds.NodeShellConfig)
+}
+
+func run(app *cli.Context, agentCfg *cmds.Agent, cfg *cmds.NodeShell) error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("%s is only supported on Linux", cmds.NodeShellCommand)
+	}
+	command := []string(app.Args())
+	if len(command) == 0 {
+		command = []string{"sh"}
+	}
+	image := cfg.Image
+	if image == "" {
+		image = images.Reference(images.LocalPathHelper, "", nil)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	tty := term.TTY{In: os.Stdin, Out: os.Stdout, Raw: true}
+	isTerminal := tty.IsTerminalIn()
+
+	ctx := context.Background()
+	// The kubelet removes pod sandboxes that it did not create, so the debug pod can only be created
+	// directly through the container runtime while the kubelet is not running.
+	if kubeletRunning(ctx) {
+		return fmt.Errorf("the kubelet is running on this node, and would remove the debug pod; use 'kubectl debug node/%s -it --profile=sysadmin --image=%s' instead", hostname, image)
+	}
+	conn, err := cri.Connection(ctx, runtimeAddress(agentCfg))
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the container runtime")
+	}
+	defer conn.Close()
+	runtimeClient := runtimeapi.NewRuntimeServiceClient(conn)
+	imageClient := runtimeapi.NewImageServiceClient(conn)
+
+	if err := ensureImage(ctx, imageClient, image); err != nil {
+		return err
+	}
+
+	sandboxConfig := podSandboxConfig(hostname)
+	sandbox, err := runtimeClient.RunPodSandbox(ctx, &runtimeapi.RunPodSandboxRequest{Config: sandboxConfig})
+	if err != nil {
+		return errors.Wrap(err, "failed to create debug pod")
+	}
+	if cfg.Keep {
+		logrus.Infof("Debug pod %s will be left running; remove it with 'crictl rmp -f %s'", sandboxConfig.Metadata.Name, sandbox.PodSandboxId)
+	} else {
+		defer removePod(runtimeClient, sandbox.PodSandboxId)
+	}
+
+	container, err := runtimeClient.CreateContainer(ctx, &runtimeapi.CreateContainerRequest{
+		PodSandboxId:  sandbox.PodSandboxId,
+		Config:        containerConfig(image, command, isTerminal),
+		SandboxConfig: sandboxConfig,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create debug container")
+	}
+	if _, err := runtimeClient.StartContainer(ctx, &runtimeapi.StartContainerRequest{ContainerId: container.ContainerId}); err != nil {
+		return errors.Wrap(err, "failed to start debug container")
+	}
+
+	if isTerminal {
+		fmt.Fprintf(os.Stderr, "Host filesystem is mounted at %s; run 'chroot %s' for a shell in the host root. If you don't see a command prompt, try pressing enter.\n", hostMountPath, hostMountPath)
+	}
+	if err := attach(ctx, runtimeClient, container.ContainerId, tty, isTerminal); err != nil {
+		return err
+	}
+
+	status, err := runtimeClient.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{ContainerId: container.ContainerId})
+	if err != nil {
+		return err
+	}
+	if code := status.Status.GetExitCode(); status.Status.GetState() == runtimeapi.ContainerState_CONTAINER_EXITED && code != 0 {
+		return fmt.Errorf("command exited with code %d", code)
+	}
+	return nil
+}
+
+// kubeletRunning returns true if the kubelet is answering on any of its healthz endpoints.
+func kubeletRunning(ctx context.Context) bool {
+	client := &http.Client{Timeout: 2 * time.Second}
+	for _, u := range kubeletHealthzURLs {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			continue
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		return true
+	}
+	return false
+}
+
+// runtimeAddress returns the path to the CRI socket: the container-runtime-endpoint if set, or the
+// socket of the embedded containerd.
+func runtimeAddress(agentCfg *cmds.Agent) string {
+	if agentCfg.ContainerRuntimeEndpoint != "" {
+		return strings.TrimPrefix(agentCfg.ContainerRuntimeEndpoint, "unix://")
+	}
+	return filepath.Join(instance.RunDir(agentCfg.InstanceName), "containerd", "containerd.sock")
+}
+
+// ensureImage pulls the image if it is not already present. Images must be imported ahead of time on
+// nodes without access to a registry.
+func ensureImage(ctx context.Context, imageClient runtimeapi.ImageServiceClient, image string) error {
+	spec := &runtimeapi.ImageSpec{Image: image}
+	status, err := imageClient.ImageStatus(ctx, &runtimeapi.ImageStatusRequest{Image: spec})
+	if err != nil {
+		return err
+	}
+	if status.Image != nil {
+		return nil
+	}
+	logrus.Infof("Pulling image %s", image)
+	if _, err := imageClient.PullImage(ctx, &runtimeapi.PullImageRequest{Image: spec}); err != nil {
+		return errors.Wrapf(err, "failed to pull image %s; import it or set --image to an image that is present on the node", image)
+	}
+	return nil
+}
+
+// podSandboxConfig returns the config for a privileged pod that shares the host network, PID and IPC
+// namespaces, equivalent to the pods created by 'kubectl debug node/'.
+func podSandboxConfig(hostname string) *runtimeapi.PodSandboxConfig {
+	return &runtimeapi.PodSandboxConfig{
+		Metadata: &runtimeapi.PodSandboxMetadata{
+			Name:      "node-debugger-" + hostname + "-" + rand.String(5),
+			Uid:       string(uuid.NewUUID()),
+			Namespace: "default",
+		},
+		Labels: map[string]string{nodeShellLabel: "true"},
+		Linux: &runtimeapi.LinuxPodSandboxConfig{
+			SecurityContext: &runtimeapi.LinuxSandboxSecurityContext{
+				NamespaceOptions: hostNamespaces(),
+				Privileged:       true,
+			},
+		},
+	}
+}
+
+// containerConfig returns the config for a privileged container that runs the command, with the host
+// filesystem mounted at hostMountPath.
+func containerConfig(image string, command []string, tty bool) *runtimeapi.ContainerConfig {
+	return &runtimeapi.ContainerConfig{
+		Metadata:  &runtimeapi.ContainerMetadata{Name: "debugger"},
+		Image:     &runtimeapi.ImageSpec{Image: image},
+		Command:   command,
+		Stdin:     true,
+		StdinOnce: true,
+		Tty:       tty,
+		Labels:    map[string]string{nodeShellLabel: "true"},
+		Mounts: []*runtimeapi.Mount{{
+			ContainerPath: hostMountPath,
+			HostPath:      "/",
+			Propagation:   runtimeapi.MountPropagation_PROPAGATION_HOST_TO_CONTAINER,
+		}},
+		Linux: &runtimeapi.LinuxContainerConfig{
+			SecurityContext: &runtimeapi.LinuxContainerSecurityContext{
+				NamespaceOptions: hostNamespaces(),
+				Privileged:       true,
+			},
+		},
+	}
+}
+
+func hostNamespaces() *runtimeapi.NamespaceOption {
+	return &runtimeapi.NamespaceOption{
+		Network: runtimeapi.NamespaceMode_NODE,
+		Pid:     runtimeapi.NamespaceMode_NODE,
+		Ipc:     runtimeapi.NamespaceMode_NODE,
+	}
+}
+
+// attach streams stdin, stdout and stderr to the container until its command exits, in the same way
+// as crictl attach.
+func attach(ctx context.Context, runtimeClient runtimeapi.RuntimeServiceClient, containerID string, tty term.TTY, isTerminal bool) error {
+	resp, err := runtimeClient.Attach(ctx, &runtimeapi.AttachRequest{
+		ContainerId: containerID,
+		Stdin:       true,
+		Tty:         isTerminal,
+		Stdout:      true,
+		Stderr:      !isTerminal,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to attach to debug container")
+	}
+	u, err := url.Parse(resp.Url)
+	if err != nil {
+		return err
+	}
+	executor, err := remotecommand.NewSPDYExecutor(&rest.Config{TLSClientConfig: rest.TLSClientConfig{Insecure: true}}, "POST", u)
+	if err != nil {
+		return err
+	}
+
+	options := remotecommand.StreamOptions{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Tty:    isTerminal,
+	}
+	if !isTerminal {
+		options.Stderr = os.Stderr
+		return executor.StreamWithContext(ctx, options)
+	}
+	options.TerminalSizeQueue = tty.MonitorSize(tty.GetSize())
+	return tty.Safe(func() error { return executor.StreamWithContext(ctx, options) })
+}
+
+// removePod stops and removes the debug pod and its container.
+func removePod(runtimeClient runtimeapi.RuntimeServiceClient, podID string) {
+	ctx := context.Background()
+	if _, err := runtimeClient.StopPodSandbox(ctx, &runtimeapi.StopPodSandboxRequest{PodSandboxId: podID}); err != nil {
+		logrus.Warnf("Failed to stop debug pod %s: %v", podID, err)
+		return
+	}
+	if _, err := runtimeClient.RemovePodSandbox(ctx, &runtimeapi.RemovePodSandboxRequest{PodSandboxId: podID}); err != nil {
+		logrus.Warnf("Failed to remove debug pod %s: %v", podID, err)
+	}
+}
@@ -0,0 +1,79 @@
+package nodeshell
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+func Test_UnitRuntimeAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		agentCfg cmds.Agent
+		want     string
+	}{
+		{
+			name: "Embedded containerd",
+			want: "/run/k3s/containerd/containerd.sock",
+		},
+		{
+			name:     "External runtime endpoint",
+			agentCfg: cmds.Agent{ContainerRuntimeEndpoint: "unix:///var/run/crio/crio.sock"},
+			want:     "/var/run/crio/crio.sock",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runtimeAddress(&tt.agentCfg); got != tt.want {
+				t.Errorf("runtimeAddress() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_UnitDebugPodConfig(t *testing.T) {
+	sandboxConfig := podSandboxConfig("node-1")
+	if name := sandboxConfig.Metadata.Name; !strings.HasPrefix(name, "node-debugger-node-1-") {
+		t.Errorf("pod name = %s, want node-debugger-node-1- prefix", name)
+	}
+	if !sandboxConfig.Linux.SecurityContext.Privileged {
+		t.Errorf("pod is not privileged")
+	}
+
+	config := containerConfig("busybox", []string{"sh"}, true)
+	if !config.Linux.SecurityContext.Privileged {
+		t.Errorf("container is not privileged")
+	}
+	for _, namespaces := range []*runtimeapi.NamespaceOption{sandboxConfig.Linux.SecurityContext.NamespaceOptions, config.Linux.SecurityContext.NamespaceOptions} {
+		if namespaces.Network != runtimeapi.NamespaceMode_NODE || namespaces.Pid != runtimeapi.NamespaceMode_NODE || namespaces.Ipc != runtimeapi.NamespaceMode_NODE {
+			t.Errorf("namespaces = %v, want host network, PID and IPC namespaces", namespaces)
+		}
+	}
+	if len(config.Mounts) != 1 || config.Mounts[0].HostPath != "/" || config.Mounts[0].ContainerPath != hostMountPath {
+		t.Errorf("mounts = %v, want host root at %s", config.Mounts, hostMountPath)
+	}
+}
+
+func Test_UnitKubeletRunning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	stopped := httptest.NewServer(http.NotFoundHandler())
+	stopped.Close()
+
+	defer func(urls []string) { kubeletHealthzURLs = urls }(kubeletHealthzURLs)
+	kubeletHealthzURLs = []string{stopped.URL + "/healthz"}
+	if kubeletRunning(context.Background()) {
+		t.Errorf("kubeletRunning() = true with no kubelet listening, want false")
+	}
+	kubeletHealthzURLs = []string{stopped.URL + "/healthz", server.URL + "/healthz"}
+	if !kubeletRunning(context.Background()) {
+		t.Errorf("kubeletRunning() = false with kubelet listening, want true")
+	}
+}
@@ -0,0 +1,80 @@
+package resetidentity
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/datadir"
+	"github.com/k3s-io/k3s/pkg/identity"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"k8s.io/kubernetes/pkg/cluster/ports"
+)
+
+// supervisorPort is the default port of the supervisor, which a server listens on.
+const supervisorPort = 6443
+
+func Run(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return run(app, &cmds.ServerConfig, &cmds.ResetIdentityConfig)
+}
+
+func run(app *cli.Context, cfg *cmds.Server, resetCfg *cmds.ResetIdentity) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	dataDir, err := datadir.LocalHome(cfg.DataDir, resetCfg.Rootless)
+	if err != nil {
+		return err
+	}
+
+	if resetCfg.AcceptHost {
+		if err := identity.Record(dataDir, identity.Current()); err != nil {
+			return err
+		}
+		logrus.Infof("Recorded this host as the owner of data-dir %s", dataDir)
+		return nil
+	}
+
+	for _, port := range []int{ports.KubeletPort, supervisorPort} {
+		if listening(port) {
+			return fmt.Errorf("port %d is in use; %s must be stopped before its identity is reset", port, version.Program)
+		}
+	}
+
+	etcdDataDir := resetCfg.EtcdDataDir
+	if etcdDataDir != "" {
+		if etcdDataDir, err = filepath.Abs(etcdDataDir); err != nil {
+			return err
+		}
+	}
+	dest, err := identity.Reset(dataDir, identity.NodePasswordFile(dataDir, resetCfg.Rootless), etcdDataDir, identity.Current())
+	if err != nil {
+		return err
+	}
+	if dest == "" {
+		logrus.Infof("No identity material found in data-dir %s", dataDir)
+		return nil
+	}
+	logrus.Infof("Previous identity material moved to %s; new certificates, keys, and tokens will be generated when %s is started", dest, version.Program)
+	logrus.Warnf("A token set in the %s configuration or environment is not changed, and must be changed there if it is shared with other hosts", version.Program)
+	return nil
+}
+
+// listening returns true if a process is accepting connections on the port on the loopback address.
+func listening(port int) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
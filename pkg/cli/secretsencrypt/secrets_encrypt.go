@@ -12,12 +12,15 @@ import (
 
 	"github.com/k3s-io/k3s/pkg/cli/cmds"
 	"github.com/k3s-io/k3s/pkg/clientaccess"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/daemons/control/deps"
 	"github.com/k3s-io/k3s/pkg/proctitle"
 	"github.com/k3s-io/k3s/pkg/secretsencrypt"
 	"github.com/k3s-io/k3s/pkg/server"
 	"github.com/k3s-io/k3s/pkg/server/handlers"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 	"k8s.io/utils/ptr"
 )
@@ -161,6 +164,9 @@ func Prepare(app *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	if cmds.ServerConfig.EncryptDryRun {
+		return dryRun(info, b)
+	}
 	if err = info.Put("/v1-"+version.Program+"/encrypt/config", b); err != nil {
 		return wrapServerError(err)
 	}
@@ -183,6 +189,9 @@ func Rotate(app *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	if cmds.ServerConfig.EncryptDryRun {
+		return dryRun(info, b)
+	}
 	if err = info.Put("/v1-"+version.Program+"/encrypt/config", b); err != nil {
 		return wrapServerError(err)
 	}
@@ -206,6 +215,9 @@ func Reencrypt(app *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	if cmds.ServerConfig.EncryptDryRun {
+		return dryRun(info, b)
+	}
 	if err = info.Put("/v1-"+version.Program+"/encrypt/config", b); err != nil {
 		return wrapServerError(err)
 	}
@@ -213,6 +225,55 @@ func Reencrypt(app *cli.Context) error {
 	return nil
 }
 
+// dryRun requests the plan for a stage from the server, and prints it without making any changes
+func dryRun(info *clientaccess.Info, request []byte) error {
+	data, err := info.Post("/v1-"+version.Program+"/encrypt/dry-run", request)
+	if err != nil {
+		return wrapServerError(err)
+	}
+	plan := handlers.EncryptionPlan{}
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return err
+	}
+
+	if strings.ToLower(cmds.ServerConfig.EncryptOutput) == "json" {
+		json, err := json.MarshalIndent(plan, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(json))
+		return nil
+	}
+	fmt.Print(formatPlan(plan))
+	return nil
+}
+
+func formatPlan(plan handlers.EncryptionPlan) string {
+	var planOutput string
+	planOutput += fmt.Sprintln("Dry run, no changes made")
+	planOutput += fmt.Sprintln("Requested Stage:", plan.Stage)
+	if plan.Allowed && plan.Error != "" {
+		planOutput += fmt.Sprintf("Stage Allowed: forced, %s\n", plan.Error)
+	} else if plan.Allowed {
+		planOutput += fmt.Sprintln("Stage Allowed: yes")
+	} else {
+		planOutput += fmt.Sprintf("Stage Allowed: no, %s\n", plan.Error)
+	}
+	planOutput += fmt.Sprintln("Secrets To Reencrypt:", plan.Secrets)
+	planOutput += fmt.Sprintln("Estimated Duration:", plan.EstimatedDuration)
+
+	var tabBuffer bytes.Buffer
+	w := tabwriter.NewWriter(&tabBuffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\n")
+	fmt.Fprintf(w, "Server\tStage\tHash Match\n")
+	fmt.Fprintf(w, "------\t-----\t----------\n")
+	for _, s := range plan.Servers {
+		fmt.Fprintf(w, "%s\t%s\t%t\n", s.Name, s.Stage, s.HashMatch)
+	}
+	w.Flush()
+	return planOutput + tabBuffer.String()
+}
+
 func RotateKeys(app *cli.Context) error {
 	if err := cmds.InitLogging(); err != nil {
 		return err
@@ -234,3 +295,80 @@ func RotateKeys(app *cli.Context) error {
 	fmt.Println("keys rotated, reencryption started")
 	return nil
 }
+
+func Export(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	controlConfig, passphrase, err := localPrep(&cmds.ServerConfig)
+	if err != nil {
+		return err
+	}
+	b, err := secretsencrypt.ExportEncryptionConfig(controlConfig.Runtime, passphrase)
+	if err != nil {
+		return err
+	}
+	if cmds.ServerConfig.EncryptFile == "" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	if err := os.WriteFile(cmds.ServerConfig.EncryptFile, b, 0600); err != nil {
+		return err
+	}
+	if passphrase == "" {
+		logrus.Warnf("Exported secrets encryption config to %s without a passphrase; store it securely", cmds.ServerConfig.EncryptFile)
+	}
+	fmt.Println("secrets-encryption config exported to " + cmds.ServerConfig.EncryptFile)
+	return nil
+}
+
+func Import(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	if cmds.ServerConfig.EncryptFile == "" {
+		return errors.New("--file is required")
+	}
+	controlConfig, passphrase, err := localPrep(&cmds.ServerConfig)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(cmds.ServerConfig.EncryptFile)
+	if err != nil {
+		return err
+	}
+	if err := secretsencrypt.ImportEncryptionConfig(controlConfig.Runtime, b, passphrase, cmds.ServerConfig.EncryptForce); err != nil {
+		return err
+	}
+	fmt.Println("secrets-encryption config imported to " + controlConfig.Runtime.EncryptionConfig)
+	return nil
+}
+
+// localPrep resolves the paths to the server's secrets encryption files, and reads the passphrase
+// file if one is set. Export and import operate on local files, so that they can be used when the
+// server is not running.
+func localPrep(cfg *cmds.Server) (*config.Control, string, error) {
+	proctitle.SetProcTitle(os.Args[0] + " secrets-encrypt")
+
+	dataDir, err := server.ResolveDataDir(cfg.DataDir)
+	if err != nil {
+		return nil, "", err
+	}
+	controlConfig := &config.Control{
+		DataDir: dataDir,
+		Runtime: &config.ControlRuntime{},
+	}
+	deps.CreateRuntimeCertFiles(controlConfig)
+
+	passphrase := ""
+	if cfg.EncryptPassphraseFile != "" {
+		b, err := os.ReadFile(cfg.EncryptPassphraseFile)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "failed to read passphrase file")
+		}
+		if passphrase = strings.TrimSpace(string(b)); passphrase == "" {
+			return nil, "", errors.New("passphrase file is empty")
+		}
+	}
+	return controlConfig, passphrase, nil
+}
@@ -6,6 +6,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,17 +15,30 @@ import (
 	"github.com/k3s-io/k3s/pkg/agent"
 	"github.com/k3s-io/k3s/pkg/agent/https"
 	"github.com/k3s-io/k3s/pkg/agent/loadbalancer"
+	"github.com/k3s-io/k3s/pkg/cgroups"
 	"github.com/k3s-io/k3s/pkg/cli/cmds"
 	"github.com/k3s-io/k3s/pkg/clientaccess"
+	"github.com/k3s-io/k3s/pkg/cluster"
+	"github.com/k3s-io/k3s/pkg/clusterdomain"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/datadir"
+	"github.com/k3s-io/k3s/pkg/datadir/encryption"
 	"github.com/k3s-io/k3s/pkg/etcd"
+	"github.com/k3s-io/k3s/pkg/firewall"
+	"github.com/k3s-io/k3s/pkg/identity"
+	"github.com/k3s-io/k3s/pkg/images"
+	"github.com/k3s-io/k3s/pkg/instance"
+	"github.com/k3s-io/k3s/pkg/keystore"
 	k3smetrics "github.com/k3s-io/k3s/pkg/metrics"
+	"github.com/k3s-io/k3s/pkg/notify"
+	"github.com/k3s-io/k3s/pkg/preflight"
 	"github.com/k3s-io/k3s/pkg/proctitle"
 	"github.com/k3s-io/k3s/pkg/profile"
 	"github.com/k3s-io/k3s/pkg/rootless"
 	"github.com/k3s-io/k3s/pkg/server"
 	"github.com/k3s-io/k3s/pkg/spegel"
+	"github.com/k3s-io/k3s/pkg/startup"
+	"github.com/k3s-io/k3s/pkg/tracing"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/k3s-io/k3s/pkg/vpn"
@@ -33,6 +47,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 	etcdversion "go.etcd.io/etcd/api/v3/version"
+	"go.opentelemetry.io/otel/attribute"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilnet "k8s.io/apimachinery/pkg/util/net"
 	kubeapiserverflag "k8s.io/component-base/cli/flag"
@@ -41,15 +56,23 @@ import (
 )
 
 func Run(app *cli.Context) error {
-	return run(app, &cmds.ServerConfig, server.CustomControllers{}, server.CustomControllers{})
+	return run(app, &cmds.ServerConfig, server.CustomControllers{}, server.CustomControllers{}, signals.SetupSignalContext)
 }
 
 func RunWithControllers(app *cli.Context, leaderControllers server.CustomControllers, controllers server.CustomControllers) error {
-	return run(app, &cmds.ServerConfig, leaderControllers, controllers)
+	return run(app, &cmds.ServerConfig, leaderControllers, controllers, signals.SetupSignalContext)
 }
 
-func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomControllers, controllers server.CustomControllers) error {
+// RunWithContext is like RunWithControllers, but stops the server when the provided context
+// is cancelled, instead of on receipt of a signal.
+func RunWithContext(ctx context.Context, app *cli.Context, leaderControllers server.CustomControllers, controllers server.CustomControllers) error {
+	return run(app, &cmds.ServerConfig, leaderControllers, controllers, func() context.Context { return ctx })
+}
+
+func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomControllers, controllers server.CustomControllers, newContext func() context.Context) (rerr error) {
 	var err error
+	defer func() { startup.Failed(rerr) }()
+	startup.SetStartTimes(cmds.StartupTimesFromEnv())
 	// Validate build env
 	cmds.MustValidateGolang()
 
@@ -57,6 +80,12 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 	// database credentials or other secrets.
 	proctitle.SetProcTitle(os.Args[0] + " server")
 
+	if cfg.EtcdArbiter {
+		if err := configureArbiter(app, cfg); err != nil {
+			return err
+		}
+	}
+
 	// If the agent is enabled, evacuate cgroup v2 before doing anything else that may fork.
 	// If the agent is disabled, we don't need to bother doing this as it is only the kubelet
 	// that cares about cgroups.
@@ -76,6 +105,19 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 		return fmt.Errorf("server must run as root, or with --rootless and/or --disable-agent")
 	}
 
+	// unsealed keys are stored under the runtime directory, and sealing with the TPM requires root
+	if cfg.Rootless && cfg.KeyStorage == keystore.TPM {
+		return fmt.Errorf("key-storage=%s is not supported with --rootless", keystore.TPM)
+	}
+
+	if err := startup.WaitForGates(context.Background(), cmds.AgentConfig.StartupGates.Value(), cmds.AgentConfig.StartupGateTimeout); err != nil {
+		return err
+	}
+
+	if err := startup.WaitForPlausibleClock(context.Background(), startup.ClockFloor(), cmds.AgentConfig.InsecureSkewTolerance); err != nil {
+		return err
+	}
+
 	if cfg.Rootless {
 		dataDir, err := datadir.LocalHome(cfg.DataDir, true)
 		if err != nil {
@@ -137,8 +179,17 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 	serverConfig.ControlConfig.ServiceLBNamespace = cfg.ServiceLBNamespace
 	serverConfig.ControlConfig.SANs = util.SplitStringSlice(cfg.TLSSan)
 	serverConfig.ControlConfig.SANSecurity = cfg.TLSSanSecurity
+	serverConfig.ControlConfig.CACertDuration = cfg.CACertDuration
+	serverConfig.ControlConfig.ServerCertDuration = cfg.ServerCertDuration
+	serverConfig.ControlConfig.ClientCertDuration = cfg.ClientCertDuration
+	if err := validateCertDurations(&serverConfig.ControlConfig); err != nil {
+		return err
+	}
 	serverConfig.ControlConfig.BindAddress = cmds.AgentConfig.BindAddress
 	serverConfig.ControlConfig.SupervisorPort = cfg.SupervisorPort
+	serverConfig.ControlConfig.SupervisorLegacyPort = cfg.SupervisorLegacyPort
+	serverConfig.ControlConfig.SupervisorAuthMaxFailures = cfg.SupervisorAuthMaxFailures
+	serverConfig.ControlConfig.SupervisorAuthLockout = cfg.SupervisorAuthLockout
 	serverConfig.ControlConfig.HTTPSPort = cfg.HTTPSPort
 	serverConfig.ControlConfig.APIServerPort = cfg.APIServerPort
 	serverConfig.ControlConfig.APIServerBindAddress = cfg.APIServerBindAddress
@@ -146,6 +197,9 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 	serverConfig.ControlConfig.ExtraControllerArgs = cfg.ExtraControllerArgs
 	serverConfig.ControlConfig.ExtraEtcdArgs = cfg.ExtraEtcdArgs
 	serverConfig.ControlConfig.ExtraSchedulerAPIArgs = cfg.ExtraSchedulerArgs
+	if err := clusterdomain.Validate(cfg.ClusterDomain); err != nil {
+		return err
+	}
 	serverConfig.ControlConfig.ClusterDomain = cfg.ClusterDomain
 	serverConfig.ControlConfig.Datastore.NotifyInterval = 5 * time.Second
 	serverConfig.ControlConfig.Datastore.EmulatedETCDVersion = etcdversion.Version
@@ -159,23 +213,71 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 	serverConfig.ControlConfig.FlannelBackend = cfg.FlannelBackend
 	serverConfig.ControlConfig.FlannelIPv6Masq = cfg.FlannelIPv6Masq
 	serverConfig.ControlConfig.FlannelExternalIP = cfg.FlannelExternalIP
+	serverConfig.ControlConfig.NodeCIDRMaskSizeIPv4 = cfg.NodeCIDRMaskSizeIPv4
+	serverConfig.ControlConfig.NodeCIDRMaskSizeIPv6 = cfg.NodeCIDRMaskSizeIPv6
 	serverConfig.ControlConfig.EgressSelectorMode = cfg.EgressSelectorMode
+	serverConfig.ControlConfig.EgressSelectorTypeModes = map[string]string{}
+	for _, typeMode := range util.SplitStringSlice(cfg.EgressSelectorTypeModes) {
+		egressType, mode, ok := strings.Cut(typeMode, "=")
+		if !ok {
+			return fmt.Errorf("invalid egress-selector-type-mode %s: must be in the format type=mode", typeMode)
+		}
+		serverConfig.ControlConfig.EgressSelectorTypeModes[egressType] = mode
+	}
+	serverConfig.ControlConfig.TunnelBandwidthLimits, err = config.ParseTunnelBandwidthLimits(util.SplitStringSlice(cfg.TunnelBandwidthLimits))
+	if err != nil {
+		return err
+	}
 	serverConfig.ControlConfig.ExtraCloudControllerArgs = cfg.ExtraCloudControllerArgs
+	if err := applyAgentProfile(cfg, &cmds.AgentConfig); err != nil {
+		return err
+	}
 	serverConfig.ControlConfig.DisableCCM = cfg.DisableCCM
 	serverConfig.ControlConfig.DisableNPC = cfg.DisableNPC
 	serverConfig.ControlConfig.DisableHelmController = cfg.DisableHelmController
+	serverConfig.ControlConfig.TokenViewer = cfg.TokenViewer
 	serverConfig.ControlConfig.DisableKubeProxy = cfg.DisableKubeProxy
 	serverConfig.ControlConfig.DisableETCD = cfg.DisableETCD
 	serverConfig.ControlConfig.DisableAPIServer = cfg.DisableAPIServer
 	serverConfig.ControlConfig.DisableScheduler = cfg.DisableScheduler
 	serverConfig.ControlConfig.DisableControllerManager = cfg.DisableControllerManager
 	serverConfig.ControlConfig.DisableAgent = cfg.DisableAgent
+	if cfg.DisableAgent {
+		if ignored := configureAgentless(app, &serverConfig.ControlConfig); len(ignored) > 0 {
+			msg := "Agent is disabled, ignoring agent flags: --" + strings.Join(ignored, ", --")
+			logrus.Warn(msg)
+			startup.Warn(msg)
+		}
+	}
 	serverConfig.ControlConfig.EmbeddedRegistry = cfg.EmbeddedRegistry
+	if cfg.EmbeddedRegistryPin && !cfg.EmbeddedRegistry {
+		return errors.New("embedded-registry-pin-digests requires embedded-registry")
+	}
+	serverConfig.ControlConfig.EmbeddedRegistryPin = cfg.EmbeddedRegistryPin
 	serverConfig.ControlConfig.ClusterInit = cfg.ClusterInit
 	serverConfig.ControlConfig.EncryptSecrets = cfg.EncryptSecrets
 	serverConfig.ControlConfig.EtcdExposeMetrics = cfg.EtcdExposeMetrics
+	if cfg.EtcdIOWeight < 0 || cfg.EtcdIOWeight > 10000 {
+		return fmt.Errorf("invalid etcd-io-weight %d: must be between 1 and 10000", cfg.EtcdIOWeight)
+	}
+	serverConfig.ControlConfig.EtcdIOWeight = cfg.EtcdIOWeight
+	serverConfig.ControlConfig.EtcdFsyncWarning = cfg.EtcdFsyncWarning
+	if cfg.EtcdDataDir != "" {
+		serverConfig.ControlConfig.EtcdDataDir, err = filepath.Abs(cfg.EtcdDataDir)
+		if err != nil {
+			return err
+		}
+	}
 	serverConfig.ControlConfig.EtcdDisableSnapshots = cfg.EtcdDisableSnapshots
 	serverConfig.ControlConfig.SupervisorMetrics = cfg.SupervisorMetrics
+	serverConfig.ControlConfig.DRA = cfg.DRA
+	serverConfig.ControlConfig.ControlPlaneExecMode = cfg.ControlPlaneExecMode
+	serverConfig.ControlConfig.ResourceProfile = cfg.ResourceProfile
+	serverConfig.ControlConfig.EnablePProf = cmds.AgentConfig.EnablePProf
+	if err := util.ValidateTunnelKeepAliveTimeout(cmds.AgentConfig.TunnelKeepAliveTimeout); err != nil {
+		return err
+	}
+	serverConfig.ControlConfig.TunnelKeepAliveTimeout = cmds.AgentConfig.TunnelKeepAliveTimeout
 	serverConfig.ControlConfig.VLevel = cmds.LogConfig.VLevel
 	serverConfig.ControlConfig.VModule = cmds.LogConfig.VModule
 
@@ -183,6 +285,7 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 		serverConfig.ControlConfig.EtcdSnapshotCompress = cfg.EtcdSnapshotCompress
 		serverConfig.ControlConfig.EtcdSnapshotName = cfg.EtcdSnapshotName
 		serverConfig.ControlConfig.EtcdSnapshotCron = cfg.EtcdSnapshotCron
+		serverConfig.ControlConfig.EtcdIncrementalCron = cfg.EtcdIncrementalCron
 		serverConfig.ControlConfig.EtcdSnapshotDir = cfg.EtcdSnapshotDir
 		serverConfig.ControlConfig.EtcdSnapshotRetention = cfg.EtcdSnapshotRetention
 		if cfg.EtcdS3 {
@@ -212,12 +315,34 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 
 	serverConfig.ControlConfig.ClusterReset = cfg.ClusterReset
 	serverConfig.ControlConfig.ClusterResetRestorePath = cfg.ClusterResetRestorePath
+
+	if cfg.ClusterImport != "" {
+		if serverConfig.ControlConfig.JoinURL != "" {
+			return errors.New("invalid flag use; --cluster-import cannot be used with --server")
+		}
+		if err := clusterImport(cfg, &serverConfig.ControlConfig); err != nil {
+			return errors.Wrap(err, "failed to import cluster archive")
+		}
+	}
 	serverConfig.ControlConfig.SystemDefaultRegistry = cfg.SystemDefaultRegistry
+	serverConfig.ControlConfig.SystemImages, err = images.LoadOverrides(cfg.SystemImagesFile, cfg.SystemImages.Value())
+	if err != nil {
+		return err
+	}
 
 	if serverConfig.ControlConfig.SupervisorPort == 0 {
 		serverConfig.ControlConfig.SupervisorPort = serverConfig.ControlConfig.HTTPSPort
 	}
 
+	if legacyPort := serverConfig.ControlConfig.SupervisorLegacyPort; legacyPort != 0 {
+		if legacyPort == serverConfig.ControlConfig.SupervisorPort {
+			return errors.New("invalid flag use; --supervisor-legacy-port must not be the same as the supervisor port")
+		}
+		if legacyPort == serverConfig.ControlConfig.APIServerPort {
+			return errors.New("invalid flag use; --supervisor-legacy-port must not be the same as --apiserver-port")
+		}
+	}
+
 	if serverConfig.ControlConfig.DisableETCD && serverConfig.ControlConfig.JoinURL == "" {
 		return errors.New("invalid flag use; --server is required with --disable-etcd")
 	}
@@ -252,6 +377,10 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 		serverConfig.ControlConfig.PrivateIP = util.GetFirstValidIPString(cmds.AgentConfig.NodeIP)
 	}
 
+	if cmds.AgentConfig.NodeName, err = identity.ResolveNodeName(cmds.AgentConfig.NodeName, cmds.AgentConfig.NodeNameTemplate); err != nil {
+		return err
+	}
+
 	// Ensure that we add the localhost name/ip and node name/ip to the SAN list. This list is shared by the
 	// certs for the supervisor, kube-apiserver cert, and etcd. DNS entries for the in-cluster kubernetes
 	// service endpoint are added later when the certificates are created.
@@ -351,6 +480,7 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 	if err != nil {
 		return errors.Wrapf(err, "invalid port range %s", cfg.ServiceNodePortRange)
 	}
+	serverConfig.ControlConfig.AllowServiceRangeChange = cfg.AllowServiceRangeChange
 
 	// the apiserver service does not yet support dual-stack operation
 	_, apiServerServiceIP, err := options.ServiceIPRange(*serverConfig.ControlConfig.ServiceIPRanges[0])
@@ -372,21 +502,62 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 			serverConfig.ControlConfig.ClusterDNSs = append(serverConfig.ControlConfig.ClusterDNSs, clusterDNS)
 		}
 	} else {
+		// Multiple addresses may be provided for each IP family, and need not be within the service-cidr, so
+		// that pods can be pointed at a node-local DNS cache.
 		for _, ip := range util.SplitStringSlice(cmds.ServerConfig.ClusterDNS) {
 			parsed := net.ParseIP(ip)
 			if parsed == nil {
 				return fmt.Errorf("invalid cluster-dns address %s", ip)
 			}
+			if slices.ContainsFunc(serverConfig.ControlConfig.ClusterDNSs, parsed.Equal) {
+				return fmt.Errorf("invalid cluster-dns address %s: address is listed more than once", ip)
+			}
 			serverConfig.ControlConfig.ClusterDNSs = append(serverConfig.ControlConfig.ClusterDNSs, parsed)
 		}
 	}
 
 	serverConfig.ControlConfig.ClusterDNS = serverConfig.ControlConfig.ClusterDNSs[0]
+	serverConfig.ControlConfig.ClusterDNSServiceIPs, err = clusterDNSServiceIPs(util.SplitStringSlice(cmds.ServerConfig.ClusterDNSServiceIP), serverConfig.ControlConfig.ClusterDNSs, serverConfig.ControlConfig.ServiceIPRanges)
+	if err != nil {
+		return err
+	}
+
+	if err := validateClusterCIDRs(&serverConfig.ControlConfig); err != nil {
+		return err
+	}
 
 	if err := validateNetworkConfiguration(serverConfig); err != nil {
 		return err
 	}
 
+	limits, err := cgroups.ParseLimits(cmds.AgentConfig.ComponentLimits, cgroups.ComponentSupervisor, cgroups.ComponentContainerd, cgroups.ComponentKubelet, cgroups.ComponentAPIServer, cgroups.ComponentScheduler, cgroups.ComponentControllerManager)
+	if err != nil {
+		return err
+	}
+	switch serverConfig.ControlConfig.ControlPlaneExecMode {
+	case config.ControlPlaneExecModeEmbedded:
+		if limits[cgroups.ComponentAPIServer] != nil || limits[cgroups.ComponentScheduler] != nil || limits[cgroups.ComponentControllerManager] != nil {
+			return errors.New("invalid flag use; kube-apiserver, kube-scheduler and kube-controller-manager resource limits require --control-plane-exec-mode=processes")
+		}
+	case config.ControlPlaneExecModeProcesses:
+		serverConfig.ControlConfig.ControlPlaneLimits = limits
+	default:
+		return fmt.Errorf("invalid control-plane-exec-mode %s", serverConfig.ControlConfig.ControlPlaneExecMode)
+	}
+	if _, ok := config.ResourceProfiles[serverConfig.ControlConfig.ResourceProfile]; !ok {
+		return fmt.Errorf("invalid control-plane-resource-profile %s: must be one of %s", serverConfig.ControlConfig.ResourceProfile, strings.Join(config.ResourceProfileNames(), ", "))
+	}
+
+	if cfg.NotifyWebhookURL != "" {
+		webhook, err := notify.NewWebhook(cfg.NotifyWebhookURL, nodeName, cfg.NotifyWebhookEvents.Value())
+		if err != nil {
+			return errors.Wrap(err, "invalid notify-webhook configuration")
+		}
+		serverConfig.ControlConfig.Runtime.Notifier = webhook
+	} else if len(cfg.NotifyWebhookEvents) > 0 {
+		return errors.New("invalid flag use; --notify-webhook-events requires --notify-webhook-url")
+	}
+
 	if cfg.DefaultLocalStoragePath == "" {
 		dataDir, err := datadir.LocalHome(cfg.DataDir, false)
 		if err != nil {
@@ -404,10 +575,48 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 		serverConfig.ControlConfig.Skips[disable] = true
 		serverConfig.ControlConfig.Disables[disable] = true
 	}
+	enables := map[string]bool{}
+	for _, enable := range util.SplitStringSlice(app.StringSlice("enable")) {
+		enables[strings.TrimSpace(enable)] = true
+	}
+	// Optional components are not deployed unless enabled, and are deleted if they were previously enabled.
+	for _, optional := range strings.Split(cmds.EnableItems, ",") {
+		optional = strings.TrimSpace(optional)
+		if optional != "" && !enables[optional] {
+			serverConfig.ControlConfig.Skips[optional] = true
+			serverConfig.ControlConfig.Disables[optional] = true
+		}
+	}
+	if enables["monitoring-defaults"] && !cfg.SupervisorMetrics {
+		logrus.Warnf("Monitoring defaults are enabled, but %s metrics will not be collected unless --supervisor-metrics is also set", version.Program)
+	}
+	serverConfig.ControlConfig.SkipDeploys = map[string]bool{}
+	for _, skip := range util.SplitStringSlice(cfg.SkipDeploy) {
+		serverConfig.ControlConfig.SkipDeploys[strings.TrimSpace(skip)] = true
+	}
+	serverConfig.ControlConfig.PinDeploys = map[string]bool{}
+	for _, pin := range util.SplitStringSlice(cfg.PinDeploy) {
+		pin = strings.TrimSpace(pin)
+		if serverConfig.ControlConfig.SkipDeploys[pin] {
+			return fmt.Errorf("%s cannot be set in both --skip-deploy and --pin-deploy", pin)
+		}
+		serverConfig.ControlConfig.PinDeploys[pin] = true
+	}
+	if cfg.AgentProfile == config.AgentProfilePico && !serverConfig.ControlConfig.Skips["servicelb"] {
+		logrus.Infof("Disabling servicelb for agent-profile %s", cfg.AgentProfile)
+		serverConfig.ControlConfig.Skips["servicelb"] = true
+		serverConfig.ControlConfig.Disables["servicelb"] = true
+	}
 	if serverConfig.ControlConfig.Skips["servicelb"] {
 		serverConfig.ControlConfig.DisableServiceLB = true
 	}
 
+	if !cfg.TokenViewer {
+		// the view-only service account is only deployed when token exchange is enabled
+		serverConfig.ControlConfig.Skips["token-viewer"] = true
+		serverConfig.ControlConfig.Disables["token-viewer"] = true
+	}
+
 	if serverConfig.ControlConfig.DisableCCM && serverConfig.ControlConfig.DisableServiceLB {
 		serverConfig.ControlConfig.Skips["ccm"] = true
 		serverConfig.ControlConfig.Disables["ccm"] = true
@@ -450,6 +659,29 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 		return errors.Wrap(err, "invalid tls-cipher-suites")
 	}
 
+	// The encrypted directories must be unlocked before anything in them is read or written,
+	// including the cluster-reset restore token check below.
+	serverDataDir, err := server.ResolveDataDir(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+	topDataDir, err := datadir.Resolve(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+	encryptedDirs := encryption.Dirs(topDataDir, serverDataDir, "", serverConfig.ControlConfig.EtcdDataDir)
+	if !cfg.DisableAgent {
+		encryptedDirs = encryption.Dirs(topDataDir, serverDataDir, identity.NodePasswordFile(topDataDir, cfg.Rootless), serverConfig.ControlConfig.EtcdDataDir)
+	}
+	encryptionConfig := encryption.Config{
+		Mode:    cfg.DataDirEncryption,
+		KeyFile: cfg.DataDirEncryptionKeyFile,
+		TPM:     cfg.DataDirEncryptionTPM,
+	}
+	if err := encryption.Setup(encryptedDirs, encryptionConfig); err != nil {
+		return errors.Wrap(err, "failed to set up data-dir encryption")
+	}
+
 	// If performing a cluster reset, make sure control-plane components are
 	// disabled so we only perform a reset or restore and bail out.
 	if cfg.ClusterReset {
@@ -487,7 +719,8 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 			if cfg.Token == "" {
 				tokenFile := filepath.Join(dataDir, "server", "token")
 				if _, err := os.Stat(tokenFile); err != nil {
-					if os.IsNotExist(err) {
+					// the token may be sealed, and not unsealed until the server is started
+					if _, serr := os.Stat(tokenFile + keystore.SealedSuffix); os.IsNotExist(err) && os.IsNotExist(serr) {
 						return errors.New(tokenFile + " does not exist, please pass --token to complete the restoration")
 					}
 				}
@@ -495,24 +728,122 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 		}
 	}
 
+	var phases []string
+	if !serverConfig.ControlConfig.DisableETCD {
+		phases = append(phases, startup.PhaseDatastore)
+	}
+	if !serverConfig.ControlConfig.DisableAPIServer {
+		phases = append(phases, startup.PhaseAPIServer)
+	}
+	if !cfg.DisableAgent {
+		phases = append(phases, startup.PhaseContainerRuntime, startup.PhaseNodeRegistered)
+	}
+	startup.Expect(version.Program+" is up and running", phases...)
+	if cmds.LogConfig.StartupSummary {
+		startup.EnableSummary(os.Stderr)
+	}
+	if err := startup.EnableStateFile(filepath.Join(serverDataDir, "startup-state.json")); err != nil {
+		return errors.Wrap(err, "failed to write startup state file")
+	}
+
+	nodePasswordFile := ""
+	if !cfg.DisableAgent {
+		nodePasswordFile = identity.NodePasswordFile(topDataDir, cfg.Rootless)
+	}
+	if err := identity.ResetIfCloned(topDataDir, nodePasswordFile, serverConfig.ControlConfig.EtcdDataDir, cmds.AgentConfig.ResetIdentityOnClone); err != nil {
+		return err
+	}
+	// keys are unsealed after the clone check, as keys sealed to the TPM of another host cannot be unsealed;
+	// the material is reset if the data-dir was cloned.
+	keyStore, err := keystore.New(cfg.KeyStorage)
+	if err != nil {
+		return err
+	}
+	keyStorageDir := filepath.Join(instance.RunDir(cmds.AgentConfig.InstanceName), "keys")
+	// the admin kubeconfig embeds the admin client certificate key, so it is protected as well.
+	adminKubeConfig := cfg.KubeConfigOutput
+	if adminKubeConfig == "" {
+		if adminKubeConfig, err = server.HomeKubeConfig(true, cfg.Rootless); err != nil {
+			adminKubeConfig = filepath.Join(serverDataDir, "kubeconfig-"+version.Program+".yaml")
+		}
+	}
+	if err := keystore.Sync(keyStore, serverDataDir, keyStorageDir, adminKubeConfig); err != nil {
+		return errors.Wrap(err, "failed to unseal keys and tokens")
+	}
+
 	logrus.Info("Starting " + version.Program + " " + app.App.Version)
 
+	if !cfg.Rootless {
+		ports := []int{serverConfig.ControlConfig.HTTPSPort}
+		if serverConfig.ControlConfig.SupervisorPort != serverConfig.ControlConfig.HTTPSPort {
+			ports = append(ports, serverConfig.ControlConfig.SupervisorPort)
+		}
+		if legacyPort := serverConfig.ControlConfig.SupervisorLegacyPort; legacyPort != 0 && !slices.Contains(ports, legacyPort) {
+			ports = append(ports, legacyPort)
+		}
+		if !serverConfig.ControlConfig.DisableAPIServer {
+			apiServerPort := serverConfig.ControlConfig.APIServerPort
+			if apiServerPort == 0 {
+				apiServerPort = serverConfig.ControlConfig.HTTPSPort + 1
+			}
+			ports = append(ports, apiServerPort)
+		}
+		if err := preflight.CheckConflicts(preflight.ConflictOptions{Ports: ports}); err != nil {
+			return err
+		}
+	}
+
+	firewallPorts := []firewall.Port{firewall.TCP(serverConfig.ControlConfig.HTTPSPort)}
+	if serverConfig.ControlConfig.SupervisorPort != serverConfig.ControlConfig.HTTPSPort {
+		firewallPorts = append(firewallPorts, firewall.TCP(serverConfig.ControlConfig.SupervisorPort))
+	}
+	if legacyPort := serverConfig.ControlConfig.SupervisorLegacyPort; legacyPort != 0 && legacyPort != serverConfig.ControlConfig.HTTPSPort {
+		firewallPorts = append(firewallPorts, firewall.TCP(legacyPort))
+	}
+	if etcd.WillRun(&serverConfig.ControlConfig) {
+		firewallPorts = append(firewallPorts, firewall.Port{Start: 2379, End: 2380, Protocol: "tcp"})
+	}
+	firewallCIDRs := []string{}
+	for _, cidr := range append(serverConfig.ControlConfig.ClusterIPRanges, serverConfig.ControlConfig.ServiceIPRanges...) {
+		firewallCIDRs = append(firewallCIDRs, cidr.String())
+	}
+	if err := firewall.Register(cmds.AgentConfig.HostFirewall, firewall.RoleServer, firewallPorts, firewallCIDRs); err != nil {
+		return errors.Wrap(err, "failed to register with host firewall")
+	}
+
 	notifySocket := os.Getenv("NOTIFY_SOCKET")
 	os.Unsetenv("NOTIFY_SOCKET")
 
-	ctx := signals.SetupSignalContext()
+	ctx := newContext()
+
+	if err := tracing.Setup(ctx, cmds.AgentConfig.TracingEndpoint, cmds.AgentConfig.TracingSamplingRate, "server"); err != nil {
+		return err
+	}
+	ctx, span := tracing.Start(ctx, "server.start", attribute.String("server.name", serverConfig.ControlConfig.ServerNodeName))
+
+	serverConfig.ControlConfig.Runtime.Notifier.Start(ctx)
 
 	if err := server.StartServer(ctx, &serverConfig, cfg); err != nil {
+		tracing.End(span, err)
 		return err
 	}
 
 	go cmds.WriteCoverage(ctx)
+	go keystore.Watch(ctx, keyStore, serverDataDir, keyStorageDir, adminKubeConfig)
+
+	if !serverConfig.ControlConfig.DisableETCD {
+		go func() {
+			<-serverConfig.ControlConfig.Runtime.ETCDReady
+			startup.Done(startup.PhaseDatastore)
+		}()
+	}
 
 	go func() {
 		if !serverConfig.ControlConfig.DisableAPIServer {
 			<-serverConfig.ControlConfig.Runtime.APIServerReady
 			logrus.Info("Kube API server is now running")
 			serverConfig.ControlConfig.Runtime.StartupHooksWg.Wait()
+			startup.Done(startup.PhaseAPIServer)
 		}
 		if !serverConfig.ControlConfig.DisableETCD {
 			<-serverConfig.ControlConfig.Runtime.ETCDReady
@@ -520,6 +851,10 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 		}
 
 		logrus.Info(version.Program + " is up and running")
+		span.End()
+		if cfg.ServerReady != nil {
+			close(cfg.ServerReady)
+		}
 		os.Setenv("NOTIFY_SOCKET", notifySocket)
 		systemd.SdNotify(true, "READY=1\n")
 	}()
@@ -540,6 +875,7 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 	agentConfig.DisableServiceLB = serverConfig.ControlConfig.DisableServiceLB
 	agentConfig.ETCDAgent = serverConfig.ControlConfig.DisableAPIServer
 	agentConfig.ClusterReset = serverConfig.ControlConfig.ClusterReset
+	agentConfig.EtcdIOWeight = serverConfig.ControlConfig.EtcdIOWeight
 	agentConfig.Rootless = cfg.Rootless
 
 	if agentConfig.Rootless {
@@ -578,12 +914,16 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 	registry.Router = func(ctx context.Context, nodeConfig *config.Node) (*mux.Router, error) {
 		return https.Start(ctx, nodeConfig, serverConfig.ControlConfig.Runtime)
 	}
+	registry.ServerKubeConfig = func() string {
+		return serverConfig.ControlConfig.Runtime.KubeConfigSupervisor
+	}
 
 	// same deal for metrics - these are not used if the extra metrics listener is not enabled.
 	metrics := k3smetrics.DefaultMetrics
 	metrics.Router = func(ctx context.Context, nodeConfig *config.Node) (*mux.Router, error) {
 		return https.Start(ctx, nodeConfig, serverConfig.ControlConfig.Runtime)
 	}
+	metrics.Gatherers = k3smetrics.ControlPlaneGatherers(&serverConfig.ControlConfig)
 
 	// and for pprof as well
 	pprof := profile.DefaultProfiler
@@ -599,21 +939,272 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 	return agent.Run(ctx, agentConfig)
 }
 
+// agentFlags are server flags that only configure the local agent.
+var agentFlags = []cli.Flag{
+	cmds.NodeLabels,
+	cmds.NodeTaints,
+	cmds.NodeLabelPolicy,
+	cmds.NodeTaintPolicy,
+	cmds.ImageCredProvBinDirFlag,
+	cmds.ImageCredProvConfigFlag,
+	cmds.StaticPodDirFlag,
+	cmds.SystemReservedProfileFlag,
+	cmds.SwapBehaviorFlag,
+	cmds.KubeletServingCSRFlag,
+	cmds.DockerFlag,
+	cmds.CRIEndpointFlag,
+	cmds.DefaultRuntimeFlag,
+	cmds.ImageServiceEndpointFlag,
+	cmds.DisableDefaultRegistryEndpointFlag,
+	cmds.NonrootDevicesFlag,
+	cmds.PauseImageFlag,
+	cmds.SnapshotterFlag,
+	cmds.PrivateRegistryFlag,
+	cmds.AirgapExtraRegistryFlag,
+	cmds.ResolvConfFlag,
+	cmds.FlannelIfaceFlag,
+	cmds.FlannelConfFlag,
+	cmds.FlannelCniConfFileFlag,
+	cmds.FlannelVXLANPortFlag,
+	cmds.FlannelWireguardPortFlag,
+	cmds.FlannelWireguardPortIPv6Flag,
+	cmds.CNIConfDirFlag,
+	cmds.CNIBinDirFlag,
+	cmds.CNIConfCompatFlag,
+	cmds.TunnelCompressionFlag,
+	cmds.TunnelReconnectDelayFlag,
+	cmds.TunnelReconnectJitterFlag,
+	cmds.RegistryProxyFlag,
+	cmds.ProxyCredentialsFileFlag,
+	cmds.SysctlProfileFlag,
+	cmds.SysctlCheckIntervalFlag,
+	cmds.SysctlResetDriftFlag,
+	cmds.ImagePullProgressFlag,
+	cmds.LogShippingTypeFlag,
+	cmds.LogShippingEndpointFlag,
+	cmds.DisableLogShippingFlag,
+	cmds.DNSFallbackCacheFlag,
+	cmds.ExtraKubeletArgs,
+	cmds.KubeletConfigFlag,
+	cmds.ExtraKubeProxyArgs,
+	cmds.KubeProxyConfigFlag,
+	cmds.ProtectKernelDefaultsFlag,
+}
+
+// applyAgentProfile disables the node components that are not used by the selected agent profile,
+// and validates that the CNI settings are supported by the profile. A warning is logged if kube-proxy
+// settings are given for a profile that disables kube-proxy.
+func applyAgentProfile(cfg *cmds.Server, agentCfg *cmds.Agent) error {
+	switch cfg.AgentProfile {
+	case config.AgentProfileDefault:
+	case config.AgentProfilePico:
+		if cfg.FlannelBackend != config.FlannelBackendNone {
+			return fmt.Errorf("invalid flag use; agent-profile %s requires --flannel-backend=none and a CNI that replaces kube-proxy and network policy enforcement", cfg.AgentProfile)
+		}
+		if len(agentCfg.ExtraKubeProxyArgs) > 0 || agentCfg.KubeProxyConfig != "" {
+			logrus.Warnf("kube-proxy-arg and kube-proxy-config are ignored, as agent-profile %s disables kube-proxy", cfg.AgentProfile)
+		}
+		cfg.DisableKubeProxy = true
+		cfg.DisableNPC = true
+	default:
+		return fmt.Errorf("invalid agent-profile %s: must be one of %s", cfg.AgentProfile, strings.Join([]string{config.AgentProfileDefault, config.AgentProfilePico}, ", "))
+	}
+	return nil
+}
+
+// configureAgentless adjusts the control-plane configuration for a server that runs without a local agent.
+// Agentless servers run only the control-plane and datastore; they do not run a kubelet or container runtime,
+// and never register a Node. The names of any agent flags that were set, and will be ignored, are returned so
+// that they can be reported in the startup state.
+func configureAgentless(app *cli.Context, controlConfig *config.Control) []string {
+	// The apiserver cannot reach pods through the agent tunnel without a local agent, so unless the user
+	// has chosen otherwise, route all cluster traffic through the tunnels of the remote agents instead.
+	if !app.IsSet("egress-selector-mode") && controlConfig.EgressSelectorMode == config.EgressSelectorModeAgent {
+		logrus.Infof("Agent is disabled, using egress-selector-mode %s", config.EgressSelectorModeCluster)
+		controlConfig.EgressSelectorMode = config.EgressSelectorModeCluster
+	}
+
+	var ignored []string
+	for _, flag := range agentFlags {
+		name := strings.TrimSpace(strings.Split(flag.GetName(), ",")[0])
+		if app.IsSet(name) {
+			ignored = append(ignored, name)
+		}
+	}
+	return ignored
+}
+
+// configureArbiter configures the server to run only an etcd voting member, so that a small node
+// can provide quorum for a cluster whose control-plane is split across two sites. The arbiter
+// must join an existing cluster, as it cannot serve the apiserver that other nodes join through.
+func configureArbiter(app *cli.Context, cfg *cmds.Server) error {
+	if cfg.DisableETCD {
+		return errors.New("invalid flag use; cannot use --disable-etcd with --etcd-arbiter")
+	}
+	if cfg.ServerURL == "" {
+		return errors.New("invalid flag use; --server is required with --etcd-arbiter")
+	}
+
+	logrus.Info("Running as etcd arbiter; apiserver, controller-manager, scheduler, cloud-controller-manager, and agent are disabled")
+	cfg.DisableAPIServer = true
+	cfg.DisableControllerManager = true
+	cfg.DisableScheduler = true
+	cfg.DisableCCM = true
+	cfg.DisableAgent = true
+
+	// The arbiter holds a full copy of the datastore, but there is no need for it to take
+	// snapshots as well unless the user has asked for them.
+	if !app.IsSet("etcd-disable-snapshots") {
+		cfg.EtcdDisableSnapshots = true
+	}
+	return nil
+}
+
 // validateNetworkConfig ensures that the network configuration values make sense.
 func validateNetworkConfiguration(serverConfig server.Config) error {
 	switch serverConfig.ControlConfig.EgressSelectorMode {
 	case config.EgressSelectorModeCluster, config.EgressSelectorModePod:
+	case config.EgressSelectorModeAgent, config.EgressSelectorModeDisabled:
+	default:
+		return fmt.Errorf("invalid egress-selector-mode %s", serverConfig.ControlConfig.EgressSelectorMode)
+	}
+
+	// The tunnel server routes all proxied connections according to the egress-selector-mode, so
+	// individual egress types can only be proxied using that mode, or not proxied at all.
+	for egressType, mode := range serverConfig.ControlConfig.EgressSelectorTypeModes {
+		if egressTypes := slices.Concat(config.EgressSelectorTypes, config.EgressSelectorClusterTypes); !slices.Contains(egressTypes, egressType) {
+			return fmt.Errorf("invalid egress-selector-type-mode type %s: must be one of %s", egressType, strings.Join(egressTypes, ", "))
+		}
+		if mode != config.EgressSelectorModeDisabled && mode != serverConfig.ControlConfig.EgressSelectorMode {
+			return fmt.Errorf("invalid egress-selector-type-mode %s=%s: mode must be %s or %s", egressType, mode, config.EgressSelectorModeDisabled, serverConfig.ControlConfig.EgressSelectorMode)
+		}
+	}
+
+	switch serverConfig.ControlConfig.EgressSelectorModeForType(config.EgressSelectorTypeWebhook) {
 	case config.EgressSelectorModeAgent, config.EgressSelectorModeDisabled:
 		if serverConfig.DisableAgent {
 			logrus.Warn("Webhooks and apiserver aggregation may not function properly without an agent; please set egress-selector-mode to 'cluster' or 'pod'")
 		}
-	default:
-		return fmt.Errorf("invalid egress-selector-mode %s", serverConfig.ControlConfig.EgressSelectorMode)
 	}
 
 	return nil
 }
 
+// clusterDNSServiceIPs returns the cluster IPs of the coredns service. If none are set, the first cluster-dns
+// address of each IP family that is within the service CIDRs is used; if there are none, the tenth address of
+// each service CIDR is used, as when cluster-dns is not set.
+func clusterDNSServiceIPs(serviceIPs []string, clusterDNSs []net.IP, serviceCIDRs []*net.IPNet) ([]net.IP, error) {
+	inServiceCIDRs := func(ip net.IP) bool {
+		return slices.ContainsFunc(serviceCIDRs, func(cidr *net.IPNet) bool { return cidr.Contains(ip) })
+	}
+	sameFamily := func(ips []net.IP, ip net.IP) bool {
+		return slices.ContainsFunc(ips, func(i net.IP) bool { return (i.To4() == nil) == (ip.To4() == nil) })
+	}
+
+	var parsed []net.IP
+	for _, ip := range serviceIPs {
+		addr := net.ParseIP(ip)
+		if addr == nil {
+			return nil, fmt.Errorf("invalid cluster-dns-service-ip address %s", ip)
+		}
+		if !inServiceCIDRs(addr) {
+			return nil, fmt.Errorf("invalid cluster-dns-service-ip address %s: must be within service-cidr %s", ip, util.JoinIPNets(serviceCIDRs))
+		}
+		if sameFamily(parsed, addr) {
+			return nil, fmt.Errorf("invalid cluster-dns-service-ip address %s: only one address per IP family is supported", ip)
+		}
+		parsed = append(parsed, addr)
+	}
+	if len(parsed) > 0 {
+		return parsed, nil
+	}
+
+	for _, ip := range clusterDNSs {
+		if inServiceCIDRs(ip) && !sameFamily(parsed, ip) {
+			parsed = append(parsed, ip)
+		}
+	}
+	if len(parsed) > 0 {
+		return parsed, nil
+	}
+
+	for _, cidr := range serviceCIDRs {
+		ip, err := utilsnet.GetIndexedIP(cidr, 10)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot configure default cluster-dns-service-ip address")
+		}
+		parsed = append(parsed, ip)
+	}
+	return parsed, nil
+}
+
+// validateClusterCIDRs ensures that there is at most one cluster CIDR per address family, as required by the
+// controller-manager's node IPAM and by flannel, that the controller-manager can allocate pod CIDRs of the
+// configured sizes from the cluster CIDRs, and that the cluster CIDRs do not overlap each other or the service CIDRs.
+func validateClusterCIDRs(controlConfig *config.Control) error {
+	var families []string
+	for i, cidr := range controlConfig.ClusterIPRanges {
+		family, maskSize, defaultMaskSize := "ipv4", controlConfig.NodeCIDRMaskSizeIPv4, config.DefaultNodeCIDRMaskSizeIPv4
+		if utilsnet.IsIPv6CIDR(cidr) {
+			family, maskSize, defaultMaskSize = "ipv6", controlConfig.NodeCIDRMaskSizeIPv6, config.DefaultNodeCIDRMaskSizeIPv6
+		}
+		if slices.Contains(families, family) {
+			return fmt.Errorf("invalid cluster-cidr %s: only one %s CIDR is supported", util.JoinIPNets(controlConfig.ClusterIPRanges), family)
+		}
+		families = append(families, family)
+
+		for _, other := range controlConfig.ClusterIPRanges[:i] {
+			if cidr.Contains(other.IP) || other.Contains(cidr.IP) {
+				return fmt.Errorf("invalid cluster-cidr %s: overlaps cluster-cidr %s", cidr, other)
+			}
+		}
+
+		for _, serviceCIDR := range controlConfig.ServiceIPRanges {
+			if cidr.Contains(serviceCIDR.IP) || serviceCIDR.Contains(cidr.IP) {
+				return fmt.Errorf("invalid cluster-cidr %s: overlaps service-cidr %s", cidr, serviceCIDR)
+			}
+		}
+
+		// The controller-manager cannot allocate more than 2^16 pod CIDRs from a cluster CIDR.
+		prefixLen, bits := cidr.Mask.Size()
+		if maskSize == 0 {
+			maskSize = defaultMaskSize
+		}
+		if maskSize < prefixLen || maskSize > bits || maskSize-prefixLen > 16 {
+			return fmt.Errorf("invalid node-cidr-mask-size-%s %d: must be between %d and %d for cluster-cidr %s", family, maskSize, prefixLen, min(prefixLen+16, bits), cidr)
+		}
+	}
+	for family, maskSize := range map[string]int{"ipv4": controlConfig.NodeCIDRMaskSizeIPv4, "ipv6": controlConfig.NodeCIDRMaskSizeIPv6} {
+		if maskSize != 0 && !slices.Contains(families, family) {
+			return fmt.Errorf("invalid flag use; --node-cidr-mask-size-%s cannot be used without an %s cluster-cidr", family, family)
+		}
+	}
+	return nil
+}
+
+// validateCertDurations ensures that certificates signed by the cluster CAs do not outlive the CAs.
+func validateCertDurations(controlConfig *config.Control) error {
+	if controlConfig.CACertDuration < 0 {
+		return fmt.Errorf("invalid cluster-signing-ca-duration %s: must not be negative", controlConfig.CACertDuration)
+	}
+	caDuration := controlConfig.CACertValidity()
+	for flag, duration := range map[string]time.Duration{
+		"cluster-signing-server-duration": controlConfig.ServerCertDuration,
+		"cluster-signing-client-duration": controlConfig.ClientCertDuration,
+	} {
+		if duration < 0 {
+			return fmt.Errorf("invalid %s %s: must not be negative", flag, duration)
+		}
+		if duration == 0 {
+			duration = config.DefaultLeafCertDuration
+		}
+		if duration > caDuration {
+			return fmt.Errorf("invalid %s %s: must not be longer than the CA certificate validity of %s", flag, duration, caDuration)
+		}
+	}
+	return nil
+}
+
 func getArgValueFromList(searchArg string, argList []string) string {
 	var value string
 	for _, arg := range argList {
@@ -643,3 +1234,29 @@ func getAPIAddressFromEtcd(ctx context.Context, serverConfig server.Config, agen
 		<-toCtx.Done()
 	}
 }
+
+// clusterImport extracts a cluster archive into the server data-dir. Tokens from the archive
+// are used if tokens were not otherwise provided.
+func clusterImport(cfg *cmds.Server, controlConfig *config.Control) error {
+	dataDir, err := server.ResolveDataDir(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(cfg.ClusterImport)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tokens, err := cluster.Import(f, dataDir)
+	if err != nil {
+		return err
+	}
+	if controlConfig.Token == "" {
+		controlConfig.Token = tokens.Token
+	}
+	if controlConfig.AgentToken == "" {
+		controlConfig.AgentToken = tokens.AgentToken
+	}
+	return nil
+}
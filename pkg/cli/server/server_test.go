@@ -0,0 +1,448 @@
+package server
+
+import (
+	"flag"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/server"
+	"github.com/urfave/cli"
+)
+
+func Test_UnitConfigureAgentless(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantMode    string
+		wantIgnored []string
+	}{
+		{
+			name:     "Default egress-selector-mode",
+			wantMode: config.EgressSelectorModeCluster,
+		},
+		{
+			name:     "Explicit egress-selector-mode",
+			args:     []string{"--egress-selector-mode=agent"},
+			wantMode: config.EgressSelectorModeAgent,
+		},
+		{
+			name:        "Agent flags ignored",
+			args:        []string{"--egress-selector-mode=pod", "--node-label=foo=bar", "--kubelet-arg=v=2", "--node-name=server-1"},
+			wantMode:    config.EgressSelectorModePod,
+			wantIgnored: []string{"node-label", "kubelet-arg"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode := config.EgressSelectorModeAgent
+			set := flag.NewFlagSet("server", flag.ContinueOnError)
+			set.StringVar(&mode, "egress-selector-mode", mode, "")
+			set.String("node-name", "", "")
+			for _, f := range agentFlags {
+				f.Apply(set)
+			}
+			if err := set.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			app := cli.NewContext(nil, set, nil)
+
+			controlConfig := &config.Control{}
+			controlConfig.EgressSelectorMode = mode
+			ignored := configureAgentless(app, controlConfig)
+			if controlConfig.EgressSelectorMode != tt.wantMode {
+				t.Errorf("configureAgentless() egress-selector-mode = %s, want %s", controlConfig.EgressSelectorMode, tt.wantMode)
+			}
+			if !reflect.DeepEqual(ignored, tt.wantIgnored) {
+				t.Errorf("configureAgentless() ignored = %v, want %v", ignored, tt.wantIgnored)
+			}
+		})
+	}
+}
+
+func Test_UnitConfigureArbiter(t *testing.T) {
+	tests := []struct {
+		name              string
+		args              []string
+		cfg               cmds.Server
+		wantErr           bool
+		wantNoSnapshots   bool
+		wantDisableAgent  bool
+		wantDisableServer bool
+	}{
+		{
+			name:    "Missing server URL",
+			wantErr: true,
+		},
+		{
+			name:    "Etcd disabled",
+			cfg:     cmds.Server{ServerURL: "https://server-1:6443", DisableETCD: true},
+			wantErr: true,
+		},
+		{
+			name:              "Default snapshots",
+			cfg:               cmds.Server{ServerURL: "https://server-1:6443"},
+			wantNoSnapshots:   true,
+			wantDisableAgent:  true,
+			wantDisableServer: true,
+		},
+		{
+			name:              "Explicit snapshots",
+			args:              []string{"--etcd-disable-snapshots=false"},
+			cfg:               cmds.Server{ServerURL: "https://server-1:6443"},
+			wantDisableAgent:  true,
+			wantDisableServer: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := flag.NewFlagSet("server", flag.ContinueOnError)
+			set.BoolVar(&tt.cfg.EtcdDisableSnapshots, "etcd-disable-snapshots", false, "")
+			if err := set.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			app := cli.NewContext(nil, set, nil)
+
+			if err := configureArbiter(app, &tt.cfg); (err != nil) != tt.wantErr {
+				t.Fatalf("configureArbiter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.cfg.EtcdDisableSnapshots != tt.wantNoSnapshots {
+				t.Errorf("configureArbiter() etcd-disable-snapshots = %v, want %v", tt.cfg.EtcdDisableSnapshots, tt.wantNoSnapshots)
+			}
+			if tt.cfg.DisableAgent != tt.wantDisableAgent {
+				t.Errorf("configureArbiter() disable-agent = %v, want %v", tt.cfg.DisableAgent, tt.wantDisableAgent)
+			}
+			disabled := tt.cfg.DisableAPIServer && tt.cfg.DisableControllerManager && tt.cfg.DisableScheduler && tt.cfg.DisableCCM
+			if disabled != tt.wantDisableServer {
+				t.Errorf("configureArbiter() control-plane components disabled = %v, want %v", disabled, tt.wantDisableServer)
+			}
+		})
+	}
+}
+
+func Test_UnitValidateEgressSelectorTypeModes(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		typeModes map[string]string
+		wantErr   bool
+		wantModes map[string]string
+	}{
+		{
+			name:      "Defaults",
+			mode:      config.EgressSelectorModeAgent,
+			wantModes: map[string]string{"cluster": "agent", "controlplane": "disabled", "etcd": "disabled", "kubelet": "agent", "webhook": "agent"},
+		},
+		{
+			name:      "Proxied control-plane egress",
+			mode:      config.EgressSelectorModePod,
+			typeModes: map[string]string{"controlplane": "pod"},
+			wantModes: map[string]string{"cluster": "pod", "controlplane": "pod", "etcd": "disabled"},
+		},
+		{
+			name:      "Direct cluster egress",
+			mode:      config.EgressSelectorModeCluster,
+			typeModes: map[string]string{"cluster": "disabled", "etcd": "cluster"},
+			wantModes: map[string]string{"cluster": "disabled", "controlplane": "disabled", "etcd": "cluster"},
+		},
+		{
+			name:      "Tunneled kubelet egress with direct webhook egress",
+			mode:      config.EgressSelectorModeAgent,
+			typeModes: map[string]string{"webhook": "disabled"},
+			wantModes: map[string]string{"cluster": "agent", "kubelet": "agent", "webhook": "disabled"},
+		},
+		{
+			name:      "Tunneled webhook egress with direct cluster egress",
+			mode:      config.EgressSelectorModePod,
+			typeModes: map[string]string{"cluster": "disabled", "webhook": "pod"},
+			wantModes: map[string]string{"cluster": "pod", "kubelet": "disabled", "webhook": "pod"},
+		},
+		{
+			name:      "Direct kubelet and webhook egress",
+			mode:      config.EgressSelectorModeCluster,
+			typeModes: map[string]string{"kubelet": "disabled", "webhook": "disabled"},
+			wantModes: map[string]string{"cluster": "disabled", "kubelet": "disabled", "webhook": "disabled"},
+		},
+		{
+			name:      "Unknown egress type",
+			mode:      config.EgressSelectorModeAgent,
+			typeModes: map[string]string{"konnectivity": "disabled"},
+			wantErr:   true,
+		},
+		{
+			name:      "Mode does not match egress-selector-mode",
+			mode:      config.EgressSelectorModeAgent,
+			typeModes: map[string]string{"cluster": "pod"},
+			wantErr:   true,
+		},
+		{
+			name:      "Proxied egress with egress-selector-mode disabled",
+			mode:      config.EgressSelectorModeDisabled,
+			typeModes: map[string]string{"etcd": "agent"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverConfig := server.Config{}
+			serverConfig.ControlConfig.EgressSelectorMode = tt.mode
+			serverConfig.ControlConfig.EgressSelectorTypeModes = tt.typeModes
+			err := validateNetworkConfiguration(serverConfig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateNetworkConfiguration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for egressType, wantMode := range tt.wantModes {
+				if mode := serverConfig.ControlConfig.EgressSelectorModeForType(egressType); mode != wantMode {
+					t.Errorf("EgressSelectorModeForType(%s) = %s, want %s", egressType, mode, wantMode)
+				}
+			}
+		})
+	}
+}
+
+func Test_UnitApplyAgentProfile(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           cmds.Server
+		wantErr       bool
+		wantKubeProxy bool
+		wantNPC       bool
+	}{
+		{
+			name: "default profile",
+			cfg:  cmds.Server{AgentProfile: config.AgentProfileDefault, FlannelBackend: "vxlan"},
+		},
+		{
+			name:          "default profile with components disabled",
+			cfg:           cmds.Server{AgentProfile: config.AgentProfileDefault, FlannelBackend: config.FlannelBackendNone, DisableKubeProxy: true, DisableNPC: true},
+			wantKubeProxy: true,
+			wantNPC:       true,
+		},
+		{
+			name:          "pico profile",
+			cfg:           cmds.Server{AgentProfile: config.AgentProfilePico, FlannelBackend: config.FlannelBackendNone},
+			wantKubeProxy: true,
+			wantNPC:       true,
+		},
+		{
+			name:    "pico profile with flannel",
+			cfg:     cmds.Server{AgentProfile: config.AgentProfilePico, FlannelBackend: "vxlan"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown profile",
+			cfg:     cmds.Server{AgentProfile: "nano", FlannelBackend: config.FlannelBackendNone},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := applyAgentProfile(&tt.cfg, &cmds.Agent{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyAgentProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.cfg.DisableKubeProxy != tt.wantKubeProxy || tt.cfg.DisableNPC != tt.wantNPC {
+				t.Errorf("applyAgentProfile() DisableKubeProxy = %v, DisableNPC = %v, want %v, %v", tt.cfg.DisableKubeProxy, tt.cfg.DisableNPC, tt.wantKubeProxy, tt.wantNPC)
+			}
+		})
+	}
+}
+
+func Test_UnitValidateCertDurations(t *testing.T) {
+	tests := []struct {
+		name    string
+		ca      time.Duration
+		server  time.Duration
+		client  time.Duration
+		wantErr bool
+	}{
+		{
+			name: "Defaults",
+		},
+		{
+			name:   "Short leaf certificates",
+			server: 30 * 24 * time.Hour,
+			client: 7 * 24 * time.Hour,
+		},
+		{
+			name:    "Leaf certificates with default validity outlive a short CA",
+			ca:      180 * 24 * time.Hour,
+			server:  90 * 24 * time.Hour,
+			wantErr: true,
+		},
+		{
+			name:   "Short CA and leaf certificates",
+			ca:     180 * 24 * time.Hour,
+			server: 90 * 24 * time.Hour,
+			client: 30 * 24 * time.Hour,
+		},
+		{
+			name:    "Server certificates outlive the CA",
+			server:  20 * 365 * 24 * time.Hour,
+			wantErr: true,
+		},
+		{
+			name:    "Negative duration",
+			client:  -time.Hour,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controlConfig := &config.Control{CACertDuration: tt.ca, ServerCertDuration: tt.server, ClientCertDuration: tt.client}
+			if err := validateCertDurations(controlConfig); (err != nil) != tt.wantErr {
+				t.Errorf("validateCertDurations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_UnitValidateClusterCIDRs(t *testing.T) {
+	parse := func(cidrs ...string) []*net.IPNet {
+		var parsed []*net.IPNet
+		for _, cidr := range cidrs {
+			_, ipnet, _ := net.ParseCIDR(cidr)
+			parsed = append(parsed, ipnet)
+		}
+		return parsed
+	}
+	tests := []struct {
+		name         string
+		clusterCIDRs []string
+		serviceCIDRs []string
+		maskIPv4     int
+		maskIPv6     int
+		wantErr      bool
+	}{
+		{
+			name:         "Defaults",
+			clusterCIDRs: []string{"10.42.0.0/16"},
+			serviceCIDRs: []string{"10.43.0.0/16"},
+		},
+		{
+			name:         "Dual-stack with mask sizes",
+			clusterCIDRs: []string{"10.42.0.0/16", "fd00:42::/56"},
+			serviceCIDRs: []string{"10.43.0.0/16", "fd00:43::/112"},
+			maskIPv4:     26,
+			maskIPv6:     72,
+		},
+		{
+			name:         "Two IPv4 cluster CIDRs",
+			clusterCIDRs: []string{"10.42.0.0/16", "10.44.0.0/16"},
+			serviceCIDRs: []string{"10.43.0.0/16"},
+			wantErr:      true,
+		},
+		{
+			name:         "Overlaps service CIDR",
+			clusterCIDRs: []string{"10.42.0.0/15"},
+			serviceCIDRs: []string{"10.43.0.0/16"},
+			wantErr:      true,
+		},
+		{
+			name:         "Mask size larger than cluster CIDR",
+			clusterCIDRs: []string{"10.42.0.0/16"},
+			serviceCIDRs: []string{"10.43.0.0/16"},
+			maskIPv4:     8,
+			wantErr:      true,
+		},
+		{
+			name:         "Default mask size allocates too many pod CIDRs",
+			clusterCIDRs: []string{"10.0.0.0/7"},
+			serviceCIDRs: []string{"192.168.0.0/16"},
+			wantErr:      true,
+		},
+		{
+			name:         "IPv6 mask size without IPv6 cluster CIDR",
+			clusterCIDRs: []string{"10.42.0.0/16"},
+			serviceCIDRs: []string{"10.43.0.0/16"},
+			maskIPv6:     64,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controlConfig := &config.Control{}
+			controlConfig.ClusterIPRanges = parse(tt.clusterCIDRs...)
+			controlConfig.ServiceIPRanges = parse(tt.serviceCIDRs...)
+			controlConfig.NodeCIDRMaskSizeIPv4 = tt.maskIPv4
+			controlConfig.NodeCIDRMaskSizeIPv6 = tt.maskIPv6
+			if err := validateClusterCIDRs(controlConfig); (err != nil) != tt.wantErr {
+				t.Errorf("validateClusterCIDRs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_UnitClusterDNSServiceIPs(t *testing.T) {
+	_, svcV4, _ := net.ParseCIDR("10.43.0.0/16")
+	_, svcV6, _ := net.ParseCIDR("fd00:43::/112")
+	parse := func(ips ...string) []net.IP {
+		var parsed []net.IP
+		for _, ip := range ips {
+			parsed = append(parsed, net.ParseIP(ip))
+		}
+		return parsed
+	}
+	tests := []struct {
+		name         string
+		serviceIPs   []string
+		clusterDNSs  []net.IP
+		serviceCIDRs []*net.IPNet
+		want         []net.IP
+		wantErr      bool
+	}{
+		{
+			name:         "First cluster-dns address per family",
+			clusterDNSs:  parse("10.43.0.10", "10.43.0.20", "fd00:43::a"),
+			serviceCIDRs: []*net.IPNet{svcV4, svcV6},
+			want:         parse("10.43.0.10", "fd00:43::a"),
+		},
+		{
+			name:         "Link-local cluster-dns",
+			clusterDNSs:  parse("169.254.20.10"),
+			serviceCIDRs: []*net.IPNet{svcV4},
+			want:         parse("10.43.0.10"),
+		},
+		{
+			name:         "Explicit service IP",
+			serviceIPs:   []string{"10.43.0.53"},
+			clusterDNSs:  parse("169.254.20.10", "10.43.0.10"),
+			serviceCIDRs: []*net.IPNet{svcV4},
+			want:         parse("10.43.0.53"),
+		},
+		{
+			name:         "Explicit service IP outside service-cidr",
+			serviceIPs:   []string{"169.254.20.10"},
+			clusterDNSs:  parse("169.254.20.10"),
+			serviceCIDRs: []*net.IPNet{svcV4},
+			wantErr:      true,
+		},
+		{
+			name:         "Two explicit service IPs in one family",
+			serviceIPs:   []string{"10.43.0.10", "10.43.0.11"},
+			clusterDNSs:  parse("10.43.0.10"),
+			serviceCIDRs: []*net.IPNet{svcV4},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := clusterDNSServiceIPs(tt.serviceIPs, tt.clusterDNSs, tt.serviceCIDRs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("clusterDNSServiceIPs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clusterDNSServiceIPs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/clientaccess"
+	"github.com/k3s-io/k3s/pkg/datadir"
+	"github.com/k3s-io/k3s/pkg/servicerange"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+func Reallocate(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return reallocate(app, &cmds.ServerConfig, &cmds.ServicesConfig)
+}
+
+func reallocate(app *cli.Context, cfg *cmds.Server, servicesCfg *cmds.Services) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	info, err := commandSetup(cfg, servicesCfg)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(servicerange.Request{DryRun: servicesCfg.DryRun})
+	if err != nil {
+		return err
+	}
+	r, err := info.Post("/v1-"+version.Program+"/services/reallocate", b)
+	if err != nil {
+		return errors.Wrap(err, "failed to reallocate services; see server log for details")
+	}
+	result := &servicerange.Result{}
+	if err := json.Unmarshal(r, result); err != nil {
+		return err
+	}
+
+	if len(result.Changes) == 0 {
+		logrus.Info("All services are within the current service CIDRs and NodePort range")
+		return nil
+	}
+	if err := printChanges(os.Stdout, result); err != nil {
+		return err
+	}
+	failed := 0
+	for _, change := range result.Changes {
+		if change.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to reallocate %d of %d services; see server log for details", failed, len(result.Changes))
+	}
+	return nil
+}
+
+func printChanges(out io.Writer, result *servicerange.Result) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "SERVICE\tACTION\tREASON\tERROR\n")
+	for _, change := range result.Changes {
+		action := change.Action
+		if result.DryRun {
+			action += " (dry run)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", change.Service, action, strings.Join(change.Reasons, "; "), change.Error)
+	}
+	return w.Flush()
+}
+
+// commandSetup returns a client for the server. The token is read from the data-dir if not set.
+func commandSetup(cfg *cmds.Server, servicesCfg *cmds.Services) (*clientaccess.Info, error) {
+	if servicesCfg.Token == "" {
+		dataDir, err := datadir.Resolve(cfg.DataDir)
+		if err != nil {
+			return nil, err
+		}
+		tokenByte, err := os.ReadFile(filepath.Join(dataDir, "server", "token"))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read server token; set --token when not running on a server")
+		}
+		servicesCfg.Token = string(bytes.TrimRight(tokenByte, "\n"))
+	}
+	return clientaccess.ParseAndValidateToken(servicesCfg.ServerURL, servicesCfg.Token, clientaccess.WithUser("server"))
+}
@@ -0,0 +1,215 @@
+package simulate
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/server"
+	"github.com/k3s-io/k3s/pkg/simulate"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/rancher/wrangler/v3/pkg/signals"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// pollInterval is the interval at which the simulated control plane is checked while waiting for it to be ready.
+const pollInterval = 2 * time.Second
+
+func Upgrade(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return upgrade(app, &cmds.ServerConfig, &cmds.SimulateConfig)
+}
+
+func upgrade(app *cli.Context, cfg *cmds.Server, simulateCfg *cmds.Simulate) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	dataDir, err := server.ResolveDataDir(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+
+	snapshotPath := simulateCfg.Snapshot
+	if snapshotPath == "" {
+		if snapshotPath, err = simulate.LatestSnapshot(cmp.Or(cfg.EtcdSnapshotDir, filepath.Join(dataDir, "db", "snapshots"))); err != nil {
+			return errors.Wrap(err, "failed to find the latest etcd snapshot; set --snapshot to the snapshot to restore")
+		}
+	}
+	if simulateCfg.Token == "" {
+		tokenByte, err := os.ReadFile(filepath.Join(dataDir, "token"))
+		if err != nil {
+			return errors.Wrap(err, "failed to read server token; set --token when not running on a server")
+		}
+		simulateCfg.Token = string(bytes.TrimRight(tokenByte, "\n"))
+	}
+	binary := simulateCfg.Binary
+	if binary == "" {
+		if binary, err = os.Executable(); err != nil {
+			return err
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", version.Program+"-simulate-upgrade-")
+	if err != nil {
+		return err
+	}
+	if simulateCfg.Keep {
+		logrus.Infof("The restored datastore and simulated control plane data will be left in %s", tmpDir)
+	} else {
+		defer os.RemoveAll(tmpDir)
+	}
+
+	ctx, cancel := context.WithTimeout(signals.SetupSignalContext(), simulateCfg.Timeout)
+	defer cancel()
+
+	logrus.Infof("Restoring etcd snapshot %s", snapshotPath)
+	datastore, err := simulate.NewDatastore(filepath.Join(tmpDir, "etcd"))
+	if err != nil {
+		return err
+	}
+	if err := datastore.Restore(snapshotPath); err != nil {
+		return errors.Wrap(err, "failed to restore etcd snapshot")
+	}
+	if err := datastore.Start(ctx); err != nil {
+		return err
+	}
+	defer datastore.Close()
+
+	kubeconfig := filepath.Join(tmpDir, "kubeconfig.yaml")
+	logFile := filepath.Join(tmpDir, "server.log")
+	if err := startServer(ctx, binary, tmpDir, kubeconfig, logFile, datastore, simulateCfg); err != nil {
+		return err
+	}
+
+	logrus.Infof("Waiting for the simulated control plane to be ready on port %d", simulateCfg.HTTPSPort)
+	if err := wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		client, err := util.GetClientSet(kubeconfig)
+		if err != nil {
+			return false, nil
+		}
+		_, err = client.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx)
+		return err == nil, nil
+	}); err != nil {
+		return fmt.Errorf("simulated control plane did not become ready; see %s for details (set --keep to retain it): %w", logFile, err)
+	}
+
+	restConfig, err := util.GetRESTConfig(kubeconfig)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := datastore.TLSInfo().ClientConfig()
+	if err != nil {
+		return err
+	}
+	etcdClient, err := clientv3.New(clientv3.Config{Endpoints: []string{datastore.ClientURL}, TLS: tlsConfig, DialTimeout: 10 * time.Second})
+	if err != nil {
+		return err
+	}
+	defer etcdClient.Close()
+
+	client, err := util.GetClientSet(kubeconfig)
+	if err != nil {
+		return err
+	}
+	serverVersion, err := client.Discovery().ServerVersion()
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Checking the restored datastore against the APIs served by %s", serverVersion.GitVersion)
+	issues, err := simulate.Check(ctx, restConfig, etcdClient)
+	if err != nil {
+		return err
+	}
+
+	if len(issues) == 0 {
+		fmt.Printf("\nNo compatibility issues found upgrading snapshot %s to %s\n", filepath.Base(snapshotPath), serverVersion.GitVersion)
+		return nil
+	}
+	errorCount := 0
+	fmt.Printf("\nCompatibility issues upgrading snapshot %s to %s:\n", filepath.Base(snapshotPath), serverVersion.GitVersion)
+	for _, issue := range issues {
+		if issue.Level == simulate.IssueError {
+			errorCount++
+		}
+		fmt.Printf("  - %s: %s\n", issue.Level, issue.Message)
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("found %d compatibility errors", errorCount)
+	}
+	return nil
+}
+
+// startServer starts the simulated control plane. Only the apiserver and supervisor are run, with the
+// restored datastore as an external datastore. The server is stopped when the context is done.
+func startServer(ctx context.Context, binary, tmpDir, kubeconfig, logFile string, datastore *simulate.Datastore, simulateCfg *cmds.Simulate) error {
+	// an empty config file prevents this host's server configuration from being used
+	configFile := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configFile, nil, 0600); err != nil {
+		return err
+	}
+	// the token is passed in a file so that it is not visible in the process list
+	tokenFile := filepath.Join(tmpDir, "token")
+	if err := os.WriteFile(tokenFile, []byte(simulateCfg.Token+"\n"), 0600); err != nil {
+		return err
+	}
+	log, err := os.Create(logFile)
+	if err != nil {
+		return err
+	}
+	args := []string{
+		"server",
+		"--config", configFile,
+		"--data-dir", filepath.Join(tmpDir, "server"),
+		"--datastore-endpoint", datastore.ClientURL,
+		"--datastore-cafile", datastore.CAFile,
+		"--datastore-certfile", datastore.CertFile,
+		"--datastore-keyfile", datastore.KeyFile,
+		"--token-file", tokenFile,
+		"--bind-address", "127.0.0.1",
+		"--https-listen-port", strconv.Itoa(simulateCfg.HTTPSPort),
+		"--write-kubeconfig", kubeconfig,
+		"--disable-agent",
+		"--disable-scheduler",
+		"--disable-controller-manager",
+		"--disable-cloud-controller",
+		"--disable-helm-controller",
+		"--disable-network-policy",
+		"--egress-selector-mode", "disabled",
+		"--disable", strings.ReplaceAll(cmds.DisableItems, " ", ""),
+	}
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	// configuration from the environment would also be used by the simulated control plane
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, version.ProgramUpper+"_") {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	if err := cmd.Start(); err != nil {
+		log.Close()
+		return errors.Wrapf(err, "failed to start %s", binary)
+	}
+	go func() {
+		defer log.Close()
+		if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+			logrus.Errorf("Simulated control plane exited: %v; see %s for details", err, logFile)
+		}
+	}()
+	return nil
+}
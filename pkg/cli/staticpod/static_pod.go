@@ -0,0 +1,94 @@
+package staticpod
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/k3s-io/k3s/pkg/agent/config"
+	"github.com/k3s-io/k3s/pkg/agent/staticpod"
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/datadir"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+func List(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return list(app, &cmds.ServerConfig, &cmds.AgentConfig)
+}
+
+func list(app *cli.Context, cfg *cmds.Server, agentCfg *cmds.Agent) error {
+	dataDir, err := datadir.Resolve(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+	podManifests := filepath.Join(dataDir, "agent", config.DefaultPodManifestPath)
+
+	manifests, err := staticpod.List(agentCfg.StaticPodDir)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 4, 3, ' ', 0)
+	defer w.Flush()
+
+	format := "%s\t%s\t%s\n"
+	fmt.Fprintf(w, format, "MANIFEST", "POD", "STATUS")
+	for _, m := range manifests {
+		status := "pending"
+		if m.Err != nil {
+			status = "invalid: " + m.Err.Error()
+		} else if synced, err := os.ReadFile(staticpod.SyncedPath(podManifests, m.Path)); err == nil {
+			if current, err := os.ReadFile(m.Path); err == nil && bytes.Equal(synced, current) {
+				status = "synced"
+			}
+		}
+		podName := m.PodName
+		if podName == "" {
+			podName = "<none>"
+		}
+		fmt.Fprintf(w, format, filepath.Base(m.Path), podName, status)
+	}
+	return nil
+}
+
+func Validate(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return validate(app, &cmds.AgentConfig)
+}
+
+func validate(app *cli.Context, agentCfg *cmds.Agent) error {
+	var manifests []*staticpod.Manifest
+	if app.NArg() > 0 {
+		for _, path := range app.Args() {
+			manifests = append(manifests, staticpod.Validate(path))
+		}
+	} else {
+		var err error
+		manifests, err = staticpod.List(agentCfg.StaticPodDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	var invalid int
+	for _, m := range manifests {
+		if m.Err != nil {
+			fmt.Printf("%s: invalid: %v\n", m.Path, m.Err)
+			invalid++
+			continue
+		}
+		fmt.Printf("%s: valid pod %s\n", m.Path, m.PodName)
+	}
+	if invalid > 0 {
+		return errors.Errorf("%d of %d static pod manifests are invalid", invalid, len(manifests))
+	}
+	return nil
+}
@@ -25,6 +25,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
 	bootstraputil "k8s.io/cluster-bootstrap/token/util"
 	"k8s.io/utils/ptr"
@@ -169,6 +171,62 @@ func Rotate(app *cli.Context) error {
 	return nil
 }
 
+func Kubeconfig(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return kubeconfig(app, &cmds.TokenConfig)
+}
+
+func kubeconfig(app *cli.Context, cfg *cmds.Token) error {
+	if len(app.Args()) > 0 {
+		return util.ErrCommandNoArgs
+	}
+	info, err := serverAccess(cfg)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(handlers.ViewerTokenRequest{TTL: cfg.TTL})
+	if err != nil {
+		return err
+	}
+	b, err = info.Post("/v1-"+version.Program+"/token/viewer", b)
+	if err != nil {
+		return errors.Wrap(err, "failed to exchange token; check that servers are started with --token-viewer, and see server log for details")
+	}
+	vtr := &handlers.ViewerTokenResponse{}
+	if err := json.Unmarshal(b, vtr); err != nil {
+		return err
+	}
+
+	name := version.Program + "-viewer"
+	config := clientcmdapi.NewConfig()
+	config.Clusters[name] = &clientcmdapi.Cluster{
+		Server:                   info.BaseURL,
+		CertificateAuthorityData: info.CACerts,
+	}
+	config.AuthInfos[name] = &clientcmdapi.AuthInfo{
+		Token: vtr.Token,
+	}
+	config.Contexts[name] = &clientcmdapi.Context{
+		Cluster:  name,
+		AuthInfo: name,
+	}
+	config.CurrentContext = name
+
+	if cfg.Output == "" {
+		b, err := clientcmd.Write(*config)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(b))
+	} else if err := clientcmd.WriteToFile(*config, cfg.Output); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "View-only kubeconfig is valid until %s\n", vtr.ExpiresAt.Local().Format(time.RFC3339))
+	return nil
+}
+
 func serverAccess(cfg *cmds.Token) (*clientaccess.Info, error) {
 	// hide process arguments from ps output, since they likely contain tokens.
 	proctitle.SetProcTitle(os.Args[0] + " token")
@@ -2,6 +2,7 @@ package clientaccess
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
@@ -16,6 +17,7 @@ import (
 	"time"
 
 	"github.com/k3s-io/k3s/pkg/kubeadm"
+	"github.com/k3s-io/k3s/pkg/tracing"
 	"github.com/pkg/errors"
 	certutil "github.com/rancher/dynamiclistener/cert"
 	"github.com/sirupsen/logrus"
@@ -27,6 +29,9 @@ import (
 const (
 	tokenPrefix  = "K10"
 	caHashLength = sha256.Size * 2
+	// caPinPrefix is the prefix of kubeadm-style CA public key pins, as accepted by
+	// kubeadm's --discovery-token-ca-cert-hash flag and generated by Cluster API bootstrap providers.
+	caPinPrefix = "sha256:"
 
 	defaultClientTimeout = 10 * time.Second
 )
@@ -120,6 +125,34 @@ func ParseAndValidateToken(server string, token string, options ...ValidationOpt
 	return info, nil
 }
 
+// ParseTokenWithCACerts parses a token, and uses the provided CA bundle to validate the server's certificate,
+// instead of retrieving the bundle from the server and validating it against the caHash from the token.
+// This allows an agent that already trusts the cluster CA to keep using its token after the CA has been
+// rotated, when the token's caHash no longer matches the server's CA bundle.
+func ParseTokenWithCACerts(server string, token string, cacerts []byte, options ...ValidationOption) (*Info, error) {
+	info, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, option := range options {
+		option(info)
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid server url, failed to parse: %s", server)
+	}
+	if u.Scheme != "https" {
+		return nil, errors.New("only https:// URLs are supported, invalid scheme: " + server)
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+
+	info.BaseURL = u.String()
+	info.CACerts = cacerts
+	return info, nil
+}
+
 // setAndValidateServer updates the remote server's cert info, and validates it against the provided hash
 func (i *Info) setAndValidateServer(server string) error {
 	if err := i.setServer(server); err != nil {
@@ -130,11 +163,52 @@ func (i *Info) setAndValidateServer(server string) error {
 
 // validateCACerts returns a boolean indicating whether or not a CA bundle matches the
 // provided hash, and a string containing the hash of the CA bundle.
+// If the hash is a kubeadm-style CA public key pin, it is matched against the public key
+// of each CA certificate in the bundle.
 func validateCACerts(cacerts []byte, hash string) (bool, string) {
+	if strings.HasPrefix(hash, caPinPrefix) {
+		pins, _ := pinCA(cacerts)
+		for _, pin := range pins {
+			if hash == pin {
+				return true, pin
+			}
+		}
+		return false, strings.Join(pins, ",")
+	}
 	newHash, _ := hashCA(cacerts)
 	return hash == newHash, newHash
 }
 
+// pinCA returns kubeadm-style public key pins for all CA certificates in a CA bundle.
+// A pin is the hex-encoded SHA256 digest of the certificate's DER-encoded SubjectPublicKeyInfo,
+// prefixed with the hash algorithm.
+func pinCA(b []byte) ([]string, error) {
+	certs, err := certutil.ParseCertsPEM(b)
+	if err != nil {
+		return nil, err
+	}
+
+	pins := []string{}
+	for _, cert := range certs {
+		if !cert.IsCA {
+			continue
+		}
+		digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		pins = append(pins, caPinPrefix+hex.EncodeToString(digest[:]))
+	}
+	return pins, nil
+}
+
+// isCAPin returns true if the hash is a valid kubeadm-style CA public key pin.
+func isCAPin(hash string) bool {
+	digest, ok := strings.CutPrefix(hash, caPinPrefix)
+	if !ok || len(digest) != caHashLength {
+		return false
+	}
+	_, err := hex.DecodeString(digest)
+	return err == nil
+}
+
 // hashCA returns the hex-encoded SHA256 digest of a CA bundle.
 // If the certificate bundle contains only a single certificate, a legacy hash is generated from
 // the literal bytes of the file; usually a PEM-encoded self-signed cluster CA certificate.
@@ -214,7 +288,7 @@ func parseToken(token string) (*Info, error) {
 	token = parts[0]
 	if len(parts) > 1 {
 		hashLen := len(parts[0])
-		if hashLen > 0 && hashLen != caHashLength {
+		if hashLen > 0 && hashLen != caHashLength && !isCAPin(parts[0]) {
 			return nil, errors.New("invalid token CA hash length")
 		}
 		info.caHash = parts[0]
@@ -283,6 +357,14 @@ func WithTimeout(d time.Duration) ClientOption {
 	}
 }
 
+// WithTraceContext adds the trace context carried by ctx to the request headers, so that the
+// request is traced as part of the caller's operation.
+func WithTraceContext(ctx context.Context) RequestOption {
+	return func(r *http.Request) {
+		tracing.Inject(ctx, r.Header)
+	}
+}
+
 func WithHeader(k, v string) RequestOption {
 	return func(r *http.Request) {
 		r.Header.Add(k, v)
@@ -144,6 +144,7 @@ func Test_UnitValidTokens(t *testing.T) {
 	server := newTLSServer(t, defaultUsername, defaultPassword, false)
 	defer server.Close()
 	digest, _ := hashCA(getServerCA(server))
+	pins, _ := pinCA(getServerCA(server))
 
 	testCases := []struct {
 		server         string
@@ -157,6 +158,8 @@ func Test_UnitValidTokens(t *testing.T) {
 		{server.URL, "K10" + digest + ":::" + defaultPassword, "", defaultPassword, ""},
 		{server.URL, "K10" + digest + "::" + defaultUsername + ":" + defaultPassword, defaultUsername, defaultPassword, ""},
 		{server.URL, "K10" + digest + "::" + defaultToken, "", "", defaultToken},
+		{server.URL, "K10" + pins[0] + "::" + defaultToken, "", "", defaultToken},
+		{server.URL, "K10" + pins[0] + "::" + defaultUsername + ":" + defaultPassword, defaultUsername, defaultPassword, ""},
 	}
 
 	for _, testCase := range testCases {
@@ -174,6 +177,7 @@ func Test_UnitInvalidTokens(t *testing.T) {
 	server := newTLSServer(t, defaultUsername, defaultPassword, false)
 	defer server.Close()
 	digest, _ := hashCA(getServerCA(server))
+	pins, _ := pinCA(getServerCA(server))
 
 	testCases := []struct {
 		server   string
@@ -185,6 +189,10 @@ func Test_UnitInvalidTokens(t *testing.T) {
 		{server.URL, "K10::x", "invalid token format"},
 		{server.URL, "K10::x:", "invalid token format"},
 		{server.URL, "K10XX::x:y", "invalid token CA hash length"},
+		{server.URL, "K10sha256:XX::x:y", "invalid token CA hash length"},
+		{server.URL,
+			"K10sha256:0000000000000000000000000000000000000000000000000000000000000000::x:y",
+			"token CA hash does not match the Cluster CA certificate hash: sha256:0000000000000000000000000000000000000000000000000000000000000000 != " + pins[0]},
 		{server.URL,
 			"K10XXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX::x:y",
 			"token CA hash does not match the Cluster CA certificate hash: XXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX != " + digest},
@@ -344,6 +352,41 @@ func Test_UnitParseAndGet(t *testing.T) {
 	}
 }
 
+// Test_UnitParseTokenWithCACerts tests that a token whose CA hash no longer matches the server's CA bundle
+// can be used with a CA bundle that is already trusted, as is the case after the cluster CA is rotated.
+func Test_UnitParseTokenWithCACerts(t *testing.T) {
+	assert := assert.New(t)
+	server := newTLSServer(t, defaultUsername, defaultPassword, false)
+	defer server.Close()
+	otherServer := newTLSServer(t, defaultUsername, defaultPassword, false)
+	defer otherServer.Close()
+
+	// token with the CA hash of a different server
+	digest, _ := hashCA(getServerCA(otherServer))
+	token := (&Info{CACerts: getServerCA(otherServer), Username: defaultUsername, Password: defaultPassword, caHash: digest}).String()
+
+	_, err := ParseAndValidateToken(server.URL, token)
+	assert.Error(err)
+
+	info, err := ParseTokenWithCACerts(server.URL+"/", token, getServerCA(server))
+	if assert.NoError(err) {
+		assert.Equal(server.URL, info.BaseURL)
+		assert.Equal(defaultUsername, info.Username)
+		_, err = info.Get("/cacerts")
+		assert.NoError(err)
+	}
+
+	// the server's cert is not trusted by the other server's CA bundle
+	info, err = ParseTokenWithCACerts(server.URL, token, getServerCA(otherServer))
+	if assert.NoError(err) {
+		_, err = info.Get("/cacerts")
+		assert.Error(err)
+	}
+
+	_, err = ParseTokenWithCACerts("http://"+server.Listener.Addr().String(), token, getServerCA(server))
+	assert.Error(err)
+}
+
 // newTLSServer returns a HTTPS server that mocks the basic functionality required to validate K3s join tokens.
 // Each call to this function will generate new CA and server certificates unique to the returned server.
 func newTLSServer(t *testing.T, username, password string, sendWrongCA bool) *httptest.Server {
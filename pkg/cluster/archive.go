@@ -0,0 +1,276 @@
+package cluster
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/bootstrap"
+	"github.com/k3s-io/k3s/pkg/clientaccess"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/daemons/control/deps"
+	"github.com/k3s-io/k3s/pkg/deploy"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Names of files within a cluster archive
+const (
+	archiveBootstrapFile  = "bootstrap.json"
+	archiveTokenFile      = "token"
+	archiveAgentTokenFile = "agent-token"
+	archiveManifestsDir   = "manifests"
+)
+
+// ArchiveTokens holds the join tokens read from a cluster archive.
+type ArchiveTokens struct {
+	Token      string
+	AgentToken string
+}
+
+// Export writes a gzipped tar archive containing the bootstrap data, join tokens, and manifest
+// overrides from the server data-dir. The archive can be used to create a new cluster that shares
+// the same certificate authorities and configuration, via Import. If rekey is set, new tokens are
+// generated and the passwd file is left out of the archive, so that the new cluster does not accept
+// the tokens of the original cluster.
+func Export(w io.Writer, dataDir string, rekey bool) error {
+	control := archiveControl(dataDir)
+	files := map[string][]byte{}
+
+	buf := &bytes.Buffer{}
+	if err := bootstrap.ReadFromDisk(buf, &control.Runtime.ControlRuntimeBootstrap); err != nil {
+		return errors.Wrap(err, "failed to read bootstrap data")
+	}
+	data := bootstrap.PathsDataformat{}
+	if err := json.NewDecoder(buf).Decode(&data); err != nil {
+		return err
+	}
+	if _, ok := data["ServerCA"]; !ok {
+		return fmt.Errorf("no cluster CA certificates found in %s", dataDir)
+	}
+
+	if rekey {
+		delete(data, "PasswdFile")
+		serverToken, agentToken, err := newTokens(data["ServerCA"].Content)
+		if err != nil {
+			return errors.Wrap(err, "failed to generate tokens")
+		}
+		files[archiveTokenFile] = []byte(serverToken + "\n")
+		files[archiveAgentTokenFile] = []byte(agentToken + "\n")
+	} else {
+		for _, name := range []string{archiveTokenFile, archiveAgentTokenFile} {
+			b, err := os.ReadFile(filepath.Join(dataDir, name))
+			if err == nil {
+				files[name] = b
+			} else if !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	files[archiveBootstrapFile] = b
+
+	manifests, err := manifestOverrides(filepath.Join(dataDir, "manifests"))
+	if err != nil {
+		return errors.Wrap(err, "failed to read manifests")
+	}
+	for name, content := range manifests {
+		files[path.Join(archiveManifestsDir, name)] = content
+	}
+
+	return writeArchive(w, files)
+}
+
+// Import extracts a cluster archive created by Export into the server data-dir, and returns the
+// tokens from the archive. The archive is not imported if the data-dir already contains cluster
+// CA certificates; in that case the returned tokens are empty.
+func Import(r io.Reader, dataDir string) (*ArchiveTokens, error) {
+	control := archiveControl(dataDir)
+	tokens := &ArchiveTokens{}
+
+	if _, err := os.Stat(control.Runtime.ServerCA); err == nil {
+		logrus.Infof("Skipping cluster import: CA certificates already exist in %s", dataDir)
+		return tokens, nil
+	}
+
+	files, err := readArchive(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read cluster archive")
+	}
+
+	b, ok := files[archiveBootstrapFile]
+	if !ok {
+		return nil, fmt.Errorf("cluster archive does not contain %s", archiveBootstrapFile)
+	}
+	data := bootstrap.PathsDataformat{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, errors.Wrap(err, "failed to decode bootstrap data")
+	}
+	if err := bootstrap.WriteToDiskFromStorage(data, &control.Runtime.ControlRuntimeBootstrap); err != nil {
+		return nil, err
+	}
+
+	for name, content := range files {
+		name, ok := strings.CutPrefix(name, archiveManifestsDir+"/")
+		if !ok {
+			continue
+		}
+		p := filepath.Join(dataDir, "manifests", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			return nil, err
+		}
+		logrus.Info("Writing manifest: ", p)
+		if err := os.WriteFile(p, content, 0600); err != nil {
+			return nil, errors.Wrapf(err, "failed to write to %s", p)
+		}
+	}
+
+	tokens.Token = strings.TrimSpace(string(files[archiveTokenFile]))
+	tokens.AgentToken = strings.TrimSpace(string(files[archiveAgentTokenFile]))
+	return tokens, nil
+}
+
+// archiveControl returns a minimal control config with runtime file paths for the data-dir.
+func archiveControl(dataDir string) *config.Control {
+	control := &config.Control{
+		DataDir: dataDir,
+		Runtime: config.NewRuntime(nil),
+	}
+	deps.CreateRuntimeCertFiles(control)
+	return control
+}
+
+// newTokens returns new random server and agent tokens, in the full format that includes
+// the CA hash.
+func newTokens(caCerts []byte) (string, string, error) {
+	serverPass, err := util.Random(16)
+	if err != nil {
+		return "", "", err
+	}
+	agentPass, err := util.Random(16)
+	if err != nil {
+		return "", "", err
+	}
+	serverToken, err := clientaccess.FormatTokenBytes("server:"+serverPass, caCerts)
+	if err != nil {
+		return "", "", err
+	}
+	agentToken, err := clientaccess.FormatTokenBytes("node:"+agentPass, caCerts)
+	if err != nil {
+		return "", "", err
+	}
+	return serverToken, agentToken, nil
+}
+
+// manifestOverrides returns the content of files in the manifests dir that are not
+// packaged manifests, such as .skip files and user-provided manifests. Packaged
+// manifests are not included, as they are written by the server at startup.
+func manifestOverrides(dir string) (map[string][]byte, error) {
+	assets := deploy.AssetNames()
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(assets, rel) {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = b
+		return nil
+	})
+	return files, err
+}
+
+func writeArchive(w io.Writer, files map[string][]byte) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	for _, name := range names {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(files[name])),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func readArchive(r io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if !filepath.IsLocal(hdr.Name) {
+			return nil, fmt.Errorf("invalid file name %q", hdr.Name)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = b
+	}
+	return files, nil
+}
@@ -0,0 +1,113 @@
+package cluster
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k3s-io/k3s/pkg/clientaccess"
+	"github.com/k3s-io/k3s/pkg/deploy"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func Test_UnitArchiveExportImport(t *testing.T) {
+	caCert, caKey, err := certutil.GenerateSelfSignedCertKey("k3s-server-ca", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverToken, err := clientaccess.FormatTokenBytes("server:serverpass", caCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentToken, err := clientaccess.FormatTokenBytes("node:agentpass", caCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srcDir := t.TempDir()
+	srcFiles := map[string]string{
+		"tls/server-ca.crt":           string(caCert),
+		"tls/server-ca.key":           string(caKey),
+		"cred/passwd":                 "serverpass,server,server,k3s:server\n",
+		"token":                       serverToken + "\n",
+		"agent-token":                 agentToken + "\n",
+		"manifests/traefik.yaml.skip": "",
+		"manifests/custom/app.yaml":   "kind: ConfigMap\n",
+	}
+	if assets := deploy.AssetNames(); len(assets) > 0 {
+		srcFiles[filepath.Join("manifests", assets[0])] = "packaged\n"
+	}
+	for name, content := range srcFiles {
+		p := filepath.Join(srcDir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		rekey          bool
+		wantPasswd     bool
+		wantSameTokens bool
+	}{
+		{
+			name:           "Export and import",
+			wantPasswd:     true,
+			wantSameTokens: true,
+		},
+		{
+			name:  "Export with rekey and import",
+			rekey: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := Export(buf, srcDir, tt.rekey); err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+
+			dstDir := t.TempDir()
+			tokens, err := Import(bytes.NewReader(buf.Bytes()), dstDir)
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+
+			for _, name := range []string{"tls/server-ca.crt", "tls/server-ca.key", "manifests/traefik.yaml.skip", "manifests/custom/app.yaml"} {
+				b, err := os.ReadFile(filepath.Join(dstDir, name))
+				if err != nil {
+					t.Errorf("Import() did not write %s: %v", name, err)
+				} else if string(b) != srcFiles[name] {
+					t.Errorf("Import() wrote %s = %q, want %q", name, b, srcFiles[name])
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dstDir, "cred", "passwd")); (err == nil) != tt.wantPasswd {
+				t.Errorf("Import() wrote passwd = %v, want %v", err == nil, tt.wantPasswd)
+			}
+			if entries, _ := os.ReadDir(filepath.Join(dstDir, "manifests")); len(entries) != 2 {
+				t.Errorf("Import() wrote %d manifest entries, want 2", len(entries))
+			}
+
+			if (tokens.Token == serverToken) != tt.wantSameTokens || (tokens.AgentToken == agentToken) != tt.wantSameTokens {
+				t.Errorf("Import() tokens = %+v, want same tokens %v", tokens, tt.wantSameTokens)
+			}
+			if strings.Split(tokens.Token, "::")[0] != strings.Split(serverToken, "::")[0] {
+				t.Errorf("Import() token %s does not have CA hash of %s", tokens.Token, serverToken)
+			}
+
+			// A second import must not overwrite the existing cluster CA
+			tokens, err = Import(bytes.NewReader(buf.Bytes()), dstDir)
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			if tokens.Token != "" || tokens.AgentToken != "" {
+				t.Errorf("Import() into existing data-dir returned tokens %+v", tokens)
+			}
+		})
+	}
+}
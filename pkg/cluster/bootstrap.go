@@ -282,7 +282,7 @@ func (c *Cluster) ReconcileBootstrapData(ctx context.Context, buf io.ReadSeeker,
 			return nil
 		}
 
-		dbRawData, err = decrypt(normalizedToken, value.Data)
+		dbRawData, err = Decrypt(normalizedToken, value.Data)
 		if err != nil {
 			return err
 		}
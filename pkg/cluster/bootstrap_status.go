@@ -0,0 +1,171 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/bootstrap"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/kine/pkg/client"
+	"github.com/pkg/errors"
+)
+
+// Bootstrap file states, as reported by BootstrapStatus
+const (
+	// BootstrapFileMatch indicates that the file on disk matches the datastore.
+	BootstrapFileMatch = "match"
+	// BootstrapFileDiffers indicates that the file on disk differs from the datastore, and will be
+	// replaced with the datastore copy when the server is restarted.
+	BootstrapFileDiffers = "differs"
+	// BootstrapFileNewerOnDisk indicates that the file on disk differs from the datastore and is
+	// newer; the server will refuse to start until one of the copies is removed.
+	BootstrapFileNewerOnDisk = "newer-on-disk"
+	// BootstrapFileMissingOnDisk indicates that the file is only present in the datastore, and will
+	// be written to disk when the server is restarted.
+	BootstrapFileMissingOnDisk = "missing-on-disk"
+	// BootstrapFileMissingInDatastore indicates that the file is only present on disk.
+	BootstrapFileMissingInDatastore = "missing-in-datastore"
+)
+
+// BootstrapFileStatus describes a single bootstrap file, as stored on disk and in the datastore.
+type BootstrapFileStatus struct {
+	Name               string    `json:"name"`
+	Path               string    `json:"path"`
+	Status             string    `json:"status"`
+	DiskHash           string    `json:"diskHash,omitempty"`
+	DiskTimestamp      time.Time `json:"diskTimestamp,omitempty"`
+	DatastoreHash      string    `json:"datastoreHash,omitempty"`
+	DatastoreTimestamp time.Time `json:"datastoreTimestamp,omitempty"`
+}
+
+// BootstrapStatus compares the bootstrap data in the datastore to the files on a server's disk.
+// Revision is the datastore revision of the bootstrap key, which changes whenever the bootstrap
+// data is saved.
+type BootstrapStatus struct {
+	Server   string                `json:"server"`
+	Revision int64                 `json:"revision"`
+	Files    []BootstrapFileStatus `json:"files"`
+}
+
+// Mismatched returns the files that are not in sync between disk and the datastore.
+func (s *BootstrapStatus) Mismatched() []BootstrapFileStatus {
+	files := []BootstrapFileStatus{}
+	for _, file := range s.Files {
+		if file.Status != BootstrapFileMatch {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// GetBootstrapStatus reads and decrypts the bootstrap data from the datastore, and compares it to the
+// bootstrap files on disk. The datastore is not modified.
+func GetBootstrapStatus(ctx context.Context, config *config.Control) (*BootstrapStatus, error) {
+	token, err := util.ReadTokenFromFile(config.Runtime.ServerToken, config.Runtime.ServerCA, config.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	normalizedToken, err := util.NormalizeToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	storageClient, err := client.New(config.Runtime.EtcdConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer storageClient.Close()
+
+	value, err := storageClient.Get(ctx, storageKey(normalizedToken))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get bootstrap data from datastore")
+	}
+	data, err := Decrypt(normalizedToken, value.Data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt bootstrap data")
+	}
+
+	files := make(bootstrap.PathsDataformat)
+	if !isMigrated(bytes.NewReader(data), &files) {
+		return nil, errors.New("bootstrap data in datastore has not been migrated to the current format")
+	}
+
+	statuses, err := compareBootstrapFiles(files, &config.Runtime.ControlRuntimeBootstrap)
+	if err != nil {
+		return nil, err
+	}
+	return &BootstrapStatus{
+		Server:   config.ServerNodeName,
+		Revision: value.Modified,
+		Files:    statuses,
+	}, nil
+}
+
+// compareBootstrapFiles compares the given bootstrap files to their paths on disk. Files are
+// considered newer on disk using the same rules as ReconcileBootstrapData.
+func compareBootstrapFiles(files bootstrap.PathsDataformat, crb *config.ControlRuntimeBootstrap) ([]BootstrapFileStatus, error) {
+	paths, err := bootstrap.ObjToMap(crb)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []BootstrapFileStatus{}
+	for name, path := range paths {
+		if path == "" {
+			continue
+		}
+		status := BootstrapFileStatus{Name: name, Path: path}
+
+		file, inDatastore := files[name]
+		if inDatastore {
+			status.DatastoreHash = hashBytes(file.Content)
+			status.DatastoreTimestamp = file.Timestamp
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		onDisk := err == nil
+		if onDisk {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			status.DiskHash = hashBytes(content)
+			status.DiskTimestamp = info.ModTime()
+		}
+
+		switch {
+		case !onDisk && !inDatastore:
+			continue
+		case !onDisk:
+			status.Status = BootstrapFileMissingOnDisk
+		case !inDatastore:
+			status.Status = BootstrapFileMissingInDatastore
+		case bytes.Equal(content, file.Content):
+			status.Status = BootstrapFileMatch
+		case status.DiskTimestamp.Unix()-file.Timestamp.Unix() >= systemTimeSkew:
+			status.Status = BootstrapFileNewerOnDisk
+		default:
+			status.Status = BootstrapFileDiffers
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses, nil
+}
+
+func hashBytes(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,76 @@
+package cluster
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/bootstrap"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+)
+
+func Test_UnitCompareBootstrapFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	crb := &config.ControlRuntimeBootstrap{
+		ServerCA:           filepath.Join(dir, "server-ca.crt"),
+		ServerCAKey:        filepath.Join(dir, "server-ca.key"),
+		ClientCA:           filepath.Join(dir, "client-ca.crt"),
+		ClientCAKey:        filepath.Join(dir, "client-ca.key"),
+		RequestHeaderCA:    filepath.Join(dir, "request-header-ca.crt"),
+		RequestHeaderCAKey: filepath.Join(dir, "request-header-ca.key"),
+	}
+
+	write := func(path, content string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(crb.ServerCA, "server-ca", now)
+	write(crb.ServerCAKey, "local-server-ca-key", now.Add(-time.Hour))
+	write(crb.ClientCA, "local-client-ca", now)
+	write(crb.RequestHeaderCA, "request-header-ca", now)
+
+	files := bootstrap.PathsDataformat{
+		"ServerCA":    {Timestamp: now, Content: []byte("server-ca")},
+		"ServerCAKey": {Timestamp: now, Content: []byte("server-ca-key")},
+		"ClientCA":    {Timestamp: now.Add(-time.Hour), Content: []byte("client-ca")},
+		"ClientCAKey": {Timestamp: now, Content: []byte("client-ca-key")},
+	}
+
+	statuses, err := compareBootstrapFiles(files, crb)
+	if err != nil {
+		t.Fatalf("compareBootstrapFiles() error = %v", err)
+	}
+
+	want := map[string]string{
+		"ClientCA":        BootstrapFileNewerOnDisk,
+		"ClientCAKey":     BootstrapFileMissingOnDisk,
+		"RequestHeaderCA": BootstrapFileMissingInDatastore,
+		"ServerCA":        BootstrapFileMatch,
+		"ServerCAKey":     BootstrapFileDiffers,
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("compareBootstrapFiles() returned %d files, want %d: %+v", len(statuses), len(want), statuses)
+	}
+	for i, status := range statuses {
+		if i > 0 && statuses[i-1].Name > status.Name {
+			t.Errorf("compareBootstrapFiles() files not sorted: %s before %s", statuses[i-1].Name, status.Name)
+		}
+		if status.Status != want[status.Name] {
+			t.Errorf("compareBootstrapFiles() %s status = %s, want %s", status.Name, status.Status, want[status.Name])
+		}
+	}
+	if statuses[3].DiskHash != statuses[3].DatastoreHash {
+		t.Errorf("compareBootstrapFiles() ServerCA hashes differ: %s != %s", statuses[3].DiskHash, statuses[3].DatastoreHash)
+	}
+
+	mismatched := (&BootstrapStatus{Files: statuses}).Mismatched()
+	if len(mismatched) != 4 {
+		t.Errorf("Mismatched() returned %d files, want 4", len(mismatched))
+	}
+}
@@ -20,9 +20,9 @@ func storageKey(passphrase string) string {
 	return "/bootstrap/" + util.ShortHash(passphrase, 12)
 }
 
-// encrypt encrypts a byte slice using aes+gcm with a pbkdf2 key derived from the passphrase and a random salt.
+// Encrypt encrypts a byte slice using aes+gcm with a pbkdf2 key derived from the passphrase and a random salt.
 // It returns a byte slice containing the salt and base64-encoded ciphertext.
-func encrypt(passphrase string, plaintext []byte) ([]byte, error) {
+func Encrypt(passphrase string, plaintext []byte) ([]byte, error) {
 	salt, err := util.Random(8)
 	if err != nil {
 		return nil, err
@@ -49,9 +49,9 @@ func encrypt(passphrase string, plaintext []byte) ([]byte, error) {
 	return []byte(salt + ":" + base64.StdEncoding.EncodeToString(sealed)), nil
 }
 
-// decrypt attempts to decrypt the byte slice using the supplied passphrase.
-// The input byte slice should be the ciphertext output from the encrypt function.
-func decrypt(passphrase string, ciphertext []byte) ([]byte, error) {
+// Decrypt attempts to decrypt the byte slice using the supplied passphrase.
+// The input byte slice should be the ciphertext output from the Encrypt function.
+func Decrypt(passphrase string, ciphertext []byte) ([]byte, error) {
 	parts := strings.SplitN(string(ciphertext), ":", 2)
 	if len(parts) != 2 {
 		return nil, fmt.Errorf("invalid cipher text, not : delimited")
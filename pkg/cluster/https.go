@@ -45,11 +45,24 @@ func (c *Cluster) newListener(ctx context.Context) (net.Listener, http.Handler,
 	if err != nil {
 		return nil, nil, err
 	}
+	// If a legacy port is configured, serve on it as well, using the same certificate
+	if c.config.SupervisorLegacyPort != 0 {
+		legacy, err := util.ListenWithLoopback(ctx, c.config.BindAddress, strconv.Itoa(c.config.SupervisorLegacyPort))
+		if err != nil {
+			tcp.Close()
+			return nil, nil, err
+		}
+		logrus.Infof("Supervisor listening on legacy port %d in addition to port %d", c.config.SupervisorLegacyPort, c.config.SupervisorPort)
+		tcp = util.MergeListeners(tcp, legacy)
+	}
 	certs, key, err := factory.LoadCertsChain(c.config.Runtime.ServerCA, c.config.Runtime.ServerCAKey)
 	if err != nil {
 		return nil, nil, err
 	}
 	c.config.SANs = append(c.config.SANs, "kubernetes", "kubernetes.default", "kubernetes.default.svc", "kubernetes.default.svc."+c.config.ClusterDomain)
+	for _, domain := range c.config.PreviousClusterDomains {
+		c.config.SANs = append(c.config.SANs, "kubernetes.default.svc."+domain)
+	}
 	if c.config.SANSecurity {
 		c.config.Runtime.ClusterControllerStarts["server-cn-filter"] = func(ctx context.Context) {
 			registerAddressHandlers(ctx, c)
@@ -108,6 +121,9 @@ func (c *Cluster) initClusterAndHTTPS(ctx context.Context) error {
 		return err
 	}
 
+	// Track which supervisor ports are in use by agents
+	handler = c.listenerMetrics(handler)
+
 	// Create a HTTP server with the registered request handlers, using logrus for logging
 	server := http.Server{
 		Handler: handler,
@@ -42,8 +42,11 @@ func Default() Driver {
 }
 
 // SnapshotResult is returned by the Snapshot function,
-// and lists the names of created and deleted snapshots.
+// and lists the names of created, deleted, and verified snapshots.
+// Snapshots that failed verification are mapped to the reason for the failure.
 type SnapshotResult struct {
-	Created []string `json:"created,omitempty"`
-	Deleted []string `json:"deleted,omitempty"`
+	Created  []string          `json:"created,omitempty"`
+	Deleted  []string          `json:"deleted,omitempty"`
+	Verified []string          `json:"verified,omitempty"`
+	Failed   map[string]string `json:"failed,omitempty"`
 }
@@ -0,0 +1,81 @@
+package cluster
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/k3s-io/k3s/pkg/metrics"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/request/x509"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+)
+
+var (
+	supervisorRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: version.Program + "_supervisor_requests_total",
+		Help: "Total requests to the supervisor, by listener port and requesting node",
+	}, []string{"port", "node"})
+
+	registerMetrics sync.Once
+)
+
+// listenerMetrics wraps the supervisor request handler, counting requests by the port they were received
+// on and the node that sent them. When a legacy port is configured, nodes still using it are logged once,
+// so that administrators can tell when it is safe to stop listening on the legacy port.
+func (c *Cluster) listenerMetrics(handler http.Handler) http.Handler {
+	registerMetrics.Do(func() {
+		metrics.DefaultRegisterer.MustRegister(supervisorRequests)
+	})
+	var nodeAuth authenticator.Request
+	if ca, err := dynamiccertificates.NewDynamicCAContentFromFile("client-ca", c.config.Runtime.ClientCA); err != nil {
+		logrus.Warnf("Failed to load client CA, supervisor requests will not be counted by node: %v", err)
+	} else {
+		nodeAuth = x509.NewDynamic(ca.VerifyOptions, x509.CommonNameUserConversion)
+	}
+	legacyPort := strconv.Itoa(c.config.SupervisorLegacyPort)
+	legacyNodes := sync.Map{}
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		port := requestPort(req)
+		nodeName := requestNodeName(req, nodeAuth)
+		supervisorRequests.WithLabelValues(port, nodeName).Inc()
+		if c.config.SupervisorLegacyPort != 0 && port == legacyPort && nodeName != "" {
+			if _, loaded := legacyNodes.LoadOrStore(nodeName, struct{}{}); !loaded {
+				logrus.Warnf("Node %s is using legacy supervisor port %s; agents should be restarted or reconfigured to use port %d", nodeName, legacyPort, c.config.SupervisorPort)
+			}
+		}
+		handler.ServeHTTP(resp, req)
+	})
+}
+
+// requestPort returns the local port that the request was received on.
+func requestPort(req *http.Request) string {
+	if addr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		if _, port, err := net.SplitHostPort(addr.String()); err == nil {
+			return port
+		}
+	}
+	return ""
+}
+
+// requestNodeName returns the name of the node that sent the request, from the CN of its node client
+// certificate. The certificate is not verified by the supervisor's TLS listener, so it must be verified
+// by the authenticator. An empty string is returned for requests from other clients.
+func requestNodeName(req *http.Request, nodeAuth authenticator.Request) string {
+	if nodeAuth == nil || req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+	resp, ok, err := nodeAuth.AuthenticateRequest(req)
+	if err != nil || !ok {
+		return ""
+	}
+	if nodeName, ok := strings.CutPrefix(resp.User.GetName(), "system:node:"); ok {
+		return nodeName
+	}
+	return ""
+}
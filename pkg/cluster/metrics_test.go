@@ -0,0 +1,147 @@
+package cluster
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	certutil "github.com/rancher/dynamiclistener/cert"
+	authx509 "k8s.io/apiserver/pkg/authentication/request/x509"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+)
+
+func Test_UnitListenerMetrics(t *testing.T) {
+	var ports []int
+	var listeners []net.Listener
+	for range 2 {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		listeners = append(listeners, l)
+		ports = append(ports, l.Addr().(*net.TCPAddr).Port)
+	}
+	c := &Cluster{config: &config.Control{SupervisorPort: ports[0], SupervisorLegacyPort: ports[1], Runtime: &config.ControlRuntime{}}}
+	listener := util.MergeListeners(listeners...)
+	if listener.Addr().String() != listeners[0].Addr().String() {
+		t.Errorf("expected merged listener address %s, got %s", listeners[0].Addr(), listener.Addr())
+	}
+
+	server := &http.Server{Handler: c.listenerMetrics(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {}))}
+	go server.Serve(listener)
+	defer server.Close()
+
+	// requests are counted by port; the node name header is not trusted, so these requests are not
+	// attributed to a node.
+	requests := []struct {
+		port     int
+		nodeName string
+	}{
+		{port: ports[0], nodeName: "agent-1"},
+		{port: ports[1], nodeName: "agent-2"},
+		{port: ports[1], nodeName: "agent-2"},
+		{port: ports[1]},
+	}
+	for _, r := range requests {
+		req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:"+strconv.Itoa(r.port)+"/ping", nil)
+		if r.nodeName != "" {
+			req.Header.Set(version.Program+"-Node-Name", r.nodeName)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	for _, want := range []struct {
+		port     int
+		nodeName string
+		count    float64
+	}{
+		{port: ports[0], count: 1},
+		{port: ports[1], nodeName: "agent-2", count: 0},
+		{port: ports[1], count: 3},
+	} {
+		if got := testutil.ToFloat64(supervisorRequests.WithLabelValues(strconv.Itoa(want.port), want.nodeName)); got != want.count {
+			t.Errorf("expected %v requests from %q on port %d, got %v", want.count, want.nodeName, want.port, got)
+		}
+	}
+}
+
+func Test_UnitRequestNodeName(t *testing.T) {
+	newCA := func(name string) (*x509.Certificate, crypto.Signer) {
+		key, err := certutil.NewPrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: name}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert, key
+	}
+	newCert := func(commonName string, caCert *x509.Certificate, caKey crypto.Signer) *x509.Certificate {
+		key, err := certutil.NewPrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := certutil.NewSignedCert(certutil.Config{
+			CommonName: commonName,
+			Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}, key, caCert, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	clientCA, clientCAKey := newCA("client-ca")
+	otherCA, otherCAKey := newCA("other-ca")
+	clientCAFile := filepath.Join(t.TempDir(), "client-ca.crt")
+	if err := os.WriteFile(clientCAFile, certutil.EncodeCertPEM(clientCA), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ca, err := dynamiccertificates.NewDynamicCAContentFromFile("client-ca", clientCAFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeAuth := authx509.NewDynamic(ca.VerifyOptions, authx509.CommonNameUserConversion)
+
+	tests := []struct {
+		name   string
+		cert   *x509.Certificate
+		header string
+		want   string
+	}{
+		{name: "node cert", cert: newCert("system:node:agent-1", clientCA, clientCAKey), want: "agent-1"},
+		{name: "node cert with spoofed header", cert: newCert("system:node:agent-1", clientCA, clientCAKey), header: "agent-2", want: "agent-1"},
+		{name: "header only", header: "agent-2"},
+		{name: "non-node cert", cert: newCert("system:admin", clientCA, clientCAKey)},
+		{name: "untrusted node cert", cert: newCert("system:node:agent-1", otherCA, otherCAKey)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "https://127.0.0.1:9345/ping", nil)
+			if tt.header != "" {
+				req.Header.Set(version.Program+"-Node-Name", tt.header)
+			}
+			if tt.cert != nil {
+				req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{tt.cert}}
+			}
+			if got := requestNodeName(req, nodeAuth); got != tt.want {
+				t.Errorf("requestNodeName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
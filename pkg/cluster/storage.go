@@ -84,7 +84,7 @@ func Save(ctx context.Context, config *config.Control, override bool) error {
 		return err
 	}
 
-	data, err := encrypt(normalizedToken, buf.Bytes())
+	data, err := Encrypt(normalizedToken, buf.Bytes())
 	if err != nil {
 		return err
 	}
@@ -237,7 +237,7 @@ func (c *Cluster) storageBootstrap(ctx context.Context) error {
 			return false, nil
 		}
 
-		data, err := decrypt(normalizedToken, value.Data)
+		data, err := Decrypt(normalizedToken, value.Data)
 		if err != nil {
 			return false, err
 		}
@@ -327,12 +327,12 @@ func migrateTokens(ctx context.Context, bootstrapList []client.Value, storageCli
 
 func doMigrateToken(ctx context.Context, storageClient client.Client, keyValue client.Value, oldToken, oldTokenKey, newToken, newTokenKey string) error {
 	// make sure that the process is non-destructive by decrypting/re-encrypting/storing the data before deleting the old key
-	data, err := decrypt(oldToken, keyValue.Data)
+	data, err := Decrypt(oldToken, keyValue.Data)
 	if err != nil {
 		return err
 	}
 
-	encryptedData, err := encrypt(newToken, data)
+	encryptedData, err := Encrypt(newToken, data)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,180 @@
+// Package clustercidr tracks the cluster CIDRs that the server was started with. Nodes keep the pod
+// CIDRs that they were allocated, and the controller-manager and flannel both require every node's
+// pod CIDR to be within the cluster CIDR of its IP family, so the cluster CIDRs can only be changed by
+// expanding them to CIDRs that contain the previous ones.
+package clustercidr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	utilsnet "k8s.io/utils/net"
+)
+
+// stateFile is the name of the file in the server data dir that records the cluster CIDRs that the
+// server was last started with.
+const stateFile = "cluster-cidr.json"
+
+// State holds the cluster CIDRs that the server was started with, and the service CIDRs that they must
+// not overlap.
+type State struct {
+	ClusterCIDRs []string `json:"clusterCIDRs"`
+	ServiceCIDRs []string `json:"serviceCIDRs,omitempty"`
+}
+
+// NewState returns the state for the given cluster and service CIDRs.
+func NewState(clusterCIDRs, serviceCIDRs []*net.IPNet) *State {
+	state := &State{}
+	for _, cidr := range clusterCIDRs {
+		state.ClusterCIDRs = append(state.ClusterCIDRs, cidr.String())
+	}
+	for _, cidr := range serviceCIDRs {
+		state.ServiceCIDRs = append(state.ServiceCIDRs, cidr.String())
+	}
+	return state
+}
+
+// Load returns the recorded state, or nil if no state has been recorded.
+func Load(dataDir string) (*State, error) {
+	b, err := os.ReadFile(filepath.Join(dataDir, stateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	state := &State{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", stateFile)
+	}
+	return state, nil
+}
+
+// Check compares the current cluster CIDRs to those that the server was last started with, and logs an
+// error if a previous cluster CIDR is not contained within the current cluster CIDR of the same IP family,
+// as nodes that were allocated pod CIDRs from the previous cluster CIDR will not work until they are
+// deleted and rejoined. The server is still started, as the nodes may already have been deleted. The
+// current cluster CIDRs are recorded.
+func Check(dataDir string, current *State) error {
+	previous, err := Load(dataDir)
+	if err != nil {
+		return err
+	}
+	if previous != nil && !slices.Equal(previous.ClusterCIDRs, current.ClusterCIDRs) {
+		expanded := true
+		for _, cidr := range previous.ClusterCIDRs {
+			if !contained(current.ClusterCIDRs, cidr) {
+				expanded = false
+				logrus.Errorf("Cluster CIDR changed from %s to %s; nodes with pod CIDRs within %s, which is not within the cluster-cidr, must be deleted and rejoined. "+
+					"The cluster-cidr can only be expanded to a CIDR that contains the previous one; run '%s cluster expand-cidr --add <cidr>' to find an expanded cluster-cidr, "+
+					"or revert the change",
+					strings.Join(previous.ClusterCIDRs, ","), strings.Join(current.ClusterCIDRs, ","), cidr, version.Program)
+			}
+		}
+		if expanded {
+			logrus.Infof("Cluster CIDR expanded from %s to %s; run '%s cluster expand-cidr' to find nodes that must be restarted to use the expanded cluster-cidr",
+				strings.Join(previous.ClusterCIDRs, ","), strings.Join(current.ClusterCIDRs, ","), version.Program)
+		}
+	}
+	b, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	return util.AtomicWrite(filepath.Join(dataDir, stateFile), b, 0600)
+}
+
+// Expand returns the cluster CIDRs with the cluster CIDR of the same IP family as the additional CIDR
+// replaced by the smallest CIDR that contains both. Pod CIDRs that have already been allocated to nodes
+// cannot be moved, and the controller-manager and flannel only support one cluster CIDR per IP family,
+// so an error is returned if the expanded CIDR would overlap one of the service CIDRs. With the default
+// cluster-cidr and service-cidr, which are adjacent, the cluster-cidr cannot be expanded.
+func Expand(clusterCIDRs, serviceCIDRs []*net.IPNet, additional *net.IPNet) ([]*net.IPNet, error) {
+	expanded := slices.Clone(clusterCIDRs)
+	for i, cidr := range expanded {
+		if utilsnet.IsIPv6CIDR(cidr) != utilsnet.IsIPv6CIDR(additional) {
+			continue
+		}
+		expanded[i] = supernet(cidr, additional)
+		for _, service := range serviceCIDRs {
+			if !overlaps(expanded[i], service) {
+				continue
+			}
+			if overlaps(grow(cidr), service) {
+				return nil, fmt.Errorf("the cluster-cidr %s cannot be expanded, as any CIDR that contains it overlaps the service-cidr %s. "+
+					"Only one cluster-cidr per IP family is supported, so pods cannot be given addresses from %s without re-creating the cluster with a larger cluster-cidr", cidr, service, additional)
+			}
+			return nil, fmt.Errorf("cannot add %s: the smallest cluster-cidr containing %s and %s is %s, which overlaps the service-cidr %s. "+
+				"Choose an additional CIDR closer to the current cluster-cidr that does not require the service-cidr to be included", additional, cidr, additional, expanded[i], service)
+		}
+		return expanded, nil
+	}
+	return nil, fmt.Errorf("no cluster-cidr of the same IP family as %s; adding an IP family is not supported", additional)
+}
+
+// supernet returns the smallest CIDR that contains both a and b, which must be of the same IP family.
+func supernet(a, b *net.IPNet) *net.IPNet {
+	ones, _ := a.Mask.Size()
+	bOnes, bits := b.Mask.Size()
+	for ones = min(ones, bOnes); ones > 0; ones-- {
+		mask := net.CIDRMask(ones, bits)
+		if a.IP.Mask(mask).Equal(b.IP.Mask(mask)) {
+			break
+		}
+	}
+	mask := net.CIDRMask(ones, bits)
+	return &net.IPNet{IP: a.IP.Mask(mask), Mask: mask}
+}
+
+// grow returns the CIDR that is one bit shorter than cidr, which is the smallest expansion of it.
+func grow(cidr *net.IPNet) *net.IPNet {
+	ones, bits := cidr.Mask.Size()
+	mask := net.CIDRMask(max(ones-1, 0), bits)
+	return &net.IPNet{IP: cidr.IP.Mask(mask), Mask: mask}
+}
+
+// overlaps returns true if the CIDRs overlap.
+func overlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// PendingNodes returns the names of nodes whose agents were not started with the current cluster CIDRs.
+func PendingNodes(nodes []corev1.Node, current *State) []string {
+	var pending []string
+	value := strings.Join(current.ClusterCIDRs, ",")
+	for _, node := range nodes {
+		if node.Annotations[nodeconfig.ClusterCIDRAnnotation] != value {
+			pending = append(pending, node.Name)
+		}
+	}
+	return pending
+}
+
+// contained returns true if the CIDR is contained within one of the CIDRs in outer.
+func contained(outer []string, cidr string) bool {
+	_, in, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	inOnes, inBits := in.Mask.Size()
+	for _, o := range outer {
+		_, out, err := net.ParseCIDR(o)
+		if err != nil {
+			continue
+		}
+		if outOnes, outBits := out.Mask.Size(); outBits == inBits && outOnes <= inOnes && out.Contains(in.IP) {
+			return true
+		}
+	}
+	return false
+}
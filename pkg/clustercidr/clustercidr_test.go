@@ -0,0 +1,134 @@
+package clustercidr
+
+import (
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
+	"github.com/k3s-io/k3s/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	var parsed []*net.IPNet
+	for _, cidr := range cidrs {
+		_, ipnet, _ := net.ParseCIDR(cidr)
+		parsed = append(parsed, ipnet)
+	}
+	return parsed
+}
+
+func Test_UnitCheck(t *testing.T) {
+	dataDir := t.TempDir()
+	// incompatible changes are logged, but do not prevent the server from starting, as the nodes with
+	// pod CIDRs outside the cluster-cidr may already have been deleted.
+	for _, cidrs := range [][]string{
+		{"10.42.0.0/16"},
+		{"10.42.0.0/15"},
+		{"10.42.0.0/15", "fd00:42::/56"},
+		{"10.42.0.0/16", "fd00:42::/56"},
+		{"10.44.0.0/14", "fd00:42::/56"},
+		{"10.40.0.0/13", "fd00:42::/48"},
+	} {
+		if err := Check(dataDir, NewState(parseCIDRs(cidrs...), parseCIDRs("10.96.0.0/16"))); err != nil {
+			t.Fatalf("Check(%v) error = %v", cidrs, err)
+		}
+	}
+	state, err := Load(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"10.40.0.0/13", "fd00:42::/48"}; !slices.Equal(state.ClusterCIDRs, want) {
+		t.Errorf("Load() = %v, want %v", state.ClusterCIDRs, want)
+	}
+	if want := []string{"10.96.0.0/16"}; !slices.Equal(state.ServiceCIDRs, want) {
+		t.Errorf("Load() service CIDRs = %v, want %v", state.ServiceCIDRs, want)
+	}
+}
+
+func Test_UnitExpand(t *testing.T) {
+	tests := []struct {
+		name       string
+		cidrs      []string
+		services   []string
+		additional string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "Adjacent",
+			cidrs:      []string{"10.42.0.0/16"},
+			additional: "10.43.0.0/16",
+			want:       "10.42.0.0/15",
+		},
+		{
+			name:       "Not adjacent",
+			cidrs:      []string{"10.42.0.0/16"},
+			additional: "10.44.0.0/16",
+			want:       "10.40.0.0/13",
+		},
+		{
+			name:       "Already contained",
+			cidrs:      []string{"10.42.0.0/16"},
+			additional: "10.42.128.0/17",
+			want:       "10.42.0.0/16",
+		},
+		{
+			name:       "Dual-stack",
+			cidrs:      []string{"10.42.0.0/16", "fd00:42::/56"},
+			additional: "fd00:42:0:100::/56",
+			want:       "10.42.0.0/16,fd00:42::/55",
+		},
+		{
+			name:       "Default service-cidr",
+			cidrs:      []string{"10.42.0.0/16"},
+			services:   []string{"10.43.0.0/16"},
+			additional: "10.44.0.0/16",
+			wantErr:    true,
+		},
+		{
+			name:       "Overlaps service-cidr",
+			cidrs:      []string{"10.42.0.0/16"},
+			services:   []string{"10.96.0.0/16"},
+			additional: "10.100.0.0/16",
+			wantErr:    true,
+		},
+		{
+			name:       "Does not overlap service-cidr",
+			cidrs:      []string{"10.42.0.0/16"},
+			services:   []string{"10.96.0.0/16"},
+			additional: "10.44.0.0/16",
+			want:       "10.40.0.0/13",
+		},
+		{
+			name:       "Different IP family",
+			cidrs:      []string{"10.42.0.0/16"},
+			additional: "fd00:42::/56",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expanded, err := Expand(parseCIDRs(tt.cidrs...), parseCIDRs(tt.services...), parseCIDRs(tt.additional)[0])
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := util.JoinIPNets(expanded); got != tt.want {
+				t.Errorf("Expand() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_UnitPendingNodes(t *testing.T) {
+	node := func(name, cidrs string) corev1.Node {
+		return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{nodeconfig.ClusterCIDRAnnotation: cidrs}}}
+	}
+	nodes := []corev1.Node{node("node-1", "10.42.0.0/15"), node("node-2", "10.42.0.0/16"), {ObjectMeta: metav1.ObjectMeta{Name: "node-3"}}}
+	pending := PendingNodes(nodes, &State{ClusterCIDRs: []string{"10.42.0.0/15"}})
+	if want := []string{"node-2", "node-3"}; !slices.Equal(pending, want) {
+		t.Errorf("PendingNodes() = %v, want %v", pending, want)
+	}
+}
@@ -0,0 +1,94 @@
+// Package clusterdomain tracks changes to the cluster domain. When the domain is changed, the
+// previous domains continue to be served by CoreDNS, accepted as service account token issuers,
+// and included in the apiserver certificate, so that existing pods and tokens keep working until
+// they have been recreated with the new domain and the migration is finalized.
+package clusterdomain
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// stateFile is the name of the file in the server data dir that records the cluster domain, and
+// any previous domains that are still being migrated from.
+const stateFile = "cluster-domain.json"
+
+// State holds the current cluster domain, and any previous domains that are still in use.
+type State struct {
+	Domain   string   `json:"domain"`
+	Previous []string `json:"previous,omitempty"`
+}
+
+// Validate returns an error if the domain is not a valid DNS subdomain.
+func Validate(domain string) error {
+	if errs := validation.IsDNS1123Subdomain(domain); len(errs) > 0 {
+		return errors.Errorf("invalid cluster-domain %q: %s", domain, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Load returns the recorded state, or nil if no state has been recorded.
+func Load(dataDir string) (*State, error) {
+	b, err := os.ReadFile(filepath.Join(dataDir, stateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	state := &State{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", stateFile)
+	}
+	return state, nil
+}
+
+// Update records the current cluster domain and returns the updated state. If the domain has
+// changed, the previously recorded domain is added to the list of previous domains. Changing back
+// to a previous domain removes it from the list.
+func Update(dataDir, domain string) (*State, error) {
+	state, err := Load(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		state = &State{Domain: domain}
+	}
+	if state.Domain != domain {
+		if !slices.Contains(state.Previous, state.Domain) {
+			state.Previous = append(state.Previous, state.Domain)
+		}
+		state.Domain = domain
+	}
+	state.Previous = slices.DeleteFunc(state.Previous, func(d string) bool { return d == domain })
+	return state, save(dataDir, state)
+}
+
+// Finalize removes the previous domains from the recorded state, and returns the updated state.
+// The previous domains are no longer used once the server is restarted.
+func Finalize(dataDir string) (*State, error) {
+	state, err := Load(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, errors.New("no cluster domain has been recorded; the server has not been started with this data-dir")
+	}
+	state.Previous = nil
+	return state, save(dataDir, state)
+}
+
+func save(dataDir string, state *State) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return util.AtomicWrite(filepath.Join(dataDir, stateFile), b, 0600)
+}
@@ -0,0 +1,64 @@
+package clusterdomain
+
+import (
+	"slices"
+	"testing"
+)
+
+func Test_UnitValidate(t *testing.T) {
+	for _, domain := range []string{"cluster.local", "k8s.example.com"} {
+		if err := Validate(domain); err != nil {
+			t.Errorf("expected %q to be valid: %v", domain, err)
+		}
+	}
+	for _, domain := range []string{"", "Cluster.Local", "cluster..local", "-cluster.local"} {
+		if err := Validate(domain); err == nil {
+			t.Errorf("expected %q to be invalid", domain)
+		}
+	}
+}
+
+func Test_UnitUpdate(t *testing.T) {
+	dataDir := t.TempDir()
+
+	state, err := Update(dataDir, "cluster.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Domain != "cluster.local" || len(state.Previous) != 0 {
+		t.Fatalf("unexpected initial state %+v", state)
+	}
+
+	if state, err = Update(dataDir, "k8s.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if state.Domain != "k8s.example.com" || !slices.Equal(state.Previous, []string{"cluster.local"}) {
+		t.Fatalf("unexpected state after change %+v", state)
+	}
+
+	// restarting with the same domain keeps the previous domains
+	if state, err = Update(dataDir, "k8s.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(state.Previous, []string{"cluster.local"}) {
+		t.Fatalf("unexpected state after restart %+v", state)
+	}
+
+	// changing back to a previous domain removes it from the previous domains
+	if state, err = Update(dataDir, "cluster.local"); err != nil {
+		t.Fatal(err)
+	}
+	if state.Domain != "cluster.local" || !slices.Equal(state.Previous, []string{"k8s.example.com"}) {
+		t.Fatalf("unexpected state after revert %+v", state)
+	}
+
+	if state, err = Finalize(dataDir); err != nil {
+		t.Fatal(err)
+	}
+	if state, err = Load(dataDir); err != nil {
+		t.Fatal(err)
+	}
+	if state.Domain != "cluster.local" || len(state.Previous) != 0 {
+		t.Fatalf("unexpected state after finalize %+v", state)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	bindata "github.com/go-bindata/go-bindata"
 	v1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	"github.com/k3s-io/k3s/pkg/preflight"
 	controllergen "github.com/rancher/wrangler/v3/pkg/controller-gen"
 	"github.com/rancher/wrangler/v3/pkg/controller-gen/args"
 	"github.com/sirupsen/logrus"
@@ -67,6 +68,17 @@ func main() {
 		logrus.Fatal(err)
 	}
 
+	script, err := os.ReadFile(preflight.CheckConfigScript)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if script, err = preflight.UpdateScript(script); err != nil {
+		logrus.Fatal(err)
+	}
+	if err := os.WriteFile(preflight.CheckConfigScript, script, 0755); err != nil {
+		logrus.Fatal(err)
+	}
+
 	controllergen.Run(args.Options{
 		OutputPackage: "github.com/k3s-io/k3s/pkg/generated",
 		Boilerplate:   "scripts/boilerplate.go.txt",
@@ -75,6 +87,10 @@ func main() {
 				Types: []interface{}{
 					v1.Addon{},
 					v1.ETCDSnapshotFile{},
+					v1.ClusterSecretsEncryption{},
+					v1.ImageDigestPin{},
+					v1.ClusterCARotation{},
+					v1.NodeConfig{},
 				},
 				GenerateTypes:   true,
 				GenerateClients: true,
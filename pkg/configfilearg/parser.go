@@ -278,6 +278,9 @@ func readConfigFile(file string) (result []string, _ error) {
 
 		for _, i := range data {
 			k, v := convert.ToString(i.Key), i.Value
+			if m, ok := v.(yaml.MapSlice); ok {
+				v = mapToSlice(m)
+			}
 			isAppend := strings.HasSuffix(k, "+")
 			k = strings.TrimSuffix(k, "+")
 
@@ -315,6 +318,16 @@ func readConfigFile(file string) (result []string, _ error) {
 	return
 }
 
+// mapToSlice converts a map value to a slice of key=value strings, so that maps can be
+// used to set slice flags that take key=value pairs.
+func mapToSlice(m yaml.MapSlice) []interface{} {
+	result := make([]interface{}, 0, len(m))
+	for _, i := range m {
+		result = append(result, convert.ToString(i.Key)+"="+convert.ToString(i.Value))
+	}
+	return result
+}
+
 func toSlice(v interface{}) []interface{} {
 	switch k := v.(type) {
 	case string:
@@ -329,6 +329,16 @@ func Test_UnitParser_Parse(t *testing.T) {
 				"--e-slice=two",
 				"before", "-c", "./testdata/data.yaml.d/02-data.yaml", "after"},
 		},
+		{
+			name: "read config file with map value",
+			fields: fields{
+				After:         []string{"server", "agent"},
+				FlagNames:     []string{"-c", "--config"},
+				DefaultConfig: "missing",
+			},
+			arg:  []string{"server", "-c", "./testdata/map-data.yaml"},
+			want: []string{"server", "--a-map=one=1", "--a-map=two=two", "-c", "./testdata/map-data.yaml"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -8,6 +8,10 @@ import (
 func List() []crd.CRD {
 	addon := v1.Addon{}
 	etcdSnapshotFile := v1.ETCDSnapshotFile{}
+	clusterSecretsEncryption := v1.ClusterSecretsEncryption{}
+	imageDigestPin := v1.ImageDigestPin{}
+	clusterCARotation := v1.ClusterCARotation{}
+	nodeConfig := v1.NodeConfig{}
 	return []crd.CRD{
 		crd.NamespacedType("Addon.k3s.cattle.io/v1").
 			WithSchemaFromStruct(addon).
@@ -20,5 +24,29 @@ func List() []crd.CRD {
 			WithColumn("Location", ".spec.location").
 			WithColumn("Size", ".status.size").
 			WithColumn("CreationTime", ".status.creationTime"),
+		crd.NonNamespacedType("ClusterSecretsEncryption.k3s.cattle.io/v1").
+			WithSchemaFromStruct(clusterSecretsEncryption).
+			WithStatus().
+			WithColumn("Phase", ".status.phase").
+			WithColumn("Done", ".status.nodesDone").
+			WithColumn("Total", ".status.nodesTotal").
+			WithColumn("Converged", ".status.converged"),
+		crd.NonNamespacedType("ImageDigestPin.k3s.cattle.io/v1").
+			WithSchemaFromStruct(imageDigestPin).
+			WithColumn("Image", ".spec.image").
+			WithColumn("Digest", ".spec.digest").
+			WithColumn("Node", ".spec.nodeName"),
+		crd.NonNamespacedType("ClusterCARotation.k3s.cattle.io/v1").
+			WithSchemaFromStruct(clusterCARotation).
+			WithStatus().
+			WithColumn("Phase", ".status.phase").
+			WithColumn("Done", ".status.nodesDone").
+			WithColumn("Total", ".status.nodesTotal").
+			WithColumn("Converged", ".status.converged"),
+		crd.NonNamespacedType("NodeConfig.k3s.cattle.io/v1").
+			WithSchemaFromStruct(nodeConfig).
+			WithStatus().
+			WithColumn("Selected", ".status.nodesSelected").
+			WithColumn("Applied", ".status.nodesApplied"),
 	}
 }
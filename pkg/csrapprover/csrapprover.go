@@ -0,0 +1,173 @@
+// Package csrapprover implements a controller that approves kubelet serving certificate signing
+// requests created by kubelets with serverTLSBootstrap enabled. Requests are approved only if
+// they are made by the node that they are for, and are valid only for that node's name and addresses.
+package csrapprover
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/rancher/lasso/pkg/controller"
+	coreclient "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	"github.com/rancher/wrangler/v3/pkg/schemes"
+	"github.com/sirupsen/logrus"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/kubernetes"
+)
+
+// retryPeriod is the time after a request is created during which invalid requests are retried
+// instead of denied, as the kubelet may create the request before its node or the node's updated
+// addresses are observed.
+const retryPeriod = time.Minute
+
+// nodeUserNamePrefix is the prefix of the user name that nodes authenticate as.
+const nodeUserNamePrefix = "system:node:"
+
+func init() {
+	schemes.Register(certificatesv1.AddToScheme)
+}
+
+// Register starts a controller that approves or denies kubelet serving certificate signing requests.
+func Register(ctx context.Context, k8s kubernetes.Interface, controllerFactory controller.SharedControllerFactory, nodes coreclient.NodeCache) error {
+	csrs := generic.NewNonNamespacedController[*certificatesv1.CertificateSigningRequest, *certificatesv1.CertificateSigningRequestList](
+		certificatesv1.SchemeGroupVersion.WithKind("CertificateSigningRequest"), "certificatesigningrequests", controllerFactory)
+	h := &handler{
+		ctx:   ctx,
+		k8s:   k8s,
+		nodes: nodes,
+	}
+	csrs.OnChange(ctx, version.Program+"-csr-approver", h.onChange)
+	return nil
+}
+
+type handler struct {
+	ctx   context.Context
+	k8s   kubernetes.Interface
+	nodes coreclient.NodeCache
+}
+
+func (h *handler) onChange(key string, csr *certificatesv1.CertificateSigningRequest) (*certificatesv1.CertificateSigningRequest, error) {
+	if csr == nil || csr.Spec.SignerName != certificatesv1.KubeletServingSignerName || finished(csr) {
+		return csr, nil
+	}
+
+	nodeName, ok := strings.CutPrefix(csr.Spec.Username, nodeUserNamePrefix)
+	if !ok {
+		// not requested by a node; leave it for an administrator to approve or deny
+		return csr, nil
+	}
+	node, err := h.nodes.Get(nodeName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return csr, err
+	}
+
+	condition := certificatesv1.CertificateSigningRequestCondition{
+		Type:    certificatesv1.CertificateApproved,
+		Status:  corev1.ConditionTrue,
+		Reason:  "AutoApproved",
+		Message: "Auto approving kubelet serving certificate after SAN and node name validation",
+	}
+	if err := validate(csr, node); err != nil {
+		if time.Since(csr.CreationTimestamp.Time) < retryPeriod {
+			return csr, err
+		}
+		logrus.Warnf("Denying kubelet serving certificate signing request %s: %v", csr.Name, err)
+		condition.Type = certificatesv1.CertificateDenied
+		condition.Reason = "PolicyViolation"
+		condition.Message = err.Error()
+	} else {
+		logrus.Infof("Approving kubelet serving certificate signing request %s for node %s", csr.Name, nodeName)
+	}
+
+	csr = csr.DeepCopy()
+	csr.Status.Conditions = append(csr.Status.Conditions, condition)
+	return h.k8s.CertificatesV1().CertificateSigningRequests().UpdateApproval(h.ctx, csr.Name, csr, metav1.UpdateOptions{})
+}
+
+// finished returns true if the request has already been approved or denied.
+func finished(csr *certificatesv1.CertificateSigningRequest) bool {
+	for _, c := range csr.Status.Conditions {
+		if c.Type == certificatesv1.CertificateApproved || c.Type == certificatesv1.CertificateDenied {
+			return true
+		}
+	}
+	return false
+}
+
+// validate returns an error if the request is not a valid kubelet serving certificate request for
+// the node. The request must be made by the node, for the node's user name, and may only include
+// the node's name and hostname or DNS addresses, and the node's IP addresses, as SANs.
+func validate(csr *certificatesv1.CertificateSigningRequest, node *corev1.Node) error {
+	if node == nil {
+		return fmt.Errorf("node for user %s not found", csr.Spec.Username)
+	}
+	username := nodeUserNamePrefix + node.Name
+	if csr.Spec.Username != username || !slices.Contains(csr.Spec.Groups, user.NodesGroup) {
+		return fmt.Errorf("request must be made by %s in group %s", username, user.NodesGroup)
+	}
+
+	for _, usage := range csr.Spec.Usages {
+		switch usage {
+		case certificatesv1.UsageDigitalSignature, certificatesv1.UsageKeyEncipherment, certificatesv1.UsageServerAuth:
+		default:
+			return fmt.Errorf("usage %s is not allowed", usage)
+		}
+	}
+
+	block, _ := pem.Decode(csr.Spec.Request)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return fmt.Errorf("request is not a PEM encoded certificate request")
+	}
+	req, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return err
+	}
+	if req.Subject.CommonName != username {
+		return fmt.Errorf("subject common name %s does not match %s", req.Subject.CommonName, username)
+	}
+	if !slices.Equal(req.Subject.Organization, []string{user.NodesGroup}) {
+		return fmt.Errorf("subject organization %v does not match %s", req.Subject.Organization, user.NodesGroup)
+	}
+	if len(req.EmailAddresses) > 0 || len(req.URIs) > 0 {
+		return fmt.Errorf("email and URI SANs are not allowed")
+	}
+	if len(req.DNSNames) == 0 && len(req.IPAddresses) == 0 {
+		return fmt.Errorf("request must include at least one DNS or IP SAN")
+	}
+
+	dnsNames := []string{node.Name}
+	ips := []net.IP{}
+	for _, addr := range node.Status.Addresses {
+		switch addr.Type {
+		case corev1.NodeHostName, corev1.NodeInternalDNS, corev1.NodeExternalDNS:
+			dnsNames = append(dnsNames, addr.Address)
+		case corev1.NodeInternalIP, corev1.NodeExternalIP:
+			if ip := net.ParseIP(addr.Address); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	for _, name := range req.DNSNames {
+		if !slices.Contains(dnsNames, name) {
+			return fmt.Errorf("DNS SAN %s is not an address of node %s", name, node.Name)
+		}
+	}
+	for _, ip := range req.IPAddresses {
+		if !slices.ContainsFunc(ips, ip.Equal) {
+			return fmt.Errorf("IP SAN %s is not an address of node %s", ip, node.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package csrapprover
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"testing"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_UnitValidate(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: "node1.example.com"},
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "2001:db8::1"},
+			},
+		},
+	}
+	tests := []struct {
+		name     string
+		username string
+		cn       string
+		dnsNames []string
+		ips      []string
+		usages   []certificatesv1.KeyUsage
+		noNode   bool
+		wantErr  bool
+	}{
+		{name: "valid", dnsNames: []string{"node1", "node1.example.com"}, ips: []string{"10.0.0.1", "2001:db8::1"}},
+		{name: "missing node", dnsNames: []string{"node1"}, noNode: true, wantErr: true},
+		{name: "requested by another node", username: "system:node:node2", dnsNames: []string{"node1"}, wantErr: true},
+		{name: "common name for another node", cn: "system:node:node2", dnsNames: []string{"node1"}, wantErr: true},
+		{name: "DNS SAN not on node", dnsNames: []string{"node2"}, wantErr: true},
+		{name: "IP SAN not on node", ips: []string{"10.0.0.2"}, wantErr: true},
+		{name: "no SANs", wantErr: true},
+		{name: "client auth usage", dnsNames: []string{"node1"}, usages: []certificatesv1.KeyUsage{certificatesv1.UsageClientAuth}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.username == "" {
+				tt.username = "system:node:node1"
+			}
+			if tt.cn == "" {
+				tt.cn = "system:node:node1"
+			}
+			if tt.usages == nil {
+				tt.usages = []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageServerAuth}
+			}
+			n := node
+			if tt.noNode {
+				n = nil
+			}
+			csr := &certificatesv1.CertificateSigningRequest{
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Request:    newRequest(t, tt.cn, tt.dnsNames, tt.ips),
+					SignerName: certificatesv1.KubeletServingSignerName,
+					Usages:     tt.usages,
+					Username:   tt.username,
+					Groups:     []string{"system:nodes", "system:authenticated"},
+				},
+			}
+			if err := validate(csr, n); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func newRequest(t *testing.T, cn string, dnsNames, ips []string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: cn, Organization: []string{"system:nodes"}},
+		DNSNames: dnsNames,
+	}
+	for _, ip := range ips {
+		template.IPAddresses = append(template.IPAddresses, net.ParseIP(ip))
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
@@ -19,9 +19,12 @@ package ctr
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/containerd/containerd/cmd/ctr/app"
 	"github.com/containerd/containerd/pkg/seed"
+	"github.com/k3s-io/k3s/pkg/instance"
+	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/urfave/cli"
 )
 
@@ -35,7 +38,7 @@ func main() {
 	for i, flag := range app.Flags {
 		if sFlag, ok := flag.(cli.StringFlag); ok {
 			if sFlag.Name == "address, a" {
-				sFlag.Value = "/run/k3s/containerd/containerd.sock"
+				sFlag.Value = filepath.Join(instance.RunDir(os.Getenv(version.ProgramUpper+"_INSTANCE_NAME")), "containerd", "containerd.sock")
 				app.Flags[i] = sFlag
 			} else if sFlag.Name == "namespace, n" {
 				sFlag.Value = "k8s.io"
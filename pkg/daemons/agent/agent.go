@@ -32,6 +32,9 @@ import (
 	"sigs.k8s.io/yaml"
 )
 
+// defaultKubeletRootDir is the kubelet's root dir, when not overridden by the agent config.
+const defaultKubeletRootDir = "/var/lib/kubelet"
+
 func Agent(ctx context.Context, nodeConfig *daemonconfig.Node, proxy proxy.Proxy) error {
 	rand.Seed(time.Now().UTC().UnixNano())
 	logsapi.ReapplyHandling = logsapi.ReapplyHandlingIgnoreUnchanged
@@ -55,22 +58,42 @@ func Agent(ctx context.Context, nodeConfig *daemonconfig.Node, proxy proxy.Proxy
 
 func startKubeProxy(ctx context.Context, cfg *daemonconfig.Agent) error {
 	argsMap := kubeProxyArgs(cfg)
+	if cfg.KubeProxyConfig != "" {
+		var err error
+		if argsMap, err = kubeProxyConfigArgs(cfg, argsMap); err != nil {
+			return errors.Wrap(err, "prepare kube-proxy configuration")
+		}
+	}
 	args := daemonconfig.GetArgs(argsMap, cfg.ExtraKubeProxyArgs)
 	logrus.Infof("Running kube-proxy %s", daemonconfig.ArgString(args))
 	return executor.KubeProxy(ctx, args)
 }
 
 func startKubelet(ctx context.Context, cfg *daemonconfig.Agent) error {
+	if len(cfg.ImageCredProviders) > 0 {
+		if err := writeCredentialProviderConfig(cfg); err != nil {
+			return errors.Wrap(err, "generate image credential provider configuration")
+		}
+	}
+
 	argsMap, defaultConfig, err := kubeletArgsAndConfig(cfg)
 	if err != nil {
 		return errors.Wrap(err, "prepare default configuration drop-in")
 	}
 
+	if err := linkPodResourcesDir(cfg); err != nil {
+		return errors.Wrap(err, "link pod resources socket dir")
+	}
+
 	extraArgs, err := extractConfigArgs(cfg.KubeletConfigDir, cfg.ExtraKubeletArgs, defaultConfig)
 	if err != nil {
 		return errors.Wrap(err, "prepare user configuration drop-ins")
 	}
 
+	if err := copyKubeletConfig(cfg.KubeletConfigDir, cfg.KubeletConfig); err != nil {
+		return errors.Wrap(err, "prepare kubelet-config drop-ins")
+	}
+
 	if err := writeKubeletConfig(cfg.KubeletConfigDir, defaultConfig); err != nil {
 		return errors.Wrap(err, "generate default kubelet configuration drop-in")
 	}
@@ -95,6 +118,35 @@ func ImageCredProvAvailable(cfg *daemonconfig.Agent) bool {
 	return true
 }
 
+// linkPodResourcesDir creates a symlink within the data-dir to the kubelet's pod resources socket
+// directory, so that device monitoring and DRA components can find the socket at a predictable path
+// regardless of the kubelet root dir.
+func linkPodResourcesDir(cfg *daemonconfig.Agent) error {
+	if cfg.PodResourcesDir == "" {
+		return nil
+	}
+	rootDir := cfg.RootDir
+	if rootDir == "" {
+		rootDir = defaultKubeletRootDir
+	}
+	target := filepath.Join(rootDir, "pod-resources")
+	if current, err := os.Readlink(cfg.PodResourcesDir); err == nil {
+		if current == target {
+			return nil
+		}
+		if err := os.Remove(cfg.PodResourcesDir); err != nil {
+			return err
+		}
+	} else if _, err := os.Lstat(cfg.PodResourcesDir); err == nil {
+		logrus.Warnf("Not linking kubelet pod resources dir: %s exists and is not a symlink", cfg.PodResourcesDir)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.PodResourcesDir), 0755); err != nil {
+		return err
+	}
+	return os.Symlink(target, cfg.PodResourcesDir)
+}
+
 // extractConfigArgs strips out any --config or --config-dir flags from the
 // provided args list, and if set, copies the content of the file or dir into
 // the target drop-in directory.
@@ -150,6 +202,65 @@ func extractConfigArgs(path string, extraArgs []string, config *kubeletconfig.Ku
 	return args, nil
 }
 
+// copyKubeletConfig copies the KubeletConfiguration fragments from the provided
+// file or directory into the target drop-in directory. Fragments in a directory
+// are merged by the kubelet in lexical order; files with a .yaml or .yml
+// extension are renamed so that the kubelet will load them. Fragments copied by
+// a previous run are removed first, so that fragments removed from the source
+// directory are no longer applied.
+func copyKubeletConfig(path, src string) error {
+	dest := filepath.Join(path, "30-kubelet-config")
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+	if src == "" {
+		return nil
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	files := []string{src}
+	if info.IsDir() {
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			switch filepath.Ext(entry.Name()) {
+			case ".conf", ".yaml", ".yml":
+				if !entry.IsDir() {
+					files = append(files, filepath.Join(src, entry.Name()))
+				}
+			}
+		}
+	}
+
+	if err := os.MkdirAll(dest, 0700); err != nil {
+		return err
+	}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		typeMeta := &metav1.TypeMeta{}
+		if err := yaml.Unmarshal(b, typeMeta); err != nil {
+			return errors.Wrapf(err, "failed to parse kubelet configuration %s", file)
+		}
+		if typeMeta.Kind != "KubeletConfiguration" || typeMeta.APIVersion == "" {
+			return fmt.Errorf("kubelet configuration %s must set apiVersion, and kind KubeletConfiguration", file)
+		}
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)) + ".conf"
+		if err := os.WriteFile(filepath.Join(dest, name), b, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // writeKubeletConfig marshals the provided KubeletConfiguration object into a
 // drop-in config file in the target drop-in directory.
 func writeKubeletConfig(path string, config *kubeletconfig.KubeletConfiguration) error {
@@ -231,7 +342,11 @@ func defaultKubeletConfig(cfg *daemonconfig.Agent) (*kubeletconfig.KubeletConfig
 		defaultConfig.Authentication.X509.ClientCAFile = cfg.ClientCA
 	}
 
-	if cfg.ServingKubeletCert != "" && cfg.ServingKubeletKey != "" {
+	if cfg.KubeletServingCSR {
+		// The kubelet requests its serving certificate from the CertificateSigningRequest API and
+		// rotates it before expiry; requests are approved by the supervisor's CSR approver.
+		defaultConfig.ServerTLSBootstrap = true
+	} else if cfg.ServingKubeletCert != "" && cfg.ServingKubeletKey != "" {
 		defaultConfig.TLSCertFile = cfg.ServingKubeletCert
 		defaultConfig.TLSPrivateKeyFile = cfg.ServingKubeletKey
 	}
@@ -251,6 +366,22 @@ func defaultKubeletConfig(cfg *daemonconfig.Agent) (*kubeletconfig.KubeletConfig
 		return nil, errors.Wrapf(err, "failed to create static pod manifest dir %s", defaultConfig.StaticPodPath)
 	}
 
+	if cfg.DRA {
+		defaultConfig.FeatureGates = map[string]bool{"DynamicResourceAllocation": true}
+	}
+
+	if cfg.SwapBehavior != "" {
+		defaultConfig.MemorySwap.SwapBehavior = cfg.SwapBehavior
+	}
+
+	if err := applyReservedProfile(cfg.SystemReservedProfile, defaultConfig); err != nil {
+		return nil, err
+	}
+
+	if err := applyImageFSEviction(cfg, defaultConfig); err != nil {
+		return nil, err
+	}
+
 	if t, _, err := taints.ParseTaints(cfg.NodeTaints); err != nil {
 		return nil, errors.Wrap(err, "failed to parse node taints")
 	} else {
@@ -15,7 +15,11 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	componentbaseconfig "k8s.io/component-base/config/v1alpha1"
+	kubeproxyconfig "k8s.io/kube-proxy/config/v1alpha1"
 	kubeletconfig "k8s.io/kubelet/config/v1beta1"
+	"k8s.io/kubernetes/pkg/cluster/ports"
 	utilsnet "k8s.io/utils/net"
 	utilsptr "k8s.io/utils/ptr"
 )
@@ -65,6 +69,34 @@ func kubeProxyArgs(cfg *config.Agent) map[string]string {
 	return argsMap
 }
 
+// defaultKubeProxyConfig generates default kube-proxy configuration, equivalent
+// to the args returned by kubeProxyArgs, for use when the user provides a
+// configuration file.
+func defaultKubeProxyConfig(cfg *config.Agent) (*kubeproxyconfig.KubeProxyConfiguration, error) {
+	bindAddress := "127.0.0.1"
+	if utilsnet.IsIPv6(net.ParseIP(cfg.NodeIP)) {
+		bindAddress = "::1"
+	}
+	return &kubeproxyconfig.KubeProxyConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: kubeproxyconfig.SchemeGroupVersion.String(),
+			Kind:       "KubeProxyConfiguration",
+		},
+		ClientConnection: componentbaseconfig.ClientConnectionConfiguration{
+			Kubeconfig: cfg.KubeConfigKubeProxy,
+		},
+		HostnameOverride:   cfg.NodeName,
+		HealthzBindAddress: net.JoinHostPort(bindAddress, strconv.Itoa(ports.ProxyHealthzPort)),
+		Mode:               "iptables",
+		ClusterCIDR:        util.JoinIPNets(cfg.ClusterCIDRs),
+		Conntrack: kubeproxyconfig.KubeProxyConntrackConfiguration{
+			MaxPerCore:            utilsptr.To[int32](0),
+			TCPEstablishedTimeout: &metav1.Duration{},
+			TCPCloseWaitTimeout:   &metav1.Duration{},
+		},
+	}, nil
+}
+
 // kubeletArgsAndConfig generates default kubelet args and configuration.
 // Kubelet config is frustratingly split across deprecated CLI flags that raise warnings if you use them,
 // and a structured configuration file that upstream does not provide a convienent way to initailize with default values.
@@ -132,7 +164,9 @@ func kubeletArgsAndConfig(cfg *config.Agent) (map[string]string, *kubeletconfig.
 		logrus.Warn("Disabling CPU quotas due to missing cpu controller or cpu.cfs_period_us")
 		defaultConfig.CPUCFSQuota = utilsptr.To(false)
 	}
-	if kubeletRoot != "" {
+	// When resource limits are set, the kubelet is placed into its own cgroup by the supervisor,
+	// and must not move itself elsewhere.
+	if kubeletRoot != "" && cfg.KubeletLimits == nil {
 		defaultConfig.KubeletCgroups = kubeletRoot
 	}
 	if runtimeRoot != "" {
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	daemonconfig "github.com/k3s-io/k3s/pkg/daemons/config"
+)
+
+func Test_UnitLinkPodResourcesDir(t *testing.T) {
+	dataDir := t.TempDir()
+	cfg := &daemonconfig.Agent{
+		RootDir:         filepath.Join(dataDir, "kubelet"),
+		PodResourcesDir: filepath.Join(dataDir, "agent", "pod-resources"),
+	}
+	want := filepath.Join(cfg.RootDir, "pod-resources")
+
+	// link is created, and is left alone if already correct
+	for i := 0; i < 2; i++ {
+		if err := linkPodResourcesDir(cfg); err != nil {
+			t.Fatalf("linkPodResourcesDir() error = %v", err)
+		}
+		if got, err := os.Readlink(cfg.PodResourcesDir); err != nil || got != want {
+			t.Fatalf("linkPodResourcesDir() link = %q, %v; want %q", got, err, want)
+		}
+	}
+
+	// link is updated when the kubelet root dir changes
+	cfg.RootDir = filepath.Join(dataDir, "kubelet2")
+	want = filepath.Join(cfg.RootDir, "pod-resources")
+	if err := linkPodResourcesDir(cfg); err != nil {
+		t.Fatalf("linkPodResourcesDir() error = %v", err)
+	}
+	if got, err := os.Readlink(cfg.PodResourcesDir); err != nil || got != want {
+		t.Fatalf("linkPodResourcesDir() link = %q, %v; want %q", got, err, want)
+	}
+
+	// existing directories are not replaced
+	os.Remove(cfg.PodResourcesDir)
+	os.Mkdir(cfg.PodResourcesDir, 0755)
+	if err := linkPodResourcesDir(cfg); err != nil {
+		t.Fatalf("linkPodResourcesDir() error = %v", err)
+	}
+	if info, err := os.Lstat(cfg.PodResourcesDir); err != nil || !info.IsDir() {
+		t.Fatalf("linkPodResourcesDir() replaced existing directory")
+	}
+}
+
+func Test_UnitCopyKubeletConfig(t *testing.T) {
+	configDir := t.TempDir()
+	src := t.TempDir()
+	fragment := "apiVersion: kubelet.config.k8s.io/v1beta1\nkind: KubeletConfiguration\nmaxPods: 250\n"
+	for name, content := range map[string]string{
+		"10-pods.yaml":     fragment,
+		"20-eviction.conf": fragment,
+		"README.md":        "not a fragment",
+	} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dest := filepath.Join(configDir, "30-kubelet-config")
+	if err := copyKubeletConfig(configDir, src); err != nil {
+		t.Fatalf("copyKubeletConfig() error = %v", err)
+	}
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	if len(got) != 2 || got[0] != "10-pods.conf" || got[1] != "20-eviction.conf" {
+		t.Errorf("copyKubeletConfig() copied %v, want [10-pods.conf 20-eviction.conf]", got)
+	}
+
+	// fragments are removed when no longer configured
+	if err := copyKubeletConfig(configDir, ""); err != nil {
+		t.Fatalf("copyKubeletConfig() error = %v", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("copyKubeletConfig() expected %s to be removed, got %v", dest, err)
+	}
+
+	// fragments must be KubeletConfiguration
+	file := filepath.Join(src, "20-eviction.conf")
+	if err := os.WriteFile(file, []byte("maxPods: 250\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyKubeletConfig(configDir, file); err == nil {
+		t.Errorf("copyKubeletConfig() expected error for fragment without kind")
+	}
+}
@@ -10,7 +10,9 @@ import (
 
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	kubeproxyconfig "k8s.io/kube-proxy/config/v1alpha1"
 	kubeletconfig "k8s.io/kubelet/config/v1beta1"
 	utilsnet "k8s.io/utils/net"
 	utilsptr "k8s.io/utils/ptr"
@@ -38,6 +40,12 @@ func kubeProxyArgs(cfg *config.Agent) map[string]string {
 	return argsMap
 }
 
+// defaultKubeProxyConfig is not supported on Windows, as the HNS network
+// settings are only passed to kube-proxy as flags.
+func defaultKubeProxyConfig(cfg *config.Agent) (*kubeproxyconfig.KubeProxyConfiguration, error) {
+	return nil, errors.New("kube-proxy-config is not supported on Windows")
+}
+
 // kubeletArgsAndConfig generates default kubelet args and configuration.
 // Kubelet config is frustratingly split across deprecated CLI flags that raise warnings if you use them,
 // and a structured configuration file that upstream does not provide a convienent way to initailize with default values.
@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	daemonconfig "github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	credentialproviderconfig "k8s.io/kubelet/config/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const credentialProviderAPIVersion = "credentialprovider.kubelet.k8s.io/v1"
+
+// knownCredentialProviders contains default settings for the credential provider plugins
+// published by the major cloud providers, so that users only need to specify the plugin name.
+var knownCredentialProviders = map[string]credentialproviderconfig.CredentialProvider{
+	"ecr-credential-provider": {
+		MatchImages: []string{
+			"*.dkr.ecr.*.amazonaws.com",
+			"*.dkr.ecr.*.amazonaws.com.cn",
+			"*.dkr.ecr-fips.*.amazonaws.com",
+			"*.dkr.ecr.us-iso-east-1.c2s.ic.gov",
+			"*.dkr.ecr.us-isob-east-1.sc2s.sgov.gov",
+		},
+		DefaultCacheDuration: &metav1.Duration{Duration: 12 * time.Hour},
+	},
+	"acr-credential-provider": {
+		MatchImages: []string{
+			"*.azurecr.io",
+			"*.azurecr.cn",
+			"*.azurecr.de",
+			"*.azurecr.us",
+		},
+		DefaultCacheDuration: &metav1.Duration{Duration: 10 * time.Minute},
+		Args:                 []string{"/etc/kubernetes/azure.json"},
+	},
+	"auth-provider-gcp": {
+		MatchImages: []string{
+			"container.cloud.google.com",
+			"gcr.io",
+			"*.gcr.io",
+			"*.pkg.dev",
+		},
+		DefaultCacheDuration: &metav1.Duration{Duration: time.Minute},
+		Args:                 []string{"get-credentials", "--v=3"},
+	},
+}
+
+// writeCredentialProviderConfig renders a kubelet CredentialProviderConfig for the configured
+// credential provider plugins, and ensures that the plugin bin dir exists.
+func writeCredentialProviderConfig(cfg *daemonconfig.Agent) error {
+	providerConfig, err := credentialProviderConfig(cfg.ImageCredProviders)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(cfg.ImageCredProvBinDir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create image credential provider bin dir %s", cfg.ImageCredProvBinDir)
+	}
+	for _, provider := range providerConfig.Providers {
+		if !credentialProviderInstalled(cfg.ImageCredProvBinDir, provider.Name) {
+			logrus.Warnf("Image credential provider %s not found in %s", provider.Name, cfg.ImageCredProvBinDir)
+		}
+	}
+
+	b, err := yaml.Marshal(providerConfig)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.ImageCredProvConfig), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(cfg.ImageCredProvConfig, b, 0600)
+}
+
+// credentialProviderConfig parses a list of NAME[=MATCH-IMAGE] provider specs into a CredentialProviderConfig.
+// Match images for the same provider are merged, in order. Known providers use their default match
+// images if none are given; unknown providers must specify at least one match image.
+func credentialProviderConfig(specs []string) (*credentialproviderconfig.CredentialProviderConfig, error) {
+	providerConfig := &credentialproviderconfig.CredentialProviderConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "kubelet.config.k8s.io/v1",
+			Kind:       "CredentialProviderConfig",
+		},
+	}
+
+	providers := map[string]*credentialproviderconfig.CredentialProvider{}
+	names := []string{}
+	for _, spec := range specs {
+		name, matchImage, _ := strings.Cut(spec, "=")
+		name = strings.TrimSpace(name)
+		matchImage = strings.TrimSpace(matchImage)
+		if name == "" || strings.ContainsAny(name, `/\`) {
+			return nil, fmt.Errorf("invalid image credential provider %q", spec)
+		}
+		provider, ok := providers[name]
+		if !ok {
+			provider = &credentialproviderconfig.CredentialProvider{
+				Name:       name,
+				APIVersion: credentialProviderAPIVersion,
+			}
+			if known, ok := knownCredentialProviders[name]; ok {
+				provider.DefaultCacheDuration = known.DefaultCacheDuration
+				provider.Args = known.Args
+			}
+			providers[name] = provider
+			names = append(names, name)
+		}
+		if matchImage != "" {
+			provider.MatchImages = append(provider.MatchImages, matchImage)
+		}
+	}
+
+	for _, name := range names {
+		provider := providers[name]
+		if len(provider.MatchImages) == 0 {
+			known, ok := knownCredentialProviders[name]
+			if !ok {
+				return nil, fmt.Errorf("image credential provider %s must specify at least one match image", name)
+			}
+			provider.MatchImages = known.MatchImages
+		}
+		if provider.DefaultCacheDuration == nil {
+			provider.DefaultCacheDuration = &metav1.Duration{Duration: 10 * time.Minute}
+		}
+		providerConfig.Providers = append(providerConfig.Providers, *provider)
+	}
+	return providerConfig, nil
+}
+
+// credentialProviderInstalled returns true if the provider executable exists in the bin dir.
+func credentialProviderInstalled(binDir, name string) bool {
+	for _, file := range []string{name, name + ".exe"} {
+		if info, err := os.Stat(filepath.Join(binDir, file)); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
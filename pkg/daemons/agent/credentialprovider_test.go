@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_UnitCredentialProviderConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		specs      []string
+		wantNames  []string
+		wantImages [][]string
+		wantArgs   [][]string
+		wantErr    bool
+	}{
+		{
+			name:       "known provider uses default match images",
+			specs:      []string{"ecr-credential-provider"},
+			wantNames:  []string{"ecr-credential-provider"},
+			wantImages: [][]string{knownCredentialProviders["ecr-credential-provider"].MatchImages},
+			wantArgs:   [][]string{nil},
+		},
+		{
+			name:       "known provider with custom match image",
+			specs:      []string{"auth-provider-gcp=us-docker.pkg.dev"},
+			wantNames:  []string{"auth-provider-gcp"},
+			wantImages: [][]string{{"us-docker.pkg.dev"}},
+			wantArgs:   [][]string{{"get-credentials", "--v=3"}},
+		},
+		{
+			name:       "custom provider match images are merged in order",
+			specs:      []string{"my-provider=registry.example.com", "acr-credential-provider", "my-provider=registry.example.com:5000/team"},
+			wantNames:  []string{"my-provider", "acr-credential-provider"},
+			wantImages: [][]string{{"registry.example.com", "registry.example.com:5000/team"}, knownCredentialProviders["acr-credential-provider"].MatchImages},
+			wantArgs:   [][]string{nil, {"/etc/kubernetes/azure.json"}},
+		},
+		{
+			name:    "custom provider without match images",
+			specs:   []string{"my-provider"},
+			wantErr: true,
+		},
+		{
+			name:    "provider name with path",
+			specs:   []string{"../bin/my-provider=registry.example.com"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := credentialProviderConfig(tt.specs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("credentialProviderConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got.Providers) != len(tt.wantNames) {
+				t.Fatalf("credentialProviderConfig() returned %d providers, want %d", len(got.Providers), len(tt.wantNames))
+			}
+			for i, provider := range got.Providers {
+				if provider.Name != tt.wantNames[i] {
+					t.Errorf("provider %d name = %s, want %s", i, provider.Name, tt.wantNames[i])
+				}
+				if !reflect.DeepEqual(provider.MatchImages, tt.wantImages[i]) {
+					t.Errorf("provider %s matchImages = %v, want %v", provider.Name, provider.MatchImages, tt.wantImages[i])
+				}
+				if !reflect.DeepEqual(provider.Args, tt.wantArgs[i]) {
+					t.Errorf("provider %s args = %v, want %v", provider.Name, provider.Args, tt.wantArgs[i])
+				}
+				if provider.APIVersion != credentialProviderAPIVersion || provider.DefaultCacheDuration == nil {
+					t.Errorf("provider %s missing apiVersion or defaultCacheDuration", provider.Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+
+	daemonconfig "github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	kubeletconfig "k8s.io/kubelet/config/v1beta1"
+)
+
+// Eviction thresholds used for the image filesystem when it is separate from the node filesystem.
+// These match the upstream kubelet defaults, as the node filesystem thresholds set by the defaults
+// and system reserved profiles are sized for a single shared filesystem.
+const (
+	imageFSAvailableEviction  = "15%"
+	imageFSInodesFreeEviction = "5%"
+)
+
+// applyImageFSEviction sets eviction thresholds for the image filesystem, if the containerd image
+// store is on a different filesystem than the kubelet root dir. The kubelet discovers the image
+// filesystem from the container runtime, and applies the imagefs thresholds to it.
+func applyImageFSEviction(cfg *daemonconfig.Agent, config *kubeletconfig.KubeletConfiguration) error {
+	if cfg.ImageStoreDir == "" {
+		return nil
+	}
+	rootDir := cfg.RootDir
+	if rootDir == "" {
+		rootDir = defaultKubeletRootDir
+	}
+	same, err := sameFilesystem(cfg.ImageStoreDir, rootDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to compare image store and kubelet root dir filesystems")
+	}
+	if same {
+		return nil
+	}
+	logrus.Infof("Image store %s is on a separate filesystem from kubelet root dir %s", cfg.ImageStoreDir, rootDir)
+	setImageFSEviction(config)
+	return nil
+}
+
+// setImageFSEviction sets the hard eviction thresholds for a separate image filesystem. Node
+// filesystem thresholds are left unchanged.
+func setImageFSEviction(config *kubeletconfig.KubeletConfiguration) {
+	if config.EvictionHard == nil {
+		config.EvictionHard = map[string]string{}
+	}
+	config.EvictionHard["imagefs.available"] = imageFSAvailableEviction
+	config.EvictionHard["imagefs.inodesFree"] = imageFSInodesFreeEviction
+}
+
+// existingParent returns the path, or its closest parent directory that exists.
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,21 @@
+//go:build linux
+// +build linux
+
+package agent
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// sameFilesystem returns true if both paths are on the same filesystem. Paths that do not exist
+// yet are compared using their closest existing parent directory.
+func sameFilesystem(a, b string) (bool, error) {
+	var statA, statB unix.Stat_t
+	if err := unix.Stat(existingParent(a), &statA); err != nil {
+		return false, err
+	}
+	if err := unix.Stat(existingParent(b), &statB); err != nil {
+		return false, err
+	}
+	return statA.Dev == statB.Dev, nil
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	daemonconfig "github.com/k3s-io/k3s/pkg/daemons/config"
+	kubeletconfig "k8s.io/kubelet/config/v1beta1"
+)
+
+func Test_UnitImageFSEviction(t *testing.T) {
+	dataDir := t.TempDir()
+	config := &kubeletconfig.KubeletConfiguration{
+		EvictionHard: map[string]string{
+			"imagefs.available": "5%",
+			"nodefs.available":  "5%",
+		},
+	}
+
+	// image store on the same filesystem as a kubelet root dir that does not exist yet
+	cfg := &daemonconfig.Agent{
+		RootDir:       filepath.Join(dataDir, "kubelet"),
+		ImageStoreDir: filepath.Join(dataDir, "images"),
+	}
+	if err := applyImageFSEviction(cfg, config); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"imagefs.available": "5%",
+		"nodefs.available":  "5%",
+	}
+	if !reflect.DeepEqual(config.EvictionHard, want) {
+		t.Errorf("unexpected eviction thresholds for shared filesystem: %v", config.EvictionHard)
+	}
+
+	setImageFSEviction(config)
+	want = map[string]string{
+		"imagefs.available":  imageFSAvailableEviction,
+		"imagefs.inodesFree": imageFSInodesFreeEviction,
+		"nodefs.available":   "5%",
+	}
+	if !reflect.DeepEqual(config.EvictionHard, want) {
+		t.Errorf("unexpected eviction thresholds for separate filesystem: %v", config.EvictionHard)
+	}
+}
+
+func Test_UnitExistingParent(t *testing.T) {
+	dataDir := t.TempDir()
+	if got := existingParent(filepath.Join(dataDir, "a", "b")); got != dataDir {
+		t.Errorf("existingParent() = %s, want %s", got, dataDir)
+	}
+}
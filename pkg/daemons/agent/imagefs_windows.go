@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package agent
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// sameFilesystem returns true if both paths are on the same volume.
+func sameFilesystem(a, b string) (bool, error) {
+	return strings.EqualFold(filepath.VolumeName(a), filepath.VolumeName(b)), nil
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+
+	daemonconfig "github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	kubeproxyconfigv1alpha1 "k8s.io/kube-proxy/config/v1alpha1"
+	kubeproxyconfig "k8s.io/kubernetes/pkg/proxy/apis/config"
+	kubeproxyconfigscheme "k8s.io/kubernetes/pkg/proxy/apis/config/scheme"
+	"k8s.io/kubernetes/pkg/proxy/apis/config/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// ipvsSchedulers are the IPVS schedulers supported by kube-proxy.
+var ipvsSchedulers = []string{"rr", "wrr", "lc", "wlc", "lblc", "lblcr", "dh", "sh", "sed", "nq", "mh"}
+
+// kubeProxyConfigArgs generates kube-proxy configuration from the default
+// configuration and the user's KubeProxyConfiguration file, validates it, and
+// writes it to disk so that the effective configuration can be inspected. The
+// returned args point kube-proxy at the generated configuration. kube-proxy
+// ignores most flags when a configuration file is used, so only logging flags
+// and the hostname override are retained.
+func kubeProxyConfigArgs(cfg *daemonconfig.Agent, argsMap map[string]string) (map[string]string, error) {
+	config, err := defaultKubeProxyConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(cfg.KubeProxyConfig)
+	if err != nil {
+		return nil, err
+	}
+	typeMeta := &metav1.TypeMeta{}
+	if err := yaml.Unmarshal(b, typeMeta); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse kube-proxy configuration %s", cfg.KubeProxyConfig)
+	}
+	if typeMeta.Kind != "KubeProxyConfiguration" || typeMeta.APIVersion != kubeproxyconfigv1alpha1.SchemeGroupVersion.String() {
+		return nil, fmt.Errorf("kube-proxy configuration %s must set apiVersion %s, and kind KubeProxyConfiguration", cfg.KubeProxyConfig, kubeproxyconfigv1alpha1.SchemeGroupVersion)
+	}
+	if err := yaml.UnmarshalStrict(b, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse kube-proxy configuration %s", cfg.KubeProxyConfig)
+	}
+
+	kubeproxyconfigscheme.Scheme.Default(config)
+	if err := validateKubeProxyConfig(config); err != nil {
+		return nil, errors.Wrapf(err, "invalid kube-proxy configuration %s", cfg.KubeProxyConfig)
+	}
+
+	b, err = yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(cfg.KubeProxyConfigPath, b, 0600); err != nil {
+		return nil, err
+	}
+
+	configArgsMap := map[string]string{"config": cfg.KubeProxyConfigPath}
+	for _, key := range []string{"hostname-override", "v", "vmodule", "log_file", "alsologtostderr"} {
+		if v, ok := argsMap[key]; ok {
+			configArgsMap[key] = v
+		}
+	}
+	for _, arg := range cfg.ExtraKubeProxyArgs {
+		key, _, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if _, ok := configArgsMap[key]; !ok {
+			logrus.Warnf("kube-proxy-arg %s may be ignored by kube-proxy when kube-proxy-config is set; set it in the configuration file instead", arg)
+		}
+	}
+	return configArgsMap, nil
+}
+
+// validateKubeProxyConfig validates the configuration with the same checks
+// kube-proxy applies at startup, along with checks for mode-specific settings
+// that kube-proxy does not validate until the proxier is started.
+func validateKubeProxyConfig(config *kubeproxyconfigv1alpha1.KubeProxyConfiguration) error {
+	internal := &kubeproxyconfig.KubeProxyConfiguration{}
+	if err := kubeproxyconfigscheme.Scheme.Convert(config, internal, nil); err != nil {
+		return err
+	}
+	allErrs := validation.Validate(internal)
+
+	fldPath := field.NewPath("KubeProxyConfiguration")
+	if scheduler := internal.IPVS.Scheduler; internal.Mode == kubeproxyconfig.ProxyModeIPVS && scheduler != "" && !slices.Contains(ipvsSchedulers, scheduler) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("ipvs", "scheduler"), scheduler, ipvsSchedulers))
+	}
+
+	// The conntrack table size is computed from these values and written to nf_conntrack_max, which is a signed 32-bit integer.
+	conntrack := internal.Linux.Conntrack
+	if conntrack.MaxPerCore != nil && int64(*conntrack.MaxPerCore)*int64(runtime.NumCPU()) > math.MaxInt32 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("conntrack", "maxPerCore"), *conntrack.MaxPerCore, fmt.Sprintf("conntrack table size for %d cores must not exceed %d", runtime.NumCPU(), math.MaxInt32)))
+	}
+
+	return allErrs.ToAggregate()
+}
@@ -0,0 +1,81 @@
+//go:build linux
+// +build linux
+
+package agent
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	daemonconfig "github.com/k3s-io/k3s/pkg/daemons/config"
+	kubeproxyconfig "k8s.io/kube-proxy/config/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+func Test_UnitKubeProxyConfigArgs(t *testing.T) {
+	dir := t.TempDir()
+	header := "apiVersion: kubeproxy.config.k8s.io/v1alpha1\nkind: KubeProxyConfiguration\n"
+	type test struct {
+		name    string
+		config  string
+		wantErr string
+	}
+	tests := []test{
+		{name: "ipvs", config: header + "mode: ipvs\nipvs:\n  scheduler: wrr\n"},
+		{name: "nftables with conntrack", config: header + "mode: nftables\nconntrack:\n  maxPerCore: 65536\n  min: 131072\n"},
+		{name: "missing kind", config: "mode: ipvs\n", wantErr: "kind KubeProxyConfiguration"},
+		{name: "unknown field", config: header + "ipvs:\n  schedulr: rr\n", wantErr: "unknown field"},
+		{name: "invalid mode", config: header + "mode: userspace\n", wantErr: "Mode"},
+		{name: "invalid ipvs scheduler", config: header + "mode: ipvs\nipvs:\n  scheduler: fastest\n", wantErr: "ipvs.scheduler"},
+		{name: "invalid conntrack size", config: header + "conntrack:\n  maxPerCore: -1\n", wantErr: "MaxPerCore"},
+	}
+	if runtime.NumCPU() > 1 {
+		tests = append(tests, test{name: "conntrack size overflow", config: header + "conntrack:\n  maxPerCore: " + strconv.Itoa(math.MaxInt32/runtime.NumCPU()+1) + "\n", wantErr: "conntrack.maxPerCore"})
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &daemonconfig.Agent{
+				NodeName:            "node1",
+				NodeIP:              "10.0.0.1",
+				KubeConfigKubeProxy: filepath.Join(dir, "kubeproxy.kubeconfig"),
+				KubeProxyConfig:     filepath.Join(dir, "config.yaml"),
+				KubeProxyConfigPath: filepath.Join(dir, "kube-proxy.yaml"),
+				VLevel:              2,
+			}
+			os.Remove(cfg.KubeProxyConfigPath)
+			if err := os.WriteFile(cfg.KubeProxyConfig, []byte(tt.config), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			args, err := kubeProxyConfigArgs(cfg, kubeProxyArgs(cfg))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("kubeProxyConfigArgs() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("kubeProxyConfigArgs() error = %v", err)
+			}
+
+			if args["config"] != cfg.KubeProxyConfigPath || args["v"] != "2" || args["hostname-override"] != "node1" || args["proxy-mode"] != "" {
+				t.Errorf("kubeProxyConfigArgs() args = %v, want config, logging and hostname-override args only", args)
+			}
+			b, err := os.ReadFile(cfg.KubeProxyConfigPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			written := &kubeproxyconfig.KubeProxyConfiguration{}
+			if err := yaml.Unmarshal(b, written); err != nil {
+				t.Fatal(err)
+			}
+			if written.ClientConnection.Kubeconfig != cfg.KubeConfigKubeProxy || written.HealthzBindAddress != "127.0.0.1:10256" {
+				t.Errorf("kubeProxyConfigArgs() expected default configuration to be retained, got %s", b)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	kubeletconfig "k8s.io/kubelet/config/v1beta1"
+)
+
+const (
+	mebibyte = 1024 * 1024
+	gibibyte = 1024 * mebibyte
+)
+
+// reservedProfile describes how a system reserved profile scales the default reservations,
+// and the eviction thresholds that are used with it.
+type reservedProfile struct {
+	scale          float64
+	memoryEviction string
+	nodefsEviction string
+}
+
+var reservedProfiles = map[string]reservedProfile{
+	"small": {
+		scale:          0.5,
+		memoryEviction: "100Mi",
+		nodefsEviction: "5%",
+	},
+	"medium": {
+		scale:          1,
+		memoryEviction: "250Mi",
+		nodefsEviction: "10%",
+	},
+	"large": {
+		scale:          1.5,
+		memoryEviction: "500Mi",
+		nodefsEviction: "10%",
+	},
+}
+
+// applyReservedProfile sets kubelet system and kube reserved resources, and eviction thresholds,
+// based on the selected profile and the node's detected CPU and memory capacity.
+func applyReservedProfile(profileName string, config *kubeletconfig.KubeletConfiguration) error {
+	if profileName == "" {
+		return nil
+	}
+	profile, ok := reservedProfiles[profileName]
+	if !ok {
+		return fmt.Errorf("invalid system reserved profile %q: must be one of small, medium, or large", profileName)
+	}
+	memory, err := detectMemory()
+	if err != nil {
+		return errors.Wrap(err, "failed to detect node memory capacity")
+	}
+	setReservedResources(profile, detectNumCPU(), memory, config)
+	return nil
+}
+
+// setReservedResources splits the reserved CPU and memory evenly between system and kube reserved,
+// and sets hard eviction thresholds for memory and disk.
+func setReservedResources(profile reservedProfile, numCPU int, memory uint64, config *kubeletconfig.KubeletConfiguration) {
+	cpuMillis := int64(float64(reservedCPUMillis(numCPU)) * profile.scale)
+	memoryMi := int64(float64(reservedMemory(memory)) * profile.scale / mebibyte)
+
+	config.SystemReserved = map[string]string{
+		"cpu":    fmt.Sprintf("%dm", cpuMillis/2),
+		"memory": fmt.Sprintf("%dMi", memoryMi/2),
+	}
+	config.KubeReserved = map[string]string{
+		"cpu":    fmt.Sprintf("%dm", cpuMillis-cpuMillis/2),
+		"memory": fmt.Sprintf("%dMi", memoryMi-memoryMi/2),
+	}
+	config.EvictionHard = map[string]string{
+		"memory.available":  profile.memoryEviction,
+		"imagefs.available": profile.nodefsEviction,
+		"nodefs.available":  profile.nodefsEviction,
+	}
+}
+
+// reservedCPUMillis returns the CPU to reserve for system daemons: 6% of the first core,
+// 1% of the second core, 0.5% of the next two cores, and 0.25% of any remaining cores.
+func reservedCPUMillis(numCPU int) int64 {
+	var millis float64
+	for core := 1; core <= numCPU; core++ {
+		switch {
+		case core == 1:
+			millis += 60
+		case core == 2:
+			millis += 10
+		case core <= 4:
+			millis += 5
+		default:
+			millis += 2.5
+		}
+	}
+	return int64(millis)
+}
+
+// reservedMemory returns the memory to reserve for system daemons: 25% of the first 4GiB,
+// 20% of the next 4GiB, 10% of the next 8GiB, 6% of the next 112GiB, and 2% of any remaining
+// memory. Nodes with less than 1GiB of memory have a flat 255MiB reserved.
+func reservedMemory(memory uint64) uint64 {
+	if memory < gibibyte {
+		return 255 * mebibyte
+	}
+	tiers := []struct {
+		size    uint64
+		percent uint64
+	}{
+		{4 * gibibyte, 25},
+		{4 * gibibyte, 20},
+		{8 * gibibyte, 10},
+		{112 * gibibyte, 6},
+		{memory, 2},
+	}
+	var reserved uint64
+	for _, tier := range tiers {
+		size := min(memory, tier.size)
+		reserved += size * tier.percent / 100
+		memory -= size
+		if memory == 0 {
+			break
+		}
+	}
+	return reserved
+}
@@ -0,0 +1,30 @@
+//go:build linux
+// +build linux
+
+package agent
+
+import (
+	"runtime"
+
+	"github.com/google/cadvisor/machine"
+	"github.com/google/cadvisor/utils/sysfs"
+	"golang.org/x/sys/unix"
+)
+
+// detectMemory returns the total physical memory of the node, in bytes.
+func detectMemory() (uint64, error) {
+	info := &unix.Sysinfo_t{}
+	if err := unix.Sysinfo(info); err != nil {
+		return 0, err
+	}
+	return uint64(info.Totalram) * uint64(info.Unit), nil
+}
+
+// detectNumCPU returns the number of CPUs on the node, preferring the topology reported by sysfs.
+func detectNumCPU() int {
+	_, numCPU, err := machine.GetTopology(sysfs.NewRealSysFs())
+	if err != nil || numCPU < 1 {
+		return runtime.NumCPU()
+	}
+	return numCPU
+}
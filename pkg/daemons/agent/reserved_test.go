@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	kubeletconfig "k8s.io/kubelet/config/v1beta1"
+)
+
+func Test_UnitReservedMemory(t *testing.T) {
+	tests := []struct {
+		name   string
+		memory uint64
+		want   uint64
+	}{
+		{"512MiB", 512 * mebibyte, 255 * mebibyte},
+		{"2GiB", 2 * gibibyte, 512 * mebibyte},
+		{"8GiB", 8 * gibibyte, gibibyte + 4*gibibyte*20/100},
+		{"16GiB", 16 * gibibyte, gibibyte + 4*gibibyte*20/100 + 8*gibibyte*10/100},
+		{"256GiB", 256 * gibibyte, gibibyte + 4*gibibyte*20/100 + 8*gibibyte*10/100 + 112*gibibyte*6/100 + 128*gibibyte*2/100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reservedMemory(tt.memory); got != tt.want {
+				t.Errorf("reservedMemory() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_UnitReservedCPUMillis(t *testing.T) {
+	tests := []struct {
+		numCPU int
+		want   int64
+	}{
+		{1, 60},
+		{2, 70},
+		{4, 80},
+		{8, 90},
+	}
+	for _, tt := range tests {
+		if got := reservedCPUMillis(tt.numCPU); got != tt.want {
+			t.Errorf("reservedCPUMillis(%d) = %d, want %d", tt.numCPU, got, tt.want)
+		}
+	}
+}
+
+func Test_UnitSetReservedResources(t *testing.T) {
+	config := &kubeletconfig.KubeletConfiguration{}
+	setReservedResources(reservedProfiles["medium"], 4, 8*gibibyte, config)
+
+	if want := map[string]string{"cpu": "40m", "memory": "921Mi"}; !reflect.DeepEqual(config.SystemReserved, want) {
+		t.Errorf("SystemReserved = %v, want %v", config.SystemReserved, want)
+	}
+	if want := map[string]string{"cpu": "40m", "memory": "922Mi"}; !reflect.DeepEqual(config.KubeReserved, want) {
+		t.Errorf("KubeReserved = %v, want %v", config.KubeReserved, want)
+	}
+	if want := map[string]string{"memory.available": "250Mi", "imagefs.available": "10%", "nodefs.available": "10%"}; !reflect.DeepEqual(config.EvictionHard, want) {
+		t.Errorf("EvictionHard = %v, want %v", config.EvictionHard, want)
+	}
+
+	if err := applyReservedProfile("huge", config); err == nil {
+		t.Errorf("applyReservedProfile() with invalid profile did not return an error")
+	}
+}
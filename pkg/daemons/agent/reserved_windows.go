@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package agent
+
+import (
+	"errors"
+	"runtime"
+)
+
+// detectMemory is not implemented on Windows; system reserved profiles are only supported on Linux.
+func detectMemory() (uint64, error) {
+	return 0, errors.New("system reserved profiles are not supported on windows")
+}
+
+// detectNumCPU returns the number of CPUs on the node.
+func detectNumCPU() int {
+	return runtime.NumCPU()
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"maps"
+	"slices"
+)
+
+const (
+	ResourceProfileTiny    = "tiny"
+	ResourceProfileSmall   = "small"
+	ResourceProfileDefault = "default"
+
+	AgentProfileDefault = "default"
+	// AgentProfilePico disables kube-proxy, the network policy controller, and ServiceLB on all
+	// nodes, for use with a CNI that replaces them.
+	AgentProfilePico = "pico"
+)
+
+// ResourceProfile holds the component args that are set together to tune the control-plane for the
+// memory available on the server. Args set by a profile are overridden by args set with the
+// corresponding --kube-*-arg or --etcd-arg flags.
+type ResourceProfile struct {
+	APIServerArgs         map[string]string
+	ControllerManagerArgs map[string]string
+	ETCDArgs              map[string]string
+}
+
+// ResourceProfiles are the available control-plane resource profiles. The default profile
+// leaves all components at their upstream defaults.
+var ResourceProfiles = map[string]ResourceProfile{
+	ResourceProfileTiny: {
+		APIServerArgs: map[string]string{
+			// Only cache the resources that are watched by every node; everything else is read from the datastore.
+			"default-watch-cache-size":       "0",
+			"watch-cache-sizes":              "pods#100,nodes#100,services#100,endpointslices.discovery.k8s.io#100,leases.coordination.k8s.io#100",
+			"etcd-compaction-interval":       "2m",
+			"max-requests-inflight":          "100",
+			"max-mutating-requests-inflight": "50",
+		},
+		ControllerManagerArgs: map[string]string{
+			"concurrent-deployment-syncs":       "1",
+			"concurrent-replicaset-syncs":       "1",
+			"concurrent-endpoint-syncs":         "1",
+			"concurrent-service-endpoint-syncs": "1",
+			"concurrent-namespace-syncs":        "1",
+			"concurrent-gc-syncs":               "5",
+			"kube-api-qps":                      "10",
+			"kube-api-burst":                    "20",
+		},
+		ETCDArgs: map[string]string{
+			"snapshot-count": "2500",
+		},
+	},
+	ResourceProfileSmall: {
+		APIServerArgs: map[string]string{
+			"etcd-compaction-interval":       "3m",
+			"max-requests-inflight":          "200",
+			"max-mutating-requests-inflight": "100",
+		},
+		ControllerManagerArgs: map[string]string{
+			"concurrent-deployment-syncs":       "2",
+			"concurrent-replicaset-syncs":       "2",
+			"concurrent-endpoint-syncs":         "2",
+			"concurrent-service-endpoint-syncs": "2",
+			"concurrent-namespace-syncs":        "2",
+			"concurrent-gc-syncs":               "10",
+		},
+		ETCDArgs: map[string]string{
+			"snapshot-count": "5000",
+		},
+	},
+	ResourceProfileDefault: {},
+}
+
+// ResourceProfileNames returns the names of the available control-plane resource profiles, in sorted order.
+func ResourceProfileNames() []string {
+	return slices.Sorted(maps.Keys(ResourceProfiles))
+}
+
+// AddProfileArgs copies a profile's args into the provided args map, without overwriting args that are already set.
+func AddProfileArgs(argsMap, profileArgs map[string]string) {
+	for k, v := range profileArgs {
+		if _, ok := argsMap[k]; !ok {
+			argsMap[k] = v
+		}
+	}
+}
+
+// ETCDArgList returns the profile's etcd args in --key=value form, sorted by key, for use ahead of
+// the user-provided etcd args so that the user-provided args take precedence.
+func (p ResourceProfile) ETCDArgList() []string {
+	args := make([]string, 0, len(p.ETCDArgs))
+	for _, k := range slices.Sorted(maps.Keys(p.ETCDArgs)) {
+		args = append(args, "--"+k+"="+p.ETCDArgs[k])
+	}
+	return args
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_UnitResourceProfileArgs(t *testing.T) {
+	argsMap := map[string]string{"max-requests-inflight": "400", "secure-port": "6444"}
+	AddProfileArgs(argsMap, ResourceProfiles[ResourceProfileTiny].APIServerArgs)
+	if argsMap["max-requests-inflight"] != "400" {
+		t.Errorf("AddProfileArgs() overwrote existing arg: max-requests-inflight=%s", argsMap["max-requests-inflight"])
+	}
+	if argsMap["default-watch-cache-size"] != "0" {
+		t.Errorf("AddProfileArgs() did not set default-watch-cache-size: %v", argsMap)
+	}
+
+	// args provided by the user replace args set by the profile
+	args := GetArgs(argsMap, []string{"default-watch-cache-size=50"})
+	for _, arg := range args {
+		if arg == "--default-watch-cache-size=0" {
+			t.Errorf("GetArgs() used profile value instead of user-provided arg: %v", args)
+		}
+	}
+
+	want := []string{"--snapshot-count=2500"}
+	if got := ResourceProfiles[ResourceProfileTiny].ETCDArgList(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ETCDArgList() = %v, want %v", got, want)
+	}
+	if got := ResourceProfiles[ResourceProfileDefault].ETCDArgList(); len(got) != 0 {
+		t.Errorf("ETCDArgList() for default profile = %v, want none", got)
+	}
+	if got, want := ResourceProfileNames(), []string{"default", "small", "tiny"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ResourceProfileNames() = %v, want %v", got, want)
+	}
+}
@@ -8,12 +8,16 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/k3s-io/k3s/pkg/cgroups"
 	"github.com/k3s-io/k3s/pkg/generated/controllers/k3s.cattle.io"
+	"github.com/k3s-io/k3s/pkg/notify"
 	"github.com/k3s-io/kine/pkg/endpoint"
 	"github.com/rancher/wharfie/pkg/registries"
 	"github.com/rancher/wrangler/v3/pkg/generated/controllers/core"
 	"github.com/rancher/wrangler/v3/pkg/leader"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilnet "k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/apiserver/pkg/authentication/authenticator"
@@ -23,17 +27,37 @@ import (
 )
 
 const (
-	FlannelBackendNone            = "none"
-	FlannelBackendVXLAN           = "vxlan"
-	FlannelBackendHostGW          = "host-gw"
-	FlannelBackendWireguardNative = "wireguard-native"
-	FlannelBackendTailscale       = "tailscale"
-	EgressSelectorModeAgent       = "agent"
-	EgressSelectorModeCluster     = "cluster"
-	EgressSelectorModeDisabled    = "disabled"
-	EgressSelectorModePod         = "pod"
-	CertificateRenewDays          = 90
-	StreamServerPort              = "10010"
+	FlannelBackendNone             = "none"
+	FlannelBackendVXLAN            = "vxlan"
+	FlannelBackendHostGW           = "host-gw"
+	FlannelBackendWireguardNative  = "wireguard-native"
+	FlannelBackendTailscale        = "tailscale"
+	DefaultFlannelVXLANPort        = 8472
+	DefaultFlannelVXLANPortWindows = 4789
+	DefaultFlannelWireguardPort    = 51820
+	DefaultFlannelWireguardPortV6  = 51821
+	DefaultNodeCIDRMaskSizeIPv4    = 24
+	DefaultNodeCIDRMaskSizeIPv6    = 64
+	EgressSelectorModeAgent        = "agent"
+	EgressSelectorModeCluster      = "cluster"
+	EgressSelectorModeDisabled     = "disabled"
+	EgressSelectorModePod          = "pod"
+	EgressSelectorTypeCluster      = "cluster"
+	EgressSelectorTypeControlPlane = "controlplane"
+	EgressSelectorTypeEtcd         = "etcd"
+	EgressSelectorTypeKubelet      = "kubelet"
+	EgressSelectorTypeWebhook      = "webhook"
+	ControlPlaneExecModeEmbedded   = "embedded"
+	ControlPlaneExecModeProcesses  = "processes"
+	CertificateRenewDays           = 90
+	StreamServerPort               = "10010"
+	// ExtensionsDir is the directory within the server data-dir that holds operator-provided
+	// manifests and config for scheduler extenders and admission webhooks.
+	ExtensionsDir = "extensions"
+	// DefaultCACertDuration is the validity period of generated CA certificates.
+	DefaultCACertDuration = 10 * 365 * 24 * time.Hour
+	// DefaultLeafCertDuration is the validity period of certificates signed by the cluster CAs.
+	DefaultLeafCertDuration = 365 * 24 * time.Hour
 )
 
 type Node struct {
@@ -44,16 +68,23 @@ type Node struct {
 	SELinux                  bool
 	EnablePProf              bool
 	SupervisorMetrics        bool
+	InstanceName             string
 	EmbeddedRegistry         bool
+	EmbeddedRegistryPin      bool
 	FlannelBackend           string
 	FlannelConfFile          string
 	FlannelConfOverride      bool
 	FlannelIface             *net.Interface
 	FlannelIPv6Masq          bool
 	FlannelExternalIP        bool
+	FlannelVXLANPort         int
+	FlannelWireguardPort     int
+	FlannelWireguardPortIPv6 int
 	EgressSelectorMode       string
 	Containerd               Containerd
 	CRIDockerd               CRIDockerd
+	CRIO                     CRIO
+	LogShipping              LogShipping
 	Images                   string
 	AgentConfig              Agent
 	Token                    string
@@ -93,6 +124,15 @@ type Containerd struct {
 	NonrootDevices bool
 	SELinux        bool
 	Debug          bool
+	Proxy          string
+	Limits         *cgroups.Limits
+}
+
+// LogShipping configures forwarding of container logs to a remote endpoint
+type LogShipping struct {
+	Type          string
+	Endpoint      string
+	PositionsFile string
 }
 
 type CRIDockerd struct {
@@ -100,12 +140,23 @@ type CRIDockerd struct {
 	Root    string
 }
 
+// CRIO holds the configuration used when the container runtime endpoint is CRI-O.
+type CRIO struct {
+	Enabled      bool
+	AuthFile     string
+	ManagedFiles string
+}
+
 type Agent struct {
 	PodManifests            string
 	NodeName                string
 	NodeConfigPath          string
 	ClientKubeletCert       string
 	ClientKubeletKey        string
+	ClientKubeProxyCert     string
+	ClientKubeProxyKey      string
+	ClientK3sControllerCert string
+	ClientK3sControllerKey  string
 	ServingKubeletCert      string
 	ServingKubeletKey       string
 	ServiceCIDR             *net.IPNet
@@ -118,7 +169,11 @@ type Agent struct {
 	ClusterDomain           string
 	ResolvConf              string
 	RootDir                 string
+	ImageStoreDir           string
 	KubeletConfigDir        string
+	KubeletConfig           string
+	KubeProxyConfig         string
+	KubeProxyConfigPath     string
 	KubeConfigKubelet       string
 	KubeConfigKubeProxy     string
 	KubeConfigK3sController string
@@ -132,8 +187,11 @@ type Agent struct {
 	ImageServiceSocket      string
 	ListenAddress           string
 	ClientCA                string
+	ServerCA                string
 	CNIBinDir               string
 	CNIConfDir              string
+	CNIConfName             string
+	CNINetworkName          string
 	ExtraKubeletArgs        []string
 	ExtraKubeProxyArgs      []string
 	PauseImage              string
@@ -142,8 +200,21 @@ type Agent struct {
 	CNIPlugin               bool
 	NodeTaints              []string
 	NodeLabels              []string
+	NodeTaintPolicies       []string
+	NodeLabelPolicies       []string
 	ImageCredProvBinDir     string
 	ImageCredProvConfig     string
+	ImageCredProviders      []string
+	StaticPodDir            string
+	SystemReservedProfile   string
+	SwapBehavior            string
+	KubeletServingCSR       bool
+	DRA                     bool
+	PodResourcesDir         string
+	DNSFallbackCacheFile    string
+	SupervisorLimits        *cgroups.Limits
+	KubeletLimits           *cgroups.Limits
+	SupervisorOOMProtection bool
 	IPSECPSK                string
 	FlannelCniConfFile      string
 	Registry                *registries.Registry
@@ -162,12 +233,19 @@ type Agent struct {
 	VModule                 string
 	LogFile                 string
 	AlsoLogToStderr         bool
+	WarmRestartFile         string
+	TunnelKeepAliveTimeout  time.Duration
+	TunnelCompression       bool
+	TunnelReconnectDelay    time.Duration
+	TunnelReconnectJitter   float64
+	SupervisorProxy         string
 }
 
 // CriticalControlArgs contains parameters that all control plane nodes in HA must share
 // The cli tag is used to provide better error information to the user on mismatch
 type CriticalControlArgs struct {
 	ClusterDNSs           []net.IP     `cli:"cluster-dns"`
+	ClusterDNSServiceIPs  []net.IP     `cli:"cluster-dns-service-ip"`
 	ClusterIPRanges       []*net.IPNet `cli:"cluster-cidr"`
 	ClusterDNS            net.IP       `cli:"cluster-dns"`
 	ClusterDomain         string       `cli:"cluster-domain"`
@@ -178,13 +256,17 @@ type CriticalControlArgs struct {
 	DisableServiceLB      bool         `cli:"disable-service-lb"`
 	EncryptSecrets        bool         `cli:"secrets-encryption"`
 	EmbeddedRegistry      bool         `cli:"embedded-registry"`
+	EmbeddedRegistryPin   bool         `cli:"embedded-registry-pin-digests"`
 	FlannelBackend        string       `cli:"flannel-backend"`
 	FlannelIPv6Masq       bool         `cli:"flannel-ipv6-masq"`
 	FlannelExternalIP     bool         `cli:"flannel-external-ip"`
+	NodeCIDRMaskSizeIPv4  int          `cli:"node-cidr-mask-size-ipv4"`
+	NodeCIDRMaskSizeIPv6  int          `cli:"node-cidr-mask-size-ipv6"`
 	EgressSelectorMode    string       `cli:"egress-selector-mode"`
 	ServiceIPRange        *net.IPNet   `cli:"service-cidr"`
 	ServiceIPRanges       []*net.IPNet `cli:"service-cidr"`
 	SupervisorMetrics     bool         `cli:"supervisor-metrics"`
+	DRA                   bool         `cli:"dynamic-resource-allocation"`
 }
 
 type Control struct {
@@ -195,12 +277,26 @@ type Control struct {
 	HTTPSPort int
 	// The port which custom k3s API runs on
 	SupervisorPort int
+	// An additional port that the custom k3s API is served on while agents are migrated to the supervisor port
+	SupervisorLegacyPort int
+	// Failed supervisor authentication attempts from a client address within the lockout period, after which
+	// the address is locked out; 0 disables lockout
+	SupervisorAuthMaxFailures int
+	// The period over which failed supervisor authentication attempts are counted, and for which a client
+	// address is locked out
+	SupervisorAuthLockout time.Duration
+	// Egress selector mode overrides for apiserver egress types and kinds of cluster egress traffic, keyed by type
+	EgressSelectorTypeModes map[string]string
+	// Bandwidth limits for connections proxied through agent tunnels
+	TunnelBandwidthLimits *TunnelBandwidthLimits
 	// The port which kube-apiserver runs on
 	APIServerPort            int
 	APIServerBindAddress     string
 	AgentToken               string `json:"-"`
 	Token                    string `json:"-"`
 	ServiceNodePortRange     *utilnet.PortRange
+	AllowServiceRangeChange  bool
+	PreviousClusterDomains   []string `json:"-"`
 	KubeConfigOutput         string
 	KubeConfigMode           string
 	KubeConfigGroup          string
@@ -216,6 +312,7 @@ type Control struct {
 	DisableKubeProxy         bool
 	DisableScheduler         bool
 	DisableServiceLB         bool
+	TokenViewer              bool
 	Rootless                 bool
 	ServiceLBNamespace       string
 	ExtraAPIArgs             []string
@@ -223,28 +320,40 @@ type Control struct {
 	ExtraCloudControllerArgs []string
 	ExtraEtcdArgs            []string
 	ExtraSchedulerAPIArgs    []string
+	ControlPlaneExecMode     string
+	ResourceProfile          string
+	EnablePProf              bool
+	TunnelKeepAliveTimeout   time.Duration
+	ControlPlaneLimits       map[string]*cgroups.Limits `json:"-"`
 	NoLeaderElect            bool
 	JoinURL                  string
 	IPSECPSK                 string
 	DefaultLocalStoragePath  string
 	Skips                    map[string]bool
+	SkipDeploys              map[string]bool
+	PinDeploys               map[string]bool
 	SystemDefaultRegistry    string
+	SystemImages             map[string]string
 	ClusterInit              bool
 	ClusterReset             bool
 	ClusterResetRestorePath  string
 	MinTLSVersion            string
 	CipherSuites             []string
-	TLSMinVersion            uint16   `json:"-"`
-	TLSCipherSuites          []uint16 `json:"-"`
-	EtcdSnapshotName         string   `json:"-"`
-	EtcdDisableSnapshots     bool     `json:"-"`
-	EtcdExposeMetrics        bool     `json:"-"`
-	EtcdSnapshotDir          string   `json:"-"`
-	EtcdSnapshotCron         string   `json:"-"`
-	EtcdSnapshotRetention    int      `json:"-"`
-	EtcdSnapshotCompress     bool     `json:"-"`
-	EtcdListFormat           string   `json:"-"`
-	EtcdS3                   *EtcdS3  `json:"-"`
+	TLSMinVersion            uint16        `json:"-"`
+	TLSCipherSuites          []uint16      `json:"-"`
+	EtcdSnapshotName         string        `json:"-"`
+	EtcdDisableSnapshots     bool          `json:"-"`
+	EtcdExposeMetrics        bool          `json:"-"`
+	EtcdDataDir              string        `json:"-"`
+	EtcdIOWeight             int           `json:"-"`
+	EtcdFsyncWarning         time.Duration `json:"-"`
+	EtcdSnapshotDir          string        `json:"-"`
+	EtcdSnapshotCron         string        `json:"-"`
+	EtcdIncrementalCron      string        `json:"-"`
+	EtcdSnapshotRetention    int           `json:"-"`
+	EtcdSnapshotCompress     bool          `json:"-"`
+	EtcdListFormat           string        `json:"-"`
+	EtcdS3                   *EtcdS3       `json:"-"`
 	ServerNodeName           string
 	VLevel                   int
 	VModule                  string
@@ -254,6 +363,155 @@ type Control struct {
 	SANSecurity bool
 	PrivateIP   string
 	Runtime     *ControlRuntime `json:"-"`
+
+	// CACertDuration, ServerCertDuration and ClientCertDuration are the validity periods of
+	// generated CA, server and client certificates. Zero uses the default for the class.
+	CACertDuration     time.Duration
+	ServerCertDuration time.Duration
+	ClientCertDuration time.Duration
+}
+
+// EgressSelectorTypes are the apiserver egress types that can be configured.
+var EgressSelectorTypes = []string{EgressSelectorTypeCluster, EgressSelectorTypeControlPlane, EgressSelectorTypeEtcd}
+
+// EgressSelectorClusterTypes are the kinds of traffic carried by cluster egress, which the tunnel server
+// routes separately: connections to kubelets, and connections to webhooks and aggregated apiservers.
+var EgressSelectorClusterTypes = []string{EgressSelectorTypeKubelet, EgressSelectorTypeWebhook}
+
+// EgressSelectorModeForType returns the egress selector mode for an apiserver egress type, or for a kind of
+// cluster egress traffic. Cluster egress traffic uses the cluster egress mode unless overridden, which in turn
+// defaults to the egress selector mode; other egress types are direct unless overridden. Cluster egress itself
+// is proxied if any of the traffic it carries is.
+func (c *Control) EgressSelectorModeForType(egressType string) string {
+	switch egressType {
+	case EgressSelectorTypeCluster:
+		for _, clusterType := range EgressSelectorClusterTypes {
+			if mode := c.EgressSelectorModeForType(clusterType); mode != EgressSelectorModeDisabled {
+				return mode
+			}
+		}
+		return EgressSelectorModeDisabled
+	case EgressSelectorTypeKubelet, EgressSelectorTypeWebhook:
+		if mode, ok := c.EgressSelectorTypeModes[egressType]; ok {
+			return mode
+		}
+		if mode, ok := c.EgressSelectorTypeModes[EgressSelectorTypeCluster]; ok {
+			return mode
+		}
+		return c.EgressSelectorMode
+	}
+	if mode, ok := c.EgressSelectorTypeModes[egressType]; ok {
+		return mode
+	}
+	return EgressSelectorModeDisabled
+}
+
+// EgressSelectorProxied returns true if any apiserver egress type is proxied by the supervisor.
+func (c *Control) EgressSelectorProxied() bool {
+	for _, egressType := range EgressSelectorTypes {
+		if c.EgressSelectorModeForType(egressType) != EgressSelectorModeDisabled {
+			return true
+		}
+	}
+	return false
+}
+
+// FlannelPorts returns the UDP ports that the flannel backend listens on for overlay traffic
+// between nodes, or nil if the backend does not use a port of its own.
+func (n *Node) FlannelPorts() []int {
+	if n.NoFlannel {
+		return nil
+	}
+	switch n.FlannelBackend {
+	case FlannelBackendVXLAN:
+		return []int{n.FlannelVXLANPort}
+	case FlannelBackendWireguardNative:
+		return []int{n.FlannelWireguardPort, n.FlannelWireguardPortIPv6}
+	}
+	return nil
+}
+
+// CACertValidity returns the validity period of generated CA certificates.
+func (c *Control) CACertValidity() time.Duration {
+	if c.CACertDuration > 0 {
+		return c.CACertDuration
+	}
+	return DefaultCACertDuration
+}
+
+// TunnelBandwidthLimits are the rates, in bytes per second, at which connections proxied through agent tunnels
+// are limited. Node limits are shared by all tunneled connections to a node; namespace limits are shared by all
+// tunneled connections to pods in a namespace. The default limits apply to nodes or namespaces without their own limit.
+type TunnelBandwidthLimits struct {
+	Node       *resource.Quantity
+	Namespace  *resource.Quantity
+	Nodes      map[string]*resource.Quantity
+	Namespaces map[string]*resource.Quantity
+}
+
+// NodeLimit returns the bandwidth limit for tunneled connections to a node, or nil if the node is not limited.
+func (l *TunnelBandwidthLimits) NodeLimit(nodeName string) *resource.Quantity {
+	if l == nil {
+		return nil
+	}
+	if q, ok := l.Nodes[nodeName]; ok {
+		return q
+	}
+	return l.Node
+}
+
+// NamespaceLimit returns the bandwidth limit for tunneled connections to pods in a namespace, or nil if the namespace is not limited.
+func (l *TunnelBandwidthLimits) NamespaceLimit(namespace string) *resource.Quantity {
+	if l == nil || namespace == "" {
+		return nil
+	}
+	if q, ok := l.Namespaces[namespace]; ok {
+		return q
+	}
+	return l.Namespace
+}
+
+// ParseTunnelBandwidthLimits parses a list of SCOPE=QUANTITY entries into tunnel bandwidth limits. Scope is
+// either node or namespace to set the default limit, or node/NAME or namespace/NAME to set the limit for a
+// single node or namespace.
+func ParseTunnelBandwidthLimits(entries []string) (*TunnelBandwidthLimits, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	limits := &TunnelBandwidthLimits{
+		Nodes:      map[string]*resource.Quantity{},
+		Namespaces: map[string]*resource.Quantity{},
+	}
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid tunnel bandwidth limit %q: must be in the form SCOPE=QUANTITY", entry)
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tunnel bandwidth limit %q: %v", entry, err)
+		}
+		if quantity.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid tunnel bandwidth limit %q: quantity must be greater than zero", entry)
+		}
+		scope, name, named := strings.Cut(key, "/")
+		if named && name == "" {
+			return nil, fmt.Errorf("invalid tunnel bandwidth limit %q: name must not be empty", entry)
+		}
+		switch {
+		case scope == "node" && named:
+			limits.Nodes[name] = &quantity
+		case scope == "node":
+			limits.Node = &quantity
+		case scope == "namespace" && named:
+			limits.Namespaces[name] = &quantity
+		case scope == "namespace":
+			limits.Namespace = &quantity
+		default:
+			return nil, fmt.Errorf("invalid tunnel bandwidth limit %q: unsupported scope %s; must be one of node, node/NAME, namespace, namespace/NAME", entry, key)
+		}
+	}
+	return limits, nil
 }
 
 // BindAddressOrLoopback returns an IPv4 or IPv6 address suitable for embedding in
@@ -377,6 +635,7 @@ type ControlRuntime struct {
 	K3s        *k3s.Factory
 	Core       CoreFactory
 	Event      record.EventRecorder
+	Notifier   *notify.Webhook
 	EtcdConfig endpoint.ETCDConfig
 }
 
@@ -386,6 +645,55 @@ type CoreFactory interface {
 	Start(ctx context.Context, defaultThreadiness int) error
 }
 
+// TunnelSession describes an agent websocket tunnel connected to the supervisor.
+type TunnelSession struct {
+	NodeName       string    `json:"nodeName"`
+	Address        string    `json:"address"`
+	ConnectedSince time.Time `json:"connectedSince"`
+	BytesReceived  int64     `json:"bytesReceived"`
+	BytesSent      int64     `json:"bytesSent"`
+}
+
+// EgressStatus describes the apiserver egress selector configuration, and the connections
+// proxied by the supervisor's tunnel server since it started.
+type EgressStatus struct {
+	Mode                string             `json:"mode"`
+	Types               []EgressTypeStatus `json:"types"`
+	Sessions            int                `json:"sessions"`
+	TunneledConnections int64              `json:"tunneledConnections"`
+	DirectConnections   int64              `json:"directConnections"`
+	FailedConnections   int64              `json:"failedConnections"`
+}
+
+// EgressTypeStatus describes the egress selector mode for a single apiserver egress type.
+type EgressTypeStatus struct {
+	Type    string `json:"type"`
+	Mode    string `json:"mode"`
+	Proxied bool   `json:"proxied"`
+}
+
+// MemStats describes the memory use of the supervisor process, and the embedded components that
+// have been disabled to reduce it.
+type MemStats struct {
+	RSS        uint64   `json:"rss"`
+	HeapAlloc  uint64   `json:"heapAlloc"`
+	HeapInuse  uint64   `json:"heapInuse"`
+	Sys        uint64   `json:"sys"`
+	NumGC      uint32   `json:"numGC"`
+	Goroutines int      `json:"goroutines"`
+	Disabled   []string `json:"disabled"`
+}
+
+// EgressStatusReporter is implemented by tunnel servers that report egress status.
+type EgressStatusReporter interface {
+	EgressStatus() EgressStatus
+}
+
+// TunnelSessionLister is implemented by tunnel servers that track connected agent sessions.
+type TunnelSessionLister interface {
+	Sessions() []TunnelSession
+}
+
 func NewRuntime(containerRuntimeReady <-chan struct{}) *ControlRuntime {
 	return &ControlRuntime{
 		ContainerRuntimeReady:                containerRuntimeReady,
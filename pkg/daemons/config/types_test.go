@@ -149,3 +149,74 @@ func Test_UnitGetArgs(t *testing.T) {
 		})
 	}
 }
+
+func Test_UnitParseTunnelBandwidthLimits(t *testing.T) {
+	tests := []struct {
+		name          string
+		entries       []string
+		wantErr       bool
+		wantNode      map[string]string
+		wantNamespace map[string]string
+	}{
+		{
+			name:          "No limits",
+			wantNode:      map[string]string{"agent-1": ""},
+			wantNamespace: map[string]string{"default": ""},
+		},
+		{
+			name:          "Defaults and overrides",
+			entries:       []string{"node=10Mi", "node/agent-1=1Mi", "namespace/bulk=512Ki"},
+			wantNode:      map[string]string{"agent-1": "1Mi", "agent-2": "10Mi"},
+			wantNamespace: map[string]string{"bulk": "512Ki", "default": "", "": ""},
+		},
+		{
+			name:          "Namespace default",
+			entries:       []string{"namespace=1M"},
+			wantNode:      map[string]string{"agent-1": ""},
+			wantNamespace: map[string]string{"default": "1M", "": ""},
+		},
+		{
+			name:    "Missing quantity",
+			entries: []string{"node"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid quantity",
+			entries: []string{"node=fast"},
+			wantErr: true,
+		},
+		{
+			name:    "Zero quantity",
+			entries: []string{"node=0"},
+			wantErr: true,
+		},
+		{
+			name:    "Empty name",
+			entries: []string{"namespace/=1Mi"},
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported scope",
+			entries: []string{"pod/foo=1Mi"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits, err := ParseTunnelBandwidthLimits(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTunnelBandwidthLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for name, want := range tt.wantNode {
+				if got := limits.NodeLimit(name); (got == nil && want != "") || (got != nil && got.String() != want) {
+					t.Errorf("NodeLimit(%q) = %v, want %q", name, got, want)
+				}
+			}
+			for name, want := range tt.wantNamespace {
+				if got := limits.NamespaceLimit(name); (got == nil && want != "") || (got != nil && got.String() != want) {
+					t.Errorf("NamespaceLimit(%q) = %v, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package control
+
+import (
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/authenticator"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	k8sauthenticator "k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/authenticatorfactory"
+	"k8s.io/apiserver/pkg/server/options"
+	"k8s.io/client-go/transport"
+)
+
+// setupAPIServerProxy configures the supervisor to serve the apiserver's API when the apiserver is run as a
+// child process, and its request handlers are not available in-process. Requests are authenticated by the
+// supervisor and forwarded to the apiserver with the authenticated user set in request headers, using the
+// front-proxy client certificate, in the same way that the aggregation layer forwards requests to extension
+// apiservers. Bearer tokens are authenticated by the apiserver via TokenReview.
+func setupAPIServerProxy(cfg *config.Control) error {
+	runtime := cfg.Runtime
+
+	client, err := util.GetClientSet(runtime.KubeConfigSupervisor)
+	if err != nil {
+		return err
+	}
+	tokenAuth, _, err := authenticatorfactory.DelegatingAuthenticatorConfig{
+		TokenAccessReviewClient:  client.AuthenticationV1(),
+		TokenAccessReviewTimeout: 10 * time.Second,
+		WebhookRetryBackoff:      options.DefaultAuthWebhookRetryBackoff(),
+		CacheTTL:                 10 * time.Second,
+	}.New()
+	if err != nil {
+		return errors.Wrap(err, "failed to create token authenticator")
+	}
+
+	rt, err := transport.New(&transport.Config{
+		TLS: transport.TLSConfig{
+			CAFile:   runtime.ServerCA,
+			CertFile: runtime.ClientAuthProxyCert,
+			KeyFile:  runtime.ClientAuthProxyKey,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create apiserver proxy transport")
+	}
+
+	target := &url.URL{
+		Scheme: "https",
+		Host:   net.JoinHostPort(cfg.Loopback(true), strconv.Itoa(cfg.APIServerPort)),
+	}
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.SetXForwarded()
+		},
+		Transport:     rt,
+		FlushInterval: -1,
+		ErrorHandler: func(resp http.ResponseWriter, req *http.Request, err error) {
+			logrus.Debugf("Failed to proxy request to apiserver: %v", err)
+			util.SendError(errors.New("apiserver not ready"), resp, req, http.StatusServiceUnavailable)
+		},
+	}
+
+	runtime.Authenticator = authenticator.Combine(runtime.Authenticator, tokenAuth)
+	runtime.APIServer = apiServerProxyHandler(runtime.Authenticator, proxy)
+
+	return nil
+}
+
+// apiServerProxyHandler returns a handler that authenticates requests, replaces any client-supplied
+// authentication headers with the authenticated user, and passes the request to the proxy.
+func apiServerProxyHandler(auth k8sauthenticator.Request, proxy http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		authResp, ok, err := auth.AuthenticateRequest(req)
+		if err != nil || !ok {
+			util.SendError(errors.New("Unauthorized"), resp, req, http.StatusUnauthorized)
+			return
+		}
+
+		req = req.Clone(req.Context())
+		req.Header.Del("Authorization")
+		for name := range req.Header {
+			if strings.HasPrefix(name, "X-Remote-") {
+				req.Header.Del(name)
+			}
+		}
+		user := authResp.User
+		req.Header.Set("X-Remote-User", user.GetName())
+		for _, group := range user.GetGroups() {
+			req.Header.Add("X-Remote-Group", group)
+		}
+		for key, values := range user.GetExtra() {
+			for _, value := range values {
+				req.Header.Add("X-Remote-Extra-"+url.PathEscape(key), value)
+			}
+		}
+
+		proxy.ServeHTTP(resp, req)
+	})
+}
@@ -0,0 +1,60 @@
+package control
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func Test_UnitAPIServerProxyHandler(t *testing.T) {
+	auth := authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+		if req.Header.Get("Authorization") != "Bearer valid" {
+			return nil, false, nil
+		}
+		return &authenticator.Response{User: &user.DefaultInfo{Name: "alice", Groups: []string{"devs"}}}, true, nil
+	})
+
+	var proxied *http.Request
+	handler := apiServerProxyHandler(auth, http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		proxied = req
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Remote-User", "system:admin")
+	req.Header.Set("X-Remote-Group", "system:masters")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	if resp.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d for unauthenticated request, got %d", http.StatusUnauthorized, resp.Code)
+	}
+	if proxied != nil {
+		t.Fatalf("expected unauthenticated request not to be proxied")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("Authorization", "Bearer valid")
+	req.Header.Set("X-Remote-User", "system:admin")
+	req.Header.Add("X-Remote-Group", "system:masters")
+	req.Header.Set("X-Remote-Extra-Scopes", "all")
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	if proxied == nil {
+		t.Fatalf("expected authenticated request to be proxied, got status %d", resp.Code)
+	}
+	if got := proxied.Header.Get("Authorization"); got != "" {
+		t.Errorf("expected Authorization header to be removed, got %q", got)
+	}
+	if got := proxied.Header.Values("X-Remote-User"); !reflect.DeepEqual(got, []string{"alice"}) {
+		t.Errorf("expected X-Remote-User [alice], got %v", got)
+	}
+	if got := proxied.Header.Values("X-Remote-Group"); !reflect.DeepEqual(got, []string{"devs"}) {
+		t.Errorf("expected X-Remote-Group [devs], got %v", got)
+	}
+	if got := proxied.Header.Values("X-Remote-Extra-Scopes"); len(got) != 0 {
+		t.Errorf("expected client-supplied X-Remote-Extra-Scopes to be removed, got %v", got)
+	}
+}
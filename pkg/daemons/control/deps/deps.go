@@ -6,15 +6,18 @@ import (
 	cryptorand "crypto/rand"
 	"crypto/sha256"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	b64 "encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
+	"math/big"
 	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 	"time"
@@ -22,6 +25,8 @@ import (
 	"github.com/k3s-io/k3s/pkg/clientaccess"
 	"github.com/k3s-io/k3s/pkg/cloudprovider"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/images"
+	"github.com/k3s-io/k3s/pkg/notify"
 	"github.com/k3s-io/k3s/pkg/passwd"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
@@ -40,6 +45,9 @@ const (
 	aescbcKeySize  = 32
 
 	RequestHeaderCN = "system:auth-proxy"
+
+	// caBackdate is subtracted from the NotBefore time of generated CA certificates.
+	caBackdate = 24 * time.Hour
 )
 
 var kubeconfigTemplate = template.Must(template.New("kubeconfig").Parse(`apiVersion: v1
@@ -173,9 +181,14 @@ func CreateRuntimeCertFiles(config *config.Control) {
 // needed to successfully bootstrap a cluster.
 func GenServerDeps(config *config.Control) error {
 	runtime := config.Runtime
+	tlsDir := filepath.Join(config.DataDir, "tls")
+	existing := readCerts(tlsDir)
 	if err := genCerts(config); err != nil {
 		return err
 	}
+	if rotated := rotatedCerts(tlsDir, existing); len(rotated) > 0 {
+		runtime.Notifier.Notifyf(notify.CertificatesRotated, config.ServerNodeName, "Rotated certificates: %s", strings.Join(rotated, ", "))
+	}
 
 	if err := genServiceAccount(runtime); err != nil {
 		return err
@@ -310,15 +323,15 @@ func genCerts(config *config.Control) error {
 	return genETCDCerts(config)
 }
 
-func getSigningCertFactory(regen bool, altNames *certutil.AltNames, extKeyUsage []x509.ExtKeyUsage, caCertFile, caKeyFile string) signedCertFactory {
+func getSigningCertFactory(regen bool, altNames *certutil.AltNames, extKeyUsage []x509.ExtKeyUsage, duration time.Duration, caCertFile, caKeyFile string) signedCertFactory {
 	return func(commonName string, organization []string, certFile, keyFile string) (bool, error) {
-		return createClientCertKey(regen, commonName, organization, altNames, extKeyUsage, caCertFile, caKeyFile, certFile, keyFile)
+		return createClientCertKey(regen, commonName, organization, altNames, extKeyUsage, duration, caCertFile, caKeyFile, certFile, keyFile)
 	}
 }
 
 func genClientCerts(config *config.Control) error {
 	runtime := config.Runtime
-	regen, err := createSigningCertKey(version.Program+"-client", runtime.ClientCA, runtime.ClientCAKey)
+	regen, err := createSigningCertKey(version.Program+"-client", runtime.ClientCA, runtime.ClientCAKey, config.CACertValidity())
 	if err != nil {
 		return err
 	}
@@ -335,7 +348,7 @@ func genClientCerts(config *config.Control) error {
 		return err
 	}
 
-	factory := getSigningCertFactory(regen, nil, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, runtime.ClientCA, runtime.ClientCAKey)
+	factory := getSigningCertFactory(regen, nil, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, config.ClientCertDuration, runtime.ClientCA, runtime.ClientCAKey)
 
 	var certGen bool
 
@@ -426,11 +439,14 @@ func genServerCerts(config *config.Control) error {
 	altNames := &certutil.AltNames{
 		DNSNames: []string{"kubernetes", "kubernetes.default", "kubernetes.default.svc", "kubernetes.default.svc." + config.ClusterDomain},
 	}
+	for _, domain := range config.PreviousClusterDomains {
+		altNames.DNSNames = append(altNames.DNSNames, "kubernetes.default.svc."+domain)
+	}
 
 	addSANs(altNames, config.SANs)
 
 	if _, err := createClientCertKey(regen, "kube-apiserver", nil,
-		altNames, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		altNames, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, config.ServerCertDuration,
 		runtime.ServerCA, runtime.ServerCAKey,
 		runtime.ServingKubeAPICert, runtime.ServingKubeAPIKey); err != nil {
 		return err
@@ -445,7 +461,7 @@ func genServerCerts(config *config.Control) error {
 
 func genETCDCerts(config *config.Control) error {
 	runtime := config.Runtime
-	regen, err := createSigningCertKey("etcd-server", runtime.ETCDServerCA, runtime.ETCDServerCAKey)
+	regen, err := createSigningCertKey("etcd-server", runtime.ETCDServerCA, runtime.ETCDServerCAKey, config.CACertValidity())
 	if err != nil {
 		return err
 	}
@@ -457,19 +473,19 @@ func genETCDCerts(config *config.Control) error {
 	addSANs(altNames, config.SANs)
 
 	if _, err := createClientCertKey(regen, "etcd-client", nil,
-		nil, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		nil, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, config.ClientCertDuration,
 		runtime.ETCDServerCA, runtime.ETCDServerCAKey,
 		runtime.ClientETCDCert, runtime.ClientETCDKey); err != nil {
 		return err
 	}
 
-	regen, err = createSigningCertKey("etcd-peer", runtime.ETCDPeerCA, runtime.ETCDPeerCAKey)
+	regen, err = createSigningCertKey("etcd-peer", runtime.ETCDPeerCA, runtime.ETCDPeerCAKey, config.CACertValidity())
 	if err != nil {
 		return err
 	}
 
 	if _, err := createClientCertKey(regen, "etcd-peer", nil,
-		altNames, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		altNames, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}, config.ServerCertDuration,
 		runtime.ETCDPeerCA, runtime.ETCDPeerCAKey,
 		runtime.PeerServerClientETCDCert, runtime.PeerServerClientETCDKey); err != nil {
 		return err
@@ -480,7 +496,7 @@ func genETCDCerts(config *config.Control) error {
 	}
 
 	if _, err := createClientCertKey(regen, "etcd-server", nil,
-		altNames, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		altNames, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}, config.ServerCertDuration,
 		runtime.ETCDServerCA, runtime.ETCDServerCAKey,
 		runtime.ServerETCDCert, runtime.ServerETCDKey); err != nil {
 		return err
@@ -491,13 +507,13 @@ func genETCDCerts(config *config.Control) error {
 
 func genRequestHeaderCerts(config *config.Control) error {
 	runtime := config.Runtime
-	regen, err := createSigningCertKey(version.Program+"-request-header", runtime.RequestHeaderCA, runtime.RequestHeaderCAKey)
+	regen, err := createSigningCertKey(version.Program+"-request-header", runtime.RequestHeaderCA, runtime.RequestHeaderCAKey, config.CACertValidity())
 	if err != nil {
 		return err
 	}
 
 	if _, err := createClientCertKey(regen, RequestHeaderCN, nil,
-		nil, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		nil, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, config.ClientCertDuration,
 		runtime.RequestHeaderCA, runtime.RequestHeaderCAKey,
 		runtime.ClientAuthProxyCert, runtime.ClientAuthProxyKey); err != nil {
 		return err
@@ -523,7 +539,7 @@ func createServerSigningCertKey(config *config.Control) (bool, error) {
 		}
 		return true, nil
 	}
-	regen, err := createSigningCertKey(version.Program+"-server", runtime.ServerCA, runtime.ServerCAKey)
+	regen, err := createSigningCertKey(version.Program+"-server", runtime.ServerCA, runtime.ServerCAKey, config.CACertValidity())
 	if err != nil {
 		return regen, err
 	}
@@ -595,7 +611,7 @@ func fieldsChanged(certFile string, commonName string, organization []string, sa
 	return !bytes.Equal(certificates[0].AuthorityKeyId, caCertificates[0].SubjectKeyId)
 }
 
-func createClientCertKey(regen bool, commonName string, organization []string, altNames *certutil.AltNames, extKeyUsage []x509.ExtKeyUsage, caCertFile, caKeyFile, certFile, keyFile string) (bool, error) {
+func createClientCertKey(regen bool, commonName string, organization []string, altNames *certutil.AltNames, extKeyUsage []x509.ExtKeyUsage, duration time.Duration, caCertFile, caKeyFile, certFile, keyFile string) (bool, error) {
 	// check for reasons to renew the certificate even if not manually requested.
 	regen = regen || expired(certFile) || fieldsChanged(certFile, commonName, organization, altNames, caCertFile)
 
@@ -629,6 +645,7 @@ func createClientCertKey(regen bool, commonName string, organization []string, a
 		CommonName:   commonName,
 		Organization: organization,
 		Usages:       extKeyUsage,
+		ExpiresAt:    util.LeafCertDuration(duration, caCerts[0]),
 	}
 	if altNames != nil {
 		cfg.AltNames = *altNames
@@ -682,7 +699,7 @@ func genServiceAccount(runtime *config.ControlRuntime) error {
 	return certutil.WriteKey(runtime.ServiceCurrentKey, keyData)
 }
 
-func createSigningCertKey(prefix, certFile, keyFile string) (bool, error) {
+func createSigningCertKey(prefix, certFile, keyFile string, duration time.Duration) (bool, error) {
 	if exists(certFile, keyFile) {
 		return false, nil
 	}
@@ -701,7 +718,7 @@ func createSigningCertKey(prefix, certFile, keyFile string) (bool, error) {
 		CommonName: fmt.Sprintf("%s-ca@%d", prefix, time.Now().Unix()),
 	}
 
-	cert, err := certutil.NewSelfSignedCACert(cfg, caKey.(crypto.Signer))
+	cert, err := newSelfSignedCACert(cfg, caKey.(crypto.Signer), time.Now(), duration)
 	if err != nil {
 		return false, err
 	}
@@ -712,6 +729,35 @@ func createSigningCertKey(prefix, certFile, keyFile string) (bool, error) {
 	return true, nil
 }
 
+// newSelfSignedCACert creates a CA certificate in the same way as certutil.NewSelfSignedCACert, but
+// with the NotBefore time backdated by caBackdate. Certificates signed by the CA are valid from the
+// NotBefore time of the CA, so this allows nodes whose clocks lag behind the server's, such as devices
+// without a real-time clock, to accept newly issued certificates.
+func newSelfSignedCACert(cfg certutil.Config, key crypto.Signer, now time.Time, duration time.Duration) (*x509.Certificate, error) {
+	tmpl := x509.Certificate{
+		SerialNumber: new(big.Int).SetInt64(0),
+		Subject: pkix.Name{
+			CommonName:   cfg.CommonName,
+			Organization: cfg.Organization,
+		},
+		NotBefore:             now.Add(-caBackdate).UTC(),
+		NotAfter:              now.Add(duration).UTC(),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certDERBytes, err := x509.CreateCertificate(cryptorand.Reader, &tmpl, &tmpl, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("generated self-signed CA certificate %s: notBefore=%s notAfter=%s",
+		tmpl.Subject, tmpl.NotBefore, tmpl.NotAfter)
+
+	return x509.ParseCertificate(certDERBytes)
+}
+
 func expired(certFile string) bool {
 	certificates, err := certutil.CertsFromFile(certFile)
 	if err != nil {
@@ -720,6 +766,34 @@ func expired(certFile string) bool {
 	return certutil.IsCertExpired(certificates[0], config.CertificateRenewDays)
 }
 
+// readCerts returns the contents of all certificate files within a directory, keyed by path.
+func readCerts(dir string) map[string][]byte {
+	certs := map[string][]byte{}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".crt" {
+			return nil
+		}
+		if b, err := os.ReadFile(path); err == nil {
+			certs[path] = b
+		}
+		return nil
+	})
+	return certs
+}
+
+// rotatedCerts returns the names of previously existing certificate files whose contents have changed.
+func rotatedCerts(dir string, existing map[string][]byte) []string {
+	rotated := []string{}
+	for path, b := range readCerts(dir) {
+		if old, ok := existing[path]; ok && !bytes.Equal(old, b) {
+			name, _ := filepath.Rel(dir, path)
+			rotated = append(rotated, name)
+		}
+	}
+	sort.Strings(rotated)
+	return rotated
+}
+
 func genEncryptionConfigAndState(controlConfig *config.Control) error {
 	runtime := controlConfig.Runtime
 	if !controlConfig.EncryptSecrets {
@@ -785,39 +859,37 @@ func genEncryptionConfigAndState(controlConfig *config.Control) error {
 }
 
 func genEgressSelectorConfig(controlConfig *config.Control) error {
-	var clusterConn apiserverv1beta1.Connection
+	egressConfig := apiserverv1beta1.EgressSelectorConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "EgressSelectorConfiguration",
+			APIVersion: "apiserver.k8s.io/v1beta1",
+		},
+	}
 
-	if controlConfig.EgressSelectorMode == config.EgressSelectorModeDisabled {
-		clusterConn = apiserverv1beta1.Connection{
+	// Each egress type is either proxied through the supervisor's tunnel server, or dialed directly.
+	for _, egressType := range config.EgressSelectorTypes {
+		conn := apiserverv1beta1.Connection{
 			ProxyProtocol: apiserverv1beta1.ProtocolDirect,
 		}
-	} else {
-		clusterConn = apiserverv1beta1.Connection{
-			ProxyProtocol: apiserverv1beta1.ProtocolHTTPConnect,
-			Transport: &apiserverv1beta1.Transport{
-				TCP: &apiserverv1beta1.TCPTransport{
-					URL: fmt.Sprintf("https://%s:%d", controlConfig.BindAddressOrLoopback(false, true), controlConfig.SupervisorPort),
-					TLSConfig: &apiserverv1beta1.TLSConfig{
-						CABundle:   controlConfig.Runtime.ServerCA,
-						ClientKey:  controlConfig.Runtime.ClientKubeAPIKey,
-						ClientCert: controlConfig.Runtime.ClientKubeAPICert,
+		if controlConfig.EgressSelectorModeForType(egressType) != config.EgressSelectorModeDisabled {
+			conn = apiserverv1beta1.Connection{
+				ProxyProtocol: apiserverv1beta1.ProtocolHTTPConnect,
+				Transport: &apiserverv1beta1.Transport{
+					TCP: &apiserverv1beta1.TCPTransport{
+						URL: fmt.Sprintf("https://%s:%d", controlConfig.BindAddressOrLoopback(false, true), controlConfig.SupervisorPort),
+						TLSConfig: &apiserverv1beta1.TLSConfig{
+							CABundle:   controlConfig.Runtime.ServerCA,
+							ClientKey:  controlConfig.Runtime.ClientKubeAPIKey,
+							ClientCert: controlConfig.Runtime.ClientKubeAPICert,
+						},
 					},
 				},
-			},
+			}
 		}
-	}
-
-	egressConfig := apiserverv1beta1.EgressSelectorConfiguration{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "EgressSelectorConfiguration",
-			APIVersion: "apiserver.k8s.io/v1beta1",
-		},
-		EgressSelections: []apiserverv1beta1.EgressSelection{
-			{
-				Name:       "cluster",
-				Connection: clusterConn,
-			},
-		},
+		egressConfig.EgressSelections = append(egressConfig.EgressSelections, apiserverv1beta1.EgressSelection{
+			Name:       egressType,
+			Connection: conn,
+		})
 	}
 
 	b, err := json.Marshal(egressConfig)
@@ -832,13 +904,10 @@ func genCloudConfig(controlConfig *config.Control) error {
 		LBDefaultPriorityClassName: cloudprovider.DefaultLBPriorityClassName,
 		LBEnabled:                  !controlConfig.DisableServiceLB,
 		LBNamespace:                controlConfig.ServiceLBNamespace,
-		LBImage:                    cloudprovider.DefaultLBImage,
+		LBImage:                    images.Reference(images.ServiceLB, controlConfig.SystemDefaultRegistry, controlConfig.SystemImages),
 		Rootless:                   controlConfig.Rootless,
 		NodeEnabled:                !controlConfig.DisableCCM,
 	}
-	if controlConfig.SystemDefaultRegistry != "" {
-		cloudConfig.LBImage = controlConfig.SystemDefaultRegistry + "/" + cloudConfig.LBImage
-	}
 	b, err := json.Marshal(cloudConfig)
 	if err != nil {
 		return err
@@ -1,10 +1,14 @@
 package deps
 
 import (
+	"crypto"
+	"crypto/x509"
 	"net"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/k3s-io/k3s/pkg/daemons/config"
 	certutil "github.com/rancher/dynamiclistener/cert"
 )
 
@@ -49,3 +53,35 @@ func Test_UnitAddSANs(t *testing.T) {
 		})
 	}
 }
+
+func Test_UnitNewSelfSignedCACert(t *testing.T) {
+	caKey, err := certutil.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Truncate(time.Second)
+	caCert, err := newSelfSignedCACert(certutil.Config{CommonName: "test-ca"}, caKey, now, config.DefaultCACertDuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := now.Add(-caBackdate); !caCert.NotBefore.Equal(want) {
+		t.Errorf("CA NotBefore = %s, want %s", caCert.NotBefore, want)
+	}
+	if want := now.Add(config.DefaultCACertDuration); !caCert.NotAfter.Equal(want) {
+		t.Errorf("CA NotAfter = %s, want %s", caCert.NotAfter, want)
+	}
+
+	// Leaf certificates are valid from the NotBefore time of the CA, so they are accepted by a
+	// client whose clock is behind the server's.
+	key, err := certutil.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := certutil.NewSignedCert(certutil.Config{CommonName: "test", Usages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, crypto.Signer(key), caCert, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lagging := now.Add(-time.Hour); lagging.Before(cert.NotBefore) {
+		t.Errorf("leaf NotBefore = %s, not valid at %s", cert.NotBefore, lagging)
+	}
+}
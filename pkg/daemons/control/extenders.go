@@ -0,0 +1,75 @@
+package control
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+)
+
+// SchedulerExtendersFile is the name of the file in the extensions directory that lists the
+// scheduler extenders, using the same schema as the extenders field of the kube-scheduler config.
+const SchedulerExtendersFile = "scheduler-extenders.yaml"
+
+// writeSchedulerConfig writes a kube-scheduler config file that registers the scheduler extenders
+// listed in the extensions directory, and returns its path. If no extenders are listed, an empty
+// path is returned. Extenders that use HTTPS without a client certificate are configured to
+// authenticate with the kube-scheduler's client certificate, so that they can verify requests
+// using the cluster client CA.
+func writeSchedulerConfig(cfg *config.Control, profiling bool) (string, error) {
+	b, err := os.ReadFile(filepath.Join(cfg.DataDir, config.ExtensionsDir, SchedulerExtendersFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	extenders := []map[string]any{}
+	if err := yaml.Unmarshal(b, &extenders); err != nil {
+		return "", errors.Wrapf(err, "failed to parse %s", SchedulerExtendersFile)
+	}
+	if len(extenders) == 0 {
+		return "", nil
+	}
+	for _, extender := range extenders {
+		if https, _ := extender["enableHTTPS"].(bool); !https {
+			continue
+		}
+		tlsConfig, _ := extender["tlsConfig"].(map[string]any)
+		if tlsConfig == nil {
+			tlsConfig = map[string]any{}
+			extender["tlsConfig"] = tlsConfig
+		}
+		if tlsConfig["certFile"] == nil && tlsConfig["certData"] == nil {
+			tlsConfig["certFile"] = cfg.Runtime.ClientSchedulerCert
+			tlsConfig["keyFile"] = cfg.Runtime.ClientSchedulerKey
+		}
+	}
+
+	schedulerConfig := map[string]any{
+		"apiVersion":      "kubescheduler.config.k8s.io/v1",
+		"kind":            "KubeSchedulerConfiguration",
+		"enableProfiling": profiling,
+		"clientConnection": map[string]any{
+			"kubeconfig": cfg.Runtime.KubeConfigScheduler,
+		},
+		"extenders": extenders,
+	}
+	b, err = yaml.Marshal(schedulerConfig)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(cfg.DataDir, "etc", "scheduler-config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := util.AtomicWrite(path, b, 0600); err != nil {
+		return "", err
+	}
+	logrus.Infof("Registered %d scheduler extenders from %s", len(extenders), SchedulerExtendersFile)
+	return path, nil
+}
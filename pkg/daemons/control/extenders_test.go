@@ -0,0 +1,78 @@
+package control
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"sigs.k8s.io/yaml"
+)
+
+func Test_UnitWriteSchedulerConfig(t *testing.T) {
+	cfg := &config.Control{DataDir: t.TempDir(), Runtime: &config.ControlRuntime{}}
+	cfg.Runtime.KubeConfigScheduler = "/tmp/scheduler.kubeconfig"
+	cfg.Runtime.ClientSchedulerCert = "/tmp/client-scheduler.crt"
+	cfg.Runtime.ClientSchedulerKey = "/tmp/client-scheduler.key"
+
+	path, err := writeSchedulerConfig(cfg, false)
+	if err != nil || path != "" {
+		t.Fatalf("expected no config without extenders, got %q, %v", path, err)
+	}
+
+	extenders := `
+- urlPrefix: https://127.0.0.1:8888
+  filterVerb: filter
+  enableHTTPS: true
+- urlPrefix: https://127.0.0.1:8889
+  enableHTTPS: true
+  tlsConfig:
+    certData: Y2VydA==
+    keyData: a2V5
+- urlPrefix: http://127.0.0.1:8890
+`
+	if err := os.MkdirAll(filepath.Join(cfg.DataDir, config.ExtensionsDir), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.DataDir, config.ExtensionsDir, SchedulerExtendersFile), []byte(extenders), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path, err = writeSchedulerConfig(cfg, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schedulerConfig := struct {
+		ClientConnection struct {
+			Kubeconfig string `json:"kubeconfig"`
+		} `json:"clientConnection"`
+		Extenders []struct {
+			TLSConfig *struct {
+				CertFile string `json:"certFile"`
+				KeyFile  string `json:"keyFile"`
+				CertData string `json:"certData"`
+			} `json:"tlsConfig"`
+		} `json:"extenders"`
+	}{}
+	if err := yaml.Unmarshal(b, &schedulerConfig); err != nil {
+		t.Fatal(err)
+	}
+	if schedulerConfig.ClientConnection.Kubeconfig != cfg.Runtime.KubeConfigScheduler {
+		t.Errorf("unexpected kubeconfig %q", schedulerConfig.ClientConnection.Kubeconfig)
+	}
+	if n := len(schedulerConfig.Extenders); n != 3 {
+		t.Fatalf("expected 3 extenders, got %d", n)
+	}
+	if tls := schedulerConfig.Extenders[0].TLSConfig; tls == nil || tls.CertFile != cfg.Runtime.ClientSchedulerCert || tls.KeyFile != cfg.Runtime.ClientSchedulerKey {
+		t.Errorf("expected HTTPS extender to default to the scheduler client certificate, got %+v", tls)
+	}
+	if tls := schedulerConfig.Extenders[1].TLSConfig; tls == nil || tls.CertFile != "" || tls.CertData == "" {
+		t.Errorf("expected HTTPS extender client certificate to be preserved, got %+v", tls)
+	}
+	if tls := schedulerConfig.Extenders[2].TLSConfig; tls != nil {
+		t.Errorf("expected HTTP extender to have no TLS config, got %+v", tls)
+	}
+}
@@ -11,9 +11,12 @@ import (
 
 	"github.com/k3s-io/k3s/pkg/authenticator"
 	"github.com/k3s-io/k3s/pkg/cluster"
+	"github.com/k3s-io/k3s/pkg/clustercidr"
+	"github.com/k3s-io/k3s/pkg/clusterdomain"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/daemons/control/deps"
 	"github.com/k3s-io/k3s/pkg/daemons/executor"
+	"github.com/k3s-io/k3s/pkg/servicerange"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/pkg/errors"
@@ -30,11 +33,19 @@ import (
 	logsapi "k8s.io/component-base/logs/api/v1"
 	"k8s.io/kubernetes/pkg/kubeapiserver/authorizer/modes"
 	"k8s.io/kubernetes/pkg/registry/core/node"
+	utilsnet "k8s.io/utils/net"
 
 	// for client metric registration
 	_ "k8s.io/component-base/metrics/prometheus/restclient"
 )
 
+const (
+	// draFeatureGate and draRuntimeConfig enable the Dynamic Resource Allocation APIs, which are
+	// beta and disabled by default in this Kubernetes release.
+	draFeatureGate   = "DynamicResourceAllocation=true"
+	draRuntimeConfig = "resource.k8s.io/v1beta1=true"
+)
+
 func Server(ctx context.Context, cfg *config.Control) error {
 	rand.Seed(time.Now().UTC().UnixNano())
 
@@ -62,7 +73,13 @@ func Server(ctx context.Context, cfg *config.Control) error {
 	cfg.Runtime.Authenticator = auth
 
 	if !cfg.DisableAPIServer {
-		go waitForAPIServerHandlers(ctx, cfg.Runtime)
+		if cfg.ControlPlaneExecMode == config.ControlPlaneExecModeProcesses {
+			if err := setupAPIServerProxy(cfg); err != nil {
+				return err
+			}
+		} else {
+			go waitForAPIServerHandlers(ctx, cfg.Runtime)
+		}
 
 		if err := apiServer(ctx, cfg); err != nil {
 			return err
@@ -123,10 +140,39 @@ func controllerManager(ctx context.Context, cfg *config.Control) error {
 	if cfg.NoLeaderElect {
 		argsMap["leader-elect"] = "false"
 	}
+	// The controller-manager rejects mask sizes for IP families that are not in the cluster CIDRs.
+	for _, cidr := range cfg.ClusterIPRanges {
+		if utilsnet.IsIPv6CIDR(cidr) {
+			if cfg.NodeCIDRMaskSizeIPv6 != 0 {
+				argsMap["node-cidr-mask-size-ipv6"] = strconv.Itoa(cfg.NodeCIDRMaskSizeIPv6)
+			}
+		} else if cfg.NodeCIDRMaskSizeIPv4 != 0 {
+			argsMap["node-cidr-mask-size-ipv4"] = strconv.Itoa(cfg.NodeCIDRMaskSizeIPv4)
+		}
+	}
+	// The controller-manager signs client and serving CSRs with a single duration, so use the
+	// shorter of the two if either has been set.
+	if cfg.ServerCertDuration > 0 || cfg.ClientCertDuration > 0 {
+		serverDuration, clientDuration := cfg.ServerCertDuration, cfg.ClientCertDuration
+		if serverDuration == 0 {
+			serverDuration = config.DefaultLeafCertDuration
+		}
+		if clientDuration == 0 {
+			clientDuration = config.DefaultLeafCertDuration
+		}
+		argsMap["cluster-signing-duration"] = min(serverDuration, clientDuration).String()
+	}
 	if !cfg.DisableCCM {
 		argsMap["configure-cloud-routes"] = "false"
 		argsMap["controllers"] = argsMap["controllers"] + ",-service,-route,-cloud-node-lifecycle"
 	}
+	if cfg.DRA {
+		argsMap["feature-gates"] = util.AddFeatureGate(argsMap["feature-gates"], draFeatureGate)
+	}
+	// Components run embedded are profiled along with the supervisor
+	if cfg.EnablePProf && cfg.ControlPlaneExecMode == config.ControlPlaneExecModeProcesses {
+		argsMap["profiling"] = "true"
+	}
 
 	if cfg.VLevel != 0 {
 		argsMap["v"] = strconv.Itoa(cfg.VLevel)
@@ -135,9 +181,13 @@ func controllerManager(ctx context.Context, cfg *config.Control) error {
 		argsMap["vmodule"] = cfg.VModule
 	}
 
+	config.AddProfileArgs(argsMap, config.ResourceProfiles[cfg.ResourceProfile].ControllerManagerArgs)
 	args := config.GetArgs(argsMap, cfg.ExtraControllerArgs)
 	logrus.Infof("Running kube-controller-manager %s", config.ArgString(args))
 
+	if cfg.ControlPlaneExecMode == config.ControlPlaneExecModeProcesses {
+		return executor.Process(ctx, "kube-controller-manager", cfg.Runtime.APIServerReady, args, cfg.ControlPlaneLimits["kube-controller-manager"])
+	}
 	return executor.ControllerManager(ctx, cfg.Runtime.APIServerReady, args)
 }
 
@@ -154,6 +204,13 @@ func scheduler(ctx context.Context, cfg *config.Control) error {
 	if cfg.NoLeaderElect {
 		argsMap["leader-elect"] = "false"
 	}
+	if cfg.DRA {
+		argsMap["feature-gates"] = util.AddFeatureGate(argsMap["feature-gates"], draFeatureGate)
+	}
+	// Components run embedded are profiled along with the supervisor
+	if cfg.EnablePProf && cfg.ControlPlaneExecMode == config.ControlPlaneExecModeProcesses {
+		argsMap["profiling"] = "true"
+	}
 
 	if cfg.VLevel != 0 {
 		argsMap["v"] = strconv.Itoa(cfg.VLevel)
@@ -162,6 +219,14 @@ func scheduler(ctx context.Context, cfg *config.Control) error {
 		argsMap["vmodule"] = cfg.VModule
 	}
 
+	schedulerConfig, err := writeSchedulerConfig(cfg, argsMap["profiling"] == "true")
+	if err != nil {
+		return errors.Wrap(err, "failed to configure scheduler extenders")
+	}
+	if schedulerConfig != "" {
+		argsMap["config"] = schedulerConfig
+	}
+
 	args := config.GetArgs(argsMap, cfg.ExtraSchedulerAPIArgs)
 
 	schedulerNodeReady := make(chan struct{})
@@ -193,6 +258,9 @@ func scheduler(ctx context.Context, cfg *config.Control) error {
 	}()
 
 	logrus.Infof("Running kube-scheduler %s", config.ArgString(args))
+	if cfg.ControlPlaneExecMode == config.ControlPlaneExecModeProcesses {
+		return executor.Process(ctx, "kube-scheduler", schedulerNodeReady, args, cfg.ControlPlaneLimits["kube-scheduler"])
+	}
 	return executor.Scheduler(ctx, schedulerNodeReady, args)
 }
 
@@ -222,19 +290,33 @@ func apiServer(ctx context.Context, cfg *config.Control) error {
 	} else {
 		argsMap["bind-address"] = cfg.APIServerBindAddress
 	}
-	if cfg.EgressSelectorMode != config.EgressSelectorModeDisabled {
-		argsMap["enable-aggregator-routing"] = "true"
+	if cfg.EgressSelectorProxied() {
 		argsMap["egress-selector-config-file"] = runtime.EgressSelectorConfig
 	}
+	if cfg.EgressSelectorModeForType(config.EgressSelectorTypeWebhook) != config.EgressSelectorModeDisabled {
+		argsMap["enable-aggregator-routing"] = "true"
+	}
 	argsMap["tls-cert-file"] = runtime.ServingKubeAPICert
 	argsMap["tls-private-key-file"] = runtime.ServingKubeAPIKey
 	argsMap["service-account-key-file"] = runtime.ServiceKey
 	argsMap["service-account-issuer"] = "https://kubernetes.default.svc." + cfg.ClusterDomain
 	argsMap["api-audiences"] = "https://kubernetes.default.svc." + cfg.ClusterDomain + "," + version.Program
+	// Tokens issued for previous cluster domains are accepted until the domain migration is finalized.
+	// The first issuer is used to sign new tokens.
+	var issuerArgs []string
+	for _, domain := range cfg.PreviousClusterDomains {
+		issuerArgs = append(issuerArgs, "service-account-issuer+=https://kubernetes.default.svc."+domain)
+		argsMap["api-audiences"] += ",https://kubernetes.default.svc." + domain
+	}
 	argsMap["kubelet-certificate-authority"] = runtime.ServerCA
 	argsMap["kubelet-client-certificate"] = runtime.ClientKubeAPICert
 	argsMap["kubelet-client-key"] = runtime.ClientKubeAPIKey
-	if cfg.FlannelExternalIP {
+	if cfg.FlannelExternalIP || (!cfg.DisableCCM && cfg.EgressSelectorModeForType(config.EgressSelectorTypeKubelet) != config.EgressSelectorModeDisabled) {
+		// When connections to the kubelet are tunneled, any node address reaches the right node as long as it is
+		// unique. With the embedded cloud controller, node external IPs are only those advertised by the node itself,
+		// set with node-external-ip or discovered with stun-server, which are valid for its kubelet serving cert. They
+		// are preferred, as nodes in different private networks behind NAT may share internal IPs; nodes that do not
+		// advertise an external IP are still reached at their internal IP.
 		argsMap["kubelet-preferred-address-types"] = "ExternalIP,InternalIP,Hostname"
 	} else {
 		argsMap["kubelet-preferred-address-types"] = "InternalIP,ExternalIP,Hostname"
@@ -250,6 +332,14 @@ func apiServer(ctx context.Context, cfg *config.Control) error {
 	argsMap["enable-admission-plugins"] = "NodeRestriction"
 	argsMap["anonymous-auth"] = "false"
 	argsMap["profiling"] = "false"
+	// Components run embedded are profiled along with the supervisor
+	if cfg.EnablePProf && cfg.ControlPlaneExecMode == config.ControlPlaneExecModeProcesses {
+		argsMap["profiling"] = "true"
+	}
+	if cfg.DRA {
+		argsMap["feature-gates"] = util.AddFeatureGate(argsMap["feature-gates"], draFeatureGate)
+		argsMap["runtime-config"] = draRuntimeConfig
+	}
 	if cfg.EncryptSecrets {
 		argsMap["encryption-provider-config"] = runtime.EncryptionConfig
 		argsMap["encryption-provider-config-automatic-reload"] = "true"
@@ -261,10 +351,14 @@ func apiServer(ctx context.Context, cfg *config.Control) error {
 		argsMap["vmodule"] = cfg.VModule
 	}
 
-	args := config.GetArgs(argsMap, cfg.ExtraAPIArgs)
+	config.AddProfileArgs(argsMap, config.ResourceProfiles[cfg.ResourceProfile].APIServerArgs)
+	args := config.GetArgs(argsMap, append(issuerArgs, cfg.ExtraAPIArgs...))
 
 	logrus.Infof("Running kube-apiserver %s", config.ArgString(args))
 
+	if cfg.ControlPlaneExecMode == config.ControlPlaneExecModeProcesses {
+		return executor.Process(ctx, "kube-apiserver", runtime.ETCDReady, args, cfg.ControlPlaneLimits["kube-apiserver"])
+	}
 	return executor.APIServer(ctx, runtime.ETCDReady, args)
 }
 
@@ -304,6 +398,27 @@ func prepare(ctx context.Context, config *config.Control) error {
 	os.MkdirAll(filepath.Join(config.DataDir, "tls"), 0700)
 	os.MkdirAll(filepath.Join(config.DataDir, "cred"), 0700)
 
+	if !config.DisableAPIServer {
+		state := servicerange.NewState(config.ServiceIPRanges, config.ServiceNodePortRange)
+		if err := servicerange.Check(config.DataDir, state, config.AllowServiceRangeChange); err != nil {
+			return err
+		}
+	}
+
+	if err := clustercidr.Check(config.DataDir, clustercidr.NewState(config.ClusterIPRanges, config.ServiceIPRanges)); err != nil {
+		return err
+	}
+
+	domainState, err := clusterdomain.Update(config.DataDir, config.ClusterDomain)
+	if err != nil {
+		return err
+	}
+	config.PreviousClusterDomains = domainState.Previous
+	if len(config.PreviousClusterDomains) > 0 {
+		logrus.Warnf("Cluster domain changed from %s to %s; the previous domains will continue to be served until the migration is finalized with '%s cluster migrate-domain --finalize'",
+			strings.Join(config.PreviousClusterDomains, ","), config.ClusterDomain, version.Program)
+	}
+
 	deps.CreateRuntimeCertFiles(config)
 
 	cluster := cluster.New(config)
@@ -6,12 +6,15 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/daemons/control/proxy"
+	"github.com/k3s-io/k3s/pkg/metrics"
 	"github.com/k3s-io/k3s/pkg/nodeconfig"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
@@ -19,12 +22,17 @@ import (
 	"github.com/rancher/remotedialer"
 	"github.com/sirupsen/logrus"
 	"github.com/yl2chen/cidranger"
+	"golang.org/x/time/rate"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/client-go/kubernetes"
 )
 
-var defaultDialer = net.Dialer{}
+var (
+	defaultDialer = net.Dialer{}
+
+	registerMetrics sync.Once
+)
 
 func loggingErrorWriter(rw http.ResponseWriter, req *http.Request, code int, err error) {
 	logrus.Debugf("Tunnel server error: %d %v", code, err)
@@ -32,11 +40,17 @@ func loggingErrorWriter(rw http.ResponseWriter, req *http.Request, code int, err
 }
 
 func setupTunnel(ctx context.Context, cfg *config.Control) (http.Handler, error) {
+	registerMetrics.Do(func() {
+		metrics.DefaultRegisterer.MustRegister(tunnelSessions, tunnelBytes)
+	})
 	tunnel := &TunnelServer{
-		cidrs:  cidranger.NewPCTrieRanger(),
-		config: cfg,
-		server: remotedialer.New(authorizer, loggingErrorWriter),
-		egress: map[string]bool{},
+		cidrs:     cidranger.NewPCTrieRanger(),
+		config:    cfg,
+		server:    remotedialer.New(authorizer, loggingErrorWriter),
+		egress:    map[string]bool{},
+		conflicts: map[string]bool{},
+		sessions:  map[*tunnelSession]struct{}{},
+		limiters:  map[string]*rate.Limiter{},
 	}
 	cfg.Runtime.ClusterControllerStarts["tunnel-server"] = tunnel.watch
 	return tunnel, nil
@@ -65,6 +79,18 @@ type TunnelServer struct {
 	config *config.Control
 	server *remotedialer.Server
 	egress map[string]bool
+	// conflicts holds node IPs that have been found on more than one node, so that each is only logged once
+	conflicts map[string]bool
+
+	sessionsMu sync.Mutex
+	sessions   map[*tunnelSession]struct{}
+
+	limitersMu sync.Mutex
+	limiters   map[string]*rate.Limiter
+
+	tunneled atomic.Int64
+	direct   atomic.Int64
+	failed   atomic.Int64
 }
 
 // explicit interface check
@@ -73,6 +99,7 @@ var _ cidranger.RangerEntry = &tunnelEntry{}
 type tunnelEntry struct {
 	kubeletPort string
 	nodeName    string
+	namespace   string
 	cidr        net.IPNet
 }
 
@@ -92,7 +119,7 @@ func (t *TunnelServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 	if req.Method == http.MethodConnect {
 		t.serveConnect(resp, req)
 	} else {
-		t.server.ServeHTTP(resp, req)
+		t.serveSession(resp, req)
 	}
 }
 
@@ -100,6 +127,9 @@ func (t *TunnelServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 // and registers OnChange handlers to observe changes to Nodes (and Endpoints if necessary).
 func (t *TunnelServer) watch(ctx context.Context) {
 	logrus.Infof("Tunnel server egress proxy mode: %s", t.config.EgressSelectorMode)
+	for _, egressType := range slices.Concat(config.EgressSelectorTypes, config.EgressSelectorClusterTypes) {
+		logrus.Infof("Tunnel server egress proxy mode for %s egress: %s", egressType, t.config.EgressSelectorModeForType(egressType))
+	}
 
 	if t.config.EgressSelectorMode == config.EgressSelectorModeDisabled {
 		return
@@ -127,6 +157,11 @@ func (t *TunnelServer) onChangeNode(nodeName string, node *v1.Node) (*v1.Node, e
 						t.cidrs.Remove(*n)
 					} else {
 						logrus.Debugf("Tunnel server egress proxy updating Node %s IP %v", nodeName, n)
+						if owner := t.nodeForIP(n.IP); owner != "" && owner != nodeName && !t.conflicts[n.IP.String()] {
+							t.conflicts[n.IP.String()] = true
+							logrus.Warnf("Tunnel server egress proxy found %s IP %s on both Node %s and Node %s; set node-external-ip or stun-server on nodes behind NAT so that they can be reached by a unique address",
+								addr.Type, n.IP, owner, nodeName)
+						}
 						kubeletPort := strconv.FormatInt(int64(node.Status.DaemonEndpoints.KubeletEndpoint.Port), 10)
 						t.cidrs.Insert(&tunnelEntry{cidr: *n, nodeName: nodeName, kubeletPort: kubeletPort})
 					}
@@ -137,6 +172,18 @@ func (t *TunnelServer) onChangeNode(nodeName string, node *v1.Node) (*v1.Node, e
 	return node, nil
 }
 
+// nodeForIP returns the name of the node that the IP address is currently mapped to, if any.
+func (t *TunnelServer) nodeForIP(ip net.IP) string {
+	if nets, err := t.cidrs.ContainingNetworks(ip); err == nil {
+		for _, n := range nets {
+			if e, ok := n.(*tunnelEntry); ok && e.kubeletPort != "" && e.cidr.IP.Equal(ip) {
+				return e.nodeName
+			}
+		}
+	}
+	return ""
+}
+
 // onChangePod updates the pod address mappings by observing changes to pods.
 func (t *TunnelServer) onChangePod(podName string, pod *v1.Pod) (*v1.Pod, error) {
 	if pod != nil {
@@ -152,7 +199,7 @@ func (t *TunnelServer) onChangePod(podName string, pod *v1.Pod) (*v1.Pod, error)
 						t.cidrs.Remove(*cidr)
 					} else {
 						logrus.Debugf("Tunnel server egress proxy updating Node %s Pod IP %s", nodeName, cidr)
-						t.cidrs.Insert(&tunnelEntry{cidr: *cidr, nodeName: nodeName})
+						t.cidrs.Insert(&tunnelEntry{cidr: *cidr, nodeName: nodeName, namespace: pod.Namespace})
 					}
 				}
 			}
@@ -166,6 +213,7 @@ func (t *TunnelServer) onChangePod(podName string, pod *v1.Pod) (*v1.Pod, error)
 func (t *TunnelServer) serveConnect(resp http.ResponseWriter, req *http.Request) {
 	bconn, err := t.dialBackend(req.Context(), req.Host)
 	if err != nil {
+		t.failed.Add(1)
 		util.SendError(err, resp, req, http.StatusBadGateway)
 		return
 	}
@@ -197,7 +245,7 @@ func (t *TunnelServer) dialBackend(ctx context.Context, addr string) (net.Conn,
 		return nil, err
 	}
 
-	var nodeName string
+	var nodeName, namespace string
 	var toKubelet, useTunnel bool
 	if ip := net.ParseIP(host); ip != nil {
 		// Destination is an IP address, which could be either a pod, or node by IP.
@@ -205,6 +253,7 @@ func (t *TunnelServer) dialBackend(ctx context.Context, addr string) (net.Conn,
 		if nets, err := t.cidrs.ContainingNetworks(ip); err == nil && len(nets) > 0 {
 			if n, ok := nets[0].(*tunnelEntry); ok {
 				nodeName = n.nodeName
+				namespace = n.namespace
 				if n.IsReservedPort(port) {
 					toKubelet = true
 					useTunnel = true
@@ -222,6 +271,16 @@ func (t *TunnelServer) dialBackend(ctx context.Context, addr string) (net.Conn,
 		useTunnel = true
 	}
 
+	// Connections to kubelets, and to webhooks and aggregated apiservers, may be configured to be dialed directly
+	// rather than through the tunnel.
+	clusterType := config.EgressSelectorTypeWebhook
+	if toKubelet {
+		clusterType = config.EgressSelectorTypeKubelet
+	}
+	if t.config.EgressSelectorModeForType(clusterType) == config.EgressSelectorModeDisabled {
+		useTunnel = false
+	}
+
 	// If connecting to something hosted by the local node, don't tunnel
 	if nodeName == t.config.ServerNodeName {
 		useTunnel = false
@@ -229,7 +288,7 @@ func (t *TunnelServer) dialBackend(ctx context.Context, addr string) (net.Conn,
 			// Dial local kubelet at the configured bind address
 			addr = net.JoinHostPort(t.config.BindAddress, port)
 		}
-	} else if toKubelet {
+	} else if toKubelet && useTunnel {
 		// Dial remote kubelet via the loopback address, the remotedialer client
 		// will ensure that it hits the right local address.
 		addr = net.JoinHostPort(t.config.Loopback(false), port)
@@ -249,14 +308,19 @@ func (t *TunnelServer) dialBackend(ctx context.Context, addr string) (net.Conn,
 		} else {
 			// Have a session and it is safe to use for this destination, do so.
 			logrus.Debugf("Tunnel server egress proxy dialing %s via Session to %s", addr, nodeName)
-			return conn, err
+			t.tunneled.Add(1)
+			return t.limitConn(ctx, conn, nodeName, namespace), err
 		}
 	}
 
 	// Don't have a session, the agent doesn't support tunneling to this destination, or
 	// the destination is local; fall back to direct connection.
 	logrus.Debugf("Tunnel server egress proxy dialing %s directly", addr)
-	return defaultDialer.DialContext(ctx, "tcp", addr)
+	conn, err := defaultDialer.DialContext(ctx, "tcp", addr)
+	if err == nil {
+		t.direct.Add(1)
+	}
+	return conn, err
 }
 
 // connReadWriteCloser bundles a net.Conn and a wrapping bufio.ReadWriter together into a type that
@@ -0,0 +1,128 @@
+package control
+
+import (
+	"context"
+	"net"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// minLimiterBurst is the smallest burst allowed by a tunnel bandwidth limiter. Limiters allow
+// bursts of one second of traffic, but no less than this, so that low limits do not require
+// reads and writes to be split into tiny chunks.
+const minLimiterBurst = 32 * 1024
+
+// limitConn wraps a tunneled connection so that traffic in both directions is limited by the bandwidth
+// limits for the destination node and namespace. Limiters are shared by all connections to the same
+// node or namespace, so that bulk transfers are limited in aggregate. Traffic on the tunnel session
+// itself, including keepalives, is not limited. Waits for the limiters are cancelled when the context
+// is done or the connection is closed.
+func (t *TunnelServer) limitConn(ctx context.Context, conn net.Conn, nodeName, namespace string) net.Conn {
+	var limiters []*rate.Limiter
+	if limiter := t.getLimiter("node/"+nodeName, t.config.TunnelBandwidthLimits.NodeLimit(nodeName)); limiter != nil {
+		limiters = append(limiters, limiter)
+	}
+	if limiter := t.getLimiter("namespace/"+namespace, t.config.TunnelBandwidthLimits.NamespaceLimit(namespace)); limiter != nil {
+		limiters = append(limiters, limiter)
+	}
+	if len(limiters) == 0 {
+		return conn
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &rateLimitedConn{Conn: conn, ctx: ctx, cancel: cancel, limiters: limiters}
+}
+
+// getLimiter returns the limiter for a node or namespace, creating it if necessary.
+// Nil is returned if there is no limit.
+func (t *TunnelServer) getLimiter(key string, limit *resource.Quantity) *rate.Limiter {
+	if limit == nil {
+		return nil
+	}
+	t.limitersMu.Lock()
+	defer t.limitersMu.Unlock()
+	if limiter, ok := t.limiters[key]; ok {
+		return limiter
+	}
+	bytesPerSecond := limit.Value()
+	burst := int(max(bytesPerSecond, minLimiterBurst))
+	logrus.Infof("Tunnel server limiting %s to %s bytes per second", key, limit)
+	limiter := rate.NewLimiter(rate.Limit(bytesPerSecond), burst)
+	t.limiters[key] = limiter
+	return limiter
+}
+
+// removeNodeLimiter removes the limiter for a node once its agent has disconnected. Connections that are
+// still open keep using the removed limiter; a new one is created when the agent reconnects.
+func (t *TunnelServer) removeNodeLimiter(nodeName string) {
+	t.limitersMu.Lock()
+	defer t.limitersMu.Unlock()
+	delete(t.limiters, "node/"+nodeName)
+}
+
+// rateLimitedConn is a net.Conn that waits for all of its limiters to allow traffic before completing
+// reads and writes. Reads and writes are split so that no single wait exceeds the burst of any limiter.
+type rateLimitedConn struct {
+	net.Conn
+	ctx      context.Context
+	cancel   context.CancelFunc
+	limiters []*rate.Limiter
+}
+
+var _ net.Conn = &rateLimitedConn{}
+
+func (c *rateLimitedConn) Read(b []byte) (int, error) {
+	if chunk := c.chunkSize(); len(b) > chunk {
+		b = b[:chunk]
+	}
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		if werr := c.wait(n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+func (c *rateLimitedConn) Write(b []byte) (int, error) {
+	var written int
+	chunk := c.chunkSize()
+	for len(b) > 0 {
+		n := min(len(b), chunk)
+		if err := c.wait(n); err != nil {
+			return written, err
+		}
+		n, err := c.Conn.Write(b[:n])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// Close closes the connection, and cancels any reads or writes that are waiting for the limiters.
+func (c *rateLimitedConn) Close() error {
+	c.cancel()
+	return c.Conn.Close()
+}
+
+// chunkSize returns the smallest burst of all limiters.
+func (c *rateLimitedConn) chunkSize() int {
+	chunk := c.limiters[0].Burst()
+	for _, limiter := range c.limiters[1:] {
+		chunk = min(chunk, limiter.Burst())
+	}
+	return chunk
+}
+
+func (c *rateLimitedConn) wait(n int) error {
+	for _, limiter := range c.limiters {
+		if err := limiter.WaitN(c.ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"golang.org/x/time/rate"
+)
+
+func Test_UnitTunnelRateLimit(t *testing.T) {
+	limits, err := config.ParseTunnelBandwidthLimits([]string{"node/agent-1=1Mi", "namespace/bulk=16Ki"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tunnel := &TunnelServer{
+		config:   &config.Control{TunnelBandwidthLimits: limits},
+		limiters: map[string]*rate.Limiter{},
+	}
+
+	ctx := context.Background()
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	if conn := tunnel.limitConn(ctx, server, "agent-2", "default"); conn != server {
+		t.Errorf("expected connection to unlimited node and namespace to be unwrapped")
+	}
+	if conn, ok := tunnel.limitConn(ctx, server, "agent-2", "bulk").(*rateLimitedConn); !ok || len(conn.limiters) != 1 || conn.chunkSize() != minLimiterBurst {
+		t.Errorf("expected connection to limited namespace to have a single limiter with the minimum burst")
+	}
+	conn, ok := tunnel.limitConn(ctx, server, "agent-1", "bulk").(*rateLimitedConn)
+	if !ok || len(conn.limiters) != 2 {
+		t.Fatalf("expected connection to limited node and namespace to have two limiters")
+	}
+	if conn.limiters[1] != tunnel.limiters["namespace/bulk"] || len(tunnel.limiters) != 2 {
+		t.Errorf("expected limiters to be shared between connections")
+	}
+
+	// Writes to the node are split into chunks no larger than the smallest burst, and
+	// are held to the node limit once the burst is used up.
+	conn, _ = tunnel.limitConn(ctx, server, "agent-1", "").(*rateLimitedConn)
+	go io.Copy(io.Discard, client)
+	start := time.Now()
+	n, err := conn.Write(make([]byte, 2*1024*1024))
+	if err != nil || n != 2*1024*1024 {
+		t.Fatalf("unexpected write result %d: %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("expected write to be rate limited, completed in %v", elapsed)
+	}
+}
+
+func Test_UnitTunnelRateLimitClose(t *testing.T) {
+	limits, err := config.ParseTunnelBandwidthLimits([]string{"node=32Ki"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tunnel := &TunnelServer{
+		config:   &config.Control{TunnelBandwidthLimits: limits},
+		limiters: map[string]*rate.Limiter{},
+	}
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go io.Copy(io.Discard, client)
+
+	// A write that has to wait for the limiter returns as soon as the connection is closed.
+	conn := tunnel.limitConn(context.Background(), server, "agent-1", "")
+	result := make(chan error, 1)
+	go func() {
+		_, err := conn.Write(make([]byte, 1024*1024))
+		result <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	conn.Close()
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected write to be cancelled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected write to return when the connection is closed")
+	}
+
+	// The limiter for a node is removed when its agent disconnects.
+	tunnel.removeNodeLimiter("agent-1")
+	if len(tunnel.limiters) != 0 {
+		t.Errorf("expected node limiter to be removed, got %v", tunnel.limiters)
+	}
+}
@@ -0,0 +1,198 @@
+package control
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	tunnelSessions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: version.Program + "_tunnel_server_sessions",
+		Help: "Count of current agent websocket tunnel sessions",
+	}, []string{"node"})
+
+	tunnelBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: version.Program + "_tunnel_server_bytes_total",
+		Help: "Total bytes transferred over agent websocket tunnel sessions",
+	}, []string{"node", "direction"})
+)
+
+// explicit interface checks
+var (
+	_ config.TunnelSessionLister  = &TunnelServer{}
+	_ config.EgressStatusReporter = &TunnelServer{}
+)
+
+// tunnelSession tracks an agent websocket tunnel session for the lifetime of the request.
+type tunnelSession struct {
+	nodeName       string
+	address        string
+	connectedSince time.Time
+	received       atomic.Int64
+	sent           atomic.Int64
+	receivedTotal  prometheus.Counter
+	sentTotal      prometheus.Counter
+}
+
+// serveSession passes websocket requests to the remotedialer server, tracking the session
+// and counting bytes transferred over the hijacked connection until the session is closed.
+func (t *TunnelServer) serveSession(resp http.ResponseWriter, req *http.Request) {
+	nodeName, authed, _ := authorizer(req)
+	if !authed {
+		t.server.ServeHTTP(resp, req)
+		return
+	}
+
+	s := &tunnelSession{
+		nodeName:       nodeName,
+		address:        req.RemoteAddr,
+		connectedSince: time.Now(),
+		receivedTotal:  tunnelBytes.WithLabelValues(nodeName, "received"),
+		sentTotal:      tunnelBytes.WithLabelValues(nodeName, "sent"),
+	}
+
+	t.sessionsMu.Lock()
+	t.sessions[s] = struct{}{}
+	tunnelSessions.WithLabelValues(nodeName).Inc()
+	t.sessionsMu.Unlock()
+
+	defer func() {
+		t.sessionsMu.Lock()
+		delete(t.sessions, s)
+		connected := false
+		for other := range t.sessions {
+			connected = connected || other.nodeName == nodeName
+		}
+		// Remove the series for nodes that are no longer connected, so that series for deleted
+		// nodes are not exported for the lifetime of the server.
+		if connected {
+			tunnelSessions.WithLabelValues(nodeName).Dec()
+		} else {
+			tunnelSessions.DeleteLabelValues(nodeName)
+			tunnelBytes.DeletePartialMatch(prometheus.Labels{"node": nodeName})
+		}
+		t.sessionsMu.Unlock()
+		if !connected {
+			t.removeNodeLimiter(nodeName)
+		}
+	}()
+
+	t.server.ServeHTTP(&sessionResponseWriter{
+		ResponseWriter:   resp,
+		session:          s,
+		compress:         req.Header.Get(util.TunnelCompressionHeader) == util.TunnelCompressionDeflate,
+		keepAliveTimeout: t.config.TunnelKeepAliveTimeout,
+	}, req)
+}
+
+// Sessions returns the currently connected agent tunnel sessions, sorted by node name.
+func (t *TunnelServer) Sessions() []config.TunnelSession {
+	t.sessionsMu.Lock()
+	defer t.sessionsMu.Unlock()
+
+	sessions := make([]config.TunnelSession, 0, len(t.sessions))
+	for s := range t.sessions {
+		sessions = append(sessions, config.TunnelSession{
+			NodeName:       s.nodeName,
+			Address:        s.address,
+			ConnectedSince: s.connectedSince,
+			BytesReceived:  s.received.Load(),
+			BytesSent:      s.sent.Load(),
+		})
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].NodeName != sessions[j].NodeName {
+			return sessions[i].NodeName < sessions[j].NodeName
+		}
+		return sessions[i].ConnectedSince.Before(sessions[j].ConnectedSince)
+	})
+	return sessions
+}
+
+// EgressStatus returns the egress selector mode for each apiserver egress type and kind of cluster egress
+// traffic, along with the number of connected agent sessions and connections proxied since the server started.
+func (t *TunnelServer) EgressStatus() config.EgressStatus {
+	status := config.EgressStatus{
+		Mode:                t.config.EgressSelectorMode,
+		TunneledConnections: t.tunneled.Load(),
+		DirectConnections:   t.direct.Load(),
+		FailedConnections:   t.failed.Load(),
+	}
+	for _, egressType := range slices.Concat(config.EgressSelectorTypes, config.EgressSelectorClusterTypes) {
+		mode := t.config.EgressSelectorModeForType(egressType)
+		status.Types = append(status.Types, config.EgressTypeStatus{
+			Type:    egressType,
+			Mode:    mode,
+			Proxied: mode != config.EgressSelectorModeDisabled,
+		})
+	}
+	t.sessionsMu.Lock()
+	status.Sessions = len(t.sessions)
+	t.sessionsMu.Unlock()
+	return status
+}
+
+// sessionResponseWriter wraps the connection returned when the websocket upgrade
+// hijacks the response, so that bytes transferred over the session can be counted,
+// the session compressed if requested by the agent, and the keepalive timeout applied.
+type sessionResponseWriter struct {
+	http.ResponseWriter
+	session          *tunnelSession
+	compress         bool
+	keepAliveTimeout time.Duration
+}
+
+func (w *sessionResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	// The connection cannot be wrapped without losing any data already read
+	// into the buffer, so it is left uncounted if the client sent data early.
+	// The websocket upgrade rejects such connections anyway.
+	if rw.Reader.Buffered() > 0 {
+		return conn, rw, nil
+	}
+	// Bytes are counted as sent over the network, after compression.
+	conn = util.NewTunnelServerConn(&countingConn{Conn: conn, session: w.session}, w.compress, w.keepAliveTimeout)
+	return conn, bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)), nil
+}
+
+// countingConn counts bytes read from and written to the wrapped connection.
+type countingConn struct {
+	net.Conn
+	session *tunnelSession
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.session.received.Add(int64(n))
+		c.session.receivedTotal.Add(float64(n))
+	}
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.session.sent.Add(int64(n))
+		c.session.sentTotal.Add(float64(n))
+	}
+	return n, err
+}
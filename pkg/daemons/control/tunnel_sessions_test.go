@@ -0,0 +1,103 @@
+package control
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.conn, bufio.NewReadWriter(bufio.NewReader(h.conn), bufio.NewWriter(h.conn)), nil
+}
+
+func Test_UnitTunnelSessions(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	s := &tunnelSession{
+		nodeName:       "agent-1",
+		address:        "10.0.0.2:51234",
+		connectedSince: time.Now(),
+		receivedTotal:  tunnelBytes.WithLabelValues("agent-1", "received"),
+		sentTotal:      tunnelBytes.WithLabelValues("agent-1", "sent"),
+	}
+	tunnel := &TunnelServer{sessions: map[*tunnelSession]struct{}{s: {}}}
+
+	var resp http.ResponseWriter = &sessionResponseWriter{
+		ResponseWriter: &hijackRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server},
+		session:        s,
+	}
+	conn, rw, err := resp.(http.Hijacker).Hijack()
+	if err != nil {
+		t.Fatalf("Hijack() error = %v", err)
+	}
+	defer conn.Close()
+
+	go func() {
+		client.Write([]byte("hello"))
+		io.ReadFull(client, make([]byte, 3))
+	}()
+	if _, err := io.ReadFull(rw, make([]byte, 5)); err != nil {
+		t.Fatal(err)
+	}
+	rw.WriteString("bye")
+	if err := rw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := tunnel.Sessions()
+	if len(sessions) != 1 {
+		t.Fatalf("Sessions() returned %d sessions, want 1", len(sessions))
+	}
+	if got := sessions[0]; got.NodeName != "agent-1" || got.BytesReceived != 5 || got.BytesSent != 3 {
+		t.Errorf("Sessions() = %+v, want node agent-1 with 5 bytes received and 3 bytes sent", got)
+	}
+	tunnel.config = &config.Control{CriticalControlArgs: config.CriticalControlArgs{EgressSelectorMode: config.EgressSelectorModePod}}
+	tunnel.tunneled.Add(2)
+	status := tunnel.EgressStatus()
+	if status.Sessions != 1 || status.TunneledConnections != 2 || len(status.Types) != len(config.EgressSelectorTypes)+len(config.EgressSelectorClusterTypes) {
+		t.Errorf("EgressStatus() = %+v, want 1 session and 2 tunneled connections", status)
+	}
+	if got := status.Types[0]; got.Type != config.EgressSelectorTypeCluster || got.Mode != config.EgressSelectorModePod || !got.Proxied {
+		t.Errorf("EgressStatus() cluster egress = %+v, want proxied pod mode", got)
+	}
+}
+
+func Test_UnitTunnelSessionMetrics(t *testing.T) {
+	cfg := &config.Control{Runtime: config.NewRuntime(nil)}
+	var handler http.Handler
+	// setting up the tunnel more than once, as on an in-process restart, must not panic when registering metrics
+	for i := 0; i < 2; i++ {
+		var err error
+		if handler, err = setupTunnel(context.Background(), cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the request is not a websocket upgrade, so the session is closed as soon as it is served
+	req := httptest.NewRequest(http.MethodGet, "/v1-k3s/connect", nil)
+	req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: "system:node:agent-2"}))
+	handler.(*TunnelServer).serveSession(httptest.NewRecorder(), req)
+
+	if tunnelSessions.DeleteLabelValues("agent-2") {
+		t.Errorf("expected session gauge series to be deleted after the last session for the node closed")
+	}
+	if n := tunnelBytes.DeletePartialMatch(prometheus.Labels{"node": "agent-2"}); n != 0 {
+		t.Errorf("expected %d byte counter series to be deleted after the last session for the node closed", n)
+	}
+}
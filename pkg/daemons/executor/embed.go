@@ -14,6 +14,7 @@ import (
 
 	"github.com/k3s-io/k3s/pkg/agent/containerd"
 	"github.com/k3s-io/k3s/pkg/agent/cridockerd"
+	"github.com/k3s-io/k3s/pkg/cgroups"
 	"github.com/k3s-io/k3s/pkg/cli/cmds"
 	daemonconfig "github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/util"
@@ -21,11 +22,13 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apiserver/pkg/authentication/authenticator"
+	genericapiserver "k8s.io/apiserver/pkg/server"
 	cloudprovider "k8s.io/cloud-provider"
 	ccmapp "k8s.io/cloud-provider/app"
 	cloudcontrollerconfig "k8s.io/cloud-provider/app/config"
 	"k8s.io/cloud-provider/names"
 	ccmopt "k8s.io/cloud-provider/options"
+	"k8s.io/component-base/cli"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/klog/v2"
 	apiapp "k8s.io/kubernetes/cmd/kube-apiserver/app"
@@ -68,6 +71,18 @@ func (e *Embedded) Bootstrap(ctx context.Context, nodeConfig *daemonconfig.Node,
 }
 
 func (e *Embedded) Kubelet(ctx context.Context, args []string) error {
+	// The kubelet is run as a child process when resource limits are set, so that it can be placed into its own cgroup.
+	if limits := e.nodeConfig.AgentConfig.KubeletLimits; limits != nil {
+		ready := make(chan struct{})
+		go func() {
+			if err := util.WaitForAPIServerReady(ctx, e.nodeConfig.AgentConfig.KubeConfigKubelet, util.DefaultAPIServerReadyTimeout); err != nil {
+				logrus.Fatalf("Kubelet failed to wait for apiserver ready: %v", err)
+			}
+			close(ready)
+		}()
+		return Process(ctx, cgroups.ComponentKubelet, ready, args, limits)
+	}
+
 	command := kubelet.NewKubeletCommand(context.Background())
 	command.SetArgs(args)
 
@@ -186,6 +201,26 @@ func (*Embedded) ControllerManager(ctx context.Context, apiReady <-chan struct{}
 	return nil
 }
 
+// KubeletMain runs the kubelet when the binary is re-executed as a child process.
+func KubeletMain() {
+	os.Exit(cli.Run(kubelet.NewKubeletCommand(context.Background())))
+}
+
+// APIServerMain runs kube-apiserver when the binary is re-executed as a child process.
+func APIServerMain() {
+	os.Exit(cli.Run(apiapp.NewAPIServerCommand(genericapiserver.SetupSignalHandler())))
+}
+
+// SchedulerMain runs kube-scheduler when the binary is re-executed as a child process.
+func SchedulerMain() {
+	os.Exit(cli.Run(sapp.NewSchedulerCommand(genericapiserver.SetupSignalHandler())))
+}
+
+// ControllerManagerMain runs kube-controller-manager when the binary is re-executed as a child process.
+func ControllerManagerMain() {
+	os.Exit(cli.Run(cmapp.NewControllerManagerCommand()))
+}
+
 func (*Embedded) CloudControllerManager(ctx context.Context, ccmRBACReady <-chan struct{}, args []string) error {
 	ccmOptions, err := ccmopt.NewCloudControllerManagerOptions()
 	if err != nil {
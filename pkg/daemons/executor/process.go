@@ -0,0 +1,54 @@
+package executor
+
+import (
+	"context"
+	"os"
+
+	"github.com/docker/docker/pkg/reexec"
+	"github.com/k3s-io/k3s/pkg/cgroups"
+	"github.com/sirupsen/logrus"
+)
+
+// Process runs a component as a child process, by re-executing the current binary with the
+// component name as argv[0]. The reexec handler for the component must be registered by the
+// main package. The child process is started once the ready channel is closed, and is placed into
+// its own cgroup if limits are set. As with the embedded executor, the supervisor exits if the
+// child process exits unexpectedly.
+func Process(ctx context.Context, name string, ready <-chan struct{}, args []string, limits *cgroups.Limits) error {
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ready:
+		}
+
+		cmd := reexec.Command(append([]string{name}, args...)...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			logrus.Fatalf("Failed to start %s: %v", name, err)
+		}
+		logrus.Infof("Started %s as child process %d", name, cmd.Process.Pid)
+
+		if limits != nil {
+			if err := cgroups.AddComponentProcess(name, cmd.Process.Pid, limits); err != nil {
+				cmd.Process.Kill()
+				logrus.Fatalf("Failed to apply resource limits to %s: %v", name, err)
+			}
+		}
+
+		go func() {
+			<-ctx.Done()
+			cmd.Process.Kill()
+		}()
+
+		err := cmd.Wait()
+		if ctx.Err() != nil {
+			return
+		}
+		logrus.Errorf("%s exited: %v", name, err)
+		os.Exit(1)
+	}()
+
+	return nil
+}
@@ -0,0 +1,182 @@
+// Package encryption protects the data-dir, which holds the cluster certificate authorities, tokens,
+// datastore, and node credentials, with fscrypt or dm-crypt, for nodes that may be physically accessible.
+package encryption
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/keystore"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Fscrypt encrypts the server directory using native filesystem encryption.
+	Fscrypt = "fscrypt"
+	// DMCrypt requires the server directory to be on a dm-crypt volume, which is set up and
+	// unlocked outside of k3s.
+	DMCrypt = "dm-crypt"
+)
+
+// keySize is the size of fscrypt keys. AES-256-XTS, used to encrypt file contents, requires
+// a 64-byte key.
+const keySize = 64
+
+// Config configures the protection of the data-dir.
+type Config struct {
+	// Mode is the encryption mode: fscrypt, dm-crypt, or empty if not enabled.
+	Mode string
+	// KeyFile is the file containing the fscrypt key.
+	KeyFile string
+	// TPM seals the key file to the host's TPM.
+	TPM bool
+}
+
+// Dirs returns the directories that hold the node's credentials and cluster data, and must be
+// protected: the server directory, the agent directory with its client keys and kubeconfigs, the
+// directory holding the node password file, and the etcd data dir. Directories within another
+// protected directory, or unset, are omitted.
+func Dirs(dataDir, serverDir, nodePasswordFile, etcdDataDir string) []string {
+	dirs := []string{}
+	for _, dir := range []string{serverDir, filepath.Join(dataDir, "agent"), filepath.Dir(nodePasswordFile), etcdDataDir} {
+		if dir == "" || dir == "." {
+			continue
+		}
+		dir = filepath.Clean(dir)
+		if !slices.ContainsFunc(dirs, func(parent string) bool { return within(parent, dir) }) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// within returns true if path is dir, or is within it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Setup sets up, unlocks, or validates encryption of the directories returned by Dirs. It must be
+// called before anything is read from or written to the directories.
+func Setup(dirs []string, cfg Config) error {
+	switch cfg.Mode {
+	case "":
+		for _, dir := range dirs {
+			if err := checkUnencrypted(dir); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Fscrypt:
+		key, err := loadKey(dirs, cfg)
+		if err != nil {
+			return err
+		}
+		defer clear(key)
+		for _, dir := range dirs {
+			if err := setupFscrypt(dir, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	case DMCrypt:
+		if cfg.KeyFile != "" || cfg.TPM {
+			return fmt.Errorf("data-dir-encryption-key-file and data-dir-encryption-tpm are not used with data-dir-encryption=%s; the volume must be unlocked before %s is started", DMCrypt, version.Program)
+		}
+		for _, dir := range dirs {
+			if err := validateDMCrypt(dir); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid data-dir-encryption %q: must be %s or %s", cfg.Mode, Fscrypt, DMCrypt)
+}
+
+// loadKey returns the fscrypt key from the key file, generating and saving a new key if the file does
+// not exist.
+func loadKey(dirs []string, cfg Config) ([]byte, error) {
+	if cfg.KeyFile == "" {
+		return nil, fmt.Errorf("data-dir-encryption-key-file is required with data-dir-encryption=%s", Fscrypt)
+	}
+	keyFile, err := filepath.Abs(cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if within(dir, keyFile) {
+			return nil, fmt.Errorf("data-dir-encryption-key-file %s cannot be stored in the encrypted directory %s", keyFile, dir)
+		}
+	}
+
+	key, err := readKey(keyFile, cfg.TPM)
+	if os.IsNotExist(err) {
+		key = make([]byte, keySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := writeKey(keyFile, key, cfg.TPM); err != nil {
+			return nil, errors.Wrapf(err, "failed to save data-dir encryption key to %s", keyFile)
+		}
+		logrus.Infof("Generated data-dir encryption key %s", keyFile)
+		return key, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to read data-dir encryption key from %s", keyFile)
+	}
+	if len(key) != keySize {
+		clear(key)
+		return nil, fmt.Errorf("data-dir encryption key %s must contain exactly %d bytes", keyFile, keySize)
+	}
+	return key, nil
+}
+
+// readKey reads the key file, unsealing it with the TPM if tpm is set.
+func readKey(keyFile string, tpm bool) ([]byte, error) {
+	b, err := os.ReadFile(keyFile)
+	if err != nil || !tpm {
+		return b, err
+	}
+	return keystore.NewTPM().Unseal(credentialName(), b)
+}
+
+// writeKey writes the key file, sealing it with the TPM if tpm is set.
+func writeKey(keyFile string, key []byte, tpm bool) error {
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+		return err
+	}
+	if tpm {
+		var err error
+		if key, err = keystore.NewTPM().Seal(credentialName(), key); err != nil {
+			return err
+		}
+	}
+	return util.AtomicWrite(keyFile, key, 0600)
+}
+
+// credentialName is the name embedded in the sealed key file, which must match when it is unsealed.
+func credentialName() string {
+	return version.Program + "-data-dir-key"
+}
+
+// isEmptyDir returns true if the directory has no entries.
+func isEmptyDir(dir string) (bool, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	if _, err := f.Readdirnames(1); err == io.EOF {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return false, nil
+}
@@ -0,0 +1,231 @@
+package encryption
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	sysfsRoot     = "/sys"
+	mountinfoFile = "/proc/self/mountinfo"
+)
+
+// setupFscrypt unlocks the directory by adding the key to the filesystem keyring. If the directory is
+// not yet encrypted, it must be empty, and is encrypted with the key.
+func setupFscrypt(dir string, key []byte) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	fd, err := unix.Open(dir, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	policy, err := getPolicy(fd)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get encryption policy of %s", dir)
+	}
+	identifier, err := addKey(fd, key)
+	if err != nil {
+		return errors.Wrapf(err, "failed to add data-dir encryption key to the filesystem keyring")
+	}
+
+	if policy != nil {
+		if policy.Master_key_identifier != identifier {
+			return fmt.Errorf("%s is encrypted with a different key than the data-dir encryption key", dir)
+		}
+		logrus.Infof("Unlocked encrypted directory %s", dir)
+		return nil
+	}
+
+	if empty, err := isEmptyDir(dir); err != nil {
+		return err
+	} else if !empty {
+		return fmt.Errorf("%s is not empty, and cannot be encrypted in place; move its contents aside, start %s with data-dir-encryption=%s to create the encrypted directory, and move the contents back while %s is stopped", dir, version.Program, Fscrypt, version.Program)
+	}
+	if err := setPolicy(fd, identifier); err != nil {
+		return errors.Wrapf(err, "failed to set encryption policy on %s", dir)
+	}
+	logrus.Infof("Encrypted directory %s with %s", dir, Fscrypt)
+	return nil
+}
+
+// checkUnencrypted returns an error if the directory is encrypted with fscrypt, as its contents cannot be
+// read unless data-dir encryption is enabled.
+func checkUnencrypted(dir string) error {
+	fd, err := unix.Open(dir, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	if policy, err := getPolicy(fd); err == nil && policy != nil {
+		return fmt.Errorf("%s is encrypted with %s; data-dir-encryption=%s must be set to unlock it", dir, Fscrypt, Fscrypt)
+	}
+	return nil
+}
+
+// getPolicy returns the fscrypt policy of the directory, or nil if it is not encrypted.
+func getPolicy(fd int) (*unix.FscryptPolicyV2, error) {
+	arg := unix.FscryptGetPolicyExArg{}
+	arg.Size = uint64(len(arg.Policy))
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.FS_IOC_GET_ENCRYPTION_POLICY_EX, uintptr(unsafe.Pointer(&arg)))
+	switch errno {
+	case 0:
+	case unix.ENODATA:
+		return nil, nil
+	case unix.ENOTTY, unix.EOPNOTSUPP:
+		return nil, errors.New("the filesystem does not support encryption; on ext4, the encrypt feature must be enabled with 'tune2fs -O encrypt'")
+	default:
+		return nil, errno
+	}
+	policy := (*unix.FscryptPolicyV2)(unsafe.Pointer(&arg.Policy[0]))
+	if policy.Version != unix.FSCRYPT_POLICY_V2 {
+		return nil, fmt.Errorf("unsupported encryption policy version %d", policy.Version)
+	}
+	return policy, nil
+}
+
+// addKey adds the key to the filesystem keyring, and returns its identifier. Adding a key that is already
+// present succeeds.
+func addKey(fd int, key []byte) ([unix.FSCRYPT_KEY_IDENTIFIER_SIZE]byte, error) {
+	var identifier [unix.FSCRYPT_KEY_IDENTIFIER_SIZE]byte
+	argSize := unsafe.Sizeof(unix.FscryptAddKeyArg{})
+	buf := make([]byte, int(argSize)+len(key))
+	defer clear(buf)
+	arg := (*unix.FscryptAddKeyArg)(unsafe.Pointer(&buf[0]))
+	arg.Key_spec.Type = unix.FSCRYPT_KEY_SPEC_TYPE_IDENTIFIER
+	arg.Raw_size = uint32(len(key))
+	copy(buf[argSize:], key)
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.FS_IOC_ADD_ENCRYPTION_KEY, uintptr(unsafe.Pointer(&buf[0]))); errno != 0 {
+		return identifier, errno
+	}
+	copy(identifier[:], arg.Key_spec.U[:])
+	return identifier, nil
+}
+
+// setPolicy encrypts the empty directory with the key that has the given identifier.
+func setPolicy(fd int, identifier [unix.FSCRYPT_KEY_IDENTIFIER_SIZE]byte) error {
+	policy := unix.FscryptPolicyV2{
+		Version:                   unix.FSCRYPT_POLICY_V2,
+		Contents_encryption_mode:  unix.FSCRYPT_MODE_AES_256_XTS,
+		Filenames_encryption_mode: unix.FSCRYPT_MODE_AES_256_CTS,
+		Flags:                     unix.FSCRYPT_POLICY_FLAGS_PAD_32,
+		Master_key_identifier:     identifier,
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.FS_IOC_SET_ENCRYPTION_POLICY, uintptr(unsafe.Pointer(&policy))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// validateDMCrypt returns an error if the directory is not on a block device that is backed by dm-crypt.
+func validateDMCrypt(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	device, err := blockDevice(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the block device holding %s", dir)
+	}
+	if !isCrypt(device) {
+		return fmt.Errorf("%s is not on a dm-crypt volume", dir)
+	}
+	logrus.Infof("Directory %s is on a dm-crypt volume", dir)
+	return nil
+}
+
+// blockDevice returns the major:minor number of the block device holding the directory. Filesystems such
+// as btrfs report an anonymous device number, so the device is found from the source of the mount.
+func blockDevice(dir string) (string, error) {
+	st := unix.Stat_t{}
+	if err := unix.Stat(dir, &st); err != nil {
+		return "", err
+	}
+	if unix.Major(st.Dev) != 0 {
+		return fmt.Sprintf("%d:%d", unix.Major(st.Dev), unix.Minor(st.Dev)), nil
+	}
+	source, err := mountSource(dir)
+	if err != nil {
+		return "", err
+	}
+	if err := unix.Stat(source, &st); err != nil {
+		return "", err
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFBLK {
+		return "", fmt.Errorf("mount source %s is not a block device", source)
+	}
+	return fmt.Sprintf("%d:%d", unix.Major(st.Rdev), unix.Minor(st.Rdev)), nil
+}
+
+// mountSource returns the source of the mount that holds the directory.
+func mountSource(dir string) (string, error) {
+	f, err := os.Open(mountinfoFile)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var mountPoint, source string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if len(fields) < 5 || sep < 0 || len(fields) < sep+3 {
+			continue
+		}
+		point := fields[4]
+		if rel, err := filepath.Rel(point, dir); err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if len(point) >= len(mountPoint) {
+			mountPoint, source = point, fields[sep+2]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(source, "/dev/") {
+		return "", fmt.Errorf("%s is not mounted from a block device", dir)
+	}
+	return source, nil
+}
+
+// isCrypt returns true if the block device, or any device that it is stacked on, is a dm-crypt device.
+// This allows for LVM or other device-mapper targets on top of LUKS.
+func isCrypt(device string) bool {
+	base := filepath.Join(sysfsRoot, "dev", "block", device)
+	if uuid, err := os.ReadFile(filepath.Join(base, "dm", "uuid")); err == nil && strings.HasPrefix(string(uuid), "CRYPT-") {
+		return true
+	}
+	slaves, err := os.ReadDir(filepath.Join(base, "slaves"))
+	if err != nil {
+		return false
+	}
+	for _, slave := range slaves {
+		b, err := os.ReadFile(filepath.Join(base, "slaves", slave.Name(), "dev"))
+		if err != nil {
+			continue
+		}
+		if isCrypt(strings.TrimSpace(string(b))) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package encryption
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_UnitIsCrypt(t *testing.T) {
+	sysfsRoot = t.TempDir()
+	defer func() { sysfsRoot = "/sys" }()
+
+	device := func(id, uuid string, slaves ...string) {
+		base := filepath.Join(sysfsRoot, "dev", "block", id)
+		if uuid != "" {
+			if err := os.MkdirAll(filepath.Join(base, "dm"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(base, "dm", "uuid"), []byte(uuid+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, slave := range slaves {
+			dir := filepath.Join(base, "slaves", "dev-"+slave)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "dev"), []byte(slave+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	device("8:1", "")
+	device("8:2", "")
+	device("253:0", "CRYPT-LUKS2-0123456789abcdef-luks", "8:2")
+	device("253:1", "LVM-abcdef", "253:0")
+	device("253:2", "LVM-012345", "8:1")
+
+	tests := []struct {
+		device string
+		want   bool
+	}{
+		{device: "8:1"},
+		{device: "253:0", want: true},
+		{device: "253:1", want: true},
+		{device: "253:2"},
+		{device: "1:1"},
+	}
+	for _, tt := range tests {
+		if got := isCrypt(tt.device); got != tt.want {
+			t.Errorf("isCrypt(%s) = %v, want %v", tt.device, got, tt.want)
+		}
+	}
+}
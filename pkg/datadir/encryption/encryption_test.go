@@ -0,0 +1,98 @@
+package encryption
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_UnitLoadKey(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "server")
+	keyFile := filepath.Join(t.TempDir(), "keys", "data-dir.key")
+	cfg := Config{Mode: Fscrypt, KeyFile: keyFile}
+
+	key, err := loadKey([]string{dir}, cfg)
+	if err != nil {
+		t.Fatalf("loadKey() of new key file error = %v", err)
+	}
+	if len(key) != keySize {
+		t.Fatalf("loadKey() generated %d byte key, want %d", len(key), keySize)
+	}
+	if info, err := os.Stat(keyFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("key file was not saved with mode 0600: %v, %v", info, err)
+	}
+	reloaded, err := loadKey([]string{dir}, cfg)
+	if err != nil {
+		t.Fatalf("loadKey() of existing key file error = %v", err)
+	}
+	if !bytes.Equal(key, reloaded) {
+		t.Errorf("loadKey() of existing key file returned a different key")
+	}
+
+	if err := os.WriteFile(keyFile, []byte("short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadKey([]string{dir}, cfg); err == nil {
+		t.Errorf("loadKey() of short key succeeded, want error")
+	}
+	if _, err := loadKey([]string{dir}, Config{Mode: Fscrypt, KeyFile: filepath.Join(dir, "key")}); err == nil {
+		t.Errorf("loadKey() of key file in encrypted directory succeeded, want error")
+	}
+	if _, err := loadKey([]string{dir}, Config{Mode: Fscrypt}); err == nil {
+		t.Errorf("loadKey() without key file succeeded, want error")
+	}
+}
+
+func Test_UnitSetup(t *testing.T) {
+	dirs := []string{t.TempDir(), filepath.Join(t.TempDir(), "missing")}
+	if err := Setup(dirs, Config{}); err != nil {
+		t.Errorf("Setup() without encryption error = %v", err)
+	}
+	if err := Setup(dirs, Config{Mode: "luks"}); err == nil {
+		t.Errorf("Setup() with invalid mode succeeded, want error")
+	}
+	if err := Setup(dirs, Config{Mode: DMCrypt, KeyFile: "/etc/key"}); err == nil {
+		t.Errorf("Setup() of %s with key file succeeded, want error", DMCrypt)
+	}
+}
+
+func Test_UnitDirs(t *testing.T) {
+	tests := []struct {
+		name                                 string
+		serverDir, nodePasswordFile, etcdDir string
+		want                                 []string
+	}{
+		{
+			name:      "server only",
+			serverDir: "/var/lib/rancher/k3s/server",
+			want:      []string{"/var/lib/rancher/k3s/server", "/var/lib/rancher/k3s/agent"},
+		},
+		{
+			name:             "node password and etcd in data-dir",
+			serverDir:        "/var/lib/rancher/k3s/server",
+			nodePasswordFile: "/var/lib/rancher/k3s/agent/etc/rancher/node/password",
+			etcdDir:          "/var/lib/rancher/k3s/server/db/etcd",
+			want:             []string{"/var/lib/rancher/k3s/server", "/var/lib/rancher/k3s/agent"},
+		},
+		{
+			name:             "node password and external etcd",
+			serverDir:        "/var/lib/rancher/k3s/server",
+			nodePasswordFile: "/etc/rancher/node/password",
+			etcdDir:          "/var/lib/etcd/",
+			want:             []string{"/var/lib/rancher/k3s/server", "/var/lib/rancher/k3s/agent", "/etc/rancher/node", "/var/lib/etcd"},
+		},
+		{
+			name:      "etcd with common prefix",
+			serverDir: "/var/lib/rancher/k3s/server",
+			etcdDir:   "/var/lib/rancher/k3s/server-etcd",
+			want:      []string{"/var/lib/rancher/k3s/server", "/var/lib/rancher/k3s/agent", "/var/lib/rancher/k3s/server-etcd"},
+		},
+	}
+	for _, tt := range tests {
+		if got := Dirs("/var/lib/rancher/k3s", tt.serverDir, tt.nodePasswordFile, tt.etcdDir); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Dirs(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package encryption
+
+import (
+	"errors"
+)
+
+var errEncryptionNotSupported = errors.New("data-dir encryption is not supported on windows")
+
+func setupFscrypt(dir string, key []byte) error {
+	return errEncryptionNotSupported
+}
+
+func checkUnencrypted(dir string) error {
+	return nil
+}
+
+func validateDMCrypt(dir string) error {
+	return errEncryptionNotSupported
+}
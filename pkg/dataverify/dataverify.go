@@ -15,8 +15,15 @@ import (
 
 // Verify will check the sha256sums and links from the files in a given directory
 func Verify(dir string) error {
+	return VerifyWithSums(dir, nil)
+}
+
+// VerifyWithSums will check the sha256sums and links from the files in a given directory,
+// using the provided precomputed sums where available instead of reading the file back from disk.
+// The map of precomputed sums is keyed by file path.
+func VerifyWithSums(dir string, knownSums map[string]string) error {
 	failed := false
-	if err := VerifySums(dir, ".sha256sums"); err != nil {
+	if err := verifySums(dir, ".sha256sums", knownSums); err != nil {
 		logrus.Errorf("Unable to verify sums: %s", err)
 		failed = true
 	}
@@ -32,6 +39,10 @@ func Verify(dir string) error {
 
 // VerifySums will take a file which contains a list of hash sums for files and verify they match
 func VerifySums(root, sumListFile string) error {
+	return verifySums(root, sumListFile, nil)
+}
+
+func verifySums(root, sumListFile string, knownSums map[string]string) error {
 	sums, err := fileMapFields(filepath.Join(root, sumListFile), 1, 0)
 	if err != nil {
 		return err
@@ -42,7 +53,10 @@ func VerifySums(root, sumListFile string) error {
 	numFailed := 0
 	for sumFile, sumExpected := range sums {
 		file := filepath.Join(root, sumFile)
-		sumActual, _ := sha256Sum(file)
+		sumActual, ok := knownSums[file]
+		if !ok {
+			sumActual, _ = sha256Sum(file)
+		}
 		if sumExpected != sumActual {
 			logrus.Errorf("Hash for file %s expected to be %s (fail)", sumFile, sumExpected)
 			numFailed++
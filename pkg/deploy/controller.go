@@ -18,6 +18,8 @@ import (
 	"github.com/k3s-io/k3s/pkg/agent/util"
 	apisv1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
 	controllersv1 "github.com/k3s-io/k3s/pkg/generated/controllers/k3s.cattle.io/v1"
+	"github.com/k3s-io/k3s/pkg/startup"
+	"github.com/k3s-io/k3s/pkg/tracing"
 	pkgutil "github.com/k3s-io/k3s/pkg/util"
 	errors2 "github.com/pkg/errors"
 	"github.com/rancher/wrangler/v3/pkg/apply"
@@ -25,6 +27,7 @@ import (
 	"github.com/rancher/wrangler/v3/pkg/merr"
 	"github.com/rancher/wrangler/v3/pkg/objectset"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -45,13 +48,18 @@ const (
 )
 
 // WatchFiles sets up an OnChange callback to start a periodic goroutine to watch files for changes once the controller has started up.
-func WatchFiles(ctx context.Context, client kubernetes.Interface, apply apply.Apply, addons controllersv1.AddonController, disables map[string]bool, bases ...string) error {
+// Files matching an entry in skips are ignored, and files matching an entry in pins are deployed even if
+// there is a .skip file for them. Files are not processed while paused returns true.
+func WatchFiles(ctx context.Context, client kubernetes.Interface, apply apply.Apply, addons controllersv1.AddonController, disables, skips, pins map[string]bool, paused func() bool, bases ...string) error {
 	w := &watcher{
+		paused:     paused,
 		apply:      apply,
 		addonCache: addons.Cache(),
 		addons:     addons,
 		bases:      bases,
 		disables:   disables,
+		skips:      skips,
+		pins:       pins,
 		modTime:    map[string]time.Time{},
 		gvkCache:   map[schema.GroupVersionKind]bool{},
 		discovery:  client.Discovery(),
@@ -76,6 +84,9 @@ type watcher struct {
 	addons     controllersv1.AddonClient
 	bases      []string
 	disables   map[string]bool
+	skips      map[string]bool
+	pins       map[string]bool
+	paused     func() bool
 	modTime    map[string]time.Time
 	gvkCache   map[schema.GroupVersionKind]bool
 	recorder   record.EventRecorder
@@ -86,11 +97,26 @@ type watcher struct {
 func (w *watcher) start(ctx context.Context, client kubernetes.Interface) {
 	w.recorder = pkgutil.BuildControllerEventRecorder(client, ControllerName, metav1.NamespaceSystem)
 	force := true
+	paused := false
 	for {
-		if err := w.listFiles(force); err == nil {
-			force = false
+		if w.paused != nil && w.paused() {
+			if !paused {
+				logrus.Infof("Pausing %s controller while node is in maintenance mode", ControllerName)
+				paused = true
+			}
 		} else {
-			logrus.Errorf("Failed to process config: %v", err)
+			if paused {
+				logrus.Infof("Resuming %s controller", ControllerName)
+				paused = false
+			}
+			if err := w.listFiles(force); err == nil {
+				if force {
+					startup.Done(startup.PhaseAddons)
+				}
+				force = false
+			} else {
+				logrus.Errorf("Failed to process config: %v", err)
+			}
 		}
 		select {
 		case <-ctx.Done():
@@ -147,7 +173,7 @@ func (w *watcher) listFilesIn(base string, force bool) error {
 
 	// Make a map of .skip files - these are used later to indicate that a given file should be ignored
 	// For example, 'addon.yaml.skip' will cause 'addon.yaml' to be ignored completely - unless it is also
-	// disabled, since disable processing happens first.
+	// disabled, skipped, or pinned in the config, since config processing happens first.
 	skips := map[string]bool{}
 	keys := make([]string, len(files))
 	keyIndex := 0
@@ -169,8 +195,17 @@ func (w *watcher) listFilesIn(base string, force bool) error {
 			}
 			continue
 		}
+		// Files skipped in the config are just ignored, regardless of any .skip file
+		if matchesManifest(base, path, w.skips) {
+			continue
+		}
+		// Files pinned in the config are deployed even if there is a .skip file for them
+		fileSkips := skips
+		if matchesManifest(base, path, w.pins) {
+			fileSkips = nil
+		}
 		// Skipped files are just ignored
-		if shouldSkipFile(files[path].Name(), skips) {
+		if shouldSkipFile(files[path].Name(), fileSkips) {
 			continue
 		}
 		modTime := files[path].ModTime()
@@ -251,7 +286,10 @@ func (w *watcher) deploy(path string, compareChecksum bool) error {
 	// doesn't know to search that GVK for owner references, it won't find and delete them.
 	w.recorder.Eventf(&addon, corev1.EventTypeNormal, "ApplyingManifest", "Applying manifest at %q", path)
 
-	if err := w.apply.WithOwner(&addon).WithGVK(addonGVKs...).Apply(objects); err != nil {
+	_, span := tracing.Start(context.Background(), "manifest.apply", attribute.String("manifest.path", path))
+	err = w.apply.WithOwner(&addon).WithGVK(addonGVKs...).Apply(objects)
+	tracing.End(span, err)
+	if err != nil {
 		w.recorder.Eventf(&addon, corev1.EventTypeWarning, "ApplyManifestFailed", "Applying manifest at %q failed: %v", path, err)
 		return err
 	}
@@ -500,6 +538,24 @@ func shouldDisableFile(base, fileName string, disables map[string]bool) bool {
 	return disables[baseName]
 }
 
+// Returns true if a file matches an entry in the names map. Entries may be the file basename without
+// extension, or the path of the file or of any of its parent directories, relative to the base directory.
+// The file path may be given with or without its extension.
+func matchesManifest(base, fileName string, names map[string]bool) bool {
+	if len(names) == 0 {
+		return false
+	}
+	relFile := strings.TrimPrefix(strings.TrimPrefix(fileName, base), string(os.PathSeparator))
+	namePath := strings.Split(relFile, string(os.PathSeparator))
+	for i := 1; i < len(namePath); i++ {
+		if names[filepath.Join(namePath[0:i]...)] {
+			return true
+		}
+	}
+	suffix := filepath.Ext(relFile)
+	return names[relFile] || names[strings.TrimSuffix(relFile, suffix)] || names[strings.TrimSuffix(filepath.Base(relFile), suffix)]
+}
+
 func getGVK(s string) (*schema.GroupVersionKind, error) {
 	parts := strings.Split(s, ", Kind=")
 	if len(parts) != 2 {
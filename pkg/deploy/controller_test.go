@@ -0,0 +1,79 @@
+package deploy
+
+import (
+	"testing"
+)
+
+func Test_UnitMatchesManifest(t *testing.T) {
+	base := "/var/lib/rancher/k3s/server/manifests"
+	tests := []struct {
+		name     string
+		base     string
+		fileName string
+		names    map[string]bool
+		want     bool
+	}{
+		{
+			name:     "No names",
+			base:     base,
+			fileName: base + "/traefik.yaml",
+			want:     false,
+		},
+		{
+			name:     "Name without extension",
+			base:     base,
+			fileName: base + "/traefik.yaml",
+			names:    map[string]bool{"traefik": true},
+			want:     true,
+		},
+		{
+			name:     "Name with extension",
+			base:     base,
+			fileName: base + "/traefik.yaml",
+			names:    map[string]bool{"traefik.yaml": true},
+			want:     true,
+		},
+		{
+			name:     "Parent directory",
+			base:     base,
+			fileName: base + "/metrics-server/metrics-server-deployment.yaml",
+			names:    map[string]bool{"metrics-server": true},
+			want:     true,
+		},
+		{
+			name:     "Relative path",
+			base:     base,
+			fileName: base + "/metrics-server/metrics-server-deployment.yaml",
+			names:    map[string]bool{"metrics-server/metrics-server-deployment": true},
+			want:     true,
+		},
+		{
+			name:     "Nested basename",
+			base:     base,
+			fileName: base + "/metrics-server/metrics-server-deployment.yaml",
+			names:    map[string]bool{"metrics-server-deployment": true},
+			want:     true,
+		},
+		{
+			name:     "Other file",
+			base:     base,
+			fileName: base + "/local-storage.yaml",
+			names:    map[string]bool{"traefik": true, "metrics-server": true},
+			want:     false,
+		},
+		{
+			name:     "Packaged asset name",
+			base:     "",
+			fileName: "metrics-server/auth-reader.yaml",
+			names:    map[string]bool{"metrics-server/auth-reader": true},
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesManifest(tt.base, tt.fileName, tt.names); got != tt.want {
+				t.Errorf("matchesManifest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
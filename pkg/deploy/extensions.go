@@ -0,0 +1,70 @@
+package deploy
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// extensionPrefix is prepended to the names of staged extension manifests, so that their addon
+// names do not conflict with packaged manifests.
+const extensionPrefix = "extension-"
+
+// StageExtensions writes the manifests found in srcDir to dataDir, with the same template variables
+// that are used for packaged manifests, so that operator-provided webhook configurations and
+// extender deployments always reference the current cluster CA bundles and ports. Manifests are
+// re-staged on every start, and staged manifests whose source has been removed are also removed;
+// as with other manifests, removing a manifest does not delete the resources it created. Files
+// in srcDir listed in ignores are not staged.
+func StageExtensions(srcDir, dataDir string, templateVars map[string]string, ignores ...string) error {
+	if err := os.MkdirAll(srcDir, 0700); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return err
+	}
+	staged := map[string]bool{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || shouldSkipFile(name, nil) || slices.Contains(ignores, name) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(srcDir, name))
+		if err != nil {
+			return err
+		}
+		for k, v := range templateVars {
+			content = bytes.ReplaceAll(content, []byte(k), []byte(v))
+		}
+		p := filepath.Join(dataDir, extensionPrefix+name)
+		staged[filepath.Base(p)] = true
+		if existing, err := os.ReadFile(p); err == nil && bytes.Equal(existing, content) {
+			continue
+		}
+		logrus.Info("Writing extension manifest: ", p)
+		if err := util.AtomicWrite(p, content, 0600); err != nil {
+			return errors.Wrapf(err, "failed to write to %s", p)
+		}
+	}
+
+	existing, err := os.ReadDir(dataDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range existing {
+		if name := entry.Name(); strings.HasPrefix(name, extensionPrefix) && !entry.IsDir() && !staged[name] {
+			logrus.Info("Removing extension manifest: ", name)
+			if err := os.Remove(filepath.Join(dataDir, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_UnitStageExtensions(t *testing.T) {
+	srcDir := filepath.Join(t.TempDir(), "extensions")
+	dataDir := t.TempDir()
+	templateVars := map[string]string{"%{SERVER_CA_BUNDLE}%": "Y2E="}
+
+	if err := StageExtensions(srcDir, dataDir, templateVars, "scheduler-extenders.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"webhook.yaml":             "caBundle: %{SERVER_CA_BUNDLE}%\n",
+		"scheduler-extenders.yaml": "- urlPrefix: http://127.0.0.1:8888\n",
+		"notes.txt":                "not a manifest\n",
+	} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := StageExtensions(srcDir, dataDir, templateVars, "scheduler-extenders.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dataDir, "extension-webhook.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "caBundle: Y2E=\n" {
+		t.Errorf("unexpected staged manifest content %q", b)
+	}
+	for _, name := range []string{"extension-scheduler-extenders.yaml", "extension-notes.txt"} {
+		if _, err := os.Stat(filepath.Join(dataDir, name)); err == nil {
+			t.Errorf("unexpected staged file %s", name)
+		}
+	}
+
+	// manifests removed from the extensions directory are removed from the data dir
+	if err := os.Remove(filepath.Join(srcDir, "webhook.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := StageExtensions(srcDir, dataDir, templateVars, "scheduler-extenders.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "extension-webhook.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected removed extension manifest to be removed from the data dir: %v", err)
+	}
+}
@@ -3,6 +3,10 @@
 
 package deploy
 
-func Stage(dataDir string, templateVars map[string]string, skips map[string]bool) error {
+func Stage(dataDir string, templateVars map[string]string, skips, pins map[string]bool) error {
+	return nil
+}
+
+func AssetNames() []string {
 	return nil
 }
@@ -12,7 +12,9 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func Stage(dataDir string, templateVars map[string]string, skips map[string]bool) error {
+// Stage writes the packaged manifests to dataDir. Manifests matching an entry in skips are not written,
+// and manifests matching an entry in pins are not overwritten if they already exist.
+func Stage(dataDir string, templateVars map[string]string, skips, pins map[string]bool) error {
 staging:
 	for _, name := range AssetNames() {
 		nameNoExtension := strings.TrimSuffix(name, filepath.Ext(name))
@@ -35,6 +37,12 @@ staging:
 			content = bytes.Replace(content, []byte(k), []byte(v), -1)
 		}
 		p := filepath.Join(dataDir, name)
+		if matchesManifest("", name, pins) {
+			if _, err := os.Stat(p); err == nil {
+				logrus.Info("Not updating pinned manifest: ", p)
+				continue
+			}
+		}
 		os.MkdirAll(filepath.Dir(p), 0700)
 		logrus.Info("Writing manifest: ", p)
 		if err := os.WriteFile(p, content, 0600); err != nil {
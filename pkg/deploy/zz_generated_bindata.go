@@ -10,8 +10,13 @@
 // manifests/metrics-server/metrics-server-deployment.yaml
 // manifests/metrics-server/metrics-server-service.yaml
 // manifests/metrics-server/resource-reader.yaml
+// manifests/monitoring-defaults/dashboards.yaml
+// manifests/monitoring-defaults/rules.yaml
+// manifests/monitoring-defaults/servicemonitor.yaml
 // manifests/rolebindings.yaml
 // manifests/runtimes.yaml
+// manifests/supervisor-api.yaml
+// manifests/token-viewer.yaml
 // manifests/traefik.yaml
 //go:build !no_stage
 // +build !no_stage
@@ -92,7 +97,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _ccmYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcc\x94\x41\x8f\x13\x31\x0c\x85\xef\xf3\x2b\xa2\x1e\x91\xd2\x15\xe2\x82\xe6\x08\x07\xee\x2b\xc1\xdd\x4d\x1e\xdd\xd0\x4c\x1c\xd9\x4e\x61\xf9\xf5\x68\x3a\x5d\x51\x3a\xdb\xd2\x16\x10\x7b\x8b\xac\xf8\xf3\xf3\x73\x62\xaa\xe9\x13\x44\x13\x97\xde\xc9\x8a\xc2\x92\x9a\x3d\xb0\xa4\xef\x64\x89\xcb\x72\xf3\x56\x97\x89\xef\xb6\xaf\xbb\x4d\x2a\xb1\x77\xef\x73\x53\x83\xdc\x73\x46\x37\xc0\x28\x92\x51\xdf\x39\x57\x68\x40\xef\x36\x6f\xd4\x87\xcc\x2d\xfa\xc0\xc5\x84\x73\x86\xf8\x81\x0a\xad\x21\x9d\xb4\x0c\xed\x3b\xef\xa8\xa6\x0f\xc2\xad\xea\x98\xe8\x5d\x60\x96\x98\xca\x61\xbd\xce\x39\x81\x72\x93\x80\xfd\xa5\x0c\x52\x68\xe7\xdc\x16\xb2\xda\xc7\xd6\xb0\x09\x20\x20\xc3\xee\xd8\x6a\x1c\x8f\xb3\x1a\x8b\xc5\x1c\x89\x2d\x8a\x1d\x21\x0f\x50\x95\x2c\x3c\x5c\x0d\x2d\x1c\x8f\x65\x2e\x5e\x2d\xae\xc8\xbd\x53\x23\x6b\xba\x0b\x28\x64\x9b\xc2\x61\xec\x00\x3b\xe9\xbb\x08\xfc\xc4\x99\xf2\x38\x9e\xf0\x31\x27\x9d\x0e\x5f\x6f\x42\xcf\xb4\x5d\xeb\xdd\x9e\x45\x21\x70\x3b\x37\x99\x51\xef\x65\x86\xd2\x00\xad\x34\x93\xf7\x3b\x16\xd5\xaa\x73\x5a\x24\x0c\x5c\x14\xc7\xca\x9e\x9f\x6f\x4c\x1a\x78\x0b\x79\xdc\x3f\xe9\xe7\x1e\x60\x89\x95\x53\x31\xcd\x73\x07\x4f\xcd\xc4\xfb\xee\xf6\x1f\xfb\x2e\x95\x98\xca\xfa\xea\x8f\xcb\x19\xf7\xf8\x3c\xde\x7e\xea\xf2\x4c\xe5\xce\xb9\xf9\xaa\xb8\xa8\x8e\xb6\xd5\x17\x04\xdb\xed\x88\x09\xf1\x51\x21\x97\xe5\xba\x9f\xc3\xee\xdd\xa6\xad\xe0\xf5\x51\x0d\xc3\x7f\x71\xcc\x8f\x7c\x1f\x91\xb1\x26\xe3\xbf\x6a\xe0\xd4\x55\x7f\x54\xe0\xa5\x38\xf7\x87\x96\xa1\x58\x0a\x3b\xb2\x17\x50\x3c\x27\xee\x46\x4b\x7f\xf1\x12\xdf\x0c\x65\xec\xcd\x53\x4d\xe3\xf2\x39\x29\xe3\x9f\xf8\xfb\x23\x00\x00\xff\xff\xde\xc0\x02\x82\x7a\x07\x00\x00")
+var _ccmYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcc\x94\x4f\x8f\xd3\x30\x10\xc5\xef\xf9\x14\x56\x8f\x48\xee\x0a\x71\x41\x39\xc2\x81\xfb\x4a\x70\x9f\xda\x8f\xae\xa9\xeb\xb1\x3c\xe3\xc0\xf2\xe9\x91\x93\xae\x54\x1a\x5a\x25\x05\x04\xa7\x38\x96\xfd\x9b\xe7\x37\x7f\x28\x87\x4f\x28\x12\x38\xf5\xa6\xec\xc8\x6d\xa9\xea\x13\x97\xf0\x9d\x34\x70\xda\x1e\xde\xca\x36\xf0\xc3\xf0\xba\x3b\x84\xe4\x7b\xf3\x3e\x56\x51\x94\x47\x8e\xe8\x8e\x50\xf2\xa4\xd4\x77\xc6\x24\x3a\xa2\x37\x87\x37\x62\x5d\xe4\xea\xad\xe3\xa4\x85\x63\x44\xb1\x47\x4a\xb4\x47\xe9\x4a\x8d\x90\xbe\xb3\x86\x72\xf8\x50\xb8\x66\x69\x17\xad\x71\xcc\xc5\x87\x74\x1e\xaf\x33\xa6\x40\xb8\x16\x87\xd3\xa1\x08\x12\x48\x67\xcc\x80\xb2\x3b\xed\xed\xa1\xe3\xd7\x15\x90\x62\x5c\xd6\xec\xdb\x72\x16\x63\xb3\x99\x23\x31\x20\xe9\x05\xf2\x0c\x95\x49\xdd\xd3\x6a\x68\x62\x7f\x29\x73\xf3\x6a\xb3\xe2\xee\x83\x28\x69\x6d\x08\x6b\x04\x65\x08\xee\x7c\xef\x0c\x3b\xe9\x5b\x04\x7e\xe1\x8c\x3f\x99\xfd\x15\x1f\x63\x90\xc9\xd0\xaf\x77\xa1\x67\xda\xd6\x7a\x77\x62\x91\x73\x5c\x6f\x65\xa6\xe5\x7d\x11\xb0\x15\xa5\x64\x72\x58\xc9\xa2\x9c\x65\x4e\xf3\x84\x23\x27\x81\x2e\xca\xaf\x0f\xe2\x78\x40\x79\x3e\x95\xf4\x2f\xe4\x21\xf9\xcc\x21\xa9\xc4\xe0\xae\xd5\xf6\x65\x4e\xac\xed\xee\xef\xd8\x77\x21\xf9\x90\xf6\xab\x1b\x97\x23\x1e\xf1\xb9\x09\x7b\x79\xe5\x8d\xc8\x9d\x31\xf3\x51\xb1\x28\x8e\xd4\xdd\x17\x38\x1d\x67\xc4\x84\xf8\x28\x28\xcb\xee\x4e\x87\xc6\x64\xf7\xe6\x50\x77\xb0\xf2\x2c\x8a\xe3\x3f\x71\xcc\x36\xbe\xf5\x88\xd8\x93\xf2\x1f\x35\x70\x7a\x55\x7f\x11\xe0\x7f\x71\xee\x37\x2d\x43\xd2\xe0\x46\xb2\x2d\x20\x7f\x4b\xdc\x9d\x96\xfe\xe4\x25\xbe\x29\x52\xeb\x23\x4b\x39\xb4\xe1\x73\x55\xc6\x5f\xf1\xf7\xc7\x00\xde\xc0\x02\x82\x7a\x07\x00\x00")
 
 func ccmYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -112,7 +117,7 @@ func ccmYaml() (*asset, error) {
 	return a, nil
 }
 
-var _corednsYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x57\xdf\x6f\xe3\xbe\x0d\x7f\xcf\x5f\x21\x18\xe8\xcb\x30\xa7\xcd\xb7\xb8\x5b\xa7\xb7\x5e\x92\xde\x05\x6b\x73\x41\x92\x1e\x50\x0c\x43\xa1\xc8\x4c\xac\x55\x16\x35\x49\x4e\x9b\xdd\xfa\xbf\x0f\xf2\xaf\x58\x89\xdb\x6b\xbb\x5b\x5e\x62\x9b\x22\x29\x7e\x44\x7e\x48\x31\x2d\x7e\x80\xb1\x02\x15\x25\xdb\x41\xef\x41\xa8\x84\x92\x05\x98\xad\xe0\x70\xc9\x39\xe6\xca\xf5\x32\x70\x2c\x61\x8e\xd1\x1e\x21\x8a\x65\x40\x09\x47\x03\x89\xb2\xd5\xbb\xd5\x8c\x03\x25\x0f\xf9\x0a\x62\xbb\xb3\x0e\xb2\x5e\x1c\xc7\xbd\xb6\x69\xb3\x62\xbc\xcf\x72\x97\xa2\x11\xff\x66\x4e\xa0\xea\x3f\x5c\xd8\xbe\xc0\xd3\xc6\xe9\x50\xe6\xd6\x81\x99\xa3\x84\xc0\xa3\x64\x2b\x90\xd6\x3f\x91\xc2\x85\x51\xe0\xa0\x50\x5d\x21\x3a\xeb\x0c\xd3\x5a\xa8\x4d\xe9\x23\x4e\x60\xcd\x72\xe9\x6c\xb3\xd5\x72\x43\xb4\xde\xb1\xc9\x25\x58\xda\x8b\x09\xd3\xe2\xab\xc1\x5c\x17\x96\x63\x12\x45\x3d\x42\x0c\x58\xcc\x0d\x87\xea\x1b\xa8\x44\xa3\x50\x85\xb1\x98\xd8\x12\x94\xf2\x45\x63\x52\x3e\x34\xf1\xfb\xd7\x2d\x98\x55\xa5\x2b\x85\x75\xc5\xc3\x23\x73\x3c\x3d\xf6\x97\x08\xcb\x71\x0b\x66\x57\xe1\xf0\x8a\x77\x29\x7e\x69\xfd\x7f\x42\xfb\x8b\x50\x89\x50\x9b\x00\x74\xa6\x14\xba\x42\xb3\x42\xbe\xcb\x64\x70\x18\x2c\x77\x98\xeb\x84\x39\xa0\x24\x72\x26\x87\xe8\xf7\x9f\x1d\x4a\x98\xc3\xba\xd8\x5f\x85\xe6\x2b\xb1\xf6\x08\x39\x4e\xac\x17\x2c\xdb\x7c\xf5\x4f\xe0\xae\x48\x8c\xce\x12\xf8\x70\xe2\xef\x01\x47\xb5\x16\x9b\x1b\xa6\x3f\x52\x4e\xf5\xf2\x21\x1a\x58\x0b\x09\x94\xfc\xa7\xc0\xb4\x4f\x3f\x9d\x93\x9f\xc5\xa3\xff\x81\x31\x68\x6c\xf3\x9a\x02\x93\x2e\x6d\x5e\x0d\xb0\x64\xd7\xbc\xed\x8f\x83\x9c\xfc\x1c\x5e\xdf\x2e\x96\xe3\xf9\xfd\xe8\xfb\xcd\xe5\x64\xfa\x7c\x42\x84\x8a\x59\x92\x98\x3e\x33\x9a\x11\xa1\x3f\x97\x0f\x7b\x4f\xa4\xa8\x00\x22\x94\x05\x9e\x1b\x68\x7d\x5f\x33\x29\x5d\x6a\x30\xdf\xa4\xdd\x56\x9a\xb5\xcf\xfb\x8d\xa2\x75\x96\x9c\x82\xe3\xa7\x15\x14\xa7\x53\x4c\xe0\x5b\xf1\xb9\xed\xd4\x39\x49\x3e\x9f\xb5\x3e\x18\x90\xc8\x12\x32\xf8\x64\xbb\xb7\xd0\xe1\x4c\x1b\xcc\xc0\xa5\x90\x5b\x42\xff\x3a\xf8\x74\xde\x08\xd6\x68\x1e\x99\x49\x48\xbf\xdc\x89\x2f\x47\xb9\xed\x73\x54\xeb\x66\x09\x67\x3c\x05\x72\xbe\xdf\x81\x44\xd4\xbd\x70\x33\x2d\x19\x4b\x56\x4c\x32\xc5\xf7\xf8\x88\x4c\xa3\x71\x61\xa8\x3c\xb7\x0e\xb3\xd3\x3f\xf5\x3d\x1f\x18\x91\x94\xab\xcb\x0d\xbf\xba\xde\x33\x12\x98\xa3\x94\x63\x5a\xdb\x7d\xa1\x8f\x40\x4b\xdc\x65\xf0\x31\x1e\x3f\x28\xe1\x0b\x1b\x33\xad\xab\x25\xa5\xe2\x61\x61\x97\x86\x23\x9f\xa9\xa3\xe9\x22\xea\x59\x0d\x9c\x16\xec\xb6\x15\x7e\x7f\xdf\x84\x75\x68\x76\xd7\x22\x13\x8e\x12\x8f\xa4\xa7\x01\x07\x9b\x5d\xe9\xc3\xed\x34\x50\x32\x47\x29\x85\xda\xdc\x16\x84\x52\x12\x50\xfb\x0b\xad\x00\xcd\xd8\xd3\xad\x62\x5b\x26\x24\x5b\xf9\xaa\x18\x78\x73\x20\x81\x3b\x34\xe5\x9a\xcc\x13\xe4\x75\x2b\x86\xee\x28\x1c\x64\x5a\x36\x86\xdb\x40\x15\x27\x19\xe8\xbf\x84\x43\x1d\x69\x99\x64\x02\x8d\x70\xbb\xa1\x64\xd6\x4e\x4b\x48\x4a\x48\x63\x5e\xd2\x51\xcc\x8d\x70\x82\x33\x19\x55\x2a\x36\x60\x9c\xe9\xc1\xf9\x14\xd0\xa0\x04\xd3\x26\x65\xff\x8b\xc9\x03\xec\x3c\xe0\x95\xb9\xcb\x24\x41\x65\xbf\x2b\xb9\x8b\x5a\x25\x81\xda\x6b\xa2\xa1\x24\x1a\x3f\x09\xeb\x6c\x74\x64\x40\x61\x02\xb1\xa7\xd8\x03\x62\xe7\xa8\x9c\x41\x19\x6b\xc9\x14\xbc\xd1\x26\x21\xb0\x5e\x03\x77\x94\x44\x53\x5c\xf0\x14\x92\x5c\xc2\xdb\x5d\x66\xcc\x23\xf4\x3b\x7c\x79\x0f\x8b\x20\x21\x8e\x33\x16\x2d\x25\x52\xa8\xfc\xa9\x81\x59\xa3\xc4\xcd\x6e\xa1\x3d\x63\x0e\x51\xf9\x04\xf5\x8d\xb8\x0d\x7a\xc6\x9e\x16\x0f\xf0\x58\xa6\x5c\xfd\xab\x35\xff\xe6\xa3\x0b\x9d\x78\x8a\xf3\xa5\xd1\x5a\xfd\x98\x82\xba\x55\x96\x39\x61\xd7\xa2\xcc\xdf\x11\x4e\xd1\xd5\x31\xb4\x96\x16\x09\x78\x1c\xc7\x0b\x09\xfe\x7a\x9a\x12\xe2\x4f\x94\x09\x05\xa6\xd1\x88\x8f\xf8\xa0\xfc\x89\x8c\x6d\x7c\xea\x9e\xfc\x5c\xdc\x2d\x96\xe3\x9b\xfb\xd1\xf8\xea\xf2\xf6\x7a\x79\x3f\x1f\x7f\x9d\x2c\x96\xf3\xbb\xe7\x13\xc3\x14\x4f\xc1\x9c\x66\xc2\x37\x1f\x48\xe2\xca\x46\xfd\x4f\x07\xfd\xc1\x1f\xfd\xb3\x28\xb4\x39\xcb\xa5\x9c\xa1\x14\x7c\x47\xc9\x64\x3d\x45\x37\x33\x60\xa1\x68\xb4\xe5\x2f\x18\x86\x1a\x18\x3c\x69\x1c\x84\x99\x41\x86\x66\x47\xc9\xe0\x2f\x67\x37\x22\xe8\x0c\xff\xca\xc1\x1e\xae\xe6\x3a\xa7\x64\x70\x76\x96\x75\xda\x08\x4c\x30\xb3\xb1\x94\xfc\x9d\x44\xb1\x6f\x01\xd1\x9f\x49\x14\xb0\x70\xdd\x8a\x23\xf2\x8f\x46\x65\x8b\x32\xcf\xe0\xc6\xd7\x6f\x90\x2c\x35\xb8\x7e\x02\x88\xcb\x45\x2d\xff\x99\x5f\x3f\x63\x2e\xa5\x01\xcf\x07\xb1\xb0\xc4\x57\x34\x25\x7e\xb0\x3a\x36\x5c\x34\x84\xf8\x9d\xf6\xab\x3e\xf2\x6b\x37\xbe\x03\x05\xe1\x34\xf9\x33\x43\xe3\x28\x69\xb5\xd0\xba\xaf\x84\xdb\xd7\x06\x1d\x72\x94\x94\xdc\x8e\x66\xef\xb5\x13\x3b\xae\x3b\x6d\x2d\x87\xaf\xd8\x0a\x1a\x7b\x6d\x2d\x03\x67\x04\xef\xde\x59\xdb\x5a\x31\xd3\x78\xf2\x46\xe5\xe0\xc9\xb5\x33\x88\x49\x89\x8f\x33\x23\xb6\x42\xc2\x06\xc6\x96\x33\x59\x10\x32\xf5\x43\x87\x6d\xa3\xce\x99\x66\x2b\x21\x85\x13\x70\x90\x83\x2c\x49\xc2\x0f\x31\x99\x8e\x97\xf7\x5f\x26\xd3\xd1\xfd\x62\x3c\xff\x31\x19\x8e\x03\x71\x62\x50\x1f\x2a\x30\x29\x3b\x0e\x6e\x8e\xe8\xae\x84\x84\x6a\xba\x0d\x8f\x51\x8a\x2d\x28\xb0\x76\x66\x70\x05\x6d\x7b\xa9\x73\xfa\x2b\xb8\xd0\x85\x2e\xf3\xe5\x60\x84\x24\x55\x3a\x50\x72\x71\x76\x71\x16\x7c\xb6\x3c\x05\x0f\xf2\xb7\xe5\x72\xd6\x12\x08\x25\x9c\x60\x72\x04\x92\xed\x16\xc0\x51\x25\x96\x86\x23\x9c\x06\x23\x30\x69\x64\x83\xb6\xcc\x89\x0c\x30\x77\x7b\x61\x4b\x66\x73\xce\xc1\xda\x65\x6a\xc0\xa6\x28\x93\x50\xba\x66\x42\xe6\x06\x5a\xd2\xf3\x60\x10\x16\xef\x86\x22\x1c\x9f\x5b\x48\x0c\x2e\x06\x1f\x46\xe2\x15\x20\xfe\xf8\x3f\xe3\x90\x28\x5b\x33\xf0\xa8\xbc\x78\x55\x82\x92\x40\xde\x41\x60\xbc\xbe\xda\x84\xb8\x75\xb7\x94\x02\x0a\x07\x99\x3d\x4c\xe9\x62\x24\xa8\x59\xf5\xa0\x93\x95\x47\xd0\x29\xac\x14\x9b\xfb\x42\xa7\xe6\xb1\xf4\x8d\xdc\xf9\x96\xd0\xe2\x23\x22\xf5\xf3\x8a\x67\x05\x26\xab\x1a\x7c\xf1\x56\x58\x5d\x33\x3b\x46\xf3\x56\xcf\x7e\x71\x36\x3f\xba\xa5\xef\xef\x36\x7e\xe6\x28\xf3\x33\xf2\x5c\x18\x75\x88\x2d\x37\x4c\xbf\x78\x5b\x7f\xc3\xa8\x5f\x4f\xb2\xd5\xe4\xda\xb2\xf4\xd6\x4b\x41\x38\xab\x77\xf9\xac\x7c\x4c\x66\xb4\x7d\x4d\x9d\x2e\x9e\x4f\xda\x42\x7b\x20\xbd\xbf\x9e\x2c\x96\xc5\x92\xa6\x79\xc5\x07\xad\x49\xb7\x7b\xce\x61\x87\x8a\x3b\xfa\xcf\x0b\x0a\x65\xe3\x88\x3b\x5a\x8c\x0e\x3b\xd1\xa1\x8a\xd0\x57\x2c\x13\x72\x57\x17\x61\x18\xc0\x64\x76\x75\x79\x33\xb9\xbe\x9b\x7d\xbf\x9e\x0c\xef\x9e\x4f\x7a\xff\x0d\x00\x00\xff\xff\x5c\x1d\xf4\x93\xa9\x13\x00\x00")
+var _corednsYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x57\x5f\x6f\x1b\xb9\x11\x7f\xd7\xa7\x18\x2c\x90\x97\xa2\x6b\x5b\x0d\x72\xf5\xf1\xcd\x27\x39\x89\x50\x5b\x16\x2c\xf9\x80\x43\x51\x18\x14\x77\xa4\x65\xcd\xe5\xb0\x24\x57\xf6\x36\xcd\x77\x2f\xb8\xff\xb4\x2b\xad\x1c\x27\x4d\xb1\x7a\x10\x39\xff\x38\xc3\x99\xdf\x0c\xb9\x91\xbf\xa3\x75\x92\x34\x83\xdd\x78\xf4\x24\x75\xc2\x60\x89\x76\x27\x05\x5e\x09\x41\xb9\xf6\xa3\x0c\x3d\x4f\xb8\xe7\x6c\x04\xa0\x79\x86\x0c\x04\x59\x4c\xb4\xab\xd7\xce\x70\x81\x0c\x9e\xf2\x35\xc6\xae\x70\x1e\xb3\x51\x1c\xc7\xa3\xae\x6a\xbb\xe6\xe2\x8c\xe7\x3e\x25\x2b\xff\xcd\xbd\x24\x7d\xf6\x74\xe9\xce\x24\x9d\xb7\x46\x27\x2a\x77\x1e\xed\x3d\x29\xec\x59\x54\x7c\x8d\xca\x05\xdb\x50\x9a\xb0\x1a\x3d\x96\xa2\x6b\x22\xef\xbc\xe5\xc6\x48\xbd\xad\x6c\xc4\x09\x6e\x78\xae\x7c\x73\x34\x06\xd5\x81\x58\x73\x62\x9b\x2b\x74\x6c\x14\x03\x37\xf2\x93\xa5\xdc\x94\x9a\x63\x88\xa2\x11\x80\x45\x47\xb9\x15\x58\xef\xa1\x4e\x0c\x49\x5d\x2a\x8b\xc1\x55\x41\xa9\x16\x86\x92\xea\x4f\xeb\x7f\x58\xee\xd0\xae\x6b\x59\x25\x9d\x2f\xff\x3c\x73\x2f\xd2\x63\x7b\x89\x74\x82\x76\x68\x8b\x3a\x0e\xaf\x58\x57\xf2\x9b\xda\xff\xa7\x68\xff\x26\x75\x22\xf5\xb6\x17\x74\xae\x35\xf9\x52\xb2\x8e\xfc\x90\xca\xde\x65\xf0\xdc\x53\x6e\x12\xee\x91\x41\xe4\x6d\x8e\xd1\xcf\xbf\x3b\x52\x78\x8f\x9b\xa0\xae\x89\xe6\x2b\xbe\x8e\x00\x8e\x13\xeb\x84\x66\x97\xaf\xff\x89\xc2\x97\x89\x31\x58\x02\x8d\xdc\x77\x27\xfe\x3e\xe0\xa4\x37\x72\x7b\xcb\xcd\x8f\x94\x53\xc3\x3e\x21\x8b\x1b\xa9\x90\xc1\x7f\xca\x5b\x39\x63\x1f\xde\xc3\x97\xf2\x6f\xf8\xa1\xb5\x64\x5d\xbb\x4c\x91\x2b\x9f\xb6\x4b\x8b\x3c\x29\xda\xd5\xfe\x3a\xe0\xdd\x97\xc9\xcd\xc3\x72\x75\x7d\xff\x38\xbd\xbb\xbd\x9a\xcd\x97\x5f\xdf\x81\xd4\x31\x4f\x12\x7b\xc6\xad\xe1\x20\xcd\x2f\xd5\x9f\xbd\x29\x28\x4b\x00\xa4\x76\x28\x72\x8b\x9d\xfd\x0d\x57\xca\xa7\x96\xf2\x6d\x3a\xac\xa5\xe5\xfd\xda\xfe\x4b\xc9\x79\x07\xe7\xe8\xc5\x79\x1d\x8b\xf3\x39\x25\xf8\xb9\xdc\xee\x1a\xf5\x5e\xc1\x2f\x17\x9d\x0d\x8b\x8a\x78\x02\xe3\x0f\x6e\xf8\x08\x03\xc6\x8c\xa5\x0c\x7d\x8a\xb9\x03\xf6\xeb\xf8\xc3\xfb\x96\xb0\x21\xfb\xcc\x6d\x02\x67\xd5\x49\x02\x1a\xa8\xdd\x99\x20\xbd\x69\x59\x04\x17\x29\xc2\xfb\xfd\x09\x14\x91\x69\x17\xd5\x61\x3a\x34\x9e\xac\xb9\xe2\x5a\xec\xe3\x23\x33\x43\xd6\xf7\x5d\x15\xb9\xf3\x94\x9d\xff\xe9\x2c\x00\x82\x95\x49\xc5\xfd\x75\xf4\x4d\xfe\x00\x49\x68\x8f\x72\x8e\x1b\xe3\xf6\x95\x3e\x45\xa3\xa8\xc8\xf0\xc7\x80\xfc\xa0\x86\x2f\x5d\xcc\x8d\xa9\x59\xaa\x42\x38\xac\xec\x50\x18\x0c\xa2\x90\xaa\xd3\xf9\x32\x1a\x39\x83\x22\x48\x5b\xdc\xc9\xd0\x0c\x3e\x4b\xe7\xc9\x16\x37\x32\x93\x9e\x41\x88\x64\xc0\x01\x8f\xdb\x22\x70\x01\xf8\xc2\x20\x83\x7b\x52\x4a\xea\xed\x43\x89\x28\xe5\xbe\xed\xee\xb0\x3a\xa0\x19\x7f\x79\xd0\x7c\xc7\xa5\xe2\xeb\x50\x16\xe3\xa0\x0e\x15\x0a\x4f\xb6\xe2\xc9\x02\x42\xde\x74\x7c\x18\xf6\xc2\x63\x66\x54\xab\xb8\x1b\x28\x80\x7e\x0c\x4e\xc7\xa1\xf1\x34\x7c\xc6\x4a\xb2\xd2\x17\x13\xc5\x9d\x9b\x57\x21\xa9\x20\x22\x16\x55\xa3\x8b\x85\x95\x5e\x0a\xae\xa2\x5a\xc4\xf5\x20\x67\x7e\x70\x3f\xe1\xf3\xa4\xd0\x76\x51\x39\x7c\x31\x3c\x61\x11\x02\x5e\xab\xbb\x4a\x12\xd2\xee\x4e\xab\xa2\x51\x1c\x3e\x32\x41\x92\x2c\x83\xe8\xfa\x45\x3a\xef\xa2\x23\x05\x9a\x12\x8c\x2d\x29\x3c\x40\x76\x41\xda\x5b\x52\xb1\x51\x5c\xe3\x1b\x75\x02\xe0\x66\x83\xc2\x33\x88\xe6\xb4\x14\x29\x26\xb9\xc2\xb7\x9b\xcc\x78\x88\xd0\xcf\xb0\x15\x9c\x5a\xf6\x12\xe2\x38\x63\xc9\x31\x50\x52\xe7\x2f\x35\xdd\x93\x21\x45\xdb\x62\x69\x02\x64\x4e\x48\x87\x04\x0d\x73\x40\x37\xe8\x19\x7f\x59\x3e\xe1\x73\x95\x72\x00\x7d\xc9\xbf\x05\xef\xfa\x46\x02\xc4\x85\xd2\xe8\x70\x3f\xa7\xa8\x1f\xb4\xe3\x5e\xba\x8d\xac\xf2\x77\x4a\x73\xf2\x8d\x0f\x1d\xd6\x32\x01\x8f\xfd\x38\x91\xe0\xaf\xa7\x29\x40\xb8\x51\x2e\x35\xda\x56\x22\x3e\xc2\x83\x06\xae\xf8\x36\xa4\xee\xbb\x2f\xb3\xdb\xab\x4f\xd7\x8f\x93\xbb\xfb\xeb\x69\x68\x10\xfb\xf0\x97\x2c\x8b\x5c\xa9\x05\x29\x29\x0a\x06\xb3\xcd\x9c\xfc\xc2\xa2\x0b\x90\xd3\x70\xf5\x86\x9b\xe6\x53\x01\x03\x7a\x3b\x00\x19\x66\x64\x0b\x06\xe3\xbf\x5e\xdc\xca\x0e\xc5\xe2\xbf\x72\x74\x87\xdc\xc2\xe4\x0c\xc6\x17\x17\xd9\xa0\x8e\x9e\x0a\x6e\xb7\x8e\xc1\xdf\x21\x8a\x03\xa2\x47\x7f\x86\xa8\x07\xaa\x4d\x6b\x8d\xe0\x1f\xad\xc8\x8e\x54\x9e\xe1\x6d\x28\xc7\x8e\xdd\x7d\xac\x42\x47\x8f\x2b\xa6\x96\x0a\x90\x05\xfe\x05\xf7\x29\xeb\xc1\x76\x87\x23\xa4\xd5\x9d\x56\x05\x83\x30\x28\x1d\x2b\x2e\xfb\x41\xfc\x9d\xfa\xeb\xb6\xf0\x6d\x33\xa1\xa1\xf4\xdc\x69\xd3\x61\x41\xd6\x33\xe8\x74\xc4\xa6\x4d\xf4\x8f\x6f\x2c\x79\x12\xa4\x18\x3c\x4c\x17\xdf\xab\x27\xf6\xc2\x0c\xea\x5a\x4d\x5e\xd1\xf5\xeb\x78\x40\x5b\x86\xde\x4a\xe1\xbe\xa9\xad\x1c\x51\x02\x16\x93\xf6\xf8\xe2\xf7\xae\x03\x70\xa5\xe8\x79\x61\xe5\x4e\x2a\xdc\xe2\xb5\x13\x5c\x95\xf8\xca\x60\xc3\x95\xeb\x46\x5d\x70\xc3\xd7\x52\x49\x2f\xfb\x39\x0c\xc0\x93\xa4\xbf\x11\xc3\xfc\x7a\xf5\xf8\xdb\x6c\x3e\x7d\x5c\x5e\xdf\xff\x3e\x9b\x5c\xf7\xc8\x89\x25\x73\x28\xc0\x95\x1a\xb8\xb8\x7b\x22\xff\x51\x2a\xac\xa7\xd5\xfe\x35\x2a\xb9\x43\x8d\xce\x2d\x2c\xad\xdb\x7e\x18\x7e\xa9\xf7\xe6\x13\xf6\xdc\x04\x30\x55\x3e\x1e\x8c\x84\x4d\x3a\x30\xb8\xbc\xb8\xec\x8e\x55\x00\x4e\xa4\x18\xae\xfe\xf3\x6a\xb5\x8f\x24\x80\xd4\xd2\x4b\xae\xa6\xa8\x78\xb1\x44\x41\x3a\x71\xac\x3f\x91\x19\xb4\x92\x92\x96\x36\xee\xd2\xbc\xcc\x90\x72\xbf\x27\x76\x68\x2e\x17\x02\x9d\x5b\xa5\x16\x5d\x4a\x2a\xe9\x53\x37\x5c\xaa\xdc\x62\x87\xba\xcf\x87\x50\x4e\xf2\xbb\x43\xd1\x1f\x87\x3b\x91\x18\x5f\x8e\x7f\x38\x12\xaf\x04\xe2\x2f\xff\xe7\x38\x24\xda\x35\x08\x3c\xad\x1e\xc1\x35\xa1\x02\x10\xc7\x8e\x71\xe6\x04\xc0\x88\xe6\xa9\xd2\x8f\xdb\x70\x87\x08\x9f\xf4\x98\x1d\x14\x45\xdd\xe1\x1b\x54\xed\xd1\x9a\x2b\x18\x24\xd6\x82\xed\xf8\x3f\x28\x79\x4c\x7d\x23\x76\xbe\xc5\xb5\xf8\x08\x48\xc3\xf8\x11\x50\x81\xab\x1a\x4a\x4f\xbe\xf2\xea\x67\xe3\xc0\xa4\xdd\x69\xc1\x27\x47\xed\xa3\x57\xf7\xfe\xa9\x12\x46\x88\x2a\x3f\xa3\x80\x85\xd1\x00\xd9\x09\xcb\xcd\xc9\xd7\xf7\xe0\x28\xd0\x1f\x51\x9a\xc1\xb4\x1e\x44\x3b\x9a\xde\x3a\xe3\xf7\x47\xef\x21\x9b\xb5\x8d\xd9\x82\x75\x9f\x9d\x61\xa2\xe8\x12\xdd\x01\xf5\xf1\x66\xb6\x5c\x95\x2c\x6d\xf3\x6a\x2e\xbc\x71\xc5\x74\x7b\xce\xbe\x0f\x54\x1d\x2a\x1e\xe8\x3f\x27\x04\x56\x93\xae\x40\xb7\xc5\x98\x7e\x27\x3a\x14\x91\xe6\x23\xcf\xa4\x2a\x9a\x22\xec\x3b\x30\x5b\x7c\xbc\xba\x9d\xdd\xfc\xb1\xb8\xbb\x99\x4d\xfe\xf8\xfa\x6e\xf4\xdf\x01\x00\x88\x37\xc2\xe8\x79\x13\x00\x00")
 
 func corednsYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -132,7 +137,7 @@ func corednsYaml() (*asset, error) {
 	return a, nil
 }
 
-var _localStorageYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x56\x5f\x6f\xdb\x36\x10\x7f\xd7\xa7\xb8\x69\xcb\xcb\x50\xca\x09\x0a\x2c\x03\xdf\xbc\xd8\x69\x03\x38\xb6\x61\xbb\x1d\x8a\xa2\x30\x68\xea\x6c\xb3\xa1\x48\x82\xa4\xdc\x7a\x59\xbe\xfb\x40\x52\x76\xe4\x24\x4d\x1c\x6c\xd3\x8b\xa0\xe3\xdd\xef\x8e\xf7\xbb\x3f\x62\x46\x7c\x44\xeb\x84\x56\x14\x36\x67\xd9\x8d\x50\x25\x85\x29\xda\x8d\xe0\xd8\xe5\x5c\xd7\xca\x67\x15\x7a\x56\x32\xcf\x68\x06\xa0\x58\x85\x14\xa4\xe6\x4c\x12\xc3\xfc\x9a\x18\xab\x37\x22\xd8\xa3\x25\x2e\xd9\x11\xd6\x18\x26\x75\x67\x18\x47\x0a\x37\xf5\x02\x89\xdb\x3a\x8f\x55\x46\x08\xc9\xda\x9e\xed\x82\xf1\x82\xd5\x7e\xad\xad\xf8\x8b\x79\xa1\x55\x71\xf3\xbb\x2b\x84\xee\xec\x63\xba\x90\xb5\xf3\x68\x27\x5a\xe2\xf1\x01\xd9\xa0\x6d\x6b\x89\x8e\x66\x04\x98\x11\xef\xac\xae\x8d\xa3\xf0\x39\xcf\xbf\x64\x00\x16\x9d\xae\x2d\xc7\x28\x51\xba\x44\x97\xbf\x81\xdc\x84\xb0\x9c\x47\xe5\x37\x5a\xd6\x15\x72\xc9\x44\x15\x4f\xb8\x56\x4b\xb1\xaa\x98\x49\x7a\xba\x74\x1d\xa9\x57\x11\x6a\x83\x76\x11\x61\x56\xe8\xc3\xa1\x14\x2e\xbe\xbf\x31\xcf\xd7\xf9\x97\x97\xdd\xa3\x2a\x8d\x16\xca\x3f\x19\xc2\xde\xdf\xa1\xaf\x5f\x8f\x02\xde\x60\x40\x3d\x30\xe4\x16\x99\xc7\x08\xfa\x74\x7c\xce\x6b\xcb\x56\xd8\xd0\xf0\x18\xb4\x39\xe7\x92\x39\x87\xee\xb8\x0c\xfc\x2b\xd2\xff\x10\xaa\x14\x6a\x75\x3c\xf7\x0b\xa1\xca\x2c\x14\xc0\x04\x97\x41\x79\x77\xbd\x67\x1c\x67\x00\x8f\x8b\xed\x98\x12\x73\xf5\xe2\x2b\x72\x1f\xab\xec\xc9\x16\xfa\xbf\x1a\x87\x19\xe3\xee\xd3\xd5\x43\x23\xf5\xb6\xc2\x57\xf4\xec\x8f\x5d\x39\x83\x9c\x46\xda\x93\xee\x7b\x11\x38\xdf\x0e\x44\x25\x3c\x85\xd3\x0c\xc0\x79\xcb\x3c\xae\xb6\x41\x0b\xc0\x6f\x0d\x52\x98\x68\x29\x85\x5a\x7d\x30\x25\xf3\x18\xe5\xb6\x2d\x49\xaa\x00\x15\xfb\xfe\x41\xb1\x0d\x13\x92\x2d\x24\x52\x38\x0b\x70\x28\x91\x7b\x6d\x93\x4e\x15\xaa\x66\xc0\x16\x28\xdd\xce\x88\x19\xf3\xcc\x35\x3c\x56\x46\xee\x5d\xb4\xef\x1f\x1e\x79\x80\xf4\x12\x16\xc0\xee\xf6\xe1\x31\x56\x68\x2b\xfc\xf6\x22\x14\xfb\x30\x26\x33\x4f\x49\x22\x61\x66\x10\x6e\x85\x17\x9c\xc9\xbc\xd1\x77\x07\xdc\x0f\x5f\x47\x7c\x4c\xa5\x96\x68\x63\x61\xb6\x22\x06\x20\x70\x83\x5b\x0a\xf9\x45\xe3\xaf\x5b\x96\x5a\xb9\x91\x92\xdb\xbc\xa5\x05\xa0\x4d\xb0\xd6\x96\x42\xde\xff\x2e\x9c\x77\xf9\x13\x20\x31\xf2\x50\xbc\x45\x20\xdd\x2a\xf4\x18\x7b\x8f\x6b\xe5\xad\x96\xc4\x48\xa6\xf0\x15\xb8\x00\xb8\x5c\x22\xf7\x14\xf2\xa1\x9e\xf2\x35\x96\xb5\xc4\xd7\x38\xae\x58\x68\xb9\xff\xca\x63\xb8\x06\x13\x0a\xed\x3e\x83\xe4\xa5\x3e\x48\x8f\xa8\xd8\x2a\x10\x7c\x72\x3b\xfd\x34\x9d\xf5\xaf\xe7\xbd\xfe\x65\xf7\xc3\x60\x36\x9f\xf4\xdf\x5d\x4d\x67\x93\x4f\x77\x27\x96\x29\xbe\x46\xdb\x79\x1a\x89\x6e\x4e\x8b\xd3\xe2\xed\x59\x7e\x08\x39\xae\xa5\x1c\x6b\x29\xf8\x96\xc2\xd5\x72\xa8\xfd\xd8\xa2\xc3\x3d\xe5\x21\xe2\xaa\x62\xaa\xbc\x27\x9c\xbc\x14\x2a\x01\xe7\x99\xf5\xad\x6f\x42\xd2\x86\x6a\x89\x3a\xe8\x79\x27\x49\x9b\x57\xf1\xd5\x69\xb5\xd7\x48\xfb\xe5\x3a\x54\x9f\x6b\xfb\x4e\xc9\x4a\x16\x24\x29\xb5\x72\x5f\x05\xfd\x31\xf3\x6b\x7a\xe0\x60\xaf\x81\x6a\xf3\x18\x6c\x3c\xea\xcd\x87\xdd\xeb\xfe\x74\xdc\xbd\xe8\xb7\xc0\x36\x4c\xd6\x78\x69\x75\x45\x0f\xd8\x5d\x0a\x94\x65\x33\xbc\x1f\xc9\x93\xef\x5d\x97\x17\xfb\x19\x96\xb5\x6f\xf5\x8a\x0b\x25\xf9\x35\x33\x87\xde\x1e\x95\x4c\x93\xdf\x87\x73\xf8\x70\x5d\xde\x4f\xe4\x69\x92\xc7\xc9\xf1\xec\x4c\x0e\x0b\x4a\x29\xed\xdb\x5d\x5f\xe2\x92\xd5\xd2\x7f\x8c\xb1\xce\xe2\x78\xcd\xa3\x45\x2a\xad\xf6\x0a\x7e\xd0\x4b\xc2\x91\xc6\x98\xc4\x63\x0a\xb9\xb7\x35\xe6\x59\xbb\x4e\xa1\xa9\xe3\x60\xd0\x0a\x24\xa5\xa6\x59\xb7\xd7\xba\x44\x0a\x7f\x32\xe1\x2f\xb5\xbd\x14\xd6\xf9\x0b\xad\x5c\x5d\xa1\xcd\x6c\xfa\x2f\xda\xd5\x74\x0f\x25\x7a\x8c\x89\x69\x76\xe8\x2e\xa3\xd9\x83\x7f\xcc\x67\x57\xd3\xbe\x7e\x7f\xb0\x95\x76\x86\xad\x52\xa6\xf0\x37\x89\x09\xb9\x6d\xa8\x8b\x23\x26\x14\xc8\x35\x33\x39\xfd\xdc\x48\x6f\xf7\xc4\xc6\xf3\x9c\xe6\xbb\xce\x1e\x77\x67\xef\xe7\x97\xa3\xc9\x7c\x38\x1a\xce\x07\x57\xd3\x59\xbf\x37\x1f\x8e\x7a\xfd\x69\xfe\xe6\xde\x26\x44\xe7\x72\xfa\x39\x3f\xb9\xdd\xd9\x0d\x46\x17\xdd\xc1\x7c\x3a\x1b\x4d\xba\xef\xfa\x11\xe5\xee\x24\xfe\x09\x85\xe7\xae\x79\xa7\xef\xbb\xb8\xdf\x7c\xf8\xfb\x68\x82\xfd\xf9\xa7\xce\x42\xa8\x8e\x5b\x27\x2e\xd1\x03\xc1\x3a\xad\xae\x9b\x52\x58\x20\x15\x9c\x9e\x9f\x9f\x03\x31\x90\xff\x72\xfb\x71\x34\x98\xf7\xae\x26\x77\x89\x79\xbe\xae\x74\x09\xe7\xa7\xa7\xed\xa3\x4e\x51\xe4\x71\x0d\x32\x5b\xea\x6f\xea\x08\x47\xb6\x02\x62\x97\x0f\xe1\xd7\x28\x0d\xda\xb1\x2e\x8b\x2d\xab\xe4\x1e\xe6\x01\x89\x41\x94\x78\x1e\xeb\xf2\xc9\x8d\x9b\xa8\x4d\x68\xc4\x34\x4a\xed\xb5\xfa\xe3\x11\xfd\xc0\x08\x5e\x37\x96\x2b\x61\xad\xb6\x58\x12\x29\x16\x96\xd9\x2d\x59\xd4\x6e\xbb\xd0\xdf\xe9\x59\xf1\xf6\xb7\xe2\xe8\xb9\xfc\x4f\x00\x00\x00\xff\xff\xd7\x27\x39\x0d\x1a\x0d\x00\x00")
+var _localStorageYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x56\x4b\x8f\x22\xb7\x13\xbf\xf7\xa7\xa8\x7f\xff\x33\x97\x68\x0d\xb3\xa7\x89\xfa\x46\x80\xd9\x45\xe2\x25\x98\x9d\x1c\x56\x2b\x64\xdc\x05\x78\xc7\x2f\xd9\x6e\x76\xc9\x64\xbe\x7b\x64\x77\xd3\x34\x30\x0f\x46\x49\xe4\x43\x63\xbb\xea\x57\x3f\xd7\x13\x6a\xf8\x3d\x5a\xc7\xb5\xca\x60\xfb\x31\x79\xe0\x2a\xcf\x60\x8e\x76\xcb\x19\x76\x18\xd3\x85\xf2\x89\x44\x4f\x73\xea\x69\x96\x00\x28\x2a\x31\x03\xa1\x19\x15\xc4\x50\xbf\x21\xc6\xea\x2d\x0f\xfa\x68\x89\x2b\xf5\x08\xad\x14\x4b\x71\x67\x28\xc3\x0c\x1e\x8a\x25\x12\xb7\x73\x1e\x65\x42\x08\x49\x9a\x96\xed\x92\xb2\x16\x2d\xfc\x46\x5b\xfe\x27\xf5\x5c\xab\xd6\xc3\x6f\xae\xc5\x75\xbb\xe6\xd4\x15\x85\xf3\x68\x67\x5a\xe0\xe5\x84\x6c\x90\xb6\x85\x40\x97\x25\x04\xa8\xe1\x9f\xac\x2e\x8c\xcb\xe0\x6b\x9a\x7e\x4b\x00\x2c\x3a\x5d\x58\x86\xf1\x44\xe9\x1c\x5d\xfa\x01\x52\x13\x68\x39\x8f\xca\x6f\xb5\x28\x24\x32\x41\xb9\x8c\x37\x4c\xab\x15\x5f\x4b\x6a\xe2\xce\xe8\xdc\xb5\x85\x5e\x47\xa8\x2d\xda\x65\x84\x59\xa3\x0f\x97\x82\xbb\xf8\xfd\x41\x3d\xdb\xa4\xdf\xde\x36\x8f\x2a\x37\x9a\x2b\xff\x2c\x85\xda\xde\xb1\xad\x5f\x2f\x02\xde\xa2\xf2\x27\x8a\xcc\x22\xf5\x18\x41\x9f\xe7\xe7\xbc\xb6\x74\x8d\x55\x18\xce\x41\xab\x7b\x26\xa8\x73\xe8\x2e\xf3\xc0\x3f\x0a\xfa\xef\x5c\xe5\x5c\xad\x2f\x8f\xfd\x92\xab\x3c\x09\x09\x30\xc3\x55\xc8\xdc\xfd\xf3\x5e\x31\x9c\x00\x9c\x27\xdb\x25\x29\xe6\x8a\xe5\x77\x64\x3e\x66\xd9\xb3\x25\xf4\x5f\x15\x0e\x35\xc6\x1d\xdc\xd5\x43\x23\xf4\x4e\xe2\x3b\x6a\xf6\x65\x53\xce\x20\x0b\x7e\xb3\x58\xd2\xfc\xcc\x43\xcc\x77\x43\x2e\xb9\xcf\xe0\x3a\x01\x70\xde\x52\x8f\xeb\x5d\x90\x02\xf0\x3b\x83\x19\xcc\xb4\x10\x5c\xad\xbf\x98\x9c\xfa\xe0\x3b\x00\xdb\x3c\x29\x45\x01\x24\xfd\xf9\x45\xd1\x2d\xe5\x82\x2e\x05\x66\xf0\x31\xc0\xa1\x40\xe6\xb5\x2d\x65\x64\xc8\xcb\x21\x5d\xa2\x70\x7b\x25\x6a\xcc\x2b\xcf\xf0\x28\x8d\xa8\x4d\x34\xdf\x1f\x96\x38\x42\x7a\x0b\x0b\x60\xff\xfa\xb0\x8c\xe5\xda\x72\xbf\xeb\x86\x64\x1f\x47\x67\xa6\x65\x23\x23\xa1\x67\x10\x66\xb9\xe7\x8c\x8a\xb4\x92\x77\x47\xb1\x1f\xbf\x2f\xf0\x01\xc1\x6b\x81\x36\x56\x44\x83\x31\x00\x81\x07\xdc\x65\x90\x76\x2b\x7b\x9d\x3c\xd7\xca\x4d\x94\xd8\xed\x2d\x97\x4b\x9b\xa0\xad\x6d\x06\x69\xff\x27\x77\xde\xa5\xcf\x80\x44\xe6\xa1\x3c\x5a\x21\xe8\x56\xa1\xc7\x58\x7b\x4c\x2b\x6f\xb5\x20\x46\x50\x85\xef\xc0\x05\xc0\xd5\x0a\x99\xcf\x20\x1d\xeb\x39\xdb\x60\x5e\x08\x7c\x8f\x61\x49\x43\x7f\xff\xb7\x2c\x86\x67\x50\xae\xd0\xd6\x1e\x24\x6f\xd5\x41\xb9\xb8\xa4\xeb\x10\xe0\xab\xc7\xc1\xa8\xf3\xa9\xbf\x18\x4e\xba\x9d\xe1\x62\xda\xb9\xfb\xbc\x98\xce\x26\xf7\x83\xf9\x60\x32\xee\xcf\x9e\xae\x0e\x4c\xa2\xc6\xb4\x10\x62\xaa\x05\x67\xbb\x0c\x06\xab\xb1\xf6\x53\x8b\x0e\xeb\x88\x06\x42\x52\x52\x95\x1f\xe2\x49\xde\x62\x42\xc0\x79\x6a\x0f\x08\x04\x08\x29\x07\x50\xe3\xa8\x8d\x9e\xb5\xcb\xd3\xea\xd3\xfa\xee\xb4\xaa\x25\xca\x09\x36\x0a\xc9\xd5\xc8\xa5\xbd\x2f\x4a\x0d\x52\x0a\xd5\xb7\x00\x32\xc8\x4f\xa9\xdf\x64\x47\x06\x6a\x09\x54\xdb\x73\xb0\xe9\xa4\xb7\x18\x77\x46\xfd\xf9\xb4\xd3\xed\xd7\xb7\x00\x5b\x2a\x0a\xbc\xb5\x5a\x1e\x54\xc2\x5a\x71\x14\x79\xd5\x9b\x9b\x2b\x9e\x97\xb6\xf7\x45\xdc\xaa\x5b\x54\x25\x5b\x0d\xc5\x73\x0e\x2f\x3d\xa8\x3c\x1f\x51\x73\x6c\xed\x2c\x23\x2a\xff\x9e\xb6\xd9\xe3\x69\x78\x68\xb8\xf3\xf2\x3c\x36\x86\x57\x5b\x6e\x98\x3f\x4a\x69\xdf\x2c\xea\x1c\x57\xb4\x10\xfe\x3e\x72\xbd\x8b\xdd\x33\x8d\x54\xca\xd4\x6a\x4e\xd8\x93\x52\xe1\x8e\x54\xca\x24\x0e\xe0\x0c\x52\x6f\x0b\x4c\x93\x46\x1a\x65\x60\xa9\x62\x1b\xb4\xa1\xa8\x1b\x44\x4a\xd7\x55\xd3\x74\xa4\x73\xcc\xe0\x0f\xca\xfd\xad\xb6\xb7\xdc\x3a\xdf\xd5\xca\x15\x12\x6d\x62\x83\x65\x2e\xf7\x39\xdd\x43\x81\x1e\xe3\x1f\xb7\x6a\x44\xee\x3d\x7a\xe4\xa8\xed\xc7\xd7\x27\x4f\x9d\xbf\x2f\x0c\x9d\xbd\x62\x23\x95\x33\xf8\x8b\x44\x87\x3c\x56\xa1\x8b\x1d\x24\x24\xc8\x88\x9a\x34\xfb\x5a\x9d\xee\x6f\xab\xfb\x34\x4b\x7b\xfd\xdb\xce\x97\xe1\x5d\x59\xbb\xb7\x93\xd9\x62\x3c\x19\x2f\x86\x83\xf9\x5d\xbf\xb7\x18\x4f\x7a\xfd\x79\xfa\xe1\xa0\x13\x7c\xe3\xd2\xec\x6b\x7a\xf5\xb8\xd7\x2b\x2b\x7f\x7e\x37\x99\x85\x3e\x10\x50\x9e\xae\xe2\x1f\x9d\xa0\xf1\x54\x7d\xcb\x7d\xd8\x39\xf4\x85\xa9\xc9\xfe\xff\x7f\xed\x25\x57\x6d\xb7\x89\x3b\x87\x1e\x08\x16\xf1\xb7\x7c\xc8\xb9\x05\x22\xe1\xfa\xe6\xe6\x06\x88\x81\xf4\x97\xc7\xfb\xc9\x70\xd1\x1b\xcc\x9e\xca\xc8\xb3\x8d\xd4\x39\xdc\x5c\x5f\x37\xaf\xda\xad\x56\xb8\xf5\x48\x6d\xae\x7f\xa8\x0b\x0c\x59\x09\xc4\xae\x4e\xe1\x37\x28\x0c\xda\xa9\xce\x5b\x3b\x2a\x45\x0d\x73\x12\xc4\x40\xa3\x8c\xf3\x54\xe7\xcf\x0e\xd4\x10\xc0\xac\x42\x23\x46\xe7\x67\x53\xf3\xe5\x0e\x7c\xa2\xf4\x46\xd7\xfd\xdc\x1f\x4e\xdf\xd3\x70\xff\x1e\x00\x63\xc9\x04\x7f\xd2\x0c\x00\x00")
 
 func localStorageYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -152,7 +157,7 @@ func localStorageYaml() (*asset, error) {
 	return a, nil
 }
 
-var _metricsServerAggregatedMetricsReaderYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9c\xcf\x31\x6b\xf4\x30\x0c\xc6\xf1\xdd\x9f\x42\x78\x7e\x93\x97\x6e\xc5\x6b\x87\xee\x1d\xba\x94\x1b\x94\xf8\x21\x27\xce\xb1\x83\x24\xe7\x68\x3f\x7d\xb9\x70\xdc\x58\x68\x27\x0d\x7f\x7e\x0f\xe8\x22\x35\x27\x7a\x29\xdd\x1c\xfa\xd6\x0a\x02\x6f\xf2\x0e\x35\x69\x35\x91\x4e\x3c\x8f\xdc\xfd\xdc\x54\xbe\xd8\xa5\xd5\xf1\xf2\x6c\xa3\xb4\xff\xfb\x53\x58\xe1\x9c\xd9\x39\x05\xa2\xca\x2b\x12\xd9\xa7\x39\xd6\xc4\xcb\xa2\x58\xd8\x91\x87\x15\xae\x32\xdb\xa0\xe0\x0c\x0d\x44\x85\x27\x14\xbb\x11\xfa\x61\xfd\xb1\x30\x78\x1b\x76\xc1\x35\x51\x74\xed\x88\xbf\x71\xc8\xe2\x7f\x71\x9c\x57\xa9\x0f\xa8\xbd\xc0\x52\x18\x88\x37\x79\xd5\xd6\x37\x4b\xf4\x11\xef\x7f\xdd\x7d\x3c\x05\x22\x85\xb5\xae\x33\x8e\xbe\xb5\x6c\xf1\x1f\xc5\xda\x32\xec\xc8\x3b\x74\x3a\xd2\x02\xbf\x95\x22\x76\xdc\x2b\xfb\x7c\x8e\xa7\xf0\x1d\x00\x00\xff\xff\xe5\x1d\x7a\x17\x89\x01\x00\x00")
+var _metricsServerAggregatedMetricsReaderYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9c\xcf\x31\x6b\xf4\x30\x0c\xc6\xf1\xdd\x9f\x42\x78\x7e\x93\x97\x6e\xc5\x6b\x87\xee\x1d\xba\x94\x1b\x94\xf8\x21\x27\xce\xb1\x83\x24\xe7\x68\x3f\x7d\xb9\x70\xdc\x58\x68\x27\x0d\x7f\x7e\x0f\xe8\x22\x35\x27\x7a\x29\xdd\x1c\xfa\xd6\x0a\x02\x6f\xf2\x0e\x35\x69\x35\x91\x4e\x3c\x8f\xdc\xfd\xdc\x54\xbe\xd8\xa5\xd5\xf1\xf2\x6c\xa3\xb4\xff\xfb\x53\x58\xe1\x9c\xd9\x39\x05\xa2\xca\x2b\x12\xd9\xa7\x39\xd6\xc4\xcb\xa2\x58\xd8\x91\x87\x15\xae\x32\xdb\xa0\xe0\x0c\x0d\x44\x85\x27\x14\xbb\x11\xfa\x61\xfd\xb1\x30\x78\x1b\x76\xc1\x35\x51\x74\xed\x88\xbf\x71\xc8\xe2\x7f\x71\x9c\x57\xa9\x0f\xa8\xbd\xc0\x52\x18\x88\x37\x79\xd5\xd6\x37\x4b\xf4\x11\xef\x7f\xdd\x7d\x3c\x05\x22\x85\xb5\xae\x33\x8e\xbe\xb5\x6c\xf1\x1f\xc5\xda\x32\xec\xc8\x3b\x74\x3a\xd2\x02\xbf\x95\x22\x76\xdc\x2b\xfb\x7c\x8e\xa7\xf0\x3d\x00\xe5\x1d\x7a\x17\x89\x01\x00\x00")
 
 func metricsServerAggregatedMetricsReaderYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -172,7 +177,7 @@ func metricsServerAggregatedMetricsReaderYaml() (*asset, error) {
 	return a, nil
 }
 
-var _metricsServerAuthDelegatorYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x8e\x31\x4e\xc4\x40\x0c\x45\xfb\x39\xc5\x5c\x60\x82\xe8\x90\x3b\xa0\xa0\x5f\x24\x7a\x67\xf2\x59\x4c\x92\x71\x64\x7b\x22\xc1\xe9\xd1\x8a\x88\x06\xd8\xf6\x4b\xff\xbd\x57\x4a\x49\xbc\xc9\x0b\xcc\x45\x1b\x65\x1b\xb9\x0e\xdc\xe3\x4d\x4d\x3e\x39\x44\xdb\x30\xdf\xf9\x20\x7a\xb3\xdf\xa6\x59\xda\x44\xf9\x71\xe9\x1e\xb0\x93\x2e\x78\x90\x36\x49\x3b\xa7\x15\xc1\x13\x07\x53\xca\xb9\xf1\x0a\xca\x2b\xc2\xa4\x7a\x71\xd8\x0e\x23\xff\xf0\xc0\x4a\x17\x70\x99\xb0\xe0\xcc\xa1\x96\x4c\x17\x9c\xf0\x7a\x79\xf1\x26\x4f\xa6\x7d\xbb\x52\x90\x72\xfe\x15\xf0\xe3\xfb\x5b\xe0\x7d\x7c\x47\x0d\xa7\x54\x8e\xef\x33\x6c\x97\x8a\xfb\x5a\xb5\xb7\xf8\x27\xf7\x98\x7d\xe3\x0a\xca\x73\x1f\x51\xbe\xf9\xe9\x2b\x00\x00\xff\xff\xa5\xb5\x26\x22\x2f\x01\x00\x00")
+var _metricsServerAuthDelegatorYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x8e\x31\x4e\xc4\x30\x10\x45\x7b\x9f\xc2\x17\x70\x10\x1d\x72\x07\x14\xf4\x8b\x44\x3f\x71\x3e\xcb\x90\xd8\x63\xcd\x8c\x23\x2d\xa7\x47\x2b\x45\x34\xc0\xb6\x5f\x7a\xff\xbd\x94\x52\xa0\xce\x6f\x50\x63\x69\x39\xea\x4c\x65\xa2\xe1\x1f\xa2\xfc\x45\xce\xd2\xa6\xf5\xc1\x26\x96\xbb\xfd\x3e\xac\xdc\x96\x1c\x9f\xb7\x61\x0e\x3d\xc9\x86\x27\x6e\x0b\xb7\x73\xa8\x70\x5a\xc8\x29\x87\x18\x1b\x55\xe4\x58\xe1\xca\xc5\x92\x41\x77\x68\xb6\x8b\x39\x6a\xbe\x1e\xa7\x05\x1b\xce\xe4\xa2\x41\x65\xc3\x09\xef\x57\x8a\x3a\xbf\xa8\x8c\x7e\xa3\x20\xc4\xf8\x2b\xe0\xc7\xf7\xb7\xc0\xc6\xfc\x89\xe2\x96\x43\x3a\xd8\x57\xe8\xce\x05\x8f\xa5\xc8\x68\xfe\x4f\xee\x31\x5b\xa7\x82\x1c\xd7\x31\x23\xd9\xc5\x1c\x35\x7c\x0f\x00\xa5\xb5\x26\x22\x2f\x01\x00\x00")
 
 func metricsServerAuthDelegatorYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -192,7 +197,7 @@ func metricsServerAuthDelegatorYaml() (*asset, error) {
 	return a, nil
 }
 
-var _metricsServerAuthReaderYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x8f\x3d\x4e\x04\x31\x0c\x46\xfb\x9c\x22\x17\xf0\x22\x3a\x94\x0e\x1a\xfa\x45\xa2\xf7\x64\x3e\xc0\xcc\x8e\x13\xd9\xce\x08\x38\x3d\x1a\xb4\xfc\x34\x4b\x6f\xbf\xef\x3d\x22\x4a\xdc\xe5\x11\xe6\xd2\xb4\x64\x9b\xb8\x1e\x78\xc4\x4b\x33\xf9\xe0\x90\xa6\x87\xe5\xc6\x0f\xd2\xae\xb6\xeb\xb4\x88\xce\x25\x1f\xdb\x09\x77\xa2\xb3\xe8\x73\x5a\x11\x3c\x73\x70\x49\x39\x2b\xaf\x28\x79\x45\x98\x54\x27\x87\x6d\x30\xda\x51\x64\xe0\x19\x76\x3e\xf1\xce\x15\x25\x2f\x63\x02\xf9\xbb\x07\xd6\x64\xed\x84\x23\x9e\x76\x08\x77\xb9\xb7\x36\xfa\x3f\x26\x29\xe7\x5f\x91\x9f\x5d\xbc\x05\x74\x6f\x20\xee\xf2\x67\x1c\x1a\x52\xbf\xde\xbf\x35\x7c\x4c\xaf\xa8\xe1\x25\xd1\x19\xf4\x00\xdb\xa4\xe2\xb6\xd6\x36\x34\x2e\xa4\x5c\xd6\xff\x0c\x00\x00\xff\xff\x2a\x39\xe6\xe4\x44\x01\x00\x00")
+var _metricsServerAuthReaderYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x8f\xbb\x4e\x04\x31\x0c\x45\xfb\x7c\x45\x7e\xc0\x8b\xe8\x50\x3a\x68\xe8\x17\x89\xde\x93\xb9\x80\x99\x1d\x27\xb2\x9d\x11\xf0\xf5\x68\xd0\xf2\x68\x96\xfe\xea\xdc\x73\x88\x28\x71\x97\x47\x98\x4b\xd3\x92\x6d\xe2\x7a\xe0\x11\x2f\xcd\xe4\x83\x43\x9a\x1e\x96\x1b\x3f\x48\xbb\xda\xae\xd3\x22\x3a\x97\x7c\x6c\x27\xdc\x89\xce\xa2\xcf\x69\x45\xf0\xcc\xc1\x25\xe5\xac\xbc\xa2\xe4\x15\x61\x52\x9d\x1c\xb6\xc1\x68\x47\x91\x81\x67\xd8\x79\xe2\x9d\x2b\x4a\x5e\xc6\x04\xf2\x77\x0f\xac\xc9\xda\x09\x47\x3c\xed\x10\xee\x72\x6f\x6d\xf4\x7f\x4c\x52\xce\xbf\x22\x3f\xbf\x78\x0b\xe8\xde\x40\xdc\xe5\xcf\x39\x34\xa4\x7e\x85\x7c\x6b\xf8\x98\x5e\x51\xc3\x4b\xa2\x33\xe8\x01\xb6\x49\xc5\x6d\xad\x6d\x68\x5c\x48\xb9\xac\xff\x39\x00\x2a\x39\xe6\xe4\x44\x01\x00\x00")
 
 func metricsServerAuthReaderYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -212,7 +217,7 @@ func metricsServerAuthReaderYaml() (*asset, error) {
 	return a, nil
 }
 
-var _metricsServerMetricsApiserviceYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x44\x8e\x4d\x6a\xc4\x30\x0c\x85\xf7\x3e\x85\x2e\xe0\x74\xb2\x2b\xde\x75\x59\x68\x61\x20\x65\xf6\x1a\x8f\x3a\x88\xe0\x1f\x24\xd9\x90\xdb\x97\x30\x71\xba\x13\x7a\xef\xfb\x24\xef\xbd\xc3\xca\x37\x12\xe5\x92\x03\x60\x65\xa1\x27\xab\x09\x1a\x97\x3c\xad\xef\x3a\x71\x79\xeb\xb3\x5b\x39\x3f\x02\x7c\x5c\x3f\x17\x92\xce\x91\x5c\x22\xc3\x07\x1a\x06\x07\x90\x31\x51\x80\x3e\xdf\xc9\x70\x9e\x12\x99\x70\xd4\x03\x76\x5a\x29\xee\x25\x7d\x81\xfb\x38\x88\xa3\xe9\xf7\x88\xe4\x0c\xb4\x62\xa4\x00\x6b\xbb\x93\xd7\x4d\x8d\x92\x03\x78\x4a\x69\xf5\x44\x86\x1c\xa0\x8f\xdf\x8f\xf3\x0e\x80\xb3\x52\x6c\x42\xcb\xca\xf5\xe7\x6b\xb9\x91\xf0\xef\x16\xc0\xa4\xd1\x10\x5d\x85\x8b\xb0\x6d\xdf\x9c\x39\xb5\x14\x60\xbe\x5c\xfe\x65\x23\x7d\xad\xff\x02\x00\x00\xff\xff\x14\x74\xa9\x1b\x25\x01\x00\x00")
+var _metricsServerMetricsApiserviceYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x5c\x8e\x4d\x6a\xc4\x30\x0c\x46\xf7\x3e\x85\x2e\x90\x34\xde\x15\xed\xba\x2c\xb4\x30\x90\x32\x7b\x8d\x47\x1d\x44\xf0\x0f\x92\x1c\xc8\xed\x4b\x68\xd2\xc2\xec\x0c\xef\x7b\xcf\x1a\x86\x21\x50\x93\x2b\xab\x49\x2d\x08\xd4\x44\xf9\x21\xe6\x4a\x2e\xb5\x8c\xcb\xab\x8d\x52\x5f\xd6\x18\x16\x29\x77\x84\xb7\xcb\xfb\xcc\xba\x4a\xe2\x90\xd9\xe9\x4e\x4e\x18\x00\x0a\x65\x46\x58\xe3\x8d\x9d\xe2\x98\xd9\x55\x92\x1d\x72\xb0\xc6\x69\x1f\xd9\xaf\xb8\x3f\x4f\xe3\x58\x0e\x3b\x62\xfd\x03\xd6\x28\x31\xc2\xd2\x6f\x3c\xd8\x66\xce\x39\x00\x3c\xb4\xf6\x86\xf0\x14\x07\x58\xcf\xdb\x8f\xef\x03\x80\x14\xe3\xd4\x95\xe7\x45\xda\xd7\xc7\x7c\x65\x95\xef\x0d\xc1\xb5\xf3\x19\xba\xa8\x54\x15\xdf\x3e\xa5\x48\xee\x19\x21\x4e\xd3\x7f\xec\xa4\x08\x71\x9a\xc2\xcf\x00\x14\x74\xa9\x1b\x25\x01\x00\x00")
 
 func metricsServerMetricsApiserviceYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -232,7 +237,7 @@ func metricsServerMetricsApiserviceYaml() (*asset, error) {
 	return a, nil
 }
 
-var _metricsServerMetricsServerDeploymentYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x55\x4f\x6f\xdb\xc8\x0f\xbd\xfb\x53\x10\xfe\x21\xb7\x9f\xe2\x3f\xdd\xb4\x85\x80\x1c\x0c\x5b\xad\x0b\x24\xa9\x61\x39\xbb\xc8\xc9\x98\x8c\xe8\x78\x90\xd1\xcc\x2c\x49\xb9\xd5\x16\xfd\xee\x8b\xb1\x52\x45\x4a\x93\xa2\x8b\xdd\xea\xa0\x03\xf9\xf8\xf8\xf4\x86\x23\x26\x49\x32\x50\xc1\xfc\x8e\xc4\xc6\xbb\x14\x0e\x93\xc1\xbd\x71\x45\x0a\x39\xd2\xc1\x68\x9c\x69\xed\x2b\x27\x83\x12\x45\x15\x4a\x54\x3a\x00\x70\xaa\xc4\x14\x4a\x14\x32\x9a\x13\x46\x3a\x20\x3d\x84\x39\x28\x8d\x29\xdc\x57\xb7\x98\x70\xcd\x82\xe5\xe0\x69\x07\x15\x02\x8f\xda\x36\x0b\x0c\xd6\xd7\x25\xfe\xab\x16\x00\x56\xdd\xa2\xe5\x58\x09\x70\xff\x96\x13\x15\xc2\x77\xe5\x1c\x50\x47\x04\xe1\xc1\x44\x29\x4b\xc3\xe2\xa9\xbe\x30\xa5\x91\x14\xc6\x03\x00\x16\x52\x82\x77\x75\xc3\x23\x75\xc0\x14\xd6\xde\x5a\xe3\xee\xae\x43\xa1\x04\x8f\x71\xea\x46\x1a\x28\x40\xa9\x3e\x5f\x3b\x75\x50\xc6\xaa\x5b\x8b\x29\x4c\x22\x1d\x5a\xd4\xe2\xa9\xc1\x94\x4a\xf4\xfe\xa2\xa3\xf3\x65\xa5\x00\x82\x65\xb0\x2d\x7d\xd7\x99\xf8\xbc\xe0\x4e\x7c\x6c\xaf\xc1\x8f\x5a\x00\x7c\x33\x24\x3e\x81\x8c\x27\x23\xf5\xdc\x2a\xe6\xab\x23\xff\xb0\x71\x37\x71\xbe\xc0\x44\x93\x11\xa3\x95\x1d\x3e\xe0\xb9\x37\x1e\x57\x2f\x0b\x12\x6f\x91\x94\x18\xef\x3a\xaa\x12\xb8\xc7\x3a\x85\xe1\xfc\x81\x75\x56\x14\xde\xf1\x47\x67\xeb\x61\x8b\x01\xf0\x21\x56\x7a\x4a\x61\x98\x7d\x36\x2c\x3c\xfc\x8e\xe0\xa8\x8d\xbc\xc5\xd3\x38\x0f\xe4\x50\x90\x4f\x8d\x1f\x69\xef\x84\xbc\x4d\x82\x55\x0e\x7f\x92\x13\x00\x77\x3b\xd4\x92\xc2\xf0\xca\xe7\x7a\x8f\x45\x65\xf1\xe7\x5b\x96\x8a\x05\xe9\xbf\xe8\x75\xf0\xb6\x2a\xb1\xb5\xeb\x7f\x50\x46\x8f\xc1\x38\x90\x32\x00\x7b\xf8\x84\xa0\x95\x03\x56\x3b\xb4\x35\x54\x8c\xb0\x23\x5f\x26\xac\x29\xce\x18\x98\x52\xdd\x21\x83\x72\xc5\xc8\x13\x10\xaa\x22\xf1\xce\xd6\x10\x4d\x51\xc6\x21\xf1\xe0\xdb\x27\x35\x93\x24\x65\x48\x0a\x43\xad\x3a\x2c\x83\xd4\x0b\x43\x29\x7c\xf9\xfa\x10\x7c\xac\x4d\x9f\x14\x3f\x7b\xea\xd0\x88\x48\x61\x78\xf2\x25\xbf\xc9\x37\xd9\xe5\x76\x91\xbd\x9b\x5d\x5f\x6c\xb6\xeb\xec\xfd\x87\x7c\xb3\xbe\xf9\x7a\x42\xca\xe9\x3d\xd2\xa8\x34\x44\x9e\xb0\x48\xfa\x54\xe9\x61\x7c\xfa\xe6\x74\xfa\x68\x9a\xa2\xbb\xde\x0c\x25\x89\x46\x92\xa8\xfc\x7c\x24\x65\xe8\x65\x18\x75\x45\x98\x04\x4f\x72\x3e\x19\x4f\xcf\xc6\xbd\x6c\x3c\x39\x8b\x92\x04\xc2\x1d\x52\x6c\xad\x8a\x82\x90\x39\x89\x97\x9e\xcf\x4f\xbe\xac\xd6\xd9\xbb\x6c\xbd\xce\x16\xdb\xd9\x62\xb1\xce\xf2\x7c\xbb\xb9\x59\x65\xf9\xd7\x93\x67\x79\x2a\xc6\xe6\x9a\xb0\x28\xa9\xf8\xd8\xb6\x07\x6c\xbe\x2c\x21\x64\x6f\xab\x78\x19\xce\x27\x67\xdc\x43\x88\xe5\x44\x9b\xb0\x47\x4a\xb8\x32\x82\x7c\xbe\xb9\xc8\xb7\xd9\x7c\xb1\xcc\xe2\x3b\x9f\x6d\xff\xf8\xb0\x59\x6e\x67\x59\xbe\x9d\x9e\xbd\xde\xbe\x9f\x5f\x6e\xf3\xe5\xec\xd5\xdb\xdf\xfe\xff\x88\x5b\xff\x14\xea\x09\xdb\x64\xfa\xf6\x1b\x6e\x7a\xf6\xfa\x25\xb6\x17\x51\x1d\xb6\xf9\x72\x36\x5f\xce\xa6\xe3\xed\xea\xe3\xc5\xcd\xe4\xd5\xf8\xec\x39\xb2\xef\x40\xad\x0b\xd1\x9c\x8a\x34\x76\xce\x38\x06\xff\xac\x90\xa5\x17\x03\xd0\xa1\x4a\x61\x32\x1e\x97\xbd\x68\x89\xa5\xa7\x3a\x85\x37\xe3\x4b\xd3\x26\xe2\x51\xf4\xa6\xa6\x99\xda\xbd\x48\xe0\x4e\x75\x3b\xdf\x2b\x4f\x12\xb9\xbb\x23\x13\x7f\x8f\x5e\xbc\xf6\x36\x85\xcd\x7c\xd5\x51\xac\x0a\xe3\x90\x79\x45\xfe\x16\xbb\x12\x23\xfd\x7b\x94\xbe\xea\xa0\x64\x9f\xc2\x28\x56\xd5\x7f\xf5\x33\xc7\xa6\x4f\x35\x01\xb0\xde\x63\x54\xbb\xdc\x6c\x56\x79\x27\x63\x9c\x11\xa3\xec\x02\xad\xaa\x73\xd4\xde\x15\xdc\x6c\xb0\x96\x10\xc9\xf8\xa2\x4d\x4d\x3b\x29\x31\x25\xfa\x4a\xda\xdc\xa4\x93\xe3\x4a\x6b\x64\xde\xec\x09\x79\xef\x6d\xd1\xcf\xee\x94\xb1\x15\x61\x27\xfb\xaa\xcd\x5a\x73\xc0\x7f\xec\x44\x2c\xfa\x05\x46\xbc\xfe\x81\x13\x93\xf1\x2f\xb7\xe2\xf8\xeb\x89\xab\xd4\x3b\xc1\xcf\xd2\x9f\x66\x55\xc4\x2d\xb7\xf6\x5e\xde\x19\x8b\xcd\x86\x4d\x41\xa8\xc2\x2e\xac\x72\x33\xbe\xf2\x2e\xc2\x9e\x4f\x5e\x33\xd2\xf1\x06\x74\x3f\x47\x59\xeb\x3f\xad\xc8\x1c\x8c\xc5\x3b\xcc\x58\x2b\x7b\x5c\xbc\x29\xec\x94\xe5\x47\x8e\x66\xbf\x5c\xc6\xa5\xf2\xcc\xcd\x78\xba\x0c\xa0\x59\x3f\xab\xe6\xc8\xe2\x7f\xf6\xef\x00\x00\x00\xff\xff\x73\x53\x17\xa1\x34\x0a\x00\x00")
+var _metricsServerMetricsServerDeploymentYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x55\x5b\x6f\xdb\x48\x0f\x7d\xf7\xaf\x20\xfc\x21\x6f\x9f\xea\x4b\x37\xdd\x42\x40\x1e\x0c\x5b\x8d\x03\x24\xa9\x61\x39\x5d\xf4\xc9\x98\x8c\xe8\x78\x90\xd1\xcc\x2c\x49\xb9\xd1\x06\xf9\xef\x8b\xf1\x55\xce\xa5\xc8\x62\xb7\x51\xe0\x07\x9e\xc3\x43\xea\x0c\x35\x4c\x92\xa4\xa5\x82\xf9\x86\xc4\xc6\xbb\x14\x56\xbd\xd6\xbd\x71\x45\x0a\x39\xd2\xca\x68\x1c\x68\xed\x2b\x27\xad\x12\x45\x15\x4a\x54\xda\x02\x70\xaa\xc4\x14\x4a\x14\x32\x9a\x13\x46\x5a\x21\x6d\xc3\x1c\x94\xc6\x14\xee\xab\x5b\x4c\xb8\x66\xc1\xb2\xf5\xbc\x82\x0a\x81\x3b\xfb\x32\x23\x0c\xd6\xd7\x25\xfe\xab\x12\x00\x56\xdd\xa2\xe5\xd8\x1c\xc0\xfd\x67\x4e\x54\x08\x2f\xd2\x39\xa0\x8e\x0c\xc2\x95\x89\xad\x8c\x0d\x8b\xa7\xfa\xd2\x94\x46\x52\xe8\xb6\x00\x58\x48\x09\xde\xd5\x91\x05\x20\x75\xc0\x14\xa6\xde\x5a\xe3\xee\x6e\x42\xa1\x04\xd7\x71\x6a\x46\x36\x54\x80\x52\x3d\xdc\x38\xb5\x52\xc6\xaa\x5b\x8b\x29\xf4\xa2\x1c\x5a\xd4\xe2\x69\xc3\x29\x95\xe8\xe5\x65\xa3\xcf\xb7\x3b\x05\x10\x2c\x83\xdd\xcb\x37\x9d\x01\x78\xd3\x1d\x80\x63\x23\x7e\x5e\x02\x60\x67\x48\x7c\x02\x19\x4f\x46\xea\xa1\x55\xcc\xd7\x6b\xf7\xdb\x1b\x77\x13\xe7\x0b\x4c\x34\x19\x31\x5a\xd9\xf6\x96\xcf\x47\xe3\x71\xfd\x76\x43\xe2\x2d\x92\x12\xe3\x5d\xa3\xab\x04\xee\xb1\x4e\xa1\x3d\xdc\xaa\x0e\x8a\xc2\x3b\xfe\xea\x6c\xbd\xd3\x8f\x8f\x0f\x31\xd3\x53\x0a\xed\xec\xc1\xb0\x70\xfb\x85\xc0\xba\x37\xf2\x16\x3f\xc4\x91\x23\x87\x82\xfc\xc1\xf8\x8e\xf6\x4e\xc8\xdb\x24\x58\xe5\xf0\x9d\x9a\x00\xb8\x58\xa0\x96\x14\xda\xd7\x3e\xd7\x4b\x2c\x2a\x8b\xef\x2f\x59\x2a\x16\xa4\xff\xa2\xd6\xca\xdb\xaa\xc4\xbd\x5d\xff\x83\x32\x7a\x0c\xc6\x81\x94\x01\xd8\xc3\x0f\x04\xad\x1c\xb0\x5a\xa0\xad\xa1\x62\x84\x05\xf9\x32\x61\x4d\x71\xc6\xc0\x94\xea\x0e\x19\x94\x2b\x3a\x9e\x80\x50\x15\x89\x77\xb6\x86\x68\x8a\x32\x0e\x89\xb7\xca\xc9\x76\x92\xa4\x0c\x49\x61\x76\x27\x06\x80\x65\x90\x7a\x64\x28\x85\xc7\xa7\x6d\xf0\x90\x9b\x3e\x4b\x7e\xf5\xd4\x61\xd3\x44\x0a\xed\x93\xc7\x8b\xab\xc1\x79\x36\xbf\xca\x66\xd3\x8b\x61\x3e\xcf\xb3\xe9\xb7\x6c\xfa\x74\x72\xf0\x42\xd1\xdd\x5e\x34\xca\x26\x89\x46\x92\xd8\xd0\x59\x47\xca\x70\x84\x30\xea\x8a\x30\x09\x9e\xe4\xac\xd7\xed\x9f\x76\x8f\xd0\x38\x03\x16\x25\x09\x84\x0b\x24\xc2\x22\x51\x45\x41\xc8\x9c\xc4\x6f\x99\xcf\x4e\x1e\x27\xd3\xec\x4b\x36\x9d\x66\xa3\xf9\x60\x34\x9a\x66\x79\x3e\x9f\x7d\x9f\x64\xf9\xd3\xc9\xab\x3a\x15\xe3\x66\xfa\x59\x94\x54\xbc\x2e\x7b\x44\xdc\xbc\x7b\x42\xc8\xde\x56\x71\xc6\xcf\x7a\xa7\x3b\x73\x37\x2d\x89\xe5\x44\x9b\xb0\x44\x4a\xb8\x32\x82\x7c\x36\xbb\xcc\xe7\xd9\x70\x34\xce\xe2\x6f\x3e\x98\xff\x71\x31\x1b\xcf\x07\x59\x3e\xef\x9f\x7e\x9a\x9f\x0f\xaf\xe6\xf9\x78\xf0\xf1\xf3\x6f\xff\x3f\xf0\xa6\xef\x62\x3d\x53\xeb\xf5\x3f\xef\x78\xfd\xd3\x4f\x6f\xa9\xbd\xc9\x6a\xa8\x0d\xc7\x83\xe1\x78\xd0\xef\xce\x27\x5f\x2f\xbf\xf7\x3e\x76\x4f\x5f\x13\x7b\x41\xda\xbb\x10\xcd\xa9\x48\x1f\xe6\x39\x3e\x84\x7f\x56\xc8\x72\x14\x03\xd0\xa1\x4a\xa1\xd7\xed\x96\x47\xd1\x12\x4b\x4f\x75\x0a\xbf\x77\xaf\xcc\x1e\x88\x47\xd1\xc8\xde\x0d\xe3\x52\x24\x1c\x0e\xa0\x31\xb6\x13\x4f\x92\xc2\xf1\xc8\xc4\x5b\xcf\x8b\xd7\xde\xa6\x30\x1b\x4e\xf6\xf1\xf8\xc5\x18\x87\xcc\x13\xf2\xb7\xfb\x1b\x3e\xfe\x47\xf9\x73\x94\x66\x08\x20\x28\x59\xa6\xd0\x89\x59\xf5\x5f\xc7\xc8\xba\xe8\xf3\x9e\x00\x58\x2f\x31\x76\x3b\x9e\xcd\x26\x79\x03\x31\xce\x88\x51\x76\x84\x56\xd5\x39\x6a\xef\x0a\xde\x2c\xa6\xdd\x5f\x40\x32\xbe\xd8\x43\xfd\x06\x24\xa6\x44\x5f\xc9\x1e\xeb\x35\x30\xae\xb4\x46\xe6\xd9\x92\x90\x97\xde\x16\xc7\xe8\x42\x19\x5b\x11\x36\xd0\x8f\x7b\xd4\x9a\x15\xfe\x63\x27\x62\xd2\x2f\x30\xe2\xd3\x4f\x9c\xe8\x75\x7f\xb9\x15\xeb\xab\x27\x6e\x48\xef\x04\x1f\x8e\xde\x3c\x1e\x7d\x5c\x5e\x53\xef\xe5\x8b\xb1\xb8\x59\x9c\x29\x08\x55\xd8\xa4\x55\x6e\xc0\xd7\xde\x45\xda\xeb\xe0\x0d\x23\xc5\x29\xed\x36\x5f\x47\x59\xeb\x7f\x4c\xc8\xac\x8c\xc5\x3b\xcc\x58\x2b\xbb\xde\xa7\x29\x2c\x94\xe5\x83\xc6\x66\x6d\x5c\xc5\x5d\xf1\xca\x97\xf1\xfc\x8e\x87\xcd\x56\x99\x28\x59\xa6\xd0\x91\x32\xb4\xfe\x1e\x00\xb5\xa2\xd4\x14\x0b\x0a\x00\x00")
 
 func metricsServerMetricsServerDeploymentYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -252,7 +257,7 @@ func metricsServerMetricsServerDeploymentYaml() (*asset, error) {
 	return a, nil
 }
 
-var _metricsServerMetricsServerServiceYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x8e\x3f\x4b\x04\x31\x10\xc5\xfb\x7c\x8a\x61\xfb\x28\xe2\x15\x92\x56\xb1\x13\x16\x4e\xec\xe7\x72\x4f\x0d\x9b\x6c\xc2\xcc\xec\xc2\x7e\x7b\xd9\xdc\x59\x1c\x5c\x97\xbc\x79\x7f\x7e\xde\x7b\xc7\x2d\x7d\x41\x34\xd5\x39\xd0\xfa\xe4\xa6\x34\x9f\x03\x1d\x21\x6b\x8a\x70\x05\xc6\x67\x36\x0e\x8e\x68\xe6\x82\x40\x05\x26\x29\xaa\x57\xc8\x0a\xb9\xca\xda\x38\x22\xd0\xb4\x9c\xe0\x75\x53\x43\x71\x44\x99\x4f\xc8\xba\x27\xa9\x5f\x64\x86\x41\x1f\x52\x7d\xbc\x34\x0d\x1f\x37\x55\xc3\x1d\x63\xcc\x8b\x1a\xa4\x3b\xd2\xbe\x30\x98\x2c\x18\x9c\x36\xc4\xbd\x58\x91\x11\xad\xca\x75\xe4\x45\x3d\xb7\x76\x87\xb1\x55\xb1\x4e\xe2\xfb\x33\xd0\xe1\xf0\xdc\x23\x17\x92\x5f\xb3\xa6\xfd\xdf\xa4\x5a\x8d\x35\x07\xfa\x7c\x1d\xbb\x62\x2c\x3f\xb0\xb1\xa7\xfe\x7d\xa9\xbd\x73\x49\x79\x1b\x6b\x4e\x71\x0b\x34\x0a\xbe\x21\x6f\x0b\xe7\xa3\x71\x9c\xdc\x5f\x00\x00\x00\xff\xff\x7b\xf5\x71\x2a\x57\x01\x00\x00")
+var _metricsServerMetricsServerServiceYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x8e\x3f\x4b\x04\x31\x10\xc5\xfb\x7c\x8a\x61\xfb\x28\xe2\x15\x92\x56\xb1\x13\x16\x4e\xec\xe7\x72\x4f\x0d\x9b\x6c\xc2\xcc\xec\xc2\x7e\x7b\xd9\xdc\x59\x1c\x5c\x97\xbc\x79\x7f\x7e\xde\x7b\xc7\x2d\x7d\x41\x34\xd5\x39\xd0\xfa\xe4\xa6\x34\x9f\x03\x1d\x21\x6b\x8a\x70\x05\xc6\x67\x36\x0e\x8e\x68\xe6\x82\x40\x05\x26\x29\xaa\x57\xc8\x0a\xb9\xca\xda\x38\x22\xd0\xb4\x9c\xe0\x75\x53\x43\x71\x44\x99\x4f\xc8\xba\x27\xa9\x5f\x64\x86\x41\x1f\x52\x7d\xbc\x34\x0d\x1f\x37\x55\xc3\x1d\x63\xcc\x8b\x1a\xa4\x3b\xd2\xbe\x30\x98\x2c\x18\x9c\x36\xc4\xbd\x58\x91\x11\xad\xca\x75\xe4\x45\x3d\xb7\x76\x87\xb1\x55\xb1\x4e\xe2\xfb\x33\xd0\xe1\xf0\xdc\x23\x17\x92\x5f\xb3\xa6\xfd\xdf\xa4\x5a\x8d\x35\x07\xfa\x7c\x1d\xbb\x62\x2c\x3f\xb0\xb1\xa7\xfe\x7d\xa9\xbd\x73\x49\x79\x1b\x6b\x4e\x71\x0b\x34\x0a\xbe\x21\x6f\x0b\xe7\xa3\x71\x9c\xdc\xdf\x00\x7b\xf5\x71\x2a\x57\x01\x00\x00")
 
 func metricsServerMetricsServerServiceYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -272,7 +277,7 @@ func metricsServerMetricsServerServiceYaml() (*asset, error) {
 	return a, nil
 }
 
-var _metricsServerResourceReaderYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x90\x41\x4b\xc4\x30\x10\x85\xef\xf9\x15\xc3\xde\xd3\xc5\x9b\xe4\xa6\x1e\xbc\xaf\xe0\x3d\x4d\x9f\xbb\x63\xdb\xa4\xcc\x4c\x2a\xfa\xeb\xa5\xb6\x2a\xb8\xb0\x2c\x78\x4a\x98\xe4\x7d\x8f\xf9\xbc\xf7\x2e\x4e\xfc\x0c\x51\x2e\x39\x90\xb4\x31\x35\xb1\xda\xa9\x08\x7f\x44\xe3\x92\x9b\xfe\x56\x1b\x2e\xfb\xf9\xc6\xf5\x9c\xbb\x40\x0f\x43\x55\x83\x1c\xca\x00\x37\xc2\x62\x17\x2d\x06\x47\x94\xe3\x88\x40\xfa\xae\x86\x31\x8c\x30\xe1\xa4\x5e\x21\x33\xc4\x49\x1d\xa0\xc1\x79\x8a\x13\x3f\x4a\xa9\x93\x2e\x09\x4f\xbb\x9d\x23\x12\x68\xa9\x92\xb0\xcd\x72\xe9\xa0\xfb\x0d\xe0\x88\x66\x48\xbb\x3d\x1d\x61\xd7\x31\xa6\xd2\xe9\x2f\xec\x1c\xb2\x9c\x03\xeb\x7a\x79\x8b\x96\x4e\xee\x7f\x26\xee\x39\x77\x9c\x8f\xd7\x0b\x29\x03\x0e\x78\x59\xbe\x7d\xaf\x73\xa1\xd2\x11\x9d\xbb\xbf\x5c\xa0\xb5\x7d\x45\xb2\x2f\xe9\x6b\xf6\x09\x32\x73\xc2\x5d\x4a\xa5\x66\xfb\x89\xff\xc9\xad\x63\x9d\x62\x42\xa0\xbe\xb6\xf0\x2b\xdf\x7d\x06\x00\x00\xff\xff\xdb\x55\x9e\x61\x2a\x02\x00\x00")
+var _metricsServerResourceReaderYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x90\xc1\x4e\xeb\x30\x10\x45\xf7\xfe\x8a\x51\xf7\x4e\xf5\x76\x4f\xde\x01\x0b\xf6\x45\x62\xef\x38\x97\x76\x48\x62\x47\x33\xe3\xa0\xf2\xf5\x28\x24\x80\x44\xa5\xaa\x12\x2b\x5b\x63\xdd\x73\x3d\xc7\x7b\xef\xe2\xc4\xcf\x10\xe5\x92\x03\x49\x1b\x53\x13\xab\x9d\x8a\xf0\x7b\x34\x2e\xb9\xe9\xff\x6b\xc3\x65\x3f\xff\x73\x3d\xe7\x2e\xd0\xc3\x50\xd5\x20\x87\x32\xc0\x8d\xb0\xd8\x45\x8b\xc1\x11\xe5\x38\x22\x90\x9e\xd5\x30\x86\x11\x26\x9c\xd4\x2b\x64\x86\x38\xa9\x03\x34\x38\x4f\x71\xe2\x47\x29\x75\xd2\x25\xe1\x69\xb7\x73\x44\x02\x2d\x55\x12\xb6\x59\x2e\x1d\x74\xbf\x01\x1c\xd1\x0c\x69\xb7\xa7\x23\xec\x36\xc6\x54\x3a\xfd\x81\x5d\x42\x96\x73\x60\x5d\x2f\x6f\xd1\xd2\xc9\xfd\xcd\xc4\x3d\xe7\x8e\xf3\xf1\x76\x21\x65\xc0\x01\x2f\xcb\x8f\xbe\xd6\xb9\x52\xe9\x88\x2e\xdd\x5f\x2f\xd0\xda\xbe\x22\xd9\xa7\xf4\x35\xfb\x04\x99\x39\xe1\x2e\xa5\x52\xb3\x7d\xc7\x7f\xe5\xd6\xb1\x4e\x31\x21\x50\x5f\x5b\x78\x3d\xab\x61\x74\x1f\x03\x00\xdb\x55\x9e\x61\x2a\x02\x00\x00")
 
 func metricsServerResourceReaderYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -292,7 +297,67 @@ func metricsServerResourceReaderYaml() (*asset, error) {
 	return a, nil
 }
 
-var _rolebindingsYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x94\x41\x8f\xda\x30\x10\x85\xef\xfe\x15\x16\x77\x83\xaa\x5e\xaa\x1c\xdb\x43\xef\x48\xed\xdd\xb1\xa7\x30\x8d\x63\x5b\x33\x63\x50\xfb\xeb\xab\x90\x40\x17\x92\xb0\x64\x97\x3d\x25\xb1\xec\xf7\x8d\x67\xde\x8b\xcd\xf8\x13\x88\x31\xc5\x4a\x53\x6d\xdd\xda\x16\xd9\x27\xc2\xbf\x56\x30\xc5\x75\xf3\x85\xd7\x98\x36\x87\x4f\xaa\xc1\xe8\x2b\xfd\x2d\x14\x16\xa0\x6d\x0a\xf0\x15\xa3\xc7\xb8\x53\x2d\x88\xf5\x56\x6c\xa5\xb4\x8e\xb6\x85\x4a\x37\xa5\x06\x63\x33\x32\xd0\x01\xc8\x74\x9f\x01\xc4\x58\xdf\x62\x54\x94\x02\x6c\xe1\x57\xb7\xdb\x66\xfc\x4e\xa9\xe4\x3b\x64\xa5\xf5\x08\x7c\xe1\xf0\x1f\x16\x68\xab\x8b\x7e\xc6\x81\xc1\xa5\xfe\x0d\x4e\xb8\x52\x66\x11\xe4\x07\x03\xcd\xdc\x42\x29\x63\x8c\x7a\x7b\xb7\x26\xda\x74\x2e\xff\x33\x1b\x97\xa2\x50\x0a\x01\x48\x51\x09\x70\x55\x38\x77\x27\x8c\x5e\xad\x94\xd6\x04\x9c\x0a\x39\x18\xd6\x62\xf2\xc0\x4a\xeb\x03\x50\x3d\x2c\xed\x40\x4e\xcf\x80\xdc\xbf\x1c\xad\xb8\xfd\x02\xb9\x0d\x8b\x95\x72\xa3\x9a\x17\x88\xd8\x16\x38\x5b\x77\x5b\xd8\xab\x05\x45\x90\x63\xa2\x06\xe3\x6e\xe8\xe3\x94\x78\xbf\x27\xa7\x80\x0e\x4f\x04\xa3\x5d\xdf\x64\x87\x9e\x96\x22\x27\x08\x10\x7d\x4e\x18\xa5\xd7\xce\xc9\xcf\x69\x9e\x1b\xdd\x6b\xbf\xd3\x1d\xf3\x59\x9a\x31\xc9\xf3\x43\x74\x0d\xf8\x9f\xa0\xee\x8e\x8f\x31\x6e\x52\x74\x1f\xf0\xfc\x38\xbd\xf4\x81\xe9\xac\x3c\x1b\xa5\x91\xd3\xc6\x36\x78\xd8\x54\x1f\x36\xf8\x89\xeb\x3c\x6f\xe8\x63\xf1\xeb\x81\xf7\x27\x4f\x88\xf1\x24\xcf\x7f\x9d\xc7\xca\xf8\x17\x00\x00\xff\xff\x40\xa6\x57\x0f\x61\x06\x00\x00")
+var _monitoringDefaultsDashboardsYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\x9a\xdd\x6e\xdb\xb8\x12\xc7\xef\xf5\x14\x03\xa2\x17\x09\x6a\xe7\xd4\x76\x93\xd6\x02\xce\x45\xdb\x73\x16\x28\xb6\x0b\xec\xa2\x40\x6f\xda\x40\xa0\xc4\xb1\xc3\x35\x45\xaa\x1c\xca\xa9\xeb\xf5\x3e\xfb\x82\x8a\x3f\x64\x46\x8a\xf3\xe1\x20\x2e\xb6\x70\x51\x44\xe4\x68\x38\xf3\x9f\xdf\x50\x22\xec\x6e\xb7\x1b\xf1\x42\x7e\x42\x4b\xd2\xe8\x18\xa6\xbd\x68\x22\xb5\x88\xe1\x9d\xd1\x23\x39\xfe\x8d\x17\x51\x8e\x8e\x0b\xee\x78\x1c\x01\x68\x9e\x63\x0c\x93\x01\x75\x05\xa7\x8b\xd4\x70\x2b\x68\x39\x4c\x05\xcf\xfc\x5c\x99\x62\x97\x66\xe4\x30\x8f\x00\x14\x4f\x51\x91\xbf\x13\x80\x17\xc5\xc9\xa4\x4c\xd1\x6a\x74\x48\x27\xd2\xfc\x67\xed\xad\x9a\x1f\x5b\x3e\xe2\x9a\x27\x6b\xcf\x31\xb0\x1e\x8b\x56\x4b\x4f\x06\x74\xf2\x27\xf9\x18\xff\xaa\xcc\xe7\xd5\xff\x00\xcc\x49\xa7\x90\xc5\xc0\x26\x03\x62\x9d\xd5\x68\x29\xc5\x72\xac\x2b\xb5\x43\xab\xb9\xaa\xcd\x3a\x3e\x26\x16\xc3\xe7\xe5\x35\x54\x86\x6c\x79\x75\xbe\x31\x93\x39\x7e\x37\xba\xf2\x9e\x5a\x73\x49\x68\x37\x3e\x2c\x8e\x2c\xd2\x85\x9f\x1b\xbc\xa8\xf9\xa6\xec\x02\x73\xbe\x54\x94\xc5\x30\x18\xae\xa7\x9c\xcc\xbd\xaf\x55\xe8\x00\x6c\x64\x4d\xee\x3d\x68\x73\xd9\x3d\xbb\x58\x3b\xf1\xb6\x66\x39\xbe\x0a\x6b\xb1\x71\x83\x79\xa1\xb8\x93\x7a\xbc\xed\x4c\x49\x72\x5b\x59\x6d\x54\x5a\x5a\x78\xc5\xbd\x5b\x2f\x2a\x99\xd2\x66\x58\x5b\xd2\xff\x63\x6e\x56\xec\xb0\xf8\x5a\xa2\x9d\x79\x93\xc2\x9a\x1c\xdd\x05\x96\x14\x9a\x54\x65\xf7\x26\xff\xe3\x8e\xc3\xd2\x4d\xcd\x64\xd1\xd9\x1d\xa2\xd4\xe4\xb8\x6e\x0f\xf0\x2a\x8c\xb6\x85\xdf\xb7\xdc\x5d\x4b\xab\x2e\x5d\xe0\xbb\x35\xb3\x0d\x58\xcf\xe6\x1b\x57\x8b\x55\x89\x1a\xb2\xab\x0b\x56\xc9\x92\x4c\xb9\x2a\x91\x8e\x26\x03\x4a\x32\xb4\x4e\x8e\x64\xc6\x1d\x26\xf8\xad\x90\x96\x3b\x69\x74\x42\x98\x19\x2d\xa8\x03\x2b\x0d\x8e\xc3\x34\xa4\xce\x54\x29\xf0\x8d\x52\x2c\x06\x67\x4b\x0c\xe6\xf3\x52\x39\xd9\x3c\xb5\xc1\xb6\x1f\xcc\x70\xa5\x3e\xf9\xd8\x7c\xa8\x27\xcf\xeb\x39\x2d\xd6\x7f\x9f\x47\x41\x8e\xac\xe0\x1a\xd5\x76\x33\xd5\x75\x65\x95\x5c\xbd\x4e\xd4\x20\xb3\x35\x97\x5b\x89\x6d\x5a\xf9\x63\x59\xa0\x9d\x4a\x32\x9b\x7e\xf3\x1f\x96\x19\xa5\x78\x41\xe8\x7d\x8e\xb8\xa2\xad\xe4\xd8\xd8\x4a\xf1\xbb\xa1\xad\xa6\xf0\x1f\xf6\x8d\xc5\xf0\xa2\x6e\x0a\xc0\x66\x0d\x63\x97\x2c\x86\xfe\xcb\x60\xd0\x4b\xd5\x8b\x5a\xca\x5b\x4b\xff\x3c\x6a\xb0\xb8\x2e\x45\xbf\x51\x0a\xbf\x33\x10\x5a\x89\xd4\xa2\xc8\x9b\x31\x6a\x07\xae\xd4\x1a\x15\x10\x92\xdf\xae\x03\xdb\x1b\xd8\xde\x4d\xf6\x4e\xae\x17\x0f\x93\xba\xd7\x20\x75\x2f\x24\xd0\x4b\xfd\xba\x75\xcd\x91\x44\x25\xae\x9e\x4b\xd7\xd7\x15\x38\xe2\xa5\x72\xd7\x23\xf2\xa9\x69\xe9\xf7\x45\x46\x17\xc6\xba\x7a\x4e\xc1\x0a\x00\xcc\x4c\xd1\x5a\x29\x30\xa8\x68\x18\x8a\xe3\x76\x8c\x6e\x9b\xfa\xb0\xdc\xab\x6e\x7b\xef\xab\xce\xde\x04\x7a\x03\x30\xfc\x56\xd8\xe5\x33\x2a\xbe\x2a\x6c\x42\x68\xa7\x68\x93\x55\x7d\x63\x2a\xf3\xf9\x6a\x13\xf8\xef\xdf\x5f\xd8\xb3\xd5\xc5\x17\xb6\xb8\xee\x50\xe1\x18\xb5\xf8\xc5\xd8\x9c\x57\xf9\xce\xd7\xf7\x2e\xc2\x2d\xaa\x76\x75\x4b\x6e\x07\x9d\xa8\x81\xa7\xbb\x71\xeb\x2c\x1f\x8d\x64\x76\xa0\xd8\xf6\xfa\x07\xca\xed\xdb\x82\x82\xf2\x05\xeb\x1f\x06\xb5\xe9\xcc\x21\xc5\x96\x3b\x3c\xdd\x17\xb5\x30\x9f\x0b\x69\x31\xf3\x8f\xc5\x3d\x30\xfc\xf2\x7e\x0c\xbf\xdb\x3c\xa6\x61\xf3\x98\x3e\x50\x8a\x9b\x36\xdf\x61\x30\xf6\x24\x9b\x6f\x50\xbd\x60\xf5\xa7\x42\xf8\xe6\x57\xb0\x38\x97\x7a\x4f\x28\x07\xe9\xdf\x1d\xde\xd3\x4e\xd4\xc0\xd4\x4e\x78\x3f\x18\x2e\xba\x29\x57\x3e\x68\x0b\x42\x72\x05\x8a\x3b\xd4\xd9\x0c\x8e\x8a\xe1\xf0\xf8\x40\x31\xee\xf5\x7f\x72\x7c\x17\x8e\x95\xe1\x62\x55\xe5\xc4\x57\x39\x11\x65\xc0\x72\x31\x1c\xee\x89\x65\x98\xcf\xfd\x31\x6d\x0f\x50\x9f\x75\xa2\x06\xc2\xda\x0f\x06\xfe\x40\x49\xce\x58\x7c\xd4\x73\x41\xef\xd5\x13\x1c\x0c\x5e\x35\x4a\xb1\xb3\xbf\x7f\x95\x1a\xe1\xe3\x1f\x1f\x7e\x88\xae\x6e\x14\xfb\xf5\xcf\xae\x6e\xeb\xea\x89\xd4\x98\xd0\x57\x95\x78\x0c\x1e\xa1\x91\x83\xac\x6b\x57\xb7\x84\xf6\xf5\x03\xa1\x35\x05\x5e\xed\x52\x74\xa0\xc4\xf6\xfa\x87\x8a\xac\x39\xc4\x53\x01\x95\x39\xa4\x33\x38\x5a\x21\xd6\x01\xb4\xd6\xd8\x24\x33\x02\x8f\xe1\xc8\x1f\x0e\x8e\x36\x50\x1b\xc7\x55\x2b\xc9\x9f\x4f\xf3\xf3\xe3\xe3\xbb\xf1\x0c\xf3\xf9\x66\xbd\x3d\xe0\x3d\xbc\x1f\xde\xff\x77\x99\x00\xcf\x5e\xca\x09\x81\xe4\x77\x3c\x50\xba\x9b\xf6\xe3\xfe\xd9\x01\xc0\x5d\x9d\x25\x83\xf2\x05\x11\x3c\x05\xde\xe8\x32\x91\xe4\xd3\x2c\x4b\x44\x7a\x85\x6f\xe2\xab\x9b\x48\x9d\x54\x11\xb7\xc2\x7c\x47\x8c\x2b\xd7\x37\xa6\xdf\x1e\xfe\xdb\x7b\x85\x5f\xd2\xfe\xb3\x90\x1a\x4a\xc2\x20\x8d\xbb\x37\x61\xef\xc5\x43\xba\x50\xd2\xe4\xc7\x3d\xf0\xf4\xcf\x7e\xbe\x1b\xb5\xbd\x1b\x55\x30\x0b\x49\x93\xe4\x92\xab\x64\x44\x33\x9d\x3d\xea\x99\xe7\x92\x2b\xa8\x56\xb9\x51\x8e\x7b\xf5\xe5\x76\x3a\x29\xcf\x26\xa8\x45\x92\x99\x3c\x97\xee\x51\x73\x5a\x2e\x05\x57\x4b\x05\x89\xdd\xa3\x53\x7b\x8d\x9d\x7a\xc3\xf7\x3c\xfe\x5b\x9e\x0c\x3f\xbc\x7d\xd4\xe3\xdc\xe0\xe5\x13\x1c\xe7\x7a\xfd\x46\x2d\x76\xee\x5a\x6b\x49\xa0\x30\x82\x0e\x74\xb3\x6a\x54\xf9\xf4\xb6\x2a\xff\xfb\xbe\xe2\x59\xbd\x16\xaf\x7f\x28\xd1\x01\xc1\x31\x37\x9a\xd0\x1d\xc3\x91\xff\x59\x44\xb2\x1e\x48\xc8\x71\x57\x52\xa2\xcb\x3c\x45\x9b\x58\xe4\x62\x36\x5f\xcf\xfa\x8e\xa7\x69\xa6\xd2\xee\xc9\xf3\x2f\x6c\x71\xab\x17\xe4\xf5\xcd\x8b\x05\x54\xee\x6e\x54\xe8\x5e\x5b\xd8\x43\x32\x2c\x35\x9f\x72\xa9\x78\xaa\x70\x7f\x79\xd6\x9c\x06\xd9\xd6\xae\xce\xa3\x70\xf4\x3c\x02\x00\x58\x44\xff\x0c\x00\x9c\x5b\xbe\xfc\x19\x23\x00\x00")
+
+func monitoringDefaultsDashboardsYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_monitoringDefaultsDashboardsYaml,
+		"monitoring-defaults/dashboards.yaml",
+	)
+}
+
+func monitoringDefaultsDashboardsYaml() (*asset, error) {
+	bytes, err := monitoringDefaultsDashboardsYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "monitoring-defaults/dashboards.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _monitoringDefaultsRulesYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xc4\x98\x61\x6f\xdb\x36\x13\xc7\xdf\xe7\x53\xdc\x93\xe7\x01\x9a\xf4\x89\x15\xa7\x99\xd7\xc5\x6d\x0a\xac\x4b\xf6\xa6\x41\xd1\x2e\xeb\xde\x0c\x83\x70\x22\xcf\x16\x61\x8a\x54\x78\x94\x53\x37\xf3\x3e\xfb\x40\x4a\xb5\x65\x5b\x89\x5d\xac\xe9\xe0\x37\x36\x4d\xde\xfd\xef\x77\xa7\xe3\xd9\xbd\x5e\x6f\x0f\x4b\xf5\x1b\x39\x56\xd6\x0c\xa1\xb0\x46\x79\xeb\x94\x19\x27\xc2\x3a\xb2\x9c\x08\x5b\x1c\x4f\x4f\xf6\x26\xca\xc8\x21\xbc\x73\xb6\x20\x9f\x53\xc5\xbf\x54\x9a\xf6\x0a\xf2\x28\xd1\xe3\x70\x0f\xc0\x60\x41\x43\x98\x9c\x72\xf3\x9e\x4b\x14\x61\xa1\xca\xa8\xc7\x33\xf6\x54\xec\x01\x68\xcc\x48\x73\xd8\x0e\x80\x65\x99\x4c\xaa\x8c\x9c\x21\x4f\x9c\x28\x7b\xbc\x34\xc1\x25\x89\xb0\x6b\xec\x6c\x55\xc6\xfd\xbd\xa5\x83\xc4\x55\x9a\x82\x1b\x80\xf8\x2e\x7c\x0d\xd0\x03\x47\xc2\x3a\x19\x0d\x0c\x7d\x65\x0c\xe9\x94\xc9\x4d\xc9\xa5\x4c\x1c\xc2\xe3\x21\x57\x41\x45\x78\xd1\xc7\xd2\x0d\x81\xab\x02\xb2\x19\x1c\x28\xc3\x1e\x8d\xa0\x43\x38\x98\x9c\x72\xda\x7d\xfa\x70\xab\x9f\x6c\xe6\x89\x87\x0e\x3d\x0d\x1e\xf6\x73\x04\x52\x39\x12\x5e\x59\x73\x08\x07\xe1\x40\x87\xdf\x68\x2d\xf5\xd6\xa3\xfe\x7d\x50\xfc\x71\xd8\xe5\x5f\x5b\x94\x19\xea\x60\xd3\xa5\x52\xa1\x4e\x65\xe5\x30\xd8\x4d\x99\x84\x35\x92\x87\xe5\xd9\xd9\x8a\x96\x5c\xb1\xb7\x63\x87\x45\x7a\x53\xa1\xf1\x4a\xd3\x41\x3f\x39\x3b\x3b\xea\x10\x19\x90\x1f\x81\xa6\xb6\xc6\xed\x1e\xd3\xac\x12\x13\xf2\xb5\xe6\x2e\xd1\x82\x9c\x57\x23\x25\xd0\x53\x4a\x1f\x4b\xb5\x26\xb8\x50\x66\x45\x70\xa1\x4c\x57\x92\x1e\xb6\xd2\xe5\x77\xa2\x0c\xa5\x7c\xa3\x53\xaf\x0a\xfa\x1a\x7c\x5a\x64\xba\x4c\x6f\x05\xb1\x10\x44\xce\x59\x17\x2b\x47\xd9\x74\x87\xfa\xd9\x74\x1b\xaa\xe4\x2e\x9a\x49\x85\x95\xf4\x9f\xf3\xfd\xfd\x79\x9d\x01\x38\xde\xf1\xf8\xbd\x45\x46\x5e\xc8\x54\x2a\x9e\xa4\xb7\xa8\xd3\x11\xcf\x8c\xf8\xaa\x65\xb6\xc4\xb8\x8b\xa7\xad\x54\x97\x46\x32\x14\x13\x32\x32\x15\xb6\x28\x94\x7f\x74\xcd\x5b\xdc\x6d\x08\x6f\xf7\x34\xd4\xe4\x7c\x47\x53\x8b\xeb\x43\x78\x73\xca\x3f\x2d\xeb\xfd\x32\x3c\x34\xca\x8c\xaf\xad\x5d\x7d\x54\x76\x7b\xba\xe0\x25\x3c\x87\xa7\xf0\xec\x3b\x78\x0a\xa7\xdf\xf7\xfb\x8d\x89\x91\x75\x43\x38\xc9\x9b\x4f\xed\x56\x1d\x5e\x4c\x53\x72\xca\xcf\x86\x70\x8b\xce\x28\x33\x6e\xbe\x41\x63\xac\x8f\x71\xb6\x37\x57\x45\x81\x6e\x16\x05\x41\x4b\x10\x03\x3a\x0a\x35\x1d\xe5\x03\x2f\xf5\x03\x48\x62\xe1\x54\x19\x2c\x0d\xe1\xc7\xf6\x29\xb0\x06\xee\xee\xe0\x7f\xb5\xa4\xe4\x73\x12\x60\x3e\xaf\x4d\x11\x83\x32\xa0\x89\x19\x7c\x8e\x06\x9e\x83\xc4\x19\x27\xd0\x22\x56\x3b\x76\x64\xe8\x96\x24\xdc\xe6\x64\xa2\x34\xc5\xe0\x88\x3d\x3a\x4f\xf2\x08\xac\x83\x5b\xe5\x73\xf0\x39\xad\x0b\x07\x17\x82\x24\x08\xa9\x45\x23\x93\x8d\xec\x5c\x59\x94\xaf\x9b\xb6\x78\x1d\xef\x8d\x0f\x26\x27\xd4\x3e\x9f\x35\x11\xfe\x17\x38\xae\x43\xbd\x0c\x53\xd4\x15\x31\xd8\x11\x0c\x00\x8d\x04\xcc\xec\x94\xa2\xcc\xe6\xdc\x11\x94\x8e\x46\xe4\x5c\xa3\x0d\x85\x57\x53\x5a\x6d\x8d\xf8\x71\xad\x2e\x43\x45\x1d\xc2\x66\xa7\x6e\xee\x94\xda\xf4\x21\xbc\x84\x41\x63\xa8\xce\x7a\xbf\xf8\xfa\x69\xd7\x8a\x8c\x87\x70\x5f\xf4\x3e\xcb\x80\x1c\x19\x8c\x6d\x10\xcc\x1a\x22\xdc\x5d\x03\xbf\xe6\xd4\x4e\x7b\x88\x2c\xa4\x7c\xd5\xe0\xfd\xa5\x51\xbb\xf2\x90\xa3\x04\x5c\xf3\x08\x23\xeb\xe0\xa4\x0f\x85\x32\x95\x27\x7e\x38\x9d\x17\x0a\xf5\xb5\xb6\xb7\x1b\x4f\xda\xf6\xab\x30\x5c\x2e\xf0\x0a\x4e\x9a\xa3\x35\xeb\xc1\x37\x62\x2d\xac\x31\xf5\x8c\x51\x57\x3f\x2f\x63\x58\x23\x7d\x76\xe6\x73\x28\xc9\x09\x8a\xf7\x1d\x84\x41\x02\x34\x7a\x32\x62\x16\x51\xf9\x7f\x96\x0a\xc5\x31\x49\xb1\xe2\xe1\x4f\xc8\xab\x02\x8d\xfa\x44\x17\x0d\x2d\x98\xcf\x37\x33\xf0\x46\x19\xba\x7e\x7f\xd5\x09\xbe\xf3\xb6\xed\x64\xfd\x08\x75\x1d\x06\x5e\xf6\xd6\x11\xdc\x54\xe4\x14\x7d\x29\xdc\x20\x1e\xae\xdf\x5f\x2d\x00\x3f\x0a\xb7\xcb\x30\x0a\xf0\xfd\xe4\x3a\x26\x0e\x78\x05\xfd\xa4\x3f\xf8\x77\xf8\x8d\x50\xe9\xa5\x95\x35\x84\x4f\xba\x28\xbc\xab\x91\xe2\x38\x96\x98\x1d\x2d\xc1\xda\x92\xea\xc2\xe2\x07\x6a\xb2\xe5\x34\x79\xb2\x41\xf1\xd2\x0b\xf9\xd6\x5e\x11\x4a\x72\x8d\xa6\x1a\x62\x1c\x2e\x3e\x37\x53\xe4\x54\xc7\x2d\x70\x7e\x0e\xab\xd7\xe8\x76\x6e\xc2\x29\xaf\x04\xea\x9d\xc1\x51\x91\x91\x94\x24\xa3\x06\x28\xc2\xc7\x45\x43\xd5\x6d\xa5\x6b\xf4\x42\x1f\xed\x3c\x7b\x3f\x9c\x15\xab\x9b\x35\x16\xe8\x5c\xa0\xc7\x0c\x99\xde\x57\xd6\xe3\x5b\x42\xa7\x67\x3f\x57\x5a\x6f\xc2\x2a\xa6\x42\xa4\x32\xab\x47\xd4\x94\xd5\x27\x4a\x95\xa9\x7f\xdc\xc0\xf1\x0a\xcf\x9b\x60\x6a\x31\xb4\xd5\x3b\x42\x4d\xfe\xf0\xd8\x25\xb9\x4a\x47\x36\x91\x81\x62\x30\x31\x30\x18\x2d\x23\xdb\x0a\x77\x71\xfc\x7e\xbc\x8a\xa1\xe2\x30\x00\xed\x54\xd7\xca\x33\x44\x34\x09\x5c\xd0\xc8\xe1\xb8\x08\xbd\x3e\xfa\xb2\x0e\x94\x11\x8e\x90\x29\x4e\x2d\x71\x5b\x3d\xc4\xf4\x7a\x61\x47\x0f\xdd\xf8\x3c\xae\xf6\x1a\xae\xbd\xc8\xf5\x9e\x9c\x2a\x9e\x74\x36\xdc\x5d\xe6\xf2\xa6\xff\xf6\x93\x41\x18\xa4\xbe\x78\x10\x8f\x47\x9f\x0d\xbe\x71\xaa\x15\x4f\x42\x9a\xef\xef\xde\x97\x9b\xfb\x77\xea\xdb\xde\x5a\xc8\xd5\x38\x7f\x51\xd7\x14\xe7\xb6\xd2\x12\x32\x02\x57\x99\xd0\x95\x46\xc8\x1e\xc2\x35\x82\x63\xda\x4c\x46\x98\x20\x95\xa0\xab\xd7\x1f\x0c\x4e\x51\x69\xcc\xf4\xea\xe0\x17\xfe\x38\x49\x25\x52\x61\x0d\x93\x4f\xd9\xa3\xaf\x38\x35\x55\x78\xae\xd3\x6a\x79\xe8\x6e\xb1\xe7\xfc\xaf\x7d\x9e\x0a\x9d\xf5\x92\xff\xef\xcf\x03\xec\xc7\x9e\x49\x16\x31\x40\x69\x65\xdd\xe4\x5b\xc2\xba\x69\xb7\x1a\xfd\x7c\x5e\x9f\xb3\xa3\xae\xe9\x23\xfe\xbb\x04\xf3\xf9\x71\x6b\x7d\x11\x6a\x48\xc1\xd2\xfb\x45\x5c\xbe\x26\xbf\x2e\xe1\x05\x88\x9c\xc4\x24\x10\x80\xdc\xb2\x87\xd2\x3a\x0f\xc2\x9a\x91\x56\xc2\xc7\xbb\xc3\x58\x49\x9c\x3c\xd9\xfb\x7b\x00\xd4\x2e\xf9\x52\x27\x13\x00\x00")
+
+func monitoringDefaultsRulesYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_monitoringDefaultsRulesYaml,
+		"monitoring-defaults/rules.yaml",
+	)
+}
+
+func monitoringDefaultsRulesYaml() (*asset, error) {
+	bytes, err := monitoringDefaultsRulesYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "monitoring-defaults/rules.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _monitoringDefaultsServicemonitorYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9c\x53\xc1\x6e\xd5\x40\x0c\xbc\xe7\x2b\x2c\x71\x01\xa1\x24\xa0\xde\xc2\x11\x89\x53\x01\x89\x22\xae\x91\xb3\x99\xbe\xb7\x4d\xb2\xbb\xb2\x9d\x88\x4a\xfd\x78\xb4\x49\xfa\xde\x6b\x69\x85\xc4\x31\xf6\x78\x76\x3c\x9e\x94\x65\x59\x70\xf2\xbf\x20\xea\x63\x68\x68\x8a\xc1\x5b\x14\x1f\x0e\x95\x8b\x82\xa8\x95\x8b\x53\xbd\x7c\x2c\x06\x1f\xfa\x86\x6e\x20\x8b\x77\xf8\xba\xa1\x8a\x09\xc6\x3d\x1b\x37\x05\x51\xe0\x09\x0d\x0d\x57\x5a\xea\x9c\x32\x4c\xa3\xec\x65\x4d\xec\x72\x6f\xee\x50\xea\xbd\x1a\xa6\x82\x68\xe4\x0e\xa3\xe6\x49\x22\x4e\xa9\x1a\xe6\x0e\x12\x60\xd0\xca\xc7\xfa\xc4\xf6\x4a\xdf\xc5\x29\xc5\x80\x60\x0d\x5d\x3c\xa7\x09\x2e\x33\xde\xc5\xee\x3a\xd3\x37\x74\xc2\x5d\x4a\xb9\xc1\x08\x67\x51\x32\x94\x68\x62\x73\xc7\x6f\xb9\xb7\x7d\x97\xd4\xe3\x96\xe7\xd1\x0a\x22\xfd\x1b\x79\x7d\xa1\x9b\xce\xfc\x0d\x71\xf2\x0a\x59\x20\x7b\x2b\x49\x5c\x7c\x0f\xd9\x16\xdf\x56\x2b\x88\x10\xfa\x14\x7d\xb0\x75\xf5\x92\x52\x14\x6b\xe8\x68\x96\x74\x9d\x53\x77\xc4\x84\xcb\x4a\x62\x3b\x36\x54\x4f\x30\xf1\x6e\x2b\xf9\x60\x90\x85\xc7\x86\xae\x3e\x6c\x95\x0e\x2c\x90\x9f\x71\x40\xf8\xe2\x47\x34\x54\x2f\x2c\xb5\xcc\xa1\x56\x38\x81\x69\xfd\xd4\xbf\xac\xd4\x3b\xb0\x73\x71\x0e\x56\x5b\x1e\x5c\x89\x6c\xd4\xcf\x31\xdc\xfa\xc3\x69\x43\xfe\x1f\x42\xc7\x95\x13\xdb\x29\xf2\x63\x90\x6c\xf1\x33\x2f\x88\x04\x6b\x0e\x7c\x38\x9c\xdc\x67\x67\x6b\x14\x05\x69\x64\x87\x9d\xc3\x58\x0e\xb0\xfd\xaa\x77\xb1\xdb\xcb\x3b\x68\x42\xb0\x17\xc2\xf7\x3a\x9d\xc6\x59\x1c\x9e\xde\xb2\xa4\xb6\xcd\x89\x6e\xcf\x1a\xdb\xc7\x6b\xb5\xdc\xf7\x02\xd5\x76\xd3\xd1\xe6\x7c\xbe\xa4\x2c\xc4\x7e\x7b\x62\x3b\xd7\x8f\xe7\xeb\xbd\xa1\xef\x61\xbc\xa7\x01\x48\x3b\x44\xc9\x8e\x6c\xc4\x02\xca\xf1\xf5\xb7\xde\x91\x45\xb2\x23\x2e\x92\x4d\x1c\x7a\xca\x7f\x9a\x5a\x14\x7c\x3a\x67\xed\x91\x64\x27\x5f\x8b\x3d\xc5\xb0\xcd\xf3\x84\x35\x60\x2b\xbb\x8b\x63\x0e\x33\x7a\xea\xee\xd7\x76\x5e\xb3\x3c\x33\x3d\xfd\xb7\xab\x67\xf6\x65\xc5\xff\xf0\x2e\x7b\xd2\xb6\x7b\x41\x70\xc0\xef\x86\xde\x0e\x57\xfa\x30\xf8\x80\x07\x98\xeb\x1f\x46\x56\x8d\xef\xda\xea\x7d\xf1\x67\x00\xa6\x2b\xca\xbf\x79\x04\x00\x00")
+
+func monitoringDefaultsServicemonitorYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_monitoringDefaultsServicemonitorYaml,
+		"monitoring-defaults/servicemonitor.yaml",
+	)
+}
+
+func monitoringDefaultsServicemonitorYaml() (*asset, error) {
+	bytes, err := monitoringDefaultsServicemonitorYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "monitoring-defaults/servicemonitor.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _rolebindingsYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x94\x41\x6f\xdb\x30\x0c\x85\xef\xfa\x15\x44\xef\x4a\x31\xf4\x32\xf8\xb8\x1d\x76\x2f\xb0\xdd\x19\x89\x73\x39\xcb\x92\x40\xd2\x29\xb6\x5f\x3f\x38\x49\xdb\x25\xb6\xb3\x78\x4b\x4f\xb6\x05\xf9\x7d\x14\xf9\x9e\xb0\xf2\x37\x12\xe5\x92\x1b\x90\x2d\x86\x0d\x0e\xf6\x54\x84\x7f\xa1\x71\xc9\x9b\xee\xa3\x6e\xb8\xdc\xef\x3e\xb8\x8e\x73\x6c\xe0\x73\x1a\xd4\x48\x1e\x4b\xa2\x4f\x9c\x23\xe7\xd6\xf5\x64\x18\xd1\xb0\x71\x00\x19\x7b\x6a\xa0\x1b\xb6\xe4\xb1\xb2\x92\xec\x48\xfc\xf8\x99\xc8\x3c\xc6\x9e\xb3\x93\x92\xe8\x91\xbe\x8f\xbb\xb1\xf2\x17\x29\x43\xbd\x40\x76\x00\x13\xf0\x2b\x47\x7f\xaa\x51\xdf\xbc\xea\x57\x3e\x32\x74\xd8\xfe\xa0\x60\xda\x38\xbf\x0a\xf2\x55\x49\x16\x4e\xe1\x9c\xf7\xde\xfd\x7b\xb7\x66\xda\xf4\x52\xfe\x83\xfa\x50\xb2\x49\x49\x89\xc4\xc9\x90\xe8\xa4\x70\x1d\x5b\xe5\xe1\xee\xce\x01\x08\x69\x19\x24\xd0\x71\x2d\x97\x48\xea\x00\x76\x24\xdb\xe3\x52\x4b\xb6\x7f\x26\xd6\xc3\xcb\x33\x5a\x78\x5a\x21\x77\xaf\x86\x36\x9c\xa9\xd6\x15\x22\xd8\x93\x56\x0c\xe7\x85\xfd\xb5\xa0\x4c\xf6\x5c\xa4\xe3\xdc\x1e\xfb\x38\x27\x7e\xd8\x53\x4b\xe2\xc0\x7b\x82\x87\x70\x68\x72\xe0\x28\x6b\x91\x33\x04\xca\xb1\x16\xce\x36\x4a\x79\xa8\x25\x2e\x69\xb6\x74\x59\xbb\x7b\xd0\x4d\x40\xb3\x44\xf3\x47\xe1\x1e\x5b\x8a\xdc\x92\x5a\xe5\x7c\x1d\xe5\x3f\x3d\xb8\x9c\xd8\x05\x2b\xde\x3e\xaa\xa7\x80\xb7\x9c\x02\xbc\x75\xf0\x32\xe3\x2c\xab\x97\x01\xb7\x0f\xed\x9f\x6e\xf3\x63\x60\x16\x03\x3b\xf1\xf3\xd4\x03\x57\x5b\xf7\xdd\x06\x3f\x73\x9c\xdb\x0d\x7d\x2a\x7e\x3a\xf0\xc3\x9f\xfb\x4b\x60\x3a\xc9\x97\xbb\xed\xba\x32\x7e\x0f\x00\xbd\x1e\x6f\x0f\xc7\x06\x00\x00")
 
 func rolebindingsYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -312,7 +377,7 @@ func rolebindingsYaml() (*asset, error) {
 	return a, nil
 }
 
-var _runtimesYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xac\xd0\x31\x8e\x84\x30\x0c\x05\xd0\x3e\xa7\xc8\x05\xc2\x6a\xbb\x55\xda\xbd\xc1\x14\xd3\x5b\xc4\x02\x8b\xc4\xa0\x38\xc0\x1c\x7f\x04\x1a\x26\x40\xed\xf2\x7f\x4b\xef\x4b\x86\x89\x9e\x98\x85\x46\xf6\x96\xc7\x80\xcd\xf0\x27\x0d\x8d\x3f\xcb\xaf\x19\x88\x83\xb7\x8f\x99\x0b\x25\xfc\x8f\x20\x62\x12\x16\x08\x50\xc0\x1b\x6b\x19\x12\x7a\xcb\x0b\x05\x02\xd3\x03\x87\x88\xf9\x9b\x9d\x73\x46\x87\x76\xf8\x9a\x30\x53\x42\x2e\x10\xef\x3b\xd7\xa3\xc6\x68\x9b\x67\xae\x2b\x7b\xd2\x60\xe3\xcc\x50\xa8\xad\xf2\x51\x68\xe0\x12\xa9\xeb\x4b\xb5\x3f\x59\x85\x9e\xe8\xf4\x8e\x3d\x69\xb0\xeb\x2a\x55\xdd\x82\x0a\x0a\x92\x30\x74\x78\x92\x8f\x46\x8d\xcf\x37\x3c\xab\xd1\xdb\xfd\x8a\x6f\xcd\x3b\x00\x00\xff\xff\xc7\xad\x48\x21\x9f\x03\x00\x00")
+var _runtimesYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xac\xd0\x31\x8e\x84\x30\x0c\x85\xe1\x3e\xa7\xc8\x05\xc2\x6a\xbb\x55\xda\xbd\xc1\x14\xd3\x5b\xc4\x02\x8b\xc4\xa0\xc4\xc0\x1c\x7f\x04\x1a\x26\x40\xed\xf2\x77\xa4\xef\x49\x81\x89\x9e\x98\x0b\x8d\xec\x2d\x8f\x01\x9b\xe1\xaf\x34\x34\xfe\x2c\xbf\x66\x20\x0e\xde\x3e\x66\x16\x4a\xf8\x1f\xa1\x14\x93\x50\x20\x80\x80\x37\xd6\x32\x24\xf4\x96\x17\x0a\x04\xa6\x07\x0e\x11\xf3\xb7\x9d\x73\x46\x87\x76\xf8\x9a\x30\x53\x42\x16\x88\xf7\x9d\xeb\xa3\xc6\x68\x9b\x67\xae\x2b\x7b\x69\xb0\x71\x66\x10\x6a\xab\x7c\x1c\x34\xf0\x12\xa9\xeb\xa5\xda\x9f\x56\xa1\x27\x3a\x7d\xc7\x5e\x1a\xec\xba\x96\xaa\x6e\xa1\x82\x42\x49\x18\x3a\x3c\xc9\xc7\x45\x8d\xcf\x37\x3c\xab\xd1\xdb\xf4\x15\x17\x4a\xf8\x1e\x00\xc7\xad\x48\x21\x9f\x03\x00\x00")
 
 func runtimesYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -332,7 +397,47 @@ func runtimesYaml() (*asset, error) {
 	return a, nil
 }
 
-var _traefikYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x91\xd1\x6f\xd3\x30\x10\xc6\xdf\xf3\x57\x9c\x22\xf5\x09\x39\x59\xfb\xc2\x94\xb7\xd2\x65\x30\x01\x63\x6a\x3a\xd0\x9e\x2a\xd7\xbe\x36\x56\x1d\xdb\x3a\x5f\x2a\xc2\xd8\xff\x8e\xdc\x76\x5b\x27\x81\x40\x08\xde\x92\xf3\xdd\xef\xbb\xfb\x3e\x21\x44\x26\x83\xf9\x8c\x14\x8d\x77\x15\xb4\x68\xbb\x42\x49\x66\x8b\x85\xf1\xe5\x6e\x9c\x6d\x8d\xd3\x15\xbc\x43\xdb\xcd\x5a\x49\x9c\x75\xc8\x52\x4b\x96\x55\x06\xe0\x64\x87\x15\x30\x49\x5c\x9b\xad\x50\xa4\x8f\xb5\x18\xa4\xc2\x0a\xb6\xfd\x0a\x45\x1c\x22\x63\x97\xc5\x80\x2a\x8d\xa8\x04\xa9\xa0\x65\x0e\xb1\x2a\xcb\xd1\xfd\xfb\xdb\x37\xf5\xfc\xba\x5e\xd4\xcd\x72\x7a\x73\xf5\x30\x2a\x23\x4b\x36\xaa\xdc\x37\xc6\xf2\x04\x2e\x26\xaf\x8b\xb3\x62\x72\x36\x7e\xd5\x87\xc3\x67\xc1\x9b\x6f\xd9\x3f\x3c\xe1\xff\xad\xff\xf3\xd5\x01\x22\x72\xc2\x02\x6c\xac\x5f\x49\x5b\x1c\xe4\x2e\x70\x2d\x7b\xcb\x73\xdc\x98\xc8\x34\x54\x90\x8f\xee\x9b\xbb\x66\x51\x7f\x5c\x5e\xd4\x97\xd3\xdb\x0f\x8b\xe5\xbc\x7e\x7b\xd5\x2c\xe6\x77\xcb\xf9\xf4\xcb\xc3\x28\xcf\x00\x76\xd2\xf6\x18\x67\xde\x31\x3a\xae\xe0\xbb\xd8\x73\x35\x06\xeb\x87\x2e\x95\xf6\xff\x00\xc1\xeb\xa9\x73\x3e\xad\xe9\x5d\x7c\xac\x02\x04\xf2\x1d\x72\x8b\x7d\x4c\xb6\x05\x9f\xee\xcc\xcf\xcf\xce\x27\xf9\x2f\x5a\xa2\x22\x19\xb0\x82\x9c\xa9\xc7\x43\x53\x20\xbf\x33\x1a\xe9\x09\x9b\x3c\x24\x87\x8c\xf1\xca\x6d\x08\xe3\xa9\x5e\xbf\xb2\x26\xb6\xa8\x1b\xa4\x9d\x51\xf8\xfc\x02\x80\x4e\xae\x2c\xea\x14\x4c\x8f\x47\xb2\xf1\x64\x78\x98\x59\x19\xe3\xf5\x3e\xb4\xfc\x60\x96\x50\xb6\x8f\x8c\x24\x14\x19\x36\x4a\xda\xc3\x2a\xa6\x93\x9b\x27\x26\x61\xf0\xd1\xb0\xdf\x7b\x49\xd2\xa9\x16\xa9\xec\x0c\x91\x27\xd4\xc2\x9a\x15\x49\x1a\xc4\x31\xac\xc7\x7b\x59\x6e\x2a\xc8\x27\xc5\x78\x5c\x8c\xcf\x0f\x45\xf6\x16\xe9\xd4\x38\x01\x5b\x4c\xcc\xd9\x51\x7b\xaa\xb5\x77\xf1\x93\xb3\xc3\x23\xc5\x87\x34\xe1\xa9\x82\xbc\xfe\x6a\x22\xc7\xfc\xc5\xa0\xf3\x1a\x05\x79\x8b\xc5\xb3\x55\xc9\x5c\xe5\x1d\x93\xb7\x22\x58\xe9\xf0\x37\x2c\x00\x5c\xaf\x51\xa5\xbc\xae\x7d\xa3\x5a\xd4\xbd\xc5\x3f\x93\xe9\x64\xb2\xee\xef\xf9\xf1\x65\x76\x26\x5c\xca\xce\xd8\xe1\xc6\x5b\xa3\x92\xee\x0d\xe1\x1a\xe9\xa2\x97\xb6\x61\xa9\xb6\x79\xf6\x23\x00\x00\xff\xff\xff\x29\x37\xab\x71\x04\x00\x00")
+var _supervisorApiYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xbc\x91\xcf\x6e\x13\x41\x0c\xc6\xef\xf3\x14\xbe\xf4\xb8\x4b\xa2\xf4\x80\xe6\xd6\x96\x08\x21\x01\xaa\x52\xd1\x6b\xe5\xcc\xba\x89\xb5\xbb\x33\x23\xdb\xb3\xa8\x20\xde\x1d\x4d\xc8\x02\x81\x5c\x22\x21\x6e\x96\xff\x7c\xf6\xf7\x33\x66\x7e\x24\x51\x4e\xd1\x03\x66\x16\xda\xb1\x9a\xa0\x71\x8a\x6d\xff\x5a\x5b\x4e\xaf\xa6\xa5\xeb\x39\x76\x1e\x6e\xee\xdf\x3d\x90\x4c\x1c\xc8\x8d\x64\xd8\xa1\xa1\x77\x00\x11\x47\xf2\x30\x2d\x71\xc8\x7b\x5c\xb6\xfd\x4a\xdb\x80\x66\x03\xb5\x9c\x9c\x66\x0a\xb5\x49\x7f\x0c\xd6\x70\x9e\xe8\xcb\x96\x24\x92\x91\xfe\x4c\x6a\xc6\x40\x1e\x3a\x7a\xc6\x32\xd8\x21\x9d\x93\x98\x87\xeb\xeb\x95\x03\xd8\x49\x2a\xd9\xc3\xe9\x06\x80\x69\x36\x30\xdf\xe0\x00\x02\xde\x96\xd8\x0d\xe4\xe1\xea\xeb\xc3\x7a\xf3\xb8\xde\x3c\xdd\xdd\x3c\xdd\x7e\xfa\xf8\xe6\xfd\xfa\xdb\xd5\xac\x75\x2f\x9c\x84\xed\xe5\x03\x47\x1e\xcb\xe8\x61\xb9\x58\x2c\x7e\x29\xce\xe5\x9a\x77\xae\x69\x1a\xf7\x3b\x2e\xd9\x62\x68\xb1\xd8\x3e\x09\x7f\x39\x4f\xec\x6e\x28\x6a\x24\x9b\x34\x9c\x43\xa6\x2f\x6a\x34\xfa\x7e\xa5\x8d\x96\x5c\x01\x69\x92\x06\xbb\x91\xa3\x93\x32\x90\x7a\xd7\xd4\xa7\xbc\xad\xa7\x6a\x9d\x6b\xfe\xf2\x2e\xa4\xa9\x48\xa0\x63\x99\x2c\x74\x1a\x31\xeb\x3e\x59\xc5\x3a\x91\x6c\x8f\xa5\x20\x84\x46\x87\xb0\xa3\x81\x8e\xe1\x8e\x2a\xe6\x06\x06\x56\xbb\x78\x9b\xa5\x9e\xa2\x24\x3b\x98\x3f\xbf\xee\x52\xc9\x40\x62\xfc\xcc\x01\x8d\xc2\x9e\x42\xff\x87\xea\xc9\xb9\xff\xeb\x23\x13\xd3\xe7\x7f\xf5\x90\xcb\x1c\x7e\x1f\x00\xef\x60\xce\x23\x9e\x03\x00\x00")
+
+func supervisorApiYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_supervisorApiYaml,
+		"supervisor-api.yaml",
+	)
+}
+
+func supervisorApiYaml() (*asset, error) {
+	bytes, err := supervisorApiYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "supervisor-api.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _tokenViewerYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x90\xc1\x4a\xc6\x40\x0c\x84\xef\xfb\x14\x79\x81\xad\x88\x17\xe9\x4d\x3d\x78\xff\x05\xef\xe9\x36\x6a\x6c\x9b\x2c\x49\xb6\xa2\x4f\x2f\x0b\xf2\x23\x68\xc5\xf3\xc0\x7c\xdf\x0c\x56\x7e\x24\x73\x56\x19\x61\xbf\x4c\x0b\xcb\x3c\xc2\x03\xd9\xce\x85\x6e\x4a\xd1\x26\x91\x36\x0a\x9c\x31\x70\x4c\x00\x82\x1b\x8d\xb0\x5c\x79\x0e\x5d\x48\xf2\xce\xf4\x46\xf6\x15\x78\xc5\xd2\xd3\x36\x51\xf6\x77\x0f\xda\x52\xce\x39\x7d\x67\xd8\x84\x65\xc0\x16\x2f\x6a\xfc\x81\xc1\x2a\xc3\x72\xed\x03\xeb\xc5\x99\x7e\xb7\x36\x0f\xb2\x93\xae\x74\xcb\x32\xb3\x3c\xff\xc7\xc0\x74\xa5\x13\x3d\x75\x47\xac\x7c\x6f\xda\xea\x1f\xb4\x04\xf0\x03\x76\xee\xee\x9b\x92\xb7\xe9\x95\x4a\x78\x2f\xcc\xf0\xeb\x2f\x00\x87\x36\x00\x87\x8f\x7c\x0e\x00\x8b\x88\x7d\x7b\x72\x01\x00\x00")
+
+func tokenViewerYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_tokenViewerYaml,
+		"token-viewer.yaml",
+	)
+}
+
+func tokenViewerYaml() (*asset, error) {
+	bytes, err := tokenViewerYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "token-viewer.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _traefikYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x92\x4f\x6b\xdb\x4c\x10\x87\xef\xfa\x14\x83\xc0\xa7\x17\xc9\x49\x2e\x6f\xd0\xcd\x75\x94\xd4\x84\x26\xc6\x76\x0b\x3d\x85\xf1\x6a\x6c\x2f\x5e\xed\x2c\x3b\x23\x53\x35\xcd\x77\x2f\x6b\xc7\xf9\x03\x09\x2d\xa5\xc5\x17\x6b\x34\xf3\xec\xec\xf3\x53\x51\x14\x19\x06\xfb\x85\xa2\x58\xf6\x15\x6c\xc8\xb5\xa5\x41\x55\x47\xa5\xe5\xe1\xee\x34\xdb\x5a\xdf\x54\xf0\x91\x5c\x3b\xde\x60\xd4\xac\x25\xc5\x06\x15\xab\x0c\xc0\x63\x4b\x15\x68\x44\x5a\xd9\x6d\x61\x62\xf3\x58\x93\x80\x86\x2a\xd8\x76\x4b\x2a\xa4\x17\xa5\x36\x93\x40\x26\x8d\x98\x04\xa9\x60\xa3\x1a\xa4\x1a\x0e\x07\xf7\xd7\x9f\x3f\xd4\xb3\x9b\x7a\x51\xcf\xef\x46\xd3\xc9\xc3\x60\x28\x8a\x6a\xcd\x70\xdf\x28\xc3\x17\xf0\xe2\xec\xff\xf2\xa4\x3c\x3b\x39\xfd\xaf\x0b\x87\xbf\xa5\xae\xbf\x67\x7f\xf1\x0a\xff\x6e\xfd\xb7\x57\x07\x10\xd2\x84\x05\x58\x3b\x5e\xa2\x2b\x0f\xb6\x2e\x68\x85\x9d\xd3\x19\xad\xad\x68\xec\x2b\xc8\x07\xf7\x93\x4f\xa3\xab\xfa\x6e\x31\x1b\xd5\x97\x93\xeb\xbb\x59\x7d\x35\x99\x2f\x66\x5f\x1f\x06\x79\x06\xb0\x43\xd7\x91\x8c\xd9\x2b\x79\xad\xe0\x47\xb1\x47\x36\x14\x1c\xf7\x6d\x2a\xed\x9f\x01\x02\x37\x23\xef\x39\x09\x66\x2f\xc7\x2a\x40\x88\xdc\x92\x6e\xa8\x93\x14\x7a\xe0\x94\x50\x7e\x7e\x72\x7e\x96\xbf\xd3\x22\x26\x62\xa0\x0a\x72\x8d\x1d\x1d\x9a\x42\xe4\x9d\x6d\x28\x3e\x61\x93\xbe\xe8\x49\x49\x26\x7e\x1d\x49\x5e\x9e\xd7\x2d\x9d\x95\x0d\x35\x73\x8a\x3b\x6b\xe8\xf9\x0d\x00\x79\x5c\x3a\x6a\x52\x26\x1d\x3d\x92\x2d\x47\xab\xfd\xd8\xa1\xc8\xcd\xfe\x93\xcb\x0f\x9e\x0a\xe3\x3a\x51\x8a\x85\x89\x56\xad\x41\x77\x58\xc5\xb6\xb8\x7e\x62\x46\x0a\x2c\x56\xf9\x1d\x8d\xd3\xdb\xf9\x64\x71\x7b\x14\x99\x7e\x8a\xeb\x37\x3a\x17\xa3\xab\x63\x8b\xb2\xa3\xf8\x52\x61\x01\x5b\x4a\xf4\xf1\xe3\x16\xa3\xa6\x61\x2f\xb7\xde\xf5\x47\x26\x87\x34\xc1\xb1\x82\xbc\xfe\x66\x45\x25\x7f\x35\xe8\xb9\xa1\x22\xb2\xa3\xf2\x59\x5a\xd2\x6c\xd8\x6b\x64\x57\x04\x87\x9e\x7e\xc1\x02\xa0\xd5\x8a\x4c\x4a\xee\x86\xe7\x66\x43\x4d\xe7\xe8\xf7\x8e\x69\x31\x49\xfc\x73\xbe\xbc\x4e\xd1\x86\x4b\x6c\xad\xeb\xa7\xec\xac\x49\xd7\x9b\x46\x5a\x51\xbc\xe8\xd0\xcd\x15\xcd\x36\xcf\x7e\x0e\x00\x7f\x1f\xfc\x1a\x76\x04\x00\x00")
 
 func traefikYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -414,8 +519,13 @@ var _bindata = map[string]func() (*asset, error){
 	"metrics-server/metrics-server-deployment.yaml": metricsServerMetricsServerDeploymentYaml,
 	"metrics-server/metrics-server-service.yaml":    metricsServerMetricsServerServiceYaml,
 	"metrics-server/resource-reader.yaml":           metricsServerResourceReaderYaml,
+	"monitoring-defaults/dashboards.yaml":           monitoringDefaultsDashboardsYaml,
+	"monitoring-defaults/rules.yaml":                monitoringDefaultsRulesYaml,
+	"monitoring-defaults/servicemonitor.yaml":       monitoringDefaultsServicemonitorYaml,
 	"rolebindings.yaml":                             rolebindingsYaml,
 	"runtimes.yaml":                                 runtimesYaml,
+	"supervisor-api.yaml":                           supervisorApiYaml,
+	"token-viewer.yaml":                             tokenViewerYaml,
 	"traefik.yaml":                                  traefikYaml,
 }
 
@@ -474,9 +584,16 @@ var _bintree = &bintree{nil, map[string]*bintree{
 		"metrics-server-service.yaml":    &bintree{metricsServerMetricsServerServiceYaml, map[string]*bintree{}},
 		"resource-reader.yaml":           &bintree{metricsServerResourceReaderYaml, map[string]*bintree{}},
 	}},
-	"rolebindings.yaml": &bintree{rolebindingsYaml, map[string]*bintree{}},
-	"runtimes.yaml":     &bintree{runtimesYaml, map[string]*bintree{}},
-	"traefik.yaml":      &bintree{traefikYaml, map[string]*bintree{}},
+	"monitoring-defaults": &bintree{nil, map[string]*bintree{
+		"dashboards.yaml":     &bintree{monitoringDefaultsDashboardsYaml, map[string]*bintree{}},
+		"rules.yaml":          &bintree{monitoringDefaultsRulesYaml, map[string]*bintree{}},
+		"servicemonitor.yaml": &bintree{monitoringDefaultsServicemonitorYaml, map[string]*bintree{}},
+	}},
+	"rolebindings.yaml":   &bintree{rolebindingsYaml, map[string]*bintree{}},
+	"runtimes.yaml":       &bintree{runtimesYaml, map[string]*bintree{}},
+	"supervisor-api.yaml": &bintree{supervisorApiYaml, map[string]*bintree{}},
+	"token-viewer.yaml":   &bintree{tokenViewerYaml, map[string]*bintree{}},
+	"traefik.yaml":        &bintree{traefikYaml, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
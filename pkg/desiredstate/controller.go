@@ -0,0 +1,256 @@
+package desiredstate
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	apisv1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	controllersv1 "github.com/k3s-io/k3s/pkg/generated/controllers/k3s.cattle.io/v1"
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
+	coreclient "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+)
+
+// statusSyncDelay is the delay before the status of NodeConfigs is updated after a node changes, so that
+// changes to many nodes are reflected in a single update.
+var statusSyncDelay = 5 * time.Second
+
+// managedCacheTimeout is the time to wait for the ConfigMap cache to reflect the labels and taints most
+// recently recorded for a node, after which the cached value is used.
+var managedCacheTimeout = 30 * time.Second
+
+// pendingManaged is the labels and taints most recently recorded for a node, and the time that they were
+// recorded.
+type pendingManaged struct {
+	managed *Managed
+	time    time.Time
+}
+
+type handler struct {
+	nodes       coreclient.NodeController
+	nodeCache   coreclient.NodeCache
+	configs     controllersv1.NodeConfigController
+	configCache controllersv1.NodeConfigCache
+	configMaps  coreclient.ConfigMapController
+	cmCache     coreclient.ConfigMapCache
+
+	mu sync.Mutex
+	// generations holds the generation of each NodeConfig that was last applied to the nodes, so that
+	// nodes are only enqueued when the spec changes, not when the status is updated.
+	generations map[string]int64
+	// pending holds the labels and taints most recently recorded for each node, until the ConfigMap cache
+	// reflects them.
+	pending map[string]pendingManaged
+}
+
+// RegisterController registers a controller that sets the labels and taints from NodeConfigs on the nodes
+// that they select, and maintains the status of each NodeConfig using the desired state hash annotations
+// set on each node by its agent.
+func RegisterController(ctx context.Context, nodes coreclient.NodeController, configs controllersv1.NodeConfigController, configMaps coreclient.ConfigMapController) {
+	h := &handler{
+		nodes:       nodes,
+		nodeCache:   nodes.Cache(),
+		configs:     configs,
+		configCache: configs.Cache(),
+		configMaps:  configMaps,
+		cmCache:     configMaps.Cache(),
+		generations: map[string]int64{},
+		pending:     map[string]pendingManaged{},
+	}
+	logrus.Infof("Starting node config controller")
+	nodes.OnChange(ctx, "node-config", h.onChangeNode)
+	configs.OnChange(ctx, "node-config", h.onChangeConfig)
+}
+
+func (h *handler) onChangeNode(key string, node *corev1.Node) (*corev1.Node, error) {
+	if node == nil || node.DeletionTimestamp != nil {
+		return node, nil
+	}
+	configs, err := h.configCache.List(labels.Everything())
+	if err != nil {
+		return node, err
+	}
+	managed := ManagedFromConfigMap(h.cachedManagedConfigMap())[node.Name]
+	// The cache may not yet reflect the labels and taints most recently recorded for the node; the node is
+	// retried once it does, so that they are not dropped from the record if the node has changed since.
+	if !h.cacheSynced(node.Name, managed) {
+		h.nodes.EnqueueAfter(node.Name, time.Second)
+		return node, nil
+	}
+	if len(configs) == 0 && managed == nil {
+		return node, nil
+	}
+
+	state := Merge(configs, node, managed)
+	updated := node.DeepCopy()
+	current, changed := ApplyToNode(updated, state, managed)
+	// Record the labels and taints set from NodeConfigs before setting them, so that they are removed
+	// when no longer set, even if the node is changed before this handler is retried.
+	if !managedEqual(managed, current) {
+		if err := SetManaged(h.configMaps, node.Name, current); err != nil {
+			return node, err
+		}
+		h.mu.Lock()
+		h.pending[node.Name] = pendingManaged{managed: current, time: time.Now()}
+		h.mu.Unlock()
+	}
+	if changed {
+		logrus.Infof("Updating labels and taints on node %s from NodeConfigs %v", node.Name, state.Configs)
+		if node, err = h.nodes.Update(updated); err != nil {
+			return node, err
+		}
+	}
+	for _, nc := range configs {
+		h.configs.EnqueueAfter(nc.Name, statusSyncDelay)
+	}
+	return node, nil
+}
+
+func (h *handler) onChangeConfig(key string, nc *apisv1.NodeConfig) (*apisv1.NodeConfig, error) {
+	h.mu.Lock()
+	specChanged := nc == nil || h.generations[key] != nc.Generation
+	if nc == nil {
+		delete(h.generations, key)
+	} else {
+		h.generations[key] = nc.Generation
+	}
+	h.mu.Unlock()
+
+	if specChanged {
+		if nc != nil && nc.DeletionTimestamp == nil {
+			if err := Validate(nc.Spec); err != nil {
+				logrus.Warnf("NodeConfig %s contains invalid labels or taints, which will not be applied: %v", nc.Name, err)
+			}
+		}
+		// Labels and taints are applied by the node handler; enqueue all nodes so that changes to the
+		// NodeConfig, including its removal, are applied to any nodes it selects or previously selected.
+		nodes, err := h.nodeCache.List(labels.Everything())
+		if err != nil {
+			return nc, err
+		}
+		for _, node := range nodes {
+			h.nodes.Enqueue(node.Name)
+		}
+	}
+	if nc == nil || nc.DeletionTimestamp != nil {
+		return nc, nil
+	}
+	return nc, h.syncStatus(nc)
+}
+
+// cacheSynced returns true if the cached labels and taints for the node match those most recently recorded
+// for it, or if the cache has not caught up within managedCacheTimeout.
+func (h *handler) cacheSynced(nodeName string, cached *Managed) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p, ok := h.pending[nodeName]
+	if !ok {
+		return true
+	}
+	if managedEqual(p.managed, cached) || time.Since(p.time) > managedCacheTimeout {
+		delete(h.pending, nodeName)
+		return true
+	}
+	return false
+}
+
+// cachedManagedConfigMap returns the managed ConfigMap from the cache, or nil if it does not exist.
+func (h *handler) cachedManagedConfigMap() *corev1.ConfigMap {
+	configMap, err := h.cmCache.Get(metav1.NamespaceSystem, ManagedConfigMap)
+	if err != nil {
+		return nil
+	}
+	return configMap
+}
+
+// syncStatus updates the status of the NodeConfig to reflect the state of the nodes that it selects.
+func (h *handler) syncStatus(nc *apisv1.NodeConfig) error {
+	configs, err := h.configCache.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	nodes, err := h.nodeCache.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	managed := ManagedFromConfigMap(h.cachedManagedConfigMap())
+	states := map[string]*State{}
+	for _, node := range nodes {
+		states[node.Name] = Merge(configs, node, managed[node.Name])
+	}
+
+	now := metav1.Now()
+	if equality.Semantic.DeepEqual(nc.Status, buildStatus(nc.Name, nc.Status, nodes, states, now)) {
+		return nil
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := h.configs.Get(nc.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		status := buildStatus(existing.Name, existing.Status, nodes, states, now)
+		if equality.Semantic.DeepEqual(existing.Status, status) {
+			return nil
+		}
+		if status.NodesApplied == status.NodesSelected && existing.Status.NodesApplied != existing.Status.NodesSelected {
+			logrus.Infof("All %d nodes selected by NodeConfig %s have applied their configuration", status.NodesSelected, existing.Name)
+		}
+		existing = existing.DeepCopy()
+		existing.Status = status
+		_, err = h.configs.UpdateStatus(existing)
+		return err
+	})
+}
+
+// buildStatus returns the status of the named NodeConfig for the given nodes and their desired states. A
+// node has applied its configuration once its agent has retrieved its current desired state from the
+// servers, applied it, and recorded its hash on the node. Kubelet settings are not in effect on nodes that
+// have applied their configuration until k3s is restarted on them, which is not done automatically; such
+// nodes are counted separately.
+func buildStatus(name string, prev apisv1.NodeConfigStatus, nodes []*corev1.Node, states map[string]*State, now metav1.Time) apisv1.NodeConfigStatus {
+	prevNodes := map[string]apisv1.NodeConfigNodeStatus{}
+	for _, n := range prev.Nodes {
+		prevNodes[n.Name] = n
+	}
+
+	status := apisv1.NodeConfigStatus{}
+	for _, node := range nodes {
+		state, ok := states[node.Name]
+		if !ok || !slices.Contains(state.Configs, name) {
+			continue
+		}
+		applied := node.Annotations[nodeconfig.DesiredStateHashAnnotation] == state.Hash()
+		nodeStatus := apisv1.NodeConfigNodeStatus{
+			Name:               node.Name,
+			Applied:            applied,
+			RestartRequired:    applied && node.Annotations[nodeconfig.DesiredStateRestartAnnotation] == "true",
+			LastTransitionTime: now,
+		}
+		if p, ok := prevNodes[node.Name]; ok && p.Applied == applied {
+			nodeStatus.LastTransitionTime = p.LastTransitionTime
+		}
+		if applied {
+			status.NodesApplied++
+		}
+		if nodeStatus.RestartRequired {
+			status.NodesRestartRequired++
+		}
+		status.Nodes = append(status.Nodes, nodeStatus)
+	}
+	sort.Slice(status.Nodes, func(i, j int) bool {
+		return status.Nodes[i].Name < status.Nodes[j].Name
+	})
+	status.NodesSelected = len(status.Nodes)
+	return status
+}
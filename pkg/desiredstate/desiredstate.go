@@ -0,0 +1,324 @@
+// Package desiredstate merges the NodeConfig resources that select a node into the node's desired state,
+// and applies it. Labels and taints are applied to the node object by the servers; kubelet settings and
+// registry mirrors are retrieved from the servers and applied by the node's agent.
+package desiredstate
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	apisv1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	"github.com/rancher/wharfie/pkg/registries"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// KubeletDropIn is the name of the file in the kubelet configuration drop-in directory that kubelet
+// settings from NodeConfigs are written to. It sorts after the drop-ins generated from the node's own
+// configuration, so that its settings take precedence.
+const KubeletDropIn = "40-node-config.conf"
+
+// State is the desired state of a node, merged from all NodeConfigs that select it.
+type State struct {
+	// Configs are the names of the NodeConfigs that select the node, in the order they were merged.
+	Configs []string `json:"configs,omitempty"`
+	// Labels are set on the node.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Taints are set on the node, sorted by key and effect.
+	Taints []apisv1.NodeConfigTaint `json:"taints,omitempty"`
+	// Kubelet contains kubelet settings that are written to the kubelet configuration drop-in directory.
+	Kubelet apisv1.NodeConfigKubelet `json:"kubelet"`
+	// Registries contains registry mirrors that are merged with the node's registries.yaml.
+	Registries apisv1.NodeConfigRegistries `json:"registries"`
+}
+
+// Hash returns a hash of the desired state, as recorded in the desired state hash annotation by the
+// node's agent once the state has been applied.
+func (s *State) Hash() string {
+	b, _ := json.Marshal(s)
+	h := sha256.Sum256(b)
+	return base32.StdEncoding.EncodeToString(h[:])
+}
+
+// Validate returns an error if the NodeConfig sets labels or taints that cannot be applied to a node.
+// Invalid labels and taints are skipped when NodeConfigs are merged.
+func Validate(spec apisv1.NodeConfigSpec) error {
+	var errs []error
+	for key, value := range spec.Labels {
+		if err := validateLabel(key, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, taint := range spec.Taints {
+		if err := validateTaint(taint); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateLabel(key, value string) error {
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+		return fmt.Errorf("invalid value for label %q: %s", key, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func validateTaint(taint apisv1.NodeConfigTaint) error {
+	if errs := validation.IsQualifiedName(taint.Key); len(errs) > 0 {
+		return fmt.Errorf("invalid taint key %q: %s", taint.Key, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsValidLabelValue(taint.Value); len(errs) > 0 {
+		return fmt.Errorf("invalid value for taint %q: %s", taint.Key, strings.Join(errs, "; "))
+	}
+	switch corev1.TaintEffect(taint.Effect) {
+	case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		return nil
+	}
+	return fmt.Errorf("invalid effect %q for taint %q", taint.Effect, taint.Key)
+}
+
+// Selects returns true if the NodeConfig selects the node. Labels that were set on the node from
+// NodeConfigs, as listed in managed, are not considered, so that NodeConfigs cannot select nodes by labels
+// that they set.
+func Selects(nc *apisv1.NodeConfig, node *corev1.Node, managed *Managed) bool {
+	if len(nc.Spec.NodeNames) == 0 && len(nc.Spec.NodeSelector) == 0 {
+		return true
+	}
+	if slices.Contains(nc.Spec.NodeNames, node.Name) {
+		return true
+	}
+	if len(nc.Spec.NodeSelector) == 0 {
+		return false
+	}
+	nodeLabels := labels.Set{}
+	for key, value := range node.Labels {
+		nodeLabels[key] = value
+	}
+	if managed != nil {
+		for _, key := range managed.Labels {
+			delete(nodeLabels, key)
+		}
+	}
+	return labels.SelectorFromSet(nc.Spec.NodeSelector).Matches(nodeLabels)
+}
+
+// Merge returns the desired state of the node, merged from the NodeConfigs that select it in order of
+// name. Labels, kubelet settings, and registry mirrors from later NodeConfigs replace those with the same
+// key from earlier NodeConfigs; taints are replaced if they have the same key and effect. The labels and
+// taints that were set on the node from NodeConfigs are passed in managed.
+func Merge(configs []*apisv1.NodeConfig, node *corev1.Node, managed *Managed) *State {
+	configs = slices.Clone(configs)
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Name < configs[j].Name
+	})
+
+	state := &State{}
+	taints := map[string]apisv1.NodeConfigTaint{}
+	for _, nc := range configs {
+		if nc.DeletionTimestamp != nil || !Selects(nc, node, managed) {
+			continue
+		}
+		state.Configs = append(state.Configs, nc.Name)
+		for key, value := range nc.Spec.Labels {
+			if validateLabel(key, value) != nil {
+				continue
+			}
+			if state.Labels == nil {
+				state.Labels = map[string]string{}
+			}
+			state.Labels[key] = value
+		}
+		for _, taint := range nc.Spec.Taints {
+			if validateTaint(taint) != nil {
+				continue
+			}
+			taints[taintKey(taint.Key, taint.Effect)] = taint
+		}
+		mergeKubelet(&state.Kubelet, nc.Spec.Kubelet)
+		for host, mirror := range nc.Spec.Registries.Mirrors {
+			if state.Registries.Mirrors == nil {
+				state.Registries.Mirrors = map[string]apisv1.NodeConfigMirror{}
+			}
+			state.Registries.Mirrors[host] = *mirror.DeepCopy()
+		}
+	}
+	for _, key := range sortedKeys(taints) {
+		state.Taints = append(state.Taints, taints[key])
+	}
+	return state
+}
+
+func mergeKubelet(dst *apisv1.NodeConfigKubelet, src apisv1.NodeConfigKubelet) {
+	if src.MaxPods != 0 {
+		dst.MaxPods = src.MaxPods
+	}
+	if src.ImageGCHighThresholdPercent != 0 {
+		dst.ImageGCHighThresholdPercent = src.ImageGCHighThresholdPercent
+	}
+	if src.ImageGCLowThresholdPercent != 0 {
+		dst.ImageGCLowThresholdPercent = src.ImageGCLowThresholdPercent
+	}
+	dst.EvictionHard = mergeMap(dst.EvictionHard, src.EvictionHard)
+	dst.SystemReserved = mergeMap(dst.SystemReserved, src.SystemReserved)
+	dst.KubeReserved = mergeMap(dst.KubeReserved, src.KubeReserved)
+}
+
+func mergeMap(dst, src map[string]string) map[string]string {
+	for key, value := range src {
+		if dst == nil {
+			dst = map[string]string{}
+		}
+		dst[key] = value
+	}
+	return dst
+}
+
+// ApplyToNode sets the labels and taints from the desired state on the node, and removes the labels and
+// taints listed in prev that are no longer in the desired state. Labels and taints that were not set from
+// NodeConfigs are not removed. The labels and taints that are now set from NodeConfigs are returned, to
+// replace prev; labels and taints that were already set on the node with the same value are not included
+// unless they were in prev, so that they are not removed when no NodeConfig sets them. prev must not be
+// stored anywhere that the node can modify. Returns true if the node was changed.
+func ApplyToNode(node *corev1.Node, state *State, prev *Managed) (*Managed, bool) {
+	if prev == nil {
+		prev = &Managed{}
+	}
+	current := &Managed{}
+	changed := false
+
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	for _, key := range prev.Labels {
+		if _, ok := state.Labels[key]; ok {
+			continue
+		}
+		if _, ok := node.Labels[key]; ok {
+			delete(node.Labels, key)
+			changed = true
+		}
+	}
+	for _, key := range sortedKeys(state.Labels) {
+		value := state.Labels[key]
+		if existing, ok := node.Labels[key]; ok && existing == value {
+			if slices.Contains(prev.Labels, key) {
+				current.Labels = append(current.Labels, key)
+			}
+			continue
+		}
+		current.Labels = append(current.Labels, key)
+		node.Labels[key] = value
+		changed = true
+	}
+
+	desired := map[string]apisv1.NodeConfigTaint{}
+	for _, taint := range state.Taints {
+		desired[taintKey(taint.Key, taint.Effect)] = taint
+	}
+	taints := make([]corev1.Taint, 0, len(node.Spec.Taints)+len(desired))
+	for _, taint := range node.Spec.Taints {
+		key := taintKey(taint.Key, string(taint.Effect))
+		if d, ok := desired[key]; ok {
+			if taint.Value != d.Value {
+				taint.Value = d.Value
+				current.Taints = append(current.Taints, key)
+				changed = true
+			} else if slices.Contains(prev.Taints, key) {
+				current.Taints = append(current.Taints, key)
+			}
+			delete(desired, key)
+		} else if slices.Contains(prev.Taints, key) {
+			changed = true
+			continue
+		}
+		taints = append(taints, taint)
+	}
+	for _, key := range sortedKeys(desired) {
+		taint := desired[key]
+		taints = append(taints, corev1.Taint{Key: taint.Key, Value: taint.Value, Effect: corev1.TaintEffect(taint.Effect)})
+		current.Taints = append(current.Taints, key)
+		changed = true
+	}
+	if changed {
+		node.Spec.Taints = taints
+	}
+	sort.Strings(current.Taints)
+	return current, changed
+}
+
+// KubeletConfig returns a kubelet configuration drop-in containing the kubelet settings from the desired
+// state, or nil if there are none. Only the settings that are set are included in the drop-in, so that
+// other settings are not overridden.
+func KubeletConfig(kubelet apisv1.NodeConfigKubelet) ([]byte, error) {
+	settings := map[string]any{}
+	if kubelet.MaxPods != 0 {
+		settings["maxPods"] = kubelet.MaxPods
+	}
+	if kubelet.ImageGCHighThresholdPercent != 0 {
+		settings["imageGCHighThresholdPercent"] = kubelet.ImageGCHighThresholdPercent
+	}
+	if kubelet.ImageGCLowThresholdPercent != 0 {
+		settings["imageGCLowThresholdPercent"] = kubelet.ImageGCLowThresholdPercent
+	}
+	if len(kubelet.EvictionHard) > 0 {
+		settings["evictionHard"] = kubelet.EvictionHard
+	}
+	if len(kubelet.SystemReserved) > 0 {
+		settings["systemReserved"] = kubelet.SystemReserved
+	}
+	if len(kubelet.KubeReserved) > 0 {
+		settings["kubeReserved"] = kubelet.KubeReserved
+	}
+	if len(settings) == 0 {
+		return nil, nil
+	}
+	settings["apiVersion"] = "kubelet.config.k8s.io/v1beta1"
+	settings["kind"] = "KubeletConfiguration"
+	return yaml.Marshal(settings)
+}
+
+// MergeRegistry returns a copy of the registry configuration from registries.yaml, with the mirrors from
+// the desired state replacing any mirrors for the same registry.
+func MergeRegistry(registry *registries.Registry, nodeRegistries apisv1.NodeConfigRegistries) *registries.Registry {
+	merged := &registries.Registry{
+		Mirrors: map[string]registries.Mirror{},
+		Configs: map[string]registries.RegistryConfig{},
+	}
+	if registry != nil {
+		for host, mirror := range registry.Mirrors {
+			merged.Mirrors[host] = mirror
+		}
+		for host, config := range registry.Configs {
+			merged.Configs[host] = config
+		}
+	}
+	for host, mirror := range nodeRegistries.Mirrors {
+		merged.Mirrors[host] = registries.Mirror{Endpoints: slices.Clone(mirror.Endpoints)}
+	}
+	return merged
+}
+
+func taintKey(key, effect string) string {
+	return key + ":" + effect
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
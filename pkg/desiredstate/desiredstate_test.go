@@ -0,0 +1,202 @@
+package desiredstate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	apisv1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
+	"github.com/rancher/wharfie/pkg/registries"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newNode(name string, labels map[string]string) *corev1.Node {
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func newConfig(name string, spec apisv1.NodeConfigSpec) *apisv1.NodeConfig {
+	return &apisv1.NodeConfig{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: spec}
+}
+
+func Test_UnitMerge(t *testing.T) {
+	configs := []*apisv1.NodeConfig{
+		newConfig("20-gpu", apisv1.NodeConfigSpec{
+			NodeSelector: map[string]string{"gpu": "true"},
+			Labels:       map[string]string{"tier": "gpu"},
+			Taints:       []apisv1.NodeConfigTaint{{Key: "gpu", Value: "true", Effect: "NoSchedule"}},
+			Kubelet:      apisv1.NodeConfigKubelet{MaxPods: 50, SystemReserved: map[string]string{"memory": "2Gi"}},
+		}),
+		newConfig("10-all", apisv1.NodeConfigSpec{
+			Labels:     map[string]string{"tier": "default", "fleet": "edge", "bad key!": "x"},
+			Taints:     []apisv1.NodeConfigTaint{{Key: "gpu", Value: "false", Effect: "NoSchedule"}, {Key: "invalid", Effect: "Sometimes"}},
+			Kubelet:    apisv1.NodeConfigKubelet{MaxPods: 200, SystemReserved: map[string]string{"cpu": "500m"}},
+			Registries: apisv1.NodeConfigRegistries{Mirrors: map[string]apisv1.NodeConfigMirror{"docker.io": {Endpoints: []string{"https://mirror.example.com"}}}},
+		}),
+		newConfig("30-named", apisv1.NodeConfigSpec{
+			NodeNames: []string{"node-b"},
+			Labels:    map[string]string{"named": "true"},
+		}),
+	}
+
+	state := Merge(configs, newNode("node-a", map[string]string{"gpu": "true"}), nil)
+	want := &State{
+		Configs: []string{"10-all", "20-gpu"},
+		Labels:  map[string]string{"tier": "gpu", "fleet": "edge"},
+		Taints:  []apisv1.NodeConfigTaint{{Key: "gpu", Value: "true", Effect: "NoSchedule"}},
+		Kubelet: apisv1.NodeConfigKubelet{MaxPods: 50, SystemReserved: map[string]string{"cpu": "500m", "memory": "2Gi"}},
+		Registries: apisv1.NodeConfigRegistries{
+			Mirrors: map[string]apisv1.NodeConfigMirror{"docker.io": {Endpoints: []string{"https://mirror.example.com"}}},
+		},
+	}
+	if !reflect.DeepEqual(state, want) {
+		t.Errorf("Merge() = %+v, want %+v", state, want)
+	}
+
+	state = Merge(configs, newNode("node-b", nil), nil)
+	if !reflect.DeepEqual(state.Configs, []string{"10-all", "30-named"}) {
+		t.Errorf("Merge() selected %v, want [10-all 30-named]", state.Configs)
+	}
+
+	// labels set from NodeConfigs are not used to select nodes
+	node := newNode("node-c", nil)
+	managed, _ := ApplyToNode(node, &State{Labels: map[string]string{"gpu": "true"}}, nil)
+	if state := Merge(configs, node, managed); reflect.DeepEqual(state.Configs, []string{"10-all", "20-gpu"}) {
+		t.Errorf("Merge() selected node by label set from NodeConfig")
+	}
+
+	if err := Validate(configs[1].Spec); err == nil || !strings.Contains(err.Error(), "bad key!") || !strings.Contains(err.Error(), "Sometimes") {
+		t.Errorf("Validate() = %v, want errors for invalid label and taint", err)
+	}
+}
+
+func Test_UnitApplyToNode(t *testing.T) {
+	node := newNode("node-a", map[string]string{"existing": "true", "fleet": "edge"})
+	node.Spec.Taints = []corev1.Taint{{Key: "existing", Effect: corev1.TaintEffectNoExecute}}
+
+	state := &State{
+		Labels: map[string]string{"fleet": "edge", "tier": "gpu"},
+		Taints: []apisv1.NodeConfigTaint{{Key: "gpu", Value: "true", Effect: "NoSchedule"}, {Key: "existing", Effect: "NoExecute"}},
+	}
+	managed, changed := ApplyToNode(node, state, nil)
+	if !changed {
+		t.Fatal("ApplyToNode() = false, want true")
+	}
+	// labels and taints already set on the node are not recorded as set from NodeConfigs
+	wantManaged := &Managed{Labels: []string{"tier"}, Taints: []string{"gpu:NoSchedule"}}
+	if !reflect.DeepEqual(managed, wantManaged) {
+		t.Errorf("managed = %+v, want %+v", managed, wantManaged)
+	}
+	if managed, changed = ApplyToNode(node, state, managed); changed || !reflect.DeepEqual(managed, wantManaged) {
+		t.Errorf("ApplyToNode() of the same state = %+v, %v, want %+v, false", managed, changed, wantManaged)
+	}
+	wantLabels := map[string]string{"existing": "true", "fleet": "edge", "tier": "gpu"}
+	if !reflect.DeepEqual(node.Labels, wantLabels) {
+		t.Errorf("labels = %v, want %v", node.Labels, wantLabels)
+	}
+	wantTaints := []corev1.Taint{
+		{Key: "existing", Effect: corev1.TaintEffectNoExecute},
+		{Key: "gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule},
+	}
+	if !reflect.DeepEqual(node.Spec.Taints, wantTaints) {
+		t.Errorf("taints = %v, want %v", node.Spec.Taints, wantTaints)
+	}
+
+	// labels and taints that are no longer set from NodeConfigs are removed, others are kept
+	managed, changed = ApplyToNode(node, &State{}, managed)
+	if !changed {
+		t.Fatal("ApplyToNode() = false, want true")
+	}
+	if len(managed.Labels) != 0 || len(managed.Taints) != 0 {
+		t.Errorf("managed = %+v, want none", managed)
+	}
+	wantLabels = map[string]string{"existing": "true", "fleet": "edge"}
+	if !reflect.DeepEqual(node.Labels, wantLabels) {
+		t.Errorf("labels = %v, want %v", node.Labels, wantLabels)
+	}
+	wantTaints = wantTaints[:1]
+	if !reflect.DeepEqual(node.Spec.Taints, wantTaints) {
+		t.Errorf("taints = %v, want %v", node.Spec.Taints, wantTaints)
+	}
+}
+
+func Test_UnitKubeletConfig(t *testing.T) {
+	if b, err := KubeletConfig(apisv1.NodeConfigKubelet{}); err != nil || b != nil {
+		t.Errorf("KubeletConfig() of empty settings = %q, %v", b, err)
+	}
+	b, err := KubeletConfig(apisv1.NodeConfigKubelet{MaxPods: 50, EvictionHard: map[string]string{"memory.available": "500Mi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `apiVersion: kubelet.config.k8s.io/v1beta1
+evictionHard:
+  memory.available: 500Mi
+kind: KubeletConfiguration
+maxPods: 50
+`
+	if string(b) != want {
+		t.Errorf("KubeletConfig() = %q, want %q", b, want)
+	}
+}
+
+func Test_UnitMergeRegistry(t *testing.T) {
+	registry := &registries.Registry{
+		Mirrors: map[string]registries.Mirror{
+			"docker.io": {Endpoints: []string{"https://old.example.com"}},
+			"quay.io":   {Endpoints: []string{"https://quay-mirror.example.com"}},
+		},
+		Configs: map[string]registries.RegistryConfig{"mirror.example.com": {}},
+	}
+	merged := MergeRegistry(registry, apisv1.NodeConfigRegistries{
+		Mirrors: map[string]apisv1.NodeConfigMirror{"docker.io": {Endpoints: []string{"https://mirror.example.com"}}},
+	})
+	if got := merged.Mirrors["docker.io"].Endpoints; !reflect.DeepEqual(got, []string{"https://mirror.example.com"}) {
+		t.Errorf("docker.io endpoints = %v", got)
+	}
+	if _, ok := merged.Mirrors["quay.io"]; !ok {
+		t.Errorf("quay.io mirror was not kept")
+	}
+	if _, ok := merged.Configs["mirror.example.com"]; !ok {
+		t.Errorf("registry config was not kept")
+	}
+	if got := registry.Mirrors["docker.io"].Endpoints; !reflect.DeepEqual(got, []string{"https://old.example.com"}) {
+		t.Errorf("registries.yaml configuration was modified: %v", got)
+	}
+}
+
+func Test_UnitBuildStatus(t *testing.T) {
+	configs := []*apisv1.NodeConfig{newConfig("edge", apisv1.NodeConfigSpec{NodeSelector: map[string]string{"edge": "true"}})}
+	applied := newNode("applied", map[string]string{"edge": "true"})
+	pending := newNode("pending", map[string]string{"edge": "true"})
+	other := newNode("other", nil)
+	nodes := []*corev1.Node{pending, other, applied}
+
+	states := map[string]*State{}
+	for _, node := range nodes {
+		states[node.Name] = Merge(configs, node, nil)
+	}
+	applied.Annotations = map[string]string{
+		nodeconfig.DesiredStateHashAnnotation:    states["applied"].Hash(),
+		nodeconfig.DesiredStateRestartAnnotation: "true",
+	}
+	pending.Annotations = map[string]string{nodeconfig.DesiredStateHashAnnotation: "stale"}
+
+	earlier := metav1.NewTime(metav1.Now().Add(-time.Minute))
+	now := metav1.Now()
+	prev := apisv1.NodeConfigStatus{Nodes: []apisv1.NodeConfigNodeStatus{{Name: "pending", LastTransitionTime: earlier}}}
+	status := buildStatus("edge", prev, nodes, states, now)
+	want := apisv1.NodeConfigStatus{
+		NodesSelected:        2,
+		NodesApplied:         1,
+		NodesRestartRequired: 1,
+		Nodes: []apisv1.NodeConfigNodeStatus{
+			{Name: "applied", Applied: true, RestartRequired: true, LastTransitionTime: now},
+			{Name: "pending", LastTransitionTime: earlier},
+		},
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("buildStatus() = %+v, want %+v", status, want)
+	}
+}
@@ -0,0 +1,109 @@
+package desiredstate
+
+import (
+	"encoding/json"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	coreclient "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+var (
+	// ManagedConfigMap is the name of the ConfigMap in the kube-system namespace that lists, for each node,
+	// the labels and taints that were set on the node from NodeConfigs, so that they can be removed when no
+	// NodeConfig sets them. It is kept out of the node object, as nodes can modify their own annotations.
+	ManagedConfigMap = version.Program + "-node-config-managed"
+)
+
+// Managed lists the labels and taints set on a node from NodeConfigs. Taints are listed as key:effect.
+type Managed struct {
+	Labels []string `json:"labels,omitempty"`
+	Taints []string `json:"taints,omitempty"`
+}
+
+// ManagedFromConfigMap returns the labels and taints set from NodeConfigs on each node, from the managed
+// ConfigMap. Entries that cannot be parsed are skipped, as the ConfigMap is only written by the servers.
+func ManagedFromConfigMap(configMap *corev1.ConfigMap) map[string]*Managed {
+	managed := map[string]*Managed{}
+	if configMap == nil {
+		return managed
+	}
+	for nodeName, value := range configMap.Data {
+		m := &Managed{}
+		if err := json.Unmarshal([]byte(value), m); err == nil {
+			managed[nodeName] = m
+		}
+	}
+	return managed
+}
+
+// GetManaged retrieves the labels and taints set from NodeConfigs on the node, or nil if there are none.
+func GetManaged(configMaps coreclient.ConfigMapClient, nodeName string) (*Managed, error) {
+	configMap, err := configMaps.Get(metav1.NamespaceSystem, ManagedConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return ManagedFromConfigMap(configMap)[nodeName], nil
+}
+
+// SetManaged stores the labels and taints set from NodeConfigs on the node, removing the node's entry if
+// there are none.
+func SetManaged(configMaps coreclient.ConfigMapClient, nodeName string, m *Managed) error {
+	if m != nil && len(m.Labels) == 0 && len(m.Taints) == 0 {
+		m = nil
+	}
+	var value string
+	if m != nil {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		value = string(b)
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := configMaps.Get(metav1.NamespaceSystem, ManagedConfigMap, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			if m == nil {
+				return nil
+			}
+			_, err = configMaps.Create(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: ManagedConfigMap, Namespace: metav1.NamespaceSystem},
+				Data:       map[string]string{nodeName: value},
+			})
+			return err
+		} else if err != nil {
+			return err
+		}
+		if current, ok := configMap.Data[nodeName]; (m == nil && !ok) || (m != nil && current == value) {
+			return nil
+		}
+		configMap = configMap.DeepCopy()
+		if m == nil {
+			delete(configMap.Data, nodeName)
+		} else {
+			if configMap.Data == nil {
+				configMap.Data = map[string]string{}
+			}
+			configMap.Data[nodeName] = value
+		}
+		_, err = configMaps.Update(configMap)
+		return err
+	})
+}
+
+// managedEqual returns true if both list the same labels and taints, treating nil as empty.
+func managedEqual(a, b *Managed) bool {
+	if a == nil {
+		a = &Managed{}
+	}
+	if b == nil {
+		b = &Managed{}
+	}
+	return equality.Semantic.DeepEqual(a.Labels, b.Labels) && equality.Semantic.DeepEqual(a.Taints, b.Taints)
+}
@@ -0,0 +1,137 @@
+// Package embed runs a server or agent in-process, for projects that embed k3s instead of running
+// it as a separate process. The server and agent are configured using the same flags that are
+// accepted by the server and agent commands.
+//
+// The server and agent share process-global state, including their parsed configuration and
+// signal handlers, so only one server or agent may be started per process.
+package embed
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	cliagent "github.com/k3s-io/k3s/pkg/cli/agent"
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	cliserver "github.com/k3s-io/k3s/pkg/cli/server"
+	"github.com/k3s-io/k3s/pkg/configfilearg"
+	"github.com/k3s-io/k3s/pkg/server"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/urfave/cli"
+)
+
+// ErrAlreadyStarted is returned when a server or agent has already been started in this process.
+var ErrAlreadyStarted = errors.New("a server or agent has already been started in this process")
+
+var started atomic.Bool
+
+// ServerConfig configures an embedded server.
+type ServerConfig struct {
+	// Args are the flags for the server, as accepted by the server command; for example
+	// --cluster-init or --disable=traefik. The default config file is not read, but a
+	// config file may be specified with --config.
+	Args []string
+	// StartupHooks are called once the apiserver is ready. The server is not considered to be
+	// up and running until all hooks have marked the WaitGroup as done.
+	StartupHooks []cmds.StartupHook
+	// LeaderControllers are started on the server that holds the controller leader lease.
+	LeaderControllers server.CustomControllers
+	// Controllers are started on all servers.
+	Controllers server.CustomControllers
+}
+
+// AgentConfig configures an embedded agent.
+type AgentConfig struct {
+	// Args are the flags for the agent, as accepted by the agent command; for example
+	// --server and --token. The default config file is not read, but a config file may
+	// be specified with --config.
+	Args []string
+}
+
+// Instance is a running server or agent.
+type Instance struct {
+	ready chan struct{}
+	done  chan struct{}
+	err   error
+}
+
+// Ready returns a channel that is closed once the server or agent is up and running.
+// For a server, this is once the apiserver and etcd are ready and all startup hooks have
+// completed. For an agent, this is once the node has been registered and its network
+// has been configured.
+func (i *Instance) Ready() <-chan struct{} {
+	return i.ready
+}
+
+// Done returns a channel that is closed once the server or agent has stopped, either
+// because the context was cancelled or because it failed.
+func (i *Instance) Done() <-chan struct{} {
+	return i.done
+}
+
+// Err returns the error that caused the server or agent to stop. It returns nil until
+// Done is closed.
+func (i *Instance) Err() error {
+	select {
+	case <-i.done:
+		return i.err
+	default:
+		return nil
+	}
+}
+
+// StartServer starts a server in the background. The server runs until the context is
+// cancelled or the server fails; use the returned Instance to wait for it to be ready or to stop.
+func StartServer(ctx context.Context, cfg ServerConfig) (*Instance, error) {
+	if !started.CompareAndSwap(false, true) {
+		return nil, ErrAlreadyStarted
+	}
+
+	i := newInstance()
+	cmds.ServerConfig.StartupHooks = append(cmds.ServerConfig.StartupHooks, cfg.StartupHooks...)
+	cmds.ServerConfig.ServerReady = i.ready
+	command := cmds.NewServerCommand(func(app *cli.Context) error {
+		return cliserver.RunWithContext(ctx, app, cfg.LeaderControllers, cfg.Controllers)
+	})
+	return i.start(command, cfg.Args)
+}
+
+// StartAgent starts an agent in the background. The agent runs until the context is
+// cancelled or the agent fails; use the returned Instance to wait for it to be ready or to stop.
+func StartAgent(ctx context.Context, cfg AgentConfig) (*Instance, error) {
+	if !started.CompareAndSwap(false, true) {
+		return nil, ErrAlreadyStarted
+	}
+
+	i := newInstance()
+	cmds.AgentConfig.AgentReady = i.ready
+	command := cmds.NewAgentCommand(func(app *cli.Context) error {
+		return cliagent.RunWithContext(ctx, app)
+	})
+	return i.start(command, cfg.Args)
+}
+
+func newInstance() *Instance {
+	return &Instance{
+		ready: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// start parses the args for the command, and then runs the command in the background.
+func (i *Instance) start(command cli.Command, args []string) (*Instance, error) {
+	parser := *configfilearg.DefaultParser
+	parser.DefaultConfig = ""
+	args, err := parser.Parse(append([]string{version.Program, command.Name}, args...))
+	if err != nil {
+		return nil, err
+	}
+
+	app := cmds.NewApp()
+	app.Commands = []cli.Command{command}
+	go func() {
+		defer close(i.done)
+		i.err = app.Run(args)
+	}()
+	return i, nil
+}
@@ -0,0 +1,75 @@
+package embed
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+func Test_UnitInstanceStart(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte("token: from-config\nnode-name: from-config\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		actionErr    error
+		wantToken    string
+		wantNodeName string
+	}{
+		{
+			name:         "Flags from args",
+			args:         []string{"--token=from-args", "--node-name=from-args"},
+			wantToken:    "from-args",
+			wantNodeName: "from-args",
+		},
+		{
+			name:         "Flags from config file",
+			args:         []string{"--config=" + configFile, "--node-name=from-args"},
+			wantToken:    "from-config",
+			wantNodeName: "from-args",
+		},
+		{
+			name:      "Command failure",
+			actionErr: errors.New("failed"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var token, nodeName string
+			command := cli.Command{
+				Name: "server",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "config"},
+					&cli.StringFlag{Name: "token", Destination: &token},
+					&cli.StringFlag{Name: "node-name", Destination: &nodeName},
+				},
+				Action: func(app *cli.Context) error {
+					return tt.actionErr
+				},
+			}
+
+			i, err := newInstance().start(command, tt.args)
+			if err != nil {
+				t.Fatalf("start() error = %v", err)
+			}
+			<-i.Done()
+			if !errors.Is(i.Err(), tt.actionErr) {
+				t.Errorf("Err() = %v, want %v", i.Err(), tt.actionErr)
+			}
+			if token != tt.wantToken || nodeName != tt.wantNodeName {
+				t.Errorf("start() token = %q, node-name = %q, want %q, %q", token, nodeName, tt.wantToken, tt.wantNodeName)
+			}
+			select {
+			case <-i.Ready():
+				t.Errorf("Ready() closed, but command did not report ready")
+			default:
+			}
+		})
+	}
+}
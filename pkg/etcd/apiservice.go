@@ -0,0 +1,185 @@
+package etcd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+	k3s "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	"github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1alpha1"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// etcdSnapshotsPath is the path to ETCDSnapshot resources in the supervisor's aggregated API.
+var etcdSnapshotsPath = "/apis/" + v1alpha1.SchemeGroupVersion.String() + "/etcdsnapshots"
+
+// apiServiceHandler handles ETCDSnapshot requests proxied to the supervisor by the apiserver's
+// aggregation layer. Requests use the server's snapshot configuration, including S3 if configured.
+func (e *ETCD) apiServiceHandler() http.Handler {
+	r := mux.NewRouter().SkipClean(true)
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		util.SendError(fmt.Errorf("method not allowed"), rw, req, http.StatusMethodNotAllowed)
+	})
+	r.Path(etcdSnapshotsPath).Methods(http.MethodGet).HandlerFunc(e.handleListResources)
+	r.Path(etcdSnapshotsPath).Methods(http.MethodPost).HandlerFunc(e.handleCreateResource)
+	r.Path(etcdSnapshotsPath + "/{name}").Methods(http.MethodGet).HandlerFunc(e.handleGetResource)
+	r.Path(etcdSnapshotsPath + "/{name}").Methods(http.MethodDelete).HandlerFunc(e.handleDeleteResource)
+	return r
+}
+
+func (e *ETCD) handleListResources(rw http.ResponseWriter, req *http.Request) {
+	snapshots, err := e.listSnapshotResources(req)
+	if err != nil {
+		util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusInternalServerError)
+		return
+	}
+	sendResource(rw, req, http.StatusOK, &v1alpha1.ETCDSnapshotList{
+		TypeMeta: metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "ETCDSnapshotList"},
+		Items:    snapshots,
+	})
+}
+
+func (e *ETCD) handleGetResource(rw http.ResponseWriter, req *http.Request) {
+	snapshot, err := e.getSnapshotResource(req)
+	if err != nil {
+		util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusInternalServerError)
+		return
+	} else if snapshot == nil {
+		util.SendError(fmt.Errorf("etcdsnapshots.%s %q not found", v1alpha1.SchemeGroupVersion.Group, mux.Vars(req)["name"]), rw, req, http.StatusNotFound)
+		return
+	}
+	sendResource(rw, req, http.StatusOK, snapshot)
+}
+
+// handleCreateResource takes an on-demand snapshot, using the name of the requested ETCDSnapshot as
+// the base name of the snapshot file. Automatic pruning is disabled, as it is for the CLI.
+func (e *ETCD) handleCreateResource(rw http.ResponseWriter, req *http.Request) {
+	if util.RejectDryRun(rw, req) {
+		return
+	}
+	request := &v1alpha1.ETCDSnapshot{}
+	if err := json.NewDecoder(req.Body).Decode(request); err != nil {
+		util.SendError(err, rw, req, http.StatusBadRequest)
+		return
+	}
+	name := request.Name
+	if name == "" {
+		name = strings.TrimSuffix(request.GenerateName, "-")
+	}
+	if name == "" {
+		name = "on-demand"
+	}
+
+	sr, err := e.withServerConfig(&SnapshotRequest{Name: []string{name}, Retention: ptr.To(0)}).Snapshot(req.Context())
+	if sr == nil {
+		util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusInternalServerError)
+		return
+	}
+	snapshots, err := e.listSnapshotResources(req)
+	if err != nil {
+		util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusInternalServerError)
+		return
+	}
+	for _, snapshot := range snapshots {
+		if slices.Contains(sr.Created, snapshot.Spec.SnapshotName) {
+			sendResource(rw, req, http.StatusCreated, &snapshot)
+			return
+		}
+	}
+	util.SendErrorWithID(errors.New("snapshot was not created, see server log for details"), "etcd-snapshot", rw, req, http.StatusInternalServerError)
+}
+
+// handleDeleteResource deletes the snapshot file for the requested ETCDSnapshot, along with any other
+// copies of the snapshot with the same snapshot name.
+func (e *ETCD) handleDeleteResource(rw http.ResponseWriter, req *http.Request) {
+	if util.RejectDryRun(rw, req) {
+		return
+	}
+	snapshot, err := e.getSnapshotResource(req)
+	if err != nil {
+		util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusInternalServerError)
+		return
+	} else if snapshot == nil {
+		util.SendError(fmt.Errorf("etcdsnapshots.%s %q not found", v1alpha1.SchemeGroupVersion.Group, mux.Vars(req)["name"]), rw, req, http.StatusNotFound)
+		return
+	}
+	sr, err := e.withServerConfig(&SnapshotRequest{}).DeleteSnapshots(req.Context(), []string{snapshot.Spec.SnapshotName})
+	if sr == nil {
+		util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusInternalServerError)
+		return
+	}
+	sendResource(rw, req, http.StatusOK, snapshot)
+}
+
+// withServerConfig returns a modified ETCD struct that uses the server's snapshot configuration,
+// overridden with any options set in the snapshot request.
+func (e *ETCD) withServerConfig(sr *SnapshotRequest) *ETCD {
+	if sr.Dir == nil {
+		sr.Dir = &e.config.EtcdSnapshotDir
+	}
+	if sr.S3 == nil {
+		sr.S3 = e.config.EtcdS3
+	}
+	return e.withRequest(sr)
+}
+
+func (e *ETCD) listSnapshotResources(req *http.Request) ([]v1alpha1.ETCDSnapshot, error) {
+	sf, err := e.withServerConfig(&SnapshotRequest{}).ListSnapshots(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make([]v1alpha1.ETCDSnapshot, 0, len(sf.Items))
+	for _, esf := range sf.Items {
+		snapshots = append(snapshots, toSnapshotResource(&esf))
+	}
+	return snapshots, nil
+}
+
+// getSnapshotResource returns the ETCDSnapshot named in the request path, or nil if it is not found.
+func (e *ETCD) getSnapshotResource(req *http.Request) (*v1alpha1.ETCDSnapshot, error) {
+	name := mux.Vars(req)["name"]
+	snapshots, err := e.listSnapshotResources(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.Name == name {
+			return &snapshot, nil
+		}
+	}
+	return nil, nil
+}
+
+func toSnapshotResource(esf *k3s.ETCDSnapshotFile) v1alpha1.ETCDSnapshot {
+	snapshot := v1alpha1.ETCDSnapshot{
+		TypeMeta: metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "ETCDSnapshot"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        esf.Name,
+			Labels:      esf.Labels,
+			Annotations: esf.Annotations,
+		},
+		Spec:   esf.Spec,
+		Status: esf.Status,
+	}
+	if esf.Status.CreationTime != nil {
+		snapshot.CreationTimestamp = *esf.Status.CreationTime
+	}
+	return snapshot
+}
+
+func sendResource(rw http.ResponseWriter, req *http.Request, code int, obj any) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	rw.Write(b)
+}
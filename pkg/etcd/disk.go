@@ -0,0 +1,92 @@
+package etcd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/cgroups"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// fsyncSamples is the number of writes made when measuring fsync latency
+	fsyncSamples = 50
+	// fsyncWriteSize approximates the size of a typical etcd WAL entry
+	fsyncWriteSize = 2048
+)
+
+// checkDataDir ensures that a custom etcd data dir is not used while existing etcd data remains
+// in the default data dir, as etcd would otherwise start with an empty datastore.
+func checkDataDir(config *config.Control) error {
+	if config.EtcdDataDir == "" || config.EtcdDataDir == defaultDBDir(config) {
+		return nil
+	}
+	defaultWALDir := filepath.Join(defaultDBDir(config), "member", "wal")
+	if _, err := os.Stat(defaultWALDir); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(walDir(config)); err == nil {
+		return nil
+	}
+	return fmt.Errorf("etcd data found in %s; move it to %s before starting with --etcd-data-dir", defaultDBDir(config), config.EtcdDataDir)
+}
+
+// prepareDataDir creates the etcd data dir, applies the configured IO weight, and warns if the fsync
+// latency of the data dir is higher than the configured threshold.
+func (e *ETCD) prepareDataDir() error {
+	dir := dbDir(e.config)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	if e.config.EtcdIOWeight > 0 {
+		if err := cgroups.SetSupervisorIOWeight(uint16(e.config.EtcdIOWeight)); err != nil {
+			return errors.Wrap(err, "failed to set etcd io weight")
+		}
+		logrus.Infof("Set etcd io weight to %d", e.config.EtcdIOWeight)
+	}
+
+	if e.config.EtcdFsyncWarning > 0 {
+		latency, err := fsyncLatency(dir, fsyncSamples)
+		if err != nil {
+			logrus.Warnf("Failed to measure fsync latency of etcd data dir %s: %v", dir, err)
+		} else if latency > e.config.EtcdFsyncWarning {
+			logrus.Warnf("The 99th percentile fsync latency of etcd data dir %s is %s, which exceeds the warning threshold of %s. "+
+				"etcd may be unstable; consider moving the etcd data dir to a faster dedicated disk with --etcd-data-dir.", dir, latency, e.config.EtcdFsyncWarning)
+		} else {
+			logrus.Infof("The 99th percentile fsync latency of etcd data dir %s is %s", dir, latency)
+		}
+	}
+	return nil
+}
+
+// fsyncLatency measures the 99th percentile latency of sequential writes to a temporary file in dir,
+// each followed by an fsync, which approximates the write pattern of the etcd WAL.
+func fsyncLatency(dir string, samples int) (time.Duration, error) {
+	f, err := os.CreateTemp(dir, ".fsync-check-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	buf := make([]byte, fsyncWriteSize)
+	latencies := make([]time.Duration, samples)
+	for i := range latencies {
+		start := time.Now()
+		if _, err := f.Write(buf); err != nil {
+			return 0, err
+		}
+		if err := f.Sync(); err != nil {
+			return 0, err
+		}
+		latencies[i] = time.Since(start)
+	}
+	slices.Sort(latencies)
+	return latencies[(len(latencies)*99)/100], nil
+}
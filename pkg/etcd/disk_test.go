@@ -0,0 +1,75 @@
+package etcd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+)
+
+func Test_UnitCheckDataDir(t *testing.T) {
+	tests := []struct {
+		name       string
+		defaultWAL bool
+		customWAL  bool
+		custom     bool
+		wantErr    bool
+	}{
+		{
+			name:       "Default data dir",
+			defaultWAL: true,
+		},
+		{
+			name:   "Custom data dir for new member",
+			custom: true,
+		},
+		{
+			name:       "Custom data dir with existing data",
+			defaultWAL: true,
+			customWAL:  true,
+			custom:     true,
+		},
+		{
+			name:       "Custom data dir with data left in default data dir",
+			defaultWAL: true,
+			custom:     true,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cnf := &config.Control{DataDir: t.TempDir()}
+			if tt.defaultWAL {
+				if err := os.MkdirAll(walDir(cnf), 0700); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.custom {
+				cnf.EtcdDataDir = filepath.Join(t.TempDir(), "etcd")
+			}
+			if tt.customWAL {
+				if err := os.MkdirAll(walDir(cnf), 0700); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := checkDataDir(cnf); (err != nil) != tt.wantErr {
+				t.Errorf("checkDataDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_UnitFsyncLatency(t *testing.T) {
+	dir := t.TempDir()
+	latency, err := fsyncLatency(dir, 10)
+	if err != nil {
+		t.Fatalf("fsyncLatency() error = %v", err)
+	}
+	if latency <= 0 {
+		t.Errorf("fsyncLatency() = %s, want > 0", latency)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("fsyncLatency() left %d files in %s", len(files), dir)
+	}
+}
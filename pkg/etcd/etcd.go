@@ -20,6 +20,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	k3s "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
 	"github.com/k3s-io/k3s/pkg/clientaccess"
 	"github.com/k3s-io/k3s/pkg/cluster/managed"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
@@ -27,6 +28,7 @@ import (
 	"github.com/k3s-io/k3s/pkg/daemons/executor"
 	"github.com/k3s-io/k3s/pkg/etcd/s3"
 	"github.com/k3s-io/k3s/pkg/etcd/snapshot"
+	"github.com/k3s-io/k3s/pkg/notify"
 	"github.com/k3s-io/k3s/pkg/server/auth"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
@@ -56,6 +58,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	utilnet "k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/client-go/kubernetes"
 	nodeHelper "k8s.io/component-helpers/node/util"
 	nodeUtil "k8s.io/kubernetes/pkg/controller/util/node"
@@ -117,6 +120,7 @@ type ETCD struct {
 	cancel     context.CancelFunc
 	s3         *s3.Controller
 	snapshotMu *sync.Mutex
+	retention  *k3s.ETCDSnapshotRetention
 }
 
 type learnerProgress struct {
@@ -192,6 +196,10 @@ func (e *ETCD) SetControlConfig(config *config.Control) error {
 
 	e.config = config
 
+	if err := checkDataDir(e.config); err != nil {
+		return err
+	}
+
 	address, err := getAdvertiseAddress(e.config.PrivateIP)
 	if err != nil {
 		return err
@@ -271,8 +279,16 @@ func (e *ETCD) Test(ctx context.Context) error {
 	return &membershipError{members: memberNameUrls, self: e.name + "=" + e.peerURL()}
 }
 
-// dbDir returns the path to dataDir/db/etcd
+// dbDir returns the path to the etcd data dir, which defaults to dataDir/db/etcd
 func dbDir(config *config.Control) string {
+	if config.EtcdDataDir != "" {
+		return config.EtcdDataDir
+	}
+	return defaultDBDir(config)
+}
+
+// defaultDBDir returns the path to dataDir/db/etcd
+func defaultDBDir(config *config.Control) string {
 	return filepath.Join(config.DataDir, "db", "etcd")
 }
 
@@ -321,6 +337,20 @@ func (e *ETCD) ResetFile() string {
 	return filepath.Join(e.config.DataDir, "db", "reset-flag")
 }
 
+// WillRun returns true if the server will run etcd as its datastore: either because an etcd datastore
+// has already been initialized in the data dir, or because the server has been asked to initialize or
+// join a cluster without an external datastore.
+func WillRun(config *config.Control) bool {
+	if config.Datastore.Endpoint != "" || config.DisableETCD {
+		return false
+	}
+	if config.ClusterInit || (config.Token != "" && config.JoinURL != "") {
+		return true
+	}
+	s, err := os.Stat(walDir(config))
+	return err == nil && s.IsDir()
+}
+
 // IsInitialized checks to see if a WAL directory exists. If so, we assume that etcd
 // has already been brought up at least once.
 func (e *ETCD) IsInitialized() (bool, error) {
@@ -421,6 +451,11 @@ func (e *ETCD) Reset(ctx context.Context, rebootstrap func() error) error {
 			if err != nil {
 				return errors.Wrap(err, "failed to download snapshot from S3")
 			}
+			if strings.HasSuffix(path, snapshot.DeltaExtension) {
+				if err := downloadIncrementalChain(ctx, s3client, path); err != nil {
+					return err
+				}
+			}
 			e.config.ClusterResetRestorePath = path
 			logrus.Infof("S3 download complete for %s", e.config.ClusterResetRestorePath)
 		}
@@ -455,6 +490,10 @@ func (e *ETCD) Start(ctx context.Context, clientAccessInfo *clientaccess.Info) e
 		return errors.Wrapf(err, "failed to check for initialized etcd datastore")
 	}
 
+	if err := e.prepareDataDir(); err != nil {
+		return err
+	}
+
 	if err := e.startClient(ctx); err != nil {
 		return err
 	}
@@ -620,6 +659,7 @@ func (e *ETCD) join(ctx context.Context, clientAccessInfo *clientaccess.Info) er
 		if _, err = client.MemberAddAsLearner(clientCtx, []string{e.peerURL()}); err != nil {
 			return err
 		}
+		e.config.Runtime.Notifier.Notifyf(notify.ETCDMemberAdded, e.config.ServerNodeName, "Added member %s=%s to etcd cluster", e.name, e.peerURL())
 		cluster = append(cluster, fmt.Sprintf("%s=%s", e.name, e.peerURL()))
 		state = "existing"
 	} else if len(cluster) > 1 {
@@ -710,10 +750,16 @@ func (e *ETCD) handler(next http.Handler) http.Handler {
 	ir.Use(auth.IsLocalOrHasRole(e.config, version.Program+":server"))
 	ir.Handle("", e.infoHandler())
 
+	r.Path(snapshotDownloadPath).Handler(e.downloadHandler())
+
 	sr := r.Path("/db/snapshot").Subrouter()
-	sr.Use(auth.HasRole(e.config, version.Program+":server"))
+	sr.Use(auth.HasRole(e.config, version.Program+":server", user.SystemPrivilegedGroup))
 	sr.Handle("", e.snapshotHandler())
 
+	ar := r.PathPrefix(etcdSnapshotsPath).Subrouter()
+	ar.Use(auth.IsAggregated(e.config, next))
+	ar.NewRoute().Handler(e.apiServiceHandler())
+
 	return r
 }
 
@@ -1027,7 +1073,7 @@ func (e *ETCD) cluster(ctx context.Context, reset bool, options executor.Initial
 
 		ExperimentalInitialCorruptCheck:         true,
 		ExperimentalWatchProgressNotifyInterval: e.config.Datastore.NotifyInterval,
-	}, e.config.ExtraEtcdArgs)
+	}, append(config.ResourceProfiles[e.config.ResourceProfile].ETCDArgList(), e.config.ExtraEtcdArgs...))
 }
 
 func (e *ETCD) StartEmbeddedTemporary(ctx context.Context) error {
@@ -1137,6 +1183,9 @@ func (e *ETCD) RemovePeer(ctx context.Context, name, address string, allowSelfRe
 				if errors.Is(err, rpctypes.ErrGRPCMemberNotFound) {
 					return nil
 				}
+				if err == nil {
+					e.config.Runtime.Notifier.Notifyf(notify.ETCDMemberRemoved, "", "Removed member %s from etcd cluster", member.Name)
+				}
 				return err
 			}
 		}
@@ -1265,6 +1314,7 @@ func (e *ETCD) trackLearnerProgress(ctx context.Context, progress *learnerProgre
 		logrus.Debugf("Unable to promote learner %s: %v", member.Name, err)
 	} else {
 		logrus.Infof("Promoted learner %s", member.Name)
+		e.config.Runtime.Notifier.Notifyf(notify.ETCDMemberPromoted, "", "Promoted learner %s to voting member of etcd cluster", member.Name)
 		return nil
 	}
 
@@ -1305,6 +1355,7 @@ func (e *ETCD) trackLearnerProgress(ctx context.Context, progress *learnerProgre
 			return err
 		}
 		logrus.Warnf("Removed learner %s from etcd cluster", member.Name)
+		e.config.Runtime.Notifier.Notifyf(notify.ETCDMemberRemoved, "", "Removed stalled learner %s from etcd cluster", member.Name)
 		return nil
 	}
 
@@ -1538,13 +1589,30 @@ func (e *ETCD) Restore(ctx context.Context) error {
 	if e.config.ClusterResetRestorePath == "" {
 		return errors.New("no etcd restore path was specified")
 	}
-	// make sure snapshot exists before restoration
+	// make sure snapshot exists and matches the checksum recorded when it was taken before restoration
 	if _, err := os.Stat(e.config.ClusterResetRestorePath); err != nil {
 		return err
 	}
+	if ok, err := snapshot.VerifyChecksum(e.config.ClusterResetRestorePath); err != nil {
+		return errors.Wrapf(err, "etcd snapshot %s failed verification", e.config.ClusterResetRestorePath)
+	} else if !ok {
+		logrus.Warnf("No checksum recorded for etcd snapshot %s; only the snapshot structure will be verified", e.config.ClusterResetRestorePath)
+	}
 
 	var restorePath string
-	if strings.HasSuffix(e.config.ClusterResetRestorePath, snapshot.CompressedExtension) {
+	var skipHashCheck bool
+	if strings.HasSuffix(e.config.ClusterResetRestorePath, snapshot.DeltaExtension) {
+		reconstructed, err := e.reconstructSnapshot(e.config.ClusterResetRestorePath)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(reconstructed)
+
+		// the reconstructed database does not carry the hash that etcd appends to snapshots;
+		// the hash of the base snapshot is verified during reconstruction instead.
+		restorePath = reconstructed
+		skipHashCheck = true
+	} else if strings.HasSuffix(e.config.ClusterResetRestorePath, snapshot.CompressedExtension) {
 		dir, err := snapshotDir(e.config, true)
 		if err != nil {
 			return errors.Wrap(err, "failed to get the snapshot dir")
@@ -1560,6 +1628,11 @@ func (e *ETCD) Restore(ctx context.Context) error {
 		restorePath = e.config.ClusterResetRestorePath
 	}
 
+	// make sure the snapshot is intact before moving the existing datastore out of the way
+	if _, err := snapshot.Check(restorePath); err != nil {
+		return errors.Wrapf(err, "etcd snapshot %s failed verification", restorePath)
+	}
+
 	// move the data directory to a temp path
 	if err := os.Rename(dbDir(e.config), oldDataDir); err != nil {
 		return err
@@ -1573,6 +1646,7 @@ func (e *ETCD) Restore(ctx context.Context) error {
 		OutputWALDir:   walDir(e.config),
 		PeerURLs:       []string{e.peerURL()},
 		InitialCluster: e.name + "=" + e.peerURL(),
+		SkipHashCheck:  skipHashCheck,
 	})
 }
 
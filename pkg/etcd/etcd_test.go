@@ -15,6 +15,7 @@ import (
 	"github.com/k3s-io/k3s/pkg/clientaccess"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/etcd/s3"
+	"github.com/k3s-io/kine/pkg/endpoint"
 	testutil "github.com/k3s-io/k3s/tests"
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
@@ -78,6 +79,58 @@ func generateTestHandler() http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
 }
 
+func Test_UnitWillRun(t *testing.T) {
+	dataDir := t.TempDir()
+	tests := []struct {
+		name   string
+		config config.Control
+		wal    bool
+		want   bool
+	}{
+		{
+			name:   "sqlite",
+			config: config.Control{DataDir: dataDir},
+		},
+		{
+			name:   "cluster-init",
+			config: config.Control{DataDir: dataDir, ClusterInit: true},
+			want:   true,
+		},
+		{
+			name:   "joining",
+			config: config.Control{DataDir: dataDir, Token: "token", JoinURL: "https://server:6443"},
+			want:   true,
+		},
+		{
+			name:   "initialized",
+			config: config.Control{DataDir: dataDir},
+			wal:    true,
+			want:   true,
+		},
+		{
+			name:   "external datastore",
+			config: config.Control{DataDir: dataDir, ClusterInit: true, Datastore: endpoint.Config{Endpoint: "postgres://db"}},
+		},
+		{
+			name:   "etcd disabled",
+			config: config.Control{DataDir: dataDir, Token: "token", JoinURL: "https://server:6443", DisableETCD: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.RemoveAll(walDir(&tt.config))
+			if tt.wal {
+				if err := os.MkdirAll(walDir(&tt.config), 0700); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := WillRun(&tt.config); got != tt.want {
+				t.Errorf("WillRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_UnitETCD_IsInitialized(t *testing.T) {
 	type args struct {
 		ctx    context.Context
@@ -0,0 +1,343 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/cluster/managed"
+	"github.com/k3s-io/k3s/pkg/etcd/s3"
+	"github.com/k3s-io/k3s/pkg/etcd/snapshot"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	incrementalStateFile = ".incremental"
+	incrementalPageSize  = 500
+)
+
+var errNoBaseSnapshot = errors.New("no full snapshot available to take an incremental snapshot on top of")
+
+// incrementalState tracks the chain of incremental snapshots taken on top of the most recent full snapshot.
+// The list of keys present at the revision of the last snapshot in the chain is required to
+// identify keys that have been deleted since.
+type incrementalState struct {
+	Base     string   `json:"base"`
+	Last     string   `json:"last,omitempty"`
+	Revision int64    `json:"revision"`
+	Keys     [][]byte `json:"keys,omitempty"`
+}
+
+func incrementalStatePath(snapshotDir string) string {
+	return filepath.Join(snapshotDir, "..", incrementalStateFile)
+}
+
+// readIncrementalState reads the incremental snapshot chain state from disk.
+// A nil state is returned if no chain has been started.
+func readIncrementalState(snapshotDir string) (*incrementalState, error) {
+	b, err := os.ReadFile(incrementalStatePath(snapshotDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	state := &incrementalState{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal incremental snapshot state")
+	}
+	return state, nil
+}
+
+func writeIncrementalState(snapshotDir string, state *incrementalState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return util.AtomicWrite(incrementalStatePath(snapshotDir), b, 0600)
+}
+
+// clearIncrementalState removes the incremental snapshot chain state, so that the
+// next incremental snapshot is preceded by a new full snapshot.
+func clearIncrementalState(snapshotDir string) {
+	if err := os.Remove(incrementalStatePath(snapshotDir)); err != nil && !os.IsNotExist(err) {
+		logrus.Warnf("Failed to remove incremental snapshot state: %v", err)
+	}
+}
+
+// startIncrementalChain records the given full snapshot as the base for subsequent incremental snapshots.
+func (e *ETCD) startIncrementalChain(ctx context.Context, snapshotDir, base string, revision int64) error {
+	state := &incrementalState{Base: base, Revision: revision}
+	if _, err := e.rangeKeys(ctx, revision, func(kv *mvccpb.KeyValue) {
+		state.Keys = append(state.Keys, kv.Key)
+	}); err != nil {
+		clearIncrementalState(snapshotDir)
+		return err
+	}
+	return writeIncrementalState(snapshotDir, state)
+}
+
+// rangeKeys calls fn for every key in the datastore at the given revision, or at the current
+// revision if rev is 0. Only keys and their metadata are retrieved, in pages to limit memory use.
+// The revision at which the keys were retrieved is returned.
+func (e *ETCD) rangeKeys(ctx context.Context, rev int64, fn func(*mvccpb.KeyValue)) (int64, error) {
+	key := "\x00"
+	for {
+		resp, err := e.client.Get(ctx, key, clientv3.WithFromKey(), clientv3.WithLimit(incrementalPageSize), clientv3.WithRev(rev), clientv3.WithKeysOnly())
+		if err != nil {
+			return 0, err
+		}
+		// pin subsequent pages to the revision of the first page
+		rev = resp.Header.Revision
+		for _, kv := range resp.Kvs {
+			fn(kv)
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			return rev, nil
+		}
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+}
+
+// activeLeases returns all leases that have not yet expired.
+func (e *ETCD) activeLeases(ctx context.Context) ([]snapshot.Lease, error) {
+	resp, err := e.client.Leases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var leases []snapshot.Lease
+	for _, l := range resp.Leases {
+		ttl, err := e.client.TimeToLive(ctx, l.ID)
+		if err != nil {
+			return nil, err
+		}
+		if ttl.TTL <= 0 {
+			continue
+		}
+		leases = append(leases, snapshot.Lease{ID: int64(l.ID), TTL: ttl.GrantedTTL, RemainingTTL: ttl.TTL})
+	}
+	return leases, nil
+}
+
+// IncrementalSnapshot saves the changes to the datastore since the previous snapshot in the current chain
+// of incremental snapshots to the configured directory, and uploads it to S3 if enabled. Incremental snapshots
+// are much smaller than full snapshots, but can only be restored alongside the full snapshot at the start of the
+// chain and all the incremental snapshots taken since. If no full snapshot is available to start a chain from,
+// errNoBaseSnapshot is returned.
+func (e *ETCD) IncrementalSnapshot(ctx context.Context) (*managed.SnapshotResult, error) {
+	if !e.snapshotMu.TryLock() {
+		return nil, errors.New("snapshot save already in progress")
+	}
+	defer e.snapshotMu.Unlock()
+
+	endpoints := getEndpoints(e.config)
+	status, err := e.client.Status(ctx, endpoints[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check etcd status for snapshot")
+	}
+
+	if status.IsLearner {
+		logrus.Warnf("Unable to take snapshot: not supported for learner")
+		return nil, nil
+	}
+
+	snapshotDir, err := snapshotDir(e.config, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get etcd-snapshot-dir")
+	}
+
+	state, err := readIncrementalState(snapshotDir)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, errNoBaseSnapshot
+	}
+	if _, err := os.Stat(filepath.Join(snapshotDir, state.Base)); err != nil {
+		// the base snapshot has been pruned or deleted; a new chain must be started
+		clearIncrementalState(snapshotDir)
+		return nil, errNoBaseSnapshot
+	}
+
+	tokenHash, err := util.GetTokenHash(e.config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get server token hash for etcd snapshot")
+	}
+
+	prevKeys := make(map[string]struct{}, len(state.Keys))
+	for _, key := range state.Keys {
+		prevKeys[string(key)] = struct{}{}
+	}
+
+	delta := &snapshot.Delta{
+		Base:         state.Base,
+		Previous:     state.Last,
+		PrevRevision: state.Revision,
+	}
+	// Leases are listed before keys are read, so that any lease that expires in the meantime is
+	// restored along with its keys, and then expires again.
+	if delta.Leases, err = e.activeLeases(ctx); err != nil {
+		return nil, errors.Wrap(err, "failed to list leases for incremental snapshot")
+	}
+	// Keys are diffed against the previous snapshot by their mod revision, rather than read from the
+	// watch history, so that the chain does not depend on the history being retained until the next
+	// snapshot. Only the values of keys that have changed are retrieved.
+	var keys, changed [][]byte
+	delta.Revision, err = e.rangeKeys(ctx, 0, func(kv *mvccpb.KeyValue) {
+		keys = append(keys, kv.Key)
+		delete(prevKeys, string(kv.Key))
+		if kv.ModRevision > state.Revision {
+			changed = append(changed, kv.Key)
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list keys for incremental snapshot")
+	}
+	for _, key := range changed {
+		resp, err := e.client.Get(ctx, string(key), clientv3.WithRev(delta.Revision))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get key %q for incremental snapshot", key)
+		}
+		delta.Puts = append(delta.Puts, resp.Kvs...)
+	}
+	for key := range prevKeys {
+		delta.Deletes = append(delta.Deletes, []byte(key))
+	}
+	sort.Slice(delta.Puts, func(i, j int) bool {
+		if delta.Puts[i].ModRevision != delta.Puts[j].ModRevision {
+			return delta.Puts[i].ModRevision < delta.Puts[j].ModRevision
+		}
+		return bytes.Compare(delta.Puts[i].Key, delta.Puts[j].Key) < 0
+	})
+	sort.Slice(delta.Deletes, func(i, j int) bool {
+		return bytes.Compare(delta.Deletes[i], delta.Deletes[j]) < 0
+	})
+
+	nodeName := os.Getenv("NODE_NAME")
+	now := time.Now().Round(time.Second)
+	snapshotName := snapshot.DeltaName(state.Base, now)
+	snapshotPath := filepath.Join(snapshotDir, snapshotName)
+	logrus.Infof("Saving incremental etcd snapshot to %s with %d changes since revision %d", snapshotPath, len(delta.Puts)+len(delta.Deletes), delta.PrevRevision)
+
+	if err := snapshot.WriteDelta(snapshotPath, delta); err != nil {
+		return nil, errors.Wrap(err, "failed to write incremental snapshot")
+	}
+
+	f, err := os.Stat(snapshotPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to retrieve snapshot information from local snapshot")
+	}
+
+	checksum, err := snapshot.Checksum(snapshotPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to calculate snapshot checksum")
+	}
+	if err := snapshot.SaveChecksum(snapshotPath, checksum); err != nil {
+		logrus.Warnf("Failed to save local snapshot checksum: %v", err)
+	}
+
+	sf := &snapshot.File{
+		Name:     f.Name(),
+		Location: "file://" + snapshotPath,
+		NodeName: nodeName,
+		CreatedAt: &metav1.Time{
+			Time: now,
+		},
+		Status:    snapshot.SuccessfulStatus,
+		Size:      f.Size(),
+		Checksum:  checksum,
+		Revision:  delta.Revision,
+		TokenHash: tokenHash,
+	}
+	res := &managed.SnapshotResult{Created: []string{sf.Name}}
+
+	// If this fails, just log an error - the snapshot file will remain on disk
+	// and will be recorded next time the snapshot list is reconciled.
+	if err := e.addSnapshotData(*sf); err != nil {
+		logrus.Warnf("Failed to sync ETCDSnapshotFile: %v", err)
+	}
+
+	state.Last = snapshotName
+	state.Revision = delta.Revision
+	state.Keys = keys
+
+	if e.config.EtcdS3 != nil {
+		if s3client, err := e.getS3Client(ctx); err != nil {
+			logrus.Warnf("Unable to initialize S3 client: %v", err)
+		} else {
+			logrus.Infof("Saving incremental etcd snapshot %s to S3", snapshotName)
+			sf, err = s3client.Upload(ctx, snapshotPath, nil, now)
+			if err != nil {
+				// A chain with a missing link cannot be restored from S3, so start a new chain next time.
+				logrus.Errorf("Error received during snapshot upload to S3: %s", err)
+				state = nil
+			} else {
+				sf.Checksum = checksum
+				sf.Revision = delta.Revision
+				res.Created = append(res.Created, sf.Name)
+				logrus.Infof("S3 upload complete for %s", snapshotName)
+			}
+			if err := e.addSnapshotData(*sf); err != nil {
+				logrus.Warnf("Failed to sync ETCDSnapshotFile: %v", err)
+			}
+		}
+	}
+
+	if state == nil {
+		clearIncrementalState(snapshotDir)
+	} else if err := writeIncrementalState(snapshotDir, state); err != nil {
+		clearIncrementalState(snapshotDir)
+		logrus.Warnf("Failed to save incremental snapshot state: %v", err)
+	}
+
+	return res, e.reconcileSnapshotData(ctx, res)
+}
+
+// downloadIncrementalChain downloads the full snapshot and all previous incremental snapshots
+// that the given incremental snapshot depends on from S3, into the same directory.
+func downloadIncrementalChain(ctx context.Context, s3client *s3.Client, snapshotPath string) error {
+	dir := filepath.Dir(snapshotPath)
+	for {
+		d, err := snapshot.ReadDelta(snapshotPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read incremental snapshot %s", snapshotPath)
+		}
+		name := d.Previous
+		if name == "" {
+			name = d.Base
+		}
+		logrus.Infof("Retrieving etcd snapshot %s from S3", name)
+		if snapshotPath, err = s3client.Download(ctx, name, dir); err != nil {
+			return errors.Wrapf(err, "failed to download snapshot %s from S3", name)
+		}
+		if d.Previous == "" {
+			return nil
+		}
+	}
+}
+
+// reconstructSnapshot rebuilds a full snapshot from the given incremental snapshot and
+// the chain of snapshots it depends on, and returns the path to the rebuilt snapshot.
+func (e *ETCD) reconstructSnapshot(snapshotPath string) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(dbDir(e.config)), "restore-")
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+
+	logrus.Infof("Reconstructing etcd snapshot from incremental snapshot %s", snapshotPath)
+	if err := snapshot.Reconstruct(snapshotPath, f.Name()); err != nil {
+		os.Remove(f.Name())
+		return "", errors.Wrap(err, "failed to reconstruct snapshot")
+	}
+	return f.Name(), nil
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/etcd/snapshot"
+	"github.com/k3s-io/k3s/pkg/tracing"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/minio/minio-go/v7"
@@ -29,6 +30,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rancher/wrangler/v3/pkg/generated/controllers/core"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -280,7 +282,9 @@ func (c *Client) Upload(ctx context.Context, snapshotPath string, extraMetadata
 	}
 
 	logrus.Infof("Uploading snapshot to s3://%s/%s", c.etcdS3.Bucket, snapshotKey)
+	ctx, span := tracing.Start(ctx, "etcd.snapshot.upload", attribute.String("s3.bucket", c.etcdS3.Bucket), attribute.String("s3.key", snapshotKey))
 	uploadInfo, err := c.uploadSnapshot(ctx, snapshotKey, snapshotPath)
+	tracing.End(span, err)
 	if err != nil {
 		sf.Status = snapshot.FailedStatus
 		sf.Message = base64.StdEncoding.EncodeToString([]byte(err.Error()))
@@ -394,6 +398,7 @@ func (c *Client) SnapshotRetention(ctx context.Context, retention int, prefix st
 	logrus.Infof("Applying snapshot retention=%d to snapshots stored in s3://%s/%s", retention, c.etcdS3.Bucket, prefix)
 
 	var snapshotFiles []minio.ObjectInfo
+	deltas := map[string][]string{}
 
 	toCtx, cancel := context.WithTimeout(ctx, c.etcdS3.Timeout.Duration)
 	defer cancel()
@@ -412,6 +417,12 @@ func (c *Client) SnapshotRetention(ctx context.Context, retention int, prefix st
 			continue
 		}
 
+		// incremental snapshots are pruned along with their base snapshot
+		if base, ok := snapshot.DeltaBase(path.Base(info.Key)); ok {
+			deltas[base] = append(deltas[base], path.Base(info.Key))
+			continue
+		}
+
 		snapshotFiles = append(snapshotFiles, info)
 	}
 
@@ -433,6 +444,14 @@ func (c *Client) SnapshotRetention(ctx context.Context, retention int, prefix st
 			return deleted, err
 		}
 		deleted = append(deleted, key)
+
+		for _, name := range deltas[strings.TrimSuffix(key, snapshot.CompressedExtension)] {
+			logrus.Infof("Removing S3 incremental snapshot: s3://%s/%s", c.etcdS3.Bucket, path.Join(c.etcdS3.Folder, name))
+			if err := c.DeleteSnapshot(ctx, name); err != nil && !snapshot.IsNotExist(err) {
+				return deleted, err
+			}
+			deleted = append(deleted, name)
+		}
 	}
 
 	return deleted, nil
@@ -505,6 +524,7 @@ func (c *Client) ListSnapshots(ctx context.Context) (map[string]snapshot.File, e
 		}
 
 		basename, compressed := strings.CutSuffix(filename, snapshot.CompressedExtension)
+		basename = strings.TrimSuffix(basename, snapshot.DeltaExtension)
 		ts, err := strconv.ParseInt(basename[strings.LastIndexByte(basename, '-')+1:], 10, 64)
 		if err != nil {
 			ts = obj.LastModified.Unix()
@@ -22,6 +22,8 @@ import (
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/etcd/s3"
 	"github.com/k3s-io/k3s/pkg/etcd/snapshot"
+	"github.com/k3s-io/k3s/pkg/notify"
+	"github.com/k3s-io/k3s/pkg/tracing"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/pkg/errors"
@@ -190,11 +192,14 @@ func (e *ETCD) decompressSnapshot(snapshotDir, snapshotFile string) (string, err
 // Note that the prune step is generally disabled when snapshotting from the CLI, as there is a separate
 // subcommand for prune that can be run manually if the user wants to remove old snapshots.
 // Returns metadata about the new and pruned snapshots.
-func (e *ETCD) Snapshot(ctx context.Context) (*managed.SnapshotResult, error) {
+func (e *ETCD) Snapshot(ctx context.Context) (_ *managed.SnapshotResult, err error) {
 	if !e.snapshotMu.TryLock() {
 		return nil, errors.New("snapshot save already in progress")
 	}
 	defer e.snapshotMu.Unlock()
+	ctx, span := tracing.Start(ctx, "etcd.snapshot.save")
+	defer func() { tracing.End(span, err) }()
+
 	// make sure the core.Factory is initialized before attempting to add snapshot metadata
 	var extraMetadata *v1.ConfigMap
 	if e.config.Runtime.Core == nil {
@@ -242,8 +247,16 @@ func (e *ETCD) Snapshot(ctx context.Context) (*managed.SnapshotResult, error) {
 	logrus.Infof("Saving etcd snapshot to %s", snapshotPath)
 
 	var sf *snapshot.File
+	var revision int64
 
-	if err := snapshotv3.Save(ctx, e.client.GetLogger(), *cfg, snapshotPath); err != nil {
+	err = snapshotv3.Save(ctx, e.client.GetLogger(), *cfg, snapshotPath)
+	if err == nil {
+		// Make sure the snapshot was written intact before recording it as successful.
+		if revision, err = snapshot.Check(snapshotPath); err != nil {
+			os.Remove(snapshotPath)
+		}
+	}
+	if err != nil {
 		sf = &snapshot.File{
 			Name:     snapshotName,
 			Location: "",
@@ -285,6 +298,14 @@ func (e *ETCD) Snapshot(ctx context.Context) (*managed.SnapshotResult, error) {
 			return nil, errors.Wrap(err, "unable to retrieve snapshot information from local snapshot")
 		}
 
+		checksum, err := snapshot.Checksum(snapshotPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to calculate snapshot checksum")
+		}
+		if err := snapshot.SaveChecksum(snapshotPath, checksum); err != nil {
+			logrus.Warnf("Failed to save local snapshot checksum: %v", err)
+		}
+
 		sf = &snapshot.File{
 			Name:     f.Name(),
 			Location: "file://" + snapshotPath,
@@ -295,6 +316,8 @@ func (e *ETCD) Snapshot(ctx context.Context) (*managed.SnapshotResult, error) {
 			Status:         snapshot.SuccessfulStatus,
 			Size:           f.Size(),
 			Compressed:     e.config.EtcdSnapshotCompress,
+			Checksum:       checksum,
+			Revision:       revision,
 			MetadataSource: extraMetadata,
 			TokenHash:      tokenHash,
 		}
@@ -311,6 +334,12 @@ func (e *ETCD) Snapshot(ctx context.Context) (*managed.SnapshotResult, error) {
 			logrus.Warnf("Failed to sync ETCDSnapshotFile: %v", err)
 		}
 
+		if e.config.EtcdIncrementalCron != "" {
+			if err := e.startIncrementalChain(ctx, snapshotDir, sf.Name, revision); err != nil {
+				logrus.Warnf("Failed to start incremental snapshot chain: %v", err)
+			}
+		}
+
 		// Snapshot retention may prune some files before returning an error. Failing to prune is not fatal.
 		deleted, err := snapshotRetention(e.config.EtcdSnapshotRetention, e.config.EtcdSnapshotName, snapshotDir)
 		if err != nil {
@@ -343,7 +372,11 @@ func (e *ETCD) Snapshot(ctx context.Context) (*managed.SnapshotResult, error) {
 				sf, err = s3client.Upload(ctx, snapshotPath, extraMetadata, now)
 				if err != nil {
 					logrus.Errorf("Error received during snapshot upload to S3: %s", err)
+					// incremental snapshots cannot be restored from S3 without their base snapshot
+					clearIncrementalState(snapshotDir)
 				} else {
+					sf.Checksum = checksum
+					sf.Revision = revision
 					res.Created = append(res.Created, sf.Name)
 					logrus.Infof("S3 upload complete for %s", snapshotName)
 				}
@@ -385,6 +418,7 @@ func (e *ETCD) listLocalSnapshots() (map[string]snapshot.File, error) {
 		}
 
 		basename, compressed := strings.CutSuffix(file.Name(), snapshot.CompressedExtension)
+		basename = strings.TrimSuffix(basename, snapshot.DeltaExtension)
 		ts, err := strconv.ParseInt(basename[strings.LastIndexByte(basename, '-')+1:], 10, 64)
 		if err != nil {
 			ts = file.ModTime().Unix()
@@ -570,11 +604,141 @@ func (e *ETCD) deleteSnapshot(snapshotPath string) error {
 		if merr := os.Remove(metadataPath); err != nil && !snapshot.IsNotExist(err) {
 			err = merr
 		}
+		if cerr := snapshot.RemoveChecksum(snapshotPath); err == nil {
+			err = cerr
+		}
 	}
 
 	return err
 }
 
+// VerifySnapshots checks the integrity of the given snapshots in local storage and S3.
+// The checksum of each snapshot is compared against the value recorded when the snapshot was
+// taken, and the etcd database within the snapshot is opened read-only to validate its structure.
+// Returns a list of verified snapshots, along with the reason that any others failed verification.
+func (e *ETCD) VerifySnapshots(ctx context.Context, snapshots []string) (*managed.SnapshotResult, error) {
+	snapshotDir, err := snapshotDir(e.config, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get etcd-snapshot-dir")
+	}
+
+	var s3client *s3.Client
+	if e.config.EtcdS3 != nil {
+		s3client, err = e.getS3Client(ctx)
+		if err != nil {
+			logrus.Warnf("Unable to initialize S3 client: %v", err)
+			if !errors.Is(err, s3.ErrNoConfigSecret) {
+				return nil, errors.Wrap(err, "failed to initialize S3 client")
+			}
+		}
+	}
+
+	res := &managed.SnapshotResult{}
+	fail := func(s string, err error) {
+		if res.Failed == nil {
+			res.Failed = map[string]string{}
+		}
+		if msg := res.Failed[s]; msg != "" {
+			res.Failed[s] = msg + "; " + err.Error()
+		} else {
+			res.Failed[s] = err.Error()
+		}
+	}
+
+	for _, s := range snapshots {
+		var found, verified bool
+		snapshotPath := filepath.Join(snapshotDir, s)
+		if _, err := os.Stat(snapshotPath); err != nil {
+			if os.IsNotExist(err) {
+				logrus.Infof("Snapshot %s not found locally", s)
+			} else {
+				found = true
+				logrus.Errorf("Failed to verify local snapshot %s: %v", s, err)
+				fail(s, errors.Wrap(err, "local"))
+			}
+		} else if err := e.getSnapshotFile(s, os.Getenv("NODE_NAME")).Verify(snapshotPath); err != nil {
+			found = true
+			logrus.Errorf("Local snapshot %s failed verification: %v", s, err)
+			fail(s, errors.Wrap(err, "local"))
+		} else {
+			found, verified = true, true
+			logrus.Infof("Snapshot %s verified locally", s)
+		}
+
+		if s3client != nil {
+			if err := e.verifyS3Snapshot(ctx, s3client, s); err != nil {
+				if snapshot.IsNotExist(err) {
+					logrus.Infof("Snapshot %s not found in S3", s)
+				} else {
+					found = true
+					logrus.Errorf("S3 snapshot %s failed verification: %v", s, err)
+					fail(s, errors.Wrap(err, "s3"))
+				}
+			} else {
+				found, verified = true, true
+				logrus.Infof("Snapshot %s verified in S3", s)
+			}
+		}
+
+		if !found {
+			fail(s, errors.New("snapshot not found"))
+		} else if verified {
+			res.Verified = append(res.Verified, s)
+		}
+	}
+
+	return res, nil
+}
+
+// verifyS3Snapshot downloads the given snapshot from S3 to a temporary directory, and verifies it.
+func (e *ETCD) verifyS3Snapshot(ctx context.Context, s3client *s3.Client, name string) error {
+	tmpDir, err := os.MkdirTemp("", "etcd-snapshot-verify-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Download also retrieves the snapshot metadata into a sibling of the snapshot directory,
+	// so use a subdirectory to keep everything within the temporary directory.
+	downloadDir := filepath.Join(tmpDir, "snapshots")
+	if err := os.Mkdir(downloadDir, 0700); err != nil {
+		return err
+	}
+	snapshotPath, err := s3client.Download(ctx, name, downloadDir)
+	if err != nil {
+		return err
+	}
+	return e.getSnapshotFile(name, "s3").Verify(snapshotPath)
+}
+
+// getSnapshotFile returns the metadata recorded for the named snapshot on the given storage node.
+// If no metadata is available, a record without checksum or revision is returned, so that the
+// snapshot can still be checked for structural integrity.
+func (e *ETCD) getSnapshotFile(name, storageNode string) *snapshot.File {
+	sf := &snapshot.File{Name: name}
+	if e.config.Runtime.K3s == nil {
+		logrus.Warnf("Unable to retrieve metadata for snapshot %s: runtime not ready", name)
+		return sf
+	}
+
+	selector := labels.Set{snapshot.LabelStorageNode: storageNode}.AsSelector()
+	esfList, err := e.config.Runtime.K3s.K3s().V1().ETCDSnapshotFile().List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		logrus.Warnf("Unable to retrieve metadata for snapshot %s: %v", name, err)
+		return sf
+	}
+	for i := range esfList.Items {
+		if esfList.Items[i].Spec.SnapshotName == name {
+			sf.FromETCDSnapshotFile(&esfList.Items[i])
+			break
+		}
+	}
+	if sf.Checksum == "" {
+		logrus.Warnf("No checksum recorded for snapshot %s; only the snapshot structure will be verified", name)
+	}
+	return sf
+}
+
 // addSnapshotData syncs an internal snapshotFile representation to an ETCDSnapshotFile resource
 // of the same name. Resources will be created or updated as necessary.
 func (e *ETCD) addSnapshotData(sf snapshot.File) error {
@@ -603,8 +767,21 @@ func (e *ETCD) addSnapshotData(sf snapshot.File) error {
 			}
 		}
 
-		// mutate object
+		// mutate object, keeping the existing download URL if it is still valid. The checksum and
+		// revision are only known when the snapshot is saved, and are kept when the snapshot is
+		// reconciled from a listing of local or S3 snapshots that does not include them.
 		existing := esf.DeepCopyObject()
+		if sf.DownloadURL == "" {
+			sf.DownloadURL = esf.Status.DownloadURL
+			sf.DownloadURLExpiration = esf.Status.DownloadURLExpiration
+		}
+		if sf.Checksum == "" {
+			sf.Checksum = esf.Status.Checksum
+		}
+		if sf.Revision == 0 {
+			sf.Revision = esf.Status.Revision
+		}
+		e.setSnapshotStatus(&sf, time.Now())
 		sf.ToETCDSnapshotFile(esf)
 
 		// create or update as necessary
@@ -633,6 +810,10 @@ func generateETCDSnapshotFileConfigMapKey(esf k3s.ETCDSnapshotFile) string {
 }
 
 func (e *ETCD) emitEvent(esf *k3s.ETCDSnapshotFile) {
+	if esf.DeletionTimestamp.IsZero() {
+		e.notifySnapshot(esf)
+	}
+
 	switch {
 	case e.config.Runtime.Event == nil:
 	case !esf.DeletionTimestamp.IsZero():
@@ -648,6 +829,19 @@ func (e *ETCD) emitEvent(esf *k3s.ETCDSnapshotFile) {
 	}
 }
 
+// notifySnapshot sends the result of a snapshot to the lifecycle event webhook, if configured.
+func (e *ETCD) notifySnapshot(esf *k3s.ETCDSnapshotFile) {
+	if esf.Status.Error != nil {
+		message := fmt.Sprintf("Failed to save snapshot %s on %s", esf.Spec.SnapshotName, esf.Spec.NodeName)
+		if esf.Status.Error.Message != nil {
+			message += ": " + *esf.Status.Error.Message
+		}
+		e.config.Runtime.Notifier.Notify(notify.ETCDSnapshotFailed, esf.Spec.NodeName, message)
+		return
+	}
+	e.config.Runtime.Notifier.Notifyf(notify.ETCDSnapshotCreated, esf.Spec.NodeName, "Snapshot %s saved on %s", esf.Spec.SnapshotName, esf.Spec.NodeName)
+}
+
 // ReconcileSnapshotData reconciles snapshot data in the ETCDSnapshotFile resources.
 // It will reconcile snapshot data from disk locally always, and if S3 is enabled, will attempt to
 // list S3 snapshots and reconcile snapshots from S3.
@@ -750,8 +944,10 @@ func (e *ETCD) reconcileSnapshotData(ctx context.Context, res *managed.SnapshotR
 		sfKey := generateETCDSnapshotFileConfigMapKey(*esf)
 		logrus.Debugf("Found ETCDSnapshotFile for %s with key %s", esf.Spec.SnapshotName, sfKey)
 		if sf, ok := snapshotFiles[sfKey]; ok && sf.GenerateName() == esf.Name {
-			// exists in both and names match, don't need to sync
-			delete(snapshotFiles, sfKey)
+			// exists in both and names match, only need to sync if the retention policy or download URL are out of date
+			if !e.snapshotStatusStale(esf, now) {
+				delete(snapshotFiles, sfKey)
+			}
 		} else {
 			// doesn't exist on disk/s3
 			if res != nil && slices.Contains(res.Deleted, esf.Spec.SnapshotName) {
@@ -861,7 +1057,8 @@ func (e *ETCD) reconcileSnapshotData(ctx context.Context, res *managed.SnapshotR
 	return err
 }
 
-// setSnapshotFunction schedules snapshots at the configured interval.
+// setSnapshotFunction schedules full and incremental snapshots at the configured intervals, and
+// periodic reconciliation of snapshot records.
 func (e *ETCD) setSnapshotFunction(ctx context.Context) {
 	skipJob := cron.SkipIfStillRunning(cronLogger)
 	e.cron.AddJob(e.config.EtcdSnapshotCron, skipJob(cron.FuncJob(func() {
@@ -873,6 +1070,27 @@ func (e *ETCD) setSnapshotFunction(ctx context.Context) {
 			logrus.Errorf("Failed to take scheduled snapshot: %v", err)
 		}
 	})))
+	if e.config.EtcdIncrementalCron != "" {
+		if _, err := e.cron.AddJob(e.config.EtcdIncrementalCron, skipJob(cron.FuncJob(func() {
+			time.Sleep(time.Duration(rand.Float64() * float64(snapshotJitterMax)))
+			_, err := e.IncrementalSnapshot(ctx)
+			if errors.Is(err, errNoBaseSnapshot) {
+				logrus.Warnf("Taking full snapshot instead of incremental snapshot: %v", err)
+				_, err = e.Snapshot(ctx)
+			}
+			if err != nil {
+				logrus.Errorf("Failed to take scheduled incremental snapshot: %v", err)
+			}
+		}))); err != nil {
+			logrus.Errorf("Invalid incremental snapshot schedule: %v", err)
+		}
+	}
+	// Periodically reconcile snapshot records so that download URLs are refreshed before they expire.
+	e.cron.Schedule(cron.Every(downloadURLRefresh), skipJob(cron.FuncJob(func() {
+		if err := e.ReconcileSnapshotData(ctx); err != nil {
+			logrus.Errorf("Failed to refresh snapshot download URLs: %v", err)
+		}
+	})))
 }
 
 // snapshotRetention iterates through the snapshots and removes the oldest
@@ -885,10 +1103,15 @@ func snapshotRetention(retention int, snapshotPrefix string, snapshotDir string)
 	logrus.Infof("Applying snapshot retention=%d to local snapshots with prefix %s in %s", retention, snapshotPrefix, snapshotDir)
 
 	var snapshotFiles []snapshot.File
+	deltas := map[string][]string{}
 	if err := filepath.Walk(snapshotDir, func(path string, info os.FileInfo, err error) error {
 		if info.IsDir() || err != nil {
 			return err
 		}
+		if base, ok := snapshot.DeltaBase(info.Name()); ok {
+			deltas[base] = append(deltas[base], info.Name())
+			return nil
+		}
 		if strings.HasPrefix(info.Name(), snapshotPrefix) {
 			basename, compressed := strings.CutSuffix(info.Name(), snapshot.CompressedExtension)
 			ts, err := strconv.ParseInt(basename[strings.LastIndexByte(basename, '-')+1:], 10, 64)
@@ -921,7 +1144,22 @@ func snapshotRetention(retention int, snapshotPrefix string, snapshotDir string)
 		if err := os.Remove(metadataPath); err != nil && !os.IsNotExist(err) {
 			return deleted, err
 		}
+		if err := snapshot.RemoveChecksum(snapshotPath); err != nil {
+			return deleted, err
+		}
 		deleted = append(deleted, df.Name)
+
+		// incremental snapshots cannot be restored without their base snapshot
+		for _, name := range deltas[strings.TrimSuffix(df.Name, snapshot.CompressedExtension)] {
+			logrus.Infof("Removing local incremental snapshot %s", filepath.Join(snapshotDir, name))
+			if err := os.Remove(filepath.Join(snapshotDir, name)); err != nil && !os.IsNotExist(err) {
+				return deleted, err
+			}
+			if err := snapshot.RemoveChecksum(filepath.Join(snapshotDir, name)); err != nil {
+				return deleted, err
+			}
+			deleted = append(deleted, name)
+		}
 	}
 
 	return deleted, nil
@@ -0,0 +1,295 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/server/v3/lease/leasepb"
+)
+
+// DeltaExtension is the file extension used for incremental snapshots.
+const DeltaExtension = ".delta"
+
+var (
+	// keyBucket is the name of the bolt bucket in which etcd stores key revisions.
+	keyBucket = []byte("key")
+	// leaseBucket is the name of the bolt bucket in which etcd stores leases.
+	leaseBucket = []byte("lease")
+)
+
+// Delta records the changes to the etcd keyspace between the previous snapshot in a
+// chain of incremental snapshots, and the revision at which the delta was taken.
+type Delta struct {
+	// Base is the name of the full snapshot at the start of the chain.
+	Base string `json:"base"`
+	// Previous is the name of the previous incremental snapshot in the chain.
+	// If empty, the delta applies directly to the base snapshot.
+	Previous string `json:"previous,omitempty"`
+	// PrevRevision is the etcd revision of the previous snapshot in the chain.
+	PrevRevision int64 `json:"prevRevision"`
+	// Revision is the etcd revision at which the delta was taken.
+	Revision int64 `json:"revision"`
+	// Puts contains the keys created or modified since the previous snapshot.
+	Puts []*mvccpb.KeyValue `json:"puts,omitempty"`
+	// Deletes contains the keys deleted since the previous snapshot.
+	Deletes [][]byte `json:"deletes,omitempty"`
+	// Leases contains all the leases that were active at the revision of the delta.
+	Leases []Lease `json:"leases,omitempty"`
+}
+
+// Lease is an etcd lease, with the TTL it was granted with and the TTL remaining when the delta was taken.
+type Lease struct {
+	ID           int64 `json:"id"`
+	TTL          int64 `json:"ttl"`
+	RemainingTTL int64 `json:"remainingTTL"`
+}
+
+// DeltaName returns the name of an incremental snapshot taken at the given time,
+// on top of the named base snapshot.
+func DeltaName(base string, now time.Time) string {
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, CompressedExtension), now.Unix(), DeltaExtension)
+}
+
+// DeltaBase returns the name of the full snapshot that the named incremental snapshot was taken on
+// top of, without any compression extension. The boolean return is false if the name is not that
+// of an incremental snapshot.
+func DeltaBase(name string) (string, bool) {
+	basename, ok := strings.CutSuffix(name, DeltaExtension)
+	if !ok {
+		return "", false
+	}
+	i := strings.LastIndexByte(basename, '-')
+	if i < 0 {
+		return "", false
+	}
+	if _, err := strconv.ParseInt(basename[i+1:], 10, 64); err != nil {
+		return "", false
+	}
+	return basename[:i], true
+}
+
+// WriteDelta writes the delta to the given path as gzip-compressed JSON.
+func WriteDelta(path string, d *Delta) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	if err := json.NewEncoder(zw).Encode(d); err != nil {
+		os.Remove(path)
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return f.Sync()
+}
+
+// ReadDelta reads a delta previously written by WriteDelta.
+func ReadDelta(path string) (*Delta, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	d := &Delta{}
+	if err := json.NewDecoder(zr).Decode(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Apply writes the changes recorded in the delta into the etcd database at the given path.
+// Puts are stored at their original revision, while deletes are stored at the revision of the delta,
+// as only the final state of each key is recorded. The leases in the database are replaced with
+// those recorded in the delta, so that keys attached to leases still expire after a restore.
+func (d *Delta) Apply(dbPath string) error {
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	puts := make([]*mvccpb.KeyValue, len(d.Puts))
+	copy(puts, d.Puts)
+	sort.SliceStable(puts, func(i, j int) bool {
+		return puts[i].ModRevision < puts[j].ModRevision
+	})
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(keyBucket)
+		if b == nil {
+			return errors.New("database does not contain a key bucket")
+		}
+		subs := map[int64]int64{}
+		for _, kv := range puts {
+			if kv.ModRevision <= d.PrevRevision || kv.ModRevision > d.Revision {
+				return fmt.Errorf("key %q has revision %d outside of delta range %d-%d", kv.Key, kv.ModRevision, d.PrevRevision, d.Revision)
+			}
+			v, err := kv.Marshal()
+			if err != nil {
+				return err
+			}
+			if err := b.Put(revisionKey(kv.ModRevision, subs[kv.ModRevision], false), v); err != nil {
+				return err
+			}
+			subs[kv.ModRevision]++
+		}
+		for _, key := range d.Deletes {
+			v, err := (&mvccpb.KeyValue{Key: key}).Marshal()
+			if err != nil {
+				return err
+			}
+			if err := b.Put(revisionKey(d.Revision, subs[d.Revision], true), v); err != nil {
+				return err
+			}
+			subs[d.Revision]++
+		}
+
+		if err := tx.DeleteBucket(leaseBucket); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		lb, err := tx.CreateBucket(leaseBucket)
+		if err != nil {
+			return err
+		}
+		for _, l := range d.Leases {
+			v, err := (&leasepb.Lease{ID: l.ID, TTL: l.TTL, RemainingTTL: l.RemainingTTL}).Marshal()
+			if err != nil {
+				return err
+			}
+			id := make([]byte, 8)
+			binary.BigEndian.PutUint64(id, uint64(l.ID))
+			if err := lb.Put(id, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Reconstruct builds a complete etcd database at outPath, by replaying the chain of incremental
+// snapshots ending with the given delta on top of the chain's base snapshot. The base snapshot and
+// all previous deltas in the chain must be present in the same directory as the given delta.
+func Reconstruct(deltaPath, outPath string) error {
+	dir := filepath.Dir(deltaPath)
+	var chain []*Delta
+	for name := filepath.Base(deltaPath); name != ""; {
+		d, err := ReadDelta(filepath.Join(dir, name))
+		if err != nil {
+			return errors.Wrapf(err, "failed to read incremental snapshot %s", name)
+		}
+		if len(chain) > 0 && chain[0].Base != d.Base {
+			return fmt.Errorf("incremental snapshot %s is based on %s, expected %s", name, d.Base, chain[0].Base)
+		}
+		chain = append([]*Delta{d}, chain...)
+		name = d.Previous
+	}
+
+	if err := copyDB(filepath.Join(dir, chain[0].Base), outPath); err != nil {
+		return errors.Wrapf(err, "failed to copy base snapshot %s", chain[0].Base)
+	}
+	revision, err := Check(outPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to verify base snapshot %s", chain[0].Base)
+	}
+	for _, d := range chain {
+		if d.PrevRevision != revision {
+			return fmt.Errorf("incremental snapshot chain is broken: expected delta from revision %d, found delta from revision %d", revision, d.PrevRevision)
+		}
+		if err := d.Apply(outPath); err != nil {
+			return errors.Wrapf(err, "failed to apply incremental snapshot at revision %d", d.Revision)
+		}
+		revision = d.Revision
+	}
+	return nil
+}
+
+// copyDB copies the etcd database contained in the snapshot file at src to dst. Compressed snapshots
+// are decompressed, and the sha256 hash that etcd appends to the database is verified and removed.
+func copyDB(src, dst string) error {
+	if strings.HasSuffix(src, CompressedExtension) {
+		extracted, err := Extract(src)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(extracted)
+		src = extracted
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	size := info.Size()
+	hasHash := size%512 == sha256.Size
+	var h hash.Hash
+	w := io.Writer(out)
+	if hasHash {
+		size -= sha256.Size
+		h = sha256.New()
+		w = io.MultiWriter(out, h)
+	}
+	if _, err := io.CopyN(w, in, size); err != nil {
+		return err
+	}
+	if hasHash {
+		sum := make([]byte, sha256.Size)
+		if _, err := io.ReadFull(in, sum); err != nil {
+			return err
+		}
+		if !bytes.Equal(sum, h.Sum(nil)) {
+			return errors.New("snapshot database hash mismatch")
+		}
+	}
+	return out.Sync()
+}
+
+// revisionKey returns the bolt key under which etcd stores the given revision.
+func revisionKey(main, sub int64, tombstone bool) []byte {
+	b := make([]byte, 17, 18)
+	binary.BigEndian.PutUint64(b[0:8], uint64(main))
+	b[8] = '_'
+	binary.BigEndian.PutUint64(b[9:], uint64(sub))
+	if tombstone {
+		b = append(b, 't')
+	}
+	return b
+}
@@ -0,0 +1,154 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/server/v3/lease/leasepb"
+)
+
+func Test_UnitDeltaBase(t *testing.T) {
+	now := time.Unix(1700003600, 0)
+	tests := []struct {
+		name     string
+		base     string
+		wantBase string
+	}{
+		{
+			name:     "Uncompressed base",
+			base:     "etcd-snapshot-server-1-1700000000",
+			wantBase: "etcd-snapshot-server-1-1700000000",
+		},
+		{
+			name:     "Compressed base",
+			base:     "etcd-snapshot-server-1-1700000000.zip",
+			wantBase: "etcd-snapshot-server-1-1700000000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := DeltaName(tt.base, now)
+			if got, ok := DeltaBase(name); !ok || got != tt.wantBase {
+				t.Errorf("DeltaBase(%q) = %q, %v, want %q, true", name, got, ok, tt.wantBase)
+			}
+		})
+	}
+	for _, name := range []string{"etcd-snapshot-server-1-1700000000", "etcd-snapshot-server-1-1700000000.zip", "etcd-snapshot.delta"} {
+		if got, ok := DeltaBase(name); ok {
+			t.Errorf("DeltaBase(%q) = %q, true, want false", name, got)
+		}
+	}
+}
+
+func Test_UnitReconstruct(t *testing.T) {
+	newChain := func(t *testing.T, broken bool) string {
+		dir := t.TempDir()
+		base := "etcd-snapshot-server-1-1700000000"
+		basePath := filepath.Join(dir, base)
+		writeTestDB(t, basePath, 42)
+
+		// append the hash that etcd adds to saved snapshots
+		b, err := os.ReadFile(basePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(b)
+		if err := os.WriteFile(basePath, append(b, sum[:]...), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		first := &Delta{
+			Base:         base,
+			PrevRevision: 42,
+			Revision:     45,
+			Puts: []*mvccpb.KeyValue{
+				{Key: []byte("/registry/b"), Value: []byte("b"), CreateRevision: 44, ModRevision: 44, Version: 1},
+				{Key: []byte("/registry/a"), Value: []byte("a"), CreateRevision: 43, ModRevision: 43, Version: 1},
+			},
+			Leases: []Lease{{ID: 1, TTL: 60, RemainingTTL: 30}, {ID: 2, TTL: 60, RemainingTTL: 45}},
+		}
+		firstName := DeltaName(base, time.Unix(1700003600, 0))
+		if err := WriteDelta(filepath.Join(dir, firstName), first); err != nil {
+			t.Fatal(err)
+		}
+
+		second := &Delta{
+			Base:         base,
+			Previous:     firstName,
+			PrevRevision: 45,
+			Revision:     47,
+			Puts: []*mvccpb.KeyValue{
+				{Key: []byte("/registry/c"), Value: []byte("c"), CreateRevision: 47, ModRevision: 47, Version: 1},
+			},
+			Deletes: [][]byte{[]byte("/registry/a")},
+			Leases:  []Lease{{ID: 2, TTL: 60, RemainingTTL: 15}},
+		}
+		if broken {
+			second.PrevRevision = 44
+		}
+		secondName := DeltaName(base, time.Unix(1700007200, 0))
+		if err := WriteDelta(filepath.Join(dir, secondName), second); err != nil {
+			t.Fatal(err)
+		}
+		return filepath.Join(dir, secondName)
+	}
+
+	t.Run("Complete chain", func(t *testing.T) {
+		outPath := filepath.Join(t.TempDir(), "restore")
+		if err := Reconstruct(newChain(t, false), outPath); err != nil {
+			t.Fatalf("Reconstruct() error = %v", err)
+		}
+		revision, err := Check(outPath)
+		if err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+		if revision != 47 {
+			t.Errorf("Check() revision = %d, want 47", revision)
+		}
+
+		db, err := bolt.Open(outPath, 0400, &bolt.Options{ReadOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		var tombstones, total int
+		var leases []*leasepb.Lease
+		db.View(func(tx *bolt.Tx) error {
+			tx.Bucket(leaseBucket).ForEach(func(k, v []byte) error {
+				lease := &leasepb.Lease{}
+				if err := lease.Unmarshal(v); err != nil {
+					t.Error(err)
+				}
+				leases = append(leases, lease)
+				return nil
+			})
+			return tx.Bucket(keyBucket).ForEach(func(k, v []byte) error {
+				total++
+				if len(k) == 18 && k[17] == 't' {
+					tombstones++
+				}
+				return nil
+			})
+		})
+		// one key from the base snapshot, three puts and one delete from the deltas
+		if total != 5 || tombstones != 1 {
+			t.Errorf("Reconstruct() wrote %d revisions with %d tombstones, want 5 with 1", total, tombstones)
+		}
+		// only the leases active at the last delta are restored
+		if len(leases) != 1 || leases[0].ID != 2 || leases[0].RemainingTTL != 15 {
+			t.Errorf("Reconstruct() wrote leases %v, want only lease 2 with 15s remaining", leases)
+		}
+	})
+
+	t.Run("Broken chain", func(t *testing.T) {
+		outPath := filepath.Join(t.TempDir(), "restore")
+		if err := Reconstruct(newChain(t, true), outPath); err == nil {
+			t.Errorf("Reconstruct() error = nil, want error")
+		}
+	})
+}
@@ -31,6 +31,7 @@ const (
 
 	CompressedExtension = ".zip"
 	MetadataDir         = ".metadata"
+	ChecksumDir         = ".checksums"
 )
 
 var (
@@ -68,12 +69,20 @@ type File struct {
 	Status     SnapshotStatus `json:"status,omitempty"`
 	S3         *S3Config      `json:"s3Config,omitempty"`
 	Compressed bool           `json:"compressed"`
+	Checksum   string         `json:"checksum,omitempty"`
+	Revision   int64          `json:"revision,omitempty"`
 
 	// these fields are used for the internal representation of the snapshot
 	// to populate other fields before serialization to the legacy configmap.
 	MetadataSource *v1.ConfigMap `json:"-"`
 	NodeSource     string        `json:"-"`
 	TokenHash      string        `json:"-"`
+
+	// these fields are only stored in the ETCDSnapshotFile status, as they are
+	// specific to the server that recorded the snapshot.
+	DownloadURL           string                     `json:"-"`
+	DownloadURLExpiration *metav1.Time               `json:"-"`
+	Retention             *k3s.ETCDSnapshotRetention `json:"-"`
 }
 
 // GenerateConfigMapKey generates a derived name for the snapshot that is safe for use
@@ -136,6 +145,12 @@ func (sf *File) FromETCDSnapshotFile(esf *k3s.ETCDSnapshotFile) {
 		sf.Size = esf.Status.Size.Value()
 	}
 
+	sf.Checksum = esf.Status.Checksum
+	sf.Revision = esf.Status.Revision
+	sf.DownloadURL = esf.Status.DownloadURL
+	sf.DownloadURLExpiration = esf.Status.DownloadURLExpiration
+	sf.Retention = esf.Status.Retention
+
 	if esf.Status.Error != nil {
 		if esf.Status.Error.Time != nil {
 			sf.CreatedAt = esf.Status.Error.Time
@@ -187,6 +202,11 @@ func (sf *File) ToETCDSnapshotFile(esf *k3s.ETCDSnapshotFile) {
 	esf.Status.CreationTime = sf.CreatedAt
 	esf.Status.ReadyToUse = ptr.To(sf.Status == SuccessfulStatus)
 	esf.Status.Size = resource.NewQuantity(sf.Size, resource.DecimalSI)
+	esf.Status.Checksum = sf.Checksum
+	esf.Status.Revision = sf.Revision
+	esf.Status.DownloadURL = sf.DownloadURL
+	esf.Status.DownloadURLExpiration = sf.DownloadURLExpiration
+	esf.Status.Retention = sf.Retention
 
 	if sf.NodeSource != "" {
 		esf.Spec.NodeName = sf.NodeSource
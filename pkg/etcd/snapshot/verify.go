@@ -0,0 +1,161 @@
+package snapshot
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	snapshotv3 "go.etcd.io/etcd/etcdutl/v3/snapshot"
+	"go.uber.org/zap"
+)
+
+// Checksum returns the hex-encoded sha256 checksum of the given file.
+func Checksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checksumPath returns the path of the file holding the checksum recorded for the given snapshot,
+// in a directory alongside the snapshot directory, so that it is not listed as a snapshot.
+func checksumPath(snapshotPath string) string {
+	return filepath.Join(filepath.Dir(snapshotPath), "..", ChecksumDir, filepath.Base(snapshotPath))
+}
+
+// SaveChecksum records the checksum of the given snapshot on disk, so that it can be verified
+// before the snapshot is restored, when the checksums recorded in the cluster are not available.
+func SaveChecksum(snapshotPath, checksum string) error {
+	path := checksumPath(snapshotPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(checksum), 0600)
+}
+
+// RemoveChecksum removes the checksum recorded on disk for the given snapshot, if any.
+func RemoveChecksum(snapshotPath string) error {
+	if err := os.Remove(checksumPath(snapshotPath)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// VerifyChecksum compares the checksum of the given snapshot against the checksum recorded on disk
+// when it was taken. Returns false if no checksum was recorded for the snapshot.
+func VerifyChecksum(snapshotPath string) (bool, error) {
+	b, err := os.ReadFile(checksumPath(snapshotPath))
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	expected := strings.TrimSpace(string(b))
+	checksum, err := Checksum(snapshotPath)
+	if err != nil {
+		return false, err
+	}
+	if checksum != expected {
+		return false, fmt.Errorf("checksum mismatch: expected %s, got %s", expected, checksum)
+	}
+	return true, nil
+}
+
+// Check opens the etcd database contained in the given snapshot file read-only, and
+// validates its structure. Compressed snapshots are extracted to a temporary file first.
+// Returns the etcd revision at which the snapshot was taken.
+func Check(path string) (int64, error) {
+	if strings.HasSuffix(path, CompressedExtension) {
+		extracted, err := Extract(path)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to decompress snapshot")
+		}
+		defer os.Remove(extracted)
+		path = extracted
+	}
+
+	status, err := snapshotv3.NewV3(zap.NewNop()).Status(path)
+	if err != nil {
+		return 0, errors.Wrap(err, "snapshot database is corrupt")
+	}
+	return status.Revision, nil
+}
+
+// Verify checks the snapshot file at the given path against the checksum and revision recorded
+// for this snapshot, and validates the structure of the etcd database or incremental snapshot it
+// contains. Snapshots taken before checksums were recorded are only checked for structure.
+func (sf *File) Verify(path string) error {
+	if sf.Checksum != "" {
+		checksum, err := Checksum(path)
+		if err != nil {
+			return err
+		}
+		if checksum != sf.Checksum {
+			return fmt.Errorf("checksum mismatch: expected %s, got %s", sf.Checksum, checksum)
+		}
+	}
+
+	var revision int64
+	if strings.HasSuffix(path, DeltaExtension) {
+		d, err := ReadDelta(path)
+		if err != nil {
+			return errors.Wrap(err, "incremental snapshot is corrupt")
+		}
+		revision = d.Revision
+	} else {
+		r, err := Check(path)
+		if err != nil {
+			return err
+		}
+		revision = r
+	}
+	if sf.Revision != 0 && revision != sf.Revision {
+		return fmt.Errorf("revision mismatch: expected %d, got %d", sf.Revision, revision)
+	}
+	return nil
+}
+
+// Extract decompresses the first file in the given zip archive to a temporary file,
+// and returns the path to the temporary file.
+func Extract(path string) (string, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	if len(r.File) == 0 {
+		return "", errors.New("archive is empty")
+	}
+
+	src, err := r.File[0].Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp("", "etcd-snapshot-")
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
@@ -0,0 +1,167 @@
+package snapshot
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// writeTestDB writes a minimal etcd backend database with a single key at the given revision.
+func writeTestDB(t *testing.T, path string, revision int64) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("key"))
+		if err != nil {
+			return err
+		}
+		rev := make([]byte, 17)
+		binary.BigEndian.PutUint64(rev[0:8], uint64(revision))
+		rev[8] = '_'
+		return b.Put(rev, []byte("value"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestZip(t *testing.T, path, src string) {
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	defer zw.Close()
+
+	w, err := zw.Create(filepath.Base(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	if _, err := io.Copy(w, in); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_UnitFileVerify(t *testing.T) {
+	dir := t.TempDir()
+
+	dbPath := filepath.Join(dir, "on-demand-server-1")
+	writeTestDB(t, dbPath, 42)
+	zipPath := dbPath + CompressedExtension
+	writeTestZip(t, zipPath, dbPath)
+	corruptPath := filepath.Join(dir, "on-demand-server-2")
+	if err := os.WriteFile(corruptPath, []byte("not a snapshot"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dbChecksum, err := Checksum(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipChecksum, err := Checksum(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		sf      File
+		wantErr bool
+	}{
+		{
+			name: "Matching checksum and revision",
+			path: dbPath,
+			sf:   File{Checksum: dbChecksum, Revision: 42},
+		},
+		{
+			name: "Compressed snapshot",
+			path: zipPath,
+			sf:   File{Checksum: zipChecksum, Revision: 42},
+		},
+		{
+			name: "No recorded metadata",
+			path: dbPath,
+		},
+		{
+			name:    "Checksum mismatch",
+			path:    dbPath,
+			sf:      File{Checksum: zipChecksum},
+			wantErr: true,
+		},
+		{
+			name:    "Revision mismatch",
+			path:    zipPath,
+			sf:      File{Revision: 41},
+			wantErr: true,
+		},
+		{
+			name:    "Corrupt snapshot",
+			path:    corruptPath,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.sf.Verify(tt.path); (err != nil) != tt.wantErr {
+				t.Errorf("File.Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_UnitVerifyChecksum(t *testing.T) {
+	snapshotDir := filepath.Join(t.TempDir(), "snapshots")
+	if err := os.Mkdir(snapshotDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(snapshotDir, "etcd-snapshot-1")
+	if err := os.WriteFile(path, []byte("snapshot"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := VerifyChecksum(path); ok || err != nil {
+		t.Errorf("expected no checksum to be recorded, got %v, %v", ok, err)
+	}
+
+	checksum, err := Checksum(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveChecksum(path, checksum); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyChecksum(path); !ok || err != nil {
+		t.Errorf("expected checksum to be verified, got %v, %v", ok, err)
+	}
+
+	if err := os.WriteFile(path, []byte("corrupt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyChecksum(path); err == nil {
+		t.Errorf("expected checksum mismatch to fail verification")
+	}
+
+	if err := RemoveChecksum(path); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyChecksum(path); ok || err != nil {
+		t.Errorf("expected checksum to be removed, got %v, %v", ok, err)
+	}
+}
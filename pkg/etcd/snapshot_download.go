@@ -0,0 +1,191 @@
+package etcd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	k3s "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	"github.com/k3s-io/k3s/pkg/etcd/snapshot"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	snapshotDownloadPath = "/db/snapshot/download"
+	downloadURLTTL       = 24 * time.Hour
+	// downloadURLRefresh is the interval at which snapshot records are reconciled to refresh download
+	// URLs. URLs are refreshed when they are within half their TTL of expiring, so that a valid URL is
+	// always available between reconciles.
+	downloadURLRefresh = downloadURLTTL / 4
+
+	retentionPolicyCount = "Count"
+	retentionPolicyNone  = "None"
+)
+
+// retentionPolicy returns the automatic retention policy applied to scheduled snapshots by this server.
+// Snapshot requests may override the retention for a single operation, but do not change the policy.
+func (e *ETCD) retentionPolicy() *k3s.ETCDSnapshotRetention {
+	if e.retention != nil {
+		return e.retention
+	}
+	if e.config.EtcdSnapshotRetention < 1 {
+		return &k3s.ETCDSnapshotRetention{Policy: retentionPolicyNone}
+	}
+	return &k3s.ETCDSnapshotRetention{
+		Policy: retentionPolicyCount,
+		Count:  e.config.EtcdSnapshotRetention,
+		Prefix: e.config.EtcdSnapshotName,
+	}
+}
+
+// setSnapshotStatus sets the retention policy and download URL for a snapshot file. The download
+// URL is only regenerated if the existing URL is missing or close to expiring.
+func (e *ETCD) setSnapshotStatus(sf *snapshot.File, now time.Time) {
+	policy := e.retentionPolicy()
+	if policy.Policy == retentionPolicyCount && strings.HasPrefix(sf.Name, policy.Prefix) {
+		sf.Retention = policy
+	} else {
+		sf.Retention = &k3s.ETCDSnapshotRetention{Policy: retentionPolicyNone}
+	}
+
+	if sf.Status != snapshot.SuccessfulStatus || e.config.Token == "" || e.config.SupervisorPort == 0 {
+		sf.DownloadURL = ""
+		sf.DownloadURLExpiration = nil
+		return
+	}
+	if sf.DownloadURL != "" && sf.DownloadURLExpiration != nil && now.Add(downloadURLTTL/2).Before(sf.DownloadURLExpiration.Time) {
+		return
+	}
+
+	expires := now.Add(downloadURLTTL).Truncate(time.Second)
+	query := url.Values{}
+	query.Set("node", sf.NodeName)
+	query.Set("name", sf.Name)
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", signDownload(e.config.Token, sf.NodeName, sf.Name, expires.Unix()))
+	u := url.URL{
+		Scheme:   "https",
+		Host:     net.JoinHostPort(e.address, strconv.Itoa(e.config.SupervisorPort)),
+		Path:     snapshotDownloadPath,
+		RawQuery: query.Encode(),
+	}
+	sf.DownloadURL = u.String()
+	sf.DownloadURLExpiration = &metav1.Time{Time: expires}
+}
+
+// snapshotStatusStale returns true if the retention policy or download URL recorded for a snapshot
+// need to be updated.
+func (e *ETCD) snapshotStatusStale(esf *k3s.ETCDSnapshotFile, now time.Time) bool {
+	sf := &snapshot.File{}
+	sf.FromETCDSnapshotFile(esf)
+	e.setSnapshotStatus(sf, now)
+	return sf.DownloadURL != esf.Status.DownloadURL || !equality.Semantic.DeepEqual(sf.Retention, esf.Status.Retention)
+}
+
+// signDownload returns the hex-encoded HMAC of the snapshot download parameters, keyed by the server token.
+func signDownload(token, storageNode, name string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	fmt.Fprintf(mac, "%s\n%s\n%d", storageNode, name, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// downloadHandler serves snapshot files to clients presenting a valid signed download URL. Local snapshots
+// can only be downloaded from the server that stores them; S3 snapshots are retrieved from S3 by the server.
+func (e *ETCD) downloadHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			util.SendError(fmt.Errorf("method not allowed"), rw, req, http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := req.URL.Query()
+		storageNode := query.Get("node")
+		name := query.Get("name")
+		expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+		if err != nil || e.config.Token == "" || name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") ||
+			!hmac.Equal([]byte(query.Get("signature")), []byte(signDownload(e.config.Token, storageNode, name, expires))) {
+			util.SendError(errors.New("invalid snapshot download signature"), rw, req, http.StatusForbidden)
+			return
+		}
+		if time.Now().After(time.Unix(expires, 0)) {
+			util.SendError(errors.New("snapshot download URL expired"), rw, req, http.StatusForbidden)
+			return
+		}
+
+		var snapshotPath string
+		switch storageNode {
+		case "s3":
+			s3client, err := e.getS3Client(req.Context())
+			if err != nil {
+				util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusServiceUnavailable)
+				return
+			}
+			tmpDir, err := os.MkdirTemp("", "etcd-snapshot-download-")
+			if err != nil {
+				util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusInternalServerError)
+				return
+			}
+			defer os.RemoveAll(tmpDir)
+
+			// Download also retrieves the snapshot metadata into a sibling of the snapshot directory,
+			// so use a subdirectory to keep everything within the temporary directory.
+			downloadDir := filepath.Join(tmpDir, "snapshots")
+			if err := os.Mkdir(downloadDir, 0700); err != nil {
+				util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusInternalServerError)
+				return
+			}
+			if snapshotPath, err = s3client.Download(req.Context(), name, downloadDir); err != nil {
+				if snapshot.IsNotExist(err) {
+					util.SendError(fmt.Errorf("snapshot %s not found", name), rw, req, http.StatusNotFound)
+				} else {
+					util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusInternalServerError)
+				}
+				return
+			}
+		case os.Getenv("NODE_NAME"):
+			snapshotDir, err := snapshotDir(e.config, false)
+			if err != nil {
+				util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusInternalServerError)
+				return
+			}
+			snapshotPath = filepath.Join(snapshotDir, name)
+		default:
+			util.SendError(fmt.Errorf("snapshot %s is not stored on this server", name), rw, req, http.StatusNotFound)
+			return
+		}
+
+		f, err := os.Open(snapshotPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				util.SendError(fmt.Errorf("snapshot %s not found", name), rw, req, http.StatusNotFound)
+			} else {
+				util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusInternalServerError)
+			}
+			return
+		}
+		defer f.Close()
+
+		fi, err := f.Stat()
+		if err != nil {
+			util.SendErrorWithID(err, "etcd-snapshot", rw, req, http.StatusInternalServerError)
+			return
+		}
+		logrus.Infof("Serving snapshot %s from %s to %s", name, storageNode, req.RemoteAddr)
+		rw.Header().Set("Content-Type", "application/octet-stream")
+		rw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		http.ServeContent(rw, req, name, fi.ModTime(), f)
+	})
+}
@@ -0,0 +1,134 @@
+package etcd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/etcd/snapshot"
+)
+
+func Test_UnitETCD_SnapshotDownload(t *testing.T) {
+	nodeName := "k3s-server-1"
+	t.Setenv("NODE_NAME", nodeName)
+
+	e := &ETCD{
+		address: "127.0.0.1",
+		config: &config.Control{
+			DataDir:               t.TempDir(),
+			EtcdSnapshotName:      "etcd-snapshot",
+			EtcdSnapshotRetention: 5,
+			SupervisorPort:        6443,
+			Token:                 "token",
+		},
+	}
+	snapshotDir, err := snapshotDir(e.config, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"etcd-snapshot-k3s-server-1-1700000000", "on-demand-k3s-server-1-1700000000"} {
+		if err := os.WriteFile(filepath.Join(snapshotDir, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	scheduled := &snapshot.File{Name: "etcd-snapshot-k3s-server-1-1700000000", NodeName: nodeName, Status: snapshot.SuccessfulStatus}
+	onDemand := &snapshot.File{Name: "on-demand-k3s-server-1-1700000000", NodeName: nodeName, Status: snapshot.SuccessfulStatus}
+	failed := &snapshot.File{Name: "etcd-snapshot-k3s-server-1-1700000001", NodeName: nodeName, Status: snapshot.FailedStatus}
+	remote := &snapshot.File{Name: "etcd-snapshot-k3s-server-2-1700000000", NodeName: "k3s-server-2", Status: snapshot.SuccessfulStatus}
+	for _, sf := range []*snapshot.File{scheduled, onDemand, failed, remote} {
+		e.setSnapshotStatus(sf, now)
+	}
+
+	t.Run("retention", func(t *testing.T) {
+		if r := scheduled.Retention; r == nil || r.Policy != retentionPolicyCount || r.Count != 5 || r.Prefix != "etcd-snapshot" {
+			t.Errorf("unexpected retention for scheduled snapshot: %+v", r)
+		}
+		if r := onDemand.Retention; r == nil || r.Policy != retentionPolicyNone {
+			t.Errorf("unexpected retention for on-demand snapshot: %+v", r)
+		}
+	})
+
+	t.Run("download URL", func(t *testing.T) {
+		if failed.DownloadURL != "" || failed.DownloadURLExpiration != nil {
+			t.Errorf("unexpected download URL for failed snapshot: %s", failed.DownloadURL)
+		}
+		if scheduled.DownloadURLExpiration == nil || scheduled.DownloadURLExpiration.Sub(now) > downloadURLTTL {
+			t.Errorf("unexpected download URL expiration: %v", scheduled.DownloadURLExpiration)
+		}
+		// URLs that are not close to expiring are kept
+		existing := scheduled.DownloadURL
+		e.setSnapshotStatus(scheduled, now.Add(downloadURLTTL/4))
+		if scheduled.DownloadURL != existing {
+			t.Errorf("download URL was regenerated before it needed to be refreshed")
+		}
+		e.setSnapshotStatus(scheduled, now.Add(downloadURLTTL*3/4))
+		if scheduled.DownloadURL == existing {
+			t.Errorf("download URL was not refreshed before it expired")
+		}
+	})
+
+	tests := []struct {
+		name     string
+		url      func() string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "valid signature",
+			url:      func() string { return onDemand.DownloadURL },
+			wantCode: http.StatusOK,
+			wantBody: onDemand.Name,
+		},
+		{
+			name: "invalid signature",
+			url: func() string {
+				u, _ := url.Parse(onDemand.DownloadURL)
+				q := u.Query()
+				q.Set("name", scheduled.Name)
+				u.RawQuery = q.Encode()
+				return u.String()
+			},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name: "expired",
+			url: func() string {
+				sf := &snapshot.File{Name: onDemand.Name, NodeName: nodeName, Status: snapshot.SuccessfulStatus}
+				e.setSnapshotStatus(sf, now.Add(-2*downloadURLTTL))
+				return sf.DownloadURL
+			},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "stored on another node",
+			url:      func() string { return remote.DownloadURL },
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Path != snapshotDownloadPath || u.Host != "127.0.0.1:6443" {
+				t.Errorf("unexpected download URL %s", u)
+			}
+			resp := httptest.NewRecorder()
+			e.handler(http.NotFoundHandler()).ServeHTTP(resp, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+			if resp.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d: %s", tt.wantCode, resp.Code, resp.Body.String())
+			}
+			if tt.wantBody != "" && resp.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, resp.Body.String())
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ const (
 	SnapshotOperationList   SnapshotOperation = "list"
 	SnapshotOperationPrune  SnapshotOperation = "prune"
 	SnapshotOperationDelete SnapshotOperation = "delete"
+	SnapshotOperationVerify SnapshotOperation = "verify"
 )
 
 type SnapshotRequest struct {
@@ -42,7 +43,7 @@ func (sr *SnapshotRequest) context() context.Context {
 	return context.Background()
 }
 
-// snapshotHandler handles snapshot save/list/prune requests from the CLI.
+// snapshotHandler handles snapshot save/list/prune/delete/verify requests from the CLI.
 func (e *ETCD) snapshotHandler() http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		sr, err := getSnapshotRequest(req)
@@ -58,6 +59,8 @@ func (e *ETCD) snapshotHandler() http.Handler {
 			err = e.withRequest(sr).handlePrune(rw, req)
 		case SnapshotOperationDelete:
 			err = e.withRequest(sr).handleDelete(rw, req, sr.Name)
+		case SnapshotOperationVerify:
+			err = e.withRequest(sr).handleVerify(rw, req, sr.Name)
 		default:
 			err = e.handleInvalid(rw, req)
 		}
@@ -135,6 +138,23 @@ func (e *ETCD) handleDelete(rw http.ResponseWriter, req *http.Request, snapshots
 	return err
 }
 
+func (e *ETCD) handleVerify(rw http.ResponseWriter, req *http.Request, snapshots []string) error {
+	if e.config.EtcdS3 != nil {
+		if _, err := e.getS3Client(req.Context()); err != nil {
+			err = errors.Wrap(err, "failed to initialize S3 client")
+			util.SendError(err, rw, req, http.StatusBadRequest)
+			return nil
+		}
+	}
+	sr, err := e.VerifySnapshots(req.Context(), snapshots)
+	if sr == nil {
+		util.SendError(err, rw, req, http.StatusInternalServerError)
+		return nil
+	}
+	sendSnapshotResponse(rw, req, sr)
+	return err
+}
+
 func (e *ETCD) handleInvalid(rw http.ResponseWriter, req *http.Request) error {
 	util.SendErrorWithID(fmt.Errorf("invalid snapshot operation"), "etcd-snapshot", rw, req, http.StatusBadRequest)
 	return nil
@@ -154,6 +174,8 @@ func (e *ETCD) withRequest(sr *SnapshotRequest) *ETCD {
 			EtcdSnapshotName:      e.config.EtcdSnapshotName,
 			EtcdSnapshotRetention: e.config.EtcdSnapshotRetention,
 			EtcdS3:                sr.S3,
+			SupervisorPort:        e.config.SupervisorPort,
+			Token:                 e.config.Token,
 		},
 		s3:         e.s3,
 		name:       e.name,
@@ -161,6 +183,7 @@ func (e *ETCD) withRequest(sr *SnapshotRequest) *ETCD {
 		cron:       e.cron,
 		cancel:     e.cancel,
 		snapshotMu: e.snapshotMu,
+		retention:  e.retentionPolicy(),
 	}
 	if len(sr.Name) > 0 {
 		re.config.EtcdSnapshotName = sr.Name[0]
@@ -0,0 +1,85 @@
+// Package firewall registers the ports and cluster CIDRs used by k3s with the host firewall, so
+// that nodes can run with firewalld or ufw enabled.
+package firewall
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ModeFirewalld registers a firewalld service for each role in the default zone, and adds the
+	// cluster CIDRs as sources of a zone that accepts and masquerades their traffic.
+	ModeFirewalld = "firewalld"
+	// ModeUFW registers a ufw application profile for each role, and allows traffic from the
+	// cluster CIDRs. All rules are commented with the program name, so that they can be found
+	// and deleted on uninstall.
+	ModeUFW = "ufw"
+)
+
+// Roles that ports are registered for. The server and agent on a node register their ports
+// separately, under a service or profile named for the role.
+const (
+	RoleServer = "server"
+	RoleAgent  = "agent"
+)
+
+// Port is a port or range of ports to be opened.
+type Port struct {
+	Start    int
+	End      int
+	Protocol string
+}
+
+// TCP returns a single TCP port.
+func TCP(port int) Port {
+	return Port{Start: port, Protocol: "tcp"}
+}
+
+// UDP returns a single UDP port.
+func UDP(port int) Port {
+	return Port{Start: port, Protocol: "udp"}
+}
+
+// firewalld returns the port in the form used by firewall-cmd, for example 2379-2380/tcp
+func (p Port) firewalld() string {
+	if p.End > p.Start {
+		return fmt.Sprintf("%d-%d/%s", p.Start, p.End, p.Protocol)
+	}
+	return fmt.Sprintf("%d/%s", p.Start, p.Protocol)
+}
+
+// ufw returns the port in the form used by ufw application profiles, for example 2379:2380/tcp
+func (p Port) ufw() string {
+	if p.End > p.Start {
+		return fmt.Sprintf("%d:%d/%s", p.Start, p.End, p.Protocol)
+	}
+	return fmt.Sprintf("%d/%s", p.Start, p.Protocol)
+}
+
+// Validate returns an error if the mode is not supported.
+func Validate(mode string) error {
+	switch mode {
+	case "", ModeFirewalld, ModeUFW:
+		return nil
+	}
+	return errors.Errorf("unsupported host firewall %q; must be one of %s, %s", mode, ModeFirewalld, ModeUFW)
+}
+
+// name returns the name of the firewalld service or ufw application profile for the role.
+func name(role string) string {
+	return version.Program + "-" + role
+}
+
+// ufwProfile returns the contents of the ufw application profile for the role.
+func ufwProfile(role string, ports []Port) string {
+	portList := make([]string, 0, len(ports))
+	for _, port := range ports {
+		portList = append(portList, port.ufw())
+	}
+	return fmt.Sprintf("[%s]\ntitle=%s %s\ndescription=Ports used by the %s %s\nports=%s\n",
+		name(role), version.Program, role, version.Program, role, strings.Join(portList, "|"))
+}
@@ -0,0 +1,127 @@
+//go:build linux
+// +build linux
+
+package firewall
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const ufwApplicationsDir = "/etc/ufw/applications.d"
+
+// Register opens the given ports for the role, and allows traffic from the given cluster and
+// service CIDRs, using the host firewall selected by mode. Changes are made to the permanent
+// firewall configuration, so that they persist across restarts of the firewall; registering
+// the same role again replaces its ports.
+func Register(mode, role string, ports []Port, cidrs []string) error {
+	if err := Validate(mode); err != nil {
+		return err
+	}
+	switch mode {
+	case ModeFirewalld:
+		return registerFirewalld(role, ports, cidrs)
+	case ModeUFW:
+		return registerUFW(role, ports, cidrs)
+	}
+	return nil
+}
+
+func registerFirewalld(role string, ports []Port, cidrs []string) error {
+	service := name(role)
+	zone := version.Program
+
+	// Recreate the service, so that ports that are no longer used are removed.
+	if run("firewall-cmd", "--permanent", "--info-service="+service) == nil {
+		run("firewall-cmd", "--permanent", "--remove-service="+service)
+		if err := run("firewall-cmd", "--permanent", "--delete-service="+service); err != nil {
+			return err
+		}
+	}
+	if err := run("firewall-cmd", "--permanent", "--new-service="+service); err != nil {
+		return err
+	}
+	for _, port := range ports {
+		if err := run("firewall-cmd", "--permanent", "--service="+service, "--add-port="+port.firewalld()); err != nil {
+			return err
+		}
+	}
+	if err := run("firewall-cmd", "--permanent", "--add-service="+service); err != nil {
+		return err
+	}
+
+	// Pod and service traffic is accepted and masqueraded by a dedicated zone, so that it can
+	// be removed on uninstall without affecting the host's own zones.
+	if run("firewall-cmd", "--permanent", "--info-zone="+zone) != nil {
+		if err := run("firewall-cmd", "--permanent", "--new-zone="+zone); err != nil {
+			return err
+		}
+	}
+	if err := run("firewall-cmd", "--permanent", "--zone="+zone, "--set-target=ACCEPT"); err != nil {
+		return err
+	}
+	if err := run("firewall-cmd", "--permanent", "--zone="+zone, "--add-masquerade"); err != nil {
+		return err
+	}
+	for _, cidr := range cidrs {
+		// A source can only be bound to a single zone; leave it alone if it has already been added
+		// to another zone, such as the trusted zone.
+		if out, err := exec.Command("firewall-cmd", "--permanent", "--get-zone-of-source="+cidr).Output(); err == nil {
+			if existing := strings.TrimSpace(string(out)); existing != zone {
+				logrus.Infof("Cluster CIDR %s is already bound to firewalld zone %s", cidr, existing)
+			}
+			continue
+		}
+		if err := run("firewall-cmd", "--permanent", "--zone="+zone, "--add-source="+cidr); err != nil {
+			return err
+		}
+	}
+
+	if err := run("firewall-cmd", "--reload"); err != nil {
+		return err
+	}
+	logrus.Infof("Registered firewalld service %s and zone %s", service, zone)
+	return nil
+}
+
+func registerUFW(role string, ports []Port, cidrs []string) error {
+	profile := name(role)
+	if err := os.MkdirAll(ufwApplicationsDir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(ufwApplicationsDir, profile), []byte(ufwProfile(role, ports)), 0644); err != nil {
+		return errors.Wrap(err, "failed to write ufw application profile")
+	}
+	if err := run("ufw", "app", "update", profile); err != nil {
+		return err
+	}
+	// ufw skips rules that already exist, so these are safe to add on every start.
+	if err := run("ufw", "allow", profile, "comment", version.Program); err != nil {
+		return err
+	}
+	for _, cidr := range cidrs {
+		if err := run("ufw", "allow", "from", cidr, "comment", version.Program); err != nil {
+			return err
+		}
+		if err := run("ufw", "route", "allow", "from", cidr, "comment", version.Program); err != nil {
+			return err
+		}
+	}
+	logrus.Infof("Registered ufw application profile %s", profile)
+	return nil
+}
+
+// run runs the given command, and returns an error that includes its output if it fails.
+func run(command string, args ...string) error {
+	out, err := exec.Command(command, args...).CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "%s %s failed: %s", command, strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package firewall
+
+import "github.com/pkg/errors"
+
+// Register opens the given ports for the role using the host firewall selected by mode. Host
+// firewall integration is only supported on Linux.
+func Register(mode, role string, ports []Port, cidrs []string) error {
+	if err := Validate(mode); err != nil {
+		return err
+	}
+	if mode != "" {
+		return errors.New("host firewall integration is only supported on Linux")
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package firewall
+
+import "testing"
+
+func Test_UnitUFWProfile(t *testing.T) {
+	ports := []Port{TCP(6443), {Start: 2379, End: 2380, Protocol: "tcp"}, UDP(8472)}
+	want := "[k3s-server]\ntitle=k3s server\ndescription=Ports used by the k3s server\nports=6443/tcp|2379:2380/tcp|8472/udp\n"
+	if got := ufwProfile(RoleServer, ports); got != want {
+		t.Errorf("ufwProfile() = %q, want %q", got, want)
+	}
+	for _, tt := range []struct {
+		port Port
+		want string
+	}{
+		{TCP(10250), "10250/tcp"},
+		{Port{Start: 2379, End: 2380, Protocol: "tcp"}, "2379-2380/tcp"},
+	} {
+		if got := tt.port.firewalld(); got != tt.want {
+			t.Errorf("firewalld() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func Test_UnitValidate(t *testing.T) {
+	for _, mode := range []string{"", ModeFirewalld, ModeUFW} {
+		if err := Validate(mode); err != nil {
+			t.Errorf("Validate(%q) error = %v", mode, err)
+		}
+	}
+	if err := Validate("iptables"); err == nil {
+		t.Errorf("Validate(%q) error = nil, want error", "iptables")
+	}
+}
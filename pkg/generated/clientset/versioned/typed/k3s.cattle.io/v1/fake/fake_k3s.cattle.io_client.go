@@ -32,10 +32,26 @@ func (c *FakeK3sV1) Addons(namespace string) v1.AddonInterface {
 	return newFakeAddons(c, namespace)
 }
 
+func (c *FakeK3sV1) ClusterCARotations() v1.ClusterCARotationInterface {
+	return newFakeClusterCARotations(c)
+}
+
+func (c *FakeK3sV1) ClusterSecretsEncryptions() v1.ClusterSecretsEncryptionInterface {
+	return newFakeClusterSecretsEncryptions(c)
+}
+
 func (c *FakeK3sV1) ETCDSnapshotFiles() v1.ETCDSnapshotFileInterface {
 	return newFakeETCDSnapshotFiles(c)
 }
 
+func (c *FakeK3sV1) ImageDigestPins() v1.ImageDigestPinInterface {
+	return newFakeImageDigestPins(c)
+}
+
+func (c *FakeK3sV1) NodeConfigs() v1.NodeConfigInterface {
+	return newFakeNodeConfigs(c)
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeK3sV1) RESTClient() rest.Interface {
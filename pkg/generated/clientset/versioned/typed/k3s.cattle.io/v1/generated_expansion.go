@@ -20,4 +20,12 @@ package v1
 
 type AddonExpansion interface{}
 
+type ClusterCARotationExpansion interface{}
+
+type ClusterSecretsEncryptionExpansion interface{}
+
 type ETCDSnapshotFileExpansion interface{}
+
+type ImageDigestPinExpansion interface{}
+
+type NodeConfigExpansion interface{}
@@ -29,7 +29,11 @@ import (
 type K3sV1Interface interface {
 	RESTClient() rest.Interface
 	AddonsGetter
+	ClusterCARotationsGetter
+	ClusterSecretsEncryptionsGetter
 	ETCDSnapshotFilesGetter
+	ImageDigestPinsGetter
+	NodeConfigsGetter
 }
 
 // K3sV1Client is used to interact with features provided by the k3s.cattle.io group.
@@ -41,10 +45,26 @@ func (c *K3sV1Client) Addons(namespace string) AddonInterface {
 	return newAddons(c, namespace)
 }
 
+func (c *K3sV1Client) ClusterCARotations() ClusterCARotationInterface {
+	return newClusterCARotations(c)
+}
+
+func (c *K3sV1Client) ClusterSecretsEncryptions() ClusterSecretsEncryptionInterface {
+	return newClusterSecretsEncryptions(c)
+}
+
 func (c *K3sV1Client) ETCDSnapshotFiles() ETCDSnapshotFileInterface {
 	return newETCDSnapshotFiles(c)
 }
 
+func (c *K3sV1Client) ImageDigestPins() ImageDigestPinInterface {
+	return newImageDigestPins(c)
+}
+
+func (c *K3sV1Client) NodeConfigs() NodeConfigInterface {
+	return newNodeConfigs(c)
+}
+
 // NewForConfig creates a new K3sV1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
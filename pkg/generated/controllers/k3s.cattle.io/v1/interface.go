@@ -32,7 +32,11 @@ func init() {
 
 type Interface interface {
 	Addon() AddonController
+	ClusterCARotation() ClusterCARotationController
+	ClusterSecretsEncryption() ClusterSecretsEncryptionController
 	ETCDSnapshotFile() ETCDSnapshotFileController
+	ImageDigestPin() ImageDigestPinController
+	NodeConfig() NodeConfigController
 }
 
 func New(controllerFactory controller.SharedControllerFactory) Interface {
@@ -49,6 +53,22 @@ func (v *version) Addon() AddonController {
 	return generic.NewController[*v1.Addon, *v1.AddonList](schema.GroupVersionKind{Group: "k3s.cattle.io", Version: "v1", Kind: "Addon"}, "addons", true, v.controllerFactory)
 }
 
+func (v *version) ClusterCARotation() ClusterCARotationController {
+	return generic.NewNonNamespacedController[*v1.ClusterCARotation, *v1.ClusterCARotationList](schema.GroupVersionKind{Group: "k3s.cattle.io", Version: "v1", Kind: "ClusterCARotation"}, "clustercarotations", v.controllerFactory)
+}
+
+func (v *version) ClusterSecretsEncryption() ClusterSecretsEncryptionController {
+	return generic.NewNonNamespacedController[*v1.ClusterSecretsEncryption, *v1.ClusterSecretsEncryptionList](schema.GroupVersionKind{Group: "k3s.cattle.io", Version: "v1", Kind: "ClusterSecretsEncryption"}, "clustersecretsencryptions", v.controllerFactory)
+}
+
 func (v *version) ETCDSnapshotFile() ETCDSnapshotFileController {
 	return generic.NewNonNamespacedController[*v1.ETCDSnapshotFile, *v1.ETCDSnapshotFileList](schema.GroupVersionKind{Group: "k3s.cattle.io", Version: "v1", Kind: "ETCDSnapshotFile"}, "etcdsnapshotfiles", v.controllerFactory)
 }
+
+func (v *version) ImageDigestPin() ImageDigestPinController {
+	return generic.NewNonNamespacedController[*v1.ImageDigestPin, *v1.ImageDigestPinList](schema.GroupVersionKind{Group: "k3s.cattle.io", Version: "v1", Kind: "ImageDigestPin"}, "imagedigestpins", v.controllerFactory)
+}
+
+func (v *version) NodeConfig() NodeConfigController {
+	return generic.NewNonNamespacedController[*v1.NodeConfig, *v1.NodeConfigList](schema.GroupVersionKind{Group: "k3s.cattle.io", Version: "v1", Kind: "NodeConfig"}, "nodeconfigs", v.controllerFactory)
+}
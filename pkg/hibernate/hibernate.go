@@ -0,0 +1,119 @@
+// Package hibernate implements server hibernation. Hibernating a server puts its node into
+// maintenance mode and scales down the packaged components in the kube-system namespace, so that
+// the server and its containers can be stopped cleanly before the host is suspended. Resuming a
+// server restores the scaled-down components to their previous replica counts.
+package hibernate
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/k3s-io/k3s/pkg/maintenance"
+	"github.com/k3s-io/k3s/pkg/version"
+	v1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsclient "k8s.io/client-go/kubernetes/typed/apps/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
+)
+
+// ReplicasAnnotation is set on deployments that were scaled down when the server was hibernated,
+// with the number of replicas to restore when the server is resumed.
+var ReplicasAnnotation = version.Program + ".io/hibernate-replicas"
+
+// Request is the body of a request to hibernate or resume a server.
+type Request struct {
+	Hibernate bool `json:"hibernate"`
+}
+
+// Status is the hibernation status of a server.
+type Status struct {
+	Node        string `json:"node"`
+	Hibernating bool   `json:"hibernating"`
+	// Deployments lists the deployments that were scaled down or restored by the request.
+	Deployments []string `json:"deployments"`
+	// Etcd is true if the node is an etcd member, in which case a snapshot should be taken
+	// before the server is stopped.
+	Etcd bool `json:"etcd"`
+}
+
+// Set hibernates or resumes the server running on the named node, and returns its updated status.
+// When hibernating, the node is put into maintenance mode before packaged components are scaled
+// down, so that the deploy controller does not apply manifests that would scale them back up.
+// When resuming, components are restored before maintenance mode is disabled.
+func Set(ctx context.Context, deployments appsclient.DeploymentInterface, nodes v1.NodeClient, nodeName string, hibernate bool) (*Status, error) {
+	if hibernate {
+		if _, err := maintenance.Set(nodes, nodeName, true); err != nil {
+			return nil, err
+		}
+	}
+
+	list, err := deployments.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	scaled := []string{}
+	for _, item := range list.Items {
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			deployment, err := deployments.Get(ctx, item.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if !scale(deployment, hibernate) {
+				return nil
+			}
+			if _, err := deployments.Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+				return err
+			}
+			scaled = append(scaled, deployment.Name)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(scaled)
+
+	status, err := maintenance.Set(nodes, nodeName, hibernate)
+	if err != nil {
+		return nil, err
+	}
+	return &Status{
+		Node:        status.Node,
+		Hibernating: status.Enabled,
+		Deployments: scaled,
+		Etcd:        status.Etcd,
+	}, nil
+}
+
+// scale scales the deployment down when hibernating, or restores its previous replica count when
+// resuming, and returns true if the deployment was modified. Deployments that are already scaled
+// to zero are not modified when hibernating, so that they are left scaled down when resuming.
+func scale(deployment *appsv1.Deployment, hibernate bool) bool {
+	value, ok := deployment.Annotations[ReplicasAnnotation]
+	if hibernate {
+		if ok || ptr.Deref(deployment.Spec.Replicas, 1) == 0 {
+			return false
+		}
+		if deployment.Annotations == nil {
+			deployment.Annotations = map[string]string{}
+		}
+		deployment.Annotations[ReplicasAnnotation] = strconv.Itoa(int(ptr.Deref(deployment.Spec.Replicas, 1)))
+		deployment.Spec.Replicas = ptr.To[int32](0)
+		return true
+	}
+	if !ok {
+		return false
+	}
+	delete(deployment.Annotations, ReplicasAnnotation)
+	replicas, err := strconv.Atoi(value)
+	if err != nil || replicas < 0 {
+		logrus.Warnf("Invalid %s annotation %q on deployment %s/%s; restoring 1 replica", ReplicasAnnotation, value, deployment.Namespace, deployment.Name)
+		replicas = 1
+	}
+	deployment.Spec.Replicas = ptr.To(int32(replicas))
+	return true
+}
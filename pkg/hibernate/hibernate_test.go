@@ -0,0 +1,53 @@
+package hibernate
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func Test_UnitScale(t *testing.T) {
+	tests := []struct {
+		name        string
+		replicas    *int32
+		wantScaled  bool
+		wantRestore int32
+	}{
+		{name: "scaled deployment is scaled down and restored", replicas: ptr.To[int32](2), wantScaled: true, wantRestore: 2},
+		{name: "deployment with default replicas is scaled down and restored", replicas: nil, wantScaled: true, wantRestore: 1},
+		{name: "deployment scaled to zero is left scaled down", replicas: ptr.To[int32](0), wantScaled: false, wantRestore: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: metav1.NamespaceSystem},
+				Spec:       appsv1.DeploymentSpec{Replicas: tt.replicas},
+			}
+
+			if got := scale(deployment, true); got != tt.wantScaled {
+				t.Fatalf("scale() = %v when hibernating, want %v", got, tt.wantScaled)
+			}
+			if tt.wantScaled && (*deployment.Spec.Replicas != 0 || deployment.Annotations[ReplicasAnnotation] == "") {
+				t.Errorf("scale() = %+v, want deployment scaled to zero and annotated", deployment)
+			}
+			if scale(deployment, true) {
+				t.Error("scale() modified deployment that is already hibernating")
+			}
+
+			if got := scale(deployment, false); got != tt.wantScaled {
+				t.Fatalf("scale() = %v when resuming, want %v", got, tt.wantScaled)
+			}
+			if got := ptr.Deref(deployment.Spec.Replicas, 1); got != tt.wantRestore {
+				t.Errorf("scale() replicas = %d after resuming, want %d", got, tt.wantRestore)
+			}
+			if _, ok := deployment.Annotations[ReplicasAnnotation]; ok {
+				t.Errorf("scale() left %s annotation after resuming", ReplicasAnnotation)
+			}
+			if scale(deployment, false) {
+				t.Error("scale() modified deployment that is not hibernating")
+			}
+		})
+	}
+}
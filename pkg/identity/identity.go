@@ -0,0 +1,283 @@
+// Package identity detects when a data-dir has been copied to another host, as happens when a disk
+// image that was booted with k3s installed is cloned onto many devices, and resets the certificates,
+// keys, and tokens that must be unique to each node and cluster.
+package identity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/sirupsen/logrus"
+)
+
+// stateFile is the file, relative to the data-dir, that records the host the data-dir belongs to.
+const stateFile = "host-identity.json"
+
+// backupDir is the directory, relative to the data-dir, that reset identity material is moved to.
+const backupDir = "reset-identity"
+
+var (
+	machineIDFiles  = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+	productUUIDFile = "/sys/class/dmi/id/product_uuid"
+)
+
+// agentMaterial are the patterns, relative to the data-dir, of the agent's certificates, keys, and
+// kubeconfigs. They are requested from the server again when the agent starts.
+var agentMaterial = []string{
+	"agent/*.crt",
+	"agent/*.key",
+	"agent/*.kubeconfig",
+	"agent/node-password.txt",
+}
+
+// serverMaterial are the paths, relative to the data-dir, of the server's certificate authorities,
+// tokens, and datastore. A new cluster is bootstrapped when the server starts without them, or the
+// material is retrieved from the cluster when joining an existing server. The etcd data dir may also
+// be set outside the data-dir with --etcd-data-dir.
+var serverMaterial = []string{
+	"server/tls",
+	"server/cred",
+	"server/token",
+	"server/token.sealed",
+	"server/node-token",
+	"server/agent-token",
+	"server/agent-token.sealed",
+	"server/db/etcd",
+	"server/db/state.db",
+	"server/db/state.db-shm",
+	"server/db/state.db-wal",
+}
+
+// datastoreMaterial are the paths, relative to the data-dir, of the server's datastore. Resetting them
+// discards the cluster state, or the node's etcd membership, so they are never reset automatically.
+var datastoreMaterial = []string{
+	"server/db/etcd",
+	"server/db/state.db",
+}
+
+// Host identifies the host that a data-dir belongs to. Fields that cannot be read on the host are
+// left empty, and are not compared.
+type Host struct {
+	MachineID   string `json:"machineID,omitempty"`
+	ProductUUID string `json:"productUUID,omitempty"`
+}
+
+// Current returns the identity of this host.
+func Current() Host {
+	host := Host{}
+	for _, file := range machineIDFiles {
+		if b, err := os.ReadFile(file); err == nil {
+			if host.MachineID = strings.TrimSpace(string(b)); host.MachineID != "" {
+				break
+			}
+		}
+	}
+	if b, err := os.ReadFile(productUUIDFile); err == nil {
+		host.ProductUUID = strings.ToLower(strings.TrimSpace(string(b)))
+	}
+	return host
+}
+
+// SameAs returns false if any field that is known for both hosts differs.
+func (h Host) SameAs(other Host) bool {
+	if h.MachineID != "" && other.MachineID != "" && h.MachineID != other.MachineID {
+		return false
+	}
+	if h.ProductUUID != "" && other.ProductUUID != "" && h.ProductUUID != other.ProductUUID {
+		return false
+	}
+	return true
+}
+
+// Check returns true if the data-dir contains identity material that was created on a different
+// host. If the data-dir does not record the host it belongs to, this host is recorded. The etcd data
+// dir is checked for material if it is set.
+func Check(dataDir, etcdDataDir string, current Host) (bool, error) {
+	b, err := os.ReadFile(filepath.Join(dataDir, stateFile))
+	if os.IsNotExist(err) {
+		return false, Record(dataDir, current)
+	} else if err != nil {
+		return false, err
+	}
+	recorded := Host{}
+	if err := json.Unmarshal(b, &recorded); err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", filepath.Join(dataDir, stateFile), err)
+	}
+	if recorded.SameAs(current) {
+		return false, nil
+	}
+	material, err := Material(dataDir, "", etcdDataDir)
+	if err != nil {
+		return false, err
+	}
+	return len(material) > 0, nil
+}
+
+// Record records that the data-dir belongs to the given host.
+func Record(dataDir string, host Host) error {
+	b, err := json.Marshal(host)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return err
+	}
+	return util.AtomicWrite(filepath.Join(dataDir, stateFile), b, 0600)
+}
+
+// NodePasswordFile returns the path of the node password file used by an agent with the given data-dir.
+func NodePasswordFile(dataDir string, rootless bool) string {
+	root := "/"
+	if rootless {
+		root = filepath.Join(dataDir, "agent")
+	}
+	return filepath.Join(root, "etc", "rancher", "node", "password")
+}
+
+// Material returns the identity material that exists in the data-dir, and the node password file and
+// etcd data dir if they are set and exist.
+func Material(dataDir, nodePasswordFile, etcdDataDir string) ([]string, error) {
+	var material []string
+	for _, pattern := range agentMaterial {
+		matches, err := filepath.Glob(filepath.Join(dataDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		material = append(material, matches...)
+	}
+	for _, path := range serverMaterial {
+		if _, err := os.Lstat(filepath.Join(dataDir, path)); err == nil {
+			material = append(material, filepath.Join(dataDir, path))
+		}
+	}
+	for _, path := range []string{nodePasswordFile, etcdDataDir} {
+		if path == "" || slices.Contains(material, path) {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			material = append(material, path)
+		}
+	}
+	return material, nil
+}
+
+// Reset moves the identity material in the data-dir, and the node password file if set, to a new
+// directory under the data-dir so that it can be recovered if the reset was not intended. An etcd
+// data dir outside the data-dir is likely on a different disk, and is renamed next to itself instead. New
+// material is generated or retrieved from the server when k3s is next started. This host is recorded
+// as the owner of the data-dir. The directory the material was moved to is returned, or an empty
+// string if there was no material to reset.
+func Reset(dataDir, nodePasswordFile, etcdDataDir string, current Host) (string, error) {
+	material, err := Material(dataDir, nodePasswordFile, etcdDataDir)
+	if err != nil {
+		return "", err
+	}
+	if len(material) == 0 {
+		return "", Record(dataDir, current)
+	}
+	now := time.Now()
+	dest := filepath.Join(dataDir, backupDir, now.UTC().Format("20060102T150405Z"))
+	for _, path := range material {
+		rel, err := filepath.Rel(dataDir, path)
+		outside := err != nil || strings.HasPrefix(rel, "..")
+		if outside && path == etcdDataDir {
+			// use the same name that etcd uses when it moves its data dir aside
+			target := path + "-old-" + strconv.FormatInt(now.Unix(), 10)
+			if err := os.Rename(path, target); err != nil {
+				return "", fmt.Errorf("failed to move %s to %s: %w", path, target, err)
+			}
+			logrus.Infof("Moved %s to %s", path, target)
+			continue
+		}
+		if outside {
+			// files outside the data-dir are kept under their absolute path
+			rel = filepath.Join("root", path)
+		}
+		target := filepath.Join(dest, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return "", err
+		}
+		if err := move(path, target); err != nil {
+			return "", fmt.Errorf("failed to move %s to %s: %w", path, target, err)
+		}
+		logrus.Infof("Moved %s to %s", path, target)
+	}
+	return dest, Record(dataDir, current)
+}
+
+// move renames the file or directory, falling back to copying and removing a file that is on a
+// different filesystem, such as the node password file.
+func move(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	info, statErr := os.Lstat(src)
+	if statErr != nil || !info.Mode().IsRegular() {
+		return err
+	}
+	b, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, b, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// Datastore returns the datastore material that exists in the data-dir, and the etcd data dir if it is
+// set and exists.
+func Datastore(dataDir, etcdDataDir string) []string {
+	var datastore []string
+	for _, path := range datastoreMaterial {
+		if _, err := os.Lstat(filepath.Join(dataDir, path)); err == nil {
+			datastore = append(datastore, filepath.Join(dataDir, path))
+		}
+	}
+	if etcdDataDir != "" && !slices.Contains(datastore, etcdDataDir) {
+		if _, err := os.Stat(etcdDataDir); err == nil {
+			datastore = append(datastore, etcdDataDir)
+		}
+	}
+	return datastore
+}
+
+// ResetIfCloned checks whether the data-dir was created on a different host when k3s starts. If it was,
+// the identity material is reset if reset is set, or a warning is logged. The material is not reset
+// automatically if the data-dir contains a datastore, as a host that was moved to new hardware, or
+// restored from a backup, cannot be told apart from a clone; an error is returned instead.
+func ResetIfCloned(dataDir, nodePasswordFile, etcdDataDir string, reset bool) error {
+	return resetIfCloned(dataDir, nodePasswordFile, etcdDataDir, reset, Current())
+}
+
+func resetIfCloned(dataDir, nodePasswordFile, etcdDataDir string, reset bool, current Host) error {
+	cloned, err := Check(dataDir, etcdDataDir, current)
+	if err != nil || !cloned {
+		return err
+	}
+	if !reset {
+		logrus.Warnf("Data-dir %s was created on a different host. If this host was cloned from a disk image, it shares certificates, keys, and tokens with the other clones; stop %s and run '%s reset-identity' to generate new ones, or set --reset-identity-on-clone to do so automatically", dataDir, version.Program, version.Program)
+		return nil
+	}
+	if datastore := Datastore(dataDir, etcdDataDir); len(datastore) > 0 {
+		return fmt.Errorf("data-dir %s was created on a different host, but contains a datastore that is not reset automatically: %s; if this host was cloned from a disk image, run '%s reset-identity' to reset it, or remove --reset-identity-on-clone to start with the existing identity", dataDir, strings.Join(datastore, ", "), version.Program)
+	}
+	logrus.Warnf("Data-dir %s was created on a different host; resetting certificates, keys, and tokens", dataDir)
+	dest, err := Reset(dataDir, nodePasswordFile, etcdDataDir, current)
+	if err != nil {
+		return fmt.Errorf("failed to reset identity: %w", err)
+	}
+	if dest != "" {
+		logrus.Infof("Previous identity material moved to %s", dest)
+	}
+	return nil
+}
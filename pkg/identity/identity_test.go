@@ -0,0 +1,162 @@
+package identity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("test"), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_UnitSameAs(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Host
+		want bool
+	}{
+		{name: "Same", a: Host{MachineID: "a", ProductUUID: "1"}, b: Host{MachineID: "a", ProductUUID: "1"}, want: true},
+		{name: "Different machine-id", a: Host{MachineID: "a", ProductUUID: "1"}, b: Host{MachineID: "b", ProductUUID: "1"}},
+		{name: "Different product UUID", a: Host{MachineID: "a", ProductUUID: "1"}, b: Host{MachineID: "a", ProductUUID: "2"}},
+		{name: "Unknown product UUID", a: Host{MachineID: "a", ProductUUID: "1"}, b: Host{MachineID: "a"}, want: true},
+		{name: "Unknown", a: Host{}, b: Host{MachineID: "a"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.SameAs(tt.b); got != tt.want {
+				t.Errorf("SameAs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_UnitCheck(t *testing.T) {
+	dataDir := t.TempDir()
+	golden := Host{MachineID: "golden"}
+	clone := Host{MachineID: "clone"}
+
+	if cloned, err := Check(dataDir, "", golden); err != nil || cloned {
+		t.Fatalf("Check() of new data-dir = %v, %v", cloned, err)
+	}
+	if cloned, err := Check(dataDir, "", clone); err != nil || cloned {
+		t.Fatalf("Check() of data-dir without identity material = %v, %v", cloned, err)
+	}
+	writeFile(t, filepath.Join(dataDir, "server", "token"))
+	if cloned, err := Check(dataDir, "", golden); err != nil || cloned {
+		t.Fatalf("Check() on recorded host = %v, %v", cloned, err)
+	}
+	if cloned, err := Check(dataDir, "", clone); err != nil || !cloned {
+		t.Fatalf("Check() on clone = %v, %v", cloned, err)
+	}
+}
+
+func Test_UnitReset(t *testing.T) {
+	dataDir := t.TempDir()
+	nodePasswordFile := filepath.Join(t.TempDir(), "etc", "rancher", "node", "password")
+	reset := []string{
+		filepath.Join(dataDir, "agent", "client-kubelet.crt"),
+		filepath.Join(dataDir, "agent", "client-kubelet.key"),
+		filepath.Join(dataDir, "agent", "kubelet.kubeconfig"),
+		filepath.Join(dataDir, "server", "tls", "server-ca.crt"),
+		filepath.Join(dataDir, "server", "token"),
+		filepath.Join(dataDir, "server", "token.sealed"),
+		filepath.Join(dataDir, "server", "db", "state.db"),
+		nodePasswordFile,
+	}
+	kept := []string{
+		filepath.Join(dataDir, "agent", "images", "airgap.tar"),
+		filepath.Join(dataDir, "agent", "etc", "containerd", "config.toml"),
+		filepath.Join(dataDir, "server", "manifests", "app.yaml"),
+		filepath.Join(dataDir, "server", "db", "snapshots", "etcd-snapshot"),
+	}
+	for _, path := range append(reset, kept...) {
+		writeFile(t, path)
+	}
+	if err := Record(dataDir, Host{MachineID: "golden"}); err != nil {
+		t.Fatal(err)
+	}
+
+	clone := Host{MachineID: "clone"}
+	dest, err := Reset(dataDir, nodePasswordFile, "", clone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range reset {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was not reset", path)
+		}
+	}
+	for _, path := range kept {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was not kept: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dest, "server", "tls", "server-ca.crt")); err != nil {
+		t.Errorf("reset material was not moved to %s: %v", dest, err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "root", nodePasswordFile)); err != nil {
+		t.Errorf("node password file was not moved to %s: %v", dest, err)
+	}
+	if cloned, err := Check(dataDir, "", clone); err != nil || cloned {
+		t.Errorf("Check() after reset = %v, %v", cloned, err)
+	}
+}
+
+func Test_UnitResetEtcdDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+	etcdDataDir := filepath.Join(t.TempDir(), "etcd")
+	writeFile(t, filepath.Join(etcdDataDir, "member", "snap", "db"))
+	if err := Record(dataDir, Host{MachineID: "golden"}); err != nil {
+		t.Fatal(err)
+	}
+
+	clone := Host{MachineID: "clone"}
+	if cloned, err := Check(dataDir, etcdDataDir, clone); err != nil || !cloned {
+		t.Fatalf("Check() of clone with etcd data dir = %v, %v", cloned, err)
+	}
+	if _, err := Reset(dataDir, "", etcdDataDir, clone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(etcdDataDir); !os.IsNotExist(err) {
+		t.Errorf("%s was not reset", etcdDataDir)
+	}
+	matches, err := filepath.Glob(filepath.Join(etcdDataDir+"-old-*", "member", "snap", "db"))
+	if err != nil || len(matches) != 1 {
+		t.Errorf("etcd data dir was not moved aside: %v, %v", matches, err)
+	}
+}
+
+func Test_UnitResetIfClonedDatastore(t *testing.T) {
+	dataDir := t.TempDir()
+	token := filepath.Join(dataDir, "server", "token")
+	writeFile(t, token)
+	writeFile(t, filepath.Join(dataDir, "server", "db", "state.db"))
+	if err := Record(dataDir, Host{MachineID: "golden"}); err != nil {
+		t.Fatal(err)
+	}
+
+	clone := Host{MachineID: "clone"}
+	if err := resetIfCloned(dataDir, "", "", true, clone); err == nil {
+		t.Fatalf("resetIfCloned() of clone with datastore succeeded, want error")
+	}
+	if _, err := os.Stat(token); err != nil {
+		t.Errorf("%s was reset with a datastore present: %v", token, err)
+	}
+
+	if err := os.Remove(filepath.Join(dataDir, "server", "db", "state.db")); err != nil {
+		t.Fatal(err)
+	}
+	if err := resetIfCloned(dataDir, "", "", true, clone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(token); !os.IsNotExist(err) {
+		t.Errorf("%s was not reset", token)
+	}
+}
@@ -0,0 +1,98 @@
+package identity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// shortHashLength is the number of hex characters returned by the shorthash template function.
+const shortHashLength = 8
+
+// NodeNameData is the data available to node name templates.
+type NodeNameData struct {
+	Hostname    string
+	MachineID   string
+	ProductUUID string
+}
+
+var nodeNameFuncs = template.FuncMap{
+	"shorthash": shortHash,
+	"lower":     strings.ToLower,
+	"trunc":     trunc,
+}
+
+// ResolveNodeName returns the node name rendered from the template if one is set, or the configured
+// node name if not. Only one of the two may be set.
+func ResolveNodeName(nodeName, tmpl string) (string, error) {
+	if tmpl == "" {
+		return nodeName, nil
+	}
+	if nodeName != "" {
+		return "", errors.New("node-name and node-name-template cannot both be set")
+	}
+	nodeName, err := NodeName(tmpl)
+	if err != nil {
+		return "", err
+	}
+	logrus.Infof("Using node name %s rendered from node-name-template", nodeName)
+	return nodeName, nil
+}
+
+// NodeName renders the node name template for this host.
+func NodeName(tmpl string) (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	host := Current()
+	return RenderNodeName(tmpl, NodeNameData{
+		Hostname:    strings.ToLower(hostname),
+		MachineID:   host.MachineID,
+		ProductUUID: host.ProductUUID,
+	})
+}
+
+// RenderNodeName renders the node name template with the given data. The rendered name is lowercased,
+// and must be a valid node name.
+func RenderNodeName(tmpl string, data NodeNameData) (string, error) {
+	t, err := template.New("node-name-template").Funcs(nodeNameFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid node-name-template: %w", err)
+	}
+	b := &strings.Builder{}
+	if err := t.Execute(b, data); err != nil {
+		return "", fmt.Errorf("failed to render node-name-template: %w", err)
+	}
+	name := strings.ToLower(strings.TrimSpace(b.String()))
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", fmt.Errorf("node-name-template rendered invalid node name %q: %s", name, strings.Join(errs, "; "))
+	}
+	return name, nil
+}
+
+// shortHash returns the first characters of the hex-encoded SHA-256 hash of the value. An empty value is
+// an error, so that a template referencing an identifier that is not available on this host does not
+// render the same name on every host.
+func shortHash(value string) (string, error) {
+	if value == "" {
+		return "", errors.New("shorthash of empty value; the identifier is not available on this host")
+	}
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])[:shortHashLength], nil
+}
+
+// trunc returns at most the first n characters of the value.
+func trunc(n int, value string) string {
+	if len(value) > n {
+		return value[:n]
+	}
+	return value
+}
@@ -0,0 +1,47 @@
+package identity
+
+import (
+	"testing"
+)
+
+func Test_UnitRenderNodeName(t *testing.T) {
+	data := NodeNameData{Hostname: "edge", MachineID: "0123456789abcdef", ProductUUID: "4C4C4544-0042"}
+	tests := []struct {
+		name    string
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		{name: "Hostname", tmpl: "{{.Hostname}}", want: "edge"},
+		{name: "Machine ID hash", tmpl: "{{.Hostname}}-{{.MachineID | shorthash}}", want: "edge-9f9f5111"},
+		{name: "Lowercased", tmpl: "{{.ProductUUID}}", want: "4c4c4544-0042"},
+		{name: "Truncated", tmpl: "{{.Hostname}}-{{.ProductUUID | lower | trunc 4}}", want: "edge-4c4c"},
+		{name: "Invalid name", tmpl: "{{.Hostname}}_1", wantErr: true},
+		{name: "Unknown field", tmpl: "{{.Serial}}", wantErr: true},
+		{name: "Invalid template", tmpl: "{{.Hostname", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderNodeName(tt.tmpl, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderNodeName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RenderNodeName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := RenderNodeName("{{.Hostname}}-{{.MachineID | shorthash}}", NodeNameData{Hostname: "edge"}); err == nil {
+		t.Error("RenderNodeName() with unavailable machine-id did not return an error")
+	}
+}
+
+func Test_UnitResolveNodeName(t *testing.T) {
+	if got, err := ResolveNodeName("node-1", ""); err != nil || got != "node-1" {
+		t.Errorf("ResolveNodeName() without template = %q, %v", got, err)
+	}
+	if _, err := ResolveNodeName("node-1", "{{.Hostname}}"); err == nil {
+		t.Error("ResolveNodeName() with node name and template did not return an error")
+	}
+}
@@ -0,0 +1,273 @@
+// Package images lists the container images used by packaged components, along with the digest of
+// each image for each architecture, and applies the system default registry and per-component
+// image overrides.
+package images
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/distribution/reference"
+	helmchart "github.com/k3s-io/helm-controller/pkg/controllers/chart"
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/cloudprovider"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Packaged components that images can be overridden for.
+const (
+	CoreDNS              = "coredns"
+	HelmJob              = "helm-job"
+	LocalPathHelper      = "local-path-helper"
+	LocalPathProvisioner = "local-path-provisioner"
+	MetricsServer        = "metrics-server"
+	Pause                = "pause"
+	ServiceLB            = "servicelb"
+	Traefik              = "traefik"
+)
+
+// defaults are the images that components are packaged with, relative to the system default registry.
+var defaults = map[string]string{
+	CoreDNS:              "rancher/mirrored-coredns-coredns:1.12.0",
+	HelmJob:              helmchart.DefaultJobImage,
+	LocalPathHelper:      "rancher/mirrored-library-busybox:1.36.1",
+	LocalPathProvisioner: "rancher/local-path-provisioner:v0.0.31",
+	MetricsServer:        "rancher/mirrored-metrics-server:v0.7.2",
+	Pause:                cmds.DefaultPauseImage,
+	ServiceLB:            cloudprovider.DefaultLBImage,
+	Traefik:              "rancher/mirrored-library-traefik:2.11.18",
+}
+
+// digestsJSON maps the fully qualified name of each default image to its digests. It is generated
+// by scripts/airgap/generate-digests.sh, which scripts/build runs if the digests are empty or do not
+// cover every image in the airgap image list; the build fails if they still do not.
+//
+//go:embed digests.json
+var digestsJSON []byte
+
+// Digests are the digests of a multi-architecture image.
+type Digests struct {
+	// Digest is the digest of the image index.
+	Digest string `json:"digest,omitempty"`
+	// Platforms maps each platform, in os/arch[/variant] format, to the digest of its image manifest.
+	Platforms map[string]string `json:"platforms,omitempty"`
+}
+
+// Image is the image used by a packaged component.
+type Image struct {
+	Component string `json:"component"`
+	// Image is the reference that the component is deployed with, after the system default
+	// registry and any override are applied.
+	Image string `json:"image"`
+	// Default is the reference that the component is packaged with.
+	Default  string `json:"default"`
+	Override bool   `json:"override,omitempty"`
+	Digests
+}
+
+// Components returns the names of all packaged components that have images.
+func Components() []string {
+	components := make([]string, 0, len(defaults))
+	for component := range defaults {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	return components
+}
+
+// compatibility lists the versions of component images that are supported by this release, as
+// semver ranges. Overrides that change the tag of these images must specify a version within the
+// range, so that images can be updated to pick up fixes without moving to a version that is not
+// compatible with the packaged manifests or charts.
+var compatibility = map[string]string{
+	CoreDNS:              ">=1.12.0 <1.13.0",
+	LocalPathProvisioner: ">=0.0.31 <0.1.0",
+	MetricsServer:        ">=0.7.2 <0.8.0",
+	Traefik:              ">=2.11.18 <2.12.0",
+}
+
+var (
+	// tagRegexp matches valid image tags.
+	tagRegexp = regexp.MustCompile("^" + reference.TagRegexp.String() + "$")
+	// versionRegexp matches tags that look like a version, which may be used as overrides without a
+	// leading ':', as they cannot be mistaken for an image name.
+	versionRegexp = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+)*(-[\w.-]+)?$`)
+)
+
+// tagOverride returns the tag of an override that only replaces the tag of the default image. Such
+// overrides are either a tag prefixed with ':', or a version; anything else is an image reference.
+func tagOverride(override string) (string, bool) {
+	if tag, ok := strings.CutPrefix(override, ":"); ok {
+		return tag, true
+	}
+	return override, versionRegexp.MatchString(override)
+}
+
+// ParseOverrides parses image overrides in component=image format, and returns a map of component
+// to image. Components must be known, and images must be either a tag, which replaces the tag of the
+// default image, or a valid reference with a tag or digest. Tags must be prefixed with ':', unless
+// they are a version. Tags must be compatible with this
+// release, for components that have a compatibility range.
+func ParseOverrides(values []string) (map[string]string, error) {
+	overrides := map[string]string{}
+	for _, value := range values {
+		component, image, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid system image %q: must be in component=image format", value)
+		}
+		if _, ok := defaults[component]; !ok {
+			return nil, fmt.Errorf("invalid system image %q: unknown component %s; must be one of %s", value, component, strings.Join(Components(), ", "))
+		}
+		tag, ok := tagOverride(image)
+		if ok && !tagRegexp.MatchString(tag) {
+			return nil, fmt.Errorf("invalid system image %q: invalid tag %s", value, tag)
+		} else if !ok {
+			named, err := reference.ParseNormalizedNamed(image)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid system image %q", value)
+			}
+			if reference.IsNameOnly(named) {
+				return nil, fmt.Errorf("invalid system image %q: image must include a tag or digest", value)
+			}
+			tag = ""
+			if tagged, ok := named.(reference.Tagged); ok {
+				tag = tagged.Tag()
+			}
+		}
+		if err := checkCompatibility(component, tag); err != nil {
+			return nil, errors.Wrapf(err, "invalid system image %q", value)
+		}
+		overrides[component] = image
+	}
+	return overrides, nil
+}
+
+// LoadOverrides reads image overrides from a YAML file that maps components to images, and parses
+// them along with image overrides in component=image format. Overrides in values take precedence
+// over overrides in the file.
+func LoadOverrides(file string, values []string) (map[string]string, error) {
+	if file == "" {
+		return ParseOverrides(values)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read system images file")
+	}
+	fileOverrides := map[string]string{}
+	if err := yaml.Unmarshal(b, &fileOverrides); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse system images file %s", file)
+	}
+	fileValues := make([]string, 0, len(fileOverrides)+len(values))
+	for component, image := range fileOverrides {
+		fileValues = append(fileValues, component+"="+image)
+	}
+	sort.Strings(fileValues)
+	return ParseOverrides(append(fileValues, values...))
+}
+
+// checkCompatibility returns an error if the tag is not within the compatibility range for the
+// component. Overrides without a tag, which reference an image only by digest, cannot be checked.
+func checkCompatibility(component, tag string) error {
+	compatible, ok := compatibility[component]
+	if !ok || tag == "" {
+		return nil
+	}
+	version, err := semver.ParseTolerant(tag)
+	if err != nil {
+		return fmt.Errorf("tag %s is not a version; %s images must be versions in the range %s", tag, component, compatible)
+	}
+	if !semver.MustParseRange(compatible)(version) {
+		return fmt.Errorf("version %s is not compatible with this release; %s images must be versions in the range %s", tag, component, compatible)
+	}
+	return nil
+}
+
+// Reference returns the image reference for a component. Overrides that are a tag replace the tag
+// of the default image; other overrides are used as-is. Default images are prefixed with the
+// system default registry, if set.
+func Reference(component, registry string, overrides map[string]string) string {
+	image := defaults[component]
+	if override, ok := overrides[component]; ok {
+		tag, ok := tagOverride(override)
+		if !ok {
+			return override
+		}
+		repository, _, _ := strings.Cut(image, ":")
+		image = repository + ":" + tag
+	}
+	if registry == "" {
+		return image
+	}
+	return registry + "/" + image
+}
+
+// List returns the images used by all packaged components.
+func List(registry string, overrides map[string]string) ([]Image, error) {
+	digests := map[string]Digests{}
+	if err := json.Unmarshal(digestsJSON, &digests); err != nil {
+		return nil, errors.Wrap(err, "failed to read packaged image digests")
+	}
+
+	images := []Image{}
+	for _, component := range Components() {
+		image := Image{
+			Component: component,
+			Image:     Reference(component, registry, overrides),
+			Default:   defaults[component],
+		}
+		if _, ok := overrides[component]; ok {
+			image.Override = true
+			if named, err := reference.ParseNormalizedNamed(image.Image); err == nil {
+				if digested, ok := named.(reference.Digested); ok {
+					image.Digest = digested.Digest().String()
+				}
+			}
+		} else if named, err := reference.ParseNormalizedNamed(image.Default); err == nil {
+			image.Digests = digests[named.String()]
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+// TemplateVars returns the manifest template variables for the images of all packaged components.
+// For each component, %{IMAGE_<COMPONENT>}% is replaced with the image reference, and
+// %{IMAGE_<COMPONENT>_REGISTRY}%, %{IMAGE_<COMPONENT>_REPOSITORY}% and %{IMAGE_<COMPONENT>_TAG}% are
+// replaced with its parts, for use in Helm chart values. If an override is a reference with a
+// digest, the tag includes the digest, and the default tag is used if the reference does not
+// have a tag.
+func TemplateVars(registry string, overrides map[string]string) map[string]string {
+	templateVars := map[string]string{}
+	for _, component := range Components() {
+		image := Reference(component, registry, overrides)
+		repository, tag, _ := strings.Cut(defaults[component], ":")
+		imageRegistry := registry
+		if override, ok := overrides[component]; ok {
+			if overrideTag, ok := tagOverride(override); ok {
+				tag = overrideTag
+			} else if named, err := reference.ParseNormalizedNamed(image); err == nil {
+				imageRegistry = reference.Domain(named)
+				repository = reference.Path(named)
+				if tagged, ok := named.(reference.Tagged); ok {
+					tag = tagged.Tag()
+				}
+				if digested, ok := named.(reference.Digested); ok {
+					tag += "@" + digested.Digest().String()
+				}
+			}
+		}
+		key := "IMAGE_" + strings.ToUpper(strings.ReplaceAll(component, "-", "_"))
+		templateVars["%{"+key+"}%"] = image
+		templateVars["%{"+key+"_REGISTRY}%"] = imageRegistry
+		templateVars["%{"+key+"_REPOSITORY}%"] = repository
+		templateVars["%{"+key+"_TAG}%"] = tag
+	}
+	return templateVars
+}
@@ -0,0 +1,179 @@
+package images
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_UnitParseOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "tag and digest references",
+			values: []string{"coredns=registry.example.com/coredns:1.12.0", "pause=registry.example.com/pause@sha256:" + sha},
+			want:   map[string]string{CoreDNS: "registry.example.com/coredns:1.12.0", Pause: "registry.example.com/pause@sha256:" + sha},
+		},
+		{name: "missing separator", values: []string{"coredns"}, wantErr: true},
+		{name: "unknown component", values: []string{"kube-proxy=registry.example.com/kube-proxy:v1.32.0"}, wantErr: true},
+		{name: "invalid reference", values: []string{"coredns=Registry.Example.com/CoreDNS:1.12.0"}, wantErr: true},
+		{name: "reference without tag or digest", values: []string{"coredns=registry.example.com/coredns"}, wantErr: true},
+		{
+			name:   "compatible tag",
+			values: []string{"traefik=2.11.20", "metrics-server=registry.example.com/metrics-server:v0.7.3"},
+			want:   map[string]string{Traefik: "2.11.20", MetricsServer: "registry.example.com/metrics-server:v0.7.3"},
+		},
+		{
+			name:   "tag for component without compatibility range",
+			values: []string{"local-path-helper=:latest"},
+			want:   map[string]string{LocalPathHelper: ":latest"},
+		},
+		{name: "bare tag that is not a version", values: []string{"local-path-helper=latest"}, wantErr: true},
+		{name: "invalid tag", values: []string{"local-path-helper=:-latest"}, wantErr: true},
+		{name: "incompatible tag", values: []string{"traefik=3.3.2"}, wantErr: true},
+		{name: "incompatible reference", values: []string{"coredns=registry.example.com/coredns:1.11.4"}, wantErr: true},
+		{name: "tag that is not a version", values: []string{"metrics-server=:latest"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOverrides(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOverrides() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseOverrides() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("ParseOverrides()[%s] = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func Test_UnitLoadOverrides(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "images.yaml")
+	if err := os.WriteFile(file, []byte("traefik: 2.11.19\ncoredns: 1.12.1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadOverrides(file, []string{"traefik=2.11.20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[Traefik] != "2.11.20" || got[CoreDNS] != "1.12.1" {
+		t.Errorf("LoadOverrides() = %v, want flag value to take precedence over file", got)
+	}
+
+	if err := os.WriteFile(file, []byte("coredns: 1.13.0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOverrides(file, nil); err == nil {
+		t.Error("LoadOverrides() expected error for incompatible version in file")
+	}
+	if _, err := LoadOverrides(filepath.Join(t.TempDir(), "missing.yaml"), nil); err == nil {
+		t.Error("LoadOverrides() expected error for missing file")
+	}
+}
+
+const sha = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func Test_UnitList(t *testing.T) {
+	overrides := map[string]string{Traefik: "registry.example.com/traefik@sha256:" + sha}
+	list, err := List("mirror.example.com", overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(Components()) {
+		t.Fatalf("List() returned %d images, want %d", len(list), len(Components()))
+	}
+	for _, image := range list {
+		switch image.Component {
+		case CoreDNS:
+			if image.Image != "mirror.example.com/"+defaults[CoreDNS] || image.Override {
+				t.Errorf("List() coredns = %+v, want default image from system default registry", image)
+			}
+		case Traefik:
+			if image.Image != overrides[Traefik] || !image.Override || image.Digest != "sha256:"+sha {
+				t.Errorf("List() traefik = %+v, want override with digest", image)
+			}
+		}
+	}
+}
+
+func Test_UnitTemplateVars(t *testing.T) {
+	tests := []struct {
+		name         string
+		registry     string
+		overrides    map[string]string
+		wantImage    string
+		wantRegistry string
+		wantRepo     string
+		wantTag      string
+	}{
+		{
+			name:      "default",
+			wantImage: "rancher/mirrored-library-traefik:2.11.18",
+			wantRepo:  "rancher/mirrored-library-traefik",
+			wantTag:   "2.11.18",
+		},
+		{
+			name:         "system default registry",
+			registry:     "mirror.example.com",
+			wantImage:    "mirror.example.com/rancher/mirrored-library-traefik:2.11.18",
+			wantRegistry: "mirror.example.com",
+			wantRepo:     "rancher/mirrored-library-traefik",
+			wantTag:      "2.11.18",
+		},
+		{
+			name:         "override with tag",
+			registry:     "mirror.example.com",
+			overrides:    map[string]string{Traefik: "registry.example.com:5000/traefik/traefik:2.11.20"},
+			wantImage:    "registry.example.com:5000/traefik/traefik:2.11.20",
+			wantRegistry: "registry.example.com:5000",
+			wantRepo:     "traefik/traefik",
+			wantTag:      "2.11.20",
+		},
+		{
+			name:         "override with version tag",
+			registry:     "mirror.example.com",
+			overrides:    map[string]string{Traefik: "2.11.20"},
+			wantImage:    "mirror.example.com/rancher/mirrored-library-traefik:2.11.20",
+			wantRegistry: "mirror.example.com",
+			wantRepo:     "rancher/mirrored-library-traefik",
+			wantTag:      "2.11.20",
+		},
+		{
+			name:         "override with digest",
+			overrides:    map[string]string{Traefik: "registry.example.com/traefik@sha256:" + sha},
+			wantImage:    "registry.example.com/traefik@sha256:" + sha,
+			wantRegistry: "registry.example.com",
+			wantRepo:     "traefik",
+			wantTag:      "2.11.18@sha256:" + sha,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TemplateVars(tt.registry, tt.overrides)
+			if got["%{IMAGE_TRAEFIK}%"] != tt.wantImage {
+				t.Errorf("TemplateVars() image = %q, want %q", got["%{IMAGE_TRAEFIK}%"], tt.wantImage)
+			}
+			if got["%{IMAGE_TRAEFIK_REGISTRY}%"] != tt.wantRegistry {
+				t.Errorf("TemplateVars() registry = %q, want %q", got["%{IMAGE_TRAEFIK_REGISTRY}%"], tt.wantRegistry)
+			}
+			if got["%{IMAGE_TRAEFIK_REPOSITORY}%"] != tt.wantRepo {
+				t.Errorf("TemplateVars() repository = %q, want %q", got["%{IMAGE_TRAEFIK_REPOSITORY}%"], tt.wantRepo)
+			}
+			if got["%{IMAGE_TRAEFIK_TAG}%"] != tt.wantTag {
+				t.Errorf("TemplateVars() tag = %q, want %q", got["%{IMAGE_TRAEFIK_TAG}%"], tt.wantTag)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Package instance derives the names of host-global resources used by the agent, so that more than
+// one independent instance can run on the same host. When no instance name is set, the names used
+// by a standalone installation are returned. Not all host resources can be namespaced: kube-proxy
+// and the network policy controller must be disabled, and pod host ports are mapped in iptables
+// chains shared by all instances.
+package instance
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/version"
+)
+
+// nameRegexp limits instance names to a length that keeps the derived network interface names
+// within the kernel's 15 character limit.
+var nameRegexp = regexp.MustCompile(`^[a-z0-9]{1,8}$`)
+
+// Validate returns an error if the instance name cannot be used to derive resource names.
+func Validate(name string) error {
+	if name != "" && !nameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid instance name %q: must be 1-8 lowercase alphanumeric characters", name)
+	}
+	return nil
+}
+
+// RunDir returns the directory holding runtime state and sockets, such as the embedded containerd
+// socket.
+func RunDir(name string) string {
+	if name == "" {
+		return "/run/" + version.Program
+	}
+	return "/run/" + version.Program + "-" + name
+}
+
+// KubeletRootDir returns the kubelet's root directory, which holds pod volumes, the device plugin
+// and pod resources sockets, and the kubelet's certificates.
+func KubeletRootDir(name string) string {
+	if name == "" {
+		return "/var/lib/kubelet"
+	}
+	return "/var/lib/kubelet-" + name
+}
+
+// SubnetFile returns the path of the flannel subnet file read by the flannel CNI plugin.
+func SubnetFile(name string) string {
+	if name == "" {
+		return "/run/flannel/subnet.env"
+	}
+	return filepath.Join(RunDir(name), "flannel", "subnet.env")
+}
+
+// CNIDataDir returns the directory used by the flannel CNI plugin to store the network
+// configuration of each container.
+func CNIDataDir(name string) string {
+	if name == "" {
+		return "/var/lib/cni/flannel"
+	}
+	return "/var/lib/cni/flannel-" + name
+}
+
+// NetworkName returns the CNI network name. The host-local IPAM plugin stores address
+// allocations in a directory named for the network.
+func NetworkName(name string) string {
+	if name == "" {
+		return "cbr0"
+	}
+	return "cbr0-" + name
+}
+
+// CNIConfName returns the name of the flannel CNI config file, so that instances can share a CNI
+// config directory.
+func CNIConfName(name string) string {
+	if name == "" {
+		return "10-flannel.conflist"
+	}
+	return "10-flannel-" + name + ".conflist"
+}
+
+// BridgeName returns the name of the bridge interface that pods are attached to.
+func BridgeName(name string) string {
+	if name == "" {
+		return "cni0"
+	}
+	return "cni-" + name
+}
+
+// VNI returns the flannel VXLAN network identifier, which also determines the names of the flannel
+// VXLAN interfaces. Named instances use an identifier between 100 and 9999 derived from the name;
+// the default identifier of 1 is never used by a named instance.
+func VNI(name string) int {
+	if name == "" {
+		return 1
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return 100 + int(h.Sum32()%9900)
+}
+
+// vniDir holds a file for each VNI used by a named instance on the host, containing the instance's
+// name. It is under /run so that it is cleared on reboot, along with the interfaces.
+var vniDir = "/run/" + version.Program + "-vni"
+
+// ClaimVNI records that the instance uses its flannel VXLAN network identifier, and returns an error
+// if it is already used by another instance on the host. As identifiers are derived from a hash of
+// the instance name, different names may map to the same identifier.
+func ClaimVNI(name string) error {
+	if name == "" {
+		return nil
+	}
+	vni := VNI(name)
+	if err := os.MkdirAll(vniDir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(vniDir, strconv.Itoa(vni))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err == nil {
+		defer f.Close()
+		_, err = f.WriteString(name)
+		return err
+	} else if !os.IsExist(err) {
+		return err
+	}
+	owner, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if other := strings.TrimSpace(string(owner)); other != name {
+		return fmt.Errorf("flannel VNI %d for instance name %q is already used by instance %q on this host; choose a different instance name", vni, name, other)
+	}
+	return nil
+}
+
+// Interfaces returns the names of the network interfaces that are created for the instance by
+// flannel and the CNI bridge plugin.
+func Interfaces(name string) []string {
+	vni := VNI(name)
+	interfaces := []string{BridgeName(name), fmt.Sprintf("flannel.%d", vni), fmt.Sprintf("flannel-v6.%d", vni)}
+	if name == "" {
+		interfaces = append(interfaces, "flannel-wg", "flannel-wg-v6")
+	}
+	return interfaces
+}
@@ -0,0 +1,76 @@
+package instance
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func Test_UnitValidate(t *testing.T) {
+	for _, name := range []string{"", "ci1", "abcdefgh"} {
+		if err := Validate(name); err != nil {
+			t.Errorf("expected %q to be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"CI", "ci-1", "abcdefghi", "a/b"} {
+		if err := Validate(name); err == nil {
+			t.Errorf("expected %q to be invalid", name)
+		}
+	}
+}
+
+func Test_UnitNames(t *testing.T) {
+	if got := Interfaces(""); !slices.Equal(got, []string{"cni0", "flannel.1", "flannel-v6.1", "flannel-wg", "flannel-wg-v6"}) {
+		t.Errorf("unexpected default interfaces %v", got)
+	}
+	if got := RunDir(""); got != "/run/k3s" {
+		t.Errorf("unexpected default run dir %s", got)
+	}
+
+	seen := map[string]bool{}
+	for _, name := range []string{"a", "b", "ci1", "ci2", "abcdefgh"} {
+		if vni := VNI(name); vni < 100 || vni > 9999 {
+			t.Errorf("VNI %d for %q out of range", vni, name)
+		}
+		if VNI(name) != VNI(name) {
+			t.Errorf("VNI for %q is not stable", name)
+		}
+		for _, iface := range Interfaces(name) {
+			if len(iface) > 15 {
+				t.Errorf("interface name %s for %q is too long", iface, name)
+			}
+			if seen[iface] {
+				t.Errorf("interface name %s for %q is not unique", iface, name)
+			}
+			seen[iface] = true
+		}
+		if RunDir(name) == RunDir("") || KubeletRootDir(name) == KubeletRootDir("") || SubnetFile(name) == SubnetFile("") || CNIDataDir(name) == CNIDataDir("") || NetworkName(name) == NetworkName("") || CNIConfName(name) == CNIConfName("") {
+			t.Errorf("names for %q are not namespaced", name)
+		}
+	}
+}
+
+func Test_UnitClaimVNI(t *testing.T) {
+	vniDir = t.TempDir()
+
+	// find a name that collides with "a"
+	var other string
+	for i := 0; other == ""; i++ {
+		if name := fmt.Sprintf("c%d", i); VNI(name) == VNI("a") {
+			other = name
+		}
+	}
+
+	if err := ClaimVNI("a"); err != nil {
+		t.Fatalf("expected VNI to be claimed: %v", err)
+	}
+	if err := ClaimVNI("a"); err != nil {
+		t.Errorf("expected VNI to be claimed again by the same instance: %v", err)
+	}
+	if err := ClaimVNI(other); err == nil {
+		t.Errorf("expected VNI %d claimed by \"a\" to be refused for %q", VNI("a"), other)
+	}
+	if err := ClaimVNI(""); err != nil {
+		t.Errorf("expected default instance not to claim a VNI: %v", err)
+	}
+}
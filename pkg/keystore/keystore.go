@@ -0,0 +1,403 @@
+// Package keystore protects the private keys, tokens, and credentials in the server data-dir, and the admin
+// kubeconfig, by sealing them with a key storage backend, such as the host's TPM, so that a copy of the disk
+// alone is not sufficient to impersonate the cluster or its administrators.
+package keystore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// File stores keys and tokens unencrypted in the server data-dir.
+	File = "file"
+	// TPM seals keys and tokens to the host's TPM.
+	TPM = "tpm"
+)
+
+// SealedSuffix is appended to the name of a protected file to get the name of its sealed copy.
+const SealedSuffix = ".sealed"
+
+// externalDir is the directory within the run dir that holds unsealed copies of protected files that are
+// outside the server data-dir.
+const externalDir = "external"
+
+// syncInterval is the interval at which protected files are checked for changes, in case a change was not
+// seen by the file watcher.
+var syncInterval = time.Minute
+
+// settleTime is the time that a protected file must be unchanged for before it is sealed by Watch, so that
+// files are not replaced while they are still being written.
+var settleTime = time.Second
+
+// Files are the protected files, relative to the server data-dir, and may contain glob patterns. Private keys
+// can be used to issue credentials for, or impersonate, any identity in the cluster; the tokens and passwd file
+// can be used to join the cluster or decrypt the bootstrap data stored in the datastore; and the encryption
+// config holds the keys used to encrypt secrets at rest.
+var Files = []string{
+	"tls/*.key",
+	"tls/etcd/*.key",
+	"tls/dynamic-cert.json",
+	"cred/passwd",
+	"cred/ipsec.psk",
+	"cred/encryption-config.json",
+	"token",
+	"agent-token",
+}
+
+// Store seals and unseals data. The name identifies the data, and must match when it is unsealed.
+type Store interface {
+	Seal(name string, data []byte) ([]byte, error)
+	Unseal(name string, data []byte) ([]byte, error)
+}
+
+// New returns the store for the given key storage backend. A nil store is returned for file storage.
+func New(backend string) (Store, error) {
+	switch backend {
+	case "", File:
+		return nil, nil
+	case TPM:
+		return NewTPM(), nil
+	}
+	return nil, fmt.Errorf("invalid key-storage %q: must be %s or %s", backend, File, TPM)
+}
+
+// Sync seals any protected files in dataDir, and any externalFiles, that have been created or changed, and
+// unseals protected files into runDir, which should be on a tmpfs, replacing each file with a symlink to its
+// unsealed copy so that it can be read and written at its usual path. External files must be absolute paths,
+// such as the admin kubeconfig. If store is nil, any sealed files are instead restored to their usual path,
+// so that key storage can be disabled.
+func Sync(store Store, dataDir, runDir string, externalFiles ...string) error {
+	_, err := syncFiles(store, dataDir, runDir, 0, externalFiles)
+	return err
+}
+
+// syncFiles syncs the protected files, skipping any plaintext files that have been modified within the settle
+// time. It returns true if any files were skipped.
+func syncFiles(store Store, dataDir, runDir string, settle time.Duration, externalFiles []string) (bool, error) {
+	files, err := protectedFiles(dataDir, runDir, externalFiles)
+	if err != nil {
+		return false, err
+	}
+	skipped := false
+	for _, f := range files {
+		if fi, err := os.Lstat(f.path); err == nil && fi.Mode().IsRegular() && time.Since(fi.ModTime()) < settle {
+			skipped = true
+			continue
+		}
+		if err := syncFile(store, f); err != nil {
+			return skipped, errors.Wrapf(err, "failed to sync %s", f.path)
+		}
+	}
+	return skipped, nil
+}
+
+// Watch calls Sync shortly after a protected file may have been created or changed, until the context is
+// cancelled, so that files written while the server is running are sealed as soon as they have been
+// written. Sync is also called periodically, in case a change was not seen.
+func Watch(ctx context.Context, store Store, dataDir, runDir string, externalFiles ...string) {
+	var events <-chan fsnotify.Event
+	var watchErrors <-chan error
+	if watcher, err := fsnotify.NewWatcher(); err != nil {
+		logrus.Warnf("Failed to watch protected files, changes will be sealed every %s: %v", syncInterval, err)
+	} else {
+		defer watcher.Close()
+		for _, dir := range watchDirs(dataDir, externalFiles) {
+			if err := watcher.Add(dir); err != nil && !os.IsNotExist(err) {
+				logrus.Warnf("Failed to watch %s, changes will be sealed every %s: %v", dir, syncInterval, err)
+			}
+		}
+		events, watchErrors = watcher.Events, watcher.Errors
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			skipped, err := syncFiles(store, dataDir, runDir, settleTime, externalFiles)
+			if err != nil {
+				logrus.Errorf("Failed to sync key storage: %v", err)
+			}
+			if skipped {
+				timer.Reset(settleTime)
+			} else {
+				timer.Reset(syncInterval)
+			}
+		case <-events:
+			// wait for writes to the file to settle before syncing.
+			timer.Reset(settleTime)
+		case err := <-watchErrors:
+			logrus.Warnf("Error watching protected files: %v", err)
+		}
+	}
+}
+
+// protectedFile is a file that is sealed by the key storage backend.
+type protectedFile struct {
+	// path is the usual path of the file.
+	path string
+	// unsealed is the path of the unsealed copy of the file in the run dir.
+	unsealed string
+	// name is the name embedded in the sealed copy of the file.
+	name string
+	// external is true if the file is outside the data-dir. External files, such as the admin kubeconfig,
+	// are rewritten by the server at startup, so a sealed copy that cannot be unsealed, because the disk
+	// was cloned from another host, is discarded instead of preventing the server from starting.
+	external bool
+}
+
+// protectedFiles returns the protected files that currently exist in dataDir, either sealed or unsealed,
+// and the external files.
+func protectedFiles(dataDir, runDir string, externalFiles []string) ([]protectedFile, error) {
+	seen := map[string]bool{}
+	files := []protectedFile{}
+	for _, pattern := range Files {
+		matches, err := filepath.Glob(filepath.Join(dataDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		sealed, err := filepath.Glob(filepath.Join(dataDir, pattern) + SealedSuffix)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range sealed {
+			matches = append(matches, strings.TrimSuffix(s, SealedSuffix))
+		}
+		for _, path := range matches {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			rel, err := filepath.Rel(dataDir, path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, protectedFile{path: path, unsealed: filepath.Join(runDir, rel), name: credentialName(rel)})
+		}
+	}
+	for _, path := range externalFiles {
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		name := credentialName(path)
+		files = append(files, protectedFile{path: path, unsealed: filepath.Join(runDir, externalDir, name), name: name, external: true})
+	}
+	return files, nil
+}
+
+// watchDirs returns the directories containing protected files.
+func watchDirs(dataDir string, externalFiles []string) []string {
+	seen := map[string]bool{}
+	dirs := []string{}
+	for _, file := range Files {
+		seen[filepath.Dir(filepath.Join(dataDir, file))] = true
+	}
+	for _, file := range externalFiles {
+		if file != "" {
+			seen[filepath.Dir(file)] = true
+		}
+	}
+	for dir := range seen {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+func syncFile(store Store, f protectedFile) error {
+	path := f.path
+	sealed := path + SealedSuffix
+	unsealed := f.unsealed
+
+	fi, err := os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+		if _, err := os.Stat(sealed); os.IsNotExist(err) {
+			return nil
+		}
+		if _, err := os.Stat(unsealed); err == nil {
+			// the symlink was removed while the unsealed copy was in use; the file has been deleted.
+			if err := removeIfExists(sealed); err != nil {
+				return err
+			}
+			return removeIfExists(unsealed)
+		}
+		if store == nil {
+			return fmt.Errorf("file is sealed but key-storage is %s", File)
+		}
+		if err := unseal(store, sealed, unsealed, f.name); err != nil {
+			return discardExternal(f, err)
+		}
+		return symlink(unsealed, path)
+	case err != nil:
+		return err
+	case fi.Mode().IsRegular():
+		// the file was created, or replaced by an atomic write, since it was last sealed.
+		if store == nil {
+			return removeIfExists(sealed)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// the unsealed copy keeps the file's mode and owner, as the admin kubeconfig may be readable by
+		// other users.
+		if err := writeFile(unsealed, b, fi.Mode().Perm()); err != nil {
+			return err
+		}
+		if err := copyOwner(fi, unsealed); err != nil {
+			return err
+		}
+		if err := seal(store, sealed, f.name, b); err != nil {
+			return err
+		}
+		logrus.Infof("Sealed %s with %s key storage", path, TPM)
+		return symlink(unsealed, path)
+	case fi.Mode()&os.ModeSymlink == 0:
+		return nil
+	}
+
+	// the file is a symlink; ignore it unless it points at the unsealed copy, as agent-token may be a
+	// symlink to the server token.
+	if target, err := os.Readlink(path); err != nil || target != unsealed {
+		return err
+	}
+	ufi, err := os.Stat(unsealed)
+	if os.IsNotExist(err) {
+		// runDir has been cleared by a reboot.
+		if store == nil {
+			return fmt.Errorf("file is sealed but key-storage is %s", File)
+		}
+		if err := unseal(store, sealed, unsealed, f.name); err != nil {
+			return discardExternal(f, err)
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if store == nil {
+		b, err := os.ReadFile(unsealed)
+		if err != nil {
+			return err
+		}
+		if err := util.AtomicWrite(path, b, ufi.Mode().Perm()); err != nil {
+			return err
+		}
+		if err := copyOwner(ufi, path); err != nil {
+			return err
+		}
+		logrus.Infof("Restored %s from %s key storage", path, TPM)
+		if err := removeIfExists(sealed); err != nil {
+			return err
+		}
+		return removeIfExists(unsealed)
+	}
+	// the file was written through the symlink since it was last sealed.
+	sfi, err := os.Stat(sealed)
+	if err == nil && !ufi.ModTime().After(sfi.ModTime()) {
+		return nil
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	b, err := os.ReadFile(unsealed)
+	if err != nil {
+		return err
+	}
+	if err := seal(store, sealed, f.name, b); err != nil {
+		return err
+	}
+	logrus.Infof("Sealed %s with %s key storage", path, TPM)
+	return nil
+}
+
+// discardExternal removes an external file and its sealed copy if it could not be unsealed, so that it is
+// rewritten by the server. The unseal error is returned for files in the data-dir.
+func discardExternal(f protectedFile, err error) error {
+	if !f.external {
+		return err
+	}
+	logrus.Warnf("Discarding %s, which will be rewritten: %v", f.path, err)
+	if err := removeIfExists(f.path); err != nil {
+		return err
+	}
+	return removeIfExists(f.path + SealedSuffix)
+}
+
+// seal seals the data and writes it to the sealed file.
+func seal(store Store, sealed, name string, b []byte) error {
+	s, err := store.Seal(name, b)
+	if err != nil {
+		return err
+	}
+	return util.AtomicWrite(sealed, s, 0600)
+}
+
+// unseal unseals the sealed file and writes it to the unsealed file, with the same modification time, so that
+// it is not sealed again until it is changed.
+func unseal(store Store, sealed, unsealed, name string) error {
+	s, err := os.ReadFile(sealed)
+	if err != nil {
+		return err
+	}
+	sfi, err := os.Stat(sealed)
+	if err != nil {
+		return err
+	}
+	b, err := store.Unseal(name, s)
+	if err != nil {
+		return err
+	}
+	defer clear(b)
+	if err := writeFile(unsealed, b, 0600); err != nil {
+		return err
+	}
+	return os.Chtimes(unsealed, sfi.ModTime(), sfi.ModTime())
+}
+
+// writeFile writes an unsealed file, creating its parent directories. The directories can be traversed, but
+// not listed, by other users, so that unsealed files that are readable by other users, such as the admin
+// kubeconfig, can be read through their symlinks.
+func writeFile(file string, b []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0711); err != nil {
+		return err
+	}
+	return util.AtomicWrite(file, b, perm)
+}
+
+// symlink atomically replaces path with a symlink to target.
+func symlink(target, path string) error {
+	tmp := path + ".tmp"
+	if err := removeIfExists(tmp); err != nil {
+		return err
+	}
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func removeIfExists(file string) error {
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// credentialName returns the name embedded in the sealed copy of a file. The file is relative to the server
+// data-dir, or an absolute path for external files.
+func credentialName(file string) string {
+	return version.Program + "-" + strings.ReplaceAll(strings.TrimPrefix(filepath.ToSlash(file), "/"), "/", "-")
+}
@@ -0,0 +1,252 @@
+package keystore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeStore "seals" data by prefixing it with its name.
+type fakeStore struct {
+	sealed int
+}
+
+func (f *fakeStore) Seal(name string, data []byte) ([]byte, error) {
+	f.sealed++
+	return append([]byte(name+":"), data...), nil
+}
+
+func (f *fakeStore) Unseal(name string, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(name+":")) {
+		return nil, errors.New("name mismatch")
+	}
+	return bytes.TrimPrefix(data, []byte(name+":")), nil
+}
+
+func Test_UnitSync(t *testing.T) {
+	dataDir := t.TempDir()
+	runDir := t.TempDir()
+	store := &fakeStore{}
+	key := filepath.Join(dataDir, "tls", "server-ca.key")
+	if err := os.MkdirAll(filepath.Dir(key), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(key, []byte("ca-key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// agent-token is not protected when it is a symlink to the server token
+	if err := os.Symlink(filepath.Join(dataDir, "token"), filepath.Join(dataDir, "agent-token")); err != nil {
+		t.Fatal(err)
+	}
+
+	// plaintext files are sealed and replaced with a symlink to the unsealed copy
+	if err := Sync(store, dataDir, runDir); err != nil {
+		t.Fatal(err)
+	}
+	checkUnsealed(t, key, runDir, filepath.Join("tls", "server-ca.key"), "ca-key")
+	if b, _ := os.ReadFile(key + SealedSuffix); string(b) != "k3s-tls-server-ca.key:ca-key" {
+		t.Errorf("sealed file = %q", b)
+	}
+	if target, _ := os.Readlink(filepath.Join(dataDir, "agent-token")); target != filepath.Join(dataDir, "token") {
+		t.Errorf("agent-token symlink was changed to %q", target)
+	}
+
+	// unchanged files are not sealed again
+	if err := Sync(store, dataDir, runDir); err != nil {
+		t.Fatal(err)
+	}
+	if store.sealed != 1 {
+		t.Errorf("sealed %d times, want 1", store.sealed)
+	}
+
+	// files are unsealed after the run dir is cleared by a reboot
+	if err := os.RemoveAll(runDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := Sync(store, dataDir, runDir); err != nil {
+		t.Fatal(err)
+	}
+	checkUnsealed(t, key, runDir, filepath.Join("tls", "server-ca.key"), "ca-key")
+	if store.sealed != 1 {
+		t.Errorf("sealed %d times after unsealing, want 1", store.sealed)
+	}
+
+	// files written through the symlink are sealed again
+	future := time.Now().Add(time.Minute)
+	if err := os.WriteFile(key, []byte("rotated"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(key, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if err := Sync(store, dataDir, runDir); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(key + SealedSuffix); string(b) != "k3s-tls-server-ca.key:rotated" {
+		t.Errorf("sealed file after write = %q", b)
+	}
+
+	// sealed files cannot be read with file storage once the run dir is cleared
+	if err := os.RemoveAll(runDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := Sync(nil, dataDir, runDir); err == nil {
+		t.Error("Sync() with file storage of sealed files succeeded, want error")
+	}
+
+	// switching to file storage restores the plaintext files
+	if err := Sync(store, dataDir, runDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := Sync(nil, dataDir, runDir); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Lstat(key); err != nil || !fi.Mode().IsRegular() {
+		t.Fatalf("%s is not a regular file after restoring: %v", key, err)
+	}
+	if b, _ := os.ReadFile(key); string(b) != "rotated" {
+		t.Errorf("restored file = %q", b)
+	}
+	if _, err := os.Stat(key + SealedSuffix); !os.IsNotExist(err) {
+		t.Errorf("sealed file was not removed: %v", err)
+	}
+}
+
+func checkUnsealed(t *testing.T, path, runDir, wantRel, want string) {
+	t.Helper()
+	target, err := os.Readlink(path)
+	if err != nil {
+		t.Fatalf("%s is not a symlink: %v", path, err)
+	}
+	if rel, err := filepath.Rel(runDir, target); err != nil || rel != wantRel {
+		t.Errorf("%s links to %s, want file in %s", path, target, runDir)
+	}
+	if b, _ := os.ReadFile(path); string(b) != want {
+		t.Errorf("%s = %q, want %q", path, b, want)
+	}
+}
+
+func Test_UnitSyncKeysAndExternalFiles(t *testing.T) {
+	dataDir := t.TempDir()
+	runDir := t.TempDir()
+	store := &fakeStore{}
+	etcdKey := filepath.Join(dataDir, "tls", "etcd", "client.key")
+	cert := filepath.Join(dataDir, "tls", "etcd", "client.crt")
+	kubeconfig := filepath.Join(t.TempDir(), "k3s.yaml")
+	for file, mode := range map[string]os.FileMode{etcdKey: 0600, cert: 0644, kubeconfig: 0640} {
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(filepath.Base(file)), mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(file, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// all private keys, and external files, are sealed; certificates are not
+	if err := Sync(store, dataDir, runDir, kubeconfig); err != nil {
+		t.Fatal(err)
+	}
+	checkUnsealed(t, etcdKey, runDir, filepath.Join("tls", "etcd", "client.key"), "client.key")
+	checkUnsealed(t, kubeconfig, runDir, filepath.Join(externalDir, credentialName(kubeconfig)), "k3s.yaml")
+	if fi, err := os.Lstat(cert); err != nil || !fi.Mode().IsRegular() {
+		t.Errorf("%s is not a regular file: %v", cert, err)
+	}
+	if fi, err := os.Stat(kubeconfig); err != nil || fi.Mode().Perm() != 0640 {
+		t.Errorf("unsealed %s mode = %v, want 0640: %v", kubeconfig, fi.Mode().Perm(), err)
+	}
+	if b, _ := os.ReadFile(kubeconfig + SealedSuffix); !bytes.HasPrefix(b, []byte(credentialName(kubeconfig)+":")) {
+		t.Errorf("sealed file = %q", b)
+	}
+
+	// sealed files are found after the run dir is cleared and the symlinks are replaced
+	if err := os.RemoveAll(runDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(etcdKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := Sync(store, dataDir, runDir, kubeconfig); err != nil {
+		t.Fatal(err)
+	}
+	checkUnsealed(t, etcdKey, runDir, filepath.Join("tls", "etcd", "client.key"), "client.key")
+
+	// files that are deleted while in use are not unsealed again
+	if err := os.Remove(etcdKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := Sync(store, dataDir, runDir, kubeconfig); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(etcdKey); !os.IsNotExist(err) {
+		t.Errorf("deleted file was restored: %v", err)
+	}
+	if _, err := os.Stat(etcdKey + SealedSuffix); !os.IsNotExist(err) {
+		t.Errorf("sealed copy of deleted file was not removed: %v", err)
+	}
+}
+
+func Test_UnitWatch(t *testing.T) {
+	dataDir := t.TempDir()
+	runDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dataDir, "tls"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer func(d time.Duration) { syncInterval = d }(syncInterval)
+	syncInterval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Watch(ctx, &fakeStore{}, dataDir, runDir)
+
+	// new files are sealed without waiting for the sync interval
+	key := filepath.Join(dataDir, "tls", "client-admin.key")
+	time.Sleep(100 * time.Millisecond)
+	if err := os.WriteFile(key, []byte("admin-key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if fi, err := os.Lstat(key); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s was not sealed", key)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	checkUnsealed(t, key, runDir, filepath.Join("tls", "client-admin.key"), "admin-key")
+}
+
+func Test_UnitSyncUnsealFailure(t *testing.T) {
+	dataDir := t.TempDir()
+	runDir := t.TempDir()
+	kubeconfig := filepath.Join(t.TempDir(), "k3s.yaml")
+	key := filepath.Join(dataDir, "token")
+	for _, file := range []string{kubeconfig, key} {
+		if err := os.WriteFile(file+SealedSuffix, []byte("sealed-on-another-host"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// files in the data-dir that cannot be unsealed are an error
+	if err := Sync(&fakeStore{}, dataDir, runDir, kubeconfig); err == nil {
+		t.Error("Sync() with a file sealed on another host succeeded, want error")
+	}
+	// external files that cannot be unsealed are discarded
+	if err := os.Remove(key + SealedSuffix); err != nil {
+		t.Fatal(err)
+	}
+	if err := Sync(&fakeStore{}, dataDir, runDir, kubeconfig); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(kubeconfig + SealedSuffix); !os.IsNotExist(err) {
+		t.Errorf("sealed copy of external file was not discarded: %v", err)
+	}
+}
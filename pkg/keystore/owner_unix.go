@@ -0,0 +1,16 @@
+//go:build !windows
+
+package keystore
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwner sets the owner and group of the file to those from fi.
+func copyOwner(fi os.FileInfo, file string) error {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return os.Lchown(file, int(st.Uid), int(st.Gid))
+	}
+	return nil
+}
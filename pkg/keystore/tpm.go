@@ -0,0 +1,47 @@
+package keystore
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// systemdCreds is the command used to seal and unseal data with the TPM.
+var systemdCreds = "systemd-creds"
+
+type tpm struct{}
+
+// NewTPM returns a store that seals data to the host's TPM using systemd-creds, so that it can only be
+// unsealed on this host.
+func NewTPM() Store {
+	return tpm{}
+}
+
+func (tpm) Seal(name string, data []byte) ([]byte, error) {
+	b, err := run(data, "encrypt", "--with-key=tpm2", "--name="+name, "-", "-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal %s with TPM: %w", name, err)
+	}
+	return b, nil
+}
+
+func (tpm) Unseal(name string, data []byte) ([]byte, error) {
+	b, err := run(data, "decrypt", "--name="+name, "-", "-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to unseal %s with TPM: %w", name, err)
+	}
+	return b, nil
+}
+
+func run(stdin []byte, args ...string) ([]byte, error) {
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(systemdCreds, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stderr = stderr
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return b, nil
+}
@@ -0,0 +1,411 @@
+package kubectlplugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	k3s "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	"github.com/k3s-io/k3s/pkg/cli/cert"
+	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/cluster/managed"
+	"github.com/k3s-io/k3s/pkg/etcd"
+	"github.com/k3s-io/k3s/pkg/server/handlers"
+	util2 "github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/utils/ptr"
+
+	// Import to initialize client auth plugins.
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+)
+
+// Name is the name of the plugin executable. kubectl runs it when invoked as
+// `kubectl k3s`, as long as it can be found in the PATH.
+var Name = "kubectl-" + version.Program
+
+var timeout = 2 * time.Minute
+
+// Plugin holds CLI values for the kubectl plugin
+type Plugin struct {
+	Kubeconfig        string
+	Context           string
+	SnapshotName      string
+	SnapshotDir       string
+	SnapshotCompress  bool
+	SnapshotRetention int
+	Output            string
+	NewToken          string
+	CACertPath        string
+	Force             bool
+}
+
+// Main is the entrypoint for the kubectl plugin. The plugin operates the
+// cluster through the supervisor API, using only the credentials from a
+// kubeconfig; the server token and data-dir are not required.
+func Main() {
+	if err := NewApp(&Plugin{}).Run(os.Args); err != nil && !errors.Is(err, context.Canceled) {
+		logrus.Fatal(err)
+	}
+}
+
+// NewApp returns the CLI application for the kubectl plugin
+func NewApp(cfg *Plugin) *cli.App {
+	app := cli.NewApp()
+	app.Name = Name
+	app.Usage = "Manage " + version.Program + " clusters with cluster-admin credentials from a kubeconfig"
+	app.Version = fmt.Sprintf("%s (%s)", version.Version, version.GitCommit)
+	app.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "kubeconfig",
+			Usage:       "Path to the kubeconfig file to use. If not set, the KUBECONFIG environment variable and default kubeconfig path are used",
+			Destination: &cfg.Kubeconfig,
+		},
+		&cli.StringFlag{
+			Name:        "context",
+			Usage:       "The name of the kubeconfig context to use",
+			Destination: &cfg.Context,
+		},
+	}
+	app.Commands = []cli.Command{
+		{
+			Name:  cmds.EtcdSnapshotCommand,
+			Usage: "Manage etcd snapshots",
+			Subcommands: []cli.Command{
+				{
+					Name:   "save",
+					Usage:  "Trigger an immediate etcd snapshot",
+					Action: clientAction(cfg, save),
+					Flags: []cli.Flag{
+						snapshotNameFlag(cfg),
+						snapshotDirFlag(cfg),
+						&cli.BoolFlag{
+							Name:        "compress",
+							Usage:       "Compress etcd snapshot",
+							Destination: &cfg.SnapshotCompress,
+						},
+					},
+				},
+				{
+					Name:   "delete",
+					Usage:  "Delete given snapshot(s)",
+					Action: clientAction(cfg, delete),
+					Flags:  []cli.Flag{snapshotDirFlag(cfg)},
+				},
+				{
+					Name:    "list",
+					Aliases: []string{"ls"},
+					Usage:   "List snapshots",
+					Action:  clientAction(cfg, list),
+					Flags: []cli.Flag{
+						snapshotDirFlag(cfg),
+						&cli.StringFlag{
+							Name:        "output,o",
+							Usage:       "List format. Default: table. Options: table, json, yaml",
+							Destination: &cfg.Output,
+						},
+					},
+				},
+				{
+					Name:   "prune",
+					Usage:  "Remove snapshots that match the name prefix that exceed the configured retention count",
+					Action: clientAction(cfg, prune),
+					Flags: []cli.Flag{
+						snapshotNameFlag(cfg),
+						snapshotDirFlag(cfg),
+						&cli.IntFlag{
+							Name:        "retention",
+							Usage:       "Number of snapshots to retain",
+							Value:       5,
+							Destination: &cfg.SnapshotRetention,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  cmds.TokenCommand,
+			Usage: "Manage the server token",
+			Subcommands: []cli.Command{
+				{
+					Name:   "rotate",
+					Usage:  "Rotate the server token",
+					Action: clientAction(cfg, rotateToken),
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:        "new-token",
+							Usage:       "New token that replaces the existing server token. If not set, a random token is generated and printed",
+							Destination: &cfg.NewToken,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  cmds.CertCommand,
+			Usage: "Manage cluster certificate authorities",
+			Subcommands: []cli.Command{
+				{
+					Name:   "rotate-ca",
+					Usage:  "Write updated cluster CA certificates to the datastore",
+					Action: clientAction(cfg, rotateCA),
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:        "path",
+							Usage:       "Path to directory containing updated CA certificates and keys",
+							Destination: &cfg.CACertPath,
+						},
+						&cli.BoolFlag{
+							Name:        "force",
+							Usage:       "Force certificate replacement, even if consistency checks fail",
+							Destination: &cfg.Force,
+						},
+					},
+				},
+			},
+		},
+	}
+	return app
+}
+
+func snapshotNameFlag(cfg *Plugin) cli.Flag {
+	return &cli.StringFlag{
+		Name:        "name",
+		Usage:       "Set the base name of etcd snapshots",
+		Value:       "on-demand",
+		Destination: &cfg.SnapshotName,
+	}
+}
+
+func snapshotDirFlag(cfg *Plugin) cli.Flag {
+	return &cli.StringFlag{
+		Name:        "dir",
+		Usage:       "Directory to save etcd on-demand snapshot to, on the server. Default: the server's snapshot directory",
+		Destination: &cfg.SnapshotDir,
+	}
+}
+
+// clientAction returns a command action that calls the given function with a
+// client configured from the kubeconfig.
+func clientAction(cfg *Plugin, f func(*cli.Context, *Plugin, rest.Interface) error) func(*cli.Context) error {
+	return func(app *cli.Context) error {
+		client, err := newClient(cfg)
+		if err != nil {
+			return err
+		}
+		return f(app, cfg, client)
+	}
+}
+
+// snapshotRequest returns an etcd snapshot request with the options set on the command line;
+// options that are not set take the server defaults.
+func snapshotRequest(app *cli.Context, cfg *Plugin) *etcd.SnapshotRequest {
+	sr := &etcd.SnapshotRequest{}
+	if app.IsSet("dir") {
+		sr.Dir = &cfg.SnapshotDir
+	}
+	if app.IsSet("compress") {
+		sr.Compress = &cfg.SnapshotCompress
+	}
+	if app.IsSet("retention") {
+		sr.Retention = &cfg.SnapshotRetention
+	}
+	return sr
+}
+
+// postSnapshot sends an etcd snapshot request to the supervisor, returning the response body.
+func postSnapshot(client rest.Interface, sr *etcd.SnapshotRequest) ([]byte, error) {
+	b, err := json.Marshal(sr)
+	if err != nil {
+		return nil, err
+	}
+	r, err := client.Post().AbsPath("/db/snapshot").Body(b).Timeout(timeout).DoRaw(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "see server log for details")
+	}
+	return r, nil
+}
+
+// newClient returns a REST client for the supervisor API, using the server URL
+// and credentials from the kubeconfig. The supervisor shares a port with the
+// apiserver, and accepts the same client certificates.
+func newClient(cfg *Plugin) (rest.Interface, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = cfg.Kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: cfg.Context}
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	restConfig.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	return rest.UnversionedRESTClientFor(restConfig)
+}
+
+func save(app *cli.Context, cfg *Plugin, client rest.Interface) error {
+	if len(app.Args()) > 0 {
+		return util2.ErrCommandNoArgs
+	}
+	sr := snapshotRequest(app, cfg)
+	sr.Operation = etcd.SnapshotOperationSave
+	sr.Name = []string{cfg.SnapshotName}
+	// Save always sets retention to 0 to disable automatic pruning.
+	sr.Retention = ptr.To(0)
+
+	r, err := postSnapshot(client, sr)
+	if err != nil {
+		return err
+	}
+	resp := &managed.SnapshotResult{}
+	if err := json.Unmarshal(r, resp); err != nil {
+		return err
+	}
+	for _, name := range resp.Created {
+		fmt.Printf("Snapshot %s saved.\n", name)
+	}
+	return nil
+}
+
+func delete(app *cli.Context, cfg *Plugin, client rest.Interface) error {
+	snapshots := app.Args()
+	if len(snapshots) == 0 {
+		return errors.New("no snapshots given for removal")
+	}
+	sr := snapshotRequest(app, cfg)
+	sr.Operation = etcd.SnapshotOperationDelete
+	sr.Name = snapshots
+
+	r, err := postSnapshot(client, sr)
+	if err != nil {
+		return err
+	}
+	resp := &managed.SnapshotResult{}
+	if err := json.Unmarshal(r, resp); err != nil {
+		return err
+	}
+	for _, name := range resp.Deleted {
+		fmt.Printf("Snapshot %s deleted.\n", name)
+	}
+	for _, name := range snapshots {
+		if !slices.Contains(resp.Deleted, name) {
+			logrus.Warnf("Snapshot %s not found.", name)
+		}
+	}
+	return nil
+}
+
+func list(app *cli.Context, cfg *Plugin, client rest.Interface) error {
+	if cfg.Output != "" && cfg.Output != "json" && cfg.Output != "yaml" && cfg.Output != "table" {
+		return errors.New("invalid output format: " + cfg.Output)
+	}
+	sr := snapshotRequest(app, cfg)
+	sr.Operation = etcd.SnapshotOperationList
+
+	r, err := postSnapshot(client, sr)
+	if err != nil {
+		return err
+	}
+	sf := &k3s.ETCDSnapshotFileList{}
+	if err := json.Unmarshal(r, sf); err != nil {
+		return err
+	}
+	return printSnapshots(os.Stdout, sf, cfg.Output)
+}
+
+func prune(app *cli.Context, cfg *Plugin, client rest.Interface) error {
+	sr := snapshotRequest(app, cfg)
+	sr.Operation = etcd.SnapshotOperationPrune
+	sr.Name = []string{cfg.SnapshotName}
+
+	r, err := postSnapshot(client, sr)
+	if err != nil {
+		return err
+	}
+	resp := &managed.SnapshotResult{}
+	if err := json.Unmarshal(r, resp); err != nil {
+		return err
+	}
+	for _, name := range resp.Deleted {
+		fmt.Printf("Snapshot %s deleted.\n", name)
+	}
+	return nil
+}
+
+// printSnapshots writes the snapshot list in the requested format, sorted by creation time.
+func printSnapshots(w io.Writer, sf *k3s.ETCDSnapshotFileList, format string) error {
+	sort.Slice(sf.Items, func(i, j int) bool {
+		if sf.Items[i].Status.CreationTime.Equal(sf.Items[j].Status.CreationTime) {
+			return sf.Items[i].Spec.SnapshotName < sf.Items[j].Spec.SnapshotName
+		}
+		return sf.Items[i].Status.CreationTime.Before(sf.Items[j].Status.CreationTime)
+	})
+
+	switch format {
+	case "json":
+		return (&printers.JSONPrinter{}).PrintObj(sf, w)
+	case "yaml":
+		return (&printers.YAMLPrinter{}).PrintObj(sf, w)
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+		defer tw.Flush()
+
+		fmt.Fprint(tw, "Name\tLocation\tSize\tCreated\n")
+		for _, esf := range sf.Items {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", esf.Spec.SnapshotName, esf.Spec.Location, esf.Status.Size.Value(), esf.Status.CreationTime.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+func rotateToken(app *cli.Context, cfg *Plugin, client rest.Interface) error {
+	// The server does not return the token it generates, so a random token is generated here instead,
+	// in the same format as the server's.
+	newToken := cfg.NewToken
+	if newToken == "" {
+		var err error
+		if newToken, err = util2.Random(16); err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(handlers.TokenRotateRequest{
+		NewToken: ptr.To(newToken),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := client.Put().AbsPath("/v1-" + version.Program + "/token").Body(b).DoRaw(context.Background()); err != nil {
+		return err
+	}
+	if cfg.NewToken == "" {
+		fmt.Println("New token:", newToken)
+	}
+	fmt.Println("Token rotated, restart", version.Program, "nodes with new token")
+	return nil
+}
+
+func rotateCA(app *cli.Context, cfg *Plugin, client rest.Interface) error {
+	if cfg.CACertPath == "" {
+		return errors.New("path to updated CA certificates and keys is required")
+	}
+	b, err := cert.ReadCACertData(cfg.CACertPath)
+	if err != nil {
+		return err
+	}
+	req := client.Put().AbsPath("/v1-"+version.Program+"/cert/cacerts").Param("force", fmt.Sprint(cfg.Force)).Body(b)
+	if _, err := req.DoRaw(context.Background()); err != nil {
+		return errors.Wrap(err, "see server log for details")
+	}
+	fmt.Println("certificates saved to datastore")
+	return nil
+}
@@ -0,0 +1,88 @@
+package kubectlplugin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/k3s-io/k3s/pkg/cluster/managed"
+	"github.com/k3s-io/k3s/pkg/etcd"
+	"github.com/k3s-io/k3s/pkg/server/handlers"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func Test_UnitPlugin(t *testing.T) {
+	type request struct {
+		method        string
+		path          string
+		authorization string
+		body          []byte
+	}
+	var got request
+	server := httptest.NewTLSServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		got = request{method: req.Method, path: req.URL.Path, authorization: req.Header.Get("Authorization"), body: body}
+		resp.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(resp).Encode(&managed.SnapshotResult{Created: []string{"on-demand-test"}})
+	}))
+	defer server.Close()
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	config := clientcmdapi.NewConfig()
+	config.Clusters["default"] = &clientcmdapi.Cluster{Server: server.URL, InsecureSkipTLSVerify: true}
+	config.AuthInfos["default"] = &clientcmdapi.AuthInfo{Token: "admin-token"}
+	config.Contexts["default"] = &clientcmdapi.Context{Cluster: "default", AuthInfo: "default"}
+	config.CurrentContext = "default"
+	if err := clientcmd.WriteToFile(*config, kubeconfig); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("etcd-snapshot save", func(t *testing.T) {
+		if err := NewApp(&Plugin{}).Run([]string{Name, "--kubeconfig", kubeconfig, "etcd-snapshot", "save", "--name", "test"}); err != nil {
+			t.Fatal(err)
+		}
+		if got.method != http.MethodPost || got.path != "/db/snapshot" || got.authorization != "Bearer admin-token" {
+			t.Errorf("unexpected request %s %s with authorization %q", got.method, got.path, got.authorization)
+		}
+		sr := &etcd.SnapshotRequest{}
+		if err := json.Unmarshal(got.body, sr); err != nil {
+			t.Fatal(err)
+		}
+		if sr.Operation != etcd.SnapshotOperationSave || len(sr.Name) != 1 || sr.Name[0] != "test" || sr.Retention == nil || *sr.Retention != 0 || sr.Dir != nil {
+			t.Errorf("unexpected snapshot request %s", got.body)
+		}
+	})
+
+	t.Run("token rotate", func(t *testing.T) {
+		if err := NewApp(&Plugin{}).Run([]string{Name, "--kubeconfig", kubeconfig, "token", "rotate", "--new-token", "new"}); err != nil {
+			t.Fatal(err)
+		}
+		if got.method != http.MethodPut || got.path != "/v1-k3s/token" {
+			t.Errorf("unexpected request %s %s", got.method, got.path)
+		}
+		tr := &handlers.TokenRotateRequest{}
+		if err := json.Unmarshal(got.body, tr); err != nil {
+			t.Fatal(err)
+		}
+		if tr.NewToken == nil || *tr.NewToken != "new" {
+			t.Errorf("unexpected token rotate request %s", got.body)
+		}
+	})
+
+	t.Run("token rotate generated", func(t *testing.T) {
+		if err := NewApp(&Plugin{}).Run([]string{Name, "--kubeconfig", kubeconfig, "token", "rotate"}); err != nil {
+			t.Fatal(err)
+		}
+		tr := &handlers.TokenRotateRequest{}
+		if err := json.Unmarshal(got.body, tr); err != nil {
+			t.Fatal(err)
+		}
+		if tr.NewToken == nil || *tr.NewToken == "" {
+			t.Errorf("token rotate request %s does not set the generated token", got.body)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+// Package maintenance implements node maintenance mode. Nodes in maintenance mode are cordoned,
+// and servers in maintenance mode pause the deploy controller, so that nodes can be patched and
+// rebooted without workloads being scheduled or manifests being applied.
+package maintenance
+
+import (
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	v1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+var (
+	// Annotation is set on nodes that are in maintenance mode, with the time that maintenance mode was enabled.
+	Annotation = version.Program + ".io/maintenance"
+	// CordonedAnnotation is set on nodes that were cordoned when maintenance mode was enabled, so
+	// that nodes that were already cordoned are left cordoned when maintenance mode is disabled.
+	CordonedAnnotation = version.Program + ".io/maintenance-cordoned"
+)
+
+// Request is the body of a request to enable or disable maintenance mode on a node.
+type Request struct {
+	Node   string `json:"node"`
+	Enable bool   `json:"enable"`
+}
+
+// Status is the maintenance mode status of a node.
+type Status struct {
+	Node          string `json:"node"`
+	Enabled       bool   `json:"enabled"`
+	Since         string `json:"since,omitempty"`
+	Unschedulable bool   `json:"unschedulable"`
+	// Etcd is true if the node is an etcd member, in which case a snapshot should be taken
+	// before the node is taken down for maintenance.
+	Etcd bool `json:"etcd"`
+}
+
+// Enabled returns true if the node is in maintenance mode.
+func Enabled(node *corev1.Node) bool {
+	_, ok := node.Annotations[Annotation]
+	return ok
+}
+
+// Set enables or disables maintenance mode on the named node, and returns its updated status.
+func Set(nodes v1.NodeClient, name string, enable bool) (*Status, error) {
+	var status *Status
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := nodes.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if update(node, enable, time.Now()) {
+			if node, err = nodes.Update(node); err != nil {
+				return err
+			}
+		}
+		status = getStatus(node)
+		return nil
+	})
+	return status, err
+}
+
+// update enables or disables maintenance mode on the node, and returns true if the node was modified.
+// Enabling maintenance mode cordons the node; disabling it uncordons the node only if it was
+// cordoned when maintenance mode was enabled.
+func update(node *corev1.Node, enable bool, now time.Time) bool {
+	if enable == Enabled(node) {
+		return false
+	}
+	if enable {
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[Annotation] = now.UTC().Format(time.RFC3339)
+		if !node.Spec.Unschedulable {
+			node.Annotations[CordonedAnnotation] = "true"
+			node.Spec.Unschedulable = true
+		}
+		return true
+	}
+	if _, ok := node.Annotations[CordonedAnnotation]; ok {
+		node.Spec.Unschedulable = false
+	}
+	delete(node.Annotations, Annotation)
+	delete(node.Annotations, CordonedAnnotation)
+	return true
+}
+
+func getStatus(node *corev1.Node) *Status {
+	return &Status{
+		Node:          node.Name,
+		Enabled:       Enabled(node),
+		Since:         node.Annotations[Annotation],
+		Unschedulable: node.Spec.Unschedulable,
+		Etcd:          node.Labels[util.ETCDRoleLabelKey] == "true",
+	}
+}
@@ -0,0 +1,52 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_UnitUpdate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name              string
+		unschedulable     bool
+		wantUnschedulable bool
+	}{
+		{name: "schedulable node is cordoned and uncordoned", unschedulable: false, wantUnschedulable: false},
+		{name: "cordoned node is left cordoned", unschedulable: true, wantUnschedulable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+				Spec:       corev1.NodeSpec{Unschedulable: tt.unschedulable},
+			}
+
+			if !update(node, true, now) {
+				t.Fatal("update() did not modify node when enabling maintenance mode")
+			}
+			if !Enabled(node) || node.Annotations[Annotation] != "2024-01-01T12:00:00Z" || !node.Spec.Unschedulable {
+				t.Errorf("update() = %+v, want node cordoned and annotated", node)
+			}
+			if update(node, true, now.Add(time.Hour)) {
+				t.Error("update() modified node that is already in maintenance mode")
+			}
+
+			if !update(node, false, now) {
+				t.Fatal("update() did not modify node when disabling maintenance mode")
+			}
+			if Enabled(node) || len(node.Annotations) != 0 {
+				t.Errorf("update() annotations = %v, want none", node.Annotations)
+			}
+			if node.Spec.Unschedulable != tt.wantUnschedulable {
+				t.Errorf("update() unschedulable = %v, want %v", node.Spec.Unschedulable, tt.wantUnschedulable)
+			}
+			if update(node, false, now) {
+				t.Error("update() modified node that is not in maintenance mode")
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+// componentLabel identifies the control-plane component that a federated metric was collected from. It is
+// prefixed with the program name, as some components already label their own metrics with component.
+var componentLabel = version.Program + "_component"
+
+// ControlPlaneGatherers returns gatherers for metrics from all control-plane components on a server.
+// Components that run within the supervisor process share a single registry, and are reported as the
+// supervisor component. Datastore metrics from embedded etcd or kine are collected from the default
+// prometheus registry. Components that run as separate processes are scraped over their secure ports,
+// using the supervisor's client certificate.
+func ControlPlaneGatherers(control *config.Control) map[string]prometheus.Gatherer {
+	gatherers := map[string]prometheus.Gatherer{
+		"supervisor": prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return DefaultGatherer.Gather()
+		}),
+		"datastore": &filteredGatherer{
+			Gatherer: prometheus.DefaultGatherer,
+			prefixes: []string{"go_", "process_", "promhttp_"},
+		},
+	}
+
+	if control.ControlPlaneExecMode == config.ControlPlaneExecModeProcesses {
+		if !control.DisableAPIServer {
+			gatherers["kube-apiserver"] = &scrapeGatherer{
+				control: control,
+				url:     fmt.Sprintf("https://%s:%d/metrics", control.Loopback(true), control.APIServerPort),
+			}
+		}
+		if !control.DisableScheduler {
+			gatherers["kube-scheduler"] = &scrapeGatherer{
+				control: control,
+				url:     fmt.Sprintf("https://%s:10259/metrics", control.Loopback(true)),
+			}
+		}
+		if !control.DisableControllerManager {
+			gatherers["kube-controller-manager"] = &scrapeGatherer{
+				control: control,
+				url:     fmt.Sprintf("https://%s:10257/metrics", control.Loopback(true)),
+			}
+		}
+	}
+
+	return gatherers
+}
+
+// federate returns a Gatherer that collects metrics from all the provided gatherers,
+// with a component label added to identify the source of each metric.
+func federate(gatherers map[string]prometheus.Gatherer) prometheus.Gatherer {
+	federated := prometheus.Gatherers{}
+	for component, gatherer := range gatherers {
+		federated = append(federated, &componentGatherer{component: component, Gatherer: gatherer})
+	}
+	return federated
+}
+
+// componentGatherer adds a component label to all metrics returned by the wrapped Gatherer. Metrics that
+// already have the label are left as-is, as duplicate label names are rejected by Prometheus.
+type componentGatherer struct {
+	prometheus.Gatherer
+	component string
+}
+
+func (g *componentGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			if hasLabel(metric, componentLabel) {
+				continue
+			}
+			metric.Label = append(metric.Label, &dto.LabelPair{
+				Name:  proto.String(componentLabel),
+				Value: proto.String(g.component),
+			})
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to gather %s metrics: %w", g.component, err)
+	}
+	return families, err
+}
+
+func hasLabel(metric *dto.Metric, name string) bool {
+	for _, label := range metric.Label {
+		if label.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// filteredGatherer drops metric families whose names start with any of the listed prefixes.
+type filteredGatherer struct {
+	prometheus.Gatherer
+	prefixes []string
+}
+
+func (g *filteredGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	filtered := make([]*dto.MetricFamily, 0, len(families))
+families:
+	for _, family := range families {
+		for _, prefix := range g.prefixes {
+			if strings.HasPrefix(family.GetName(), prefix) {
+				continue families
+			}
+		}
+		filtered = append(filtered, family)
+	}
+	return filtered, err
+}
+
+// scrapeGatherer collects metrics from a component's secure metrics endpoint.
+type scrapeGatherer struct {
+	control *config.Control
+	url     string
+}
+
+func (g *scrapeGatherer) Gather() ([]*dto.MetricFamily, error) {
+	cert, err := tls.LoadX509KeyPair(g.control.Runtime.ClientSupervisorCert, g.control.Runtime.ClientSupervisorKey)
+	if err != nil {
+		return nil, err
+	}
+	// Components run as processes generate a self-signed serving certificate, which cannot be verified.
+	// The endpoint is only ever scraped over the loopback address.
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				Certificates:       []tls.Certificate{cert},
+				InsecureSkipVerify: true,
+			},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get(g.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", g.url, resp.Status)
+	}
+
+	parser := expfmt.TextParser{}
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*dto.MetricFamily, 0, len(families))
+	for _, family := range families {
+		result = append(result, family)
+	}
+	return result, nil
+}
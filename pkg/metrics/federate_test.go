@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func Test_UnitFederate(t *testing.T) {
+	supervisor := prometheus.NewRegistry()
+	supervisor.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "supervisor_requests_total", Help: "test"}))
+	// metrics that already have a component label keep it
+	apiserverRequests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "apiserver_request_total", Help: "test"}, []string{"component"})
+	apiserverRequests.WithLabelValues("apiserver").Inc()
+	supervisor.MustRegister(apiserverRequests)
+	datastore := prometheus.NewRegistry()
+	datastore.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "etcd_requests_total", Help: "test"}))
+	datastore.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "go_goroutines", Help: "test"}))
+
+	gatherer := federate(map[string]prometheus.Gatherer{
+		"supervisor": supervisor,
+		"datastore":  &filteredGatherer{Gatherer: datastore, prefixes: []string{"go_"}},
+	})
+	families, err := gatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	want := map[string]string{
+		"supervisor_requests_total": "supervisor",
+		"apiserver_request_total":   "supervisor",
+		"etcd_requests_total":       "datastore",
+	}
+	if len(families) != len(want) {
+		t.Fatalf("Gather() returned %d families, want %d", len(families), len(want))
+	}
+	for _, family := range families {
+		component, ok := want[family.GetName()]
+		if !ok {
+			t.Errorf("Gather() returned unexpected family %s", family.GetName())
+			continue
+		}
+		for _, metric := range family.Metric {
+			if got := labelValue(metric, componentLabel); got != component {
+				t.Errorf("%s component label = %q, want %q", family.GetName(), got, component)
+			}
+			if family.GetName() == "apiserver_request_total" && labelValue(metric, "component") != "apiserver" {
+				t.Errorf("%s labels = %v, want existing component label to be kept", family.GetName(), metric.Label)
+			}
+		}
+	}
+}
+
+func labelValue(metric *dto.Metric, name string) string {
+	for _, label := range metric.Label {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
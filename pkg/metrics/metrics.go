@@ -8,6 +8,8 @@ import (
 	"github.com/k3s-io/k3s/pkg/agent/https"
 	"github.com/k3s-io/k3s/pkg/agent/loadbalancer"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/startup"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	lassometrics "github.com/rancher/lasso/pkg/metrics"
 	"k8s.io/component-base/metrics/legacyregistry"
@@ -35,12 +37,17 @@ func init() {
 	lassometrics.MustRegister(DefaultRegisterer)
 	// same for loadbalancer metrics
 	loadbalancer.MustRegister(DefaultRegisterer)
+	// and startup phase durations
+	startup.MustRegister(DefaultRegisterer)
 }
 
 // Config holds fields for the metrics listener
 type Config struct {
 	// Router will be called to add the metrics API handler to an existing router.
 	Router https.RouterFunc
+	// Gatherers, if set, are federated into the metrics API response, with a component label
+	// identifying the source of each metric. If not set, the DefaultGatherer is used.
+	Gatherers map[string]prometheus.Gatherer
 }
 
 // Start starts binds the metrics API to an existing HTTP router.
@@ -49,6 +56,10 @@ func (c *Config) Start(ctx context.Context, nodeConfig *config.Node) error {
 	if err != nil {
 		return err
 	}
-	mRouter.Handle("/metrics", promhttp.HandlerFor(DefaultGatherer, promhttp.HandlerOpts{}))
+	if len(c.Gatherers) > 0 {
+		mRouter.Handle("/metrics", promhttp.HandlerFor(federate(c.Gatherers), promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}))
+	} else {
+		mRouter.Handle("/metrics", promhttp.HandlerFor(DefaultGatherer, promhttp.HandlerOpts{}))
+	}
 	return nil
 }
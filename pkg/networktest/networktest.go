@@ -0,0 +1,331 @@
+// Package networktest builds the pods used to test connectivity between nodes, and interprets their
+// results. Server pods on the pod network and the host network serve small and large files over HTTP
+// on every node; client pods on each node then fetch the files from every server, look up the cluster
+// DNS name of the apiserver, and print the result of each check to their log.
+package networktest
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+// Checks run by the client pods.
+const (
+	// CheckPod fetches a small file from the server pod on each node, over the pod network.
+	CheckPod = "pod"
+	// CheckMTU fetches a file larger than the network MTU from the server pod on each node.
+	CheckMTU = "mtu"
+	// CheckNode fetches a small file from the host network server on each node, from the host network.
+	CheckNode = "node"
+	// CheckService fetches a small file through the ClusterIP service for the server pods.
+	CheckService = "service"
+	// CheckDNS looks up the cluster DNS name of the apiserver service.
+	CheckDNS = "dns"
+)
+
+const (
+	// ServerPort is the port that server pods on the pod network listen on.
+	ServerPort = 8080
+	// DefaultHostPort is the default port that host network server pods listen on.
+	DefaultHostPort = 18080
+	// largeFileSize is the size of the file fetched by CheckMTU; large enough that it must be sent in
+	// many full-sized packets.
+	largeFileSize = 64 * 1024
+
+	roleLabel    = "role"
+	roleServer   = "server"
+	roleHost     = "host-server"
+	roleClient   = "client"
+	resultPrefix = "RESULT"
+	checkTimeout = 5
+)
+
+// Namespace is the namespace that test pods are created in. It is deleted when the test completes.
+var Namespace = version.Program + "-network-test"
+
+// Node is the network configuration of a node under test.
+type Node struct {
+	Name       string
+	InternalIP string
+	// FlannelBackend and FlannelPublicIP are the backend type and public IP annotated on the node by
+	// flannel; they are empty if flannel is not used.
+	FlannelBackend  string
+	FlannelPublicIP string
+	// FlannelPorts lists the UDP ports that the flannel backend uses, as annotated on the node by the
+	// agent; it is empty if the backend does not use a port of its own, or the agent does not set it.
+	FlannelPorts string
+}
+
+// NodeFromObject returns the network configuration of a node.
+func NodeFromObject(node *corev1.Node) Node {
+	n := Node{
+		Name:            node.Name,
+		FlannelBackend:  node.Annotations["flannel.alpha.coreos.com/backend-type"],
+		FlannelPublicIP: node.Annotations["flannel.alpha.coreos.com/public-ip"],
+		FlannelPorts:    node.Annotations[version.Program+".io/flannel-ports"],
+	}
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			n.InternalIP = address.Address
+			break
+		}
+	}
+	return n
+}
+
+// Target is a server that client pods check connectivity to.
+type Target struct {
+	// Node is the name of the node that the server runs on.
+	Node string
+	// PodIP is the address of the server pod on the pod network.
+	PodIP string
+	// HostIP is the address of the host network server.
+	HostIP string
+}
+
+// Result is the result of a check from a client pod.
+type Result struct {
+	// Source is the name of the node that the check was run from.
+	Source string
+	Check  string
+	// Target is the name of the node that was checked, or the service or DNS name.
+	Target string
+	OK     bool
+}
+
+// ServerDaemonSet returns a DaemonSet that serves the test files on every node. If hostNetwork is
+// true, the server listens on the port on the host network; otherwise it listens on ServerPort on
+// the pod network.
+func ServerDaemonSet(image string, hostNetwork bool, hostPort int) *appsv1.DaemonSet {
+	role, port := roleServer, ServerPort
+	if hostNetwork {
+		role, port = roleHost, hostPort
+	}
+	labels := map[string]string{roleLabel: role}
+	script := fmt.Sprintf("mkdir -p /www && echo ok > /www/ok && head -c %d /dev/zero > /www/large && exec httpd -f -p %d -h /www", largeFileSize, port)
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: role, Namespace: Namespace, Labels: labels},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					HostNetwork:                   hostNetwork,
+					Tolerations:                   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+					TerminationGracePeriodSeconds: ptr.To[int64](0),
+					Containers: []corev1.Container{{
+						Name:    role,
+						Image:   image,
+						Command: []string{"sh", "-c", script},
+						Ports:   []corev1.ContainerPort{{ContainerPort: int32(port)}},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(port)},
+							},
+							PeriodSeconds: 2,
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// ServerService returns the ClusterIP service for the server pods on the pod network.
+func ServerService() *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: roleServer, Namespace: Namespace},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{roleLabel: roleServer},
+			Ports:    []corev1.ServicePort{{Port: ServerPort, TargetPort: intstr.FromInt(ServerPort)}},
+		},
+	}
+}
+
+// ClientPods returns the client pods for a node: one on the pod network that checks the server pods,
+// the service and DNS, and one on the host network that checks the host network servers.
+func ClientPods(image, node, serviceIP string, hostPort int, targets []Target) []*corev1.Pod {
+	var podChecks, hostChecks []string
+	for _, target := range targets {
+		if target.PodIP != "" {
+			podChecks = append(podChecks,
+				checkLine(CheckPod, target.Node, fetch(target.PodIP, ServerPort, "ok")),
+				checkLine(CheckMTU, target.Node, fetch(target.PodIP, ServerPort, "large")),
+			)
+		}
+		if target.HostIP != "" {
+			hostChecks = append(hostChecks, checkLine(CheckNode, target.Node, fetch(target.HostIP, hostPort, "ok")))
+		}
+	}
+	podChecks = append(podChecks,
+		checkLine(CheckService, roleServer, fetch(serviceIP, ServerPort, "ok")),
+		checkLine(CheckDNS, "kubernetes.default", "nslookup kubernetes.default"),
+	)
+	return []*corev1.Pod{
+		clientPod(image, roleClient+"-"+node, node, false, podChecks),
+		clientPod(image, "host-"+roleClient+"-"+node, node, true, hostChecks),
+	}
+}
+
+func clientPod(image, name, node string, hostNetwork bool, checks []string) *corev1.Pod {
+	script := `check() { if sh -c "$3" >/dev/null 2>&1; then echo "` + resultPrefix + ` $1 $2 ok"; else echo "` + resultPrefix + ` $1 $2 fail"; fi; }` + "\n" + strings.Join(checks, "\n")
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: Namespace, Labels: map[string]string{roleLabel: roleClient}},
+		Spec: corev1.PodSpec{
+			NodeName:                      node,
+			HostNetwork:                   hostNetwork,
+			DNSPolicy:                     corev1.DNSClusterFirst,
+			RestartPolicy:                 corev1.RestartPolicyNever,
+			Tolerations:                   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+			TerminationGracePeriodSeconds: ptr.To[int64](0),
+			Containers: []corev1.Container{{
+				Name:    roleClient,
+				Image:   image,
+				Command: []string{"sh", "-c", script},
+			}},
+		},
+	}
+}
+
+func checkLine(check, target, command string) string {
+	return fmt.Sprintf("check %s %s '%s'", check, target, command)
+}
+
+func fetch(ip string, port int, file string) string {
+	if strings.Contains(ip, ":") {
+		ip = "[" + ip + "]"
+	}
+	return fmt.Sprintf("wget -q -T %d -O /dev/null http://%s:%d/%s", checkTimeout, ip, port, file)
+}
+
+// ParseResults parses the results printed by a client pod on the source node.
+func ParseResults(source string, r io.Reader) ([]Result, error) {
+	var results []Result
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 || fields[0] != resultPrefix {
+			continue
+		}
+		results = append(results, Result{Source: source, Check: fields[1], Target: fields[2], OK: fields[3] == "ok"})
+	}
+	return results, scanner.Err()
+}
+
+// Diagnose returns the likely causes of failed checks.
+func Diagnose(nodes []Node, results []Result) []string {
+	nodeMap := map[string]Node{}
+	for _, node := range nodes {
+		nodeMap[node.Name] = node
+	}
+	failed := map[string]bool{}
+	for _, result := range results {
+		if !result.OK {
+			failed[result.Check+"/"+result.Source+"/"+result.Target] = true
+		}
+	}
+
+	var causes []string
+	for _, node := range nodes {
+		if node.FlannelPublicIP != "" && node.InternalIP != "" && node.FlannelPublicIP != node.InternalIP {
+			causes = append(causes, fmt.Sprintf("Node %s: flannel is using address %s, which is not the node InternalIP %s; the wrong interface may be selected, set --flannel-iface or --node-ip", node.Name, node.FlannelPublicIP, node.InternalIP))
+		}
+	}
+	for _, node := range nodes[min(1, len(nodes)):] {
+		if node.FlannelPorts != "" && nodes[0].FlannelPorts != "" && node.FlannelPorts != nodes[0].FlannelPorts {
+			causes = append(causes, fmt.Sprintf("Node %s: flannel is using UDP ports %s, but node %s is using %s; set the same --flannel-vxlan-port or --flannel-wireguard-port on all nodes", node.Name, node.FlannelPorts, nodes[0].Name, nodes[0].FlannelPorts))
+		}
+	}
+	for _, result := range results {
+		if result.OK {
+			continue
+		}
+		source, target := result.Source, result.Target
+		switch result.Check {
+		case CheckNode:
+			causes = append(causes, fmt.Sprintf("Node %s cannot reach node %s at %s; check that the node IP is on the right interface and that the host firewall allows traffic between nodes", source, target, nodeMap[target].InternalIP))
+		case CheckPod:
+			if source == target {
+				causes = append(causes, fmt.Sprintf("Pods on node %s cannot reach each other; check the CNI and that the host firewall allows traffic on the pod CIDR", source))
+			} else if !failed[CheckNode+"/"+source+"/"+target] {
+				causes = append(causes, fmt.Sprintf("Pods on node %s cannot reach pods on node %s, but the nodes can reach each other; %s may be blocked between the nodes", source, target, overlayTraffic(nodeMap[target])))
+			}
+		case CheckMTU:
+			if !failed[CheckPod+"/"+source+"/"+target] {
+				causes = append(causes, fmt.Sprintf("Large packets from pods on node %s to pods on node %s are dropped; check the MTU of the network path and of the flannel interface", source, target))
+			}
+		case CheckService:
+			causes = append(causes, fmt.Sprintf("Pods on node %s cannot reach the test service; check kube-proxy or the service proxy on the node", source))
+		case CheckDNS:
+			causes = append(causes, fmt.Sprintf("DNS lookups from pods on node %s failed; check that the coredns pods are running and reachable through the cluster DNS service", source))
+		}
+	}
+	return causes
+}
+
+// overlayTraffic describes the traffic used by a node's flannel backend between nodes.
+func overlayTraffic(node Node) string {
+	switch node.FlannelBackend {
+	case "vxlan":
+		return fmt.Sprintf("VXLAN traffic on UDP port %s", cmp.Or(node.FlannelPorts, "8472"))
+	case "wireguard":
+		ports := strings.Split(cmp.Or(node.FlannelPorts, "51820,51821"), ",")
+		if len(ports) != 2 {
+			return "WireGuard traffic on UDP ports " + node.FlannelPorts
+		}
+		return fmt.Sprintf("WireGuard traffic on UDP ports %s (IPv4) and %s (IPv6)", ports[0], ports[1])
+	case "host-gw":
+		return "routed pod traffic, which requires nodes on the same layer 2 network,"
+	default:
+		return "overlay traffic"
+	}
+}
+
+// WriteMatrix writes a matrix of the results, with a row for each source node and a column for each
+// target node, followed by the service and DNS checks from each node. Cells list the failed checks.
+func WriteMatrix(w io.Writer, nodes []string, results []Result) error {
+	cells := map[string][]string{}
+	seen := map[string]bool{}
+	for _, result := range results {
+		key := result.Source + "/" + result.Target
+		if result.Check == CheckService || result.Check == CheckDNS {
+			key = result.Source + "/" + result.Check
+		}
+		seen[key] = true
+		if !result.OK {
+			cells[key] = append(cells[key], result.Check)
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "FROM \\ TO\t%s\t%s\t%s\n", strings.Join(nodes, "\t"), strings.ToUpper(CheckService), strings.ToUpper(CheckDNS))
+	for _, source := range nodes {
+		row := []string{source}
+		for _, column := range append(append([]string{}, nodes...), CheckService, CheckDNS) {
+			key := source + "/" + column
+			switch {
+			case len(cells[key]) > 0:
+				sort.Strings(cells[key])
+				row = append(row, "FAIL("+strings.Join(cells[key], ",")+")")
+			case seen[key]:
+				row = append(row, "ok")
+			default:
+				row = append(row, "-")
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
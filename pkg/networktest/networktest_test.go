@@ -0,0 +1,112 @@
+package networktest
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_UnitParseResults(t *testing.T) {
+	log := "RESULT pod node-2 ok\nConnecting to 10.42.1.5:8080\nRESULT mtu node-2 fail\nRESULT dns kubernetes.default ok\n"
+	results, err := ParseResults("node-1", strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Result{
+		{Source: "node-1", Check: CheckPod, Target: "node-2", OK: true},
+		{Source: "node-1", Check: CheckMTU, Target: "node-2", OK: false},
+		{Source: "node-1", Check: CheckDNS, Target: "kubernetes.default", OK: true},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("ParseResults() = %+v, want %+v", results, want)
+	}
+}
+
+func Test_UnitDiagnose(t *testing.T) {
+	nodes := []Node{
+		{Name: "node-1", InternalIP: "10.0.0.1", FlannelBackend: "vxlan", FlannelPublicIP: "10.0.0.1"},
+		{Name: "node-2", InternalIP: "10.0.0.2", FlannelBackend: "vxlan", FlannelPublicIP: "172.17.0.1"},
+	}
+	tests := []struct {
+		name    string
+		nodes   []Node
+		results []Result
+		want    []string
+	}{
+		{
+			name: "Overlay blocked",
+			results: []Result{
+				{Source: "node-1", Check: CheckNode, Target: "node-2", OK: true},
+				{Source: "node-1", Check: CheckPod, Target: "node-2", OK: false},
+				{Source: "node-1", Check: CheckMTU, Target: "node-2", OK: false},
+			},
+			want: []string{"wrong interface", "UDP port 8472"},
+		},
+		{
+			name: "Overlay blocked on configured port",
+			nodes: []Node{
+				{Name: "node-1", InternalIP: "10.0.0.1", FlannelBackend: "vxlan", FlannelPorts: "4789"},
+				{Name: "node-2", InternalIP: "10.0.0.2", FlannelBackend: "vxlan", FlannelPorts: "8472"},
+			},
+			results: []Result{
+				{Source: "node-1", Check: CheckNode, Target: "node-2", OK: true},
+				{Source: "node-1", Check: CheckPod, Target: "node-2", OK: false},
+			},
+			want: []string{"node node-1 is using 4789", "UDP port 8472"},
+		},
+		{
+			name: "Nodes unreachable",
+			results: []Result{
+				{Source: "node-1", Check: CheckNode, Target: "node-2", OK: false},
+				{Source: "node-1", Check: CheckPod, Target: "node-2", OK: false},
+			},
+			want: []string{"wrong interface", "cannot reach node node-2 at 10.0.0.2"},
+		},
+		{
+			name: "MTU",
+			results: []Result{
+				{Source: "node-2", Check: CheckPod, Target: "node-1", OK: true},
+				{Source: "node-2", Check: CheckMTU, Target: "node-1", OK: false},
+			},
+			want: []string{"wrong interface", "MTU"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testNodes := tt.nodes
+			if testNodes == nil {
+				testNodes = nodes
+			}
+			causes := Diagnose(testNodes, tt.results)
+			if len(causes) != len(tt.want) {
+				t.Fatalf("Diagnose() = %q, want %d causes", causes, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(causes[i], want) {
+					t.Errorf("Diagnose() cause %d = %q, want it to contain %q", i, causes[i], want)
+				}
+			}
+		})
+	}
+}
+
+func Test_UnitWriteMatrix(t *testing.T) {
+	results := []Result{
+		{Source: "node-1", Check: CheckPod, Target: "node-1", OK: true},
+		{Source: "node-1", Check: CheckPod, Target: "node-2", OK: false},
+		{Source: "node-1", Check: CheckMTU, Target: "node-2", OK: false},
+		{Source: "node-1", Check: CheckDNS, Target: "kubernetes.default", OK: true},
+	}
+	b := &bytes.Buffer{}
+	if err := WriteMatrix(b, []string{"node-1", "node-2"}, results); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("WriteMatrix() wrote %d lines, want 3:\n%s", len(lines), b.String())
+	}
+	if fields := strings.Fields(lines[1]); !reflect.DeepEqual(fields, []string{"node-1", "ok", "FAIL(mtu,pod)", "-", "ok"}) {
+		t.Errorf("WriteMatrix() row = %q", fields)
+	}
+}
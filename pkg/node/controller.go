@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
 	"github.com/k3s-io/k3s/pkg/nodepassword"
 	"github.com/pkg/errors"
 	coreclient "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
@@ -26,8 +27,11 @@ func Register(ctx context.Context,
 		modCoreDNS: modCoreDNS,
 		secrets:    secrets,
 		configMaps: configMaps,
+		cmCache:    configMaps.Cache(),
+		nodes:      nodes,
 	}
 	nodes.OnChange(ctx, "node", h.onChange)
+	nodes.OnChange(ctx, "node-metadata", h.onChangeMetadata)
 	nodes.OnRemove(ctx, "node", h.onRemove)
 
 	return nil
@@ -37,6 +41,8 @@ type handler struct {
 	modCoreDNS bool
 	secrets    coreclient.SecretController
 	configMaps coreclient.ConfigMapController
+	cmCache    coreclient.ConfigMapCache
+	nodes      coreclient.NodeController
 }
 
 func (h *handler) onChange(key string, node *core.Node) (*core.Node, error) {
@@ -46,7 +52,45 @@ func (h *handler) onChange(key string, node *core.Node) (*core.Node, error) {
 	return h.updateHosts(node, false)
 }
 
+// onChangeMetadata enforces the labels and taints that the node's agent has
+// configured with the enforced reconciliation policy.
+func (h *handler) onChangeMetadata(key string, node *core.Node) (*core.Node, error) {
+	if node == nil {
+		return nil, nil
+	}
+	applied, err := h.appliedMetadata(node.Name, true)
+	if err != nil {
+		return node, err
+	}
+	if _, ok := node.Annotations[nodeconfig.NodeMetadataAnnotation]; !ok && applied == nil {
+		return node, nil
+	}
+	// the cache may not yet reflect the labels and taints most recently added to the node
+	if applied, err = h.appliedMetadata(node.Name, false); err != nil {
+		return node, err
+	}
+	updated := node.DeepCopy()
+	result, changed, err := nodeconfig.ReconcileMetadata(updated, applied)
+	if err != nil {
+		logrus.Errorf("Failed to enforce labels and taints on node %s: %v", node.Name, err)
+		return node, nil
+	}
+	// Record the labels and taints added by the server before adding them, so that they are removed
+	// if they are no longer required, even if the node is changed before this handler is retried.
+	if err := h.setAppliedMetadata(node.Name, applied, result); err != nil {
+		return node, err
+	}
+	if !changed {
+		return node, nil
+	}
+	logrus.Infof("Enforcing labels and taints on node %s", node.Name)
+	return h.nodes.Update(updated)
+}
+
 func (h *handler) onRemove(key string, node *core.Node) (*core.Node, error) {
+	if err := h.setAppliedMetadata(node.Name, nil, nil); err != nil {
+		logrus.Warn(errors.Wrap(err, "Unable to remove enforced node labels and taints"))
+	}
 	return h.updateHosts(node, true)
 }
 
@@ -0,0 +1,87 @@
+package node
+
+import (
+	"encoding/json"
+
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// appliedMetadata returns the labels and taints that the server has added to the node, or nil if there are none.
+// The cache is used if cached is set; otherwise, the current entry is retrieved from the apiserver.
+func (h *handler) appliedMetadata(nodeName string, cached bool) (*nodeconfig.Metadata, error) {
+	var configMap *core.ConfigMap
+	var err error
+	if cached {
+		configMap, err = h.cmCache.Get(metav1.NamespaceSystem, nodeconfig.NodeMetadataConfigMap)
+	} else {
+		configMap, err = h.configMaps.Get(metav1.NamespaceSystem, nodeconfig.NodeMetadataConfigMap, metav1.GetOptions{})
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	value, ok := configMap.Data[nodeName]
+	if !ok {
+		return nil, nil
+	}
+	// The ConfigMap is only written by the server; if it is damaged, nothing can be removed.
+	applied, err := nodeconfig.ParseMetadata(value)
+	if err != nil {
+		return nil, nil
+	}
+	return applied, nil
+}
+
+// setAppliedMetadata stores the labels and taints that the server has added to the node, replacing
+// those previously stored. The entry for the node is removed if there are none.
+func (h *handler) setAppliedMetadata(nodeName string, prev, applied *nodeconfig.Metadata) error {
+	if applied != nil && len(applied.Labels) == 0 && len(applied.Taints) == 0 {
+		applied = nil
+	}
+	if equality.Semantic.DeepEqual(prev, applied) {
+		return nil
+	}
+	var value string
+	if applied != nil {
+		b, err := json.Marshal(applied)
+		if err != nil {
+			return err
+		}
+		value = string(b)
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := h.configMaps.Get(metav1.NamespaceSystem, nodeconfig.NodeMetadataConfigMap, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			if applied == nil {
+				return nil
+			}
+			_, err = h.configMaps.Create(&core.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeconfig.NodeMetadataConfigMap, Namespace: metav1.NamespaceSystem},
+				Data:       map[string]string{nodeName: value},
+			})
+			return err
+		} else if err != nil {
+			return err
+		}
+		if current, ok := configMap.Data[nodeName]; (applied == nil && !ok) || (applied != nil && current == value) {
+			return nil
+		}
+		configMap = configMap.DeepCopy()
+		if applied == nil {
+			delete(configMap.Data, nodeName)
+		} else {
+			if configMap.Data == nil {
+				configMap.Data = map[string]string{}
+			}
+			configMap.Data[nodeName] = value
+		}
+		_, err = h.configMaps.Update(configMap)
+		return err
+	})
+}
@@ -0,0 +1,242 @@
+package nodeconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kubeletapis "k8s.io/kubelet/pkg/apis"
+	"k8s.io/kubernetes/pkg/util/taints"
+)
+
+var (
+	// NodeMetadataAnnotation holds the labels and taints that the agent requires to be enforced on the node.
+	NodeMetadataAnnotation = version.Program + ".io/node-metadata-enforced"
+	// NodeMetadataConfigMap is the name of the ConfigMap in the kube-system namespace that holds, for each
+	// node, the labels and taints that were added to the node by the server, so that they can be removed
+	// once the agent no longer requires them. It is kept out of the node object, as nodes can modify
+	// their own annotations.
+	NodeMetadataConfigMap = version.Program + "-node-metadata"
+)
+
+// Reconciliation policies for node labels and taints.
+const (
+	// PolicyInitial applies labels when the agent starts and taints when the node registers. They
+	// may then be changed or removed through the Kubernetes API.
+	PolicyInitial = "initial"
+	// PolicyEnforced keeps labels and taints in sync with the agent configuration. Changes made
+	// through the Kubernetes API are reverted, and they are removed from the node when removed from
+	// the agent configuration.
+	PolicyEnforced = "enforced"
+)
+
+// Metadata is the set of labels and taints enforced on a node.
+type Metadata struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Taints []corev1.Taint    `json:"taints,omitempty"`
+}
+
+// ValidatePolicies checks that reconciliation policies are either a policy to use for all keys, or
+// in key=policy format.
+func ValidatePolicies(policies []string) error {
+	for _, p := range policies {
+		_, policy, ok := strings.Cut(p, "=")
+		if !ok {
+			policy = p
+		}
+		if policy != PolicyInitial && policy != PolicyEnforced {
+			return fmt.Errorf("invalid reconciliation policy %q: must be %s or %s, optionally prefixed with key=", p, PolicyInitial, PolicyEnforced)
+		}
+	}
+	return nil
+}
+
+// policyFor returns the reconciliation policy for a label or taint key. Policies for a specific key
+// take precedence over policies for all keys; if no policy is set, the initial policy is used.
+func policyFor(policies []string, key string) string {
+	policy := PolicyInitial
+	for _, p := range policies {
+		if k, v, ok := strings.Cut(p, "="); !ok {
+			policy = p
+		} else if k == key {
+			return v
+		}
+	}
+	return policy
+}
+
+// EnforcedMetadata returns the labels and taints from the agent configuration that use the
+// enforced reconciliation policy.
+func EnforcedMetadata(agentConfig *config.Agent) (*Metadata, error) {
+	metadata := &Metadata{Labels: map[string]string{}}
+	for _, label := range agentConfig.NodeLabels {
+		k, v, _ := strings.Cut(label, "=")
+		if policyFor(agentConfig.NodeLabelPolicies, k) == PolicyEnforced {
+			if !allowedLabel(k) {
+				return nil, fmt.Errorf("label %s cannot use the %s reconciliation policy, as nodes are not permitted to set it", k, PolicyEnforced)
+			}
+			metadata.Labels[k] = v
+		}
+	}
+	nodeTaints, _, err := taints.ParseTaints(agentConfig.NodeTaints)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse node taints")
+	}
+	for _, taint := range nodeTaints {
+		if policyFor(agentConfig.NodeTaintPolicies, taint.Key) == PolicyEnforced {
+			if !allowedTaint(taint.Key) {
+				return nil, fmt.Errorf("taint %s cannot use the %s reconciliation policy, as it is reserved for Kubernetes components", taint.Key, PolicyEnforced)
+			}
+			metadata.Taints = append(metadata.Taints, taint)
+		}
+	}
+	return metadata, nil
+}
+
+// SetNodeMetadataAnnotation stores the labels and taints that use the enforced
+// reconciliation policy as an annotation on the node object, for the server to
+// enforce. The kubelet's credentials are not permitted to modify taints on its
+// own node, so this cannot be done by the agent.
+func SetNodeMetadataAnnotation(nodeConfig *config.Node, node *corev1.Node) (bool, error) {
+	metadata, err := EnforcedMetadata(&nodeConfig.AgentConfig)
+	if err != nil {
+		return false, err
+	}
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	if len(metadata.Labels) == 0 && len(metadata.Taints) == 0 {
+		if _, ok := node.Annotations[NodeMetadataAnnotation]; ok {
+			delete(node.Annotations, NodeMetadataAnnotation)
+			return true, nil
+		}
+		return false, nil
+	}
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return false, err
+	}
+	if node.Annotations[NodeMetadataAnnotation] == string(b) {
+		return false, nil
+	}
+	node.Annotations[NodeMetadataAnnotation] = string(b)
+	return true, nil
+}
+
+// ReconcileMetadata applies the labels and taints that the agent requires to be enforced to the
+// node, and removes those that the server previously added but are no longer required. The labels and
+// taints previously added by the server are passed in applied, and must not be stored anywhere that the
+// node can modify, as they are removed from the node; the labels and taints that the server has now added
+// are returned, to be stored in their place. Only labels that the node is permitted to set on itself are
+// applied, taints already set through the API are never replaced or removed, and taints reserved for
+// Kubernetes components are ignored. The node is modified in place; true is returned if it was changed.
+func ReconcileMetadata(node *corev1.Node, applied *Metadata) (*Metadata, bool, error) {
+	desired, err := ParseMetadata(node.Annotations[NodeMetadataAnnotation])
+	if err != nil {
+		return applied, false, errors.Wrapf(err, "invalid %s annotation", NodeMetadataAnnotation)
+	}
+	if applied == nil {
+		applied = &Metadata{}
+	}
+
+	original := node.DeepCopy()
+	result := &Metadata{Labels: map[string]string{}}
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	for k, v := range applied.Labels {
+		if _, ok := desired.Labels[k]; !ok && node.Labels[k] == v {
+			delete(node.Labels, k)
+		}
+	}
+	for k, v := range desired.Labels {
+		if !allowedLabel(k) {
+			continue
+		}
+		_, owned := applied.Labels[k]
+		if current, ok := original.Labels[k]; owned || !ok || current != v {
+			result.Labels[k] = v
+		}
+		node.Labels[k] = v
+	}
+
+	for _, taint := range applied.Taints {
+		if !taints.TaintExists(desired.Taints, &taint) && hasTaint(node.Spec.Taints, taint) {
+			node.Spec.Taints, _ = taints.DeleteTaint(node.Spec.Taints, &taint)
+		}
+	}
+	for _, taint := range desired.Taints {
+		if !allowedTaint(taint.Key) {
+			continue
+		}
+		if !taints.TaintExists(applied.Taints, &taint) && taints.TaintExists(original.Spec.Taints, &taint) {
+			// the taint was set through the API; it is left as is, so that it is never removed.
+			continue
+		}
+		result.Taints = append(result.Taints, taint)
+		if updated, changed, err := taints.AddOrUpdateTaint(node, &taint); err != nil {
+			return applied, false, err
+		} else if changed {
+			node.Spec.Taints = updated.Spec.Taints
+		}
+	}
+
+	if len(result.Labels) == 0 {
+		result.Labels = nil
+	}
+	return result, !equality.Semantic.DeepEqual(original, node), nil
+}
+
+// allowedLabel returns true if nodes are permitted to set the label on themselves. This matches the labels
+// allowed by the NodeRestriction admission plugin, so that the server does not apply labels on behalf of a
+// node that it could not set itself.
+func allowedLabel(key string) bool {
+	return !isKubernetesKey(key) || kubeletapis.IsKubeletLabel(key)
+}
+
+// allowedTaint returns true if the taint is not reserved for Kubernetes components, such as the
+// node lifecycle controller and cloud controller manager.
+func allowedTaint(key string) bool {
+	return !isKubernetesKey(key)
+}
+
+// isKubernetesKey returns true if the key is prefixed with a kubernetes.io or k8s.io namespace.
+func isKubernetesKey(key string) bool {
+	namespace, _, ok := strings.Cut(key, "/")
+	if !ok {
+		return false
+	}
+	for _, reserved := range []string{"kubernetes.io", "k8s.io"} {
+		if namespace == reserved || strings.HasSuffix(namespace, "."+reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTaint returns true if a taint with the same key, value, and effect is in the list.
+func hasTaint(list []corev1.Taint, taint corev1.Taint) bool {
+	for _, t := range list {
+		if t.MatchTaint(&taint) && t.Value == taint.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMetadata parses labels and taints stored as JSON.
+func ParseMetadata(value string) (*Metadata, error) {
+	metadata := &Metadata{}
+	if value == "" {
+		return metadata, nil
+	}
+	if err := json.Unmarshal([]byte(value), metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
@@ -0,0 +1,160 @@
+package nodeconfig
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_UnitValidatePolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies []string
+		wantErr  bool
+	}{
+		{name: "empty"},
+		{name: "all keys", policies: []string{"enforced"}},
+		{name: "per key", policies: []string{"initial", "example.com/zone=enforced"}},
+		{name: "invalid policy", policies: []string{"always"}, wantErr: true},
+		{name: "invalid per key policy", policies: []string{"example.com/zone=always"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePolicies(tt.policies); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePolicies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_UnitEnforcedMetadata(t *testing.T) {
+	agentConfig := &config.Agent{
+		NodeLabels:        []string{"example.com/zone=a", "example.com/rack=1", "example.com/gpu"},
+		NodeLabelPolicies: []string{"enforced", "example.com/rack=initial"},
+		NodeTaints:        []string{"dedicated=gpu:NoSchedule", "example.com/maintenance:NoExecute"},
+		NodeTaintPolicies: []string{"dedicated=enforced"},
+	}
+	metadata, err := EnforcedMetadata(agentConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metadata.Labels) != 2 || metadata.Labels["example.com/zone"] != "a" || metadata.Labels["example.com/gpu"] != "" {
+		t.Errorf("EnforcedMetadata() labels = %v, want zone and gpu labels", metadata.Labels)
+	}
+	if len(metadata.Taints) != 1 || metadata.Taints[0].Key != "dedicated" || metadata.Taints[0].Value != "gpu" {
+		t.Errorf("EnforcedMetadata() taints = %v, want dedicated taint", metadata.Taints)
+	}
+}
+
+func Test_UnitEnforcedMetadataRestricted(t *testing.T) {
+	for _, agentConfig := range []*config.Agent{
+		{NodeLabels: []string{"node-restriction.kubernetes.io/trusted=true"}, NodeLabelPolicies: []string{"enforced"}},
+		{NodeTaints: []string{"node-role.kubernetes.io/control-plane:NoSchedule"}, NodeTaintPolicies: []string{"enforced"}},
+	} {
+		if _, err := EnforcedMetadata(agentConfig); err == nil {
+			t.Errorf("EnforcedMetadata(%v) expected error for restricted key", agentConfig)
+		}
+	}
+	agentConfig := &config.Agent{NodeLabels: []string{"node.kubernetes.io/pool=a"}, NodeLabelPolicies: []string{"enforced"}}
+	if _, err := EnforcedMetadata(agentConfig); err != nil {
+		t.Errorf("EnforcedMetadata() error = %v for label permitted to nodes", err)
+	}
+}
+
+func Test_UnitReconcileMetadata(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "fakeNode-metadata",
+			Labels: map[string]string{
+				"example.com/zone": "b",
+				"example.com/old":  "true",
+				"example.com/api":  "true",
+			},
+			Annotations: map[string]string{
+				NodeMetadataAnnotation: `{"labels":{"example.com/zone":"a","node-restriction.kubernetes.io/trusted":"true"},"taints":[{"key":"dedicated","value":"gpu","effect":"NoSchedule"},{"key":"api","effect":"NoSchedule"}]}`,
+			},
+		},
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{
+				{Key: "old", Effect: corev1.TaintEffectNoExecute},
+				{Key: "dedicated", Value: "cpu", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "api", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "admin", Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+	}
+	applied := &Metadata{
+		Labels: map[string]string{"example.com/zone": "a", "example.com/old": "true"},
+		Taints: []corev1.Taint{
+			{Key: "old", Effect: corev1.TaintEffectNoExecute},
+			{Key: "dedicated", Value: "cpu", Effect: corev1.TaintEffectNoSchedule},
+		},
+	}
+
+	applied, changed, err := ReconcileMetadata(node, applied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Errorf("ReconcileMetadata() expected true")
+	}
+	if node.Labels["example.com/zone"] != "a" {
+		t.Errorf("ReconcileMetadata() expected enforced label to be restored, got labels %v", node.Labels)
+	}
+	if _, ok := node.Labels["example.com/old"]; ok {
+		t.Errorf("ReconcileMetadata() expected previously enforced label to be removed, got labels %v", node.Labels)
+	}
+	if _, ok := node.Labels["example.com/api"]; !ok {
+		t.Errorf("ReconcileMetadata() expected label set through the API to be kept, got labels %v", node.Labels)
+	}
+	if _, ok := node.Labels["node-restriction.kubernetes.io/trusted"]; ok {
+		t.Errorf("ReconcileMetadata() expected restricted label to be ignored, got labels %v", node.Labels)
+	}
+	want := []corev1.Taint{
+		{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "api", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "admin", Effect: corev1.TaintEffectNoSchedule},
+	}
+	if len(node.Spec.Taints) != len(want) {
+		t.Fatalf("ReconcileMetadata() taints = %v, want %v", node.Spec.Taints, want)
+	}
+	for _, taint := range want {
+		if !hasTaint(node.Spec.Taints, taint) {
+			t.Errorf("ReconcileMetadata() taints = %v, missing %v", node.Spec.Taints, taint)
+		}
+	}
+	// the api taint was set through the API, so it is not recorded as applied by the server
+	wantApplied := &Metadata{
+		Labels: map[string]string{"example.com/zone": "a"},
+		Taints: []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}},
+	}
+	if !reflect.DeepEqual(applied, wantApplied) {
+		t.Errorf("ReconcileMetadata() applied = %+v, want %+v", applied, wantApplied)
+	}
+
+	if _, changed, err := ReconcileMetadata(node, applied); err != nil || changed {
+		t.Errorf("ReconcileMetadata() = %v, %v; expected no change when already reconciled", changed, err)
+	}
+
+	delete(node.Annotations, NodeMetadataAnnotation)
+	applied, changed, err = ReconcileMetadata(node, applied)
+	if err != nil || !changed {
+		t.Errorf("ReconcileMetadata() = %v, %v; expected change when enforcement is removed", changed, err)
+	}
+	if _, ok := node.Labels["example.com/zone"]; ok {
+		t.Errorf("ReconcileMetadata() expected label to be removed when no longer enforced, got labels %v", node.Labels)
+	}
+	want = []corev1.Taint{
+		{Key: "api", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "admin", Effect: corev1.TaintEffectNoSchedule},
+	}
+	if !reflect.DeepEqual(node.Spec.Taints, want) {
+		t.Errorf("ReconcileMetadata() taints = %v, want only taints set through the API", node.Spec.Taints)
+	}
+	if len(applied.Labels) != 0 || len(applied.Taints) != 0 {
+		t.Errorf("ReconcileMetadata() applied = %+v, want none", applied)
+	}
+}
@@ -2,14 +2,22 @@ package nodeconfig
 
 import (
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base32"
 	"encoding/json"
 	"fmt"
+	"io/fs"
+	"math"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/k3s-io/k3s/pkg/configfilearg"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/startup"
+	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -20,6 +28,38 @@ var (
 	NodeEnvAnnotation        = version.Program + ".io/node-env"
 	NodeConfigHashAnnotation = version.Program + ".io/node-config-hash"
 	ClusterEgressLabel       = "egress." + version.Program + ".io/cluster"
+	// ComponentVersionAnnotationPrefix is prefixed to the name of each embedded component to form
+	// the annotation that records its version, for example k3s.io/version-containerd.
+	ComponentVersionAnnotationPrefix = version.Program + ".io/version-"
+	// ContainerdConfigHashAnnotation holds a hash of the containerd configuration and registry hosts
+	// files generated by the agent, so that components can be restarted when registry configuration changes.
+	ContainerdConfigHashAnnotation = version.Program + ".io/containerd-config-hash"
+	// StartupDurationsAnnotation records the time taken by each phase of the node's most recent startup,
+	// in seconds from the start of the process, keyed by phase.
+	StartupDurationsAnnotation = version.Program + ".io/startup-durations"
+	// ServerCAHashAnnotation holds a hash of the server CA bundle that the node's agent trusts, so that
+	// servers can track which nodes have picked up a new CA during phased CA rotation.
+	ServerCAHashAnnotation = version.Program + ".io/server-ca-hash"
+	// ClientCertIssuerAnnotation holds a hash of the CA certificate that issued the client certificates used
+	// by the node's agent components, so that servers can track which nodes have re-issued their client
+	// certificates with a new CA during phased CA rotation.
+	ClientCertIssuerAnnotation = version.Program + ".io/client-cert-issuer"
+	// ServingCertIssuerAnnotation holds a hash of the CA certificate that issued the kubelet serving certificate
+	// of the node, so that servers can track which nodes have re-issued their serving certificates with a new CA
+	// during phased CA rotation.
+	ServingCertIssuerAnnotation = version.Program + ".io/serving-cert-issuer"
+	// FlannelPortsAnnotation lists the UDP ports that the node's flannel backend uses for overlay traffic,
+	// so that nodes configured with ports that differ from their peers can be found.
+	FlannelPortsAnnotation = version.Program + ".io/flannel-ports"
+	// ClusterCIDRAnnotation lists the cluster CIDRs that the node's agent was started with, so that nodes
+	// that have not been restarted since the cluster-cidr was expanded can be found.
+	ClusterCIDRAnnotation = version.Program + ".io/cluster-cidr"
+	// DesiredStateHashAnnotation holds a hash of the desired state, merged from NodeConfig resources, that
+	// the node's agent has most recently applied.
+	DesiredStateHashAnnotation = version.Program + ".io/desired-state-hash"
+	// DesiredStateRestartAnnotation is set to true when the node's agent has applied kubelet settings from
+	// NodeConfig resources that do not take effect until k3s is restarted.
+	DesiredStateRestartAnnotation = version.Program + ".io/desired-state-restart-required"
 )
 
 const (
@@ -103,6 +143,122 @@ func SetNodeConfigAnnotations(nodeConfig *config.Node, node *corev1.Node) (bool,
 	return true, nil
 }
 
+// SetContainerdConfigAnnotation stores a hash of the containerd configuration and registry hosts files
+// as an annotation on the node object. The annotation is removed if the agent did not generate a containerd
+// configuration, as is the case when an external container runtime is used.
+func SetContainerdConfigAnnotation(nodeConfig *config.Node, node *corev1.Node) (bool, error) {
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	b, err := os.ReadFile(nodeConfig.Containerd.Config)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return false, err
+		}
+		if _, ok := node.Annotations[ContainerdConfigHashAnnotation]; ok {
+			delete(node.Annotations, ContainerdConfigHashAnnotation)
+			return true, nil
+		}
+		return false, nil
+	}
+	h := sha256.New()
+	h.Write(b)
+	// WalkDir visits files in lexical order, so the hash is stable
+	err = filepath.WalkDir(nodeConfig.Containerd.Registry, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "\n%s\n", path)
+		h.Write(b)
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to hash the containerd config: %v", err)
+	}
+	encoded := base32.StdEncoding.EncodeToString(h.Sum(nil))
+	if node.Annotations[ContainerdConfigHashAnnotation] == encoded {
+		return false, nil
+	}
+	node.Annotations[ContainerdConfigHashAnnotation] = encoded
+	return true, nil
+}
+
+// SetFlannelPortsAnnotation stores the UDP ports used by the flannel backend as an annotation on the
+// node object. The annotation is removed if the backend does not use a port of its own.
+func SetFlannelPortsAnnotation(nodeConfig *config.Node, node *corev1.Node) bool {
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	ports := nodeConfig.FlannelPorts()
+	if len(ports) == 0 {
+		if _, ok := node.Annotations[FlannelPortsAnnotation]; ok {
+			delete(node.Annotations, FlannelPortsAnnotation)
+			return true
+		}
+		return false
+	}
+	value := FlannelPortsValue(ports)
+	if node.Annotations[FlannelPortsAnnotation] == value {
+		return false
+	}
+	node.Annotations[FlannelPortsAnnotation] = value
+	return true
+}
+
+// FlannelPortsValue returns the flannel ports annotation value for the given ports.
+func FlannelPortsValue(ports []int) string {
+	values := make([]string, len(ports))
+	for i, port := range ports {
+		values[i] = strconv.Itoa(port)
+	}
+	return strings.Join(values, ",")
+}
+
+// SetClusterCIDRAnnotation stores the cluster CIDRs used by the agent as an annotation on the node object.
+func SetClusterCIDRAnnotation(nodeConfig *config.Node, node *corev1.Node) bool {
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	value := util.JoinIPNets(nodeConfig.AgentConfig.ClusterCIDRs)
+	if value == "" || node.Annotations[ClusterCIDRAnnotation] == value {
+		return false
+	}
+	node.Annotations[ClusterCIDRAnnotation] = value
+	return true
+}
+
+// SetNodeVersionAnnotations stores the versions of embedded components as
+// annotations on the node object, so that nodes running outdated components
+// can be found. Annotations for components that are no longer embedded, or
+// whose version is not known, are removed.
+func SetNodeVersionAnnotations(node *corev1.Node) bool {
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	components := version.Components()
+	changed := false
+	for key := range node.Annotations {
+		if name, ok := strings.CutPrefix(key, ComponentVersionAnnotationPrefix); ok {
+			if _, ok := components[name]; !ok {
+				delete(node.Annotations, key)
+				changed = true
+			}
+		}
+	}
+	for name, componentVersion := range components {
+		key := ComponentVersionAnnotationPrefix + name
+		if node.Annotations[key] != componentVersion {
+			node.Annotations[key] = componentVersion
+			changed = true
+		}
+	}
+	return changed
+}
+
 // SetNodeConfigLabels adds labels for functionality flags
 // that may not be present on down-level or up-level nodes.
 // These labels are used by other components to determine whether
@@ -151,3 +307,81 @@ func isSecret(key string) bool {
 	}
 	return false
 }
+
+// StartupDurationsPatch returns a merge patch that sets the startup durations annotation on a node.
+// Durations are rounded to tenths of a second.
+func StartupDurationsPatch(durations map[string]time.Duration) ([]byte, error) {
+	seconds := map[string]float64{}
+	for phase, d := range durations {
+		seconds[startup.PhaseKey(phase)] = math.Round(d.Seconds()*10) / 10
+	}
+	b, err := json.Marshal(seconds)
+	if err != nil {
+		return nil, err
+	}
+	patch := map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{StartupDurationsAnnotation: string(b)},
+		},
+	}
+	return json.Marshal(patch)
+}
+
+// ServerCAHash returns the hash of a server CA bundle, as recorded in the server CA hash annotation.
+func ServerCAHash(b []byte) string {
+	h := sha256.Sum256(b)
+	return base32.StdEncoding.EncodeToString(h[:])
+}
+
+// ServerCAHashPatch returns a merge patch that sets the server CA hash annotation on a node.
+func ServerCAHashPatch(hash string) ([]byte, error) {
+	patch := map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{ServerCAHashAnnotation: hash},
+		},
+	}
+	return json.Marshal(patch)
+}
+
+// IssuerHash returns the hash of a CA certificate, as recorded in the client cert issuer annotation.
+func IssuerHash(cert *x509.Certificate) string {
+	return ServerCAHash(cert.Raw)
+}
+
+// ClientCertIssuerPatch returns a merge patch that sets the client cert issuer annotation on a node.
+func ClientCertIssuerPatch(hash string) ([]byte, error) {
+	patch := map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{ClientCertIssuerAnnotation: hash},
+		},
+	}
+	return json.Marshal(patch)
+}
+
+// ServingCertIssuerPatch returns a merge patch that sets the serving cert issuer annotation on a node.
+func ServingCertIssuerPatch(hash string) ([]byte, error) {
+	patch := map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{ServingCertIssuerAnnotation: hash},
+		},
+	}
+	return json.Marshal(patch)
+}
+
+// DesiredStatePatch returns a merge patch that sets the desired state hash annotation on a node, and sets
+// or removes the desired state restart annotation.
+func DesiredStatePatch(hash string, restartRequired bool) ([]byte, error) {
+	annotations := map[string]any{
+		DesiredStateHashAnnotation:    hash,
+		DesiredStateRestartAnnotation: nil,
+	}
+	if restartRequired {
+		annotations[DesiredStateRestartAnnotation] = "true"
+	}
+	patch := map[string]any{
+		"metadata": map[string]any{
+			"annotations": annotations,
+		},
+	}
+	return json.Marshal(patch)
+}
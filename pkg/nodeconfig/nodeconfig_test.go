@@ -1,8 +1,10 @@
 package nodeconfig
 
 import (
+	"net"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/version"
@@ -124,3 +126,89 @@ func Test_UnitSetNodeConfigAnnotations(t *testing.T) {
 		})
 	}
 }
+
+func Test_UnitSetNodeVersionAnnotations(t *testing.T) {
+	defer func(containerd, kine string) {
+		version.Containerd, version.Kine = containerd, kine
+	}(version.Containerd, version.Kine)
+	version.Containerd = "v1.7.23-k3s2"
+	version.Kine = ""
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "fakeNode-versions",
+			Annotations: map[string]string{
+				ComponentVersionAnnotationPrefix + "containerd": "v1.7.22-k3s1",
+				ComponentVersionAnnotationPrefix + "kine":       "v0.13.5",
+			},
+		},
+	}
+	if !SetNodeVersionAnnotations(node) {
+		t.Errorf("Test_UnitSetNodeVersionAnnotations() expected true")
+	}
+	if got := node.Annotations[ComponentVersionAnnotationPrefix+"containerd"]; got != version.Containerd {
+		t.Errorf("Test_UnitSetNodeVersionAnnotations() containerd version = %q, want %q", got, version.Containerd)
+	}
+	if _, ok := node.Annotations[ComponentVersionAnnotationPrefix+"kine"]; ok {
+		t.Errorf("Test_UnitSetNodeVersionAnnotations() expected kine version annotation to be removed")
+	}
+	if SetNodeVersionAnnotations(node) {
+		t.Errorf("Test_UnitSetNodeVersionAnnotations() expected false when versions are unchanged")
+	}
+}
+
+func Test_UnitSetFlannelPortsAnnotation(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "fakeNode-flannel-ports"}}
+	nodeConfig := &config.Node{
+		FlannelBackend:           config.FlannelBackendWireguardNative,
+		FlannelWireguardPort:     51000,
+		FlannelWireguardPortIPv6: 51001,
+	}
+	if !SetFlannelPortsAnnotation(nodeConfig, node) {
+		t.Errorf("Test_UnitSetFlannelPortsAnnotation() expected true")
+	}
+	if got := node.Annotations[FlannelPortsAnnotation]; got != "51000,51001" {
+		t.Errorf("Test_UnitSetFlannelPortsAnnotation() annotation = %q, want %q", got, "51000,51001")
+	}
+	if SetFlannelPortsAnnotation(nodeConfig, node) {
+		t.Errorf("Test_UnitSetFlannelPortsAnnotation() expected false when ports are unchanged")
+	}
+	nodeConfig.FlannelBackend = config.FlannelBackendHostGW
+	if !SetFlannelPortsAnnotation(nodeConfig, node) {
+		t.Errorf("Test_UnitSetFlannelPortsAnnotation() expected true when the backend does not use a port")
+	}
+	if _, ok := node.Annotations[FlannelPortsAnnotation]; ok {
+		t.Errorf("Test_UnitSetFlannelPortsAnnotation() expected annotation to be removed")
+	}
+}
+
+func Test_UnitSetClusterCIDRAnnotation(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "fakeNode-cluster-cidr"}}
+	_, ipv4, _ := net.ParseCIDR("10.42.0.0/15")
+	_, ipv6, _ := net.ParseCIDR("fd00:42::/56")
+	nodeConfig := &config.Node{}
+	nodeConfig.AgentConfig.ClusterCIDRs = []*net.IPNet{ipv4, ipv6}
+	if !SetClusterCIDRAnnotation(nodeConfig, node) {
+		t.Errorf("Test_UnitSetClusterCIDRAnnotation() expected true")
+	}
+	if got := node.Annotations[ClusterCIDRAnnotation]; got != "10.42.0.0/15,fd00:42::/56" {
+		t.Errorf("Test_UnitSetClusterCIDRAnnotation() annotation = %q, want %q", got, "10.42.0.0/15,fd00:42::/56")
+	}
+	if SetClusterCIDRAnnotation(nodeConfig, node) {
+		t.Errorf("Test_UnitSetClusterCIDRAnnotation() expected false when cluster CIDRs are unchanged")
+	}
+}
+
+func Test_UnitStartupDurationsPatch(t *testing.T) {
+	patch, err := StartupDurationsPatch(map[string]time.Duration{
+		"Container runtime ready": 4240 * time.Millisecond,
+		"Node ready":              21 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"metadata":{"annotations":{"` + StartupDurationsAnnotation + `":"{\"container_runtime_ready\":4.2,\"node_ready\":21}"}}}`
+	if string(patch) != want {
+		t.Errorf("StartupDurationsPatch() = %s, want %s", patch, want)
+	}
+}
@@ -2,6 +2,7 @@ package nodepassword
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"path"
@@ -17,6 +18,7 @@ import (
 	coreclient "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apiserver/pkg/authentication/user"
@@ -89,7 +91,7 @@ func GetNodeAuthValidator(ctx context.Context, control *config.Control) NodeAuth
 		if err := Ensure(secretClient, node.Name, node.Password); err != nil {
 			// if the verification failed, reject the request
 			if errors.Is(err, ErrVerifyFailed) {
-				return "", http.StatusForbidden, err
+				return "", http.StatusForbidden, conflictError(nodeClient, node.Name, err)
 			}
 			// If verification failed due to an error creating the node password secret, allow
 			// the request, but retry verification until the outage is resolved.  This behavior
@@ -225,3 +227,33 @@ func ensureSecret(ctx context.Context, control *config.Control, node *nodeInfo)
 		return false, nil
 	})
 }
+
+// conflictError returns an error describing how to resolve a node password verification failure. This
+// is most often caused by a new node joining with the same name as an existing node.
+func conflictError(nodeClient coreclient.NodeController, nodeName string, err error) error {
+	existing, getErr := nodeClient.Cache().Get(nodeName)
+	if getErr != nil {
+		existing = nil
+	}
+	return fmt.Errorf("%s: %w", conflictMessage(existing, nodeName), err)
+}
+
+// conflictMessage returns an actionable message for a node that was rejected because its node password
+// does not match the one stored for the node name. The existing node is nil if it is not registered.
+func conflictMessage(existing *corev1.Node, nodeName string) string {
+	if existing == nil {
+		return fmt.Sprintf("node name %s was previously used by a node with a different node password that is no longer registered; "+
+			"if that node will not rejoin, delete its node password with 'kubectl -n %s delete secret %s' and retry",
+			nodeName, metav1.NamespaceSystem, getSecretName(nodeName))
+	}
+	var addresses []string
+	for _, address := range existing.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP || address.Type == corev1.NodeExternalIP {
+			addresses = append(addresses, address.Address)
+		}
+	}
+	return fmt.Sprintf("node name %s is already in use by an existing node with a different node password (addresses: %s, created: %s); "+
+		"if this is a different host, give it a unique name with --node-name, --node-name-template such as '{{.Hostname}}-{{.MachineID | shorthash}}', or --with-node-id; "+
+		"if this host is replacing the existing node, delete it with 'kubectl delete node %s' and retry",
+		nodeName, strings.Join(addresses, ","), existing.CreationTimestamp.UTC().Format(time.RFC3339), nodeName)
+}
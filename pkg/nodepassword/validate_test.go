@@ -0,0 +1,30 @@
+package nodepassword
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_UnitConflictMessage(t *testing.T) {
+	existing := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge"},
+		Status: v1.NodeStatus{Addresses: []v1.NodeAddress{
+			{Type: v1.NodeInternalIP, Address: "10.0.0.5"},
+			{Type: v1.NodeHostName, Address: "edge"},
+		}},
+	}
+	message := conflictMessage(existing, "edge")
+	for _, want := range []string{"already in use", "10.0.0.5", "--node-name-template", "kubectl delete node edge"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("conflictMessage() for existing node = %q, want it to contain %q", message, want)
+		}
+	}
+
+	message = conflictMessage(nil, "edge")
+	if want := "delete secret edge.node-password.k3s"; !strings.Contains(message, want) {
+		t.Errorf("conflictMessage() for unregistered node = %q, want it to contain %q", message, want)
+	}
+}
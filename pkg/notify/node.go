@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	coreclient "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	core "k8s.io/api/core/v1"
+)
+
+// RegisterNodeController sends node lifecycle events to the webhook. Nodes that exist when the
+// controller starts are not reported as having joined the cluster.
+func RegisterNodeController(ctx context.Context, webhook *Webhook, nodes coreclient.NodeController) {
+	h := &nodeHandler{
+		webhook:  webhook,
+		started:  time.Now(),
+		versions: map[string]string{},
+	}
+	nodes.OnChange(ctx, "notify-node", h.onChange)
+}
+
+type nodeHandler struct {
+	webhook  *Webhook
+	started  time.Time
+	mu       sync.Mutex
+	versions map[string]string
+}
+
+func (h *nodeHandler) onChange(key string, node *core.Node) (*core.Node, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if node == nil {
+		if _, ok := h.versions[key]; ok {
+			delete(h.versions, key)
+			h.webhook.Notifyf(NodeRemoved, key, "Node %s removed from cluster", key)
+		}
+		return nil, nil
+	}
+
+	version := node.Status.NodeInfo.KubeletVersion
+	previous, known := h.versions[node.Name]
+	h.versions[node.Name] = version
+
+	switch {
+	case !known && node.CreationTimestamp.Time.After(h.started):
+		h.webhook.Notifyf(NodeJoined, node.Name, "Node %s joined cluster", node.Name)
+	case known && previous != "" && version != "" && previous != version:
+		h.webhook.Notifyf(NodeUpgraded, node.Name, "Node %s upgraded from %s to %s", node.Name, previous, version)
+	}
+
+	return node, nil
+}
@@ -0,0 +1,178 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Cluster lifecycle event types that may be sent to the webhook.
+const (
+	NodeJoined          = "NodeJoined"
+	NodeRemoved         = "NodeRemoved"
+	NodeUpgraded        = "NodeUpgraded"
+	ETCDMemberAdded     = "ETCDMemberAdded"
+	ETCDMemberPromoted  = "ETCDMemberPromoted"
+	ETCDMemberRemoved   = "ETCDMemberRemoved"
+	ETCDSnapshotCreated = "ETCDSnapshotCreated"
+	ETCDSnapshotFailed  = "ETCDSnapshotFailed"
+	CertificatesRotated = "CertificatesRotated"
+)
+
+// EventTypes lists all supported event types.
+var EventTypes = []string{
+	NodeJoined,
+	NodeRemoved,
+	NodeUpgraded,
+	ETCDMemberAdded,
+	ETCDMemberPromoted,
+	ETCDMemberRemoved,
+	ETCDSnapshotCreated,
+	ETCDSnapshotFailed,
+	CertificatesRotated,
+}
+
+const (
+	queueSize      = 256
+	requestTimeout = 10 * time.Second
+)
+
+// Event is the payload posted to the webhook.
+type Event struct {
+	// Type is the event type, for example NodeJoined
+	Type string `json:"type"`
+	// Node is the name of the node that the event is about, if any
+	Node string `json:"node,omitempty"`
+	// Message is a human-readable description of the event
+	Message string `json:"message"`
+	// Source is the name of the server that sent the event
+	Source string `json:"source"`
+	// Timestamp is the time the event occurred
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Webhook posts cluster lifecycle events to a URL. Events are queued and sent asynchronously,
+// so that notification does not block the operations that generate events. A nil Webhook
+// discards all events.
+type Webhook struct {
+	url     string
+	source  string
+	events  []string
+	client  *http.Client
+	queue   chan Event
+	backoff wait.Backoff
+}
+
+// NewWebhook creates a webhook that sends events of the listed types to the URL. If no
+// types are listed, all events are sent.
+func NewWebhook(webhookURL, source string, events []string) (*Webhook, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported webhook URL scheme %q; must be one of http, https", u.Scheme)
+	}
+	for _, event := range events {
+		if !slices.Contains(EventTypes, event) {
+			return nil, fmt.Errorf("unsupported event type %q; must be one of %s", event, strings.Join(EventTypes, ", "))
+		}
+	}
+	return &Webhook{
+		url:    webhookURL,
+		source: source,
+		events: events,
+		client: &http.Client{Timeout: requestTimeout},
+		queue:  make(chan Event, queueSize),
+		backoff: wait.Backoff{
+			Duration: time.Second,
+			Factor:   2,
+			Steps:    5,
+		},
+	}, nil
+}
+
+// Start sends queued events until the context is cancelled.
+func (w *Webhook) Start(ctx context.Context) {
+	if w == nil {
+		return
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-w.queue:
+				if err := w.send(ctx, event); err != nil {
+					logrus.Errorf("Failed to send %s event to webhook: %v", event.Type, err)
+				}
+			}
+		}
+	}()
+}
+
+// Notify queues an event for delivery to the webhook.
+func (w *Webhook) Notify(eventType, node, message string) {
+	if w == nil || (len(w.events) > 0 && !slices.Contains(w.events, eventType)) {
+		return
+	}
+	event := Event{
+		Type:      eventType,
+		Node:      node,
+		Message:   message,
+		Source:    w.source,
+		Timestamp: time.Now().UTC(),
+	}
+	select {
+	case w.queue <- event:
+	default:
+		logrus.Warnf("Dropped %s event: webhook queue is full", eventType)
+	}
+}
+
+// Notifyf is like Notify, but takes a format string for the message.
+func (w *Webhook) Notifyf(eventType, node, messageFmt string, args ...interface{}) {
+	w.Notify(eventType, node, fmt.Sprintf(messageFmt, args...))
+}
+
+// send posts an event to the webhook, retrying with backoff on failure.
+func (w *Webhook) send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	err = wait.ExponentialBackoffWithContext(ctx, w.backoff, func(ctx context.Context) (bool, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", version.Program+"/"+version.Version)
+		resp, err := w.client.Do(req)
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			lastErr = fmt.Errorf("webhook returned %s", resp.Status)
+			return false, nil
+		}
+		return true, nil
+	})
+	if wait.Interrupted(err) && lastErr != nil {
+		return lastErr
+	}
+	return err
+}
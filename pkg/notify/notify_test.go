@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_UnitNewWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		events  []string
+		wantErr bool
+	}{
+		{name: "all events", url: "https://example.com/hook"},
+		{name: "filtered events", url: "http://example.com/hook", events: []string{NodeJoined, ETCDSnapshotFailed}},
+		{name: "unsupported scheme", url: "ftp://example.com/hook", wantErr: true},
+		{name: "unsupported event", url: "https://example.com/hook", events: []string{"NodeExploded"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewWebhook(tt.url, "server-1", tt.events); (err != nil) != tt.wantErr {
+				t.Errorf("NewWebhook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_UnitWebhookNotify(t *testing.T) {
+	received := make(chan Event, 10)
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first request to exercise retries
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		event := Event{}
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode event: %v", err)
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	webhook, err := NewWebhook(server.URL, "server-1", []string{NodeJoined})
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook.backoff.Duration = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	webhook.Start(ctx)
+
+	webhook.Notifyf(NodeRemoved, "node-1", "Node %s removed from cluster", "node-1")
+	webhook.Notifyf(NodeJoined, "node-2", "Node %s joined cluster", "node-2")
+
+	select {
+	case event := <-received:
+		if event.Type != NodeJoined || event.Node != "node-2" || event.Source != "server-1" || event.Message != "Node node-2 joined cluster" {
+			t.Errorf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	select {
+	case event := <-received:
+		t.Errorf("unexpected event %+v; filtered events should not be sent", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func Test_UnitNilWebhook(t *testing.T) {
+	var webhook *Webhook
+	webhook.Start(context.Background())
+	webhook.Notify(NodeJoined, "node-1", "Node node-1 joined cluster")
+}
+
+func Test_UnitNodeHandler(t *testing.T) {
+	webhook, err := NewWebhook("https://example.com/hook", "server-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := time.Now()
+	h := &nodeHandler{
+		webhook:  webhook,
+		started:  started,
+		versions: map[string]string{},
+	}
+
+	node := func(name, version string, created time.Time) *core.Node {
+		return &core.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+			Status:     core.NodeStatus{NodeInfo: core.NodeSystemInfo{KubeletVersion: version}},
+		}
+	}
+
+	steps := []struct {
+		name string
+		key  string
+		node *core.Node
+		want string
+	}{
+		{name: "existing node", key: "node-1", node: node("node-1", "v1.31.4+k3s1", started.Add(-time.Hour))},
+		{name: "existing node unchanged", key: "node-1", node: node("node-1", "v1.31.4+k3s1", started.Add(-time.Hour))},
+		{name: "existing node upgraded", key: "node-1", node: node("node-1", "v1.32.1+k3s1", started.Add(-time.Hour)), want: NodeUpgraded},
+		{name: "new node", key: "node-2", node: node("node-2", "v1.32.1+k3s1", started.Add(time.Minute)), want: NodeJoined},
+		{name: "node removed", key: "node-2", want: NodeRemoved},
+		{name: "unknown node removed", key: "node-3"},
+	}
+	for _, step := range steps {
+		if _, err := h.onChange(step.key, step.node); err != nil {
+			t.Fatalf("%s: onChange() error = %v", step.name, err)
+		}
+		select {
+		case event := <-webhook.queue:
+			if event.Type != step.want || event.Node != step.key {
+				t.Errorf("%s: got %s event for %s, want %q", step.name, event.Type, event.Node, step.want)
+			}
+		default:
+			if step.want != "" {
+				t.Errorf("%s: got no event, want %s", step.name, step.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,396 @@
+//go:build linux
+// +build linux
+
+package preflight
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/dataverify"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+// defaultClusterCIDRs are the default cluster and service CIDRs, which should not already be routed.
+var defaultClusterCIDRs = []string{"10.42.0.0/16", "10.43.0.0/16"}
+
+// minRootMaxKeys is the minimum value for the kernel.keys.root_maxkeys sysctl.
+const minRootMaxKeys = 10000
+
+// Run runs all checks and returns the report. If the kernel config cannot be found or read, the
+// report contains a failed result in place of the kernel config checks.
+func Run(opts Options) (*Report, error) {
+	r := &Report{}
+	if opts.BinDir != "" {
+		r.add(checkBinaries(opts.BinDir)...)
+	}
+	r.add(checkSystem(opts.BinDir)...)
+	r.add(checkLimits()...)
+
+	release := kernelRelease()
+	kernelVersion, _ := utilversion.ParseGeneric(release)
+	r.add(checkCgroups())
+	r.add(checkAppArmor()...)
+	configFile, err := findKernelConfig(opts.KernelConfig, release)
+	if err != nil {
+		r.add(Result{Group: GroupRequired, Name: "kernel config", Status: StatusFail, Message: "not found", Hint: err.Error()})
+		return r, nil
+	}
+	config, err := readKernelConfig(configFile)
+	if err != nil {
+		r.add(Result{Group: GroupRequired, Name: "kernel config", Status: StatusFail, Message: "unreadable", Hint: errors.Wrapf(err, "failed to read kernel config %s", configFile).Error()})
+		return r, nil
+	}
+	r.KernelConfig = configFile
+
+	r.add(config.checkRequired(kernelVersion)...)
+	r.add(config.checkOptional(kernelVersion)...)
+	r.add(checkDistroUserNamespaces()...)
+	r.add(config.checkDrivers()...)
+	if opts.SELinux {
+		r.add(DetectSELinux().Results()...)
+	}
+	return r, nil
+}
+
+func kernelRelease() string {
+	uname := unix.Utsname{}
+	if err := unix.Uname(&uname); err != nil {
+		return ""
+	}
+	return unix.ByteSliceToString(uname.Release[:])
+}
+
+// findKernelConfig returns the path to the kernel config. If no path is provided, well-known
+// locations are searched, after attempting to load the configs module that provides /proc/config.gz.
+func findKernelConfig(configFile, release string) (string, error) {
+	if configFile != "" {
+		if _, err := os.Stat(configFile); err != nil {
+			return "", fmt.Errorf("cannot find kernel config %s", configFile)
+		}
+		return configFile, nil
+	}
+
+	if _, err := os.Stat("/proc/config.gz"); err != nil && os.Geteuid() == 0 {
+		exec.Command("modprobe", "configs").Run()
+	}
+	for _, configFile := range kernelConfigPaths(release) {
+		if _, err := os.Stat(configFile); err == nil {
+			return configFile, nil
+		}
+	}
+	return "", errors.New("cannot find kernel config; specify the path to the kernel config")
+}
+
+// checkBinaries verifies the checksums and links of the binaries in binDir.
+func checkBinaries(binDir string) []Result {
+	results := []Result{}
+
+	if _, err := os.Stat(filepath.Join(binDir, ".sha256sums")); err != nil {
+		results = append(results, Result{Group: GroupBinaries, Name: "sha256sum", Status: StatusWarn, Message: "sha256sums unavailable"})
+	} else if err := dataverify.VerifySums(binDir, ".sha256sums"); err != nil {
+		results = append(results, Result{Group: GroupBinaries, Name: "sha256sum", Status: StatusFail, Message: "does not match", Hint: err.Error()})
+	} else {
+		results = append(results, Result{Group: GroupBinaries, Name: "sha256sum", Status: StatusPass, Message: "good"})
+	}
+
+	f, err := os.Open(filepath.Join(binDir, ".links"))
+	if err != nil {
+		return append(results, Result{Group: GroupBinaries, Name: "links", Status: StatusWarn, Message: "link list unavailable"})
+	}
+	defer f.Close()
+
+	linkFail := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		file, link := fields[0], fields[1]
+		switch actual, _ := os.Readlink(filepath.Join(binDir, file)); actual {
+		case link:
+		case "xtables-legacy-multi", "xtables-nft-multi":
+			// If no iptables is installed on the host system, the symlink will be different
+			results = append(results, Result{Group: GroupBinaries, Name: file, Status: StatusWarn, Message: "symlink to " + actual})
+		default:
+			results = append(results, Result{Group: GroupBinaries, Name: file, Status: StatusFail, Message: "symlink to " + link})
+			linkFail = true
+		}
+	}
+	if !linkFail {
+		results = append(results, Result{Group: GroupBinaries, Name: "links", Status: StatusPass, Message: "good"})
+	}
+	return results
+}
+
+// checkSystem checks the iptables version, swap, and routes.
+func checkSystem(binDir string) []Result {
+	results := []Result{checkIPTables(binDir)}
+	results = append(results, checkSwap()...)
+	return append(results, checkRoutes())
+}
+
+// checkIPTables checks the version and mode of iptables. The system iptables is preferred over
+// the bundled iptables, as the bundled iptables is not used if the system iptables is available.
+func checkIPTables(binDir string) Result {
+	result := Result{Group: GroupSystem, Name: "iptables"}
+
+	cmd := ""
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" || dir == binDir {
+			continue
+		}
+		if path, err := exec.LookPath(filepath.Join(dir, "iptables")); err == nil {
+			cmd = path
+			break
+		}
+	}
+	if cmd == "" && binDir != "" {
+		if path, err := exec.LookPath(filepath.Join(binDir, "iptables")); err == nil {
+			cmd = path
+		}
+	}
+	if cmd == "" {
+		result.Status = StatusWarn
+		result.Message = "unknown version: iptables not found"
+		return result
+	}
+
+	out, _ := exec.Command(cmd, "--version").Output()
+	info := strings.TrimSpace(string(out))
+	fields := strings.Fields(info)
+	result.Name = filepath.Dir(cmd) + " " + info
+
+	var iptablesVersion *utilversion.Version
+	if len(fields) > 1 && strings.HasPrefix(fields[1], "v") {
+		iptablesVersion, _ = utilversion.ParseGeneric(fields[1])
+	}
+	switch {
+	case iptablesVersion == nil:
+		result.Name = cmd
+		result.Status = StatusWarn
+		result.Message = "unknown version: " + info
+	case iptablesVersion.LessThan(utilversion.MajorMinor(1, 8)):
+		result.Status = StatusPass
+		result.Message = "older than v1.8"
+	case (len(fields) < 3 || fields[2] != "(legacy)") && iptablesVersion.LessThan(utilversion.MustParseGeneric("1.8.4")):
+		result.Status = StatusFail
+		result.Message = "should be older than v1.8.0, newer than v1.8.3, or in legacy mode"
+	default:
+		result.Status = StatusPass
+		result.Message = "ok"
+	}
+	return result
+}
+
+// checkSwap checks whether swap is enabled, and reports zram swap devices and zswap, which
+// reduce the impact of swapping.
+func checkSwap() []Result {
+	results := []Result{}
+	if swapTotal() == 0 {
+		results = append(results, Result{Group: GroupSystem, Name: "swap", Status: StatusPass, Message: "disabled"})
+	} else {
+		results = append(results, Result{Group: GroupSystem, Name: "swap", Status: StatusWarn, Message: "enabled; disable swap or set kubelet-swap-behavior=LimitedSwap (requires cgroups v2)"})
+		if b, err := os.ReadFile("/proc/swaps"); err == nil && strings.Contains(string(b), "\n/dev/zram") {
+			results = append(results, Result{Group: GroupSystem, Name: "zram", Status: StatusPass, Message: "swap device in use"})
+		}
+	}
+	if b, err := os.ReadFile("/sys/module/zswap/parameters/enabled"); err == nil && strings.TrimSpace(string(b)) == "Y" {
+		results = append(results, Result{Group: GroupSystem, Name: "zswap", Status: StatusPass, Message: "enabled"})
+	}
+	return results
+}
+
+// swapTotal returns the total swap space in kB, as reported by /proc/meminfo.
+func swapTotal() int {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && fields[0] == "SwapTotal:" {
+			total, _ := strconv.Atoi(fields[1])
+			return total
+		}
+	}
+	return 0
+}
+
+// checkRoutes checks that the default cluster and service CIDRs are not already routed by
+// interfaces other than the cni bridge.
+func checkRoutes() Result {
+	result := Result{Group: GroupSystem, Name: "routes", Status: StatusPass, Message: "ok"}
+
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return result
+	}
+	defer f.Close()
+
+	cidrs := make([]*net.IPNet, 0, len(defaultClusterCIDRs))
+	for _, cidr := range defaultClusterCIDRs {
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		cidrs = append(cidrs, ipNet)
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] == "Iface" || fields[0] == "cni0" {
+			continue
+		}
+		b, err := hex.DecodeString(fields[1])
+		if err != nil || len(b) != 4 {
+			continue
+		}
+		// destinations are in host byte order, which is little-endian on all supported architectures
+		dest := make(net.IP, 4)
+		binary.BigEndian.PutUint32(dest, binary.LittleEndian.Uint32(b))
+		for _, cidr := range cidrs {
+			if cidr.Contains(dest) {
+				result.Status = StatusWarn
+				result.Message = "default CIDRs " + strings.Join(defaultClusterCIDRs, " or ") + " already routed"
+				return result
+			}
+		}
+	}
+	return result
+}
+
+// checkLimits checks kernel limits.
+func checkLimits() []Result {
+	const file = "/proc/sys/kernel/keys/root_maxkeys"
+	result := Result{Group: GroupLimits, Name: file, Status: StatusPass}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		result.Status = StatusWarn
+		result.Message = "unavailable"
+		return []Result{result}
+	}
+	result.Message = strings.TrimSpace(string(b))
+	if value, _ := strconv.Atoi(result.Message); value <= minRootMaxKeys {
+		result.Status = StatusFail
+		result.Hint = fmt.Sprintf("This should be set to at least %d, for example set: sysctl -w kernel/keys/root_maxkeys=1000000", minRootMaxKeys)
+	}
+	return []Result{result}
+}
+
+// checkCgroups checks the cgroup hierarchy version and required controllers.
+func checkCgroups() Result {
+	result := Result{Group: GroupRequired, Name: "cgroup hierarchy"}
+
+	variant := "V1"
+	statfs := unix.Statfs_t{}
+	if err := unix.Statfs("/sys/fs/cgroup", &statfs); err != nil {
+		result.Status = StatusFail
+		result.Message = "cgroups Nonexistent"
+		return result
+	}
+	if statfs.Type == unix.CGROUP2_SUPER_MAGIC {
+		variant = "V2"
+	} else if err := unix.Statfs("/sys/fs/cgroup/unified", &statfs); err == nil && statfs.Type == unix.CGROUP2_SUPER_MAGIC {
+		variant = "Hybrid"
+	}
+
+	required := []string{"cpuset", "memory"}
+	controllers := map[string]bool{}
+	if variant == "V2" {
+		required = []string{"cpu", "cpuset", "memory"}
+		b, _ := os.ReadFile("/sys/fs/cgroup/cgroup.controllers")
+		for _, controller := range strings.Fields(string(b)) {
+			controllers[controller] = true
+		}
+	} else {
+		b, _ := os.ReadFile("/proc/self/cgroup")
+		for _, line := range strings.Split(string(b), "\n") {
+			if fields := strings.Split(line, ":"); len(fields) == 3 {
+				for _, controller := range strings.Split(fields[1], ",") {
+					controllers[controller] = true
+				}
+			}
+		}
+	}
+
+	status := "good"
+	result.Status = StatusPass
+	for _, controller := range required {
+		if !controllers[controller] {
+			status = "bad"
+			result.Status = StatusFail
+			result.Hint = "for cgroups V1/Hybrid on non-Systemd init see https://github.com/tianon/cgroupfs-mount"
+		}
+	}
+	result.Message = fmt.Sprintf("cgroups %s mounted, %s controllers status: %s", variant, strings.Join(required, "|"), status)
+	return result
+}
+
+// checkAppArmor checks that the apparmor tools are installed, if apparmor is enabled.
+func checkAppArmor() []Result {
+	if b, err := os.ReadFile("/sys/module/apparmor/parameters/enabled"); err != nil || strings.TrimSpace(string(b)) != "Y" {
+		return nil
+	}
+	result := Result{Group: GroupRequired, Name: "apparmor", Status: StatusPass, Message: "enabled and tools installed"}
+	if _, err := exec.LookPath("apparmor_parser"); err != nil {
+		result.Status = StatusFail
+		result.Message = "enabled, but apparmor_parser missing"
+		switch {
+		case commandExists("apt-get"):
+			result.Hint = `use "apt-get install apparmor" to fix this`
+		case commandExists("yum"):
+			result.Hint = `your best bet is "yum install apparmor-parser"`
+		case commandExists("zypper"):
+			result.Hint = `your best bet is "zypper install apparmor-parser"`
+		default:
+			result.Hint = `look for an "apparmor" package for your distribution`
+		}
+	}
+	return []Result{result}
+}
+
+// checkDistroUserNamespaces checks that user namespaces are enabled on RHEL7 and CentOS7,
+// where they are disabled by default.
+func checkDistroUserNamespaces() []Result {
+	b, err := os.ReadFile("/etc/os-release")
+	if err != nil {
+		return nil
+	}
+	osRelease := map[string]string{}
+	for _, line := range strings.Split(string(b), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			osRelease[key] = strings.Trim(value, `"'`)
+		}
+	}
+	if id := osRelease["ID"]; (id != "centos" && id != "rhel") || !strings.HasPrefix(osRelease["VERSION_ID"], "7") {
+		return nil
+	}
+	if cmdline, _ := os.ReadFile("/proc/cmdline"); strings.Contains(string(cmdline), "user_namespace.enable=1") {
+		return nil
+	}
+	return []Result{{
+		Group:   GroupOptional,
+		Name:    "RHEL7/CentOS7 user namespaces",
+		Status:  StatusFail,
+		Message: "disabled",
+		Hint:    "add 'user_namespace.enable=1' to boot command line",
+	}}
+}
+
+func commandExists(cmd string) bool {
+	_, err := exec.LookPath(cmd)
+	return err == nil
+}
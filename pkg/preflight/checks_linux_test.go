@@ -0,0 +1,36 @@
+//go:build linux
+// +build linux
+
+package preflight
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func Test_UnitRunKernelConfigNotFound(t *testing.T) {
+	r, err := Run(Options{KernelConfig: filepath.Join(t.TempDir(), "config")})
+	if err != nil {
+		t.Fatalf("expected missing kernel config to be reported as a result, got error: %v", err)
+	}
+	if r.Passed() {
+		t.Errorf("expected report to fail when the kernel config is missing")
+	}
+
+	b := &bytes.Buffer{}
+	if err := r.WriteJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	report := &Report{}
+	if err := json.Unmarshal(b.Bytes(), report); err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range report.Results {
+		if result.Name == "kernel config" && result.Status == StatusFail && result.Message == "not found" {
+			return
+		}
+	}
+	t.Errorf("expected JSON report to include a failed kernel config result, got %s", b)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package preflight
+
+import "errors"
+
+// Run runs all checks and returns the report. Checks are only supported on Linux.
+func Run(opts Options) (*Report, error) {
+	return &Report{}, errors.New("preflight checks are only supported on Linux")
+}
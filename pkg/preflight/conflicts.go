@@ -0,0 +1,81 @@
+package preflight
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ConflictOptions configures the conflicting service checks that are run at startup.
+type ConflictOptions struct {
+	// Ports lists the TCP ports that must not be bound by another process.
+	Ports []int
+	// Services enables checks for other kubelets, container runtimes, leftover network interfaces,
+	// and host network services that conflict with the agent.
+	Services bool
+	// CNIConfDir is the agent's CNI config directory. Leftover network interfaces are only reported
+	// if it does not exist, as the interfaces are otherwise likely to have been created by k3s.
+	CNIConfDir string
+	// EmbeddedContainerd indicates that the embedded containerd is in use, in which case other
+	// container runtime sockets on the node are reported.
+	EmbeddedContainerd bool
+	// ClusterCIDRs lists the cluster and service CIDRs that must be trusted by the host firewall.
+	// If empty, the default CIDRs are used.
+	ClusterCIDRs []string
+	// InstanceName is the name used to namespace the agent's network interfaces, if more than one
+	// instance is run on the host.
+	InstanceName string
+}
+
+// CheckConflicts checks for host services that conflict with k3s, and logs a warning with
+// remediation steps for each potential conflict. An error is returned if any conflict will
+// prevent k3s from starting.
+func CheckConflicts(opts ConflictOptions) error {
+	failed := []string{}
+	for _, result := range Conflicts(opts) {
+		switch result.Status {
+		case StatusWarn:
+			logrus.Warnf("Potential conflict with %s: %s", result.Name, result.Message)
+		case StatusFail:
+			logrus.Errorf("Conflict with %s: %s", result.Name, result.Message)
+			failed = append(failed, result.Name)
+		default:
+			continue
+		}
+		if result.Hint != "" {
+			logrus.Warnf("To resolve this, %s", result.Hint)
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("conflicting host services detected: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// parseListeners parses the contents of /proc/net/tcp or /proc/net/tcp6, and returns the socket
+// inode for each port in the listening state.
+func parseListeners(r io.Reader) map[int]string {
+	listeners := map[int]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != "0A" {
+			continue
+		}
+		i := strings.LastIndex(fields[1], ":")
+		if i < 0 {
+			continue
+		}
+		port, err := strconv.ParseUint(fields[1][i+1:], 16, 16)
+		if err != nil {
+			continue
+		}
+		listeners[int(port)] = fields[9]
+	}
+	return listeners
+}
@@ -0,0 +1,238 @@
+//go:build linux
+// +build linux
+
+package preflight
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/instance"
+	"github.com/k3s-io/k3s/pkg/version"
+)
+
+// conflictingSockets are the well-known sockets of other container runtimes.
+var conflictingSockets = map[string]string{
+	"/run/containerd/containerd.sock": "containerd",
+	"/run/crio/crio.sock":             "cri-o",
+	"/run/cri-dockerd.sock":           "cri-dockerd",
+	"/run/docker.sock":                "docker",
+}
+
+// Conflicts checks for host services that conflict with k3s: other processes listening on the
+// given ports and, if enabled, other kubelets and container runtimes, network interfaces left
+// behind by other Kubernetes distributions, and host firewall and network management services
+// that interfere with cluster traffic. Only conflicts are returned; checks that pass are omitted.
+func Conflicts(opts ConflictOptions) []Result {
+	results := checkPorts(opts.Ports)
+	if !opts.Services {
+		return results
+	}
+
+	processes := runningProcesses()
+	if pid, ok := processes["kubelet"]; ok {
+		results = append(results, Result{
+			Group:   GroupConflicts,
+			Name:    "kubelet",
+			Status:  StatusFail,
+			Message: fmt.Sprintf("another kubelet is running (pid %d)", pid),
+			Hint:    "stop and disable the kubelet service, for example: systemctl disable --now kubelet",
+		})
+	}
+	if opts.EmbeddedContainerd {
+		results = append(results, checkSockets()...)
+	}
+	if opts.CNIConfDir != "" {
+		results = append(results, checkInterfaces(opts.CNIConfDir, opts.InstanceName)...)
+	}
+	if _, ok := processes["firewalld"]; ok {
+		cidrs := opts.ClusterCIDRs
+		if len(cidrs) == 0 {
+			cidrs = defaultClusterCIDRs
+		}
+		results = append(results, checkFirewalld(cidrs)...)
+	}
+	if _, ok := processes["NetworkManager"]; ok {
+		results = append(results, checkNetworkManager()...)
+	}
+	return results
+}
+
+// runningProcesses returns the pid of each running process, by command name.
+func runningProcesses() map[string]int {
+	processes := map[string]int{}
+	dirs, _ := filepath.Glob("/proc/[0-9]*")
+	for _, dir := range dirs {
+		pid, err := strconv.Atoi(filepath.Base(dir))
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		if b, err := os.ReadFile(filepath.Join(dir, "comm")); err == nil {
+			processes[strings.TrimSpace(string(b))] = pid
+		}
+	}
+	return processes
+}
+
+// checkPorts checks that the given TCP ports are not already bound by another process.
+func checkPorts(ports []int) []Result {
+	listeners := map[int]string{}
+	for _, file := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+		for port, inode := range parseListeners(f) {
+			listeners[port] = inode
+		}
+		f.Close()
+	}
+
+	results := []Result{}
+	var owners map[string]int
+	for _, port := range ports {
+		inode, ok := listeners[port]
+		if !ok {
+			continue
+		}
+		if owners == nil {
+			owners = socketOwners()
+		}
+		result := Result{
+			Group:   GroupConflicts,
+			Name:    fmt.Sprintf("port %d", port),
+			Status:  StatusFail,
+			Message: "already in use by another process",
+			Hint:    fmt.Sprintf("stop the process listening on port %d, or configure %s to use a different port", port, version.Program),
+		}
+		if pid, ok := owners[inode]; ok {
+			if pid == os.Getpid() {
+				continue
+			}
+			name, _ := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+			result.Message = fmt.Sprintf("already in use by %s (pid %d)", strings.TrimSpace(string(name)), pid)
+			result.Hint = fmt.Sprintf("stop %s (pid %d), or configure %s to use a different port", strings.TrimSpace(string(name)), pid, version.Program)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// socketOwners returns the pid of the process holding each socket inode open. Sockets held open by
+// processes whose file descriptors cannot be read are omitted.
+func socketOwners() map[string]int {
+	owners := map[string]int{}
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, fd := range fds {
+		link, err := os.Readlink(fd)
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.Split(fd, "/")[2])
+		if err != nil {
+			continue
+		}
+		owners[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] = pid
+	}
+	return owners
+}
+
+// checkSockets checks for other container runtimes that are accepting connections on their
+// well-known sockets.
+func checkSockets() []Result {
+	results := []Result{}
+	for socket, runtime := range conflictingSockets {
+		conn, err := net.DialTimeout("unix", socket, time.Second)
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		results = append(results, Result{
+			Group:   GroupConflicts,
+			Name:    runtime,
+			Status:  StatusWarn,
+			Message: "another container runtime is listening on " + socket,
+			Hint:    fmt.Sprintf("stop and disable %s, or use it instead of the embedded containerd by setting --container-runtime-endpoint=%s", runtime, socket),
+		})
+	}
+	return results
+}
+
+// checkInterfaces checks for flannel and cni bridge interfaces left behind by other Kubernetes
+// distributions. Interfaces are only reported if the CNI config directory does not exist yet,
+// as they may otherwise have been created by a previous run of k3s. Only the interfaces that
+// would be created for the named instance are checked.
+func checkInterfaces(cniConfDir, instanceName string) []Result {
+	if _, err := os.Stat(cniConfDir); err == nil {
+		return nil
+	}
+	results := []Result{}
+	for _, name := range instance.Interfaces(instanceName) {
+		if _, err := net.InterfaceByName(name); err != nil {
+			continue
+		}
+		results = append(results, Result{
+			Group:   GroupConflicts,
+			Name:    "interface " + name,
+			Status:  StatusWarn,
+			Message: "already exists and may have been created by another Kubernetes distribution",
+			Hint:    "remove the interface: ip link delete " + name,
+		})
+	}
+	return results
+}
+
+// checkFirewalld checks that the cluster CIDRs are bound to a firewalld zone that accepts their traffic:
+// either the trusted zone, or the zone registered by --host-firewall=firewalld. Otherwise, pod and service
+// traffic is rejected.
+func checkFirewalld(cidrs []string) []Result {
+	if !commandExists("firewall-cmd") {
+		return nil
+	}
+	missing := []string{}
+	for _, cidr := range cidrs {
+		out, _ := exec.Command("firewall-cmd", "--get-zone-of-source="+cidr).Output()
+		if zone := strings.TrimSpace(string(out)); zone != "trusted" && zone != version.Program {
+			missing = append(missing, cidr)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	hint := "disable firewalld: systemctl disable --now firewalld; or set --host-firewall=firewalld; or add the cluster CIDRs to the trusted zone:"
+	for _, cidr := range missing {
+		hint += " firewall-cmd --permanent --zone=trusted --add-source=" + cidr + ";"
+	}
+	hint += " firewall-cmd --reload"
+	return []Result{{
+		Group:   GroupConflicts,
+		Name:    "firewalld",
+		Status:  StatusWarn,
+		Message: "running, and " + strings.Join(missing, ", ") + " not in trusted zone",
+		Hint:    hint,
+	}}
+}
+
+// checkNetworkManager checks that NetworkManager is configured to ignore the flannel and cni
+// bridge interfaces, so that it does not interfere with their routing.
+func checkNetworkManager() []Result {
+	files, _ := filepath.Glob("/etc/NetworkManager/conf.d/*.conf")
+	for _, file := range files {
+		if b, err := os.ReadFile(file); err == nil && strings.Contains(string(b), "interface-name:flannel") {
+			return nil
+		}
+	}
+	return []Result{{
+		Group:   GroupConflicts,
+		Name:    "NetworkManager",
+		Status:  StatusWarn,
+		Message: "running, and not configured to ignore cni0 and flannel interfaces",
+		Hint:    "create /etc/NetworkManager/conf.d/k3s.conf containing \"[keyfile]\" and \"unmanaged-devices=interface-name:cni0;interface-name:flannel*\", then restart NetworkManager",
+	}}
+}
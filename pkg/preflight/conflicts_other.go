@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package preflight
+
+// Conflicts checks for host services that conflict with k3s. Checks are only supported on Linux.
+func Conflicts(opts ConflictOptions) []Result {
+	return nil
+}
@@ -0,0 +1,20 @@
+package preflight
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_UnitParseListeners(t *testing.T) {
+	content := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:192B 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 21564 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:192C 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 21570 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:192C 0100007F:D3A2 01 00000000:00000000 00:00000000 00000000     0        0 31337 1 0000000000000000 20 4 30 10 -1
+   3: 00000000000000000000000000000000:280A 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 21600 1 0000000000000000 100 0 0 10 0
+`
+	want := map[int]string{6443: "21564", 6444: "21570", 10250: "21600"}
+	if got := parseListeners(strings.NewReader(content)); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseListeners() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,176 @@
+package preflight
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+// requiredKernelFlags are kernel config options that must be enabled.
+var requiredKernelFlags = []string{
+	"NAMESPACES", "NET_NS", "PID_NS", "IPC_NS", "UTS_NS",
+	"CGROUPS", "CGROUP_PIDS", "CGROUP_CPUACCT", "CGROUP_DEVICE", "CGROUP_FREEZER", "CGROUP_SCHED", "CPUSETS", "MEMCG",
+	"KEYS",
+	"VETH", "BRIDGE", "BRIDGE_NETFILTER",
+	"IP_NF_FILTER", "IP_NF_TARGET_MASQUERADE", "IP_NF_TARGET_REJECT",
+	"NETFILTER_XT_MATCH_ADDRTYPE", "NETFILTER_XT_MATCH_CONNTRACK", "NETFILTER_XT_MATCH_IPVS", "NETFILTER_XT_MATCH_COMMENT", "NETFILTER_XT_MATCH_MULTIPORT",
+	"IP_NF_NAT", "NF_NAT",
+	"POSIX_MQUEUE",
+}
+
+// optionalKernelFlags are kernel config options that are used by optional features.
+var optionalKernelFlags = []string{
+	"BLK_CGROUP", "BLK_DEV_THROTTLING",
+	"CGROUP_PERF",
+	"CGROUP_HUGETLB",
+	"NET_CLS_CGROUP",
+	"CFS_BANDWIDTH", "FAIR_GROUP_SCHED", "RT_GROUP_SCHED",
+	"IP_NF_TARGET_REDIRECT",
+	"IP_SET",
+	"IP_VS",
+	"IP_VS_NFCT",
+	"IP_VS_PROTO_TCP",
+	"IP_VS_PROTO_UDP",
+	"IP_VS_RR",
+}
+
+// ext4KernelFlags are kernel config options required to use ext4 as a backing filesystem.
+var ext4KernelFlags = []string{"EXT4_FS", "EXT4_FS_POSIX_ACL", "EXT4_FS_SECURITY"}
+
+// networkKernelFlags are kernel config options used by the overlay network driver.
+var networkKernelFlags = []string{"VXLAN"}
+
+// encryptionKernelFlags are kernel config options used by encrypted overlay networks.
+var encryptionKernelFlags = []string{
+	"CRYPTO", "CRYPTO_AEAD", "CRYPTO_GCM", "CRYPTO_SEQIV", "CRYPTO_GHASH",
+	"XFRM", "XFRM_USER", "XFRM_ALGO", "INET_ESP", "INET_XFRM_MODE_TRANSPORT",
+}
+
+// storageKernelFlags are kernel config options used by the overlay storage driver.
+var storageKernelFlags = []string{"OVERLAY_FS"}
+
+// kernelConfig maps kernel config options, without the CONFIG_ prefix, to their values.
+type kernelConfig map[string]string
+
+// kernelConfigPaths returns the well-known locations of the config for the given kernel release.
+func kernelConfigPaths(release string) []string {
+	return []string{
+		"/proc/config.gz",
+		"/boot/config-" + release,
+		"/boot/config-" + release[strings.LastIndex(release, "-")+1:],
+		"/usr/src/linux-" + release + "/.config",
+		"/usr/src/linux/.config",
+	}
+}
+
+// readKernelConfig reads a plain or gzipped kernel config file.
+func readKernelConfig(file string) (kernelConfig, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader = bytes.NewReader(b)
+	if gz, err := gzip.NewReader(bytes.NewReader(b)); err == nil {
+		defer gz.Close()
+		r = gz
+	}
+
+	config := kernelConfig{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || !strings.HasPrefix(key, "CONFIG_") {
+			continue
+		}
+		config[strings.TrimPrefix(key, "CONFIG_")] = value
+	}
+	return config, scanner.Err()
+}
+
+// isSet returns true if the option is built in to the kernel or enabled as a module.
+func (c kernelConfig) isSet(flag string) bool {
+	return c[flag] == "y" || c[flag] == "m"
+}
+
+// check returns the result of checking that a kernel config option is enabled. If the
+// option is not enabled, the result has the given status.
+func (c kernelConfig) check(group, flag string, missing Status) Result {
+	result := Result{Group: group, Name: "CONFIG_" + flag, Status: StatusPass}
+	switch c[flag] {
+	case "y":
+		result.Message = "enabled"
+	case "m":
+		result.Message = "enabled (as module)"
+	default:
+		result.Status = missing
+		result.Message = "missing"
+	}
+	return result
+}
+
+func (c kernelConfig) checkAll(group string, flags []string, missing Status) []Result {
+	results := make([]Result, 0, len(flags))
+	for _, flag := range flags {
+		results = append(results, c.check(group, flag, missing))
+	}
+	return results
+}
+
+// checkRequired checks the kernel config options that must be enabled.
+func (c kernelConfig) checkRequired(kernelVersion *utilversion.Version) []Result {
+	results := c.checkAll(GroupRequired, requiredKernelFlags, StatusFail)
+	if kernelVersion != nil && kernelVersion.LessThan(utilversion.MajorMinor(4, 8)) {
+		results = append(results, c.check(GroupRequired, "DEVPTS_MULTIPLE_INSTANCES", StatusWarn))
+	}
+	return results
+}
+
+// checkOptional checks the kernel config options used by optional features.
+func (c kernelConfig) checkOptional(kernelVersion *utilversion.Version) []Result {
+	results := c.checkAll(GroupOptional, []string{"USER_NS", "SECCOMP"}, StatusWarn)
+
+	flags := []string{}
+	if kernelVersion != nil && kernelVersion.LessThan(utilversion.MajorMinor(4, 6)) {
+		flags = append(flags, "MEMCG_KMEM")
+	}
+	if kernelVersion != nil && kernelVersion.LessThan(utilversion.MajorMinor(3, 19)) {
+		flags = append(flags, "RESOURCE_COUNTERS")
+	}
+	flags = append(flags, optionalKernelFlags...)
+	if kernelVersion != nil && kernelVersion.LessThan(utilversion.MajorMinor(3, 14)) {
+		flags = append(flags, "NETPRIO_CGROUP")
+	} else {
+		flags = append(flags, "CGROUP_NET_PRIO")
+	}
+	results = append(results, c.checkAll(GroupOptional, flags, StatusWarn)...)
+
+	hint := "enable these ext4 configs if you are using ext4 as backing filesystem"
+	if c.isSet("EXT4_USE_FOR_EXT2") {
+		hint = "enable these ext4 configs if you are using ext3 or ext4 as backing filesystem"
+	}
+	for _, result := range c.checkAll(GroupOptional, ext4KernelFlags, StatusWarn) {
+		if result.Status != StatusPass {
+			result.Hint = hint
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkDrivers checks the kernel config options used by the network and storage drivers.
+func (c kernelConfig) checkDrivers() []Result {
+	results := c.checkAll(GroupNetwork, networkKernelFlags, StatusWarn)
+	for _, result := range c.checkAll(GroupNetwork, encryptionKernelFlags, StatusWarn) {
+		if result.Status != StatusPass {
+			result.Hint = "optional, for encrypted networks"
+		}
+		results = append(results, result)
+	}
+	return append(results, c.checkAll(GroupStorage, storageKernelFlags, StatusWarn)...)
+}
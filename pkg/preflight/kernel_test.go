@@ -0,0 +1,126 @@
+package preflight
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+func Test_UnitReadKernelConfig(t *testing.T) {
+	content := "# CONFIG_USER_NS is not set\nCONFIG_NAMESPACES=y\nCONFIG_VXLAN=m\nCONFIG_LOCALVERSION=\"\"\n"
+
+	gzipped := &bytes.Buffer{}
+	gz := gzip.NewWriter(gzipped)
+	gz.Write([]byte(content))
+	gz.Close()
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{
+			name:    "Plain kernel config",
+			content: []byte(content),
+		},
+		{
+			name:    "Gzipped kernel config",
+			content: gzipped.Bytes(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config")
+			if err := os.WriteFile(file, tt.content, 0600); err != nil {
+				t.Fatal(err)
+			}
+			config, err := readKernelConfig(file)
+			if err != nil {
+				t.Fatalf("readKernelConfig() error = %v", err)
+			}
+			if !config.isSet("NAMESPACES") || !config.isSet("VXLAN") || config.isSet("USER_NS") || config.isSet("LOCALVERSION") {
+				t.Errorf("readKernelConfig() = %v, want NAMESPACES and VXLAN set", config)
+			}
+		})
+	}
+}
+
+func Test_UnitKernelConfigChecks(t *testing.T) {
+	config := kernelConfig{}
+	for _, flag := range requiredKernelFlags {
+		config[flag] = "y"
+	}
+	config["VETH"] = "m"
+	delete(config, "KEYS")
+
+	tests := []struct {
+		name          string
+		kernelVersion *utilversion.Version
+		wantResults   int
+	}{
+		{
+			name:          "Current kernel",
+			kernelVersion: utilversion.MustParseGeneric("6.1.0"),
+			wantResults:   len(requiredKernelFlags),
+		},
+		{
+			name:          "Kernel without multiple devpts instances",
+			kernelVersion: utilversion.MustParseGeneric("4.4.0"),
+			wantResults:   len(requiredKernelFlags) + 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Report{}
+			r.add(config.checkRequired(tt.kernelVersion)...)
+			if len(r.Results) != tt.wantResults {
+				t.Errorf("checkRequired() returned %d results, want %d", len(r.Results), tt.wantResults)
+			}
+			if r.Failures != 1 || r.Passed() {
+				t.Errorf("checkRequired() failures = %d, want 1", r.Failures)
+			}
+			for _, result := range r.Results {
+				switch result.Name {
+				case "CONFIG_KEYS":
+					if result.Status != StatusFail || result.Message != "missing" {
+						t.Errorf("checkRequired() %s = %+v, want missing", result.Name, result)
+					}
+				case "CONFIG_VETH":
+					if result.Status != StatusPass || result.Message != "enabled (as module)" {
+						t.Errorf("checkRequired() %s = %+v, want enabled as module", result.Name, result)
+					}
+				case "CONFIG_DEVPTS_MULTIPLE_INSTANCES":
+					if result.Status != StatusWarn {
+						t.Errorf("checkRequired() %s = %+v, want warning", result.Name, result)
+					}
+				}
+			}
+
+			buf := &bytes.Buffer{}
+			if err := r.WriteText(buf, false); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buf.String(), "- CONFIG_KEYS: missing (fail)\n") || !strings.HasSuffix(buf.String(), "STATUS: 1 (fail)\n") {
+				t.Errorf("WriteText() = %s", buf.String())
+			}
+		})
+	}
+}
+
+func Test_UnitCheckConfigScript(t *testing.T) {
+	script, err := os.ReadFile(filepath.Join("..", "..", CheckConfigScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := UpdateScript(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(script, updated) {
+		t.Errorf("kernel config options in %s do not match those checked by Run; run go generate to update them", CheckConfigScript)
+	}
+}
@@ -0,0 +1,139 @@
+// Package preflight checks that a node meets the prerequisites for running k3s. The checks
+// are the same as those run by the check-config command, and may be used by provisioning tools
+// that embed k3s to validate a node before installation.
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	// StatusPass indicates that the check passed.
+	StatusPass Status = "pass"
+	// StatusWarn indicates that an optional prerequisite is not met.
+	StatusWarn Status = "warn"
+	// StatusFail indicates that a required prerequisite is not met.
+	StatusFail Status = "fail"
+)
+
+// Check groups, in the order that they are run.
+const (
+	GroupBinaries = "Binaries"
+	GroupSystem   = "System"
+	GroupLimits   = "Limits"
+	GroupRequired = "Generally Necessary"
+	GroupOptional = "Optional Features"
+	GroupNetwork  = "Network Drivers"
+	GroupStorage  = "Storage Drivers"
+	GroupSELinux  = "SELinux"
+	// GroupConflicts is not part of the check-config report; see Conflicts.
+	GroupConflicts = "Conflicting Services"
+)
+
+// Options configures the checks that are run.
+type Options struct {
+	// BinDir is the directory containing the binaries to verify. If empty, binaries are not verified.
+	BinDir string
+	// KernelConfig is the path to the kernel config. If empty, well-known locations are searched.
+	KernelConfig string
+	// SELinux includes details of the SELinux policy in the report.
+	SELinux bool
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	// Group is the group that the check belongs to, for example Generally Necessary
+	Group string `json:"group"`
+	// Name is the name of the checked item, for example CONFIG_NAMESPACES
+	Name string `json:"name"`
+	// Status is the outcome of the check
+	Status Status `json:"status"`
+	// Message describes the state of the checked item
+	Message string `json:"message"`
+	// Hint describes how to resolve a failed check, if available
+	Hint string `json:"hint,omitempty"`
+}
+
+// Report contains the results of all checks.
+type Report struct {
+	// KernelConfig is the path that the kernel config was read from
+	KernelConfig string `json:"kernelConfig,omitempty"`
+	// Results lists the outcome of each check, in the order that they were run
+	Results []Result `json:"results"`
+	// Failures is the number of checks that failed
+	Failures int `json:"failures"`
+}
+
+// Passed returns true if no checks failed.
+func (r *Report) Passed() bool {
+	return r.Failures == 0
+}
+
+func (r *Report) add(results ...Result) {
+	for _, result := range results {
+		if result.Status == StatusFail {
+			r.Failures++
+		}
+		r.Results = append(r.Results, result)
+	}
+}
+
+// WriteJSON writes the report to w as JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(r)
+}
+
+// WriteText writes the report to w in human-readable form, grouped by check group. If color
+// is set, ANSI escape codes are used to highlight the status of each check.
+func (r *Report) WriteText(w io.Writer, color bool) error {
+	c := colorizer(color)
+	group := ""
+	for _, result := range r.Results {
+		if result.Group != group {
+			group = result.Group
+			fmt.Fprintln(w)
+			if group == GroupRequired && r.KernelConfig != "" {
+				fmt.Fprintln(w, c("info: reading kernel config from "+r.KernelConfig+" ...", "36"))
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintln(w, group+":")
+		}
+		switch result.Status {
+		case StatusPass:
+			fmt.Fprintf(w, "- %s: %s\n", c(result.Name, "37"), c(result.Message, "32"))
+		case StatusWarn:
+			fmt.Fprintf(w, "- %s: %s\n", c(result.Name, "1"), c(result.Message, "1;33"))
+		case StatusFail:
+			fmt.Fprintf(w, "- %s: %s\n", c(result.Name, "1"), c(result.Message+" (fail)", "1;31"))
+		}
+		if result.Hint != "" {
+			fmt.Fprintf(w, "    %s\n", c(result.Hint, "1;30"))
+		}
+	}
+
+	fmt.Fprintln(w)
+	if r.Passed() {
+		fmt.Fprintf(w, "%s: %s\n", c("STATUS", "37"), c("pass", "32"))
+	} else {
+		fmt.Fprintf(w, "%s: %s\n", c("STATUS", "1"), c(fmt.Sprintf("%d (fail)", r.Failures), "1;31"))
+	}
+	return nil
+}
+
+// colorizer returns a function that wraps text in the given ANSI SGR codes, or returns text
+// unmodified if color is not enabled.
+func colorizer(color bool) func(text, codes string) string {
+	return func(text, codes string) string {
+		if !color {
+			return text
+		}
+		return "\033[" + codes + "m" + text + "\033[m"
+	}
+}
@@ -0,0 +1,72 @@
+package preflight
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// CheckConfigScript is the path, relative to the root of the repository, of the standalone
+// check-config script for hosts that do not have k3s installed. The kernel config options that
+// it checks are generated from the lists used by Run, so that the two do not drift apart.
+const CheckConfigScript = "contrib/util/check-config.sh"
+
+const (
+	scriptBegin = "# BEGIN GENERATED KERNEL CONFIG OPTIONS"
+	scriptEnd   = "# END GENERATED KERNEL CONFIG OPTIONS"
+)
+
+// scriptKernelFlags are the shell variables set in the check-config script, and the kernel
+// config options that each contains.
+var scriptKernelFlags = []struct {
+	name  string
+	flags []string
+}{
+	{"requiredFlags", requiredKernelFlags},
+	{"optionalFlags", optionalKernelFlags},
+	{"ext4Flags", ext4KernelFlags},
+	{"networkFlags", networkKernelFlags},
+	{"encryptionFlags", encryptionKernelFlags},
+	{"storageFlags", storageKernelFlags},
+}
+
+// UpdateScript returns the check-config script with the generated kernel config options
+// replaced by the options currently checked by Run.
+func UpdateScript(script []byte) ([]byte, error) {
+	begin := bytes.Index(script, []byte(scriptBegin+"\n"))
+	end := bytes.Index(script, []byte(scriptEnd+"\n"))
+	if begin == -1 || end < begin {
+		return nil, errors.New("check-config script does not contain generated kernel config options")
+	}
+
+	b := &bytes.Buffer{}
+	b.Write(script[:begin])
+	b.WriteString(scriptBegin + "\n")
+	b.WriteString("# generated from pkg/preflight by go generate; do not edit\n")
+	for _, v := range scriptKernelFlags {
+		b.WriteString(v.name + `="` + "\n")
+		for _, line := range wrapFlags(v.flags, 100) {
+			b.WriteString("  " + line + "\n")
+		}
+		b.WriteString(`"` + "\n")
+	}
+	b.Write(script[end:])
+	return b.Bytes(), nil
+}
+
+// wrapFlags joins flags with spaces, into lines no longer than width where possible.
+func wrapFlags(flags []string, width int) []string {
+	lines := []string{}
+	line := ""
+	for _, flag := range flags {
+		if line != "" && len(line)+1+len(flag) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		line = strings.TrimPrefix(line+" "+flag, " ")
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
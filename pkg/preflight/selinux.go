@@ -0,0 +1,115 @@
+package preflight
+
+import (
+	"fmt"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+// SELinux policy condition reasons
+const (
+	SELinuxReasonReady        = "PolicyReady"
+	SELinuxReasonNotInstalled = "PolicyNotInstalled"
+	SELinuxReasonOutdated     = "PolicyOutdated"
+	SELinuxReasonNotEnabled   = "SELinuxSupportDisabled"
+	SELinuxReasonWrongContext = "ProcessContextInvalid"
+)
+
+// selinuxContextType is the SELinux type that k3s runs as when the k3s-selinux policy is applied.
+const selinuxContextType = "container_runtime_t"
+
+// SELinuxStatus describes the state of SELinux and the k3s-selinux policy on the host.
+type SELinuxStatus struct {
+	// Enabled is true if SELinux is enabled on the host
+	Enabled bool `json:"enabled"`
+	// Enforcing is true if SELinux is in enforcing mode
+	Enforcing bool `json:"enforcing"`
+	// PolicyType is the loaded SELinux policy, for example targeted
+	PolicyType string `json:"policyType,omitempty"`
+	// PolicyInstalled is true if the k3s policy module is installed
+	PolicyInstalled bool `json:"policyInstalled"`
+	// PolicyVersion is the installed version of the k3s-selinux package, if known
+	PolicyVersion string `json:"policyVersion,omitempty"`
+	// ExpectedPolicyVersion is the minimum version of the k3s-selinux package expected by this release, if known
+	ExpectedPolicyVersion string `json:"expectedPolicyVersion,omitempty"`
+	// ContainerSELinuxVersion is the installed version of the container-selinux package, if known
+	ContainerSELinuxVersion string `json:"containerSELinuxVersion,omitempty"`
+	// ProcessContext is the SELinux type of the current process
+	ProcessContext string `json:"processContext,omitempty"`
+}
+
+// Condition returns whether the SELinux policy is ready for use, along with a reason and message
+// describing the state of the policy. selinuxRequested indicates whether k3s was started with
+// --selinux. The returned reason is empty if SELinux is not enabled on the host.
+func (s *SELinuxStatus) Condition(selinuxRequested bool) (bool, string, string) {
+	policy := version.Program + "-selinux"
+	switch {
+	case !s.Enabled:
+		return false, "", ""
+	case !s.PolicyInstalled:
+		return false, SELinuxReasonNotInstalled, fmt.Sprintf("The %s policy is not installed; install the %s package to run with SELinux enabled", policy, policy)
+	case s.outdated():
+		return false, SELinuxReasonOutdated, fmt.Sprintf("The installed %s policy version %s is older than the expected version %s; upgrade the %s package", policy, s.PolicyVersion, s.ExpectedPolicyVersion, policy)
+	case !selinuxRequested:
+		return false, SELinuxReasonNotEnabled, fmt.Sprintf("SELinux is enabled on this host, but %s has not been started with --selinux", version.Program)
+	case s.ProcessContext != selinuxContextType:
+		return false, SELinuxReasonWrongContext, fmt.Sprintf("%s is running in context %q instead of %q; relabel the %s binary with restorecon and restart", version.Program, s.ProcessContext, selinuxContextType, version.Program)
+	}
+	message := fmt.Sprintf("The %s policy is installed", policy)
+	if s.PolicyVersion != "" {
+		message += " at version " + s.PolicyVersion
+	}
+	return true, SELinuxReasonReady, message
+}
+
+// Results returns the SELinux policy details as check results, for inclusion in the check-config report.
+func (s *SELinuxStatus) Results() []Result {
+	if !s.Enabled {
+		return []Result{{Group: GroupSELinux, Name: "SELinux", Status: StatusPass, Message: "disabled"}}
+	}
+	mode := "permissive"
+	if s.Enforcing {
+		mode = "enforcing"
+	}
+	policy := Result{Group: GroupSELinux, Name: version.Program + "-selinux", Status: StatusPass, Message: versionOrUnknown(s.PolicyVersion)}
+	// The process context is not checked, as it only applies to the running service.
+	switch _, reason, message := s.Condition(true); reason {
+	case SELinuxReasonNotInstalled:
+		policy.Status = StatusFail
+		policy.Message = "not installed"
+		policy.Hint = message
+	case SELinuxReasonOutdated:
+		policy.Status = StatusFail
+		policy.Message = s.PolicyVersion + ", expected " + s.ExpectedPolicyVersion + " or newer"
+		policy.Hint = message
+	}
+	return []Result{
+		{Group: GroupSELinux, Name: "SELinux", Status: StatusPass, Message: "enabled, " + mode + ", policy " + s.PolicyType},
+		{Group: GroupSELinux, Name: "container-selinux", Status: StatusPass, Message: versionOrUnknown(s.ContainerSELinuxVersion)},
+		policy,
+	}
+}
+
+// outdated returns true if the installed policy version is known, and older than the expected version.
+func (s *SELinuxStatus) outdated() bool {
+	if s.PolicyVersion == "" || s.ExpectedPolicyVersion == "" {
+		return false
+	}
+	installed, err := utilversion.ParseGeneric(s.PolicyVersion)
+	if err != nil {
+		return false
+	}
+	expected, err := utilversion.ParseGeneric(s.ExpectedPolicyVersion)
+	if err != nil {
+		return false
+	}
+	return installed.LessThan(expected)
+}
+
+func versionOrUnknown(v string) string {
+	if v == "" {
+		return "unknown version"
+	}
+	return v
+}
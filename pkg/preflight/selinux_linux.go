@@ -0,0 +1,74 @@
+//go:build linux
+// +build linux
+
+package preflight
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/opencontainers/selinux/go-selinux"
+)
+
+// DetectSELinux returns the state of SELinux and the k3s-selinux policy on the host.
+func DetectSELinux() *SELinuxStatus {
+	s := &SELinuxStatus{
+		Enabled:               selinux.GetEnabled(),
+		ExpectedPolicyVersion: version.SELinuxPolicy,
+	}
+	if !s.Enabled {
+		return s
+	}
+	s.Enforcing = selinux.EnforceMode() == selinux.Enforcing
+	s.PolicyType = selinuxPolicyType()
+
+	// policy modules are stored in a directory per priority; the policy package installs at priority 200
+	modules, _ := filepath.Glob(filepath.Join("/var/lib/selinux", s.PolicyType, "active", "modules", "*", version.Program))
+	s.PolicyInstalled = len(modules) > 0
+	s.PolicyVersion = rpmVersion(version.Program + "-selinux")
+	if s.PolicyVersion != "" {
+		s.PolicyInstalled = true
+	}
+	s.ContainerSELinuxVersion = rpmVersion("container-selinux")
+
+	if label, err := selinux.CurrentLabel(); err == nil {
+		if ctx, err := selinux.NewContext(label); err == nil {
+			s.ProcessContext = ctx["type"]
+		}
+	}
+	return s
+}
+
+// selinuxPolicyType returns the configured SELinux policy type, defaulting to targeted.
+func selinuxPolicyType() string {
+	f, err := os.Open("/etc/selinux/config")
+	if err != nil {
+		return "targeted"
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "="); ok && key == "SELINUXTYPE" {
+			return strings.Trim(value, `"'`)
+		}
+	}
+	return "targeted"
+}
+
+// rpmVersion returns the installed version and release of the package, or an empty string if the
+// package is not installed, or the package manager is not rpm-based.
+func rpmVersion(pkg string) string {
+	if !commandExists("rpm") {
+		return ""
+	}
+	out, err := exec.Command("rpm", "-q", "--qf", "%{VERSION}-%{RELEASE}", pkg).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package preflight
+
+import "github.com/k3s-io/k3s/pkg/version"
+
+// DetectSELinux returns the state of SELinux on the host. SELinux is only supported on Linux.
+func DetectSELinux() *SELinuxStatus {
+	return &SELinuxStatus{ExpectedPolicyVersion: version.SELinuxPolicy}
+}
@@ -0,0 +1,83 @@
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func Test_UnitSELinuxCondition(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    SELinuxStatus
+		requested bool
+		wantReady bool
+		want      string
+	}{
+		{
+			name:   "SELinux disabled",
+			status: SELinuxStatus{ExpectedPolicyVersion: "1.2"},
+		},
+		{
+			name:      "Policy not installed",
+			status:    SELinuxStatus{Enabled: true, ExpectedPolicyVersion: "1.2"},
+			requested: true,
+			want:      SELinuxReasonNotInstalled,
+		},
+		{
+			name:      "Policy outdated",
+			status:    SELinuxStatus{Enabled: true, PolicyInstalled: true, PolicyVersion: "1.1-1.el8", ExpectedPolicyVersion: "1.2"},
+			requested: true,
+			want:      SELinuxReasonOutdated,
+		},
+		{
+			name:   "SELinux not requested",
+			status: SELinuxStatus{Enabled: true, PolicyInstalled: true, PolicyVersion: "1.6-1.el9", ExpectedPolicyVersion: "1.2"},
+			want:   SELinuxReasonNotEnabled,
+		},
+		{
+			name:      "Wrong process context",
+			status:    SELinuxStatus{Enabled: true, PolicyInstalled: true, ExpectedPolicyVersion: "1.2", ProcessContext: "unconfined_t"},
+			requested: true,
+			want:      SELinuxReasonWrongContext,
+		},
+		{
+			name:      "Policy ready",
+			status:    SELinuxStatus{Enabled: true, PolicyInstalled: true, PolicyVersion: "1.2-2.el8", ExpectedPolicyVersion: "1.2", ProcessContext: selinuxContextType},
+			requested: true,
+			wantReady: true,
+			want:      SELinuxReasonReady,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, reason, _ := tt.status.Condition(tt.requested)
+			if ready != tt.wantReady || reason != tt.want {
+				t.Errorf("Condition() = %v, %q, want %v, %q", ready, reason, tt.wantReady, tt.want)
+			}
+		})
+	}
+}
+
+func Test_UnitSELinuxPolicyVersion(t *testing.T) {
+	versions := map[string]*regexp.Regexp{
+		"scripts/version.sh": regexp.MustCompile(`(?m)^VERSION_SELINUX_POLICY="([^"]+)"$`),
+		CheckConfigScript:    regexp.MustCompile(`(?m)^selinuxPolicyVersion=(\S+)$`),
+	}
+	found := map[string]string{}
+	for file, re := range versions {
+		b, err := os.ReadFile(filepath.Join("..", "..", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		match := re.FindSubmatch(b)
+		if match == nil {
+			t.Fatalf("SELinux policy version not found in %s", file)
+		}
+		found[file] = string(match[1])
+	}
+	if found["scripts/version.sh"] != found[CheckConfigScript] {
+		t.Errorf("SELinux policy version %s in %s does not match %s in scripts/version.sh", found[CheckConfigScript], CheckConfigScript, found["scripts/version.sh"])
+	}
+}
@@ -0,0 +1,326 @@
+package restart
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
+	"github.com/k3s-io/k3s/pkg/version"
+	appsclient "github.com/rancher/wrangler/v3/pkg/generated/controllers/apps/v1"
+	coreclient "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/record"
+)
+
+var (
+	// ConfigHashAnnotation records the hash of the configuration that a component's pods were last started with.
+	ConfigHashAnnotation = version.Program + ".io/config-hash"
+	// RestartedAtAnnotation is set on a component's pod template to trigger a rolling restart.
+	RestartedAtAnnotation = version.Program + ".io/restartedAt"
+	// RestartAnnotation can be set to "false" on a component's deployment to opt out of restarts after configuration changes.
+	RestartAnnotation = version.Program + ".io/restart-on-config-change"
+)
+
+const (
+	// notReadyRequeue is the delay before checking again if a deployment that needs to be restarted is ready.
+	notReadyRequeue = 15 * time.Second
+	// ConfigChangedEvent is the reason for events recorded when a component is restarted.
+	ConfigChangedEvent = "ConfigChanged"
+)
+
+// ConfigMapKeys identifies keys of a ConfigMap in the kube-system namespace. If no keys are listed, all keys are used.
+type ConfigMapKeys struct {
+	Name string
+	Keys []string
+}
+
+// Component is a packaged component, deployed in the kube-system namespace, and the configuration that
+// it reads at startup. Components are restarted when their configuration changes, and pods that are
+// failing to pull images are deleted when the containerd configuration on their node changes.
+type Component struct {
+	// Manifest is the name of the packaged manifest that deploys the component, as passed to --disable.
+	Manifest   string
+	Deployment string
+	ConfigMaps []ConfigMapKeys
+}
+
+// Components are the packaged components that are restarted after configuration changes. The CoreDNS
+// NodeHosts key is excluded, as CoreDNS reloads it without a restart, and it changes as nodes are added.
+var Components = []Component{
+	{Manifest: "coredns", Deployment: "coredns", ConfigMaps: []ConfigMapKeys{{Name: "coredns", Keys: []string{"Corefile"}}, {Name: "coredns-custom"}}},
+	{Manifest: "local-storage", Deployment: "local-path-provisioner", ConfigMaps: []ConfigMapKeys{{Name: "local-path-config"}}},
+	{Manifest: "metrics-server", Deployment: "metrics-server"},
+	{Manifest: "traefik", Deployment: "traefik"},
+}
+
+// EnabledComponents returns the packaged components whose manifests have not been disabled.
+func EnabledComponents(skips map[string]bool) []Component {
+	components := []Component{}
+	for _, component := range Components {
+		if !skips[component.Manifest] {
+			components = append(components, component)
+		}
+	}
+	return components
+}
+
+// Register registers handlers that restart packaged components when their configuration changes.
+// Handlers are only registered for the resources that the enabled components use, so that caches
+// are not started for resources that are not needed: the ConfigMap and deployment handlers are
+// only registered if an enabled component reads ConfigMaps, and no handlers are registered if no
+// components are enabled.
+func Register(ctx context.Context,
+	components []Component,
+	recorder record.EventRecorder,
+	deployments appsclient.DeploymentController,
+	configMaps coreclient.ConfigMapController,
+	pods coreclient.PodController,
+	nodes coreclient.NodeController,
+) error {
+	if len(components) == 0 {
+		logrus.Info("All packaged components are disabled; not starting component restart controller")
+		return nil
+	}
+	h := &handler{
+		components:       components,
+		recorder:         recorder,
+		deployments:      deployments,
+		configMaps:       configMaps,
+		pods:             pods,
+		containerdHashes: map[string]string{},
+	}
+	if readsConfigMaps(components) {
+		configMaps.OnChange(ctx, "restart-configmap", h.onChangeConfigMap)
+		configMaps.OnRemove(ctx, "restart-configmap", h.onChangeConfigMap)
+		deployments.OnChange(ctx, "restart-deployment", h.onChangeDeployment)
+	}
+	nodes.OnChange(ctx, "restart-node", h.onChangeNode)
+	return nil
+}
+
+// readsConfigMaps returns true if any of the components read configuration from ConfigMaps.
+func readsConfigMaps(components []Component) bool {
+	return slices.ContainsFunc(components, func(c Component) bool { return len(c.ConfigMaps) > 0 })
+}
+
+type handler struct {
+	components  []Component
+	recorder    record.EventRecorder
+	deployments appsclient.DeploymentController
+	configMaps  coreclient.ConfigMapController
+	pods        coreclient.PodController
+
+	mu               sync.Mutex
+	containerdHashes map[string]string
+}
+
+// onChangeConfigMap enqueues the deployments of all components that use a ConfigMap.
+func (h *handler) onChangeConfigMap(key string, cm *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	namespace, name, _ := strings.Cut(key, "/")
+	if namespace != metav1.NamespaceSystem {
+		return cm, nil
+	}
+	for _, component := range h.components {
+		for _, ref := range component.ConfigMaps {
+			if ref.Name == name {
+				h.deployments.Enqueue(metav1.NamespaceSystem, component.Deployment)
+			}
+		}
+	}
+	return cm, nil
+}
+
+// onChangeDeployment restarts a component's deployment if the hash of its configuration has changed.
+func (h *handler) onChangeDeployment(key string, deployment *appsv1.Deployment) (*appsv1.Deployment, error) {
+	if deployment == nil || deployment.Namespace != metav1.NamespaceSystem {
+		return deployment, nil
+	}
+	component := getComponent(h.components, deployment.Name)
+	if component == nil || len(component.ConfigMaps) == 0 {
+		return deployment, nil
+	}
+
+	hash, err := h.configHash(component)
+	if err != nil {
+		return deployment, err
+	}
+	updated, wait := setConfigHash(deployment, hash, time.Now())
+	if wait {
+		logrus.Infof("Waiting for %s rollout to complete before restarting after configuration change", deployment.Name)
+		h.deployments.EnqueueAfter(deployment.Namespace, deployment.Name, notReadyRequeue)
+		return deployment, nil
+	}
+	if updated == nil {
+		return deployment, nil
+	}
+	restart := updated.Spec.Template.Annotations[RestartedAtAnnotation] != deployment.Spec.Template.Annotations[RestartedAtAnnotation]
+	deployment, err = h.deployments.Update(updated)
+	if err != nil {
+		return deployment, err
+	}
+	if restart {
+		logrus.Infof("Restarting %s after configuration change", deployment.Name)
+		h.recorder.Eventf(deployment, corev1.EventTypeNormal, ConfigChangedEvent, "Restarting %s after configuration change", deployment.Name)
+	}
+	return deployment, nil
+}
+
+// onChangeNode deletes component pods on a node that are failing to pull images, when the containerd
+// configuration on the node changes. This allows the pods to be recreated and pull images using the
+// new registry configuration, without waiting for the image pull backoff to expire.
+func (h *handler) onChangeNode(key string, node *corev1.Node) (*corev1.Node, error) {
+	if node == nil {
+		return node, nil
+	}
+	hash := node.Annotations[nodeconfig.ContainerdConfigHashAnnotation]
+	h.mu.Lock()
+	previous, ok := h.containerdHashes[node.Name]
+	h.containerdHashes[node.Name] = hash
+	h.mu.Unlock()
+	if !ok || hash == "" || hash == previous {
+		return node, nil
+	}
+
+	pods, err := h.pods.Cache().List(metav1.NamespaceSystem, labels.Everything())
+	if err != nil {
+		return node, err
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName != node.Name || !isPullingImage(pod) {
+			continue
+		}
+		component := getPodComponent(h.components, pod)
+		if component == nil {
+			continue
+		}
+		if deployment, err := h.deployments.Cache().Get(metav1.NamespaceSystem, component.Deployment); err != nil || deployment.Annotations[RestartAnnotation] == "false" {
+			continue
+		}
+		logrus.Infof("Deleting pod %s/%s after containerd configuration change on node %s", pod.Namespace, pod.Name, node.Name)
+		if err := h.pods.Delete(pod.Namespace, pod.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return node, err
+		}
+	}
+	return node, nil
+}
+
+// configHash returns a hash of the configuration used by a component. ConfigMaps that do not exist are
+// treated as empty, so that creating or deleting an optional ConfigMap is treated as a change.
+func (h *handler) configHash(component *Component) (string, error) {
+	hash := sha256.New()
+	for _, ref := range component.ConfigMaps {
+		cm, err := h.configMaps.Cache().Get(metav1.NamespaceSystem, ref.Name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\n", ref.Name)
+		if cm == nil || apierrors.IsNotFound(err) {
+			continue
+		}
+		keys := ref.Keys
+		if len(keys) == 0 {
+			for key := range cm.Data {
+				keys = append(keys, key)
+			}
+			for key := range cm.BinaryData {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+		}
+		for _, key := range keys {
+			fmt.Fprintf(hash, "%s=%q%x\n", key, cm.Data[key], cm.BinaryData[key])
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// setConfigHash returns a copy of the deployment with the configuration hash recorded, and the pod template
+// annotated to trigger a rolling restart if the hash has changed. Nil is returned if no update is needed.
+// The restart is not triggered until the deployment's current rollout has completed; if the deployment
+// is not ready, wait is true.
+func setConfigHash(deployment *appsv1.Deployment, hash string, now time.Time) (updated *appsv1.Deployment, wait bool) {
+	previous, ok := deployment.Annotations[ConfigHashAnnotation]
+	if previous == hash {
+		return nil, false
+	}
+	// Only record the hash if it has not been recorded before, or restarts are disabled.
+	restart := ok && deployment.Annotations[RestartAnnotation] != "false"
+	if restart && !deploymentReady(deployment) {
+		return nil, true
+	}
+	updated = deployment.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	updated.Annotations[ConfigHashAnnotation] = hash
+	if restart {
+		if updated.Spec.Template.Annotations == nil {
+			updated.Spec.Template.Annotations = map[string]string{}
+		}
+		updated.Spec.Template.Annotations[RestartedAtAnnotation] = now.Format(time.RFC3339)
+	}
+	return updated, false
+}
+
+// deploymentReady returns true if the deployment's most recent rollout has completed, and all replicas are available.
+func deploymentReady(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	return status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas == replicas &&
+		status.AvailableReplicas == replicas &&
+		status.Replicas == replicas
+}
+
+// getPodComponent returns the component whose deployment owns the pod's ReplicaSet, or nil if the pod
+// is not part of a component.
+func getPodComponent(components []Component, pod *corev1.Pod) *Component {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind != "ReplicaSet" {
+			continue
+		}
+		if i := strings.LastIndex(owner.Name, "-"); i > 0 {
+			if component := getComponent(components, owner.Name[:i]); component != nil {
+				return component
+			}
+		}
+	}
+	return nil
+}
+
+// isPullingImage returns true if any of the pod's containers are waiting to pull an image after a failure.
+func isPullingImage(pod *corev1.Pod) bool {
+	for _, status := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+		if waiting := status.State.Waiting; waiting != nil {
+			switch waiting.Reason {
+			case "ErrImagePull", "ImagePullBackOff":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func getComponent(components []Component, name string) *Component {
+	for i := range components {
+		if components[i].Deployment == name {
+			return &components[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package restart
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func newDeployment(annotations map[string]string, ready bool) *appsv1.Deployment {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: metav1.NamespaceSystem, Generation: 2, Annotations: annotations},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](2)},
+		Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+	}
+	if !ready {
+		deployment.Status.AvailableReplicas = 1
+	}
+	return deployment
+}
+
+func Test_UnitSetConfigHash(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		deployment  *appsv1.Deployment
+		wantUpdate  bool
+		wantWait    bool
+		wantRestart bool
+	}{
+		{
+			name:       "hash not recorded",
+			deployment: newDeployment(nil, true),
+			wantUpdate: true,
+		},
+		{
+			name:       "hash unchanged",
+			deployment: newDeployment(map[string]string{ConfigHashAnnotation: "new"}, true),
+		},
+		{
+			name:        "hash changed",
+			deployment:  newDeployment(map[string]string{ConfigHashAnnotation: "old"}, true),
+			wantUpdate:  true,
+			wantRestart: true,
+		},
+		{
+			name:       "hash changed during rollout",
+			deployment: newDeployment(map[string]string{ConfigHashAnnotation: "old"}, false),
+			wantWait:   true,
+		},
+		{
+			name:       "hash changed with restarts disabled",
+			deployment: newDeployment(map[string]string{ConfigHashAnnotation: "old", RestartAnnotation: "false"}, false),
+			wantUpdate: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, wait := setConfigHash(tt.deployment, "new", now)
+			if wait != tt.wantWait {
+				t.Errorf("setConfigHash() wait = %v, want %v", wait, tt.wantWait)
+			}
+			if (updated != nil) != tt.wantUpdate {
+				t.Fatalf("setConfigHash() updated = %v, want update %v", updated, tt.wantUpdate)
+			}
+			if updated == nil {
+				return
+			}
+			if updated.Annotations[ConfigHashAnnotation] != "new" {
+				t.Errorf("expected config hash to be recorded, got %q", updated.Annotations[ConfigHashAnnotation])
+			}
+			if restartedAt, ok := updated.Spec.Template.Annotations[RestartedAtAnnotation]; ok != tt.wantRestart || (ok && restartedAt != now.Format(time.RFC3339)) {
+				t.Errorf("unexpected restart annotation %q, want restart %v", restartedAt, tt.wantRestart)
+			}
+			if tt.deployment.Annotations[ConfigHashAnnotation] == "new" {
+				t.Errorf("original deployment was modified")
+			}
+		})
+	}
+}
+
+func Test_UnitComponentPods(t *testing.T) {
+	pullFailed := corev1.ContainerStatus{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}}
+	running := corev1.ContainerStatus{State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}
+	tests := []struct {
+		name          string
+		owner         metav1.OwnerReference
+		statuses      []corev1.ContainerStatus
+		wantComponent string
+		wantPulling   bool
+	}{
+		{
+			name:          "component pod failing to pull",
+			owner:         metav1.OwnerReference{Kind: "ReplicaSet", Name: "local-path-provisioner-5cf85fd84d"},
+			statuses:      []corev1.ContainerStatus{running, pullFailed},
+			wantComponent: "local-path-provisioner",
+			wantPulling:   true,
+		},
+		{
+			name:          "component pod running",
+			owner:         metav1.OwnerReference{Kind: "ReplicaSet", Name: "coredns-ccb96694c"},
+			statuses:      []corev1.ContainerStatus{running},
+			wantComponent: "coredns",
+		},
+		{
+			name:        "other pod",
+			owner:       metav1.OwnerReference{Kind: "ReplicaSet", Name: "coredns-custom-7d8f9c"},
+			statuses:    []corev1.ContainerStatus{pullFailed},
+			wantPulling: true,
+		},
+		{
+			name:  "daemonset pod",
+			owner: metav1.OwnerReference{Kind: "DaemonSet", Name: "traefik-x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{tt.owner}},
+				Status:     corev1.PodStatus{ContainerStatuses: tt.statuses},
+			}
+			var name string
+			if component := getPodComponent(Components, pod); component != nil {
+				name = component.Deployment
+			}
+			if name != tt.wantComponent {
+				t.Errorf("getPodComponent() = %q, want %q", name, tt.wantComponent)
+			}
+			if got := isPullingImage(pod); got != tt.wantPulling {
+				t.Errorf("isPullingImage() = %v, want %v", got, tt.wantPulling)
+			}
+		})
+	}
+}
+
+func Test_UnitEnabledComponents(t *testing.T) {
+	tests := []struct {
+		name           string
+		skips          map[string]bool
+		want           []string
+		wantConfigMaps bool
+	}{
+		{
+			name:           "none disabled",
+			want:           []string{"coredns", "local-path-provisioner", "metrics-server", "traefik"},
+			wantConfigMaps: true,
+		},
+		{
+			name:           "some disabled",
+			skips:          map[string]bool{"traefik": true, "local-storage": true, "servicelb": true},
+			want:           []string{"coredns", "metrics-server"},
+			wantConfigMaps: true,
+		},
+		{
+			name:  "only components without configmaps",
+			skips: map[string]bool{"coredns": true, "local-storage": true},
+			want:  []string{"metrics-server", "traefik"},
+		},
+		{
+			name:  "all disabled",
+			skips: map[string]bool{"coredns": true, "local-storage": true, "metrics-server": true, "traefik": true},
+			want:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, component := range EnabledComponents(tt.skips) {
+				got = append(got, component.Deployment)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EnabledComponents() = %v, want %v", got, tt.want)
+			}
+			if got := readsConfigMaps(EnabledComponents(tt.skips)); got != tt.wantConfigMaps {
+				t.Errorf("readsConfigMaps() = %v, want %v", got, tt.wantConfigMaps)
+			}
+		})
+	}
+}
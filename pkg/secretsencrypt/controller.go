@@ -0,0 +1,147 @@
+package secretsencrypt
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	apisv1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	controllersv1 "github.com/k3s-io/k3s/pkg/generated/controllers/k3s.cattle.io/v1"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	coreclient "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+)
+
+// StatusName is the name of the ClusterSecretsEncryption object that tracks secrets encryption progress.
+var StatusName = version.Program + "-secrets-encryption"
+
+type statusHandler struct {
+	nodes       coreclient.NodeCache
+	encryptions controllersv1.ClusterSecretsEncryptionClient
+}
+
+// RegisterStatusController registers a controller that maintains the ClusterSecretsEncryption status
+// object, using the encryption hash annotations on the control-plane nodes. Every server runs the
+// controller, so that progress is still reported while some servers are down.
+func RegisterStatusController(ctx context.Context, nodes coreclient.NodeController, encryptions controllersv1.ClusterSecretsEncryptionController) {
+	h := &statusHandler{
+		nodes:       nodes.Cache(),
+		encryptions: encryptions,
+	}
+	logrus.Infof("Starting secrets encryption status controller")
+	nodes.OnChange(ctx, "secrets-encryption-status", h.onChange)
+}
+
+func (h *statusHandler) onChange(key string, node *corev1.Node) (*corev1.Node, error) {
+	if node != nil {
+		if _, ok := node.Labels[util.ControlPlaneRoleLabelKey]; !ok {
+			return node, nil
+		}
+	}
+	return node, h.sync()
+}
+
+// sync updates the status object to reflect the current state of all control-plane nodes.
+func (h *statusHandler) sync() error {
+	selector := labels.Set{util.ControlPlaneRoleLabelKey: "true"}.AsSelector()
+	nodes, err := h.nodes.List(selector)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := h.encryptions.Get(StatusName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			existing = &apisv1.ClusterSecretsEncryption{ObjectMeta: metav1.ObjectMeta{Name: StatusName}}
+		} else if err != nil {
+			return err
+		}
+
+		status := buildStatus(existing.Status, nodes, metav1.Now())
+		if len(status.Nodes) == 0 && existing.UID == "" {
+			// encryption has not been enabled on any server yet
+			return nil
+		}
+		if existing.UID != "" && equality.Semantic.DeepEqual(existing.Status, status) {
+			return nil
+		}
+
+		if existing.UID == "" {
+			if existing, err = h.encryptions.Create(existing); err != nil {
+				return err
+			}
+		}
+		existing = existing.DeepCopy()
+		existing.Status = status
+		_, err = h.encryptions.UpdateStatus(existing)
+		return err
+	})
+}
+
+// buildStatus returns the status for the given control-plane nodes. The phase and key hash that
+// the cluster is converging on are taken from the server that transitioned most recently, as
+// that is the server on which the last secrets-encrypt command was run.
+func buildStatus(prev apisv1.ClusterSecretsEncryptionStatus, nodes []*corev1.Node, now metav1.Time) apisv1.ClusterSecretsEncryptionStatus {
+	prevNodes := map[string]apisv1.SecretsEncryptionNodeStatus{}
+	for _, n := range prev.Nodes {
+		prevNodes[n.Name] = n
+	}
+
+	status := apisv1.ClusterSecretsEncryptionStatus{}
+	for _, node := range nodes {
+		phase, hash, ok := parseEncryptionHashAnnotation(node.Annotations[EncryptionHashAnnotation])
+		if !ok {
+			continue
+		}
+		nodeStatus := apisv1.SecretsEncryptionNodeStatus{
+			Name:               node.Name,
+			Phase:              phase,
+			KeyHash:            hash,
+			LastTransitionTime: now,
+		}
+		if p, ok := prevNodes[node.Name]; ok && p.Phase == phase && p.KeyHash == hash {
+			nodeStatus.LastTransitionTime = p.LastTransitionTime
+		}
+		status.Nodes = append(status.Nodes, nodeStatus)
+	}
+	sort.Slice(status.Nodes, func(i, j int) bool {
+		return status.Nodes[i].Name < status.Nodes[j].Name
+	})
+
+	var latest *apisv1.SecretsEncryptionNodeStatus
+	for i, n := range status.Nodes {
+		if latest == nil || latest.LastTransitionTime.Before(&n.LastTransitionTime) {
+			latest = &status.Nodes[i]
+		}
+	}
+	if latest == nil {
+		return status
+	}
+
+	status.Phase = latest.Phase
+	status.KeyHash = latest.KeyHash
+	status.NodesTotal = len(status.Nodes)
+	for _, n := range status.Nodes {
+		if n.Phase == status.Phase && n.KeyHash == status.KeyHash {
+			status.NodesDone++
+		}
+	}
+	status.Converged = status.NodesDone == status.NodesTotal
+	return status
+}
+
+// parseEncryptionHashAnnotation splits an encryption hash annotation into its stage and hash.
+func parseEncryptionHashAnnotation(ann string) (string, string, bool) {
+	split := strings.Split(ann, "-")
+	if len(split) != 2 {
+		return "", "", false
+	}
+	return split[0], split[1], true
+}
@@ -0,0 +1,85 @@
+package secretsencrypt
+
+import (
+	"testing"
+	"time"
+
+	apisv1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_UnitBuildStatus(t *testing.T) {
+	before := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	newNode := func(name, ann string) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{}}}
+		if ann != "" {
+			node.Annotations[EncryptionHashAnnotation] = ann
+		}
+		return node
+	}
+	prev := apisv1.ClusterSecretsEncryptionStatus{
+		Nodes: []apisv1.SecretsEncryptionNodeStatus{
+			{Name: "server-1", Phase: EncryptionStart, KeyHash: "aaaa", LastTransitionTime: before},
+			{Name: "server-2", Phase: EncryptionStart, KeyHash: "aaaa", LastTransitionTime: before},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		nodes         []*corev1.Node
+		wantPhase     string
+		wantKeyHash   string
+		wantDone      int
+		wantTotal     int
+		wantConverged bool
+	}{
+		{
+			name:          "All servers unchanged",
+			nodes:         []*corev1.Node{newNode("server-2", "start-aaaa"), newNode("server-1", "start-aaaa")},
+			wantPhase:     EncryptionStart,
+			wantKeyHash:   "aaaa",
+			wantDone:      2,
+			wantTotal:     2,
+			wantConverged: true,
+		},
+		{
+			name:        "One server prepared",
+			nodes:       []*corev1.Node{newNode("server-1", "prepare-bbbb"), newNode("server-2", "start-aaaa"), newNode("server-3", "")},
+			wantPhase:   EncryptionPrepare,
+			wantKeyHash: "bbbb",
+			wantDone:    1,
+			wantTotal:   2,
+		},
+		{
+			name:  "Encryption not enabled",
+			nodes: []*corev1.Node{newNode("server-3", ""), newNode("server-4", "invalid")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildStatus(prev, tt.nodes, now)
+			if got.Phase != tt.wantPhase || got.KeyHash != tt.wantKeyHash {
+				t.Errorf("buildStatus() phase = %q, keyHash = %q, want %q, %q", got.Phase, got.KeyHash, tt.wantPhase, tt.wantKeyHash)
+			}
+			if got.NodesDone != tt.wantDone || got.NodesTotal != tt.wantTotal || got.Converged != tt.wantConverged {
+				t.Errorf("buildStatus() done = %d/%d, converged = %v, want %d/%d, %v", got.NodesDone, got.NodesTotal, got.Converged, tt.wantDone, tt.wantTotal, tt.wantConverged)
+			}
+			for i, n := range got.Nodes {
+				if i > 0 && got.Nodes[i-1].Name > n.Name {
+					t.Errorf("buildStatus() nodes are not sorted: %v", got.Nodes)
+				}
+				wantTime := now
+				for _, p := range prev.Nodes {
+					if p.Name == n.Name && p.Phase == n.Phase && p.KeyHash == n.KeyHash {
+						wantTime = before
+					}
+				}
+				if !n.LastTransitionTime.Equal(&wantTime) {
+					t.Errorf("buildStatus() node %s lastTransitionTime = %v, want %v", n.Name, n.LastTransitionTime, wantTime)
+				}
+			}
+		})
+	}
+}
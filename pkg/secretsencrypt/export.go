@@ -0,0 +1,90 @@
+package secretsencrypt
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/k3s-io/k3s/pkg/cluster"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	apiserverconfigv1 "k8s.io/apiserver/pkg/apis/apiserver/v1"
+)
+
+// EncryptionExport contains the secrets encryption config and rotation state of a server, so that
+// encrypted secrets can be recovered if the server's data directory is lost. The config is stored
+// as a string rather than embedded, so that its content and hash are preserved exactly.
+type EncryptionExport struct {
+	EncryptionConfig string `json:"encryptionConfig"`
+	EncryptionState  string `json:"encryptionState,omitempty"`
+}
+
+// ExportEncryptionConfig returns the server's secrets encryption config and rotation state. If a
+// passphrase is provided, the export is encrypted using the same scheme as the bootstrap data.
+func ExportEncryptionConfig(runtime *config.ControlRuntime, passphrase string) ([]byte, error) {
+	encryptionConfig, err := os.ReadFile(runtime.EncryptionConfig)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("secrets encryption config not found; secrets encryption may not be enabled")
+		}
+		return nil, err
+	}
+	export := EncryptionExport{EncryptionConfig: string(encryptionConfig)}
+	if state, err := getEncryptionHashFile(runtime); err == nil {
+		export.EncryptionState = state
+	}
+
+	b, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return append(b, '\n'), nil
+	}
+	return cluster.Encrypt(passphrase, b)
+}
+
+// ImportEncryptionConfig writes an exported secrets encryption config and rotation state to the
+// server's data directory. An existing config is only replaced if force is set.
+func ImportEncryptionConfig(runtime *config.ControlRuntime, data []byte, passphrase string, force bool) error {
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte("{")) {
+		if passphrase == "" {
+			return errors.New("export is passphrase-protected, but no passphrase was provided")
+		}
+		decrypted, err := cluster.Decrypt(passphrase, data)
+		if err != nil {
+			return errors.Wrap(err, "failed to decrypt export; check that the passphrase is correct")
+		}
+		data = decrypted
+	}
+
+	export := EncryptionExport{}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return errors.Wrap(err, "failed to parse export")
+	}
+	encConfig := apiserverconfigv1.EncryptionConfiguration{}
+	if err := json.Unmarshal([]byte(export.EncryptionConfig), &encConfig); err != nil || encConfig.Kind != "EncryptionConfiguration" {
+		return errors.New("export does not contain a valid encryption configuration")
+	}
+
+	if existing, err := os.ReadFile(runtime.EncryptionConfig); err == nil && !force {
+		if string(existing) == export.EncryptionConfig {
+			return nil
+		}
+		return errors.Errorf("secrets encryption config %s already exists; use --force to replace it", runtime.EncryptionConfig)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(runtime.EncryptionConfig), 0700); err != nil {
+		return err
+	}
+	if err := util.AtomicWrite(runtime.EncryptionConfig, []byte(export.EncryptionConfig), 0600); err != nil {
+		return err
+	}
+	if export.EncryptionState != "" {
+		return util.AtomicWrite(runtime.EncryptionHash, []byte(export.EncryptionState), 0600)
+	}
+	return nil
+}
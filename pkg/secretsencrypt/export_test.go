@@ -0,0 +1,65 @@
+package secretsencrypt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	apiserverconfigv1 "k8s.io/apiserver/pkg/apis/apiserver/v1"
+)
+
+func Test_UnitExportImportEncryptionConfig(t *testing.T) {
+	newRuntime := func(dir string) *config.ControlRuntime {
+		runtime := &config.ControlRuntime{}
+		runtime.EncryptionConfig = filepath.Join(dir, "cred", "encryption-config.json")
+		runtime.EncryptionHash = filepath.Join(dir, "cred", "encryption-state.json")
+		return runtime
+	}
+
+	source := newRuntime(t.TempDir())
+	os.MkdirAll(filepath.Dir(source.EncryptionConfig), 0700)
+	keys := []apiserverconfigv1.Key{{Name: "aescbckey", Secret: "c2VjcmV0c2VjcmV0c2VjcmV0c2VjcmV0c2VjcmV0MTI="}}
+	if err := WriteEncryptionConfig(source, keys, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(source.EncryptionHash, []byte("start-abc123"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := os.ReadFile(source.EncryptionConfig)
+
+	for _, passphrase := range []string{"", "correct horse battery staple"} {
+		export, err := ExportEncryptionConfig(source, passphrase)
+		if err != nil {
+			t.Fatalf("ExportEncryptionConfig() error = %v", err)
+		}
+		if passphrase != "" && bytes.Contains(export, []byte("aescbckey")) {
+			t.Errorf("ExportEncryptionConfig() with passphrase contains plaintext key name")
+		}
+
+		target := newRuntime(t.TempDir())
+		if passphrase != "" {
+			if err := ImportEncryptionConfig(target, export, "wrong", false); err == nil {
+				t.Errorf("ImportEncryptionConfig() with wrong passphrase error = nil, want error")
+			}
+		}
+		if err := ImportEncryptionConfig(target, export, passphrase, false); err != nil {
+			t.Fatalf("ImportEncryptionConfig() error = %v", err)
+		}
+		if got, _ := os.ReadFile(target.EncryptionConfig); !bytes.Equal(got, want) {
+			t.Errorf("ImportEncryptionConfig() wrote config %s, want %s", got, want)
+		}
+		if got, _ := os.ReadFile(target.EncryptionHash); string(got) != "start-abc123" {
+			t.Errorf("ImportEncryptionConfig() wrote state %q, want %q", got, "start-abc123")
+		}
+
+		os.WriteFile(target.EncryptionConfig, []byte("{}"), 0600)
+		if err := ImportEncryptionConfig(target, export, passphrase, false); err == nil {
+			t.Errorf("ImportEncryptionConfig() over existing config error = nil, want error")
+		}
+		if err := ImportEncryptionConfig(target, export, passphrase, true); err != nil {
+			t.Errorf("ImportEncryptionConfig() with force error = %v", err)
+		}
+	}
+}
@@ -6,10 +6,16 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/daemons/control/deps"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/request/headerrequest"
+	"k8s.io/apiserver/pkg/authentication/request/x509"
+	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
 )
 
 func hasRole(mustRoles []string, roles []string) bool {
@@ -36,12 +42,30 @@ func doAuth(roles []string, serverConfig *config.Control, next http.Handler, rw
 		return
 	}
 
+	// Attempts to authenticate with a token or password are limited by client address before the
+	// credentials are checked, so that tokens cannot be guessed by brute force.
+	addr := clientAddress(req)
+	l := getLockout(serverConfig)
+	if l != nil && (isLoopback(addr) || !hasCredentials(req)) {
+		l = nil
+	}
+	if l != nil && rejectLimited(l, addr, rw, req) {
+		return
+	}
+
 	resp, ok, err := serverConfig.Runtime.Authenticator.AuthenticateRequest(req)
 	if err != nil {
-		logrus.Errorf("Failed to authenticate request from %s: %v", req.RemoteAddr, err)
+		username, _, _ := req.BasicAuth()
+		logrus.Errorf("Failed to authenticate request from %s for user %q to %s: %v", addr, username, req.URL.Path, err)
+		if l != nil {
+			l.failed(addr)
+		}
 		util.SendError(errors.New("not authorized"), rw, req, http.StatusUnauthorized)
 		return
 	}
+	if ok && l != nil {
+		l.succeeded(addr)
+	}
 
 	if !ok || !hasRole(roles, resp.User.GetGroups()) {
 		util.SendError(errors.New("forbidden"), rw, req, http.StatusForbidden)
@@ -77,3 +101,46 @@ func IsLocalOrHasRole(serverConfig *config.Control, roles ...string) mux.Middlew
 		})
 	}
 }
+
+// IsAggregated returns a middleware function that validates that the request was proxied by
+// the apiserver's aggregation layer, and adds the user from the request headers to the request
+// context. The apiserver authorizes requests before proxying them to an aggregated API. Requests
+// that were not proxied by the aggregation layer are passed to the fallback handler, so that
+// clients reach the aggregated API through the apiserver.
+func IsAggregated(serverConfig *config.Control, fallback http.Handler) mux.MiddlewareFunc {
+	var verifier authenticator.Request
+	if serverConfig != nil && serverConfig.Runtime != nil && serverConfig.Runtime.RequestHeaderCA != "" {
+		ca, err := dynamiccertificates.NewDynamicCAContentFromFile("request-header-ca", serverConfig.Runtime.RequestHeaderCA)
+		if err != nil {
+			logrus.Errorf("Failed to load request header CA, aggregated API requests will not be handled: %v", err)
+		} else {
+			verifier = x509.NewDynamicCAVerifier(ca.VerifyOptions, authenticator.RequestFunc(requestHeaderUser), headerrequest.StaticStringSlice{deps.RequestHeaderCN})
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if verifier == nil || req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+				fallback.ServeHTTP(rw, req)
+				return
+			}
+			resp, ok, err := verifier.AuthenticateRequest(req)
+			if err != nil || !ok {
+				fallback.ServeHTTP(rw, req)
+				return
+			}
+			ctx := request.WithUser(req.Context(), resp.User)
+			next.ServeHTTP(rw, req.WithContext(ctx))
+		})
+	}
+}
+
+// requestHeaderUser returns the user set in the request headers by the aggregation layer. Requests
+// made by the aggregation layer on its own behalf, such as availability checks, do not set a user.
+func requestHeaderUser(req *http.Request) (*authenticator.Response, bool, error) {
+	return &authenticator.Response{
+		User: &user.DefaultInfo{
+			Name:   req.Header.Get("X-Remote-User"),
+			Groups: req.Header.Values("X-Remote-Group"),
+		},
+	}, true, nil
+}
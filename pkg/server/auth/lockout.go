@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/metrics"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var (
+	authFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: version.Program + "_supervisor_auth_failures_total",
+		Help: "Total supervisor requests with invalid credentials",
+	})
+
+	authLockouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: version.Program + "_supervisor_auth_lockouts_total",
+		Help: "Total times a client address was locked out after repeated supervisor authentication failures",
+	})
+
+	authRejected = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: version.Program + "_supervisor_auth_rejected_total",
+		Help: "Total supervisor requests presenting credentials that were rejected before authentication because the client address was locked out or rate limited",
+	})
+
+	registerMetrics sync.Once
+
+	// lockouts holds the lockout state for each server config, so that it is shared by all of the
+	// middleware functions that authenticate requests for the same server.
+	lockouts sync.Map
+)
+
+const (
+	// authRate and authBurst limit the rate at which requests presenting credentials are accepted
+	// from a single client address. The burst allows a number of agents behind a shared address to
+	// join at once, as each makes several requests while starting.
+	authRate  = rate.Limit(10)
+	authBurst = 50
+)
+
+// lockout limits authentication attempts by client address, for requests that present a token or
+// password. Attempts are rate limited, and once an address has failed to authenticate maxFailures
+// times within the lockout period, all further attempts from that address are rejected as too many
+// requests until the period has passed. Attempts are rejected before credentials are checked, so that
+// a locked out client cannot continue guessing tokens. Requests authenticated by client certificate,
+// and requests from loopback addresses, are not limited.
+type lockout struct {
+	mu          sync.Mutex
+	maxFailures int
+	period      time.Duration
+	addresses   map[string]*address
+	lastPrune   time.Time
+	now         func() time.Time
+	// onLockout is called without the lock held when an address is locked out.
+	onLockout func(addr string, failures int)
+}
+
+type address struct {
+	limiter     *rate.Limiter
+	lastSeen    time.Time
+	failures    int
+	since       time.Time
+	lockedUntil time.Time
+}
+
+func newLockout(maxFailures int, period time.Duration) *lockout {
+	return &lockout{
+		maxFailures: maxFailures,
+		period:      period,
+		addresses:   map[string]*address{},
+		now:         time.Now,
+	}
+}
+
+// getLockout returns the lockout state for the server config, or nil if lockout is disabled.
+func getLockout(serverConfig *config.Control) *lockout {
+	if serverConfig.SupervisorAuthMaxFailures <= 0 || serverConfig.SupervisorAuthLockout <= 0 {
+		return nil
+	}
+	if l, ok := lockouts.Load(serverConfig); ok {
+		return l.(*lockout)
+	}
+	registerMetrics.Do(func() {
+		metrics.DefaultRegisterer.MustRegister(authFailures, authLockouts, authRejected)
+	})
+	l := newLockout(serverConfig.SupervisorAuthMaxFailures, serverConfig.SupervisorAuthLockout)
+	l.onLockout = func(addr string, failures int) {
+		logrus.Warnf("Locked out %s for %s after %d failed supervisor authentication attempts", addr, l.period, failures)
+		if serverConfig.Runtime != nil && serverConfig.Runtime.Event != nil && serverConfig.ServerNodeName != "" {
+			nodeRef := &corev1.ObjectReference{
+				Kind: "Node",
+				Name: serverConfig.ServerNodeName,
+				UID:  types.UID(serverConfig.ServerNodeName),
+			}
+			serverConfig.Runtime.Event.Eventf(nodeRef, corev1.EventTypeWarning, "SupervisorAuthLockout", "Locked out %s for %s after %d failed supervisor authentication attempts", addr, l.period, failures)
+		}
+	}
+	actual, _ := lockouts.LoadOrStore(serverConfig, l)
+	return actual.(*lockout)
+}
+
+// allow records an authentication attempt from the address. If the address is locked out or has
+// exceeded the attempt rate, the time after which it may try again is returned, along with false.
+func (l *lockout) allow(addr string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.prune(now)
+	a, ok := l.addresses[addr]
+	if !ok {
+		a = &address{limiter: rate.NewLimiter(authRate, authBurst)}
+		l.addresses[addr] = a
+	}
+	a.lastSeen = now
+	if remaining := a.lockedUntil.Sub(now); remaining > 0 {
+		return remaining, false
+	}
+	if !a.limiter.AllowN(now, 1) {
+		r := a.limiter.ReserveN(now, 1)
+		defer r.CancelAt(now)
+		return r.DelayFrom(now), false
+	}
+	return 0, true
+}
+
+// failed records a failed authentication attempt from the address, locking it out if it has reached
+// the maximum number of failures within the lockout period.
+func (l *lockout) failed(addr string) {
+	l.mu.Lock()
+	now := l.now()
+	a, ok := l.addresses[addr]
+	if !ok {
+		a = &address{limiter: rate.NewLimiter(authRate, authBurst), lastSeen: now}
+		l.addresses[addr] = a
+	}
+	if a.failures == 0 || now.Sub(a.since) > l.period {
+		a.failures = 0
+		a.since = now
+	}
+	a.failures++
+	failures := a.failures
+	lockedOut := failures == l.maxFailures
+	if lockedOut {
+		a.lockedUntil = now.Add(l.period)
+		a.failures = 0
+	}
+	l.mu.Unlock()
+
+	authFailures.Inc()
+	if lockedOut {
+		authLockouts.Inc()
+		if l.onLockout != nil {
+			l.onLockout(addr, failures)
+		}
+	}
+}
+
+// succeeded clears failed authentication attempts from the address.
+func (l *lockout) succeeded(addr string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if a, ok := l.addresses[addr]; ok {
+		a.failures = 0
+	}
+}
+
+// prune removes addresses that have not been seen for a lockout period and are not locked out, at
+// most once per lockout period. The caller must hold the lock.
+func (l *lockout) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < l.period {
+		return
+	}
+	l.lastPrune = now
+	for addr, a := range l.addresses {
+		if now.Sub(a.lastSeen) > l.period && !a.lockedUntil.After(now) {
+			delete(l.addresses, addr)
+		}
+	}
+}
+
+// rejectLimited sends an error if the client address is locked out or rate limited, and returns true if
+// the request was rejected. It must be called before the request is authenticated.
+func rejectLimited(l *lockout, addr string, rw http.ResponseWriter, req *http.Request) bool {
+	retryAfter, ok := l.allow(addr)
+	if ok {
+		return false
+	}
+	authRejected.Inc()
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	rw.Header().Set("Retry-After", strconv.Itoa(seconds))
+	util.SendError(errors.New("too many authentication attempts"), rw, req, http.StatusTooManyRequests)
+	return true
+}
+
+// hasCredentials returns true if the request presents a password or token, which could be guessed.
+func hasCredentials(req *http.Request) bool {
+	authorization := req.Header.Get("Authorization")
+	return strings.HasPrefix(authorization, "Basic ") || strings.HasPrefix(authorization, "Bearer ")
+}
+
+// clientAddress returns the address used to track failed authentication attempts from the request.
+func clientAddress(req *http.Request) string {
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+	return req.RemoteAddr
+}
+
+// isLoopback returns true if the address is a loopback address. Local clients, such as the server's own
+// agent and the CLI, are never limited.
+func isLoopback(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func Test_UnitLockout(t *testing.T) {
+	now := time.Now()
+	var lockedOut []string
+	l := newLockout(3, time.Minute)
+	l.now = func() time.Time { return now }
+	l.onLockout = func(addr string, failures int) {
+		lockedOut = append(lockedOut, addr)
+	}
+
+	l.failed("192.0.2.1")
+	l.failed("192.0.2.1")
+	if _, ok := l.allow("192.0.2.1"); !ok {
+		t.Fatal("address locked out before reaching max failures")
+	}
+	// successful authentication clears failures
+	l.succeeded("192.0.2.1")
+	l.failed("192.0.2.1")
+	l.failed("192.0.2.1")
+	if _, ok := l.allow("192.0.2.1"); !ok {
+		t.Fatal("address locked out after failures were cleared")
+	}
+
+	l.failed("192.0.2.1")
+	if remaining, ok := l.allow("192.0.2.1"); ok || remaining != time.Minute {
+		t.Fatalf("allow() = %s, %v, want 1m0s, false", remaining, ok)
+	}
+	if len(lockedOut) != 1 || lockedOut[0] != "192.0.2.1" {
+		t.Errorf("onLockout called for %v, want [192.0.2.1]", lockedOut)
+	}
+	if _, ok := l.allow("192.0.2.2"); !ok {
+		t.Error("other address is locked out")
+	}
+
+	// requests from locked out addresses are rejected as too many requests
+	rw := httptest.NewRecorder()
+	if !rejectLimited(l, "192.0.2.1", rw, httptest.NewRequest(http.MethodGet, "/v1-k3s/server-bootstrap", nil)) {
+		t.Fatal("rejectLimited() = false, want true")
+	}
+	if rw.Code != http.StatusTooManyRequests || rw.Header().Get("Retry-After") != "60" {
+		t.Errorf("response = %d with Retry-After %q, want 429 with Retry-After 60", rw.Code, rw.Header().Get("Retry-After"))
+	}
+
+	// successful authentication does not clear a lockout
+	l.succeeded("192.0.2.1")
+	if _, ok := l.allow("192.0.2.1"); ok {
+		t.Error("lockout was cleared by successful authentication")
+	}
+
+	// lockouts expire, and idle addresses are pruned
+	now = now.Add(time.Minute + time.Second)
+	if _, ok := l.allow("192.0.2.1"); !ok {
+		t.Error("address still locked out after lockout period")
+	}
+	now = now.Add(time.Minute + time.Second)
+	l.allow("192.0.2.3")
+	if _, ok := l.addresses["192.0.2.1"]; ok {
+		t.Error("idle address was not pruned")
+	}
+}
+
+func Test_UnitLockoutRate(t *testing.T) {
+	now := time.Now()
+	l := newLockout(3, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < authBurst; i++ {
+		if _, ok := l.allow("192.0.2.1"); !ok {
+			t.Fatalf("attempt %d within burst was rate limited", i)
+		}
+	}
+	if retryAfter, ok := l.allow("192.0.2.1"); ok || retryAfter <= 0 {
+		t.Fatalf("allow() after burst = %s, %v, want delay, false", retryAfter, ok)
+	}
+	if _, ok := l.allow("192.0.2.2"); !ok {
+		t.Error("other address is rate limited")
+	}
+	now = now.Add(time.Second)
+	if _, ok := l.allow("192.0.2.1"); !ok {
+		t.Error("address still rate limited after tokens were replenished")
+	}
+}
+
+func Test_UnitLockoutCredentials(t *testing.T) {
+	serverConfig := &config.Control{
+		SupervisorAuthMaxFailures: 1,
+		SupervisorAuthLockout:     time.Minute,
+		Runtime:                   &config.ControlRuntime{},
+	}
+	serverConfig.Runtime.Authenticator = authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+		if _, password, _ := req.BasicAuth(); password != "token" {
+			return nil, false, errors.New("invalid token")
+		}
+		return &authenticator.Response{User: &user.DefaultInfo{Name: "node", Groups: []string{"k3s:agent"}}}, true, nil
+	})
+	handler := HasRole(serverConfig, "k3s:agent")(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	doRequest := func(password string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1-k3s/config", nil)
+		req.RemoteAddr = "192.0.2.1:12345"
+		req.SetBasicAuth("node", password)
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req)
+		return rw.Code
+	}
+
+	if code := doRequest("wrong"); code != http.StatusUnauthorized {
+		t.Errorf("first invalid request = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := doRequest("wrong"); code != http.StatusTooManyRequests {
+		t.Errorf("invalid request while locked out = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	// valid credentials from a locked out address are also rejected, as they are not checked
+	if code := doRequest("token"); code != http.StatusTooManyRequests {
+		t.Errorf("valid request while locked out = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	// requests that do not present a token or password are not limited
+	req := httptest.NewRequest(http.MethodGet, "/v1-k3s/config", nil)
+	req.RemoteAddr = "192.0.2.1:12345"
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	if rw.Code == http.StatusTooManyRequests {
+		t.Errorf("request without credentials while locked out = %d, want not %d", rw.Code, http.StatusTooManyRequests)
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1alpha1"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/util/services"
+	"github.com/pkg/errors"
+	certutil "github.com/rancher/dynamiclistener/cert"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// APIService returns a handler for requests to the supervisor's aggregated API, that have been
+// proxied to the supervisor by the apiserver's aggregation layer. ETCDSnapshot resources are
+// handled by the etcd request handler, when etcd is in use.
+func APIService(ctx context.Context, control *config.Control) http.Handler {
+	prefix := "/apis/" + v1alpha1.SchemeGroupVersion.String()
+	r := mux.NewRouter().SkipClean(true)
+	r.NotFoundHandler = http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		util.SendError(fmt.Errorf("the server could not find the requested resource"), resp, req, http.StatusNotFound)
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		util.SendError(fmt.Errorf("method not allowed"), resp, req, http.StatusMethodNotAllowed)
+	})
+	r.Path(prefix).Methods(http.MethodGet).Handler(apiResources())
+	r.Path(prefix + "/etcdsnapshots").Handler(etcdDisabled())
+	r.Path(prefix + "/etcdsnapshots/{name}").Handler(etcdDisabled())
+	r.Path(prefix + "/tokenrotations").Methods(http.MethodPost).Handler(tokenRotations(ctx, control))
+	r.Path(prefix + "/certificatechecks").Methods(http.MethodGet).Handler(certificateChecks(control))
+	r.Path(prefix + "/certificatechecks/{name}").Methods(http.MethodGet).Handler(certificateChecks(control))
+	return r
+}
+
+// apiResources returns the resources served by the aggregated API, for legacy discovery. Aggregated
+// discovery is not supported; requests for it fall through to the not found handler, and the
+// aggregation layer falls back to legacy discovery.
+func apiResources() http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		sendAPIObject(resp, req, http.StatusOK, &metav1.APIResourceList{
+			TypeMeta:     metav1.TypeMeta{APIVersion: "v1", Kind: "APIResourceList"},
+			GroupVersion: v1alpha1.SchemeGroupVersion.String(),
+			APIResources: []metav1.APIResource{
+				{Name: "certificatechecks", SingularName: "certificatecheck", Kind: "CertificateCheck", Verbs: metav1.Verbs{"get", "list"}},
+				{Name: "etcdsnapshots", SingularName: "etcdsnapshot", Kind: "ETCDSnapshot", Verbs: metav1.Verbs{"create", "delete", "get", "list"}},
+				{Name: "tokenrotations", SingularName: "tokenrotation", Kind: "TokenRotation", Verbs: metav1.Verbs{"create"}},
+			},
+		})
+	})
+}
+
+// etcdDisabled handles ETCDSnapshot requests when they are not handled by the etcd request handler.
+func etcdDisabled() http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		util.SendError(errors.New("etcd datastore disabled"), resp, req, http.StatusBadRequest)
+	})
+}
+
+// tokenRotations rotates the server token, as the token rotate command does.
+func tokenRotations(ctx context.Context, control *config.Control) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if util.RejectDryRun(resp, req) {
+			return
+		}
+		rotation := &v1alpha1.TokenRotation{}
+		if err := json.NewDecoder(req.Body).Decode(rotation); err != nil {
+			util.SendError(err, resp, req, http.StatusBadRequest)
+			return
+		}
+		if err := tokenRotate(ctx, control, rotation.Spec.NewToken); err != nil {
+			util.SendErrorWithID(err, "token", resp, req, http.StatusInternalServerError)
+			return
+		}
+		logrus.Infof("Server token rotated by %s", requestUser(req))
+
+		// The new token is not returned to the client.
+		rotation.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "TokenRotation"}
+		rotation.Spec.NewToken = ""
+		if rotation.Name == "" {
+			rotation.Name = rotation.GenerateName
+		}
+		rotation.CreationTimestamp = metav1.Now()
+		sendAPIObject(resp, req, http.StatusCreated, rotation)
+	})
+}
+
+// certificateChecks returns the status of the certificates used by components on this server,
+// as the certificate check command does.
+func certificateChecks(control *config.Control) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		checks, err := getCertificateChecks(control, time.Now())
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		name, ok := mux.Vars(req)["name"]
+		if !ok {
+			sendAPIObject(resp, req, http.StatusOK, &v1alpha1.CertificateCheckList{
+				TypeMeta: metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "CertificateCheckList"},
+				Items:    checks,
+			})
+			return
+		}
+		for _, check := range checks {
+			if check.Name == name {
+				sendAPIObject(resp, req, http.StatusOK, &check)
+				return
+			}
+		}
+		util.SendError(fmt.Errorf("certificatechecks.%s %q not found", v1alpha1.SchemeGroupVersion.Group, name), resp, req, http.StatusNotFound)
+	})
+}
+
+// getCertificateChecks returns the status of all certificates used by server and agent components,
+// and the cluster certificate authorities. Checks are named for the service and file; if a file contains
+// more than one certificate, the index of the certificate within the file is appended to the name.
+func getCertificateChecks(control *config.Control, now time.Time) ([]v1alpha1.CertificateCheck, error) {
+	fileMap, err := services.FilesForServices(*control, append(services.All, services.CA...))
+	if err != nil {
+		return nil, err
+	}
+	warn := now.Add(time.Hour * 24 * config.CertificateRenewDays)
+	checks := []v1alpha1.CertificateCheck{}
+	for service, files := range fileMap {
+		for _, file := range files {
+			// ignore errors, as some files may not exist, or may not contain certs.
+			certs, _ := certutil.CertsFromFile(file)
+			for i, cert := range certs {
+				name := service + "." + strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+				if i > 0 {
+					name += "-" + strconv.Itoa(i)
+				}
+				var status string
+				if now.Before(cert.NotBefore) {
+					status = "NOT YET VALID"
+				} else if now.After(cert.NotAfter) {
+					status = "EXPIRED"
+				} else if warn.After(cert.NotAfter) {
+					status = "WARNING"
+				} else {
+					status = "OK"
+				}
+				checks = append(checks, v1alpha1.CertificateCheck{
+					TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "CertificateCheck"},
+					ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(cert.NotBefore)},
+					Status: v1alpha1.CertificateCheckStatus{
+						NodeName:  control.ServerNodeName,
+						Service:   service,
+						File:      file,
+						Subject:   cert.Subject.String(),
+						NotBefore: metav1.NewTime(cert.NotBefore),
+						NotAfter:  metav1.NewTime(cert.NotAfter),
+						Status:    status,
+					},
+				})
+			}
+		}
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Name < checks[j].Name })
+	return checks, nil
+}
+
+// requestUser returns the name of the user that made the request, as set by the aggregation layer.
+func requestUser(req *http.Request) string {
+	if user, ok := request.UserFrom(req.Context()); ok && user.GetName() != "" {
+		return user.GetName()
+	}
+	return "unknown user"
+}
+
+func sendAPIObject(resp http.ResponseWriter, req *http.Request, code int, obj any) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		util.SendError(err, resp, req, http.StatusInternalServerError)
+		return
+	}
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(code)
+	resp.Write(b)
+}
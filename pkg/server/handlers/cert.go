@@ -16,9 +16,11 @@ import (
 	"strings"
 
 	"github.com/k3s-io/k3s/pkg/bootstrap"
+	"github.com/k3s-io/k3s/pkg/carotation"
 	"github.com/k3s-io/k3s/pkg/cluster"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/daemons/control/deps"
+	"github.com/k3s-io/k3s/pkg/nodeconfig"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/pkg/errors"
@@ -35,11 +37,20 @@ func CACertReplace(control *config.Control) http.HandlerFunc {
 			return
 		}
 		force, _ := strconv.ParseBool(req.FormValue("force"))
-		if err := caCertReplace(control, req.Body, force); err != nil {
+		phase := req.FormValue("phase")
+		if phase != "" && !carotation.IsValidPhase(phase) {
+			util.SendError(fmt.Errorf("invalid CA rotation phase %s: must be one of %s", phase, strings.Join(carotation.Phases, ", ")), resp, req, http.StatusBadRequest)
+			return
+		}
+		if err := caCertReplace(control, req.Body, force, phase); err != nil {
 			util.SendErrorWithID(err, "certificate", resp, req, http.StatusInternalServerError)
 			return
 		}
-		logrus.Infof("certificate: Cluster Certificate Authority data has been updated, %s must be restarted.", version.Program)
+		if phase != "" {
+			logrus.Infof("certificate: Cluster Certificate Authority %s phase has been saved, %s must be restarted.", phase, version.Program)
+		} else {
+			logrus.Infof("certificate: Cluster Certificate Authority data has been updated, %s must be restarted.", version.Program)
+		}
 		resp.WriteHeader(http.StatusNoContent)
 	})
 }
@@ -48,7 +59,9 @@ func CACertReplace(control *config.Control) http.HandlerFunc {
 // validated to confirm that the new certs share a common root with the existing certs, and if so are saved to
 // the datastore.  If the functions succeeds, servers should be restarted immediately to load the new certs
 // from the bootstrap data.
-func caCertReplace(control *config.Control, buf io.ReadCloser, force bool) error {
+// If a phase is set, the new CA certs are instead combined with the existing certs as appropriate for
+// that phase of a phased rotation, and the phase is recorded in the CA rotation status object.
+func caCertReplace(control *config.Control, buf io.ReadCloser, force bool, phase string) error {
 	tmpdir, err := os.MkdirTemp(control.DataDir, ".rotate-ca-tmp-")
 	if err != nil {
 		return err
@@ -79,6 +92,10 @@ func caCertReplace(control *config.Control, buf io.ReadCloser, force bool) error
 		return errors.Wrap(err, "failed to set default bootstrap values")
 	}
 
+	if phase != "" {
+		return caCertReplacePhase(control, tmpControl, force, phase)
+	}
+
 	if err := validateBootstrap(control, tmpControl); err != nil {
 		if !force {
 			return errors.Wrap(err, "failed to validate new CA certificates and keys")
@@ -89,6 +106,119 @@ func caCertReplace(control *config.Control, buf io.ReadCloser, force bool) error
 	return cluster.Save(context.TODO(), tmpControl, true)
 }
 
+// caCertReplacePhase applies a phase of a phased CA rotation. The new CA certs are combined with the
+// current certs, so that both CAs are trusted until the old CA is retired. Unlike a full replacement,
+// the new CA certs do not need to be signed by the current CA.
+func caCertReplacePhase(control, tmpControl *config.Control, force bool, phase string) error {
+	if control.Runtime.K3s == nil {
+		return errors.New("CA rotation status is not available until the apiserver is ready")
+	}
+	rotations := control.Runtime.K3s.K3s().V1().ClusterCARotation()
+
+	if err := carotation.CheckConverged(rotations, phase); err != nil {
+		if !force {
+			return errors.Wrap(err, "not all nodes are ready for the next CA rotation phase")
+		}
+		logrus.Warnf("CA rotation %s phase forced, ignoring node status: %v", phase, err)
+	}
+
+	if err := composeBootstrap(control, tmpControl, phase); err != nil {
+		if !force {
+			return errors.Wrap(err, "failed to validate new CA certificates and keys")
+		}
+		logrus.Warnf("Save of CA certificates and keys forced, ignoring validation errors: %v", err)
+	}
+
+	if err := cluster.Save(context.TODO(), tmpControl, true); err != nil {
+		return err
+	}
+
+	serverCA, err := os.ReadFile(tmpControl.Runtime.ServerCA)
+	if err != nil {
+		return err
+	}
+	// Once the new CA is signing, nodes must re-issue their client certificates and kubelet serving
+	// certificates with it before the old CA can be retired.
+	var issuerHash, servingIssuerHash string
+	if phase != carotation.PhaseTrust {
+		clientCAs, err := certutil.CertsFromFile(tmpControl.Runtime.ClientCA)
+		if err != nil {
+			return err
+		}
+		issuerHash = nodeconfig.IssuerHash(clientCAs[0])
+		serverCAs, err := certutil.ParseCertsPEM(serverCA)
+		if err != nil {
+			return err
+		}
+		servingIssuerHash = nodeconfig.IssuerHash(serverCAs[0])
+	}
+	return carotation.SetPhase(rotations, phase, nodeconfig.ServerCAHash(serverCA), issuerHash, servingIssuerHash)
+}
+
+// composeBootstrap replaces each new CA cert bundle with the bundle for the requested rotation phase.
+// During the trust phase the current CA key is retained, so that the current CA continues to sign.
+// All CA bundles are composed even if validation fails, so that the phase can be forced.
+func composeBootstrap(oldControl, newControl *config.Control, phase string) error {
+	errs := []error{}
+
+	oldMeta := reflect.ValueOf(&oldControl.Runtime.ControlRuntimeBootstrap).Elem()
+	newMeta := reflect.ValueOf(&newControl.Runtime.ControlRuntimeBootstrap).Elem()
+
+	for _, field := range reflect.VisibleFields(oldMeta.Type()) {
+		if field.Tag.Get("rotate") != "true" {
+			continue
+		}
+		oldVal := oldMeta.FieldByName(field.Name)
+		newVal := newMeta.FieldByName(field.Name)
+
+		// Check signing key rotation
+		if field.Name == "ServiceKey" {
+			if err := validateServiceKey(oldVal.String(), newVal.String()); err != nil {
+				errs = append(errs, errors.Wrap(err, field.Name))
+			}
+			continue
+		}
+
+		// Skip CAs that are not being rotated, as defaultBootstrap has set the current paths
+		if !strings.HasSuffix(field.Name, "CA") || oldVal.String() == newVal.String() {
+			continue
+		}
+		oldKeyVal := oldMeta.FieldByName(field.Name + "Key")
+		newKeyVal := newMeta.FieldByName(field.Name + "Key")
+		if err := validateCAKey(oldVal.String(), oldKeyVal.String(), newVal.String(), newKeyVal.String()); err != nil {
+			errs = append(errs, errors.Wrap(err, field.Name+"Key"))
+		}
+
+		oldCerts, err := certutil.CertsFromFile(oldVal.String())
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, field.Name))
+			continue
+		}
+		newCerts, err := certutil.CertsFromFile(newVal.String())
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, field.Name))
+			continue
+		}
+		if err := carotation.ValidatePhase(phase, oldCerts, newCerts); err != nil {
+			errs = append(errs, errors.Wrap(err, field.Name))
+		}
+
+		buf := &bytes.Buffer{}
+		for _, cert := range carotation.ComposeBundle(phase, oldCerts, newCerts) {
+			buf.Write(certutil.EncodeCertPEM(cert))
+		}
+		if err := os.WriteFile(newVal.String(), buf.Bytes(), 0600); err != nil {
+			errs = append(errs, errors.Wrap(err, field.Name))
+			continue
+		}
+		if phase == carotation.PhaseTrust {
+			newKeyVal.Set(oldKeyVal)
+		}
+	}
+
+	return merr.NewErrors(errs...)
+}
+
 // defaultBootstrap provides default values from the existing bootstrap fields
 // if the value is not tagged for rotation, or the current value is empty.
 func defaultBootstrap(oldControl, newControl *config.Control) error {
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+
+	apisv1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/desiredstate"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/kubernetes/pkg/auth/nodeidentifier"
+)
+
+// NodeDesiredState returns the desired state of the requesting node, merged from all NodeConfigs that
+// select it. If the node has not yet registered, only NodeConfigs that select all nodes or select the
+// node by name are merged.
+func NodeDesiredState(control *config.Control) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			util.SendError(errors.New("method not allowed"), resp, req, http.StatusMethodNotAllowed)
+			return
+		}
+		if control.Runtime.Core == nil || control.Runtime.K3s == nil {
+			util.SendError(util.ErrCoreNotReady, resp, req, http.StatusServiceUnavailable)
+			return
+		}
+		user, ok := request.UserFrom(req.Context())
+		if !ok {
+			util.SendError(errors.New("auth user not set"), resp, req, http.StatusUnauthorized)
+			return
+		}
+		nodeName, _ := nodeidentifier.NewDefaultNodeIdentifier().NodeIdentity(user)
+		if nodeName == "" {
+			util.SendError(errors.New("not a node"), resp, req, http.StatusForbidden)
+			return
+		}
+
+		list, err := control.Runtime.K3s.K3s().V1().NodeConfig().List(metav1.ListOptions{})
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		node, err := control.Runtime.Core.Core().V1().Node().Get(nodeName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
+		} else if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+
+		managed, err := desiredstate.GetManaged(control.Runtime.Core.Core().V1().ConfigMap(), nodeName)
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+
+		configs := make([]*apisv1.NodeConfig, len(list.Items))
+		for i := range list.Items {
+			configs[i] = &list.Items[i]
+		}
+		b, err := json.Marshal(desiredstate.Merge(configs, node, managed))
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		resp.Write(b)
+	})
+}
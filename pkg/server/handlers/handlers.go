@@ -16,13 +16,21 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/k3s-io/k3s/pkg/bootstrap"
 	"github.com/k3s-io/k3s/pkg/cli/cmds"
+	"github.com/k3s-io/k3s/pkg/cluster"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/etcd"
+	"github.com/k3s-io/k3s/pkg/hibernate"
+	"github.com/k3s-io/k3s/pkg/maintenance"
 	"github.com/k3s-io/k3s/pkg/nodepassword"
+	"github.com/k3s-io/k3s/pkg/servicerange"
+	"github.com/k3s-io/k3s/pkg/startup"
+	"github.com/k3s-io/k3s/pkg/tracing"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/pkg/errors"
 	certutil "github.com/rancher/dynamiclistener/cert"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -80,6 +88,7 @@ func ServingKubeletCert(control *config.Control, auth nodepassword.NodeAuthValid
 		signAndSend(resp, req, control.Runtime.ServerCA, control.Runtime.ServerCAKey, control.Runtime.ServingKubeletKey, certutil.Config{
 			CommonName: nodeName,
 			Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			ExpiresAt:  control.ServerCertDuration,
 			AltNames: certutil.AltNames{
 				DNSNames: []string{nodeName, "localhost"},
 				IPs:      ips,
@@ -88,6 +97,18 @@ func ServingKubeletCert(control *config.Control, auth nodepassword.NodeAuthValid
 	})
 }
 
+// NodePassword validates the node password, without signing a certificate. Agents that reuse their
+// existing kubelet certificates use this to confirm that the node password is still accepted.
+func NodePassword(auth nodepassword.NodeAuthValidator) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if _, errCode, err := auth(req); err != nil {
+			util.SendError(err, resp, req, errCode)
+			return
+		}
+		resp.WriteHeader(http.StatusOK)
+	})
+}
+
 func ClientKubeletCert(control *config.Control, auth nodepassword.NodeAuthValidator) http.Handler {
 	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		nodeName, errCode, err := auth(req)
@@ -99,6 +120,7 @@ func ClientKubeletCert(control *config.Control, auth nodepassword.NodeAuthValida
 			CommonName:   "system:node:" + nodeName,
 			Organization: []string{user.NodesGroup},
 			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			ExpiresAt:    control.ClientCertDuration,
 		})
 	})
 }
@@ -108,6 +130,7 @@ func ClientKubeProxyCert(control *config.Control) http.Handler {
 		signAndSend(resp, req, control.Runtime.ClientCA, control.Runtime.ClientCAKey, control.Runtime.ClientKubeProxyKey, certutil.Config{
 			CommonName: user.KubeProxy,
 			Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			ExpiresAt:  control.ClientCertDuration,
 		})
 	})
 }
@@ -118,6 +141,7 @@ func ClientControllerCert(control *config.Control) http.Handler {
 		signAndSend(resp, req, control.Runtime.ClientCA, control.Runtime.ClientCAKey, control.Runtime.ClientK3sControllerKey, certutil.Config{
 			CommonName: "system:" + program + "-controller",
 			Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			ExpiresAt:  control.ClientCertDuration,
 		})
 	})
 }
@@ -184,12 +208,20 @@ func Config(control *config.Control, cfg *cmds.Server) http.Handler {
 	})
 }
 
+// Readyz reports whether the control-plane run by this server is ready: the datastore and apiserver
+// startup phases expected for this server have completed, and the core controllers are running.
+// Readiness does not depend on the local agent, so servers run with --disable-agent report ready
+// without registering a Node. If not ready, the phases that have not completed are listed in the error.
 func Readyz(control *config.Control) http.Handler {
 	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		if control.Runtime.Core == nil {
 			util.SendError(util.ErrCoreNotReady, resp, req, http.StatusServiceUnavailable)
 			return
 		}
+		if pending := startup.Pending(startup.PhaseDatastore, startup.PhaseAPIServer); len(pending) > 0 {
+			util.SendError(fmt.Errorf("waiting for: %s", strings.Join(pending, ", ")), resp, req, http.StatusServiceUnavailable)
+			return
+		}
 		data := []byte("ok")
 		resp.WriteHeader(http.StatusOK)
 		resp.Header().Set("Content-Type", "text/plain")
@@ -198,6 +230,23 @@ func Readyz(control *config.Control) http.Handler {
 	})
 }
 
+// StartupState returns the startup state of this server, as written to the startup state file.
+func StartupState() http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			util.SendError(errors.New("method not allowed"), resp, req, http.StatusMethodNotAllowed)
+			return
+		}
+		b, err := json.Marshal(startup.GetState())
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		resp.Write(b)
+	})
+}
+
 func Bootstrap(control *config.Control) http.Handler {
 	if control.Runtime.HTTPBootstrap {
 		return bootstrap.Handler(&control.Runtime.ControlRuntimeBootstrap)
@@ -208,6 +257,157 @@ func Bootstrap(control *config.Control) http.Handler {
 	})
 }
 
+// BootstrapStatus returns a comparison of the bootstrap data in the datastore to the files on this
+// server's disk.
+func BootstrapStatus(ctx context.Context, control *config.Control) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			util.SendError(errors.New("method not allowed"), resp, req, http.StatusMethodNotAllowed)
+			return
+		}
+		status, err := cluster.GetBootstrapStatus(ctx, control)
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		b, err := json.Marshal(status)
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		resp.Write(b)
+	})
+}
+
+// Maintenance enables or disables maintenance mode on a node.
+func Maintenance(control *config.Control) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			util.SendError(errors.New("method not allowed"), resp, req, http.StatusMethodNotAllowed)
+			return
+		}
+		if control.Runtime.Core == nil {
+			util.SendError(util.ErrCoreNotReady, resp, req, http.StatusServiceUnavailable)
+			return
+		}
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusBadRequest)
+			return
+		}
+		mr := &maintenance.Request{}
+		if err := json.Unmarshal(b, mr); err != nil {
+			util.SendError(err, resp, req, http.StatusBadRequest)
+			return
+		}
+		if mr.Node == "" {
+			util.SendError(errors.New("node name is required"), resp, req, http.StatusBadRequest)
+			return
+		}
+		status, err := maintenance.Set(control.Runtime.Core.Core().V1().Node(), mr.Node, mr.Enable)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				util.SendError(err, resp, req, http.StatusNotFound)
+				return
+			}
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		logrus.Infof("Maintenance mode enabled=%v on node %s", status.Enabled, status.Node)
+		b, err = json.Marshal(status)
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		resp.Write(b)
+	})
+}
+
+func Hibernate(control *config.Control) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			util.SendError(errors.New("method not allowed"), resp, req, http.StatusMethodNotAllowed)
+			return
+		}
+		if control.Runtime.Core == nil || control.Runtime.K8s == nil {
+			util.SendError(util.ErrCoreNotReady, resp, req, http.StatusServiceUnavailable)
+			return
+		}
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusBadRequest)
+			return
+		}
+		hr := &hibernate.Request{}
+		if err := json.Unmarshal(b, hr); err != nil {
+			util.SendError(err, resp, req, http.StatusBadRequest)
+			return
+		}
+		deployments := control.Runtime.K8s.AppsV1().Deployments(metav1.NamespaceSystem)
+		status, err := hibernate.Set(req.Context(), deployments, control.Runtime.Core.Core().V1().Node(), control.ServerNodeName, hr.Hibernate)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				util.SendError(err, resp, req, http.StatusNotFound)
+				return
+			}
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		logrus.Infof("Hibernation enabled=%v on node %s; scaled deployments %v", status.Hibernating, status.Node, status.Deployments)
+		b, err = json.Marshal(status)
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		resp.Write(b)
+	})
+}
+
+// ReallocateServices moves services with cluster IPs or node ports outside of the current service
+// CIDRs and NodePort range into the ranges.
+func ReallocateServices(control *config.Control) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			util.SendError(errors.New("method not allowed"), resp, req, http.StatusMethodNotAllowed)
+			return
+		}
+		if control.Runtime.Core == nil || control.Runtime.K3s == nil {
+			util.SendError(util.ErrCoreNotReady, resp, req, http.StatusServiceUnavailable)
+			return
+		}
+		if control.ServiceNodePortRange == nil {
+			util.SendError(errors.New("service-node-port-range is not set"), resp, req, http.StatusInternalServerError)
+			return
+		}
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusBadRequest)
+			return
+		}
+		rr := &servicerange.Request{}
+		if err := json.Unmarshal(b, rr); err != nil {
+			util.SendError(err, resp, req, http.StatusBadRequest)
+			return
+		}
+		result, err := servicerange.Reallocate(req.Context(), control.Runtime.Core.Core().V1().Service(), control.Runtime.K3s.K3s().V1().Addon(),
+			control.ServiceIPRanges, *control.ServiceNodePortRange, rr.DryRun)
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		b, err = json.Marshal(result)
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		resp.Write(b)
+	})
+}
+
 func Static(urlPrefix, staticDir string) http.Handler {
 	return http.StripPrefix(urlPrefix, http.FileServer(http.Dir(staticDir)))
 }
@@ -232,6 +432,9 @@ func (c csrSigner) Sign(_ io.Reader, _ []byte, _ crypto.SignerOpts) ([]byte, err
 // client did not submit a signing request, the legacy shared key is used to generate the
 // certificate, and the key is sent along with the certificate.
 func signAndSend(resp http.ResponseWriter, req *http.Request, caCertFile, caKeyFile, signingKeyFile string, certConfig certutil.Config) {
+	_, span := tracing.Start(req.Context(), "certificate.issue", attribute.String("certificate.common_name", certConfig.CommonName))
+	defer span.End()
+
 	caCerts, caKey, err := getCACertAndKey(caCertFile, caKeyFile)
 	if err != nil {
 		util.SendError(err, resp, req)
@@ -259,6 +462,7 @@ func signAndSend(resp http.ResponseWriter, req *http.Request, caCertFile, caKeyF
 	}
 
 	// create the signed cert using dynamiclistener cert utils
+	certConfig.ExpiresAt = util.LeafCertDuration(certConfig.ExpiresAt, caCerts[0])
 	cert, err := certutil.NewSignedCert(certConfig, key, caCerts[0], caKey)
 	if err != nil {
 		util.SendError(err, resp, req)
@@ -383,3 +587,39 @@ func getAddressCollector(control *config.Control) func(ctx context.Context) []st
 		return a.UnsortedList()
 	}
 }
+
+// TunnelSessions returns a list of agent websocket tunnel sessions connected to this server.
+func TunnelSessions(control *config.Control) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		lister, ok := control.Runtime.Tunnel.(config.TunnelSessionLister)
+		if !ok {
+			util.SendError(errors.New("tunnel server does not track sessions"), resp, req, http.StatusServiceUnavailable)
+			return
+		}
+		b, err := json.Marshal(lister.Sessions())
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		resp.Write(b)
+	})
+}
+
+// EgressStatus returns the apiserver egress selector configuration, and egress proxy connection counts.
+func EgressStatus(control *config.Control) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		reporter, ok := control.Runtime.Tunnel.(config.EgressStatusReporter)
+		if !ok {
+			util.SendError(errors.New("tunnel server does not report egress status"), resp, req, http.StatusServiceUnavailable)
+			return
+		}
+		b, err := json.Marshal(reporter.EgressStatus())
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		resp.Write(b)
+	})
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/k3s-io/k3s/pkg/authenticator"
 	"github.com/k3s-io/k3s/pkg/cli/cmds"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/daemons/control/deps"
 	testutil "github.com/k3s-io/k3s/tests"
 	"github.com/k3s-io/k3s/tests/mock"
 	. "github.com/onsi/gomega"
@@ -1124,6 +1125,49 @@ func Test_UnitHandlers(t *testing.T) {
 							},
 						},
 					),
+				}, {
+					method: http.MethodPost,
+					path:   "/v1-k3s/node-password",
+					subs: append(genericFailures,
+						sub{
+							name: "V00 valid basic but missing headers",
+							prepare: func(control *config.Control, req *http.Request) {
+								req.SetBasicAuth("node", control.AgentToken)
+							},
+							match: func(_ *config.Control) types.GomegaMatcher {
+								return HaveHTTPStatus(http.StatusBadRequest)
+							},
+						},
+						sub{
+							name: "V01 valid basic",
+							prepare: func(control *config.Control, req *http.Request) {
+								req.Header.Add("k3s-Node-Name", control.ServerNodeName)
+								req.Header.Add("k3s-Node-Password", "password")
+								req.SetBasicAuth("node", control.AgentToken)
+							},
+							match: func(_ *config.Control) types.GomegaMatcher {
+								return And(
+									HaveHTTPStatus(http.StatusOK),
+									HaveHTTPBody(BeEmpty()),
+								)
+							},
+						},
+						sub{
+							name: "V02 valid cert",
+							prepare: func(control *config.Control, req *http.Request) {
+								req.Header.Add("k3s-Node-Name", control.ServerNodeName)
+								req.Header.Add("k3s-Node-Password", "password")
+								withNewClientCert(req, control.Runtime.ClientCA, control.Runtime.ClientCAKey, control.Runtime.ClientKubeletKey, certutil.Config{
+									CommonName:   "system:node:" + control.ServerNodeName,
+									Organization: []string{user.NodesGroup},
+									Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+								})
+							},
+							match: func(_ *config.Control) types.GomegaMatcher {
+								return HaveHTTPStatus(http.StatusOK)
+							},
+						},
+					),
 				},
 				//** paths accessible with node cert or agent token **
 				{
@@ -1494,6 +1538,19 @@ func Test_UnitHandlers(t *testing.T) {
 								return HaveHTTPStatus(http.StatusMethodNotAllowed)
 							},
 						},
+						sub{
+							name: "M01 valid admin cert",
+							prepare: func(control *config.Control, req *http.Request) {
+								withNewClientCert(req, control.Runtime.ClientCA, control.Runtime.ClientCAKey, control.Runtime.ClientAdminKey, certutil.Config{
+									CommonName:   "system:admin",
+									Organization: []string{user.SystemPrivilegedGroup},
+									Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+								})
+							},
+							match: func(_ *config.Control) types.GomegaMatcher {
+								return HaveHTTPStatus(http.StatusMethodNotAllowed)
+							},
+						},
 					),
 				}, {
 					method: http.MethodGet,
@@ -1525,8 +1582,98 @@ func Test_UnitHandlers(t *testing.T) {
 								return HaveHTTPStatus(http.StatusMethodNotAllowed)
 							},
 						},
+						sub{
+							name: "O01 valid admin cert",
+							prepare: func(control *config.Control, req *http.Request) {
+								withNewClientCert(req, control.Runtime.ClientCA, control.Runtime.ClientCAKey, control.Runtime.ClientAdminKey, certutil.Config{
+									CommonName:   "system:admin",
+									Organization: []string{user.SystemPrivilegedGroup},
+									Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+								})
+							},
+							match: func(_ *config.Control) types.GomegaMatcher {
+								return HaveHTTPStatus(http.StatusMethodNotAllowed)
+							},
+						},
 					),
 				},
+				//** paths accessible with front-proxy cert **
+				{
+					method: http.MethodGet,
+					path:   "/apis/k3s.cattle.io/v1alpha1",
+					subs: []sub{
+						{
+							name: "S00 anonymous",
+							match: func(_ *config.Control) types.GomegaMatcher {
+								return HaveHTTPStatus(http.StatusServiceUnavailable)
+							},
+						},
+						{
+							name: "S01 valid front-proxy cert",
+							prepare: func(control *config.Control, req *http.Request) {
+								withClientCert(req, control.Runtime.ClientAuthProxyCert)
+								req.Header.Add("X-Remote-User", "system:kube-aggregator")
+							},
+							match: func(_ *config.Control) types.GomegaMatcher {
+								return And(
+									HaveHTTPStatus(http.StatusOK),
+									HaveHTTPBody(ContainSubstring(`"kind":"APIResourceList"`)),
+								)
+							},
+						},
+						{
+							name: "S02 valid cert but untrusted front-proxy CA",
+							prepare: func(control *config.Control, req *http.Request) {
+								withNewClientCert(req, control.Runtime.ClientCA, control.Runtime.ClientCAKey, control.Runtime.ClientAuthProxyKey, certutil.Config{
+									CommonName: deps.RequestHeaderCN,
+									Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+								})
+								req.Header.Add("X-Remote-User", "system:kube-aggregator")
+							},
+							match: func(_ *config.Control) types.GomegaMatcher {
+								return HaveHTTPStatus(http.StatusServiceUnavailable)
+							},
+						},
+					},
+				}, {
+					method: http.MethodGet,
+					path:   "/apis/k3s.cattle.io/v1alpha1/certificatechecks",
+					subs: []sub{
+						{
+							name: "T00 valid front-proxy cert",
+							prepare: func(control *config.Control, req *http.Request) {
+								withClientCert(req, control.Runtime.ClientAuthProxyCert)
+								req.Header.Add("X-Remote-User", "system:admin")
+								req.Header.Add("X-Remote-Group", user.SystemPrivilegedGroup)
+							},
+							match: func(_ *config.Control) types.GomegaMatcher {
+								return And(
+									HaveHTTPStatus(http.StatusOK),
+									HaveHTTPBody(ContainSubstring(`"name":"api-server.client-kube-apiserver"`)),
+								)
+							},
+						},
+					},
+				}, {
+					method: http.MethodGet,
+					path:   "/apis/k3s.cattle.io/v1alpha1/etcdsnapshots",
+					subs: []sub{
+						{
+							name: "U00 valid front-proxy cert",
+							prepare: func(control *config.Control, req *http.Request) {
+								withClientCert(req, control.Runtime.ClientAuthProxyCert)
+								req.Header.Add("X-Remote-User", "system:admin")
+								req.Header.Add("X-Remote-Group", user.SystemPrivilegedGroup)
+							},
+							match: func(_ *config.Control) types.GomegaMatcher {
+								return And(
+									HaveHTTPStatus(http.StatusBadRequest),
+									HaveHTTPBody(ContainSubstring("etcd datastore disabled")),
+								)
+							},
+						},
+					},
+				},
 				//** paths accessible with apiserver cert **
 				{
 					method: http.MethodConnect,
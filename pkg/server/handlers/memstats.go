@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// MemStats returns the memory use of the supervisor process, along with the list of embedded
+// components that are disabled.
+func MemStats(control *config.Control) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			util.SendError(errors.New("method not allowed"), resp, req, http.StatusMethodNotAllowed)
+			return
+		}
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		stats := config.MemStats{
+			RSS:        util.ReadRSS(util.SelfStatusFile),
+			HeapAlloc:  ms.HeapAlloc,
+			HeapInuse:  ms.HeapInuse,
+			Sys:        ms.Sys,
+			NumGC:      ms.NumGC,
+			Goroutines: runtime.NumGoroutine(),
+			Disabled:   disabledComponents(control),
+		}
+		b, err := json.Marshal(stats)
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		resp.Write(b)
+	})
+}
+
+// disabledComponents returns a sorted list of the packaged components and embedded controllers
+// that are disabled.
+func disabledComponents(control *config.Control) []string {
+	disabled := sets.New[string]()
+	for name, ok := range control.Disables {
+		if ok {
+			disabled.Insert(name)
+		}
+	}
+	for name, ok := range map[string]bool{
+		"cloud-controller": control.DisableCCM,
+		"helm-controller":  control.DisableHelmController,
+		"kube-proxy":       control.DisableKubeProxy,
+		"network-policy":   control.DisableNPC,
+		"servicelb":        control.DisableServiceLB,
+	} {
+		if ok {
+			disabled.Insert(name)
+		}
+	}
+	return sets.List(disabled)
+}
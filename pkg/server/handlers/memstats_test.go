@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+)
+
+func Test_UnitDisabledComponents(t *testing.T) {
+	control := &config.Control{
+		Disables:         map[string]bool{"traefik": true, "servicelb": true, "metrics-server": false},
+		DisableServiceLB: true,
+	}
+	control.DisableHelmController = true
+	want := []string{"helm-controller", "servicelb", "traefik"}
+	if got := disabledComponents(control); !reflect.DeepEqual(got, want) {
+		t.Errorf("disabledComponents() = %v, want %v", got, want)
+	}
+}
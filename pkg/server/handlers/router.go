@@ -4,8 +4,10 @@ import (
 	"context"
 	"net/http"
 	"path/filepath"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1alpha1"
 	"github.com/k3s-io/k3s/pkg/cli/cmds"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/nodepassword"
@@ -28,6 +30,7 @@ func NewHandler(ctx context.Context, control *config.Control, cfg *cmds.Server)
 	authed.Use(auth.HasRole(control, version.Program+":agent", user.NodesGroup, bootstrapapi.BootstrapDefaultGroup))
 	authed.Handle(prefix+"/serving-kubelet.crt", ServingKubeletCert(control, nodeAuth))
 	authed.Handle(prefix+"/client-kubelet.crt", ClientKubeletCert(control, nodeAuth))
+	authed.Handle(prefix+"/node-password", NodePassword(nodeAuth))
 	authed.Handle(prefix+"/client-kube-proxy.crt", ClientKubeProxyCert(control))
 	authed.Handle(prefix+"/client-{program}-controller.crt", ClientControllerCert(control))
 	authed.Handle(prefix+"/client-ca.crt", File(control.Runtime.ClientCA))
@@ -35,24 +38,42 @@ func NewHandler(ctx context.Context, control *config.Control, cfg *cmds.Server)
 	authed.Handle(prefix+"/apiservers", APIServers(control))
 	authed.Handle(prefix+"/config", Config(control, cfg))
 	authed.Handle(prefix+"/readyz", Readyz(control))
+	authed.Handle(prefix+"/startup/state", StartupState())
 
 	nodeAuthed := mux.NewRouter().SkipClean(true)
 	nodeAuthed.NotFoundHandler = authed
 	nodeAuthed.Use(auth.HasRole(control, user.NodesGroup))
 	nodeAuthed.Handle(prefix+"/connect", control.Runtime.Tunnel)
+	nodeAuthed.Handle(prefix+"/desired-state", NodeDesiredState(control))
 
 	serverAuthed := mux.NewRouter().SkipClean(true)
 	serverAuthed.NotFoundHandler = nodeAuthed
 	serverAuthed.Use(auth.HasRole(control, version.Program+":server"))
 	serverAuthed.Handle(prefix+"/encrypt/status", EncryptionStatus(control))
 	serverAuthed.Handle(prefix+"/encrypt/config", EncryptionConfig(ctx, control))
-	serverAuthed.Handle(prefix+"/cert/cacerts", CACertReplace(control))
+	serverAuthed.Handle(prefix+"/encrypt/dry-run", EncryptionDryRun(ctx, control))
 	serverAuthed.Handle(prefix+"/server-bootstrap", Bootstrap(control))
-	serverAuthed.Handle(prefix+"/token", TokenRequest(ctx, control))
+	serverAuthed.Handle(prefix+"/bootstrap/status", BootstrapStatus(ctx, control))
+	serverAuthed.Handle(prefix+"/tunnel/sessions", TunnelSessions(control))
+	serverAuthed.Handle(prefix+"/egress/status", EgressStatus(control))
+	serverAuthed.Handle(prefix+"/debug/memstats", MemStats(control))
+	serverAuthed.Handle(prefix+"/maintenance", Maintenance(control))
+	serverAuthed.Handle(prefix+"/services/reallocate", ReallocateServices(control))
+	serverAuthed.Handle(prefix+"/hibernate", Hibernate(control))
+
+	// Paths accessible with the server token, or with cluster-admin credentials from a kubeconfig
+	adminAuthed := mux.NewRouter().SkipClean(true)
+	adminAuthed.NotFoundHandler = serverAuthed
+	adminAuthed.Use(auth.HasRole(control, version.Program+":server", user.SystemPrivilegedGroup))
+	adminAuthed.Handle(prefix+"/cert/cacerts", CACertReplace(control))
+	adminAuthed.Handle(prefix+"/token", TokenRequest(ctx, control))
+	if control.TokenViewer {
+		adminAuthed.Handle(prefix+"/token/viewer", ViewerToken(control))
+	}
 
 	systemAuthed := mux.NewRouter().SkipClean(true)
-	systemAuthed.NotFoundHandler = serverAuthed
-	systemAuthed.MethodNotAllowedHandler = serverAuthed
+	systemAuthed.NotFoundHandler = adminAuthed
+	systemAuthed.MethodNotAllowedHandler = adminAuthed
 	systemAuthed.Use(auth.HasRole(control, user.SystemPrivilegedGroup))
 	systemAuthed.Methods(http.MethodConnect).Handler(control.Runtime.Tunnel)
 
@@ -62,5 +83,16 @@ func NewHandler(ctx context.Context, control *config.Control, cfg *cmds.Server)
 	router.Handle("/cacerts", CACerts(control))
 	router.Handle("/ping", Ping())
 
+	// Requests proxied to the supervisor's aggregated API by the apiserver are authenticated by the
+	// front-proxy client certificate; any other requests fall through to the authenticated handlers.
+	aggregated := router.NewRoute().MatcherFunc(isAggregatedPath).Subrouter()
+	aggregated.Use(auth.IsAggregated(control, systemAuthed))
+	aggregated.NewRoute().Handler(APIService(ctx, control))
+
 	return router
 }
+
+// isAggregatedPath returns true for discovery and resource requests sent to the supervisor's aggregated API.
+func isAggregatedPath(req *http.Request, _ *mux.RouteMatch) bool {
+	return req.URL.Path == "/apis" || strings.HasPrefix(req.URL.Path, "/apis/"+v1alpha1.SchemeGroupVersion.Group+"/")
+}
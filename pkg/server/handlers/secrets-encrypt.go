@@ -34,6 +34,10 @@ import (
 
 const aescbcKeySize = 32
 
+// reencryptSecretDuration is a rough estimate of how long it takes to rewrite a single secret
+// during reencryption. Secrets are updated one at a time, so the total scales linearly.
+var reencryptSecretDuration = 10 * time.Millisecond
+
 type EncryptionState struct {
 	Stage        string   `json:"stage"`
 	ActiveKey    string   `json:"activekey"`
@@ -50,6 +54,23 @@ type EncryptionRequest struct {
 	Skip   bool    `json:"skip"`
 }
 
+// EncryptionPlan describes what a stage request would do, without making any changes.
+type EncryptionPlan struct {
+	Stage             string                  `json:"stage"`
+	Allowed           bool                    `json:"allowed"`
+	Error             string                  `json:"error,omitempty"`
+	Secrets           int                     `json:"secrets"`
+	EstimatedDuration string                  `json:"estimatedduration"`
+	Servers           []EncryptionServerState `json:"servers,omitempty"`
+}
+
+// EncryptionServerState is the secrets encryption stage reported by a single control-plane node
+type EncryptionServerState struct {
+	Name      string `json:"name"`
+	Stage     string `json:"stage"`
+	HashMatch bool   `json:"hashmatch"`
+}
+
 func getEncryptionRequest(req *http.Request) (*EncryptionRequest, error) {
 	b, err := io.ReadAll(req.Body)
 	if err != nil {
@@ -199,9 +220,141 @@ func EncryptionConfig(ctx context.Context, control *config.Control) http.Handler
 	})
 }
 
+// EncryptionDryRun returns the plan for a stage request, without making any changes.
+func EncryptionDryRun(ctx context.Context, control *config.Control) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			util.SendError(fmt.Errorf("method not allowed"), resp, req, http.StatusMethodNotAllowed)
+			return
+		}
+		encryptReq, err := getEncryptionRequest(req)
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusBadRequest)
+			return
+		}
+		if encryptReq.Stage == nil {
+			util.SendError(fmt.Errorf("stage is required"), resp, req, http.StatusBadRequest)
+			return
+		}
+		plan, err := encryptionDryRun(ctx, control, *encryptReq.Stage, encryptReq.Force)
+		if err != nil {
+			util.SendErrorWithID(err, "secret-encrypt", resp, req, http.StatusBadRequest)
+			return
+		}
+		b, err := json.Marshal(plan)
+		if err != nil {
+			util.SendErrorWithID(err, "secret-encrypt", resp, req, http.StatusInternalServerError)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		resp.Write(b)
+	})
+}
+
+// encryptionDryRun validates a stage request and reports the number of secrets that would be
+// reencrypted and the stage of each control-plane node, without modifying the encryption config
+// or node annotations.
+func encryptionDryRun(ctx context.Context, control *config.Control, stage string, force bool) (*EncryptionPlan, error) {
+	if _, ok := stagePrerequisites[stage]; !ok {
+		return nil, fmt.Errorf("unknown stage %s requested", stage)
+	}
+	plan := &EncryptionPlan{Stage: stage}
+	err := verifyStage(control, stage)
+	// rotate-keys cannot be forced
+	plan.Allowed = err == nil || (force && stage != secretsencrypt.EncryptionRotateKeys)
+	if err != nil {
+		plan.Error = err.Error()
+	}
+
+	if plan.Secrets, err = countSecrets(ctx, control); err != nil {
+		return nil, err
+	}
+	plan.EstimatedDuration = estimateReencryptDuration(plan.Secrets).String()
+
+	encryptionConfigHash, err := secretsencrypt.GenEncryptionConfigHash(control.Runtime)
+	if err != nil {
+		return nil, err
+	}
+	labelSelector := labels.Set{util.ControlPlaneRoleLabelKey: "true"}.String()
+	nodes, err := control.Runtime.Core.Core().V1().Node().List(metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, err
+	}
+	plan.Servers = encryptionServerStates(nodes.Items, encryptionConfigHash)
+	return plan, nil
+}
+
+// encryptionServerStates returns the encryption stage of each node, as read from the encryption
+// hash annotation, and whether the node's config hash matches the local config hash.
+func encryptionServerStates(nodes []corev1.Node, encryptionConfigHash string) []EncryptionServerState {
+	states := []EncryptionServerState{}
+	for _, node := range nodes {
+		state := EncryptionServerState{Name: node.Name, Stage: "unknown"}
+		if ann, ok := node.Annotations[secretsencrypt.EncryptionHashAnnotation]; ok {
+			if split := strings.Split(ann, "-"); len(split) == 2 {
+				state.Stage = split[0]
+				state.HashMatch = split[1] == encryptionConfigHash
+			}
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// estimateReencryptDuration returns the approximate time needed to reencrypt the given number of secrets
+func estimateReencryptDuration(secrets int) time.Duration {
+	return (time.Duration(secrets) * reencryptSecretDuration).Round(time.Second)
+}
+
+// countSecrets returns the number of secrets that would be rewritten by reencryption. Only a single
+// secret is listed; the apiserver reports the number of remaining secrets with the first page.
+func countSecrets(ctx context.Context, control *config.Control) (int, error) {
+	secrets, err := control.Runtime.K8s.CoreV1().Secrets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return 0, err
+	}
+	return listCount(secrets.ListMeta, len(secrets.Items))
+}
+
+// listCount returns the total number of items in a list, given the metadata and number of items
+// of its first page.
+func listCount(meta metav1.ListMeta, items int) (int, error) {
+	if meta.RemainingItemCount != nil {
+		return items + int(*meta.RemainingItemCount), nil
+	}
+	if meta.Continue == "" {
+		return items, nil
+	}
+	return 0, errors.New("unable to count secrets: the remaining item count was not returned")
+}
+
+// stagePrerequisites are the stages that the cluster must be in before each stage can be requested.
+var stagePrerequisites = map[string]string{
+	secretsencrypt.EncryptionPrepare:         secretsencrypt.EncryptionStart + "-" + secretsencrypt.EncryptionReencryptFinished,
+	secretsencrypt.EncryptionRotate:          secretsencrypt.EncryptionPrepare,
+	secretsencrypt.EncryptionReencryptActive: secretsencrypt.EncryptionRotate,
+	secretsencrypt.EncryptionRotateKeys:      secretsencrypt.EncryptionStart + "-" + secretsencrypt.EncryptionReencryptFinished,
+}
+
+// verifyStage checks that all control-plane nodes are in a stage from which the requested stage
+// can be entered.
+func verifyStage(control *config.Control, stage string) error {
+	prevStage, ok := stagePrerequisites[stage]
+	if !ok {
+		return fmt.Errorf("unknown stage %s requested", stage)
+	}
+	core := control.Runtime.Core.Core()
+	if err := verifyEncryptionHashAnnotation(control.Runtime, core, prevStage); err != nil {
+		return err
+	}
+	if stage == secretsencrypt.EncryptionRotateKeys {
+		return verifyRotateKeysSupport(core)
+	}
+	return nil
+}
+
 func encryptionPrepare(ctx context.Context, control *config.Control, force bool) error {
-	states := secretsencrypt.EncryptionStart + "-" + secretsencrypt.EncryptionReencryptFinished
-	if err := verifyEncryptionHashAnnotation(control.Runtime, control.Runtime.Core.Core(), states); err != nil && !force {
+	if err := verifyStage(control, secretsencrypt.EncryptionPrepare); err != nil && !force {
 		return err
 	}
 
@@ -233,7 +386,7 @@ func encryptionPrepare(ctx context.Context, control *config.Control, force bool)
 }
 
 func encryptionRotate(ctx context.Context, control *config.Control, force bool) error {
-	if err := verifyEncryptionHashAnnotation(control.Runtime, control.Runtime.Core.Core(), secretsencrypt.EncryptionPrepare); err != nil && !force {
+	if err := verifyStage(control, secretsencrypt.EncryptionRotate); err != nil && !force {
 		return err
 	}
 
@@ -264,7 +417,7 @@ func encryptionRotate(ctx context.Context, control *config.Control, force bool)
 }
 
 func encryptionReencrypt(ctx context.Context, control *config.Control, force bool, skip bool) error {
-	if err := verifyEncryptionHashAnnotation(control.Runtime, control.Runtime.Core.Core(), secretsencrypt.EncryptionRotate); err != nil && !force {
+	if err := verifyStage(control, secretsencrypt.EncryptionReencryptActive); err != nil && !force {
 		return err
 	}
 	// Set the reencrypt-active annotation so other nodes know we are in the process of reencrypting.
@@ -310,12 +463,7 @@ func addAndRotateKeys(control *config.Control) error {
 // encryptionRotateKeys is both adds and rotates keys, and sets the annotaiton that triggers the
 // reencryption process. It is the preferred way to rotate keys, starting with v1.28
 func encryptionRotateKeys(ctx context.Context, control *config.Control) error {
-	states := secretsencrypt.EncryptionStart + "-" + secretsencrypt.EncryptionReencryptFinished
-	if err := verifyEncryptionHashAnnotation(control.Runtime, control.Runtime.Core.Core(), states); err != nil {
-		return err
-	}
-
-	if err := verifyRotateKeysSupport(control.Runtime.Core.Core()); err != nil {
+	if err := verifyStage(control, secretsencrypt.EncryptionRotateKeys); err != nil {
 		return err
 	}
 
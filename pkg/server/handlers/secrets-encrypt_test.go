@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/secretsencrypt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func Test_UnitEncryptionServerStates(t *testing.T) {
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:        "server-1",
+			Annotations: map[string]string{secretsencrypt.EncryptionHashAnnotation: secretsencrypt.EncryptionPrepare + "-abc"},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:        "server-2",
+			Annotations: map[string]string{secretsencrypt.EncryptionHashAnnotation: secretsencrypt.EncryptionStart + "-def"},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{
+			Name: "server-3",
+		}},
+	}
+	want := []EncryptionServerState{
+		{Name: "server-1", Stage: secretsencrypt.EncryptionPrepare, HashMatch: true},
+		{Name: "server-2", Stage: secretsencrypt.EncryptionStart, HashMatch: false},
+		{Name: "server-3", Stage: "unknown", HashMatch: false},
+	}
+	if got := encryptionServerStates(nodes, "abc"); !reflect.DeepEqual(got, want) {
+		t.Errorf("encryptionServerStates() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_UnitEstimateReencryptDuration(t *testing.T) {
+	tests := []struct {
+		secrets int
+		want    time.Duration
+	}{
+		{secrets: 0, want: 0},
+		{secrets: 40, want: 0},
+		{secrets: 1000, want: 10 * time.Second},
+		{secrets: 6000, want: time.Minute},
+	}
+	for _, tt := range tests {
+		if got := estimateReencryptDuration(tt.secrets); got != tt.want {
+			t.Errorf("estimateReencryptDuration(%d) = %v, want %v", tt.secrets, got, tt.want)
+		}
+	}
+}
+
+func Test_UnitListCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		meta    metav1.ListMeta
+		items   int
+		want    int
+		wantErr bool
+	}{
+		{name: "empty", want: 0},
+		{name: "single page", items: 1, want: 1},
+		{name: "remaining items", meta: metav1.ListMeta{Continue: "next", RemainingItemCount: ptr.To[int64](41)}, items: 1, want: 42},
+		{name: "remaining items not returned", meta: metav1.ListMeta{Continue: "next"}, items: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := listCount(tt.meta, tt.items)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("listCount(%s) = %d, %v; want %d, error %t", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/k3s-io/k3s/pkg/clientaccess"
 	"github.com/k3s-io/k3s/pkg/cluster"
@@ -16,12 +17,98 @@ import (
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/sirupsen/logrus"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/utils/ptr"
 )
 
 type TokenRotateRequest struct {
 	NewToken *string `json:"newToken,omitempty"`
 }
 
+const (
+	// minViewerTokenTTL is the minimum lifetime of a view-only token, as enforced by the apiserver.
+	minViewerTokenTTL = 10 * time.Minute
+	// maxViewerTokenTTL is the maximum lifetime of a view-only token.
+	maxViewerTokenTTL = 24 * time.Hour
+)
+
+// ViewerServiceAccount is the service account that view-only tokens are issued for. It is bound to
+// the view ClusterRole by the packaged token-viewer manifest, which is only deployed when servers are
+// started with --token-viewer.
+var ViewerServiceAccount = version.Program + "-token-viewer"
+
+// ViewerTokenRequest is the body of a request to exchange a join token for a view-only token.
+type ViewerTokenRequest struct {
+	TTL time.Duration `json:"ttl"`
+}
+
+// ViewerTokenResponse holds a view-only token, and the time that it expires.
+type ViewerTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ViewerToken issues a short-lived token for the view-only service account, so that holders of the
+// server token or cluster-admin credentials can hand out read-only access to the cluster.
+func ViewerToken(control *config.Control) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			util.SendError(fmt.Errorf("method not allowed"), resp, req, http.StatusMethodNotAllowed)
+			return
+		}
+		if control.Runtime.K8s == nil {
+			util.SendError(util.ErrCoreNotReady, resp, req, http.StatusServiceUnavailable)
+			return
+		}
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusBadRequest)
+			return
+		}
+		vtr := &ViewerTokenRequest{}
+		if err := json.Unmarshal(b, vtr); err != nil {
+			util.SendError(err, resp, req, http.StatusBadRequest)
+			return
+		}
+		if vtr.TTL < minViewerTokenTTL || vtr.TTL > maxViewerTokenTTL {
+			util.SendError(fmt.Errorf("ttl must be between %s and %s", minViewerTokenTTL, maxViewerTokenTTL), resp, req, http.StatusBadRequest)
+			return
+		}
+
+		tr := &authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				ExpirationSeconds: ptr.To(int64(vtr.TTL.Seconds())),
+			},
+		}
+		tr, err = control.Runtime.K8s.CoreV1().ServiceAccounts(metav1.NamespaceSystem).CreateToken(req.Context(), ViewerServiceAccount, tr, metav1.CreateOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				util.SendError(fmt.Errorf("service account %s/%s not found; it is created by the packaged token-viewer manifest", metav1.NamespaceSystem, ViewerServiceAccount), resp, req, http.StatusServiceUnavailable)
+				return
+			}
+			util.SendErrorWithID(err, "token", resp, req, http.StatusInternalServerError)
+			return
+		}
+
+		requester := "unknown"
+		if user, ok := request.UserFrom(req.Context()); ok {
+			requester = user.GetName()
+		}
+		logrus.Infof("Issued view-only token to %s from %s, valid until %s", requester, req.RemoteAddr, tr.Status.ExpirationTimestamp.UTC().Format(time.RFC3339))
+
+		b, err = json.Marshal(ViewerTokenResponse{Token: tr.Status.Token, ExpiresAt: tr.Status.ExpirationTimestamp.Time})
+		if err != nil {
+			util.SendError(err, resp, req, http.StatusInternalServerError)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		resp.Write(b)
+	})
+}
+
 func getServerTokenRequest(req *http.Request) (TokenRotateRequest, error) {
 	b, err := io.ReadAll(req.Body)
 	if err != nil {
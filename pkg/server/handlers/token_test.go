@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func Test_UnitViewerToken(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		create := action.(k8stesting.CreateAction)
+		if create.GetSubresource() != "token" || create.GetNamespace() != "kube-system" {
+			t.Errorf("unexpected create %s/%s in %s", action.GetResource().Resource, create.GetSubresource(), create.GetNamespace())
+		}
+		tr := create.GetObject().(*authenticationv1.TokenRequest).DeepCopy()
+		tr.Status.Token = "viewer-token"
+		tr.Status.ExpirationTimestamp.Time = time.Now().Add(time.Duration(*tr.Spec.ExpirationSeconds) * time.Second)
+		return true, tr, nil
+	})
+	control := &config.Control{Runtime: &config.ControlRuntime{K8s: client}}
+
+	tests := []struct {
+		name   string
+		method string
+		ttl    time.Duration
+		status int
+	}{
+		{name: "valid", method: http.MethodPost, ttl: time.Hour, status: http.StatusOK},
+		{name: "ttl too short", method: http.MethodPost, ttl: time.Minute, status: http.StatusBadRequest},
+		{name: "ttl too long", method: http.MethodPost, ttl: 48 * time.Hour, status: http.StatusBadRequest},
+		{name: "wrong method", method: http.MethodGet, ttl: time.Hour, status: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := json.Marshal(ViewerTokenRequest{TTL: tt.ttl})
+			req := httptest.NewRequest(tt.method, "/v1-k3s/token/viewer", bytes.NewReader(b))
+			resp := httptest.NewRecorder()
+			ViewerToken(control).ServeHTTP(resp, req)
+			if resp.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, resp.Code, resp.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			vtr := &ViewerTokenResponse{}
+			if err := json.Unmarshal(resp.Body.Bytes(), vtr); err != nil {
+				t.Fatal(err)
+			}
+			if vtr.Token != "viewer-token" || time.Until(vtr.ExpiresAt) > tt.ttl || time.Until(vtr.ExpiresAt) < tt.ttl-time.Minute {
+				t.Errorf("unexpected response %+v", vtr)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/daemons/config"
+	v1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilsnet "k8s.io/utils/net"
+)
+
+// checkNodeCIDRAllocations warns about pod CIDRs that were allocated to existing nodes before the cluster-cidr
+// or node-cidr-mask-size was changed. The controller-manager does not change the pod CIDR of existing nodes.
+func checkNodeCIDRAllocations(ctx context.Context, controlConfig *config.Control, nodes v1.NodeClient) {
+	var nodeList *corev1.NodeList
+	if err := wait.PollUntilContextCancel(ctx, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		var err error
+		if nodeList, err = nodes.List(metav1.ListOptions{}); err != nil {
+			logrus.Warnf("Failed to list nodes to check pod CIDR allocations: %v", err)
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		return
+	}
+	for _, conflict := range nodeCIDRConflicts(nodeList.Items, controlConfig.ClusterIPRanges, controlConfig.NodeCIDRMaskSizeIPv4, controlConfig.NodeCIDRMaskSizeIPv6) {
+		logrus.Warn(conflict)
+	}
+}
+
+// nodeCIDRConflicts returns a description of each node pod CIDR that is outside of the cluster CIDRs, that overlaps
+// the pod CIDR of another node, or that is not of the size allocated to new nodes.
+func nodeCIDRConflicts(nodes []corev1.Node, clusterCIDRs []*net.IPNet, maskSizeIPv4, maskSizeIPv6 int) []string {
+	type allocation struct {
+		node string
+		cidr *net.IPNet
+	}
+	var conflicts []string
+	var allocations []allocation
+	for _, node := range nodes {
+		podCIDRs := node.Spec.PodCIDRs
+		if len(podCIDRs) == 0 && node.Spec.PodCIDR != "" {
+			podCIDRs = []string{node.Spec.PodCIDR}
+		}
+		for _, podCIDR := range podCIDRs {
+			_, cidr, err := net.ParseCIDR(podCIDR)
+			if err != nil {
+				continue
+			}
+			for _, other := range allocations {
+				if other.cidr.Contains(cidr.IP) || cidr.Contains(other.cidr.IP) {
+					conflicts = append(conflicts, fmt.Sprintf("Pod CIDR %s of node %s overlaps pod CIDR %s of node %s", cidr, node.Name, other.cidr, other.node))
+				}
+			}
+			allocations = append(allocations, allocation{node: node.Name, cidr: cidr})
+
+			if !cidrsContain(clusterCIDRs, cidr) {
+				conflicts = append(conflicts, fmt.Sprintf("Pod CIDR %s of node %s is outside of the cluster-cidr; delete the node and rejoin it to allocate a new pod CIDR", cidr, node.Name))
+				continue
+			}
+			maskSize := maskSizeIPv4
+			if maskSize == 0 {
+				maskSize = config.DefaultNodeCIDRMaskSizeIPv4
+			}
+			if utilsnet.IsIPv6CIDR(cidr) {
+				maskSize = maskSizeIPv6
+				if maskSize == 0 {
+					maskSize = config.DefaultNodeCIDRMaskSizeIPv6
+				}
+			}
+			if ones, _ := cidr.Mask.Size(); ones != maskSize {
+				conflicts = append(conflicts, fmt.Sprintf("Pod CIDR %s of node %s is not a /%d; only new nodes are allocated pod CIDRs of the configured size", cidr, node.Name, maskSize))
+			}
+		}
+	}
+	return conflicts
+}
+
+// cidrsContain returns true if the CIDR is entirely within one of the CIDRs.
+func cidrsContain(cidrs []*net.IPNet, cidr *net.IPNet) bool {
+	ones, _ := cidr.Mask.Size()
+	for _, c := range cidrs {
+		if cOnes, _ := c.Mask.Size(); c.Contains(cidr.IP) && cOnes <= ones {
+			return true
+		}
+	}
+	return false
+}
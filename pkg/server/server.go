@@ -2,10 +2,15 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,18 +18,26 @@ import (
 
 	helmchart "github.com/k3s-io/helm-controller/pkg/controllers/chart"
 	helmcommon "github.com/k3s-io/helm-controller/pkg/controllers/common"
+	"github.com/k3s-io/k3s/pkg/carotation"
 	"github.com/k3s-io/k3s/pkg/cli/cmds"
 	"github.com/k3s-io/k3s/pkg/clientaccess"
+	"github.com/k3s-io/k3s/pkg/csrapprover"
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/daemons/control"
 	"github.com/k3s-io/k3s/pkg/datadir"
 	"github.com/k3s-io/k3s/pkg/deploy"
+	"github.com/k3s-io/k3s/pkg/desiredstate"
+	"github.com/k3s-io/k3s/pkg/images"
+	"github.com/k3s-io/k3s/pkg/maintenance"
 	"github.com/k3s-io/k3s/pkg/node"
 	"github.com/k3s-io/k3s/pkg/nodepassword"
+	"github.com/k3s-io/k3s/pkg/notify"
+	"github.com/k3s-io/k3s/pkg/restart"
 	"github.com/k3s-io/k3s/pkg/rootlessports"
 	"github.com/k3s-io/k3s/pkg/secretsencrypt"
 	"github.com/k3s-io/k3s/pkg/server/handlers"
 	"github.com/k3s-io/k3s/pkg/static"
+	"github.com/k3s-io/k3s/pkg/tracing"
 	"github.com/k3s-io/k3s/pkg/util"
 	"github.com/k3s-io/k3s/pkg/version"
 	"github.com/pkg/errors"
@@ -34,10 +47,15 @@ import (
 	"github.com/rancher/wrangler/v3/pkg/resolvehome"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	clientset "k8s.io/client-go/kubernetes"
 )
 
+// clusterDNSReservationLabel is set on Services that reserve additional cluster-dns addresses.
+var clusterDNSReservationLabel = version.Program + ".io/cluster-dns-reservation"
+
 func ResolveDataDir(dataDir string) (string, error) {
 	dataDir, err := datadir.Resolve(dataDir)
 	return filepath.Join(dataDir, "server"), err
@@ -52,14 +70,17 @@ func StartServer(ctx context.Context, config *Config, cfg *cmds.Server) error {
 		return err
 	}
 
-	if err := control.Server(ctx, &config.ControlConfig); err != nil {
+	controlCtx, span := tracing.Start(ctx, "server.control")
+	err := control.Server(controlCtx, &config.ControlConfig)
+	tracing.End(span, err)
+	if err != nil {
 		return errors.Wrap(err, "starting kubernetes")
 	}
 
 	wg := &sync.WaitGroup{}
 	wg.Add(len(config.StartupHooks))
 
-	config.ControlConfig.Runtime.Handler = handlers.NewHandler(ctx, &config.ControlConfig, cfg)
+	config.ControlConfig.Runtime.Handler = tracing.Handler(handlers.NewHandler(ctx, &config.ControlConfig, cfg), "supervisor")
 	config.ControlConfig.Runtime.StartupHooksWg = wg
 
 	shArgs := cmds.StartupHookArgs{
@@ -128,6 +149,15 @@ func runControllers(ctx context.Context, config *Config) error {
 		}
 	}
 
+	if controlConfig.EncryptSecrets && !controlConfig.DisableAPIServer {
+		secretsencrypt.RegisterStatusController(ctx, sc.Core.Core().V1().Node(), sc.K3s.K3s().V1().ClusterSecretsEncryption())
+	}
+
+	if !controlConfig.DisableAPIServer {
+		carotation.RegisterStatusController(ctx, sc.Core.Core().V1().Node(), sc.K3s.K3s().V1().ClusterCARotation())
+		desiredstate.RegisterController(ctx, sc.Core.Core().V1().Node(), sc.K3s.K3s().V1().NodeConfig(), sc.Core.Core().V1().ConfigMap())
+	}
+
 	if err := sc.Start(ctx); err != nil {
 		return errors.Wrap(err, "failed to start wranger controllers")
 	}
@@ -172,6 +202,12 @@ func apiserverControllers(ctx context.Context, sc *Context, config *Config) {
 	if err := sc.Start(ctx); err != nil {
 		panic(errors.Wrap(err, "failed to start wranger controllers"))
 	}
+
+	if !config.ControlConfig.Skips["coredns"] {
+		go reserveClusterDNSIPs(ctx, config, sc.Core.Core().V1().Service())
+	}
+
+	go checkNodeCIDRAllocations(ctx, &config.ControlConfig, sc.Core.Core().V1().Node())
 }
 
 // runOrDie is similar to leader.RunOrDie, except that it runs the callback
@@ -188,6 +224,9 @@ func runOrDie(ctx context.Context, name string, cb leader.Callback) {
 
 // coreControllers starts the following controllers, if they are enabled:
 // * Node controller (manages nodes passwords and coredns hosts file)
+// * Node lifecycle notifications
+// * Kubelet serving certificate signing request approver
+// * Packaged component restarts after configuration changes
 // * Helm controller
 // * Secrets encryption
 // * Rootless ports
@@ -201,11 +240,32 @@ func coreControllers(ctx context.Context, sc *Context, config *Config) error {
 		return err
 	}
 
-	// apply SystemDefaultRegistry setting to Helm before starting controllers
+	if config.ControlConfig.Runtime.Notifier != nil {
+		notify.RegisterNodeController(ctx, config.ControlConfig.Runtime.Notifier, sc.Core.Core().V1().Node())
+	}
+
+	if err := csrapprover.Register(ctx,
+		sc.K8s,
+		sc.Core.ControllerFactory(),
+		sc.Core.Core().V1().Node().Cache()); err != nil {
+		return err
+	}
+
+	if err := restart.Register(ctx,
+		restart.EnabledComponents(config.ControlConfig.Skips),
+		sc.Event,
+		sc.Apps.Apps().V1().Deployment(),
+		sc.Core.Core().V1().ConfigMap(),
+		sc.Core.Core().V1().Pod(),
+		sc.Core.Core().V1().Node()); err != nil {
+		return err
+	}
+
+	// apply SystemDefaultRegistry and SystemImages settings to Helm before starting controllers
 	if config.ControlConfig.HelmJobImage != "" {
 		helmchart.DefaultJobImage = config.ControlConfig.HelmJobImage
-	} else if config.ControlConfig.SystemDefaultRegistry != "" {
-		helmchart.DefaultJobImage = config.ControlConfig.SystemDefaultRegistry + "/" + helmchart.DefaultJobImage
+	} else {
+		helmchart.DefaultJobImage = images.Reference(images.HelmJob, config.ControlConfig.SystemDefaultRegistry, config.ControlConfig.SystemImages)
 	}
 
 	if !config.ControlConfig.DisableHelmController {
@@ -260,29 +320,70 @@ func stageFiles(ctx context.Context, sc *Context, controlConfig *config.Control)
 		return nil
 	}
 	dataDir := filepath.Join(controlConfig.DataDir, "static")
-	if err := static.Stage(dataDir); err != nil {
+	if err := static.Stage(dataDir, controlConfig.Skips); err != nil {
 		return err
 	}
 	dataDir = filepath.Join(controlConfig.DataDir, "manifests")
 
+	corednsIPs := controlConfig.ClusterDNSServiceIPs
+	if len(corednsIPs) == 0 {
+		corednsIPs = []net.IP{controlConfig.ClusterDNS}
+	}
 	dnsIPFamilyPolicy := "SingleStack"
-	if len(controlConfig.ClusterDNSs) > 1 {
+	if len(corednsIPs) > 1 {
 		dnsIPFamilyPolicy = "RequireDualStack"
 	}
 
+	serverCA, err := os.ReadFile(controlConfig.Runtime.ServerCA)
+	if err != nil {
+		return err
+	}
+	clientCA, err := os.ReadFile(controlConfig.Runtime.ClientCA)
+	if err != nil {
+		return err
+	}
+
 	templateVars := map[string]string{
-		"%{CLUSTER_DNS}%":                 controlConfig.ClusterDNS.String(),
-		"%{CLUSTER_DNS_LIST}%":            fmt.Sprintf("[%s]", util.JoinIPs(controlConfig.ClusterDNSs)),
+		"%{CLUSTER_DNS}%":                 corednsIPs[0].String(),
+		"%{CLUSTER_DNS_LIST}%":            fmt.Sprintf("[%s]", util.JoinIPs(corednsIPs)),
 		"%{CLUSTER_DNS_IPFAMILYPOLICY}%":  dnsIPFamilyPolicy,
 		"%{CLUSTER_DOMAIN}%":              controlConfig.ClusterDomain,
+		"%{CLUSTER_DOMAINS}%":             strings.Join(append([]string{controlConfig.ClusterDomain}, controlConfig.PreviousClusterDomains...), " "),
 		"%{DEFAULT_LOCAL_STORAGE_PATH}%":  controlConfig.DefaultLocalStoragePath,
 		"%{SYSTEM_DEFAULT_REGISTRY}%":     registryTemplate(controlConfig.SystemDefaultRegistry),
 		"%{SYSTEM_DEFAULT_REGISTRY_RAW}%": controlConfig.SystemDefaultRegistry,
 		"%{PREFERRED_ADDRESS_TYPES}%":     addrTypesPrioTemplate(controlConfig.FlannelExternalIP),
+		"%{SERVER_CA_BUNDLE}%":            base64.StdEncoding.EncodeToString(serverCA),
+		"%{CLIENT_CA_BUNDLE}%":            base64.StdEncoding.EncodeToString(clientCA),
+		"%{HTTPS_PORT}%":                  strconv.Itoa(controlConfig.HTTPSPort),
+	}
+	for k, v := range images.TemplateVars(controlConfig.SystemDefaultRegistry, controlConfig.SystemImages) {
+		templateVars[k] = v
 	}
 
-	skip := controlConfig.Skips
-	if err := deploy.Stage(dataDir, templateVars, skip); err != nil {
+	skip := map[string]bool{}
+	for _, skips := range []map[string]bool{controlConfig.Skips, controlConfig.SkipDeploys} {
+		for k, v := range skips {
+			skip[k] = v
+		}
+	}
+	if err := deploy.Stage(dataDir, templateVars, skip, controlConfig.PinDeploys); err != nil {
+		return err
+	}
+	if err := deploy.StageExtensions(filepath.Join(controlConfig.DataDir, config.ExtensionsDir), dataDir, templateVars, control.SchedulerExtendersFile); err != nil {
+		return err
+	}
+
+	// write the list of images used by packaged components, for tools that mirror images by digest
+	imageList, err := images.List(controlConfig.SystemDefaultRegistry, controlConfig.SystemImages)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(imageList, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := util.AtomicWrite(filepath.Join(controlConfig.DataDir, "images.json"), b, 0644); err != nil {
 		return err
 	}
 
@@ -300,11 +401,21 @@ func stageFiles(ctx context.Context, sc *Context, controlConfig *config.Control)
 	apply := apply.New(k8s, apply.NewClientFactory(restConfig)).WithDynamicLookup()
 	k3s := sc.K3s.WithAgent(restConfig.UserAgent)
 
+	// manifests are not applied while this server's node is in maintenance mode
+	nodes := sc.Core.Core().V1().Node().Cache()
+	paused := func() bool {
+		node, err := nodes.Get(controlConfig.ServerNodeName)
+		return err == nil && maintenance.Enabled(node)
+	}
+
 	return deploy.WatchFiles(ctx,
 		k8s,
 		apply,
 		k3s.V1().Addon(),
 		controlConfig.Disables,
+		controlConfig.SkipDeploys,
+		controlConfig.PinDeploys,
+		paused,
 		dataDir)
 }
 
@@ -575,18 +686,125 @@ func setNodeLabelsAndAnnotations(ctx context.Context, nodes v1.NodeClient, confi
 	return nil
 }
 
+// reservedClusterDNS returns the cluster-dns addresses that are reserved for node-local DNS caches: those
+// that are within the service CIDRs, but are not used by the coredns service. Addresses outside the service
+// CIDRs cannot be allocated to Services, and do not need to be reserved.
+func reservedClusterDNS(ips, corednsIPs []net.IP, serviceCIDRs []*net.IPNet) []net.IP {
+	var reserved []net.IP
+	for _, ip := range ips {
+		if slices.ContainsFunc(corednsIPs, ip.Equal) {
+			continue
+		}
+		if slices.ContainsFunc(serviceCIDRs, func(cidr *net.IPNet) bool { return cidr.Contains(ip) }) {
+			reserved = append(reserved, ip)
+		}
+	}
+	return reserved
+}
+
+// clusterDNSReservation returns a Service that reserves an additional cluster-dns address, so that it
+// cannot be allocated to any other Service. The Service selects the coredns pods, so that the address
+// can be queried before a node-local DNS cache is deployed to intercept traffic to it.
+func clusterDNSReservation(ip net.IP) *corev1.Service {
+	name := strings.ReplaceAll(ip.String(), ".", "-")
+	family := corev1.IPv4Protocol
+	if ip.To4() == nil {
+		name = hex.EncodeToString(ip.To16())
+		family = corev1.IPv6Protocol
+	}
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kube-dns-" + name,
+			Namespace: metav1.NamespaceSystem,
+			Labels: map[string]string{
+				clusterDNSReservationLabel: "true",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector:   map[string]string{"k8s-app": "kube-dns"},
+			ClusterIP:  ip.String(),
+			ClusterIPs: []string{ip.String()},
+			IPFamilies: []corev1.IPFamily{family},
+			Ports: []corev1.ServicePort{
+				{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP},
+				{Name: "dns-tcp", Port: 53, Protocol: corev1.ProtocolTCP},
+			},
+		},
+	}
+}
+
+// reserveClusterDNSIPs creates Services for the additional cluster-dns addresses, and deletes Services
+// for addresses that are no longer configured.
+func reserveClusterDNSIPs(ctx context.Context, config *Config, services v1.ServiceClient) {
+	reserved := reservedClusterDNS(config.ControlConfig.ClusterDNSs, config.ControlConfig.ClusterDNSServiceIPs, config.ControlConfig.ServiceIPRanges)
+	wanted := map[string]*corev1.Service{}
+	for _, ip := range reserved {
+		svc := clusterDNSReservation(ip)
+		wanted[svc.Name] = svc
+	}
+	if err := wait.PollUntilContextCancel(ctx, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		existing, err := services.List(metav1.NamespaceSystem, metav1.ListOptions{LabelSelector: clusterDNSReservationLabel})
+		if err != nil {
+			logrus.Warnf("Failed to list cluster-dns reservations: %v", err)
+			return false, nil
+		}
+		for _, svc := range existing.Items {
+			if _, ok := wanted[svc.Name]; ok {
+				continue
+			}
+			logrus.Infof("Deleting cluster-dns reservation for %s", svc.Spec.ClusterIP)
+			if err := services.Delete(svc.Namespace, svc.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				logrus.Warnf("Failed to delete cluster-dns reservation for %s: %v", svc.Spec.ClusterIP, err)
+				return false, nil
+			}
+		}
+		for name, svc := range wanted {
+			if _, err := services.Create(svc); err != nil {
+				if apierrors.IsAlreadyExists(err) {
+					continue
+				}
+				// The address is already allocated to another service.
+				// Retrying will not succeed until the configuration is changed, so give up on this address.
+				if apierrors.IsInvalid(err) {
+					logrus.Errorf("Unable to reserve cluster-dns address %s: %v; delete the service using this address, or remove it from --cluster-dns and restart", svc.Spec.ClusterIP, err)
+					delete(wanted, name)
+					continue
+				}
+				logrus.Warnf("Failed to reserve cluster-dns address %s: %v", svc.Spec.ClusterIP, err)
+				return false, nil
+			}
+			logrus.Infof("Reserved cluster-dns address %s", svc.Spec.ClusterIP)
+		}
+		return true, nil
+	}); err != nil && ctx.Err() == nil {
+		logrus.Errorf("Failed to reserve cluster-dns addresses: %v", err)
+	}
+}
+
 func setClusterDNSConfig(ctx context.Context, config *Config, configMap v1.ConfigMapClient) error {
 	if config.ControlConfig.DisableAPIServer {
 		return nil
 	}
-	// check if configmap already exists
-	_, err := configMap.Get("kube-system", "cluster-dns", metav1.GetOptions{})
+	clusterDNS := config.ControlConfig.ClusterDNS
+	clusterDomain := config.ControlConfig.ClusterDomain
+	// check if configmap already exists, and update the cluster domain if it has been changed
+	existing, err := configMap.Get("kube-system", "cluster-dns", metav1.GetOptions{})
 	if err == nil {
+		if existing.Data["clusterDomain"] != clusterDomain {
+			existing = existing.DeepCopy()
+			if existing.Data == nil {
+				existing.Data = map[string]string{}
+			}
+			existing.Data["clusterDomain"] = clusterDomain
+			if _, err := configMap.Update(existing); err != nil {
+				return err
+			}
+			logrus.Infof("Cluster dns configmap has been updated with cluster domain %s", clusterDomain)
+			return nil
+		}
 		logrus.Infof("Cluster dns configmap already exists")
 		return nil
 	}
-	clusterDNS := config.ControlConfig.ClusterDNS
-	clusterDomain := config.ControlConfig.ClusterDomain
 	c := &corev1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "ConfigMap",
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_UnitReservedClusterDNS(t *testing.T) {
+	parse := func(ips ...string) []net.IP {
+		var parsed []net.IP
+		for _, ip := range ips {
+			parsed = append(parsed, net.ParseIP(ip))
+		}
+		return parsed
+	}
+	_, svcV4, _ := net.ParseCIDR("10.43.0.0/16")
+	_, svcV6, _ := net.ParseCIDR("fd00:43::/112")
+	serviceCIDRs := []*net.IPNet{svcV4, svcV6}
+	tests := []struct {
+		name         string
+		ips          []net.IP
+		corednsIPs   []net.IP
+		wantReserved []net.IP
+	}{
+		{
+			name:       "Single address",
+			ips:        parse("10.43.0.10"),
+			corednsIPs: parse("10.43.0.10"),
+		},
+		{
+			name:       "Dual-stack",
+			ips:        parse("fd00:43::a", "10.43.0.10"),
+			corednsIPs: parse("fd00:43::a", "10.43.0.10"),
+		},
+		{
+			name:         "Dual-stack with reserved addresses",
+			ips:          parse("10.43.0.10", "10.43.0.20", "fd00:43::a", "fd00:43::14"),
+			corednsIPs:   parse("10.43.0.10", "fd00:43::a"),
+			wantReserved: parse("10.43.0.20", "fd00:43::14"),
+		},
+		{
+			name:         "Service IP listed after reserved address",
+			ips:          parse("10.43.0.20", "10.43.0.10"),
+			corednsIPs:   parse("10.43.0.10"),
+			wantReserved: parse("10.43.0.20"),
+		},
+		{
+			name:       "Link-local node-local DNS cache",
+			ips:        parse("169.254.20.10"),
+			corednsIPs: parse("10.43.0.10"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reserved := reservedClusterDNS(tt.ips, tt.corednsIPs, serviceCIDRs); !reflect.DeepEqual(reserved, tt.wantReserved) {
+				t.Errorf("reservedClusterDNS() = %v, want %v", reserved, tt.wantReserved)
+			}
+		})
+	}
+}
+
+func Test_UnitClusterDNSReservation(t *testing.T) {
+	for ip, want := range map[string]string{
+		"10.43.0.20":  "kube-dns-10-43-0-20",
+		"fd00:43::14": "kube-dns-fd000043000000000000000000000014",
+	} {
+		svc := clusterDNSReservation(net.ParseIP(ip))
+		if svc.Name != want {
+			t.Errorf("clusterDNSReservation(%s) name = %s, want %s", ip, svc.Name, want)
+		}
+		if svc.Spec.ClusterIP != ip {
+			t.Errorf("clusterDNSReservation(%s) clusterIP = %s", ip, svc.Spec.ClusterIP)
+		}
+	}
+}
+
+func Test_UnitNodeCIDRConflicts(t *testing.T) {
+	_, clusterCIDR, _ := net.ParseCIDR("10.42.0.0/16")
+	node := func(name string, podCIDRs ...string) corev1.Node {
+		return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: corev1.NodeSpec{PodCIDRs: podCIDRs}}
+	}
+	tests := []struct {
+		name  string
+		nodes []corev1.Node
+		mask  int
+		want  []string
+	}{
+		{
+			name:  "No conflicts",
+			nodes: []corev1.Node{node("node-1", "10.42.0.0/24"), node("node-2", "10.42.1.0/24")},
+		},
+		{
+			name:  "Outside of cluster CIDR",
+			nodes: []corev1.Node{node("node-1", "10.44.0.0/24")},
+			want:  []string{"outside of the cluster-cidr"},
+		},
+		{
+			name:  "Mask size changed",
+			nodes: []corev1.Node{node("node-1", "10.42.0.0/24"), node("node-2", "10.42.1.0/25")},
+			mask:  25,
+			want:  []string{"10.42.0.0/24 of node node-1 is not a /25"},
+		},
+		{
+			name:  "Overlapping nodes",
+			nodes: []corev1.Node{node("node-1", "10.42.0.0/23"), node("node-2", "10.42.1.0/24")},
+			want:  []string{"is not a /24", "overlaps pod CIDR 10.42.0.0/23 of node node-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts := nodeCIDRConflicts(tt.nodes, []*net.IPNet{clusterCIDR}, tt.mask, 0)
+			if len(conflicts) != len(tt.want) {
+				t.Fatalf("nodeCIDRConflicts() = %q, want %d conflicts", conflicts, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(conflicts[i], want) {
+					t.Errorf("nodeCIDRConflicts() conflict %d = %q, want it to contain %q", i, conflicts[i], want)
+				}
+			}
+		})
+	}
+}
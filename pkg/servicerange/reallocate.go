@@ -0,0 +1,228 @@
+package servicerange
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	controllersv1 "github.com/k3s-io/k3s/pkg/generated/controllers/k3s.cattle.io/v1"
+	"github.com/rancher/wrangler/v3/pkg/apply"
+	v1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// ActionUpdate clears the node ports that are outside of the NodePort range, so that the
+	// apiserver allocates new ones.
+	ActionUpdate = "update"
+	// ActionRecreate deletes the service and creates it again without its cluster IPs, so that the
+	// apiserver allocates new ones, as cluster IPs cannot be changed.
+	ActionRecreate = "recreate"
+	// ActionDelete deletes the service, for services that are recreated by the apiserver or by the
+	// deploy controller with addresses from the new ranges.
+	ActionDelete = "delete"
+)
+
+// deleteTimeout is the time allowed for finalizers to be removed from a deleted service before it
+// is recreated.
+const deleteTimeout = 30 * time.Second
+
+// Request is the body of a request to reallocate services.
+type Request struct {
+	DryRun bool `json:"dryRun"`
+}
+
+// Change is a change made to a service to move it into the current ranges.
+type Change struct {
+	Service string   `json:"service"`
+	Action  string   `json:"action"`
+	Reasons []string `json:"reasons"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Result is the result of a request to reallocate services.
+type Result struct {
+	DryRun  bool     `json:"dryRun"`
+	Changes []Change `json:"changes"`
+}
+
+// Reallocate moves services with cluster IPs or node ports outside of the given ranges into the
+// ranges. Services are processed individually, and errors are reported in the result for each
+// service. If dryRun is set, the changes are reported but not made.
+func Reallocate(ctx context.Context, services v1.ServiceClient, addons controllersv1.AddonClient, serviceCIDRs []*net.IPNet, nodePortRange utilnet.PortRange, dryRun bool) (*Result, error) {
+	list, err := services.List(metav1.NamespaceAll, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{DryRun: dryRun, Changes: []Change{}}
+	for i := range list.Items {
+		svc := &list.Items[i]
+		change := plan(svc, serviceCIDRs, nodePortRange)
+		if change == nil {
+			continue
+		}
+		if !dryRun {
+			if err := reallocate(ctx, services, addons, svc, change.Action, nodePortRange); err != nil {
+				change.Error = err.Error()
+				logrus.Errorf("Failed to %s service %s: %v", change.Action, change.Service, err)
+			} else {
+				logrus.Infof("Reallocated service %s: %s", change.Service, strings.Join(change.Reasons, "; "))
+			}
+		}
+		result.Changes = append(result.Changes, *change)
+	}
+	return result, nil
+}
+
+// plan returns the change required to move the service into the given ranges, or nil if the
+// service is already within the ranges.
+func plan(svc *corev1.Service, serviceCIDRs []*net.IPNet, nodePortRange utilnet.PortRange) *Change {
+	change := &Change{Service: svc.Namespace + "/" + svc.Name}
+	recreate := false
+	for _, ip := range clusterIPs(svc) {
+		if !cidrsContainIP(serviceCIDRs, ip) {
+			change.Reasons = append(change.Reasons, fmt.Sprintf("cluster IP %s is outside of the service CIDRs", ip))
+			recreate = true
+		}
+	}
+	if port := svc.Spec.HealthCheckNodePort; port != 0 && !nodePortRange.Contains(int(port)) {
+		// the health check node port cannot be changed once allocated
+		change.Reasons = append(change.Reasons, fmt.Sprintf("health check node port %d is outside of the NodePort range", port))
+		recreate = true
+	}
+	for _, port := range svc.Spec.Ports {
+		if port.NodePort != 0 && !nodePortRange.Contains(int(port.NodePort)) {
+			change.Reasons = append(change.Reasons, fmt.Sprintf("node port %d is outside of the NodePort range", port.NodePort))
+		}
+	}
+
+	switch {
+	case len(change.Reasons) == 0:
+		return nil
+	case recreate && recreatedByOwner(svc):
+		change.Action = ActionDelete
+	case recreate:
+		change.Action = ActionRecreate
+	default:
+		change.Action = ActionUpdate
+	}
+	return change
+}
+
+// reallocate makes the change to the service.
+func reallocate(ctx context.Context, services v1.ServiceClient, addons controllersv1.AddonClient, svc *corev1.Service, action string, nodePortRange utilnet.PortRange) error {
+	svc = svc.DeepCopy()
+	for i, port := range svc.Spec.Ports {
+		if port.NodePort != 0 && !nodePortRange.Contains(int(port.NodePort)) {
+			svc.Spec.Ports[i].NodePort = 0
+		}
+	}
+	if action == ActionUpdate {
+		_, err := services.Update(svc)
+		return err
+	}
+
+	if err := services.Delete(svc.Namespace, svc.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if action == ActionDelete {
+		if addonOwned(svc) {
+			return redeploy(addons, svc)
+		}
+		return nil
+	}
+
+	// wait for finalizers, such as the load-balancer cleanup finalizer, to be removed
+	if err := wait.PollUntilContextTimeout(ctx, time.Second, deleteTimeout, true, func(ctx context.Context) (bool, error) {
+		_, err := services.Get(svc.Namespace, svc.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}); err != nil {
+		return fmt.Errorf("service was deleted but not recreated: %w", err)
+	}
+
+	svc.ObjectMeta = metav1.ObjectMeta{
+		Name:            svc.Name,
+		Namespace:       svc.Namespace,
+		Labels:          svc.Labels,
+		Annotations:     svc.Annotations,
+		OwnerReferences: svc.OwnerReferences,
+	}
+	svc.Spec.ClusterIP = ""
+	svc.Spec.ClusterIPs = nil
+	svc.Spec.IPFamilies = nil
+	if port := svc.Spec.HealthCheckNodePort; port != 0 && !nodePortRange.Contains(int(port)) {
+		svc.Spec.HealthCheckNodePort = 0
+	}
+	svc.Status = corev1.ServiceStatus{}
+	if _, err := services.Create(svc); err != nil {
+		return fmt.Errorf("service was deleted but not recreated: %w", err)
+	}
+	return nil
+}
+
+// clusterIPs returns the allocated cluster IPs of the service.
+func clusterIPs(svc *corev1.Service) []string {
+	ips := svc.Spec.ClusterIPs
+	if len(ips) == 0 && svc.Spec.ClusterIP != "" {
+		ips = []string{svc.Spec.ClusterIP}
+	}
+	allocated := []string{}
+	for _, ip := range ips {
+		if ip != "" && ip != corev1.ClusterIPNone {
+			allocated = append(allocated, ip)
+		}
+	}
+	return allocated
+}
+
+// cidrsContainIP returns true if the IP is within one of the CIDRs.
+func cidrsContainIP(cidrs []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(ip)
+	for _, cidr := range cidrs {
+		if cidr.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// recreatedByOwner returns true if the service is recreated after being deleted: the kubernetes
+// service is recreated by the apiserver, and services from manifests are recreated by the deploy
+// controller, with cluster IPs from the current ranges.
+func recreatedByOwner(svc *corev1.Service) bool {
+	return (svc.Namespace == metav1.NamespaceDefault && svc.Name == "kubernetes") || addonOwned(svc)
+}
+
+// addonOwned returns true if the service was applied from a manifest by the deploy controller.
+func addonOwned(svc *corev1.Service) bool {
+	return strings.HasSuffix(svc.Annotations[apply.LabelGVK], "Kind=Addon")
+}
+
+// redeploy clears the checksum of the addon that owns the service and touches its manifest, so
+// that the deploy controller applies the manifest again and recreates the service.
+func redeploy(addons controllersv1.AddonClient, svc *corev1.Service) error {
+	addon, err := addons.Get(svc.Annotations[apply.LabelNamespace], svc.Annotations[apply.LabelName], metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("service was deleted but its addon could not be redeployed: %w", err)
+	}
+	addon.Spec.Checksum = ""
+	if _, err := addons.Update(addon); err != nil {
+		return fmt.Errorf("service was deleted but its addon could not be redeployed: %w", err)
+	}
+	now := time.Now()
+	if err := os.Chtimes(addon.Spec.Source, now, now); err != nil {
+		return fmt.Errorf("service was deleted but its addon could not be redeployed: %w", err)
+	}
+	return nil
+}
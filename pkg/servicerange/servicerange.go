@@ -0,0 +1,131 @@
+// Package servicerange detects changes to the service CIDRs and NodePort range that would leave
+// existing services with cluster IPs or node ports outside of the configured ranges, and
+// reallocates the cluster IPs and node ports of those services where possible.
+package servicerange
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// stateFile is the name of the file in the server data dir that records the ranges that the
+// server was last started with.
+const stateFile = "service-ranges.json"
+
+// State holds the service CIDRs and NodePort range that the server was started with.
+type State struct {
+	ServiceCIDRs  []string `json:"serviceCIDRs"`
+	NodePortRange string   `json:"nodePortRange"`
+}
+
+// NewState returns the state for the given service CIDRs and NodePort range.
+func NewState(serviceCIDRs []*net.IPNet, nodePortRange *utilnet.PortRange) *State {
+	s := &State{}
+	for _, cidr := range serviceCIDRs {
+		s.ServiceCIDRs = append(s.ServiceCIDRs, cidr.String())
+	}
+	if nodePortRange != nil {
+		s.NodePortRange = nodePortRange.String()
+	}
+	return s
+}
+
+// Check compares the current service CIDRs and NodePort range to those that the server was last
+// started with, and returns an error if they have changed such that existing services may be outside
+// of the current ranges, unless allow is set. Expanding a range is always allowed. The current
+// ranges are recorded if they are allowed.
+func Check(dataDir string, current *State, allow bool) error {
+	path := filepath.Join(dataDir, stateFile)
+	previous := &State{}
+	b, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		previous = current
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(b, previous); err != nil {
+			return errors.Wrapf(err, "failed to parse %s", path)
+		}
+	}
+
+	if changes := incompatibleChanges(previous, current); len(changes) > 0 {
+		if !allow {
+			return fmt.Errorf("%s; existing services may have cluster IPs or node ports outside of the new ranges. "+
+				"To continue, restart %s with --allow-service-range-change, then run '%s services reallocate' to move services into the new ranges, "+
+				"or revert the change", strings.Join(changes, "; "), version.Program, version.Program)
+		}
+		logrus.Warnf("%s; run '%s services reallocate' to move existing services into the new ranges", strings.Join(changes, "; "), version.Program)
+	}
+
+	if b, err = json.Marshal(current); err != nil {
+		return err
+	}
+	return util.AtomicWrite(path, b, 0600)
+}
+
+// incompatibleChanges returns descriptions of changes to the ranges that do not include all of the
+// previous ranges.
+func incompatibleChanges(previous, current *State) []string {
+	changes := []string{}
+	if !cidrsContain(current.ServiceCIDRs, previous.ServiceCIDRs) {
+		changes = append(changes, fmt.Sprintf("service-cidr changed from %s to %s", strings.Join(previous.ServiceCIDRs, ","), strings.Join(current.ServiceCIDRs, ",")))
+	}
+	if !portRangeContains(current.NodePortRange, previous.NodePortRange) {
+		changes = append(changes, fmt.Sprintf("service-node-port-range changed from %s to %s", previous.NodePortRange, current.NodePortRange))
+	}
+	return changes
+}
+
+// cidrsContain returns true if every CIDR in inner is contained within a CIDR in outer.
+func cidrsContain(outer, inner []string) bool {
+	for _, i := range inner {
+		_, in, err := net.ParseCIDR(i)
+		if err != nil {
+			return false
+		}
+		inOnes, inBits := in.Mask.Size()
+		found := false
+		for _, o := range outer {
+			_, out, err := net.ParseCIDR(o)
+			if err != nil {
+				continue
+			}
+			outOnes, outBits := out.Mask.Size()
+			if outBits == inBits && outOnes <= inOnes && out.Contains(in.IP) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// portRangeContains returns true if the inner port range is contained within the outer port range.
+func portRangeContains(outer, inner string) bool {
+	if inner == "" || outer == inner {
+		return true
+	}
+	out, err := utilnet.ParsePortRange(outer)
+	if err != nil {
+		return false
+	}
+	in, err := utilnet.ParsePortRange(inner)
+	if err != nil {
+		return false
+	}
+	return out.Base <= in.Base && in.Base+in.Size <= out.Base+out.Size
+}
@@ -0,0 +1,105 @@
+package servicerange
+
+import (
+	"net"
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/apply"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+func mustState(t *testing.T, portRange string, cidrs ...string) *State {
+	nets := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nets = append(nets, n)
+	}
+	pr, err := utilnet.ParsePortRange(portRange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewState(nets, pr)
+}
+
+func Test_UnitCheck(t *testing.T) {
+	dataDir := t.TempDir()
+	initial := mustState(t, "30000-32767", "10.43.0.0/16")
+	if err := Check(dataDir, initial, false); err != nil {
+		t.Fatalf("expected first start to be allowed: %v", err)
+	}
+	if err := Check(dataDir, initial, false); err != nil {
+		t.Fatalf("expected unchanged ranges to be allowed: %v", err)
+	}
+
+	expanded := mustState(t, "30000-32767", "10.42.0.0/15", "fd00:43::/112")
+	if err := Check(dataDir, expanded, false); err != nil {
+		t.Fatalf("expected expanded ranges to be allowed: %v", err)
+	}
+
+	for _, changed := range []*State{
+		mustState(t, "30000-32767", "10.44.0.0/16"),
+		mustState(t, "31000-32767", "10.42.0.0/15"),
+	} {
+		if err := Check(dataDir, changed, false); err == nil {
+			t.Errorf("expected change to %v to be refused", changed)
+		}
+	}
+
+	changed := mustState(t, "31000-32767", "10.44.0.0/16")
+	if err := Check(dataDir, changed, true); err != nil {
+		t.Fatalf("expected allowed change to be accepted: %v", err)
+	}
+	if err := Check(dataDir, changed, false); err != nil {
+		t.Fatalf("expected accepted change to be recorded: %v", err)
+	}
+}
+
+func Test_UnitPlan(t *testing.T) {
+	_, cidr, _ := net.ParseCIDR("10.44.0.0/16")
+	cidrs := []*net.IPNet{cidr}
+	portRange := utilnet.PortRange{Base: 31000, Size: 1000}
+
+	service := func(namespace, name string, clusterIP string, nodePort int32, annotations map[string]string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Annotations: annotations},
+			Spec: corev1.ServiceSpec{
+				ClusterIP:  clusterIP,
+				ClusterIPs: []string{clusterIP},
+				Ports:      []corev1.ServicePort{{Port: 80, NodePort: nodePort}},
+			},
+		}
+	}
+	addon := map[string]string{apply.LabelGVK: "k3s.cattle.io/v1, Kind=Addon"}
+
+	tests := []struct {
+		name    string
+		service *corev1.Service
+		action  string
+	}{
+		{name: "in range", service: service("default", "web", "10.44.0.20", 31080, nil)},
+		{name: "headless", service: service("default", "headless", corev1.ClusterIPNone, 0, nil)},
+		{name: "node port", service: service("default", "web", "10.44.0.20", 30080, nil), action: ActionUpdate},
+		{name: "cluster IP", service: service("default", "web", "10.43.0.20", 31080, nil), action: ActionRecreate},
+		{name: "kubernetes", service: service("default", "kubernetes", "10.43.0.1", 0, nil), action: ActionDelete},
+		{name: "addon", service: service("kube-system", "kube-dns", "10.43.0.10", 0, addon), action: ActionDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := plan(tt.service, cidrs, portRange)
+			if tt.action == "" {
+				if change != nil {
+					t.Errorf("expected no change, got %+v", change)
+				}
+				return
+			}
+			if change == nil || change.Action != tt.action {
+				t.Errorf("expected action %s, got %+v", tt.action, change)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package simulate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// IssueError is the level of issues that will cause stored data or APIs to be unusable after the upgrade.
+	IssueError = "error"
+	// IssueWarning is the level of issues that may need action after the upgrade, or that could not be checked.
+	IssueWarning = "warning"
+)
+
+// registryPrefix is the datastore key prefix under which the apiserver stores objects.
+const registryPrefix = "/registry/"
+
+// listLimit is the number of keys or objects requested at a time.
+const listLimit = 500
+
+var (
+	// protobufPrefix is the prefix of objects that are stored by the apiserver in protobuf encoding.
+	protobufPrefix = []byte{'k', '8', 's', 0}
+	// encryptedPrefix is the prefix of objects that are stored encrypted.
+	encryptedPrefix = []byte("k8s:enc:")
+	// internalTypes are stored by the apiserver for its own use, and are not served.
+	internalTypes = []StoredType{{APIVersion: "v1", Kind: "RangeAllocation"}}
+	crdResource   = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+)
+
+// Issue is a compatibility issue found in the simulated control plane.
+type Issue struct {
+	Level   string
+	Message string
+}
+
+// StoredType is the API version and kind of objects stored in the datastore.
+type StoredType struct {
+	APIVersion string
+	Kind       string
+}
+
+func (t StoredType) String() string {
+	return t.APIVersion + " " + t.Kind
+}
+
+// Check compares the objects stored in the restored datastore against the APIs served by the simulated
+// control plane, and returns the issues that were found.
+func Check(ctx context.Context, restConfig *rest.Config, etcdClient *clientv3.Client) ([]Issue, error) {
+	var issues []Issue
+
+	stored, encrypted, err := StoredTypes(ctx, etcdClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read stored objects")
+	}
+	if encrypted > 0 {
+		issues = append(issues, Issue{Level: IssueWarning, Message: fmt.Sprintf("%d objects are encrypted at rest; their stored versions were checked by reading them through the apiserver only", encrypted)})
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	_, resourceLists, err := discoveryClient.ServerGroupsAndResources()
+	if err != nil {
+		failed, ok := err.(*discovery.ErrGroupDiscoveryFailed)
+		if !ok {
+			return nil, errors.Wrap(err, "failed to discover served APIs")
+		}
+		for gv, err := range failed.Groups {
+			issues = append(issues, Issue{Level: IssueWarning, Message: fmt.Sprintf("API %s could not be discovered and was not checked; aggregated APIs are not available in the simulated control plane: %v", gv, err)})
+		}
+	}
+	served := servedKinds(resourceLists)
+	issues = append(issues, storedTypeIssues(stored, served)...)
+
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	crds, err := dynamicClient.Resource(crdResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list CustomResourceDefinitions")
+	}
+	crdIssues, webhookGroups := checkCRDs(crds.Items)
+	issues = append(issues, crdIssues...)
+
+	metadataClient, err := metadata.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	preferred, err := discoveryClient.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, errors.Wrap(err, "failed to discover preferred API versions")
+	}
+	lastApplied := map[StoredType]int{}
+	for _, list := range preferred {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil || slices.Contains(webhookGroups, gv.Group) {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			if !slices.Contains(resource.Verbs, "list") || strings.Contains(resource.Name, "/") {
+				continue
+			}
+			gvr := gv.WithResource(resource.Name)
+			if err := listMetadata(ctx, metadataClient, gvr, func(obj *metav1.PartialObjectMetadata) {
+				if t, ok := lastAppliedType(obj.Annotations); ok && !served[t] {
+					lastApplied[t]++
+				}
+			}); err != nil {
+				issues = append(issues, Issue{Level: IssueError, Message: fmt.Sprintf("Stored %s cannot be read: %v", gvr.GroupResource(), err)})
+			}
+		}
+	}
+	for _, t := range sortedTypes(lastApplied) {
+		issues = append(issues, Issue{Level: IssueWarning, Message: fmt.Sprintf("%d objects were last applied from manifests using %s, which is not served; update the manifests before applying them again", lastApplied[t], t)})
+	}
+	return issues, nil
+}
+
+// StoredTypes returns the number of objects of each type in the datastore, and the number of objects
+// whose type cannot be read because they are encrypted.
+func StoredTypes(ctx context.Context, client *clientv3.Client) (map[StoredType]int, int, error) {
+	stored := map[StoredType]int{}
+	encrypted := 0
+	key := registryPrefix
+	end := clientv3.GetPrefixRangeEnd(registryPrefix)
+	for {
+		resp, err := client.Get(ctx, key, clientv3.WithRange(end), clientv3.WithLimit(listLimit))
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, kv := range resp.Kvs {
+			if bytes.HasPrefix(kv.Value, encryptedPrefix) {
+				encrypted++
+			} else if t, ok := decodeType(kv.Value); ok {
+				stored[t]++
+			}
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			return stored, encrypted, nil
+		}
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+}
+
+// decodeType returns the type of a stored object, which is stored in either protobuf or JSON encoding.
+func decodeType(value []byte) (StoredType, bool) {
+	var typeMeta runtime.TypeMeta
+	if bytes.HasPrefix(value, protobufPrefix) {
+		unknown := &runtime.Unknown{}
+		if err := unknown.Unmarshal(value[len(protobufPrefix):]); err != nil {
+			return StoredType{}, false
+		}
+		typeMeta = unknown.TypeMeta
+	} else if err := json.Unmarshal(value, &typeMeta); err != nil {
+		return StoredType{}, false
+	}
+	if typeMeta.APIVersion == "" || typeMeta.Kind == "" {
+		return StoredType{}, false
+	}
+	return StoredType{APIVersion: typeMeta.APIVersion, Kind: typeMeta.Kind}, true
+}
+
+// servedKinds returns the types that are served by the apiserver.
+func servedKinds(resourceLists []*metav1.APIResourceList) map[StoredType]bool {
+	served := map[StoredType]bool{}
+	for _, list := range resourceLists {
+		for _, resource := range list.APIResources {
+			served[StoredType{APIVersion: list.GroupVersion, Kind: resource.Kind}] = true
+		}
+	}
+	return served
+}
+
+// storedTypeIssues returns an issue for each stored type that is not served.
+func storedTypeIssues(stored map[StoredType]int, served map[StoredType]bool) []Issue {
+	var issues []Issue
+	for _, t := range sortedTypes(stored) {
+		if served[t] || slices.Contains(internalTypes, t) {
+			continue
+		}
+		issues = append(issues, Issue{Level: IssueError, Message: fmt.Sprintf("%d objects are stored as %s, which is not served; they cannot be read after the upgrade", stored[t], t)})
+	}
+	return issues
+}
+
+// checkCRDs returns an issue for each CustomResourceDefinition with objects stored in a version that
+// is no longer defined, and the groups of CustomResourceDefinitions whose objects cannot be read because
+// they use conversion webhooks, which are not available in the simulated control plane.
+func checkCRDs(crds []unstructured.Unstructured) ([]Issue, []string) {
+	var issues []Issue
+	var webhookGroups []string
+	for _, crd := range crds {
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		if strategy, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "strategy"); strategy == "Webhook" {
+			webhookGroups = append(webhookGroups, group)
+			issues = append(issues, Issue{Level: IssueWarning, Message: fmt.Sprintf("CustomResourceDefinition %s uses a conversion webhook, which is not available in the simulated control plane; its objects were not checked", crd.GetName())})
+		}
+		var defined []string
+		versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+		for _, v := range versions {
+			if version, ok := v.(map[string]interface{}); ok {
+				if name, ok := version["name"].(string); ok {
+					defined = append(defined, name)
+				}
+			}
+		}
+		storedVersions, _, _ := unstructured.NestedStringSlice(crd.Object, "status", "storedVersions")
+		for _, version := range storedVersions {
+			if !slices.Contains(defined, version) {
+				issues = append(issues, Issue{Level: IssueError, Message: fmt.Sprintf("CustomResourceDefinition %s has objects stored as version %s, which is no longer defined; they cannot be read after the upgrade", crd.GetName(), version)})
+			}
+		}
+	}
+	return issues, webhookGroups
+}
+
+// lastAppliedType returns the type of the manifest that the object was last applied from with kubectl apply.
+func lastAppliedType(annotations map[string]string) (StoredType, bool) {
+	lastApplied, ok := annotations[corev1.LastAppliedConfigAnnotation]
+	if !ok {
+		return StoredType{}, false
+	}
+	return decodeType([]byte(lastApplied))
+}
+
+// listMetadata calls f with the metadata of each object of the resource.
+func listMetadata(ctx context.Context, client metadata.Interface, gvr schema.GroupVersionResource, f func(*metav1.PartialObjectMetadata)) error {
+	opts := metav1.ListOptions{Limit: listLimit}
+	for {
+		list, err := client.Resource(gvr).List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for i := range list.Items {
+			f(&list.Items[i])
+		}
+		if list.Continue == "" {
+			return nil
+		}
+		opts.Continue = list.Continue
+	}
+}
+
+// sortedTypes returns the types in the map, sorted by API version and kind.
+func sortedTypes(types map[StoredType]int) []StoredType {
+	var sorted []StoredType
+	for t := range types {
+		sorted = append(sorted, t)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].String() < sorted[j].String()
+	})
+	return sorted
+}
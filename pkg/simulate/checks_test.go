@@ -0,0 +1,127 @@
+package simulate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func Test_UnitDecodeType(t *testing.T) {
+	unknown := &runtime.Unknown{TypeMeta: runtime.TypeMeta{APIVersion: "policy/v1beta1", Kind: "PodSecurityPolicy"}, Raw: []byte{0x0a}}
+	raw, err := unknown.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		value  []byte
+		want   StoredType
+		wantOk bool
+	}{
+		{
+			name:   "JSON",
+			value:  []byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"test"}}`),
+			want:   StoredType{APIVersion: "apps/v1", Kind: "Deployment"},
+			wantOk: true,
+		},
+		{
+			name:   "Protobuf",
+			value:  append(append([]byte{}, protobufPrefix...), raw...),
+			want:   StoredType{APIVersion: "policy/v1beta1", Kind: "PodSecurityPolicy"},
+			wantOk: true,
+		},
+		{
+			name:  "No type",
+			value: []byte(`{"metadata":{"name":"test"}}`),
+		},
+		{
+			name:  "Invalid",
+			value: []byte("not an object"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := decodeType(tt.value)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("decodeType() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_UnitStoredTypeIssues(t *testing.T) {
+	stored := map[StoredType]int{
+		{APIVersion: "apps/v1", Kind: "Deployment"}:               3,
+		{APIVersion: "policy/v1beta1", Kind: "PodSecurityPolicy"}: 2,
+		{APIVersion: "v1", Kind: "RangeAllocation"}:               2,
+	}
+	served := map[StoredType]bool{{APIVersion: "apps/v1", Kind: "Deployment"}: true}
+	issues := storedTypeIssues(stored, served)
+	if len(issues) != 1 || issues[0].Level != IssueError {
+		t.Errorf("storedTypeIssues() = %v, want one error for PodSecurityPolicy", issues)
+	}
+}
+
+func Test_UnitCheckCRDs(t *testing.T) {
+	crd := func(name, group, strategy string, versions []interface{}, storedVersions []interface{}) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name},
+			"spec": map[string]interface{}{
+				"group":      group,
+				"versions":   versions,
+				"conversion": map[string]interface{}{"strategy": strategy},
+			},
+			"status": map[string]interface{}{"storedVersions": storedVersions},
+		}}
+	}
+	version := func(name string) interface{} {
+		return map[string]interface{}{"name": name}
+	}
+	crds := []unstructured.Unstructured{
+		crd("widgets.example.com", "example.com", "None", []interface{}{version("v1")}, []interface{}{"v1"}),
+		crd("gadgets.example.com", "example.com", "None", []interface{}{version("v1")}, []interface{}{"v1alpha1", "v1"}),
+		crd("gizmos.webhook.example.com", "webhook.example.com", "Webhook", []interface{}{version("v1"), version("v2")}, []interface{}{"v1"}),
+	}
+	issues, webhookGroups := checkCRDs(crds)
+	if len(issues) != 2 || issues[0].Level != IssueError || issues[1].Level != IssueWarning {
+		t.Errorf("checkCRDs() issues = %v, want one error and one warning", issues)
+	}
+	if len(webhookGroups) != 1 || webhookGroups[0] != "webhook.example.com" {
+		t.Errorf("checkCRDs() webhookGroups = %v, want [webhook.example.com]", webhookGroups)
+	}
+}
+
+func Test_UnitLatestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LatestSnapshot(dir); err == nil {
+		t.Error("LatestSnapshot() of empty dir did not return an error")
+	}
+	now := time.Now()
+	for i, name := range []string{"etcd-snapshot-2", "etcd-snapshot-3", "etcd-snapshot-1"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(time.Duration(i) * time.Minute)
+		if name == "etcd-snapshot-3" {
+			modTime = now.Add(time.Hour)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "etcd-snapshot-dir"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := LatestSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "etcd-snapshot-3"); latest != want {
+		t.Errorf("LatestSnapshot() = %s, want %s", latest, want)
+	}
+}
@@ -0,0 +1,235 @@
+package simulate
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/etcd/snapshot"
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	certutil "github.com/rancher/dynamiclistener/cert"
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	snapshotv3 "go.etcd.io/etcd/etcdutl/v3/snapshot"
+	"go.etcd.io/etcd/server/v3/embed"
+	"go.uber.org/zap"
+)
+
+// memberName is the name of the single etcd member that serves the restored snapshot.
+const memberName = "simulate"
+
+// startTimeout is the time allowed for the restored datastore to become ready.
+const startTimeout = 2 * time.Minute
+
+// Datastore is a throwaway single-member etcd cluster restored from a snapshot. It listens only on
+// ports chosen at random on the loopback address, so that it does not conflict with a running server.
+// As the datastore holds a full copy of the cluster, including secrets, the client and peer ports
+// require TLS client certificates signed by a CA generated for the datastore.
+type Datastore struct {
+	DataDir   string
+	ClientURL string
+	PeerURL   string
+	// CAFile, CertFile, and KeyFile are the CA certificate, and the certificate and key used by both
+	// the datastore and its clients.
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	etcd     *embed.Etcd
+}
+
+// NewDatastore returns a datastore that stores its data and certificates in the given directory.
+func NewDatastore(dir string) (*Datastore, error) {
+	clientPort, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	peerPort, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	d := &Datastore{
+		DataDir:   filepath.Join(dir, "data"),
+		ClientURL: fmt.Sprintf("https://127.0.0.1:%d", clientPort),
+		PeerURL:   fmt.Sprintf("https://127.0.0.1:%d", peerPort),
+		CAFile:    filepath.Join(dir, "tls", "ca.crt"),
+		CertFile:  filepath.Join(dir, "tls", "datastore.crt"),
+		KeyFile:   filepath.Join(dir, "tls", "datastore.key"),
+	}
+	if err := d.generateCerts(); err != nil {
+		return nil, errors.Wrap(err, "failed to generate datastore certificates")
+	}
+	return d, nil
+}
+
+// generateCerts generates a CA, and a certificate for the loopback address that is used for both
+// server and client authentication. The CA key is not kept, so no other certificates can be issued.
+func (d *Datastore) generateCerts() error {
+	caKey, err := certutil.NewPrivateKey()
+	if err != nil {
+		return err
+	}
+	caCert, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: version.Program + "-simulate-ca"}, caKey)
+	if err != nil {
+		return err
+	}
+	key, err := certutil.NewPrivateKey()
+	if err != nil {
+		return err
+	}
+	cert, err := certutil.NewSignedCert(certutil.Config{
+		CommonName: version.Program + "-simulate",
+		AltNames:   certutil.AltNames{IPs: []net.IP{net.ParseIP("127.0.0.1")}},
+		Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}, key, caCert, caKey)
+	if err != nil {
+		return err
+	}
+	if err := certutil.WriteCert(d.CAFile, certutil.EncodeCertPEM(caCert)); err != nil {
+		return err
+	}
+	if err := certutil.WriteCert(d.CertFile, certutil.EncodeCertPEM(cert)); err != nil {
+		return err
+	}
+	return certutil.WriteKey(d.KeyFile, certutil.EncodePrivateKeyPEM(key))
+}
+
+// TLSInfo returns the TLS configuration for clients of the datastore.
+func (d *Datastore) TLSInfo() transport.TLSInfo {
+	return transport.TLSInfo{
+		TrustedCAFile: d.CAFile,
+		CertFile:      d.CertFile,
+		KeyFile:       d.KeyFile,
+	}
+}
+
+// Restore restores the snapshot at the given path into the datastore. Compressed and incremental
+// snapshots are supported.
+func (d *Datastore) Restore(snapshotPath string) error {
+	restorePath := snapshotPath
+	skipHashCheck := false
+	switch {
+	case strings.HasSuffix(snapshotPath, snapshot.DeltaExtension):
+		f, err := os.CreateTemp("", "etcd-snapshot-")
+		if err != nil {
+			return err
+		}
+		f.Close()
+		defer os.Remove(f.Name())
+		if err := snapshot.Reconstruct(snapshotPath, f.Name()); err != nil {
+			return errors.Wrap(err, "failed to reconstruct snapshot")
+		}
+		// the reconstructed database does not carry the hash that etcd appends to snapshots
+		restorePath = f.Name()
+		skipHashCheck = true
+	case strings.HasSuffix(snapshotPath, snapshot.CompressedExtension):
+		extracted, err := snapshot.Extract(snapshotPath)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(extracted)
+		restorePath = extracted
+	}
+	if !skipHashCheck {
+		if _, err := snapshot.Check(restorePath); err != nil {
+			return errors.Wrapf(err, "etcd snapshot %s failed verification", snapshotPath)
+		}
+	}
+
+	return snapshotv3.NewV3(zap.NewNop()).Restore(snapshotv3.RestoreConfig{
+		SnapshotPath:   restorePath,
+		Name:           memberName,
+		OutputDataDir:  d.DataDir,
+		PeerURLs:       []string{d.PeerURL},
+		InitialCluster: memberName + "=" + d.PeerURL,
+		SkipHashCheck:  skipHashCheck,
+	})
+}
+
+// Start starts the etcd member, and waits for it to be ready.
+func (d *Datastore) Start(ctx context.Context) error {
+	clientURL, err := url.Parse(d.ClientURL)
+	if err != nil {
+		return err
+	}
+	peerURL, err := url.Parse(d.PeerURL)
+	if err != nil {
+		return err
+	}
+	cfg := embed.NewConfig()
+	cfg.Name = memberName
+	cfg.Dir = d.DataDir
+	cfg.ListenClientUrls = []url.URL{*clientURL}
+	cfg.AdvertiseClientUrls = []url.URL{*clientURL}
+	cfg.ListenPeerUrls = []url.URL{*peerURL}
+	cfg.AdvertisePeerUrls = []url.URL{*peerURL}
+	cfg.InitialCluster = memberName + "=" + d.PeerURL
+	cfg.ClientTLSInfo = d.TLSInfo()
+	cfg.ClientTLSInfo.ClientCertAuth = true
+	cfg.PeerTLSInfo = d.TLSInfo()
+	cfg.PeerTLSInfo.ClientCertAuth = true
+	cfg.LogLevel = "error"
+
+	if d.etcd, err = embed.StartEtcd(cfg); err != nil {
+		return err
+	}
+	select {
+	case <-d.etcd.Server.ReadyNotify():
+		return nil
+	case err := <-d.etcd.Err():
+		return errors.Wrap(err, "restored datastore exited")
+	case <-time.After(startTimeout):
+		return errors.New("timed out waiting for the restored datastore to be ready")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the etcd member.
+func (d *Datastore) Close() {
+	if d.etcd != nil {
+		d.etcd.Close()
+	}
+}
+
+// LatestSnapshot returns the path of the most recently created snapshot in the given directory.
+func LatestSnapshot(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var latest string
+	var latestTime time.Time
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if latest == "" || info.ModTime().After(latestTime) {
+			latest = filepath.Join(dir, entry.Name())
+			latestTime = info.ModTime()
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("no snapshots found in %s", dir)
+	}
+	return latest, nil
+}
+
+// freePort returns a port on the loopback address that is not currently in use.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
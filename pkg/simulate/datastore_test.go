@@ -0,0 +1,43 @@
+package simulate
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_UnitNewDatastore(t *testing.T) {
+	d, err := NewDatastore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDatastore() error = %v", err)
+	}
+	for _, url := range []string{d.ClientURL, d.PeerURL} {
+		if !strings.HasPrefix(url, "https://127.0.0.1:") {
+			t.Errorf("NewDatastore() URL = %s, want https on the loopback address", url)
+		}
+	}
+	if info, err := os.Stat(d.KeyFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("key file was not saved with mode 0600: %v, %v", info, err)
+	}
+
+	// the certificate is valid for both the datastore and its clients
+	tlsConfig, err := d.TLSInfo().ClientConfig()
+	if err != nil {
+		t.Fatalf("TLSInfo().ClientConfig() error = %v", err)
+	}
+	cert, err := tls.LoadX509KeyPair(d.CertFile, d.KeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, usage := range []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: "127.0.0.1", Roots: tlsConfig.RootCAs, KeyUsages: []x509.ExtKeyUsage{usage}}); err != nil {
+			t.Errorf("certificate does not verify for usage %v: %v", usage, err)
+		}
+	}
+}
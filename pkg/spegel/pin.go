@@ -0,0 +1,316 @@
+package spegel
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/namespaces"
+	criLabels "github.com/containerd/containerd/pkg/cri/labels"
+	"github.com/distribution/reference"
+
+	v1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	k3s "github.com/k3s-io/k3s/pkg/generated/controllers/k3s.cattle.io"
+	controllersv1 "github.com/k3s-io/k3s/pkg/generated/controllers/k3s.cattle.io/v1"
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spegel-org/spegel/pkg/oci"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/pager"
+)
+
+const (
+	// pinDelay is the time to wait after an image is created or updated before pinning it.
+	pinDelay = 5 * time.Second
+	// pinGCInterval is the interval at which unused pins are deleted.
+	pinGCInterval = time.Hour
+	// pinGCMinAge is the minimum age of a pin before it is deleted if not in use.
+	pinGCMinAge = 24 * time.Hour
+)
+
+// manifestPathRegex matches registry API manifest requests, capturing the repository and reference.
+var manifestPathRegex = regexp.MustCompile(`^/v2/(.+)/manifests/([^/]+)$`)
+
+// pinner pins image tags to the digest that they first resolved to on a server in the cluster,
+// using ImageDigestPin resources. Tags pulled on servers are pinned if they are not already, and
+// requests to resolve a pinned tag through the embedded registry are answered with the pinned
+// digest, so that containerd pulls the same manifest on every node even if the upstream tag has
+// since been moved. Only servers create and delete pins; agents only resolve tags to existing pins.
+type pinner struct {
+	nodeName  string
+	latestTag bool
+	server    bool
+
+	// pinned returns true if an image is pinned in containerd, as is the case for images imported
+	// or pre-pulled from the agent images directory, and the sandbox image. These images are not
+	// pinned, as they were not resolved from the tag by a registry.
+	pinned func(ctx context.Context, name string) bool
+
+	mu    sync.RWMutex
+	pins  controllersv1.ImageDigestPinClient
+	cache controllersv1.ImageDigestPinCache
+}
+
+// pinName returns the ImageDigestPin resource name for an image reference.
+func pinName(image string) string {
+	h := sha256.Sum256([]byte(image))
+	return hex.EncodeToString(h[:])
+}
+
+// lookup returns the pin for an image reference, or nil if the tag is not pinned or the pin cache
+// has not yet synced.
+func (p *pinner) lookup(image string) *v1.ImageDigestPin {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.cache == nil {
+		return nil
+	}
+	pin, err := p.cache.Get(pinName(image))
+	if err != nil || pin.Spec.Image != image {
+		return nil
+	}
+	return pin
+}
+
+// handler wraps the registry API handler. Manifest requests by tag for a pinned image are resolved
+// to the pinned digest; HEAD requests are answered directly from the pin, while GET requests are
+// passed to the registry by digest. All other requests are passed through unmodified.
+func (p *pinner) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		match := manifestPathRegex.FindStringSubmatch(req.URL.Path)
+		registry := req.URL.Query().Get("ns")
+		if match == nil || registry == "" {
+			next.ServeHTTP(resp, req)
+			return
+		}
+		if _, err := digest.Parse(match[2]); err == nil {
+			next.ServeHTTP(resp, req)
+			return
+		}
+		pin := p.lookup(registry + "/" + match[1] + ":" + match[2])
+		if pin == nil {
+			next.ServeHTTP(resp, req)
+			return
+		}
+		logrus.Debugf("Resolved pinned image %s to %s", pin.Spec.Image, pin.Spec.Digest)
+		if req.Method == http.MethodHead {
+			resp.Header().Set("Content-Type", pin.Spec.MediaType)
+			resp.Header().Set("Content-Length", strconv.FormatInt(pin.Spec.Size, 10))
+			resp.Header().Set("Docker-Content-Digest", pin.Spec.Digest)
+			resp.WriteHeader(http.StatusOK)
+			return
+		}
+		req = req.Clone(req.Context())
+		req.URL.Path = "/v2/" + match[1] + "/manifests/" + pin.Spec.Digest
+		req.URL.RawPath = ""
+		next.ServeHTTP(resp, req)
+	})
+}
+
+// run waits for the apiserver and starts the pin cache. On servers, it then pins tagged images as
+// they are pulled, and periodically deletes pins for tags that are no longer in use.
+func (p *pinner) run(ctx context.Context, kubeConfig string, ociClient oci.Client) error {
+	if err := util.WaitForAPIServerReady(ctx, kubeConfig, util.DefaultAPIServerReadyTimeout); err != nil {
+		return errors.Wrap(err, "failed to wait for apiserver ready")
+	}
+	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
+	if err != nil {
+		return err
+	}
+	factory, err := k3s.NewFactoryFromConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	controller := factory.K3s().V1().ImageDigestPin()
+	cache := controller.Cache()
+	// Start also waits for the cache to sync
+	if err := factory.Start(ctx, 1); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.pins, p.cache = controller, cache
+	p.mu.Unlock()
+	if !p.server {
+		logrus.Info("Embedded registry mirror is resolving image tags to pinned digests")
+		return nil
+	}
+	logrus.Info("Embedded registry mirror is pinning image tags to digests")
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := p.gc(ctx, client); err != nil {
+			logrus.Warnf("Failed to delete unused image digest pins: %v", err)
+		}
+	}, pinGCInterval)
+
+	if images, err := ociClient.ListImages(ctx); err != nil {
+		logrus.Warnf("Failed to list images to pin: %v", err)
+	} else {
+		for _, img := range images {
+			p.pin(ctx, ociClient, img)
+		}
+	}
+	eventCh, errCh, err := ociClient.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-errCh:
+			if !ok {
+				return nil
+			}
+			logrus.Warnf("Failed to handle image event: %v", err)
+		case event, ok := <-eventCh:
+			if !ok {
+				return nil
+			}
+			if event.Type == oci.DeleteEvent {
+				continue
+			}
+			// Imported images are labeled after they are created, so wait for the labels to be
+			// applied before checking whether the image should be pinned.
+			go func() {
+				select {
+				case <-ctx.Done():
+				case <-time.After(pinDelay):
+					p.pin(ctx, ociClient, event.Image)
+				}
+			}()
+		}
+	}
+}
+
+// pin creates a pin for a tagged image, if the tag is not already pinned. If the tag is pinned to a
+// different digest, a warning is logged, as the image was pulled without going through the
+// embedded registry, or before the tag was pinned. Errors are logged, so that a failure to pin one
+// image does not prevent others from being pinned.
+func (p *pinner) pin(ctx context.Context, ociClient oci.Client, img oci.Image) {
+	if img.Tag == "" || (img.Tag == "latest" && !p.latestTag) {
+		return
+	}
+	image := img.Registry + "/" + img.Repository + ":" + img.Tag
+	if pin := p.lookup(image); pin != nil {
+		if pin.Spec.Digest != img.Digest.String() {
+			logrus.Warnf("Image %s on this node is %s, but the tag is pinned to %s", image, img.Digest, pin.Spec.Digest)
+		}
+		return
+	}
+	if p.pinned != nil && p.pinned(ctx, img.Name) {
+		logrus.Debugf("Not pinning image %s: image is pinned in containerd", image)
+		return
+	}
+	b, mediaType, err := ociClient.GetManifest(ctx, img.Digest)
+	if err != nil {
+		logrus.Warnf("Failed to get manifest for image %s: %v", image, err)
+		return
+	}
+	pin := &v1.ImageDigestPin{
+		ObjectMeta: metav1.ObjectMeta{Name: pinName(image)},
+		Spec: v1.ImageDigestPinSpec{
+			Image:     image,
+			Digest:    img.Digest.String(),
+			MediaType: mediaType,
+			Size:      int64(len(b)),
+			NodeName:  p.nodeName,
+		},
+	}
+	if _, err := p.pins.Create(pin); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			logrus.Warnf("Failed to pin image %s: %v", image, err)
+		}
+		return
+	}
+	logrus.Infof("Pinned image %s to %s", image, img.Digest)
+}
+
+// gc deletes pins older than pinGCMinAge for tags that are not used by any pod, so that the tag is
+// pinned to its current digest the next time that it is pulled.
+func (p *pinner) gc(ctx context.Context, client kubernetes.Interface) error {
+	inUse := map[string]bool{}
+	err := pager.New(func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, opts)
+	}).EachListItem(ctx, metav1.ListOptions{}, func(obj runtime.Object) error {
+		pod := obj.(*corev1.Pod)
+		for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+			for _, container := range containers {
+				inUse[normalizeImage(container.Image)] = true
+			}
+		}
+		for _, container := range pod.Spec.EphemeralContainers {
+			inUse[normalizeImage(container.Image)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	pins, err := p.cache.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, pin := range pins {
+		if inUse[pin.Spec.Image] || time.Since(pin.CreationTimestamp.Time) < pinGCMinAge {
+			continue
+		}
+		if err := p.pins.Delete(pin.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			logrus.Warnf("Failed to delete unused pin for image %s: %v", pin.Spec.Image, err)
+			continue
+		}
+		logrus.Infof("Deleted unused pin for image %s", pin.Spec.Image)
+	}
+	return nil
+}
+
+// containerdPinned returns a function that checks whether an image is pinned in containerd.
+func containerdPinned(ociClient *oci.Containerd) func(ctx context.Context, name string) bool {
+	return func(ctx context.Context, name string) bool {
+		client, err := ociClient.Client()
+		if err != nil {
+			return false
+		}
+		image, err := client.ImageService().Get(namespaces.WithNamespace(ctx, registryNamespace), name)
+		if err != nil {
+			return false
+		}
+		return image.Labels[criLabels.PinnedImageLabelKey] == criLabels.PinnedImageLabelValue
+	}
+}
+
+// normalizeImage returns the fully-qualified tagged reference for a pod's container image, as used
+// for pin names. An empty string is returned for images that cannot be parsed, or that are
+// referenced by digest.
+func normalizeImage(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return ""
+	}
+	tagged, ok := reference.TagNameOnly(named).(reference.Tagged)
+	if !ok {
+		return ""
+	}
+	return named.Name() + ":" + tagged.Tag()
+}
@@ -0,0 +1,213 @@
+package spegel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v1 "github.com/k3s-io/k3s/pkg/apis/k3s.cattle.io/v1"
+	controllersv1 "github.com/k3s-io/k3s/pkg/generated/controllers/k3s.cattle.io/v1"
+	"github.com/opencontainers/go-digest"
+	"github.com/spegel-org/spegel/pkg/oci"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type pinCache struct {
+	controllersv1.ImageDigestPinCache
+	pins map[string]*v1.ImageDigestPin
+}
+
+func (c *pinCache) Get(name string) (*v1.ImageDigestPin, error) {
+	if pin, ok := c.pins[name]; ok {
+		return pin, nil
+	}
+	return nil, apierrors.NewNotFound(v1.Resource("imagedigestpin"), name)
+}
+
+func (c *pinCache) List(selector labels.Selector) ([]*v1.ImageDigestPin, error) {
+	pins := []*v1.ImageDigestPin{}
+	for _, pin := range c.pins {
+		pins = append(pins, pin)
+	}
+	return pins, nil
+}
+
+// pinClient creates and deletes pins in the cache.
+type pinClient struct {
+	controllersv1.ImageDigestPinClient
+	cache *pinCache
+}
+
+func (c *pinClient) Create(pin *v1.ImageDigestPin) (*v1.ImageDigestPin, error) {
+	if _, ok := c.cache.pins[pin.Name]; ok {
+		return nil, apierrors.NewAlreadyExists(v1.Resource("imagedigestpin"), pin.Name)
+	}
+	c.cache.pins[pin.Name] = pin
+	return pin, nil
+}
+
+func (c *pinClient) Delete(name string, opts *metav1.DeleteOptions) error {
+	if _, ok := c.cache.pins[name]; !ok {
+		return apierrors.NewNotFound(v1.Resource("imagedigestpin"), name)
+	}
+	delete(c.cache.pins, name)
+	return nil
+}
+
+func Test_UnitPin(t *testing.T) {
+	manifest := []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[]}`)
+	dgst := digest.FromBytes(manifest)
+	ociClient := oci.NewMemory()
+	ociClient.AddBlob(manifest, dgst)
+
+	cache := &pinCache{pins: map[string]*v1.ImageDigestPin{}}
+	p := &pinner{
+		nodeName: "server-1",
+		server:   true,
+		cache:    cache,
+		pins:     &pinClient{cache: cache},
+		pinned: func(_ context.Context, name string) bool {
+			return name == "docker.io/library/imported:1.0"
+		},
+	}
+
+	images := map[string]bool{
+		"docker.io/library/busybox:1.36":   true,
+		"docker.io/library/busybox:latest": false,
+		"docker.io/library/imported:1.0":   false,
+		"docker.io/library/missing:1.0":    false,
+	}
+	for name := range images {
+		img, err := oci.Parse(name, dgst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "docker.io/library/missing:1.0" {
+			img.Digest = digest.FromString("missing")
+		}
+		p.pin(context.Background(), ociClient, img)
+	}
+	for name, want := range images {
+		pin := p.lookup(name)
+		if got := pin != nil; got != want {
+			t.Errorf("expected image %s pinned=%t, got %t", name, want, got)
+		}
+		if pin != nil && (pin.Spec.Digest != dgst.String() || pin.Spec.Size != int64(len(manifest)) || pin.Spec.NodeName != "server-1") {
+			t.Errorf("unexpected pin for image %s: %+v", name, pin.Spec)
+		}
+	}
+}
+
+func Test_UnitPinGC(t *testing.T) {
+	old := metav1.NewTime(time.Now().Add(-2 * pinGCMinAge))
+	cache := &pinCache{pins: map[string]*v1.ImageDigestPin{}}
+	for image, created := range map[string]metav1.Time{
+		"docker.io/library/busybox:1.36":   old,
+		"docker.io/library/nginx:latest":   old,
+		"registry.example.com/app:v1":      old,
+		"docker.io/library/unused:1.0":     old,
+		"docker.io/library/new-unused:1.0": metav1.Now(),
+	} {
+		cache.pins[pinName(image)] = &v1.ImageDigestPin{
+			ObjectMeta: metav1.ObjectMeta{Name: pinName(image), CreationTimestamp: created},
+			Spec:       v1.ImageDigestPinSpec{Image: image},
+		}
+	}
+	client := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Image: "busybox:1.36"}},
+			Containers:     []corev1.Container{{Image: "nginx"}, {Image: "registry.example.com/app:v1"}},
+		},
+	})
+	p := &pinner{server: true, cache: cache, pins: &pinClient{cache: cache}}
+	if err := p.gc(context.Background(), client); err != nil {
+		t.Fatal(err)
+	}
+	for _, image := range []string{"docker.io/library/busybox:1.36", "docker.io/library/nginx:latest", "registry.example.com/app:v1", "docker.io/library/new-unused:1.0"} {
+		if p.lookup(image) == nil {
+			t.Errorf("expected pin for image %s to be kept", image)
+		}
+	}
+	if p.lookup("docker.io/library/unused:1.0") != nil {
+		t.Errorf("expected unused pin to be deleted")
+	}
+}
+
+func Test_UnitNormalizeImage(t *testing.T) {
+	const digestHex = "0000000000000000000000000000000000000000000000000000000000000001"
+	for image, want := range map[string]string{
+		"busybox":                          "docker.io/library/busybox:latest",
+		"busybox:1.36":                     "docker.io/library/busybox:1.36",
+		"rancher/mirrored-pause:3.6":       "docker.io/rancher/mirrored-pause:3.6",
+		"registry.example.com:5000/app:v1": "registry.example.com:5000/app:v1",
+		"busybox@sha256:" + digestHex:      "",
+		"busybox:1.36@sha256:" + digestHex: "",
+		"Invalid Image":                    "",
+	} {
+		if got := normalizeImage(image); got != want {
+			t.Errorf("normalizeImage(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func Test_UnitPinHandler(t *testing.T) {
+	const (
+		image = "docker.io/library/busybox:1.36"
+		dgst  = "sha256:0000000000000000000000000000000000000000000000000000000000000001"
+	)
+	p := &pinner{cache: &pinCache{pins: map[string]*v1.ImageDigestPin{
+		pinName(image): {
+			ObjectMeta: metav1.ObjectMeta{Name: pinName(image)},
+			Spec:       v1.ImageDigestPinSpec{Image: image, Digest: dgst, MediaType: "application/vnd.oci.image.index.v1+json", Size: 1234},
+		},
+	}}}
+
+	var nextPath string
+	handler := p.handler(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		nextPath = req.URL.Path
+		resp.WriteHeader(http.StatusNotFound)
+	}))
+
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		status   int
+		nextPath string
+	}{
+		{name: "head pinned tag", method: http.MethodHead, url: "/v2/library/busybox/manifests/1.36?ns=docker.io", status: http.StatusOK},
+		{name: "get pinned tag", method: http.MethodGet, url: "/v2/library/busybox/manifests/1.36?ns=docker.io", status: http.StatusNotFound, nextPath: "/v2/library/busybox/manifests/" + dgst},
+		{name: "unpinned tag", method: http.MethodHead, url: "/v2/library/busybox/manifests/1.37?ns=docker.io", status: http.StatusNotFound, nextPath: "/v2/library/busybox/manifests/1.37"},
+		{name: "other registry", method: http.MethodHead, url: "/v2/library/busybox/manifests/1.36?ns=registry.example.com", status: http.StatusNotFound, nextPath: "/v2/library/busybox/manifests/1.36"},
+		{name: "digest", method: http.MethodHead, url: "/v2/library/busybox/manifests/" + dgst + "?ns=docker.io", status: http.StatusNotFound, nextPath: "/v2/library/busybox/manifests/" + dgst},
+		{name: "blob", method: http.MethodGet, url: "/v2/library/busybox/blobs/" + dgst + "?ns=docker.io", status: http.StatusNotFound, nextPath: "/v2/library/busybox/blobs/" + dgst},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nextPath = ""
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, httptest.NewRequest(tt.method, tt.url, nil))
+			if resp.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, resp.Code)
+			}
+			if nextPath != tt.nextPath {
+				t.Errorf("expected request to be passed to %q, got %q", tt.nextPath, nextPath)
+			}
+			if tt.status == http.StatusOK {
+				if got := resp.Header().Get("Docker-Content-Digest"); got != dgst {
+					t.Errorf("expected digest %s, got %s", dgst, got)
+				}
+				if got := resp.Header().Get("Content-Length"); got != "1234" {
+					t.Errorf("expected content length 1234, got %s", got)
+				}
+			}
+		})
+	}
+}
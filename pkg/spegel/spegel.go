@@ -84,6 +84,10 @@ type Config struct {
 
 	// HandlerFunc will be called to add the registry API handler to an existing router.
 	Router https.RouterFunc
+
+	// ServerKubeConfig returns the path to the kubeconfig used to create and delete image digest
+	// pins. It is only set on servers; agents only read pins.
+	ServerKubeConfig func() string
 }
 
 // These values are not currently configurable
@@ -229,7 +233,21 @@ func (c *Config) Start(ctx context.Context, nodeConfig *config.Node) error {
 	if err != nil {
 		return err
 	}
-	mRouter.PathPrefix("/v2").Handler(regSvr.Handler)
+	if nodeConfig.EmbeddedRegistryPin {
+		p := &pinner{nodeName: nodeConfig.AgentConfig.NodeName, latestTag: resolveLatestTag, pinned: containerdPinned(ociClient)}
+		kubeConfig := nodeConfig.AgentConfig.KubeConfigK3sController
+		if c.ServerKubeConfig != nil {
+			kubeConfig, p.server = c.ServerKubeConfig(), true
+		}
+		go func() {
+			if err := p.run(ctx, kubeConfig, ociClient); err != nil && !errors.Is(err, context.Canceled) {
+				logrus.Errorf("Embedded registry image digest pinning failed: %v", err)
+			}
+		}()
+		mRouter.PathPrefix("/v2").Handler(p.handler(regSvr.Handler))
+	} else {
+		mRouter.PathPrefix("/v2").Handler(regSvr.Handler)
+	}
 	mRouter.PathPrefix("/v1-{program}/p2p").Handler(c.peerInfo())
 
 	// Wait up to 5 seconds for the p2p network to find peers. This will return
@@ -0,0 +1,50 @@
+package startup
+
+import (
+	"context"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// minPlausibleTime is the earliest time that the system clock can plausibly be set to. It predates
+// this release, so any clock earlier than this has not been set, as on devices without a real-time
+// clock that boot with the clock at the epoch.
+var minPlausibleTime = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// ClockFloor returns the earliest time that the system clock can plausibly be set to: the later of
+// minPlausibleTime and the time at which the binary was built. The build time is fixed when the
+// binary is built, unlike file modification times, which may be set to any time when the binary is
+// copied or extracted.
+func ClockFloor() time.Time {
+	floor := minPlausibleTime
+	if buildDate, err := time.Parse(time.RFC3339, version.BuildDate); err == nil && buildDate.After(floor) {
+		floor = buildDate
+	}
+	return floor
+}
+
+// WaitForPlausibleClock blocks until the system clock is no earlier than the floor, less the
+// tolerance. Certificates issued while the clock is wrong are not valid once the clock is set, so
+// certificate generation must not begin until this returns. An error is only returned if the
+// context is cancelled.
+func WaitForPlausibleClock(ctx context.Context, floor time.Time, tolerance time.Duration) error {
+	earliest := floor.Add(-tolerance)
+	if !time.Now().Before(earliest) {
+		if now := time.Now(); now.Before(floor) {
+			logrus.Warnf("System clock %s is earlier than %s; continuing as it is within the insecure skew tolerance of %s", now.Format(time.RFC3339), floor.Format(time.RFC3339), tolerance)
+		}
+		return nil
+	}
+
+	logrus.Warnf("System clock %s is earlier than %s; waiting for the clock to be set before generating certificates. Set --insecure-skew-tolerance to start anyway", time.Now().Format(time.RFC3339), floor.Format(time.RFC3339))
+	if err := wait.PollUntilContextCancel(ctx, gatePollInterval, false, func(ctx context.Context) (bool, error) {
+		return !time.Now().Before(earliest), nil
+	}); err != nil {
+		return err
+	}
+	logrus.Infof("System clock has been set to %s, continuing startup", time.Now().Format(time.RFC3339))
+	return nil
+}
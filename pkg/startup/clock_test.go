@@ -0,0 +1,50 @@
+package startup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/version"
+)
+
+func Test_UnitWaitForPlausibleClock(t *testing.T) {
+	defer func(interval time.Duration) { gatePollInterval = interval }(gatePollInterval)
+	gatePollInterval = 10 * time.Millisecond
+
+	// A clock later than the floor does not wait
+	if err := WaitForPlausibleClock(context.Background(), time.Now().Add(-time.Hour), 0); err != nil {
+		t.Errorf("WaitForPlausibleClock() with plausible clock error = %v", err)
+	}
+
+	// A clock earlier than the floor does not wait if within the tolerance
+	if err := WaitForPlausibleClock(context.Background(), time.Now().Add(time.Hour), 2*time.Hour); err != nil {
+		t.Errorf("WaitForPlausibleClock() within tolerance error = %v", err)
+	}
+
+	// A clock earlier than the floor waits until the context is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := WaitForPlausibleClock(ctx, time.Now().Add(time.Hour), time.Minute); err == nil {
+		t.Errorf("WaitForPlausibleClock() with implausible clock succeeded, want error")
+	}
+}
+
+func Test_UnitClockFloor(t *testing.T) {
+	defer func(buildDate string) { version.BuildDate = buildDate }(version.BuildDate)
+
+	for _, tt := range []struct {
+		buildDate string
+		want      time.Time
+	}{
+		{"", minPlausibleTime},
+		{"not-a-date", minPlausibleTime},
+		{"2020-01-01T00:00:00Z", minPlausibleTime},
+		{"2030-06-01T12:00:00Z", time.Date(2030, time.June, 1, 12, 0, 0, 0, time.UTC)},
+	} {
+		version.BuildDate = tt.buildDate
+		if floor := ClockFloor(); !floor.Equal(tt.want) {
+			t.Errorf("ClockFloor() with build date %q = %s, want %s", tt.buildDate, floor, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package startup
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Startup gates that can be waited for before the supervisor begins bootstrapping.
+const (
+	// GateInterfaceAddress waits for a network interface to have a global unicast address. An
+	// interface name may be given as an argument, for example interface-address=eth0; otherwise
+	// any interface is accepted.
+	GateInterfaceAddress = "interface-address"
+	// GateDefaultRoute waits for an IPv4 or IPv6 default route to exist.
+	GateDefaultRoute = "default-route"
+	// GateClockSync waits for the kernel to report that the system clock is synchronized.
+	GateClockSync = "clock-sync"
+)
+
+// gatePollInterval is the interval at which unsatisfied gates are checked.
+var gatePollInterval = 5 * time.Second
+
+// Gate is a condition that must be met before startup continues.
+type Gate struct {
+	Name  string
+	Arg   string
+	check func() error
+}
+
+func (g Gate) String() string {
+	if g.Arg != "" {
+		return g.Name + "=" + g.Arg
+	}
+	return g.Name
+}
+
+// ParseGates parses a list of gates, in the form NAME or NAME=ARG.
+func ParseGates(specs []string) ([]Gate, error) {
+	gates := []Gate{}
+	for _, spec := range specs {
+		name, arg, _ := strings.Cut(strings.TrimSpace(spec), "=")
+		gate := Gate{Name: name, Arg: arg}
+		switch name {
+		case GateInterfaceAddress:
+			gate.check = func() error { return checkInterfaceAddress(arg, interfaceAddrs) }
+		case GateDefaultRoute:
+			if arg != "" {
+				return nil, fmt.Errorf("invalid startup gate %q: %s does not take an argument", spec, name)
+			}
+			gate.check = checkDefaultRoute
+		case GateClockSync:
+			if arg != "" {
+				return nil, fmt.Errorf("invalid startup gate %q: %s does not take an argument", spec, name)
+			}
+			gate.check = checkClockSync
+		default:
+			return nil, fmt.Errorf("invalid startup gate %q: must be one of %s", spec, strings.Join([]string{GateInterfaceAddress, GateDefaultRoute, GateClockSync}, ", "))
+		}
+		gates = append(gates, gate)
+	}
+	return gates, nil
+}
+
+// WaitForGates parses the list of gates, and blocks until all gates are satisfied or the timeout
+// expires. Startup continues with a warning if the timeout expires, as the conditions may be satisfied
+// later without intervention; an error is only returned if a gate is invalid or the context is
+// cancelled. A timeout of zero waits indefinitely.
+func WaitForGates(ctx context.Context, specs []string, timeout time.Duration) error {
+	gates, err := ParseGates(specs)
+	if err != nil || len(gates) == 0 {
+		return err
+	}
+	return waitForGates(ctx, gates, timeout)
+}
+
+func waitForGates(ctx context.Context, gates []Gate, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	pending := gates
+	err := wait.PollUntilContextCancel(ctx, gatePollInterval, true, func(ctx context.Context) (bool, error) {
+		remaining := []Gate{}
+		for _, gate := range pending {
+			if err := gate.check(); err != nil {
+				logrus.Infof("Waiting for startup gate %s: %v", gate, err)
+				remaining = append(remaining, gate)
+			} else {
+				logrus.Infof("Startup gate %s satisfied", gate)
+			}
+		}
+		pending = remaining
+		return len(pending) == 0, nil
+	})
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		names := make([]string, len(pending))
+		for i, gate := range pending {
+			names[i] = gate.String()
+		}
+		logrus.Warnf("Startup gates not satisfied after %s, continuing: %s", timeout, strings.Join(names, ", "))
+		return nil
+	}
+	return err
+}
+
+// interfaceAddrs returns the addresses of the named interface, or of all interfaces if name is empty.
+func interfaceAddrs(name string) ([]net.Addr, error) {
+	if name == "" {
+		return net.InterfaceAddrs()
+	}
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return iface.Addrs()
+}
+
+// checkInterfaceAddress returns an error if the interface does not have a global unicast address.
+func checkInterfaceAddress(name string, addrs func(string) ([]net.Addr, error)) error {
+	list, err := addrs(name)
+	if err != nil {
+		return err
+	}
+	for _, addr := range list {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.IsGlobalUnicast() {
+			return nil
+		}
+	}
+	if name != "" {
+		return fmt.Errorf("interface %s has no global unicast address", name)
+	}
+	return fmt.Errorf("no interface has a global unicast address")
+}
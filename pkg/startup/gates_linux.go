@@ -0,0 +1,66 @@
+//go:build linux
+// +build linux
+
+package startup
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// checkDefaultRoute returns an error if there is no IPv4 or IPv6 default route.
+func checkDefaultRoute() error {
+	if ok, err := hasDefaultRoute("/proc/net/route", isIPv4DefaultRoute); ok || err != nil {
+		return err
+	}
+	if ok, err := hasDefaultRoute("/proc/net/ipv6_route", isIPv6DefaultRoute); ok || (err != nil && !os.IsNotExist(err)) {
+		return err
+	}
+	return errors.New("no default route")
+}
+
+// hasDefaultRoute returns true if any line of the route table file is a default route.
+func hasDefaultRoute(file string, isDefault func(fields []string) bool) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if isDefault(strings.Fields(scanner.Text())) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// isIPv4DefaultRoute returns true if the fields of a /proc/net/route line describe a default route:
+// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+func isIPv4DefaultRoute(fields []string) bool {
+	return len(fields) >= 8 && fields[1] == "00000000" && fields[7] == "00000000" && fields[0] != "lo"
+}
+
+// isIPv6DefaultRoute returns true if the fields of a /proc/net/ipv6_route line describe a default route:
+// Destination PrefixLen Source SourcePrefixLen NextHop Metric RefCnt Use Flags Iface
+func isIPv6DefaultRoute(fields []string) bool {
+	return len(fields) >= 10 && fields[0] == strings.Repeat("0", 32) && fields[1] == "00" && fields[9] != "lo"
+}
+
+// checkClockSync returns an error if the kernel does not consider the system clock to be synchronized.
+// The kernel's synchronization status is maintained by NTP clients such as chrony and systemd-timesyncd.
+func checkClockSync() error {
+	timex := &unix.Timex{}
+	state, err := unix.Adjtimex(timex)
+	if err != nil {
+		return err
+	}
+	if state == unix.TIME_ERROR || timex.Status&unix.STA_UNSYNC != 0 {
+		return errors.New("system clock is not synchronized")
+	}
+	return nil
+}
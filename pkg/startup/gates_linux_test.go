@@ -0,0 +1,61 @@
+//go:build linux
+// +build linux
+
+package startup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_UnitHasDefaultRoute(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name      string
+		content   string
+		isDefault func([]string) bool
+		want      bool
+	}{
+		{
+			name: "ipv4 default route",
+			content: "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+				"eth0\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+				"eth0\t0001A8C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n",
+			isDefault: isIPv4DefaultRoute,
+			want:      true,
+		},
+		{
+			name: "ipv4 link routes only",
+			content: "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+				"eth0\t0001A8C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n",
+			isDefault: isIPv4DefaultRoute,
+		},
+		{
+			name:      "ipv6 default route",
+			content:   "00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000001 00000000 00450003 eth0\n",
+			isDefault: isIPv6DefaultRoute,
+			want:      true,
+		},
+		{
+			name:      "ipv6 unreachable default on loopback",
+			content:   "00000000000000000000000000000000 00 00000000000000000000000000000000 00 00000000000000000000000000000000 ffffffff 00000001 00000000 00200200 lo\n",
+			isDefault: isIPv6DefaultRoute,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, string(rune('a'+i)))
+			if err := os.WriteFile(file, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := hasDefaultRoute(file, tt.isDefault)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("hasDefaultRoute() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
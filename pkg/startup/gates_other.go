@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package startup
+
+import "errors"
+
+func checkDefaultRoute() error {
+	return errors.New("default route startup gate is only supported on Linux")
+}
+
+func checkClockSync() error {
+	return errors.New("clock sync startup gate is only supported on Linux")
+}
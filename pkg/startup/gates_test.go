@@ -0,0 +1,112 @@
+package startup
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func Test_UnitParseGates(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "no gates",
+			want: []string{},
+		},
+		{
+			name:  "all gates",
+			specs: []string{"interface-address=eth0", " default-route", "clock-sync"},
+			want:  []string{"interface-address=eth0", "default-route", "clock-sync"},
+		},
+		{
+			name:    "unknown gate",
+			specs:   []string{"dhcp"},
+			wantErr: true,
+		},
+		{
+			name:    "unexpected argument",
+			specs:   []string{"clock-sync=ntp"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gates, err := ParseGates(tt.specs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(gates) != len(tt.want) {
+				t.Fatalf("ParseGates() returned %d gates, want %d", len(gates), len(tt.want))
+			}
+			for i, gate := range gates {
+				if gate.String() != tt.want[i] {
+					t.Errorf("ParseGates()[%d] = %s, want %s", i, gate, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_UnitCheckInterfaceAddress(t *testing.T) {
+	addrs := map[string][]net.Addr{
+		"lo":   {&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)}},
+		"eth0": {&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)}},
+		"eth1": {&net.IPNet{IP: net.ParseIP("192.168.1.10"), Mask: net.CIDRMask(24, 32)}},
+	}
+	lookup := func(name string) ([]net.Addr, error) {
+		if name == "" {
+			return append(addrs["lo"], addrs["eth1"]...), nil
+		}
+		if a, ok := addrs[name]; ok {
+			return a, nil
+		}
+		return nil, errors.New("no such network interface")
+	}
+	for name, wantErr := range map[string]bool{"": false, "lo": true, "eth0": true, "eth1": false, "wlan0": true} {
+		if err := checkInterfaceAddress(name, lookup); (err != nil) != wantErr {
+			t.Errorf("checkInterfaceAddress(%q) error = %v, wantErr %v", name, err, wantErr)
+		}
+	}
+}
+
+func Test_UnitWaitForGates(t *testing.T) {
+	defer func(interval time.Duration) { gatePollInterval = interval }(gatePollInterval)
+	gatePollInterval = 10 * time.Millisecond
+
+	checks := 0
+	ready := Gate{Name: "ready", check: func() error { return nil }}
+	eventually := Gate{Name: "eventually", check: func() error {
+		if checks++; checks < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	}}
+	never := Gate{Name: "never", check: func() error { return errors.New("never") }}
+
+	if err := waitForGates(context.Background(), []Gate{ready, eventually}, time.Second); err != nil {
+		t.Errorf("waitForGates() error = %v", err)
+	}
+	if checks != 3 {
+		t.Errorf("waitForGates() checked gate %d times, want 3", checks)
+	}
+
+	// startup continues if the timeout expires
+	if err := waitForGates(context.Background(), []Gate{never}, 50*time.Millisecond); err != nil {
+		t.Errorf("waitForGates() with timeout error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := waitForGates(ctx, []Gate{never}, time.Second); err == nil {
+		t.Errorf("waitForGates() with cancelled context did not return an error")
+	}
+}
@@ -0,0 +1,24 @@
+package startup
+
+import (
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var phaseDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: version.Program + "_startup_phase_duration_seconds",
+	Help: "Time from process start until each startup phase completed",
+}, []string{"phase"})
+
+// MustRegister registers startup metrics
+func MustRegister(registerer prometheus.Registerer) {
+	registerer.MustRegister(phaseDuration)
+}
+
+// PhaseKey returns the phase name in a form suitable for use as a metric label or map key,
+// for example container_runtime_ready.
+func PhaseKey(phase string) string {
+	return strings.ReplaceAll(strings.ToLower(phase), " ", "_")
+}
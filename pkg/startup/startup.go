@@ -0,0 +1,349 @@
+package startup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/util"
+	"github.com/sirupsen/logrus"
+)
+
+// Startup phases reported in the summary and state
+const (
+	PhaseDataExtracted    = "Data extracted"
+	PhaseDatastore        = "Datastore ready"
+	PhaseAPIServer        = "Kube API server ready"
+	PhaseContainerRuntime = "Container runtime ready"
+	PhaseKubelet          = "Kubelet started"
+	PhaseNodeRegistered   = "Node registered"
+	PhaseNodeReady        = "Node ready"
+	PhaseAddons           = "Addons applied"
+
+	// PhaseStarting is reported as the current phase until the first phase has completed
+	PhaseStarting = "Starting"
+)
+
+const (
+	colorGreen = "\x1b[32m"
+	colorDim   = "\x1b[2m"
+	colorBold  = "\x1b[1m"
+	colorReset = "\x1b[0m"
+)
+
+// State is the machine-readable startup state, written to the state file and served by the supervisor.
+// Ready is set once all of the expected phases have completed. LastError holds the most recent error
+// logged or returned during startup, and is not cleared when later phases complete. Warnings lists
+// problems with the configuration that do not prevent startup, such as flags that are ignored.
+type State struct {
+	Phase       string        `json:"phase"`
+	Ready       bool          `json:"ready"`
+	StartedAt   time.Time     `json:"startedAt"`
+	ReadyAt     *time.Time    `json:"readyAt,omitempty"`
+	UpdatedAt   time.Time     `json:"updatedAt"`
+	LastError   string        `json:"lastError,omitempty"`
+	LastErrorAt *time.Time    `json:"lastErrorAt,omitempty"`
+	Warnings    []string      `json:"warnings,omitempty"`
+	Phases      []PhaseStatus `json:"phases"`
+}
+
+// PhaseStatus is the status of a single startup phase.
+type PhaseStatus struct {
+	Name        string     `json:"name"`
+	Expected    bool       `json:"expected"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// tracker tracks the completion of startup phases. The summary and state file outputs are optional.
+type tracker struct {
+	mu       sync.Mutex
+	state    State
+	message  string
+	expected []string
+
+	// summary output
+	out      io.Writer
+	color    bool
+	logLevel logrus.Level
+
+	// state file output
+	stateFile string
+}
+
+var defaultTracker = newTracker(time.Now())
+
+func newTracker(start time.Time) *tracker {
+	return &tracker{state: State{Phase: PhaseStarting, StartedAt: start, UpdatedAt: start, Phases: []PhaseStatus{}}}
+}
+
+// SetStartTimes records the times at which the multicall binary was started and finished extracting
+// the data-dir, for a process that it executed. The time that the multicall binary was started is used
+// as the start time, and the data extraction phase is recorded as complete. Times that are unset or
+// out of order are ignored.
+func SetStartTimes(startedAt, extractedAt time.Time) {
+	defaultTracker.setStartTimes(startedAt, extractedAt, time.Now())
+}
+
+func (t *tracker) setStartTimes(startedAt, extractedAt, now time.Time) {
+	if startedAt.IsZero() || extractedAt.IsZero() || startedAt.After(now) || extractedAt.Before(startedAt) {
+		return
+	}
+	t.mu.Lock()
+	t.state.StartedAt = startedAt
+	t.mu.Unlock()
+	t.phaseDone(PhaseDataExtracted, extractedAt)
+}
+
+// Expect sets the phases that must complete before startup is considered complete, and the message
+// printed in the summary when they have. Other phases may be reported, but are not waited for.
+func Expect(message string, expected ...string) {
+	defaultTracker.expect(message, expected...)
+}
+
+// EnableSummary enables printing a line to the given writer as each startup phase completes, with the time since
+// the process started. While startup is in progress, informational log messages are suppressed so that the
+// summary is not interleaved with logs. Once all of the expected phases have completed, the total startup
+// time is printed and the previous log level is restored.
+func EnableSummary(out io.Writer) {
+	defaultTracker.enableSummary(out)
+}
+
+// EnableStateFile enables writing the startup state to a JSON file whenever it changes. Errors
+// logged while startup is in progress are recorded as the last error.
+func EnableStateFile(path string) error {
+	return defaultTracker.enableStateFile(path)
+}
+
+// Done records the completion of a startup phase. Each phase is only reported once.
+func Done(phase string) {
+	defaultTracker.phaseDone(phase, time.Now())
+}
+
+// Failed records an error that occurred during startup.
+func Failed(err error) {
+	if err != nil {
+		defaultTracker.failed(err.Error(), time.Now())
+	}
+}
+
+// Warn records a problem with the configuration that does not prevent startup.
+func Warn(message string) {
+	defaultTracker.warn(message, time.Now())
+}
+
+// Pending returns those of the given phases that are expected to complete, but have not yet done so.
+// Phases that are not expected, such as the node phases of a server without an agent, are not returned.
+func Pending(phases ...string) []string {
+	return defaultTracker.pending(phases...)
+}
+
+// GetState returns a copy of the current startup state.
+func GetState() State {
+	return defaultTracker.getState()
+}
+
+// GetDurations returns the time taken for each completed startup phase to complete, measured from
+// the start of the process.
+func GetDurations() map[string]time.Duration {
+	return defaultTracker.getState().Durations()
+}
+
+// Durations returns the time taken for each completed phase to complete, measured from the start time.
+func (s State) Durations() map[string]time.Duration {
+	durations := map[string]time.Duration{}
+	for _, phase := range s.Phases {
+		if phase.CompletedAt != nil {
+			durations[phase.Name] = phase.CompletedAt.Sub(s.StartedAt)
+		}
+	}
+	return durations
+}
+
+func (t *tracker) expect(message string, expected ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.message = message
+	t.expected = expected
+	for _, phase := range expected {
+		if i := t.phaseIndex(phase); i >= 0 {
+			t.state.Phases[i].Expected = true
+		} else {
+			t.state.Phases = append(t.state.Phases, PhaseStatus{Name: phase, Expected: true})
+		}
+	}
+	t.updated(t.state.UpdatedAt)
+}
+
+func (t *tracker) enableSummary(out io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.out = out
+	t.color = isTerminal(out)
+	t.logLevel = logrus.GetLevel()
+	if t.logLevel > logrus.WarnLevel {
+		logrus.SetLevel(logrus.WarnLevel)
+	}
+}
+
+func (t *tracker) enableStateFile(path string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	t.stateFile = path
+	logrus.AddHook(&errorHook{tracker: t})
+	return t.writeStateFile()
+}
+
+func (t *tracker) phaseDone(phase string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i := t.phaseIndex(phase)
+	if i < 0 {
+		t.state.Phases = append(t.state.Phases, PhaseStatus{Name: phase})
+		i = len(t.state.Phases) - 1
+	}
+	if t.state.Phases[i].CompletedAt != nil {
+		return
+	}
+	t.state.Phases[i].CompletedAt = &now
+	t.state.Phase = phase
+	phaseDuration.WithLabelValues(PhaseKey(phase)).Set(now.Sub(t.state.StartedAt).Seconds())
+	t.printLine(phase, now.Sub(t.state.StartedAt), false)
+
+	if !t.state.Ready && t.allExpectedDone() {
+		t.state.Ready = true
+		t.state.ReadyAt = &now
+		t.printLine(t.message, now.Sub(t.state.StartedAt), true)
+		if t.out != nil {
+			logrus.SetLevel(t.logLevel)
+		}
+	}
+	t.updated(now)
+}
+
+func (t *tracker) failed(message string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.LastError = message
+	t.state.LastErrorAt = &now
+	t.updated(now)
+}
+
+func (t *tracker) warn(message string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Warnings = append(t.state.Warnings, message)
+	t.updated(now)
+}
+
+func (t *tracker) pending(phases ...string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pending := []string{}
+	for _, phase := range phases {
+		if !slices.Contains(t.expected, phase) {
+			continue
+		}
+		if i := t.phaseIndex(phase); i < 0 || t.state.Phases[i].CompletedAt == nil {
+			pending = append(pending, phase)
+		}
+	}
+	return pending
+}
+
+func (t *tracker) getState() State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := t.state
+	state.Phases = slices.Clone(t.state.Phases)
+	state.Warnings = slices.Clone(t.state.Warnings)
+	return state
+}
+
+// updated records the time that the state was last updated, and writes it to the state file.
+// The caller must hold the lock.
+func (t *tracker) updated(now time.Time) {
+	t.state.UpdatedAt = now
+	if err := t.writeStateFile(); err != nil {
+		// Write directly to stderr, as logging an error would re-enter the error hook while the lock is held
+		fmt.Fprintf(os.Stderr, "Failed to write startup state file %s: %v\n", t.stateFile, err)
+	}
+}
+
+// writeStateFile writes the state to the state file, if enabled. The caller must hold the lock.
+func (t *tracker) writeStateFile() error {
+	if t.stateFile == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(t.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return util.AtomicWrite(t.stateFile, append(b, '\n'), 0600)
+}
+
+func (t *tracker) phaseIndex(phase string) int {
+	return slices.IndexFunc(t.state.Phases, func(p PhaseStatus) bool { return p.Name == phase })
+}
+
+func (t *tracker) allExpectedDone() bool {
+	for _, phase := range t.expected {
+		if i := t.phaseIndex(phase); i < 0 || t.state.Phases[i].CompletedAt == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// printLine prints a line to the summary output, if enabled. The caller must hold the lock.
+func (t *tracker) printLine(text string, elapsed time.Duration, final bool) {
+	if t.out == nil {
+		return
+	}
+	seconds := fmt.Sprintf("%6.1fs", elapsed.Seconds())
+	switch {
+	case !t.color:
+		fmt.Fprintf(t.out, "[%s] %s\n", seconds, text)
+	case final:
+		fmt.Fprintf(t.out, "%s%s✓ %s%s %s%s%s\n", colorBold, colorGreen, text, colorReset, colorDim, seconds, colorReset)
+	default:
+		fmt.Fprintf(t.out, "%s✓%s %-28s %s%s%s\n", colorGreen, colorReset, text, colorDim, seconds, colorReset)
+	}
+}
+
+// errorHook is a logrus hook that records errors logged before startup has completed.
+type errorHook struct {
+	tracker *tracker
+}
+
+func (h *errorHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+func (h *errorHook) Fire(entry *logrus.Entry) error {
+	if state := h.tracker.getState(); !state.Ready {
+		h.tracker.failed(entry.Message, entry.Time)
+	}
+	return nil
+}
+
+// isTerminal returns true if the writer is a terminal, and color output has not been disabled
+// by setting the NO_COLOR environment variable.
+func isTerminal(out io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
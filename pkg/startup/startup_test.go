@@ -0,0 +1,136 @@
+package startup
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func Test_UnitSummary(t *testing.T) {
+	level := logrus.GetLevel()
+	defer logrus.SetLevel(level)
+	logrus.SetLevel(logrus.InfoLevel)
+
+	out := &bytes.Buffer{}
+	start := time.Now()
+	tr := newTracker(start)
+	tr.expect("k3s is up and running", PhaseDatastore, PhaseAPIServer)
+	tr.enableSummary(out)
+	if logrus.GetLevel() != logrus.WarnLevel {
+		t.Errorf("expected log level to be raised to warn during startup, got %s", logrus.GetLevel())
+	}
+
+	tr.phaseDone(PhaseDatastore, start.Add(1500*time.Millisecond))
+	tr.phaseDone(PhaseDatastore, start.Add(2*time.Second))
+	tr.phaseDone(PhaseAddons, start.Add(2*time.Second))
+	if logrus.GetLevel() != logrus.WarnLevel {
+		t.Errorf("expected log level to remain at warn until all phases are complete, got %s", logrus.GetLevel())
+	}
+	tr.phaseDone(PhaseAPIServer, start.Add(10*time.Second))
+	if logrus.GetLevel() != logrus.InfoLevel {
+		t.Errorf("expected log level to be restored after startup, got %s", logrus.GetLevel())
+	}
+	tr.phaseDone(PhaseNodeRegistered, start.Add(12*time.Second))
+
+	want := "[   1.5s] Datastore ready\n" +
+		"[   2.0s] Addons applied\n" +
+		"[  10.0s] Kube API server ready\n" +
+		"[  10.0s] k3s is up and running\n" +
+		"[  12.0s] Node registered\n"
+	if got := out.String(); got != want {
+		t.Errorf("unexpected summary output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func Test_UnitStateFile(t *testing.T) {
+	start := time.Now().Truncate(time.Second)
+	path := filepath.Join(t.TempDir(), "server", "startup-state.json")
+	tr := newTracker(start)
+	tr.expect("k3s is up and running", PhaseDatastore, PhaseAPIServer)
+	if err := tr.enableStateFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	readState := func() State {
+		t.Helper()
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := State{}
+		if err := json.Unmarshal(b, &state); err != nil {
+			t.Fatal(err)
+		}
+		return state
+	}
+
+	state := readState()
+	if state.Phase != PhaseStarting || state.Ready || len(state.Phases) != 2 || state.Phases[0].CompletedAt != nil {
+		t.Errorf("unexpected initial state: %+v", state)
+	}
+
+	if pending := tr.pending(PhaseDatastore, PhaseAPIServer, PhaseNodeRegistered); !reflect.DeepEqual(pending, []string{PhaseDatastore, PhaseAPIServer}) {
+		t.Errorf("expected only expected phases to be pending, got %v", pending)
+	}
+
+	tr.phaseDone(PhaseDatastore, start.Add(time.Second))
+	tr.failed("failed to connect to apiserver", start.Add(2*time.Second))
+	tr.warn("Agent is disabled, ignoring agent flags: --node-label", start.Add(2*time.Second))
+	state = readState()
+	if state.Phase != PhaseDatastore || state.Ready || state.LastError != "failed to connect to apiserver" {
+		t.Errorf("unexpected state after first phase: %+v", state)
+	}
+	if !reflect.DeepEqual(state.Warnings, []string{"Agent is disabled, ignoring agent flags: --node-label"}) {
+		t.Errorf("expected warnings to be recorded, got %v", state.Warnings)
+	}
+	if pending := tr.pending(PhaseDatastore, PhaseAPIServer); !reflect.DeepEqual(pending, []string{PhaseAPIServer}) {
+		t.Errorf("expected completed phases not to be pending, got %v", pending)
+	}
+
+	tr.phaseDone(PhaseAPIServer, start.Add(3*time.Second))
+	state = readState()
+	if state.Phase != PhaseAPIServer || !state.Ready || state.ReadyAt == nil || !state.ReadyAt.Equal(start.Add(3*time.Second)) {
+		t.Errorf("unexpected state after all phases: %+v", state)
+	}
+	if state.LastError == "" {
+		t.Errorf("expected last error to be retained after startup completes")
+	}
+	if got := tr.getState(); got.Phase != state.Phase || len(got.Phases) != len(state.Phases) {
+		t.Errorf("state file does not match current state: %+v", got)
+	}
+}
+
+func Test_UnitSetStartTimes(t *testing.T) {
+	now := time.Now()
+	startedAt := now.Add(-3 * time.Second)
+
+	tr := newTracker(now)
+	tr.setStartTimes(startedAt, now.Add(-time.Second), now)
+	state := tr.getState()
+	if !state.StartedAt.Equal(startedAt) {
+		t.Errorf("StartedAt = %s, want %s", state.StartedAt, startedAt)
+	}
+	tr.phaseDone(PhaseContainerRuntime, now.Add(5*time.Second))
+	want := map[string]time.Duration{PhaseDataExtracted: 2 * time.Second, PhaseContainerRuntime: 8 * time.Second}
+	if got := tr.getState().Durations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Durations() = %v, want %v", got, want)
+	}
+
+	// without start times, or with times out of order, the current time is used and no phases are complete
+	for _, times := range [][2]time.Time{{}, {now.Add(time.Second), now.Add(2 * time.Second)}, {startedAt, startedAt.Add(-time.Second)}} {
+		tr = newTracker(now)
+		tr.setStartTimes(times[0], times[1], now)
+		if state := tr.getState(); !state.StartedAt.Equal(now) || len(state.Phases) != 0 {
+			t.Errorf("unexpected state with start times %v: %+v", times, state)
+		}
+	}
+	if got := PhaseKey(PhaseContainerRuntime); got != "container_runtime_ready" {
+		t.Errorf("PhaseKey() = %s, want container_runtime_ready", got)
+	}
+}
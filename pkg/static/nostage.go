@@ -3,6 +3,6 @@
 
 package static
 
-func Stage(dataDir string) error {
+func Stage(dataDir string, skips map[string]bool) error {
 	return nil
 }
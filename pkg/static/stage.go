@@ -4,14 +4,21 @@ package static
 
 import (
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
-func Stage(dataDir string) error {
+// Stage writes the static files to the data-dir. Charts for packaged components that are skipped are
+// not written, so that they are not decompressed into memory.
+func Stage(dataDir string, skips map[string]bool) error {
 	for _, name := range AssetNames() {
+		if skipped(name, skips) {
+			continue
+		}
 		content, err := Asset(name)
 		if err != nil {
 			return err
@@ -26,3 +33,18 @@ func Stage(dataDir string) error {
 
 	return nil
 }
+
+// skipped returns true if the file is a chart for a skipped packaged component. Charts are named for
+// the manifest that deploys them, followed by a version or chart suffix.
+func skipped(name string, skips map[string]bool) bool {
+	dir, file := path.Split(name)
+	if dir != "charts/" {
+		return false
+	}
+	for component, skip := range skips {
+		if skip && strings.HasPrefix(file, component+"-") {
+			return true
+		}
+	}
+	return false
+}
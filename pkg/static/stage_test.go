@@ -0,0 +1,26 @@
+//go:build !no_stage
+
+package static
+
+import "testing"
+
+func Test_UnitSkipped(t *testing.T) {
+	skips := map[string]bool{"traefik": true, "local-storage": false}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "charts/traefik-27.0.201+up27.0.2.tgz", want: true},
+		{name: "charts/traefik-crd-27.0.201+up27.0.2.tgz", want: true},
+		{name: "charts/local-storage-1.0.0.tgz"},
+		{name: "charts/other-1.0.0.tgz"},
+		{name: "traefik-1.0.0.tgz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := skipped(tt.name, skips); got != tt.want {
+				t.Errorf("skipped() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
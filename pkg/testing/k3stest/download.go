@@ -0,0 +1,114 @@
+package k3stest
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ReleaseURL is the base URL that release binaries are downloaded from.
+var ReleaseURL = "https://github.com/k3s-io/k3s/releases/download"
+
+// Download downloads the release binary for the given version, verifies its checksum, and
+// returns its path. Binaries are cached in the user cache dir, and are only downloaded once.
+func Download(ctx context.Context, version string) (string, error) {
+	binaryName, sumsName, err := releaseAssets(runtime.GOARCH)
+	if err != nil {
+		return "", err
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cacheDir, "k3stest", version)
+	binary := filepath.Join(dir, "k3s")
+	if _, err := os.Stat(binary); err == nil {
+		return binary, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	baseURL := ReleaseURL + "/" + url.PathEscape(version)
+	sums, err := fetch(ctx, baseURL+"/"+sumsName)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to download checksums for %s", version)
+	}
+	expected, err := findSum(sums, binaryName)
+	if err != nil {
+		return "", err
+	}
+
+	logrus.Infof("Downloading k3s %s from %s", version, baseURL)
+	b, err := fetch(ctx, baseURL+"/"+binaryName)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to download k3s %s", version)
+	}
+	sum := sha256.Sum256(b)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return "", fmt.Errorf("checksum mismatch for k3s %s: expected %s, got %s", version, expected, actual)
+	}
+
+	// write to a temp file and rename, so that an interrupted download is not cached
+	tmp := binary + ".tmp"
+	if err := os.WriteFile(tmp, b, 0755); err != nil {
+		return "", err
+	}
+	return binary, os.Rename(tmp, binary)
+}
+
+// releaseAssets returns the names of the release binary and checksum file for the architecture.
+func releaseAssets(arch string) (string, string, error) {
+	switch arch {
+	case "amd64":
+		return "k3s", "sha256sum-amd64.txt", nil
+	case "arm64":
+		return "k3s-arm64", "sha256sum-arm64.txt", nil
+	case "arm":
+		return "k3s-armhf", "sha256sum-arm.txt", nil
+	case "s390x":
+		return "k3s-s390x", "sha256sum-s390x.txt", nil
+	}
+	return "", "", fmt.Errorf("no release binary for architecture %s", arch)
+}
+
+// findSum returns the checksum for the named file from a sha256sum file.
+func findSum(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(string(sums)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("no checksum found for %s", name)
+}
+
+func fetch(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", u, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
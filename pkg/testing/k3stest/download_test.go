@@ -0,0 +1,78 @@
+package k3stest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func Test_UnitDownload(t *testing.T) {
+	binaryName, sumsName, err := releaseAssets(runtime.GOARCH)
+	if err != nil {
+		t.Skip(err)
+	}
+	content := []byte("#!/bin/sh\necho k3s\n")
+	sum := sha256.Sum256(content)
+
+	tests := []struct {
+		name    string
+		version string
+		sum     string
+		wantErr bool
+	}{
+		{
+			name:    "Valid checksum",
+			version: "v1.31.4+k3s1",
+			sum:     hex.EncodeToString(sum[:]),
+		},
+		{
+			name:    "Invalid checksum",
+			version: "v1.31.5+k3s1",
+			sum:     hex.EncodeToString(make([]byte, sha256.Size)),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				requests++
+				switch req.URL.Path {
+				case "/" + tt.version + "/" + sumsName:
+					rw.Write([]byte(tt.sum + "  " + binaryName + "\n"))
+				case "/" + tt.version + "/" + binaryName:
+					rw.Write(content)
+				default:
+					http.NotFound(rw, req)
+				}
+			}))
+			defer server.Close()
+			ReleaseURL = server.URL
+
+			binary, err := Download(context.Background(), tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if b, err := os.ReadFile(binary); err != nil || string(b) != string(content) {
+				t.Errorf("Download() wrote %q, %v; want %q", b, err, content)
+			}
+
+			// a second download should use the cached binary
+			if _, err := Download(context.Background(), tt.version); err != nil {
+				t.Errorf("Download() error = %v", err)
+			}
+			if requests != 2 {
+				t.Errorf("Download() made %d requests, want 2", requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Package tracing exports OpenTelemetry spans for supervisor and bootstrap operations, such as
+// server startup, agent join, certificate issuance, manifest apply, and etcd snapshots, so that
+// slow operations can be traced across nodes.
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/component-base/tracing"
+	tracingapi "k8s.io/component-base/tracing/api/v1"
+)
+
+const instrumentationScope = "github.com/k3s-io/k3s"
+
+// shutdownTimeout is the time allowed for buffered spans to be exported on shutdown.
+const shutdownTimeout = 5 * time.Second
+
+// Setup configures the global tracer provider to export spans to the given OTLP gRPC endpoint,
+// sampling the given fraction of traces. The provider is shut down, and any buffered spans
+// exported, when the context is cancelled. Nothing is done if the endpoint is empty.
+func Setup(ctx context.Context, endpoint string, samplingRate float64, role string) error {
+	if endpoint == "" {
+		return nil
+	}
+	if samplingRate < 0 || samplingRate > 1 {
+		return errors.Errorf("tracing sampling rate %v must be between 0 and 1", samplingRate)
+	}
+	ratePerMillion := int32(samplingRate * 1000000)
+	tp, err := tracing.NewProvider(ctx, &tracingapi.TracingConfiguration{
+		Endpoint:               &endpoint,
+		SamplingRatePerMillion: &ratePerMillion,
+	}, nil, []resource.Option{
+		resource.WithHost(),
+		resource.WithAttributes(
+			semconv.ServiceName(version.Program+"-"+role),
+			semconv.ServiceVersion(version.Version),
+		),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create tracer provider")
+	}
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(tracing.Propagators())
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := tp.Shutdown(shutdownCtx); err != nil {
+			logrus.Debugf("Failed to shut down tracer provider: %v", err)
+		}
+	}()
+
+	logrus.Infof("Exporting traces to OTLP endpoint %s", endpoint)
+	return nil
+}
+
+// Start starts a span with the given name. If the context carries a span that has already
+// ended, for example the server startup span in the context of a long-running controller, a new
+// trace is started instead, with a link to the ended span.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{trace.WithAttributes(attributes...)}
+	if parent, ok := trace.SpanFromContext(ctx).(sdktrace.ReadOnlySpan); ok && !parent.EndTime().IsZero() {
+		opts = append(opts, trace.WithNewRoot(), trace.WithLinks(trace.Link{SpanContext: parent.SpanContext()}))
+	}
+	return otel.Tracer(instrumentationScope).Start(ctx, name, opts...)
+}
+
+// End ends the span, recording the error if it is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject adds the trace context carried by ctx to the request headers, so that spans created
+// by the server handling the request are part of the same trace.
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Handler wraps the handler to start a span for requests that carry a trace context, such as
+// certificate requests from agents joining with tracing enabled. Other requests, including those
+// proxied to the apiserver, are passed through without creating spans.
+func Handler(handler http.Handler, name string) http.Handler {
+	traced := tracing.WithTracing(handler, otel.GetTracerProvider(), name)
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("traceparent") != "" {
+			traced.ServeHTTP(resp, req)
+			return
+		}
+		handler.ServeHTTP(resp, req)
+	})
+}
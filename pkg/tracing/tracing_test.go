@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_UnitStartAndEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// spans started while the parent is running are part of the same trace
+	ctx, parent := Start(context.Background(), "server.start")
+	_, child := Start(ctx, "server.control")
+	End(child, errors.New("failed"))
+
+	// spans started after the parent has ended start a new trace linked to the parent
+	parent.End()
+	_, late := Start(ctx, "certificate.issue")
+	End(late, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d ended spans, want 3", len(spans))
+	}
+	childSpan, parentSpan, lateSpan := spans[0], spans[1], spans[2]
+	if childSpan.Parent().SpanID() != parentSpan.SpanContext().SpanID() {
+		t.Errorf("child span parent = %s, want %s", childSpan.Parent().SpanID(), parentSpan.SpanContext().SpanID())
+	}
+	if childSpan.Status().Code != codes.Error || childSpan.Status().Description != "failed" {
+		t.Errorf("child span status = %+v, want error", childSpan.Status())
+	}
+	if lateSpan.Parent().IsValid() || lateSpan.SpanContext().TraceID() == parentSpan.SpanContext().TraceID() {
+		t.Errorf("late span is part of the ended parent's trace, want a new trace")
+	}
+	if links := lateSpan.Links(); len(links) != 1 || links[0].SpanContext.SpanID() != parentSpan.SpanContext().SpanID() {
+		t.Errorf("late span links = %+v, want link to parent span", links)
+	}
+
+	// only requests carrying a trace context are traced by the handler
+	handler := Handler(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {}), "supervisor")
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1-k3s/config", nil))
+	if got := len(recorder.Ended()); got != 3 {
+		t.Errorf("handler traced request without trace context; got %d ended spans, want 3", got)
+	}
+	ctx, span := Start(context.Background(), "agent.join")
+	req := httptest.NewRequest(http.MethodGet, "/v1-k3s/config", nil)
+	Inject(ctx, req.Header)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	span.End()
+	spans = recorder.Ended()
+	if len(spans) != 5 || spans[3].SpanContext().TraceID() != spans[4].SpanContext().TraceID() {
+		t.Errorf("handler did not trace request with trace context as part of the caller's trace")
+	}
+}
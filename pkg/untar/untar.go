@@ -7,6 +7,8 @@ package untar
 
 import (
 	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
@@ -24,24 +26,45 @@ import (
 // forked for now.  Unfork and add some opts arguments here, so the
 // buildlet can use this code somehow.
 
+// copyBufferSize is the size of the buffer used when writing file contents to disk.
+// A single buffer is reused for all files, to keep memory usage bounded regardless
+// of the size of the archive.
+const copyBufferSize = 32 * 1024
+
 // Untar reads the zstd-compressed tar file from r and writes it into dir.
 func Untar(r io.Reader, dir string) error {
+	_, err := untar(r, dir)
+	return err
+}
+
+// UntarWithSums reads the zstd-compressed tar file from r and writes it into dir.
+// The sha256 sum of each regular file is calculated as it is written, and returned
+// in a map keyed by the path of the file on disk.
+func UntarWithSums(r io.Reader, dir string) (map[string]string, error) {
 	return untar(r, dir)
 }
 
-func untar(r io.Reader, dir string) (err error) {
+func untar(r io.Reader, dir string) (sums map[string]string, err error) {
 	t0 := time.Now()
 	nFiles := 0
 	madeDir := map[string]bool{}
+	sums = map[string]string{}
+	buf := make([]byte, copyBufferSize)
 	defer func() {
 		td := time.Since(t0)
 		if err != nil {
 			logrus.Printf("error extracting tarball into %s after %d files, %d dirs, %v: %v", dir, nFiles, len(madeDir), td, err)
 		}
 	}()
-	zr, err := zstd.NewReader(r, zstd.WithDecoderMaxMemory(tarfile.MaxDecoderMemory))
+	// Decode synchronously in low-memory mode; extraction is bound by disk I/O anyway,
+	// and this avoids allocating per-goroutine decoder buffers on small systems.
+	zr, err := zstd.NewReader(r,
+		zstd.WithDecoderMaxMemory(tarfile.MaxDecoderMemory),
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderLowmem(true),
+	)
 	if err != nil {
-		return fmt.Errorf("error extracting zstd-compressed body: %v", err)
+		return nil, fmt.Errorf("error extracting zstd-compressed body: %v", err)
 	}
 	defer zr.Close()
 	tr := tar.NewReader(zr)
@@ -53,10 +76,10 @@ func untar(r io.Reader, dir string) (err error) {
 		}
 		if err != nil {
 			logrus.Printf("tar reading error: %v", err)
-			return fmt.Errorf("tar error: %v", err)
+			return nil, fmt.Errorf("tar error: %v", err)
 		}
 		if !validRelPath(f.Name) {
-			return fmt.Errorf("tar contained invalid name error %q", f.Name)
+			return nil, fmt.Errorf("tar contained invalid name error %q", f.Name)
 		}
 		rel := filepath.FromSlash(f.Name)
 		abs := filepath.Join(dir, rel)
@@ -72,24 +95,26 @@ func untar(r io.Reader, dir string) (err error) {
 			dir := filepath.Dir(abs)
 			if !madeDir[dir] {
 				if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
-					return err
+					return nil, err
 				}
 				madeDir[dir] = true
 			}
 			wf, err := os.OpenFile(abs, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode.Perm())
 			if err != nil {
-				return err
+				return nil, err
 			}
-			n, err := io.Copy(wf, tr)
+			hash := sha256.New()
+			n, err := io.CopyBuffer(io.MultiWriter(wf, hash), tr, buf)
 			if closeErr := wf.Close(); closeErr != nil && err == nil {
 				err = closeErr
 			}
 			if err != nil {
-				return fmt.Errorf("error writing to %s: %v", abs, err)
+				return nil, fmt.Errorf("error writing to %s: %v", abs, err)
 			}
 			if n != f.Size {
-				return fmt.Errorf("only wrote %d bytes to %s; expected %d", n, abs, f.Size)
+				return nil, fmt.Errorf("only wrote %d bytes to %s; expected %d", n, abs, f.Size)
 			}
+			sums[abs] = hex.EncodeToString(hash.Sum(nil))
 			modTime := f.ModTime
 			if modTime.After(t0) {
 				// Clamp modtimes at system time. See
@@ -112,18 +137,18 @@ func untar(r io.Reader, dir string) (err error) {
 			nFiles++
 		case mode.IsDir():
 			if err := os.MkdirAll(abs, 0755); err != nil {
-				return err
+				return nil, err
 			}
 			madeDir[abs] = true
 		case f.Linkname != "":
 			if err := os.Symlink(f.Linkname, abs); err != nil {
-				return err
+				return nil, err
 			}
 		default:
-			return fmt.Errorf("tar file entry %s contained unsupported file type %v", f.Name, mode)
+			return nil, fmt.Errorf("tar file entry %s contained unsupported file type %v", f.Name, mode)
 		}
 	}
-	return nil
+	return sums, nil
 }
 
 func validRelPath(p string) bool {
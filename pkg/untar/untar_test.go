@@ -0,0 +1,58 @@
+package untar
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func Test_UnitUntarWithSums(t *testing.T) {
+	files := map[string]string{
+		"./bin/k3s":           "k3s binary",
+		"./bin/aux/iptables":  "iptables binary",
+		"./etc/crictl.yaml":   "runtime-endpoint: unix:///run/k3s/containerd/containerd.sock",
+		"./bin/.sha256sums":   "",
+		"./bin/empty-file.sh": "",
+	}
+
+	buf := &bytes.Buffer{}
+	zw, err := zstd.NewWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(zw)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "./bin/crictl", Linkname: "k3s", Typeflag: tar.TypeSymlink}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	zw.Close()
+
+	dir := t.TempDir()
+	sums, err := UntarWithSums(bytes.NewReader(buf.Bytes()), dir)
+	if err != nil {
+		t.Fatalf("UntarWithSums() error = %v", err)
+	}
+	if len(sums) != len(files) {
+		t.Errorf("UntarWithSums() returned %d sums, want %d", len(sums), len(files))
+	}
+	for name, content := range files {
+		sum := sha256.Sum256([]byte(content))
+		want := hex.EncodeToString(sum[:])
+		if got := sums[filepath.Join(dir, name)]; got != want {
+			t.Errorf("UntarWithSums() sum for %s = %s\nWant = %s", name, got, want)
+		}
+	}
+}
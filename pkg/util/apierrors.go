@@ -65,6 +65,16 @@ func SendError(err error, resp http.ResponseWriter, req *http.Request, status ..
 	responsewriters.ErrorNegotiated(serr, scheme.Codecs.WithoutConversion(), schema.GroupVersion{}, resp, req)
 }
 
+// RejectDryRun sends a BadRequest response and returns true if the request is a dry run, for handlers
+// that cannot complete a request without side effects.
+func RejectDryRun(resp http.ResponseWriter, req *http.Request) bool {
+	if !req.URL.Query().Has("dryRun") {
+		return false
+	}
+	SendError(errors.New("dry run is not supported"), resp, req, http.StatusBadRequest)
+	return true
+}
+
 func newForbidden(err error) *apierrors.StatusError {
 	return &apierrors.StatusError{
 		ErrStatus: metav1.Status{
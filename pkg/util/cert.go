@@ -2,8 +2,10 @@ package util
 
 import (
 	"crypto/x509"
+	"time"
 
 	certutil "github.com/rancher/dynamiclistener/cert"
+	"github.com/sirupsen/logrus"
 )
 
 // EncodeCertsPEM is a wrapper around the EncodeCertPEM function to return the
@@ -15,3 +17,17 @@ func EncodeCertsPEM(cert *x509.Certificate, caCerts []*x509.Certificate) []byte
 	}
 	return pemBytes
 }
+
+// LeafCertDuration returns the validity period to request for a certificate signed by caCert. The
+// duration is shortened if necessary so that the certificate does not outlive the CA. A zero duration
+// is returned unchanged, to use the signer's default.
+func LeafCertDuration(duration time.Duration, caCert *x509.Certificate) time.Duration {
+	if duration <= 0 {
+		return 0
+	}
+	if remaining := time.Until(caCert.NotAfter); remaining < duration {
+		logrus.Warnf("Certificate validity of %s would outlive CA %s; limiting validity to the CA expiration at %s", duration, caCert.Subject, caCert.NotAfter.Format(time.RFC3339))
+		return remaining
+	}
+	return duration
+}
@@ -381,6 +381,23 @@ func ListenWithLoopback(ctx context.Context, addr string, port string) (net.List
 	return ml, nil
 }
 
+// MergeListeners returns a listener that accepts connections from all of the given listeners.
+// The address of the first listener is used as the address of the merged listener.
+func MergeListeners(listeners ...net.Listener) net.Listener {
+	if len(listeners) == 1 {
+		return listeners[0]
+	}
+	ml := &multiListener{
+		listeners: listeners,
+		closing:   make(chan struct{}),
+		conns:     make(chan acceptRes),
+	}
+	for i := range ml.listeners {
+		go ml.accept(ml.listeners[i])
+	}
+	return ml
+}
+
 // Addr returns the address of the non-loopback address that this multiListener is listening on
 func (ml *multiListener) Addr() net.Addr {
 	return ml.listeners[0].Addr()
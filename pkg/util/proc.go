@@ -0,0 +1,38 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SelfStatusFile is the proc status file for the current process.
+const SelfStatusFile = "/proc/self/status"
+
+// ReadRSS returns the resident set size in bytes, as reported by the VmRSS field of the given
+// proc status file. Zero is returned if the file cannot be read, as on platforms without procfs.
+func ReadRSS(path string) uint64 {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "VmRSS:")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			return 0
+		}
+		kb, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return 0
+		}
+		return kb * 1024
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_UnitReadRSS(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    uint64
+	}{
+		{
+			name:    "rss present",
+			content: "Name:\tk3s-server\nVmPeak:\t  912340 kB\nVmRSS:\t  402116 kB\nRssAnon:\t  301000 kB\n",
+			want:    402116 * 1024,
+		},
+		{
+			name:    "rss missing",
+			content: "Name:\tk3s-server\n",
+		},
+		{
+			name:    "rss malformed",
+			content: "VmRSS:\tlots kB\n",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i)))
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if got := ReadRSS(path); got != tt.want {
+				t.Errorf("ReadRSS() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+	if got := ReadRSS(filepath.Join(dir, "missing")); got != 0 {
+		t.Errorf("ReadRSS() on missing file = %d, want 0", got)
+	}
+}
@@ -50,6 +50,33 @@ var CA = []string{
 	CertificateAuthority,
 }
 
+// Groups are names that can be used in place of a list of services.
+var Groups = map[string][]string{
+	"agent":  Agent,
+	"server": Server,
+	"all":    All,
+}
+
+// Expand replaces any group names in the list of services with the services in that group,
+// and removes duplicates, preserving the order in which services were first listed.
+func Expand(services []string) []string {
+	expanded := []string{}
+	seen := map[string]bool{}
+	for _, service := range services {
+		group, ok := Groups[service]
+		if !ok {
+			group = []string{service}
+		}
+		for _, s := range group {
+			if !seen[s] {
+				seen[s] = true
+				expanded = append(expanded, s)
+			}
+		}
+	}
+	return expanded
+}
+
 func FilesForServices(controlConfig config.Control, services []string) (map[string][]string, error) {
 	agentDataDir := filepath.Join(controlConfig.DataDir, "..", "agent")
 	fileMap := map[string][]string{}
@@ -147,6 +174,9 @@ func FilesForServices(controlConfig config.Control, services []string) (map[stri
 }
 
 func IsValid(svc string) bool {
+	if _, ok := Groups[svc]; ok {
+		return true
+	}
 	for _, service := range All {
 		if svc == service {
 			return true
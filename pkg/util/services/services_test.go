@@ -6,6 +6,7 @@ import (
 
 	"github.com/k3s-io/k3s/pkg/daemons/config"
 	"github.com/k3s-io/k3s/pkg/daemons/control/deps"
+	"github.com/k3s-io/k3s/pkg/version"
 )
 
 func Test_UnitFilesForServices(t *testing.T) {
@@ -245,3 +246,39 @@ func Test_UnitFilesForServices(t *testing.T) {
 		})
 	}
 }
+
+func Test_UnitExpand(t *testing.T) {
+	tests := []struct {
+		name     string
+		services []string
+		want     []string
+	}{
+		{
+			name:     "Single Service",
+			services: []string{Kubelet},
+			want:     []string{Kubelet},
+		},
+		{
+			name:     "Agent Group",
+			services: []string{"agent"},
+			want:     Agent,
+		},
+		{
+			name:     "Group With Duplicates",
+			services: []string{Kubelet, "agent", APIServer, Kubelet},
+			want:     []string{Kubelet, KubeProxy, version.Program + ProgramController, APIServer},
+		},
+		{
+			name:     "All Group",
+			services: []string{"server", "all"},
+			want:     All,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Expand(tt.services); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package util
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/k3s-io/k3s/pkg/version"
+	"github.com/rancher/remotedialer"
+)
+
+// TunnelCompressionHeader is sent by agents to request compression of the websocket tunnel, and
+// is added to the server's upgrade response if compression has been enabled.
+var TunnelCompressionHeader = "X-" + version.Program + "-Tunnel-Compression"
+
+// TunnelCompressionDeflate is the only supported tunnel compression method.
+const TunnelCompressionDeflate = "deflate"
+
+// ValidateTunnelKeepAliveTimeout checks that the tunnel keepalive timeout is longer than the
+// interval at which agents send websocket pings, so that healthy tunnels are not closed.
+func ValidateTunnelKeepAliveTimeout(timeout time.Duration) error {
+	if timeout < 0 || (timeout > 0 && timeout <= remotedialer.PingWriteInterval) {
+		return fmt.Errorf("invalid tunnel-keepalive-timeout %s: must be longer than the websocket ping interval of %s", timeout, remotedialer.PingWriteInterval)
+	}
+	return nil
+}
+
+// NewTunnelClientConn wraps the connection that an agent has dialed to a server's websocket tunnel
+// endpoint. If compress is set, the handshake request must include the compression header, and
+// once the server's response confirms that compression is enabled, data in both directions is
+// compressed. If keepAliveTimeout is set, read deadlines set when websocket pings or pongs are
+// received are shortened to the timeout, so that tunnels that stop responding are closed sooner.
+func NewTunnelClientConn(conn net.Conn, compress bool, keepAliveTimeout time.Duration) net.Conn {
+	if compress {
+		conn = &tunnelClientConn{Conn: conn}
+	}
+	if keepAliveTimeout > 0 {
+		conn = &keepAliveConn{Conn: conn, timeout: keepAliveTimeout}
+	}
+	return conn
+}
+
+// NewTunnelServerConn wraps a connection hijacked by the websocket upgrade of an agent tunnel. If
+// compress is set, the compression header is added to the upgrade response, and data in both
+// directions is compressed once it has been sent. If keepAliveTimeout is set, read deadlines are
+// shortened as for NewTunnelClientConn.
+func NewTunnelServerConn(conn net.Conn, compress bool, keepAliveTimeout time.Duration) net.Conn {
+	if compress {
+		conn = &tunnelServerConn{Conn: conn}
+	}
+	if keepAliveTimeout > 0 {
+		conn = &keepAliveConn{Conn: conn, timeout: keepAliveTimeout}
+	}
+	return conn
+}
+
+// keepAliveConn limits read deadlines to the keepalive timeout. The tunnel sets a read deadline
+// each time a websocket ping or pong is received, so the tunnel is closed if no ping or pong is
+// received before the timeout expires.
+type keepAliveConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *keepAliveConn) SetReadDeadline(t time.Time) error {
+	if limit := time.Now().Add(c.timeout); !t.IsZero() && t.After(limit) {
+		t = limit
+	}
+	return c.Conn.SetReadDeadline(t)
+}
+
+// compressor compresses writes to a connection, flushing after each write so that the peer can
+// decompress each websocket frame as soon as it is received.
+type compressor struct {
+	writer *flate.Writer
+}
+
+func (c *compressor) write(conn net.Conn, b []byte) (int, error) {
+	if c.writer == nil {
+		c.writer, _ = flate.NewWriter(conn, flate.DefaultCompression)
+	}
+	if _, err := c.writer.Write(b); err != nil {
+		return 0, err
+	}
+	if err := c.writer.Flush(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// tunnelServerConn compresses a tunnel connection on the server. The upgrade response is written
+// uncompressed with the compression header added, and all later data is compressed. The client's
+// upgrade request has already been read, so all data read from the connection is compressed.
+type tunnelServerConn struct {
+	net.Conn
+	compressor
+	reader     io.ReadCloser
+	upgraded   bool
+	compressed bool
+}
+
+func (c *tunnelServerConn) Read(b []byte) (int, error) {
+	if c.reader == nil {
+		c.reader = flate.NewReader(c.Conn)
+	}
+	return c.reader.Read(b)
+}
+
+func (c *tunnelServerConn) Write(b []byte) (int, error) {
+	if c.compressed {
+		return c.compressor.write(c.Conn, b)
+	}
+	if c.upgraded {
+		return c.Conn.Write(b)
+	}
+	// The upgrade response is written in a single call; add the header before the final CRLF
+	c.upgraded = true
+	if !bytes.HasPrefix(b, []byte("HTTP/1.1 101 ")) || !bytes.HasSuffix(b, []byte("\r\n\r\n")) {
+		return c.Conn.Write(b)
+	}
+	header := []byte(http.CanonicalHeaderKey(TunnelCompressionHeader) + ": " + TunnelCompressionDeflate + "\r\n\r\n")
+	if _, err := c.Conn.Write(append(b[:len(b)-2:len(b)-2], header...)); err != nil {
+		return 0, err
+	}
+	c.compressed = true
+	return len(b), nil
+}
+
+// tunnelClientConn compresses a tunnel connection on the agent. The upgrade request is written
+// uncompressed, and the server's response is read uncompressed up to the end of its headers. If the
+// response includes the compression header, all later data in both directions is compressed.
+type tunnelClientConn struct {
+	net.Conn
+	compressor
+	response   []byte
+	reader     io.Reader
+	compressed bool
+}
+
+func (c *tunnelClientConn) Read(b []byte) (int, error) {
+	if c.reader != nil {
+		return c.reader.Read(b)
+	}
+
+	n, err := c.Conn.Read(b)
+	if n == 0 {
+		return n, err
+	}
+	start := len(c.response)
+	c.response = append(c.response, b[:n]...)
+	i := bytes.Index(c.response, []byte("\r\n\r\n"))
+	if i == -1 {
+		return n, err
+	}
+
+	// Only the response headers are returned by this read; any data read past the end of the
+	// headers is returned by the reader, which decompresses it if compression is enabled.
+	end := i + 4 - start
+	var reader io.Reader = io.MultiReader(bytes.NewReader(append([]byte{}, b[end:n]...)), c.Conn)
+	for _, line := range bytes.Split(c.response[:i], []byte("\r\n"))[1:] {
+		name, value, _ := bytes.Cut(line, []byte(":"))
+		if http.CanonicalHeaderKey(string(bytes.TrimSpace(name))) == http.CanonicalHeaderKey(TunnelCompressionHeader) && string(bytes.TrimSpace(value)) == TunnelCompressionDeflate {
+			reader = flate.NewReader(reader)
+			c.compressed = true
+		}
+	}
+	c.reader = reader
+	c.response = nil
+	return end, err
+}
+
+func (c *tunnelClientConn) Write(b []byte) (int, error) {
+	if c.compressed {
+		return c.compressor.write(c.Conn, b)
+	}
+	return c.Conn.Write(b)
+}
@@ -0,0 +1,141 @@
+package util
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// tunnelHijacker wraps the connection hijacked by the websocket upgrade, as the tunnel server does.
+type tunnelHijacker struct {
+	http.ResponseWriter
+	compress bool
+}
+
+func (h *tunnelHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, _, err := h.ResponseWriter.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	conn = NewTunnelServerConn(conn, h.compress, time.Minute)
+	return conn, bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)), nil
+}
+
+// writeCounter counts bytes written to the wrapped connection.
+type writeCounter struct {
+	net.Conn
+	written int
+}
+
+func (c *writeCounter) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.written += n
+	return n, err
+}
+
+func Test_UnitTunnelConn(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		compress := req.Header.Get(TunnelCompressionHeader) == TunnelCompressionDeflate
+		conn, err := (&websocket.Upgrader{}).Upgrade(&tunnelHijacker{ResponseWriter: resp, compress: compress}, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, b, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, b); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	for _, compress := range []bool{false, true} {
+		var raw *writeCounter
+		dialer := &websocket.Dialer{
+			NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				raw = &writeCounter{Conn: conn}
+				return NewTunnelClientConn(raw, compress, 0), nil
+			},
+		}
+		var headers http.Header
+		if compress {
+			headers = http.Header{TunnelCompressionHeader: []string{TunnelCompressionDeflate}}
+		}
+		conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), headers)
+		if err != nil {
+			t.Fatalf("Dial(compress=%t) error = %v", compress, err)
+		}
+		if got := resp.Header.Get(TunnelCompressionHeader) == TunnelCompressionDeflate; got != compress {
+			t.Errorf("Dial(compress=%t) response compression header = %t", compress, got)
+		}
+		written := raw.written
+		message := []byte(strings.Repeat("hello tunnel ", 1000))
+		for i := 0; i < 3; i++ {
+			if err := conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
+				t.Fatalf("WriteMessage(compress=%t) error = %v", compress, err)
+			}
+			if _, b, err := conn.ReadMessage(); err != nil || string(b) != string(message) {
+				t.Fatalf("ReadMessage(compress=%t) = %d bytes, %v; want echoed message", compress, len(b), err)
+			}
+		}
+		// the repeated message compresses to a fraction of its size
+		if written = raw.written - written; compress == (written > len(message)) {
+			t.Errorf("Dial(compress=%t) wrote %d bytes for 3 messages of %d bytes", compress, written, len(message))
+		}
+		conn.Close()
+	}
+}
+
+func Test_UnitTunnelConnKeepAlive(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	conn := NewTunnelClientConn(client, false, 50*time.Millisecond)
+	defer conn.Close()
+
+	// a deadline past the keepalive timeout is shortened to the timeout
+	if err := conn.SetReadDeadline(time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("Read() error = nil, want timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Read() returned after %s, want keepalive timeout", elapsed)
+	}
+
+	// clearing the deadline is not affected
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	go server.Write([]byte("x"))
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		t.Errorf("Read() error = %v, want nil", err)
+	}
+
+	for _, timeout := range []time.Duration{-time.Second, time.Second, 5 * time.Second} {
+		if err := ValidateTunnelKeepAliveTimeout(timeout); err == nil {
+			t.Errorf("ValidateTunnelKeepAliveTimeout(%s) error = nil, want error", timeout)
+		}
+	}
+	for _, timeout := range []time.Duration{0, 6 * time.Second, time.Minute} {
+		if err := ValidateTunnelKeepAliveTimeout(timeout); err != nil {
+			t.Errorf("ValidateTunnelKeepAliveTimeout(%s) error = %v", timeout, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package util
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/k3s-io/k3s/pkg/version"
+)
+
+// ActiveServiceUnits returns the names of the running services created by the install script, which
+// are systemd units or openrc init scripts named after the program.
+func ActiveServiceUnits() []string {
+	units := []string{}
+	systemdUnits, _ := filepath.Glob("/etc/systemd/system/" + version.Program + "*.service")
+	for _, unit := range systemdUnits {
+		if exec.Command("systemctl", "is-active", "--quiet", filepath.Base(unit)).Run() == nil {
+			units = append(units, filepath.Base(unit))
+		}
+	}
+	scripts, _ := filepath.Glob("/etc/init.d/" + version.Program + "*")
+	for _, script := range scripts {
+		if exec.Command(script, "status").Run() == nil {
+			units = append(units, script)
+		}
+	}
+	return units
+}
+
+// RunServiceUnit starts, stops, or restarts a service returned by ActiveServiceUnits.
+func RunServiceUnit(unit, action string) error {
+	cmd := exec.Command("systemctl", action, unit)
+	if filepath.IsAbs(unit) {
+		cmd = exec.Command(unit, action)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// ServiceUnitsUsing returns the units, from those returned by ActiveServiceUnits, whose processes have
+// files open within the given directory. Units are identified from the cgroup of each process: systemd
+// places each unit's processes in a cgroup named for the unit, and openrc in a cgroup named for the
+// service with an openrc prefix.
+func ServiceUnitsUsing(dir string, units []string) []string {
+	dir = filepath.Clean(dir) + string(filepath.Separator)
+	cgroups := map[string]bool{}
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, fd := range fds {
+		pid := strings.Split(fd, "/")[2]
+		link, err := os.Readlink(fd)
+		if err != nil || !strings.HasPrefix(link, dir) {
+			continue
+		}
+		if b, err := os.ReadFile(filepath.Join("/proc", pid, "cgroup")); err == nil {
+			for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+				if parts := strings.SplitN(line, ":", 3); len(parts) == 3 {
+					cgroups[parts[2]] = true
+				}
+			}
+		}
+	}
+
+	owners := []string{}
+	for _, unit := range units {
+		for cgroup := range cgroups {
+			if unitOwnsCgroup(unit, cgroup) {
+				owners = append(owners, unit)
+				break
+			}
+		}
+	}
+	return owners
+}
+
+// unitOwnsCgroup returns true if the cgroup path is within the cgroup of the systemd unit or openrc
+// init script.
+func unitOwnsCgroup(unit, cgroup string) bool {
+	name := filepath.Base(unit)
+	if filepath.IsAbs(unit) {
+		name = "openrc." + name
+	}
+	for _, segment := range strings.Split(cgroup, "/") {
+		if segment == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package util
+
+import "testing"
+
+func Test_UnitUnitOwnsCgroup(t *testing.T) {
+	tests := []struct {
+		unit   string
+		cgroup string
+		want   bool
+	}{
+		{unit: "k3s.service", cgroup: "/system.slice/k3s.service", want: true},
+		{unit: "k3s.service", cgroup: "/system.slice/k3s.service/k3s-supervisor", want: true},
+		{unit: "k3s.service", cgroup: "/system.slice/k3s-agent.service", want: false},
+		{unit: "k3s-agent.service", cgroup: "/system.slice/k3s-agent.service", want: true},
+		{unit: "/etc/init.d/k3s", cgroup: "/openrc.k3s", want: true},
+		{unit: "/etc/init.d/k3s", cgroup: "/openrc.k3s-agent", want: false},
+		{unit: "k3s.service", cgroup: "/user.slice/user-1000.slice/session-1.scope", want: false},
+	}
+	for _, tt := range tests {
+		if got := unitOwnsCgroup(tt.unit, tt.cgroup); got != tt.want {
+			t.Errorf("unitOwnsCgroup(%q, %q) = %v, want %v", tt.unit, tt.cgroup, got, tt.want)
+		}
+	}
+}
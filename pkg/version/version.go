@@ -9,4 +9,35 @@ var (
 	GitCommit    = "HEAD"
 
 	UpstreamGolang = ""
+
+	// Time at which the binary was built, in RFC3339 format, set at build time.
+	BuildDate = ""
+
+	// Minimum version of the k3s-selinux package, set at build time from the packaging version.
+	SELinuxPolicy = ""
+
+	// Versions of embedded components, set at build time.
+	Containerd = ""
+	Runc       = ""
+	CNIPlugins = ""
+	Flannel    = ""
+	Kine       = ""
 )
+
+// Components returns the versions of embedded components, keyed by component name. Components
+// whose version was not set at build time are omitted.
+func Components() map[string]string {
+	components := map[string]string{}
+	for name, version := range map[string]string{
+		"containerd":  Containerd,
+		"runc":        Runc,
+		"cni-plugins": CNIPlugins,
+		"flannel":     Flannel,
+		"kine":        Kine,
+	} {
+		if version != "" {
+			components[name] = version
+		}
+	}
+	return components
+}
@@ -0,0 +1,17 @@
+#!/bin/bash
+set -e
+
+cd $(dirname $0)
+
+# Fail if the packaged image digests embedded in the binary are empty, or do not cover every image in
+# image-list.txt.
+if [ "$(jq 'length' ../../pkg/images/digests.json)" = "0" ]; then
+    echo "pkg/images/digests.json is empty"
+    exit 1
+fi
+missing=$(jq -r --rawfile images image-list.txt '($images | split("\n") | map(select(. != ""))) - keys | .[]' ../../pkg/images/digests.json)
+if [ -n "$missing" ]; then
+    echo "pkg/images/digests.json has no digests for:"
+    echo "$missing"
+    exit 1
+fi
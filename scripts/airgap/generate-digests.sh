@@ -0,0 +1,11 @@
+#!/bin/bash
+set -e -x
+
+cd $(dirname $0)
+
+# Record the index digest and per-platform manifest digests of each image in image-list.txt, for
+# the packaged image list embedded in the binary.
+for image in $(cat image-list.txt); do
+    docker buildx imagetools inspect --format '{{json .Manifest}}' ${image} \
+        | jq --arg image "${image}" '{($image): {digest: .digest, platforms: ([.manifests[]? | select(.platform.os != "unknown") | {key: ([.platform.os, .platform.architecture, .platform.variant] | map(select(. != null)) | join("/")), value: .digest}] | from_entries)}}'
+done | jq -s 'add // {}' | tee ../../pkg/images/digests.json
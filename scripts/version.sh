@@ -63,6 +63,11 @@ if [ -z "$VERSION_FLANNEL" ]; then
   VERSION_FLANNEL="v0.0.0"
 fi
 
+VERSION_KINE=$(get-module-version github.com/k3s-io/kine)
+if [ -z "$VERSION_KINE" ]; then
+  VERSION_KINE="v0.0.0"
+fi
+
 VERSION_CRI_DOCKERD=$(get-module-version github.com/Mirantis/cri-dockerd)
 if [ -z "$VERSION_CRI_DOCKERD" ]; then
   VERSION_CRI_DOCKERD="v0.0.0"
@@ -78,6 +83,9 @@ fi
 
 VERSION_ROOT="v0.14.1"
 
+# minimum k3s-selinux package version; keep in sync with selinuxPolicyVersion in contrib/util/check-config.sh
+VERSION_SELINUX_POLICY="0.1-1"
+
 DEPENDENCIES_URL="https://raw.githubusercontent.com/kubernetes/kubernetes/${VERSION_K8S}/build/dependencies.yaml"
 VERSION_GOLANG="go"$(curl -sL "${DEPENDENCIES_URL}" | yq e '.dependencies[] | select(.name == "golang: upstream version").version' -)
 
@@ -1,7 +1,6 @@
 package integration
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -11,15 +10,12 @@ import (
 	"os/exec"
 	"os/user"
 	"strings"
-	"syscall"
-	"time"
 
 	"github.com/k3s-io/k3s/pkg/flock"
+	"github.com/k3s-io/k3s/pkg/testing/k3stest"
 	"github.com/k3s-io/k3s/tests"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"github.com/vishvananda/netlink"
-	"golang.org/x/sys/unix"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -30,10 +26,7 @@ var existingServer = "False"
 const lockFile = "/tmp/k3s-test.lock"
 const DefaultConfig = "/etc/rancher/k3s/k3s.yaml"
 
-type K3sServer struct {
-	cmd *exec.Cmd
-	log *os.File
-}
+type K3sServer = k3stest.Server
 
 func findK3sExecutable() string {
 	// if running on an existing cluster, it maybe installed via k3s.service
@@ -153,20 +146,7 @@ func GetPersistentVolume(name string) (*corev1.PersistentVolume, error) {
 }
 
 func SearchK3sLog(k3s *K3sServer, target string) (bool, error) {
-	file, err := os.Open(k3s.log.Name())
-	if err != nil {
-		return false, err
-	}
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		if strings.Contains(scanner.Text(), target) {
-			return true, nil
-		}
-	}
-	if scanner.Err() != nil {
-		return false, scanner.Err()
-	}
-	return false, nil
+	return k3s.SearchLog(target)
 }
 
 func K3sTestLock() (int, error) {
@@ -174,47 +154,23 @@ func K3sTestLock() (int, error) {
 	return flock.Acquire(lockFile)
 }
 
-// K3sStartServer acquires an exclusive lock on a temporary file, then launches a k3s cluster
-// with the provided arguments. Subsequent/parallel calls to this function will block until
-// the original lock is cleared using K3sKillServer
+// K3sStartServer launches a k3s cluster with the provided arguments.
 func K3sStartServer(inputArgs ...string) (*K3sServer, error) {
 	if !IsRoot() {
 		return nil, errors.New("integration tests must be run as sudo/root")
 	}
 
-	k3sBin := findK3sExecutable()
-	k3sCmd := append([]string{"server"}, inputArgs...)
-	cmd := exec.Command(k3sBin, k3sCmd...)
-	// Give the server a new group id so we can kill it and its children later
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-	// Pipe output to a file for debugging later
-	f, err := os.Create("./k3log.txt")
-	if err != nil {
-		return nil, err
-	}
-	cmd.Stderr = f
-	logrus.Info("Starting k3s server. Check k3log.txt for logs")
-	err = cmd.Start()
-	return &K3sServer{cmd, f}, err
+	return k3stest.StartServer(context.Background(),
+		k3stest.WithBinary(findK3sExecutable()),
+		k3stest.WithLogFile("./k3log.txt"),
+		k3stest.WithArgs(inputArgs...),
+	)
 }
 
 // K3sStopServer gracefully stops the running K3s server and does not kill its children.
 // Equivalent to stopping the K3s service
 func K3sStopServer(server *K3sServer) error {
-	if server.log != nil {
-		server.log.Close()
-	}
-	if err := server.cmd.Process.Signal(syscall.SIGTERM); err != nil {
-		return err
-	}
-	time.Sleep(10 * time.Second)
-	if err := server.cmd.Process.Kill(); err != nil {
-		return err
-	}
-	if _, err := server.cmd.Process.Wait(); err != nil {
-		return err
-	}
-	return nil
+	return server.Stop()
 }
 
 // K3sKillServer terminates the running K3s server and its children.
@@ -223,59 +179,14 @@ func K3sKillServer(server *K3sServer) error {
 	if server == nil {
 		return nil
 	}
-	if server.log != nil {
-		server.log.Close()
-		os.Remove(server.log.Name())
-	}
-	pgid, err := syscall.Getpgid(server.cmd.Process.Pid)
-	if err != nil {
-		if errors.Is(err, syscall.ESRCH) {
-			logrus.Warnf("Unable to kill k3s server: %v", err)
-			return nil
-		}
-		return errors.Wrap(err, "failed to find k3s process group")
-	}
-	if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil {
-		return errors.Wrap(err, "failed to kill k3s process group")
-	}
-	if err := server.cmd.Process.Kill(); err != nil {
-		return errors.Wrap(err, "failed to kill k3s process")
-	}
-	if _, err = server.cmd.Process.Wait(); err != nil {
-		return errors.Wrap(err, "failed to wait for k3s process exit")
-	}
-	//Unmount all the associated filesystems
-	unmountFolder("/run/k3s")
-	unmountFolder("/run/netns/cni-")
-
-	return nil
+	defer os.Remove(server.LogFile())
+	return server.Kill()
 }
 
 // K3sCleanup unlocks the test-lock and
 // attempts to cleanup networking and files leftover from an integration test.
-// This is similar to the k3s-killall.sh script, but we dynamically generate that on
-// install, so we don't have access to it during testing.
 func K3sCleanup(k3sTestLock int, dataDir string) error {
-	if cni0Link, err := netlink.LinkByName("cni0"); err == nil {
-		links, _ := netlink.LinkList()
-		for _, link := range links {
-			if link.Attrs().MasterIndex == cni0Link.Attrs().Index {
-				netlink.LinkDel(link)
-			}
-		}
-		netlink.LinkDel(cni0Link)
-	}
-
-	if flannel1, err := netlink.LinkByName("flannel.1"); err == nil {
-		netlink.LinkDel(flannel1)
-	}
-	if flannelV6, err := netlink.LinkByName("flannel-v6.1"); err == nil {
-		netlink.LinkDel(flannelV6)
-	}
-	if dataDir == "" {
-		dataDir = "/var/lib/rancher/k3s"
-	}
-	if err := os.RemoveAll(dataDir); err != nil {
+	if err := k3stest.Cleanup(dataDir); err != nil {
 		return err
 	}
 	if k3sTestLock != -1 {
@@ -285,11 +196,10 @@ func K3sCleanup(k3sTestLock int, dataDir string) error {
 }
 
 func K3sSaveLog(server *K3sServer, dump bool) error {
-	server.log.Close()
 	if !dump {
 		return nil
 	}
-	log, err := os.Open(server.log.Name())
+	log, err := os.Open(server.LogFile())
 	if err != nil {
 		return err
 	}
@@ -336,32 +246,6 @@ func RunCommand(cmd string) (string, error) {
 	return out.String(), nil
 }
 
-func unmountFolder(folder string) error {
-	mounts := []string{}
-	f, err := os.ReadFile("/proc/self/mounts")
-	if err != nil {
-		return err
-	}
-	for _, line := range strings.Split(string(f), "\n") {
-		columns := strings.Split(line, " ")
-		if len(columns) > 1 {
-			mounts = append(mounts, columns[1])
-		}
-	}
-
-	for _, mount := range mounts {
-		if strings.Contains(mount, folder) {
-			if err := unix.Unmount(mount, 0); err != nil {
-				return err
-			}
-			if err = os.Remove(mount); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
 func ParsePodsInNS(namespace string) ([]corev1.Pod, error) {
 	clientSet, err := tests.K8sClient(DefaultConfig)
 	if err != nil {
@@ -0,0 +1,81 @@
+/*
+This test verifies that a server with all optional packaged components and embedded controllers disabled
+stays within the memory budget of a 512MB device, as reported by k3s debug memstats. The budget can be
+changed with the MEMSTATS_BUDGET environment variable when checking other targets locally.
+*/
+package integration
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	testutil "github.com/k3s-io/k3s/tests/integration"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var server *testutil.K3sServer
+var memStatsServerArgs = []string{
+	"--disable=traefik,servicelb,metrics-server,local-storage",
+	"--disable-helm-controller",
+	"--disable-network-policy",
+	"--disable-cloud-controller",
+}
+var testLock int
+
+var _ = BeforeSuite(func() {
+	if !testutil.IsExistingServer() {
+		var err error
+		testLock, err = testutil.K3sTestLock()
+		Expect(err).ToNot(HaveOccurred())
+		server, err = testutil.K3sStartServer(memStatsServerArgs...)
+		Expect(err).ToNot(HaveOccurred())
+	}
+})
+
+var _ = Describe("memory budget", Ordered, func() {
+	BeforeEach(func() {
+		if testutil.IsExistingServer() && !testutil.ServerArgsPresent(memStatsServerArgs) {
+			Skip("Test needs k3s server with: " + strings.Join(memStatsServerArgs, " "))
+		}
+	})
+	When("optional components are disabled", func() {
+		It("reports the disabled components", func() {
+			Eventually(func() (string, error) {
+				return testutil.K3sCmd("debug", "memstats")
+			}, "120s", "5s").Should(And(ContainSubstring("traefik"), ContainSubstring("helm-controller")))
+		})
+		It("stays within the memory budget", func() {
+			budget := os.Getenv("MEMSTATS_BUDGET")
+			if budget == "" {
+				budget = "512Mi"
+			}
+			// let startup allocations settle before checking resident memory
+			Consistently(func() error {
+				_, err := testutil.K3sCmd("debug", "memstats", "--budget", budget)
+				return err
+			}, "30s", "10s").Should(Succeed())
+		})
+	})
+})
+
+var failed bool
+var _ = AfterEach(func() {
+	failed = failed || CurrentSpecReport().Failed()
+})
+
+var _ = AfterSuite(func() {
+	if !testutil.IsExistingServer() && os.Getenv("CI") != "true" {
+		if failed {
+			testutil.K3sSaveLog(server, false)
+		}
+		Expect(testutil.K3sKillServer(server)).To(Succeed())
+		Expect(testutil.K3sCleanup(testLock, "")).To(Succeed())
+	}
+})
+
+func Test_IntegrationMemStats(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Memory Budget Suite")
+}